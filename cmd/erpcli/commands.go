@@ -0,0 +1,260 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/config"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/database"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/eventbus"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
+	"github.com/spf13/cobra"
+)
+
+// The commands of erpcli. Each opens the database and sets up only the
+// repositories and use cases it runs on.
+
+func createAdminCommand() *cobra.Command {
+	var username, email, password string
+	cmd := &cobra.Command{
+		Use:   "create-admin",
+		Short: "Create a user with the admin role",
+		Long: `Create a user with the admin role the server seeds the database with. The
+password is read from standard input when not given.`,
+		Args: cobra.NoArgs,
+		RunE: withConfig(func(ctx context.Context, cfg *config.Config, args []string) error {
+			if password == "" {
+				fmt.Fprint(os.Stderr, "Password: ")
+				line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+				if err != nil && line == "" {
+					return fmt.Errorf("failed to read password: %w", err)
+				}
+				password = strings.TrimRight(line, "\r\n")
+			}
+			if password == "" {
+				return errors.New("the password must not be empty")
+			}
+
+			db, err := openDatabase(ctx, cfg)
+			if err != nil {
+				return err
+			}
+			defer closeDatabase(db)
+			userUC := usecase.NewUserUseCase(repository.NewUserRepository(db))
+			roleUC := usecase.NewRoleUseCase(repository.NewRoleRepository(db))
+
+			role, err := roleUC.GetRoleByName("admin")
+			if err != nil {
+				return fmt.Errorf("failed to get the admin role: %w", err)
+			}
+			user, err := userUC.CreateUser(&usecase.CreateUserInput{
+				Username: username,
+				Email:    email,
+				Password: password,
+				RoleID:   role.ID,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to create admin: %w", err)
+			}
+			fmt.Printf("created admin %s (id %d)\n", user.Username, user.ID)
+			return nil
+		}),
+	}
+	cmd.Flags().StringVar(&username, "username", "", "username of the admin")
+	cmd.Flags().StringVar(&email, "email", "", "email of the admin")
+	cmd.Flags().StringVar(&password, "password", "", "password of the admin")
+	cmd.MarkFlagRequired("username")
+	cmd.MarkFlagRequired("email")
+	return cmd
+}
+
+func assignRoleCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "assign-role <user> <role>",
+		Short: "Give a user a role",
+		Long:  "Give the user of an ID, email or username the role of a name.",
+		Args:  cobra.ExactArgs(2),
+		RunE: withConfig(func(ctx context.Context, cfg *config.Config, args []string) error {
+			login, roleName := args[0], args[1]
+
+			db, err := openDatabase(ctx, cfg)
+			if err != nil {
+				return err
+			}
+			defer closeDatabase(db)
+			userUC := usecase.NewUserUseCase(repository.NewUserRepository(db))
+			roleUC := usecase.NewRoleUseCase(repository.NewRoleRepository(db))
+
+			user, err := userUC.FindUser(login)
+			if err != nil {
+				return fmt.Errorf("failed to get user %s: %w", login, err)
+			}
+			role, err := roleUC.GetRoleByName(roleName)
+			if err != nil {
+				return fmt.Errorf("failed to get role %s: %w", roleName, err)
+			}
+			user, err = userUC.AssignRole(user.ID, role)
+			if err != nil {
+				return fmt.Errorf("failed to assign role: %w", err)
+			}
+			fmt.Printf("%s (id %d) now has role %s\n", user.Username, user.ID, roleName)
+			return nil
+		}),
+	}
+}
+
+// migrateCommand applies the pending migrations. Unlike the other commands,
+// it opens a database that is not migrated yet.
+func migrateCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "migrate",
+		Short: "Apply the pending database migrations",
+		Long: `Apply the pending database migrations. The migrate command reverts and
+inspects them.`,
+		Args: cobra.NoArgs,
+		RunE: withConfig(func(ctx context.Context, cfg *config.Config, args []string) error {
+			db, err := database.Open(cfg)
+			if err != nil {
+				return fmt.Errorf("failed to open database: %w", err)
+			}
+			defer closeDatabase(db)
+			sqlDB, err := db.DB()
+			if err != nil {
+				return fmt.Errorf("failed to get database handle: %w", err)
+			}
+
+			migrator, err := database.NewMigrator(sqlDB)
+			if err != nil {
+				return fmt.Errorf("failed to load migrations: %w", err)
+			}
+			applied, err := migrator.Up(ctx)
+			for _, m := range applied {
+				fmt.Printf("applied %d_%s\n", m.Version, m.Name)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to migrate: %w", err)
+			}
+			if len(applied) == 0 {
+				fmt.Println("no pending migrations")
+			}
+			return nil
+		}),
+	}
+}
+
+func runReportsCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "run-reports",
+		Short: "Queue the reports of the due report schedules",
+		Long: `Queue the reports of the due report schedules, as the report_schedules task
+of the scheduler does. The job workers of the running servers generate and
+send them.`,
+		Args: cobra.NoArgs,
+		RunE: withConfig(func(ctx context.Context, cfg *config.Config, args []string) error {
+			db, err := openDatabase(ctx, cfg)
+			if err != nil {
+				return err
+			}
+			defer closeDatabase(db)
+
+			// Queueing a run needs the schedules, the working calendar the next
+			// runs fall on and the job queue. The services generating and
+			// sending the reports run in the job workers of the servers, so
+			// they are not set up here.
+			jobUC := usecase.NewJobUseCase(repository.NewJobRepository(db), time.Duration(cfg.Jobs.TimeoutMinutes)*time.Minute, cfg.Jobs.MaxAttempts)
+			calendarUC := usecase.NewCalendarUseCase(repository.NewCalendarRepository(db))
+			reportUC := usecase.NewReportUseCase(repository.NewReportRepository(db), nil, nil, nil, nil, nil, calendarUC, nil, nil, jobUC, nil, nil, 0, 0)
+
+			if err := reportUC.RunScheduledReports(ctx); err != nil {
+				return fmt.Errorf("failed to run scheduled reports: %w", err)
+			}
+			fmt.Println("queued the reports of the due schedules")
+			return nil
+		}),
+	}
+}
+
+func reindexSearchCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "reindex-search [type...]",
+		Short: "Rebuild the search indexes",
+		Long: `Rebuild the search indexes of the given types (SKU, VENDOR_ITEM, CLIENT,
+VENDOR, SALES_ORDER, PURCHASE_ORDER), or of all of them.`,
+		RunE: withConfig(func(ctx context.Context, cfg *config.Config, args []string) error {
+			types := make([]entity.SearchEntityType, len(args))
+			for i, arg := range args {
+				types[i] = entity.SearchEntityType(strings.ToUpper(arg))
+			}
+
+			db, err := openDatabase(ctx, cfg)
+			if err != nil {
+				return err
+			}
+			defer closeDatabase(db)
+			searchUC := usecase.NewSearchUseCase(repository.NewSearchRepository(db, cfg.Search.Similarity))
+
+			if err := searchUC.Reindex(ctx, types); err != nil {
+				return fmt.Errorf("failed to reindex search: %w", err)
+			}
+			fmt.Println("rebuilt the search indexes")
+			return nil
+		}),
+	}
+}
+
+func replayWebhooksCommand() *cobra.Command {
+	var provider string
+	cmd := &cobra.Command{
+		Use:   "replay-webhooks",
+		Short: "Apply the failed payment gateway events again",
+		Long: `Apply the failed payment gateway events again, oldest first, printing the new
+status of each. The payments are recorded in the event log.`,
+		Args: cobra.NoArgs,
+		RunE: withConfig(func(ctx context.Context, cfg *config.Config, args []string) error {
+			db, err := openDatabase(ctx, cfg)
+			if err != nil {
+				return err
+			}
+			defer closeDatabase(db)
+
+			// The payments are applied by the finance use case, whose events
+			// are recorded in the event log. The other consumers of the
+			// server's bus, as notifications and the broker, do not run here.
+			bus := eventbus.New()
+			usecase.NewEventLogUseCase(repository.NewEventLogRepository(db)).Subscribe(bus)
+			currencyUC := usecase.NewCurrencyUseCase(repository.NewCurrencyRepository(db), cfg.Finance.BaseCurrency, nil)
+			fiscalUC := usecase.NewFiscalUseCase(repository.NewFiscalRepository(db))
+			financeRepo := repository.NewFinanceRepository(db, repository.NewPrepaymentRepository(db))
+			financeUC := usecase.NewFinanceUseCase(financeRepo, repository.NewWithholdingRepository(db), currencyUC, fiscalUC, bus)
+			paymentHookUC := usecase.NewPaymentWebhookUseCase(repository.NewPaymentWebhookRepository(db), financeUC)
+
+			replayed, skipped, err := paymentHookUC.ReplayFailed(ctx, provider)
+			failed := 0
+			for _, event := range replayed {
+				fmt.Printf("%s %s  %s", event.Provider, event.EventID, event.Status)
+				if event.Status == entity.PaymentWebhookFailed {
+					fmt.Printf("  %s", event.Error)
+					failed++
+				}
+				fmt.Println()
+			}
+			if err != nil {
+				return fmt.Errorf("failed to replay webhook events: %w", err)
+			}
+			fmt.Printf("replayed %d events, %d failed again\n", len(replayed), failed)
+			if skipped > 0 {
+				fmt.Printf("%d failed events were recorded without their payload and wait for the gateway to deliver them again\n", skipped)
+			}
+			return nil
+		}),
+	}
+	cmd.Flags().StringVar(&provider, "provider", "", "only replay the events of this provider")
+	return cmd
+}
@@ -0,0 +1,100 @@
+// Command erpcli runs administration tasks against the database directly,
+// without the HTTP API, e.g. from a shell in the server's container.
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/config"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/database"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/logging"
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+)
+
+func main() {
+	root := &cobra.Command{
+		Use:   "erpcli",
+		Short: "Run administration tasks against the database",
+		Long: `Run administration tasks against the database, without the HTTP API.
+
+The commands are configured as the server, through config.yaml or the ERP_*
+environment variables. Apart from migrate, they need the database migrated.`,
+		SilenceUsage: true,
+	}
+	root.AddCommand(
+		createAdminCommand(),
+		assignRoleCommand(),
+		migrateCommand(),
+		runReportsCommand(),
+		reindexSearchCommand(),
+		replayWebhooksCommand(),
+	)
+	if err := root.ExecuteContext(context.Background()); err != nil {
+		os.Exit(1)
+	}
+}
+
+// withConfig returns a cobra run function loading the configuration and
+// setting up logging before running fn
+func withConfig(fn func(ctx context.Context, cfg *config.Config, args []string) error) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load configuration: %w", err)
+		}
+		if err := logging.Init(cfg.Logging); err != nil {
+			return fmt.Errorf("failed to set up logging: %w", err)
+		}
+		// The commands are not traced
+		cfg.Tracing.Enabled = false
+
+		return fn(cmd.Context(), cfg, args)
+	}
+}
+
+// openDatabase opens the database, refusing one the migrations are not all
+// applied to. Unlike the server, the commands neither migrate nor seed it.
+func openDatabase(ctx context.Context, cfg *config.Config) (*gorm.DB, error) {
+	db, err := database.Open(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database handle: %w", err)
+	}
+	migrator, err := database.NewMigrator(sqlDB)
+	if err != nil {
+		sqlDB.Close()
+		return nil, fmt.Errorf("failed to load migrations: %w", err)
+	}
+	version, dirty, err := migrator.Version(ctx)
+	if err != nil {
+		sqlDB.Close()
+		return nil, fmt.Errorf("failed to read the migration version: %w", err)
+	}
+	if dirty {
+		sqlDB.Close()
+		return nil, fmt.Errorf("migration %d failed halfway; repair it with the migrate command", version)
+	}
+	if migrations := migrator.Migrations(); len(migrations) > 0 && version < migrations[len(migrations)-1].Version {
+		sqlDB.Close()
+		return nil, fmt.Errorf("the database is migrated to version %d of %d; run erpcli migrate first", version, migrations[len(migrations)-1].Version)
+	}
+	return db, nil
+}
+
+// closeDatabase closes the connections of the database
+func closeDatabase(db *gorm.DB) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return
+	}
+	if err := sqlDB.Close(); err != nil {
+		log.Printf("Failed to close database: %v", err)
+	}
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"log"
+	"log/slog"
 	"os"
 	"os/signal"
 	"syscall"
@@ -10,6 +11,7 @@ import (
 
 	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/config"
 	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/gateway"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/logging"
 )
 
 func main() {
@@ -18,17 +20,22 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
+	if err := logging.Init(cfg.Logging); err != nil {
+		log.Fatalf("Failed to set up logging: %v", err)
+	}
 
 	// Create API Gateway
 	apiGateway, err := gateway.NewGateway(cfg)
 	if err != nil {
-		log.Fatalf("Failed to create API Gateway: %v", err)
+		slog.Error("failed to create API Gateway", "error", err)
+		os.Exit(1)
 	}
 
 	// Start API Gateway in a goroutine
 	go func() {
 		if err := apiGateway.Start(); err != nil {
-			log.Fatalf("Failed to start API Gateway: %v", err)
+			slog.Error("failed to start API Gateway", "error", err)
+			os.Exit(1)
 		}
 	}()
 
@@ -36,7 +43,6 @@ func main() {
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
-	log.Println("Shutting down API Gateway...")
 
 	// Create a deadline to wait for
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -44,8 +50,9 @@ func main() {
 
 	// Gracefully shutdown the server
 	if err := apiGateway.Stop(ctx); err != nil {
-		log.Fatalf("API Gateway forced to shutdown: %v", err)
+		slog.Error("API Gateway forced to shut down", "error", err)
+		os.Exit(1)
 	}
 
-	log.Println("API Gateway exited properly")
+	slog.Info("API Gateway exited properly")
 }
@@ -0,0 +1,152 @@
+// Command migrate applies and reverts the database migrations and checks the
+// models against the migrated schema.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/config"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/database"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/logging"
+)
+
+const usage = `Usage: migrate <command> [arguments]
+
+Commands:
+  up                apply the pending migrations
+  down [n]          revert the last n applied migrations, 1 by default
+  status            list the migrations and whether each is applied
+  version           print the version the database is migrated to
+  force <version>   record version as applied without running migrations,
+                    after repairing a dirty database or to adopt a database
+                    created otherwise; 0 records that none is applied
+  drift             compare the models with the schema, exiting with status 1
+                    when they differ
+
+The database is configured as for the server, through config.yaml or the
+ERP_DATABASE_* environment variables.
+`
+
+func main() {
+	flag.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+	if err := logging.Init(cfg.Logging); err != nil {
+		log.Fatalf("Failed to set up logging: %v", err)
+	}
+	// The command only migrates, so its queries are not traced
+	cfg.Tracing.Enabled = false
+
+	db, err := database.Open(cfg)
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		log.Fatalf("Failed to get database handle: %v", err)
+	}
+	defer sqlDB.Close()
+
+	migrator, err := database.NewMigrator(sqlDB)
+	if err != nil {
+		log.Fatalf("Failed to load migrations: %v", err)
+	}
+
+	ctx := context.Background()
+	args := flag.Args()[1:]
+	switch flag.Arg(0) {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, m := range applied {
+			fmt.Printf("applied %d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatalf("Failed to migrate: %v", err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("no pending migrations")
+		}
+
+	case "down":
+		steps := 1
+		if len(args) > 0 {
+			if steps, err = strconv.Atoi(args[0]); err != nil || steps < 1 {
+				log.Fatalf("Invalid number of migrations %q", args[0])
+			}
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		for _, m := range reverted {
+			fmt.Printf("reverted %d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatalf("Failed to revert: %v", err)
+		}
+
+	case "status":
+		status, err := migrator.Status(ctx)
+		if err != nil {
+			log.Fatalf("Failed to read migration status: %v", err)
+		}
+		for _, s := range status {
+			state := "pending"
+			if s.Applied {
+				state = "applied"
+			}
+			fmt.Printf("%06d  %-8s  %s\n", s.Version, state, s.Name)
+		}
+
+	case "version":
+		version, dirty, err := migrator.Version(ctx)
+		if err != nil {
+			log.Fatalf("Failed to read version: %v", err)
+		}
+		if dirty {
+			fmt.Printf("%d (dirty)\n", version)
+		} else {
+			fmt.Println(version)
+		}
+
+	case "force":
+		if len(args) != 1 {
+			log.Fatalf("force takes the version to record")
+		}
+		version, err := strconv.ParseUint(args[0], 10, 64)
+		if err != nil {
+			log.Fatalf("Invalid version %q", args[0])
+		}
+		if err := migrator.Force(ctx, uint(version)); err != nil {
+			log.Fatalf("Failed to force version: %v", err)
+		}
+
+	case "drift":
+		issues, err := database.CheckDrift(db)
+		if err != nil {
+			log.Fatalf("Failed to check drift: %v", err)
+		}
+		for _, issue := range issues {
+			fmt.Println(issue)
+		}
+		if len(issues) > 0 {
+			fmt.Fprintf(os.Stderr, "%d differences between the models and the schema\n", len(issues))
+			os.Exit(1)
+		}
+		fmt.Println("the schema matches the models")
+
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
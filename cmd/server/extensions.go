@@ -0,0 +1,6 @@
+package main
+
+// Compiled-in extensions are enabled by blank-importing their packages here.
+// Each package registers itself with extension.Register from an init function.
+//
+//	import _ "github.com/example/erp-extensions/approvalnotify"
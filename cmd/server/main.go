@@ -1,10 +1,17 @@
 package main
 
 import (
+	"context"
 	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/config"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/logging"
 	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/server"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
@@ -38,6 +45,9 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
+	if err := logging.Init(cfg.Logging); err != nil {
+		log.Fatalf("Failed to set up logging: %v", err)
+	}
 
 	// Set Gin mode
 	if cfg.Server.Mode == "release" {
@@ -47,15 +57,37 @@ func main() {
 	// Initialize server
 	srv, err := server.NewServer(cfg)
 	if err != nil {
-		log.Fatalf("Failed to create server: %v", err)
+		slog.Error("failed to create server", "error", err)
+		os.Exit(1)
 	}
 
 	// Add Swagger documentation route
 	srv.Router().GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
-	// Start server
-	log.Printf("Server starting on port %s", cfg.Server.Port)
-	if err := srv.Run(); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	// Start server in a goroutine
+	go func() {
+		slog.Info("server starting", "port", cfg.Server.Port)
+		if err := srv.Run(); err != nil {
+			slog.Error("failed to start server", "error", err)
+			os.Exit(1)
+		}
+	}()
+
+	// Wait for interrupt signal to gracefully shut down the server
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+	slog.Info("shutting down server")
+
+	// Create a deadline to wait for
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.Server.ShutdownSeconds)*time.Second)
+	defer cancel()
+
+	// Drain requests, stop background work and release connections
+	if err := srv.Shutdown(ctx); err != nil {
+		slog.Error("server forced to shut down", "error", err)
+		os.Exit(1)
 	}
+
+	slog.Info("server exited properly")
 }
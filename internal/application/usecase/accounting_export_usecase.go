@@ -0,0 +1,224 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/accounting"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
+)
+
+var (
+	ErrAccountingExportNotFound = entity.NewError(entity.ErrCodeNotFound, "accounting export not found")
+	ErrUnknownExportFormat      = entity.NewError(entity.ErrCodeInvalidArgument, "format must be QUICKBOOKS_IIF, XERO_CSV or JSON")
+	ErrInvalidExportRange       = entity.NewError(entity.ErrCodeInvalidArgument, "to_date must not be before from_date")
+	ErrNothingToExport          = entity.NewError(entity.ErrCodeFailedPrecondition, "no finance records left to export in that range")
+	ErrAccountingExportOverlap  = entity.NewError(entity.ErrCodeConflict, "records of the range were exported by another run meanwhile")
+)
+
+// AccountingExportUseCase exports finance invoices, payments and journal
+// entries to the import files of external accounting systems. Each record is
+// exported once per format, so repeated exports of a range only pick up what
+// was posted since.
+type AccountingExportUseCase struct {
+	exportRepo *repository.AccountingExportRepository
+	accounts   accounting.Accounts
+}
+
+// NewAccountingExportUseCase creates a new accounting export use case posting
+// to the given chart of accounts
+func NewAccountingExportUseCase(exportRepo *repository.AccountingExportRepository, accounts accounting.Accounts) *AccountingExportUseCase {
+	return &AccountingExportUseCase{
+		exportRepo: exportRepo,
+		accounts:   accounts,
+	}
+}
+
+// Export renders the records of a date range not yet exported in a format and
+// flags them as exported
+func (u *AccountingExportUseCase) Export(ctx context.Context, req *entity.AccountingExportRequest, userID uint) (*entity.AccountingExportRun, error) {
+	if !accounting.ValidFormat(req.Format) {
+		return nil, ErrUnknownExportFormat
+	}
+	from := truncateDay(req.FromDate)
+	to := truncateDay(req.ToDate)
+	if to.Before(from) {
+		return nil, ErrInvalidExportRange
+	}
+	end := to.AddDate(0, 0, 1)
+
+	invoices, err := u.exportRepo.PendingInvoices(ctx, req.Format, from, end)
+	if err != nil {
+		return nil, fmt.Errorf("error getting invoices to export: %w", err)
+	}
+	payments, err := u.exportRepo.PendingPayments(ctx, req.Format, from, end)
+	if err != nil {
+		return nil, fmt.Errorf("error getting payments to export: %w", err)
+	}
+	depreciation, err := u.exportRepo.PendingDepreciation(ctx, req.Format, from, end)
+	if err != nil {
+		return nil, fmt.Errorf("error getting depreciation to export: %w", err)
+	}
+	provisions, err := u.exportRepo.PendingProvisions(ctx, req.Format, from, end)
+	if err != nil {
+		return nil, fmt.Errorf("error getting provisions to export: %w", err)
+	}
+	if len(invoices) == 0 && len(payments) == 0 && len(depreciation) == 0 && len(provisions) == 0 {
+		return nil, ErrNothingToExport
+	}
+
+	records := make([]entity.AccountingExportRecord, 0, len(invoices)+len(payments)+len(depreciation)+len(provisions))
+	for _, invoice := range invoices {
+		records = append(records, entity.AccountingExportRecord{RecordType: entity.AccountingRecordInvoice, RecordID: strconv.FormatInt(invoice.ID, 10)})
+	}
+	for _, payment := range payments {
+		records = append(records, entity.AccountingExportRecord{RecordType: entity.AccountingRecordPayment, RecordID: strconv.FormatInt(payment.ID, 10)})
+	}
+	for _, entry := range depreciation {
+		records = append(records, entity.AccountingExportRecord{RecordType: entity.AccountingRecordDepreciation, RecordID: strconv.FormatUint(uint64(entry.ID), 10)})
+	}
+	for _, entry := range provisions {
+		records = append(records, entity.AccountingExportRecord{RecordType: entity.AccountingRecordProvision, RecordID: strconv.FormatUint(uint64(entry.ID), 10)})
+	}
+
+	batch := &accounting.Batch{
+		Invoices: invoices,
+		Payments: payments,
+		Journals: append(u.depreciationJournals(depreciation), u.provisionJournals(provisions)...),
+	}
+	name := fmt.Sprintf("accounting-%s-%s", from.Format("20060102"), to.Format("20060102"))
+	file, err := accounting.Render(req.Format, batch, u.accounts, name)
+	if err != nil {
+		return nil, fmt.Errorf("error rendering accounting export: %w", err)
+	}
+
+	run := &entity.AccountingExportRun{
+		Format:         req.Format,
+		FromDate:       from,
+		ToDate:         to,
+		Invoices:       len(invoices),
+		Payments:       len(payments),
+		JournalEntries: len(batch.Journals),
+		FileName:       file.Name,
+		ContentType:    file.ContentType,
+		Content:        file.Content,
+		CreatedBy:      userID,
+	}
+	if err := u.exportRepo.CreateRun(ctx, run, records); err != nil {
+		if errors.Is(err, repository.ErrDuplicateEntry) {
+			return nil, ErrAccountingExportOverlap
+		}
+		return nil, fmt.Errorf("error saving accounting export: %w", err)
+	}
+	return run, nil
+}
+
+// GetRun gets an export run with its file
+func (u *AccountingExportUseCase) GetRun(ctx context.Context, id uint) (*entity.AccountingExportRun, error) {
+	run, err := u.exportRepo.GetRun(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, ErrAccountingExportNotFound
+		}
+		return nil, fmt.Errorf("error getting accounting export: %w", err)
+	}
+	return run, nil
+}
+
+// ListRuns lists the export run history, latest first
+func (u *AccountingExportUseCase) ListRuns(ctx context.Context, page, pageSize int) ([]entity.AccountingExportRun, int64, error) {
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 10
+	}
+	runs, total, err := u.exportRepo.ListRuns(ctx, page, pageSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error listing accounting exports: %w", err)
+	}
+	return runs, total, nil
+}
+
+// depreciationJournals posts the depreciation of each period as one journal
+// entry dated the last day of the period
+func (u *AccountingExportUseCase) depreciationJournals(entries []entity.AssetDepreciationEntry) []entity.AccountingJournalEntry {
+	totals := make(map[string]float64)
+	for _, entry := range entries {
+		totals[entry.Period] += entry.Amount
+	}
+
+	var journals []entity.AccountingJournalEntry
+	for _, period := range sortedPeriods(totals) {
+		amount := roundAmount(totals[period])
+		if amount == 0 {
+			continue
+		}
+		journals = append(journals, entity.AccountingJournalEntry{
+			Reference: "DEP-" + period,
+			Date:      periodEnd(period),
+			Memo:      "Depreciation " + period,
+			Lines: []entity.AccountingJournalLine{
+				{Account: u.accounts.DepreciationExpense, Debit: amount},
+				{Account: u.accounts.AccumulatedDepreciation, Credit: amount},
+			},
+		})
+	}
+	return journals
+}
+
+// provisionJournals posts the net inventory provision movement of each period
+// as one journal entry dated the last day of the period. A net release
+// reverses the provision posting.
+func (u *AccountingExportUseCase) provisionJournals(entries []entity.InventoryProvisionEntry) []entity.AccountingJournalEntry {
+	totals := make(map[string]float64)
+	for _, entry := range entries {
+		totals[entry.Period] += entry.Amount
+	}
+
+	var journals []entity.AccountingJournalEntry
+	for _, period := range sortedPeriods(totals) {
+		amount := roundAmount(totals[period])
+		if amount == 0 {
+			continue
+		}
+		debit, credit := u.accounts.ProvisionExpense, u.accounts.InventoryProvision
+		if amount < 0 {
+			debit, credit, amount = credit, debit, -amount
+		}
+		journals = append(journals, entity.AccountingJournalEntry{
+			Reference: "PRV-" + period,
+			Date:      periodEnd(period),
+			Memo:      "Inventory provision " + period,
+			Lines: []entity.AccountingJournalLine{
+				{Account: debit, Debit: amount},
+				{Account: credit, Credit: amount},
+			},
+		})
+	}
+	return journals
+}
+
+// sortedPeriods returns the periods of a map of totals in order
+func sortedPeriods(totals map[string]float64) []string {
+	periods := make([]string, 0, len(totals))
+	for period := range totals {
+		periods = append(periods, period)
+	}
+	sort.Strings(periods)
+	return periods
+}
+
+// periodEnd returns the last day of a YYYY-MM period
+func periodEnd(period string) time.Time {
+	start, err := time.Parse(periodLayout, period)
+	if err != nil {
+		return time.Time{}
+	}
+	return start.AddDate(0, 1, -1)
+}
@@ -0,0 +1,236 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
+)
+
+// loyaltyTierRank orders clients for the priority allocation policy, highest first
+var loyaltyTierRank = map[entity.ClientLoyaltyTier]int{
+	entity.ClientLoyaltyTierPlatinum: 3,
+	entity.ClientLoyaltyTierGold:     2,
+	entity.ClientLoyaltyTierSilver:   1,
+	entity.ClientLoyaltyTierStandard: 0,
+}
+
+// AllocationUseCase allocates scarce stock across confirmed sales orders
+type AllocationUseCase struct {
+	allocationRepo *repository.AllocationRepository
+}
+
+// NewAllocationUseCase creates a new allocation use case
+func NewAllocationUseCase(allocationRepo *repository.AllocationRepository) *AllocationUseCase {
+	return &AllocationUseCase{
+		allocationRepo: allocationRepo,
+	}
+}
+
+// demandLine is the quantity of a SKU an open order still needs
+type demandLine struct {
+	order     *entity.SalesOrder
+	tier      int
+	requested float64
+	allocated float64
+}
+
+// Run allocates the stock on hand in a store across the confirmed and processing
+// orders that still need it, following the requested policy. Unless it is a dry
+// run, the allocation replaces the store's active reservations for the SKUs involved.
+func (u *AllocationUseCase) Run(ctx context.Context, req *entity.AllocationRunRequest, userID string) (*entity.AllocationRunResult, error) {
+	orders, err := u.allocationRepo.ListOpenOrders(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error listing open orders: %w", err)
+	}
+	clientIDs := make([]uint, 0, len(orders))
+	for _, order := range orders {
+		clientIDs = append(clientIDs, order.ClientID)
+	}
+	tiers, err := u.allocationRepo.GetClientTiers(ctx, clientIDs)
+	if err != nil {
+		return nil, fmt.Errorf("error getting client tiers: %w", err)
+	}
+	onHand, err := u.allocationRepo.GetStoreStock(ctx, req.StoreID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting store stock: %w", err)
+	}
+
+	onlySKUs := make(map[string]bool, len(req.SKUIDs))
+	for _, skuID := range req.SKUIDs {
+		onlySKUs[skuID] = true
+	}
+
+	demand := make(map[string][]*demandLine)
+	for i := range orders {
+		order := &orders[i]
+		for skuID, qty := range outstandingQuantities(order) {
+			if len(onlySKUs) > 0 && !onlySKUs[skuID] {
+				continue
+			}
+			line := &demandLine{
+				order:     order,
+				tier:      loyaltyTierRank[tiers[order.ClientID]],
+				requested: qty,
+			}
+			demand[skuID] = append(demand[skuID], line)
+		}
+	}
+
+	skuIDs := make([]string, 0, len(demand))
+	for skuID := range demand {
+		skuIDs = append(skuIDs, skuID)
+	}
+	sort.Strings(skuIDs)
+
+	result := &entity.AllocationRunResult{
+		Policy:        req.Policy,
+		StoreID:       req.StoreID,
+		DryRun:        req.DryRun,
+		Available:     make(map[string]float64, len(skuIDs)),
+		Lines:         []entity.AllocationLine{},
+		ShortedOrders: []entity.ShortedOrder{},
+		RunAt:         time.Now(),
+	}
+	if !req.DryRun {
+		result.RunID = uuid.New().String()
+	}
+	createdByID, _ := parseUserID(userID)
+
+	var allocations []entity.StockAllocation
+	shorted := make(map[string]*entity.ShortedOrder)
+	var shortedOrder []string
+
+	for _, skuID := range skuIDs {
+		lines := demand[skuID]
+		available := math.Max(onHand[skuID], 0)
+		result.Available[skuID] = available
+		allocate(req.Policy, lines, available)
+
+		for _, line := range lines {
+			out := entity.AllocationLine{
+				SalesOrderID: line.order.ID,
+				OrderNumber:  line.order.OrderNumber,
+				ClientID:     line.order.ClientID,
+				OrderDate:    line.order.OrderDate,
+				SKUID:        skuID,
+				Requested:    line.requested,
+				Allocated:    line.allocated,
+				Shortfall:    line.requested - line.allocated,
+			}
+			result.Lines = append(result.Lines, out)
+
+			if out.Shortfall > 0 {
+				s, ok := shorted[out.SalesOrderID]
+				if !ok {
+					s = &entity.ShortedOrder{
+						SalesOrderID: out.SalesOrderID,
+						OrderNumber:  out.OrderNumber,
+						ClientID:     out.ClientID,
+					}
+					shorted[out.SalesOrderID] = s
+					shortedOrder = append(shortedOrder, out.SalesOrderID)
+				}
+				s.Lines = append(s.Lines, out)
+			}
+
+			if line.allocated > 0 {
+				allocations = append(allocations, entity.StockAllocation{
+					RunID:             result.RunID,
+					Policy:            req.Policy,
+					SalesOrderID:      line.order.ID,
+					SKUID:             skuID,
+					StoreID:           req.StoreID,
+					RequestedQuantity: line.requested,
+					AllocatedQuantity: line.allocated,
+					Status:            entity.StockAllocationActive,
+					CreatedByID:       createdByID,
+				})
+			}
+		}
+	}
+	for _, orderID := range shortedOrder {
+		result.ShortedOrders = append(result.ShortedOrders, *shorted[orderID])
+	}
+
+	if !req.DryRun {
+		if err := u.allocationRepo.ReplaceAllocations(ctx, req.StoreID, skuIDs, allocations); err != nil {
+			return nil, fmt.Errorf("error reserving stock: %w", err)
+		}
+	}
+	return result, nil
+}
+
+// ListAllocations lists stock allocations
+func (u *AllocationUseCase) ListAllocations(ctx context.Context, filter *entity.StockAllocationFilter) ([]entity.StockAllocation, error) {
+	allocations, err := u.allocationRepo.ListAllocations(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("error listing stock allocations: %w", err)
+	}
+	return allocations, nil
+}
+
+// outstandingQuantities returns the quantity per SKU an order still needs: the
+// ordered quantity, of the components of kits, less what its deliveries have
+// already shipped. Drop-ship lines and their deliveries are left out.
+func outstandingQuantities(order *entity.SalesOrder) map[string]float64 {
+	outstanding := order.Items.StockQuantities()
+	for _, delivery := range order.DeliveryOrders {
+		if delivery.Status != entity.DeliveryOrderStatusInTransit && delivery.Status != entity.DeliveryOrderStatusDelivered {
+			continue
+		}
+		if delivery.PurchaseOrderID != nil {
+			continue
+		}
+		for _, item := range delivery.Items {
+			outstanding[item.SKUID] -= item.ShippedQuantity
+		}
+	}
+	for skuID, qty := range outstanding {
+		if qty <= 0 {
+			delete(outstanding, skuID)
+		}
+	}
+	return outstanding
+}
+
+// allocate distributes the available quantity over the demand lines of one SKU.
+// The lines arrive in order date order.
+func allocate(policy entity.AllocationPolicy, lines []*demandLine, available float64) {
+	switch policy {
+	case entity.AllocationPolicyPriority:
+		sort.SliceStable(lines, func(i, j int) bool {
+			return lines[i].tier > lines[j].tier
+		})
+	case entity.AllocationPolicyProportional:
+		var total float64
+		for _, line := range lines {
+			total += line.requested
+		}
+		if total > available {
+			// Give every order its share in whole units; the units left over by
+			// rounding down go to the oldest orders below
+			for _, line := range lines {
+				line.allocated = math.Floor(available * line.requested / total)
+			}
+			for _, line := range lines {
+				available -= line.allocated
+			}
+		}
+	}
+
+	// Fill lines in order until the stock runs out
+	for _, line := range lines {
+		if available <= 0 {
+			break
+		}
+		qty := math.Min(line.requested-line.allocated, available)
+		line.allocated += qty
+		available -= qty
+	}
+}
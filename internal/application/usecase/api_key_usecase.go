@@ -0,0 +1,185 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
+)
+
+var (
+	ErrAPIKeyNotFound   = entity.NewError(entity.ErrCodeNotFound, "API key not found")
+	ErrAPIKeyInvalid    = entity.NewError(entity.ErrCodeUnauthenticated, "invalid API key")
+	ErrAPIKeyRevoked    = entity.NewError(entity.ErrCodeFailedPrecondition, "API key is revoked")
+	ErrAPIKeyPermission = entity.NewError(entity.ErrCodeInvalidArgument, "API keys can only be given permissions their creator holds, other than managing API keys")
+)
+
+const (
+	// apiKeyPrefix starts every key, for secret scanners to recognize them
+	apiKeyPrefix = "erp_"
+	// apiKeyShownLength is the length of the start of a key kept in clear
+	apiKeyShownLength = len(apiKeyPrefix) + 8
+	// apiKeyTouchInterval is how often the last use of a key is recorded
+	apiKeyTouchInterval = time.Minute
+)
+
+// APIKeyUseCase handles the API keys external systems call the API with:
+// creation, rotation, revocation and their authentication
+type APIKeyUseCase struct {
+	repo *repository.APIKeyRepository
+}
+
+// NewAPIKeyUseCase creates a new API key use case
+func NewAPIKeyUseCase(repo *repository.APIKeyRepository) *APIKeyUseCase {
+	return &APIKeyUseCase{repo: repo}
+}
+
+// CreateKey creates a key acting on behalf of a user, with some of the
+// permissions the user holds and the access scope of ctx, and returns it with
+// its secret
+func (u *APIKeyUseCase) CreateKey(ctx context.Context, creatorID uint, creatorPermissions []entity.Permission, req *entity.APIKeyRequest) (*entity.APIKeySecret, error) {
+	held := make(map[entity.Permission]bool, len(creatorPermissions))
+	for _, p := range creatorPermissions {
+		held[p] = true
+	}
+	seen := make(map[entity.Permission]bool, len(req.Permissions))
+	permissions := make(entity.GormPermissionSlice, 0, len(req.Permissions))
+	for _, p := range req.Permissions {
+		p = entity.Permission(strings.TrimSpace(string(p)))
+		if !held[p] || p == entity.SystemAPIKeyManage {
+			return nil, fmt.Errorf("%w: %q", ErrAPIKeyPermission, p)
+		}
+		if !seen[p] {
+			seen[p] = true
+			permissions = append(permissions, p)
+		}
+	}
+
+	scope := entity.AccessScopeFromContext(ctx)
+	key := &entity.APIKey{
+		Name:        strings.TrimSpace(req.Name),
+		Permissions: permissions,
+		StoreIDs:    pq.StringArray(scope.StoreIDs),
+		CreatedBy:   creatorID,
+	}
+	for _, id := range scope.DepartmentIDs {
+		key.DepartmentIDs = append(key.DepartmentIDs, int64(id))
+	}
+	if req.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		key.ExpiresAt = &expiresAt
+	}
+
+	secret, err := newAPIKeySecret(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := u.repo.Create(ctx, key); err != nil {
+		return nil, fmt.Errorf("error creating API key: %w", err)
+	}
+	return &entity.APIKeySecret{APIKey: *key, Key: secret}, nil
+}
+
+// ListKeys lists the API keys matching a filter
+func (u *APIKeyUseCase) ListKeys(ctx context.Context, filter *entity.APIKeyFilter) ([]entity.APIKey, error) {
+	return u.repo.List(ctx, filter)
+}
+
+// GetKey retrieves an API key
+func (u *APIKeyUseCase) GetKey(ctx context.Context, id uint) (*entity.APIKey, error) {
+	key, err := u.repo.GetByID(ctx, id)
+	if errors.Is(err, repository.ErrRecordNotFound) {
+		return nil, ErrAPIKeyNotFound
+	}
+	return key, err
+}
+
+// RotateKey replaces the secret of a key, which stops accepting the previous
+// one at once, and returns it with the new secret
+func (u *APIKeyUseCase) RotateKey(ctx context.Context, id uint) (*entity.APIKeySecret, error) {
+	key, err := u.GetKey(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if key.RevokedAt != nil {
+		return nil, ErrAPIKeyRevoked
+	}
+
+	secret, err := newAPIKeySecret(key)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	key.RotatedAt = &now
+	if err := u.repo.Update(ctx, key); err != nil {
+		return nil, fmt.Errorf("error rotating API key: %w", err)
+	}
+	return &entity.APIKeySecret{APIKey: *key, Key: secret}, nil
+}
+
+// RevokeKey stops a key from being accepted
+func (u *APIKeyUseCase) RevokeKey(ctx context.Context, id, revokedBy uint) (*entity.APIKey, error) {
+	key, err := u.GetKey(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if key.RevokedAt != nil {
+		return nil, ErrAPIKeyRevoked
+	}
+
+	now := time.Now()
+	key.RevokedAt = &now
+	key.RevokedBy = &revokedBy
+	if err := u.repo.Update(ctx, key); err != nil {
+		return nil, fmt.Errorf("error revoking API key: %w", err)
+	}
+	return key, nil
+}
+
+// Authenticate returns the active key with a secret and records its use
+func (u *APIKeyUseCase) Authenticate(ctx context.Context, secret, ip string) (*entity.APIKey, error) {
+	if !strings.HasPrefix(secret, apiKeyPrefix) {
+		return nil, ErrAPIKeyInvalid
+	}
+	key, err := u.repo.GetByHash(ctx, hashAPIKey(secret))
+	if errors.Is(err, repository.ErrRecordNotFound) {
+		return nil, ErrAPIKeyInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if !key.ActiveAt(now) {
+		return nil, ErrAPIKeyInvalid
+	}
+	if err := u.repo.TouchLastUsed(ctx, key.ID, now, ip, apiKeyTouchInterval); err != nil {
+		return nil, fmt.Errorf("error recording API key use: %w", err)
+	}
+	return key, nil
+}
+
+// newAPIKeySecret generates a secret for a key, setting its prefix and hash
+func newAPIKeySecret(key *entity.APIKey) (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("error generating API key: %w", err)
+	}
+	secret := apiKeyPrefix + hex.EncodeToString(b)
+	key.Prefix = secret[:apiKeyShownLength]
+	key.KeyHash = hashAPIKey(secret)
+	return secret, nil
+}
+
+func hashAPIKey(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
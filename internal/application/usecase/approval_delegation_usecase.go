@@ -0,0 +1,195 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
+)
+
+var (
+	ErrDelegationNotFound      = entity.NewError(entity.ErrCodeNotFound, "approval delegation not found")
+	ErrDelegationDenied        = entity.NewError(entity.ErrCodePermissionDenied, "only the delegator can manage their approval delegations")
+	ErrDelegationSelf          = entity.NewError(entity.ErrCodeInvalidArgument, "approvals cannot be delegated to the delegator")
+	ErrInvalidDelegationPeriod = entity.NewError(entity.ErrCodeInvalidArgument, "delegation must end after it starts, and in the future")
+	ErrDelegatorNotApprover    = entity.NewError(entity.ErrCodeFailedPrecondition, "the delegator holds no approval authority to delegate")
+	ErrDelegateInactive        = entity.NewError(entity.ErrCodeFailedPrecondition, "the delegate is not an active user")
+	ErrDelegationOverlap       = entity.NewError(entity.ErrCodeFailedPrecondition, "the delegator already delegates approvals during this period")
+	ErrDelegationEnded         = entity.NewError(entity.ErrCodeFailedPrecondition, "approval delegation is cancelled or over")
+	ErrNoApprovalAuthority     = entity.NewError(entity.ErrCodePermissionDenied, "you hold no approval authority for this document, directly or by delegation")
+)
+
+// ApprovalDelegationUseCase handles approvers handing their approval
+// authority to a delegate for a period, and the routing of approvals to
+// delegates while it lasts
+type ApprovalDelegationUseCase struct {
+	repo     *repository.ApprovalDelegationRepository
+	userRepo *repository.UserRepository
+}
+
+// NewApprovalDelegationUseCase creates a new approval delegation use case
+func NewApprovalDelegationUseCase(repo *repository.ApprovalDelegationRepository, userRepo *repository.UserRepository) *ApprovalDelegationUseCase {
+	return &ApprovalDelegationUseCase{
+		repo:     repo,
+		userRepo: userRepo,
+	}
+}
+
+// CreateDelegation delegates the approvals of a user, the caller unless they
+// may manage the delegations of all users
+func (u *ApprovalDelegationUseCase) CreateDelegation(ctx context.Context, callerID uint, canManage bool, req *entity.ApprovalDelegationRequest) (*entity.ApprovalDelegation, error) {
+	delegatorID := req.DelegatorID
+	if delegatorID == 0 {
+		delegatorID = callerID
+	}
+	if delegatorID != callerID && !canManage {
+		return nil, ErrDelegationDenied
+	}
+	if req.DelegateID == delegatorID {
+		return nil, ErrDelegationSelf
+	}
+	if !req.EndsAt.After(req.StartsAt) || !req.EndsAt.After(time.Now()) {
+		return nil, ErrInvalidDelegationPeriod
+	}
+
+	delegator, err := u.userRepo.FindByID(delegatorID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting delegator: %w", err)
+	}
+	approver := false
+	for _, p := range entity.DelegableApprovals {
+		if delegator.HasPermission(p) {
+			approver = true
+			break
+		}
+	}
+	if !approver {
+		return nil, ErrDelegatorNotApprover
+	}
+	delegate, err := u.userRepo.FindByID(req.DelegateID)
+	if err != nil || !delegate.IsActive() {
+		return nil, ErrDelegateInactive
+	}
+
+	overlap, err := u.repo.HasOverlap(ctx, delegatorID, req.StartsAt, req.EndsAt)
+	if err != nil {
+		return nil, err
+	}
+	if overlap {
+		return nil, ErrDelegationOverlap
+	}
+
+	delegation := &entity.ApprovalDelegation{
+		DelegatorID: delegatorID,
+		DelegateID:  req.DelegateID,
+		StartsAt:    req.StartsAt,
+		EndsAt:      req.EndsAt,
+		Reason:      strings.TrimSpace(req.Reason),
+		CreatedBy:   callerID,
+	}
+	if err := u.repo.Create(ctx, delegation); err != nil {
+		return nil, fmt.Errorf("error creating approval delegation: %w", err)
+	}
+	return u.GetDelegation(ctx, delegation.ID)
+}
+
+// ListDelegations lists the delegations matching a filter
+func (u *ApprovalDelegationUseCase) ListDelegations(ctx context.Context, filter *entity.ApprovalDelegationFilter) ([]entity.ApprovalDelegation, error) {
+	return u.repo.List(ctx, filter, time.Now())
+}
+
+// GetDelegation retrieves a delegation
+func (u *ApprovalDelegationUseCase) GetDelegation(ctx context.Context, id uint) (*entity.ApprovalDelegation, error) {
+	delegation, err := u.repo.GetByID(ctx, id)
+	if errors.Is(err, repository.ErrRecordNotFound) {
+		return nil, ErrDelegationNotFound
+	}
+	return delegation, err
+}
+
+// CancelDelegation ends a delegation now, or drops it before it starts. Only
+// its delegator can, unless the caller may manage all delegations.
+func (u *ApprovalDelegationUseCase) CancelDelegation(ctx context.Context, id, callerID uint, canManage bool) (*entity.ApprovalDelegation, error) {
+	delegation, err := u.GetDelegation(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if delegation.DelegatorID != callerID && !canManage {
+		return nil, ErrDelegationDenied
+	}
+	now := time.Now()
+	if delegation.CancelledAt != nil || !delegation.EndsAt.After(now) {
+		return nil, ErrDelegationEnded
+	}
+
+	delegation.CancelledAt = &now
+	delegation.CancelledBy = &callerID
+	if err := u.repo.Update(ctx, delegation); err != nil {
+		return nil, fmt.Errorf("error cancelling approval delegation: %w", err)
+	}
+	return delegation, nil
+}
+
+// ResolveApprover returns the user on whose behalf a user without an approval
+// permission approves, under a delegation active now from an approver holding
+// it, or ErrNoApprovalAuthority
+func (u *ApprovalDelegationUseCase) ResolveApprover(ctx context.Context, userID uint, permission entity.Permission) (*uint, error) {
+	if !entity.IsDelegableApproval(permission) {
+		return nil, ErrNoApprovalAuthority
+	}
+	delegations, err := u.repo.ListActiveFor(ctx, permission, userID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if len(delegations) == 0 {
+		return nil, ErrNoApprovalAuthority
+	}
+	return &delegations[0].DelegatorID, nil
+}
+
+// DelegatedApprovals returns the approval permissions a user holds now only
+// through delegations, with the approver each is used on behalf of
+func (u *ApprovalDelegationUseCase) DelegatedApprovals(ctx context.Context, userID uint) ([]entity.EffectivePermission, error) {
+	now := time.Now()
+	var delegated []entity.EffectivePermission
+	for _, permission := range entity.DelegableApprovals {
+		delegations, err := u.repo.ListActiveFor(ctx, permission, userID, now)
+		if err != nil {
+			return nil, err
+		}
+		if len(delegations) == 0 {
+			continue
+		}
+		// Approvals are made on behalf of the first delegator, as in ResolveApprover
+		d := delegations[0]
+		delegated = append(delegated, entity.EffectivePermission{
+			Permission:   permission,
+			Source:       entity.PermissionFromDelegation,
+			OnBehalfOfID: &d.DelegatorID,
+			ExpiresAt:    &d.EndsAt,
+		})
+	}
+	return delegated, nil
+}
+
+// RouteApproval returns the delegates a pending approval needing a permission
+// goes to, and the approvers away who delegated it. Approvals that cannot be
+// delegated are not routed.
+func (u *ApprovalDelegationUseCase) RouteApproval(ctx context.Context, permission entity.Permission) (delegates, away []uint, err error) {
+	if !entity.IsDelegableApproval(permission) {
+		return nil, nil, nil
+	}
+	delegations, err := u.repo.ListActiveFor(ctx, permission, 0, time.Now())
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, d := range delegations {
+		delegates = append(delegates, d.DelegateID)
+		away = append(away, d.DelegatorID)
+	}
+	return delegates, away, nil
+}
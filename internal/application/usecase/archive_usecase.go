@@ -0,0 +1,64 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
+)
+
+const (
+	defaultArchiveRetentionDays = 365
+	defaultArchiveBatchSize     = 1000
+)
+
+// ArchiveUseCase moves closed documents past the retention period out of the operational tables
+type ArchiveUseCase struct {
+	archiveRepo   *repository.ArchiveRepository
+	retentionDays int
+	batchSize     int
+}
+
+// NewArchiveUseCase creates a new archive use case
+func NewArchiveUseCase(archiveRepo *repository.ArchiveRepository, retentionDays, batchSize int) *ArchiveUseCase {
+	if retentionDays <= 0 {
+		retentionDays = defaultArchiveRetentionDays
+	}
+	if batchSize <= 0 {
+		batchSize = defaultArchiveBatchSize
+	}
+	return &ArchiveUseCase{
+		archiveRepo:   archiveRepo,
+		retentionDays: retentionDays,
+		batchSize:     batchSize,
+	}
+}
+
+// EnsureTables creates the archive tables so archived listings work before the first run
+func (u *ArchiveUseCase) EnsureTables(ctx context.Context) error {
+	if _, err := u.archiveRepo.EnsureTables(ctx); err != nil {
+		return fmt.Errorf("error creating archive tables: %w", err)
+	}
+	return nil
+}
+
+// Run archives closed/cancelled orders, paid invoices and stock entries last
+// changed before the retention cutoff
+func (u *ArchiveUseCase) Run(ctx context.Context) (*entity.ArchiveRunResult, error) {
+	startedAt := time.Now()
+	cutoff := truncateDay(startedAt).AddDate(0, 0, -u.retentionDays)
+
+	moved, err := u.archiveRepo.Archive(ctx, cutoff, u.batchSize)
+	if err != nil {
+		return nil, fmt.Errorf("error archiving documents: %w", err)
+	}
+
+	return &entity.ArchiveRunResult{
+		Cutoff:     cutoff,
+		MovedRows:  moved,
+		StartedAt:  startedAt,
+		FinishedAt: time.Now(),
+	}, nil
+}
@@ -0,0 +1,346 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
+)
+
+var (
+	ErrAssetNotFound                = entity.NewError(entity.ErrCodeNotFound, "fixed asset not found")
+	ErrAssetDisposed                = entity.NewError(entity.ErrCodeFailedPrecondition, "fixed asset is already disposed")
+	ErrInvalidDisposalDate          = entity.NewError(entity.ErrCodeInvalidArgument, "disposal date must be formatted as YYYY-MM-DD and fall after the acquisition and the last depreciated month")
+	ErrDepreciationPeriodInFuture   = entity.NewError(entity.ErrCodeFailedPrecondition, "cannot post depreciation for a future period")
+	ErrInvalidDepreciationMethod    = entity.NewError(entity.ErrCodeInvalidArgument, "depreciation method must be STRAIGHT_LINE or DECLINING_BALANCE")
+	ErrInvalidUsefulLife            = entity.NewError(entity.ErrCodeInvalidArgument, "asset useful life must be greater than zero")
+	ErrSalvageValueExceedsUnitPrice = entity.NewError(entity.ErrCodeInvalidArgument, "asset salvage value must be between zero and the unit price")
+)
+
+// AssetUseCase handles the fixed asset register: capitalizing purchases,
+// depreciation and disposals
+type AssetUseCase struct {
+	assetRepo *repository.AssetRepository
+}
+
+// NewAssetUseCase creates a new asset use case
+func NewAssetUseCase(assetRepo *repository.AssetRepository) *AssetUseCase {
+	return &AssetUseCase{
+		assetRepo: assetRepo,
+	}
+}
+
+// validateAssetTerms checks the asset terms of a purchase order line
+func validateAssetTerms(terms *entity.AssetTerms, unitPrice float64) error {
+	if terms.Method != entity.DepreciationStraightLine && terms.Method != entity.DepreciationDecliningBalance {
+		return ErrInvalidDepreciationMethod
+	}
+	if terms.UsefulLifeMonths <= 0 {
+		return ErrInvalidUsefulLife
+	}
+	if terms.SalvageValue < 0 || terms.SalvageValue > unitPrice {
+		return ErrSalvageValueExceedsUnitPrice
+	}
+	return nil
+}
+
+// RegisterReceipt creates a fixed asset for every received line of a purchase
+// order marked as a capital purchase. Cost and salvage value are converted to the
+// base currency at the order's exchange rate.
+func (u *AssetUseCase) RegisterReceipt(ctx context.Context, order *entity.PurchaseOrder, receipt *entity.PurchaseReceipt) ([]entity.FixedAsset, error) {
+	rate := order.ExchangeRate
+	if rate <= 0 {
+		rate = 1
+	}
+
+	var assets []entity.FixedAsset
+	for _, item := range receipt.Items {
+		line := capitalLine(order, item.SKUID)
+		if line == nil || item.ReceivedQuantity <= 0 {
+			continue
+		}
+
+		name := line.Description
+		if name == "" {
+			name = fmt.Sprintf("%s %s", order.OrderNumber, item.SKUID)
+		}
+		cost := roundAmount(item.ReceivedQuantity * item.UnitPrice * rate)
+		assets = append(assets, entity.FixedAsset{
+			Name:              name,
+			Category:          line.Asset.Category,
+			SKUID:             item.SKUID,
+			PurchaseOrderID:   order.ID,
+			PurchaseReceiptID: receipt.ID,
+			VendorID:          order.VendorID,
+			StoreID:           receipt.StoreID,
+			Quantity:          item.ReceivedQuantity,
+			AcquisitionDate:   receipt.ReceiptDate,
+			AcquisitionCost:   cost,
+			SalvageValue:      roundAmount(item.ReceivedQuantity * line.Asset.SalvageValue * rate),
+			Method:            line.Asset.Method,
+			UsefulLifeMonths:  line.Asset.UsefulLifeMonths,
+			BookValue:         cost,
+			Status:            entity.FixedAssetStatusActive,
+		})
+	}
+
+	if err := u.assetRepo.CreateAssets(ctx, assets); err != nil {
+		return nil, fmt.Errorf("error registering fixed assets: %w", err)
+	}
+	return assets, nil
+}
+
+// GetAsset retrieves a fixed asset
+func (u *AssetUseCase) GetAsset(ctx context.Context, id uint) (*entity.FixedAsset, error) {
+	asset, err := u.assetRepo.GetAsset(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, ErrAssetNotFound
+		}
+		return nil, fmt.Errorf("error getting fixed asset: %w", err)
+	}
+	return asset, nil
+}
+
+// ListAssets lists fixed assets
+func (u *AssetUseCase) ListAssets(ctx context.Context, filter *entity.FixedAssetFilter) ([]entity.FixedAsset, int64, error) {
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+	if filter.PageSize <= 0 {
+		filter.PageSize = 20
+	}
+
+	assets, total, err := u.assetRepo.ListAssets(ctx, filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error listing fixed assets: %w", err)
+	}
+	return assets, total, nil
+}
+
+// GetSchedule returns the monthly depreciation schedule of an asset over its
+// useful life, flagging the months already posted. A disposed asset's schedule
+// ends at its disposal.
+func (u *AssetUseCase) GetSchedule(ctx context.Context, id uint) ([]entity.DepreciationScheduleLine, error) {
+	asset, err := u.GetAsset(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	schedule := []entity.DepreciationScheduleLine{}
+	first := firstDepreciationPeriod(asset)
+	book := asset.AcquisitionCost
+	var accumulated float64
+	for month := 0; month < asset.UsefulLifeMonths; month++ {
+		start := first.AddDate(0, month, 0)
+		if asset.DisposalDate != nil && !start.Before(monthStart(*asset.DisposalDate)) {
+			break
+		}
+		charge := depreciationCharge(asset, month, book)
+		book = roundAmount(book - charge)
+		accumulated = roundAmount(accumulated + charge)
+
+		period := start.Format(periodLayout)
+		schedule = append(schedule, entity.DepreciationScheduleLine{
+			Period:                  period,
+			Depreciation:            charge,
+			AccumulatedDepreciation: accumulated,
+			BookValue:               book,
+			Posted:                  asset.DepreciatedThrough != "" && period <= asset.DepreciatedThrough,
+		})
+	}
+	return schedule, nil
+}
+
+// ListEntries lists the depreciation posted for an asset
+func (u *AssetUseCase) ListEntries(ctx context.Context, id uint) ([]entity.AssetDepreciationEntry, error) {
+	if _, err := u.GetAsset(ctx, id); err != nil {
+		return nil, err
+	}
+	entries, err := u.assetRepo.ListEntries(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("error listing depreciation entries: %w", err)
+	}
+	return entries, nil
+}
+
+// Post posts depreciation for every active asset through the given YYYY-MM period.
+// Assets start depreciating the month after acquisition; months an asset missed
+// are caught up, and months already posted are skipped, so posting is safe to repeat.
+func (u *AssetUseCase) Post(ctx context.Context, period string, userID *uint) (*entity.DepreciationRunResult, error) {
+	start, err := time.ParseInLocation(periodLayout, period, time.Local)
+	if err != nil {
+		return nil, ErrInvalidPeriod
+	}
+	now := time.Now()
+	if start.After(now) {
+		return nil, ErrDepreciationPeriodInFuture
+	}
+
+	assets, err := u.assetRepo.ListDepreciableAssets(ctx, start)
+	if err != nil {
+		return nil, fmt.Errorf("error listing depreciable assets: %w", err)
+	}
+
+	result := &entity.DepreciationRunResult{
+		Period:  period,
+		Entries: []entity.AssetDepreciationEntry{},
+	}
+	var changed []entity.FixedAsset
+	for i := range assets {
+		entries := depreciate(&assets[i], start, userID, now)
+		if len(entries) == 0 {
+			continue
+		}
+		changed = append(changed, assets[i])
+		result.Entries = append(result.Entries, entries...)
+		for _, entry := range entries {
+			result.Depreciation = roundAmount(result.Depreciation + entry.Amount)
+		}
+	}
+	result.Assets = len(changed)
+
+	if err := u.assetRepo.PostDepreciation(ctx, changed, result.Entries); err != nil {
+		return nil, fmt.Errorf("error posting depreciation: %w", err)
+	}
+	return result, nil
+}
+
+// PostPreviousMonth posts depreciation for the previous month unless it was already posted
+func (u *AssetUseCase) PostPreviousMonth(ctx context.Context) (*entity.DepreciationRunResult, error) {
+	now := time.Now()
+	period := now.AddDate(0, 0, -now.Day()).Format(periodLayout)
+
+	posted, err := u.assetRepo.HasEntries(ctx, period)
+	if err != nil {
+		return nil, fmt.Errorf("error checking depreciation period: %w", err)
+	}
+	if posted {
+		return nil, nil
+	}
+	return u.Post(ctx, period, nil)
+}
+
+// Dispose disposes of an asset. Depreciation is first caught up through the month
+// before the disposal; the gain or loss is the proceeds less the book value left.
+func (u *AssetUseCase) Dispose(ctx context.Context, id uint, req *entity.DisposeAssetRequest, userID *uint) (*entity.FixedAsset, error) {
+	asset, err := u.GetAsset(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if asset.Status == entity.FixedAssetStatusDisposed {
+		return nil, ErrAssetDisposed
+	}
+
+	disposalDate, err := time.ParseInLocation("2006-01-02", req.DisposalDate, time.Local)
+	if err != nil || disposalDate.Before(truncateDay(asset.AcquisitionDate)) ||
+		(asset.DepreciatedThrough != "" && disposalDate.Format(periodLayout) <= asset.DepreciatedThrough) {
+		return nil, ErrInvalidDisposalDate
+	}
+
+	entries := depreciate(asset, monthStart(disposalDate).AddDate(0, -1, 0), userID, time.Now())
+
+	asset.Status = entity.FixedAssetStatusDisposed
+	asset.DisposalDate = &disposalDate
+	asset.DisposalProceeds = roundAmount(req.Proceeds)
+	asset.DisposalGainLoss = roundAmount(req.Proceeds - asset.BookValue)
+	asset.DisposalNotes = req.Notes
+
+	if err := u.assetRepo.PostDepreciation(ctx, []entity.FixedAsset{*asset}, entries); err != nil {
+		return nil, fmt.Errorf("error disposing of fixed asset: %w", err)
+	}
+	return asset, nil
+}
+
+// capitalLine returns the purchase order line of a SKU marked as a capital purchase
+func capitalLine(order *entity.PurchaseOrder, skuID string) *entity.PurchaseOrderItem {
+	for i := range order.Items {
+		if order.Items[i].SKUID == skuID && order.Items[i].Asset != nil {
+			return &order.Items[i]
+		}
+	}
+	return nil
+}
+
+// depreciate charges an asset's depreciation for every unposted month up to and
+// including the month starting at through, and returns the entries posted
+func depreciate(asset *entity.FixedAsset, through time.Time, postedBy *uint, now time.Time) []entity.AssetDepreciationEntry {
+	first := firstDepreciationPeriod(asset)
+	next := first
+	if asset.DepreciatedThrough != "" {
+		last, err := time.ParseInLocation(periodLayout, asset.DepreciatedThrough, time.Local)
+		if err == nil {
+			next = last.AddDate(0, 1, 0)
+		}
+	}
+
+	var entries []entity.AssetDepreciationEntry
+	for ; !next.After(through) && asset.Status == entity.FixedAssetStatusActive; next = next.AddDate(0, 1, 0) {
+		charge := depreciationCharge(asset, monthsBetween(first, next), asset.BookValue)
+		asset.AccumulatedDepreciation = roundAmount(asset.AccumulatedDepreciation + charge)
+		asset.BookValue = roundAmount(asset.AcquisitionCost - asset.AccumulatedDepreciation)
+		asset.DepreciatedThrough = next.Format(periodLayout)
+		if asset.BookValue <= asset.SalvageValue {
+			asset.Status = entity.FixedAssetStatusFullyDepreciated
+		}
+		if charge == 0 {
+			continue
+		}
+
+		entries = append(entries, entity.AssetDepreciationEntry{
+			AssetID:                 asset.ID,
+			Period:                  asset.DepreciatedThrough,
+			Amount:                  charge,
+			AccumulatedDepreciation: asset.AccumulatedDepreciation,
+			BookValue:               asset.BookValue,
+			PostedBy:                postedBy,
+			PostedAt:                now,
+		})
+	}
+	return entries
+}
+
+// depreciationCharge returns the depreciation of an asset for the given month of
+// its useful life (0 is the first), given its book value at the start of the month.
+// The last month of the useful life charges whatever is left above salvage value.
+func depreciationCharge(asset *entity.FixedAsset, month int, bookValue float64) float64 {
+	depreciable := bookValue - asset.SalvageValue
+	remaining := asset.UsefulLifeMonths - month
+	if depreciable <= 0 || remaining <= 0 {
+		return 0
+	}
+	if remaining == 1 {
+		return roundAmount(depreciable)
+	}
+
+	var charge float64
+	switch asset.Method {
+	case entity.DepreciationDecliningBalance:
+		declining := bookValue * 2 / float64(asset.UsefulLifeMonths)
+		straight := depreciable / float64(remaining)
+		charge = math.Max(declining, straight)
+	default:
+		charge = (asset.AcquisitionCost - asset.SalvageValue) / float64(asset.UsefulLifeMonths)
+	}
+	return roundAmount(math.Min(charge, depreciable))
+}
+
+// firstDepreciationPeriod returns the start of the month after the asset was acquired
+func firstDepreciationPeriod(asset *entity.FixedAsset) time.Time {
+	return monthStart(asset.AcquisitionDate).AddDate(0, 1, 0)
+}
+
+// monthStart returns midnight of the first day of t's month, in local time
+func monthStart(t time.Time) time.Time {
+	t = t.In(time.Local)
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.Local)
+}
+
+// monthsBetween returns the number of calendar months from one month to another
+func monthsBetween(from, to time.Time) int {
+	return (to.Year()-from.Year())*12 + int(to.Month()-from.Month())
+}
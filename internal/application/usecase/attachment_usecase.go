@@ -0,0 +1,198 @@
+package usecase
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/filestore"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/logging"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
+)
+
+var (
+	ErrAttachmentNotFound      = entity.NewError(entity.ErrCodeNotFound, "attachment not found")
+	ErrAttachmentOwnerNotFound = entity.NewError(entity.ErrCodeNotFound, "document to attach the file to not found")
+	ErrAttachmentOwnerType     = entity.NewError(entity.ErrCodeInvalidArgument, "files cannot be attached to this kind of document")
+)
+
+// AttachmentUseCase keeps the files uploaded to documents in the file store
+type AttachmentUseCase struct {
+	attachmentRepo *repository.AttachmentRepository
+	purchaseRepo   *repository.PurchaseRepository
+	expenseRepo    *repository.ExpenseRepository
+	files          filestore.Store
+	limits         filestore.Limits
+	fileURLTTL     time.Duration
+}
+
+// NewAttachmentUseCase creates a new attachment use case. Uploads are held to
+// the limits; download URLs stay valid for fileURLTTL.
+func NewAttachmentUseCase(attachmentRepo *repository.AttachmentRepository, purchaseRepo *repository.PurchaseRepository, expenseRepo *repository.ExpenseRepository, files filestore.Store, limits filestore.Limits, fileURLTTL time.Duration) *AttachmentUseCase {
+	return &AttachmentUseCase{
+		attachmentRepo: attachmentRepo,
+		purchaseRepo:   purchaseRepo,
+		expenseRepo:    expenseRepo,
+		files:          files,
+		limits:         limits,
+		fileURLTTL:     fileURLTTL,
+	}
+}
+
+// Limits returns the limits uploads are held to
+func (u *AttachmentUseCase) Limits() filestore.Limits {
+	return u.limits
+}
+
+// Upload stores a file read from r and attaches it to a document. A missing
+// or generic content type is sniffed from the file itself.
+func (u *AttachmentUseCase) Upload(ctx context.Context, ownerType entity.AttachmentOwnerType, ownerID, fileName, contentType string, size int64, r io.Reader, userID *uint) (*entity.Attachment, error) {
+	if err := u.checkOwner(ctx, ownerType, ownerID); err != nil {
+		return nil, err
+	}
+
+	body := bufio.NewReader(u.limits.Reader(r))
+	head, err := body.Peek(512)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, err
+	}
+	contentType, err = u.limits.Check(filestore.SniffContentType(contentType, head), size)
+	if err != nil {
+		return nil, err
+	}
+
+	token := make([]byte, 8)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+	fileName = path.Base(strings.ReplaceAll(fileName, "\\", "/"))
+	ext := strings.ToLower(path.Ext(fileName))
+	if len(ext) > 10 || strings.Trim(ext, ".abcdefghijklmnopqrstuvwxyz0123456789") != "" {
+		ext = ""
+	}
+	key := fmt.Sprintf("attachments/%s/%s/%s/%s%s",
+		ownerType, fileSlug(ownerID), hex.EncodeToString(token), fileSlug(strings.TrimSuffix(fileName, path.Ext(fileName))), ext)
+
+	if err := u.files.Put(ctx, key, contentType, body); err != nil {
+		if errors.Is(err, filestore.ErrFileTooLarge) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("error storing attachment: %w", err)
+	}
+
+	attachment := &entity.Attachment{
+		OwnerType:   ownerType,
+		OwnerID:     ownerID,
+		FileName:    fileName,
+		ContentType: contentType,
+		Size:        size,
+		FileKey:     key,
+		UploadedBy:  userID,
+	}
+	if err := u.attachmentRepo.Create(ctx, attachment); err != nil {
+		if err := u.files.Delete(ctx, key); err != nil {
+			logging.FromContext(ctx).Error("error deleting attachment file", "file_key", key, "error", err)
+		}
+		return nil, fmt.Errorf("error recording attachment: %w", err)
+	}
+	u.signFileURL(ctx, attachment)
+	return attachment, nil
+}
+
+// GetAttachment retrieves an attachment with a URL to download it from
+func (u *AttachmentUseCase) GetAttachment(ctx context.Context, id uint64) (*entity.Attachment, error) {
+	attachment, err := u.attachmentRepo.Get(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, ErrAttachmentNotFound
+		}
+		return nil, fmt.Errorf("error getting attachment: %w", err)
+	}
+	u.signFileURL(ctx, attachment)
+	return attachment, nil
+}
+
+// ListAttachments retrieves the attachments of a document
+func (u *AttachmentUseCase) ListAttachments(ctx context.Context, ownerType entity.AttachmentOwnerType, ownerID string) ([]entity.Attachment, error) {
+	if err := u.checkOwner(ctx, ownerType, ownerID); err != nil {
+		return nil, err
+	}
+	attachments, err := u.attachmentRepo.ListByOwner(ctx, ownerType, ownerID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing attachments: %w", err)
+	}
+	for i := range attachments {
+		u.signFileURL(ctx, &attachments[i])
+	}
+	return attachments, nil
+}
+
+// DeleteAttachment deletes an attachment and its file
+func (u *AttachmentUseCase) DeleteAttachment(ctx context.Context, id uint64) error {
+	attachment, err := u.attachmentRepo.Get(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return ErrAttachmentNotFound
+		}
+		return fmt.Errorf("error getting attachment: %w", err)
+	}
+	if err := u.attachmentRepo.Delete(ctx, id); err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return ErrAttachmentNotFound
+		}
+		return fmt.Errorf("error deleting attachment: %w", err)
+	}
+	if err := u.files.Delete(ctx, attachment.FileKey); err != nil {
+		logging.FromContext(ctx).Error("error deleting attachment file", "attachment_id", attachment.ID, "file_key", attachment.FileKey, "error", err)
+	}
+	return nil
+}
+
+// checkOwner makes sure the document a file is attached to exists
+func (u *AttachmentUseCase) checkOwner(ctx context.Context, ownerType entity.AttachmentOwnerType, ownerID string) error {
+	var err error
+	switch ownerType {
+	case entity.AttachmentPurchaseRequest:
+		_, err = u.purchaseRepo.GetPurchaseRequestByID(ctx, ownerID)
+	case entity.AttachmentPurchaseOrder:
+		_, err = u.purchaseRepo.GetPurchaseOrderByID(ctx, ownerID)
+	case entity.AttachmentPurchaseReceipt:
+		_, err = u.purchaseRepo.GetPurchaseReceiptByID(ctx, ownerID)
+	case entity.AttachmentExpenseClaim:
+		id, parseErr := strconv.ParseUint(ownerID, 10, 32)
+		if parseErr != nil {
+			return ErrAttachmentOwnerNotFound
+		}
+		_, err = u.expenseRepo.GetClaim(ctx, uint(id))
+	default:
+		return ErrAttachmentOwnerType
+	}
+	if errors.Is(err, repository.ErrRecordNotFound) {
+		return ErrAttachmentOwnerNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("error getting document: %w", err)
+	}
+	return nil
+}
+
+// signFileURL sets the URL the attachment can be downloaded from
+func (u *AttachmentUseCase) signFileURL(ctx context.Context, attachment *entity.Attachment) {
+	expires := time.Now().Add(u.fileURLTTL)
+	fileURL, err := u.files.URL(ctx, attachment.FileKey, u.fileURLTTL)
+	if err != nil {
+		logging.FromContext(ctx).Error("error signing attachment URL", "attachment_id", attachment.ID, "error", err)
+		return
+	}
+	attachment.FileURL = fileURL
+	attachment.FileExpires = &expires
+}
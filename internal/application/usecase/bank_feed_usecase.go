@@ -0,0 +1,440 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/bankfeed"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/logging"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
+)
+
+var (
+	ErrBankAccountNotFound       = entity.NewError(entity.ErrCodeNotFound, "bank account not found")
+	ErrBankAccountInactive       = entity.NewError(entity.ErrCodeFailedPrecondition, "bank account is inactive")
+	ErrBankAccountDuplicate      = entity.NewError(entity.ErrCodeConflict, "the provider account is already set up")
+	ErrBankTransactionNotFound   = entity.NewError(entity.ErrCodeNotFound, "bank transaction not found")
+	ErrBankTransactionReconciled = entity.NewError(entity.ErrCodeFailedPrecondition, "bank transaction is already matched or ignored")
+	ErrBankMatchInvoiceNotFound  = entity.NewError(entity.ErrCodeNotFound, "invoice not found")
+	ErrBankMatchDirection        = entity.NewError(entity.ErrCodeFailedPrecondition, "money received settles sales invoices and money paid out settles purchase invoices")
+	ErrBankMatchCurrency         = entity.NewError(entity.ErrCodeFailedPrecondition, "invoice is in another currency than the transaction")
+	ErrBankMatchAmount           = entity.NewError(entity.ErrCodeFailedPrecondition, "amount exceeds the transaction or the amount due of the invoice")
+)
+
+const (
+	// importRunHistory is the number of import runs listed for a bank account
+	importRunHistory = 50
+	// matchCandidates bounds the open invoices scored for a bank transaction
+	matchCandidates = 50
+	// matchSuggestions is the number of best scored invoices suggested
+	matchSuggestions = 5
+)
+
+// BankFeedUseCase imports the booked transactions of bank accounts from bank
+// feed providers and reconciles them with open invoices: it suggests the
+// invoices a transaction may settle, and confirming a match records the
+// transaction as a completed payment of the invoice. The providers are reached
+// through the adapters of the bankfeed package.
+type BankFeedUseCase struct {
+	repo         *repository.BankFeedRepository
+	financeUC    *FinanceUseCase
+	lookbackDays int
+}
+
+// NewBankFeedUseCase creates a new bank feed use case. Imports fetch the
+// transactions booked from lookbackDays before the last import, to pick up
+// transactions the bank books late.
+func NewBankFeedUseCase(repo *repository.BankFeedRepository, financeUC *FinanceUseCase, lookbackDays int) *BankFeedUseCase {
+	if lookbackDays < 0 {
+		lookbackDays = 0
+	}
+	return &BankFeedUseCase{
+		repo:         repo,
+		financeUC:    financeUC,
+		lookbackDays: lookbackDays,
+	}
+}
+
+// CreateAccount creates a bank account, checking that its provider has an
+// adapter and that the credentials it needs are given
+func (u *BankFeedUseCase) CreateAccount(ctx context.Context, req *entity.BankAccountRequest) (*entity.BankAccount, error) {
+	account := &entity.BankAccount{Active: true}
+	if err := applyBankAccount(account, req); err != nil {
+		return nil, err
+	}
+
+	if err := u.repo.CreateAccount(ctx, account); err != nil {
+		if errors.Is(err, repository.ErrDuplicateEntry) {
+			return nil, ErrBankAccountDuplicate
+		}
+		return nil, fmt.Errorf("error creating bank account: %w", err)
+	}
+	return account, nil
+}
+
+// UpdateAccount updates a bank account. An access token left empty is kept.
+func (u *BankFeedUseCase) UpdateAccount(ctx context.Context, id uint, req *entity.BankAccountRequest) (*entity.BankAccount, error) {
+	account, err := u.GetAccount(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyBankAccount(account, req); err != nil {
+		return nil, err
+	}
+
+	if err := u.repo.UpdateAccount(ctx, account); err != nil {
+		if errors.Is(err, repository.ErrDuplicateEntry) {
+			return nil, ErrBankAccountDuplicate
+		}
+		return nil, fmt.Errorf("error updating bank account: %w", err)
+	}
+	return account, nil
+}
+
+// applyBankAccount validates a bank account request and copies it onto an
+// account
+func applyBankAccount(account *entity.BankAccount, req *entity.BankAccountRequest) error {
+	account.Name = req.Name
+	account.Provider = strings.ToUpper(req.Provider)
+	account.BaseURL = req.BaseURL
+	account.ExternalID = req.ExternalID
+	account.CurrencyCode = strings.ToUpper(req.CurrencyCode)
+	if req.AccessToken != "" {
+		account.AccessToken = req.AccessToken
+	}
+	if req.Active != nil {
+		account.Active = *req.Active
+	}
+
+	if _, err := bankfeed.NewProvider(account); err != nil {
+		return entity.WrapError(entity.ErrCodeInvalidArgument, err)
+	}
+	return nil
+}
+
+// GetAccount retrieves a bank account
+func (u *BankFeedUseCase) GetAccount(ctx context.Context, id uint) (*entity.BankAccount, error) {
+	account, err := u.repo.GetAccount(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, ErrBankAccountNotFound
+		}
+		return nil, fmt.Errorf("error getting bank account: %w", err)
+	}
+	return account, nil
+}
+
+// ListAccounts lists the bank accounts
+func (u *BankFeedUseCase) ListAccounts(ctx context.Context) ([]entity.BankAccount, error) {
+	accounts, err := u.repo.ListAccounts(ctx, false)
+	if err != nil {
+		return nil, fmt.Errorf("error listing bank accounts: %w", err)
+	}
+	return accounts, nil
+}
+
+// ListImportRuns lists the latest import runs of a bank account
+func (u *BankFeedUseCase) ListImportRuns(ctx context.Context, accountID uint) ([]entity.BankImportRun, error) {
+	if _, err := u.GetAccount(ctx, accountID); err != nil {
+		return nil, err
+	}
+	runs, err := u.repo.ListImportRuns(ctx, accountID, importRunHistory)
+	if err != nil {
+		return nil, fmt.Errorf("error listing bank import runs: %w", err)
+	}
+	return runs, nil
+}
+
+// ImportTransactions imports the transactions of a bank account booked since
+// its last import, less the lookback. Transactions imported before are left
+// as they are. A provider that cannot be read fails the run, which is
+// recorded and returned.
+func (u *BankFeedUseCase) ImportTransactions(ctx context.Context, id uint, triggeredBy string) (*entity.BankImportRun, error) {
+	account, err := u.GetAccount(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !account.Active {
+		return nil, ErrBankAccountInactive
+	}
+	provider, err := bankfeed.NewProvider(account)
+	if err != nil {
+		return nil, entity.WrapError(entity.ErrCodeFailedPrecondition, err)
+	}
+
+	run := &entity.BankImportRun{
+		AccountID:   account.ID,
+		TriggeredBy: triggeredBy,
+		StartedAt:   time.Now(),
+	}
+	since := run.StartedAt
+	if account.SyncedAt != nil {
+		since = *account.SyncedAt
+	}
+	since = since.AddDate(0, 0, -u.lookbackDays)
+
+	fetched, err := provider.FetchTransactions(ctx, since)
+	if err != nil {
+		run.Status = entity.BankImportFailed
+		run.Error = err.Error()
+		return u.finishImport(ctx, run)
+	}
+
+	transactions := make([]entity.BankTransaction, 0, len(fetched))
+	for _, t := range fetched {
+		currency := strings.ToUpper(t.CurrencyCode)
+		if currency == "" {
+			currency = account.CurrencyCode
+		}
+		transactions = append(transactions, entity.BankTransaction{
+			AccountID:    account.ID,
+			ExternalID:   t.ExternalID,
+			BookedAt:     t.BookedAt,
+			Amount:       roundAmount(t.Amount),
+			CurrencyCode: currency,
+			Description:  t.Description,
+			Counterparty: t.Counterparty,
+			Reference:    t.Reference,
+			Status:       entity.BankTransactionUnmatched,
+		})
+	}
+	imported, err := u.repo.CreateTransactions(ctx, transactions)
+	if err != nil {
+		return nil, fmt.Errorf("error storing bank transactions: %w", err)
+	}
+	if err := u.repo.SetSyncedAt(ctx, account.ID, run.StartedAt); err != nil {
+		return nil, fmt.Errorf("error recording bank import: %w", err)
+	}
+
+	run.Status = entity.BankImportSucceeded
+	run.Fetched = len(fetched)
+	run.Imported = imported
+	return u.finishImport(ctx, run)
+}
+
+// finishImport records the outcome of an import run
+func (u *BankFeedUseCase) finishImport(ctx context.Context, run *entity.BankImportRun) (*entity.BankImportRun, error) {
+	run.FinishedAt = time.Now()
+	if err := u.repo.CreateImportRun(ctx, run); err != nil {
+		return nil, fmt.Errorf("error recording bank import: %w", err)
+	}
+	return run, nil
+}
+
+// ImportAll imports the transactions of every active bank account, for the
+// scheduled import. An account that cannot be imported is logged and skipped.
+// The runs recorded are returned.
+func (u *BankFeedUseCase) ImportAll(ctx context.Context) ([]entity.BankImportRun, error) {
+	accounts, err := u.repo.ListAccounts(ctx, true)
+	if err != nil {
+		return nil, fmt.Errorf("error listing bank accounts: %w", err)
+	}
+
+	var runs []entity.BankImportRun
+	for _, account := range accounts {
+		run, err := u.ImportTransactions(ctx, account.ID, "scheduler")
+		if err != nil {
+			logging.FromContext(ctx).Error("bank feed import failed", "account", account.Name, "error", err)
+			continue
+		}
+		runs = append(runs, *run)
+	}
+	return runs, nil
+}
+
+// ListTransactions lists imported bank transactions
+func (u *BankFeedUseCase) ListTransactions(ctx context.Context, filter *entity.BankTransactionFilter) ([]entity.BankTransaction, int64, error) {
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+	if filter.PageSize <= 0 {
+		filter.PageSize = 20
+	}
+
+	transactions, total, err := u.repo.ListTransactions(ctx, filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error listing bank transactions: %w", err)
+	}
+	return transactions, total, nil
+}
+
+// GetTransaction retrieves a bank transaction
+func (u *BankFeedUseCase) GetTransaction(ctx context.Context, id uint) (*entity.BankTransaction, error) {
+	transaction, err := u.repo.GetTransaction(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, ErrBankTransactionNotFound
+		}
+		return nil, fmt.Errorf("error getting bank transaction: %w", err)
+	}
+	return transaction, nil
+}
+
+// SuggestMatches suggests the open invoices an unmatched bank transaction may
+// settle, best first: money received is matched against sales invoices
+// awaiting receipts, and money paid out against purchase invoices awaiting
+// payment, in the transaction's currency. An invoice scores for an amount due
+// equal to the transaction, for its number quoted in the transaction, and
+// for its customer or supplier named as the counterparty.
+func (u *BankFeedUseCase) SuggestMatches(ctx context.Context, id uint) ([]entity.BankMatchSuggestion, error) {
+	transaction, err := u.GetTransaction(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if transaction.Status != entity.BankTransactionUnmatched {
+		return nil, ErrBankTransactionReconciled
+	}
+
+	amount := math.Abs(transaction.Amount)
+	text := strings.ToLower(strings.Join([]string{transaction.Counterparty, transaction.Description, transaction.Reference}, " "))
+	invoices, err := u.repo.MatchCandidates(ctx, bankInvoiceType(transaction), transaction.CurrencyCode, amount, text, matchCandidates)
+	if err != nil {
+		return nil, fmt.Errorf("error finding invoices to match: %w", err)
+	}
+
+	suggestions := make([]entity.BankMatchSuggestion, 0, len(invoices))
+	for _, invoice := range invoices {
+		suggestion := entity.BankMatchSuggestion{
+			InvoiceID:     invoice.ID,
+			InvoiceNumber: invoice.InvoiceNumber,
+			InvoiceType:   invoice.Type,
+			EntityName:    invoice.EntityName,
+			DueDate:       invoice.DueDate,
+			AmountDue:     invoice.AmountDue,
+			CurrencyCode:  invoice.CurrencyCode,
+			Reasons:       []string{},
+		}
+		switch {
+		case math.Abs(invoice.AmountDue-amount) < 0.005:
+			suggestion.Score += 50
+			suggestion.Reasons = append(suggestion.Reasons, "amount equals the amount due")
+		case amount < invoice.AmountDue:
+			suggestion.Score += 10
+			suggestion.Reasons = append(suggestion.Reasons, "amount pays part of the amount due")
+		}
+		if invoice.InvoiceNumber != "" && strings.Contains(text, strings.ToLower(invoice.InvoiceNumber)) {
+			suggestion.Score += 40
+			suggestion.Reasons = append(suggestion.Reasons, "invoice number is quoted")
+		}
+		if name := strings.ToLower(strings.TrimSpace(invoice.EntityName)); name != "" && strings.Contains(text, name) {
+			suggestion.Score += 20
+			suggestion.Reasons = append(suggestion.Reasons, "counterparty is the invoice's "+strings.ToLower(invoice.EntityType))
+		}
+		if suggestion.Score > 0 {
+			suggestions = append(suggestions, suggestion)
+		}
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return suggestions[i].Score > suggestions[j].Score
+	})
+	if len(suggestions) > matchSuggestions {
+		suggestions = suggestions[:matchSuggestions]
+	}
+	return suggestions, nil
+}
+
+// MatchTransaction confirms that an unmatched bank transaction settles an
+// invoice: a completed bank transfer payment of the invoice is created for
+// the transaction, or the part of it given, and the transaction is matched to
+// it. The amount may not exceed the invoice's amount due.
+func (u *BankFeedUseCase) MatchTransaction(ctx context.Context, id uint, req *entity.BankMatchRequest, userID uint) (*entity.BankTransaction, error) {
+	transaction, err := u.GetTransaction(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if transaction.Status != entity.BankTransactionUnmatched {
+		return nil, ErrBankTransactionReconciled
+	}
+
+	invoice, err := u.financeUC.GetInvoiceByID(ctx, req.InvoiceID)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, ErrBankMatchInvoiceNotFound
+		}
+		return nil, err
+	}
+	if invoice.Type != bankInvoiceType(transaction) {
+		return nil, ErrBankMatchDirection
+	}
+	if !strings.EqualFold(invoice.CurrencyCode, transaction.CurrencyCode) {
+		return nil, ErrBankMatchCurrency
+	}
+	amount := math.Abs(transaction.Amount)
+	if req.Amount > 0 {
+		if req.Amount > amount+0.005 {
+			return nil, ErrBankMatchAmount
+		}
+		amount = roundAmount(req.Amount)
+	}
+	if amount > invoice.AmountDue+0.005 {
+		return nil, ErrBankMatchAmount
+	}
+
+	// Claiming the transaction first keeps two users from matching it at once
+	if err := u.repo.SetTransactionStatus(ctx, transaction.ID, entity.BankTransactionMatched, userID); err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, ErrBankTransactionReconciled
+		}
+		return nil, fmt.Errorf("error matching bank transaction: %w", err)
+	}
+
+	reference := transaction.Reference
+	if reference == "" {
+		reference = transaction.ExternalID
+	}
+	payment, err := u.financeUC.CreatePayment(ctx, &entity.CreateFinancePaymentRequest{
+		InvoiceID:       invoice.ID,
+		PaymentDate:     transaction.BookedAt,
+		PaymentMethod:   entity.FinancePaymentMethodBankTransfer,
+		Amount:          amount,
+		ReferenceNumber: reference,
+		Notes:           fmt.Sprintf("Matched to bank transaction %s", transaction.ExternalID),
+	}, int64(userID))
+	if err == nil {
+		if err = u.financeUC.ConfirmPayment(ctx, payment.ID); err != nil {
+			_ = u.financeUC.CancelPayment(ctx, payment.ID)
+		}
+	}
+	if err != nil {
+		if releaseErr := u.repo.ReleaseTransaction(ctx, transaction.ID); releaseErr != nil {
+			logging.FromContext(ctx).Error("bank transaction could not be released", "transaction_id", transaction.ID, "error", releaseErr)
+		}
+		return nil, err
+	}
+
+	if err := u.repo.SetTransactionPayment(ctx, transaction.ID, payment.ID); err != nil {
+		return nil, fmt.Errorf("error matching bank transaction: %w", err)
+	}
+	return u.GetTransaction(ctx, transaction.ID)
+}
+
+// IgnoreTransaction sets aside an unmatched bank transaction that settles no
+// invoice, such as bank charges
+func (u *BankFeedUseCase) IgnoreTransaction(ctx context.Context, id uint, userID uint) (*entity.BankTransaction, error) {
+	if _, err := u.GetTransaction(ctx, id); err != nil {
+		return nil, err
+	}
+	if err := u.repo.SetTransactionStatus(ctx, id, entity.BankTransactionIgnored, userID); err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, ErrBankTransactionReconciled
+		}
+		return nil, fmt.Errorf("error ignoring bank transaction: %w", err)
+	}
+	return u.GetTransaction(ctx, id)
+}
+
+// bankInvoiceType returns the type of invoices a bank transaction may settle:
+// sales invoices for money received, purchase invoices for money paid out
+func bankInvoiceType(transaction *entity.BankTransaction) entity.FinanceInvoiceType {
+	if transaction.Amount < 0 {
+		return entity.FinancePurchaseInvoice
+	}
+	return entity.FinanceSalesInvoice
+}
@@ -0,0 +1,213 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
+)
+
+// MaxLogoBytes caps the size of an uploaded logo
+const MaxLogoBytes = 1 << 20
+
+var (
+	ErrLogoNotFound    = entity.NewError(entity.ErrCodeNotFound, "no logo has been uploaded")
+	ErrLogoTooLarge    = entity.NewError(entity.ErrCodeInvalidArgument, "logo must not be larger than 1MB")
+	ErrLogoUnsupported = entity.NewError(entity.ErrCodeInvalidArgument, "logo must be a PNG, JPEG, GIF, WebP or SVG image")
+)
+
+// logoContentTypes are the sniffed content types accepted for logos
+var logoContentTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// BrandingUseCase manages the company branding printed on generated documents
+// and emails
+type BrandingUseCase struct {
+	brandingRepo *repository.BrandingRepository
+}
+
+// NewBrandingUseCase creates a new branding use case
+func NewBrandingUseCase(brandingRepo *repository.BrandingRepository) *BrandingUseCase {
+	return &BrandingUseCase{
+		brandingRepo: brandingRepo,
+	}
+}
+
+// Get returns the branding, which is empty until it is first saved
+func (u *BrandingUseCase) Get(ctx context.Context) (*entity.Branding, error) {
+	branding, err := u.brandingRepo.Get(ctx, false)
+	if errors.Is(err, repository.ErrRecordNotFound) {
+		return &entity.Branding{ID: entity.BrandingID, VATNumbers: entity.VATNumbers{}}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting branding: %w", err)
+	}
+	if branding.VATNumbers == nil {
+		branding.VATNumbers = entity.VATNumbers{}
+	}
+	return branding, nil
+}
+
+// Update replaces the branding details. The logo is kept.
+func (u *BrandingUseCase) Update(ctx context.Context, req *entity.BrandingRequest, userID *uint) (*entity.Branding, error) {
+	branding, err := u.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	branding.CompanyName = strings.TrimSpace(req.CompanyName)
+	branding.AddressLine1 = req.AddressLine1
+	branding.AddressLine2 = req.AddressLine2
+	branding.City = req.City
+	branding.PostalCode = req.PostalCode
+	branding.Country = req.Country
+	branding.Email = req.Email
+	branding.Phone = req.Phone
+	branding.Website = req.Website
+	branding.BankName = req.BankName
+	branding.BankAccountName = req.BankAccountName
+	branding.BankAccountNo = req.BankAccountNo
+	branding.IBAN = strings.ToUpper(strings.ReplaceAll(req.IBAN, " ", ""))
+	branding.BIC = strings.ToUpper(strings.TrimSpace(req.BIC))
+	branding.VATNumbers = make(entity.VATNumbers, len(req.VATNumbers))
+	for i, vat := range req.VATNumbers {
+		branding.VATNumbers[i] = entity.VATNumber{
+			Country: strings.ToUpper(vat.Country),
+			Number:  strings.TrimSpace(vat.Number),
+		}
+	}
+	branding.InvoiceFooter = strings.TrimSpace(req.InvoiceFooter)
+	branding.UpdatedBy = userID
+
+	if err := u.brandingRepo.Save(ctx, branding); err != nil {
+		return nil, fmt.Errorf("error saving branding: %w", err)
+	}
+	return branding, nil
+}
+
+// SetLogo replaces the logo with the given image. The content type is sniffed
+// from the image rather than trusted from the upload.
+func (u *BrandingUseCase) SetLogo(ctx context.Context, data []byte, userID *uint) (*entity.Branding, error) {
+	if len(data) > MaxLogoBytes {
+		return nil, ErrLogoTooLarge
+	}
+	contentType := logoContentType(data)
+	if contentType == "" {
+		return nil, ErrLogoUnsupported
+	}
+
+	branding, err := u.brandingRepo.Get(ctx, false)
+	if errors.Is(err, repository.ErrRecordNotFound) {
+		branding = &entity.Branding{VATNumbers: entity.VATNumbers{}}
+	} else if err != nil {
+		return nil, fmt.Errorf("error getting branding: %w", err)
+	}
+
+	now := time.Now()
+	branding.LogoData = data
+	branding.LogoContentType = contentType
+	branding.LogoUpdatedAt = &now
+	branding.UpdatedBy = userID
+	if err := u.brandingRepo.SaveLogo(ctx, branding); err != nil {
+		return nil, fmt.Errorf("error saving logo: %w", err)
+	}
+	return branding, nil
+}
+
+// GetLogo returns the branding with its logo image
+func (u *BrandingUseCase) GetLogo(ctx context.Context) (*entity.Branding, error) {
+	branding, err := u.brandingRepo.Get(ctx, true)
+	if errors.Is(err, repository.ErrRecordNotFound) {
+		return nil, ErrLogoNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting logo: %w", err)
+	}
+	if !branding.HasLogo() {
+		return nil, ErrLogoNotFound
+	}
+	return branding, nil
+}
+
+// DeleteLogo removes the logo
+func (u *BrandingUseCase) DeleteLogo(ctx context.Context, userID *uint) error {
+	branding, err := u.brandingRepo.Get(ctx, false)
+	if errors.Is(err, repository.ErrRecordNotFound) {
+		return ErrLogoNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("error getting branding: %w", err)
+	}
+	if branding.LogoContentType == "" {
+		return ErrLogoNotFound
+	}
+
+	branding.LogoData = nil
+	branding.LogoContentType = ""
+	branding.LogoUpdatedAt = nil
+	branding.UpdatedBy = userID
+	if err := u.brandingRepo.SaveLogo(ctx, branding); err != nil {
+		return fmt.Errorf("error removing logo: %w", err)
+	}
+	return nil
+}
+
+// Document lays the branding out for a PDF renderer or email template. The logo
+// URL is only set when a logo has been uploaded.
+func (u *BrandingUseCase) Document(ctx context.Context, logoURL string) (*entity.DocumentBranding, error) {
+	branding, err := u.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	document := &entity.DocumentBranding{
+		CompanyName:  branding.CompanyName,
+		AddressBlock: branding.AddressBlock(),
+		BankDetails:  branding.BankDetails(),
+		VATNumbers:   make([]string, len(branding.VATNumbers)),
+		FooterLines:  branding.FooterLines(),
+	}
+	if branding.LogoContentType != "" {
+		document.LogoURL = logoURL
+	}
+	for i, vat := range branding.VATNumbers {
+		document.VATNumbers[i] = vat.Country + " " + vat.Number
+	}
+	return document, nil
+}
+
+// brandingPlaceholders returns the template placeholders filled from the
+// branding, as pairs for a strings.Replacer
+func brandingPlaceholders(branding *entity.Branding) []string {
+	return []string{
+		"{{company_name}}", branding.CompanyName,
+		"{{company_address}}", strings.Join(branding.AddressBlock(), ", "),
+		"{{bank_details}}", strings.Join(branding.BankDetails(), "\n"),
+		"{{vat_numbers}}", branding.VATLine(),
+		"{{invoice_footer}}", strings.Join(branding.FooterLines(), "\n"),
+	}
+}
+
+// logoContentType returns the content type of a supported logo image, or an
+// empty string for anything else
+func logoContentType(data []byte) string {
+	contentType := http.DetectContentType(data)
+	if logoContentTypes[contentType] {
+		return contentType
+	}
+	// SVG sniffs as XML or text
+	if strings.HasPrefix(contentType, "text/") && bytes.Contains(data[:min(len(data), 1024)], []byte("<svg")) {
+		return "image/svg+xml"
+	}
+	return ""
+}
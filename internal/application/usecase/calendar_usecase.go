@@ -0,0 +1,227 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
+)
+
+var (
+	ErrCalendarNotFound = entity.NewError(entity.ErrCodeNotFound, "working calendar not found")
+	ErrCalendarExists   = entity.NewError(entity.ErrCodeConflict, "the company or warehouse already has a working calendar")
+	ErrInvalidTimezone  = entity.NewError(entity.ErrCodeInvalidArgument, "unknown time zone")
+	ErrInvalidShift     = entity.NewError(entity.ErrCodeInvalidArgument, "shift start and end must be HH:MM times")
+	ErrHolidayNotFound  = entity.NewError(entity.ErrCodeNotFound, "holiday not found")
+	ErrHolidayExists    = entity.NewError(entity.ErrCodeConflict, "the date is already a holiday of the calendar")
+	ErrInvalidHoliday   = entity.NewError(entity.ErrCodeInvalidArgument, "holiday date must be YYYY-MM-DD")
+)
+
+// CalendarUseCase manages the working calendars of the company and its
+// warehouses and does business day arithmetic on them
+type CalendarUseCase struct {
+	calendarRepo *repository.CalendarRepository
+}
+
+// NewCalendarUseCase creates a new calendar use case
+func NewCalendarUseCase(calendarRepo *repository.CalendarRepository) *CalendarUseCase {
+	return &CalendarUseCase{
+		calendarRepo: calendarRepo,
+	}
+}
+
+// Create creates a working calendar for the company or a warehouse
+func (u *CalendarUseCase) Create(ctx context.Context, req *entity.WorkingCalendarRequest) (*entity.WorkingCalendar, error) {
+	calendar := &entity.WorkingCalendar{}
+	if err := applyWorkingCalendar(calendar, req); err != nil {
+		return nil, err
+	}
+
+	if err := u.calendarRepo.Create(ctx, calendar); err != nil {
+		if errors.Is(err, repository.ErrDuplicateEntry) {
+			return nil, ErrCalendarExists
+		}
+		return nil, fmt.Errorf("error creating working calendar: %w", err)
+	}
+	return calendar, nil
+}
+
+// Update updates a working calendar. Its holidays are kept.
+func (u *CalendarUseCase) Update(ctx context.Context, id uint, req *entity.WorkingCalendarRequest) (*entity.WorkingCalendar, error) {
+	calendar, err := u.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyWorkingCalendar(calendar, req); err != nil {
+		return nil, err
+	}
+
+	if err := u.calendarRepo.Update(ctx, calendar); err != nil {
+		if errors.Is(err, repository.ErrDuplicateEntry) {
+			return nil, ErrCalendarExists
+		}
+		return nil, fmt.Errorf("error updating working calendar: %w", err)
+	}
+	return calendar, nil
+}
+
+// applyWorkingCalendar validates a calendar request and copies it onto a calendar
+func applyWorkingCalendar(calendar *entity.WorkingCalendar, req *entity.WorkingCalendarRequest) error {
+	timezone := req.Timezone
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(timezone); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidTimezone, timezone)
+	}
+
+	for _, shift := range req.Shifts {
+		if _, err := time.Parse("15:04", shift.Start); err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidShift, shift.Name)
+		}
+		if _, err := time.Parse("15:04", shift.End); err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidShift, shift.Name)
+		}
+	}
+
+	seen := make(map[int]bool, len(req.WorkingDays))
+	days := entity.Weekdays{}
+	for _, day := range req.WorkingDays {
+		if !seen[day] {
+			seen[day] = true
+			days = append(days, day)
+		}
+	}
+
+	calendar.Name = req.Name
+	calendar.StoreID = nil
+	if req.StoreID != nil && *req.StoreID != "" {
+		calendar.StoreID = req.StoreID
+	}
+	calendar.Timezone = timezone
+	calendar.WorkingDays = days
+	calendar.Shifts = append(entity.CalendarShifts{}, req.Shifts...)
+	return nil
+}
+
+// Get retrieves a working calendar with its holidays
+func (u *CalendarUseCase) Get(ctx context.Context, id uint) (*entity.WorkingCalendar, error) {
+	calendar, err := u.calendarRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, ErrCalendarNotFound
+		}
+		return nil, fmt.Errorf("error getting working calendar: %w", err)
+	}
+	return calendar, nil
+}
+
+// List lists the working calendars
+func (u *CalendarUseCase) List(ctx context.Context) ([]entity.WorkingCalendar, error) {
+	calendars, err := u.calendarRepo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error listing working calendars: %w", err)
+	}
+	return calendars, nil
+}
+
+// Delete deletes a working calendar. The warehouse falls back to the company
+// calendar, and the company to the default Monday to Friday calendar.
+func (u *CalendarUseCase) Delete(ctx context.Context, id uint) error {
+	if err := u.calendarRepo.Delete(ctx, id); err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return ErrCalendarNotFound
+		}
+		return fmt.Errorf("error deleting working calendar: %w", err)
+	}
+	return nil
+}
+
+// AddHoliday adds a holiday to a working calendar
+func (u *CalendarUseCase) AddHoliday(ctx context.Context, calendarID uint, req *entity.CalendarHolidayRequest) (*entity.CalendarHoliday, error) {
+	if _, err := u.Get(ctx, calendarID); err != nil {
+		return nil, err
+	}
+	date, err := time.Parse("2006-01-02", req.Date)
+	if err != nil {
+		return nil, ErrInvalidHoliday
+	}
+
+	holiday := &entity.CalendarHoliday{
+		CalendarID: calendarID,
+		Date:       date,
+		Name:       req.Name,
+	}
+	if err := u.calendarRepo.AddHoliday(ctx, holiday); err != nil {
+		if errors.Is(err, repository.ErrDuplicateEntry) {
+			return nil, ErrHolidayExists
+		}
+		return nil, fmt.Errorf("error adding holiday: %w", err)
+	}
+	return holiday, nil
+}
+
+// DeleteHoliday removes a holiday from a working calendar
+func (u *CalendarUseCase) DeleteHoliday(ctx context.Context, calendarID, holidayID uint) error {
+	if err := u.calendarRepo.DeleteHoliday(ctx, calendarID, holidayID); err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return ErrHolidayNotFound
+		}
+		return fmt.Errorf("error deleting holiday: %w", err)
+	}
+	return nil
+}
+
+// ForStore returns the calendar that applies to a warehouse: its own calendar,
+// else the company calendar, else the default Monday to Friday calendar. An
+// empty storeID returns the company calendar.
+func (u *CalendarUseCase) ForStore(ctx context.Context, storeID string) (*entity.WorkingCalendar, error) {
+	calendar, err := u.calendarRepo.FindForStore(ctx, storeID)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return entity.DefaultWorkingCalendar(), nil
+		}
+		return nil, fmt.Errorf("error finding working calendar: %w", err)
+	}
+	return calendar, nil
+}
+
+// AddBusinessDays returns the date the given number of business days after the
+// next working time from t, on the calendar of the warehouse
+func (u *CalendarUseCase) AddBusinessDays(ctx context.Context, storeID string, t time.Time, days int) (time.Time, error) {
+	calendar, err := u.ForStore(ctx, storeID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return calendar.AddBusinessDays(t, days), nil
+}
+
+// NextWorkingTime returns t if it is working time on the calendar of the
+// warehouse, otherwise the start of the next working time
+func (u *CalendarUseCase) NextWorkingTime(ctx context.Context, storeID string, t time.Time) (time.Time, error) {
+	calendar, err := u.ForStore(ctx, storeID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return calendar.NextWorkingTime(t), nil
+}
+
+// Calculate does business day arithmetic from t on the calendar of the warehouse
+func (u *CalendarUseCase) Calculate(ctx context.Context, storeID string, t time.Time, days int) (*entity.CalendarCalculation, error) {
+	calendar, err := u.ForStore(ctx, storeID)
+	if err != nil {
+		return nil, err
+	}
+	return &entity.CalendarCalculation{
+		CalendarID:      calendar.ID,
+		CalendarName:    calendar.Name,
+		From:            t,
+		IsBusinessDay:   calendar.IsBusinessDay(t),
+		NextWorkingTime: calendar.NextWorkingTime(t),
+		BusinessDays:    days,
+		Date:            calendar.AddBusinessDays(t, days),
+	}, nil
+}
@@ -0,0 +1,86 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
+)
+
+var (
+	ErrPrivacyClientNotFound = entity.NewError(entity.ErrCodeNotFound, "client not found")
+	ErrClientAnonymized      = entity.NewError(entity.ErrCodeFailedPrecondition, "client is already anonymized")
+)
+
+// ClientPrivacyUseCase exports and erases the personal data held on clients,
+// recording every request
+type ClientPrivacyUseCase struct {
+	repo *repository.PrivacyRepository
+}
+
+// NewClientPrivacyUseCase creates a new client privacy use case
+func NewClientPrivacyUseCase(repo *repository.PrivacyRepository) *ClientPrivacyUseCase {
+	return &ClientPrivacyUseCase{repo: repo}
+}
+
+// ExportClientData gathers the personal data held on a client for an export
+// in a format, recording the request
+func (u *ClientPrivacyUseCase) ExportClientData(ctx context.Context, clientID uint, format string, userID *uint) (*entity.ClientDataExport, error) {
+	data, err := u.repo.CollectClientData(ctx, clientID)
+	if errors.Is(err, repository.ErrRecordNotFound) {
+		return nil, ErrPrivacyClientNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error collecting client data: %w", err)
+	}
+
+	request := &entity.ClientPrivacyRequest{
+		ClientID:    clientID,
+		Type:        entity.ClientPrivacyExport,
+		Format:      format,
+		RequestedBy: userID,
+	}
+	if err := u.repo.CreateRequest(ctx, request); err != nil {
+		return nil, fmt.Errorf("error recording privacy request: %w", err)
+	}
+	data.PrivacyRequests = append([]entity.ClientPrivacyRequest{*request}, data.PrivacyRequests...)
+	return data, nil
+}
+
+// AnonymizeClient scrubs the personal data of a client and of its documents,
+// keeping the documents themselves, and records the request
+func (u *ClientPrivacyUseCase) AnonymizeClient(ctx context.Context, clientID uint, req *entity.ClientAnonymizeRequest, userID *uint) (*entity.ClientPrivacyRequest, error) {
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		return nil, entity.NewError(entity.ErrCodeInvalidArgument, "reason is required")
+	}
+	data, err := u.repo.CollectClientData(ctx, clientID)
+	if errors.Is(err, repository.ErrRecordNotFound) {
+		return nil, ErrPrivacyClientNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting client: %w", err)
+	}
+	if data.Client.AnonymizedAt != nil {
+		return nil, ErrClientAnonymized
+	}
+
+	request := &entity.ClientPrivacyRequest{
+		ClientID:    clientID,
+		Type:        entity.ClientPrivacyAnonymize,
+		Reason:      reason,
+		RequestedBy: userID,
+	}
+	if err := u.repo.AnonymizeClient(ctx, clientID, entity.NewClientAnonymization(data.Client), request); err != nil {
+		return nil, fmt.Errorf("error anonymizing client: %w", err)
+	}
+	return request, nil
+}
+
+// ListRequests retrieves the privacy requests of a client, latest first
+func (u *ClientPrivacyUseCase) ListRequests(ctx context.Context, clientID uint) ([]entity.ClientPrivacyRequest, error) {
+	return u.repo.ListRequests(ctx, clientID)
+}
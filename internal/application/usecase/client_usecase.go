@@ -147,7 +147,7 @@ func (uc *ClientUseCaseImpl) UpdateAddress(address *entity.ClientAddress) error
 	}
 
 	if existingAddress == nil {
-		return fmt.Errorf("address not found")
+		return entity.NewError(entity.ErrCodeNotFound, "address not found")
 	}
 
 	if err := uc.clientRepo.UpdateAddress(address); err != nil {
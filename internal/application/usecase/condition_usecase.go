@@ -0,0 +1,271 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/eventbus"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
+)
+
+var (
+	ErrConditionThresholdNotFound = entity.NewError(entity.ErrCodeNotFound, "condition threshold not found")
+	ErrConditionExcursionNotFound = entity.NewError(entity.ErrCodeNotFound, "condition excursion not found")
+	ErrConditionLimits            = entity.NewError(entity.ErrCodeInvalidArgument, "a threshold needs a minimum, a maximum or both, the minimum below the maximum")
+)
+
+// ConditionUseCase records the environmental readings of cold-chain stores
+// and opens excursions when they leave the thresholds set, holding the lots
+// exposed for a quality inspection
+type ConditionUseCase struct {
+	repo      *repository.ConditionRepository
+	storeRepo *repository.StoreRepository
+	qualityUC *QualityUseCase
+	bus       *eventbus.Bus
+}
+
+// NewConditionUseCase creates a new condition use case
+func NewConditionUseCase(repo *repository.ConditionRepository, storeRepo *repository.StoreRepository, qualityUC *QualityUseCase, bus *eventbus.Bus) *ConditionUseCase {
+	return &ConditionUseCase{
+		repo:      repo,
+		storeRepo: storeRepo,
+		qualityUC: qualityUC,
+		bus:       bus,
+	}
+}
+
+// CreateThreshold creates a condition threshold for a store or one zone of it
+func (u *ConditionUseCase) CreateThreshold(ctx context.Context, req *entity.ConditionThresholdRequest) (*entity.ConditionThreshold, error) {
+	threshold := &entity.ConditionThreshold{HoldStock: true, Active: true}
+	if err := u.applyThreshold(ctx, threshold, req); err != nil {
+		return nil, err
+	}
+
+	if err := u.repo.CreateThreshold(ctx, threshold); err != nil {
+		return nil, fmt.Errorf("error creating condition threshold: %w", err)
+	}
+	return threshold, nil
+}
+
+// UpdateThreshold updates a condition threshold. Open excursions keep the
+// limits they were opened with and close once a reading is within the new
+// ones.
+func (u *ConditionUseCase) UpdateThreshold(ctx context.Context, id uint, req *entity.ConditionThresholdRequest) (*entity.ConditionThreshold, error) {
+	threshold, err := u.repo.GetThreshold(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, ErrConditionThresholdNotFound
+		}
+		return nil, fmt.Errorf("error getting condition threshold: %w", err)
+	}
+	if err := entity.AccessScopeFromContext(ctx).CheckStore(threshold.StoreID); err != nil {
+		return nil, ErrConditionThresholdNotFound
+	}
+	if err := u.applyThreshold(ctx, threshold, req); err != nil {
+		return nil, err
+	}
+
+	if err := u.repo.UpdateThreshold(ctx, threshold); err != nil {
+		return nil, fmt.Errorf("error updating condition threshold: %w", err)
+	}
+	return threshold, nil
+}
+
+// applyThreshold validates a condition threshold request and copies it onto a
+// threshold
+func (u *ConditionUseCase) applyThreshold(ctx context.Context, threshold *entity.ConditionThreshold, req *entity.ConditionThresholdRequest) error {
+	if req.MinValue == nil && req.MaxValue == nil {
+		return ErrConditionLimits
+	}
+	if req.MinValue != nil && req.MaxValue != nil && *req.MinValue >= *req.MaxValue {
+		return ErrConditionLimits
+	}
+	if err := u.checkStore(ctx, req.StoreID); err != nil {
+		return err
+	}
+
+	threshold.StoreID = req.StoreID
+	threshold.ZoneCode = req.ZoneCode
+	threshold.Metric = req.Metric
+	threshold.MinValue = req.MinValue
+	threshold.MaxValue = req.MaxValue
+	if req.HoldStock != nil {
+		threshold.HoldStock = *req.HoldStock
+	}
+	if req.Active != nil {
+		threshold.Active = *req.Active
+	}
+	return nil
+}
+
+// checkStore checks that a store exists and is in the access scope
+func (u *ConditionUseCase) checkStore(ctx context.Context, storeID string) error {
+	if err := entity.AccessScopeFromContext(ctx).CheckStore(storeID); err != nil {
+		return err
+	}
+	_, err := u.storeRepo.GetByID(ctx, storeID)
+	return err
+}
+
+// ListThresholds lists the condition thresholds, optionally of a single store
+func (u *ConditionUseCase) ListThresholds(ctx context.Context, storeID string) ([]entity.ConditionThreshold, error) {
+	thresholds, err := u.repo.ListThresholds(ctx, storeID, false)
+	if err != nil {
+		return nil, fmt.Errorf("error listing condition thresholds: %w", err)
+	}
+	return thresholds, nil
+}
+
+// RecordReadings records readings, entered by hand or pushed by sensors, in
+// the order given. A reading outside an active threshold of its store and zone
+// opens an excursion, or extends the one open, and a reading back within it
+// closes the excursion. When an excursion opens, the lots stored in its zone
+// with stock not already quarantined are recorded as exposed and, if the
+// threshold holds stock, put on quality hold until inspected.
+func (u *ConditionUseCase) RecordReadings(ctx context.Context, req *entity.ConditionReadingsRequest, userID string) ([]entity.ConditionReading, error) {
+	thresholds := make(map[string][]entity.ConditionThreshold)
+	for _, r := range req.Readings {
+		if _, ok := thresholds[r.StoreID]; ok {
+			continue
+		}
+		if err := u.checkStore(ctx, r.StoreID); err != nil {
+			return nil, err
+		}
+		found, err := u.repo.ListThresholds(ctx, r.StoreID, true)
+		if err != nil {
+			return nil, err
+		}
+		thresholds[r.StoreID] = found
+	}
+
+	now := time.Now()
+	readings := make([]entity.ConditionReading, 0, len(req.Readings))
+	for _, r := range req.Readings {
+		reading := entity.ConditionReading{
+			StoreID:    r.StoreID,
+			ZoneCode:   r.ZoneCode,
+			Metric:     r.Metric,
+			Value:      *r.Value,
+			Source:     entity.ConditionSourceManual,
+			DeviceID:   r.DeviceID,
+			RecordedAt: now,
+			RecordedBy: userID,
+		}
+		if r.DeviceID != "" {
+			reading.Source = entity.ConditionSourceDevice
+		}
+		if r.RecordedAt != nil {
+			reading.RecordedAt = *r.RecordedAt
+		}
+
+		opened, err := u.repo.RecordReading(ctx, &reading, thresholds[r.StoreID])
+		if err != nil {
+			return nil, fmt.Errorf("error recording condition reading: %w", err)
+		}
+		for i := range opened {
+			if err := u.recordExposure(ctx, &opened[i], thresholds[r.StoreID]); err != nil {
+				return nil, err
+			}
+			u.bus.Publish(ctx, entity.ConditionExcursionOpened{Excursion: &opened[i], Value: reading.Value})
+		}
+		readings = append(readings, reading)
+	}
+	return readings, nil
+}
+
+// recordExposure records the lots exposed to an excursion that just opened,
+// holding them when its threshold holds stock
+func (u *ConditionUseCase) recordExposure(ctx context.Context, excursion *entity.ConditionExcursion, thresholds []entity.ConditionThreshold) error {
+	stocks, err := u.repo.ListExposedStocks(ctx, excursion.StoreID, excursion.ZoneCode)
+	if err != nil {
+		return err
+	}
+
+	var held map[string]entity.QualityInspection
+	for _, threshold := range thresholds {
+		if threshold.ID != excursion.ThresholdID || !threshold.HoldStock {
+			continue
+		}
+		quantities := make(map[string]float64, len(stocks))
+		for _, stock := range stocks {
+			quantities[stock.SKUID] += stock.Available()
+		}
+		held, err = u.qualityUC.HoldExposed(ctx, entity.InspectionSourceExcursion, excursion.StoreID, excursionReference(excursion), quantities)
+		if err != nil {
+			return err
+		}
+	}
+
+	lots := make([]entity.ConditionExcursionLot, 0, len(stocks))
+	for _, stock := range stocks {
+		lot := entity.ConditionExcursionLot{
+			ExcursionID: excursion.ID,
+			StockID:     stock.ID,
+			SKUID:       stock.SKUID,
+			BatchNumber: stock.BatchNumber,
+			LotNumber:   stock.LotNumber,
+			ZoneCode:    stock.ZoneCode,
+			Quantity:    stock.Available(),
+		}
+		if inspection, ok := held[stock.SKUID]; ok {
+			id := inspection.ID
+			lot.InspectionID = &id
+		}
+		lots = append(lots, lot)
+	}
+	if err := u.repo.CreateExcursionLots(ctx, lots); err != nil {
+		return fmt.Errorf("error recording lots exposed to excursion %d: %w", excursion.ID, err)
+	}
+	excursion.Lots = lots
+	return nil
+}
+
+// excursionReference is the reference of the quality inspections holding the
+// lots exposed to an excursion
+func excursionReference(excursion *entity.ConditionExcursion) string {
+	return fmt.Sprintf("EXC-%d", excursion.ID)
+}
+
+// ListReadings lists condition readings, latest first
+func (u *ConditionUseCase) ListReadings(ctx context.Context, filter *entity.ConditionReadingFilter) ([]entity.ConditionReading, int64, error) {
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+	if filter.PageSize <= 0 {
+		filter.PageSize = 100
+	}
+
+	readings, total, err := u.repo.ListReadings(ctx, filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error listing condition readings: %w", err)
+	}
+	return readings, total, nil
+}
+
+// ListExcursions lists condition excursions with their exposed lots, latest
+// first
+func (u *ConditionUseCase) ListExcursions(ctx context.Context, filter *entity.ConditionExcursionFilter) ([]entity.ConditionExcursion, error) {
+	excursions, err := u.repo.ListExcursions(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("error listing condition excursions: %w", err)
+	}
+	return excursions, nil
+}
+
+// GetExcursion retrieves a condition excursion with its exposed lots
+func (u *ConditionUseCase) GetExcursion(ctx context.Context, id uint) (*entity.ConditionExcursion, error) {
+	excursion, err := u.repo.GetExcursion(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, ErrConditionExcursionNotFound
+		}
+		return nil, fmt.Errorf("error getting condition excursion: %w", err)
+	}
+	if err := entity.AccessScopeFromContext(ctx).CheckStore(excursion.StoreID); err != nil {
+		return nil, ErrConditionExcursionNotFound
+	}
+	return excursion, nil
+}
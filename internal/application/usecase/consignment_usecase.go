@@ -0,0 +1,280 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/eventbus"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
+)
+
+var (
+	ErrConsignmentVendorNotFound = entity.NewError(entity.ErrCodeNotFound, "vendor not found")
+	ErrConsignmentStockEntry     = entity.NewError(entity.ErrCodeInvalidArgument, "consignment stock is received and returned through the consignment endpoints")
+)
+
+// consignmentReference is the reference of purchase invoices billing consumed
+// consignment stock
+const consignmentReference = "CONSIGNMENT"
+
+// ConsignmentUseCase receives and returns the stock vendors place in stores on
+// consignment, and bills vendors for the consigned stock consumed
+type ConsignmentUseCase struct {
+	repo       *repository.ConsignmentRepository
+	vendorRepo *repository.VendorRepository
+	storeRepo  *repository.StoreRepository
+	financeUC  *FinanceUseCase
+	bus        *eventbus.Bus
+}
+
+// NewConsignmentUseCase creates a new consignment use case
+func NewConsignmentUseCase(repo *repository.ConsignmentRepository, vendorRepo *repository.VendorRepository, storeRepo *repository.StoreRepository, financeUC *FinanceUseCase, bus *eventbus.Bus) *ConsignmentUseCase {
+	return &ConsignmentUseCase{
+		repo:       repo,
+		vendorRepo: vendorRepo,
+		storeRepo:  storeRepo,
+		financeUC:  financeUC,
+		bus:        bus,
+	}
+}
+
+// ReceiveStock posts the goods a vendor placed in a store on consignment. The
+// stock grows as with any receipt, but the vendor owns it until it is
+// consumed.
+func (u *ConsignmentUseCase) ReceiveStock(ctx context.Context, req *entity.ConsignmentReceiptRequest, userID string) ([]entity.StockEntry, error) {
+	entries, err := u.stockEntries(ctx, req, "IN", userID)
+	if err != nil {
+		return nil, err
+	}
+	unitCosts := make([]float64, len(req.Items))
+	for i, item := range req.Items {
+		unitCosts[i] = item.UnitCost
+	}
+
+	if err := u.repo.ReceiveStock(ctx, req.VendorID, entries, unitCosts, userID); err != nil {
+		return nil, err
+	}
+	u.bus.Publish(ctx, entity.StockEntryCreated{Entries: entries})
+	return entries, nil
+}
+
+// ReturnStock posts consigned goods a vendor takes back from a store. Returned
+// stock is not owed to the vendor.
+func (u *ConsignmentUseCase) ReturnStock(ctx context.Context, req *entity.ConsignmentReceiptRequest, userID string) ([]entity.StockEntry, error) {
+	entries, err := u.stockEntries(ctx, req, "OUT", userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.repo.ReturnStock(ctx, req.VendorID, entries, userID); err != nil {
+		return nil, err
+	}
+	u.bus.Publish(ctx, entity.StockEntryCreated{Entries: entries})
+	return entries, nil
+}
+
+// stockEntries validates a consignment receipt or return and builds its
+// stock entries
+func (u *ConsignmentUseCase) stockEntries(ctx context.Context, req *entity.ConsignmentReceiptRequest, entryType string, userID string) ([]entity.StockEntry, error) {
+	if err := entity.AccessScopeFromContext(ctx).CheckStore(req.StoreID); err != nil {
+		return nil, err
+	}
+	store, err := u.storeRepo.GetByID(ctx, req.StoreID)
+	if err != nil {
+		return nil, err
+	}
+	if store.Status != entity.StoreStatusActive {
+		return nil, repository.ErrInvalidData
+	}
+	vendor, err := u.vendorRepo.FindByID(ctx, req.VendorID)
+	if errors.Is(err, repository.ErrRecordNotFound) {
+		return nil, ErrConsignmentVendorNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	reference := req.Reference
+	if reference == "" {
+		reference = consignmentReference + "-" + vendor.Code
+	}
+	entries := make([]entity.StockEntry, len(req.Items))
+	for i, item := range req.Items {
+		entries[i] = entity.StockEntry{
+			SKUID:               item.SKUID,
+			StoreID:             req.StoreID,
+			Type:                entryType,
+			Quantity:            item.Quantity,
+			Reference:           reference,
+			Note:                req.Note,
+			ConsignmentVendorID: &vendor.ID,
+			CreatedBy:           userID,
+		}
+	}
+	return entries, nil
+}
+
+// ListBalances reports the consignment position with each vendor matching a
+// filter: the stock it still owns and its value, and the consumed stock not
+// billed yet
+func (u *ConsignmentUseCase) ListBalances(ctx context.Context, filter *entity.ConsignmentFilter) ([]entity.ConsignmentBalance, error) {
+	stocks, err := u.repo.ListStocks(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	unbilled, err := u.repo.UnbilledAmounts(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	balances := make(map[uint]*entity.ConsignmentBalance)
+	balance := func(vendor *entity.Vendor) *entity.ConsignmentBalance {
+		b, ok := balances[vendor.ID]
+		if !ok {
+			b = &entity.ConsignmentBalance{
+				VendorID:   vendor.ID,
+				VendorCode: vendor.Code,
+				VendorName: vendor.Name,
+				Currency:   vendor.Currency,
+				Stocks:     []entity.ConsignmentStock{},
+			}
+			balances[vendor.ID] = b
+		}
+		return b
+	}
+	for _, stock := range stocks {
+		vendor := stock.Vendor
+		if vendor == nil {
+			vendor = &entity.Vendor{ID: stock.VendorID}
+		}
+		b := balance(vendor)
+		stock.Vendor = nil
+		b.Quantity += stock.Quantity
+		b.Value += math.Round(stock.Quantity*stock.UnitCost*100) / 100
+		b.Stocks = append(b.Stocks, stock)
+	}
+	for vendorID, amount := range unbilled {
+		if _, ok := balances[vendorID]; !ok {
+			vendor, err := u.vendorRepo.FindByID(ctx, vendorID)
+			if err != nil {
+				vendor = &entity.Vendor{ID: vendorID}
+			}
+			balance(vendor)
+		}
+		balances[vendorID].UnbilledAmount = amount
+	}
+
+	result := make([]entity.ConsignmentBalance, 0, len(balances))
+	for _, b := range balances {
+		result = append(result, *b)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].VendorID < result[j].VendorID })
+	return result, nil
+}
+
+// ListConsumptions retrieves the consigned stock consumed matching a filter,
+// latest first
+func (u *ConsignmentUseCase) ListConsumptions(ctx context.Context, filter *entity.ConsignmentFilter) ([]entity.ConsignmentConsumption, error) {
+	return u.repo.ListConsumptions(ctx, filter)
+}
+
+// BillConsumptions raises a pending purchase invoice to each vendor, or to a
+// single vendor, for the consigned stock consumed and not billed yet, with a
+// line per SKU and unit cost. It is due after the vendor's payment days. A
+// vendor that fails to bill is reported in the result and left for the next
+// run.
+func (u *ConsignmentUseCase) BillConsumptions(ctx context.Context, vendorID *uint, userID int64) (*entity.ConsignmentBillingResult, error) {
+	consumptions, err := u.repo.ListConsumptions(ctx, &entity.ConsignmentFilter{VendorID: vendorID, Unbilled: true})
+	if err != nil {
+		return nil, err
+	}
+
+	byVendor := make(map[uint][]entity.ConsignmentConsumption)
+	var vendorIDs []uint
+	for _, consumption := range consumptions {
+		if _, ok := byVendor[consumption.VendorID]; !ok {
+			vendorIDs = append(vendorIDs, consumption.VendorID)
+		}
+		byVendor[consumption.VendorID] = append(byVendor[consumption.VendorID], consumption)
+	}
+	sort.Slice(vendorIDs, func(i, j int) bool { return vendorIDs[i] < vendorIDs[j] })
+
+	result := &entity.ConsignmentBillingResult{Invoices: []entity.FinanceInvoice{}}
+	for _, id := range vendorIDs {
+		invoice, err := u.bill(ctx, id, byVendor[id], userID)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("vendor %d: %v", id, err))
+			continue
+		}
+		result.Invoices = append(result.Invoices, *invoice)
+		result.Consumptions += len(byVendor[id])
+	}
+	return result, nil
+}
+
+// bill raises the purchase invoice of a vendor's unbilled consumptions
+func (u *ConsignmentUseCase) bill(ctx context.Context, vendorID uint, consumptions []entity.ConsignmentConsumption, userID int64) (*entity.FinanceInvoice, error) {
+	vendor, err := u.vendorRepo.FindByID(ctx, vendorID)
+	if err != nil {
+		return nil, err
+	}
+
+	type line struct {
+		skuID    string
+		unitCost float64
+	}
+	lines := make(map[line]int)
+	var items entity.FinanceInvoiceItems
+	ids := make([]uint, len(consumptions))
+	for i, consumption := range consumptions {
+		ids[i] = consumption.ID
+		key := line{consumption.SKUID, consumption.UnitCost}
+		if n, ok := lines[key]; ok {
+			items[n].Quantity += consumption.Quantity
+			continue
+		}
+		name := consumption.SKUID
+		if consumption.SKU != nil {
+			name = strings.TrimSpace(consumption.SKU.SKUCode + " " + consumption.SKU.Name)
+		}
+		lines[key] = len(items)
+		items = append(items, entity.FinanceInvoiceItem{
+			ProductName: name,
+			Quantity:    consumption.Quantity,
+			UnitPrice:   consumption.UnitCost,
+		})
+	}
+
+	paymentDays := vendor.PaymentDays
+	if paymentDays <= 0 {
+		paymentDays = defaultPaymentTermsDays
+	}
+	issueDate := truncateDay(time.Now())
+	invoice, err := u.financeUC.newInvoice(ctx, &entity.CreateFinanceInvoiceRequest{
+		Type:         entity.FinancePurchaseInvoice,
+		ReferenceID:  consignmentReference,
+		EntityID:     int64(vendor.ID),
+		EntityType:   "SUPPLIER",
+		IssueDate:    issueDate,
+		DueDate:      issueDate.AddDate(0, 0, paymentDays),
+		Items:        items,
+		CurrencyCode: vendor.Currency,
+		Notes:        "Consigned stock consumed",
+	}, userID)
+	if err != nil {
+		return nil, err
+	}
+	invoice.EntityName = vendor.Name
+	invoice.Status = entity.FinanceInvoicePending
+
+	if err := u.repo.BillConsumptions(ctx, ids, invoice); err != nil {
+		return nil, err
+	}
+	return invoice, nil
+}
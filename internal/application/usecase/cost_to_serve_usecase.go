@@ -0,0 +1,248 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
+)
+
+var ErrInvalidCostToServePeriod = entity.NewError(entity.ErrCodeInvalidArgument, "start date must not be after end date")
+
+// defaultCostToServeDays is the period of the cost-to-serve report when no start date is given
+const defaultCostToServeDays = 90
+
+// CostToServeUseCase charges the freight, handling, returns and late payments of
+// each customer against its gross margin
+type CostToServeUseCase struct {
+	costRepo     *repository.CostToServeRepository
+	forecastRepo *repository.ForecastRepository
+	rates        entity.CostToServeRates
+}
+
+// NewCostToServeUseCase creates a new cost-to-serve use case
+func NewCostToServeUseCase(costRepo *repository.CostToServeRepository, forecastRepo *repository.ForecastRepository, rates entity.CostToServeRates) *CostToServeUseCase {
+	return &CostToServeUseCase{
+		costRepo:     costRepo,
+		forecastRepo: forecastRepo,
+		rates:        rates,
+	}
+}
+
+// Report computes the cost to serve each customer with sales, deliveries,
+// returns or invoices in the period:
+//   - revenue is the sales orders placed in the period net of tax, less the value of returns
+//   - cost of goods prices the units sold, less restocked returns, at the average purchase price
+//   - handling charges every picked delivery and delivery line at the pick and line rates
+//   - returns are charged at the return rate each
+//   - payment cost charges the capital rate on sales invoice amounts for the days
+//     they were paid after the due date, or are still outstanding past it
+func (u *CostToServeUseCase) Report(ctx context.Context, filter *entity.CostToServeFilter) (*entity.CostToServeReport, error) {
+	now := time.Now()
+	end := now
+	if filter.EndDate != nil {
+		end = *filter.EndDate
+	}
+	start := truncateDay(end).AddDate(0, 0, -defaultCostToServeDays)
+	if filter.StartDate != nil {
+		start = *filter.StartDate
+	}
+	if start.After(end) {
+		return nil, ErrInvalidCostToServePeriod
+	}
+
+	// The sales history excludes its end, the report period does not
+	orders, err := u.forecastRepo.ListSalesHistory(ctx, start, end.Add(time.Nanosecond))
+	if err != nil {
+		return nil, fmt.Errorf("error getting sales history: %w", err)
+	}
+	deliveries, err := u.costRepo.ListDeliveries(ctx, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("error listing deliveries: %w", err)
+	}
+	returns, err := u.costRepo.ListReturns(ctx, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("error listing sales returns: %w", err)
+	}
+	invoices, err := u.costRepo.ListSalesInvoices(ctx, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("error listing sales invoices: %w", err)
+	}
+
+	returnOrderIDs := make([]string, 0, len(returns))
+	skuIDs := make(map[string]bool)
+	for _, ret := range returns {
+		returnOrderIDs = append(returnOrderIDs, ret.SalesOrderID)
+		skuIDs[ret.SKUID] = true
+	}
+	for _, order := range orders {
+		for _, item := range order.Items {
+			skuIDs[item.SKUID] = true
+		}
+	}
+	returnOrders, err := u.costRepo.GetOrders(ctx, returnOrderIDs)
+	if err != nil {
+		return nil, fmt.Errorf("error getting returned orders: %w", err)
+	}
+	ids := make([]string, 0, len(skuIDs))
+	for skuID := range skuIDs {
+		ids = append(ids, skuID)
+	}
+	unitCosts, err := u.costRepo.GetUnitCosts(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("error getting unit costs: %w", err)
+	}
+
+	invoiceIDs := make([]int64, 0, len(invoices))
+	for _, invoice := range invoices {
+		invoiceIDs = append(invoiceIDs, invoice.ID)
+	}
+	payments, err := u.costRepo.ListCompletedPayments(ctx, invoiceIDs)
+	if err != nil {
+		return nil, fmt.Errorf("error listing payments: %w", err)
+	}
+
+	customers := make(map[uint]*entity.CustomerCostToServe)
+	customer := func(clientID uint) *entity.CustomerCostToServe {
+		c, ok := customers[clientID]
+		if !ok {
+			c = &entity.CustomerCostToServe{ClientID: clientID}
+			customers[clientID] = c
+		}
+		return c
+	}
+	included := func(clientID uint) bool {
+		return filter.ClientID == nil || *filter.ClientID == clientID
+	}
+
+	for _, order := range orders {
+		if !included(order.ClientID) {
+			continue
+		}
+		c := customer(order.ClientID)
+		c.OrderCount++
+		for _, item := range order.Items {
+			c.Revenue += (item.TotalPrice - item.TaxAmount) * order.ExchangeRate
+			c.CostOfGoods += item.Quantity * unitCosts[item.SKUID]
+		}
+	}
+
+	for _, delivery := range deliveries {
+		if !included(delivery.ClientID) {
+			continue
+		}
+		c := customer(delivery.ClientID)
+		c.DeliveryCount++
+		c.LineCount += len(delivery.Items)
+		c.FreightCost += delivery.FreightCost
+	}
+
+	for _, ret := range returns {
+		if !included(ret.ClientID) {
+			continue
+		}
+		c := customer(ret.ClientID)
+		c.ReturnCount++
+		if order, ok := returnOrders[ret.SalesOrderID]; ok {
+			c.ReturnedValue += ret.Quantity * netUnitPrice(order, ret.SKUID)
+		}
+		// Restocked units are back in stock, the others are written off
+		if ret.Restocked {
+			c.CostOfGoods -= ret.Quantity * unitCosts[ret.SKUID]
+		}
+	}
+
+	paymentsByInvoice := make(map[int64][]entity.FinancePayment)
+	for _, payment := range payments {
+		paymentsByInvoice[payment.InvoiceID] = append(paymentsByInvoice[payment.InvoiceID], payment)
+	}
+	paidAmount := make(map[uint]float64)
+	daysToPay := make(map[uint]float64)
+	lateDays := make(map[uint]float64)
+	for _, invoice := range invoices {
+		clientID := uint(invoice.EntityID)
+		if !included(clientID) {
+			continue
+		}
+		c := customer(clientID)
+		for _, payment := range paymentsByInvoice[invoice.ID] {
+			late := math.Max(0, float64(daysBetween(invoice.DueDate, payment.PaymentDate)))
+			paidAmount[clientID] += payment.BaseAmount
+			daysToPay[clientID] += payment.BaseAmount * math.Max(0, float64(daysBetween(invoice.IssueDate, payment.PaymentDate)))
+			lateDays[clientID] += payment.BaseAmount * late
+			c.PaymentCost += payment.BaseAmount * late * u.rates.CapitalRate / 36500
+		}
+		if invoice.AmountDue > 0 && invoice.DueDate.Before(end) {
+			outstanding := invoice.AmountDue * invoice.ExchangeRate
+			c.OverdueAmount += outstanding
+			c.PaymentCost += outstanding * float64(daysBetween(invoice.DueDate, end)) * u.rates.CapitalRate / 36500
+		}
+	}
+
+	clientIDs := make([]uint, 0, len(customers))
+	for clientID := range customers {
+		clientIDs = append(clientIDs, clientID)
+	}
+	names, err := u.costRepo.GetClientNames(ctx, clientIDs)
+	if err != nil {
+		return nil, fmt.Errorf("error getting client names: %w", err)
+	}
+
+	report := &entity.CostToServeReport{
+		StartDate:   start,
+		EndDate:     end,
+		Rates:       u.rates,
+		Customers:   make([]entity.CustomerCostToServe, 0, len(customers)),
+		GeneratedAt: now,
+	}
+	for clientID, c := range customers {
+		c.ClientName = names[clientID]
+		c.Revenue = roundAmount(c.Revenue - c.ReturnedValue)
+		c.CostOfGoods = roundAmount(c.CostOfGoods)
+		c.GrossMargin = roundAmount(c.Revenue - c.CostOfGoods)
+		c.HandlingCost = roundAmount(float64(c.DeliveryCount)*u.rates.PickCost + float64(c.LineCount)*u.rates.LineCost)
+		c.FreightCost = roundAmount(c.FreightCost)
+		c.ReturnedValue = roundAmount(c.ReturnedValue)
+		c.ReturnCost = roundAmount(float64(c.ReturnCount) * u.rates.ReturnCost)
+		c.OverdueAmount = roundAmount(c.OverdueAmount)
+		c.PaymentCost = roundAmount(c.PaymentCost)
+		if paid := paidAmount[clientID]; paid > 0 {
+			c.AverageDaysToPay = roundAmount(daysToPay[clientID] / paid)
+			c.LatePaymentDays = roundAmount(lateDays[clientID] / paid)
+		}
+		c.CostToServe = roundAmount(c.FreightCost + c.HandlingCost + c.ReturnCost + c.PaymentCost)
+		c.NetMargin = roundAmount(c.GrossMargin - c.CostToServe)
+		if c.Revenue != 0 {
+			c.GrossMarginPercent = roundAmount(c.GrossMargin / c.Revenue * 100)
+			c.NetMarginPercent = roundAmount(c.NetMargin / c.Revenue * 100)
+		}
+		report.Customers = append(report.Customers, *c)
+	}
+
+	sort.Slice(report.Customers, func(i, j int) bool {
+		if report.Customers[i].NetMargin != report.Customers[j].NetMargin {
+			return report.Customers[i].NetMargin < report.Customers[j].NetMargin
+		}
+		return report.Customers[i].ClientID < report.Customers[j].ClientID
+	})
+	return report, nil
+}
+
+// netUnitPrice returns the base currency price net of discount and tax a SKU was sold at on an order
+func netUnitPrice(order entity.SalesOrder, skuID string) float64 {
+	var value, quantity float64
+	for _, item := range order.Items {
+		if item.SKUID == skuID {
+			value += item.TotalPrice - item.TaxAmount
+			quantity += item.Quantity
+		}
+	}
+	if quantity == 0 {
+		return 0
+	}
+	return value / quantity * order.ExchangeRate
+}
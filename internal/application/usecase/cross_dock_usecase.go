@@ -0,0 +1,157 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
+)
+
+// CrossDockUseCase receives purchased goods straight into the pending sales
+// deliveries of the receiving store, skipping putaway
+type CrossDockUseCase struct {
+	purchaseRepo *repository.PurchaseRepository
+	orderRepo    *repository.OrderRepository
+	purchaseUC   *PurchaseUseCase
+	orderUC      *OrderUseCase
+	qualityUC    *QualityUseCase
+}
+
+// NewCrossDockUseCase creates a new cross-dock use case
+func NewCrossDockUseCase(purchaseRepo *repository.PurchaseRepository, orderRepo *repository.OrderRepository, purchaseUC *PurchaseUseCase, orderUC *OrderUseCase, qualityUC *QualityUseCase) *CrossDockUseCase {
+	return &CrossDockUseCase{
+		purchaseRepo: purchaseRepo,
+		orderRepo:    orderRepo,
+		purchaseUC:   purchaseUC,
+		orderUC:      orderUC,
+		qualityUC:    qualityUC,
+	}
+}
+
+// Receive posts a purchase receipt in cross-dock mode. The pending and
+// preparing deliveries of the store the received quantities cover in full
+// are taken earliest delivery date first; the receipt lines are linked to
+// them and they are shipped right after the receipt is posted, so each
+// cross-docked quantity is received and issued at once. Capital lines and
+// SKUs held for inspection on receipt are put away as usual, as is what no
+// delivery takes.
+func (u *CrossDockUseCase) Receive(ctx context.Context, receipt *entity.PurchaseReceipt, userID string) (*entity.CrossDockResult, error) {
+	if err := entity.AccessScopeFromContext(ctx).CheckStore(receipt.StoreID); err != nil {
+		return nil, err
+	}
+	order, err := u.purchaseRepo.GetPurchaseOrderByID(ctx, receipt.PurchaseOrderID)
+	if err != nil {
+		return nil, err
+	}
+
+	skuIDs := make([]string, 0, len(receipt.Items))
+	for i := range receipt.Items {
+		receipt.Items[i].CrossDocks = nil
+		skuIDs = append(skuIDs, receipt.Items[i].SKUID)
+	}
+	inspected, err := u.qualityUC.InspectedSKUs(ctx, entity.InspectionSourceReceipt, skuIDs)
+	if err != nil {
+		return nil, err
+	}
+	available := make(map[string]float64)
+	for _, item := range receipt.Items {
+		if item.ReceivedQuantity > 0 && !inspected[item.SKUID] && capitalLine(order, item.SKUID) == nil {
+			available[item.SKUID] += item.ReceivedQuantity
+		}
+	}
+
+	candidates, err := u.orderRepo.ListCrossDockDeliveries(ctx, receipt.StoreID)
+	if err != nil {
+		return nil, err
+	}
+	var deliveries []entity.DeliveryOrder
+	for _, delivery := range candidates {
+		needed := make(map[string]float64)
+		for _, item := range delivery.Items {
+			needed[item.SKUID] += item.ShippedQuantity
+		}
+		covered := len(needed) > 0
+		for skuID, quantity := range needed {
+			if quantity > available[skuID] {
+				covered = false
+				break
+			}
+		}
+		if !covered {
+			continue
+		}
+		for skuID, quantity := range needed {
+			available[skuID] -= quantity
+		}
+		linkCrossDock(receipt, &delivery, inspected, order)
+		deliveries = append(deliveries, delivery)
+	}
+
+	receipt.CrossDock = true
+	if err := u.purchaseUC.CreatePurchaseReceipt(ctx, receipt, userID); err != nil {
+		return nil, err
+	}
+
+	result := &entity.CrossDockResult{Receipt: receipt, Deliveries: []entity.DeliveryOrder{}}
+	if len(deliveries) == 0 {
+		return result, nil
+	}
+	ids := make([]string, len(deliveries))
+	for i, delivery := range deliveries {
+		ids[i] = delivery.ID
+	}
+	if err := u.orderRepo.LinkCrossDockReceipt(ctx, ids, receipt.ID); err != nil {
+		return nil, fmt.Errorf("error linking deliveries to receipt %s: %w", receipt.ReceiptNumber, err)
+	}
+
+	for _, delivery := range deliveries {
+		if delivery.Status == entity.DeliveryOrderStatusPending {
+			if err := u.orderUC.PrepareDelivery(ctx, delivery.ID); err != nil {
+				return nil, fmt.Errorf("error preparing delivery %s: %w", delivery.DeliveryNumber, err)
+			}
+		}
+		if err := u.orderUC.ShipDelivery(ctx, delivery.ID, userID); err != nil {
+			return nil, fmt.Errorf("error shipping delivery %s: %w", delivery.DeliveryNumber, err)
+		}
+		shipped, err := u.orderRepo.GetDeliveryOrderByID(ctx, delivery.ID)
+		if err != nil {
+			return nil, err
+		}
+		result.Deliveries = append(result.Deliveries, *shipped)
+	}
+	return result, nil
+}
+
+// linkCrossDock records on the cross-dockable receipt lines the quantities of
+// a delivery they ship, filling the lines of a SKU in order
+func linkCrossDock(receipt *entity.PurchaseReceipt, delivery *entity.DeliveryOrder, inspected map[string]bool, order *entity.PurchaseOrder) {
+	for _, item := range delivery.Items {
+		remaining := item.ShippedQuantity
+		for i := range receipt.Items {
+			line := &receipt.Items[i]
+			if remaining <= 0 {
+				break
+			}
+			if line.SKUID != item.SKUID || inspected[line.SKUID] || capitalLine(order, line.SKUID) != nil {
+				continue
+			}
+			linked := 0.0
+			for _, link := range line.CrossDocks {
+				linked += link.Quantity
+			}
+			quantity := math.Min(remaining, line.ReceivedQuantity-linked)
+			if quantity <= 0 {
+				continue
+			}
+			line.CrossDocks = append(line.CrossDocks, entity.CrossDockLink{
+				DeliveryOrderID: delivery.ID,
+				DeliveryNumber:  delivery.DeliveryNumber,
+				SalesOrderID:    delivery.SalesOrderID,
+				Quantity:        quantity,
+			})
+			remaining -= quantity
+		}
+	}
+}
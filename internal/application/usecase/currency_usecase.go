@@ -0,0 +1,129 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/cache"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
+)
+
+var ErrExchangeRateNotFound = entity.NewError(entity.ErrCodeNotFound, "no exchange rate recorded for currency")
+
+// CurrencyUseCase handles exchange rates and conversion into the base currency
+type CurrencyUseCase struct {
+	currencyRepo *repository.CurrencyRepository
+	baseCurrency string
+	rateCache    *cache.Namespace // the rates of each currency, newest first
+}
+
+// NewCurrencyUseCase creates a new currency use case
+func NewCurrencyUseCase(currencyRepo *repository.CurrencyRepository, baseCurrency string, c *cache.Cache) *CurrencyUseCase {
+	return &CurrencyUseCase{
+		currencyRepo: currencyRepo,
+		baseCurrency: strings.ToUpper(baseCurrency),
+		rateCache:    c.Namespace(cache.NamespaceRates),
+	}
+}
+
+// BaseCurrency returns the currency ledgers and reports are kept in
+func (u *CurrencyUseCase) BaseCurrency() string {
+	return u.baseCurrency
+}
+
+// SetRate records the rate of a currency against the base currency for a day
+func (u *CurrencyUseCase) SetRate(ctx context.Context, req *entity.SetExchangeRateRequest) (*entity.ExchangeRate, error) {
+	currency := strings.ToUpper(req.Currency)
+	if currency == u.baseCurrency {
+		return nil, entity.NewError(entity.ErrCodeFailedPrecondition, fmt.Sprintf("cannot set a rate for the base currency %s", u.baseCurrency))
+	}
+	if req.Rate <= 0 {
+		return nil, entity.NewError(entity.ErrCodeInvalidArgument, "exchange rate must be greater than zero")
+	}
+
+	rate := &entity.ExchangeRate{
+		FromCurrency: currency,
+		ToCurrency:   u.baseCurrency,
+		RateDate:     truncateDay(req.RateDate),
+		Rate:         req.Rate,
+		Source:       req.Source,
+	}
+
+	if err := u.currencyRepo.SaveRate(ctx, rate); err != nil {
+		return nil, fmt.Errorf("error saving exchange rate: %w", err)
+	}
+	u.rateCache.Invalidate(ctx, currency)
+	return rate, nil
+}
+
+// ListRates lists recorded exchange rates
+func (u *CurrencyUseCase) ListRates(ctx context.Context, filter *entity.ExchangeRateFilter) ([]entity.ExchangeRate, error) {
+	filter.Currency = strings.ToUpper(filter.Currency)
+	rates, err := u.currencyRepo.ListRates(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("error listing exchange rates: %w", err)
+	}
+	return rates, nil
+}
+
+// GetRate returns the rate converting one unit of currency into the base currency,
+// using the latest rate recorded on or before the date. The base currency always has rate 1.
+// Without a cache the rate is looked up directly; with one, the rates of the
+// currency are cached together and the rate picked from them.
+func (u *CurrencyUseCase) GetRate(ctx context.Context, currency string, date time.Time) (float64, error) {
+	currency = strings.ToUpper(currency)
+	if currency == "" || currency == u.baseCurrency {
+		return 1, nil
+	}
+	notFound := fmt.Errorf("%w: %s on %s", ErrExchangeRateNotFound, currency, date.Format("2006-01-02"))
+
+	if !u.rateCache.Enabled(ctx) {
+		rate, err := u.currencyRepo.FindRate(ctx, currency, u.baseCurrency, date)
+		if err != nil {
+			if errors.Is(err, repository.ErrRecordNotFound) {
+				return 0, notFound
+			}
+			return 0, fmt.Errorf("error getting exchange rate: %w", err)
+		}
+		return rate.Rate, nil
+	}
+
+	rates, err := cache.Load(ctx, u.rateCache, currency, func() ([]entity.ExchangeRate, error) {
+		return u.currencyRepo.ListPairRates(ctx, currency, u.baseCurrency)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("error getting exchange rate: %w", err)
+	}
+	for _, rate := range rates {
+		if !rate.RateDate.After(date) {
+			return rate.Rate, nil
+		}
+	}
+	return 0, notFound
+}
+
+// Convert converts an amount in the given currency into the base currency
+func (u *CurrencyUseCase) Convert(ctx context.Context, amount float64, currency string, date time.Time) (*entity.CurrencyConversion, error) {
+	rate, err := u.GetRate(ctx, currency, date)
+	if err != nil {
+		return nil, err
+	}
+
+	currency = strings.ToUpper(currency)
+	if currency == "" {
+		currency = u.baseCurrency
+	}
+
+	return &entity.CurrencyConversion{
+		Currency:     currency,
+		Amount:       amount,
+		BaseCurrency: u.baseCurrency,
+		BaseAmount:   roundAmount(amount * rate),
+		Rate:         rate,
+		RateDate:     truncateDay(date),
+	}, nil
+}
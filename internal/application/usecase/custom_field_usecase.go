@@ -0,0 +1,209 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/export"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
+)
+
+var (
+	ErrCustomFieldNotFound  = entity.NewError(entity.ErrCodeNotFound, "custom field not found")
+	ErrCustomFieldExists    = entity.NewError(entity.ErrCodeConflict, "a custom field with this key is already defined on the entity type")
+	ErrCustomFieldImmutable = entity.NewError(entity.ErrCodeInvalidArgument, "the entity type, key and type of a custom field cannot be changed")
+)
+
+// CustomFieldUseCase handles the custom fields defined on SKUs, sales orders
+// and vendors, and checks the values entities hold for them
+type CustomFieldUseCase struct {
+	repo *repository.CustomFieldRepository
+}
+
+// NewCustomFieldUseCase creates a new custom field use case
+func NewCustomFieldUseCase(repo *repository.CustomFieldRepository) *CustomFieldUseCase {
+	return &CustomFieldUseCase{repo: repo}
+}
+
+// ListDefinitions lists the custom fields defined on an entity type, or on
+// every type when it is empty
+func (u *CustomFieldUseCase) ListDefinitions(ctx context.Context, entityType string) ([]entity.CustomFieldDefinition, error) {
+	return u.repo.ListDefinitions(ctx, entityType)
+}
+
+// GetDefinition gets a custom field definition
+func (u *CustomFieldUseCase) GetDefinition(ctx context.Context, id uint) (*entity.CustomFieldDefinition, error) {
+	definition, err := u.repo.GetDefinition(ctx, id)
+	if errors.Is(err, repository.ErrRecordNotFound) {
+		return nil, ErrCustomFieldNotFound
+	}
+	return definition, err
+}
+
+// CreateDefinition defines a custom field on an entity type
+func (u *CustomFieldUseCase) CreateDefinition(ctx context.Context, req *entity.CustomFieldDefinitionRequest) (*entity.CustomFieldDefinition, error) {
+	definition := &entity.CustomFieldDefinition{
+		EntityType: req.EntityType,
+		Key:        strings.TrimSpace(req.Key),
+	}
+	applyCustomFieldRequest(definition, req)
+	if err := definition.Validate(); err != nil {
+		return nil, err
+	}
+
+	existing, err := u.repo.ListDefinitions(ctx, definition.EntityType)
+	if err != nil {
+		return nil, fmt.Errorf("error listing custom fields: %w", err)
+	}
+	for _, other := range existing {
+		if other.Key == definition.Key {
+			return nil, ErrCustomFieldExists
+		}
+	}
+
+	if err := u.repo.CreateDefinition(ctx, definition); err != nil {
+		return nil, fmt.Errorf("error creating custom field: %w", err)
+	}
+	return definition, nil
+}
+
+// UpdateDefinition changes the label, validation and position of a custom
+// field. Values entities already hold are checked again when they are next
+// saved.
+func (u *CustomFieldUseCase) UpdateDefinition(ctx context.Context, id uint, req *entity.CustomFieldDefinitionRequest) (*entity.CustomFieldDefinition, error) {
+	definition, err := u.GetDefinition(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if req.EntityType != definition.EntityType || strings.TrimSpace(req.Key) != definition.Key || req.Type != definition.Type {
+		return nil, ErrCustomFieldImmutable
+	}
+	applyCustomFieldRequest(definition, req)
+	if err := definition.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := u.repo.UpdateDefinition(ctx, definition); err != nil {
+		return nil, fmt.Errorf("error updating custom field: %w", err)
+	}
+	return definition, nil
+}
+
+// DeleteDefinition removes a custom field along with the values the entities
+// of its type hold for it
+func (u *CustomFieldUseCase) DeleteDefinition(ctx context.Context, id uint) error {
+	definition, err := u.GetDefinition(ctx, id)
+	if err != nil {
+		return err
+	}
+	err = u.repo.DeleteDefinition(ctx, definition)
+	if errors.Is(err, repository.ErrRecordNotFound) {
+		return ErrCustomFieldNotFound
+	}
+	return err
+}
+
+// CheckValues validates the custom field values of an entity of a type
+// against the fields defined on the type, returning them as they are kept.
+// Values of undefined fields are refused and null values are dropped.
+func (u *CustomFieldUseCase) CheckValues(ctx context.Context, entityType string, values entity.JSONMap) (entity.JSONMap, error) {
+	definitions, err := u.definitionsByKey(ctx, entityType)
+	if err != nil {
+		return nil, err
+	}
+
+	checked := make(entity.JSONMap, len(values))
+	for key, value := range values {
+		definition, ok := definitions[key]
+		if !ok {
+			return nil, entity.NewError(entity.ErrCodeInvalidArgument, fmt.Sprintf("unknown custom field %s", key))
+		}
+		if value == nil {
+			continue
+		}
+		if checked[key], err = definition.Check(value); err != nil {
+			return nil, err
+		}
+	}
+	for key, definition := range definitions {
+		if _, ok := checked[key]; !ok && definition.Required {
+			return nil, entity.NewError(entity.ErrCodeInvalidArgument, fmt.Sprintf("custom field %s is required", key))
+		}
+	}
+	return checked, nil
+}
+
+// ParseFilter turns the custom field values entities of a type are filtered
+// by, given as text, into the values they are kept as
+func (u *CustomFieldUseCase) ParseFilter(ctx context.Context, entityType string, values entity.JSONMap) (entity.JSONMap, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	definitions, err := u.definitionsByKey(ctx, entityType)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed := make(entity.JSONMap, len(values))
+	for key, value := range values {
+		definition, ok := definitions[key]
+		if !ok {
+			return nil, entity.NewError(entity.ErrCodeInvalidArgument, fmt.Sprintf("unknown custom field %s", key))
+		}
+		if parsed[key], err = definition.Check(value); err != nil {
+			return nil, err
+		}
+	}
+	return parsed, nil
+}
+
+func (u *CustomFieldUseCase) definitionsByKey(ctx context.Context, entityType string) (map[string]*entity.CustomFieldDefinition, error) {
+	definitions, err := u.repo.ListDefinitions(ctx, entityType)
+	if err != nil {
+		return nil, fmt.Errorf("error listing custom fields: %w", err)
+	}
+	byKey := make(map[string]*entity.CustomFieldDefinition, len(definitions))
+	for i := range definitions {
+		byKey[definitions[i].Key] = &definitions[i]
+	}
+	return byKey, nil
+}
+
+// applyCustomFieldRequest copies the settings of a request that may change
+// onto a definition
+func applyCustomFieldRequest(definition *entity.CustomFieldDefinition, req *entity.CustomFieldDefinitionRequest) {
+	definition.Type = req.Type
+	definition.Label = strings.TrimSpace(req.Label)
+	definition.Required = req.Required
+	definition.Options = req.Options
+	definition.Min = req.Min
+	definition.Max = req.Max
+	definition.MaxLength = req.MaxLength
+	definition.Pattern = req.Pattern
+	definition.Position = req.Position
+}
+
+// customFieldTable lays records of an entity type out for an export: a column
+// for each of their own fields followed by one for each custom field defined
+// on the type, whose values are read with values
+func customFieldTable[T any](ctx context.Context, u *CustomFieldUseCase, entityType string, records []T, values func(*T) entity.JSONMap) ([]export.Column, [][]interface{}, error) {
+	definitions, err := u.repo.ListDefinitions(ctx, entityType)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error listing custom fields: %w", err)
+	}
+
+	columns, rows := export.Table(records)
+	for _, definition := range definitions {
+		columns = append(columns, export.Column{Key: "custom_fields." + definition.Key, Title: definition.Label})
+	}
+	for i := range rows {
+		custom := values(&records[i])
+		for _, definition := range definitions {
+			rows[i] = append(rows[i], custom[definition.Key])
+		}
+	}
+	return columns, rows, nil
+}
@@ -0,0 +1,329 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
+)
+
+var (
+	ErrInvalidCustomsPeriod = entity.NewError(entity.ErrCodeInvalidArgument, "period must be a month in the form YYYY-MM")
+	ErrInvalidCustomsFlow   = entity.NewError(entity.ErrCodeInvalidArgument, "flow must be ARRIVAL or DISPATCH")
+	ErrInvalidCustomsRegime = entity.NewError(entity.ErrCodeInvalidArgument, "regime must be INTRASTAT or CUSTOMS")
+	ErrCustomsCountry       = entity.NewError(entity.ErrCodeFailedPrecondition, "the reporting country is not configured (customs.country)")
+)
+
+// CustomsUseCase reports the cross-border movements of goods for the
+// Intrastat and customs declarations
+type CustomsUseCase struct {
+	customsRepo *repository.CustomsRepository
+	skuRepo     *repository.SKURepository
+	currencyUC  *CurrencyUseCase
+	country     string
+	union       map[string]bool
+}
+
+// NewCustomsUseCase creates a new customs use case reporting for the given
+// country, trading within a customs union of the given member countries
+func NewCustomsUseCase(customsRepo *repository.CustomsRepository, skuRepo *repository.SKURepository, currencyUC *CurrencyUseCase, country string, unionCountries []string) *CustomsUseCase {
+	union := make(map[string]bool, len(unionCountries))
+	for _, member := range unionCountries {
+		union[normalizeCountry(member)] = true
+	}
+	return &CustomsUseCase{
+		customsRepo: customsRepo,
+		skuRepo:     skuRepo,
+		currencyUC:  currencyUC,
+		country:     normalizeCountry(country),
+		union:       union,
+	}
+}
+
+// customsMovement is a receipt or delivery line crossing the border
+type customsMovement struct {
+	flow     entity.CustomsFlow
+	document string
+	skuID    string
+	partner  string
+	quantity float64
+	value    float64 // base currency
+	data     entity.CustomsData
+}
+
+// Report aggregates the month's arrivals and dispatches with other countries
+// by flow, regime, commodity code, partner country and country of origin.
+// Arrivals are purchase receipts, the partner being the vendor's country;
+// dispatches are deliveries that left the warehouse, the partner being the
+// country of the customer's shipping address. Lines carry the customs data
+// their order recorded, completed from the SKU, and are valued at the order
+// price net of discount and tax, at the order rate. Kits are declared as the
+// sets they are shipped in. Movements within the reporting country are left
+// out; lines missing data are reported as incomplete.
+func (u *CustomsUseCase) Report(ctx context.Context, filter *entity.CustomsReportFilter) (*entity.CustomsReport, error) {
+	if u.country == "" {
+		return nil, ErrCustomsCountry
+	}
+	if filter.Flow != "" && filter.Flow != entity.CustomsFlowArrival && filter.Flow != entity.CustomsFlowDispatch {
+		return nil, ErrInvalidCustomsFlow
+	}
+	if filter.Regime != "" && filter.Regime != entity.CustomsRegimeIntrastat && filter.Regime != entity.CustomsRegimeCustoms {
+		return nil, ErrInvalidCustomsRegime
+	}
+
+	now := time.Now()
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location()).AddDate(0, -1, 0)
+	if filter.Period != "" {
+		period, err := time.ParseInLocation(periodLayout, filter.Period, now.Location())
+		if err != nil {
+			return nil, ErrInvalidCustomsPeriod
+		}
+		start = period
+	}
+	end := start.AddDate(0, 1, 0)
+
+	var movements []customsMovement
+	if filter.Flow != entity.CustomsFlowDispatch {
+		arrivals, err := u.customsRepo.ListArrivals(ctx, start, end)
+		if err != nil {
+			return nil, fmt.Errorf("error listing arrivals: %w", err)
+		}
+		for _, arrival := range arrivals {
+			movements = append(movements, arrivalMovements(arrival)...)
+		}
+	}
+	if filter.Flow != entity.CustomsFlowArrival {
+		dispatches, err := u.customsRepo.ListDispatches(ctx, start, end)
+		if err != nil {
+			return nil, fmt.Errorf("error listing dispatches: %w", err)
+		}
+		for _, dispatch := range dispatches {
+			movements = append(movements, dispatchMovements(dispatch)...)
+		}
+	}
+
+	skuIDs := make([]string, 0, len(movements))
+	for _, movement := range movements {
+		skuIDs = append(skuIDs, movement.skuID)
+	}
+	skuData, err := u.skuRepo.GetCustomsData(ctx, skuIDs)
+	if err != nil {
+		return nil, fmt.Errorf("error getting customs data: %w", err)
+	}
+
+	report := &entity.CustomsReport{
+		Period:           start.Format(periodLayout),
+		ReportingCountry: u.country,
+		BaseCurrency:     u.currencyUC.BaseCurrency(),
+		Lines:            []entity.CustomsReportLine{},
+		GeneratedAt:      now,
+	}
+	lines := make(map[entity.CustomsReportLine]*entity.CustomsReportLine)
+	for _, movement := range movements {
+		if movement.partner == u.country {
+			continue
+		}
+		movement.data.Fill(skuData[movement.skuID])
+
+		var missing []string
+		if movement.data.HSCode == "" {
+			missing = append(missing, "hs_code")
+		}
+		if movement.data.CountryOfOrigin == "" {
+			missing = append(missing, "country_of_origin")
+		}
+		if movement.data.NetWeight == 0 {
+			missing = append(missing, "net_weight")
+		}
+		if movement.partner == "" {
+			missing = append(missing, "partner_country")
+		}
+		if len(missing) > 0 {
+			report.Incomplete = append(report.Incomplete, entity.CustomsIncompleteLine{
+				Flow:     movement.flow,
+				Document: movement.document,
+				SKUID:    movement.skuID,
+				Missing:  missing,
+			})
+		}
+		// Without a partner country the movement may well be domestic
+		if movement.partner == "" {
+			continue
+		}
+
+		regime := entity.CustomsRegimeCustoms
+		if u.union[u.country] && u.union[movement.partner] {
+			regime = entity.CustomsRegimeIntrastat
+		}
+		if filter.Regime != "" && regime != filter.Regime {
+			continue
+		}
+
+		key := entity.CustomsReportLine{
+			Flow:            movement.flow,
+			Regime:          regime,
+			CommodityCode:   movement.data.HSCode,
+			PartnerCountry:  movement.partner,
+			CountryOfOrigin: movement.data.CountryOfOrigin,
+		}
+		line, ok := lines[key]
+		if !ok {
+			copied := key
+			line = &copied
+			lines[key] = line
+		}
+		line.Quantity += movement.quantity
+		line.NetMass += movement.quantity * movement.data.NetWeight
+		line.Value += movement.value
+		line.Movements++
+	}
+
+	for _, line := range lines {
+		line.NetMass = math.Round(line.NetMass*1000) / 1000
+		line.Value = roundAmount(line.Value)
+		report.Lines = append(report.Lines, *line)
+	}
+	sort.Slice(report.Lines, func(i, j int) bool {
+		a, b := report.Lines[i], report.Lines[j]
+		if a.Flow != b.Flow {
+			return a.Flow < b.Flow
+		}
+		if a.Regime != b.Regime {
+			return a.Regime < b.Regime
+		}
+		if a.CommodityCode != b.CommodityCode {
+			return a.CommodityCode < b.CommodityCode
+		}
+		if a.PartnerCountry != b.PartnerCountry {
+			return a.PartnerCountry < b.PartnerCountry
+		}
+		return a.CountryOfOrigin < b.CountryOfOrigin
+	})
+	return report, nil
+}
+
+// arrivalMovements returns the lines of a purchase receipt, valued at the
+// net price of their order lines
+func arrivalMovements(arrival entity.CustomsArrival) []customsMovement {
+	lines := make(map[string]entity.PurchaseOrderItem, len(arrival.OrderItems))
+	for _, item := range arrival.OrderItems {
+		if _, ok := lines[item.SKUID]; !ok {
+			lines[item.SKUID] = item
+		}
+	}
+
+	var movements []customsMovement
+	for _, item := range arrival.ReceiptItems {
+		if item.ReceivedQuantity <= 0 {
+			continue
+		}
+		movement := customsMovement{
+			flow:     entity.CustomsFlowArrival,
+			document: arrival.ReceiptNumber,
+			skuID:    item.SKUID,
+			partner:  normalizeCountry(arrival.Country),
+			quantity: item.ReceivedQuantity,
+		}
+		if line, ok := lines[item.SKUID]; ok {
+			movement.data = line.CustomsData
+		}
+		unitPrice, ok := netPurchasePrice(arrival.OrderItems, item.SKUID)
+		if !ok {
+			unitPrice = item.UnitPrice
+		}
+		movement.value = unitPrice * item.ReceivedQuantity * baseRate(arrival.ExchangeRate)
+		movements = append(movements, movement)
+	}
+	return movements
+}
+
+// dispatchMovements returns the lines of a delivery, valued at the net price
+// of their sales order lines. The components shipped for a kit are gathered
+// back into the kits they make up.
+func dispatchMovements(dispatch entity.CustomsDispatch) []customsMovement {
+	lines := make(map[string]entity.SalesOrderItem, len(dispatch.OrderItems))
+	for _, item := range dispatch.OrderItems {
+		if _, ok := lines[item.SKUID]; !ok {
+			lines[item.SKUID] = item
+		}
+	}
+
+	var movements []customsMovement
+	kits := make(map[string]float64)
+	var kitIDs []string
+	for _, item := range dispatch.DeliveryItems {
+		if item.ShippedQuantity <= 0 {
+			continue
+		}
+		if item.KitSKUID != "" {
+			kit := lines[item.KitSKUID]
+			for _, component := range kit.Components {
+				if component.SKUID != item.SKUID || component.Quantity <= 0 || kit.Quantity <= 0 {
+					continue
+				}
+				if _, ok := kits[item.KitSKUID]; !ok {
+					kitIDs = append(kitIDs, item.KitSKUID)
+				}
+				kits[item.KitSKUID] = math.Max(kits[item.KitSKUID], item.ShippedQuantity/(component.Quantity/kit.Quantity))
+			}
+			continue
+		}
+		movements = append(movements, dispatchMovement(dispatch, lines, item.SKUID, item.ShippedQuantity))
+	}
+	for _, kitID := range kitIDs {
+		movements = append(movements, dispatchMovement(dispatch, lines, kitID, kits[kitID]))
+	}
+	return movements
+}
+
+// dispatchMovement returns the movement of a quantity of a SKU shipped in a delivery
+func dispatchMovement(dispatch entity.CustomsDispatch, lines map[string]entity.SalesOrderItem, skuID string, quantity float64) customsMovement {
+	movement := customsMovement{
+		flow:     entity.CustomsFlowDispatch,
+		document: dispatch.DeliveryNumber,
+		skuID:    skuID,
+		partner:  normalizeCountry(dispatch.Country),
+		quantity: quantity,
+	}
+	if line, ok := lines[skuID]; ok {
+		movement.data = line.CustomsData
+	}
+	order := entity.SalesOrder{Items: dispatch.OrderItems, ExchangeRate: baseRate(dispatch.ExchangeRate)}
+	movement.value = netUnitPrice(order, skuID) * quantity
+	return movement
+}
+
+// netPurchasePrice returns the price net of discount and tax a SKU was
+// bought at on a purchase order, in the order currency
+func netPurchasePrice(items entity.PurchaseOrderItems, skuID string) (float64, bool) {
+	var value, quantity float64
+	for _, item := range items {
+		if item.SKUID == skuID {
+			value += item.TotalPrice - item.TaxAmount
+			quantity += item.Quantity
+		}
+	}
+	if quantity == 0 {
+		return 0, false
+	}
+	return value / quantity, true
+}
+
+// baseRate returns the rate an order converts to the base currency at,
+// orders recorded before rates were kept being in the base currency
+func baseRate(rate float64) float64 {
+	if rate <= 0 {
+		return 1
+	}
+	return rate
+}
+
+// normalizeCountry writes a country code in capitals without spaces
+func normalizeCountry(country string) string {
+	return strings.ToUpper(strings.TrimSpace(country))
+}
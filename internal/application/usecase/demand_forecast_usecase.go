@@ -0,0 +1,433 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
+)
+
+var (
+	ErrInvalidDemandModel     = entity.NewError(entity.ErrCodeInvalidArgument, "demand forecast model must be SMA, EWMA or SEASONAL_NAIVE")
+	ErrInvalidForecastAlpha   = entity.NewError(entity.ErrCodeInvalidArgument, "smoothing factor alpha must be greater than 0 and at most 1")
+	ErrDemandForecastNotFound = entity.NewError(entity.ErrCodeNotFound, "demand forecast version not found")
+	ErrNoActiveDemandForecast = entity.NewError(entity.ErrCodeFailedPrecondition, "no demand forecast version is active")
+)
+
+// Demand forecast and replenishment parameter defaults and limits
+const (
+	defaultDemandForecastAlpha = 0.3
+	defaultReplenishmentReview = 30
+	maxReplenishmentReview     = 365
+)
+
+// Replenishment policies by class: the review period of the SKUs of each ABC
+// class when none is requested, and the share of forecast demand over the
+// cover days kept as safety stock for each XYZ class
+var (
+	classReviewDays = map[entity.ABCClass]int{
+		entity.ABCClassA: 7,
+		entity.ABCClassB: 14,
+		entity.ABCClassC: defaultReplenishmentReview,
+	}
+	classSafetyShare = map[entity.XYZClass]float64{
+		entity.XYZClassX: 0,
+		entity.XYZClassY: 0.25,
+		entity.XYZClassZ: 0.5,
+	}
+)
+
+// DemandForecastUseCase forecasts monthly unit demand per SKU from sales history,
+// stores forecast versions and turns the active version into replenishment
+// suggestions and MRP demand
+type DemandForecastUseCase struct {
+	forecastRepo *repository.ForecastRepository
+	demandRepo   *repository.DemandForecastRepository
+	calendarUC   *CalendarUseCase
+}
+
+// NewDemandForecastUseCase creates a new demand forecast use case
+func NewDemandForecastUseCase(forecastRepo *repository.ForecastRepository, demandRepo *repository.DemandForecastRepository, calendarUC *CalendarUseCase) *DemandForecastUseCase {
+	return &DemandForecastUseCase{
+		forecastRepo: forecastRepo,
+		demandRepo:   demandRepo,
+		calendarUC:   calendarUC,
+	}
+}
+
+// Forecast forecasts the monthly quantity sold of each SKU over closed months of
+// sales history with the requested model
+func (u *DemandForecastUseCase) Forecast(ctx context.Context, req *entity.DemandForecastRequest) (*entity.DemandForecastResult, error) {
+	if err := normalizeDemandForecastRequest(req); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	current := monthStart(now)
+	historyStart := current.AddDate(0, -req.HistoryMonths, 0)
+
+	orders, err := u.forecastRepo.ListSalesHistory(ctx, historyStart, current)
+	if err != nil {
+		return nil, fmt.Errorf("error getting sales history: %w", err)
+	}
+
+	only := make(map[string]bool, len(req.SKUIDs))
+	for _, skuID := range req.SKUIDs {
+		only[skuID] = true
+	}
+	demand := make(map[string]map[string]float64)
+	for _, order := range orders {
+		period := order.OrderDate.In(time.Local).Format(periodLayout)
+		for _, item := range order.Items {
+			if len(only) > 0 && !only[item.SKUID] {
+				continue
+			}
+			if demand[item.SKUID] == nil {
+				demand[item.SKUID] = make(map[string]float64)
+			}
+			demand[item.SKUID][period] += item.Quantity
+		}
+	}
+
+	months := make([]time.Time, req.HistoryMonths)
+	for i := range months {
+		months[i] = historyStart.AddDate(0, i, 0)
+	}
+	result := &entity.DemandForecastResult{
+		Model:       req.Model,
+		Periods:     make([]string, req.Horizon),
+		Lines:       []entity.DemandForecastLine{},
+		Totals:      make(map[string]float64, req.Horizon),
+		GeneratedAt: now,
+	}
+	switch req.Model {
+	case entity.DemandModelSMA:
+		result.Window = req.Window
+	case entity.DemandModelEWMA:
+		result.Alpha = req.Alpha
+	}
+	targets := make([]time.Time, req.Horizon)
+	for i := range targets {
+		targets[i] = current.AddDate(0, i, 0)
+		result.Periods[i] = targets[i].Format(periodLayout)
+		result.Totals[result.Periods[i]] = 0
+	}
+
+	skuIDs := make([]string, 0, len(demand))
+	for skuID := range demand {
+		skuIDs = append(skuIDs, skuID)
+	}
+	sort.Strings(skuIDs)
+
+	for _, skuID := range skuIDs {
+		// The series starts with the SKU's first sale so that months before it
+		// was sold do not drag its forecast down
+		first := 0
+		for first < len(months) && demand[skuID][months[first].Format(periodLayout)] == 0 {
+			first++
+		}
+		series := make([]float64, 0, len(months)-first)
+		for _, month := range months[first:] {
+			series = append(series, demand[skuID][month.Format(periodLayout)])
+		}
+
+		values := forecastDemandSeries(req, series, months[first:], targets)
+		for i, period := range result.Periods {
+			result.Lines = append(result.Lines, entity.DemandForecastLine{
+				SKUID:    skuID,
+				Period:   period,
+				Quantity: values[i],
+			})
+			result.Totals[period] = roundAmount(result.Totals[period] + values[i])
+		}
+	}
+	return result, nil
+}
+
+// StoreVersion forecasts and stores the result as a new demand forecast version,
+// optionally making it the active version
+func (u *DemandForecastUseCase) StoreVersion(ctx context.Context, req *entity.DemandForecastVersionRequest, userID *uint) (*entity.DemandForecastVersion, error) {
+	result, err := u.Forecast(ctx, &req.DemandForecastRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	version := &entity.DemandForecastVersion{
+		Model:         result.Model,
+		Window:        result.Window,
+		Alpha:         result.Alpha,
+		HistoryMonths: req.HistoryMonths,
+		FirstPeriod:   result.Periods[0],
+		LastPeriod:    result.Periods[len(result.Periods)-1],
+		Notes:         req.Notes,
+		Active:        req.Activate,
+		CreatedBy:     userID,
+		Lines:         result.Lines,
+	}
+	if err := u.demandRepo.CreateVersion(ctx, version); err != nil {
+		return nil, fmt.Errorf("error storing demand forecast version: %w", err)
+	}
+	return version, nil
+}
+
+// ListVersions lists the stored demand forecast versions, newest first
+func (u *DemandForecastUseCase) ListVersions(ctx context.Context) ([]entity.DemandForecastVersion, error) {
+	versions, err := u.demandRepo.ListVersions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error listing demand forecast versions: %w", err)
+	}
+	return versions, nil
+}
+
+// GetVersion retrieves a demand forecast version with its lines
+func (u *DemandForecastUseCase) GetVersion(ctx context.Context, id uint) (*entity.DemandForecastVersion, error) {
+	version, err := u.demandRepo.GetVersion(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, ErrDemandForecastNotFound
+		}
+		return nil, fmt.Errorf("error getting demand forecast version: %w", err)
+	}
+	return version, nil
+}
+
+// ActivateVersion makes a stored version the one replenishment and MRP use
+func (u *DemandForecastUseCase) ActivateVersion(ctx context.Context, id uint) (*entity.DemandForecastVersion, error) {
+	if err := u.demandRepo.ActivateVersion(ctx, id); err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, ErrDemandForecastNotFound
+		}
+		return nil, fmt.Errorf("error activating demand forecast version: %w", err)
+	}
+	return u.GetVersion(ctx, id)
+}
+
+// ForecastDemand returns the forecast quantity of a SKU for a YYYY-MM period in
+// the active version, or zero when no version is active
+func (u *DemandForecastUseCase) ForecastDemand(ctx context.Context, skuID, period string) (float64, error) {
+	version, err := u.demandRepo.GetActiveVersion(ctx)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("error getting active demand forecast: %w", err)
+	}
+
+	lines, err := u.demandRepo.ListVersionLines(ctx, version.ID, skuID, period, period)
+	if err != nil {
+		return 0, fmt.Errorf("error getting demand forecast lines: %w", err)
+	}
+	var quantity float64
+	for _, line := range lines {
+		quantity += line.Quantity
+	}
+	return quantity, nil
+}
+
+// Replenishment suggests order quantities from the active version for the SKUs
+// whose available stock, in one store or across all stores, does not cover
+// forecast demand until a delivery ordered today arrives plus the review period,
+// and the safety stock of their XYZ class. Delivery takes the designated
+// vendor's lead time in business days on the company calendar. Without a
+// requested review period, each SKU is reviewed by its ABC class.
+func (u *DemandForecastUseCase) Replenishment(ctx context.Context, storeID string, reviewDays int) (*entity.ReplenishmentPlan, error) {
+	if reviewDays < 0 {
+		reviewDays = 0
+	}
+	if reviewDays > maxReplenishmentReview {
+		reviewDays = maxReplenishmentReview
+	}
+
+	version, err := u.demandRepo.GetActiveVersion(ctx)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, ErrNoActiveDemandForecast
+		}
+		return nil, fmt.Errorf("error getting active demand forecast: %w", err)
+	}
+
+	now := time.Now()
+	lines, err := u.demandRepo.ListVersionLines(ctx, version.ID, "", now.Format(periodLayout), version.LastPeriod)
+	if err != nil {
+		return nil, fmt.Errorf("error getting demand forecast lines: %w", err)
+	}
+	forecast := make(map[string]map[string]float64)
+	for _, line := range lines {
+		if forecast[line.SKUID] == nil {
+			forecast[line.SKUID] = make(map[string]float64)
+		}
+		forecast[line.SKUID][line.Period] += line.Quantity
+	}
+	skuIDs := make([]string, 0, len(forecast))
+	for skuID := range forecast {
+		skuIDs = append(skuIDs, skuID)
+	}
+
+	supply, err := u.demandRepo.GetSKUSupply(ctx, skuIDs)
+	if err != nil {
+		return nil, fmt.Errorf("error getting SKU lead times: %w", err)
+	}
+	available, err := u.demandRepo.GetAvailableStock(ctx, skuIDs, storeID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting available stock: %w", err)
+	}
+	calendar, err := u.calendarUC.ForStore(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &entity.ReplenishmentPlan{
+		VersionID:   version.ID,
+		StoreID:     storeID,
+		ReviewDays:  reviewDays,
+		Lines:       []entity.ReplenishmentLine{},
+		GeneratedAt: now,
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	for _, skuID := range skuIDs {
+		sku, ok := supply[skuID]
+		if !ok {
+			continue
+		}
+
+		review := reviewDays
+		if review == 0 {
+			review = defaultReplenishmentReview
+			if days, ok := classReviewDays[sku.ABCClass]; ok {
+				review = days
+			}
+		}
+		arrival := calendar.AddBusinessDays(now, sku.LeadTimeDays)
+		arrivalDay := time.Date(arrival.Year(), arrival.Month(), arrival.Day(), 0, 0, 0, 0, time.Local)
+		coverDays := int(math.Round(arrivalDay.Sub(today).Hours()/24)) + review
+
+		// Spread each month's forecast evenly over its days
+		var demand float64
+		for day := 0; day < coverDays; day++ {
+			date := today.AddDate(0, 0, day)
+			daysInMonth := monthStart(date).AddDate(0, 1, -1).Day()
+			demand += forecast[skuID][date.Format(periodLayout)] / float64(daysInMonth)
+		}
+
+		safety := demand * classSafetyShare[sku.XYZClass]
+		suggested := math.Ceil(roundAmount(demand + safety - available[skuID]))
+		if suggested <= 0 {
+			continue
+		}
+		plan.Lines = append(plan.Lines, entity.ReplenishmentLine{
+			SKUID:             skuID,
+			SKUCode:           sku.SKUCode,
+			Name:              sku.Name,
+			ABCClass:          sku.ABCClass,
+			XYZClass:          sku.XYZClass,
+			VendorID:          sku.VendorID,
+			LeadTimeDays:      sku.LeadTimeDays,
+			CoverDays:         coverDays,
+			DailyDemand:       roundAmount(demand / float64(coverDays)),
+			ForecastDemand:    roundAmount(demand),
+			SafetyStock:       roundAmount(safety),
+			AvailableQuantity: roundAmount(available[skuID]),
+			SuggestedQuantity: suggested,
+		})
+	}
+	sort.Slice(plan.Lines, func(i, j int) bool { return plan.Lines[i].SKUCode < plan.Lines[j].SKUCode })
+	return plan, nil
+}
+
+// normalizeDemandForecastRequest validates the demand forecast model and applies
+// the parameter defaults and limits
+func normalizeDemandForecastRequest(req *entity.DemandForecastRequest) error {
+	if req.Model == "" {
+		req.Model = entity.DemandModelSMA
+	}
+	switch req.Model {
+	case entity.DemandModelSMA, entity.DemandModelEWMA, entity.DemandModelSeasonalNaive:
+	default:
+		return ErrInvalidDemandModel
+	}
+	if req.Alpha == 0 {
+		req.Alpha = defaultDemandForecastAlpha
+	}
+	if req.Alpha < 0 || req.Alpha > 1 {
+		return ErrInvalidForecastAlpha
+	}
+	if req.Horizon <= 0 {
+		req.Horizon = defaultForecastHorizon
+	}
+	if req.Horizon > maxForecastHorizon {
+		req.Horizon = maxForecastHorizon
+	}
+	if req.Window <= 0 {
+		req.Window = defaultForecastWindow
+	}
+	if req.Window > maxForecastWindow {
+		req.Window = maxForecastWindow
+	}
+	if req.HistoryMonths <= 0 {
+		req.HistoryMonths = defaultForecastHistory
+	}
+	if req.HistoryMonths > maxForecastHistory {
+		req.HistoryMonths = maxForecastHistory
+	}
+	if req.HistoryMonths < req.Window {
+		req.HistoryMonths = req.Window
+	}
+	// Seasonal naive looks a year back
+	if req.Model == entity.DemandModelSeasonalNaive && req.HistoryMonths < 12 {
+		req.HistoryMonths = 12
+	}
+	return nil
+}
+
+// forecastDemandSeries forecasts the target months of a monthly demand series
+func forecastDemandSeries(req *entity.DemandForecastRequest, series []float64, months, targets []time.Time) []float64 {
+	values := make([]float64, len(targets))
+	if len(series) == 0 {
+		return values
+	}
+
+	switch req.Model {
+	case entity.DemandModelSeasonalNaive:
+		// Each target repeats the same month of the latest year with history
+		byPeriod := make(map[string]float64, len(series))
+		for i, month := range months {
+			byPeriod[month.Format(periodLayout)] = series[i]
+		}
+		for i, target := range targets {
+			for back := target.AddDate(-1, 0, 0); !back.Before(months[0]); back = back.AddDate(-1, 0, 0) {
+				if value, ok := byPeriod[back.Format(periodLayout)]; ok {
+					values[i] = roundAmount(value)
+					break
+				}
+			}
+		}
+		return values
+
+	case entity.DemandModelEWMA:
+		level := series[0]
+		for _, value := range series[1:] {
+			level = req.Alpha*value + (1-req.Alpha)*level
+		}
+		for i := range values {
+			values[i] = roundAmount(level)
+		}
+		return values
+
+	default:
+		var sum float64
+		used := 0
+		for i := len(series) - 1; i >= 0 && used < req.Window; i-- {
+			sum += series[i]
+			used++
+		}
+		for i := range values {
+			values[i] = roundAmount(sum / float64(used))
+		}
+		return values
+	}
+}
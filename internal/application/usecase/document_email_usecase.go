@@ -0,0 +1,345 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/export"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/filestore"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/logging"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/notification"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
+)
+
+var ErrNoEmailAddress = entity.NewError(entity.ErrCodeFailedPrecondition, "no email address to send the document to")
+
+// maxEmailAttachmentBytes caps the report exports attached to emails; larger
+// ones are only linked
+const maxEmailAttachmentBytes = 10 << 20
+
+// documentEmailPayload is the payload of email.document jobs
+type documentEmailPayload struct {
+	Type entity.NotificationType `json:"type"` // one of entity.DocumentEmailTypes
+	ID   string                  `json:"id"`   // ID of the purchase order, sales invoice or report
+	To   string                  `json:"to"`
+}
+
+// DocumentEmailUseCase emails documents to the parties outside the company:
+// purchase orders to their vendors, sales invoices to their customers and
+// scheduled reports to their recipients. Each email is sent by a job, so a
+// failed delivery is retried, with the document rendered as a PDF or, for
+// reports, the export attached.
+type DocumentEmailUseCase struct {
+	purchaseRepo   *repository.PurchaseRepository
+	orderRepo      *repository.OrderRepository
+	reportRepo     *repository.ReportRepository
+	skuRepo        *repository.SKURepository
+	userRepo       *repository.UserRepository
+	brandingUC     *BrandingUseCase
+	notificationUC *NotificationUseCase
+	jobUC          *JobUseCase
+	files          filestore.Store
+	fileURLTTL     time.Duration // how long the links to emailed reports stay valid
+}
+
+// NewDocumentEmailUseCase creates a new document email use case
+func NewDocumentEmailUseCase(
+	purchaseRepo *repository.PurchaseRepository,
+	orderRepo *repository.OrderRepository,
+	reportRepo *repository.ReportRepository,
+	skuRepo *repository.SKURepository,
+	userRepo *repository.UserRepository,
+	brandingUC *BrandingUseCase,
+	notificationUC *NotificationUseCase,
+	jobUC *JobUseCase,
+	files filestore.Store,
+	fileURLTTL time.Duration,
+) *DocumentEmailUseCase {
+	u := &DocumentEmailUseCase{
+		purchaseRepo:   purchaseRepo,
+		orderRepo:      orderRepo,
+		reportRepo:     reportRepo,
+		skuRepo:        skuRepo,
+		userRepo:       userRepo,
+		brandingUC:     brandingUC,
+		notificationUC: notificationUC,
+		jobUC:          jobUC,
+		files:          files,
+		fileURLTTL:     fileURLTTL,
+	}
+	jobUC.Register(entity.JobEmailDocument, u.runEmailJob)
+	return u
+}
+
+// QueuePurchaseOrder queues emailing a purchase order to its vendor
+func (u *DocumentEmailUseCase) QueuePurchaseOrder(ctx context.Context, order *entity.PurchaseOrder) (*entity.Job, error) {
+	vendor := order.Vendor
+	if vendor == nil || vendor.Email == "" {
+		return nil, ErrNoEmailAddress
+	}
+	return u.jobUC.Enqueue(ctx, entity.JobEmailDocument, documentEmailPayload{
+		Type: entity.DocumentEmailPurchaseOrder,
+		ID:   order.ID,
+		To:   vendor.Email,
+	}, nil)
+}
+
+// QueueInvoice queues emailing a sales invoice to the customer of its order
+func (u *DocumentEmailUseCase) QueueInvoice(ctx context.Context, invoice *entity.Invoice) (*entity.Job, error) {
+	if invoice.SalesOrder == nil {
+		return nil, ErrNoEmailAddress
+	}
+	customer, err := u.userRepo.FindByID(invoice.SalesOrder.ClientID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting customer: %w", err)
+	}
+	if customer.Email == "" {
+		return nil, ErrNoEmailAddress
+	}
+	return u.jobUC.Enqueue(ctx, entity.JobEmailDocument, documentEmailPayload{
+		Type: entity.DocumentEmailInvoice,
+		ID:   invoice.ID,
+		To:   customer.Email,
+	}, nil)
+}
+
+// QueueReport queues emailing a report's export to each of the recipients,
+// in a job per recipient so that a failed address does not resend to the
+// others
+func (u *DocumentEmailUseCase) QueueReport(ctx context.Context, report *entity.Report, recipients []string, userID *uint) error {
+	for _, to := range recipients {
+		if to == "" {
+			continue
+		}
+		if _, err := u.jobUC.Enqueue(ctx, entity.JobEmailDocument, documentEmailPayload{
+			Type: entity.DocumentEmailReport,
+			ID:   report.ID,
+			To:   to,
+		}, userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runEmailJob renders and sends the document of an email.document job
+func (u *DocumentEmailUseCase) runEmailJob(ctx context.Context, job *entity.Job, progress func(int)) (interface{}, error) {
+	var payload documentEmailPayload
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return nil, permanent(fmt.Errorf("invalid job payload: %w", err))
+	}
+
+	data := map[string]string{}
+	if branding, err := u.brandingUC.Get(ctx); err == nil {
+		data["company_name"] = branding.CompanyName
+	}
+
+	var attachments []notification.Attachment
+	var err error
+	switch payload.Type {
+	case entity.DocumentEmailPurchaseOrder:
+		attachments, err = u.purchaseOrderEmail(ctx, payload.ID, data)
+	case entity.DocumentEmailInvoice:
+		attachments, err = u.invoiceEmail(ctx, payload.ID, data)
+	case entity.DocumentEmailReport:
+		attachments, err = u.reportEmail(ctx, payload.ID, data)
+	default:
+		return nil, permanent(fmt.Errorf("unknown document email type %q", payload.Type))
+	}
+	if errors.Is(err, repository.ErrRecordNotFound) {
+		return nil, permanent(fmt.Errorf("document %s not found", payload.ID))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.notificationUC.SendEmail(ctx, payload.Type, payload.To, data, attachments); err != nil {
+		if errors.Is(err, ErrEmailNotConfigured) {
+			return nil, permanent(err)
+		}
+		return nil, err
+	}
+	return map[string]string{"type": string(payload.Type), "id": payload.ID, "to": payload.To}, nil
+}
+
+// purchaseOrderEmail fills in the template data of a purchase order email and
+// renders the order as a PDF
+func (u *DocumentEmailUseCase) purchaseOrderEmail(ctx context.Context, id string, data map[string]string) ([]notification.Attachment, error) {
+	order, err := u.purchaseRepo.GetPurchaseOrderByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	vendorName := ""
+	if order.Vendor != nil {
+		vendorName = order.Vendor.Name
+	}
+	data["order_number"] = order.OrderNumber
+	data["vendor_name"] = vendorName
+	data["order_date"] = order.OrderDate.Format("2006-01-02")
+	data["expected_date"] = order.ExpectedDate.Format("2006-01-02")
+	data["grand_total"] = strconv.FormatFloat(order.GrandTotal, 'f', 2, 64)
+	data["currency"] = order.CurrencyCode
+
+	lines := make([]documentLine, len(order.Items))
+	for i, item := range order.Items {
+		lines[i] = documentLine{item.SKUID, item.Description, item.Quantity, item.UnitPrice, item.Discount, item.TaxAmount, item.TotalPrice}
+	}
+	pdf, err := u.documentPDF(ctx, &export.Document{
+		Title:    "Purchase Order " + order.OrderNumber,
+		Subtitle: fmt.Sprintf("Vendor: %s - Order date: %s - Expected: %s", vendorName, data["order_date"], data["expected_date"]),
+	}, lines, order.CurrencyCode, order.SubTotal, order.DiscountTotal, order.TaxTotal, order.GrandTotal)
+	if err != nil {
+		return nil, err
+	}
+	return []notification.Attachment{{Name: "purchase-order-" + fileSlug(order.OrderNumber) + ".pdf", ContentType: "application/pdf", Data: pdf}}, nil
+}
+
+// invoiceEmail fills in the template data of a sales invoice email and
+// renders the invoice, with the lines of its order, as a PDF
+func (u *DocumentEmailUseCase) invoiceEmail(ctx context.Context, id string, data map[string]string) ([]notification.Attachment, error) {
+	invoice, err := u.orderRepo.GetInvoiceByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	customerName := ""
+	var lines []documentLine
+	if order := invoice.SalesOrder; order != nil {
+		if customer, err := u.userRepo.FindByID(order.ClientID); err == nil {
+			customerName = customer.Username
+		}
+		for _, item := range order.Items {
+			lines = append(lines, documentLine{item.SKUID, item.Description, item.Quantity, item.UnitPrice, item.Discount, item.TaxAmount, item.TotalPrice})
+		}
+	}
+	data["invoice_number"] = invoice.InvoiceNumber
+	data["customer_name"] = customerName
+	data["issue_date"] = invoice.IssueDate.Format("2006-01-02")
+	data["due_date"] = invoice.DueDate.Format("2006-01-02")
+	data["total_amount"] = strconv.FormatFloat(invoice.TotalAmount, 'f', 2, 64)
+	data["currency"] = invoice.CurrencyCode
+
+	pdf, err := u.documentPDF(ctx, &export.Document{
+		Title:    "Invoice " + invoice.InvoiceNumber,
+		Subtitle: fmt.Sprintf("Customer: %s - Issued: %s - Due: %s", customerName, data["issue_date"], data["due_date"]),
+	}, lines, invoice.CurrencyCode, invoice.Amount, 0, invoice.TaxAmount, invoice.TotalAmount)
+	if err != nil {
+		return nil, err
+	}
+	return []notification.Attachment{{Name: "invoice-" + fileSlug(invoice.InvoiceNumber) + ".pdf", ContentType: "application/pdf", Data: pdf}}, nil
+}
+
+// reportEmail fills in the template data of a report email and attaches the
+// report's export, unless it is too large to attach
+func (u *DocumentEmailUseCase) reportEmail(ctx context.Context, id string, data map[string]string) ([]notification.Attachment, error) {
+	report, err := u.reportRepo.GetReportByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if report.FileKey == "" {
+		return nil, permanent(fmt.Errorf("report %s has not been exported", report.ID))
+	}
+	fileURL, err := u.files.URL(ctx, report.FileKey, u.fileURLTTL)
+	if err != nil {
+		return nil, fmt.Errorf("error signing export URL: %w", err)
+	}
+	data["report_name"] = report.Name
+	data["period"] = report.StartDate.Format("2006-01-02") + " - " + report.EndDate.Format("2006-01-02")
+	data["file_url"] = fileURL
+
+	object, err := u.files.Open(ctx, report.FileKey)
+	if err != nil {
+		if errors.Is(err, filestore.ErrNotFound) {
+			return nil, permanent(fmt.Errorf("export of report %s not found", report.ID))
+		}
+		return nil, fmt.Errorf("error opening export: %w", err)
+	}
+	defer object.Body.Close()
+	if object.Size > maxEmailAttachmentBytes {
+		logging.FromContext(ctx).Info("report export too large to attach, sending its link only", "report_id", report.ID, "bytes", object.Size)
+		return nil, nil
+	}
+	content, err := io.ReadAll(object.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading export: %w", err)
+	}
+	return []notification.Attachment{{Name: path.Base(report.FileKey), ContentType: object.ContentType, Data: content}}, nil
+}
+
+// documentLine is a line of a purchase order or invoice PDF
+type documentLine struct {
+	skuID       string
+	description string
+	quantity    float64
+	unitPrice   float64
+	discount    float64
+	tax         float64
+	total       float64
+}
+
+// documentPDF renders the lines of an order or invoice as a PDF table, headed
+// by the company branding and followed by the totals
+func (u *DocumentEmailUseCase) documentPDF(ctx context.Context, doc *export.Document, lines []documentLine, currency string, subTotal, discount, tax, total float64) ([]byte, error) {
+	doc.Columns = []export.Column{
+		{Key: "sku", Title: "SKU"},
+		{Key: "description", Title: "Description"},
+		{Key: "quantity", Title: "Quantity"},
+		{Key: "unit_price", Title: "Unit Price"},
+		{Key: "discount", Title: "Discount"},
+		{Key: "tax", Title: "Tax"},
+		{Key: "total", Title: "Total (" + currency + ")"},
+	}
+	if branding, err := u.brandingUC.Document(ctx, ""); err == nil {
+		if branding.CompanyName != "" {
+			doc.Header = append(doc.Header, branding.CompanyName)
+		}
+		doc.Header = append(doc.Header, branding.AddressBlock...)
+		doc.Footer = append(append([]string{}, branding.FooterLines...), branding.BankDetails...)
+	}
+
+	var buf bytes.Buffer
+	w, err := export.NewWriter(export.FormatPDF, &buf, doc)
+	if err != nil {
+		return nil, err
+	}
+	skus := make(map[string]*entity.SKU)
+	for _, line := range lines {
+		code, name := line.skuID, line.description
+		sku, ok := skus[line.skuID]
+		if !ok {
+			sku, _ = u.skuRepo.GetSKUByID(ctx, line.skuID)
+			skus[line.skuID] = sku
+		}
+		if sku != nil {
+			code = sku.SKUCode
+			if name == "" {
+				name = sku.Name
+			}
+		}
+		if err := w.WriteRow([]interface{}{code, name, line.quantity, line.unitPrice, line.discount, line.tax, line.total}); err != nil {
+			return nil, err
+		}
+	}
+	for _, summary := range []struct {
+		label  string
+		amount float64
+	}{{"Subtotal", subTotal}, {"Discount", discount}, {"Tax", tax}, {"Total", total}} {
+		if summary.amount == 0 && summary.label != "Total" {
+			continue
+		}
+		if err := w.WriteRow([]interface{}{nil, nil, nil, nil, nil, summary.label, summary.amount}); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,189 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
+)
+
+var (
+	ErrNotDropShipOrder  = entity.NewError(entity.ErrCodeFailedPrecondition, "purchase order is not a drop-ship order")
+	ErrDropShipNotPlaced = entity.NewError(entity.ErrCodeFailedPrecondition, "only sent or confirmed drop-ship orders can be shipped")
+)
+
+// DropShipUseCase fulfills the drop-ship lines of sales orders with purchase
+// orders the vendors ship straight to the customers
+type DropShipUseCase struct {
+	orderRepo    *repository.OrderRepository
+	purchaseRepo *repository.PurchaseRepository
+	orderUC      *OrderUseCase
+	purchaseUC   *PurchaseUseCase
+}
+
+// NewDropShipUseCase creates a new drop-ship use case
+func NewDropShipUseCase(orderRepo *repository.OrderRepository, purchaseRepo *repository.PurchaseRepository, orderUC *OrderUseCase, purchaseUC *PurchaseUseCase) *DropShipUseCase {
+	return &DropShipUseCase{
+		orderRepo:    orderRepo,
+		purchaseRepo: purchaseRepo,
+		orderUC:      orderUC,
+		purchaseUC:   purchaseUC,
+	}
+}
+
+// CreatePurchaseOrders generates a draft purchase order to each vendor for
+// the drop-ship lines of a confirmed sales order not ordered yet, shipping
+// to the customer's address, and returns every drop-ship order of the sales
+// order
+func (u *DropShipUseCase) CreatePurchaseOrders(ctx context.Context, salesOrderID string, userID uint) ([]entity.PurchaseOrder, error) {
+	order, err := u.orderRepo.GetSalesOrderByID(ctx, salesOrderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != entity.SalesOrderStatusConfirmed && order.Status != entity.SalesOrderStatusProcessing {
+		return nil, ErrInvalidOrderStatus
+	}
+
+	lines := make(map[uint][]int)
+	for i, item := range order.Items {
+		if item.DropShip && item.PurchaseOrderID == "" && item.VendorID != nil {
+			lines[*item.VendorID] = append(lines[*item.VendorID], i)
+		}
+	}
+	vendorIDs := make([]uint, 0, len(lines))
+	for vendorID := range lines {
+		vendorIDs = append(vendorIDs, vendorID)
+	}
+	sort.Slice(vendorIDs, func(i, j int) bool { return vendorIDs[i] < vendorIDs[j] })
+
+	for _, vendorID := range vendorIDs {
+		purchase := &entity.PurchaseOrder{
+			VendorID:        vendorID,
+			CurrencyCode:    order.CurrencyCode,
+			ShippingAddress: order.ShippingAddress,
+			SalesOrderID:    &order.ID,
+			Notes:           fmt.Sprintf("Drop-ship for sales order %s", order.OrderNumber),
+			CreatedByID:     userID,
+		}
+		for _, i := range lines[vendorID] {
+			item := order.Items[i]
+			total := item.PurchasePrice * item.Quantity
+			purchase.Items = append(purchase.Items, entity.PurchaseOrderItem{
+				SKUID:       item.SKUID,
+				Quantity:    item.Quantity,
+				UnitPrice:   item.PurchasePrice,
+				TotalPrice:  total,
+				Description: item.Description,
+			})
+			purchase.SubTotal += total
+		}
+		purchase.GrandTotal = purchase.SubTotal
+		if err := u.purchaseUC.CreatePurchaseOrder(ctx, purchase); err != nil {
+			return nil, fmt.Errorf("error creating drop-ship order to vendor %d: %w", vendorID, err)
+		}
+
+		// Link the lines as each order is placed, so a failure further on does
+		// not order them twice
+		for _, i := range lines[vendorID] {
+			order.Items[i].PurchaseOrderID = purchase.ID
+		}
+		if err := u.orderRepo.UpdateSalesOrder(ctx, order); err != nil {
+			return nil, err
+		}
+	}
+
+	return u.ListPurchaseOrders(ctx, order.ID)
+}
+
+// ListPurchaseOrders retrieves the drop-ship orders of a sales order
+func (u *DropShipUseCase) ListPurchaseOrders(ctx context.Context, salesOrderID string) ([]entity.PurchaseOrder, error) {
+	return u.purchaseRepo.ListPurchaseOrdersBySalesOrderID(ctx, salesOrderID)
+}
+
+// ConfirmShipment records that the vendor of a drop-ship order shipped it to
+// the customer: a delivery of its items is completed for the sales order
+// without moving any stock, and the purchase order counts as received. The
+// sales order is delivered once every drop-ship order has shipped, when it
+// has no lines shipped from a store.
+func (u *DropShipUseCase) ConfirmShipment(ctx context.Context, purchaseOrderID string, req *entity.DropShipShipmentRequest, userID uint) (*entity.DeliveryOrder, error) {
+	purchase, err := u.purchaseRepo.GetPurchaseOrderByID(ctx, purchaseOrderID)
+	if err != nil {
+		return nil, err
+	}
+	if purchase.SalesOrderID == nil {
+		return nil, ErrNotDropShipOrder
+	}
+	if purchase.Status != entity.PurchaseOrderStatusSent && purchase.Status != entity.PurchaseOrderStatusConfirmed {
+		return nil, ErrDropShipNotPlaced
+	}
+	order, err := u.orderRepo.GetSalesOrderByID(ctx, *purchase.SalesOrderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status == entity.SalesOrderStatusCancelled {
+		return nil, ErrInvalidOrderStatus
+	}
+
+	now := time.Now()
+	delivery := &entity.DeliveryOrder{
+		SalesOrderID:    order.ID,
+		DeliveryDate:    now,
+		ShippedAt:       &now,
+		ShippingAddress: purchase.ShippingAddress,
+		Status:          entity.DeliveryOrderStatusDelivered,
+		TrackingNumber:  req.TrackingNumber,
+		ShippingMethod:  req.ShippingMethod,
+		PurchaseOrderID: &purchase.ID,
+		Notes:           req.Notes,
+		CreatedByID:     userID,
+	}
+	if delivery.ShippingAddress == "" {
+		delivery.ShippingAddress = order.ShippingAddress
+	}
+	for _, item := range purchase.Items {
+		delivery.Items = append(delivery.Items, entity.DeliveryOrderItem{
+			SKUID:           item.SKUID,
+			OrderedQuantity: item.Quantity,
+			ShippedQuantity: item.Quantity,
+		})
+	}
+	if err := u.orderRepo.CreateDeliveryOrder(ctx, delivery); err != nil {
+		return nil, err
+	}
+
+	purchase.Status = entity.PurchaseOrderStatusReceived
+	if err := u.purchaseRepo.UpdatePurchaseOrder(ctx, purchase); err != nil {
+		return nil, err
+	}
+
+	status := entity.SalesOrderStatusProcessing
+	if u.dropShipped(order, purchase.ID) {
+		status = entity.SalesOrderStatusDelivered
+	}
+	if order.Status == entity.SalesOrderStatusConfirmed || status == entity.SalesOrderStatusDelivered {
+		if err := u.orderUC.setSalesOrderStatus(ctx, order, status); err != nil {
+			return nil, err
+		}
+	}
+	return delivery, nil
+}
+
+// dropShipped reports whether every line of a sales order is drop-shipped
+// and has shipped, the purchase order just shipped included
+func (u *DropShipUseCase) dropShipped(order *entity.SalesOrder, shippedID string) bool {
+	shipped := map[string]bool{shippedID: true}
+	for _, delivery := range order.DeliveryOrders {
+		if delivery.PurchaseOrderID != nil && delivery.Status == entity.DeliveryOrderStatusDelivered {
+			shipped[*delivery.PurchaseOrderID] = true
+		}
+	}
+	for _, item := range order.Items {
+		if !item.DropShip || !shipped[item.PurchaseOrderID] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,243 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/eventbus"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
+)
+
+var (
+	ErrDunningLevelNotFound  = entity.NewError(entity.ErrCodeNotFound, "dunning level not found")
+	ErrDuplicateDunningLevel = entity.NewError(entity.ErrCodeConflict, "a dunning level already exists for that many days overdue")
+)
+
+// defaultDunningTemplate is used by dunning levels without a template of their own
+const defaultDunningTemplate = "Invoice {{invoice_number}} for {{entity_name}} was due on {{due_date}} and is {{days_overdue}} days overdue. " +
+	"The outstanding amount is {{amount_due}} {{currency}}."
+
+// DunningUseCase chases overdue sales invoices through configurable reminder levels
+type DunningUseCase struct {
+	dunningRepo *repository.DunningRepository
+	brandingUC  *BrandingUseCase
+	bus         *eventbus.Bus
+}
+
+// NewDunningUseCase creates a new dunning use case
+func NewDunningUseCase(dunningRepo *repository.DunningRepository, brandingUC *BrandingUseCase, bus *eventbus.Bus) *DunningUseCase {
+	return &DunningUseCase{
+		dunningRepo: dunningRepo,
+		brandingUC:  brandingUC,
+		bus:         bus,
+	}
+}
+
+// CreateLevel creates a dunning level
+func (u *DunningUseCase) CreateLevel(ctx context.Context, req *entity.CreateDunningLevelRequest) (*entity.DunningLevel, error) {
+	if err := u.checkDaysOverdue(ctx, 0, req.DaysOverdue); err != nil {
+		return nil, err
+	}
+
+	level := &entity.DunningLevel{
+		Name:        req.Name,
+		DaysOverdue: req.DaysOverdue,
+		Template:    req.Template,
+		Escalation:  req.Escalation,
+		Active:      true,
+	}
+	if level.Escalation == "" {
+		level.Escalation = entity.DunningEscalationNone
+	}
+
+	if err := u.dunningRepo.CreateLevel(ctx, level); err != nil {
+		return nil, fmt.Errorf("error creating dunning level: %w", err)
+	}
+	return level, nil
+}
+
+// ListLevels lists all dunning levels in escalation order
+func (u *DunningUseCase) ListLevels(ctx context.Context) ([]entity.DunningLevel, error) {
+	levels, err := u.dunningRepo.ListLevels(ctx, false)
+	if err != nil {
+		return nil, fmt.Errorf("error listing dunning levels: %w", err)
+	}
+	return levels, nil
+}
+
+// UpdateLevel updates a dunning level. Deactivated levels are no longer sent.
+func (u *DunningUseCase) UpdateLevel(ctx context.Context, id uint, req *entity.UpdateDunningLevelRequest) (*entity.DunningLevel, error) {
+	level, err := u.dunningRepo.GetLevel(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, ErrDunningLevelNotFound
+		}
+		return nil, fmt.Errorf("error getting dunning level: %w", err)
+	}
+
+	if req.Name != "" {
+		level.Name = req.Name
+	}
+	if req.DaysOverdue != nil && *req.DaysOverdue != level.DaysOverdue {
+		if err := u.checkDaysOverdue(ctx, level.ID, *req.DaysOverdue); err != nil {
+			return nil, err
+		}
+		level.DaysOverdue = *req.DaysOverdue
+	}
+	if req.Template != nil {
+		level.Template = *req.Template
+	}
+	if req.Escalation != "" {
+		level.Escalation = req.Escalation
+	}
+	if req.Active != nil {
+		level.Active = *req.Active
+	}
+
+	if err := u.dunningRepo.UpdateLevel(ctx, level); err != nil {
+		return nil, fmt.Errorf("error updating dunning level: %w", err)
+	}
+	return level, nil
+}
+
+// ListReminders lists the reminder history
+func (u *DunningUseCase) ListReminders(ctx context.Context, filter *entity.DunningReminderFilter) ([]entity.DunningReminder, int64, error) {
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+	if filter.PageSize <= 0 {
+		filter.PageSize = 20
+	}
+
+	reminders, total, err := u.dunningRepo.ListReminders(ctx, filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error listing dunning reminders: %w", err)
+	}
+	return reminders, total, nil
+}
+
+// Run flags the sales invoices overdue at the given time and sends each the
+// highest active dunning level it has reached, unless a reminder was already
+// sent at or beyond that level. Levels passed between runs are not sent late.
+// Reminders are dispatched by the consumers of the reminder sent event.
+func (u *DunningUseCase) Run(ctx context.Context, asOf time.Time, userID *uint) (*entity.DunningRunResult, error) {
+	result := &entity.DunningRunResult{AsOf: asOf, Reminders: []entity.DunningReminder{}}
+
+	invoices, err := u.dunningRepo.ListOverdueInvoices(ctx, truncateDay(asOf))
+	if err != nil {
+		return nil, fmt.Errorf("error listing overdue invoices: %w", err)
+	}
+	result.Overdue = len(invoices)
+	if len(invoices) == 0 {
+		return result, nil
+	}
+
+	invoiceIDs := make([]int64, len(invoices))
+	for i, invoice := range invoices {
+		invoiceIDs[i] = invoice.ID
+	}
+	flagged, err := u.dunningRepo.FlagOverdue(ctx, invoiceIDs)
+	if err != nil {
+		return nil, fmt.Errorf("error flagging overdue invoices: %w", err)
+	}
+	result.Flagged = int(flagged)
+
+	levels, err := u.dunningRepo.ListLevels(ctx, true)
+	if err != nil {
+		return nil, fmt.Errorf("error listing dunning levels: %w", err)
+	}
+	if len(levels) == 0 {
+		return result, nil
+	}
+
+	branding, err := u.brandingUC.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sent, err := u.dunningRepo.ListSentReminders(ctx, invoiceIDs)
+	if err != nil {
+		return nil, fmt.Errorf("error listing sent reminders: %w", err)
+	}
+	// How many days overdue each invoice was at its latest reminder. Any level at
+	// or below that has already been covered.
+	reached := make(map[int64]int, len(sent))
+	for _, reminder := range sent {
+		if reminder.DaysOverdue > reached[reminder.InvoiceID] {
+			reached[reminder.InvoiceID] = reminder.DaysOverdue
+		}
+	}
+
+	for _, invoice := range invoices {
+		days := daysBetween(invoice.DueDate, asOf)
+
+		var level *entity.DunningLevel
+		for i := range levels {
+			if levels[i].DaysOverdue <= days {
+				level = &levels[i]
+			}
+		}
+		if level == nil || reached[invoice.ID] >= level.DaysOverdue {
+			continue
+		}
+
+		reminder := entity.DunningReminder{
+			InvoiceID:     invoice.ID,
+			InvoiceNumber: invoice.InvoiceNumber,
+			EntityID:      invoice.EntityID,
+			EntityName:    invoice.EntityName,
+			LevelID:       level.ID,
+			LevelName:     level.Name,
+			DaysOverdue:   days,
+			AmountDue:     invoice.AmountDue,
+			CurrencyCode:  invoice.CurrencyCode,
+			Escalation:    level.Escalation,
+			Message:       renderDunningMessage(level, &invoice, days, branding),
+			SentAt:        asOf,
+			SentBy:        userID,
+		}
+		if err := u.dunningRepo.CreateReminder(ctx, &reminder); err != nil {
+			return nil, fmt.Errorf("error recording dunning reminder for invoice %s: %w", invoice.InvoiceNumber, err)
+		}
+		u.bus.Publish(ctx, entity.DunningReminderSent{Reminder: &reminder})
+		result.Reminders = append(result.Reminders, reminder)
+	}
+	return result, nil
+}
+
+// checkDaysOverdue rejects a days overdue value already used by another level
+func (u *DunningUseCase) checkDaysOverdue(ctx context.Context, levelID uint, daysOverdue int) error {
+	levels, err := u.dunningRepo.ListLevels(ctx, false)
+	if err != nil {
+		return fmt.Errorf("error listing dunning levels: %w", err)
+	}
+	for _, level := range levels {
+		if level.DaysOverdue == daysOverdue && level.ID != levelID {
+			return ErrDuplicateDunningLevel
+		}
+	}
+	return nil
+}
+
+// renderDunningMessage fills in a dunning level's template for an invoice and
+// the company branding
+func renderDunningMessage(level *entity.DunningLevel, invoice *entity.FinanceInvoice, daysOverdue int, branding *entity.Branding) string {
+	template := level.Template
+	if template == "" {
+		template = defaultDunningTemplate
+	}
+	placeholders := append([]string{
+		"{{invoice_number}}", invoice.InvoiceNumber,
+		"{{entity_name}}", invoice.EntityName,
+		"{{amount_due}}", strconv.FormatFloat(invoice.AmountDue, 'f', 2, 64),
+		"{{currency}}", invoice.CurrencyCode,
+		"{{due_date}}", invoice.DueDate.Format("2006-01-02"),
+		"{{days_overdue}}", strconv.Itoa(daysOverdue),
+	}, brandingPlaceholders(branding)...)
+	return strings.NewReplacer(placeholders...).Replace(template)
+}
@@ -0,0 +1,576 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/edi"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
+)
+
+var (
+	ErrTradingPartnerNotFound = entity.NewError(entity.ErrCodeNotFound, "trading partner not found")
+	ErrTradingPartnerExists   = entity.NewError(entity.ErrCodeConflict, "vendor is already set up as a trading partner")
+	ErrEDIVendorNotFound      = entity.NewError(entity.ErrCodeNotFound, "vendor not found")
+	ErrEDIDocumentNotFound    = entity.NewError(entity.ErrCodeNotFound, "EDI document not found")
+	ErrEDINoPartner           = entity.NewError(entity.ErrCodeFailedPrecondition, "vendor is not set up as an active trading partner")
+	ErrEDIUnknownSender       = entity.NewError(entity.ErrCodeNotFound, "interchange sender is not an active trading partner")
+	ErrEDIOrderStatus         = entity.NewError(entity.ErrCodeFailedPrecondition, "only approved, sent or confirmed purchase orders can be translated to EDI")
+	ErrEDINotDeliverable      = entity.NewError(entity.ErrCodeFailedPrecondition, "only generated outbound documents can be marked delivered")
+	ErrEDINotReceivable       = entity.NewError(entity.ErrCodeFailedPrecondition, "only applied ship notices not yet received can be received")
+)
+
+// ediTotalTolerance is how far the total an invoice states may be from the
+// total of its lines, absorbing the rounding of the vendor's system
+const ediTotalTolerance = 0.05
+
+// EDIUseCase exchanges X12 documents with the vendors set up as trading
+// partners: purchase orders go out as 850s, and the 856 ship notices and 810
+// invoices coming back are archived and mapped into the purchase entities
+type EDIUseCase struct {
+	repo         *repository.EDIRepository
+	purchaseRepo *repository.PurchaseRepository
+	vendorRepo   *repository.VendorRepository
+	skuRepo      *repository.SKURepository
+	purchaseUC   *PurchaseUseCase
+	financeUC    *FinanceUseCase
+}
+
+// NewEDIUseCase creates a new EDI use case
+func NewEDIUseCase(repo *repository.EDIRepository, purchaseRepo *repository.PurchaseRepository, vendorRepo *repository.VendorRepository, skuRepo *repository.SKURepository, purchaseUC *PurchaseUseCase, financeUC *FinanceUseCase) *EDIUseCase {
+	return &EDIUseCase{
+		repo:         repo,
+		purchaseRepo: purchaseRepo,
+		vendorRepo:   vendorRepo,
+		skuRepo:      skuRepo,
+		purchaseUC:   purchaseUC,
+		financeUC:    financeUC,
+	}
+}
+
+// CreatePartner sets up a vendor as a trading partner
+func (u *EDIUseCase) CreatePartner(ctx context.Context, req *entity.TradingPartnerRequest) (*entity.TradingPartner, error) {
+	partner := &entity.TradingPartner{SendPurchaseOrders: true, Active: true}
+	if err := u.applyPartnerRequest(ctx, partner, req); err != nil {
+		return nil, err
+	}
+	if err := u.repo.CreatePartner(ctx, partner); err != nil {
+		if errors.Is(err, repository.ErrDuplicateEntry) {
+			return nil, ErrTradingPartnerExists
+		}
+		return nil, fmt.Errorf("error creating trading partner: %w", err)
+	}
+	return u.GetPartner(ctx, partner.ID)
+}
+
+// UpdatePartner updates the configuration of a trading partner
+func (u *EDIUseCase) UpdatePartner(ctx context.Context, id uint, req *entity.TradingPartnerRequest) (*entity.TradingPartner, error) {
+	partner, err := u.GetPartner(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := u.applyPartnerRequest(ctx, partner, req); err != nil {
+		return nil, err
+	}
+	if err := u.repo.UpdatePartner(ctx, partner); err != nil {
+		if errors.Is(err, repository.ErrDuplicateEntry) {
+			return nil, ErrTradingPartnerExists
+		}
+		return nil, fmt.Errorf("error updating trading partner: %w", err)
+	}
+	return u.GetPartner(ctx, id)
+}
+
+func (u *EDIUseCase) applyPartnerRequest(ctx context.Context, partner *entity.TradingPartner, req *entity.TradingPartnerRequest) error {
+	if _, err := u.vendorRepo.FindByID(ctx, req.VendorID); err != nil {
+		return ErrEDIVendorNotFound
+	}
+
+	partner.VendorID = req.VendorID
+	partner.ISAQualifier = strings.TrimSpace(req.ISAQualifier)
+	partner.ISAID = strings.TrimSpace(req.ISAID)
+	partner.GSID = strings.TrimSpace(req.GSID)
+	partner.OurISAQualifier = strings.TrimSpace(req.OurISAQualifier)
+	partner.OurISAID = strings.TrimSpace(req.OurISAID)
+	partner.OurGSID = strings.TrimSpace(req.OurGSID)
+	partner.Test = req.Test
+	if req.SendPurchaseOrders != nil {
+		partner.SendPurchaseOrders = *req.SendPurchaseOrders
+	}
+	if req.Active != nil {
+		partner.Active = *req.Active
+	}
+	partner.Vendor = nil
+	return nil
+}
+
+// GetPartner retrieves a trading partner
+func (u *EDIUseCase) GetPartner(ctx context.Context, id uint) (*entity.TradingPartner, error) {
+	partner, err := u.repo.GetPartner(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, ErrTradingPartnerNotFound
+		}
+		return nil, fmt.Errorf("error getting trading partner: %w", err)
+	}
+	return partner, nil
+}
+
+// ListPartners lists the trading partners
+func (u *EDIUseCase) ListPartners(ctx context.Context) ([]entity.TradingPartner, error) {
+	return u.repo.ListPartners(ctx)
+}
+
+// GetDocument retrieves an archived EDI document
+func (u *EDIUseCase) GetDocument(ctx context.Context, id uint) (*entity.EDIDocument, error) {
+	document, err := u.repo.GetDocument(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, ErrEDIDocumentNotFound
+		}
+		return nil, fmt.Errorf("error getting EDI document: %w", err)
+	}
+	return document, nil
+}
+
+// ListDocuments lists the archived EDI documents
+func (u *EDIUseCase) ListDocuments(ctx context.Context, filter *entity.EDIDocumentFilter, page, pageSize int) ([]entity.EDIDocument, int64, error) {
+	return u.repo.ListDocuments(ctx, filter, page, pageSize)
+}
+
+// GeneratePurchaseOrder translates a purchase order to an 850 interchange for
+// the trading partner of its vendor and archives it until it is delivered
+func (u *EDIUseCase) GeneratePurchaseOrder(ctx context.Context, orderID string) (*entity.EDIDocument, error) {
+	order, err := u.purchaseRepo.GetPurchaseOrderByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	switch order.Status {
+	case entity.PurchaseOrderStatusApproved, entity.PurchaseOrderStatusSent, entity.PurchaseOrderStatusConfirmed:
+	default:
+		return nil, ErrEDIOrderStatus
+	}
+
+	partner, err := u.repo.GetPartnerByVendor(ctx, order.VendorID)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, ErrEDINoPartner
+		}
+		return nil, err
+	}
+	if !partner.Active {
+		return nil, ErrEDINoPartner
+	}
+	return u.generatePurchaseOrder(ctx, partner, order)
+}
+
+// GenerateSentPurchaseOrder generates the 850 of a purchase order sent to a
+// vendor set up to receive them. Orders of other vendors are left alone.
+func (u *EDIUseCase) GenerateSentPurchaseOrder(ctx context.Context, order *entity.PurchaseOrder) error {
+	partner, err := u.repo.GetPartnerByVendor(ctx, order.VendorID)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if !partner.Active || !partner.SendPurchaseOrders {
+		return nil
+	}
+	_, err = u.generatePurchaseOrder(ctx, partner, order)
+	return err
+}
+
+func (u *EDIUseCase) generatePurchaseOrder(ctx context.Context, partner *entity.TradingPartner, order *entity.PurchaseOrder) (*entity.EDIDocument, error) {
+	if order.Vendor == nil {
+		order.Vendor = partner.Vendor
+	}
+	skuIDs := make([]string, len(order.Items))
+	for i, item := range order.Items {
+		skuIDs[i] = item.SKUID
+	}
+	skus, err := u.skuRepo.GetSKUsByIDs(ctx, skuIDs)
+	if err != nil {
+		return nil, err
+	}
+	skuCodes := make(map[string]string, len(skus))
+	for _, sku := range skus {
+		skuCodes[sku.ID] = sku.SKUCode
+	}
+
+	control, err := u.repo.NextControlNumber(ctx, partner.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error numbering interchange: %w", err)
+	}
+	tx := edi.PurchaseOrder(order, skuCodes)
+	content, err := edi.Encode(&edi.Interchange{
+		SenderQualifier:   partner.OurISAQualifier,
+		SenderID:          partner.OurISAID,
+		ReceiverQualifier: partner.ISAQualifier,
+		ReceiverID:        partner.ISAID,
+		SenderGS:          partner.OurGSID,
+		ReceiverGS:        partner.GSID,
+		ControlNumber:     control,
+		Date:              time.Now(),
+		Test:              partner.Test,
+		Transactions:      []edi.Transaction{tx},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	document := &entity.EDIDocument{
+		PartnerID:       partner.ID,
+		Direction:       entity.EDIOutbound,
+		Type:            entity.EDITypePurchaseOrder,
+		ControlNumber:   fmt.Sprintf("%09d", control),
+		Reference:       order.OrderNumber,
+		PurchaseOrderID: &order.ID,
+		Content:         string(content),
+		Status:          entity.EDIDocumentGenerated,
+	}
+	if err := u.repo.CreateDocument(ctx, document); err != nil {
+		return nil, fmt.Errorf("error archiving EDI document: %w", err)
+	}
+	return document, nil
+}
+
+// MarkDelivered records that an outbound document reached its partner
+func (u *EDIUseCase) MarkDelivered(ctx context.Context, id uint) (*entity.EDIDocument, error) {
+	document, err := u.GetDocument(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if document.Direction != entity.EDIOutbound || document.Status != entity.EDIDocumentGenerated {
+		return nil, ErrEDINotDeliverable
+	}
+	document.Status = entity.EDIDocumentDelivered
+	if err := u.repo.UpdateDocument(ctx, document); err != nil {
+		return nil, fmt.Errorf("error updating EDI document: %w", err)
+	}
+	return document, nil
+}
+
+// ReceiveInterchange archives the transaction sets of an inbound interchange
+// and maps them into the purchase entities: ship notices confirm and date
+// their purchase order, and invoices raise purchase invoices. A transaction
+// set that cannot be mapped is archived as failed with the reason, without
+// holding back the others.
+func (u *EDIUseCase) ReceiveInterchange(ctx context.Context, content []byte, userID int64) (*entity.EDIInboundResult, error) {
+	ic, err := edi.Parse(content)
+	if err != nil {
+		return nil, entity.WrapError(entity.ErrCodeInvalidArgument, err)
+	}
+	partner, err := u.repo.GetPartnerBySender(ctx, ic.SenderQualifier, ic.SenderID)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, ErrEDIUnknownSender
+		}
+		return nil, err
+	}
+
+	result := &entity.EDIInboundResult{Documents: []entity.EDIDocument{}}
+	for _, tx := range ic.Transactions {
+		document := &entity.EDIDocument{
+			PartnerID:     partner.ID,
+			Direction:     entity.EDIInbound,
+			Type:          tx.Type,
+			ControlNumber: tx.ControlNumber,
+			Content:       string(content),
+		}
+
+		switch tx.Type {
+		case entity.EDITypeShipNotice:
+			err = u.applyShipNotice(ctx, partner, document, tx)
+		case entity.EDITypeInvoice:
+			err = u.applyInvoice(ctx, partner, document, tx, userID)
+		default:
+			err = fmt.Errorf("%w: unsupported inbound type %q", edi.ErrTransaction, tx.Type)
+		}
+		if err != nil {
+			document.Status = entity.EDIDocumentFailed
+			document.Error = err.Error()
+		}
+
+		if document.ID == 0 {
+			err = u.repo.CreateDocument(ctx, document)
+		} else if document.Status == entity.EDIDocumentFailed {
+			err = u.repo.UpdateDocument(ctx, document)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error archiving EDI document: %w", err)
+		}
+		result.Documents = append(result.Documents, *document)
+	}
+	return result, nil
+}
+
+// applyShipNotice maps an 856 to the purchase order it ships: the order is
+// confirmed when it was only sent, and expected on the delivery date of the
+// notice. Its goods are received with ReceiveShipNotice.
+func (u *EDIUseCase) applyShipNotice(ctx context.Context, partner *entity.TradingPartner, document *entity.EDIDocument, tx edi.Transaction) error {
+	notice, err := edi.ShipNotice(tx)
+	if err != nil {
+		return err
+	}
+	document.Reference = notice.ShipmentID
+	if document.Data, err = json.Marshal(notice); err != nil {
+		return err
+	}
+	if duplicate, err := u.markDuplicate(ctx, document); duplicate || err != nil {
+		return err
+	}
+
+	order, err := u.partnerOrder(ctx, partner, notice.PurchaseOrderNumber)
+	if err != nil {
+		return err
+	}
+	document.PurchaseOrderID = &order.ID
+	switch order.Status {
+	case entity.PurchaseOrderStatusSent, entity.PurchaseOrderStatusConfirmed, entity.PurchaseOrderStatusPartial:
+	default:
+		return fmt.Errorf("purchase order %s is %s and cannot be shipped", order.OrderNumber, order.Status)
+	}
+
+	codes := make([]string, len(notice.Lines))
+	for i, line := range notice.Lines {
+		codes[i] = line.SKUCode
+	}
+	if _, err := u.orderLines(ctx, order, codes); err != nil {
+		return err
+	}
+
+	if order.Status == entity.PurchaseOrderStatusSent {
+		order.Status = entity.PurchaseOrderStatusConfirmed
+	}
+	if notice.DeliveryDate != nil {
+		order.ExpectedDate = *notice.DeliveryDate
+	}
+	if err := u.purchaseRepo.UpdatePurchaseOrder(ctx, order); err != nil {
+		return fmt.Errorf("error updating purchase order: %w", err)
+	}
+	document.Status = entity.EDIDocumentApplied
+	return nil
+}
+
+// ReceiveShipNotice posts a purchase receipt of the goods an applied ship
+// notice shipped into a store
+func (u *EDIUseCase) ReceiveShipNotice(ctx context.Context, id uint, req *entity.EDIReceiveRequest, userID uint) (*entity.PurchaseReceipt, error) {
+	document, err := u.GetDocument(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if document.Type != entity.EDITypeShipNotice || document.Status != entity.EDIDocumentApplied ||
+		document.PurchaseOrderID == nil || document.ReceiptID != nil {
+		return nil, ErrEDINotReceivable
+	}
+
+	var notice entity.EDIShipNotice
+	if err := json.Unmarshal(document.Data, &notice); err != nil {
+		return nil, fmt.Errorf("error reading ship notice: %w", err)
+	}
+	order, err := u.purchaseRepo.GetPurchaseOrderByID(ctx, *document.PurchaseOrderID)
+	if err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, len(notice.Lines))
+	for i, line := range notice.Lines {
+		codes[i] = line.SKUCode
+	}
+	lines, err := u.orderLines(ctx, order, codes)
+	if err != nil {
+		return nil, entity.WrapError(entity.ErrCodeFailedPrecondition, err)
+	}
+
+	notes := "EDI ship notice " + notice.ShipmentID
+	if req.Notes != "" {
+		notes += ": " + req.Notes
+	}
+	receipt := &entity.PurchaseReceipt{
+		PurchaseOrderID: order.ID,
+		StoreID:         req.StoreID,
+		ReceivedByID:    userID,
+		Notes:           notes,
+	}
+	for i, line := range notice.Lines {
+		item := lines[i]
+		receipt.Items = append(receipt.Items, entity.PurchaseReceiptItem{
+			SKUID:            item.SKUID,
+			OrderedQuantity:  item.Quantity,
+			ReceivedQuantity: line.Quantity,
+			UnitPrice:        item.UnitPrice,
+			TotalPrice:       line.Quantity * item.UnitPrice,
+		})
+	}
+	if err := u.purchaseUC.CreatePurchaseReceipt(ctx, receipt, strconv.FormatUint(uint64(userID), 10)); err != nil {
+		return nil, err
+	}
+
+	document.ReceiptID = &receipt.ID
+	if err := u.repo.UpdateDocument(ctx, document); err != nil {
+		return nil, fmt.Errorf("error updating EDI document: %w", err)
+	}
+	return receipt, nil
+}
+
+// applyInvoice maps an 810 to a pending purchase invoice of the purchase
+// order it bills. The invoice must add up to the total the vendor states.
+func (u *EDIUseCase) applyInvoice(ctx context.Context, partner *entity.TradingPartner, document *entity.EDIDocument, tx edi.Transaction, userID int64) error {
+	parsed, err := edi.Invoice(tx)
+	if err != nil {
+		return err
+	}
+	document.Reference = parsed.InvoiceNumber
+	if document.Data, err = json.Marshal(parsed); err != nil {
+		return err
+	}
+	if duplicate, err := u.markDuplicate(ctx, document); duplicate || err != nil {
+		return err
+	}
+
+	order, err := u.partnerOrder(ctx, partner, parsed.PurchaseOrderNumber)
+	if err != nil {
+		return err
+	}
+	document.PurchaseOrderID = &order.ID
+
+	codes := make([]string, len(parsed.Lines))
+	for i, line := range parsed.Lines {
+		codes[i] = line.SKUCode
+	}
+	if _, err := u.orderLines(ctx, order, codes); err != nil {
+		return err
+	}
+
+	// The tax of the invoice is spread over its lines at the rate it makes up
+	// of their subtotal
+	var subtotal float64
+	for _, line := range parsed.Lines {
+		subtotal += line.Quantity * line.UnitPrice
+	}
+	var taxRate float64
+	if subtotal > 0 {
+		taxRate = parsed.TaxAmount / subtotal * 100
+	}
+	items := make(entity.FinanceInvoiceItems, len(parsed.Lines))
+	for i, line := range parsed.Lines {
+		name := line.SKUCode
+		if line.Description != "" {
+			name += " " + line.Description
+		}
+		items[i] = entity.FinanceInvoiceItem{
+			ProductName: name,
+			Quantity:    line.Quantity,
+			UnitPrice:   line.UnitPrice,
+			TaxRate:     taxRate,
+		}
+	}
+
+	paymentDays := parsed.NetDays
+	if paymentDays <= 0 && order.Vendor != nil {
+		paymentDays = order.Vendor.PaymentDays
+	}
+	if paymentDays <= 0 {
+		paymentDays = defaultPaymentTermsDays
+	}
+	currency := parsed.CurrencyCode
+	if currency == "" {
+		currency = order.CurrencyCode
+	}
+	issueDate := truncateDay(parsed.InvoiceDate)
+	invoice, err := u.financeUC.newInvoice(ctx, &entity.CreateFinanceInvoiceRequest{
+		Type:           entity.FinancePurchaseInvoice,
+		ReferenceID:    order.ID,
+		EntityID:       int64(order.VendorID),
+		EntityType:     "SUPPLIER",
+		IssueDate:      issueDate,
+		DueDate:        issueDate.AddDate(0, 0, paymentDays),
+		Items:          items,
+		DiscountAmount: parsed.Allowance,
+		CurrencyCode:   currency,
+		Notes:          "EDI invoice " + parsed.InvoiceNumber,
+	}, userID)
+	if err != nil {
+		return err
+	}
+	if math.Abs(invoice.Total-parsed.Total) > ediTotalTolerance {
+		return fmt.Errorf("invoice %s states a total of %.2f but its lines add up to %.2f", parsed.InvoiceNumber, parsed.Total, invoice.Total)
+	}
+	if order.Vendor != nil {
+		invoice.EntityName = order.Vendor.Name
+	}
+	invoice.Status = entity.FinanceInvoicePending
+
+	if err := u.repo.CreateInvoice(ctx, document, invoice); err != nil {
+		return fmt.Errorf("error raising purchase invoice: %w", err)
+	}
+	return nil
+}
+
+// markDuplicate marks an inbound document as a duplicate when one of the same
+// type and reference was already applied for its partner
+func (u *EDIUseCase) markDuplicate(ctx context.Context, document *entity.EDIDocument) (bool, error) {
+	applied, err := u.repo.HasApplied(ctx, document.PartnerID, document.Type, document.Reference)
+	if err != nil {
+		return false, err
+	}
+	if applied {
+		document.Status = entity.EDIDocumentDuplicate
+	}
+	return applied, nil
+}
+
+// partnerOrder retrieves a purchase order by number, checking it was placed
+// with the partner's vendor
+func (u *EDIUseCase) partnerOrder(ctx context.Context, partner *entity.TradingPartner, orderNumber string) (*entity.PurchaseOrder, error) {
+	order, err := u.purchaseRepo.GetPurchaseOrderByNumber(ctx, orderNumber)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, fmt.Errorf("purchase order %s not found", orderNumber)
+		}
+		return nil, err
+	}
+	if order.VendorID != partner.VendorID {
+		return nil, fmt.Errorf("purchase order %s was not placed with the partner", orderNumber)
+	}
+	return order, nil
+}
+
+// orderLines returns the purchase order line of each SKU code, in order
+func (u *EDIUseCase) orderLines(ctx context.Context, order *entity.PurchaseOrder, codes []string) ([]entity.PurchaseOrderItem, error) {
+	skus, err := u.skuRepo.GetSKUsBySKUCodes(ctx, codes)
+	if err != nil {
+		return nil, err
+	}
+	skuIDs := make(map[string]string, len(skus))
+	for _, sku := range skus {
+		skuIDs[sku.SKUCode] = sku.ID
+	}
+	items := make(map[string]entity.PurchaseOrderItem, len(order.Items))
+	for _, item := range order.Items {
+		items[item.SKUID] = item
+	}
+
+	lines := make([]entity.PurchaseOrderItem, len(codes))
+	for i, code := range codes {
+		skuID, ok := skuIDs[code]
+		if !ok {
+			// Orders generated before the SKU had a code name the SKU ID
+			skuID = code
+		}
+		item, ok := items[skuID]
+		if !ok {
+			return nil, fmt.Errorf("item %s is not on purchase order %s", code, order.OrderNumber)
+		}
+		lines[i] = item
+	}
+	return lines, nil
+}
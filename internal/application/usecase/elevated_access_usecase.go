@@ -0,0 +1,309 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/cache"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/eventbus"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
+)
+
+var (
+	ErrElevationNotFound      = entity.NewError(entity.ErrCodeNotFound, "elevated access grant not found")
+	ErrElevationNotPending    = entity.NewError(entity.ErrCodeFailedPrecondition, "elevated access grant is not pending")
+	ErrElevationNotActive     = entity.NewError(entity.ErrCodeFailedPrecondition, "elevated access grant is not active")
+	ErrElevationTooLong       = entity.NewError(entity.ErrCodeInvalidArgument, "elevated access duration exceeds the maximum")
+	ErrElevationSelfReview    = entity.NewError(entity.ErrCodePermissionDenied, "elevated access cannot be approved or rejected by its requester")
+	ErrElevationPermission    = entity.NewError(entity.ErrCodeInvalidArgument, "invalid or non-grantable permission requested")
+	ErrElevationRevokeDenied  = entity.NewError(entity.ErrCodePermissionDenied, "only an approver or the grantee can revoke elevated access")
+	ErrInvalidElevationPeriod = entity.NewError(entity.ErrCodeInvalidArgument, "review period end must not be before its start")
+)
+
+// ElevatedAccessUseCase handles temporary ("break-glass") permission grants:
+// requests, approval, expiry and the review of what was done with them
+type ElevatedAccessUseCase struct {
+	repo       *repository.ElevatedAccessRepository
+	bus        *eventbus.Bus
+	maxHours   int
+	grantCache *cache.Namespace // the active grants of each user
+}
+
+// NewElevatedAccessUseCase creates a new elevated access use case. Grants cannot
+// be requested for longer than maxHours.
+func NewElevatedAccessUseCase(repo *repository.ElevatedAccessRepository, bus *eventbus.Bus, maxHours int, c *cache.Cache) *ElevatedAccessUseCase {
+	if maxHours <= 0 {
+		maxHours = 72
+	}
+	return &ElevatedAccessUseCase{
+		repo:       repo,
+		bus:        bus,
+		maxHours:   maxHours,
+		grantCache: c.Namespace(cache.NamespacePermissions),
+	}
+}
+
+// RequestElevation creates a pending grant of the given permissions to a user
+func (u *ElevatedAccessUseCase) RequestElevation(ctx context.Context, userID uint, req *entity.ElevationRequest) (*entity.ElevatedAccessGrant, error) {
+	if req.DurationHours > u.maxHours {
+		return nil, fmt.Errorf("%w of %d hours", ErrElevationTooLong, u.maxHours)
+	}
+
+	seen := make(map[entity.Permission]bool, len(req.Permissions))
+	permissions := make(entity.GormPermissionSlice, 0, len(req.Permissions))
+	for _, p := range req.Permissions {
+		p = entity.Permission(strings.TrimSpace(string(p)))
+		if !strings.Contains(string(p), ":") || p == entity.AccessElevationApprove || p == entity.UserImpersonate {
+			return nil, fmt.Errorf("%w: %q", ErrElevationPermission, p)
+		}
+		if !seen[p] {
+			seen[p] = true
+			permissions = append(permissions, p)
+		}
+	}
+
+	grant := &entity.ElevatedAccessGrant{
+		UserID:        userID,
+		Permissions:   permissions,
+		Reason:        strings.TrimSpace(req.Reason),
+		DurationHours: req.DurationHours,
+		Status:        entity.ElevationPending,
+		RequestedAt:   time.Now(),
+	}
+	if err := u.repo.CreateGrant(ctx, grant); err != nil {
+		return nil, fmt.Errorf("error creating elevated access grant: %w", err)
+	}
+
+	u.bus.Publish(ctx, entity.ApprovalRequested{
+		ApprovalPendingEvent: entity.ApprovalPendingEvent{
+			Document:    entity.ApprovalElevatedAccess,
+			DocumentID:  strconv.FormatUint(uint64(grant.ID), 10),
+			RequestedBy: grant.UserID,
+		},
+		Approver: entity.AccessElevationApprove,
+		Record:   grant,
+	})
+	return grant, nil
+}
+
+// ApproveElevation activates a pending grant from now for its duration. The
+// requester cannot approve their own grant.
+func (u *ElevatedAccessUseCase) ApproveElevation(ctx context.Context, id, reviewerID uint, note string) (*entity.ElevatedAccessGrant, error) {
+	grant, err := u.reviewable(ctx, id, reviewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(time.Duration(grant.DurationHours) * time.Hour)
+	grant.Status = entity.ElevationApproved
+	grant.ReviewedBy = &reviewerID
+	grant.ReviewedAt = &now
+	grant.ReviewNote = note
+	grant.ExpiresAt = &expiresAt
+
+	return u.saveReview(ctx, grant)
+}
+
+// RejectElevation rejects a pending grant
+func (u *ElevatedAccessUseCase) RejectElevation(ctx context.Context, id, reviewerID uint, note string) (*entity.ElevatedAccessGrant, error) {
+	grant, err := u.reviewable(ctx, id, reviewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	grant.Status = entity.ElevationRejected
+	grant.ReviewedBy = &reviewerID
+	grant.ReviewedAt = &now
+	grant.ReviewNote = note
+
+	return u.saveReview(ctx, grant)
+}
+
+// RevokeElevation ends an active grant early. Approvers can revoke any grant and
+// users can give up their own.
+func (u *ElevatedAccessUseCase) RevokeElevation(ctx context.Context, id, userID uint, approver bool) (*entity.ElevatedAccessGrant, error) {
+	grant, err := u.getGrant(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !approver && grant.UserID != userID {
+		return nil, ErrElevationRevokeDenied
+	}
+
+	now := time.Now()
+	if !grant.ActiveAt(now) {
+		return nil, ErrElevationNotActive
+	}
+	grant.Status = entity.ElevationRevoked
+	grant.RevokedBy = &userID
+	grant.RevokedAt = &now
+
+	return u.saveReview(ctx, grant)
+}
+
+// GetElevation retrieves a grant
+func (u *ElevatedAccessUseCase) GetElevation(ctx context.Context, id uint) (*entity.ElevatedAccessGrant, error) {
+	if err := u.expire(ctx); err != nil {
+		return nil, err
+	}
+	return u.getGrant(ctx, id)
+}
+
+// ListElevations lists the grants matching a filter, latest first
+func (u *ElevatedAccessUseCase) ListElevations(ctx context.Context, filter *entity.ElevationFilter) ([]entity.ElevatedAccessGrant, int64, error) {
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+	if filter.PageSize <= 0 {
+		filter.PageSize = 20
+	}
+	if err := u.expire(ctx); err != nil {
+		return nil, 0, err
+	}
+
+	grants, total, err := u.repo.ListGrants(ctx, filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error listing elevated access grants: %w", err)
+	}
+	return grants, total, nil
+}
+
+// ActiveGrants returns a user's grants in effect now. They are checked on
+// every request, so they come from the cache when it holds them; cached grants
+// that expired since are left out.
+func (u *ElevatedAccessUseCase) ActiveGrants(ctx context.Context, userID uint) ([]entity.ElevatedAccessGrant, error) {
+	now := time.Now()
+	grants, err := cache.Load(ctx, u.grantCache, strconv.FormatUint(uint64(userID), 10), func() ([]entity.ElevatedAccessGrant, error) {
+		return u.repo.ListActiveGrants(ctx, userID, now)
+	})
+	if err != nil {
+		return nil, err
+	}
+	active := grants[:0]
+	for _, grant := range grants {
+		if grant.ActiveAt(now) {
+			active = append(active, grant)
+		}
+	}
+	return active, nil
+}
+
+// RecordActions records requests made through elevated access
+func (u *ElevatedAccessUseCase) RecordActions(ctx context.Context, actions []entity.ElevatedAction) error {
+	return u.repo.CreateActions(ctx, actions)
+}
+
+// ListActions lists the elevated actions taken under a grant
+func (u *ElevatedAccessUseCase) ListActions(ctx context.Context, id uint) ([]entity.ElevatedAction, error) {
+	if _, err := u.getGrant(ctx, id); err != nil {
+		return nil, err
+	}
+
+	actions, err := u.repo.ListActions(ctx, []uint{id}, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error listing elevated actions: %w", err)
+	}
+	return actions, nil
+}
+
+// ReviewReport lists every grant active at some point in the period, of one user
+// if given, with the elevated actions taken under it in the period
+func (u *ElevatedAccessUseCase) ReviewReport(ctx context.Context, userID uint, start, end time.Time) (*entity.ElevationReviewReport, error) {
+	if end.Before(start) {
+		return nil, ErrInvalidElevationPeriod
+	}
+	if err := u.expire(ctx); err != nil {
+		return nil, err
+	}
+
+	grants, err := u.repo.ListGrantsActiveBetween(ctx, userID, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("error listing elevated access grants: %w", err)
+	}
+
+	ids := make([]uint, len(grants))
+	for i, grant := range grants {
+		ids[i] = grant.ID
+	}
+	actions, err := u.repo.ListActions(ctx, ids, &start, &end)
+	if err != nil {
+		return nil, fmt.Errorf("error listing elevated actions: %w", err)
+	}
+
+	byGrant := make(map[uint][]entity.ElevatedAction, len(grants))
+	for _, action := range actions {
+		byGrant[action.GrantID] = append(byGrant[action.GrantID], action)
+	}
+
+	report := &entity.ElevationReviewReport{
+		StartDate:    start,
+		EndDate:      end,
+		TotalGrants:  len(grants),
+		TotalActions: len(actions),
+		Entries:      make([]entity.ElevationReviewEntry, 0, len(grants)),
+	}
+	for _, grant := range grants {
+		entry := entity.ElevationReviewEntry{Grant: grant, Actions: byGrant[grant.ID]}
+		if entry.Actions == nil {
+			entry.Actions = []entity.ElevatedAction{}
+		}
+		report.Entries = append(report.Entries, entry)
+	}
+	return report, nil
+}
+
+// MaxHours returns the longest duration a grant can be requested for
+func (u *ElevatedAccessUseCase) MaxHours() int {
+	return u.maxHours
+}
+
+func (u *ElevatedAccessUseCase) getGrant(ctx context.Context, id uint) (*entity.ElevatedAccessGrant, error) {
+	grant, err := u.repo.GetGrant(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, ErrElevationNotFound
+		}
+		return nil, fmt.Errorf("error getting elevated access grant: %w", err)
+	}
+	return grant, nil
+}
+
+// reviewable returns a pending grant the reviewer may approve or reject
+func (u *ElevatedAccessUseCase) reviewable(ctx context.Context, id, reviewerID uint) (*entity.ElevatedAccessGrant, error) {
+	grant, err := u.getGrant(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if grant.Status != entity.ElevationPending {
+		return nil, ErrElevationNotPending
+	}
+	if grant.UserID == reviewerID {
+		return nil, ErrElevationSelfReview
+	}
+	return grant, nil
+}
+
+func (u *ElevatedAccessUseCase) saveReview(ctx context.Context, grant *entity.ElevatedAccessGrant) (*entity.ElevatedAccessGrant, error) {
+	if err := u.repo.UpdateGrant(ctx, grant); err != nil {
+		return nil, fmt.Errorf("error updating elevated access grant: %w", err)
+	}
+	u.grantCache.Invalidate(ctx, strconv.FormatUint(uint64(grant.UserID), 10))
+	u.bus.Publish(ctx, entity.ElevationReviewed{Grant: grant})
+	return grant, nil
+}
+
+// expire marks the grants past their expiry as expired, so listings show the
+// status in effect
+func (u *ElevatedAccessUseCase) expire(ctx context.Context) error {
+	if _, err := u.repo.ExpireGrants(ctx, time.Now()); err != nil {
+		return fmt.Errorf("error expiring elevated access grants: %w", err)
+	}
+	return nil
+}
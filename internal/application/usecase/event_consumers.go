@@ -0,0 +1,422 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/broker"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/eventbus"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/extension"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/logging"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/realtime"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
+)
+
+// approvalDocumentNames are the names approval notifications use for documents
+var approvalDocumentNames = map[entity.ApprovalDocument]string{
+	entity.ApprovalPurchaseRequest: "Purchase request",
+	entity.ApprovalPurchaseOrder:   "Purchase order",
+	entity.ApprovalElevatedAccess:  "Elevated access request",
+	entity.ApprovalExpenseClaim:    "Expense claim",
+}
+
+// SubscribeExtensions runs the after hooks of the compiled-in extensions for
+// the domain events they are attached to
+func SubscribeExtensions(bus *eventbus.Bus, hooks *extension.Hooks) {
+	bus.Subscribe("extensions", func(ctx context.Context, event entity.DomainEvent) error {
+		switch e := event.(type) {
+		case entity.OrderConfirmed:
+			hooks.After(ctx, extension.EventAfterOrderConfirm, e.Order)
+		case entity.InvoiceIssued:
+			hooks.After(ctx, extension.EventAfterInvoiceIssue, e.Invoice)
+		case entity.ReceiptPosted:
+			hooks.After(ctx, extension.EventAfterReceiptPost, e.Receipt)
+		case entity.VendorRiskAlerted:
+			hooks.After(ctx, extension.EventAfterVendorRiskAlert, e.Alert)
+		case entity.DunningReminderSent:
+			hooks.After(ctx, extension.EventAfterDunningReminder, e.Reminder)
+		case entity.ApprovalRequested:
+			if e.Document == entity.ApprovalElevatedAccess {
+				hooks.After(ctx, extension.EventAfterElevationRequest, e.Record)
+			}
+		case entity.ElevationReviewed:
+			hooks.After(ctx, extension.EventAfterElevationReview, e.Grant)
+		}
+		return nil
+	}, entity.EventOrderConfirmed, entity.EventInvoiceIssued, entity.EventReceiptPosted, entity.EventVendorRiskAlerted,
+		entity.EventDunningReminder, entity.EventApprovalRequested, entity.EventElevationReviewed)
+}
+
+// SubscribeStockLevels publishes a stock-below-reorder event for each store
+// stock that stock entries or a shipped delivery took out of and left below
+// its SKU's reorder point
+func SubscribeStockLevels(bus *eventbus.Bus, stocksRepo *repository.StocksRepository) {
+	bus.Subscribe("stock levels", func(ctx context.Context, event entity.DomainEvent) error {
+		skusByStore := make(map[string][]string)
+		seen := make(map[string]bool)
+		add := func(storeID, skuID string) {
+			key := storeID + "|" + skuID
+			if !seen[key] {
+				seen[key] = true
+				skusByStore[storeID] = append(skusByStore[storeID], skuID)
+			}
+		}
+
+		switch e := event.(type) {
+		case entity.StockEntryCreated:
+			for _, entry := range e.Entries {
+				if entry.Type == "OUT" {
+					add(entry.StoreID, entry.SKUID)
+				}
+			}
+		case entity.DeliveryShipped:
+			for _, item := range e.Delivery.Items {
+				add(e.Delivery.StoreID, item.SKUID)
+			}
+		}
+
+		for storeID, skuIDs := range skusByStore {
+			stocks, err := stocksRepo.ListBelowReorderPoint(ctx, storeID, skuIDs)
+			if err != nil {
+				return err
+			}
+			for _, stock := range stocks {
+				below := entity.StockBelowReorderEvent{
+					SKUID:             stock.SKUID,
+					StoreID:           stock.StoreID,
+					AvailableQuantity: stock.Available(),
+				}
+				if stock.SKU != nil {
+					below.SKUCode = stock.SKU.SKUCode
+					below.Name = stock.SKU.Name
+					below.ReorderPoint = stock.SKU.ReorderPoint
+				}
+				bus.Publish(ctx, below)
+			}
+		}
+		return nil
+	}, entity.EventStockEntryCreated, entity.EventDeliveryShipped)
+}
+
+// SubscribeRealtime hands low stock, order status and approval events to the
+// gateway for its WebSocket clients, and to the stream of the Server-Sent
+// Events clients. Ended impersonation sessions are handed to the gateway
+// alone, which closes their connections. Nothing is subscribed without either.
+func SubscribeRealtime(bus *eventbus.Bus, publisher *realtime.Publisher, stream *realtime.Stream) {
+	if publisher == nil && stream == nil {
+		return
+	}
+	publish := func(event *entity.RealtimeEvent) {
+		publisher.Publish(event)
+		stream.Publish(event)
+	}
+	bus.Subscribe("realtime", func(ctx context.Context, event entity.DomainEvent) error {
+		switch e := event.(type) {
+		case entity.StockBelowReorderEvent:
+			publish(&entity.RealtimeEvent{
+				Topic:      entity.TopicStockBelowReorder,
+				Permission: entity.StockRead,
+				StoreID:    e.StoreID,
+				Data:       e,
+			})
+		case entity.OrderStatusChanged:
+			publish(&entity.RealtimeEvent{
+				Topic:      entity.TopicOrderStatusChanged,
+				Permission: entity.SalesOrderRead,
+				Data: entity.OrderStatusEvent{
+					OrderID:        e.Order.ID,
+					OrderNumber:    e.Order.OrderNumber,
+					ClientID:       e.Order.ClientID,
+					PreviousStatus: e.PreviousStatus,
+					Status:         e.Order.Status,
+				},
+			})
+		case entity.ApprovalRequested:
+			publish(&entity.RealtimeEvent{
+				Topic:      entity.TopicApprovalPending,
+				Permission: e.Approver,
+				Data:       e.ApprovalPendingEvent,
+			})
+		case entity.ImpersonationEnded:
+			publisher.Publish(&entity.RealtimeEvent{
+				Topic: entity.TopicImpersonationEnded,
+				Data:  entity.ImpersonationEndedEvent{SessionID: e.Session.ID},
+			})
+		}
+		return nil
+	}, entity.EventStockBelowReorder, entity.EventOrderStatusChanged, entity.EventApprovalRequested, entity.EventImpersonationEnded)
+}
+
+// SubscribeNotifications notifies users of pending approvals, overdue invoices,
+// low stock, condition excursions and fulfillment SLA breaches, and users of
+// admins starting to impersonate them. Pending approvals go to the delegates
+// of the approvers away in their stead.
+func SubscribeNotifications(bus *eventbus.Bus, notifier *NotificationUseCase, delegations *ApprovalDelegationUseCase) {
+	bus.Subscribe("notifications", func(ctx context.Context, event entity.DomainEvent) error {
+		switch e := event.(type) {
+		case entity.ApprovalRequested:
+			number := e.Number
+			if number == "" {
+				number = "#" + e.DocumentID
+			}
+			delegates, away, err := delegations.RouteApproval(ctx, e.Approver)
+			if err != nil {
+				return err
+			}
+			notifier.Notify(ctx, &entity.NotificationEvent{
+				Type:          entity.NotificationApprovalPending,
+				Permission:    e.Approver,
+				UserIDs:       delegates,
+				ExceptUserIDs: away,
+				ReferenceType: string(e.Document),
+				ReferenceID:   e.DocumentID,
+				Data:          map[string]string{"document": approvalDocumentNames[e.Document], "number": number},
+			})
+		case entity.DunningReminderSent:
+			reminder := e.Reminder
+			notifier.Notify(ctx, &entity.NotificationEvent{
+				Type:          entity.NotificationInvoiceOverdue,
+				Permission:    entity.FinanceDunningRead,
+				ReferenceType: "FINANCE_INVOICE",
+				ReferenceID:   strconv.FormatInt(reminder.InvoiceID, 10),
+				Data: map[string]string{
+					"invoice_number": reminder.InvoiceNumber,
+					"entity_name":    reminder.EntityName,
+					"days_overdue":   strconv.Itoa(reminder.DaysOverdue),
+					"amount_due":     strconv.FormatFloat(reminder.AmountDue, 'f', 2, 64),
+					"currency":       reminder.CurrencyCode,
+					"level":          reminder.LevelName,
+				},
+			})
+		case entity.StockBelowReorderEvent:
+			notifier.Notify(ctx, &entity.NotificationEvent{
+				Type:          entity.NotificationStockLow,
+				Permission:    entity.StockRead,
+				ReferenceType: "SKU",
+				ReferenceID:   e.SKUID,
+				Data: map[string]string{
+					"sku_code":      e.SKUCode,
+					"name":          e.Name,
+					"store_id":      e.StoreID,
+					"available":     strconv.FormatFloat(e.AvailableQuantity, 'f', -1, 64),
+					"reorder_point": strconv.FormatFloat(e.ReorderPoint, 'f', -1, 64),
+				},
+			})
+		case entity.ConditionExcursionOpened:
+			excursion := e.Excursion
+			metric := string(excursion.Metric)
+			location := "store " + excursion.StoreID
+			if excursion.ZoneCode != "" {
+				location = "zone " + excursion.ZoneCode + " of " + location
+			}
+			notifier.Notify(ctx, &entity.NotificationEvent{
+				Type:          entity.NotificationExcursion,
+				Permission:    entity.ConditionRead,
+				ReferenceType: "CONDITION_EXCURSION",
+				ReferenceID:   strconv.FormatUint(uint64(excursion.ID), 10),
+				Data: map[string]string{
+					"metric":   metric[:1] + strings.ToLower(metric[1:]),
+					"value":    strconv.FormatFloat(e.Value, 'f', -1, 64),
+					"limits":   excursion.Limits,
+					"location": location,
+					"lots":     strconv.Itoa(len(excursion.Lots)),
+				},
+			})
+		case entity.FulfillmentSLABreached:
+			alert := e.Alert
+			measure, unit := "average order-to-ship lead time", " days"
+			if alert.Metric == entity.FulfillmentFillRate {
+				measure, unit = "fill rate", "%"
+			}
+			notifier.Notify(ctx, &entity.NotificationEvent{
+				Type:          entity.NotificationSLABreach,
+				Permission:    entity.ReportRead,
+				ReferenceType: "FULFILLMENT_SLA_ALERT",
+				ReferenceID:   strconv.FormatUint(uint64(alert.ID), 10),
+				Data: map[string]string{
+					"store":     alert.StoreName,
+					"measure":   measure,
+					"value":     strconv.FormatFloat(alert.Value, 'f', -1, 64) + unit,
+					"threshold": strconv.FormatFloat(alert.Threshold, 'f', -1, 64) + unit,
+					"period":    alert.PeriodStart.Format("2006-01-02") + " to " + alert.PeriodEnd.Format("2006-01-02"),
+				},
+			})
+		case entity.ImpersonationStarted:
+			session := e.Session
+			impersonator := "An administrator"
+			if session.Impersonator != nil {
+				impersonator = session.Impersonator.Username
+			}
+			notifier.Notify(ctx, &entity.NotificationEvent{
+				Type:          entity.NotificationImpersonation,
+				UserIDs:       []uint{session.UserID},
+				ReferenceType: "IMPERSONATION_SESSION",
+				ReferenceID:   strconv.FormatUint(uint64(session.ID), 10),
+				Data: map[string]string{
+					"impersonator": impersonator,
+					"reason":       session.Reason,
+					"expires_at":   session.ExpiresAt.Format("2006-01-02 15:04 MST"),
+				},
+			})
+		}
+		return nil
+	}, entity.EventApprovalRequested, entity.EventDunningReminder, entity.EventStockBelowReorder, entity.EventConditionExcursion,
+		entity.EventFulfillmentBreach, entity.EventImpersonation)
+}
+
+// SubscribeDocumentEmails queues emailing sent purchase orders to their
+// vendors and issued sales invoices to their customers. Nothing is queued
+// while email delivery is not configured.
+func SubscribeDocumentEmails(bus *eventbus.Bus, documents *DocumentEmailUseCase, notifier *NotificationUseCase) {
+	if !notifier.EmailEnabled() {
+		return
+	}
+	bus.Subscribe("document emails", func(ctx context.Context, event entity.DomainEvent) error {
+		var err error
+		switch e := event.(type) {
+		case entity.PurchaseOrderSent:
+			_, err = documents.QueuePurchaseOrder(ctx, e.Order)
+		case entity.InvoiceIssued:
+			_, err = documents.QueueInvoice(ctx, e.Invoice)
+		}
+		if errors.Is(err, ErrNoEmailAddress) {
+			logging.FromContext(ctx).Warn("not emailing document, the recipient has no email address", "event", event.EventName())
+			return nil
+		}
+		return err
+	}, entity.EventPurchaseOrderSent, entity.EventInvoiceIssued)
+}
+
+// SubscribeDropShip generates the purchase orders of the drop-ship lines of
+// sales orders as they are confirmed. Orders that failed can be generated
+// again from the sales order.
+func SubscribeDropShip(bus *eventbus.Bus, dropShip *DropShipUseCase) {
+	bus.Subscribe("drop-ship", func(ctx context.Context, event entity.DomainEvent) error {
+		e, ok := event.(entity.OrderConfirmed)
+		if !ok {
+			return nil
+		}
+		for _, item := range e.Order.Items {
+			if item.DropShip {
+				_, err := dropShip.CreatePurchaseOrders(ctx, e.Order.ID, e.Order.CreatedByID)
+				return err
+			}
+		}
+		return nil
+	}, entity.EventOrderConfirmed)
+}
+
+// SubscribeConsignment bills vendors for the consigned stock that deliveries
+// and stock issues consumed. Consumptions that failed to bill, or were
+// consumed without an event, are billed with the next ones or from the
+// consignment endpoints.
+func SubscribeConsignment(bus *eventbus.Bus, consignment *ConsignmentUseCase) {
+	bus.Subscribe("consignment", func(ctx context.Context, event entity.DomainEvent) error {
+		var userID int64
+		switch e := event.(type) {
+		case entity.DeliveryShipped:
+			userID = int64(e.Delivery.CreatedByID)
+		case entity.StockEntryCreated:
+			issued := false
+			for _, entry := range e.Entries {
+				if entry.Type == "OUT" && entry.ConsignmentVendorID == nil {
+					issued = true
+					userID, _ = strconv.ParseInt(entry.CreatedBy, 10, 64)
+				}
+			}
+			if !issued {
+				return nil
+			}
+		}
+
+		result, err := consignment.BillConsumptions(ctx, nil, userID)
+		if err != nil {
+			return err
+		}
+		if len(result.Errors) > 0 {
+			return fmt.Errorf("error billing consigned stock: %s", strings.Join(result.Errors, "; "))
+		}
+		return nil
+	}, entity.EventDeliveryShipped, entity.EventStockEntryCreated)
+}
+
+// SubscribePutaway generates the putaway tasks of purchase receipts as they
+// are posted. Receipts that failed can be generated again from the putaway
+// endpoints.
+func SubscribePutaway(bus *eventbus.Bus, putaway *PutawayUseCase) {
+	bus.Subscribe("putaway", func(ctx context.Context, event entity.DomainEvent) error {
+		e, ok := event.(entity.ReceiptPosted)
+		if !ok {
+			return nil
+		}
+		_, err := putaway.GenerateTasks(ctx, e.Receipt)
+		return err
+	}, entity.EventReceiptPosted)
+}
+
+// SubscribeEDI generates the 850 of purchase orders as they are sent to
+// vendors set up as trading partners. Orders that failed can be generated
+// again from the EDI endpoints.
+func SubscribeEDI(bus *eventbus.Bus, edi *EDIUseCase) {
+	bus.Subscribe("edi", func(ctx context.Context, event entity.DomainEvent) error {
+		e, ok := event.(entity.PurchaseOrderSent)
+		if !ok {
+			return nil
+		}
+		return edi.GenerateSentPurchaseOrder(ctx, e.Order)
+	}, entity.EventPurchaseOrderSent)
+}
+
+// SubscribeDashboardMetrics invalidates the pre-aggregated dashboard metrics
+// when orders, deliveries, receipts or stock entries change the figures they
+// are aggregated from
+func SubscribeDashboardMetrics(bus *eventbus.Bus, reports *ReportUseCase) {
+	bus.Subscribe("dashboard metrics", func(ctx context.Context, event entity.DomainEvent) error {
+		return reports.InvalidateDashboardMetrics(ctx)
+	}, entity.EventOrderConfirmed, entity.EventOrderStatusChanged, entity.EventDeliveryShipped, entity.EventPurchaseOrderSent,
+		entity.EventReceiptPosted, entity.EventStockEntryCreated)
+}
+
+// brokerPublishTimeout bounds the time spent handing one event to the broker
+const brokerPublishTimeout = 10 * time.Second
+
+// SubscribeBroker publishes every domain event to the message broker topic
+// named after the event, prefixed with the topic prefix, so other services can
+// react to it. Events are handed over in the background and failures are
+// logged. Nothing is subscribed without a broker.
+func SubscribeBroker(bus *eventbus.Bus, b broker.Broker, topicPrefix string) {
+	if b == nil {
+		return
+	}
+	bus.Subscribe("broker", func(ctx context.Context, event entity.DomainEvent) error {
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("error encoding event: %w", err)
+		}
+		name := event.EventName()
+		value, err := json.Marshal(&entity.BrokerEvent{
+			Name:       name,
+			OccurredAt: time.Now(),
+			Payload:    payload,
+		})
+		if err != nil {
+			return fmt.Errorf("error encoding event: %w", err)
+		}
+
+		logger := logging.FromContext(ctx)
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), brokerPublishTimeout)
+			defer cancel()
+			if err := b.Publish(ctx, &broker.Message{Topic: topicPrefix + name, Value: value}); err != nil {
+				logger.Error("error publishing event to the broker", "event", name, "error", err)
+			}
+		}()
+		return nil
+	})
+}
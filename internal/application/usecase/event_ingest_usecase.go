@@ -0,0 +1,89 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/broker"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/logging"
+)
+
+// Ingested event kinds. External systems publish them to the topic named after
+// the kind, prefixed with the topic prefix and "ingest.".
+const (
+	IngestSalesOrders = "sales_orders"
+)
+
+// EventIngestor handles one message of an ingested event kind
+type EventIngestor func(ctx context.Context, payload json.RawMessage) error
+
+// EventIngestUseCase consumes the events external systems publish to the
+// message broker, handing each kind to the ingestor registered for it
+type EventIngestUseCase struct {
+	broker      broker.Broker
+	topicPrefix string
+	ingestors   map[string]EventIngestor
+}
+
+// NewEventIngestUseCase creates a new event ingest use case
+func NewEventIngestUseCase(b broker.Broker, topicPrefix string) *EventIngestUseCase {
+	return &EventIngestUseCase{
+		broker:      b,
+		topicPrefix: topicPrefix,
+		ingestors:   make(map[string]EventIngestor),
+	}
+}
+
+// Register sets the ingestor of an event kind. Ingestors are registered before
+// Start.
+func (u *EventIngestUseCase) Register(kind string, ingestor EventIngestor) {
+	u.ingestors[kind] = ingestor
+}
+
+// RegisterSalesOrders ingests the orders of external systems such as web shops
+// as draft sales orders created by the given user
+func (u *EventIngestUseCase) RegisterSalesOrders(orderUC *OrderUseCase, userID uint) {
+	u.Register(IngestSalesOrders, func(ctx context.Context, payload json.RawMessage) error {
+		var external entity.ExternalSalesOrder
+		if err := json.Unmarshal(payload, &external); err != nil {
+			return fmt.Errorf("error decoding order: %w", err)
+		}
+		order, err := orderUC.IngestExternalOrder(ctx, &external, userID)
+		if err != nil {
+			return err
+		}
+		logging.FromContext(ctx).Info("ingested external order", "source", external.Source, "reference", external.Reference, "order_id", order.ID, "order_number", order.OrderNumber)
+		return nil
+	})
+}
+
+// Topic returns the broker topic an event kind is consumed from
+func (u *EventIngestUseCase) Topic(kind string) string {
+	return u.topicPrefix + "ingest." + kind
+}
+
+// Start subscribes to the topics of the registered event kinds. Nothing is
+// consumed without a broker.
+func (u *EventIngestUseCase) Start() error {
+	if u.broker == nil {
+		return nil
+	}
+	for kind, ingestor := range u.ingestors {
+		ingestor := ingestor
+		topic := u.Topic(kind)
+		err := u.broker.Subscribe(topic, func(ctx context.Context, msg *broker.Message) error {
+			if !json.Valid(msg.Value) {
+				return fmt.Errorf("%s message is not JSON", kind)
+			}
+			return ingestor(ctx, msg.Value)
+		})
+		if err != nil {
+			return fmt.Errorf("error subscribing to %s: %w", topic, err)
+		}
+		slog.Info("consuming ingested events", "topic", topic)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/eventbus"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
+)
+
+// EventLogUseCase keeps every published domain event in the event log for
+// auditing
+type EventLogUseCase struct {
+	eventLogRepo *repository.EventLogRepository
+}
+
+// NewEventLogUseCase creates a new event log use case
+func NewEventLogUseCase(eventLogRepo *repository.EventLogRepository) *EventLogUseCase {
+	return &EventLogUseCase{
+		eventLogRepo: eventLogRepo,
+	}
+}
+
+// Subscribe records every event published to the bus
+func (u *EventLogUseCase) Subscribe(bus *eventbus.Bus) {
+	bus.Subscribe("event log", u.record)
+}
+
+func (u *EventLogUseCase) record(ctx context.Context, event entity.DomainEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("error encoding event: %w", err)
+	}
+	entry := &entity.EventLogEntry{
+		Name:       event.EventName(),
+		Payload:    payload,
+		OccurredAt: time.Now(),
+	}
+	if err := u.eventLogRepo.Create(ctx, entry); err != nil {
+		return fmt.Errorf("error logging event: %w", err)
+	}
+	return nil
+}
+
+// List lists the logged events
+func (u *EventLogUseCase) List(ctx context.Context, filter *entity.EventLogFilter) ([]entity.EventLogEntry, int64, error) {
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+	if filter.PageSize <= 0 {
+		filter.PageSize = 20
+	}
+
+	entries, total, err := u.eventLogRepo.List(ctx, filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error listing events: %w", err)
+	}
+	return entries, total, nil
+}
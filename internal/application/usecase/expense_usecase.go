@@ -0,0 +1,419 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/eventbus"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
+)
+
+var (
+	ErrExpenseCategoryNotFound = entity.NewError(entity.ErrCodeNotFound, "expense category not found")
+	ErrExpenseCategoryExists   = entity.NewError(entity.ErrCodeConflict, "an expense category with this code exists")
+	ErrExpenseCategoryInactive = entity.NewError(entity.ErrCodeInvalidArgument, "expense category is inactive")
+	ErrExpenseClaimNotFound    = entity.NewError(entity.ErrCodeNotFound, "expense claim not found")
+	ErrExpenseClaimNotEditable = entity.NewError(entity.ErrCodeFailedPrecondition, "only draft or rejected expense claims can be changed or submitted")
+	ErrExpenseClaimNotPending  = entity.NewError(entity.ErrCodeFailedPrecondition, "only submitted expense claims can be approved or rejected")
+	ErrExpenseClaimNotApproved = entity.NewError(entity.ErrCodeFailedPrecondition, "only approved expense claims can be reimbursed")
+	ErrExpenseClaimOwnReview   = entity.NewError(entity.ErrCodePermissionDenied, "expense claims cannot be reviewed by their employee")
+)
+
+// ExpenseUseCase handles employee expense claims, from the draft with its
+// receipts through approval to reimbursement by a finance payment
+type ExpenseUseCase struct {
+	expenseRepo *repository.ExpenseRepository
+	financeUC   *FinanceUseCase
+	currencyUC  *CurrencyUseCase
+	bus         *eventbus.Bus
+}
+
+// NewExpenseUseCase creates a new expense use case
+func NewExpenseUseCase(expenseRepo *repository.ExpenseRepository, financeUC *FinanceUseCase, currencyUC *CurrencyUseCase, bus *eventbus.Bus) *ExpenseUseCase {
+	return &ExpenseUseCase{
+		expenseRepo: expenseRepo,
+		financeUC:   financeUC,
+		currencyUC:  currencyUC,
+		bus:         bus,
+	}
+}
+
+// CreateCategory creates an expense category
+func (u *ExpenseUseCase) CreateCategory(ctx context.Context, req *entity.ExpenseCategoryRequest) (*entity.ExpenseCategory, error) {
+	category := &entity.ExpenseCategory{Active: true}
+	applyExpenseCategory(category, req)
+	if err := u.expenseRepo.CreateCategory(ctx, category); err != nil {
+		if errors.Is(err, repository.ErrDuplicateEntry) {
+			return nil, ErrExpenseCategoryExists
+		}
+		return nil, fmt.Errorf("error creating expense category: %w", err)
+	}
+	return category, nil
+}
+
+// UpdateCategory changes an expense category. The lines already claimed in
+// it keep it.
+func (u *ExpenseUseCase) UpdateCategory(ctx context.Context, id uint, req *entity.ExpenseCategoryRequest) (*entity.ExpenseCategory, error) {
+	category, err := u.getCategory(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	applyExpenseCategory(category, req)
+	if err := u.expenseRepo.UpdateCategory(ctx, category); err != nil {
+		if errors.Is(err, repository.ErrDuplicateEntry) {
+			return nil, ErrExpenseCategoryExists
+		}
+		return nil, fmt.Errorf("error updating expense category: %w", err)
+	}
+	return category, nil
+}
+
+// applyExpenseCategory sets the fields of a category from a request
+func applyExpenseCategory(category *entity.ExpenseCategory, req *entity.ExpenseCategoryRequest) {
+	category.Code = strings.ToUpper(strings.TrimSpace(req.Code))
+	category.Name = req.Name
+	category.Description = req.Description
+	if req.Active != nil {
+		category.Active = *req.Active
+	}
+}
+
+// ListCategories lists the expense categories, only the active ones unless
+// all is set
+func (u *ExpenseUseCase) ListCategories(ctx context.Context, all bool) ([]entity.ExpenseCategory, error) {
+	categories, err := u.expenseRepo.ListCategories(ctx, all)
+	if err != nil {
+		return nil, fmt.Errorf("error listing expense categories: %w", err)
+	}
+	return categories, nil
+}
+
+func (u *ExpenseUseCase) getCategory(ctx context.Context, id uint) (*entity.ExpenseCategory, error) {
+	category, err := u.expenseRepo.GetCategory(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, ErrExpenseCategoryNotFound
+		}
+		return nil, fmt.Errorf("error getting expense category: %w", err)
+	}
+	return category, nil
+}
+
+// CreateClaim creates a draft expense claim of an employee
+func (u *ExpenseUseCase) CreateClaim(ctx context.Context, req *entity.ExpenseClaimRequest, employeeID uint) (*entity.ExpenseClaim, error) {
+	claim := &entity.ExpenseClaim{
+		EmployeeID: employeeID,
+		Status:     entity.ExpenseClaimDraft,
+	}
+	if err := u.applyClaim(ctx, claim, req); err != nil {
+		return nil, err
+	}
+	if err := u.expenseRepo.CreateClaim(ctx, claim); err != nil {
+		return nil, fmt.Errorf("error creating expense claim: %w", err)
+	}
+	return u.GetClaim(ctx, claim.ID)
+}
+
+// UpdateClaim replaces the title, currency and lines of a draft or rejected
+// expense claim of an employee. A rejected claim goes back to draft.
+func (u *ExpenseUseCase) UpdateClaim(ctx context.Context, id uint, req *entity.ExpenseClaimRequest, employeeID uint) (*entity.ExpenseClaim, error) {
+	claim, err := u.ownClaim(ctx, id, employeeID)
+	if err != nil {
+		return nil, err
+	}
+	if claim.Status != entity.ExpenseClaimDraft && claim.Status != entity.ExpenseClaimRejected {
+		return nil, ErrExpenseClaimNotEditable
+	}
+
+	if err := u.applyClaim(ctx, claim, req); err != nil {
+		return nil, err
+	}
+	claim.Status = entity.ExpenseClaimDraft
+	if err := u.expenseRepo.UpdateClaim(ctx, claim); err != nil {
+		return nil, fmt.Errorf("error updating expense claim: %w", err)
+	}
+	return u.GetClaim(ctx, claim.ID)
+}
+
+// applyClaim sets the lines of a claim from a request, converting each into
+// the base currency at the rate of its expense date, and totals them
+func (u *ExpenseUseCase) applyClaim(ctx context.Context, claim *entity.ExpenseClaim, req *entity.ExpenseClaimRequest) error {
+	currency := strings.ToUpper(req.CurrencyCode)
+	if currency == "" {
+		currency = u.currencyUC.BaseCurrency()
+	}
+
+	categories := make(map[uint]bool)
+	var lines []entity.ExpenseClaimLine
+	var total, baseTotal float64
+	for _, line := range req.Lines {
+		if _, ok := categories[line.CategoryID]; !ok {
+			category, err := u.getCategory(ctx, line.CategoryID)
+			if err != nil {
+				return err
+			}
+			categories[line.CategoryID] = category.Active
+		}
+		if !categories[line.CategoryID] {
+			return ErrExpenseCategoryInactive
+		}
+
+		conversion, err := u.currencyUC.Convert(ctx, line.Amount, currency, line.ExpenseDate)
+		if err != nil {
+			return fmt.Errorf("error converting expense amount: %w", err)
+		}
+		lines = append(lines, entity.ExpenseClaimLine{
+			CategoryID:   line.CategoryID,
+			ExpenseDate:  truncateDay(line.ExpenseDate),
+			Description:  line.Description,
+			Amount:       roundAmount(line.Amount),
+			ExchangeRate: conversion.Rate,
+			BaseAmount:   conversion.BaseAmount,
+		})
+		total += roundAmount(line.Amount)
+		baseTotal += conversion.BaseAmount
+	}
+
+	claim.Title = req.Title
+	claim.CurrencyCode = currency
+	claim.Lines = lines
+	claim.Total = roundAmount(total)
+	claim.BaseTotal = roundAmount(baseTotal)
+	return nil
+}
+
+// SubmitClaim submits a draft expense claim of an employee for approval
+func (u *ExpenseUseCase) SubmitClaim(ctx context.Context, id uint, employeeID uint) (*entity.ExpenseClaim, error) {
+	claim, err := u.ownClaim(ctx, id, employeeID)
+	if err != nil {
+		return nil, err
+	}
+	if claim.Status != entity.ExpenseClaimDraft {
+		return nil, ErrExpenseClaimNotEditable
+	}
+
+	now := time.Now()
+	claim.Status = entity.ExpenseClaimSubmitted
+	claim.SubmittedAt = &now
+	claim.ReviewerID = nil
+	claim.OnBehalfOfID = nil
+	claim.ReviewedAt = nil
+	claim.ReviewNotes = ""
+	if err := u.expenseRepo.SaveClaim(ctx, claim); err != nil {
+		return nil, fmt.Errorf("error submitting expense claim: %w", err)
+	}
+
+	u.bus.Publish(ctx, entity.ApprovalRequested{
+		ApprovalPendingEvent: entity.ApprovalPendingEvent{
+			Document:    entity.ApprovalExpenseClaim,
+			DocumentID:  fmt.Sprint(claim.ID),
+			Number:      claim.ClaimNumber,
+			RequestedBy: claim.EmployeeID,
+		},
+		Approver: entity.FinanceExpenseApprove,
+		Record:   claim,
+	})
+	return claim, nil
+}
+
+// ApproveClaim approves a submitted expense claim, on behalf of an approver
+// away when onBehalfOf is set. The claim's total becomes owed to the
+// employee by an approved purchase invoice, issued today.
+func (u *ExpenseUseCase) ApproveClaim(ctx context.Context, id uint, reviewerID uint, onBehalfOf *uint, notes string) (*entity.ExpenseClaim, error) {
+	claim, err := u.claimToReview(ctx, id, reviewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	var items entity.FinanceInvoiceItems
+	for _, line := range claim.Lines {
+		name := line.Description
+		if line.Category != nil {
+			name = strings.TrimSpace(line.Category.Name + " " + line.Description)
+		}
+		items = append(items, entity.FinanceInvoiceItem{
+			ProductName: name,
+			Quantity:    1,
+			UnitPrice:   line.Amount,
+		})
+	}
+	issueDate := truncateDay(time.Now())
+	invoice, err := u.financeUC.newInvoice(ctx, &entity.CreateFinanceInvoiceRequest{
+		Type:         entity.FinancePurchaseInvoice,
+		ReferenceID:  claim.ClaimNumber,
+		EntityID:     int64(claim.EmployeeID),
+		EntityType:   entity.ExpenseEntityType,
+		IssueDate:    issueDate,
+		DueDate:      issueDate,
+		Items:        items,
+		CurrencyCode: claim.CurrencyCode,
+		Notes:        "Expense claim " + claim.ClaimNumber + ": " + claim.Title,
+	}, int64(reviewerID))
+	if err != nil {
+		return nil, err
+	}
+	if name, err := u.expenseRepo.EmployeeName(ctx, claim.EmployeeID); err == nil {
+		invoice.EntityName = name
+	}
+	invoice.Status = entity.FinanceInvoiceApproved
+
+	u.review(claim, entity.ExpenseClaimApproved, reviewerID, onBehalfOf, notes)
+	if err := u.expenseRepo.ReviewClaim(ctx, claim, invoice); err != nil {
+		if errors.Is(err, repository.ErrExpenseClaimReviewed) {
+			return nil, ErrExpenseClaimNotPending
+		}
+		return nil, fmt.Errorf("error approving expense claim: %w", err)
+	}
+	return claim, nil
+}
+
+// RejectClaim rejects a submitted expense claim, on behalf of an approver
+// away when onBehalfOf is set. The employee may correct and submit it again.
+func (u *ExpenseUseCase) RejectClaim(ctx context.Context, id uint, reviewerID uint, onBehalfOf *uint, notes string) (*entity.ExpenseClaim, error) {
+	claim, err := u.claimToReview(ctx, id, reviewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	u.review(claim, entity.ExpenseClaimRejected, reviewerID, onBehalfOf, notes)
+	if err := u.expenseRepo.ReviewClaim(ctx, claim, nil); err != nil {
+		if errors.Is(err, repository.ErrExpenseClaimReviewed) {
+			return nil, ErrExpenseClaimNotPending
+		}
+		return nil, fmt.Errorf("error rejecting expense claim: %w", err)
+	}
+	return claim, nil
+}
+
+// claimToReview retrieves a submitted expense claim a reviewer may approve
+// or reject
+func (u *ExpenseUseCase) claimToReview(ctx context.Context, id uint, reviewerID uint) (*entity.ExpenseClaim, error) {
+	claim, err := u.GetClaim(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if claim.Status != entity.ExpenseClaimSubmitted {
+		return nil, ErrExpenseClaimNotPending
+	}
+	if claim.EmployeeID == reviewerID {
+		return nil, ErrExpenseClaimOwnReview
+	}
+	return claim, nil
+}
+
+// review records the outcome of the review of a claim
+func (u *ExpenseUseCase) review(claim *entity.ExpenseClaim, status entity.ExpenseClaimStatus, reviewerID uint, onBehalfOf *uint, notes string) {
+	now := time.Now()
+	claim.Status = status
+	claim.ReviewerID = &reviewerID
+	claim.OnBehalfOfID = onBehalfOf
+	claim.ReviewedAt = &now
+	claim.ReviewNotes = notes
+}
+
+// ReimburseClaim pays an approved expense claim in full, in the claim
+// currency, by a completed finance payment against its purchase invoice
+func (u *ExpenseUseCase) ReimburseClaim(ctx context.Context, id uint, req *entity.ReimburseExpenseClaimRequest, userID uint) (*entity.ExpenseClaim, error) {
+	claim, err := u.GetClaim(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if claim.Status != entity.ExpenseClaimApproved || claim.FinanceInvoiceID == nil {
+		return nil, ErrExpenseClaimNotApproved
+	}
+
+	payment, err := u.financeUC.CreatePayment(ctx, &entity.CreateFinancePaymentRequest{
+		InvoiceID:       *claim.FinanceInvoiceID,
+		PaymentDate:     req.PaymentDate,
+		PaymentMethod:   req.PaymentMethod,
+		Amount:          claim.Total,
+		ReferenceNumber: req.ReferenceNumber,
+		Notes:           "Reimbursement of expense claim " + claim.ClaimNumber,
+	}, int64(userID))
+	if err != nil {
+		return nil, err
+	}
+	if err := u.financeUC.ConfirmPayment(ctx, payment.ID); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	claim.Status = entity.ExpenseClaimReimbursed
+	claim.FinancePaymentID = &payment.ID
+	claim.ReimbursedAt = &now
+	if err := u.expenseRepo.SaveClaim(ctx, claim); err != nil {
+		return nil, fmt.Errorf("error recording expense claim reimbursement: %w", err)
+	}
+	return claim, nil
+}
+
+// GetClaim retrieves an expense claim with its lines
+func (u *ExpenseUseCase) GetClaim(ctx context.Context, id uint) (*entity.ExpenseClaim, error) {
+	claim, err := u.expenseRepo.GetClaim(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, ErrExpenseClaimNotFound
+		}
+		return nil, fmt.Errorf("error getting expense claim: %w", err)
+	}
+	return claim, nil
+}
+
+// ownClaim retrieves an expense claim of an employee. The claims of other
+// employees are not found.
+func (u *ExpenseUseCase) ownClaim(ctx context.Context, id uint, employeeID uint) (*entity.ExpenseClaim, error) {
+	claim, err := u.GetClaim(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if claim.EmployeeID != employeeID {
+		return nil, ErrExpenseClaimNotFound
+	}
+	return claim, nil
+}
+
+// ListClaims lists expense claims based on filter criteria
+func (u *ExpenseUseCase) ListClaims(ctx context.Context, filter *entity.ExpenseClaimFilter) ([]entity.ExpenseClaim, int64, error) {
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+	if filter.PageSize <= 0 {
+		filter.PageSize = 20
+	}
+
+	claims, total, err := u.expenseRepo.ListClaims(ctx, filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error listing expense claims: %w", err)
+	}
+	return claims, total, nil
+}
+
+// GetExpenseReport totals the approved and reimbursed expenses dated between
+// two dates by category, in the base currency
+func (u *ExpenseUseCase) GetExpenseReport(ctx context.Context, startDate, endDate time.Time) (*entity.ExpenseReport, error) {
+	if startDate.IsZero() {
+		startDate = time.Now().AddDate(0, -1, 0) // Default to last month
+	}
+	if endDate.IsZero() {
+		endDate = time.Now()
+	}
+
+	categories, err := u.expenseRepo.ExpensesByCategory(ctx, startDate, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("error generating expense report: %w", err)
+	}
+	report := &entity.ExpenseReport{
+		StartDate:  startDate,
+		EndDate:    endDate,
+		Categories: categories,
+	}
+	for _, category := range categories {
+		report.Total += category.Amount
+	}
+	report.Total = roundAmount(report.Total)
+	return report, nil
+}
@@ -0,0 +1,39 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
+)
+
+// FieldChangeUseCase reads the field-level change history of critical
+// entities, recorded by the database on every update
+type FieldChangeUseCase struct {
+	repo      *repository.FieldChangeRepository
+	stockRepo *repository.StocksRepository
+}
+
+// NewFieldChangeUseCase creates a new field change use case
+func NewFieldChangeUseCase(repo *repository.FieldChangeRepository, stockRepo *repository.StocksRepository) *FieldChangeUseCase {
+	return &FieldChangeUseCase{
+		repo:      repo,
+		stockRepo: stockRepo,
+	}
+}
+
+// GetHistory lists who changed which field of an entity when, latest first,
+// optionally only for one field
+func (u *FieldChangeUseCase) GetHistory(ctx context.Context, entityType, entityID, field string) ([]entity.FieldChange, error) {
+	// Stocks are limited to the stores of the access scope
+	if entityType == entity.ChangeEntityStock {
+		stock, err := u.stockRepo.GetByID(ctx, entityID)
+		if err != nil {
+			return nil, err
+		}
+		if err := entity.AccessScopeFromContext(ctx).CheckStore(stock.StoreID); err != nil {
+			return nil, err
+		}
+	}
+	return u.repo.ListByEntity(ctx, entityType, entityID, field)
+}
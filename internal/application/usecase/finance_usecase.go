@@ -2,27 +2,59 @@ package usecase
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
 	"time"
 
 	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/eventbus"
 	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
 )
 
 // FinanceUseCase handles business logic for finance operations
 type FinanceUseCase struct {
-	financeRepo *repository.FinanceRepository
+	financeRepo     *repository.FinanceRepository
+	withholdingRepo *repository.WithholdingRepository
+	currencyUC      *CurrencyUseCase
+	fiscalUC        *FiscalUseCase
+	bus             *eventbus.Bus
 }
 
 // NewFinanceUseCase creates a new finance use case
-func NewFinanceUseCase(financeRepo *repository.FinanceRepository) *FinanceUseCase {
+func NewFinanceUseCase(financeRepo *repository.FinanceRepository, withholdingRepo *repository.WithholdingRepository, currencyUC *CurrencyUseCase, fiscalUC *FiscalUseCase, bus *eventbus.Bus) *FinanceUseCase {
 	return &FinanceUseCase{
-		financeRepo: financeRepo,
+		financeRepo:     financeRepo,
+		withholdingRepo: withholdingRepo,
+		currencyUC:      currencyUC,
+		fiscalUC:        fiscalUC,
+		bus:             bus,
 	}
 }
 
 // CreateInvoice creates a new finance invoice
 func (u *FinanceUseCase) CreateInvoice(ctx context.Context, req *entity.CreateFinanceInvoiceRequest, userID int64) (*entity.FinanceInvoice, error) {
+	invoice, err := u.newInvoice(ctx, req, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Save invoice, settled with the unapplied deposits or advances of its order
+	if err := u.financeRepo.CreateInvoice(ctx, invoice); err != nil {
+		return nil, fmt.Errorf("error creating invoice: %w", err)
+	}
+
+	return invoice, nil
+}
+
+// newInvoice builds a draft invoice from a request, calculating its totals,
+// base currency amount and the tax withheld from a supplier without saving it.
+// Invoices cannot be issued in a closed fiscal period.
+func (u *FinanceUseCase) newInvoice(ctx context.Context, req *entity.CreateFinanceInvoiceRequest, userID int64) (*entity.FinanceInvoice, error) {
+	if err := u.fiscalUC.CheckOpen(ctx, req.IssueDate); err != nil {
+		return nil, err
+	}
+
 	// Calculate totals
 	var subtotal, taxTotal, total float64
 	var items entity.FinanceInvoiceItems
@@ -44,6 +76,16 @@ func (u *FinanceUseCase) CreateInvoice(ctx context.Context, req *entity.CreateFi
 	// Apply discount
 	total -= req.DiscountAmount
 
+	// Record the base currency amount at the issue date rate
+	currency := req.CurrencyCode
+	if currency == "" {
+		currency = u.currencyUC.BaseCurrency()
+	}
+	conversion, err := u.currencyUC.Convert(ctx, total, currency, req.IssueDate)
+	if err != nil {
+		return nil, fmt.Errorf("error converting invoice total: %w", err)
+	}
+
 	// Create invoice entity
 	invoice := &entity.FinanceInvoice{
 		Type:           req.Type,
@@ -60,19 +102,41 @@ func (u *FinanceUseCase) CreateInvoice(ctx context.Context, req *entity.CreateFi
 		Total:          total,
 		AmountPaid:     0,
 		AmountDue:      total,
+		CurrencyCode:   conversion.Currency,
+		ExchangeRate:   conversion.Rate,
+		BaseTotal:      conversion.BaseAmount,
 		Status:         entity.FinanceInvoiceDraft,
 		Notes:          req.Notes,
 		CreatedBy:      userID,
 	}
 
-	// Save invoice
-	if err := u.financeRepo.CreateInvoice(ctx, invoice); err != nil {
-		return nil, fmt.Errorf("error creating invoice: %w", err)
+	// Withhold the supplier's rate unless the request sets one
+	if req.WithholdingRate != nil {
+		invoice.WithholdingRate = *req.WithholdingRate
+	} else if req.EntityType == "SUPPLIER" {
+		rate, err := u.withholdingRepo.RateFor(ctx, uint(req.EntityID))
+		if err != nil {
+			return nil, fmt.Errorf("error getting withholding tax rate: %w", err)
+		}
+		if rate != nil {
+			invoice.WithholdingRate = rate.Rate
+		}
 	}
+	setWithholding(invoice)
 
 	return invoice, nil
 }
 
+// setWithholding calculates the tax withheld from the payments of a purchase
+// invoice at its rate, on its subtotal after discount. Nothing is withheld
+// from sales invoices.
+func setWithholding(invoice *entity.FinanceInvoice) {
+	if invoice.Type != entity.FinancePurchaseInvoice {
+		invoice.WithholdingRate = 0
+	}
+	invoice.WithholdingAmount = roundAmount((invoice.Subtotal - invoice.DiscountAmount) * invoice.WithholdingRate / 100)
+}
+
 // GetInvoiceByID retrieves a finance invoice by ID
 func (u *FinanceUseCase) GetInvoiceByID(ctx context.Context, id int64) (*entity.FinanceInvoice, error) {
 	invoice, err := u.financeRepo.GetInvoiceByID(ctx, id)
@@ -101,7 +165,10 @@ func (u *FinanceUseCase) UpdateInvoice(ctx context.Context, id int64, req *entit
 
 	// Check if invoice can be updated
 	if invoice.Status == entity.FinanceInvoicePaid || invoice.Status == entity.FinanceInvoiceCancelled {
-		return nil, fmt.Errorf("cannot update invoice with status %s", invoice.Status)
+		return nil, entity.NewError(entity.ErrCodeFailedPrecondition, fmt.Sprintf("cannot update invoice with status %s", invoice.Status))
+	}
+	if err := u.fiscalUC.CheckOpen(ctx, invoice.IssueDate); err != nil {
+		return nil, err
 	}
 
 	// Update fields
@@ -147,7 +214,12 @@ func (u *FinanceUseCase) UpdateInvoice(ctx context.Context, id int64, req *entit
 		invoice.DiscountAmount = req.DiscountAmount
 		invoice.Total = total
 		invoice.AmountDue = total - invoice.AmountPaid
+		invoice.BaseTotal = roundAmount(total * invoice.ExchangeRate)
+	}
+	if req.WithholdingRate != nil {
+		invoice.WithholdingRate = *req.WithholdingRate
 	}
+	setWithholding(invoice)
 
 	// Save invoice
 	if err := u.financeRepo.UpdateInvoice(ctx, invoice); err != nil {
@@ -167,11 +239,22 @@ func (u *FinanceUseCase) UpdateInvoiceStatus(ctx context.Context, id int64, stat
 
 	// Check if status change is valid
 	if invoice.Status == entity.FinanceInvoiceCancelled {
-		return fmt.Errorf("cannot update status of cancelled invoice")
+		return entity.NewError(entity.ErrCodeFailedPrecondition, "cannot update status of cancelled invoice")
 	}
 
 	if invoice.Status == entity.FinanceInvoicePaid && status != entity.FinanceInvoiceCancelled {
-		return fmt.Errorf("cannot change status of paid invoice except to cancelled")
+		return entity.NewError(entity.ErrCodeFailedPrecondition, "cannot change status of paid invoice except to cancelled")
+	}
+	if err := u.fiscalUC.CheckOpen(ctx, invoice.IssueDate); err != nil {
+		return err
+	}
+
+	// Cancelling returns the prepayments applied to the invoice to their balances
+	if status == entity.FinanceInvoiceCancelled {
+		if err := u.financeRepo.CancelInvoice(ctx, id); err != nil {
+			return fmt.Errorf("error cancelling invoice: %w", err)
+		}
+		return nil
 	}
 
 	// Update status
@@ -192,11 +275,14 @@ func (u *FinanceUseCase) CancelInvoice(ctx context.Context, id int64) error {
 
 	// Check if invoice can be cancelled
 	if invoice.Status == entity.FinanceInvoiceCancelled {
-		return fmt.Errorf("invoice is already cancelled")
+		return entity.NewError(entity.ErrCodeFailedPrecondition, "invoice is already cancelled")
+	}
+	if err := u.fiscalUC.CheckOpen(ctx, invoice.IssueDate); err != nil {
+		return err
 	}
 
-	// Update status
-	if err := u.financeRepo.UpdateInvoiceStatus(ctx, id, entity.FinanceInvoiceCancelled); err != nil {
+	// Cancel it, returning the prepayments applied to it to their balances
+	if err := u.financeRepo.CancelInvoice(ctx, id); err != nil {
 		return fmt.Errorf("error cancelling invoice: %w", err)
 	}
 
@@ -212,6 +298,16 @@ func (u *FinanceUseCase) ListInvoices(ctx context.Context, filter *entity.Financ
 	return invoices, total, nil
 }
 
+// ListInvoicesPage lists the page of finance invoices following a cursor,
+// with the cursor of the next page when there is one
+func (u *FinanceUseCase) ListInvoicesPage(ctx context.Context, filter *entity.FinanceInvoiceFilter, page entity.CursorPage) ([]entity.FinanceInvoice, *entity.Cursor, error) {
+	invoices, next, err := u.financeRepo.ListInvoicesPage(ctx, filter, page)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error listing invoices: %w", err)
+	}
+	return invoices, next, nil
+}
+
 // CreatePayment creates a new finance payment
 func (u *FinanceUseCase) CreatePayment(ctx context.Context, req *entity.CreateFinancePaymentRequest, userID int64) (*entity.FinancePayment, error) {
 	// Get invoice
@@ -222,12 +318,21 @@ func (u *FinanceUseCase) CreatePayment(ctx context.Context, req *entity.CreateFi
 
 	// Check if invoice can be paid
 	if invoice.Status == entity.FinanceInvoiceCancelled {
-		return nil, fmt.Errorf("cannot create payment for cancelled invoice")
+		return nil, entity.NewError(entity.ErrCodeFailedPrecondition, "cannot create payment for cancelled invoice")
 	}
 
 	// Check if payment amount is valid
 	if req.Amount <= 0 {
-		return nil, fmt.Errorf("payment amount must be greater than zero")
+		return nil, entity.NewError(entity.ErrCodeInvalidArgument, "payment amount must be greater than zero")
+	}
+	if err := u.fiscalUC.CheckOpen(ctx, req.PaymentDate); err != nil {
+		return nil, err
+	}
+
+	// Payments are made in the invoice currency at the payment date rate
+	conversion, err := u.currencyUC.Convert(ctx, req.Amount, invoice.CurrencyCode, req.PaymentDate)
+	if err != nil {
+		return nil, fmt.Errorf("error converting payment amount: %w", err)
 	}
 
 	// Create payment entity
@@ -240,11 +345,17 @@ func (u *FinanceUseCase) CreatePayment(ctx context.Context, req *entity.CreateFi
 		PaymentDate:     req.PaymentDate,
 		PaymentMethod:   req.PaymentMethod,
 		Amount:          req.Amount,
+		CurrencyCode:    conversion.Currency,
+		ExchangeRate:    conversion.Rate,
+		BaseAmount:      conversion.BaseAmount,
 		Status:          entity.FinancePaymentPending,
 		Notes:           req.Notes,
 		ReferenceNumber: req.ReferenceNumber,
 		CreatedBy:       userID,
 	}
+	if err := u.withhold(ctx, invoice, payment, req.Amount >= invoice.AmountDue); err != nil {
+		return nil, err
+	}
 
 	// Save payment
 	if err := u.financeRepo.CreatePayment(ctx, payment); err != nil {
@@ -254,6 +365,27 @@ func (u *FinanceUseCase) CreatePayment(ctx context.Context, req *entity.CreateFi
 	return payment, nil
 }
 
+// withhold splits a payment into the tax withheld from the supplier and the
+// net amount paid. Each payment withholds its share of the invoice's
+// withholding, and the one settling the invoice withholds what is left.
+func (u *FinanceUseCase) withhold(ctx context.Context, invoice *entity.FinanceInvoice, payment *entity.FinancePayment, settles bool) error {
+	payment.WithheldAmount = 0
+	if invoice.WithholdingAmount > 0 && invoice.Total > 0 {
+		withheld, err := u.withholdingRepo.WithheldOnInvoice(ctx, invoice.ID, payment.ID)
+		if err != nil {
+			return fmt.Errorf("error getting withheld tax: %w", err)
+		}
+		remaining := roundAmount(invoice.WithholdingAmount - withheld)
+		share := roundAmount(payment.Amount * invoice.WithholdingAmount / invoice.Total)
+		if settles || share > remaining {
+			share = remaining
+		}
+		payment.WithheldAmount = math.Max(share, 0)
+	}
+	payment.NetAmount = roundAmount(payment.Amount - payment.WithheldAmount)
+	return nil
+}
+
 // GetPaymentByID retrieves a finance payment by ID
 func (u *FinanceUseCase) GetPaymentByID(ctx context.Context, id int64) (*entity.FinancePayment, error) {
 	payment, err := u.financeRepo.GetPaymentByID(ctx, id)
@@ -263,6 +395,15 @@ func (u *FinanceUseCase) GetPaymentByID(ctx context.Context, id int64) (*entity.
 	return payment, nil
 }
 
+// GetPaymentByReference retrieves the latest finance payment with the given reference number
+func (u *FinanceUseCase) GetPaymentByReference(ctx context.Context, reference string) (*entity.FinancePayment, error) {
+	payment, err := u.financeRepo.GetPaymentByReference(ctx, reference)
+	if err != nil {
+		return nil, fmt.Errorf("error getting payment: %w", err)
+	}
+	return payment, nil
+}
+
 // UpdatePayment updates a finance payment
 func (u *FinanceUseCase) UpdatePayment(ctx context.Context, id int64, req *entity.UpdateFinancePaymentRequest) (*entity.FinancePayment, error) {
 	// Get existing payment
@@ -273,15 +414,26 @@ func (u *FinanceUseCase) UpdatePayment(ctx context.Context, id int64, req *entit
 
 	// Check if payment can be updated
 	if payment.Status == entity.FinancePaymentCancelled || payment.Status == entity.FinancePaymentRefunded {
-		return nil, fmt.Errorf("cannot update payment with status %s", payment.Status)
+		return nil, entity.NewError(entity.ErrCodeFailedPrecondition, fmt.Sprintf("cannot update payment with status %s", payment.Status))
+	}
+	if err := u.fiscalUC.CheckOpen(ctx, payment.PaymentDate); err != nil {
+		return nil, err
 	}
 
 	// Update fields
 	if req.PaymentMethod != "" {
 		payment.PaymentMethod = req.PaymentMethod
 	}
-	if req.Amount > 0 {
+	if req.Amount > 0 && req.Amount != payment.Amount {
+		invoice, err := u.financeRepo.GetInvoiceByID(ctx, payment.InvoiceID)
+		if err != nil {
+			return nil, fmt.Errorf("error getting invoice: %w", err)
+		}
 		payment.Amount = req.Amount
+		payment.BaseAmount = roundAmount(req.Amount * payment.ExchangeRate)
+		if err := u.withhold(ctx, invoice, payment, false); err != nil {
+			return nil, err
+		}
 	}
 	if req.Status != "" {
 		payment.Status = req.Status
@@ -311,7 +463,10 @@ func (u *FinanceUseCase) ConfirmPayment(ctx context.Context, id int64) error {
 
 	// Check if payment can be confirmed
 	if payment.Status != entity.FinancePaymentPending {
-		return fmt.Errorf("only pending payments can be confirmed")
+		return entity.NewError(entity.ErrCodeFailedPrecondition, "only pending payments can be confirmed")
+	}
+	if err := u.fiscalUC.CheckOpen(ctx, payment.PaymentDate); err != nil {
+		return err
 	}
 
 	// Update status
@@ -319,6 +474,8 @@ func (u *FinanceUseCase) ConfirmPayment(ctx context.Context, id int64) error {
 		return fmt.Errorf("error confirming payment: %w", err)
 	}
 
+	payment.Status = entity.FinancePaymentCompleted
+	u.bus.Publish(ctx, entity.PaymentConfirmed{Payment: payment})
 	return nil
 }
 
@@ -332,11 +489,14 @@ func (u *FinanceUseCase) CancelPayment(ctx context.Context, id int64) error {
 
 	// Check if payment can be cancelled
 	if payment.Status == entity.FinancePaymentCancelled {
-		return fmt.Errorf("payment is already cancelled")
+		return entity.NewError(entity.ErrCodeFailedPrecondition, "payment is already cancelled")
 	}
 
 	if payment.Status == entity.FinancePaymentRefunded {
-		return fmt.Errorf("cannot cancel refunded payment")
+		return entity.NewError(entity.ErrCodeFailedPrecondition, "cannot cancel refunded payment")
+	}
+	if err := u.fiscalUC.CheckOpen(ctx, payment.PaymentDate); err != nil {
+		return err
 	}
 
 	// Update status
@@ -357,7 +517,10 @@ func (u *FinanceUseCase) RefundPayment(ctx context.Context, id int64) error {
 
 	// Check if payment can be refunded
 	if payment.Status != entity.FinancePaymentCompleted {
-		return fmt.Errorf("only completed payments can be refunded")
+		return entity.NewError(entity.ErrCodeFailedPrecondition, "only completed payments can be refunded")
+	}
+	if err := u.fiscalUC.CheckOpen(ctx, payment.PaymentDate); err != nil {
+		return err
 	}
 
 	// Update status
@@ -401,5 +564,55 @@ func (u *FinanceUseCase) GetFinanceReport(ctx context.Context, startDate, endDat
 	if err != nil {
 		return nil, fmt.Errorf("error generating finance report: %w", err)
 	}
+
+	report.BaseCurrency = u.currencyUC.BaseCurrency()
+
+	realized, err := u.financeRepo.GetRealizedFXGain(ctx, startDate, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("error calculating realized FX gain: %w", err)
+	}
+	report.RealizedFXGain = roundAmount(realized)
+
+	unrealized, err := u.unrealizedFXGain(ctx, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("error calculating unrealized FX gain: %w", err)
+	}
+	report.UnrealizedFXGain = roundAmount(unrealized)
+
 	return report, nil
 }
+
+// unrealizedFXGain revalues open foreign-currency invoice balances at the rate on asOf.
+// Invoices in currencies without a recorded rate are left at their booked value.
+func (u *FinanceUseCase) unrealizedFXGain(ctx context.Context, asOf time.Time) (float64, error) {
+	invoices, err := u.financeRepo.ListOpenForeignInvoices(ctx, u.currencyUC.BaseCurrency(), asOf)
+	if err != nil {
+		return 0, err
+	}
+
+	rates := make(map[string]float64)
+	var gain float64
+	for _, invoice := range invoices {
+		rate, ok := rates[invoice.CurrencyCode]
+		if !ok {
+			rate, err = u.currencyUC.GetRate(ctx, invoice.CurrencyCode, asOf)
+			if err != nil {
+				if !errors.Is(err, ErrExchangeRateNotFound) {
+					return 0, err
+				}
+				rate = 0
+			}
+			rates[invoice.CurrencyCode] = rate
+		}
+		if rate == 0 {
+			continue
+		}
+
+		diff := invoice.AmountDue * (rate - invoice.ExchangeRate)
+		if invoice.Type == entity.FinancePurchaseInvoice {
+			diff = -diff
+		}
+		gain += diff
+	}
+	return gain, nil
+}
@@ -0,0 +1,188 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/logging"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
+)
+
+var (
+	ErrFiscalYearNotFound      = entity.NewError(entity.ErrCodeNotFound, "fiscal year not found")
+	ErrFiscalYearExists        = entity.NewError(entity.ErrCodeConflict, "a fiscal year of the same name or overlapping dates exists")
+	ErrInvalidFiscalYearStart  = entity.NewError(entity.ErrCodeInvalidArgument, "fiscal year start date must be YYYY-MM-DD")
+	ErrFiscalPeriodNotFound    = entity.NewError(entity.ErrCodeNotFound, "fiscal period not found")
+	ErrFiscalPeriodNotOpen     = entity.NewError(entity.ErrCodeFailedPrecondition, "the fiscal period is already closed")
+	ErrFiscalPeriodNotClosed   = entity.NewError(entity.ErrCodeFailedPrecondition, "the fiscal period is not closed")
+	ErrFiscalPeriodLocked      = entity.NewError(entity.ErrCodeFailedPrecondition, "the date falls in a closed fiscal period")
+	ErrInvalidFiscalPeriodType = entity.NewError(entity.ErrCodeInvalidArgument, "fiscal period type must be MONTHLY or QUARTERLY")
+)
+
+// fiscalPeriodMonths is the length of the periods of each type, in months
+var fiscalPeriodMonths = map[entity.FiscalPeriodType]int{
+	entity.FiscalPeriodMonthly:   1,
+	entity.FiscalPeriodQuarterly: 3,
+}
+
+// FiscalUseCase manages the fiscal years of the company and the closing of
+// their periods, and keeps postings out of closed periods
+type FiscalUseCase struct {
+	fiscalRepo *repository.FiscalRepository
+}
+
+// NewFiscalUseCase creates a new fiscal use case
+func NewFiscalUseCase(fiscalRepo *repository.FiscalRepository) *FiscalUseCase {
+	return &FiscalUseCase{
+		fiscalRepo: fiscalRepo,
+	}
+}
+
+// CreateYear sets up a fiscal year of twelve months from its start date,
+// divided into open monthly or quarterly periods
+func (u *FiscalUseCase) CreateYear(ctx context.Context, req *entity.CreateFiscalYearRequest, userID uint) (*entity.FiscalYear, error) {
+	start, err := time.Parse("2006-01-02", req.StartDate)
+	if err != nil {
+		return nil, ErrInvalidFiscalYearStart
+	}
+	periodType := req.PeriodType
+	if periodType == "" {
+		periodType = entity.FiscalPeriodMonthly
+	}
+	months, ok := fiscalPeriodMonths[periodType]
+	if !ok {
+		return nil, ErrInvalidFiscalPeriodType
+	}
+
+	year := &entity.FiscalYear{
+		Name:       req.Name,
+		StartDate:  start,
+		EndDate:    start.AddDate(1, 0, -1),
+		PeriodType: periodType,
+		CreatedBy:  userID,
+	}
+	for i := 0; i < 12/months; i++ {
+		name := fmt.Sprintf("%s-P%02d", req.Name, i+1)
+		if periodType == entity.FiscalPeriodQuarterly {
+			name = fmt.Sprintf("%s-Q%d", req.Name, i+1)
+		}
+		year.Periods = append(year.Periods, entity.FiscalPeriod{
+			Number:    i + 1,
+			Name:      name,
+			StartDate: start.AddDate(0, i*months, 0),
+			EndDate:   start.AddDate(0, (i+1)*months, -1),
+			Status:    entity.FiscalPeriodOpen,
+		})
+	}
+
+	if err := u.fiscalRepo.CreateYear(ctx, year); err != nil {
+		if errors.Is(err, repository.ErrDuplicateEntry) {
+			return nil, ErrFiscalYearExists
+		}
+		return nil, fmt.Errorf("error creating fiscal year: %w", err)
+	}
+	return year, nil
+}
+
+// GetYear retrieves a fiscal year with its periods
+func (u *FiscalUseCase) GetYear(ctx context.Context, id uint) (*entity.FiscalYear, error) {
+	year, err := u.fiscalRepo.GetYear(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, ErrFiscalYearNotFound
+		}
+		return nil, fmt.Errorf("error getting fiscal year: %w", err)
+	}
+	return year, nil
+}
+
+// ListYears lists the fiscal years with their periods, the latest first
+func (u *FiscalUseCase) ListYears(ctx context.Context) ([]entity.FiscalYear, error) {
+	years, err := u.fiscalRepo.ListYears(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error listing fiscal years: %w", err)
+	}
+	return years, nil
+}
+
+// GetPeriod retrieves a fiscal period
+func (u *FiscalUseCase) GetPeriod(ctx context.Context, id uint) (*entity.FiscalPeriod, error) {
+	period, err := u.fiscalRepo.GetPeriod(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, ErrFiscalPeriodNotFound
+		}
+		return nil, fmt.Errorf("error getting fiscal period: %w", err)
+	}
+	return period, nil
+}
+
+// ClosePeriod closes a fiscal period, after which invoices, payments and
+// stock adjustments dated in it are refused
+func (u *FiscalUseCase) ClosePeriod(ctx context.Context, id uint, userID uint) (*entity.FiscalPeriod, error) {
+	period, err := u.GetPeriod(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if period.Closed() {
+		return nil, ErrFiscalPeriodNotOpen
+	}
+
+	now := time.Now()
+	period.Status = entity.FiscalPeriodClosed
+	period.ClosedAt = &now
+	period.ClosedBy = &userID
+	if err := u.fiscalRepo.UpdatePeriod(ctx, period); err != nil {
+		return nil, fmt.Errorf("error closing fiscal period: %w", err)
+	}
+	return period, nil
+}
+
+// ReopenPeriod opens a closed fiscal period for postings again
+func (u *FiscalUseCase) ReopenPeriod(ctx context.Context, id uint) (*entity.FiscalPeriod, error) {
+	period, err := u.GetPeriod(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !period.Closed() {
+		return nil, ErrFiscalPeriodNotClosed
+	}
+
+	period.Status = entity.FiscalPeriodOpen
+	period.ClosedAt = nil
+	period.ClosedBy = nil
+	if err := u.fiscalRepo.UpdatePeriod(ctx, period); err != nil {
+		return nil, fmt.Errorf("error reopening fiscal period: %w", err)
+	}
+	return period, nil
+}
+
+// CheckOpen returns ErrFiscalPeriodLocked when a posting date falls in a
+// closed fiscal period, unless ctx overrides closed periods. Dates outside
+// the fiscal years set up are open.
+func (u *FiscalUseCase) CheckOpen(ctx context.Context, date time.Time) error {
+	period, err := u.fiscalRepo.PeriodAt(ctx, date)
+	if err != nil {
+		return fmt.Errorf("error getting fiscal period: %w", err)
+	}
+	if period == nil || !period.Closed() {
+		return nil
+	}
+	if entity.ClosedPeriodOverride(ctx) {
+		logging.FromContext(ctx).Warn("posting dated in closed fiscal period", "fiscal_period", period.Name, "date", date.Format("2006-01-02"))
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrFiscalPeriodLocked, period.Name)
+}
+
+// PeriodsBetween lists the fiscal periods overlapping two dates, in order
+func (u *FiscalUseCase) PeriodsBetween(ctx context.Context, from, to time.Time) ([]entity.FiscalPeriod, error) {
+	periods, err := u.fiscalRepo.PeriodsBetween(ctx, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("error listing fiscal periods: %w", err)
+	}
+	return periods, nil
+}
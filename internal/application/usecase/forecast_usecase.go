@@ -0,0 +1,402 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
+)
+
+var (
+	ErrInvalidForecastDimension = entity.NewError(entity.ErrCodeInvalidArgument, "forecast dimension must be CUSTOMER or CATEGORY")
+	ErrForecastPeriodNotClosed  = entity.NewError(entity.ErrCodeFailedPrecondition, "forecast accuracy is only available for closed months")
+)
+
+// Sales forecast parameter defaults and limits
+const (
+	defaultForecastHorizon = 6
+	maxForecastHorizon     = 24
+	defaultForecastWindow  = 3
+	maxForecastWindow      = 12
+	defaultForecastHistory = 36
+	maxForecastHistory     = 120
+)
+
+// ForecastUseCase forecasts monthly revenue per customer and SKU category and
+// tracks stored forecasts against actual sales
+type ForecastUseCase struct {
+	forecastRepo *repository.ForecastRepository
+}
+
+// NewForecastUseCase creates a new forecast use case
+func NewForecastUseCase(forecastRepo *repository.ForecastRepository) *ForecastUseCase {
+	return &ForecastUseCase{
+		forecastRepo: forecastRepo,
+	}
+}
+
+// Forecast forecasts monthly revenue per customer or category with a seasonal
+// moving average: the average of the last closed months, adjusted for the
+// seasonality of each calendar month once a year of history is available.
+func (u *ForecastUseCase) Forecast(ctx context.Context, req *entity.SalesForecastRequest) (*entity.SalesForecastResult, error) {
+	if err := normalizeForecastRequest(req); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	current := monthStart(now)
+	historyStart := current.AddDate(0, -req.HistoryMonths, 0)
+
+	revenue, names, err := u.revenueByKey(ctx, req.Dimension, historyStart, current)
+	if err != nil {
+		return nil, err
+	}
+
+	months := make([]time.Time, req.HistoryMonths)
+	for i := range months {
+		months[i] = historyStart.AddDate(0, i, 0)
+	}
+	targets := make([]time.Time, req.Horizon)
+	result := &entity.SalesForecastResult{
+		Dimension:   req.Dimension,
+		Window:      req.Window,
+		Periods:     make([]string, req.Horizon),
+		Forecasts:   []entity.SalesForecast{},
+		Totals:      make(map[string]float64, req.Horizon),
+		GeneratedAt: now,
+	}
+	for i := range targets {
+		targets[i] = current.AddDate(0, i, 0)
+		result.Periods[i] = targets[i].Format(periodLayout)
+		result.Totals[result.Periods[i]] = 0
+	}
+
+	keys := make([]string, 0, len(revenue))
+	for key := range revenue {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		// The series starts with the key's first sale so that months before a
+		// customer or category existed do not drag its average down
+		first := 0
+		for first < len(months) && revenue[key][months[first].Format(periodLayout)] == 0 {
+			first++
+		}
+		series := make([]float64, 0, len(months)-first)
+		for _, month := range months[first:] {
+			series = append(series, revenue[key][month.Format(periodLayout)])
+		}
+
+		values, indices := seasonalMovingAverage(series, months[first:], targets, req.Window)
+		for i, target := range targets {
+			period := target.Format(periodLayout)
+			result.Forecasts = append(result.Forecasts, entity.SalesForecast{
+				Dimension:     req.Dimension,
+				Key:           key,
+				Name:          names[key],
+				Period:        period,
+				Forecast:      values[i],
+				SeasonalIndex: indices[i],
+				GeneratedAt:   now,
+			})
+			result.Totals[period] = roundAmount(result.Totals[period] + values[i])
+		}
+	}
+	return result, nil
+}
+
+// Snapshot forecasts and stores the result as the current month's forecast of
+// record, replacing any snapshot of the same dimension taken earlier this month
+func (u *ForecastUseCase) Snapshot(ctx context.Context, req *entity.SalesForecastRequest) (*entity.SalesForecastResult, error) {
+	result, err := u.Forecast(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	generatedFor := result.GeneratedAt.Format(periodLayout)
+	for i := range result.Forecasts {
+		result.Forecasts[i].GeneratedFor = generatedFor
+	}
+	if err := u.forecastRepo.ReplaceSnapshot(ctx, result.Dimension, generatedFor, result.Forecasts); err != nil {
+		return nil, fmt.Errorf("error storing sales forecast: %w", err)
+	}
+	return result, nil
+}
+
+// SnapshotCurrentMonth stores a forecast of every dimension not yet snapshot this
+// month, with default parameters, and returns the number of forecasts stored
+func (u *ForecastUseCase) SnapshotCurrentMonth(ctx context.Context) (int, error) {
+	generatedFor := time.Now().Format(periodLayout)
+
+	stored := 0
+	for _, dimension := range []entity.ForecastDimension{entity.ForecastDimensionCustomer, entity.ForecastDimensionCategory} {
+		exists, err := u.forecastRepo.HasSnapshot(ctx, dimension, generatedFor)
+		if err != nil {
+			return stored, fmt.Errorf("error checking sales forecast snapshot: %w", err)
+		}
+		if exists {
+			continue
+		}
+
+		result, err := u.Snapshot(ctx, &entity.SalesForecastRequest{Dimension: dimension})
+		if err != nil {
+			return stored, err
+		}
+		stored += len(result.Forecasts)
+	}
+	return stored, nil
+}
+
+// Accuracy compares stored forecasts with actual sales for the closed months from
+// one YYYY-MM period to another. Each month is judged by the latest snapshot taken
+// no later than that month. Without a range, the last six closed months are used.
+func (u *ForecastUseCase) Accuracy(ctx context.Context, dimension entity.ForecastDimension, from, to string) (*entity.ForecastAccuracy, error) {
+	if dimension != entity.ForecastDimensionCustomer && dimension != entity.ForecastDimensionCategory {
+		return nil, ErrInvalidForecastDimension
+	}
+
+	current := monthStart(time.Now())
+	if to == "" {
+		to = current.AddDate(0, -1, 0).Format(periodLayout)
+	}
+	toStart, err := time.ParseInLocation(periodLayout, to, time.Local)
+	if err != nil {
+		return nil, ErrInvalidPeriod
+	}
+	if from == "" {
+		from = toStart.AddDate(0, -5, 0).Format(periodLayout)
+	}
+	fromStart, err := time.ParseInLocation(periodLayout, from, time.Local)
+	if err != nil || fromStart.After(toStart) {
+		return nil, ErrInvalidPeriod
+	}
+	if !toStart.Before(current) {
+		return nil, ErrForecastPeriodNotClosed
+	}
+
+	snapshots, err := u.forecastRepo.ListSnapshots(ctx, dimension, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("error listing sales forecasts: %w", err)
+	}
+	actuals, names, err := u.revenueByKey(ctx, dimension, fromStart, toStart.AddDate(0, 1, 0))
+	if err != nil {
+		return nil, err
+	}
+
+	// Snapshots arrive oldest first, so later ones replace earlier ones
+	type lineKey struct{ key, period string }
+	forecasts := make(map[lineKey]entity.SalesForecast)
+	forecastPeriods := make(map[string]bool)
+	for _, snapshot := range snapshots {
+		forecasts[lineKey{snapshot.Key, snapshot.Period}] = snapshot
+		forecastPeriods[snapshot.Period] = true
+	}
+	// Sales to keys nobody forecast count as misses in months that were forecast
+	for key, byPeriod := range actuals {
+		for period, actual := range byPeriod {
+			k := lineKey{key, period}
+			if _, ok := forecasts[k]; !ok && actual != 0 && forecastPeriods[period] {
+				forecasts[k] = entity.SalesForecast{Key: key, Name: names[key], Period: period}
+			}
+		}
+	}
+
+	result := &entity.ForecastAccuracy{
+		Dimension: dimension,
+		From:      from,
+		To:        to,
+		Lines:     []entity.ForecastAccuracyLine{},
+	}
+	var totalForecast, totalActual, totalAbsError, sumAPE float64
+	var apeCount int
+	for k, forecast := range forecasts {
+		actual := roundAmount(actuals[k.key][k.period])
+		line := entity.ForecastAccuracyLine{
+			Key:      k.key,
+			Name:     forecast.Name,
+			Period:   k.period,
+			Forecast: forecast.Forecast,
+			Actual:   actual,
+			Error:    roundAmount(actual - forecast.Forecast),
+		}
+		if line.Name == "" {
+			line.Name = names[k.key]
+		}
+		if actual != 0 {
+			ape := roundAmount(math.Abs(line.Error) / math.Abs(actual) * 100)
+			line.AbsolutePercentError = &ape
+			sumAPE += ape
+			apeCount++
+		}
+		totalForecast += forecast.Forecast
+		totalActual += actual
+		totalAbsError += math.Abs(line.Error)
+		result.Lines = append(result.Lines, line)
+	}
+	sort.Slice(result.Lines, func(i, j int) bool {
+		if result.Lines[i].Period != result.Lines[j].Period {
+			return result.Lines[i].Period < result.Lines[j].Period
+		}
+		return result.Lines[i].Key < result.Lines[j].Key
+	})
+
+	if apeCount > 0 {
+		result.MAPE = roundAmount(sumAPE / float64(apeCount))
+	}
+	if totalActual != 0 {
+		result.WAPE = roundAmount(totalAbsError / math.Abs(totalActual) * 100)
+		result.Bias = roundAmount((totalForecast - totalActual) / math.Abs(totalActual) * 100)
+	}
+	return result, nil
+}
+
+// revenueByKey sums base currency revenue per key and month for the orders placed
+// in a date range. Category revenue is the sum of the order lines of the category's
+// SKUs; customer revenue is the order total. It also returns display names per key.
+func (u *ForecastUseCase) revenueByKey(ctx context.Context, dimension entity.ForecastDimension, from, to time.Time) (map[string]map[string]float64, map[string]string, error) {
+	orders, err := u.forecastRepo.ListSalesHistory(ctx, from, to)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error getting sales history: %w", err)
+	}
+
+	revenue := make(map[string]map[string]float64)
+	add := func(key, period string, amount float64) {
+		if revenue[key] == nil {
+			revenue[key] = make(map[string]float64)
+		}
+		revenue[key][period] += amount
+	}
+	names := make(map[string]string)
+
+	switch dimension {
+	case entity.ForecastDimensionCategory:
+		categories, err := u.forecastRepo.GetSKUCategories(ctx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error getting SKU categories: %w", err)
+		}
+		for _, order := range orders {
+			rate := order.ExchangeRate
+			if rate <= 0 {
+				rate = 1
+			}
+			period := order.OrderDate.In(time.Local).Format(periodLayout)
+			for _, item := range order.Items {
+				category := categories[item.SKUID]
+				if category == "" {
+					category = "UNCATEGORIZED"
+				}
+				add(category, period, item.TotalPrice*rate)
+			}
+		}
+		for key := range revenue {
+			names[key] = key
+		}
+
+	default:
+		var clientIDs []uint
+		for _, order := range orders {
+			key := strconv.FormatUint(uint64(order.ClientID), 10)
+			if revenue[key] == nil {
+				clientIDs = append(clientIDs, order.ClientID)
+			}
+			add(key, order.OrderDate.In(time.Local).Format(periodLayout), order.BaseGrandTotal)
+		}
+		clientNames, err := u.forecastRepo.GetClientNames(ctx, clientIDs)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error getting client names: %w", err)
+		}
+		for id, name := range clientNames {
+			names[strconv.FormatUint(uint64(id), 10)] = name
+		}
+	}
+	return revenue, names, nil
+}
+
+// normalizeForecastRequest validates the forecast dimension and applies the
+// parameter defaults and limits
+func normalizeForecastRequest(req *entity.SalesForecastRequest) error {
+	if req.Dimension == "" {
+		req.Dimension = entity.ForecastDimensionCategory
+	}
+	if req.Dimension != entity.ForecastDimensionCustomer && req.Dimension != entity.ForecastDimensionCategory {
+		return ErrInvalidForecastDimension
+	}
+	if req.Horizon <= 0 {
+		req.Horizon = defaultForecastHorizon
+	}
+	if req.Horizon > maxForecastHorizon {
+		req.Horizon = maxForecastHorizon
+	}
+	if req.Window <= 0 {
+		req.Window = defaultForecastWindow
+	}
+	if req.Window > maxForecastWindow {
+		req.Window = maxForecastWindow
+	}
+	if req.HistoryMonths <= 0 {
+		req.HistoryMonths = defaultForecastHistory
+	}
+	if req.HistoryMonths > maxForecastHistory {
+		req.HistoryMonths = maxForecastHistory
+	}
+	if req.HistoryMonths < req.Window {
+		req.HistoryMonths = req.Window
+	}
+	return nil
+}
+
+// seasonalMovingAverage forecasts the target months of a monthly series. The level
+// is the moving average of the last window months with seasonality removed, and
+// each forecast is that level times the target month's seasonal index. Seasonal
+// indices need a full year of history; until then every index is 1.
+func seasonalMovingAverage(series []float64, months, targets []time.Time, window int) ([]float64, []float64) {
+	var index [13]float64
+	for m := range index {
+		index[m] = 1
+	}
+	if len(series) >= 12 {
+		var sums, counts [13]float64
+		for i, value := range series {
+			sums[months[i].Month()] += value
+			counts[months[i].Month()]++
+		}
+		var overall float64
+		for m := time.January; m <= time.December; m++ {
+			overall += sums[m] / counts[m]
+		}
+		overall /= 12
+		if overall > 0 {
+			for m := time.January; m <= time.December; m++ {
+				index[m] = sums[m] / counts[m] / overall
+			}
+		}
+	}
+
+	var level float64
+	var used int
+	for i := len(series) - 1; i >= 0 && i >= len(series)-window; i-- {
+		// A month that never sells says nothing about the level
+		if idx := index[months[i].Month()]; idx > 0 {
+			level += series[i] / idx
+			used++
+		}
+	}
+	if used > 0 {
+		level /= float64(used)
+	}
+
+	values := make([]float64, len(targets))
+	indices := make([]float64, len(targets))
+	for i, target := range targets {
+		indices[i] = math.Round(index[target.Month()]*10000) / 10000
+		values[i] = roundAmount(level * index[target.Month()])
+	}
+	return values, indices
+}
@@ -0,0 +1,534 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/eventbus"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
+)
+
+var (
+	ErrInvalidFulfillmentPeriod     = entity.NewError(entity.ErrCodeInvalidArgument, "start date must not be after end date")
+	ErrFulfillmentAlertNotFound     = entity.NewError(entity.ErrCodeNotFound, "fulfillment SLA alert not found")
+	ErrFulfillmentAlertAcknowledged = entity.NewError(entity.ErrCodeFailedPrecondition, "fulfillment SLA alert is already acknowledged")
+)
+
+// dropShipStoreName labels the deliveries vendors shipped, which have no store
+const dropShipStoreName = "Drop ship"
+
+// FulfillmentSettings sets the fulfillment SLA
+type FulfillmentSettings struct {
+	ShipDays       int     // days from order to shipment, and to the committed date of orders without a promised date
+	FillRateTarget float64 // percentage of ordered units to ship by the committed date
+	LookbackDays   int     // period measured by default and by the SLA check
+}
+
+// FulfillmentUseCase reports the open order book and the fulfillment
+// performance of warehouses against the SLA, and raises alerts when a
+// warehouse breaches it
+type FulfillmentUseCase struct {
+	fulfillmentRepo *repository.FulfillmentRepository
+	marginRepo      *repository.MarginRepository
+	costRepo        *repository.CostToServeRepository
+	settings        FulfillmentSettings
+	bus             *eventbus.Bus
+}
+
+// NewFulfillmentUseCase creates a new fulfillment use case
+func NewFulfillmentUseCase(fulfillmentRepo *repository.FulfillmentRepository, marginRepo *repository.MarginRepository, costRepo *repository.CostToServeRepository, settings FulfillmentSettings, bus *eventbus.Bus) *FulfillmentUseCase {
+	if settings.ShipDays <= 0 {
+		settings.ShipDays = 2
+	}
+	if settings.FillRateTarget <= 0 {
+		settings.FillRateTarget = 95
+	}
+	if settings.LookbackDays <= 0 {
+		settings.LookbackDays = 30
+	}
+	return &FulfillmentUseCase{
+		fulfillmentRepo: fulfillmentRepo,
+		marginRepo:      marginRepo,
+		costRepo:        costRepo,
+		settings:        settings,
+		bus:             bus,
+	}
+}
+
+// OpenOrders lists the sales orders with lines left to ship, earliest
+// committed date first. A line is late when it is not fully shipped and its
+// order's committed date has passed.
+func (u *FulfillmentUseCase) OpenOrders(ctx context.Context, filter *entity.OpenOrderFilter) (*entity.OpenOrderBook, error) {
+	now := time.Now()
+	today := truncateDay(now)
+
+	orders, err := u.fulfillmentRepo.ListOpenOrders(ctx, filter.ClientID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing open sales orders: %w", err)
+	}
+	deliveries, err := u.orderDeliveries(ctx, orders)
+	if err != nil {
+		return nil, err
+	}
+
+	book := &entity.OpenOrderBook{Orders: []entity.OpenOrder{}, GeneratedAt: now}
+	skuIDs := make(map[string]bool)
+	clientIDs := make(map[uint]bool)
+	for _, order := range orders {
+		committed := u.committedDate(order)
+		late := committed.Before(today)
+		shipped := shippedQuantities(order.Items, deliveries[order.ID], nil)
+
+		open := entity.OpenOrder{
+			OrderID:       order.ID,
+			OrderNumber:   order.OrderNumber,
+			ClientID:      order.ClientID,
+			OrderDate:     order.OrderDate,
+			CommittedDate: committed,
+			Status:        order.Status,
+		}
+		var remaining, lateValue float64
+		for _, item := range orderedQuantities(order.Items) {
+			line := entity.OpenOrderLine{
+				SKUID:             item.SKUID,
+				OrderedQuantity:   item.Quantity,
+				ShippedQuantity:   math.Min(shipped[item.SKUID], item.Quantity),
+				RemainingQuantity: math.Max(item.Quantity-shipped[item.SKUID], 0),
+			}
+			if line.RemainingQuantity > 0 {
+				line.OpenValue = roundAmount((item.TotalPrice - item.TaxAmount) * order.ExchangeRate * line.RemainingQuantity / item.Quantity)
+				line.Late = late
+			}
+			if line.Late {
+				open.LateLines++
+				lateValue += line.OpenValue
+			}
+			remaining += line.RemainingQuantity
+			open.OpenValue += line.OpenValue
+			open.Lines = append(open.Lines, line)
+		}
+		if remaining <= 0 || (filter.LateOnly && open.LateLines == 0) {
+			continue
+		}
+		if open.LateLines > 0 {
+			open.DaysLate = daysBetween(committed, today)
+			book.LateOrders++
+			book.LateLines += open.LateLines
+			book.LateValue += lateValue
+		}
+		open.OpenValue = roundAmount(open.OpenValue)
+		book.OpenValue += open.OpenValue
+		book.Orders = append(book.Orders, open)
+		for _, line := range open.Lines {
+			skuIDs[line.SKUID] = true
+		}
+		clientIDs[order.ClientID] = true
+	}
+	book.OrderCount = len(book.Orders)
+	book.OpenValue = roundAmount(book.OpenValue)
+	book.LateValue = roundAmount(book.LateValue)
+
+	if err := u.nameOpenOrders(ctx, book, skuIDs, clientIDs); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(book.Orders, func(i, j int) bool {
+		if !book.Orders[i].CommittedDate.Equal(book.Orders[j].CommittedDate) {
+			return book.Orders[i].CommittedDate.Before(book.Orders[j].CommittedDate)
+		}
+		return book.Orders[i].OrderNumber < book.Orders[j].OrderNumber
+	})
+	return book, nil
+}
+
+// SLAReport measures the fulfillment performance of each warehouse over a
+// period, by default the lookback days up to now:
+//   - the order-to-ship lead time of the deliveries shipped in the period, in
+//     calendar days, against the SLA ship days
+//   - the fill rate of the orders whose committed date falls in the period, the
+//     percentage of ordered units shipped by the end of that date, against the
+//     target. A warehouse's fill rate counts the units of its deliveries.
+func (u *FulfillmentUseCase) SLAReport(ctx context.Context, filter *entity.FulfillmentSLAFilter) (*entity.FulfillmentSLAReport, error) {
+	end := time.Now()
+	if filter.EndDate != nil {
+		end = *filter.EndDate
+	}
+	start := truncateDay(end).AddDate(0, 0, -u.settings.LookbackDays)
+	if filter.StartDate != nil {
+		start = *filter.StartDate
+	}
+	if start.After(end) {
+		return nil, ErrInvalidFulfillmentPeriod
+	}
+
+	report := &entity.FulfillmentSLAReport{
+		StartDate:      start,
+		EndDate:        end,
+		SLAShipDays:    u.settings.ShipDays,
+		FillRateTarget: u.settings.FillRateTarget,
+		Warehouses:     []entity.WarehouseFulfillment{},
+		GeneratedAt:    time.Now(),
+	}
+	warehouses := make(map[string]*entity.WarehouseFulfillment)
+	warehouse := func(storeID string) *entity.WarehouseFulfillment {
+		w, ok := warehouses[storeID]
+		if !ok {
+			w = &entity.WarehouseFulfillment{StoreID: storeID}
+			warehouses[storeID] = w
+		}
+		return w
+	}
+
+	// Lead times of the deliveries shipped in the period
+	shipments, err := u.fulfillmentRepo.ListShippedDeliveries(ctx, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("error listing shipped deliveries: %w", err)
+	}
+	orderIDs := make([]string, 0, len(shipments))
+	for _, delivery := range shipments {
+		orderIDs = append(orderIDs, delivery.SalesOrderID)
+	}
+	shippedOrders, err := u.fulfillmentRepo.GetOrders(ctx, orderIDs)
+	if err != nil {
+		return nil, fmt.Errorf("error getting sales orders: %w", err)
+	}
+	orderDates := make(map[string]time.Time, len(shippedOrders))
+	for _, order := range shippedOrders {
+		orderDates[order.ID] = order.OrderDate
+	}
+	leadDays := make(map[string]int)
+	totalLeadDays := 0
+	for _, delivery := range shipments {
+		orderDate, ok := orderDates[delivery.SalesOrderID]
+		if !ok {
+			continue
+		}
+		days := daysBetween(orderDate, *delivery.ShippedAt)
+		w := warehouse(delivery.StoreID)
+		w.Shipments++
+		if days > u.settings.ShipDays {
+			w.LateShipments++
+		}
+		leadDays[delivery.StoreID] += days
+		report.Shipments++
+		totalLeadDays += days
+	}
+
+	// Fill rates of the orders committed in the period
+	orders, err := u.fulfillmentRepo.ListCommittedOrders(ctx, start, end, u.settings.ShipDays)
+	if err != nil {
+		return nil, fmt.Errorf("error listing committed sales orders: %w", err)
+	}
+	committed := orders[:0]
+	for _, order := range orders {
+		date := u.committedDate(order)
+		if !date.Before(truncateDay(start)) && !date.After(end) {
+			committed = append(committed, order)
+		}
+	}
+	deliveries, err := u.orderDeliveries(ctx, committed)
+	if err != nil {
+		return nil, err
+	}
+	for _, order := range committed {
+		cutoff := endOfDay(u.committedDate(order))
+		shipped := shippedQuantities(order.Items, deliveries[order.ID], &cutoff)
+		for _, item := range orderedQuantities(order.Items) {
+			report.OrderedQuantity += item.Quantity
+			report.ShippedOnTime += math.Min(shipped[item.SKUID], item.Quantity)
+		}
+		for _, delivery := range deliveries[order.ID] {
+			onTime := deliveryShipped(delivery, &cutoff)
+			w := warehouse(delivery.StoreID)
+			for _, item := range delivery.Items {
+				w.OrderedQuantity += item.OrderedQuantity
+				if onTime {
+					w.ShippedOnTime += math.Min(item.ShippedQuantity, item.OrderedQuantity)
+				}
+			}
+		}
+	}
+
+	storeIDs := make([]string, 0, len(warehouses))
+	for storeID := range warehouses {
+		if storeID != "" {
+			storeIDs = append(storeIDs, storeID)
+		}
+	}
+	names, err := u.marginRepo.GetStoreNames(ctx, storeIDs)
+	if err != nil {
+		return nil, fmt.Errorf("error getting store names: %w", err)
+	}
+	for storeID, w := range warehouses {
+		w.StoreName = names[storeID]
+		if storeID == "" {
+			w.StoreName = dropShipStoreName
+		}
+		if w.Shipments > 0 {
+			w.AvgLeadTimeDays = roundAmount(float64(leadDays[storeID]) / float64(w.Shipments))
+			w.OnTimeShipPercent = roundAmount(float64(w.Shipments-w.LateShipments) / float64(w.Shipments) * 100)
+			w.LeadTimeBreached = w.AvgLeadTimeDays > float64(u.settings.ShipDays)
+		}
+		if w.OrderedQuantity > 0 {
+			w.FillRate = roundAmount(w.ShippedOnTime / w.OrderedQuantity * 100)
+			w.FillRateBreached = w.FillRate < u.settings.FillRateTarget
+		}
+		report.Warehouses = append(report.Warehouses, *w)
+	}
+	if report.Shipments > 0 {
+		report.AvgLeadTimeDays = roundAmount(float64(totalLeadDays) / float64(report.Shipments))
+	}
+	if report.OrderedQuantity > 0 {
+		report.FillRate = roundAmount(report.ShippedOnTime / report.OrderedQuantity * 100)
+	}
+
+	sort.Slice(report.Warehouses, func(i, j int) bool {
+		return report.Warehouses[i].StoreName < report.Warehouses[j].StoreName
+	})
+	return report, nil
+}
+
+// Run checks the SLA over the lookback days, raises an alert for each
+// warehouse and measure newly breaching it and resolves the alerts of those
+// meeting it again
+func (u *FulfillmentUseCase) Run(ctx context.Context) (*entity.FulfillmentSLARunResult, error) {
+	report, err := u.SLAReport(ctx, &entity.FulfillmentSLAFilter{})
+	if err != nil {
+		return nil, err
+	}
+	unresolved, err := u.fulfillmentRepo.ListUnresolvedAlerts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error listing fulfillment SLA alerts: %w", err)
+	}
+	open := make(map[string]uint, len(unresolved))
+	for _, alert := range unresolved {
+		open[alert.StoreID+"|"+string(alert.Metric)] = alert.ID
+	}
+
+	result := &entity.FulfillmentSLARunResult{
+		Warehouses: len(report.Warehouses),
+		Alerts:     []entity.FulfillmentSLAAlert{},
+		CheckedAt:  report.GeneratedAt,
+	}
+	breached := make(map[string]bool)
+	for _, w := range report.Warehouses {
+		if w.LeadTimeBreached || w.FillRateBreached {
+			result.Breaching++
+		}
+		for _, check := range []struct {
+			metric    entity.FulfillmentSLAMetric
+			breached  bool
+			value     float64
+			threshold float64
+		}{
+			{entity.FulfillmentLeadTime, w.LeadTimeBreached, w.AvgLeadTimeDays, float64(u.settings.ShipDays)},
+			{entity.FulfillmentFillRate, w.FillRateBreached, w.FillRate, u.settings.FillRateTarget},
+		} {
+			key := w.StoreID + "|" + string(check.metric)
+			if !check.breached {
+				continue
+			}
+			breached[key] = true
+			if _, ok := open[key]; ok {
+				continue
+			}
+			result.Alerts = append(result.Alerts, entity.FulfillmentSLAAlert{
+				StoreID:     w.StoreID,
+				StoreName:   w.StoreName,
+				Metric:      check.metric,
+				Value:       check.value,
+				Threshold:   check.threshold,
+				PeriodStart: report.StartDate,
+				PeriodEnd:   report.EndDate,
+			})
+		}
+	}
+
+	var resolved []uint
+	for key, id := range open {
+		if !breached[key] {
+			resolved = append(resolved, id)
+		}
+	}
+	if err := u.fulfillmentRepo.ResolveAlerts(ctx, resolved, result.CheckedAt); err != nil {
+		return nil, fmt.Errorf("error resolving fulfillment SLA alerts: %w", err)
+	}
+	result.Resolved = len(resolved)
+
+	if err := u.fulfillmentRepo.CreateAlerts(ctx, result.Alerts); err != nil {
+		return nil, fmt.Errorf("error storing fulfillment SLA alerts: %w", err)
+	}
+	for i := range result.Alerts {
+		u.bus.Publish(ctx, entity.FulfillmentSLABreached{Alert: &result.Alerts[i]})
+	}
+	return result, nil
+}
+
+// ListAlerts lists fulfillment SLA alerts
+func (u *FulfillmentUseCase) ListAlerts(ctx context.Context, filter *entity.FulfillmentSLAAlertFilter) ([]entity.FulfillmentSLAAlert, int64, error) {
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+	if filter.PageSize <= 0 {
+		filter.PageSize = 20
+	}
+
+	alerts, total, err := u.fulfillmentRepo.ListAlerts(ctx, filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error listing fulfillment SLA alerts: %w", err)
+	}
+	return alerts, total, nil
+}
+
+// AcknowledgeAlert marks a fulfillment SLA alert as handled
+func (u *FulfillmentUseCase) AcknowledgeAlert(ctx context.Context, id uint, userID *uint) (*entity.FulfillmentSLAAlert, error) {
+	alert, err := u.fulfillmentRepo.GetAlert(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, ErrFulfillmentAlertNotFound
+		}
+		return nil, fmt.Errorf("error getting fulfillment SLA alert: %w", err)
+	}
+	if alert.AcknowledgedAt != nil {
+		return nil, ErrFulfillmentAlertAcknowledged
+	}
+
+	now := time.Now()
+	alert.AcknowledgedAt = &now
+	alert.AcknowledgedBy = userID
+	if err := u.fulfillmentRepo.UpdateAlert(ctx, alert); err != nil {
+		return nil, fmt.Errorf("error acknowledging fulfillment SLA alert: %w", err)
+	}
+	return alert, nil
+}
+
+// committedDate returns the day an order is committed to ship by: its
+// promised date, or its order date plus the SLA ship days
+func (u *FulfillmentUseCase) committedDate(order entity.FulfillmentSalesOrder) time.Time {
+	if order.PromisedDate != nil {
+		return truncateDay(*order.PromisedDate)
+	}
+	return truncateDay(order.OrderDate).AddDate(0, 0, u.settings.ShipDays)
+}
+
+// orderDeliveries returns the deliveries of the given orders by order
+func (u *FulfillmentUseCase) orderDeliveries(ctx context.Context, orders []entity.FulfillmentSalesOrder) (map[string][]entity.FulfillmentDelivery, error) {
+	orderIDs := make([]string, 0, len(orders))
+	for _, order := range orders {
+		orderIDs = append(orderIDs, order.ID)
+	}
+	deliveries, err := u.fulfillmentRepo.ListOrderDeliveries(ctx, orderIDs)
+	if err != nil {
+		return nil, fmt.Errorf("error listing deliveries: %w", err)
+	}
+	byOrder := make(map[string][]entity.FulfillmentDelivery, len(orders))
+	for _, delivery := range deliveries {
+		byOrder[delivery.SalesOrderID] = append(byOrder[delivery.SalesOrderID], delivery)
+	}
+	return byOrder, nil
+}
+
+// nameOpenOrders fills in the client names and SKU codes of the open order book
+func (u *FulfillmentUseCase) nameOpenOrders(ctx context.Context, book *entity.OpenOrderBook, skuIDs map[string]bool, clientIDs map[uint]bool) error {
+	ids := make([]string, 0, len(skuIDs))
+	for skuID := range skuIDs {
+		ids = append(ids, skuID)
+	}
+	skus, err := u.marginRepo.GetSKUs(ctx, ids)
+	if err != nil {
+		return fmt.Errorf("error getting SKUs: %w", err)
+	}
+	clients := make([]uint, 0, len(clientIDs))
+	for clientID := range clientIDs {
+		clients = append(clients, clientID)
+	}
+	names, err := u.costRepo.GetClientNames(ctx, clients)
+	if err != nil {
+		return fmt.Errorf("error getting client names: %w", err)
+	}
+
+	for i := range book.Orders {
+		order := &book.Orders[i]
+		order.ClientName = names[order.ClientID]
+		for j := range order.Lines {
+			line := &order.Lines[j]
+			line.SKUCode = skus[line.SKUID].SKUCode
+			line.Name = skus[line.SKUID].Name
+		}
+	}
+	return nil
+}
+
+// orderedQuantities merges the lines of an order by SKU, in order of first
+// appearance
+func orderedQuantities(items entity.SalesOrderItems) []entity.SalesOrderItem {
+	merged := make([]entity.SalesOrderItem, 0, len(items))
+	index := make(map[string]int, len(items))
+	for _, item := range items {
+		if i, ok := index[item.SKUID]; ok {
+			merged[i].Quantity += item.Quantity
+			merged[i].TotalPrice += item.TotalPrice
+			merged[i].TaxAmount += item.TaxAmount
+			continue
+		}
+		index[item.SKUID] = len(merged)
+		merged = append(merged, item)
+	}
+	return merged
+}
+
+// deliveryShipped reports whether a delivery has shipped, by the cutoff when
+// one is given
+func deliveryShipped(delivery entity.FulfillmentDelivery, cutoff *time.Time) bool {
+	if delivery.ShippedAt == nil || delivery.Status == entity.DeliveryOrderStatusPending ||
+		delivery.Status == entity.DeliveryOrderStatusPreparing {
+		return false
+	}
+	return cutoff == nil || !delivery.ShippedAt.After(*cutoff)
+}
+
+// shippedQuantities returns the quantity of each SKU of an order its
+// deliveries shipped, by the cutoff when one is given. Kits count as shipped
+// as far as the least shipped of their components.
+func shippedQuantities(items entity.SalesOrderItems, deliveries []entity.FulfillmentDelivery, cutoff *time.Time) map[string]float64 {
+	shipped := make(map[string]float64)
+	components := make(map[string]map[string]float64)
+	for _, delivery := range deliveries {
+		if !deliveryShipped(delivery, cutoff) {
+			continue
+		}
+		for _, item := range delivery.Items {
+			if item.KitSKUID == "" {
+				shipped[item.SKUID] += item.ShippedQuantity
+				continue
+			}
+			if components[item.KitSKUID] == nil {
+				components[item.KitSKUID] = make(map[string]float64)
+			}
+			components[item.KitSKUID][item.SKUID] += item.ShippedQuantity
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, kit := range items {
+		if len(kit.Components) == 0 || kit.Quantity <= 0 || seen[kit.SKUID] {
+			continue
+		}
+		seen[kit.SKUID] = true
+		kits := math.Inf(1)
+		for _, component := range kit.Components {
+			perKit := component.Quantity / kit.Quantity
+			if perKit > 0 {
+				kits = math.Min(kits, components[kit.SKUID][component.SKUID]/perKit)
+			}
+		}
+		if !math.IsInf(kits, 1) {
+			shipped[kit.SKUID] += kits
+		}
+	}
+	return shipped
+}
@@ -0,0 +1,102 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
+)
+
+// idempotencyLockTimeout is how long a request holds its key. A key still in
+// progress after that was left behind by a request that never finished, and
+// may be reused.
+const idempotencyLockTimeout = 5 * time.Minute
+
+// IdempotencyUseCase keeps the responses of requests sent with an
+// Idempotency-Key header for replaying them
+type IdempotencyUseCase struct {
+	idempotencyRepo *repository.IdempotencyRepository
+	ttl             time.Duration // how long a response is replayed
+}
+
+// NewIdempotencyUseCase creates a new idempotency use case
+func NewIdempotencyUseCase(idempotencyRepo *repository.IdempotencyRepository, ttl time.Duration) *IdempotencyUseCase {
+	return &IdempotencyUseCase{
+		idempotencyRepo: idempotencyRepo,
+		ttl:             ttl,
+	}
+}
+
+// Reserve claims the user's key for a request. When the key is already in use
+// it returns the record holding it instead: completed with the response to
+// replay, or still in progress. Expired and abandoned records are replaced.
+func (u *IdempotencyUseCase) Reserve(ctx context.Context, key *entity.IdempotencyKey) (*entity.IdempotencyKey, error) {
+	// A second attempt follows the removal of a stale record
+	for attempt := 0; attempt < 2; attempt++ {
+		key.CreatedAt = time.Now()
+		created, err := u.idempotencyRepo.Create(ctx, key)
+		if err != nil {
+			return nil, fmt.Errorf("error reserving idempotency key: %w", err)
+		}
+		if created {
+			return nil, nil
+		}
+
+		existing, err := u.idempotencyRepo.Get(ctx, key.UserID, key.Key)
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error getting idempotency key: %w", err)
+		}
+		if !u.stale(existing, key.CreatedAt) {
+			return existing, nil
+		}
+		if err := u.idempotencyRepo.Delete(ctx, existing); err != nil {
+			return nil, fmt.Errorf("error removing idempotency key: %w", err)
+		}
+	}
+	return nil, fmt.Errorf("error reserving idempotency key %q: it is being reused concurrently", key.Key)
+}
+
+// stale reports whether a record no longer holds its key
+func (u *IdempotencyUseCase) stale(key *entity.IdempotencyKey, now time.Time) bool {
+	if key.Completed() {
+		return key.CreatedAt.Before(now.Add(-u.ttl))
+	}
+	return key.CreatedAt.Before(now.Add(-idempotencyLockTimeout))
+}
+
+// Complete keeps the response of a reserved key's request
+func (u *IdempotencyUseCase) Complete(ctx context.Context, key *entity.IdempotencyKey, statusCode int, contentType string, response []byte) error {
+	now := time.Now()
+	key.StatusCode = statusCode
+	key.ContentType = contentType
+	key.Response = response
+	key.CompletedAt = &now
+	if err := u.idempotencyRepo.Complete(ctx, key); err != nil {
+		return fmt.Errorf("error completing idempotency key: %w", err)
+	}
+	return nil
+}
+
+// Release frees a reserved key whose request failed, so it can be retried
+func (u *IdempotencyUseCase) Release(ctx context.Context, key *entity.IdempotencyKey) error {
+	if err := u.idempotencyRepo.DeleteInProgress(ctx, key.UserID, key.Key); err != nil {
+		return fmt.Errorf("error releasing idempotency key: %w", err)
+	}
+	return nil
+}
+
+// PurgeExpired removes the keys whose responses are no longer replayed and
+// returns how many there were
+func (u *IdempotencyUseCase) PurgeExpired(ctx context.Context) (int64, error) {
+	purged, err := u.idempotencyRepo.DeleteCreatedBefore(ctx, time.Now().Add(-u.ttl))
+	if err != nil {
+		return 0, fmt.Errorf("error purging idempotency keys: %w", err)
+	}
+	return purged, nil
+}
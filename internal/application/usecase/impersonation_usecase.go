@@ -0,0 +1,193 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/cache"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/eventbus"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
+)
+
+var (
+	ErrImpersonationNotFound     = entity.NewError(entity.ErrCodeNotFound, "impersonation session not found")
+	ErrImpersonationUserNotFound = entity.NewError(entity.ErrCodeNotFound, "user to impersonate not found")
+	ErrImpersonationTooLong      = entity.NewError(entity.ErrCodeInvalidArgument, "impersonation duration exceeds the maximum")
+	ErrImpersonationSelf         = entity.NewError(entity.ErrCodeInvalidArgument, "users cannot impersonate themselves")
+	ErrImpersonationInactiveUser = entity.NewError(entity.ErrCodeFailedPrecondition, "inactive users cannot be impersonated")
+	ErrImpersonationDenied       = entity.NewError(entity.ErrCodePermissionDenied, "users who can impersonate cannot be impersonated")
+	ErrImpersonationNotActive    = entity.NewError(entity.ErrCodeFailedPrecondition, "impersonation session has already ended")
+	ErrImpersonationEndDenied    = entity.NewError(entity.ErrCodePermissionDenied, "only the impersonator, the impersonated user or an admin can end an impersonation session")
+)
+
+// impersonationWindow is what the requests of a session check on it
+type impersonationWindow struct {
+	ExpiresAt time.Time
+	Ended     bool
+}
+
+// ImpersonationUseCase handles admins acting as other users for
+// troubleshooting: starting and ending the time-boxed sessions and telling
+// the requests made in them whether they are still open
+type ImpersonationUseCase struct {
+	repo         *repository.ImpersonationRepository
+	userRepo     *repository.UserRepository
+	bus          *eventbus.Bus
+	maxMinutes   int
+	sessionCache *cache.Namespace // whether each session is open
+}
+
+// NewImpersonationUseCase creates a new impersonation use case. Sessions
+// cannot last longer than maxMinutes.
+func NewImpersonationUseCase(repo *repository.ImpersonationRepository, userRepo *repository.UserRepository, bus *eventbus.Bus, maxMinutes int, c *cache.Cache) *ImpersonationUseCase {
+	if maxMinutes <= 0 {
+		maxMinutes = 60
+	}
+	// A session ended on one instance must be refused by the others at once,
+	// so sessions are only cached when the cache is shared
+	var sessionCache *cache.Namespace
+	if c.Shared() {
+		sessionCache = c.Namespace(cache.NamespaceSessions)
+	}
+	return &ImpersonationUseCase{
+		repo:         repo,
+		userRepo:     userRepo,
+		bus:          bus,
+		maxMinutes:   maxMinutes,
+		sessionCache: sessionCache,
+	}
+}
+
+// StartImpersonation opens a session in which the impersonator acts as the
+// user from now for the requested minutes, and notifies the user. The
+// session's user comes with their role, for issuing its token. Users who can
+// impersonate cannot be impersonated, so a session never gives more than the
+// impersonator already holds.
+func (u *ImpersonationUseCase) StartImpersonation(ctx context.Context, impersonatorID uint, ip string, req *entity.ImpersonationRequest) (*entity.ImpersonationSession, error) {
+	if req.DurationMinutes > u.maxMinutes {
+		return nil, fmt.Errorf("%w of %d minutes", ErrImpersonationTooLong, u.maxMinutes)
+	}
+	if req.UserID == impersonatorID {
+		return nil, ErrImpersonationSelf
+	}
+
+	user, err := u.userRepo.FindByID(req.UserID)
+	if err != nil {
+		return nil, ErrImpersonationUserNotFound
+	}
+	if !user.IsActive() || user.Role == nil {
+		return nil, ErrImpersonationInactiveUser
+	}
+	if user.HasPermission(entity.UserImpersonate) {
+		return nil, ErrImpersonationDenied
+	}
+
+	now := time.Now()
+	session := &entity.ImpersonationSession{
+		ImpersonatorID:  impersonatorID,
+		UserID:          user.ID,
+		Reason:          strings.TrimSpace(req.Reason),
+		DurationMinutes: req.DurationMinutes,
+		IP:              ip,
+		StartedAt:       now,
+		ExpiresAt:       now.Add(time.Duration(req.DurationMinutes) * time.Minute),
+	}
+	if err := u.repo.CreateSession(ctx, session); err != nil {
+		return nil, fmt.Errorf("error creating impersonation session: %w", err)
+	}
+
+	created, err := u.getSession(ctx, session.ID)
+	if err != nil {
+		return nil, err
+	}
+	created.User = user
+	u.bus.Publish(ctx, entity.ImpersonationStarted{Session: created})
+	return created, nil
+}
+
+// EndImpersonation closes an open session. The impersonator, the
+// impersonated user and admins holding user:impersonate can end it.
+func (u *ImpersonationUseCase) EndImpersonation(ctx context.Context, id, userID uint, admin bool) (*entity.ImpersonationSession, error) {
+	session, err := u.getSession(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !admin && userID != session.ImpersonatorID && userID != session.UserID {
+		return nil, ErrImpersonationEndDenied
+	}
+
+	now := time.Now()
+	if !session.ActiveAt(now) {
+		return nil, ErrImpersonationNotActive
+	}
+	session.EndedAt = &now
+	session.EndedBy = &userID
+	if err := u.repo.UpdateSession(ctx, session); err != nil {
+		return nil, fmt.Errorf("error updating impersonation session: %w", err)
+	}
+	u.sessionCache.Invalidate(ctx, strconv.FormatUint(uint64(id), 10))
+	u.bus.Publish(ctx, entity.ImpersonationEnded{Session: session})
+	return session, nil
+}
+
+// GetSession retrieves an impersonation session
+func (u *ImpersonationUseCase) GetSession(ctx context.Context, id uint) (*entity.ImpersonationSession, error) {
+	return u.getSession(ctx, id)
+}
+
+// ListSessions lists the impersonation sessions matching a filter, latest first
+func (u *ImpersonationUseCase) ListSessions(ctx context.Context, filter *entity.ImpersonationFilter) ([]entity.ImpersonationSession, int64, error) {
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+	if filter.PageSize <= 0 {
+		filter.PageSize = 20
+	}
+
+	sessions, total, err := u.repo.ListSessions(ctx, filter, time.Now())
+	if err != nil {
+		return nil, 0, fmt.Errorf("error listing impersonation sessions: %w", err)
+	}
+	return sessions, total, nil
+}
+
+// SessionActive reports whether a session can still be acted in. It is
+// checked on every request made in the session, so it comes from the shared
+// cache when it holds it.
+func (u *ImpersonationUseCase) SessionActive(ctx context.Context, id uint) (bool, error) {
+	window, err := cache.Load(ctx, u.sessionCache, strconv.FormatUint(uint64(id), 10), func() (*impersonationWindow, error) {
+		session, err := u.repo.GetSession(ctx, id)
+		if err != nil {
+			if errors.Is(err, repository.ErrRecordNotFound) {
+				return &impersonationWindow{Ended: true}, nil
+			}
+			return nil, err
+		}
+		return &impersonationWindow{ExpiresAt: session.ExpiresAt, Ended: session.EndedAt != nil}, nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("error checking impersonation session: %w", err)
+	}
+	return !window.Ended && window.ExpiresAt.After(time.Now()), nil
+}
+
+// MaxMinutes returns the longest a session can last
+func (u *ImpersonationUseCase) MaxMinutes() int {
+	return u.maxMinutes
+}
+
+func (u *ImpersonationUseCase) getSession(ctx context.Context, id uint) (*entity.ImpersonationSession, error) {
+	session, err := u.repo.GetSession(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, ErrImpersonationNotFound
+		}
+		return nil, fmt.Errorf("error getting impersonation session: %w", err)
+	}
+	return session, nil
+}
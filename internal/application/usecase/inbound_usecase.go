@@ -0,0 +1,448 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
+)
+
+var (
+	ErrDockDoorNotFound        = entity.NewError(entity.ErrCodeNotFound, "dock door not found")
+	ErrDockAppointmentNotFound = entity.NewError(entity.ErrCodeNotFound, "dock appointment not found")
+	ErrShipNoticeNotFound      = entity.NewError(entity.ErrCodeNotFound, "ship notice not found")
+	ErrDockDoorInactive        = entity.NewError(entity.ErrCodeFailedPrecondition, "dock door is inactive")
+	ErrDockSlotTimes           = entity.NewError(entity.ErrCodeInvalidArgument, "a slot ends after it starts, within a day")
+	ErrDockSlotClosed          = entity.NewError(entity.ErrCodeFailedPrecondition, "the slot does not start in working time on the warehouse calendar")
+	ErrDockOrderNotOpen        = entity.NewError(entity.ErrCodeFailedPrecondition, "only sent, confirmed or partially received purchase orders can be booked")
+	ErrDockOrderStore          = entity.NewError(entity.ErrCodeFailedPrecondition, "the purchase order is delivered to another warehouse")
+	ErrDockShipNoticeOrder     = entity.NewError(entity.ErrCodeInvalidArgument, "the ship notice ships another purchase order")
+	ErrDockAppointmentStatus   = entity.NewError(entity.ErrCodeFailedPrecondition, "only booked appointments can be checked in or cancelled")
+	ErrInboundCalendarRange    = entity.NewError(entity.ErrCodeInvalidArgument, "the calendar covers up to 92 days, ending on or after its start")
+)
+
+// inboundCalendarDays is the span of the calendar when no end date is given
+const inboundCalendarDays = 14
+
+// InboundUseCase schedules the deliveries expected at the warehouses: the
+// calendar of open purchase orders and ship notices, the dock door slots
+// booked for them and the orders overdue for follow-up
+type InboundUseCase struct {
+	repo         *repository.InboundRepository
+	purchaseRepo *repository.PurchaseRepository
+	ediRepo      *repository.EDIRepository
+	storeRepo    *repository.StoreRepository
+	calendarUC   *CalendarUseCase
+}
+
+// NewInboundUseCase creates a new inbound use case
+func NewInboundUseCase(repo *repository.InboundRepository, purchaseRepo *repository.PurchaseRepository, ediRepo *repository.EDIRepository, storeRepo *repository.StoreRepository, calendarUC *CalendarUseCase) *InboundUseCase {
+	return &InboundUseCase{
+		repo:         repo,
+		purchaseRepo: purchaseRepo,
+		ediRepo:      ediRepo,
+		storeRepo:    storeRepo,
+		calendarUC:   calendarUC,
+	}
+}
+
+// CreateDoor creates a dock door of a store
+func (u *InboundUseCase) CreateDoor(ctx context.Context, req *entity.DockDoorRequest) (*entity.DockDoor, error) {
+	door := &entity.DockDoor{Active: true}
+	if err := u.applyDoor(ctx, door, req); err != nil {
+		return nil, err
+	}
+	if err := u.repo.CreateDoor(ctx, door); err != nil {
+		return nil, fmt.Errorf("error creating dock door: %w", err)
+	}
+	return door, nil
+}
+
+// UpdateDoor renames, moves or deactivates a dock door. Appointments already
+// booked keep their slot.
+func (u *InboundUseCase) UpdateDoor(ctx context.Context, id uint, req *entity.DockDoorRequest) (*entity.DockDoor, error) {
+	door, err := u.getDoor(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := u.applyDoor(ctx, door, req); err != nil {
+		return nil, err
+	}
+	if err := u.repo.UpdateDoor(ctx, door); err != nil {
+		return nil, fmt.Errorf("error updating dock door: %w", err)
+	}
+	return door, nil
+}
+
+// applyDoor validates a dock door request and copies it onto a door
+func (u *InboundUseCase) applyDoor(ctx context.Context, door *entity.DockDoor, req *entity.DockDoorRequest) error {
+	if err := entity.AccessScopeFromContext(ctx).CheckStore(req.StoreID); err != nil {
+		return err
+	}
+	if _, err := u.storeRepo.GetByID(ctx, req.StoreID); err != nil {
+		return err
+	}
+
+	door.StoreID = req.StoreID
+	door.Name = req.Name
+	if req.Active != nil {
+		door.Active = *req.Active
+	}
+	return nil
+}
+
+// ListDoors lists the dock doors of the stores in the access scope, or of one store
+func (u *InboundUseCase) ListDoors(ctx context.Context, storeID string) ([]entity.DockDoor, error) {
+	doors, err := u.repo.ListDoors(ctx, storeID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing dock doors: %w", err)
+	}
+	return doors, nil
+}
+
+// getDoor retrieves a dock door of a store in the access scope
+func (u *InboundUseCase) getDoor(ctx context.Context, id uint) (*entity.DockDoor, error) {
+	door, err := u.repo.GetDoor(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, ErrDockDoorNotFound
+		}
+		return nil, fmt.Errorf("error getting dock door: %w", err)
+	}
+	if err := entity.AccessScopeFromContext(ctx).CheckStore(door.StoreID); err != nil {
+		return nil, err
+	}
+	return door, nil
+}
+
+// BookAppointment books a slot at an active dock door for a delivery,
+// optionally of a purchase order on its way or announced by a ship notice.
+// The slot must start in working time on the warehouse calendar and not
+// overlap another booking of the door.
+func (u *InboundUseCase) BookAppointment(ctx context.Context, req *entity.DockAppointmentRequest, userID uint) (*entity.DockAppointment, error) {
+	door, err := u.getDoor(ctx, req.DoorID)
+	if err != nil {
+		return nil, err
+	}
+	if !door.Active {
+		return nil, ErrDockDoorInactive
+	}
+	if !req.EndsAt.After(req.StartsAt) || req.EndsAt.Sub(req.StartsAt) > 24*time.Hour {
+		return nil, ErrDockSlotTimes
+	}
+	opens, err := u.calendarUC.NextWorkingTime(ctx, door.StoreID, req.StartsAt)
+	if err != nil {
+		return nil, err
+	}
+	if !opens.Equal(req.StartsAt) {
+		return nil, ErrDockSlotClosed
+	}
+
+	appointment := &entity.DockAppointment{
+		DoorID:     door.ID,
+		StoreID:    door.StoreID,
+		Carrier:    req.Carrier,
+		StartsAt:   req.StartsAt,
+		EndsAt:     req.EndsAt,
+		Status:     entity.DockAppointmentBooked,
+		Notes:      req.Notes,
+		BookedByID: userID,
+	}
+
+	orderID := req.PurchaseOrderID
+	if req.ShipNoticeID != nil {
+		document, err := u.ediRepo.GetDocument(ctx, *req.ShipNoticeID)
+		if err != nil || document.Type != entity.EDITypeShipNotice || document.PurchaseOrderID == nil {
+			return nil, ErrShipNoticeNotFound
+		}
+		if orderID != "" && orderID != *document.PurchaseOrderID {
+			return nil, ErrDockShipNoticeOrder
+		}
+		orderID = *document.PurchaseOrderID
+		appointment.ShipNoticeID = &document.ID
+		if appointment.Carrier == "" {
+			var notice entity.EDIShipNotice
+			if json.Unmarshal(document.Data, &notice) == nil {
+				appointment.Carrier = notice.Carrier
+			}
+		}
+	}
+	if orderID != "" {
+		order, err := u.purchaseRepo.GetPurchaseOrderByID(ctx, orderID)
+		if err != nil {
+			return nil, err
+		}
+		if !inboundOrder(order) {
+			return nil, ErrDockOrderNotOpen
+		}
+		if order.StoreID != nil && *order.StoreID != door.StoreID {
+			return nil, ErrDockOrderStore
+		}
+		appointment.PurchaseOrderID = &order.ID
+	}
+
+	if err := u.repo.CreateAppointment(ctx, appointment); err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, ErrDockDoorNotFound
+		}
+		if errors.Is(err, repository.ErrDockSlotTaken) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("error booking dock appointment: %w", err)
+	}
+	appointment.Door = door
+	return appointment, nil
+}
+
+// GetAppointment retrieves a dock appointment of a store in the access scope
+func (u *InboundUseCase) GetAppointment(ctx context.Context, id uint) (*entity.DockAppointment, error) {
+	appointment, err := u.repo.GetAppointment(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, ErrDockAppointmentNotFound
+		}
+		return nil, fmt.Errorf("error getting dock appointment: %w", err)
+	}
+	if err := entity.AccessScopeFromContext(ctx).CheckStore(appointment.StoreID); err != nil {
+		return nil, err
+	}
+	return appointment, nil
+}
+
+// ListAppointments lists the dock appointments of the stores in the access
+// scope with filters, by start time
+func (u *InboundUseCase) ListAppointments(ctx context.Context, filter *entity.DockAppointmentFilter) ([]entity.DockAppointment, error) {
+	appointments, err := u.repo.ListAppointments(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("error listing dock appointments: %w", err)
+	}
+	return appointments, nil
+}
+
+// CheckIn records that the delivery of a booked appointment arrived at the door
+func (u *InboundUseCase) CheckIn(ctx context.Context, id uint) (*entity.DockAppointment, error) {
+	return u.setAppointmentStatus(ctx, id, entity.DockAppointmentArrived)
+}
+
+// CancelAppointment cancels a booked appointment, freeing its slot
+func (u *InboundUseCase) CancelAppointment(ctx context.Context, id uint) (*entity.DockAppointment, error) {
+	return u.setAppointmentStatus(ctx, id, entity.DockAppointmentCancelled)
+}
+
+// setAppointmentStatus moves a booked appointment to a new status
+func (u *InboundUseCase) setAppointmentStatus(ctx context.Context, id uint, status entity.DockAppointmentStatus) (*entity.DockAppointment, error) {
+	appointment, err := u.GetAppointment(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if appointment.Status != entity.DockAppointmentBooked {
+		return nil, ErrDockAppointmentStatus
+	}
+
+	appointment.Status = status
+	if status == entity.DockAppointmentArrived {
+		now := time.Now()
+		appointment.ArrivedAt = &now
+	}
+	if err := u.repo.UpdateAppointment(ctx, appointment); err != nil {
+		return nil, fmt.Errorf("error updating dock appointment: %w", err)
+	}
+	return appointment, nil
+}
+
+// Calendar returns the deliveries expected from the start date, today by
+// default, to the end date, two weeks on by default, by day. Open purchase
+// orders are placed on their expected date and ship notices not received
+// yet on the delivery date they announce, else their ship date; both carry
+// the dock appointments booked for the order and whether the order is
+// overdue. With a store, only the orders delivered to it or booked at one of
+// its doors are listed.
+func (u *InboundUseCase) Calendar(ctx context.Context, filter *entity.InboundCalendarFilter) (*entity.InboundCalendar, error) {
+	today := truncateDay(time.Now())
+	start := today
+	if filter.StartDate != nil {
+		start = truncateDay(*filter.StartDate)
+	}
+	end := start.AddDate(0, 0, inboundCalendarDays-1)
+	if filter.EndDate != nil {
+		end = truncateDay(*filter.EndDate)
+	}
+	if end.Before(start) || daysBetween(start, end) >= 92 {
+		return nil, ErrInboundCalendarRange
+	}
+	until := end.AddDate(0, 0, 1)
+
+	orders, err := u.repo.ListOpenOrders(ctx, &entity.InboundOrderFilter{
+		StoreID:        filter.StoreID,
+		ExpectedFrom:   &start,
+		ExpectedBefore: &until,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing open purchase orders: %w", err)
+	}
+	var entries []entity.InboundEntry
+	for i := range orders {
+		entries = append(entries, inboundEntry(&orders[i], orders[i].ExpectedDate, today))
+	}
+
+	// Ship notices announce deliveries of open orders, possibly on another day
+	notices, err := u.repo.ListPendingShipNotices(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error listing ship notices: %w", err)
+	}
+	var noticeOrderIDs []string
+	arrivals := make(map[uint]time.Time)
+	shipments := make(map[uint]entity.EDIShipNotice)
+	for _, document := range notices {
+		var notice entity.EDIShipNotice
+		if err := json.Unmarshal(document.Data, &notice); err != nil {
+			continue
+		}
+		arrival := notice.DeliveryDate
+		if arrival == nil {
+			arrival = notice.ShipDate
+		}
+		if arrival == nil || arrival.Before(start) || !arrival.Before(until) {
+			continue
+		}
+		arrivals[document.ID] = *arrival
+		shipments[document.ID] = notice
+		noticeOrderIDs = append(noticeOrderIDs, *document.PurchaseOrderID)
+	}
+	if len(noticeOrderIDs) > 0 {
+		noticeOrders, err := u.repo.ListOpenOrders(ctx, &entity.InboundOrderFilter{StoreID: filter.StoreID, IDs: noticeOrderIDs})
+		if err != nil {
+			return nil, fmt.Errorf("error listing open purchase orders: %w", err)
+		}
+		byID := make(map[string]*entity.PurchaseOrder, len(noticeOrders))
+		for i := range noticeOrders {
+			byID[noticeOrders[i].ID] = &noticeOrders[i]
+		}
+		for _, document := range notices {
+			arrival, ok := arrivals[document.ID]
+			if !ok {
+				continue
+			}
+			order, ok := byID[*document.PurchaseOrderID]
+			if !ok {
+				continue
+			}
+			entry := inboundEntry(order, arrival, today)
+			entry.Kind = entity.InboundShipNotice
+			id := document.ID
+			entry.ShipNoticeID = &id
+			entry.ShipmentID = shipments[document.ID].ShipmentID
+			entry.Carrier = shipments[document.ID].Carrier
+			entries = append(entries, entry)
+		}
+	}
+
+	if err := u.attachAppointments(ctx, entries); err != nil {
+		return nil, err
+	}
+
+	calendar := &entity.InboundCalendar{
+		StartDate: start,
+		EndDate:   end,
+		StoreID:   filter.StoreID,
+		Days:      []entity.InboundCalendarDay{},
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if !entries[i].Date.Equal(entries[j].Date) {
+			return entries[i].Date.Before(entries[j].Date)
+		}
+		return entries[i].OrderNumber < entries[j].OrderNumber
+	})
+	for _, entry := range entries {
+		last := len(calendar.Days) - 1
+		if last < 0 || !calendar.Days[last].Date.Equal(entry.Date) {
+			calendar.Days = append(calendar.Days, entity.InboundCalendarDay{Date: entry.Date})
+			last++
+		}
+		calendar.Days[last].Entries = append(calendar.Days[last].Entries, entry)
+	}
+	return calendar, nil
+}
+
+// Overdue lists the open purchase orders past their expected date, most
+// overdue first, for buyers to follow up with the vendors, with the dock
+// appointments booked for them. With a store, only the orders delivered to
+// it or booked at one of its doors are listed.
+func (u *InboundUseCase) Overdue(ctx context.Context, storeID string) ([]entity.InboundEntry, error) {
+	today := truncateDay(time.Now())
+	orders, err := u.repo.ListOpenOrders(ctx, &entity.InboundOrderFilter{StoreID: storeID, ExpectedBefore: &today})
+	if err != nil {
+		return nil, fmt.Errorf("error listing open purchase orders: %w", err)
+	}
+
+	entries := make([]entity.InboundEntry, 0, len(orders))
+	for i := range orders {
+		entries = append(entries, inboundEntry(&orders[i], orders[i].ExpectedDate, today))
+	}
+	if err := u.attachAppointments(ctx, entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// attachAppointments adds to calendar entries the dock appointments booked
+// for their orders and not cancelled
+func (u *InboundUseCase) attachAppointments(ctx context.Context, entries []entity.InboundEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	orderIDs := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		orderIDs = append(orderIDs, entry.PurchaseOrderID)
+	}
+	appointments, err := u.repo.ListOrderAppointments(ctx, orderIDs)
+	if err != nil {
+		return fmt.Errorf("error listing dock appointments: %w", err)
+	}
+	byOrder := make(map[string][]entity.DockAppointment)
+	for _, appointment := range appointments {
+		byOrder[*appointment.PurchaseOrderID] = append(byOrder[*appointment.PurchaseOrderID], appointment)
+	}
+	for i := range entries {
+		entries[i].Appointments = byOrder[entries[i].PurchaseOrderID]
+	}
+	return nil
+}
+
+// inboundEntry returns the calendar entry of an open purchase order on a day
+func inboundEntry(order *entity.PurchaseOrder, day time.Time, today time.Time) entity.InboundEntry {
+	entry := entity.InboundEntry{
+		Date:            truncateDay(day),
+		Kind:            entity.InboundPurchaseOrder,
+		PurchaseOrderID: order.ID,
+		OrderNumber:     order.OrderNumber,
+		OrderStatus:     order.Status,
+		VendorID:        order.VendorID,
+		ExpectedDate:    order.ExpectedDate,
+	}
+	if order.StoreID != nil {
+		entry.StoreID = *order.StoreID
+	}
+	if order.Vendor != nil {
+		entry.VendorName = order.Vendor.Name
+	}
+	if days := daysBetween(order.ExpectedDate, today); days > 0 {
+		entry.Overdue = true
+		entry.DaysOverdue = days
+	}
+	return entry
+}
+
+// inboundOrder tells whether the goods of a purchase order are on their way
+func inboundOrder(order *entity.PurchaseOrder) bool {
+	switch order.Status {
+	case entity.PurchaseOrderStatusSent, entity.PurchaseOrderStatusConfirmed, entity.PurchaseOrderStatusPartial:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,298 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/logging"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/tracing"
+)
+
+var (
+	ErrJobNotFound       = entity.NewError(entity.ErrCodeNotFound, "job not found")
+	ErrUnknownJobType    = entity.NewError(entity.ErrCodeInvalidArgument, "unknown job type")
+	ErrJobNotCancellable = entity.NewError(entity.ErrCodeFailedPrecondition, "only queued jobs can be canceled")
+)
+
+const (
+	// jobPollInterval is how long an idle worker waits before looking for due jobs
+	jobPollInterval = 2 * time.Second
+	// jobRetryDelay is the wait before a failed job's second attempt; it doubles
+	// with every further attempt
+	jobRetryDelay = 30 * time.Second
+)
+
+// JobHandler runs a job of one type, reporting the percentage done through
+// progress. Its result is kept on the job for the client to fetch.
+type JobHandler func(ctx context.Context, job *entity.Job, progress func(percent int)) (interface{}, error)
+
+// permanentJobError marks a job failure that retrying cannot fix, such as an
+// invalid payload
+type permanentJobError struct {
+	err error
+}
+
+func (e permanentJobError) Error() string { return e.err.Error() }
+func (e permanentJobError) Unwrap() error { return e.err }
+
+// permanent keeps a job from being retried after failing with err
+func permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentJobError{err: err}
+}
+
+// JobUseCase queues work such as report generation and bulk imports to run in
+// the background, where workers run each job with the handler registered for
+// its type
+type JobUseCase struct {
+	jobRepo     *repository.JobRepository
+	handlers    map[string]JobHandler
+	types       []string
+	timeout     time.Duration // longest a job may run before it is abandoned
+	maxAttempts int
+
+	stop     chan struct{} // closed by Stop
+	stopOnce sync.Once
+	workers  sync.WaitGroup
+}
+
+// NewJobUseCase creates a new job use case
+func NewJobUseCase(jobRepo *repository.JobRepository, timeout time.Duration, maxAttempts int) *JobUseCase {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	return &JobUseCase{
+		jobRepo:     jobRepo,
+		handlers:    make(map[string]JobHandler),
+		timeout:     timeout,
+		maxAttempts: maxAttempts,
+		stop:        make(chan struct{}),
+	}
+}
+
+// Register sets the handler of a job type. Handlers are registered before Start.
+func (u *JobUseCase) Register(jobType string, handler JobHandler) {
+	if _, ok := u.handlers[jobType]; !ok {
+		u.types = append(u.types, jobType)
+	}
+	u.handlers[jobType] = handler
+}
+
+// Enqueue queues a job to run as soon as a worker is free. The payload is
+// encoded as JSON for the handler to decode.
+func (u *JobUseCase) Enqueue(ctx context.Context, jobType string, payload interface{}, userID *uint) (*entity.Job, error) {
+	if _, ok := u.handlers[jobType]; !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownJobType, jobType)
+	}
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding job payload: %w", err)
+	}
+
+	job := &entity.Job{
+		Type:        jobType,
+		Status:      entity.JobQueued,
+		Payload:     encoded,
+		MaxAttempts: u.maxAttempts,
+		RunAt:       time.Now(),
+		CreatedBy:   userID,
+	}
+	if err := u.jobRepo.Create(ctx, job); err != nil {
+		return nil, fmt.Errorf("error queuing job: %w", err)
+	}
+	return job, nil
+}
+
+// Get gets a job. With a user, only that user's jobs are found.
+func (u *JobUseCase) Get(ctx context.Context, id uint64, userID *uint) (*entity.Job, error) {
+	job, err := u.jobRepo.Get(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, ErrJobNotFound
+		}
+		return nil, fmt.Errorf("error getting job: %w", err)
+	}
+	if userID != nil && (job.CreatedBy == nil || *job.CreatedBy != *userID) {
+		return nil, ErrJobNotFound
+	}
+	return job, nil
+}
+
+// List lists the jobs matching a filter, latest first and without their
+// payloads and results
+func (u *JobUseCase) List(ctx context.Context, filter *entity.JobFilter) ([]entity.Job, int64, error) {
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+	if filter.PageSize <= 0 {
+		filter.PageSize = 20
+	}
+	jobs, total, err := u.jobRepo.List(ctx, filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error listing jobs: %w", err)
+	}
+	return jobs, total, nil
+}
+
+// Cancel cancels a queued job. With a user, only that user's jobs are found.
+func (u *JobUseCase) Cancel(ctx context.Context, id uint64, userID *uint) (*entity.Job, error) {
+	if _, err := u.Get(ctx, id, userID); err != nil {
+		return nil, err
+	}
+	canceled, err := u.jobRepo.Cancel(ctx, id, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("error canceling job: %w", err)
+	}
+	if !canceled {
+		return nil, ErrJobNotCancellable
+	}
+	return u.Get(ctx, id, nil)
+}
+
+// Start runs the given number of workers in the background. Jobs left running
+// for longer than the timeout, by a worker that stopped, are queued again.
+func (u *JobUseCase) Start(workers int) {
+	if len(u.types) == 0 {
+		return
+	}
+	u.workers.Add(workers)
+	for i := 0; i < workers; i++ {
+		go u.work()
+	}
+
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+
+		for {
+			requeued, err := u.jobRepo.RequeueStale(context.Background(), time.Now().Add(-u.timeout-time.Minute))
+			if err != nil {
+				slog.Error("error requeuing stale jobs", "error", err)
+			} else if requeued > 0 {
+				slog.Warn("requeued stale jobs", "jobs", requeued)
+			}
+			select {
+			case <-ticker.C:
+			case <-u.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the workers from claiming jobs and waits for the jobs they are
+// running to finish. Jobs still running when ctx is done are queued again by
+// another instance once they have run for longer than the timeout.
+func (u *JobUseCase) Stop(ctx context.Context) error {
+	u.stopOnce.Do(func() { close(u.stop) })
+
+	done := make(chan struct{})
+	go func() {
+		u.workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// work runs due jobs one at a time, waiting while none is due, until Stop
+func (u *JobUseCase) work() {
+	defer u.workers.Done()
+	for {
+		select {
+		case <-u.stop:
+			return
+		default:
+		}
+
+		job, err := u.jobRepo.Claim(context.Background(), u.types, time.Now())
+		if err != nil {
+			slog.Error("error claiming job", "error", err)
+		}
+		if job == nil {
+			select {
+			case <-time.After(jobPollInterval):
+			case <-u.stop:
+				return
+			}
+			continue
+		}
+		u.run(job)
+	}
+}
+
+// run runs a claimed job and records its outcome, queuing it again when it
+// failed with attempts left
+func (u *JobUseCase) run(job *entity.Job) {
+	ctx, cancel := context.WithTimeout(context.Background(), u.timeout)
+	defer cancel()
+
+	// Trace each run as a trace of its own, with the queries it makes
+	ctx, span := tracing.Start(ctx, "job "+string(job.Type), tracing.SpanKindInternal)
+	defer span.End()
+	span.SetAttribute("job.id", job.ID)
+	span.SetAttribute("job.attempt", job.Attempts)
+
+	ctx = logging.With(ctx, "job_id", job.ID, "job_type", job.Type, "attempt", job.Attempts)
+	logger := logging.FromContext(ctx)
+
+	progress := func(percent int) {
+		if percent < 0 || percent > 100 || percent == job.Progress {
+			return
+		}
+		job.Progress = percent
+		if err := u.jobRepo.SetProgress(context.Background(), job.ID, percent); err != nil {
+			logger.Error("error recording job progress", "error", err)
+		}
+	}
+
+	result, err := u.runHandler(ctx, job, progress)
+	span.SetError(err)
+	var encoded json.RawMessage
+	if err == nil && result != nil {
+		if encoded, err = json.Marshal(result); err != nil {
+			err = fmt.Errorf("error encoding job result: %w", err)
+		}
+	}
+
+	now := time.Now()
+	var failure permanentJobError
+	switch {
+	case err == nil:
+		err = u.jobRepo.Finish(context.Background(), job.ID, entity.JobSucceeded, encoded, "", now)
+	case job.Attempts < job.MaxAttempts && !errors.As(err, &failure):
+		logger.Warn("job failed, retrying", "error", err)
+		delay := jobRetryDelay << (job.Attempts - 1)
+		err = u.jobRepo.Requeue(context.Background(), job.ID, now.Add(delay), err.Error())
+	default:
+		logger.Error("job failed", "error", err)
+		err = u.jobRepo.Finish(context.Background(), job.ID, entity.JobFailed, nil, err.Error(), now)
+	}
+	if err != nil {
+		logger.Error("error recording job outcome", "error", err)
+	}
+}
+
+// runHandler runs a job's handler, turning a panic into an error so that one
+// job cannot stop its worker
+func (u *JobUseCase) runHandler(ctx context.Context, job *entity.Job, progress func(int)) (result interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return u.handlers[job.Type](ctx, job, progress)
+}
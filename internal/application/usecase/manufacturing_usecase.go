@@ -4,21 +4,43 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
 	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
 )
 
+var (
+	ErrInvalidIssueMode     = entity.NewError(entity.ErrCodeInvalidArgument, "issue mode must be backflush or manual")
+	ErrTargetStoreRequired  = entity.NewError(entity.ErrCodeInvalidArgument, "target store is required")
+	ErrSourceStoreRequired  = entity.NewError(entity.ErrCodeInvalidArgument, "source store is required")
+	ErrProgressDecrease     = entity.NewError(entity.ErrCodeFailedPrecondition, "completed and defect quantities cannot decrease")
+	ErrBOMNotFound          = entity.NewError(entity.ErrCodeNotFound, "product has no bill of materials")
+	ErrInsufficientMaterial = entity.NewError(entity.ErrCodeFailedPrecondition, "insufficient component stock in the source store")
+	ErrInvalidBOM           = entity.NewError(entity.ErrCodeInvalidArgument, "BOM needs a product, a version and components with positive quantities")
+	ErrInvalidBOMPeriod     = entity.NewError(entity.ErrCodeInvalidArgument, "BOM effective end must be after its start")
+	ErrBOMVersionExists     = entity.NewError(entity.ErrCodeConflict, "product already has a BOM with this version")
+	ErrBOMCycle             = entity.NewError(entity.ErrCodeInvalidArgument, "BOM components cannot include the product itself at any level")
+	ErrInvalidMaterialCost  = entity.NewError(entity.ErrCodeInvalidArgument, "material cost cannot be negative")
+)
+
+// maxBOMDepth caps the sub-assembly levels of a bill of materials
+const maxBOMDepth = 20
+
 type ManufacturingUseCase struct {
 	repo       *repository.ManufacturingRepository
 	stocksRepo *repository.StocksRepository
+	qualityUC  *QualityUseCase
+	demandUC   *DemandForecastUseCase
 }
 
-func NewManufacturingUseCase(repo *repository.ManufacturingRepository, stocksRepo *repository.StocksRepository) *ManufacturingUseCase {
+func NewManufacturingUseCase(repo *repository.ManufacturingRepository, stocksRepo *repository.StocksRepository, qualityUC *QualityUseCase, demandUC *DemandForecastUseCase) *ManufacturingUseCase {
 	return &ManufacturingUseCase{
 		repo:       repo,
 		stocksRepo: stocksRepo,
+		qualityUC:  qualityUC,
+		demandUC:   demandUC,
 	}
 }
 
@@ -44,27 +66,107 @@ func (uc *ManufacturingUseCase) CreateProductionOrder(ctx context.Context, order
 	// Validate product exists in stock by checking if it has any stock records
 	stocks, err := uc.stocksRepo.List(ctx, &entity.StockFilter{SKUID: fmt.Sprintf("%d", order.ProductID)})
 	if err != nil || len(stocks) == 0 {
-		return errors.New("invalid product ID")
+		return entity.NewError(entity.ErrCodeInvalidArgument, "invalid product ID")
 	}
 
 	// Validate facility exists
 	if _, err := uc.repo.GetFacility(ctx, order.FacilityID); err != nil {
-		return errors.New("invalid facility ID")
+		return entity.NewError(entity.ErrCodeInvalidArgument, "invalid facility ID")
+	}
+
+	switch order.IssueMode {
+	case "":
+		order.IssueMode = entity.MaterialIssueBackflush
+	case entity.MaterialIssueBackflush, entity.MaterialIssueManual:
+	default:
+		return ErrInvalidIssueMode
+	}
+	if order.TargetStoreID == "" {
+		return ErrTargetStoreRequired
+	}
+	if order.SourceStoreID == "" {
+		order.SourceStoreID = order.TargetStoreID
 	}
 
 	order.Status = entity.OrderStatusPending
 	order.StartDate = time.Now()
+	order.CompletedQty = 0
+	order.DefectQty = 0
+	// Orders are put on a work center through ScheduleOrder
+	order.WorkCenterID = nil
+	order.ScheduledStart = nil
+	order.ScheduledEnd = nil
+	order.PlannedHours = 0
 	return uc.repo.CreateProductionOrder(ctx, order)
 }
 
-func (uc *ManufacturingUseCase) UpdateProductionProgress(ctx context.Context, orderID uint, completedQty, defectQty int) error {
+// UpdateProductionProgress records the cumulative good and defective output of a
+// production order. The newly completed units are received into the target
+// warehouse, on inspection hold if the product has an inspection plan, and, for
+// backflush orders, the components of every newly produced unit, good or
+// defective, are consumed from the source warehouse at the BOM quantity. The
+// order completes once the ordered quantity is reached.
+func (uc *ManufacturingUseCase) UpdateProductionProgress(ctx context.Context, orderID uint, completedQty, defectQty int, userID string) error {
 	order, err := uc.repo.GetProductionOrder(ctx, orderID)
 	if err != nil {
 		return err
 	}
 
 	if order.Status != entity.OrderStatusInProcess {
-		return errors.New("production order is not in process")
+		return entity.NewError(entity.ErrCodeFailedPrecondition, "production order is not in process")
+	}
+	if completedQty < order.CompletedQty || defectQty < order.DefectQty {
+		return ErrProgressDecrease
+	}
+
+	completedDelta := completedQty - order.CompletedQty
+	producedDelta := completedDelta + defectQty - order.DefectQty
+	reference := order.Reference()
+
+	var entries []entity.StockEntry
+	var issues []entity.ProductionMaterialIssue
+	if producedDelta > 0 && order.IssueMode != entity.MaterialIssueManual {
+		items, err := uc.bomItems(ctx, order.ProductID, order.StartDate)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		for _, item := range items {
+			qty := item.QuantityNeeded * float64(producedDelta)
+			if qty <= 0 {
+				continue
+			}
+			entries = append(entries, entity.StockEntry{
+				StoreID:   order.SourceStoreID,
+				SKUID:     fmt.Sprintf("%d", item.MaterialID),
+				Type:      "OUT",
+				Quantity:  qty,
+				Reference: reference,
+				Note:      fmt.Sprintf("Backflush for %d units", producedDelta),
+				CreatedBy: userID,
+			})
+			issues = append(issues, entity.ProductionMaterialIssue{
+				ProductionOrderID: order.ID,
+				MaterialID:        item.MaterialID,
+				StoreID:           order.SourceStoreID,
+				Quantity:          qty,
+				Mode:              entity.MaterialIssueBackflush,
+				IssuedAt:          now,
+				IssuedBy:          userID,
+			})
+		}
+	}
+	if completedDelta > 0 {
+		entries = append(entries, entity.StockEntry{
+			StoreID:   order.TargetStoreID,
+			SKUID:     fmt.Sprintf("%d", order.ProductID),
+			Type:      "IN",
+			Quantity:  float64(completedDelta),
+			Reference: reference,
+			Note:      "Finished goods receipt",
+			CreatedBy: userID,
+		})
 	}
 
 	order.CompletedQty = completedQty
@@ -73,14 +175,144 @@ func (uc *ManufacturingUseCase) UpdateProductionProgress(ctx context.Context, or
 	// Check if production is complete
 	if completedQty >= order.Quantity {
 		order.Status = entity.OrderStatusCompleted
+	}
 
-		// Update stock levels
-		if err := uc.updateStocksOnCompletion(ctx, order); err != nil {
+	if err := uc.repo.PostProduction(ctx, order, entries, issues, userID); err != nil {
+		if errors.Is(err, repository.ErrInsufficientStock) {
+			return ErrInsufficientMaterial
+		}
+		return fmt.Errorf("error posting production: %w", err)
+	}
+
+	// Finished goods with an inspection plan stay in quarantine until they pass
+	if completedDelta > 0 {
+		output := map[string]float64{fmt.Sprintf("%d", order.ProductID): float64(completedDelta)}
+		if _, err := uc.qualityUC.Hold(ctx, entity.InspectionSourceProduction, order.TargetStoreID, reference, output); err != nil {
 			return err
 		}
 	}
+	return nil
+}
 
-	return uc.repo.UpdateProductionOrder(ctx, order)
+// IssueMaterials issues components to an in-process production order from stock,
+// in addition to or instead of backflushing
+func (uc *ManufacturingUseCase) IssueMaterials(ctx context.Context, orderID uint, req *entity.MaterialIssueRequest, userID string) ([]entity.ProductionMaterialIssue, error) {
+	order, err := uc.repo.GetProductionOrder(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	if order.Status != entity.OrderStatusInProcess {
+		return nil, entity.NewError(entity.ErrCodeFailedPrecondition, "production order is not in process")
+	}
+
+	storeID := req.StoreID
+	if storeID == "" {
+		storeID = order.SourceStoreID
+	}
+	if storeID == "" {
+		return nil, ErrSourceStoreRequired
+	}
+
+	now := time.Now()
+	reference := order.Reference()
+	entries := make([]entity.StockEntry, 0, len(req.Items))
+	issues := make([]entity.ProductionMaterialIssue, 0, len(req.Items))
+	for _, item := range req.Items {
+		entries = append(entries, entity.StockEntry{
+			StoreID:   storeID,
+			SKUID:     fmt.Sprintf("%d", item.MaterialID),
+			Type:      "OUT",
+			Quantity:  item.Quantity,
+			Reference: reference,
+			Note:      req.Note,
+			CreatedBy: userID,
+		})
+		issues = append(issues, entity.ProductionMaterialIssue{
+			ProductionOrderID: order.ID,
+			MaterialID:        item.MaterialID,
+			StoreID:           storeID,
+			Quantity:          item.Quantity,
+			Mode:              entity.MaterialIssueManual,
+			Note:              req.Note,
+			IssuedAt:          now,
+			IssuedBy:          userID,
+		})
+	}
+
+	if err := uc.repo.PostProduction(ctx, nil, entries, issues, userID); err != nil {
+		if errors.Is(err, repository.ErrInsufficientStock) {
+			return nil, ErrInsufficientMaterial
+		}
+		return nil, fmt.Errorf("error issuing materials: %w", err)
+	}
+	return issues, nil
+}
+
+// ListMaterialIssues lists the components issued to a production order
+func (uc *ManufacturingUseCase) ListMaterialIssues(ctx context.Context, orderID uint) ([]entity.ProductionMaterialIssue, error) {
+	if _, err := uc.repo.GetProductionOrder(ctx, orderID); err != nil {
+		return nil, err
+	}
+	return uc.repo.ListMaterialIssues(ctx, orderID)
+}
+
+// GetMaterialVariance compares the components issued to a production order with
+// the BOM standard for the units it produced. Components issued that are not on
+// the BOM are reported with a zero standard.
+func (uc *ManufacturingUseCase) GetMaterialVariance(ctx context.Context, orderID uint) (*entity.ProductionVarianceReport, error) {
+	order, err := uc.repo.GetProductionOrder(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := uc.bomItems(ctx, order.ProductID, order.StartDate)
+	if err != nil {
+		return nil, err
+	}
+
+	issues, err := uc.repo.ListMaterialIssues(ctx, order.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing material issues: %w", err)
+	}
+
+	report := &entity.ProductionVarianceReport{
+		ProductionOrderID: order.ID,
+		IssueMode:         order.IssueMode,
+		ProducedQty:       order.CompletedQty + order.DefectQty,
+	}
+
+	index := make(map[uint]int)
+	for _, item := range items {
+		i, ok := index[item.MaterialID]
+		if !ok {
+			i = len(report.Materials)
+			index[item.MaterialID] = i
+			report.Materials = append(report.Materials, entity.MaterialVariance{
+				MaterialID:    item.MaterialID,
+				UnitOfMeasure: item.UnitOfMeasure,
+			})
+		}
+		report.Materials[i].StandardQty += item.QuantityNeeded * float64(report.ProducedQty)
+	}
+	for _, issue := range issues {
+		i, ok := index[issue.MaterialID]
+		if !ok {
+			i = len(report.Materials)
+			index[issue.MaterialID] = i
+			report.Materials = append(report.Materials, entity.MaterialVariance{MaterialID: issue.MaterialID})
+		}
+		report.Materials[i].ActualQty += issue.Quantity
+	}
+
+	for i := range report.Materials {
+		m := &report.Materials[i]
+		m.VarianceQty = m.ActualQty - m.StandardQty
+		if m.StandardQty > 0 {
+			m.VariancePercent = m.VarianceQty / m.StandardQty * 100
+		}
+	}
+	return report, nil
 }
 
 func (uc *ManufacturingUseCase) StartProduction(ctx context.Context, orderID uint) error {
@@ -90,7 +322,7 @@ func (uc *ManufacturingUseCase) StartProduction(ctx context.Context, orderID uin
 	}
 
 	if order.Status != entity.OrderStatusPending {
-		return errors.New("order is not in pending status")
+		return entity.NewError(entity.ErrCodeFailedPrecondition, "order is not in pending status")
 	}
 
 	// Calculate material requirements
@@ -103,115 +335,265 @@ func (uc *ManufacturingUseCase) StartProduction(ctx context.Context, orderID uin
 	return uc.repo.UpdateProductionOrder(ctx, order)
 }
 
+// bomItems returns the components of the bill of materials of a product in
+// effect at the given time
+func (uc *ManufacturingUseCase) bomItems(ctx context.Context, productID uint, at time.Time) ([]entity.BOMItem, error) {
+	bom, err := uc.repo.GetEffectiveBOM(ctx, productID, at)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, ErrBOMNotFound
+		}
+		return nil, err
+	}
+	return uc.repo.GetBOMItems(ctx, bom.ID)
+}
+
 // BOM management
+
+// CreateBOM creates a version of a product's bill of materials, effective from
+// now unless a date is given. The version it follows ends where it takes effect.
+// A component may be a sub-assembly with a bill of materials of its own, as long
+// as no level of it uses the product itself.
 func (uc *ManufacturingUseCase) CreateBOM(ctx context.Context, bom *entity.BillOfMaterial, items []entity.BOMItem) error {
-	if err := uc.repo.CreateBOM(ctx, bom); err != nil {
-		return err
+	if bom.ProductID == 0 || bom.Version == "" {
+		return ErrInvalidBOM
+	}
+	if bom.LaborHours < 0 || bom.LaborRate < 0 || bom.OverheadRate < 0 {
+		return ErrInvalidBOM
+	}
+	if bom.EffectiveFrom.IsZero() {
+		bom.EffectiveFrom = time.Now()
+	}
+	if bom.EffectiveTo != nil && !bom.EffectiveTo.After(bom.EffectiveFrom) {
+		return ErrInvalidBOMPeriod
+	}
+	for _, item := range items {
+		if item.MaterialID == 0 || item.QuantityNeeded <= 0 {
+			return ErrInvalidBOM
+		}
+		if item.MaterialID == bom.ProductID {
+			return ErrBOMCycle
+		}
 	}
 
 	for _, item := range items {
-		item.BOMID = bom.ID
-		if err := uc.repo.AddBOMItem(ctx, &item); err != nil {
+		if err := uc.checkBOMCycle(ctx, bom.ProductID, item.MaterialID, bom.EffectiveFrom, 1); err != nil {
 			return err
 		}
 	}
 
+	if err := uc.repo.CreateBOMVersion(ctx, bom, items); err != nil {
+		if errors.Is(err, repository.ErrDuplicateEntry) {
+			return ErrBOMVersionExists
+		}
+		return fmt.Errorf("error creating BOM: %w", err)
+	}
 	return nil
 }
 
-// MRP calculation
-func (uc *ManufacturingUseCase) calculateMRP(ctx context.Context, order *entity.ProductionOrder) error {
-	// Get BOM for the product
-	bom, err := uc.repo.GetBOM(ctx, order.ProductID)
+// GetBOM retrieves a bill of materials version with its items
+func (uc *ManufacturingUseCase) GetBOM(ctx context.Context, id uint) (*entity.BillOfMaterial, error) {
+	return uc.repo.GetBOM(ctx, id)
+}
+
+// ListBOMs lists the bill of materials versions, of one product if given
+func (uc *ManufacturingUseCase) ListBOMs(ctx context.Context, productID *uint) ([]entity.BillOfMaterial, error) {
+	return uc.repo.ListBOMs(ctx, productID)
+}
+
+// SetMaterialCost sets the standard unit cost of a purchased component
+func (uc *ManufacturingUseCase) SetMaterialCost(ctx context.Context, materialID uint, unitCost float64) (*entity.MaterialCost, error) {
+	if unitCost < 0 {
+		return nil, ErrInvalidMaterialCost
+	}
+	cost := &entity.MaterialCost{MaterialID: materialID, UnitCost: unitCost}
+	if err := uc.repo.UpsertMaterialCost(ctx, cost); err != nil {
+		return nil, fmt.Errorf("error setting material cost: %w", err)
+	}
+	return cost, nil
+}
+
+// ListMaterialCosts lists the standard unit costs of the components
+func (uc *ManufacturingUseCase) ListMaterialCosts(ctx context.Context) ([]entity.MaterialCost, error) {
+	return uc.repo.ListMaterialCosts(ctx, nil)
+}
+
+// RollUpCost computes the standard cost of a product from the bill of materials
+// in effect on the given date. Each component is costed at its own rolled-up
+// cost if it is a sub-assembly, or else at its standard material cost; labor and
+// overhead are added at the BOM rates per labor hour. A quantity above zero also
+// prices that many units.
+func (uc *ManufacturingUseCase) RollUpCost(ctx context.Context, productID uint, date time.Time, quantity float64) (*entity.BOMCostRollup, error) {
+	if date.IsZero() {
+		date = time.Now()
+	}
+
+	rollup, err := uc.rollUp(ctx, productID, date, map[uint]bool{}, 0)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	if quantity > 0 {
+		rollup.Quantity = quantity
+		rollup.TotalCost = roundAmount(rollup.UnitCost * quantity)
+	}
+	return rollup, nil
+}
 
-	// Get BOM items
+// rollUp costs one level of a bill of materials, recursing into sub-assemblies.
+// The path holds the products above this level, to stop at a cycle.
+func (uc *ManufacturingUseCase) rollUp(ctx context.Context, productID uint, date time.Time, path map[uint]bool, depth int) (*entity.BOMCostRollup, error) {
+	if path[productID] || depth >= maxBOMDepth {
+		return nil, ErrBOMCycle
+	}
+
+	bom, err := uc.repo.GetEffectiveBOM(ctx, productID, date)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, ErrBOMNotFound
+		}
+		return nil, err
+	}
 	items, err := uc.repo.GetBOMItems(ctx, bom.ID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// Calculate required quantities and check stock levels
+	materialIDs := make([]uint, 0, len(items))
 	for _, item := range items {
-		stocks, err := uc.stocksRepo.List(ctx, &entity.StockFilter{SKUID: fmt.Sprintf("%d", item.MaterialID)})
-		if err != nil || len(stocks) == 0 {
-			return errors.New("material not found in stock")
-		}
+		materialIDs = append(materialIDs, item.MaterialID)
+	}
+	costs, err := uc.repo.ListMaterialCosts(ctx, materialIDs)
+	if err != nil {
+		return nil, err
+	}
+	unitCosts := make(map[uint]float64, len(costs))
+	for _, cost := range costs {
+		unitCosts[cost.MaterialID] = cost.UnitCost
+	}
 
-		// Sum up available quantity across all locations
-		var availableQty float64
-		for _, stock := range stocks {
-			availableQty += stock.Quantity
-		}
+	rollup := &entity.BOMCostRollup{
+		ProductID:  productID,
+		BOMID:      bom.ID,
+		BOMVersion: bom.Version,
+		Date:       date,
+		Components: make([]entity.BOMCostLine, 0, len(items)),
+	}
 
-		requiredQty := item.QuantityNeeded * float64(order.Quantity)
-		shortageQty := requiredQty - availableQty
+	path[productID] = true
+	defer delete(path, productID)
 
-		mrp := &entity.MRPCalculation{
-			ProductionID:  order.ID,
+	missing := make(map[uint]bool)
+	for _, item := range items {
+		line := entity.BOMCostLine{
 			MaterialID:    item.MaterialID,
-			RequiredQty:   requiredQty,
-			AvailableQty:  availableQty,
-			ShortageQty:   shortageQty,
+			Quantity:      item.QuantityNeeded,
 			UnitOfMeasure: item.UnitOfMeasure,
-			CalculatedAt:  time.Now(),
 		}
 
-		if err := uc.repo.CreateMRPCalculation(ctx, mrp); err != nil {
-			return err
+		sub, err := uc.rollUp(ctx, item.MaterialID, date, path, depth+1)
+		switch {
+		case err == nil:
+			line.UnitCost = sub.UnitCost
+			line.Source = entity.BOMCostFromBOM
+			line.SubAssembly = sub
+			for _, id := range sub.MissingCosts {
+				missing[id] = true
+			}
+		case errors.Is(err, ErrBOMNotFound):
+			if cost, ok := unitCosts[item.MaterialID]; ok {
+				line.UnitCost = cost
+				line.Source = entity.BOMCostFromMaterial
+			} else {
+				line.Source = entity.BOMCostMissing
+				missing[item.MaterialID] = true
+			}
+		default:
+			return nil, err
 		}
+
+		line.ExtendedCost = roundAmount(line.UnitCost * line.Quantity)
+		rollup.MaterialCost += line.ExtendedCost
+		rollup.Components = append(rollup.Components, line)
 	}
 
-	return nil
+	rollup.MaterialCost = roundAmount(rollup.MaterialCost)
+	rollup.LaborCost = roundAmount(bom.LaborHours * bom.LaborRate)
+	rollup.OverheadCost = roundAmount(bom.LaborHours * bom.OverheadRate)
+	rollup.UnitCost = roundAmount(rollup.MaterialCost + rollup.LaborCost + rollup.OverheadCost)
+	for id := range missing {
+		rollup.MissingCosts = append(rollup.MissingCosts, id)
+	}
+	sort.Slice(rollup.MissingCosts, func(i, j int) bool { return rollup.MissingCosts[i] < rollup.MissingCosts[j] })
+	return rollup, nil
 }
 
-func (uc *ManufacturingUseCase) updateStocksOnCompletion(ctx context.Context, order *entity.ProductionOrder) error {
-	// Update finished product stock
-	skuID := fmt.Sprintf("%d", order.ProductID)
-	stocks, err := uc.stocksRepo.List(ctx, &entity.StockFilter{SKUID: skuID})
-	if err != nil {
-		return err
+// checkBOMCycle walks the bills of materials in effect at the given time below a
+// component and fails if the product appears at any level
+func (uc *ManufacturingUseCase) checkBOMCycle(ctx context.Context, productID, componentID uint, at time.Time, depth int) error {
+	if depth >= maxBOMDepth {
+		return ErrBOMCycle
 	}
 
-	var stock *entity.Stock
-	if len(stocks) == 0 {
-		// Create new stock record for the finished product
-		stock = &entity.Stock{
-			SKUID:    skuID,
-			Quantity: float64(order.CompletedQty),
+	items, err := uc.bomItems(ctx, componentID, at)
+	if err != nil {
+		if errors.Is(err, ErrBOMNotFound) {
+			return nil
 		}
-		if err := uc.stocksRepo.CreateOrUpdateStock(ctx, stock); err != nil {
-			return err
+		return err
+	}
+	for _, item := range items {
+		if item.MaterialID == productID {
+			return ErrBOMCycle
 		}
-	} else {
-		// Update existing stock record
-		stock = &stocks[0]
-		stock.Quantity += float64(order.CompletedQty)
-		if err := uc.stocksRepo.CreateOrUpdateStock(ctx, stock); err != nil {
+		if err := uc.checkBOMCycle(ctx, productID, item.MaterialID, at, depth+1); err != nil {
 			return err
 		}
 	}
+	return nil
+}
 
-	// Update raw materials stock based on BOM
-	mrpCalculations, err := uc.repo.GetMRPCalculations(ctx, order.ID)
+// MRP calculation
+func (uc *ManufacturingUseCase) calculateMRP(ctx context.Context, order *entity.ProductionOrder) error {
+	// Get BOM items for the product
+	items, err := uc.bomItems(ctx, order.ProductID, time.Now())
 	if err != nil {
 		return err
 	}
 
-	for _, mrp := range mrpCalculations {
-		materialID := fmt.Sprintf("%d", mrp.MaterialID)
-		stocks, err := uc.stocksRepo.List(ctx, &entity.StockFilter{SKUID: materialID})
+	// Calculate required quantities and check stock levels
+	for _, item := range items {
+		stocks, err := uc.stocksRepo.List(ctx, &entity.StockFilter{SKUID: fmt.Sprintf("%d", item.MaterialID)})
 		if err != nil || len(stocks) == 0 {
-			return errors.New("material not found in stock")
+			return entity.NewError(entity.ErrCodeFailedPrecondition, "material not found in stock")
+		}
+
+		// Sum up available quantity across all locations
+		var availableQty float64
+		for _, stock := range stocks {
+			availableQty += stock.Quantity
+		}
+
+		requiredQty := item.QuantityNeeded * float64(order.Quantity)
+
+		// Independent demand forecast for the material competes for the same stock
+		forecastQty, err := uc.demandUC.ForecastDemand(ctx, fmt.Sprintf("%d", item.MaterialID), time.Now().Format(periodLayout))
+		if err != nil {
+			return err
 		}
+		shortageQty := requiredQty + forecastQty - availableQty
 
-		// Deduct from the first available stock location
-		stock = &stocks[0]
-		stock.Quantity -= mrp.RequiredQty
+		mrp := &entity.MRPCalculation{
+			ProductionID:  order.ID,
+			MaterialID:    item.MaterialID,
+			RequiredQty:   requiredQty,
+			ForecastQty:   forecastQty,
+			AvailableQty:  availableQty,
+			ShortageQty:   shortageQty,
+			UnitOfMeasure: item.UnitOfMeasure,
+			CalculatedAt:  time.Now(),
+		}
 
-		if err := uc.stocksRepo.CreateOrUpdateStock(ctx, stock); err != nil {
+		if err := uc.repo.CreateMRPCalculation(ctx, mrp); err != nil {
 			return err
 		}
 	}
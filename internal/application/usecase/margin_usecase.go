@@ -0,0 +1,333 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
+)
+
+var (
+	ErrInvalidMarginPeriod    = entity.NewError(entity.ErrCodeInvalidArgument, "start date must not be after end date")
+	ErrInvalidMarginDimension = entity.NewError(entity.ErrCodeInvalidArgument, "group_by must be one of product, category, customer, salesperson, warehouse or month")
+)
+
+// unassignedMarginKey labels the lines of the warehouse and category
+// dimensions that have no store or category
+const unassignedMarginKey = ""
+
+// MarginUseCase analyses the gross margin of sales by product, category,
+// customer, salesperson, warehouse or month
+type MarginUseCase struct {
+	marginRepo *repository.MarginRepository
+	costRepo   *repository.CostToServeRepository
+}
+
+// NewMarginUseCase creates a new margin use case
+func NewMarginUseCase(marginRepo *repository.MarginRepository, costRepo *repository.CostToServeRepository) *MarginUseCase {
+	return &MarginUseCase{
+		marginRepo: marginRepo,
+		costRepo:   costRepo,
+	}
+}
+
+// marginLine is an order line with its group and amounts in the base currency
+type marginLine struct {
+	order    *entity.MarginSalesOrder
+	key      string
+	quantity float64
+	revenue  float64
+	cost     float64
+}
+
+// Report computes the gross margin of the sales orders placed in the period,
+// grouped by the filter's dimension, highest margin first:
+//   - revenue is the order lines net of tax, in the base currency at the order rate
+//   - cost of goods prices the units sold at the average purchase price, or the
+//     price paid to the vendor for drop-ship lines
+func (u *MarginUseCase) Report(ctx context.Context, filter *entity.MarginFilter) (*entity.MarginReport, error) {
+	start, end, lines, err := u.lines(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make(map[string]*entity.MarginRow)
+	orders := make(map[string]map[string]bool)
+	total := entity.MarginRow{Key: "total", Label: "Total"}
+	totalOrders := make(map[string]bool)
+	for _, line := range lines {
+		row, ok := groups[line.key]
+		if !ok {
+			row = &entity.MarginRow{Key: line.key}
+			groups[line.key] = row
+			orders[line.key] = make(map[string]bool)
+		}
+		addMarginLine(row, line)
+		addMarginLine(&total, line)
+		orders[line.key][line.order.ID] = true
+		totalOrders[line.order.ID] = true
+	}
+
+	labels, err := u.labels(ctx, filter.GroupBy, lines)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &entity.MarginReport{
+		StartDate:   start,
+		EndDate:     end,
+		GroupBy:     filter.GroupBy,
+		Rows:        make([]entity.MarginRow, 0, len(groups)),
+		GeneratedAt: time.Now(),
+	}
+	for key, row := range groups {
+		row.Label = labels[key]
+		row.OrderCount = len(orders[key])
+		finishMarginRow(row)
+		report.Rows = append(report.Rows, *row)
+	}
+	total.OrderCount = len(totalOrders)
+	finishMarginRow(&total)
+	report.Total = total
+
+	sort.Slice(report.Rows, func(i, j int) bool {
+		if report.Rows[i].GrossMargin != report.Rows[j].GrossMargin {
+			return report.Rows[i].GrossMargin > report.Rows[j].GrossMargin
+		}
+		return report.Rows[i].Key < report.Rows[j].Key
+	})
+	return report, nil
+}
+
+// Orders lists the sales orders behind a group of the gross margin report,
+// with the part of each falling in the group, latest first
+func (u *MarginUseCase) Orders(ctx context.Context, filter *entity.MarginFilter) ([]entity.MarginOrder, error) {
+	_, _, lines, err := u.lines(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	byOrder := make(map[string]*entity.MarginOrder)
+	for _, line := range lines {
+		if line.key != filter.Key {
+			continue
+		}
+		order, ok := byOrder[line.order.ID]
+		if !ok {
+			order = &entity.MarginOrder{
+				OrderID:     line.order.ID,
+				OrderNumber: line.order.OrderNumber,
+				OrderDate:   line.order.OrderDate,
+				ClientID:    line.order.ClientID,
+			}
+			byOrder[line.order.ID] = order
+		}
+		order.Quantity += line.quantity
+		order.Revenue += line.revenue
+		order.CostOfGoods += line.cost
+	}
+
+	result := make([]entity.MarginOrder, 0, len(byOrder))
+	for _, order := range byOrder {
+		order.Revenue = roundAmount(order.Revenue)
+		order.CostOfGoods = roundAmount(order.CostOfGoods)
+		order.GrossMargin = roundAmount(order.Revenue - order.CostOfGoods)
+		if order.Revenue != 0 {
+			order.GrossMarginPercent = roundAmount(order.GrossMargin / order.Revenue * 100)
+		}
+		result = append(result, *order)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].OrderDate.Equal(result[j].OrderDate) {
+			return result[i].OrderDate.After(result[j].OrderDate)
+		}
+		return result[i].OrderNumber > result[j].OrderNumber
+	})
+	return result, nil
+}
+
+// lines reads the order lines of the filter's period, keyed by its dimension
+// and priced in the base currency
+func (u *MarginUseCase) lines(ctx context.Context, filter *entity.MarginFilter) (time.Time, time.Time, []marginLine, error) {
+	if filter.GroupBy == "" {
+		filter.GroupBy = entity.MarginByProduct
+	}
+	if !validMarginDimension(filter.GroupBy) {
+		return time.Time{}, time.Time{}, nil, ErrInvalidMarginDimension
+	}
+	end := time.Now()
+	if filter.EndDate != nil {
+		end = *filter.EndDate
+	}
+	start := truncateDay(end).AddDate(0, -1, 0) // Default to last month
+	if filter.StartDate != nil {
+		start = *filter.StartDate
+	}
+	if start.After(end) {
+		return time.Time{}, time.Time{}, nil, ErrInvalidMarginPeriod
+	}
+
+	orders, err := u.marginRepo.ListOrders(ctx, start, end)
+	if err != nil {
+		return time.Time{}, time.Time{}, nil, fmt.Errorf("error listing sales orders: %w", err)
+	}
+
+	skuIDs := make(map[string]bool)
+	for _, order := range orders {
+		for _, item := range order.Items {
+			skuIDs[item.SKUID] = true
+		}
+	}
+	ids := make([]string, 0, len(skuIDs))
+	for skuID := range skuIDs {
+		ids = append(ids, skuID)
+	}
+	unitCosts, err := u.costRepo.GetUnitCosts(ctx, ids)
+	if err != nil {
+		return time.Time{}, time.Time{}, nil, fmt.Errorf("error getting unit costs: %w", err)
+	}
+	var skus map[string]entity.SKU
+	if filter.GroupBy == entity.MarginByCategory {
+		if skus, err = u.marginRepo.GetSKUs(ctx, ids); err != nil {
+			return time.Time{}, time.Time{}, nil, fmt.Errorf("error getting SKUs: %w", err)
+		}
+	}
+
+	var lines []marginLine
+	for i := range orders {
+		order := &orders[i]
+		for _, item := range order.Items {
+			unitCost := unitCosts[item.SKUID]
+			if item.DropShip && item.PurchasePrice > 0 {
+				unitCost = item.PurchasePrice * order.ExchangeRate
+			}
+			lines = append(lines, marginLine{
+				order:    order,
+				key:      marginKey(filter.GroupBy, order, item, skus),
+				quantity: item.Quantity,
+				revenue:  (item.TotalPrice - item.TaxAmount) * order.ExchangeRate,
+				cost:     item.Quantity * unitCost,
+			})
+		}
+	}
+	return start, end, lines, nil
+}
+
+// labels names the groups of a dimension
+func (u *MarginUseCase) labels(ctx context.Context, dimension entity.MarginDimension, lines []marginLine) (map[string]string, error) {
+	keys := make(map[string]bool)
+	for _, line := range lines {
+		keys[line.key] = true
+	}
+	labels := make(map[string]string, len(keys))
+
+	switch dimension {
+	case entity.MarginByProduct:
+		ids := make([]string, 0, len(keys))
+		for key := range keys {
+			ids = append(ids, key)
+		}
+		skus, err := u.marginRepo.GetSKUs(ctx, ids)
+		if err != nil {
+			return nil, fmt.Errorf("error getting SKUs: %w", err)
+		}
+		for key := range keys {
+			if sku, ok := skus[key]; ok {
+				labels[key] = sku.SKUCode + " " + sku.Name
+			}
+		}
+
+	case entity.MarginByCustomer, entity.MarginBySalesperson:
+		ids := make([]uint, 0, len(keys))
+		for key := range keys {
+			if id, err := strconv.ParseUint(key, 10, 32); err == nil {
+				ids = append(ids, uint(id))
+			}
+		}
+		var names map[uint]string
+		var err error
+		if dimension == entity.MarginByCustomer {
+			names, err = u.costRepo.GetClientNames(ctx, ids)
+		} else {
+			names, err = u.marginRepo.GetUserNames(ctx, ids)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error getting names: %w", err)
+		}
+		for _, id := range ids {
+			labels[strconv.FormatUint(uint64(id), 10)] = names[id]
+		}
+
+	case entity.MarginByWarehouse:
+		ids := make([]string, 0, len(keys))
+		for key := range keys {
+			if key != unassignedMarginKey {
+				ids = append(ids, key)
+			}
+		}
+		names, err := u.marginRepo.GetStoreNames(ctx, ids)
+		if err != nil {
+			return nil, fmt.Errorf("error getting store names: %w", err)
+		}
+		for key := range keys {
+			labels[key] = names[key]
+		}
+		labels[unassignedMarginKey] = "Not delivered"
+
+	default:
+		for key := range keys {
+			labels[key] = key
+		}
+		labels[unassignedMarginKey] = "Uncategorized"
+	}
+	return labels, nil
+}
+
+// marginKey returns the group of an order line in a dimension
+func marginKey(dimension entity.MarginDimension, order *entity.MarginSalesOrder, item entity.SalesOrderItem, skus map[string]entity.SKU) string {
+	switch dimension {
+	case entity.MarginByCategory:
+		return skus[item.SKUID].Category
+	case entity.MarginByCustomer:
+		return strconv.FormatUint(uint64(order.ClientID), 10)
+	case entity.MarginBySalesperson:
+		return strconv.FormatUint(uint64(order.CreatedByID), 10)
+	case entity.MarginByWarehouse:
+		return order.Stores[item.SKUID]
+	case entity.MarginByMonth:
+		return order.OrderDate.In(time.Local).Format("2006-01")
+	default:
+		return item.SKUID
+	}
+}
+
+// validMarginDimension tells whether the margin report groups by a dimension
+func validMarginDimension(dimension entity.MarginDimension) bool {
+	for _, d := range entity.MarginDimensions {
+		if d == dimension {
+			return true
+		}
+	}
+	return false
+}
+
+// addMarginLine adds an order line to a row of the margin report
+func addMarginLine(row *entity.MarginRow, line marginLine) {
+	row.Quantity += line.quantity
+	row.Revenue += line.revenue
+	row.CostOfGoods += line.cost
+}
+
+// finishMarginRow rounds the amounts of a row and works out its margin
+func finishMarginRow(row *entity.MarginRow) {
+	row.Revenue = roundAmount(row.Revenue)
+	row.CostOfGoods = roundAmount(row.CostOfGoods)
+	row.GrossMargin = roundAmount(row.Revenue - row.CostOfGoods)
+	if row.Revenue != 0 {
+		row.GrossMarginPercent = roundAmount(row.GrossMargin / row.Revenue * 100)
+	}
+}
@@ -0,0 +1,478 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/logging"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/notification"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/realtime"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
+)
+
+var (
+	ErrNotificationNotFound        = entity.NewError(entity.ErrCodeNotFound, "notification not found")
+	ErrUnknownNotificationTemplate = entity.NewError(entity.ErrCodeInvalidArgument, "unknown notification type or channel")
+	ErrEmailNotConfigured          = entity.NewError(entity.ErrCodeUnavailable, "email delivery is not configured")
+)
+
+// deliveryTimeout bounds the background delivery of one event's email and SMS
+const deliveryTimeout = time.Minute
+
+type notificationTemplate struct {
+	subject string
+	body    string
+}
+
+// defaultNotificationTemplates are the built-in templates. Approval templates
+// may use {{document}} and {{number}}; overdue invoice templates
+// {{invoice_number}}, {{entity_name}}, {{days_overdue}}, {{amount_due}},
+// {{currency}} and {{level}}; low stock templates {{sku_code}}, {{name}},
+// {{store_id}}, {{available}} and {{reorder_point}}; condition excursion
+// templates {{metric}}, {{value}}, {{limits}}, {{location}} and {{lots}};
+// SLA breach templates {{store}}, {{measure}}, {{value}}, {{threshold}} and
+// {{period}}; impersonation templates {{impersonator}}, {{reason}} and
+// {{expires_at}}. Document emails may use
+// {{company_name}}; purchase order emails {{order_number}}, {{vendor_name}},
+// {{order_date}}, {{expected_date}}, {{grand_total}} and {{currency}}; invoice
+// emails {{invoice_number}}, {{customer_name}}, {{issue_date}}, {{due_date}},
+// {{total_amount}} and {{currency}}; report emails {{report_name}},
+// {{period}} and {{file_url}}.
+var defaultNotificationTemplates = map[entity.NotificationType]map[entity.NotificationChannel]notificationTemplate{
+	entity.NotificationApprovalPending: {
+		entity.ChannelInApp: {"{{document}} {{number}} awaits approval", "{{document}} {{number}} was submitted and is waiting for your approval."},
+		entity.ChannelEmail: {"Approval needed: {{document}} {{number}}", "{{document}} {{number}} was submitted and is waiting for your approval.\n\nSign in to review it."},
+		entity.ChannelSMS:   {"", "{{document}} {{number}} awaits your approval."},
+	},
+	entity.NotificationInvoiceOverdue: {
+		entity.ChannelInApp: {"Invoice {{invoice_number}} is {{days_overdue}} days overdue", "{{entity_name}} owes {{amount_due}} {{currency}} on invoice {{invoice_number}}. The {{level}} reminder was sent."},
+		entity.ChannelEmail: {"Overdue invoice {{invoice_number}}", "Invoice {{invoice_number}} for {{entity_name}} is {{days_overdue}} days overdue with {{amount_due}} {{currency}} outstanding.\n\nThe {{level}} reminder was sent."},
+		entity.ChannelSMS:   {"", "Invoice {{invoice_number}} ({{entity_name}}) is {{days_overdue}} days overdue: {{amount_due}} {{currency}}."},
+	},
+	entity.NotificationStockLow: {
+		entity.ChannelInApp: {"Low stock: {{sku_code}}", "{{name}} ({{sku_code}}) is down to {{available}} in store {{store_id}}, below its reorder point of {{reorder_point}}."},
+		entity.ChannelEmail: {"Low stock: {{sku_code}} {{name}}", "Available stock of {{name}} ({{sku_code}}) in store {{store_id}} is {{available}}, below its reorder point of {{reorder_point}}.\n\nConsider raising a purchase request."},
+		entity.ChannelSMS:   {"", "Low stock: {{sku_code}} at {{available}} in store {{store_id}} (reorder point {{reorder_point}})."},
+	},
+	entity.NotificationExcursion: {
+		entity.ChannelInApp: {"{{metric}} excursion in {{location}}", "{{metric}} read {{value}} in {{location}}, outside {{limits}}. {{lots}} lots were stored there."},
+		entity.ChannelEmail: {"{{metric}} excursion in {{location}}", "{{metric}} read {{value}} in {{location}}, outside its limits of {{limits}}.\n\n{{lots}} lots were stored there when the excursion started; check the quality inspections holding them."},
+		entity.ChannelSMS:   {"", "{{metric}} excursion in {{location}}: {{value}}, limits {{limits}}."},
+	},
+	entity.NotificationSLABreach: {
+		entity.ChannelInApp: {"Fulfillment SLA breached by {{store}}", "{{store}} {{measure}} was {{value}} over {{period}}, against an SLA of {{threshold}}."},
+		entity.ChannelEmail: {"Fulfillment SLA breached by {{store}}", "The {{measure}} of {{store}} was {{value}} over {{period}}, against an SLA of {{threshold}}.\n\nCheck the open order book for its late orders."},
+		entity.ChannelSMS:   {"", "SLA breach at {{store}}: {{measure}} {{value}}, SLA {{threshold}}."},
+	},
+	entity.NotificationImpersonation: {
+		entity.ChannelInApp: {"{{impersonator}} is acting as you", "{{impersonator}} started acting as you until {{expires_at}}: {{reason}}. Their actions are recorded in the audit log under both your names."},
+		entity.ChannelEmail: {"{{impersonator}} is acting as you", "{{impersonator}} started acting as you for troubleshooting until {{expires_at}}.\n\nReason: {{reason}}\n\nEverything done in the session is recorded in the audit log under both your names. You can end it from your account at any time."},
+		entity.ChannelSMS:   {"", "{{impersonator}} is acting as you until {{expires_at}}: {{reason}}."},
+	},
+	entity.DocumentEmailPurchaseOrder: {
+		entity.ChannelEmail: {"Purchase order {{order_number}} from {{company_name}}", "Dear {{vendor_name}},\n\nPlease find attached our purchase order {{order_number}} of {{order_date}} for {{grand_total}} {{currency}}, expected by {{expected_date}}.\n\nKind regards,\n{{company_name}}"},
+	},
+	entity.DocumentEmailInvoice: {
+		entity.ChannelEmail: {"Invoice {{invoice_number}} from {{company_name}}", "Dear {{customer_name}},\n\nPlease find attached invoice {{invoice_number}} of {{issue_date}} for {{total_amount}} {{currency}}, due on {{due_date}}.\n\nKind regards,\n{{company_name}}"},
+	},
+	entity.DocumentEmailReport: {
+		entity.ChannelEmail: {"Report: {{report_name}}", "The scheduled report {{report_name}} for {{period}} is attached.\n\nIt can also be downloaded from {{file_url}} until the link expires."},
+	},
+}
+
+// NotificationUseCase delivers notifications to the in-app inbox and through
+// the configured email and SMS channels, following each user's preferences.
+// A nil *NotificationUseCase is valid for raising events and sends nothing.
+type NotificationUseCase struct {
+	notificationRepo *repository.NotificationRepository
+	senders          map[entity.NotificationChannel]notification.Sender
+	publisher        *realtime.Publisher
+}
+
+// NewNotificationUseCase creates a new notification use case. Only the in-app
+// channel and the channels of the given senders are delivered. In-app
+// notifications are also pushed to their user's WebSocket connections through
+// the publisher, which may be nil.
+func NewNotificationUseCase(notificationRepo *repository.NotificationRepository, senders []notification.Sender, publisher *realtime.Publisher) *NotificationUseCase {
+	u := &NotificationUseCase{
+		notificationRepo: notificationRepo,
+		senders:          make(map[entity.NotificationChannel]notification.Sender, len(senders)),
+		publisher:        publisher,
+	}
+	for _, sender := range senders {
+		u.senders[sender.Channel()] = sender
+	}
+	return u
+}
+
+// Notify delivers an event to its recipients. The change behind the event is
+// already persisted, so failures are logged rather than returned. Email and SMS
+// are sent in the background.
+func (u *NotificationUseCase) Notify(ctx context.Context, event *entity.NotificationEvent) {
+	if u == nil {
+		return
+	}
+	if err := u.notify(ctx, event); err != nil {
+		logging.FromContext(ctx).Error("error sending notifications", "type", event.Type, "error", err)
+	}
+}
+
+func (u *NotificationUseCase) notify(ctx context.Context, event *entity.NotificationEvent) error {
+	users, err := u.notificationRepo.ListRecipients(ctx, event.Permission, event.UserIDs, event.ExceptUserIDs)
+	if err != nil {
+		return fmt.Errorf("error listing recipients: %w", err)
+	}
+	if len(users) == 0 {
+		return nil
+	}
+
+	userIDs := make([]uint, len(users))
+	for i, user := range users {
+		userIDs[i] = user.ID
+	}
+	preferences, err := u.notificationRepo.ListPreferences(ctx, userIDs)
+	if err != nil {
+		return fmt.Errorf("error listing preferences: %w", err)
+	}
+	enabled := make(map[string]bool, len(preferences))
+	for _, preference := range preferences {
+		if preference.Type == event.Type {
+			enabled[preferenceKey(preference.UserID, preference.Channel)] = preference.Enabled
+		}
+	}
+
+	templates, err := u.templates(ctx)
+	if err != nil {
+		return err
+	}
+	rendered := make(map[entity.NotificationChannel]notificationTemplate, len(entity.NotificationChannels))
+	for _, channel := range entity.NotificationChannels {
+		template := templates[templateKey(event.Type, channel)]
+		rendered[channel] = notificationTemplate{
+			subject: renderNotification(template.Subject, event.Data),
+			body:    renderNotification(template.Body, event.Data),
+		}
+	}
+
+	var inbox []entity.Notification
+	type delivery struct {
+		sender notification.Sender
+		msg    *notification.Message
+	}
+	var deliveries []delivery
+	for _, user := range users {
+		for _, channel := range entity.NotificationChannels {
+			on, ok := enabled[preferenceKey(user.ID, channel)]
+			if !ok {
+				on = channel.DefaultEnabled()
+			}
+			if !on {
+				continue
+			}
+
+			message := rendered[channel]
+			switch channel {
+			case entity.ChannelInApp:
+				inbox = append(inbox, entity.Notification{
+					UserID:        user.ID,
+					Type:          event.Type,
+					Title:         message.subject,
+					Body:          message.body,
+					ReferenceType: event.ReferenceType,
+					ReferenceID:   event.ReferenceID,
+				})
+			default:
+				sender, ok := u.senders[channel]
+				to := user.Email
+				if channel == entity.ChannelSMS {
+					to = user.Phone
+				}
+				if !ok || to == "" {
+					continue
+				}
+				deliveries = append(deliveries, delivery{sender, &notification.Message{To: to, Subject: message.subject, Body: message.body}})
+			}
+		}
+	}
+
+	if len(deliveries) > 0 {
+		logger := logging.FromContext(ctx)
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), deliveryTimeout)
+			defer cancel()
+			for _, d := range deliveries {
+				if err := d.sender.Send(ctx, d.msg); err != nil {
+					logger.Error("error sending notification", "type", event.Type, "channel", d.sender.Channel(), "to", d.msg.To, "error", err)
+				}
+			}
+		}()
+	}
+
+	if err := u.notificationRepo.CreateNotifications(ctx, inbox); err != nil {
+		return fmt.Errorf("error creating in-app notifications: %w", err)
+	}
+	for i := range inbox {
+		u.publisher.Publish(&entity.RealtimeEvent{
+			Topic:   entity.TopicNotification,
+			UserIDs: []uint{inbox[i].UserID},
+			Data:    inbox[i],
+		})
+	}
+	return nil
+}
+
+// EmailEnabled reports whether email delivery is configured
+func (u *NotificationUseCase) EmailEnabled() bool {
+	if u == nil {
+		return false
+	}
+	_, ok := u.senders[entity.ChannelEmail]
+	return ok
+}
+
+// SendEmail emails a document to an external address, such as a vendor's,
+// rendering the email template of its type. Unlike Notify, it sends before
+// returning and reports failures, so that the caller can retry.
+func (u *NotificationUseCase) SendEmail(ctx context.Context, notificationType entity.NotificationType, to string, data map[string]string, attachments []notification.Attachment) error {
+	sender, ok := u.senders[entity.ChannelEmail]
+	if !ok {
+		return ErrEmailNotConfigured
+	}
+	templates, err := u.templates(ctx)
+	if err != nil {
+		return err
+	}
+	template, ok := templates[templateKey(notificationType, entity.ChannelEmail)]
+	if !ok {
+		return ErrUnknownNotificationTemplate
+	}
+
+	return sender.Send(ctx, &notification.Message{
+		To:          to,
+		Subject:     renderNotification(template.Subject, data),
+		Body:        renderNotification(template.Body, data),
+		Attachments: attachments,
+	})
+}
+
+// ListNotifications lists a user's inbox
+func (u *NotificationUseCase) ListNotifications(ctx context.Context, filter *entity.NotificationFilter) ([]entity.Notification, int64, error) {
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+	if filter.PageSize <= 0 {
+		filter.PageSize = 20
+	}
+
+	notifications, total, err := u.notificationRepo.ListNotifications(ctx, filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error listing notifications: %w", err)
+	}
+	return notifications, total, nil
+}
+
+// CountUnread counts a user's unread notifications
+func (u *NotificationUseCase) CountUnread(ctx context.Context, userID uint) (int64, error) {
+	count, err := u.notificationRepo.CountUnread(ctx, userID)
+	if err != nil {
+		return 0, fmt.Errorf("error counting unread notifications: %w", err)
+	}
+	return count, nil
+}
+
+// SetRead marks one of a user's notifications read or unread. Marking a read
+// notification read again keeps the time it was first read.
+func (u *NotificationUseCase) SetRead(ctx context.Context, userID, id uint, read bool) (*entity.Notification, error) {
+	notification, err := u.notificationRepo.GetNotification(ctx, userID, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, ErrNotificationNotFound
+		}
+		return nil, fmt.Errorf("error getting notification: %w", err)
+	}
+	if read == (notification.ReadAt != nil) {
+		return notification, nil
+	}
+
+	notification.ReadAt = nil
+	if read {
+		now := time.Now()
+		notification.ReadAt = &now
+	}
+	if err := u.notificationRepo.SetRead(ctx, userID, id, notification.ReadAt); err != nil {
+		return nil, fmt.Errorf("error updating notification: %w", err)
+	}
+	return notification, nil
+}
+
+// MarkAllRead marks all of a user's notifications read and returns how many were unread
+func (u *NotificationUseCase) MarkAllRead(ctx context.Context, userID uint) (int64, error) {
+	count, err := u.notificationRepo.MarkAllRead(ctx, userID, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("error marking notifications read: %w", err)
+	}
+	return count, nil
+}
+
+// GetSettings returns a user's phone number and a preference for every
+// notification type and channel, defaults included
+func (u *NotificationUseCase) GetSettings(ctx context.Context, userID uint) (*entity.NotificationSettings, error) {
+	phone, err := u.notificationRepo.GetPhone(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting phone number: %w", err)
+	}
+	saved, err := u.notificationRepo.ListPreferences(ctx, []uint{userID})
+	if err != nil {
+		return nil, fmt.Errorf("error listing preferences: %w", err)
+	}
+	byKey := make(map[string]entity.NotificationPreference, len(saved))
+	for _, preference := range saved {
+		byKey[templateKey(preference.Type, preference.Channel)] = preference
+	}
+
+	settings := &entity.NotificationSettings{Phone: phone}
+	for _, notificationType := range entity.NotificationTypes {
+		for _, channel := range entity.NotificationChannels {
+			preference, ok := byKey[templateKey(notificationType, channel)]
+			if !ok {
+				preference = entity.NotificationPreference{
+					UserID:  userID,
+					Type:    notificationType,
+					Channel: channel,
+					Enabled: channel.DefaultEnabled(),
+				}
+			}
+			settings.Preferences = append(settings.Preferences, preference)
+		}
+	}
+	return settings, nil
+}
+
+// UpdateSettings changes a user's phone number and channel preferences
+func (u *NotificationUseCase) UpdateSettings(ctx context.Context, userID uint, req *entity.UpdateNotificationSettingsRequest) (*entity.NotificationSettings, error) {
+	if req.Phone != nil {
+		if err := u.notificationRepo.SetPhone(ctx, userID, *req.Phone); err != nil {
+			return nil, fmt.Errorf("error updating phone number: %w", err)
+		}
+	}
+
+	now := time.Now()
+	preferences := make([]entity.NotificationPreference, len(req.Preferences))
+	for i, input := range req.Preferences {
+		preferences[i] = entity.NotificationPreference{
+			UserID:    userID,
+			Type:      input.Type,
+			Channel:   input.Channel,
+			Enabled:   input.Enabled,
+			UpdatedAt: now,
+		}
+	}
+	if err := u.notificationRepo.SavePreferences(ctx, preferences); err != nil {
+		return nil, fmt.Errorf("error saving preferences: %w", err)
+	}
+	return u.GetSettings(ctx, userID)
+}
+
+// ListTemplates lists the template in use for every notification type and channel
+func (u *NotificationUseCase) ListTemplates(ctx context.Context) ([]entity.NotificationTemplate, error) {
+	templates, err := u.templates(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]entity.NotificationTemplate, 0, len(templates))
+	for _, notificationType := range entity.NotificationTypes {
+		for _, channel := range entity.NotificationChannels {
+			list = append(list, templates[templateKey(notificationType, channel)])
+		}
+	}
+	for _, notificationType := range entity.DocumentEmailTypes {
+		list = append(list, templates[templateKey(notificationType, entity.ChannelEmail)])
+	}
+	return list, nil
+}
+
+// UpdateTemplate overrides the built-in template of a notification type and channel
+func (u *NotificationUseCase) UpdateTemplate(ctx context.Context, notificationType entity.NotificationType, channel entity.NotificationChannel, req *entity.UpdateNotificationTemplateRequest, userID *uint) (*entity.NotificationTemplate, error) {
+	if _, ok := defaultNotificationTemplates[notificationType][channel]; !ok {
+		return nil, ErrUnknownNotificationTemplate
+	}
+
+	template := &entity.NotificationTemplate{
+		Type:      notificationType,
+		Channel:   channel,
+		Subject:   req.Subject,
+		Body:      req.Body,
+		Custom:    true,
+		UpdatedBy: userID,
+		UpdatedAt: time.Now(),
+	}
+	if err := u.notificationRepo.SaveTemplate(ctx, template); err != nil {
+		return nil, fmt.Errorf("error saving notification template: %w", err)
+	}
+	return template, nil
+}
+
+// ResetTemplate removes the override of a notification type and channel and
+// returns the built-in template
+func (u *NotificationUseCase) ResetTemplate(ctx context.Context, notificationType entity.NotificationType, channel entity.NotificationChannel) (*entity.NotificationTemplate, error) {
+	builtIn, ok := defaultNotificationTemplates[notificationType][channel]
+	if !ok {
+		return nil, ErrUnknownNotificationTemplate
+	}
+	if err := u.notificationRepo.DeleteTemplate(ctx, notificationType, channel); err != nil {
+		return nil, fmt.Errorf("error deleting notification template: %w", err)
+	}
+	return &entity.NotificationTemplate{Type: notificationType, Channel: channel, Subject: builtIn.subject, Body: builtIn.body}, nil
+}
+
+// templates returns the template in use for every notification type and
+// channel, keyed by templateKey
+func (u *NotificationUseCase) templates(ctx context.Context) (map[string]entity.NotificationTemplate, error) {
+	templates := make(map[string]entity.NotificationTemplate)
+	for notificationType, channels := range defaultNotificationTemplates {
+		for channel, builtIn := range channels {
+			templates[templateKey(notificationType, channel)] = entity.NotificationTemplate{
+				Type:    notificationType,
+				Channel: channel,
+				Subject: builtIn.subject,
+				Body:    builtIn.body,
+			}
+		}
+	}
+
+	overrides, err := u.notificationRepo.ListTemplates(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error listing notification templates: %w", err)
+	}
+	for _, override := range overrides {
+		key := templateKey(override.Type, override.Channel)
+		if _, ok := templates[key]; !ok {
+			continue
+		}
+		override.Custom = true
+		templates[key] = override
+	}
+	return templates, nil
+}
+
+// renderNotification fills in the {{key}} placeholders of a template
+func renderNotification(template string, data map[string]string) string {
+	placeholders := make([]string, 0, 2*len(data))
+	for key, value := range data {
+		placeholders = append(placeholders, "{{"+key+"}}", value)
+	}
+	return strings.NewReplacer(placeholders...).Replace(template)
+}
+
+func templateKey(notificationType entity.NotificationType, channel entity.NotificationChannel) string {
+	return string(notificationType) + "|" + string(channel)
+}
+
+func preferenceKey(userID uint, channel entity.NotificationChannel) string {
+	return fmt.Sprintf("%d|%s", userID, channel)
+}
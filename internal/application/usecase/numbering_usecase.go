@@ -0,0 +1,152 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
+)
+
+var (
+	ErrNumberingSchemeNotFound = entity.NewError(entity.ErrCodeNotFound, "numbering scheme not found")
+	ErrNumberingSchemeOverlap  = entity.NewError(entity.ErrCodeInvalidArgument, "the numbers of the scheme may repeat those of another scheme of the document type; hold {store} in the pattern of a store's scheme, or render a prefix of its own")
+	ErrNumberingStoreCode      = entity.NewError(entity.ErrCodeFailedPrecondition, "the store has no code to render {store}")
+)
+
+// NumberingUseCase handles the numbering schemes documents are numbered by
+type NumberingUseCase struct {
+	repo      *repository.NumberingRepository
+	sequences *repository.SequenceGenerator
+	storeRepo *repository.StoreRepository
+}
+
+// NewNumberingUseCase creates a new numbering use case
+func NewNumberingUseCase(repo *repository.NumberingRepository, sequences *repository.SequenceGenerator, storeRepo *repository.StoreRepository) *NumberingUseCase {
+	return &NumberingUseCase{
+		repo:      repo,
+		sequences: sequences,
+		storeRepo: storeRepo,
+	}
+}
+
+// ListSchemes lists the configured numbering schemes
+func (u *NumberingUseCase) ListSchemes(ctx context.Context) ([]entity.NumberingScheme, error) {
+	return u.repo.ListSchemes(ctx)
+}
+
+// SaveScheme configures the numbering of a document type, or of its documents
+// of one store, replacing the scheme configured before. Settings left empty
+// take the defaults of the document type. Schemes count apart, so a scheme
+// whose numbers may repeat those of another scheme of the type is refused.
+func (u *NumberingUseCase) SaveScheme(ctx context.Context, req *entity.NumberingSchemeRequest) (*entity.NumberingScheme, error) {
+	scheme := &entity.NumberingScheme{
+		DocumentType:         req.DocumentType,
+		StoreID:              strings.TrimSpace(req.StoreID),
+		Prefix:               strings.TrimSpace(req.Prefix),
+		Pattern:              strings.TrimSpace(req.Pattern),
+		Padding:              req.Padding,
+		Reset:                req.Reset,
+		FiscalYearStartMonth: req.FiscalYearStartMonth,
+	}
+	if scheme.Prefix == "" {
+		scheme.Prefix = entity.DefaultNumberingSchemes[scheme.DocumentType].Prefix
+	}
+	scheme.ApplyDefaults()
+	if err := scheme.Validate(); err != nil {
+		return nil, err
+	}
+	if scheme.StoreID != "" {
+		store, err := u.storeRepo.GetByID(ctx, scheme.StoreID)
+		if err != nil {
+			return nil, fmt.Errorf("error getting store: %w", err)
+		}
+		scheme.StoreCode = strings.TrimSpace(store.Code)
+		if scheme.StoreCode == "" && strings.Contains(scheme.Pattern, "{store}") {
+			return nil, ErrNumberingStoreCode
+		}
+	}
+
+	others, err := u.otherSchemes(ctx, scheme)
+	if err != nil {
+		return nil, err
+	}
+	for i := range others {
+		if scheme.Overlaps(&others[i]) {
+			return nil, fmt.Errorf("%w (%s)", ErrNumberingSchemeOverlap, describeScheme(&others[i]))
+		}
+	}
+
+	if err := u.repo.SaveScheme(ctx, scheme); err != nil {
+		return nil, fmt.Errorf("error saving numbering scheme: %w", err)
+	}
+	return scheme, nil
+}
+
+// otherSchemes returns the schemes of the scheme's document type it counts
+// apart from, with the default scheme of the type when none is configured
+func (u *NumberingUseCase) otherSchemes(ctx context.Context, scheme *entity.NumberingScheme) ([]entity.NumberingScheme, error) {
+	schemes, err := u.repo.ListSchemes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error listing numbering schemes: %w", err)
+	}
+
+	var others []entity.NumberingScheme
+	typeScheme := false
+	for _, s := range schemes {
+		if s.DocumentType != scheme.DocumentType {
+			continue
+		}
+		if s.StoreID == "" {
+			typeScheme = true
+		}
+		if s.StoreID != scheme.StoreID {
+			others = append(others, s)
+		}
+	}
+	if !typeScheme && scheme.StoreID != "" {
+		def := entity.DefaultNumberingSchemes[scheme.DocumentType]
+		def.DocumentType = scheme.DocumentType
+		def.ApplyDefaults()
+		others = append(others, def)
+	}
+	return others, nil
+}
+
+func describeScheme(s *entity.NumberingScheme) string {
+	if s.StoreID == "" {
+		return fmt.Sprintf("the scheme of every store, prefix %q", s.Prefix)
+	}
+	return fmt.Sprintf("the scheme of store %s, prefix %q", s.StoreID, s.Prefix)
+}
+
+// DeleteScheme removes a configured scheme, so that its documents are
+// numbered by the scheme of the document type, or the default, again
+func (u *NumberingUseCase) DeleteScheme(ctx context.Context, id uint) error {
+	err := u.repo.DeleteScheme(ctx, id)
+	if errors.Is(err, repository.ErrRecordNotFound) {
+		return ErrNumberingSchemeNotFound
+	}
+	return err
+}
+
+// PreviewNumber returns the scheme a new document of a type and store would
+// be numbered by, with the number it would be given now, without drawing it
+func (u *NumberingUseCase) PreviewNumber(ctx context.Context, documentType entity.DocumentType, storeID string) (*entity.NumberPreview, error) {
+	if _, ok := entity.DefaultNumberingSchemes[documentType]; !ok {
+		return nil, entity.NewError(entity.ErrCodeInvalidArgument, fmt.Sprintf("unknown document type %q", documentType))
+	}
+	scheme, err := u.sequences.Scheme(ctx, documentType, storeID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	current, err := u.sequences.CurrentSequence(ctx, scheme.CounterKey(now))
+	if err != nil {
+		return nil, err
+	}
+	return &entity.NumberPreview{Scheme: *scheme, Next: scheme.Format(now, current+1)}, nil
+}
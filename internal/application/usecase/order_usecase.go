@@ -4,28 +4,51 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/eventbus"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/export"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/extension"
 	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
 )
 
 var (
-	ErrInvalidOrderStatus = errors.New("invalid order status for this operation")
-	ErrInsufficientStock  = errors.New("insufficient stock for order items")
+	ErrInvalidOrderStatus   = entity.NewError(entity.ErrCodeFailedPrecondition, "invalid order status for this operation")
+	ErrInsufficientStock    = entity.NewError(entity.ErrCodeFailedPrecondition, "insufficient stock for order items")
+	ErrInvalidExternalOrder = entity.NewError(entity.ErrCodeInvalidArgument, "external orders need a source, a reference, a client, a store and items")
+	ErrDropShipVendor       = entity.NewError(entity.ErrCodeInvalidArgument, "drop-ship lines need a vendor_id, or a SKU with a vendor")
+	ErrDropShipDelivery     = entity.NewError(entity.ErrCodeFailedPrecondition, "drop-ship lines are shipped by their vendor, not from a store")
 )
 
 // OrderUseCase handles business logic for sales orders and delivery orders
 type OrderUseCase struct {
-	orderRepo  *repository.OrderRepository
-	stocksRepo *repository.StocksRepository
+	orderRepo     *repository.OrderRepository
+	stocksRepo    *repository.StocksRepository
+	skuRepo       *repository.SKURepository
+	currencyUC    *CurrencyUseCase
+	calendarUC    *CalendarUseCase
+	fiscalUC      *FiscalUseCase
+	promiseDays   int // business days after the order date that orders are promised for
+	hooks         *extension.Hooks
+	bus           *eventbus.Bus
+	customFieldUC *CustomFieldUseCase
 }
 
 // NewOrderUseCase creates a new OrderUseCase
-func NewOrderUseCase(orderRepo *repository.OrderRepository, stocksRepo *repository.StocksRepository) *OrderUseCase {
+func NewOrderUseCase(orderRepo *repository.OrderRepository, stocksRepo *repository.StocksRepository, skuRepo *repository.SKURepository, currencyUC *CurrencyUseCase, calendarUC *CalendarUseCase, fiscalUC *FiscalUseCase, promiseDays int, hooks *extension.Hooks, bus *eventbus.Bus, customFieldUC *CustomFieldUseCase) *OrderUseCase {
 	return &OrderUseCase{
-		orderRepo:  orderRepo,
-		stocksRepo: stocksRepo,
+		orderRepo:     orderRepo,
+		stocksRepo:    stocksRepo,
+		skuRepo:       skuRepo,
+		currencyUC:    currencyUC,
+		calendarUC:    calendarUC,
+		fiscalUC:      fiscalUC,
+		promiseDays:   promiseDays,
+		hooks:         hooks,
+		bus:           bus,
+		customFieldUC: customFieldUC,
 	}
 }
 
@@ -35,9 +58,23 @@ func (u *OrderUseCase) CreateSalesOrder(ctx context.Context, order *entity.Sales
 	if len(order.Items) == 0 {
 		return repository.ErrInvalidData
 	}
+	customFields, err := u.customFieldUC.CheckValues(ctx, entity.CustomFieldEntitySalesOrder, order.CustomFields)
+	if err != nil {
+		return err
+	}
+	order.CustomFields = customFields
+	if err := u.explodeKits(ctx, order.Items); err != nil {
+		return err
+	}
+	if err := u.assignDropShipVendors(ctx, order.Items); err != nil {
+		return err
+	}
+	if err := u.fillCustomsData(ctx, order.Items); err != nil {
+		return err
+	}
 
-	// Check stock availability
-	available, insufficientItems, err := u.orderRepo.CheckStockAvailability(ctx, warehouseID, order.Items)
+	// Check stock availability, of the components of kits, leaving drop-ship lines out
+	available, insufficientItems, err := u.orderRepo.CheckStockAvailability(ctx, warehouseID, order.Items.StockQuantities())
 	if err != nil {
 		return err
 	}
@@ -60,13 +97,163 @@ func (u *OrderUseCase) CreateSalesOrder(ctx context.Context, order *entity.Sales
 	order.CreatedByID = createdByID
 	order.OrderDate = time.Now()
 
+	// Promise the order on the warehouse calendar so weekends and holidays are skipped
+	promisedDate, err := u.calendarUC.AddBusinessDays(ctx, warehouseID, order.OrderDate, u.promiseDays)
+	if err != nil {
+		return err
+	}
+	order.PromisedDate = &promisedDate
+
 	// Calculate totals
 	u.calculateOrderTotals(order)
 
+	// Record the base currency total at the order date rate
+	conversion, err := u.currencyUC.Convert(ctx, order.GrandTotal, order.CurrencyCode, order.OrderDate)
+	if err != nil {
+		return err
+	}
+	order.CurrencyCode = conversion.Currency
+	order.ExchangeRate = conversion.Rate
+	order.BaseGrandTotal = conversion.BaseAmount
+
 	// Create the order
 	return u.orderRepo.CreateSalesOrder(ctx, order)
 }
 
+// explodeKits records the quantities of their components the kit lines of
+// an order take
+func (u *OrderUseCase) explodeKits(ctx context.Context, items entity.SalesOrderItems) error {
+	skuIDs := make([]string, len(items))
+	for i, item := range items {
+		skuIDs[i] = item.SKUID
+	}
+	kits, err := u.skuRepo.GetKitComponents(ctx, skuIDs)
+	if err != nil {
+		return fmt.Errorf("error getting kit components: %w", err)
+	}
+
+	for i := range items {
+		items[i].Components = nil
+		for _, component := range kits[items[i].SKUID] {
+			items[i].Components = append(items[i].Components, entity.SalesOrderComponent{
+				SKUID:    component.ComponentID,
+				Quantity: component.Quantity * items[i].Quantity,
+			})
+		}
+	}
+	return nil
+}
+
+// assignDropShipVendors gives the drop-ship lines of an order without a
+// vendor the vendor of their SKU. Purchase orders are only linked to lines
+// when they are generated.
+func (u *OrderUseCase) assignDropShipVendors(ctx context.Context, items entity.SalesOrderItems) error {
+	for i := range items {
+		items[i].PurchaseOrderID = ""
+		if !items[i].DropShip || items[i].VendorID != nil {
+			continue
+		}
+		sku, err := u.skuRepo.GetSKUByID(ctx, items[i].SKUID)
+		if err != nil {
+			return ErrSKUNotFound
+		}
+		if sku.VendorID == nil {
+			return fmt.Errorf("%w: %s", ErrDropShipVendor, sku.SKUCode)
+		}
+		items[i].VendorID = sku.VendorID
+	}
+	return nil
+}
+
+// fillCustomsData validates the customs data given on the lines of an order
+// and completes it from their SKUs
+func (u *OrderUseCase) fillCustomsData(ctx context.Context, items entity.SalesOrderItems) error {
+	skuIDs := make([]string, len(items))
+	for i := range items {
+		if err := checkCustomsData(&items[i].CustomsData); err != nil {
+			return err
+		}
+		skuIDs[i] = items[i].SKUID
+	}
+	data, err := u.skuRepo.GetCustomsData(ctx, skuIDs)
+	if err != nil {
+		return fmt.Errorf("error getting customs data: %w", err)
+	}
+	for i := range items {
+		items[i].CustomsData.Fill(data[items[i].SKUID])
+	}
+	return nil
+}
+
+// explodeDeliveryKits replaces the kits of delivery items with the
+// components the order took for them, in proportion to the kits delivered
+func explodeDeliveryKits(order *entity.SalesOrder, items entity.DeliveryOrderItems) entity.DeliveryOrderItems {
+	kits := make(map[string]entity.SalesOrderItem)
+	for _, item := range order.Items {
+		if len(item.Components) > 0 && item.Quantity > 0 {
+			kits[item.SKUID] = item
+		}
+	}
+	if len(kits) == 0 {
+		return items
+	}
+
+	exploded := make(entity.DeliveryOrderItems, 0, len(items))
+	for _, item := range items {
+		kit, ok := kits[item.SKUID]
+		if !ok {
+			exploded = append(exploded, item)
+			continue
+		}
+		for _, component := range kit.Components {
+			perKit := component.Quantity / kit.Quantity
+			exploded = append(exploded, entity.DeliveryOrderItem{
+				SKUID:             component.SKUID,
+				OrderedQuantity:   item.OrderedQuantity * perKit,
+				ShippedQuantity:   item.ShippedQuantity * perKit,
+				RemainingQuantity: item.RemainingQuantity * perKit,
+				Notes:             item.Notes,
+				KitSKUID:          kit.SKUID,
+			})
+		}
+	}
+	return exploded
+}
+
+// IngestExternalOrder creates a draft sales order for an order an external
+// system handed in, recorded as created by the given user. An order already
+// ingested is returned as it is, so a message delivered twice creates one order.
+func (u *OrderUseCase) IngestExternalOrder(ctx context.Context, external *entity.ExternalSalesOrder, userID uint) (*entity.SalesOrder, error) {
+	if external.Source == "" || external.Reference == "" || external.ClientID == 0 || external.StoreID == "" || len(external.Items) == 0 {
+		return nil, ErrInvalidExternalOrder
+	}
+
+	externalRef := external.Source + ":" + external.Reference
+	existing, err := u.orderRepo.GetSalesOrderByExternalRef(ctx, externalRef)
+	if err == nil {
+		return existing, nil
+	}
+	if !errors.Is(err, repository.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	order := &entity.SalesOrder{
+		ClientID:        external.ClientID,
+		Items:           external.Items,
+		CurrencyCode:    external.CurrencyCode,
+		PaymentMethod:   external.PaymentMethod,
+		PaymentStatus:   entity.PaymentStatusPending,
+		ShippingAddress: external.ShippingAddress,
+		BillingAddress:  external.BillingAddress,
+		Notes:           external.Notes,
+		ExternalRef:     &externalRef,
+	}
+	if err := u.CreateSalesOrder(ctx, order, external.StoreID, strconv.FormatUint(uint64(userID), 10)); err != nil {
+		return nil, fmt.Errorf("error ingesting order %s: %w", externalRef, err)
+	}
+	return order, nil
+}
+
 // Rest of the methods remain unchanged as they don't directly use stocksRepo
 // Omitted for brevity...
 
@@ -83,12 +270,25 @@ func (u *OrderUseCase) ConfirmSalesOrder(ctx context.Context, orderID string, us
 		return ErrInvalidOrderStatus
 	}
 
+	if err := u.hooks.Before(ctx, extension.EventBeforeOrderConfirm, order); err != nil {
+		return err
+	}
+
 	// Update status
-	return u.orderRepo.UpdateSalesOrderStatus(ctx, orderID, entity.SalesOrderStatusConfirmed)
+	if err := u.setSalesOrderStatus(ctx, order, entity.SalesOrderStatusConfirmed); err != nil {
+		return err
+	}
+
+	u.bus.Publish(ctx, entity.OrderConfirmed{Order: order})
+	return nil
 }
 
 // CreateDeliveryOrder creates a delivery order for a sales order
 func (u *OrderUseCase) CreateDeliveryOrder(ctx context.Context, delivery *entity.DeliveryOrder, userID string) error {
+	if err := entity.AccessScopeFromContext(ctx).CheckStore(delivery.StoreID); err != nil {
+		return err
+	}
+
 	// Get the sales order
 	order, err := u.orderRepo.GetSalesOrderByID(ctx, delivery.SalesOrderID)
 	if err != nil {
@@ -100,6 +300,22 @@ func (u *OrderUseCase) CreateDeliveryOrder(ctx context.Context, delivery *entity
 		return ErrInvalidOrderStatus
 	}
 
+	// Drop-ship lines are shipped by their vendor
+	stocked := order.Items.StockQuantities()
+	for _, item := range order.Items {
+		if _, ok := stocked[item.SKUID]; !item.DropShip || ok {
+			continue
+		}
+		for _, delivered := range delivery.Items {
+			if delivered.SKUID == item.SKUID {
+				return ErrDropShipDelivery
+			}
+		}
+	}
+
+	// Kits are shipped as their components
+	delivery.Items = explodeDeliveryKits(order, delivery.Items)
+
 	// Set initial status and created by
 	delivery.Status = entity.DeliveryOrderStatusPending
 	createdByID, _ := parseUserID(userID)
@@ -116,13 +332,13 @@ func (u *OrderUseCase) CreateDeliveryOrder(ctx context.Context, delivery *entity
 	}
 
 	// Update sales order status to processing
-	return u.orderRepo.UpdateSalesOrderStatus(ctx, order.ID, entity.SalesOrderStatusProcessing)
+	return u.setSalesOrderStatus(ctx, order, entity.SalesOrderStatusProcessing)
 }
 
 // PrepareDelivery updates a delivery order status to preparing
 func (u *OrderUseCase) PrepareDelivery(ctx context.Context, deliveryID string) error {
 	// Get the delivery order
-	delivery, err := u.orderRepo.GetDeliveryOrderByID(ctx, deliveryID)
+	delivery, err := u.getDeliveryOrder(ctx, deliveryID)
 	if err != nil {
 		return err
 	}
@@ -144,19 +360,26 @@ func (u *OrderUseCase) ShipDelivery(ctx context.Context, deliveryID string, user
 	}
 
 	// Get the delivery order to update the sales order
-	delivery, err := u.orderRepo.GetDeliveryOrderByID(ctx, deliveryID)
+	delivery, err := u.getDeliveryOrder(ctx, deliveryID)
+	if err != nil {
+		return err
+	}
+
+	u.bus.Publish(ctx, entity.DeliveryShipped{Delivery: delivery})
+
+	order, err := u.orderRepo.GetSalesOrderByID(ctx, delivery.SalesOrderID)
 	if err != nil {
 		return err
 	}
 
 	// Update sales order status to shipped
-	return u.orderRepo.UpdateSalesOrderStatus(ctx, delivery.SalesOrderID, entity.SalesOrderStatusShipped)
+	return u.setSalesOrderStatus(ctx, order, entity.SalesOrderStatusShipped)
 }
 
 // CompleteDelivery marks a delivery as delivered
 func (u *OrderUseCase) CompleteDelivery(ctx context.Context, deliveryID string) error {
 	// Get the delivery order
-	delivery, err := u.orderRepo.GetDeliveryOrderByID(ctx, deliveryID)
+	delivery, err := u.getDeliveryOrder(ctx, deliveryID)
 	if err != nil {
 		return err
 	}
@@ -171,8 +394,13 @@ func (u *OrderUseCase) CompleteDelivery(ctx context.Context, deliveryID string)
 		return err
 	}
 
+	order, err := u.orderRepo.GetSalesOrderByID(ctx, delivery.SalesOrderID)
+	if err != nil {
+		return err
+	}
+
 	// Update sales order status to delivered
-	return u.orderRepo.UpdateSalesOrderStatus(ctx, delivery.SalesOrderID, entity.SalesOrderStatusDelivered)
+	return u.setSalesOrderStatus(ctx, order, entity.SalesOrderStatusDelivered)
 }
 
 // CompleteSalesOrder marks a sales order as completed
@@ -189,7 +417,7 @@ func (u *OrderUseCase) CompleteSalesOrder(ctx context.Context, orderID string) e
 	}
 
 	// Update status
-	return u.orderRepo.UpdateSalesOrderStatus(ctx, orderID, entity.SalesOrderStatusCompleted)
+	return u.setSalesOrderStatus(ctx, order, entity.SalesOrderStatusCompleted)
 }
 
 // CreateInvoice creates an invoice for a sales order
@@ -210,6 +438,9 @@ func (u *OrderUseCase) CreateInvoice(ctx context.Context, invoice *entity.Invoic
 	createdByID, _ := parseUserID(userID)
 	invoice.CreatedByID = createdByID
 	invoice.IssueDate = time.Now()
+	if err := u.fiscalUC.CheckOpen(ctx, invoice.IssueDate); err != nil {
+		return err
+	}
 
 	// If amount not provided, use the one from sales order
 	if invoice.Amount == 0 {
@@ -222,7 +453,18 @@ func (u *OrderUseCase) CreateInvoice(ctx context.Context, invoice *entity.Invoic
 		invoice.TotalAmount = order.GrandTotal
 	}
 
-	// Create the invoice
+	// Invoices are issued in the order currency at the issue date rate
+	conversion, err := u.currencyUC.Convert(ctx, invoice.TotalAmount, order.CurrencyCode, invoice.IssueDate)
+	if err != nil {
+		return err
+	}
+	invoice.CurrencyCode = conversion.Currency
+	invoice.ExchangeRate = conversion.Rate
+	invoice.BaseTotalAmount = conversion.BaseAmount
+	invoice.PrepaidAmount = 0
+	invoice.AmountDue = invoice.TotalAmount
+
+	// Create the invoice, settled with the unapplied deposits of the order
 	return u.orderRepo.CreateInvoice(ctx, invoice)
 }
 
@@ -238,9 +480,22 @@ func (u *OrderUseCase) IssueInvoice(ctx context.Context, invoiceID string) error
 	if invoice.Status != entity.InvoiceStatusDraft {
 		return ErrInvalidOrderStatus
 	}
+	if err := u.fiscalUC.CheckOpen(ctx, invoice.IssueDate); err != nil {
+		return err
+	}
+
+	if err := u.hooks.Before(ctx, extension.EventBeforeInvoiceIssue, invoice); err != nil {
+		return err
+	}
 
 	// Update status
-	return u.orderRepo.UpdateInvoiceStatus(ctx, invoiceID, entity.InvoiceStatusIssued)
+	if err := u.orderRepo.UpdateInvoiceStatus(ctx, invoiceID, entity.InvoiceStatusIssued); err != nil {
+		return err
+	}
+
+	invoice.Status = entity.InvoiceStatusIssued
+	u.bus.Publish(ctx, entity.InvoiceIssued{Invoice: invoice})
+	return nil
 }
 
 // PayInvoice marks an invoice as paid
@@ -255,6 +510,9 @@ func (u *OrderUseCase) PayInvoice(ctx context.Context, invoiceID string) error {
 	if invoice.Status != entity.InvoiceStatusIssued && invoice.Status != entity.InvoiceStatusPartial {
 		return ErrInvalidOrderStatus
 	}
+	if err := u.fiscalUC.CheckOpen(ctx, time.Now()); err != nil {
+		return err
+	}
 
 	// Update invoice status
 	if err := u.orderRepo.UpdateInvoiceStatus(ctx, invoiceID, entity.InvoiceStatusPaid); err != nil {
@@ -289,7 +547,7 @@ func (u *OrderUseCase) CancelSalesOrder(ctx context.Context, orderID string) err
 	for _, delivery := range order.DeliveryOrders {
 		if delivery.Status == entity.DeliveryOrderStatusDelivered ||
 			delivery.Status == entity.DeliveryOrderStatusInTransit {
-			return errors.New("cannot cancel order with deliveries in transit or delivered")
+			return entity.NewError(entity.ErrCodeFailedPrecondition, "cannot cancel order with deliveries in transit or delivered")
 		}
 
 		// Cancel any pending or preparing deliveries
@@ -304,14 +562,33 @@ func (u *OrderUseCase) CancelSalesOrder(ctx context.Context, orderID string) err
 	// Cancel any draft invoices
 	for _, invoice := range order.Invoices {
 		if invoice.Status == entity.InvoiceStatusDraft {
-			if err := u.orderRepo.UpdateInvoiceStatus(ctx, invoice.ID, entity.InvoiceStatusCancelled); err != nil {
+			if err := u.orderRepo.CancelInvoice(ctx, invoice.ID); err != nil {
 				return err
 			}
 		}
 	}
 
+	// Return any reserved stock to the pool
+	if err := u.orderRepo.ReleaseOrderAllocations(ctx, orderID); err != nil {
+		return err
+	}
+
 	// Update order status
-	return u.orderRepo.UpdateSalesOrderStatus(ctx, orderID, entity.SalesOrderStatusCancelled)
+	return u.setSalesOrderStatus(ctx, order, entity.SalesOrderStatusCancelled)
+}
+
+// setSalesOrderStatus moves a sales order to a new status and publishes the change
+func (u *OrderUseCase) setSalesOrderStatus(ctx context.Context, order *entity.SalesOrder, status entity.SalesOrderStatus) error {
+	if err := u.orderRepo.UpdateSalesOrderStatus(ctx, order.ID, status); err != nil {
+		return err
+	}
+
+	previous := order.Status
+	order.Status = status
+	if previous != status {
+		u.bus.Publish(ctx, entity.OrderStatusChanged{Order: order, PreviousStatus: previous})
+	}
+	return nil
 }
 
 // GetSalesOrder retrieves a sales order by ID
@@ -319,14 +596,79 @@ func (u *OrderUseCase) GetSalesOrder(ctx context.Context, id string) (*entity.Sa
 	return u.orderRepo.GetSalesOrderByID(ctx, id)
 }
 
+// UpdateSalesOrderCustomFields replaces the custom field values of a sales order
+func (u *OrderUseCase) UpdateSalesOrderCustomFields(ctx context.Context, id string, values entity.JSONMap) (*entity.SalesOrder, error) {
+	order, err := u.orderRepo.GetSalesOrderByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if order.CustomFields, err = u.customFieldUC.CheckValues(ctx, entity.CustomFieldEntitySalesOrder, values); err != nil {
+		return nil, err
+	}
+	if err := u.orderRepo.UpdateSalesOrder(ctx, order); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
 // ListSalesOrders retrieves a list of sales orders based on filter
 func (u *OrderUseCase) ListSalesOrders(ctx context.Context, filter *entity.SalesOrderFilter) ([]entity.SalesOrder, error) {
+	if err := u.parseCustomFieldFilter(ctx, filter); err != nil {
+		return nil, err
+	}
 	return u.orderRepo.ListSalesOrders(ctx, filter)
 }
 
+// ListSalesOrdersPage retrieves the page of sales orders following a cursor,
+// with the cursor of the next page when there is one
+func (u *OrderUseCase) ListSalesOrdersPage(ctx context.Context, filter *entity.SalesOrderFilter, page entity.CursorPage) ([]entity.SalesOrder, *entity.Cursor, error) {
+	if err := u.parseCustomFieldFilter(ctx, filter); err != nil {
+		return nil, nil, err
+	}
+	return u.orderRepo.ListSalesOrdersPage(ctx, filter, page)
+}
+
+// ExportSalesOrders lays the sales orders matching a filter out for an
+// export, with a column for each custom field defined on sales orders
+func (u *OrderUseCase) ExportSalesOrders(ctx context.Context, filter *entity.SalesOrderFilter) ([]export.Column, [][]interface{}, error) {
+	orders, err := u.ListSalesOrders(ctx, filter)
+	if err != nil {
+		return nil, nil, err
+	}
+	return customFieldTable(ctx, u.customFieldUC, entity.CustomFieldEntitySalesOrder, orders, func(order *entity.SalesOrder) entity.JSONMap {
+		return order.CustomFields
+	})
+}
+
+// parseCustomFieldFilter parses the custom field values a sales order filter holds
+func (u *OrderUseCase) parseCustomFieldFilter(ctx context.Context, filter *entity.SalesOrderFilter) error {
+	if filter == nil {
+		return nil
+	}
+	values, err := u.customFieldUC.ParseFilter(ctx, entity.CustomFieldEntitySalesOrder, filter.CustomFields)
+	if err != nil {
+		return err
+	}
+	filter.CustomFields = values
+	return nil
+}
+
 // GetDeliveryOrder retrieves a delivery order by ID
 func (u *OrderUseCase) GetDeliveryOrder(ctx context.Context, id string) (*entity.DeliveryOrder, error) {
-	return u.orderRepo.GetDeliveryOrderByID(ctx, id)
+	return u.getDeliveryOrder(ctx, id)
+}
+
+// getDeliveryOrder retrieves a delivery order from a store the access scope
+// of ctx covers
+func (u *OrderUseCase) getDeliveryOrder(ctx context.Context, id string) (*entity.DeliveryOrder, error) {
+	delivery, err := u.orderRepo.GetDeliveryOrderByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := entity.AccessScopeFromContext(ctx).CheckStore(delivery.StoreID); err != nil {
+		return nil, err
+	}
+	return delivery, nil
 }
 
 // ListDeliveryOrders retrieves a list of delivery orders based on filter
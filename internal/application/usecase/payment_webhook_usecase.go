@@ -0,0 +1,227 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
+)
+
+var (
+	ErrWebhookInvoiceNotFound  = entity.NewError(entity.ErrCodeNotFound, "no invoice matches the payment event")
+	ErrWebhookPaymentNotFound  = entity.NewError(entity.ErrCodeNotFound, "no payment matches the payment event")
+	ErrWebhookCurrencyMismatch = entity.NewError(entity.ErrCodeFailedPrecondition, "payment currency does not match the invoice currency")
+)
+
+// PaymentWebhookUseCase applies verified payment gateway events to finance payments
+type PaymentWebhookUseCase struct {
+	webhookRepo *repository.PaymentWebhookRepository
+	financeUC   *FinanceUseCase
+}
+
+// NewPaymentWebhookUseCase creates a new payment webhook use case
+func NewPaymentWebhookUseCase(webhookRepo *repository.PaymentWebhookRepository, financeUC *FinanceUseCase) *PaymentWebhookUseCase {
+	return &PaymentWebhookUseCase{
+		webhookRepo: webhookRepo,
+		financeUC:   financeUC,
+	}
+}
+
+// HandleEvent records and applies a payment gateway event, once per provider and
+// event ID. A succeeded payment confirms the finance payment with the gateway's
+// payment reference, creating it against the event's invoice first if needed; a
+// refund refunds it. Other event types are recorded as ignored. Events that
+// failed are applied again when redelivered; any other redelivery returns the
+// recorded event with duplicate set.
+func (u *PaymentWebhookUseCase) HandleEvent(ctx context.Context, provider string, event *entity.PaymentGatewayEvent) (record *entity.PaymentWebhookEvent, duplicate bool, err error) {
+	record, err = u.webhookRepo.GetEvent(ctx, provider, event.ID)
+	switch {
+	case err == nil:
+		if record.Status != entity.PaymentWebhookFailed {
+			return record, true, nil
+		}
+	case errors.Is(err, repository.ErrRecordNotFound):
+		record = &entity.PaymentWebhookEvent{
+			Provider:   provider,
+			EventID:    event.ID,
+			Status:     entity.PaymentWebhookReceived,
+			ReceivedAt: time.Now(),
+		}
+		if err := u.webhookRepo.CreateEvent(ctx, record); err != nil {
+			// A concurrent delivery of the same event got there first
+			if existing, getErr := u.webhookRepo.GetEvent(ctx, provider, event.ID); getErr == nil {
+				return existing, true, nil
+			}
+			return nil, false, fmt.Errorf("error recording webhook event: %w", err)
+		}
+	default:
+		return nil, false, fmt.Errorf("error getting webhook event: %w", err)
+	}
+
+	record.EventType = string(event.Type)
+	record.PaymentReference = event.PaymentReference
+	record.Error = ""
+	if record.Payload, err = json.Marshal(event); err != nil {
+		return nil, false, fmt.Errorf("error encoding webhook event: %w", err)
+	}
+
+	var payment *entity.FinancePayment
+	switch event.Type {
+	case entity.PaymentGatewayEventSucceeded:
+		payment, err = u.settle(ctx, provider, event)
+	case entity.PaymentGatewayEventRefunded:
+		payment, err = u.refund(ctx, event)
+	default:
+		record.Status = entity.PaymentWebhookIgnored
+	}
+
+	now := time.Now()
+	record.ProcessedAt = &now
+	if payment != nil {
+		record.PaymentID = &payment.ID
+		record.InvoiceID = &payment.InvoiceID
+	}
+	if err != nil {
+		record.Status = entity.PaymentWebhookFailed
+		record.Error = err.Error()
+	} else if record.Status != entity.PaymentWebhookIgnored {
+		record.Status = entity.PaymentWebhookProcessed
+	}
+
+	if saveErr := u.webhookRepo.UpdateEvent(ctx, record); saveErr != nil {
+		return nil, false, fmt.Errorf("error updating webhook event: %w", saveErr)
+	}
+	return record, false, err
+}
+
+// ReplayFailed applies the failed events of a provider, or of all providers,
+// again from their recorded payloads, oldest first, as if the gateway had
+// redelivered them. It returns the events replayed, with their new status, and
+// the number of failed events recorded without a payload, which only the
+// gateway can redeliver.
+func (u *PaymentWebhookUseCase) ReplayFailed(ctx context.Context, provider string) (replayed []entity.PaymentWebhookEvent, skipped int, err error) {
+	failed, err := u.webhookRepo.ListFailedEvents(ctx, provider)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error listing failed webhook events: %w", err)
+	}
+
+	for _, record := range failed {
+		var event entity.PaymentGatewayEvent
+		if len(record.Payload) == 0 || json.Unmarshal(record.Payload, &event) != nil {
+			skipped++
+			continue
+		}
+		result, _, err := u.HandleEvent(ctx, record.Provider, &event)
+		if result == nil {
+			return replayed, skipped, err
+		}
+		replayed = append(replayed, *result)
+	}
+	return replayed, skipped, nil
+}
+
+// ListEvents lists the recorded webhook events
+func (u *PaymentWebhookUseCase) ListEvents(ctx context.Context, filter *entity.PaymentWebhookEventFilter) ([]entity.PaymentWebhookEvent, int64, error) {
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+	if filter.PageSize <= 0 {
+		filter.PageSize = 20
+	}
+
+	events, total, err := u.webhookRepo.ListEvents(ctx, filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error listing webhook events: %w", err)
+	}
+	return events, total, nil
+}
+
+// settle confirms the payment referenced by a succeeded event, creating it on
+// the event's invoice when the gateway payment was not recorded beforehand
+func (u *PaymentWebhookUseCase) settle(ctx context.Context, provider string, event *entity.PaymentGatewayEvent) (*entity.FinancePayment, error) {
+	payment, err := u.financeUC.GetPaymentByReference(ctx, event.PaymentReference)
+	if err != nil && !errors.Is(err, repository.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	if payment == nil {
+		invoice, err := u.eventInvoice(ctx, event)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.EqualFold(invoice.CurrencyCode, event.CurrencyCode) {
+			return nil, ErrWebhookCurrencyMismatch
+		}
+
+		payment, err = u.financeUC.CreatePayment(ctx, &entity.CreateFinancePaymentRequest{
+			InvoiceID:       invoice.ID,
+			PaymentDate:     event.OccurredAt,
+			PaymentMethod:   entity.FinancePaymentMethodCreditCard,
+			Amount:          event.Amount,
+			ReferenceNumber: event.PaymentReference,
+			Notes:           fmt.Sprintf("Received from %s payment event %s", provider, event.ID),
+		}, 0)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch payment.Status {
+	case entity.FinancePaymentCompleted:
+		return payment, nil
+	case entity.FinancePaymentPending:
+		if err := u.financeUC.ConfirmPayment(ctx, payment.ID); err != nil {
+			return payment, err
+		}
+		payment.Status = entity.FinancePaymentCompleted
+		return payment, nil
+	default:
+		return payment, entity.NewError(entity.ErrCodeFailedPrecondition, fmt.Sprintf("payment %s is %s and cannot be confirmed", payment.PaymentNumber, payment.Status))
+	}
+}
+
+// refund refunds the payment referenced by a refund event
+func (u *PaymentWebhookUseCase) refund(ctx context.Context, event *entity.PaymentGatewayEvent) (*entity.FinancePayment, error) {
+	payment, err := u.financeUC.GetPaymentByReference(ctx, event.PaymentReference)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, ErrWebhookPaymentNotFound
+		}
+		return nil, err
+	}
+
+	if payment.Status == entity.FinancePaymentRefunded {
+		return payment, nil
+	}
+	if err := u.financeUC.RefundPayment(ctx, payment.ID); err != nil {
+		return payment, err
+	}
+	payment.Status = entity.FinancePaymentRefunded
+	return payment, nil
+}
+
+// eventInvoice finds the invoice a payment event is for, by ID or number
+func (u *PaymentWebhookUseCase) eventInvoice(ctx context.Context, event *entity.PaymentGatewayEvent) (*entity.FinanceInvoice, error) {
+	var invoice *entity.FinanceInvoice
+	var err error
+	switch {
+	case event.InvoiceID != 0:
+		invoice, err = u.financeUC.GetInvoiceByID(ctx, event.InvoiceID)
+	case event.InvoiceNumber != "":
+		invoice, err = u.financeUC.GetInvoiceByNumber(ctx, event.InvoiceNumber)
+	default:
+		return nil, ErrWebhookInvoiceNotFound
+	}
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, ErrWebhookInvoiceNotFound
+		}
+		return nil, err
+	}
+	return invoice, nil
+}
@@ -0,0 +1,141 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
+)
+
+var (
+	ErrPermissionUserNotFound = entity.NewError(entity.ErrCodeNotFound, "user not found")
+	ErrPermissionRoleNotFound = entity.NewError(entity.ErrCodeNotFound, "role not found")
+	ErrInvalidPermissionCheck = entity.NewError(entity.ErrCodeInvalidArgument, "each check needs a permission, or a resource and an action")
+)
+
+// PermissionUseCase resolves the permissions users and roles hold, from
+// their role, elevated access grants and approval delegations, so clients
+// can gate features the way requests are authorized
+type PermissionUseCase struct {
+	userRepo     *repository.UserRepository
+	roleRepo     entity.RoleRepository
+	elevationUC  *ElevatedAccessUseCase
+	delegationUC *ApprovalDelegationUseCase
+}
+
+// NewPermissionUseCase creates a new permission use case
+func NewPermissionUseCase(userRepo *repository.UserRepository, roleRepo entity.RoleRepository, elevationUC *ElevatedAccessUseCase, delegationUC *ApprovalDelegationUseCase) *PermissionUseCase {
+	return &PermissionUseCase{
+		userRepo:     userRepo,
+		roleRepo:     roleRepo,
+		elevationUC:  elevationUC,
+		delegationUC: delegationUC,
+	}
+}
+
+// Resolve adds to the permissions a user holds through their role, or an API
+// key acting for them, those of their active elevated access grants and
+// approval delegations. API keys do not use elevated access.
+func (u *PermissionUseCase) Resolve(ctx context.Context, userID uint, held []entity.Permission, source entity.PermissionSource, scope entity.AccessScope) (*entity.EffectivePermissions, error) {
+	effective := newEffectivePermissions(scope)
+	effective.UserID = userID
+	effective.Active = true
+	for _, p := range held {
+		effective.Add(entity.EffectivePermission{Permission: p, Source: source})
+	}
+
+	if source != entity.PermissionFromAPIKey {
+		grants, err := u.elevationUC.ActiveGrants(ctx, userID)
+		if err != nil {
+			return nil, fmt.Errorf("error loading elevated access grants: %w", err)
+		}
+		for _, grant := range grants {
+			for _, p := range grant.Permissions {
+				effective.Add(entity.EffectivePermission{
+					Permission: p,
+					Source:     entity.PermissionFromElevation,
+					GrantID:    &grant.ID,
+					ExpiresAt:  grant.ExpiresAt,
+				})
+			}
+		}
+	}
+
+	// Delegated approvals are only used by those not holding the approval
+	delegated, err := u.delegationUC.DelegatedApprovals(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error loading approval delegations: %w", err)
+	}
+	for _, p := range delegated {
+		effective.Add(p)
+	}
+	return effective, nil
+}
+
+// UserPermissions resolves the permissions a user holds now from their
+// current role. Inactive users hold none.
+func (u *PermissionUseCase) UserPermissions(ctx context.Context, userID uint) (*entity.EffectivePermissions, error) {
+	user, err := u.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, ErrPermissionUserNotFound
+	}
+	if !user.IsActive() || user.Role == nil {
+		effective := newEffectivePermissions(entity.AccessScope{})
+		effective.UserID = user.ID
+		effective.RoleID = user.RoleID
+		if user.Role != nil {
+			effective.Role = user.Role.Name
+		}
+		return effective, nil
+	}
+
+	effective, err := u.Resolve(ctx, user.ID, user.Role.Permissions, entity.PermissionFromRole, user.Role.AccessScope())
+	if err != nil {
+		return nil, err
+	}
+	effective.RoleID = user.Role.ID
+	effective.Role = user.Role.Name
+	return effective, nil
+}
+
+// RolePermissions resolves the permissions the users of a role hold through
+// it, before any elevated access or delegation of their own
+func (u *PermissionUseCase) RolePermissions(ctx context.Context, roleID uint) (*entity.EffectivePermissions, error) {
+	role, err := u.roleRepo.FindByID(roleID)
+	if err != nil {
+		return nil, ErrPermissionRoleNotFound
+	}
+
+	effective := newEffectivePermissions(role.AccessScope())
+	effective.RoleID = role.ID
+	effective.Role = role.Name
+	effective.Active = true
+	for _, p := range role.Permissions {
+		effective.Add(entity.EffectivePermission{Permission: p, Source: entity.PermissionFromRole})
+	}
+	return effective, nil
+}
+
+// Check runs permission checks against a resolved permission set without
+// performing them
+func (u *PermissionUseCase) Check(effective *entity.EffectivePermissions, checks []entity.PermissionCheck) ([]entity.PermissionCheckResult, error) {
+	results := make([]entity.PermissionCheckResult, 0, len(checks))
+	for i, check := range checks {
+		if check.Permission == "" && (check.Resource == "" || check.Action == "") {
+			return nil, fmt.Errorf("%w: check %d", ErrInvalidPermissionCheck, i+1)
+		}
+		results = append(results, effective.Check(check))
+	}
+	return results, nil
+}
+
+func newEffectivePermissions(scope entity.AccessScope) *entity.EffectivePermissions {
+	return &entity.EffectivePermissions{
+		Permissions: []entity.Permission{},
+		Details:     []entity.EffectivePermission{},
+		AccessScope: scope,
+		ResolvedAt:  time.Now(),
+	}
+}
@@ -0,0 +1,215 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
+)
+
+var (
+	ErrPortalAccessDenied = entity.NewError(entity.ErrCodePermissionDenied, "resource does not belong to this client")
+)
+
+// PortalUseCase exposes read-only self-service views scoped to a single client.
+// Every method takes the authenticated client ID and enforces ownership itself,
+// so callers cannot widen the scope by passing a different filter.
+type PortalUseCase struct {
+	orderRepo  *repository.OrderRepository
+	clientRepo entity.ClientRepository
+}
+
+// NewPortalUseCase creates a new PortalUseCase
+func NewPortalUseCase(orderRepo *repository.OrderRepository, clientRepo entity.ClientRepository) *PortalUseCase {
+	return &PortalUseCase{
+		orderRepo:  orderRepo,
+		clientRepo: clientRepo,
+	}
+}
+
+// GetClient retrieves the client a portal token is issued for
+func (u *PortalUseCase) GetClient(ctx context.Context, clientID uint) (*entity.Client, error) {
+	return u.clientRepo.FindByID(clientID)
+}
+
+// ListOrders lists the client's own sales orders
+func (u *PortalUseCase) ListOrders(ctx context.Context, clientID uint, filter *entity.SalesOrderFilter) ([]entity.SalesOrder, error) {
+	if filter == nil {
+		filter = &entity.SalesOrderFilter{}
+	}
+	filter.ClientID = &clientID
+
+	orders, err := u.orderRepo.ListSalesOrders(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	for i := range orders {
+		sanitizePortalOrder(&orders[i])
+	}
+	return orders, nil
+}
+
+// GetOrder retrieves one of the client's sales orders
+func (u *PortalUseCase) GetOrder(ctx context.Context, clientID uint, orderID string) (*entity.SalesOrder, error) {
+	order, err := u.orderRepo.GetSalesOrderByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.ClientID != clientID {
+		return nil, ErrPortalAccessDenied
+	}
+
+	sanitizePortalOrder(order)
+	return order, nil
+}
+
+// ListDeliveries lists deliveries for the client's sales orders
+func (u *PortalUseCase) ListDeliveries(ctx context.Context, clientID uint, filter *entity.DeliveryOrderFilter) ([]entity.DeliveryOrder, error) {
+	if filter == nil {
+		filter = &entity.DeliveryOrderFilter{}
+	}
+	filter.ClientID = &clientID
+
+	deliveries, err := u.orderRepo.ListDeliveryOrders(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	for i := range deliveries {
+		deliveries[i].SalesOrder = nil
+		deliveries[i].CreatedBy = nil
+	}
+	return deliveries, nil
+}
+
+// GetDelivery retrieves a delivery belonging to one of the client's sales orders
+func (u *PortalUseCase) GetDelivery(ctx context.Context, clientID uint, deliveryID string) (*entity.DeliveryOrder, error) {
+	delivery, err := u.orderRepo.GetDeliveryOrderByID(ctx, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	if delivery.SalesOrder == nil || delivery.SalesOrder.ClientID != clientID {
+		return nil, ErrPortalAccessDenied
+	}
+
+	delivery.SalesOrder = nil
+	delivery.CreatedBy = nil
+	return delivery, nil
+}
+
+// ListInvoices lists invoices for the client's sales orders
+func (u *PortalUseCase) ListInvoices(ctx context.Context, clientID uint, filter *entity.InvoiceFilter) ([]entity.Invoice, error) {
+	if filter == nil {
+		filter = &entity.InvoiceFilter{}
+	}
+	filter.ClientID = &clientID
+
+	invoices, err := u.orderRepo.ListInvoices(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	for i := range invoices {
+		invoices[i].SalesOrder = nil
+		invoices[i].CreatedBy = nil
+	}
+	return invoices, nil
+}
+
+// GetInvoice retrieves an invoice belonging to one of the client's sales orders
+func (u *PortalUseCase) GetInvoice(ctx context.Context, clientID uint, invoiceID string) (*entity.Invoice, error) {
+	invoice, err := u.orderRepo.GetInvoiceByID(ctx, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	if invoice.SalesOrder == nil || invoice.SalesOrder.ClientID != clientID {
+		return nil, ErrPortalAccessDenied
+	}
+
+	invoice.SalesOrder = nil
+	invoice.CreatedBy = nil
+	return invoice, nil
+}
+
+// GetPaymentStatus summarizes the client's invoiced, paid and outstanding amounts
+func (u *PortalUseCase) GetPaymentStatus(ctx context.Context, clientID uint) (*entity.PortalPaymentStatus, error) {
+	invoices, err := u.orderRepo.ListInvoices(ctx, &entity.InvoiceFilter{ClientID: &clientID})
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	status := &entity.PortalPaymentStatus{
+		ClientID:      clientID,
+		PaymentStatus: entity.PaymentStatusPaid,
+		OpenInvoices:  []entity.PortalInvoiceSummary{},
+		GeneratedAt:   now,
+	}
+
+	for _, invoice := range invoices {
+		switch invoice.Status {
+		case entity.InvoiceStatusDraft, entity.InvoiceStatusCancelled:
+			continue
+		case entity.InvoiceStatusPaid:
+			status.TotalInvoiced += invoice.TotalAmount
+			status.TotalPaid += invoice.TotalAmount
+			continue
+		}
+
+		// Deposits applied to the invoice count as paid
+		status.TotalInvoiced += invoice.TotalAmount
+		status.TotalPaid += invoice.PrepaidAmount
+		status.TotalOutstanding += invoice.AmountDue
+
+		overdue := invoice.Status == entity.InvoiceStatusOverdue || invoice.DueDate.Before(now)
+		if overdue {
+			status.TotalOverdue += invoice.AmountDue
+		}
+
+		status.OpenInvoices = append(status.OpenInvoices, entity.PortalInvoiceSummary{
+			InvoiceID:     invoice.ID,
+			InvoiceNumber: invoice.InvoiceNumber,
+			SalesOrderID:  invoice.SalesOrderID,
+			DueDate:       invoice.DueDate,
+			TotalAmount:   invoice.TotalAmount,
+			Status:        invoice.Status,
+			IsOverdue:     overdue,
+		})
+	}
+
+	switch {
+	case status.TotalOverdue > 0:
+		status.PaymentStatus = entity.PaymentStatusOverdue
+	case status.TotalOutstanding > 0 && status.TotalPaid > 0:
+		status.PaymentStatus = entity.PaymentStatusPartial
+	case status.TotalOutstanding > 0:
+		status.PaymentStatus = entity.PaymentStatusPending
+	}
+
+	return status, nil
+}
+
+// sanitizePortalOrder strips internal staff references, notes and custom
+// fields, and the vendor and purchase cost of drop-ship lines, from an order
+// before it is shown to a client
+func sanitizePortalOrder(order *entity.SalesOrder) {
+	order.Client = nil
+	order.CreatedBy = nil
+	order.Notes = ""
+	order.ExternalRef = nil
+	order.CustomFields = nil
+	items := make(entity.SalesOrderItems, len(order.Items))
+	for i, item := range order.Items {
+		item.VendorID = nil
+		item.PurchasePrice = 0
+		item.PurchaseOrderID = ""
+		item.SKU = nil
+		items[i] = item
+	}
+	order.Items = items
+	for i := range order.DeliveryOrders {
+		order.DeliveryOrders[i].CreatedBy = nil
+	}
+	for i := range order.Invoices {
+		order.Invoices[i].CreatedBy = nil
+	}
+}
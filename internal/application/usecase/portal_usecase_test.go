@@ -0,0 +1,53 @@
+package usecase
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+)
+
+// A portal order never shows the vendor, purchase cost or purchase order of
+// drop-ship lines, nor the internal notes, references and custom fields
+func TestSanitizePortalOrder(t *testing.T) {
+	vendorID := uint(4)
+	ref := "shopify:1001"
+	order := &entity.SalesOrder{
+		ID:           "order-1",
+		OrderNumber:  "SO-1",
+		ClientID:     3,
+		Notes:        "margin is thin, do not discount",
+		ExternalRef:  &ref,
+		CustomFields: entity.JSONMap{"account_manager": "ops"},
+		Items: entity.SalesOrderItems{
+			{SKUID: "sku-1", Quantity: 2, UnitPrice: 50, TotalPrice: 100},
+			{SKUID: "sku-2", Quantity: 1, UnitPrice: 80, TotalPrice: 80, DropShip: true, VendorID: &vendorID, PurchasePrice: 61.5, PurchaseOrderID: "po-9", SKU: &entity.SKU{Price: 80}},
+		},
+		Client:    &entity.User{ID: 3},
+		CreatedBy: &entity.User{ID: 1},
+	}
+	stored := order.Items
+
+	sanitizePortalOrder(order)
+	data, err := json.Marshal(order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := string(data)
+	for _, field := range []string{"purchase_price", "purchase_order_id", "vendor_id", `"sku":`, "margin is thin", "external_ref", "account_manager", "created_by\"", `"client":`} {
+		if strings.Contains(body, field) {
+			t.Errorf("portal order contains %s: %s", field, body)
+		}
+	}
+	for _, field := range []string{`"drop_ship":true`, `"unit_price":80`, `"order_number":"SO-1"`} {
+		if !strings.Contains(body, field) {
+			t.Errorf("portal order lacks %s: %s", field, body)
+		}
+	}
+
+	// The order's own items are left as they were
+	if stored[1].PurchasePrice != 61.5 || stored[1].VendorID == nil {
+		t.Errorf("sanitizing changed the loaded items: %+v", stored[1])
+	}
+}
@@ -0,0 +1,185 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
+)
+
+var (
+	ErrPrepaymentNotFound      = entity.NewError(entity.ErrCodeNotFound, "prepayment not found")
+	ErrPrepaymentOrderNotFound = entity.NewError(entity.ErrCodeNotFound, "order not found")
+	ErrPrepaymentOrderStatus   = entity.NewError(entity.ErrCodeFailedPrecondition, "prepayments cannot be recorded against draft or cancelled orders")
+	ErrPrepaymentExceedsOrder  = entity.NewError(entity.ErrCodeFailedPrecondition, "prepayments would exceed the order total")
+	ErrPrepaymentNotOpen       = entity.NewError(entity.ErrCodeFailedPrecondition, "only open prepayments can be cancelled")
+)
+
+// PrepaymentUseCase handles customer deposits on sales orders and advances to
+// vendors on purchase orders. They are applied to the invoices of the order
+// by the order and finance use cases as the invoices are created.
+type PrepaymentUseCase struct {
+	prepaymentRepo *repository.PrepaymentRepository
+	orderRepo      *repository.OrderRepository
+	purchaseRepo   *repository.PurchaseRepository
+	currencyUC     *CurrencyUseCase
+	fiscalUC       *FiscalUseCase
+}
+
+// NewPrepaymentUseCase creates a new prepayment use case
+func NewPrepaymentUseCase(prepaymentRepo *repository.PrepaymentRepository, orderRepo *repository.OrderRepository, purchaseRepo *repository.PurchaseRepository, currencyUC *CurrencyUseCase, fiscalUC *FiscalUseCase) *PrepaymentUseCase {
+	return &PrepaymentUseCase{
+		prepaymentRepo: prepaymentRepo,
+		orderRepo:      orderRepo,
+		purchaseRepo:   purchaseRepo,
+		currencyUC:     currencyUC,
+		fiscalUC:       fiscalUC,
+	}
+}
+
+// Create records a deposit a customer paid against a sales order, or an
+// advance paid to a vendor against a purchase order, in the order currency.
+// The prepayments of an order cannot exceed its total.
+func (u *PrepaymentUseCase) Create(ctx context.Context, req *entity.CreatePrepaymentRequest, userID uint) (*entity.Prepayment, error) {
+	paymentDate := truncateDay(req.PaymentDate)
+	if err := u.fiscalUC.CheckOpen(ctx, paymentDate); err != nil {
+		return nil, err
+	}
+
+	prepayment := &entity.Prepayment{
+		Type:            req.Type,
+		OrderID:         req.OrderID,
+		PaymentDate:     paymentDate,
+		PaymentMethod:   req.PaymentMethod,
+		ReferenceNumber: req.ReferenceNumber,
+		Amount:          roundAmount(req.Amount),
+		Status:          entity.PrepaymentOpen,
+		Notes:           req.Notes,
+		CreatedByID:     userID,
+	}
+	orderTotal, err := u.setOrder(ctx, prepayment)
+	if err != nil {
+		return nil, err
+	}
+
+	prepaid, err := u.prepaymentRepo.OrderTotal(ctx, prepayment.OrderID)
+	if err != nil {
+		return nil, fmt.Errorf("error totalling order prepayments: %w", err)
+	}
+	if roundAmount(prepaid+prepayment.Amount) > orderTotal {
+		return nil, ErrPrepaymentExceedsOrder
+	}
+
+	conversion, err := u.currencyUC.Convert(ctx, prepayment.Amount, prepayment.CurrencyCode, paymentDate)
+	if err != nil {
+		return nil, err
+	}
+	prepayment.CurrencyCode = conversion.Currency
+	prepayment.ExchangeRate = conversion.Rate
+	prepayment.BaseAmount = conversion.BaseAmount
+	prepayment.UnappliedAmount = prepayment.Amount
+
+	if err := u.prepaymentRepo.Create(ctx, prepayment); err != nil {
+		return nil, fmt.Errorf("error creating prepayment: %w", err)
+	}
+	return prepayment, nil
+}
+
+// setOrder sets the order fields of a prepayment from its sales or purchase
+// order, and returns the order total
+func (u *PrepaymentUseCase) setOrder(ctx context.Context, prepayment *entity.Prepayment) (float64, error) {
+	if _, err := uuid.Parse(prepayment.OrderID); err != nil {
+		return 0, ErrPrepaymentOrderNotFound
+	}
+
+	if prepayment.Type == entity.PrepaymentVendorAdvance {
+		order, err := u.purchaseRepo.GetPurchaseOrderByID(ctx, prepayment.OrderID)
+		if err != nil {
+			if errors.Is(err, repository.ErrRecordNotFound) {
+				return 0, ErrPrepaymentOrderNotFound
+			}
+			return 0, fmt.Errorf("error getting purchase order: %w", err)
+		}
+		if order.Status == entity.PurchaseOrderStatusDraft || order.Status == entity.PurchaseOrderStatusCancelled {
+			return 0, ErrPrepaymentOrderStatus
+		}
+		prepayment.OrderNumber = order.OrderNumber
+		prepayment.EntityType = "SUPPLIER"
+		prepayment.EntityID = order.VendorID
+		prepayment.CurrencyCode = order.CurrencyCode
+		return order.GrandTotal, nil
+	}
+
+	order, err := u.orderRepo.GetSalesOrderByID(ctx, prepayment.OrderID)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return 0, ErrPrepaymentOrderNotFound
+		}
+		return 0, fmt.Errorf("error getting sales order: %w", err)
+	}
+	if order.Status == entity.SalesOrderStatusDraft || order.Status == entity.SalesOrderStatusCancelled {
+		return 0, ErrPrepaymentOrderStatus
+	}
+	prepayment.OrderNumber = order.OrderNumber
+	prepayment.EntityType = "CUSTOMER"
+	prepayment.EntityID = order.ClientID
+	prepayment.CurrencyCode = order.CurrencyCode
+	return order.GrandTotal, nil
+}
+
+// Get retrieves a prepayment with the invoices it was applied to
+func (u *PrepaymentUseCase) Get(ctx context.Context, id uint) (*entity.Prepayment, error) {
+	prepayment, err := u.prepaymentRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, ErrPrepaymentNotFound
+		}
+		return nil, fmt.Errorf("error getting prepayment: %w", err)
+	}
+	return prepayment, nil
+}
+
+// List lists prepayments, latest first
+func (u *PrepaymentUseCase) List(ctx context.Context, filter *entity.PrepaymentFilter) ([]entity.Prepayment, int64, error) {
+	prepayments, total, err := u.prepaymentRepo.List(ctx, filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error listing prepayments: %w", err)
+	}
+	return prepayments, total, nil
+}
+
+// Balances totals the unapplied prepayments of each customer and vendor, or
+// of one of them, by currency
+func (u *PrepaymentUseCase) Balances(ctx context.Context, entityType string, entityID uint) ([]entity.PrepaymentBalance, error) {
+	balances, err := u.prepaymentRepo.Balances(ctx, entityType, entityID)
+	if err != nil {
+		return nil, fmt.Errorf("error totalling prepayment balances: %w", err)
+	}
+	return balances, nil
+}
+
+// Cancel cancels a prepayment recorded in error or refunded. Prepayments
+// applied to invoices cannot be cancelled until the invoices are.
+func (u *PrepaymentUseCase) Cancel(ctx context.Context, id uint) (*entity.Prepayment, error) {
+	prepayment, err := u.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if prepayment.Status != entity.PrepaymentOpen {
+		return nil, ErrPrepaymentNotOpen
+	}
+	if err := u.fiscalUC.CheckOpen(ctx, prepayment.PaymentDate); err != nil {
+		return nil, err
+	}
+
+	if err := u.prepaymentRepo.Cancel(ctx, prepayment); err != nil {
+		if errors.Is(err, repository.ErrPrepaymentApplied) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("error cancelling prepayment: %w", err)
+	}
+	return prepayment, nil
+}
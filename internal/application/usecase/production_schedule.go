@@ -0,0 +1,365 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
+)
+
+const (
+	// defaultScheduleDays is the length of the schedule returned when no end date is given
+	defaultScheduleDays = 28
+	// maxScheduleDays caps the length of a schedule and of a single order's run
+	maxScheduleDays = 366
+	// scheduleTolerance absorbs floating point error when comparing hours
+	scheduleTolerance = 1e-6
+)
+
+var (
+	ErrWorkCenterCapacity    = entity.NewError(entity.ErrCodeInvalidArgument, "work center capacity cannot exceed 24 hours a day")
+	ErrWorkCenterCodeExists  = entity.NewError(entity.ErrCodeConflict, "work center code already exists")
+	ErrWorkCenterFacility    = entity.NewError(entity.ErrCodeNotFound, "facility not found")
+	ErrWorkCenterInactive    = entity.NewError(entity.ErrCodeFailedPrecondition, "work center is inactive")
+	ErrWorkCenterMismatch    = entity.NewError(entity.ErrCodeFailedPrecondition, "work center belongs to another facility than the production order")
+	ErrOrderNotSchedulable   = entity.NewError(entity.ErrCodeFailedPrecondition, "only pending or in-process production orders can be scheduled")
+	ErrPlannedHoursRequired  = entity.NewError(entity.ErrCodeInvalidArgument, "planned hours are required when the product's BOM has no labor hours")
+	ErrScheduleTooLong       = entity.NewError(entity.ErrCodeInvalidArgument, "production order does not fit within a year of work center capacity")
+	ErrInvalidScheduleDate   = entity.NewError(entity.ErrCodeInvalidArgument, "dates must be formatted as YYYY-MM-DD")
+	ErrInvalidSchedulePeriod = entity.NewError(entity.ErrCodeInvalidArgument, "schedule end must not be before its start, and cover at most 366 days")
+)
+
+// dayLoad is the hours a production order takes on a work center on one day
+type dayLoad struct {
+	day   time.Time
+	hours float64
+}
+
+// CreateWorkCenter creates a work center in a facility
+func (uc *ManufacturingUseCase) CreateWorkCenter(ctx context.Context, req *entity.WorkCenterRequest) (*entity.WorkCenter, error) {
+	workCenter := &entity.WorkCenter{Active: true}
+	if err := uc.applyWorkCenter(ctx, workCenter, req); err != nil {
+		return nil, err
+	}
+
+	if err := uc.repo.CreateWorkCenter(ctx, workCenter); err != nil {
+		if errors.Is(err, repository.ErrDuplicateEntry) {
+			return nil, ErrWorkCenterCodeExists
+		}
+		return nil, fmt.Errorf("error creating work center: %w", err)
+	}
+	return workCenter, nil
+}
+
+// UpdateWorkCenter updates a work center. The load of the orders already
+// scheduled on it is recomputed against its new capacity.
+func (uc *ManufacturingUseCase) UpdateWorkCenter(ctx context.Context, id uint, req *entity.WorkCenterRequest) (*entity.WorkCenter, error) {
+	workCenter, err := uc.repo.GetWorkCenter(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.applyWorkCenter(ctx, workCenter, req); err != nil {
+		return nil, err
+	}
+
+	if err := uc.repo.UpdateWorkCenter(ctx, workCenter); err != nil {
+		if errors.Is(err, repository.ErrDuplicateEntry) {
+			return nil, ErrWorkCenterCodeExists
+		}
+		return nil, fmt.Errorf("error updating work center: %w", err)
+	}
+	return workCenter, nil
+}
+
+func (uc *ManufacturingUseCase) GetWorkCenter(ctx context.Context, id uint) (*entity.WorkCenter, error) {
+	return uc.repo.GetWorkCenter(ctx, id)
+}
+
+// ListWorkCenters lists the work centers, of one facility when given
+func (uc *ManufacturingUseCase) ListWorkCenters(ctx context.Context, facilityID uint) ([]entity.WorkCenter, error) {
+	return uc.repo.ListWorkCenters(ctx, facilityID)
+}
+
+func (uc *ManufacturingUseCase) applyWorkCenter(ctx context.Context, workCenter *entity.WorkCenter, req *entity.WorkCenterRequest) error {
+	if float64(req.ShiftsPerDay)*req.HoursPerShift > 24 {
+		return ErrWorkCenterCapacity
+	}
+	if _, err := uc.repo.GetFacility(ctx, req.FacilityID); err != nil {
+		return ErrWorkCenterFacility
+	}
+
+	workCenter.FacilityID = req.FacilityID
+	workCenter.Code = strings.TrimSpace(req.Code)
+	workCenter.Name = strings.TrimSpace(req.Name)
+	workCenter.ShiftsPerDay = req.ShiftsPerDay
+	workCenter.HoursPerShift = req.HoursPerShift
+	workCenter.WorksWeekends = req.WorksWeekends
+	if req.Active != nil {
+		workCenter.Active = *req.Active
+	}
+	return nil
+}
+
+// ScheduleOrder schedules a production order on a work center, or moves it to
+// another work center or start date. The order takes the work center's full
+// daily capacity from its start until its planned hours are used up; days on
+// which it overlaps other orders beyond the capacity are returned as overloads.
+func (uc *ManufacturingUseCase) ScheduleOrder(ctx context.Context, orderID uint, req *entity.ScheduleRequest) (*entity.ScheduleResult, error) {
+	order, err := uc.repo.GetProductionOrder(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != entity.OrderStatusPending && order.Status != entity.OrderStatusInProcess {
+		return nil, ErrOrderNotSchedulable
+	}
+
+	workCenter, err := uc.repo.GetWorkCenter(ctx, req.WorkCenterID)
+	if err != nil {
+		return nil, err
+	}
+	if !workCenter.Active {
+		return nil, ErrWorkCenterInactive
+	}
+	if workCenter.FacilityID != order.FacilityID {
+		return nil, ErrWorkCenterMismatch
+	}
+
+	hours := req.PlannedHours
+	if hours <= 0 {
+		if bom, err := uc.repo.GetEffectiveBOM(ctx, order.ProductID, time.Now()); err == nil {
+			remaining := order.Quantity - order.CompletedQty - order.DefectQty
+			hours = bom.LaborHours * float64(max(remaining, 0))
+		}
+		if hours <= 0 {
+			return nil, ErrPlannedHoursRequired
+		}
+	}
+
+	var start time.Time
+	if req.StartDate != "" {
+		if start, err = time.Parse("2006-01-02", req.StartDate); err != nil {
+			return nil, ErrInvalidScheduleDate
+		}
+	} else {
+		// Queue the order behind the work center's other orders
+		start = scheduleToday()
+		last, err := uc.repo.LastScheduledEnd(ctx, workCenter.ID, order.ID)
+		if err != nil {
+			return nil, fmt.Errorf("error finding the work center's last scheduled order: %w", err)
+		}
+		if last != nil && !last.Before(start) {
+			start = last.AddDate(0, 0, 1)
+		}
+	}
+
+	loads := spreadHours(workCenter, start, nil, hours)
+	if len(loads) == 0 {
+		return nil, ErrScheduleTooLong
+	}
+	first, last := loads[0].day, loads[len(loads)-1].day
+
+	order.WorkCenterID = &workCenter.ID
+	order.ScheduledStart = &first
+	order.ScheduledEnd = &last
+	order.PlannedHours = roundAmount(hours)
+	if err := uc.repo.UpdateProductionOrder(ctx, order); err != nil {
+		return nil, fmt.Errorf("error scheduling production order: %w", err)
+	}
+
+	orders, err := uc.repo.ListScheduledOrders(ctx, []uint{workCenter.ID}, first, last)
+	if err != nil {
+		return nil, fmt.Errorf("error listing scheduled production orders: %w", err)
+	}
+	schedule := buildWorkCenterSchedule(*workCenter, orders, first, last)
+
+	overloads := []entity.WorkCenterLoad{}
+	for _, load := range schedule.Load {
+		if load.Overloaded {
+			overloads = append(overloads, load)
+		}
+	}
+	return &entity.ScheduleResult{Order: order, Overloads: overloads}, nil
+}
+
+// UnscheduleOrder takes a production order off its work center
+func (uc *ManufacturingUseCase) UnscheduleOrder(ctx context.Context, orderID uint) (*entity.ProductionOrder, error) {
+	order, err := uc.repo.GetProductionOrder(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	order.WorkCenterID = nil
+	order.ScheduledStart = nil
+	order.ScheduledEnd = nil
+	order.PlannedHours = 0
+	if err := uc.repo.UpdateProductionOrder(ctx, order); err != nil {
+		return nil, fmt.Errorf("error unscheduling production order: %w", err)
+	}
+	return order, nil
+}
+
+// GetSchedule returns the open production orders scheduled on the active work
+// centers, of one facility or one work center when filtered, that run in a
+// period, with each work center's daily load and overloads. The period defaults
+// to four weeks from today.
+func (uc *ManufacturingUseCase) GetSchedule(ctx context.Context, filter *entity.ScheduleFilter) (*entity.ProductionSchedule, error) {
+	if filter.StartDate.IsZero() {
+		filter.StartDate = scheduleToday()
+	}
+	if filter.EndDate.IsZero() {
+		filter.EndDate = filter.StartDate.AddDate(0, 0, defaultScheduleDays-1)
+	}
+	if filter.EndDate.Before(filter.StartDate) || daysBetween(filter.StartDate, filter.EndDate) >= maxScheduleDays {
+		return nil, ErrInvalidSchedulePeriod
+	}
+
+	var workCenters []entity.WorkCenter
+	if filter.WorkCenterID != 0 {
+		workCenter, err := uc.repo.GetWorkCenter(ctx, filter.WorkCenterID)
+		if err != nil {
+			return nil, err
+		}
+		workCenters = append(workCenters, *workCenter)
+	} else {
+		all, err := uc.repo.ListWorkCenters(ctx, filter.FacilityID)
+		if err != nil {
+			return nil, fmt.Errorf("error listing work centers: %w", err)
+		}
+		for _, workCenter := range all {
+			if workCenter.Active {
+				workCenters = append(workCenters, workCenter)
+			}
+		}
+	}
+
+	ids := make([]uint, len(workCenters))
+	for i, workCenter := range workCenters {
+		ids[i] = workCenter.ID
+	}
+	orders, err := uc.repo.ListScheduledOrders(ctx, ids, filter.StartDate, filter.EndDate)
+	if err != nil {
+		return nil, fmt.Errorf("error listing scheduled production orders: %w", err)
+	}
+	byWorkCenter := make(map[uint][]entity.ProductionOrder)
+	for _, order := range orders {
+		byWorkCenter[*order.WorkCenterID] = append(byWorkCenter[*order.WorkCenterID], order)
+	}
+
+	schedule := &entity.ProductionSchedule{
+		StartDate:   filter.StartDate,
+		EndDate:     filter.EndDate,
+		WorkCenters: make([]entity.WorkCenterSchedule, 0, len(workCenters)),
+	}
+	for _, workCenter := range workCenters {
+		schedule.WorkCenters = append(schedule.WorkCenters,
+			buildWorkCenterSchedule(workCenter, byWorkCenter[workCenter.ID], filter.StartDate, filter.EndDate))
+	}
+	return schedule, nil
+}
+
+// buildWorkCenterSchedule lays the orders scheduled on a work center out over
+// the days from start to end and totals the load of each day
+func buildWorkCenterSchedule(workCenter entity.WorkCenter, orders []entity.ProductionOrder, start, end time.Time) entity.WorkCenterSchedule {
+	days := daysBetween(start, end) + 1
+	loads := make([]entity.WorkCenterLoad, days)
+	for i := range loads {
+		day := start.AddDate(0, 0, i)
+		loads[i] = entity.WorkCenterLoad{Date: day, ProductionOrderIDs: []uint{}}
+		if workCenter.WorksOn(day) {
+			loads[i].CapacityHours = roundAmount(workCenter.DailyCapacity())
+		}
+	}
+
+	orderDays := make([][]int, len(orders))
+	for k, order := range orders {
+		for _, load := range spreadHours(&workCenter, *order.ScheduledStart, order.ScheduledEnd, order.PlannedHours) {
+			i := daysBetween(start, load.day)
+			if i < 0 || i >= days {
+				continue
+			}
+			loads[i].LoadHours += load.hours
+			if load.hours > 0 {
+				loads[i].ProductionOrderIDs = append(loads[i].ProductionOrderIDs, order.ID)
+				orderDays[k] = append(orderDays[k], i)
+			}
+		}
+	}
+
+	schedule := entity.WorkCenterSchedule{
+		WorkCenter: workCenter,
+		Orders:     make([]entity.ScheduledOrder, 0, len(orders)),
+		Load:       loads,
+	}
+	for i := range loads {
+		loads[i].Overloaded = loads[i].LoadHours > loads[i].CapacityHours+scheduleTolerance
+		if loads[i].CapacityHours > 0 {
+			loads[i].UtilizationPercent = roundAmount(loads[i].LoadHours / loads[i].CapacityHours * 100)
+		}
+		loads[i].LoadHours = roundAmount(loads[i].LoadHours)
+		if loads[i].Overloaded {
+			schedule.OverloadedDays++
+		}
+	}
+
+	for k, order := range orders {
+		bar := entity.ScheduledOrder{
+			ProductionOrderID: order.ID,
+			ProductID:         order.ProductID,
+			Quantity:          order.Quantity,
+			CompletedQty:      order.CompletedQty,
+			Status:            order.Status,
+			Start:             *order.ScheduledStart,
+			End:               *order.ScheduledEnd,
+			PlannedHours:      order.PlannedHours,
+			Deadline:          order.Deadline,
+			Late:              order.ScheduledEnd.After(truncateDay(order.Deadline.UTC())),
+		}
+		for _, i := range orderDays[k] {
+			if loads[i].Overloaded {
+				bar.Overloaded = true
+				break
+			}
+		}
+		schedule.Orders = append(schedule.Orders, bar)
+	}
+	return schedule
+}
+
+// spreadHours lays hours out over the working days of a work center from start,
+// filling its daily capacity. When the run has a fixed last day, whatever does
+// not fit before it is loaded onto that day. Returns nothing if the hours do not
+// fit within maxScheduleDays.
+func spreadHours(workCenter *entity.WorkCenter, start time.Time, end *time.Time, hours float64) []dayLoad {
+	capacity := workCenter.DailyCapacity()
+	remaining := hours
+
+	var loads []dayLoad
+	day := start
+	for i := 0; i < maxScheduleDays; i, day = i+1, day.AddDate(0, 0, 1) {
+		last := end != nil && !day.Before(*end)
+		switch {
+		case last:
+			loads = append(loads, dayLoad{day: day, hours: math.Max(remaining, 0)})
+			remaining = 0
+		case workCenter.WorksOn(day):
+			taken := math.Min(capacity, remaining)
+			loads = append(loads, dayLoad{day: day, hours: taken})
+			remaining -= taken
+		}
+		if last || (len(loads) > 0 && remaining <= scheduleTolerance) {
+			return loads
+		}
+	}
+	return nil
+}
+
+// scheduleToday returns today's date, which schedules are kept in
+func scheduleToday() time.Time {
+	now := time.Now()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,272 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
+)
+
+var (
+	ErrInvalidPeriod         = entity.NewError(entity.ErrCodeInvalidArgument, "period must be formatted as YYYY-MM")
+	ErrPeriodInFuture        = entity.NewError(entity.ErrCodeFailedPrecondition, "cannot post provisions for a future period")
+	ErrWriteDownRuleNotFound = entity.NewError(entity.ErrCodeNotFound, "write-down rule not found")
+)
+
+// ProvisionUseCase handles inventory aging write-downs: rules, the provision
+// ledger and the monthly provision posting
+type ProvisionUseCase struct {
+	provisionRepo *repository.ProvisionRepository
+}
+
+// NewProvisionUseCase creates a new provision use case
+func NewProvisionUseCase(provisionRepo *repository.ProvisionRepository) *ProvisionUseCase {
+	return &ProvisionUseCase{
+		provisionRepo: provisionRepo,
+	}
+}
+
+// CreateRule creates a new write-down rule
+func (u *ProvisionUseCase) CreateRule(ctx context.Context, req *entity.WriteDownRuleRequest) (*entity.WriteDownRule, error) {
+	rule := &entity.WriteDownRule{
+		Name:             req.Name,
+		MinAgeDays:       req.MinAgeDays,
+		ProvisionPercent: req.ProvisionPercent,
+		Active:           req.Active == nil || *req.Active,
+	}
+	if err := u.provisionRepo.CreateRule(ctx, rule); err != nil {
+		return nil, fmt.Errorf("error creating write-down rule: %w", err)
+	}
+	return rule, nil
+}
+
+// UpdateRule updates an existing write-down rule
+func (u *ProvisionUseCase) UpdateRule(ctx context.Context, id uint, req *entity.WriteDownRuleRequest) (*entity.WriteDownRule, error) {
+	rule, err := u.provisionRepo.GetRule(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, ErrWriteDownRuleNotFound
+		}
+		return nil, fmt.Errorf("error getting write-down rule: %w", err)
+	}
+
+	rule.Name = req.Name
+	rule.MinAgeDays = req.MinAgeDays
+	rule.ProvisionPercent = req.ProvisionPercent
+	if req.Active != nil {
+		rule.Active = *req.Active
+	}
+
+	if err := u.provisionRepo.UpdateRule(ctx, rule); err != nil {
+		return nil, fmt.Errorf("error updating write-down rule: %w", err)
+	}
+	return rule, nil
+}
+
+// DeleteRule deletes a write-down rule
+func (u *ProvisionUseCase) DeleteRule(ctx context.Context, id uint) error {
+	if err := u.provisionRepo.DeleteRule(ctx, id); err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return ErrWriteDownRuleNotFound
+		}
+		return fmt.Errorf("error deleting write-down rule: %w", err)
+	}
+	return nil
+}
+
+// ListRules lists all write-down rules
+func (u *ProvisionUseCase) ListRules(ctx context.Context) ([]entity.WriteDownRule, error) {
+	rules, err := u.provisionRepo.ListRules(ctx, false)
+	if err != nil {
+		return nil, fmt.Errorf("error listing write-down rules: %w", err)
+	}
+	return rules, nil
+}
+
+// ListEntries lists provision ledger entries
+func (u *ProvisionUseCase) ListEntries(ctx context.Context, filter *entity.InventoryProvisionFilter) ([]entity.InventoryProvisionEntry, int64, error) {
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+	if filter.PageSize <= 0 {
+		filter.PageSize = 10
+	}
+
+	entries, total, err := u.provisionRepo.ListEntries(ctx, filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error listing provision entries: %w", err)
+	}
+	return entries, total, nil
+}
+
+// Preview computes the provision each stock requires as of a date, combining the
+// inventory age with the active write-down rules, without posting anything
+func (u *ProvisionUseCase) Preview(ctx context.Context, asOf time.Time) ([]entity.StockProvision, error) {
+	if asOf.IsZero() {
+		asOf = time.Now()
+	}
+	return u.computeProvisions(ctx, asOf)
+}
+
+// Post posts the provision changes of a period (YYYY-MM) to the provision ledger.
+// Stock is aged as of the end of the period, or now for the current month. Stocks
+// whose required provision grew get a PROVISION entry; stocks whose provision
+// shrank, mostly because aged stock sold, get a RELEASE entry. Posting again only
+// posts what changed since, so it is safe to repeat.
+func (u *ProvisionUseCase) Post(ctx context.Context, period string, userID *uint) (*entity.ProvisionRunResult, error) {
+	start, err := time.ParseInLocation(periodLayout, period, time.Local)
+	if err != nil {
+		return nil, ErrInvalidPeriod
+	}
+	now := time.Now()
+	if start.After(now) {
+		return nil, ErrPeriodInFuture
+	}
+	asOf := start.AddDate(0, 1, 0).Add(-time.Second)
+	if asOf.After(now) {
+		asOf = now
+	}
+
+	provisions, err := u.computeProvisions(ctx, asOf)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &entity.ProvisionRunResult{
+		Period:  period,
+		AsOf:    asOf,
+		Entries: []entity.InventoryProvisionEntry{},
+	}
+	for _, p := range provisions {
+		if p.Change == 0 {
+			continue
+		}
+		entry := entity.InventoryProvisionEntry{
+			Period:   period,
+			StockID:  p.StockID,
+			SKUID:    p.SKUID,
+			StoreID:  p.StoreID,
+			Type:     entity.ProvisionEntryProvision,
+			Quantity: p.Quantity,
+			Value:    p.Value,
+			Required: p.Required,
+			Amount:   p.Change,
+			PostedBy: userID,
+			PostedAt: now,
+		}
+		if p.Change < 0 {
+			entry.Type = entity.ProvisionEntryRelease
+			result.Released = roundAmount(result.Released - p.Change)
+		} else {
+			result.Provisioned = roundAmount(result.Provisioned + p.Change)
+		}
+		result.Entries = append(result.Entries, entry)
+	}
+
+	if err := u.provisionRepo.CreateEntries(ctx, result.Entries); err != nil {
+		return nil, fmt.Errorf("error posting provision entries: %w", err)
+	}
+	return result, nil
+}
+
+// PostPreviousMonth posts the previous month unless entries were already posted for it
+func (u *ProvisionUseCase) PostPreviousMonth(ctx context.Context) (*entity.ProvisionRunResult, error) {
+	now := time.Now()
+	period := now.AddDate(0, 0, -now.Day()).Format(periodLayout)
+
+	posted, err := u.provisionRepo.HasEntries(ctx, period)
+	if err != nil {
+		return nil, fmt.Errorf("error checking provision period: %w", err)
+	}
+	if posted {
+		return nil, nil
+	}
+	return u.Post(ctx, period, nil)
+}
+
+func (u *ProvisionUseCase) computeProvisions(ctx context.Context, asOf time.Time) ([]entity.StockProvision, error) {
+	rules, err := u.provisionRepo.ListRules(ctx, true)
+	if err != nil {
+		return nil, fmt.Errorf("error listing write-down rules: %w", err)
+	}
+	stocks, err := u.provisionRepo.ListProvisionStocks(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error listing stocks: %w", err)
+	}
+	held, err := u.provisionRepo.GetHeldProvisions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error getting held provisions: %w", err)
+	}
+	receipts, err := u.provisionRepo.ListReceiptLayers(ctx, asOf)
+	if err != nil {
+		return nil, fmt.Errorf("error getting stock receipts: %w", err)
+	}
+
+	receiptsByStock := make(map[string][]entity.StockEntry)
+	for _, receipt := range receipts {
+		key := receipt.SKUID + "/" + receipt.StoreID
+		receiptsByStock[key] = append(receiptsByStock[key], receipt)
+	}
+
+	provisions := make([]entity.StockProvision, 0, len(stocks))
+	for _, stock := range stocks {
+		p := entity.StockProvision{
+			StockID:  stock.ID,
+			SKUID:    stock.SKUID,
+			StoreID:  stock.StoreID,
+			Quantity: math.Max(stock.Quantity, 0),
+			Held:     roundAmount(held[stock.ID]),
+		}
+		if stock.SKU != nil {
+			p.SKUCode = stock.SKU.SKUCode
+			p.UnitCost = stock.SKU.Price
+		}
+		p.Value = roundAmount(p.Quantity * p.UnitCost)
+
+		// Walk receipts newest first until the quantity on hand is covered. Stock not
+		// covered by a receipt, e.g. because its entries were archived, is aged from
+		// when the stock record was created.
+		remaining := p.Quantity
+		for _, receipt := range receiptsByStock[stock.SKUID+"/"+stock.StoreID] {
+			if remaining <= 0 {
+				break
+			}
+			qty := math.Min(remaining, receipt.Quantity)
+			p.Layers = append(p.Layers, ageLayer(qty, receipt.CreatedAt, asOf, p.UnitCost, rules))
+			remaining -= qty
+		}
+		if remaining > 0 {
+			p.Layers = append(p.Layers, ageLayer(remaining, stock.CreatedAt, asOf, p.UnitCost, rules))
+		}
+
+		for _, layer := range p.Layers {
+			p.Required += layer.Provision
+		}
+		p.Required = roundAmount(p.Required)
+		p.Change = roundAmount(p.Required - p.Held)
+		provisions = append(provisions, p)
+	}
+	return provisions, nil
+}
+
+// ageLayer ages a receipt layer and applies the rule with the longest threshold it
+// has passed. rules must be ordered by MinAgeDays descending.
+func ageLayer(qty float64, receivedAt, asOf time.Time, unitCost float64, rules []entity.WriteDownRule) entity.StockAgeLayer {
+	layer := entity.StockAgeLayer{
+		Quantity:   qty,
+		ReceivedAt: receivedAt,
+		AgeDays:    int(asOf.Sub(receivedAt).Hours() / 24),
+	}
+	for _, rule := range rules {
+		if layer.AgeDays >= rule.MinAgeDays {
+			layer.Percent = rule.ProvisionPercent
+			break
+		}
+	}
+	layer.Provision = roundAmount(qty * unitCost * layer.Percent / 100)
+	return layer
+}
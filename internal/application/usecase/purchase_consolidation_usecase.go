@@ -0,0 +1,313 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
+)
+
+var (
+	ErrConsolidationRequest  = entity.NewError(entity.ErrCodeFailedPrecondition, "only approved purchase requests not ordered yet can be consolidated")
+	ErrConsolidationVendor   = entity.NewError(entity.ErrCodeInvalidArgument, "every group needs an existing vendor, once")
+	ErrConsolidationCoverage = entity.NewError(entity.ErrCodeInvalidArgument, "the groups must order every line of the purchase requests in full, and nothing else")
+)
+
+// PurchaseConsolidationUseCase turns the lines of several approved purchase
+// requests into one purchase order per vendor
+type PurchaseConsolidationUseCase struct {
+	purchaseRepo *repository.PurchaseRepository
+	vendorRepo   *repository.VendorRepository
+	skuRepo      *repository.SKURepository
+	purchaseUC   *PurchaseUseCase
+}
+
+// NewPurchaseConsolidationUseCase creates a new purchase consolidation use case
+func NewPurchaseConsolidationUseCase(purchaseRepo *repository.PurchaseRepository, vendorRepo *repository.VendorRepository, skuRepo *repository.SKURepository, purchaseUC *PurchaseUseCase) *PurchaseConsolidationUseCase {
+	return &PurchaseConsolidationUseCase{
+		purchaseRepo: purchaseRepo,
+		vendorRepo:   vendorRepo,
+		skuRepo:      skuRepo,
+		purchaseUC:   purchaseUC,
+	}
+}
+
+// Plan groups the lines of approved purchase requests by the preferred vendor
+// of their SKU, vendor by vendor, the lines of SKUs without one coming last
+// under vendor 0 for the buyer to assign
+func (u *PurchaseConsolidationUseCase) Plan(ctx context.Context, requestIDs []string) (*entity.ConsolidationPlan, error) {
+	requests, err := u.approvedRequests(ctx, requestIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var skuIDs []string
+	for _, request := range requests {
+		for _, item := range request.Items {
+			skuIDs = append(skuIDs, item.SKUID)
+		}
+	}
+	skus, err := u.skuRepo.GetSKUsByIDs(ctx, skuIDs)
+	if err != nil {
+		return nil, fmt.Errorf("error getting SKUs: %w", err)
+	}
+	preferred := make(map[string]uint, len(skus))
+	for _, sku := range skus {
+		if sku.VendorID != nil {
+			preferred[sku.ID] = *sku.VendorID
+		}
+	}
+
+	groups := make(map[uint]*entity.ConsolidationGroup)
+	var vendorIDs []uint
+	for _, request := range requests {
+		for _, item := range request.Items {
+			vendorID := preferred[item.SKUID]
+			group, ok := groups[vendorID]
+			if !ok {
+				group = &entity.ConsolidationGroup{VendorID: vendorID}
+				if vendorID != 0 {
+					if vendor, err := u.vendorRepo.FindByID(ctx, vendorID); err == nil {
+						group.VendorName = vendor.Name
+					}
+				}
+				groups[vendorID] = group
+				vendorIDs = append(vendorIDs, vendorID)
+			}
+			group.Lines = append(group.Lines, entity.ConsolidationLine{
+				PurchaseRequestID: request.ID,
+				RequestNumber:     request.RequestNumber,
+				SKUID:             item.SKUID,
+				Quantity:          item.Quantity,
+				Description:       item.Description,
+			})
+		}
+	}
+	sort.Slice(vendorIDs, func(i, j int) bool {
+		if vendorIDs[i] == 0 || vendorIDs[j] == 0 {
+			return vendorIDs[j] == 0 && vendorIDs[i] != 0
+		}
+		return vendorIDs[i] < vendorIDs[j]
+	})
+
+	plan := &entity.ConsolidationPlan{Groups: []entity.ConsolidationGroup{}}
+	for _, request := range requests {
+		plan.RequestIDs = append(plan.RequestIDs, request.ID)
+	}
+	for _, vendorID := range vendorIDs {
+		plan.Groups = append(plan.Groups, *groups[vendorID])
+	}
+	return plan, nil
+}
+
+// Consolidate generates a draft purchase order per group, in one
+// transaction. The groups must order every line of the requests they draw
+// from in full, a line possibly split between vendors. The lines of a SKU
+// in a group make one order line, priced for the total quantity from the
+// vendor's price list, in the vendor's currency, that lists the requests it
+// orders for. An order is expected on the earliest date its requests need
+// the goods by, else after the vendor lead time.
+func (u *PurchaseConsolidationUseCase) Consolidate(ctx context.Context, req *entity.ConsolidationRequest, createdByID uint) ([]entity.PurchaseOrder, error) {
+	var requestIDs []string
+	seen := make(map[string]bool)
+	for _, group := range req.Groups {
+		for _, line := range group.Lines {
+			if !seen[line.PurchaseRequestID] {
+				seen[line.PurchaseRequestID] = true
+				requestIDs = append(requestIDs, line.PurchaseRequestID)
+			}
+		}
+	}
+	requests, err := u.approvedRequests(ctx, requestIDs)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]*entity.PurchaseRequest, len(requests))
+	for i := range requests {
+		byID[requests[i].ID] = &requests[i]
+	}
+
+	// Every request line is ordered in full, and nothing else
+	type requestSKU struct{ requestID, skuID string }
+	remaining := make(map[requestSKU]float64)
+	var skuIDs []string
+	for _, request := range requests {
+		for _, item := range request.Items {
+			remaining[requestSKU{request.ID, item.SKUID}] += item.Quantity
+			skuIDs = append(skuIDs, item.SKUID)
+		}
+	}
+	for _, group := range req.Groups {
+		for _, line := range group.Lines {
+			key := requestSKU{line.PurchaseRequestID, line.SKUID}
+			if _, ok := remaining[key]; !ok {
+				return nil, ErrConsolidationCoverage
+			}
+			remaining[key] -= line.Quantity
+		}
+	}
+	for _, quantity := range remaining {
+		if math.Abs(quantity) > 1e-9 {
+			return nil, ErrConsolidationCoverage
+		}
+	}
+
+	skuList, err := u.skuRepo.GetSKUsByIDs(ctx, skuIDs)
+	if err != nil {
+		return nil, fmt.Errorf("error getting SKUs: %w", err)
+	}
+	skus := make(map[string]*entity.SKU, len(skuList))
+	for i := range skuList {
+		skus[skuList[i].ID] = &skuList[i]
+	}
+
+	orderDate := time.Now()
+	vendors := make(map[uint]bool, len(req.Groups))
+	orders := make([]*entity.PurchaseOrder, 0, len(req.Groups))
+	for _, group := range req.Groups {
+		if group.VendorID == 0 || vendors[group.VendorID] {
+			return nil, ErrConsolidationVendor
+		}
+		vendors[group.VendorID] = true
+		vendor, err := u.vendorRepo.FindByID(ctx, group.VendorID)
+		if err != nil {
+			return nil, ErrConsolidationVendor
+		}
+
+		order := &entity.PurchaseOrder{
+			VendorID:      vendor.ID,
+			OrderDate:     orderDate,
+			CurrencyCode:  strings.ToUpper(vendor.Currency),
+			Status:        entity.PurchaseOrderStatusDraft,
+			PaymentStatus: entity.PaymentStatusPending,
+			Notes:         req.Notes,
+			CreatedByID:   createdByID,
+		}
+
+		lines := make(map[string]int)
+		var numbers []string
+		numbered := make(map[string]bool)
+		for _, line := range group.Lines {
+			request := byID[line.PurchaseRequestID]
+			if !request.RequiredDate.IsZero() && (order.ExpectedDate.IsZero() || request.RequiredDate.Before(order.ExpectedDate)) {
+				order.ExpectedDate = request.RequiredDate
+			}
+			if !numbered[request.ID] {
+				numbered[request.ID] = true
+				numbers = append(numbers, request.RequestNumber)
+			}
+
+			i, ok := lines[line.SKUID]
+			if !ok {
+				i = len(order.Items)
+				lines[line.SKUID] = i
+				order.Items = append(order.Items, entity.PurchaseOrderItem{SKUID: line.SKUID})
+			}
+			item := &order.Items[i]
+			item.Quantity += line.Quantity
+			if item.Description == "" {
+				item.Description = line.Description
+			}
+			merged := false
+			for j := range item.Sources {
+				if item.Sources[j].PurchaseRequestID == request.ID {
+					item.Sources[j].Quantity += line.Quantity
+					merged = true
+				}
+			}
+			if !merged {
+				item.Sources = append(item.Sources, entity.RequestSource{
+					PurchaseRequestID: request.ID,
+					RequestNumber:     request.RequestNumber,
+					Quantity:          line.Quantity,
+				})
+			}
+		}
+		if order.Notes == "" {
+			order.Notes = fmt.Sprintf("Consolidated from purchase requests %s", strings.Join(numbers, ", "))
+		}
+
+		for i := range order.Items {
+			item := &order.Items[i]
+			sku, ok := skus[item.SKUID]
+			if !ok {
+				return nil, ErrConsolidationCoverage
+			}
+			unitPrice, source, priceListID, err := u.purchaseUC.vendorPrice(ctx, vendor.ID, sku, item.Quantity, order.CurrencyCode, orderDate)
+			if err != nil {
+				return nil, err
+			}
+			item.UnitPrice = unitPrice
+			item.TotalPrice = unitPrice * item.Quantity
+			item.PriceSource = source
+			item.PriceListID = priceListID
+			item.CustomsData = sku.CustomsData
+			order.SubTotal += item.TotalPrice
+		}
+		order.GrandTotal = order.SubTotal
+
+		if order.ExpectedDate.IsZero() && vendor.LeadTimeDays > 0 {
+			expected, err := u.purchaseUC.calendarUC.AddBusinessDays(ctx, "", orderDate, vendor.LeadTimeDays)
+			if err != nil {
+				return nil, err
+			}
+			order.ExpectedDate = expected
+		}
+		if err := u.purchaseUC.convertOrderTotal(ctx, order); err != nil {
+			return nil, err
+		}
+		if err := u.purchaseUC.flagPriceVariances(ctx, order); err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+
+	if err := u.purchaseRepo.CreateConsolidatedPurchaseOrders(ctx, orders, requestIDs); err != nil {
+		if errors.Is(err, repository.ErrInvalidData) {
+			return nil, ErrConsolidationRequest
+		}
+		return nil, fmt.Errorf("error creating purchase orders: %w", err)
+	}
+
+	created := make([]entity.PurchaseOrder, 0, len(orders))
+	for _, order := range orders {
+		created = append(created, *order)
+	}
+	return created, nil
+}
+
+// ListRequestOrders retrieves the purchase orders generated from a purchase request
+func (u *PurchaseConsolidationUseCase) ListRequestOrders(ctx context.Context, requestID string) ([]entity.PurchaseOrder, error) {
+	if _, err := u.purchaseUC.getPurchaseRequest(ctx, requestID); err != nil {
+		return nil, err
+	}
+	return u.purchaseRepo.ListPurchaseOrdersByRequestID(ctx, requestID)
+}
+
+// approvedRequests retrieves purchase requests, in the order given, checking
+// they are approved and not ordered yet
+func (u *PurchaseConsolidationUseCase) approvedRequests(ctx context.Context, requestIDs []string) ([]entity.PurchaseRequest, error) {
+	requests := make([]entity.PurchaseRequest, 0, len(requestIDs))
+	seen := make(map[string]bool, len(requestIDs))
+	for _, id := range requestIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		request, err := u.purchaseUC.getPurchaseRequest(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if request.Status != entity.PurchaseRequestStatusApproved || request.PurchaseOrderID != nil {
+			return nil, ErrConsolidationRequest
+		}
+		requests = append(requests, *request)
+	}
+	return requests, nil
+}
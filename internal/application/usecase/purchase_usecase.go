@@ -3,27 +3,39 @@ package usecase
 import (
 	"context"
 	"errors"
+	"fmt"
+	"math"
 	"time"
 
 	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/eventbus"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/extension"
 	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
 )
 
 var (
-	ErrInvalidPurchaseRequest = errors.New("invalid purchase request")
-	ErrInvalidPurchaseOrder   = errors.New("invalid purchase order")
-	ErrInvalidPurchaseReceipt = errors.New("invalid purchase receipt")
-	ErrInvalidPurchasePayment = errors.New("invalid purchase payment")
-	ErrOrderAlreadyReceived   = errors.New("purchase order already fully received")
-	ErrOrderNotApproved       = errors.New("purchase order not approved")
-	ErrOrderNotReceived       = errors.New("purchase order not received")
+	ErrInvalidPurchaseRequest = entity.NewError(entity.ErrCodeInvalidArgument, "invalid purchase request")
+	ErrInvalidPurchaseOrder   = entity.NewError(entity.ErrCodeInvalidArgument, "invalid purchase order")
+	ErrInvalidPurchaseReceipt = entity.NewError(entity.ErrCodeInvalidArgument, "invalid purchase receipt")
+	ErrInvalidPurchasePayment = entity.NewError(entity.ErrCodeInvalidArgument, "invalid purchase payment")
+	ErrOrderAlreadyReceived   = entity.NewError(entity.ErrCodeFailedPrecondition, "purchase order already fully received")
+	ErrOrderNotApproved       = entity.NewError(entity.ErrCodeFailedPrecondition, "purchase order not approved")
+	ErrOrderNotReceived       = entity.NewError(entity.ErrCodeFailedPrecondition, "purchase order not received")
 )
 
 type PurchaseUseCase struct {
-	purchaseRepo *repository.PurchaseRepository
-	stocksRepo   *repository.StocksRepository
-	vendorRepo   *repository.VendorRepository
-	skuRepo      *repository.SKURepository
+	purchaseRepo  *repository.PurchaseRepository
+	stocksRepo    *repository.StocksRepository
+	vendorRepo    *repository.VendorRepository
+	skuRepo       *repository.SKURepository
+	currencyUC    *CurrencyUseCase
+	assetUC       *AssetUseCase
+	qualityUC     *QualityUseCase
+	calendarUC    *CalendarUseCase
+	priceListUC   *VendorPriceListUseCase
+	hooks         *extension.Hooks
+	bus           *eventbus.Bus
+	varianceLimit float64 // percent a price may differ from the last price paid before its line is flagged
 }
 
 func NewPurchaseUseCase(
@@ -31,12 +43,28 @@ func NewPurchaseUseCase(
 	stocksRepo *repository.StocksRepository,
 	vendorRepo *repository.VendorRepository,
 	skuRepo *repository.SKURepository,
+	currencyUC *CurrencyUseCase,
+	assetUC *AssetUseCase,
+	qualityUC *QualityUseCase,
+	calendarUC *CalendarUseCase,
+	priceListUC *VendorPriceListUseCase,
+	hooks *extension.Hooks,
+	bus *eventbus.Bus,
+	varianceLimit float64,
 ) *PurchaseUseCase {
 	return &PurchaseUseCase{
-		purchaseRepo: purchaseRepo,
-		stocksRepo:   stocksRepo,
-		vendorRepo:   vendorRepo,
-		skuRepo:      skuRepo,
+		purchaseRepo:  purchaseRepo,
+		stocksRepo:    stocksRepo,
+		vendorRepo:    vendorRepo,
+		skuRepo:       skuRepo,
+		currencyUC:    currencyUC,
+		assetUC:       assetUC,
+		qualityUC:     qualityUC,
+		calendarUC:    calendarUC,
+		priceListUC:   priceListUC,
+		hooks:         hooks,
+		bus:           bus,
+		varianceLimit: varianceLimit,
 	}
 }
 
@@ -44,6 +72,9 @@ func NewPurchaseUseCase(
 
 // CreatePurchaseRequest creates a new purchase request
 func (u *PurchaseUseCase) CreatePurchaseRequest(ctx context.Context, request *entity.PurchaseRequest) error {
+	if err := entity.AccessScopeFromContext(ctx).CheckDepartment(request.DepartmentID); err != nil {
+		return err
+	}
 	if err := u.validatePurchaseRequest(request); err != nil {
 		return err
 	}
@@ -56,19 +87,36 @@ func (u *PurchaseUseCase) CreatePurchaseRequest(ctx context.Context, request *en
 
 // GetPurchaseRequest gets a purchase request by ID
 func (u *PurchaseUseCase) GetPurchaseRequest(ctx context.Context, id string) (*entity.PurchaseRequest, error) {
-	return u.purchaseRepo.GetPurchaseRequestByID(ctx, id)
+	return u.getPurchaseRequest(ctx, id)
+}
+
+// getPurchaseRequest gets a purchase request of a department the access
+// scope of ctx covers
+func (u *PurchaseUseCase) getPurchaseRequest(ctx context.Context, id string) (*entity.PurchaseRequest, error) {
+	request, err := u.purchaseRepo.GetPurchaseRequestByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := entity.AccessScopeFromContext(ctx).CheckDepartment(request.DepartmentID); err != nil {
+		return nil, err
+	}
+	return request, nil
 }
 
 // UpdatePurchaseRequest updates a purchase request
 func (u *PurchaseUseCase) UpdatePurchaseRequest(ctx context.Context, request *entity.PurchaseRequest) error {
-	existingRequest, err := u.purchaseRepo.GetPurchaseRequestByID(ctx, request.ID)
+	existingRequest, err := u.getPurchaseRequest(ctx, request.ID)
 	if err != nil {
 		return err
 	}
 
 	// Cannot update if already ordered
 	if existingRequest.Status == entity.PurchaseRequestStatusOrdered {
-		return errors.New("cannot update a purchase request that has been ordered")
+		return entity.NewError(entity.ErrCodeFailedPrecondition, "cannot update a purchase request that has been ordered")
+	}
+	// Nor move it to a department outside the scope
+	if err := entity.AccessScopeFromContext(ctx).CheckDepartment(request.DepartmentID); err != nil {
+		return err
 	}
 
 	if err := u.validatePurchaseRequest(request); err != nil {
@@ -80,14 +128,14 @@ func (u *PurchaseUseCase) UpdatePurchaseRequest(ctx context.Context, request *en
 
 // DeletePurchaseRequest deletes a purchase request
 func (u *PurchaseUseCase) DeletePurchaseRequest(ctx context.Context, id string) error {
-	request, err := u.purchaseRepo.GetPurchaseRequestByID(ctx, id)
+	request, err := u.getPurchaseRequest(ctx, id)
 	if err != nil {
 		return err
 	}
 
 	// Cannot delete if not in draft or rejected status
 	if request.Status != entity.PurchaseRequestStatusDraft && request.Status != entity.PurchaseRequestStatusRejected {
-		return errors.New("can only delete purchase requests in draft or rejected status")
+		return entity.NewError(entity.ErrCodeFailedPrecondition, "can only delete purchase requests in draft or rejected status")
 	}
 
 	return u.purchaseRepo.DeletePurchaseRequest(ctx, id)
@@ -107,54 +155,72 @@ func (u *PurchaseUseCase) ListPurchaseRequests(ctx context.Context, filter *enti
 
 // SubmitPurchaseRequest submits a purchase request for approval
 func (u *PurchaseUseCase) SubmitPurchaseRequest(ctx context.Context, id string) error {
-	request, err := u.purchaseRepo.GetPurchaseRequestByID(ctx, id)
+	request, err := u.getPurchaseRequest(ctx, id)
 	if err != nil {
 		return err
 	}
 
 	if request.Status != entity.PurchaseRequestStatusDraft {
-		return errors.New("only draft purchase requests can be submitted")
+		return entity.NewError(entity.ErrCodeFailedPrecondition, "only draft purchase requests can be submitted")
 	}
 
 	request.Status = entity.PurchaseRequestStatusSubmitted
 
-	return u.purchaseRepo.UpdatePurchaseRequest(ctx, request)
+	if err := u.purchaseRepo.UpdatePurchaseRequest(ctx, request); err != nil {
+		return err
+	}
+
+	u.bus.Publish(ctx, entity.ApprovalRequested{
+		ApprovalPendingEvent: entity.ApprovalPendingEvent{
+			Document:    entity.ApprovalPurchaseRequest,
+			DocumentID:  request.ID,
+			Number:      request.RequestNumber,
+			RequestedBy: request.RequesterID,
+		},
+		Approver: entity.PurchaseRequestApprove,
+		Record:   request,
+	})
+	return nil
 }
 
-// ApprovePurchaseRequest approves a purchase request
-func (u *PurchaseUseCase) ApprovePurchaseRequest(ctx context.Context, id string, approverID uint, notes string) error {
-	request, err := u.purchaseRepo.GetPurchaseRequestByID(ctx, id)
+// ApprovePurchaseRequest approves a purchase request, on behalf of an approver
+// away when onBehalfOf is set
+func (u *PurchaseUseCase) ApprovePurchaseRequest(ctx context.Context, id string, approverID uint, onBehalfOf *uint, notes string) error {
+	request, err := u.getPurchaseRequest(ctx, id)
 	if err != nil {
 		return err
 	}
 
 	if request.Status != entity.PurchaseRequestStatusSubmitted {
-		return errors.New("only submitted purchase requests can be approved")
+		return entity.NewError(entity.ErrCodeFailedPrecondition, "only submitted purchase requests can be approved")
 	}
 
 	now := time.Now()
 	request.Status = entity.PurchaseRequestStatusApproved
 	request.ApproverID = &approverID
+	request.OnBehalfOfID = onBehalfOf
 	request.ApprovalDate = &now
 	request.ApprovalNotes = notes
 
 	return u.purchaseRepo.UpdatePurchaseRequest(ctx, request)
 }
 
-// RejectPurchaseRequest rejects a purchase request
-func (u *PurchaseUseCase) RejectPurchaseRequest(ctx context.Context, id string, approverID uint, notes string) error {
-	request, err := u.purchaseRepo.GetPurchaseRequestByID(ctx, id)
+// RejectPurchaseRequest rejects a purchase request, on behalf of an approver
+// away when onBehalfOf is set
+func (u *PurchaseUseCase) RejectPurchaseRequest(ctx context.Context, id string, approverID uint, onBehalfOf *uint, notes string) error {
+	request, err := u.getPurchaseRequest(ctx, id)
 	if err != nil {
 		return err
 	}
 
 	if request.Status != entity.PurchaseRequestStatusSubmitted {
-		return errors.New("only submitted purchase requests can be rejected")
+		return entity.NewError(entity.ErrCodeFailedPrecondition, "only submitted purchase requests can be rejected")
 	}
 
 	now := time.Now()
 	request.Status = entity.PurchaseRequestStatusRejected
 	request.ApproverID = &approverID
+	request.OnBehalfOfID = onBehalfOf
 	request.ApprovalDate = &now
 	request.ApprovalNotes = notes
 
@@ -168,9 +234,13 @@ func (u *PurchaseUseCase) CreatePurchaseOrder(ctx context.Context, order *entity
 	if err := u.validatePurchaseOrder(order); err != nil {
 		return err
 	}
+	if err := u.fillCustomsData(ctx, order.Items); err != nil {
+		return err
+	}
 
 	// Verify vendor exists
-	if _, err := u.vendorRepo.FindByID(ctx, order.VendorID); err != nil {
+	vendor, err := u.vendorRepo.FindByID(ctx, order.VendorID)
+	if err != nil {
 		return err
 	}
 
@@ -178,6 +248,22 @@ func (u *PurchaseUseCase) CreatePurchaseOrder(ctx context.Context, order *entity
 	order.PaymentStatus = entity.PaymentStatusPending
 	order.OrderDate = time.Now()
 
+	// Expect the goods after the vendor lead time in business days on the company calendar
+	if order.ExpectedDate.IsZero() && vendor.LeadTimeDays > 0 {
+		expected, err := u.calendarUC.AddBusinessDays(ctx, "", order.OrderDate, vendor.LeadTimeDays)
+		if err != nil {
+			return err
+		}
+		order.ExpectedDate = expected
+	}
+
+	if err := u.convertOrderTotal(ctx, order); err != nil {
+		return err
+	}
+	if err := u.flagPriceVariances(ctx, order); err != nil {
+		return err
+	}
+
 	return u.purchaseRepo.CreatePurchaseOrder(ctx, order)
 }
 
@@ -195,16 +281,123 @@ func (u *PurchaseUseCase) UpdatePurchaseOrder(ctx context.Context, order *entity
 
 	// Cannot update if not in draft or submitted status
 	if existingOrder.Status != entity.PurchaseOrderStatusDraft && existingOrder.Status != entity.PurchaseOrderStatusSubmitted {
-		return errors.New("can only update purchase orders in draft or submitted status")
+		return entity.NewError(entity.ErrCodeFailedPrecondition, "can only update purchase orders in draft or submitted status")
 	}
 
 	if err := u.validatePurchaseOrder(order); err != nil {
 		return err
 	}
+	if err := u.fillCustomsData(ctx, order.Items); err != nil {
+		return err
+	}
+
+	order.OrderDate = existingOrder.OrderDate
+	if err := u.convertOrderTotal(ctx, order); err != nil {
+		return err
+	}
+	if err := u.flagPriceVariances(ctx, order); err != nil {
+		return err
+	}
 
 	return u.purchaseRepo.UpdatePurchaseOrder(ctx, order)
 }
 
+// vendorPrice returns the unit price, in the order currency, of a SKU ordered
+// from a vendor: the price the vendor's price list gives the quantity on the
+// order date, else the price last paid to the vendor, else the SKU's price
+func (u *PurchaseUseCase) vendorPrice(ctx context.Context, vendorID uint, sku *entity.SKU, quantity float64, currency string, date time.Time) (float64, entity.PurchasePriceSource, *uint, error) {
+	orderRate, err := u.currencyUC.GetRate(ctx, currency, date)
+	if err != nil {
+		return 0, "", nil, err
+	}
+
+	price, err := u.priceListUC.QuotePrice(ctx, vendorID, sku.ID, quantity, date)
+	if err == nil {
+		listRate, err := u.currencyUC.GetRate(ctx, price.CurrencyCode, date)
+		if err != nil {
+			return 0, "", nil, err
+		}
+		return roundAmount(price.UnitPrice * listRate / orderRate), entity.PurchasePriceList, &price.PriceListID, nil
+	}
+	if !errors.Is(err, ErrVendorPriceNotFound) {
+		return 0, "", nil, err
+	}
+
+	last, err := u.purchaseRepo.GetLastPurchasePrice(ctx, sku.ID, vendorID, "")
+	if err == nil {
+		return roundAmount(last.BaseUnitPrice / orderRate), entity.PurchasePriceLastPurchase, nil, nil
+	}
+	if !errors.Is(err, repository.ErrRecordNotFound) {
+		return 0, "", nil, fmt.Errorf("error getting last purchase price: %w", err)
+	}
+
+	return roundAmount(sku.Price / orderRate), entity.PurchasePriceSKU, nil, nil
+}
+
+// flagPriceVariances records on each line of an order the price its SKU was
+// last ordered at, from any vendor, and how far the line's price is from it,
+// flagging lines beyond the variance limit. The order's rate must be set.
+func (u *PurchaseUseCase) flagPriceVariances(ctx context.Context, order *entity.PurchaseOrder) error {
+	rate := order.ExchangeRate
+	if rate <= 0 {
+		rate = 1
+	}
+	for i := range order.Items {
+		item := &order.Items[i]
+		item.LastPrice = 0
+		item.PriceVariance = 0
+		item.PriceVarianceFlagged = false
+
+		last, err := u.purchaseRepo.GetLastPurchasePrice(ctx, item.SKUID, 0, order.ID)
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("error getting last purchase price: %w", err)
+		}
+		item.LastPrice = roundAmount(last.BaseUnitPrice / rate)
+		if item.LastPrice <= 0 {
+			continue
+		}
+		item.PriceVariance = roundAmount((item.UnitPrice - item.LastPrice) / item.LastPrice * 100)
+		item.PriceVarianceFlagged = math.Abs(item.PriceVariance) > u.varianceLimit
+	}
+	return nil
+}
+
+// fillCustomsData validates the customs data given on the lines of an order
+// and completes it from their SKUs
+func (u *PurchaseUseCase) fillCustomsData(ctx context.Context, items entity.PurchaseOrderItems) error {
+	skuIDs := make([]string, len(items))
+	for i := range items {
+		if err := checkCustomsData(&items[i].CustomsData); err != nil {
+			return err
+		}
+		skuIDs[i] = items[i].SKUID
+	}
+	data, err := u.skuRepo.GetCustomsData(ctx, skuIDs)
+	if err != nil {
+		return fmt.Errorf("error getting customs data: %w", err)
+	}
+	for i := range items {
+		items[i].CustomsData.Fill(data[items[i].SKUID])
+	}
+	return nil
+}
+
+// convertOrderTotal records the order currency rate at the order date and the grand total in the base currency
+func (u *PurchaseUseCase) convertOrderTotal(ctx context.Context, order *entity.PurchaseOrder) error {
+	conversion, err := u.currencyUC.Convert(ctx, order.GrandTotal, order.CurrencyCode, order.OrderDate)
+	if err != nil {
+		return err
+	}
+
+	order.CurrencyCode = conversion.Currency
+	order.ExchangeRate = conversion.Rate
+	order.BaseGrandTotal = conversion.BaseAmount
+	return nil
+}
+
 // DeletePurchaseOrder deletes a purchase order
 func (u *PurchaseUseCase) DeletePurchaseOrder(ctx context.Context, id string) error {
 	order, err := u.purchaseRepo.GetPurchaseOrderByID(ctx, id)
@@ -214,7 +407,7 @@ func (u *PurchaseUseCase) DeletePurchaseOrder(ctx context.Context, id string) er
 
 	// Cannot delete if not in draft status
 	if order.Status != entity.PurchaseOrderStatusDraft {
-		return errors.New("can only delete purchase orders in draft status")
+		return entity.NewError(entity.ErrCodeFailedPrecondition, "can only delete purchase orders in draft status")
 	}
 
 	return u.purchaseRepo.DeletePurchaseOrder(ctx, id)
@@ -240,34 +433,51 @@ func (u *PurchaseUseCase) SubmitPurchaseOrder(ctx context.Context, id string) er
 	}
 
 	if order.Status != entity.PurchaseOrderStatusDraft {
-		return errors.New("only draft purchase orders can be submitted")
+		return entity.NewError(entity.ErrCodeFailedPrecondition, "only draft purchase orders can be submitted")
 	}
 
 	order.Status = entity.PurchaseOrderStatusSubmitted
 
-	return u.purchaseRepo.UpdatePurchaseOrder(ctx, order)
+	if err := u.purchaseRepo.UpdatePurchaseOrder(ctx, order); err != nil {
+		return err
+	}
+
+	u.bus.Publish(ctx, entity.ApprovalRequested{
+		ApprovalPendingEvent: entity.ApprovalPendingEvent{
+			Document:    entity.ApprovalPurchaseOrder,
+			DocumentID:  order.ID,
+			Number:      order.OrderNumber,
+			RequestedBy: order.CreatedByID,
+		},
+		Approver: entity.PurchaseOrderApprove,
+		Record:   order,
+	})
+	return nil
 }
 
-// ApprovePurchaseOrder approves a purchase order
-func (u *PurchaseUseCase) ApprovePurchaseOrder(ctx context.Context, id string, approverID uint) error {
+// ApprovePurchaseOrder approves a purchase order, on behalf of an approver
+// away when onBehalfOf is set
+func (u *PurchaseUseCase) ApprovePurchaseOrder(ctx context.Context, id string, approverID uint, onBehalfOf *uint) error {
 	order, err := u.purchaseRepo.GetPurchaseOrderByID(ctx, id)
 	if err != nil {
 		return err
 	}
 
 	if order.Status != entity.PurchaseOrderStatusSubmitted {
-		return errors.New("only submitted purchase orders can be approved")
+		return entity.NewError(entity.ErrCodeFailedPrecondition, "only submitted purchase orders can be approved")
 	}
 
 	now := time.Now()
 	order.Status = entity.PurchaseOrderStatusApproved
 	order.ApprovedByID = &approverID
+	order.OnBehalfOfID = onBehalfOf
 	order.ApprovalDate = &now
 
 	return u.purchaseRepo.UpdatePurchaseOrder(ctx, order)
 }
 
-// SendPurchaseOrder marks a purchase order as sent to vendor
+// SendPurchaseOrder marks a purchase order as sent to vendor. The order is
+// emailed to the vendor by the document email consumer of the event.
 func (u *PurchaseUseCase) SendPurchaseOrder(ctx context.Context, id string) error {
 	order, err := u.purchaseRepo.GetPurchaseOrderByID(ctx, id)
 	if err != nil {
@@ -275,12 +485,16 @@ func (u *PurchaseUseCase) SendPurchaseOrder(ctx context.Context, id string) erro
 	}
 
 	if order.Status != entity.PurchaseOrderStatusApproved {
-		return errors.New("only approved purchase orders can be sent")
+		return entity.NewError(entity.ErrCodeFailedPrecondition, "only approved purchase orders can be sent")
 	}
 
 	order.Status = entity.PurchaseOrderStatusSent
 
-	return u.purchaseRepo.UpdatePurchaseOrder(ctx, order)
+	if err := u.purchaseRepo.UpdatePurchaseOrder(ctx, order); err != nil {
+		return err
+	}
+	u.bus.Publish(ctx, entity.PurchaseOrderSent{Order: order})
+	return nil
 }
 
 // ConfirmPurchaseOrder marks a purchase order as confirmed by vendor
@@ -291,7 +505,7 @@ func (u *PurchaseUseCase) ConfirmPurchaseOrder(ctx context.Context, id string) e
 	}
 
 	if order.Status != entity.PurchaseOrderStatusSent {
-		return errors.New("only sent purchase orders can be confirmed")
+		return entity.NewError(entity.ErrCodeFailedPrecondition, "only sent purchase orders can be confirmed")
 	}
 
 	order.Status = entity.PurchaseOrderStatusConfirmed
@@ -308,7 +522,7 @@ func (u *PurchaseUseCase) CancelPurchaseOrder(ctx context.Context, id string) er
 
 	// Cannot cancel if already received or closed
 	if order.Status == entity.PurchaseOrderStatusReceived || order.Status == entity.PurchaseOrderStatusClosed {
-		return errors.New("cannot cancel purchase orders that are received or closed")
+		return entity.NewError(entity.ErrCodeFailedPrecondition, "cannot cancel purchase orders that are received or closed")
 	}
 
 	order.Status = entity.PurchaseOrderStatusCancelled
@@ -325,11 +539,11 @@ func (u *PurchaseUseCase) ClosePurchaseOrder(ctx context.Context, id string) err
 
 	// Can only close if received and paid
 	if order.Status != entity.PurchaseOrderStatusReceived {
-		return errors.New("only received purchase orders can be closed")
+		return entity.NewError(entity.ErrCodeFailedPrecondition, "only received purchase orders can be closed")
 	}
 
 	if order.PaymentStatus != entity.PaymentStatusPaid {
-		return errors.New("only fully paid purchase orders can be closed")
+		return entity.NewError(entity.ErrCodeFailedPrecondition, "only fully paid purchase orders can be closed")
 	}
 
 	order.Status = entity.PurchaseOrderStatusClosed
@@ -339,13 +553,13 @@ func (u *PurchaseUseCase) ClosePurchaseOrder(ctx context.Context, id string) err
 
 // CreatePurchaseOrderFromRequest creates a purchase order from a purchase request
 func (u *PurchaseUseCase) CreatePurchaseOrderFromRequest(ctx context.Context, requestID string, vendorID uint, createdByID uint) (*entity.PurchaseOrder, error) {
-	request, err := u.purchaseRepo.GetPurchaseRequestByID(ctx, requestID)
+	request, err := u.getPurchaseRequest(ctx, requestID)
 	if err != nil {
 		return nil, err
 	}
 
 	if request.Status != entity.PurchaseRequestStatusApproved {
-		return nil, errors.New("can only create purchase orders from approved purchase requests")
+		return nil, entity.NewError(entity.ErrCodeFailedPrecondition, "can only create purchase orders from approved purchase requests")
 	}
 
 	// Verify vendor exists
@@ -357,6 +571,7 @@ func (u *PurchaseUseCase) CreatePurchaseOrderFromRequest(ctx context.Context, re
 	orderItems := make(entity.PurchaseOrderItems, 0, len(request.Items))
 	subTotal := 0.0
 	taxTotal := 0.0
+	orderDate := time.Now()
 
 	for _, item := range request.Items {
 		// Get SKU details
@@ -365,8 +580,13 @@ func (u *PurchaseUseCase) CreatePurchaseOrderFromRequest(ctx context.Context, re
 			return nil, err
 		}
 
+		// Price the item from the vendor's price list
+		unitPrice, source, priceListID, err := u.vendorPrice(ctx, vendorID, sku, item.Quantity, request.CurrencyCode, orderDate)
+		if err != nil {
+			return nil, err
+		}
+
 		// Calculate item totals
-		unitPrice := sku.Price
 		taxRate := 0.0 // Default tax rate
 		taxAmount := unitPrice * item.Quantity * (taxRate / 100)
 		totalPrice := (unitPrice * item.Quantity) + taxAmount
@@ -380,6 +600,9 @@ func (u *PurchaseUseCase) CreatePurchaseOrderFromRequest(ctx context.Context, re
 			Discount:    0,
 			TotalPrice:  totalPrice,
 			Description: item.Description,
+			PriceSource: source,
+			PriceListID: priceListID,
+			CustomsData: sku.CustomsData,
 		}
 
 		orderItems = append(orderItems, orderItem)
@@ -390,7 +613,7 @@ func (u *PurchaseUseCase) CreatePurchaseOrderFromRequest(ctx context.Context, re
 	// Create purchase order
 	order := &entity.PurchaseOrder{
 		VendorID:      vendorID,
-		OrderDate:     time.Now(),
+		OrderDate:     orderDate,
 		ExpectedDate:  request.RequiredDate,
 		Items:         orderItems,
 		SubTotal:      subTotal,
@@ -403,6 +626,13 @@ func (u *PurchaseUseCase) CreatePurchaseOrderFromRequest(ctx context.Context, re
 		CreatedByID:   createdByID,
 	}
 
+	if err := u.convertOrderTotal(ctx, order); err != nil {
+		return nil, err
+	}
+	if err := u.flagPriceVariances(ctx, order); err != nil {
+		return nil, err
+	}
+
 	// Create the order
 	if err := u.purchaseRepo.CreatePurchaseOrder(ctx, order); err != nil {
 		return nil, err
@@ -433,23 +663,29 @@ func (u *PurchaseUseCase) CreatePurchaseReceipt(ctx context.Context, receipt *en
 	if order.Status != entity.PurchaseOrderStatusConfirmed &&
 		order.Status != entity.PurchaseOrderStatusPartial &&
 		order.Status != entity.PurchaseOrderStatusSent {
-		return errors.New("purchase order must be sent, confirmed, or partially received to create a receipt")
+		return entity.NewError(entity.ErrCodeFailedPrecondition, "purchase order must be sent, confirmed, or partially received to create a receipt")
 	}
 
-	// Create receipt
 	receipt.ReceiptDate = time.Now()
+	if err := u.hooks.Before(ctx, extension.EventBeforeReceiptPost, receipt); err != nil {
+		return err
+	}
+
+	// Create receipt
 	if err := u.purchaseRepo.CreatePurchaseReceipt(ctx, receipt); err != nil {
 		return err
 	}
 
-	// Update inventory for each received item
+	// Update inventory for all received items in one bulk operation. Capital
+	// purchases go to the fixed asset register instead.
+	stockEntries := make([]entity.StockEntry, 0, len(receipt.Items))
+	received := make(map[string]float64, len(receipt.Items))
 	for _, item := range receipt.Items {
-		if item.ReceivedQuantity <= 0 {
+		if item.ReceivedQuantity <= 0 || capitalLine(order, item.SKUID) != nil {
 			continue
 		}
 
-		// Create stock entry
-		stockEntry := &entity.StockEntry{
+		stockEntries = append(stockEntries, entity.StockEntry{
 			StoreID:   receipt.StoreID,
 			SKUID:     item.SKUID,
 			Type:      "IN",
@@ -457,13 +693,24 @@ func (u *PurchaseUseCase) CreatePurchaseReceipt(ctx context.Context, receipt *en
 			Reference: receipt.ReceiptNumber,
 			Note:      "Purchase receipt",
 			CreatedBy: userID,
-		}
+		})
+		received[item.SKUID] += item.ReceivedQuantity
+	}
 
-		if err := u.stocksRepo.ProcessStockEntry(ctx, stockEntry, userID); err != nil {
-			return err
-		}
+	if err := u.stocksRepo.ProcessStockEntries(ctx, stockEntries, userID); err != nil {
+		return err
 	}
 
+	// SKUs with an inspection plan stay in quarantine until they pass
+	if _, err := u.qualityUC.Hold(ctx, entity.InspectionSourceReceipt, receipt.StoreID, receipt.ReceiptNumber, received); err != nil {
+		return err
+	}
+
+	if _, err := u.assetUC.RegisterReceipt(ctx, order, receipt); err != nil {
+		return err
+	}
+
+	u.bus.Publish(ctx, entity.ReceiptPosted{Receipt: receipt})
 	return nil
 }
 
@@ -492,7 +739,7 @@ func (u *PurchaseUseCase) CreatePurchasePayment(ctx context.Context, payment *en
 	}
 
 	if order.Status != entity.PurchaseOrderStatusReceived && order.Status != entity.PurchaseOrderStatusPartial {
-		return errors.New("purchase order must be received or partially received to create a payment")
+		return entity.NewError(entity.ErrCodeFailedPrecondition, "purchase order must be received or partially received to create a payment")
 	}
 
 	// Check if payment would exceed the total amount
@@ -502,7 +749,7 @@ func (u *PurchaseUseCase) CreatePurchasePayment(ctx context.Context, payment *en
 	}
 
 	if totalPaid+payment.Amount > order.GrandTotal {
-		return errors.New("payment amount would exceed the order total")
+		return entity.NewError(entity.ErrCodeFailedPrecondition, "payment amount would exceed the order total")
 	}
 
 	payment.PaymentDate = time.Now()
@@ -545,19 +792,19 @@ func (u *PurchaseUseCase) GetPurchaseOrderPaymentSummary(ctx context.Context, or
 
 func (u *PurchaseUseCase) validatePurchaseRequest(request *entity.PurchaseRequest) error {
 	if request.RequesterID == 0 {
-		return errors.New("requester is required")
+		return entity.NewError(entity.ErrCodeInvalidArgument, "requester is required")
 	}
 
 	if len(request.Items) == 0 {
-		return errors.New("at least one item is required")
+		return entity.NewError(entity.ErrCodeInvalidArgument, "at least one item is required")
 	}
 
 	for _, item := range request.Items {
 		if item.SKUID == "" {
-			return errors.New("SKU ID is required")
+			return entity.NewError(entity.ErrCodeInvalidArgument, "SKU ID is required")
 		}
 		if item.Quantity <= 0 {
-			return errors.New("item quantity must be greater than zero")
+			return entity.NewError(entity.ErrCodeInvalidArgument, "item quantity must be greater than zero")
 		}
 	}
 
@@ -566,26 +813,31 @@ func (u *PurchaseUseCase) validatePurchaseRequest(request *entity.PurchaseReques
 
 func (u *PurchaseUseCase) validatePurchaseOrder(order *entity.PurchaseOrder) error {
 	if order.VendorID == 0 {
-		return errors.New("vendor is required")
+		return entity.NewError(entity.ErrCodeInvalidArgument, "vendor is required")
 	}
 
 	if order.CreatedByID == 0 {
-		return errors.New("created by is required")
+		return entity.NewError(entity.ErrCodeInvalidArgument, "created by is required")
 	}
 
 	if len(order.Items) == 0 {
-		return errors.New("at least one item is required")
+		return entity.NewError(entity.ErrCodeInvalidArgument, "at least one item is required")
 	}
 
 	for _, item := range order.Items {
 		if item.SKUID == "" {
-			return errors.New("SKU ID is required")
+			return entity.NewError(entity.ErrCodeInvalidArgument, "SKU ID is required")
 		}
 		if item.Quantity <= 0 {
-			return errors.New("item quantity must be greater than zero")
+			return entity.NewError(entity.ErrCodeInvalidArgument, "item quantity must be greater than zero")
 		}
 		if item.UnitPrice < 0 {
-			return errors.New("item unit price cannot be negative")
+			return entity.NewError(entity.ErrCodeInvalidArgument, "item unit price cannot be negative")
+		}
+		if item.Asset != nil {
+			if err := validateAssetTerms(item.Asset, item.UnitPrice); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -594,33 +846,33 @@ func (u *PurchaseUseCase) validatePurchaseOrder(order *entity.PurchaseOrder) err
 
 func (u *PurchaseUseCase) validatePurchaseReceipt(receipt *entity.PurchaseReceipt) error {
 	if receipt.PurchaseOrderID == "" {
-		return errors.New("purchase order ID is required")
+		return entity.NewError(entity.ErrCodeInvalidArgument, "purchase order ID is required")
 	}
 
 	if receipt.StoreID == "" {
-		return errors.New("store ID is required")
+		return entity.NewError(entity.ErrCodeInvalidArgument, "store ID is required")
 	}
 
 	if receipt.ReceivedByID == 0 {
-		return errors.New("received by is required")
+		return entity.NewError(entity.ErrCodeInvalidArgument, "received by is required")
 	}
 
 	if len(receipt.Items) == 0 {
-		return errors.New("at least one item is required")
+		return entity.NewError(entity.ErrCodeInvalidArgument, "at least one item is required")
 	}
 
 	for _, item := range receipt.Items {
 		if item.SKUID == "" {
-			return errors.New("SKU ID is required")
+			return entity.NewError(entity.ErrCodeInvalidArgument, "SKU ID is required")
 		}
 		if item.OrderedQuantity <= 0 {
-			return errors.New("ordered quantity must be greater than zero")
+			return entity.NewError(entity.ErrCodeInvalidArgument, "ordered quantity must be greater than zero")
 		}
 		if item.ReceivedQuantity < 0 {
-			return errors.New("received quantity cannot be negative")
+			return entity.NewError(entity.ErrCodeInvalidArgument, "received quantity cannot be negative")
 		}
 		if item.RejectedQuantity < 0 {
-			return errors.New("rejected quantity cannot be negative")
+			return entity.NewError(entity.ErrCodeInvalidArgument, "rejected quantity cannot be negative")
 		}
 	}
 
@@ -629,19 +881,19 @@ func (u *PurchaseUseCase) validatePurchaseReceipt(receipt *entity.PurchaseReceip
 
 func (u *PurchaseUseCase) validatePurchasePayment(payment *entity.PurchasePayment) error {
 	if payment.PurchaseOrderID == "" {
-		return errors.New("purchase order ID is required")
+		return entity.NewError(entity.ErrCodeInvalidArgument, "purchase order ID is required")
 	}
 
 	if payment.Amount <= 0 {
-		return errors.New("payment amount must be greater than zero")
+		return entity.NewError(entity.ErrCodeInvalidArgument, "payment amount must be greater than zero")
 	}
 
 	if payment.PaymentMethod == "" {
-		return errors.New("payment method is required")
+		return entity.NewError(entity.ErrCodeInvalidArgument, "payment method is required")
 	}
 
 	if payment.CreatedByID == 0 {
-		return errors.New("created by is required")
+		return entity.NewError(entity.ErrCodeInvalidArgument, "created by is required")
 	}
 
 	return nil
@@ -0,0 +1,397 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
+)
+
+var (
+	ErrPutawayRuleNotFound   = entity.NewError(entity.ErrCodeNotFound, "putaway rule not found")
+	ErrPutawayTaskNotFound   = entity.NewError(entity.ErrCodeNotFound, "putaway task not found")
+	ErrPutawayFixedSlot      = entity.NewError(entity.ErrCodeInvalidArgument, "a fixed slot rule needs a SKU and a bin location")
+	ErrPutawayVelocityZone   = entity.NewError(entity.ErrCodeInvalidArgument, "a velocity rule needs a velocity class and a zone code")
+	ErrPutawayLocation       = entity.NewError(entity.ErrCodeInvalidArgument, "the task has no suggested bin; scan the bin the goods were put in")
+	ErrPutawayTaskConfirmed  = entity.NewError(entity.ErrCodeFailedPrecondition, "putaway task is already confirmed")
+	ErrPutawayTasksGenerated = entity.NewError(entity.ErrCodeConflict, "putaway tasks were already generated for the receipt")
+)
+
+// Shares of the units a store issued that the fast and medium moving SKUs make
+// up, from the fastest down
+const (
+	velocityFastShare   = 0.80
+	velocityMediumShare = 0.95
+)
+
+// PutawayUseCase turns purchase receipts into putaway tasks, suggesting a bin
+// for each received SKU by the putaway rules of the store
+type PutawayUseCase struct {
+	repo         *repository.PutawayRepository
+	purchaseRepo *repository.PurchaseRepository
+	stocksRepo   *repository.StocksRepository
+	storeRepo    *repository.StoreRepository
+	skuRepo      *repository.SKURepository
+	velocityDays int
+}
+
+// NewPutawayUseCase creates a new putaway use case. SKUs are ranked by the
+// units issued over the last velocityDays days.
+func NewPutawayUseCase(repo *repository.PutawayRepository, purchaseRepo *repository.PurchaseRepository, stocksRepo *repository.StocksRepository, storeRepo *repository.StoreRepository, skuRepo *repository.SKURepository, velocityDays int) *PutawayUseCase {
+	if velocityDays <= 0 {
+		velocityDays = 90
+	}
+	return &PutawayUseCase{
+		repo:         repo,
+		purchaseRepo: purchaseRepo,
+		stocksRepo:   stocksRepo,
+		storeRepo:    storeRepo,
+		skuRepo:      skuRepo,
+		velocityDays: velocityDays,
+	}
+}
+
+// CreateRule creates a putaway rule for a store
+func (u *PutawayUseCase) CreateRule(ctx context.Context, req *entity.PutawayRuleRequest) (*entity.PutawayRule, error) {
+	rule := &entity.PutawayRule{Active: true}
+	if err := u.applyRule(ctx, rule, req); err != nil {
+		return nil, err
+	}
+
+	if err := u.repo.CreateRule(ctx, rule); err != nil {
+		return nil, fmt.Errorf("error creating putaway rule: %w", err)
+	}
+	return rule, nil
+}
+
+// UpdateRule updates a putaway rule. Tasks already generated keep their
+// suggestion.
+func (u *PutawayUseCase) UpdateRule(ctx context.Context, id uint, req *entity.PutawayRuleRequest) (*entity.PutawayRule, error) {
+	rule, err := u.GetRule(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := u.applyRule(ctx, rule, req); err != nil {
+		return nil, err
+	}
+
+	if err := u.repo.UpdateRule(ctx, rule); err != nil {
+		return nil, fmt.Errorf("error updating putaway rule: %w", err)
+	}
+	return rule, nil
+}
+
+// applyRule validates a putaway rule request and copies it onto a rule. A
+// consolidation rule takes no location, as it puts goods with the stock
+// already held.
+func (u *PutawayUseCase) applyRule(ctx context.Context, rule *entity.PutawayRule, req *entity.PutawayRuleRequest) error {
+	if err := entity.AccessScopeFromContext(ctx).CheckStore(req.StoreID); err != nil {
+		return err
+	}
+	if _, err := u.storeRepo.GetByID(ctx, req.StoreID); err != nil {
+		return err
+	}
+
+	switch req.Type {
+	case entity.PutawayRuleFixedSlot:
+		if req.SKUID == "" || req.BinLocation == "" {
+			return ErrPutawayFixedSlot
+		}
+		if _, err := u.skuRepo.GetSKUByID(ctx, req.SKUID); err != nil {
+			return err
+		}
+		req.VelocityClass = ""
+	case entity.PutawayRuleVelocity:
+		if req.VelocityClass == "" || req.ZoneCode == "" {
+			return ErrPutawayVelocityZone
+		}
+		req.SKUID = ""
+	default:
+		req.SKUID, req.VelocityClass = "", ""
+		req.ZoneCode, req.ShelfNumber, req.BinLocation = "", "", ""
+	}
+
+	rule.StoreID = req.StoreID
+	rule.Type = req.Type
+	rule.Priority = req.Priority
+	rule.SKUID = req.SKUID
+	rule.VelocityClass = req.VelocityClass
+	rule.ZoneCode = req.ZoneCode
+	rule.ShelfNumber = req.ShelfNumber
+	rule.BinLocation = req.BinLocation
+	if req.Active != nil {
+		rule.Active = *req.Active
+	}
+	return nil
+}
+
+// GetRule retrieves a putaway rule
+func (u *PutawayUseCase) GetRule(ctx context.Context, id uint) (*entity.PutawayRule, error) {
+	rule, err := u.repo.GetRule(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, ErrPutawayRuleNotFound
+		}
+		return nil, fmt.Errorf("error getting putaway rule: %w", err)
+	}
+	if err := entity.AccessScopeFromContext(ctx).CheckStore(rule.StoreID); err != nil {
+		return nil, ErrPutawayRuleNotFound
+	}
+	return rule, nil
+}
+
+// DeleteRule deletes a putaway rule
+func (u *PutawayUseCase) DeleteRule(ctx context.Context, id uint) error {
+	if _, err := u.GetRule(ctx, id); err != nil {
+		return err
+	}
+	if err := u.repo.DeleteRule(ctx, id); err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return ErrPutawayRuleNotFound
+		}
+		return fmt.Errorf("error deleting putaway rule: %w", err)
+	}
+	return nil
+}
+
+// ListRules lists the putaway rules, optionally of a single store, in the
+// order they are tried
+func (u *PutawayUseCase) ListRules(ctx context.Context, storeID string) ([]entity.PutawayRule, error) {
+	rules, err := u.repo.ListRules(ctx, storeID, false)
+	if err != nil {
+		return nil, fmt.Errorf("error listing putaway rules: %w", err)
+	}
+	return rules, nil
+}
+
+// GenerateForReceipt generates the putaway tasks of a purchase receipt whose
+// tasks were not generated when it was posted
+func (u *PutawayUseCase) GenerateForReceipt(ctx context.Context, receiptID string) ([]entity.PutawayTask, error) {
+	receipt, err := u.purchaseRepo.GetPurchaseReceiptByID(ctx, receiptID)
+	if err != nil {
+		return nil, err
+	}
+	if err := entity.AccessScopeFromContext(ctx).CheckStore(receipt.StoreID); err != nil {
+		return nil, err
+	}
+	generated, err := u.repo.HasTasks(ctx, receipt.ID)
+	if err != nil {
+		return nil, err
+	}
+	if generated {
+		return nil, ErrPutawayTasksGenerated
+	}
+	return u.GenerateTasks(ctx, receipt)
+}
+
+// GenerateTasks creates a pending putaway task for each SKU a purchase receipt
+// brought into stock, less what it cross-docked. Capital lines are not
+// stocked and get none. Each task suggests the location of the first active
+// rule of the store, by priority, that applies to the SKU; a task no rule
+// applies to has no suggestion.
+func (u *PutawayUseCase) GenerateTasks(ctx context.Context, receipt *entity.PurchaseReceipt) ([]entity.PutawayTask, error) {
+	order, err := u.purchaseRepo.GetPurchaseOrderByID(ctx, receipt.PurchaseOrderID)
+	if err != nil {
+		return nil, err
+	}
+
+	var skuIDs []string
+	quantities := make(map[string]float64)
+	for _, item := range receipt.Items {
+		if item.ReceivedQuantity <= 0 || capitalLine(order, item.SKUID) != nil {
+			continue
+		}
+		quantity := item.ReceivedQuantity
+		for _, link := range item.CrossDocks {
+			quantity -= link.Quantity
+		}
+		if quantity <= 0 {
+			continue
+		}
+		if _, ok := quantities[item.SKUID]; !ok {
+			skuIDs = append(skuIDs, item.SKUID)
+		}
+		quantities[item.SKUID] += quantity
+	}
+	if len(skuIDs) == 0 {
+		return []entity.PutawayTask{}, nil
+	}
+
+	rules, err := u.repo.ListRules(ctx, receipt.StoreID, true)
+	if err != nil {
+		return nil, err
+	}
+	var velocity map[string]entity.VelocityClass
+	for _, rule := range rules {
+		if rule.Type == entity.PutawayRuleVelocity {
+			issued, err := u.repo.IssuedQuantities(ctx, receipt.StoreID, time.Now().AddDate(0, 0, -u.velocityDays))
+			if err != nil {
+				return nil, err
+			}
+			velocity = velocityClasses(issued)
+			break
+		}
+	}
+
+	tasks := make([]entity.PutawayTask, 0, len(skuIDs))
+	for _, skuID := range skuIDs {
+		task := entity.PutawayTask{
+			ReceiptID:     receipt.ID,
+			ReceiptNumber: receipt.ReceiptNumber,
+			StoreID:       receipt.StoreID,
+			SKUID:         skuID,
+			Quantity:      quantities[skuID],
+			Status:        entity.PutawayTaskPending,
+		}
+		if err := u.suggest(ctx, &task, rules, velocity); err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+
+	if err := u.repo.CreateTasks(ctx, tasks); err != nil {
+		return nil, fmt.Errorf("error creating putaway tasks: %w", err)
+	}
+	return tasks, nil
+}
+
+// suggest fills in the location of the first rule that applies to a task's SKU
+func (u *PutawayUseCase) suggest(ctx context.Context, task *entity.PutawayTask, rules []entity.PutawayRule, velocity map[string]entity.VelocityClass) error {
+	for _, rule := range rules {
+		zone, shelf, bin := rule.ZoneCode, rule.ShelfNumber, rule.BinLocation
+		switch rule.Type {
+		case entity.PutawayRuleFixedSlot:
+			if rule.SKUID != task.SKUID {
+				continue
+			}
+		case entity.PutawayRuleVelocity:
+			class, ok := velocity[task.SKUID]
+			if !ok {
+				class = entity.VelocitySlow
+			}
+			if rule.VelocityClass != class {
+				continue
+			}
+		case entity.PutawayRuleConsolidate:
+			stock, err := u.stocksRepo.GetBySKUAndStore(ctx, task.SKUID, task.StoreID)
+			if errors.Is(err, repository.ErrRecordNotFound) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if stock.BinLocation == "" {
+				continue
+			}
+			zone, shelf, bin = stock.ZoneCode, stock.ShelfNumber, stock.BinLocation
+		default:
+			continue
+		}
+
+		ruleID := rule.ID
+		task.RuleID = &ruleID
+		task.RuleType = rule.Type
+		task.SuggestedZone = zone
+		task.SuggestedShelf = shelf
+		task.SuggestedBin = bin
+		return nil
+	}
+	return nil
+}
+
+// velocityClasses ranks SKUs by the units issued, fastest first: the SKUs
+// making up the first 80% of the units are fast moving, those making up the
+// next 15% medium and the rest slow
+func velocityClasses(issued map[string]float64) map[string]entity.VelocityClass {
+	skuIDs := make([]string, 0, len(issued))
+	total := 0.0
+	for skuID, quantity := range issued {
+		if quantity > 0 {
+			skuIDs = append(skuIDs, skuID)
+			total += quantity
+		}
+	}
+	sort.Slice(skuIDs, func(i, j int) bool {
+		if issued[skuIDs[i]] != issued[skuIDs[j]] {
+			return issued[skuIDs[i]] > issued[skuIDs[j]]
+		}
+		return skuIDs[i] < skuIDs[j]
+	})
+
+	classes := make(map[string]entity.VelocityClass, len(skuIDs))
+	cumulative := 0.0
+	for _, skuID := range skuIDs {
+		switch share := cumulative / total; {
+		case share < velocityFastShare:
+			classes[skuID] = entity.VelocityFast
+		case share < velocityMediumShare:
+			classes[skuID] = entity.VelocityMedium
+		default:
+			classes[skuID] = entity.VelocitySlow
+		}
+		cumulative += issued[skuID]
+	}
+	return classes
+}
+
+// ListTasks lists the putaway tasks matching a filter, oldest first
+func (u *PutawayUseCase) ListTasks(ctx context.Context, filter *entity.PutawayTaskFilter) ([]entity.PutawayTask, error) {
+	tasks, err := u.repo.ListTasks(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("error listing putaway tasks: %w", err)
+	}
+	return tasks, nil
+}
+
+// GetTask retrieves a putaway task
+func (u *PutawayUseCase) GetTask(ctx context.Context, id uint) (*entity.PutawayTask, error) {
+	task, err := u.repo.GetTask(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, ErrPutawayTaskNotFound
+		}
+		return nil, fmt.Errorf("error getting putaway task: %w", err)
+	}
+	if err := entity.AccessScopeFromContext(ctx).CheckStore(task.StoreID); err != nil {
+		return nil, ErrPutawayTaskNotFound
+	}
+	return task, nil
+}
+
+// ConfirmTask records that the goods of a putaway task were put away at the
+// scanned location, or at the suggested one when no bin was scanned, and moves
+// the stock of the SKU in the store there
+func (u *PutawayUseCase) ConfirmTask(ctx context.Context, id uint, req *entity.PutawayConfirmRequest, userID string) (*entity.PutawayTask, error) {
+	task, err := u.GetTask(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if task.Status != entity.PutawayTaskPending {
+		return nil, ErrPutawayTaskConfirmed
+	}
+
+	if req.BinLocation != "" {
+		task.ZoneCode, task.ShelfNumber, task.BinLocation = req.ZoneCode, req.ShelfNumber, req.BinLocation
+	} else {
+		task.ZoneCode, task.ShelfNumber, task.BinLocation = task.SuggestedZone, task.SuggestedShelf, task.SuggestedBin
+	}
+	if task.BinLocation == "" {
+		return nil, ErrPutawayLocation
+	}
+	now := time.Now()
+	task.Status = entity.PutawayTaskConfirmed
+	task.ConfirmedBy = userID
+	task.ConfirmedAt = &now
+
+	if err := u.repo.ConfirmTask(ctx, task); err != nil {
+		if errors.Is(err, repository.ErrPutawayTaskConfirmed) {
+			return nil, ErrPutawayTaskConfirmed
+		}
+		return nil, fmt.Errorf("error confirming putaway task: %w", err)
+	}
+	return task, nil
+}
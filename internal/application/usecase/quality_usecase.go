@@ -0,0 +1,388 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
+)
+
+var (
+	ErrInspectionPlanNotFound = entity.NewError(entity.ErrCodeNotFound, "inspection plan not found")
+	ErrInspectionPlanExists   = entity.NewError(entity.ErrCodeConflict, "the SKU already has an inspection plan")
+	ErrDefectCodeExists       = entity.NewError(entity.ErrCodeConflict, "defect code already exists")
+	ErrUnknownDefectCode      = entity.NewError(entity.ErrCodeInvalidArgument, "unknown or inactive defect code")
+	ErrInspectionNotFound     = entity.NewError(entity.ErrCodeNotFound, "quality inspection not found")
+	ErrInspectionClosed       = entity.NewError(entity.ErrCodeFailedPrecondition, "quality inspection already has a result")
+	ErrInspectionQuantity     = entity.NewError(entity.ErrCodeInvalidArgument, "passed and rejected quantities must add up to the inspected quantity")
+	ErrDefectsRequired        = entity.NewError(entity.ErrCodeInvalidArgument, "defect quantities must add up to the rejected quantity")
+	ErrInspectionStockShort   = entity.NewError(entity.ErrCodeFailedPrecondition, "the store no longer holds the rejected quantity")
+)
+
+// QualityUseCase holds received and produced goods in quarantine until they pass
+// a quality inspection
+type QualityUseCase struct {
+	qualityRepo *repository.QualityRepository
+}
+
+// NewQualityUseCase creates a new quality use case
+func NewQualityUseCase(qualityRepo *repository.QualityRepository) *QualityUseCase {
+	return &QualityUseCase{
+		qualityRepo: qualityRepo,
+	}
+}
+
+// CreatePlan creates an inspection plan for a SKU
+func (u *QualityUseCase) CreatePlan(ctx context.Context, req *entity.InspectionPlanRequest) (*entity.InspectionPlan, error) {
+	plan := &entity.InspectionPlan{Active: true}
+	applyInspectionPlan(plan, req)
+
+	if err := u.qualityRepo.CreatePlan(ctx, plan); err != nil {
+		if errors.Is(err, repository.ErrDuplicateEntry) {
+			return nil, ErrInspectionPlanExists
+		}
+		return nil, fmt.Errorf("error creating inspection plan: %w", err)
+	}
+	return plan, nil
+}
+
+// UpdatePlan updates an inspection plan. Goods already on hold stay on hold.
+func (u *QualityUseCase) UpdatePlan(ctx context.Context, id uint, req *entity.InspectionPlanRequest) (*entity.InspectionPlan, error) {
+	plan, err := u.GetPlan(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	applyInspectionPlan(plan, req)
+
+	if err := u.qualityRepo.UpdatePlan(ctx, plan); err != nil {
+		if errors.Is(err, repository.ErrDuplicateEntry) {
+			return nil, ErrInspectionPlanExists
+		}
+		return nil, fmt.Errorf("error updating inspection plan: %w", err)
+	}
+	return plan, nil
+}
+
+// applyInspectionPlan copies an inspection plan request onto a plan
+func applyInspectionPlan(plan *entity.InspectionPlan, req *entity.InspectionPlanRequest) {
+	plan.SKUID = req.SKUID
+	plan.Name = req.Name
+	plan.InspectReceipts = req.InspectReceipts
+	plan.InspectProduction = req.InspectProduction
+	plan.SampleSize = req.SampleSize
+	plan.Instructions = req.Instructions
+	if req.Active != nil {
+		plan.Active = *req.Active
+	}
+}
+
+// GetPlan retrieves an inspection plan
+func (u *QualityUseCase) GetPlan(ctx context.Context, id uint) (*entity.InspectionPlan, error) {
+	plan, err := u.qualityRepo.GetPlan(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, ErrInspectionPlanNotFound
+		}
+		return nil, fmt.Errorf("error getting inspection plan: %w", err)
+	}
+	return plan, nil
+}
+
+// ListPlans lists inspection plans, optionally for a single SKU
+func (u *QualityUseCase) ListPlans(ctx context.Context, skuID string) ([]entity.InspectionPlan, error) {
+	plans, err := u.qualityRepo.ListPlans(ctx, skuID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing inspection plans: %w", err)
+	}
+	return plans, nil
+}
+
+// CreateDefectCode creates a defect code. Codes are stored in upper case.
+func (u *QualityUseCase) CreateDefectCode(ctx context.Context, req *entity.DefectCodeRequest) (*entity.DefectCode, error) {
+	code := &entity.DefectCode{
+		Code:        strings.ToUpper(strings.TrimSpace(req.Code)),
+		Description: req.Description,
+		Active:      true,
+	}
+	if err := u.qualityRepo.CreateDefectCode(ctx, code); err != nil {
+		if errors.Is(err, repository.ErrDuplicateEntry) {
+			return nil, ErrDefectCodeExists
+		}
+		return nil, fmt.Errorf("error creating defect code: %w", err)
+	}
+	return code, nil
+}
+
+// ListDefectCodes lists the defect codes
+func (u *QualityUseCase) ListDefectCodes(ctx context.Context) ([]entity.DefectCode, error) {
+	codes, err := u.qualityRepo.ListDefectCodes(ctx, false)
+	if err != nil {
+		return nil, fmt.Errorf("error listing defect codes: %w", err)
+	}
+	return codes, nil
+}
+
+// Hold puts the quantities of SKUs with an active inspection plan for the source
+// on inspection hold in the store they were just received into. Their stock is
+// quarantined until the inspection result is recorded. SKUs without a plan are
+// available straight away.
+func (u *QualityUseCase) Hold(ctx context.Context, source entity.InspectionSource, storeID, reference string, quantities map[string]float64) ([]entity.QualityInspection, error) {
+	skuIDs := make([]string, 0, len(quantities))
+	for skuID, qty := range quantities {
+		if qty > 0 {
+			skuIDs = append(skuIDs, skuID)
+		}
+	}
+	sort.Strings(skuIDs)
+
+	plans, err := u.qualityRepo.FindActivePlans(ctx, skuIDs)
+	if err != nil {
+		return nil, fmt.Errorf("error finding inspection plans: %w", err)
+	}
+
+	inspections := []entity.QualityInspection{}
+	for _, skuID := range skuIDs {
+		plan, ok := plans[skuID]
+		if !ok || !plan.Inspects(source) {
+			continue
+		}
+		qty := quantities[skuID]
+		sampleSize := plan.SampleSize
+		if sampleSize == 0 || float64(sampleSize) > qty {
+			sampleSize = int(math.Ceil(qty))
+		}
+		planID := plan.ID
+		inspections = append(inspections, entity.QualityInspection{
+			PlanID:     &planID,
+			SKUID:      skuID,
+			StoreID:    storeID,
+			Source:     source,
+			Reference:  reference,
+			Quantity:   qty,
+			SampleSize: sampleSize,
+			Status:     entity.InspectionStatusPending,
+			Defects:    entity.InspectionDefects{},
+		})
+	}
+
+	if err := u.qualityRepo.HoldForInspection(ctx, inspections); err != nil {
+		return nil, fmt.Errorf("error holding stock for inspection: %w", err)
+	}
+	return inspections, nil
+}
+
+// HoldExposed puts stock on inspection hold whatever the inspection plans of
+// its SKUs, such as stock exposed to a condition excursion. Each SKU's whole
+// quantity given is quarantined, with the sample size of its plan when it has
+// one. The inspections are keyed by SKU ID.
+func (u *QualityUseCase) HoldExposed(ctx context.Context, source entity.InspectionSource, storeID, reference string, quantities map[string]float64) (map[string]entity.QualityInspection, error) {
+	skuIDs := make([]string, 0, len(quantities))
+	for skuID, qty := range quantities {
+		if qty > 0 {
+			skuIDs = append(skuIDs, skuID)
+		}
+	}
+	sort.Strings(skuIDs)
+
+	plans, err := u.qualityRepo.FindActivePlans(ctx, skuIDs)
+	if err != nil {
+		return nil, fmt.Errorf("error finding inspection plans: %w", err)
+	}
+
+	inspections := make([]entity.QualityInspection, 0, len(skuIDs))
+	for _, skuID := range skuIDs {
+		qty := quantities[skuID]
+		inspection := entity.QualityInspection{
+			SKUID:      skuID,
+			StoreID:    storeID,
+			Source:     source,
+			Reference:  reference,
+			Quantity:   qty,
+			SampleSize: int(math.Ceil(qty)),
+			Status:     entity.InspectionStatusPending,
+			Defects:    entity.InspectionDefects{},
+		}
+		if plan, ok := plans[skuID]; ok {
+			planID := plan.ID
+			inspection.PlanID = &planID
+			if plan.SampleSize > 0 && float64(plan.SampleSize) < qty {
+				inspection.SampleSize = plan.SampleSize
+			}
+		}
+		inspections = append(inspections, inspection)
+	}
+
+	if err := u.qualityRepo.HoldForInspection(ctx, inspections); err != nil {
+		return nil, fmt.Errorf("error holding stock for inspection: %w", err)
+	}
+	held := make(map[string]entity.QualityInspection, len(inspections))
+	for _, inspection := range inspections {
+		held[inspection.SKUID] = inspection
+	}
+	return held, nil
+}
+
+// InspectedSKUs reports which of the given SKUs an active plan holds for
+// inspection when they come from a source
+func (u *QualityUseCase) InspectedSKUs(ctx context.Context, source entity.InspectionSource, skuIDs []string) (map[string]bool, error) {
+	plans, err := u.qualityRepo.FindActivePlans(ctx, skuIDs)
+	if err != nil {
+		return nil, fmt.Errorf("error finding inspection plans: %w", err)
+	}
+
+	inspected := make(map[string]bool, len(plans))
+	for skuID, plan := range plans {
+		if plan.Inspects(source) {
+			inspected[skuID] = true
+		}
+	}
+	return inspected, nil
+}
+
+// ListInspections lists quality inspections
+func (u *QualityUseCase) ListInspections(ctx context.Context, filter *entity.QualityInspectionFilter) ([]entity.QualityInspection, int64, error) {
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+	if filter.PageSize <= 0 {
+		filter.PageSize = 20
+	}
+
+	inspections, total, err := u.qualityRepo.ListInspections(ctx, filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error listing quality inspections: %w", err)
+	}
+	return inspections, total, nil
+}
+
+// GetInspection retrieves a quality inspection
+func (u *QualityUseCase) GetInspection(ctx context.Context, id uint) (*entity.QualityInspection, error) {
+	inspection, err := u.qualityRepo.GetInspection(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, ErrInspectionNotFound
+		}
+		return nil, fmt.Errorf("error getting quality inspection: %w", err)
+	}
+	return inspection, nil
+}
+
+// RecordResult records the result of a pending inspection. The held quantity
+// leaves quarantine: passed units become available for picking and rejected
+// units are written off stock. Rejected units must be classified with defect
+// codes.
+func (u *QualityUseCase) RecordResult(ctx context.Context, id uint, req *entity.InspectionResultRequest, userID string) (*entity.QualityInspection, error) {
+	inspection, err := u.GetInspection(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if inspection.Status != entity.InspectionStatusPending {
+		return nil, ErrInspectionClosed
+	}
+	if roundAmount(req.PassedQuantity+req.RejectedQuantity) != roundAmount(inspection.Quantity) {
+		return nil, ErrInspectionQuantity
+	}
+
+	defects, err := u.checkDefects(ctx, req.Defects, req.RejectedQuantity)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	inspection.PassedQuantity = req.PassedQuantity
+	inspection.RejectedQuantity = req.RejectedQuantity
+	inspection.Defects = defects
+	inspection.Notes = req.Notes
+	inspection.InspectedAt = &now
+	if inspectorID, err := parseUserID(userID); err == nil {
+		inspection.InspectedBy = &inspectorID
+	}
+	switch {
+	case req.RejectedQuantity == 0:
+		inspection.Status = entity.InspectionStatusPassed
+	case req.PassedQuantity == 0:
+		inspection.Status = entity.InspectionStatusFailed
+	default:
+		inspection.Status = entity.InspectionStatusPartial
+	}
+
+	var entries []entity.StockEntry
+	if req.RejectedQuantity > 0 {
+		entries = append(entries, entity.StockEntry{
+			StoreID:   inspection.StoreID,
+			SKUID:     inspection.SKUID,
+			Type:      "OUT",
+			Quantity:  req.RejectedQuantity,
+			Reference: inspection.Reference,
+			Note:      fmt.Sprintf("Rejected by quality inspection %d", inspection.ID),
+			CreatedBy: userID,
+		})
+	}
+
+	if err := u.qualityRepo.RecordResult(ctx, inspection, entries, userID); err != nil {
+		if errors.Is(err, repository.ErrInvalidData) {
+			return nil, ErrInspectionClosed
+		}
+		if errors.Is(err, repository.ErrInsufficientStock) {
+			return nil, ErrInspectionStockShort
+		}
+		return nil, fmt.Errorf("error recording inspection result: %w", err)
+	}
+	return inspection, nil
+}
+
+// checkDefects validates the defects of a result against the active defect codes.
+// Their quantities must add up to the rejected quantity.
+func (u *QualityUseCase) checkDefects(ctx context.Context, defects []entity.InspectionDefect, rejected float64) (entity.InspectionDefects, error) {
+	result := make(entity.InspectionDefects, len(defects))
+	if len(defects) == 0 {
+		if rejected > 0 {
+			return nil, ErrDefectsRequired
+		}
+		return result, nil
+	}
+
+	codes, err := u.qualityRepo.ListDefectCodes(ctx, true)
+	if err != nil {
+		return nil, fmt.Errorf("error listing defect codes: %w", err)
+	}
+	known := make(map[string]bool, len(codes))
+	for _, code := range codes {
+		known[code.Code] = true
+	}
+
+	var total float64
+	for i, defect := range defects {
+		defect.Code = strings.ToUpper(strings.TrimSpace(defect.Code))
+		if !known[defect.Code] {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownDefectCode, defect.Code)
+		}
+		total += defect.Quantity
+		result[i] = defect
+	}
+	if roundAmount(total) != roundAmount(rejected) {
+		return nil, ErrDefectsRequired
+	}
+	return result, nil
+}
+
+// checkDefectCode normalises a defect code and checks that it is active
+func (u *QualityUseCase) checkDefectCode(ctx context.Context, code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	codes, err := u.qualityRepo.ListDefectCodes(ctx, true)
+	if err != nil {
+		return "", fmt.Errorf("error listing defect codes: %w", err)
+	}
+	for _, known := range codes {
+		if known.Code == code {
+			return code, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrUnknownDefectCode, code)
+}
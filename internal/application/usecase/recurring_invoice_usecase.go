@@ -0,0 +1,417 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
+)
+
+var (
+	ErrRecurringInvoiceNotFound  = entity.NewError(entity.ErrCodeNotFound, "recurring invoice not found")
+	ErrRecurringInvoiceEnded     = entity.NewError(entity.ErrCodeFailedPrecondition, "recurring invoice has ended")
+	ErrRecurringInvoiceNotActive = entity.NewError(entity.ErrCodeFailedPrecondition, "recurring invoice is not active")
+	ErrRecurringInvoiceNotPaused = entity.NewError(entity.ErrCodeFailedPrecondition, "recurring invoice is not paused")
+	ErrInvalidRecurringEndDate   = entity.NewError(entity.ErrCodeInvalidArgument, "recurring invoice end date must not be before its start date")
+)
+
+// Recurring invoice defaults and limits
+const (
+	defaultPaymentTermsDays = 30
+	defaultPreviewCount     = 12
+	maxPreviewCount         = 60
+)
+
+// RecurringInvoiceUseCase manages recurring invoice templates and materializes
+// their occurrences into finance invoices
+type RecurringInvoiceUseCase struct {
+	recurringRepo *repository.RecurringInvoiceRepository
+	financeUC     *FinanceUseCase
+}
+
+// NewRecurringInvoiceUseCase creates a new recurring invoice use case
+func NewRecurringInvoiceUseCase(recurringRepo *repository.RecurringInvoiceRepository, financeUC *FinanceUseCase) *RecurringInvoiceUseCase {
+	return &RecurringInvoiceUseCase{
+		recurringRepo: recurringRepo,
+		financeUC:     financeUC,
+	}
+}
+
+// Create creates a recurring invoice template. Its first occurrence falls on the start date.
+func (u *RecurringInvoiceUseCase) Create(ctx context.Context, req *entity.CreateRecurringInvoiceRequest, userID int64) (*entity.RecurringInvoice, error) {
+	start := truncateDay(req.StartDate)
+	if req.EndDate != nil && req.EndDate.Before(start) {
+		return nil, ErrInvalidRecurringEndDate
+	}
+
+	template := &entity.RecurringInvoice{
+		Name:              req.Name,
+		Type:              req.Type,
+		EntityID:          req.EntityID,
+		EntityType:        req.EntityType,
+		EntityName:        req.EntityName,
+		Items:             req.Items,
+		DiscountAmount:    req.DiscountAmount,
+		CurrencyCode:      req.CurrencyCode,
+		Notes:             req.Notes,
+		Frequency:         req.Frequency,
+		IntervalCount:     req.IntervalCount,
+		PaymentTermsDays:  req.PaymentTermsDays,
+		EscalationPercent: req.EscalationPercent,
+		StartDate:         start,
+		EndDate:           req.EndDate,
+		NextRunDate:       &start,
+		Status:            entity.RecurringInvoiceActive,
+		CreatedBy:         userID,
+	}
+	if template.IntervalCount <= 0 {
+		template.IntervalCount = 1
+	}
+	if template.PaymentTermsDays <= 0 {
+		template.PaymentTermsDays = defaultPaymentTermsDays
+	}
+
+	if err := u.recurringRepo.Create(ctx, template); err != nil {
+		return nil, fmt.Errorf("error creating recurring invoice: %w", err)
+	}
+	return template, nil
+}
+
+// Get retrieves a recurring invoice template
+func (u *RecurringInvoiceUseCase) Get(ctx context.Context, id uint) (*entity.RecurringInvoice, error) {
+	template, err := u.recurringRepo.Get(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, ErrRecurringInvoiceNotFound
+		}
+		return nil, fmt.Errorf("error getting recurring invoice: %w", err)
+	}
+	return template, nil
+}
+
+// List lists recurring invoice templates
+func (u *RecurringInvoiceUseCase) List(ctx context.Context, filter *entity.RecurringInvoiceFilter) ([]entity.RecurringInvoice, int64, error) {
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+	if filter.PageSize <= 0 {
+		filter.PageSize = 20
+	}
+
+	templates, total, err := u.recurringRepo.List(ctx, filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error listing recurring invoices: %w", err)
+	}
+	return templates, total, nil
+}
+
+// Update changes a recurring invoice template. Occurrences already invoiced are not
+// affected. Moving the end date before the next occurrence ends the template.
+func (u *RecurringInvoiceUseCase) Update(ctx context.Context, id uint, req *entity.UpdateRecurringInvoiceRequest) (*entity.RecurringInvoice, error) {
+	template, err := u.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if template.Status == entity.RecurringInvoiceEnded {
+		return nil, ErrRecurringInvoiceEnded
+	}
+
+	if req.Name != "" {
+		template.Name = req.Name
+	}
+	if len(req.Items) > 0 {
+		template.Items = req.Items
+	}
+	if req.DiscountAmount != nil {
+		template.DiscountAmount = *req.DiscountAmount
+	}
+	if req.Notes != "" {
+		template.Notes = req.Notes
+	}
+	if req.PaymentTermsDays != nil && *req.PaymentTermsDays > 0 {
+		template.PaymentTermsDays = *req.PaymentTermsDays
+	}
+	if req.EscalationPercent != nil {
+		template.EscalationPercent = *req.EscalationPercent
+	}
+	if req.EndDate != nil {
+		if req.EndDate.Before(template.StartDate) {
+			return nil, ErrInvalidRecurringEndDate
+		}
+		template.EndDate = req.EndDate
+		if template.NextRunDate != nil && template.NextRunDate.After(*template.EndDate) {
+			template.NextRunDate = nil
+			template.Status = entity.RecurringInvoiceEnded
+		}
+	}
+
+	if err := u.recurringRepo.Update(ctx, template); err != nil {
+		return nil, fmt.Errorf("error updating recurring invoice: %w", err)
+	}
+	return template, nil
+}
+
+// Pause stops a recurring invoice from generating invoices until it is resumed
+func (u *RecurringInvoiceUseCase) Pause(ctx context.Context, id uint) (*entity.RecurringInvoice, error) {
+	template, err := u.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if template.Status != entity.RecurringInvoiceActive {
+		return nil, ErrRecurringInvoiceNotActive
+	}
+
+	template.Status = entity.RecurringInvoicePaused
+	if err := u.recurringRepo.Update(ctx, template); err != nil {
+		return nil, fmt.Errorf("error pausing recurring invoice: %w", err)
+	}
+	return template, nil
+}
+
+// Resume restarts a paused recurring invoice. Occurrences that fell while it was
+// paused are passed over rather than invoiced late.
+func (u *RecurringInvoiceUseCase) Resume(ctx context.Context, id uint) (*entity.RecurringInvoice, error) {
+	template, err := u.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if template.Status != entity.RecurringInvoicePaused {
+		return nil, ErrRecurringInvoiceNotPaused
+	}
+
+	template.Status = entity.RecurringInvoiceActive
+	today := truncateDay(time.Now())
+	for template.NextRunDate != nil && template.NextRunDate.Before(today) {
+		advanceRecurringInvoice(template)
+	}
+
+	if err := u.recurringRepo.Update(ctx, template); err != nil {
+		return nil, fmt.Errorf("error resuming recurring invoice: %w", err)
+	}
+	return template, nil
+}
+
+// Skip passes over the next occurrence of a recurring invoice without invoicing it
+func (u *RecurringInvoiceUseCase) Skip(ctx context.Context, id uint, userID *uint) (*entity.RecurringInvoiceRun, error) {
+	template, err := u.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if template.Status == entity.RecurringInvoiceEnded || template.NextRunDate == nil {
+		return nil, ErrRecurringInvoiceEnded
+	}
+
+	run := &entity.RecurringInvoiceRun{
+		RecurringInvoiceID: template.ID,
+		Occurrence:         template.Occurrences,
+		ScheduledDate:      *template.NextRunDate,
+		Status:             entity.RecurringInvoiceRunSkipped,
+		EscalationFactor:   escalationFactor(template, *template.NextRunDate),
+		CreatedBy:          userID,
+	}
+	advanceRecurringInvoice(template)
+
+	if err := u.recurringRepo.RecordRun(ctx, template, run, nil); err != nil {
+		return nil, fmt.Errorf("error skipping recurring invoice: %w", err)
+	}
+	return run, nil
+}
+
+// Preview lists the upcoming occurrences of a recurring invoice with the amounts
+// they will be invoiced at, up to its end date
+func (u *RecurringInvoiceUseCase) Preview(ctx context.Context, id uint, count int) ([]entity.RecurringInvoicePreviewLine, error) {
+	template, err := u.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if count <= 0 {
+		count = defaultPreviewCount
+	}
+	if count > maxPreviewCount {
+		count = maxPreviewCount
+	}
+
+	currency := template.CurrencyCode
+	if currency == "" {
+		currency = u.financeUC.currencyUC.BaseCurrency()
+	}
+
+	preview := []entity.RecurringInvoicePreviewLine{}
+	if template.Status == entity.RecurringInvoiceEnded {
+		return preview, nil
+	}
+	for occurrence := template.Occurrences; len(preview) < count; occurrence++ {
+		date := occurrenceDate(template, occurrence)
+		if template.EndDate != nil && date.After(*template.EndDate) {
+			break
+		}
+
+		factor := escalationFactor(template, date)
+		var subtotal, taxTotal float64
+		for _, item := range escalateItems(template.Items, factor) {
+			lineSubtotal := item.Quantity * item.UnitPrice
+			subtotal += lineSubtotal
+			taxTotal += lineSubtotal * (item.TaxRate / 100)
+		}
+		preview = append(preview, entity.RecurringInvoicePreviewLine{
+			Occurrence:       occurrence,
+			IssueDate:        date,
+			DueDate:          date.AddDate(0, 0, template.PaymentTermsDays),
+			EscalationFactor: factor,
+			Subtotal:         roundAmount(subtotal),
+			TaxTotal:         roundAmount(taxTotal),
+			Total:            roundAmount(subtotal + taxTotal - template.DiscountAmount),
+			CurrencyCode:     currency,
+		})
+	}
+	return preview, nil
+}
+
+// ListRuns lists the occurrences of a recurring invoice invoiced or skipped so far
+func (u *RecurringInvoiceUseCase) ListRuns(ctx context.Context, id uint) ([]entity.RecurringInvoiceRun, error) {
+	if _, err := u.Get(ctx, id); err != nil {
+		return nil, err
+	}
+
+	runs, err := u.recurringRepo.ListRuns(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("error listing recurring invoice runs: %w", err)
+	}
+	return runs, nil
+}
+
+// RunDue invoices every occurrence of the active recurring invoices falling on or
+// before the given time, catching up occurrences missed since the last run. A
+// template that fails stops at the failing occurrence and is retried next run.
+func (u *RecurringInvoiceUseCase) RunDue(ctx context.Context, asOf time.Time) (*entity.RecurringInvoiceBatchResult, error) {
+	templates, err := u.recurringRepo.ListDue(ctx, asOf)
+	if err != nil {
+		return nil, fmt.Errorf("error listing due recurring invoices: %w", err)
+	}
+
+	result := &entity.RecurringInvoiceBatchResult{AsOf: asOf, Templates: len(templates)}
+	for i := range templates {
+		template := &templates[i]
+		for template.Status == entity.RecurringInvoiceActive && template.NextRunDate != nil && !template.NextRunDate.After(asOf) {
+			if err := u.generate(ctx, template); err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("recurring invoice %d: %v", template.ID, err))
+				break
+			}
+			result.Invoices++
+		}
+		if template.Status == entity.RecurringInvoiceEnded {
+			result.Ended++
+		}
+	}
+	return result, nil
+}
+
+// generate invoices the next occurrence of a recurring invoice and advances it
+func (u *RecurringInvoiceUseCase) generate(ctx context.Context, template *entity.RecurringInvoice) error {
+	date := *template.NextRunDate
+	factor := escalationFactor(template, date)
+
+	invoice, err := u.financeUC.newInvoice(ctx, &entity.CreateFinanceInvoiceRequest{
+		Type:           template.Type,
+		ReferenceID:    fmt.Sprintf("REC-%d-%d", template.ID, template.Occurrences),
+		EntityID:       template.EntityID,
+		EntityType:     template.EntityType,
+		IssueDate:      date,
+		DueDate:        date.AddDate(0, 0, template.PaymentTermsDays),
+		Items:          escalateItems(template.Items, factor),
+		DiscountAmount: template.DiscountAmount,
+		CurrencyCode:   template.CurrencyCode,
+		Notes:          template.Notes,
+	}, template.CreatedBy)
+	if err != nil {
+		return err
+	}
+	if template.EntityName != "" {
+		invoice.EntityName = template.EntityName
+	}
+	invoice.Status = entity.FinanceInvoicePending
+
+	run := &entity.RecurringInvoiceRun{
+		RecurringInvoiceID: template.ID,
+		Occurrence:         template.Occurrences,
+		ScheduledDate:      date,
+		Status:             entity.RecurringInvoiceRunGenerated,
+		EscalationFactor:   factor,
+		Total:              invoice.Total,
+	}
+	advanceRecurringInvoice(template)
+
+	if err := u.recurringRepo.RecordRun(ctx, template, run, invoice); err != nil {
+		return fmt.Errorf("error recording recurring invoice run: %w", err)
+	}
+	return nil
+}
+
+// advanceRecurringInvoice moves a template past its next occurrence, ending it once
+// the following occurrence would fall after the end date
+func advanceRecurringInvoice(template *entity.RecurringInvoice) {
+	template.Occurrences++
+	next := occurrenceDate(template, template.Occurrences)
+	if template.EndDate != nil && next.After(*template.EndDate) {
+		template.NextRunDate = nil
+		template.Status = entity.RecurringInvoiceEnded
+		return
+	}
+	template.NextRunDate = &next
+}
+
+// occurrenceDate returns the date of the nth occurrence of a recurring invoice,
+// counting from zero at the start date. Months are counted from the start date so
+// that an invoice on the 31st falls on the last day of shorter months.
+func occurrenceDate(template *entity.RecurringInvoice, n int) time.Time {
+	step := n * template.IntervalCount
+	switch template.Frequency {
+	case entity.RecurrenceWeekly:
+		return template.StartDate.AddDate(0, 0, 7*step)
+	case entity.RecurrenceQuarterly:
+		return addMonthsClamped(template.StartDate, 3*step)
+	case entity.RecurrenceYearly:
+		return addMonthsClamped(template.StartDate, 12*step)
+	default:
+		return addMonthsClamped(template.StartDate, step)
+	}
+}
+
+// addMonthsClamped adds months to a date, moving days past the end of the target
+// month back to its last day
+func addMonthsClamped(t time.Time, months int) time.Time {
+	first := time.Date(t.Year(), t.Month()+time.Month(months), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	day := t.Day()
+	if last := first.AddDate(0, 1, -1).Day(); day > last {
+		day = last
+	}
+	return first.AddDate(0, 0, day-1)
+}
+
+// escalationFactor returns the price multiplier of a recurring invoice on a date:
+// the escalation percent compounded once for every anniversary of the start date
+func escalationFactor(template *entity.RecurringInvoice, date time.Time) float64 {
+	years := date.Year() - template.StartDate.Year()
+	if date.Month() < template.StartDate.Month() ||
+		(date.Month() == template.StartDate.Month() && date.Day() < template.StartDate.Day()) {
+		years--
+	}
+	if years <= 0 || template.EscalationPercent == 0 {
+		return 1
+	}
+	return math.Round(math.Pow(1+template.EscalationPercent/100, float64(years))*1e6) / 1e6
+}
+
+// escalateItems copies invoice lines with their unit prices multiplied by a factor
+func escalateItems(items entity.FinanceInvoiceItems, factor float64) entity.FinanceInvoiceItems {
+	escalated := make(entity.FinanceInvoiceItems, len(items))
+	for i, item := range items {
+		item.UnitPrice = roundAmount(item.UnitPrice * factor)
+		escalated[i] = item
+	}
+	return escalated
+}
@@ -2,13 +2,33 @@ package usecase
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"strings"
 	"time"
 
 	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/export"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/filestore"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/logging"
 	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
 )
 
+var (
+	ErrUnsupportedReportFormat = entity.NewError(entity.ErrCodeInvalidArgument, "unsupported report format")
+	ErrReportRunInProgress     = entity.NewError(entity.ErrCodeConflict, "the report run is still in progress")
+)
+
+// reportExportFormats maps report formats to the file formats they export to
+var reportExportFormats = map[entity.ReportFormat]export.Format{
+	entity.ReportFormatCSV:   export.FormatCSV,
+	entity.ReportFormatExcel: export.FormatXLSX,
+	entity.ReportFormatPDF:   export.FormatPDF,
+	entity.ReportFormatJSON:  export.FormatJSON,
+}
+
 // ReportUseCase handles business logic for reports and analytics
 type ReportUseCase struct {
 	reportRepo   *repository.ReportRepository
@@ -16,27 +36,69 @@ type ReportUseCase struct {
 	orderRepo    *repository.OrderRepository
 	purchaseRepo *repository.PurchaseRepository
 	skuRepo      *repository.SKURepository
+	marginUC     *MarginUseCase
+	calendarUC   *CalendarUseCase
+	fiscalUC     *FiscalUseCase
+	brandingUC   *BrandingUseCase
+	jobUC        *JobUseCase
+	documentUC   *DocumentEmailUseCase
+	files        filestore.Store
+	fileURLTTL   time.Duration // how long download URLs of exports stay valid
+
+	dashboardMaxAge time.Duration // how long dashboard metrics are served from their snapshot
 }
 
-// NewReportUseCase creates a new report use case
+// reportJobPayload is the payload of report generation and export jobs
+type reportJobPayload struct {
+	ReportID   string              `json:"report_id"`
+	Format     entity.ReportFormat `json:"format,omitempty"`
+	Recipients []string            `json:"recipients,omitempty"` // addresses a generated report is exported and emailed to
+	RunID      uint64              `json:"run_id,omitempty"`     // run of a report schedule the job records its outcome on
+}
+
+// NewReportUseCase creates a new report use case. Reports are generated and
+// exported by jobs of jobUC, exports are kept in files, and scheduled reports
+// are emailed through documentUC.
 func NewReportUseCase(
 	reportRepo *repository.ReportRepository,
 	stocksRepo *repository.StocksRepository,
 	orderRepo *repository.OrderRepository,
 	purchaseRepo *repository.PurchaseRepository,
 	skuRepo *repository.SKURepository,
+	marginUC *MarginUseCase,
+	calendarUC *CalendarUseCase,
+	fiscalUC *FiscalUseCase,
+	brandingUC *BrandingUseCase,
+	jobUC *JobUseCase,
+	documentUC *DocumentEmailUseCase,
+	files filestore.Store,
+	fileURLTTL time.Duration,
+	dashboardMaxAge time.Duration,
 ) *ReportUseCase {
-	return &ReportUseCase{
+	u := &ReportUseCase{
 		reportRepo:   reportRepo,
 		stocksRepo:   stocksRepo,
 		orderRepo:    orderRepo,
 		purchaseRepo: purchaseRepo,
 		skuRepo:      skuRepo,
-	}
+		marginUC:     marginUC,
+		calendarUC:   calendarUC,
+		fiscalUC:     fiscalUC,
+		brandingUC:   brandingUC,
+		jobUC:        jobUC,
+		documentUC:   documentUC,
+		files:        files,
+		fileURLTTL:   fileURLTTL,
+
+		dashboardMaxAge: dashboardMaxAge,
+	}
+	jobUC.Register(entity.JobReportGenerate, u.runGenerateJob)
+	jobUC.Register(entity.JobReportExport, u.runExportJob)
+	return u
 }
 
-// CreateReport creates a new report
-func (u *ReportUseCase) CreateReport(ctx context.Context, req *entity.CreateReportRequest, userID uint) (*entity.Report, error) {
+// CreateReport creates a pending report and queues the job generating it
+func (u *ReportUseCase) CreateReport(ctx context.Context, req *entity.CreateReportRequest, userID uint) (*entity.Report, *entity.Job, error) {
 	report := &entity.Report{
 		Name:        req.Name,
 		Description: req.Description,
@@ -50,26 +112,27 @@ func (u *ReportUseCase) CreateReport(ctx context.Context, req *entity.CreateRepo
 	}
 
 	if err := u.reportRepo.CreateReport(ctx, report); err != nil {
-		return nil, fmt.Errorf("error creating report: %w", err)
+		return nil, nil, fmt.Errorf("error creating report: %w", err)
 	}
 
-	// Generate report data based on type
-	if err := u.generateReport(ctx, report); err != nil {
-		// Update report status to failed
+	job, err := u.jobUC.Enqueue(ctx, entity.JobReportGenerate, reportJobPayload{ReportID: report.ID}, &userID)
+	if err != nil {
 		report.Status = entity.ReportStatusFailed
 		_ = u.reportRepo.UpdateReport(ctx, report)
-		return nil, fmt.Errorf("error generating report: %w", err)
+		return nil, nil, err
 	}
 
-	return report, nil
+	return report, job, nil
 }
 
-// GetReportByID retrieves a report by ID
+// GetReportByID retrieves a report by ID, with a fresh download URL of its
+// last export
 func (u *ReportUseCase) GetReportByID(ctx context.Context, id string) (*entity.Report, error) {
 	report, err := u.reportRepo.GetReportByID(ctx, id)
 	if err != nil {
 		return nil, fmt.Errorf("error getting report: %w", err)
 	}
+	u.signFileURL(ctx, report)
 	return report, nil
 }
 
@@ -79,21 +142,33 @@ func (u *ReportUseCase) ListReports(ctx context.Context, filter *entity.ReportFi
 	if err != nil {
 		return nil, 0, fmt.Errorf("error listing reports: %w", err)
 	}
+	for i := range reports {
+		u.signFileURL(ctx, &reports[i])
+	}
 	return reports, total, nil
 }
 
-// DeleteReport deletes a report
+// DeleteReport deletes a report and its export
 func (u *ReportUseCase) DeleteReport(ctx context.Context, id string) error {
+	report, err := u.reportRepo.GetReportByID(ctx, id)
+	if err != nil && !errors.Is(err, repository.ErrRecordNotFound) {
+		return fmt.Errorf("error getting report: %w", err)
+	}
 	if err := u.reportRepo.DeleteReport(ctx, id); err != nil {
 		return fmt.Errorf("error deleting report: %w", err)
 	}
+	if report != nil && report.FileKey != "" {
+		if err := u.files.Delete(ctx, report.FileKey); err != nil {
+			logging.FromContext(ctx).Error("error deleting report export", "report_id", report.ID, "file_key", report.FileKey, "error", err)
+		}
+	}
 	return nil
 }
 
 // CreateReportSchedule creates a new report schedule
 func (u *ReportUseCase) CreateReportSchedule(ctx context.Context, req *entity.CreateReportScheduleRequest, userID uint) (*entity.ReportSchedule, error) {
 	// Calculate next run time based on frequency
-	nextRun := u.calculateNextRunTime(time.Now(), req.Frequency)
+	nextRun := u.calculateNextRunTime(ctx, time.Now(), req.Frequency)
 
 	schedule := &entity.ReportSchedule{
 		Name:        req.Name,
@@ -144,7 +219,7 @@ func (u *ReportUseCase) UpdateReportSchedule(ctx context.Context, id string, req
 	if req.Frequency != "" {
 		schedule.Frequency = req.Frequency
 		// Recalculate next run time if frequency changed
-		nextRun := u.calculateNextRunTime(time.Now(), req.Frequency)
+		nextRun := u.calculateNextRunTime(ctx, time.Now(), req.Frequency)
 		schedule.NextRunAt = &nextRun
 	}
 	if req.Format != "" {
@@ -181,59 +256,24 @@ func (u *ReportUseCase) DeleteReportSchedule(ctx context.Context, id string) err
 	return nil
 }
 
-// RunScheduledReports runs all due scheduled reports
+// RunScheduledReports runs all due scheduled reports, recording a run of
+// each schedule
 func (u *ReportUseCase) RunScheduledReports(ctx context.Context) error {
 	schedules, err := u.reportRepo.GetDueSchedules(ctx)
 	if err != nil {
 		return fmt.Errorf("error getting due schedules: %w", err)
 	}
 
-	for _, schedule := range schedules {
-		// Create report from schedule
-		report := &entity.Report{
-			Name:        schedule.Name,
-			Description: schedule.Description,
-			Type:        schedule.ReportType,
-			Parameters:  schedule.Parameters,
-			StartDate:   time.Now().AddDate(0, -1, 0), // Default to last month
-			EndDate:     time.Now(),
-			Format:      schedule.Format,
-			Status:      entity.ReportStatusPending,
-			CreatedBy:   schedule.CreatedBy,
-		}
-
-		// Adjust date range based on frequency
-		switch schedule.Frequency {
-		case entity.ReportScheduleDaily:
-			report.StartDate = time.Now().AddDate(0, 0, -1)
-		case entity.ReportScheduleWeekly:
-			report.StartDate = time.Now().AddDate(0, 0, -7)
-		case entity.ReportScheduleMonthly:
-			report.StartDate = time.Now().AddDate(0, -1, 0)
-		case entity.ReportScheduleQuarterly:
-			report.StartDate = time.Now().AddDate(0, -3, 0)
-		case entity.ReportScheduleYearly:
-			report.StartDate = time.Now().AddDate(-1, 0, 0)
-		}
-
-		// Create the report
-		if err := u.reportRepo.CreateReport(ctx, report); err != nil {
+	for i := range schedules {
+		schedule := &schedules[i]
+		now := time.Now()
+		if _, _, err := u.queueScheduleRun(ctx, schedule, reportPeriodStart(now, schedule.Frequency), now, nil, nil); err != nil {
+			logging.FromContext(ctx).Error("error queueing scheduled report", "schedule_id", schedule.ID, "error", err)
 			continue // Skip to next schedule if this one fails
 		}
 
-		// Generate report data
-		if err := u.generateReport(ctx, report); err != nil {
-			// Update report status to failed
-			report.Status = entity.ReportStatusFailed
-			_ = u.reportRepo.UpdateReport(ctx, report)
-			continue
-		}
-
-		// TODO: Send email with report to recipients
-
 		// Update schedule's last run and next run times
-		now := time.Now()
-		nextRun := u.calculateNextRunTime(now, schedule.Frequency)
+		nextRun := u.calculateNextRunTime(ctx, now, schedule.Frequency)
 		if err := u.reportRepo.UpdateScheduleNextRun(ctx, schedule.ID, now, nextRun); err != nil {
 			continue
 		}
@@ -242,14 +282,159 @@ func (u *ReportUseCase) RunScheduledReports(ctx context.Context) error {
 	return nil
 }
 
-// GetInventoryValueReport generates an inventory value report
+// ListReportRuns lists the runs of a report schedule, latest first, with the
+// download URLs of their exports
+func (u *ReportUseCase) ListReportRuns(ctx context.Context, scheduleID string, filter *entity.ReportRunFilter) ([]entity.ReportRun, int64, error) {
+	if _, err := u.reportRepo.GetReportScheduleByID(ctx, scheduleID); err != nil {
+		return nil, 0, fmt.Errorf("error getting report schedule: %w", err)
+	}
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+	if filter.PageSize <= 0 {
+		filter.PageSize = 20
+	}
+
+	runs, total, err := u.reportRepo.ListReportRuns(ctx, scheduleID, filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error listing report runs: %w", err)
+	}
+	for i := range runs {
+		u.signRunFileURL(ctx, &runs[i])
+	}
+	return runs, total, nil
+}
+
+// RerunReportRun generates the report of a finished run of a schedule again,
+// for the same period, and emails it to the schedule's current recipients.
+// The schedule's next run is left as it is.
+func (u *ReportUseCase) RerunReportRun(ctx context.Context, scheduleID string, runID uint64, userID uint) (*entity.ReportRun, *entity.Job, error) {
+	schedule, err := u.reportRepo.GetReportScheduleByID(ctx, scheduleID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error getting report schedule: %w", err)
+	}
+	previous, err := u.reportRepo.GetReportRun(ctx, runID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error getting report run: %w", err)
+	}
+	if previous.ScheduleID != schedule.ID {
+		return nil, nil, fmt.Errorf("error getting report run: %w", repository.ErrRecordNotFound)
+	}
+	if previous.Status == entity.ReportRunQueued || previous.Status == entity.ReportRunRunning {
+		return nil, nil, ErrReportRunInProgress
+	}
+
+	return u.queueScheduleRun(ctx, schedule, previous.PeriodStart, previous.PeriodEnd, &userID, &previous.ID)
+}
+
+// queueScheduleRun creates the report of a schedule for a period and queues
+// the job generating it, which exports the report and emails it to the
+// schedule's recipients once generated. The run is recorded, failed when the
+// report could not be queued.
+func (u *ReportUseCase) queueScheduleRun(ctx context.Context, schedule *entity.ReportSchedule, start, end time.Time, userID *uint, rerunOf *uint64) (*entity.ReportRun, *entity.Job, error) {
+	createdBy := schedule.CreatedBy
+	if userID != nil {
+		createdBy = *userID
+	}
+	run := &entity.ReportRun{
+		ScheduleID:  schedule.ID,
+		RerunOf:     rerunOf,
+		TriggeredBy: userID,
+		Status:      entity.ReportRunQueued,
+		PeriodStart: start,
+		PeriodEnd:   end,
+	}
+
+	report := &entity.Report{
+		Name:        schedule.Name,
+		Description: schedule.Description,
+		Type:        schedule.ReportType,
+		Parameters:  schedule.Parameters,
+		StartDate:   start,
+		EndDate:     end,
+		Format:      schedule.Format,
+		Status:      entity.ReportStatusPending,
+		CreatedBy:   createdBy,
+	}
+	if err := u.reportRepo.CreateReport(ctx, report); err != nil {
+		err = fmt.Errorf("error creating report: %w", err)
+		u.failReportRun(ctx, run, err)
+		return nil, nil, err
+	}
+	run.ReportID = &report.ID
+	if err := u.reportRepo.CreateReportRun(ctx, run); err != nil {
+		report.Status = entity.ReportStatusFailed
+		_ = u.reportRepo.UpdateReport(ctx, report)
+		return nil, nil, fmt.Errorf("error recording report run: %w", err)
+	}
+
+	payload := reportJobPayload{ReportID: report.ID, Format: schedule.Format, Recipients: schedule.Recipients, RunID: run.ID}
+	job, err := u.jobUC.Enqueue(ctx, entity.JobReportGenerate, payload, &createdBy)
+	if err != nil {
+		report.Status = entity.ReportStatusFailed
+		_ = u.reportRepo.UpdateReport(ctx, report)
+		u.failReportRun(ctx, run, err)
+		return nil, nil, err
+	}
+
+	run.JobID = &job.ID
+	if err := u.reportRepo.UpdateReportRun(ctx, run); err != nil {
+		logging.FromContext(ctx).Error("error updating report run", "report_run_id", run.ID, "error", err)
+	}
+	return run, job, nil
+}
+
+// failReportRun records a run that failed before its job ran
+func (u *ReportUseCase) failReportRun(ctx context.Context, run *entity.ReportRun, cause error) {
+	now := time.Now()
+	run.Status = entity.ReportRunFailed
+	run.Error = cause.Error()
+	run.FinishedAt = &now
+
+	var err error
+	if run.ID == 0 {
+		err = u.reportRepo.CreateReportRun(ctx, run)
+	} else {
+		err = u.reportRepo.UpdateReportRun(ctx, run)
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("error recording failed report run", "schedule_id", run.ScheduleID, "error", err)
+	}
+}
+
+// reportPeriodStart returns the start of the period a schedule's report
+// ending now covers
+func reportPeriodStart(now time.Time, frequency entity.ReportScheduleFrequency) time.Time {
+	switch frequency {
+	case entity.ReportScheduleDaily:
+		return now.AddDate(0, 0, -1)
+	case entity.ReportScheduleWeekly:
+		return now.AddDate(0, 0, -7)
+	case entity.ReportScheduleQuarterly:
+		return now.AddDate(0, -3, 0)
+	case entity.ReportScheduleYearly:
+		return now.AddDate(-1, 0, 0)
+	}
+	return now.AddDate(0, -1, 0) // Default to last month
+}
+
+// GetInventoryValueReport generates an inventory value report. The stock of a
+// past date is read from the latest daily snapshot taken on or before it; the
+// stock of today and later dates is the stock on hand now.
 func (u *ReportUseCase) GetInventoryValueReport(ctx context.Context, warehouseID string, asOfDate time.Time) ([]entity.InventoryValueReport, error) {
-	if asOfDate.IsZero() {
-		asOfDate = time.Now()
+	if asOfDate.IsZero() || asOfDate.Format("2006-01-02") >= time.Now().Format("2006-01-02") {
+		report, err := u.reportRepo.GetInventoryValueReport(ctx, warehouseID)
+		if err != nil {
+			return nil, fmt.Errorf("error generating inventory value report: %w", err)
+		}
+		return report, nil
 	}
 
-	report, err := u.reportRepo.GetInventoryValueReport(ctx, warehouseID, asOfDate)
+	report, err := u.reportRepo.GetInventoryValueSnapshot(ctx, warehouseID, asOfDate)
 	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, ErrNoStockSnapshot
+		}
 		return nil, fmt.Errorf("error generating inventory value report: %w", err)
 	}
 
@@ -270,23 +455,6 @@ func (u *ReportUseCase) GetInventoryAgeReport(ctx context.Context, warehouseID s
 	return report, nil
 }
 
-// GetProductSalesReport generates a product sales report
-func (u *ReportUseCase) GetProductSalesReport(ctx context.Context, startDate, endDate time.Time) ([]entity.ProductSalesReport, error) {
-	if startDate.IsZero() {
-		startDate = time.Now().AddDate(0, -1, 0) // Default to last month
-	}
-	if endDate.IsZero() {
-		endDate = time.Now()
-	}
-
-	report, err := u.reportRepo.GetProductSalesReport(ctx, startDate, endDate)
-	if err != nil {
-		return nil, fmt.Errorf("error generating product sales report: %w", err)
-	}
-
-	return report, nil
-}
-
 // GetCustomerSalesReport generates a customer sales report
 func (u *ReportUseCase) GetCustomerSalesReport(ctx context.Context, startDate, endDate time.Time) ([]entity.CustomerSalesReport, error) {
 	if startDate.IsZero() {
@@ -321,7 +489,8 @@ func (u *ReportUseCase) GetSupplierPurchaseReport(ctx context.Context, startDate
 	return report, nil
 }
 
-// GetProfitAndLossReport generates a profit and loss report
+// GetProfitAndLossReport generates a profit and loss report, broken down by
+// the fiscal periods it spans
 func (u *ReportUseCase) GetProfitAndLossReport(ctx context.Context, startDate, endDate time.Time) (*entity.ProfitAndLossReport, error) {
 	if startDate.IsZero() {
 		startDate = time.Now().AddDate(0, -1, 0) // Default to last month
@@ -335,12 +504,65 @@ func (u *ReportUseCase) GetProfitAndLossReport(ctx context.Context, startDate, e
 		return nil, fmt.Errorf("error generating profit and loss report: %w", err)
 	}
 
+	periods, err := u.fiscalUC.PeriodsBetween(ctx, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	for _, period := range periods {
+		from, to := period.StartDate, endOfDay(period.EndDate)
+		if from.Before(startDate) {
+			from = startDate
+		}
+		if to.After(endDate) {
+			to = endDate
+		}
+		part, err := u.reportRepo.GetProfitAndLossReport(ctx, from, to)
+		if err != nil {
+			return nil, fmt.Errorf("error generating profit and loss of fiscal period %s: %w", period.Name, err)
+		}
+		report.Periods = append(report.Periods, entity.ProfitAndLossPeriod{
+			FiscalPeriodID: period.ID,
+			Name:           period.Name,
+			Status:         period.Status,
+			StartDate:      from,
+			EndDate:        to,
+			Revenue:        part.Revenue,
+			CostOfGoods:    part.CostOfGoods,
+			GrossProfit:    part.GrossProfit,
+			Expenses:       part.Expenses,
+			NetProfit:      part.NetProfit,
+		})
+	}
+
 	return report, nil
 }
 
-// GetDashboardMetrics generates dashboard metrics
-func (u *ReportUseCase) GetDashboardMetrics(ctx context.Context, period string) (*entity.DashboardMetrics, error) {
-	metrics, err := u.reportRepo.GetDashboardMetrics(ctx, period)
+// GetFiscalProfitAndLossReport generates the profit and loss report of a
+// fiscal period, or of a whole fiscal year when periodID is 0
+func (u *ReportUseCase) GetFiscalProfitAndLossReport(ctx context.Context, yearID, periodID uint) (*entity.ProfitAndLossReport, error) {
+	if periodID != 0 {
+		period, err := u.fiscalUC.GetPeriod(ctx, periodID)
+		if err != nil {
+			return nil, err
+		}
+		return u.GetProfitAndLossReport(ctx, period.StartDate, endOfDay(period.EndDate))
+	}
+	year, err := u.fiscalUC.GetYear(ctx, yearID)
+	if err != nil {
+		return nil, err
+	}
+	return u.GetProfitAndLossReport(ctx, year.StartDate, endOfDay(year.EndDate))
+}
+
+// endOfDay returns the last instant of the day starting at t
+func endOfDay(t time.Time) time.Time {
+	return t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+}
+
+// GetDashboardMetrics retrieves dashboard metrics, pre-aggregated unless the
+// data they are aggregated from changed
+func (u *ReportUseCase) GetDashboardMetrics(ctx context.Context, period string, rfm entity.RFMFilter) (*entity.DashboardMetrics, error) {
+	metrics, err := u.reportRepo.GetDashboardMetrics(ctx, period, rfm, u.dashboardMaxAge)
 	if err != nil {
 		return nil, fmt.Errorf("error generating dashboard metrics: %w", err)
 	}
@@ -348,32 +570,316 @@ func (u *ReportUseCase) GetDashboardMetrics(ctx context.Context, period string)
 	return metrics, nil
 }
 
-// ExportReport exports a report to the specified format
-func (u *ReportUseCase) ExportReport(ctx context.Context, reportID string, format entity.ReportFormat) (string, error) {
-	report, err := u.reportRepo.GetReportByID(ctx, reportID)
+// RefreshDashboardMetrics pre-aggregates the dashboard metrics of every period
+func (u *ReportUseCase) RefreshDashboardMetrics(ctx context.Context) error {
+	if err := u.reportRepo.RefreshDashboardMetrics(ctx, u.dashboardMaxAge); err != nil {
+		return fmt.Errorf("error refreshing dashboard metrics: %w", err)
+	}
+	return nil
+}
+
+// InvalidateDashboardMetrics makes the next request for dashboard metrics
+// aggregate them again
+func (u *ReportUseCase) InvalidateDashboardMetrics(ctx context.Context) error {
+	if err := u.reportRepo.InvalidateDashboardMetrics(ctx); err != nil {
+		return fmt.Errorf("error invalidating dashboard metrics: %w", err)
+	}
+	return nil
+}
+
+// ExportReport queues the job exporting a report to the specified format. The
+// job's result holds the download URL of the file.
+func (u *ReportUseCase) ExportReport(ctx context.Context, reportID string, format entity.ReportFormat, userID uint) (*entity.Job, error) {
+	if _, ok := reportExportFormats[format]; !ok {
+		return nil, ErrUnsupportedReportFormat
+	}
+	if _, err := u.reportRepo.GetReportByID(ctx, reportID); err != nil {
+		return nil, fmt.Errorf("error getting report: %w", err)
+	}
+	return u.jobUC.Enqueue(ctx, entity.JobReportExport, reportJobPayload{ReportID: reportID, Format: format}, &userID)
+}
+
+// Helper functions
+
+// runGenerateJob generates the report of a report.generate job, marking the
+// report failed once the job has no attempts left. A report with recipients
+// is then exported and its emails are queued. The job of a schedule's run
+// records its outcome on the run.
+func (u *ReportUseCase) runGenerateJob(ctx context.Context, job *entity.Job, progress func(int)) (interface{}, error) {
+	report, payload, err := u.reportForJob(ctx, job)
+	run := u.startReportRun(ctx, payload, job)
+	rows := -1
+	if err == nil {
+		rows, err = u.generateForJob(ctx, job, report, payload)
+	}
+	u.finishReportRun(ctx, run, job, report, rows, err)
 	if err != nil {
-		return "", fmt.Errorf("error getting report: %w", err)
+		return nil, err
 	}
+	return map[string]string{"report_id": report.ID}, nil
+}
 
-	// TODO: Implement export functionality for different formats
-	// This would generate the file and return the file URL
+// generateForJob generates, exports and emails the report of a
+// report.generate job. It returns the number of rows generated, or -1 when
+// the report was generated by an earlier attempt.
+func (u *ReportUseCase) generateForJob(ctx context.Context, job *entity.Job, report *entity.Report, payload *reportJobPayload) (int, error) {
+	rows := -1
+	if report.Status != entity.ReportStatusCompleted {
+		var err error
+		if rows, err = u.generateReport(ctx, report); err != nil {
+			if job.Attempts >= job.MaxAttempts {
+				report.Status = entity.ReportStatusFailed
+				_ = u.reportRepo.UpdateReport(context.WithoutCancel(ctx), report)
+			}
+			return -1, fmt.Errorf("error generating report: %w", err)
+		}
+	}
 
-	// For now, just update the report with a dummy file URL
-	fileURL := fmt.Sprintf("/reports/%s.%s", report.ID, string(format))
-	report.FileURL = fileURL
-	report.Format = format
+	if len(payload.Recipients) > 0 {
+		if report.FileKey == "" {
+			if err := u.exportReport(ctx, report, payload.Format); err != nil {
+				return rows, err
+			}
+		}
+		if err := u.documentUC.QueueReport(ctx, report, payload.Recipients, job.CreatedBy); err != nil {
+			return rows, fmt.Errorf("error queueing report emails: %w", err)
+		}
+	}
+	return rows, nil
+}
+
+// startReportRun marks the run a report.generate job records its outcome on
+// as running, returning nil for jobs of reports outside schedules
+func (u *ReportUseCase) startReportRun(ctx context.Context, payload *reportJobPayload, job *entity.Job) *entity.ReportRun {
+	if payload == nil || payload.RunID == 0 {
+		return nil
+	}
+	run, err := u.reportRepo.GetReportRun(ctx, payload.RunID)
+	if err != nil {
+		logging.FromContext(ctx).Error("error getting report run", "report_run_id", payload.RunID, "error", err)
+		return nil
+	}
+
+	now := time.Now()
+	run.Status = entity.ReportRunRunning
+	run.Attempts = job.Attempts
+	if run.StartedAt == nil {
+		run.StartedAt = &now
+	}
+	if err := u.reportRepo.UpdateReportRun(ctx, run); err != nil {
+		logging.FromContext(ctx).Error("error updating report run", "report_run_id", run.ID, "error", err)
+	}
+	return run
+}
+
+// finishReportRun records the outcome of an attempt of a run's job. A failed
+// attempt leaves the run queued while the job is retried.
+func (u *ReportUseCase) finishReportRun(ctx context.Context, run *entity.ReportRun, job *entity.Job, report *entity.Report, rows int, err error) {
+	if run == nil {
+		return
+	}
+
+	now := time.Now()
+	if rows >= 0 {
+		run.RowCount = &rows
+	}
+	var failure permanentJobError
+	switch {
+	case err == nil:
+		run.Status = entity.ReportRunSucceeded
+		run.Error = ""
+		run.FinishedAt = &now
+		if report.FileKey != "" {
+			run.FileKey = report.FileKey
+		}
+	case job.Attempts >= job.MaxAttempts || errors.As(err, &failure):
+		run.Status = entity.ReportRunFailed
+		run.Error = err.Error()
+		run.FinishedAt = &now
+	default:
+		run.Status = entity.ReportRunQueued
+		run.Error = err.Error()
+	}
+	if err := u.reportRepo.UpdateReportRun(context.WithoutCancel(ctx), run); err != nil {
+		logging.FromContext(ctx).Error("error updating report run", "report_run_id", run.ID, "error", err)
+	}
+}
+
+// runExportJob exports the report of a report.export job
+func (u *ReportUseCase) runExportJob(ctx context.Context, job *entity.Job, progress func(int)) (interface{}, error) {
+	report, payload, err := u.reportForJob(ctx, job)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.exportReport(ctx, report, payload.Format); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"report_id":           report.ID,
+		"file_url":            report.FileURL,
+		"file_url_expires_at": report.FileExpires,
+	}, nil
+}
 
+// reportForJob decodes a job's payload and loads the report it refers to. The
+// payload is returned when the report cannot be loaded.
+func (u *ReportUseCase) reportForJob(ctx context.Context, job *entity.Job) (*entity.Report, *reportJobPayload, error) {
+	var payload reportJobPayload
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return nil, nil, permanent(fmt.Errorf("invalid job payload: %w", err))
+	}
+	report, err := u.reportRepo.GetReportByID(ctx, payload.ReportID)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, &payload, permanent(fmt.Errorf("report %s not found", payload.ReportID))
+		}
+		return nil, &payload, fmt.Errorf("error getting report: %w", err)
+	}
+	return report, &payload, nil
+}
+
+// exportReport renders a report's data to a file in the format, streaming it
+// into the file store, and keeps the file as the report's export in place of
+// the previous one
+func (u *ReportUseCase) exportReport(ctx context.Context, report *entity.Report, format entity.ReportFormat) error {
+	fileFormat, ok := reportExportFormats[format]
+	if !ok {
+		return permanent(ErrUnsupportedReportFormat)
+	}
+	data, err := u.reportData(ctx, report)
+	if err != nil {
+		return fmt.Errorf("error getting report data: %w", err)
+	}
+	columns, rows := export.Table(data)
+
+	doc := &export.Document{
+		Title:    report.Name,
+		Subtitle: report.StartDate.Format("2006-01-02") + " - " + report.EndDate.Format("2006-01-02"),
+		Columns:  columns,
+	}
+	if branding, err := u.brandingUC.Document(ctx, ""); err == nil {
+		if branding.CompanyName != "" {
+			doc.Header = append(doc.Header, branding.CompanyName)
+		}
+		doc.Header = append(doc.Header, branding.AddressBlock...)
+		doc.Footer = branding.FooterLines
+	}
+
+	// Rows are rendered while the store reads them, so the file is never held
+	// in memory as a whole
+	pr, pw := io.Pipe()
+	go func() {
+		w, err := export.NewWriter(fileFormat, pw, doc)
+		if err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+		for _, row := range rows {
+			if err := w.WriteRow(row); err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+		}
+		pw.CloseWithError(w.Close())
+	}()
+
+	key := fmt.Sprintf("reports/%s/%s-%s%s", report.ID, fileSlug(report.Name), time.Now().Format("20060102-150405"), fileFormat.Extension())
+	err = u.files.Put(ctx, key, fileFormat.ContentType(), pr)
+	pr.CloseWithError(err)
+	if err != nil {
+		return fmt.Errorf("error storing export: %w", err)
+	}
+
+	previous := report.FileKey
+	report.FileKey = key
+	report.Format = format
 	if err := u.reportRepo.UpdateReport(ctx, report); err != nil {
-		return "", fmt.Errorf("error updating report: %w", err)
+		u.files.Delete(context.WithoutCancel(ctx), key)
+		return fmt.Errorf("error updating report: %w", err)
 	}
+	if previous != "" && previous != key {
+		if err := u.files.Delete(ctx, previous); err != nil {
+			logging.FromContext(ctx).Error("error deleting previous report export", "report_id", report.ID, "file_key", previous, "error", err)
+		}
+	}
+	u.signFileURL(ctx, report)
+	return nil
+}
 
-	return fileURL, nil
+// signRunFileURL sets the download URL of the export of a schedule's run, if
+// it has one
+func (u *ReportUseCase) signRunFileURL(ctx context.Context, run *entity.ReportRun) {
+	if run.FileKey == "" {
+		return
+	}
+	expires := time.Now().Add(u.fileURLTTL)
+	fileURL, err := u.files.URL(ctx, run.FileKey, u.fileURLTTL)
+	if err != nil {
+		logging.FromContext(ctx).Error("error signing report run export URL", "report_run_id", run.ID, "error", err)
+		return
+	}
+	run.FileURL = fileURL
+	run.FileExpires = &expires
 }
 
-// Helper functions
+// signFileURL sets the download URL of a report's export, if it has one
+func (u *ReportUseCase) signFileURL(ctx context.Context, report *entity.Report) {
+	if report.FileKey == "" {
+		return
+	}
+	expires := time.Now().Add(u.fileURLTTL)
+	fileURL, err := u.files.URL(ctx, report.FileKey, u.fileURLTTL)
+	if err != nil {
+		logging.FromContext(ctx).Error("error signing report export URL", "report_id", report.ID, "error", err)
+		return
+	}
+	report.FileURL = fileURL
+	report.FileExpires = &expires
+}
 
-// generateReport generates the report data based on the report type
-func (u *ReportUseCase) generateReport(ctx context.Context, report *entity.Report) error {
+// fileSlug turns a name into a file name part of lowercase letters, digits and
+// dashes
+func fileSlug(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	slug := strings.TrimSuffix(b.String(), "-")
+	if len(slug) > 60 {
+		slug = strings.TrimSuffix(slug[:60], "-")
+	}
+	if slug == "" {
+		return "file"
+	}
+	return slug
+}
+
+// generateReport generates the report data based on the report type,
+// returning the number of rows
+func (u *ReportUseCase) generateReport(ctx context.Context, report *entity.Report) (int, error) {
+	data, err := u.reportData(ctx, report)
+	if err != nil {
+		return 0, err
+	}
+	_, rows := export.Table(data)
+
+	// Update report status to completed
+	report.Status = entity.ReportStatusCompleted
+	return len(rows), u.reportRepo.UpdateReport(ctx, report)
+}
+
+// reportData gets the data of a report based on its type: a slice of report
+// items, a single report, or nil for types without data of their own
+func (u *ReportUseCase) reportData(ctx context.Context, report *entity.Report) (interface{}, error) {
+	var data interface{}
 	var err error
 
 	switch report.Type {
@@ -387,29 +893,39 @@ func (u *ReportUseCase) generateReport(ctx context.Context, report *entity.Repor
 		warehouseID, _ := report.Parameters["warehouse_id"].(string)
 
 		if reportSubtype == "age" {
-			_, err = u.GetInventoryAgeReport(ctx, warehouseID, report.EndDate)
+			data, err = u.GetInventoryAgeReport(ctx, warehouseID, report.EndDate)
 		} else {
-			_, err = u.GetInventoryValueReport(ctx, warehouseID, report.EndDate)
+			data, err = u.GetInventoryValueReport(ctx, warehouseID, report.EndDate)
 		}
 
 	case entity.ReportTypeSales:
-		// Check if it's a product or customer sales report
+		// Check if it's a customer sales report or a gross margin report,
+		// grouped by product unless the parameters say otherwise
 		reportSubtype, ok := report.Parameters["subtype"]
 		if !ok {
 			reportSubtype = "product" // Default to product report
 		}
 
 		if reportSubtype == "customer" {
-			_, err = u.GetCustomerSalesReport(ctx, report.StartDate, report.EndDate)
+			data, err = u.GetCustomerSalesReport(ctx, report.StartDate, report.EndDate)
 		} else {
-			_, err = u.GetProductSalesReport(ctx, report.StartDate, report.EndDate)
+			groupBy, _ := report.Parameters["group_by"].(string)
+			var margin *entity.MarginReport
+			margin, err = u.marginUC.Report(ctx, &entity.MarginFilter{
+				StartDate: &report.StartDate,
+				EndDate:   &report.EndDate,
+				GroupBy:   entity.MarginDimension(groupBy),
+			})
+			if err == nil {
+				data = margin.Rows
+			}
 		}
 
 	case entity.ReportTypePurchase:
-		_, err = u.GetSupplierPurchaseReport(ctx, report.StartDate, report.EndDate)
+		data, err = u.GetSupplierPurchaseReport(ctx, report.StartDate, report.EndDate)
 
 	case entity.ReportTypeProfitAndLoss:
-		_, err = u.GetProfitAndLossReport(ctx, report.StartDate, report.EndDate)
+		data, err = u.GetProfitAndLossReport(ctx, report.StartDate, report.EndDate)
 
 	case entity.ReportTypeFinancial:
 		// Financial reports are handled by the finance use case
@@ -425,28 +941,34 @@ func (u *ReportUseCase) generateReport(ctx context.Context, report *entity.Repor
 	}
 
 	if err != nil {
-		return err
+		return nil, err
 	}
-
-	// Update report status to completed
-	report.Status = entity.ReportStatusCompleted
-	return u.reportRepo.UpdateReport(ctx, report)
+	return data, nil
 }
 
-// calculateNextRunTime calculates the next run time based on frequency
-func (u *ReportUseCase) calculateNextRunTime(from time.Time, frequency entity.ReportScheduleFrequency) time.Time {
+// calculateNextRunTime calculates the next run time based on frequency. Runs
+// falling outside working time on the company calendar move to the next
+// working time, so a daily schedule skips weekends and holidays.
+func (u *ReportUseCase) calculateNextRunTime(ctx context.Context, from time.Time, frequency entity.ReportScheduleFrequency) time.Time {
+	var next time.Time
 	switch frequency {
 	case entity.ReportScheduleDaily:
-		return from.AddDate(0, 0, 1)
+		next = from.AddDate(0, 0, 1)
 	case entity.ReportScheduleWeekly:
-		return from.AddDate(0, 0, 7)
+		next = from.AddDate(0, 0, 7)
 	case entity.ReportScheduleMonthly:
-		return from.AddDate(0, 1, 0)
+		next = from.AddDate(0, 1, 0)
 	case entity.ReportScheduleQuarterly:
-		return from.AddDate(0, 3, 0)
+		next = from.AddDate(0, 3, 0)
 	case entity.ReportScheduleYearly:
-		return from.AddDate(1, 0, 0)
+		next = from.AddDate(1, 0, 0)
 	default:
-		return from.AddDate(0, 1, 0) // Default to monthly
+		next = from.AddDate(0, 1, 0) // Default to monthly
+	}
+
+	working, err := u.calendarUC.NextWorkingTime(ctx, "", next)
+	if err != nil {
+		return next
 	}
+	return working
 }
@@ -0,0 +1,421 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
+)
+
+var (
+	ErrSalesOrderNotFound   = entity.NewError(entity.ErrCodeNotFound, "sales order not found")
+	ErrReturnOrderStatus    = entity.NewError(entity.ErrCodeFailedPrecondition, "only shipped, delivered or completed orders can take returns")
+	ErrReturnSKUNotOnOrder  = entity.NewError(entity.ErrCodeInvalidArgument, "the SKU is not on the sales order")
+	ErrReturnQuantity       = entity.NewError(entity.ErrCodeInvalidArgument, "returned quantity exceeds the quantity ordered")
+	ErrReturnStoreRequired  = entity.NewError(entity.ErrCodeInvalidArgument, "store_id is required to restock a return")
+	ErrInvalidReturnsGroup  = entity.NewError(entity.ErrCodeInvalidArgument, "group_by must be SKU, LOT, VENDOR or REASON")
+	ErrInvalidReturnsPeriod = entity.NewError(entity.ErrCodeInvalidArgument, "start date must not be after end date")
+)
+
+// defaultReturnsReportDays is the period of the returns and quality report when no start date is given
+const defaultReturnsReportDays = 90
+
+// ReturnsUseCase records sales returns and correlates them with quality
+// inspection rejections, flagging SKUs returned too often for engineering review
+type ReturnsUseCase struct {
+	returnsRepo  *repository.ReturnsRepository
+	orderRepo    *repository.OrderRepository
+	stocksRepo   *repository.StocksRepository
+	forecastRepo *repository.ForecastRepository
+	qualityUC    *QualityUseCase
+	threshold    float64
+}
+
+// NewReturnsUseCase creates a new returns use case. SKUs whose return rate in
+// percent exceeds the threshold are flagged for engineering review.
+func NewReturnsUseCase(returnsRepo *repository.ReturnsRepository, orderRepo *repository.OrderRepository, stocksRepo *repository.StocksRepository, forecastRepo *repository.ForecastRepository, qualityUC *QualityUseCase, threshold float64) *ReturnsUseCase {
+	return &ReturnsUseCase{
+		returnsRepo:  returnsRepo,
+		orderRepo:    orderRepo,
+		stocksRepo:   stocksRepo,
+		forecastRepo: forecastRepo,
+		qualityUC:    qualityUC,
+		threshold:    threshold,
+	}
+}
+
+// RecordReturn records goods a client sent back against a shipped sales order.
+// The reason must be an active defect code and the SKU's returns cannot exceed
+// the quantity ordered. Restocked goods are booked into the store and held for
+// inspection when the SKU's plan inspects receipts.
+func (u *ReturnsUseCase) RecordReturn(ctx context.Context, orderID string, req *entity.SalesReturnRequest, userID string) (*entity.SalesReturn, error) {
+	order, err := u.orderRepo.GetSalesOrderByID(ctx, orderID)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, ErrSalesOrderNotFound
+		}
+		return nil, fmt.Errorf("error getting sales order: %w", err)
+	}
+	switch order.Status {
+	case entity.SalesOrderStatusShipped, entity.SalesOrderStatusDelivered, entity.SalesOrderStatusCompleted:
+	default:
+		return nil, ErrReturnOrderStatus
+	}
+	if req.Restock && req.StoreID == "" {
+		return nil, ErrReturnStoreRequired
+	}
+
+	var ordered float64
+	for _, item := range order.Items {
+		if item.SKUID == req.SKUID {
+			ordered += item.Quantity
+		}
+	}
+	if ordered == 0 {
+		return nil, ErrReturnSKUNotOnOrder
+	}
+
+	previous, err := u.returnsRepo.ListByOrder(ctx, order.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing sales returns: %w", err)
+	}
+	returned := req.Quantity
+	for _, ret := range previous {
+		if ret.SKUID == req.SKUID {
+			returned += ret.Quantity
+		}
+	}
+	if roundAmount(returned) > roundAmount(ordered) {
+		return nil, ErrReturnQuantity
+	}
+
+	reasonCode, err := u.qualityUC.checkDefectCode(ctx, req.ReasonCode)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := &entity.SalesReturn{
+		SalesOrderID: order.ID,
+		ClientID:     order.ClientID,
+		SKUID:        req.SKUID,
+		LotNumber:    req.LotNumber,
+		Quantity:     req.Quantity,
+		ReasonCode:   reasonCode,
+		Notes:        req.Notes,
+		Restocked:    req.Restock,
+	}
+	if createdBy, err := parseUserID(userID); err == nil {
+		ret.CreatedBy = &createdBy
+	}
+
+	if req.Restock {
+		ret.StoreID = req.StoreID
+		entry := &entity.StockEntry{
+			StoreID:   req.StoreID,
+			SKUID:     req.SKUID,
+			Type:      "IN",
+			Quantity:  req.Quantity,
+			LotNumber: req.LotNumber,
+			Reference: order.OrderNumber,
+			Note:      "Sales return",
+			CreatedBy: userID,
+		}
+		if err := u.stocksRepo.ProcessStockEntry(ctx, entry, userID); err != nil {
+			return nil, fmt.Errorf("error restocking sales return: %w", err)
+		}
+
+		// Returned goods stay in quarantine until they pass inspection
+		if _, err := u.qualityUC.Hold(ctx, entity.InspectionSourceReturn, req.StoreID, order.OrderNumber,
+			map[string]float64{req.SKUID: req.Quantity}); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := u.returnsRepo.Create(ctx, ret); err != nil {
+		return nil, fmt.Errorf("error recording sales return: %w", err)
+	}
+	return ret, nil
+}
+
+// ListOrderReturns lists the returns of a sales order
+func (u *ReturnsUseCase) ListOrderReturns(ctx context.Context, orderID string) ([]entity.SalesReturn, error) {
+	returns, err := u.returnsRepo.ListByOrder(ctx, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing sales returns: %w", err)
+	}
+	return returns, nil
+}
+
+// Report sets the sales returns of the period against the quality inspection
+// rejections recorded in it, grouped by SKU, lot, vendor or reason code:
+//   - sold quantities count the sales orders placed in the period, for SKU and vendor rows
+//   - inspections count towards the vendor of their purchase receipt, other
+//     inspections and returns towards the SKU's designated vendor
+//   - lots are the return lot numbers and the inspection references
+//
+// Only rows with returns or inspections are reported. SKU rows whose return rate
+// exceeds the threshold are flagged.
+func (u *ReturnsUseCase) Report(ctx context.Context, filter *entity.ReturnsQualityFilter) (*entity.ReturnsQualityReport, error) {
+	groupBy := filter.GroupBy
+	if groupBy == "" {
+		groupBy = entity.ReturnsGroupBySKU
+	}
+	switch groupBy {
+	case entity.ReturnsGroupBySKU, entity.ReturnsGroupByLot, entity.ReturnsGroupByVendor, entity.ReturnsGroupByReason:
+	default:
+		return nil, ErrInvalidReturnsGroup
+	}
+
+	now := time.Now()
+	end := now
+	if filter.EndDate != nil {
+		end = *filter.EndDate
+	}
+	start := truncateDay(end).AddDate(0, 0, -defaultReturnsReportDays)
+	if filter.StartDate != nil {
+		start = *filter.StartDate
+	}
+	if start.After(end) {
+		return nil, ErrInvalidReturnsPeriod
+	}
+
+	returns, err := u.returnsRepo.ListReturns(ctx, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("error listing sales returns: %w", err)
+	}
+	inspections, err := u.returnsRepo.ListClosedInspections(ctx, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("error listing quality inspections: %w", err)
+	}
+	// The sales history excludes its end, the report period does not
+	var orders []entity.SalesOrder
+	if groupBy == entity.ReturnsGroupBySKU || groupBy == entity.ReturnsGroupByVendor {
+		orders, err = u.forecastRepo.ListSalesHistory(ctx, start, end.Add(time.Nanosecond))
+		if err != nil {
+			return nil, fmt.Errorf("error getting sales history: %w", err)
+		}
+	}
+
+	skuIDs := make(map[string]bool)
+	var receiptNumbers []string
+	for _, ret := range returns {
+		skuIDs[ret.SKUID] = true
+	}
+	for _, inspection := range inspections {
+		skuIDs[inspection.SKUID] = true
+		if inspection.Source == entity.InspectionSourceReceipt {
+			receiptNumbers = append(receiptNumbers, inspection.Reference)
+		}
+	}
+	for _, order := range orders {
+		for _, item := range order.Items {
+			skuIDs[item.SKUID] = true
+		}
+	}
+	ids := make([]string, 0, len(skuIDs))
+	for skuID := range skuIDs {
+		ids = append(ids, skuID)
+	}
+
+	skus, err := u.returnsRepo.GetSKUs(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("error getting SKUs: %w", err)
+	}
+	receiptVendors, err := u.returnsRepo.GetReceiptVendors(ctx, receiptNumbers)
+	if err != nil {
+		return nil, fmt.Errorf("error getting receipt vendors: %w", err)
+	}
+
+	rows := make(map[string]*entity.ReturnsQualityRow)
+	row := func(key string) *entity.ReturnsQualityRow {
+		r, ok := rows[key]
+		if !ok {
+			r = &entity.ReturnsQualityRow{Key: key, Reasons: make(map[string]float64)}
+			rows[key] = r
+		}
+		return r
+	}
+	// keyOf returns the row key of a SKU, lot and vendor, or false when the
+	// grouping has no value for it
+	keyOf := func(skuID, lot string, vendorID *uint) (string, bool) {
+		switch groupBy {
+		case entity.ReturnsGroupByLot:
+			return skuID + "|" + lot, lot != ""
+		case entity.ReturnsGroupByVendor:
+			if vendorID == nil {
+				return "", false
+			}
+			return strconv.FormatUint(uint64(*vendorID), 10), true
+		default:
+			return skuID, true
+		}
+	}
+	skuVendor := func(skuID string) *uint {
+		return skus[skuID].VendorID
+	}
+
+	active := make(map[string]bool)
+	for _, ret := range returns {
+		if groupBy == entity.ReturnsGroupByReason {
+			r := row(ret.ReasonCode)
+			r.ReturnedQuantity += ret.Quantity
+			active[r.Key] = true
+			continue
+		}
+		key, ok := keyOf(ret.SKUID, ret.LotNumber, skuVendor(ret.SKUID))
+		if !ok {
+			continue
+		}
+		r := row(key)
+		r.SKUID = ret.SKUID
+		r.ReturnedQuantity += ret.Quantity
+		r.Reasons[ret.ReasonCode] += ret.Quantity
+		active[key] = true
+	}
+
+	for _, inspection := range inspections {
+		if groupBy == entity.ReturnsGroupByReason {
+			for _, defect := range inspection.Defects {
+				r := row(defect.Code)
+				r.RejectedQuantity += defect.Quantity
+				active[r.Key] = true
+			}
+			continue
+		}
+		vendorID := skuVendor(inspection.SKUID)
+		if id, ok := receiptVendors[inspection.Reference]; ok && inspection.Source == entity.InspectionSourceReceipt {
+			vendorID = &id
+		}
+		key, ok := keyOf(inspection.SKUID, inspection.Reference, vendorID)
+		if !ok {
+			continue
+		}
+		r := row(key)
+		r.SKUID = inspection.SKUID
+		r.InspectedQuantity += inspection.Quantity
+		r.RejectedQuantity += inspection.RejectedQuantity
+		for _, defect := range inspection.Defects {
+			r.Reasons[defect.Code] += defect.Quantity
+		}
+		active[key] = true
+	}
+
+	for _, order := range orders {
+		for _, item := range order.Items {
+			if key, ok := keyOf(item.SKUID, "", skuVendor(item.SKUID)); ok && active[key] {
+				rows[key].SoldQuantity += item.Quantity
+			}
+		}
+	}
+
+	names, err := u.rowNames(ctx, groupBy, rows, skus)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &entity.ReturnsQualityReport{
+		GroupBy:     groupBy,
+		StartDate:   start,
+		EndDate:     end,
+		Threshold:   u.threshold,
+		Rows:        make([]entity.ReturnsQualityRow, 0, len(active)),
+		GeneratedAt: now,
+	}
+	for key := range active {
+		r := rows[key]
+		if groupBy == entity.ReturnsGroupByLot {
+			r.Key = key[len(r.SKUID)+1:]
+		} else {
+			r.SKUID = ""
+		}
+		r.Name = names[key]
+		r.SoldQuantity = roundAmount(r.SoldQuantity)
+		r.ReturnedQuantity = roundAmount(r.ReturnedQuantity)
+		r.InspectedQuantity = roundAmount(r.InspectedQuantity)
+		r.RejectedQuantity = roundAmount(r.RejectedQuantity)
+		if r.SoldQuantity > 0 {
+			r.ReturnRate = roundAmount(r.ReturnedQuantity / r.SoldQuantity * 100)
+		}
+		if r.InspectedQuantity > 0 {
+			r.RejectionRate = roundAmount(r.RejectedQuantity / r.InspectedQuantity * 100)
+		}
+		if len(r.Reasons) == 0 {
+			r.Reasons = nil
+		}
+		r.Flagged = groupBy == entity.ReturnsGroupBySKU && r.ReturnRate > u.threshold
+		report.Rows = append(report.Rows, *r)
+	}
+
+	sort.Slice(report.Rows, func(i, j int) bool {
+		a, b := report.Rows[i], report.Rows[j]
+		if qa, qb := a.ReturnedQuantity+a.RejectedQuantity, b.ReturnedQuantity+b.RejectedQuantity; qa != qb {
+			return qa > qb
+		}
+		if a.Key != b.Key {
+			return a.Key < b.Key
+		}
+		return a.SKUID < b.SKUID
+	})
+	return report, nil
+}
+
+// rowNames names the report rows after their SKU, vendor or defect code
+func (u *ReturnsUseCase) rowNames(ctx context.Context, groupBy entity.ReturnsGroupBy, rows map[string]*entity.ReturnsQualityRow, skus map[string]entity.SKU) (map[string]string, error) {
+	names := make(map[string]string, len(rows))
+	switch groupBy {
+	case entity.ReturnsGroupByVendor:
+		vendorIDs := make([]uint, 0, len(rows))
+		for key := range rows {
+			if id, err := strconv.ParseUint(key, 10, 32); err == nil {
+				vendorIDs = append(vendorIDs, uint(id))
+			}
+		}
+		vendors, err := u.returnsRepo.GetVendorNames(ctx, vendorIDs)
+		if err != nil {
+			return nil, fmt.Errorf("error getting vendors: %w", err)
+		}
+		for id, name := range vendors {
+			names[strconv.FormatUint(uint64(id), 10)] = name
+		}
+	case entity.ReturnsGroupByReason:
+		codes, err := u.qualityUC.ListDefectCodes(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, code := range codes {
+			names[code.Code] = code.Description
+		}
+	default:
+		for key, r := range rows {
+			names[key] = skus[r.SKUID].Name
+		}
+	}
+	return names, nil
+}
+
+// Review lists the SKUs whose return rate in the period exceeds the threshold,
+// highest return rate first, for engineering review
+func (u *ReturnsUseCase) Review(ctx context.Context, filter *entity.ReturnsQualityFilter) (*entity.ReturnsQualityReport, error) {
+	filter.GroupBy = entity.ReturnsGroupBySKU
+	report, err := u.Report(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	flagged := make([]entity.ReturnsQualityRow, 0)
+	for _, r := range report.Rows {
+		if r.Flagged {
+			flagged = append(flagged, r)
+		}
+	}
+	sort.SliceStable(flagged, func(i, j int) bool {
+		return flagged[i].ReturnRate > flagged[j].ReturnRate
+	})
+	report.Rows = flagged
+	return report, nil
+}
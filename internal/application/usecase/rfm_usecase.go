@@ -0,0 +1,164 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
+)
+
+// rfmSegments lists the RFM segments from best to worst
+var rfmSegments = []entity.RFMSegment{
+	entity.RFMSegmentChampions,
+	entity.RFMSegmentLoyal,
+	entity.RFMSegmentPotentialLoyalist,
+	entity.RFMSegmentNew,
+	entity.RFMSegmentNeedsAttention,
+	entity.RFMSegmentAtRisk,
+	entity.RFMSegmentHibernating,
+	entity.RFMSegmentLost,
+}
+
+// RFMUseCase scores clients by the recency, frequency and monetary value of their orders
+type RFMUseCase struct {
+	rfmRepo        *repository.RFMRepository
+	lookbackMonths int
+}
+
+// NewRFMUseCase creates a new RFM use case. Only orders placed within the last
+// lookbackMonths count towards the scores.
+func NewRFMUseCase(rfmRepo *repository.RFMRepository, lookbackMonths int) *RFMUseCase {
+	if lookbackMonths <= 0 {
+		lookbackMonths = 24
+	}
+	return &RFMUseCase{
+		rfmRepo:        rfmRepo,
+		lookbackMonths: lookbackMonths,
+	}
+}
+
+// Run scores every client that ordered within the lookback window and replaces the
+// stored scores. Clients without orders in the window are left unscored.
+func (u *RFMUseCase) Run(ctx context.Context) (*entity.RFMRunResult, error) {
+	now := time.Now()
+	since := truncateDay(now).AddDate(0, -u.lookbackMonths, 0)
+
+	summaries, err := u.rfmRepo.ListClientSales(ctx, since)
+	if err != nil {
+		return nil, fmt.Errorf("error summarizing client sales: %w", err)
+	}
+
+	recency := make([]float64, len(summaries))
+	frequency := make([]float64, len(summaries))
+	monetary := make([]float64, len(summaries))
+	for i, s := range summaries {
+		// Fewer days since the last order is better, so rank on the negated value
+		recency[i] = -float64(daysBetween(s.LastOrderDate, now))
+		frequency[i] = float64(s.OrderCount)
+		monetary[i] = s.MonetaryValue
+	}
+	recencyScores := quintileScores(recency)
+	frequencyScores := quintileScores(frequency)
+	monetaryScores := quintileScores(monetary)
+
+	scores := make([]entity.ClientRFMScore, len(summaries))
+	counts := make(map[entity.RFMSegment]*entity.RFMSegmentSummary)
+	for i, s := range summaries {
+		score := entity.ClientRFMScore{
+			ClientID:       s.ClientID,
+			LastOrderDate:  s.LastOrderDate,
+			RecencyDays:    daysBetween(s.LastOrderDate, now),
+			OrderCount:     s.OrderCount,
+			MonetaryValue:  roundAmount(s.MonetaryValue),
+			RecencyScore:   recencyScores[i],
+			FrequencyScore: frequencyScores[i],
+			MonetaryScore:  monetaryScores[i],
+			ComputedAt:     now,
+		}
+		score.Score = fmt.Sprintf("%d%d%d", score.RecencyScore, score.FrequencyScore, score.MonetaryScore)
+		score.Segment = rfmSegment(score.RecencyScore, score.FrequencyScore)
+		scores[i] = score
+
+		summary, ok := counts[score.Segment]
+		if !ok {
+			summary = &entity.RFMSegmentSummary{Segment: score.Segment}
+			counts[score.Segment] = summary
+		}
+		summary.Clients++
+		summary.MonetaryValue = roundAmount(summary.MonetaryValue + score.MonetaryValue)
+	}
+
+	if err := u.rfmRepo.ReplaceScores(ctx, scores); err != nil {
+		return nil, fmt.Errorf("error saving RFM scores: %w", err)
+	}
+
+	result := &entity.RFMRunResult{
+		Since:      since,
+		Scored:     len(scores),
+		Segments:   []entity.RFMSegmentSummary{},
+		ComputedAt: now,
+	}
+	for _, segment := range rfmSegments {
+		if summary, ok := counts[segment]; ok {
+			result.Segments = append(result.Segments, *summary)
+		}
+	}
+	return result, nil
+}
+
+// ListScores lists client RFM scores
+func (u *RFMUseCase) ListScores(ctx context.Context, filter entity.RFMFilter) ([]entity.ClientRFMScore, error) {
+	scores, err := u.rfmRepo.ListScores(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("error listing RFM scores: %w", err)
+	}
+	return scores, nil
+}
+
+// quintileScores ranks the values and scores each from 1 (lowest fifth) to 5
+// (highest fifth). Equal values share the score of the first of them.
+func quintileScores(values []float64) []int {
+	n := len(values)
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return values[order[a]] < values[order[b]]
+	})
+
+	scores := make([]int, n)
+	for pos, i := range order {
+		if pos > 0 && values[i] == values[order[pos-1]] {
+			scores[i] = scores[order[pos-1]]
+			continue
+		}
+		scores[i] = pos*5/n + 1
+	}
+	return scores
+}
+
+// rfmSegment names the segment of a client from its recency and frequency scores
+func rfmSegment(recency, frequency int) entity.RFMSegment {
+	switch {
+	case recency >= 4 && frequency >= 4:
+		return entity.RFMSegmentChampions
+	case recency >= 4 && frequency >= 2:
+		return entity.RFMSegmentPotentialLoyalist
+	case recency >= 4:
+		return entity.RFMSegmentNew
+	case recency == 3 && frequency >= 4:
+		return entity.RFMSegmentLoyal
+	case recency == 3:
+		return entity.RFMSegmentNeedsAttention
+	case frequency >= 3:
+		return entity.RFMSegmentAtRisk
+	case recency == 2:
+		return entity.RFMSegmentHibernating
+	default:
+		return entity.RFMSegmentLost
+	}
+}
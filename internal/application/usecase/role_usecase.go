@@ -1,6 +1,7 @@
 package usecase
 
 import (
+	"github.com/lib/pq"
 	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
 )
 
@@ -15,12 +16,14 @@ func NewRoleUseCase(repo entity.RoleRepository) *RoleUseCase {
 type CreateRoleInput struct {
 	Name        string
 	Permissions []entity.Permission
+	Scope       entity.AccessScope
 }
 
 type UpdateRoleInput struct {
 	ID          uint
 	Name        string
 	Permissions []entity.Permission
+	Scope       entity.AccessScope
 }
 
 func (uc *RoleUseCase) CreateRole(input *CreateRoleInput) (*entity.Role, error) {
@@ -28,6 +31,7 @@ func (uc *RoleUseCase) CreateRole(input *CreateRoleInput) (*entity.Role, error)
 		Name:        input.Name,
 		Permissions: input.Permissions,
 	}
+	setRoleScope(role, input.Scope)
 
 	if err := uc.roleRepo.Create(role); err != nil {
 		return nil, err
@@ -44,6 +48,7 @@ func (uc *RoleUseCase) UpdateRole(input *UpdateRoleInput) (*entity.Role, error)
 
 	role.Name = input.Name
 	role.Permissions = input.Permissions
+	setRoleScope(role, input.Scope)
 
 	if err := uc.roleRepo.Update(role); err != nil {
 		return nil, err
@@ -52,6 +57,15 @@ func (uc *RoleUseCase) UpdateRole(input *UpdateRoleInput) (*entity.Role, error)
 	return role, nil
 }
 
+// setRoleScope limits the role's users to the stores and departments of scope
+func setRoleScope(role *entity.Role, scope entity.AccessScope) {
+	role.StoreIDs = pq.StringArray(scope.StoreIDs)
+	role.DepartmentIDs = nil
+	for _, id := range scope.DepartmentIDs {
+		role.DepartmentIDs = append(role.DepartmentIDs, int64(id))
+	}
+}
+
 func (uc *RoleUseCase) GetRoleByID(id uint) (*entity.Role, error) {
 	return uc.roleRepo.FindByID(id)
 }
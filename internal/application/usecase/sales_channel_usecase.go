@@ -0,0 +1,532 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/ecommerce"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/logging"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
+)
+
+var (
+	ErrSalesChannelNotFound       = entity.NewError(entity.ErrCodeNotFound, "sales channel not found")
+	ErrSalesChannelInactive       = entity.NewError(entity.ErrCodeFailedPrecondition, "sales channel is inactive")
+	ErrSalesChannelClient         = entity.NewError(entity.ErrCodeNotFound, "client of the sales channel not found")
+	ErrChannelSKUMappingNotFound  = entity.NewError(entity.ErrCodeNotFound, "channel SKU mapping not found")
+	ErrChannelSKUUnmapped         = entity.NewError(entity.ErrCodeNotFound, "no SKU is mapped to the listing")
+	ErrChannelWarehouseDuplicated = entity.NewError(entity.ErrCodeInvalidArgument, "a store is linked to a sales channel once")
+)
+
+// syncRunHistory is the number of sync runs listed for a sales channel
+const syncRunHistory = 50
+
+// SalesChannelUseCase connects the web shops of e-commerce platforms: their
+// orders are ingested as draft sales orders and the stock available in the
+// stores linked to them is pushed back as their inventory levels. The
+// platforms are reached through the connectors of the ecommerce package.
+type SalesChannelUseCase struct {
+	repo       *repository.SalesChannelRepository
+	storeRepo  *repository.StoreRepository
+	skuRepo    *repository.SKURepository
+	clientRepo entity.ClientRepository
+	orderUC    *OrderUseCase
+}
+
+// NewSalesChannelUseCase creates a new sales channel use case
+func NewSalesChannelUseCase(repo *repository.SalesChannelRepository, storeRepo *repository.StoreRepository, skuRepo *repository.SKURepository, clientRepo entity.ClientRepository, orderUC *OrderUseCase) *SalesChannelUseCase {
+	return &SalesChannelUseCase{
+		repo:       repo,
+		storeRepo:  storeRepo,
+		skuRepo:    skuRepo,
+		clientRepo: clientRepo,
+		orderUC:    orderUC,
+	}
+}
+
+// CreateChannel creates a sales channel, checking that its platform has a
+// connector and that the credentials it needs are given
+func (u *SalesChannelUseCase) CreateChannel(ctx context.Context, req *entity.SalesChannelRequest) (*entity.SalesChannel, error) {
+	channel := &entity.SalesChannel{PushInventory: true, Active: true}
+	if err := u.applyChannel(ctx, channel, req); err != nil {
+		return nil, err
+	}
+
+	if err := u.repo.CreateChannel(ctx, channel); err != nil {
+		return nil, fmt.Errorf("error creating sales channel: %w", err)
+	}
+	return channel, nil
+}
+
+// UpdateChannel updates a sales channel. Credentials left empty are kept.
+func (u *SalesChannelUseCase) UpdateChannel(ctx context.Context, id uint, req *entity.SalesChannelRequest) (*entity.SalesChannel, error) {
+	channel, err := u.GetChannel(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := u.applyChannel(ctx, channel, req); err != nil {
+		return nil, err
+	}
+
+	if err := u.repo.UpdateChannel(ctx, channel); err != nil {
+		return nil, fmt.Errorf("error updating sales channel: %w", err)
+	}
+	return channel, nil
+}
+
+// applyChannel validates a sales channel request and copies it onto a channel
+func (u *SalesChannelUseCase) applyChannel(ctx context.Context, channel *entity.SalesChannel, req *entity.SalesChannelRequest) error {
+	if err := u.checkStore(ctx, req.StoreID); err != nil {
+		return err
+	}
+	if _, err := u.clientRepo.FindByID(req.ClientID); err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return ErrSalesChannelClient
+		}
+		return err
+	}
+
+	channel.Code = req.Code
+	channel.Name = req.Name
+	channel.Platform = strings.ToUpper(req.Platform)
+	channel.BaseURL = req.BaseURL
+	channel.ClientID = req.ClientID
+	channel.StoreID = req.StoreID
+	channel.CurrencyCode = req.CurrencyCode
+	if req.APIKey != "" {
+		channel.APIKey = req.APIKey
+	}
+	if req.APISecret != "" {
+		channel.APISecret = req.APISecret
+	}
+	if req.AccessToken != "" {
+		channel.AccessToken = req.AccessToken
+	}
+	if req.PushInventory != nil {
+		channel.PushInventory = *req.PushInventory
+	}
+	if req.Active != nil {
+		channel.Active = *req.Active
+	}
+
+	if _, err := ecommerce.NewConnector(channel); err != nil {
+		return entity.WrapError(entity.ErrCodeInvalidArgument, err)
+	}
+	return nil
+}
+
+// checkStore checks that a store exists and is in the access scope
+func (u *SalesChannelUseCase) checkStore(ctx context.Context, storeID string) error {
+	if err := entity.AccessScopeFromContext(ctx).CheckStore(storeID); err != nil {
+		return err
+	}
+	_, err := u.storeRepo.GetByID(ctx, storeID)
+	return err
+}
+
+// GetChannel retrieves a sales channel with its linked stores
+func (u *SalesChannelUseCase) GetChannel(ctx context.Context, id uint) (*entity.SalesChannel, error) {
+	channel, err := u.repo.GetChannel(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, ErrSalesChannelNotFound
+		}
+		return nil, fmt.Errorf("error getting sales channel: %w", err)
+	}
+	return channel, nil
+}
+
+// ListChannels lists the sales channels
+func (u *SalesChannelUseCase) ListChannels(ctx context.Context) ([]entity.SalesChannel, error) {
+	channels, err := u.repo.ListChannels(ctx, false)
+	if err != nil {
+		return nil, fmt.Errorf("error listing sales channels: %w", err)
+	}
+	return channels, nil
+}
+
+// SetWarehouses replaces the stores linked to a sales channel and the
+// locations they mirror
+func (u *SalesChannelUseCase) SetWarehouses(ctx context.Context, id uint, req *entity.SalesChannelWarehousesRequest) (*entity.SalesChannel, error) {
+	channel, err := u.GetChannel(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(req.Warehouses))
+	warehouses := make([]entity.SalesChannelWarehouse, 0, len(req.Warehouses))
+	for _, w := range req.Warehouses {
+		if seen[w.StoreID] {
+			return nil, ErrChannelWarehouseDuplicated
+		}
+		seen[w.StoreID] = true
+		if err := u.checkStore(ctx, w.StoreID); err != nil {
+			return nil, err
+		}
+		warehouses = append(warehouses, entity.SalesChannelWarehouse{
+			ChannelID:  channel.ID,
+			StoreID:    w.StoreID,
+			LocationID: w.LocationID,
+		})
+	}
+
+	if err := u.repo.ReplaceWarehouses(ctx, channel.ID, warehouses); err != nil {
+		return nil, fmt.Errorf("error linking stores to sales channel: %w", err)
+	}
+	channel.Warehouses = warehouses
+	return channel, nil
+}
+
+// SaveMapping maps a SKU to a listing of a sales channel, replacing the
+// mapping of the listing's external SKU
+func (u *SalesChannelUseCase) SaveMapping(ctx context.Context, channelID uint, req *entity.ChannelSKUMappingRequest) (*entity.ChannelSKUMapping, error) {
+	if _, err := u.GetChannel(ctx, channelID); err != nil {
+		return nil, err
+	}
+	sku, err := u.skuRepo.GetSKUByID(ctx, req.SKUID)
+	if err != nil {
+		return nil, ErrSKUNotFound
+	}
+
+	mapping := &entity.ChannelSKUMapping{
+		ChannelID:   channelID,
+		SKUID:       req.SKUID,
+		ExternalSKU: req.ExternalSKU,
+		ExternalID:  req.ExternalID,
+	}
+	if err := u.repo.SaveMapping(ctx, mapping); err != nil {
+		return nil, fmt.Errorf("error saving channel SKU mapping: %w", err)
+	}
+	mapping.SKU = sku
+	return mapping, nil
+}
+
+// DeleteMapping deletes a SKU mapping of a sales channel
+func (u *SalesChannelUseCase) DeleteMapping(ctx context.Context, channelID, id uint) error {
+	if err := u.repo.DeleteMapping(ctx, channelID, id); err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return ErrChannelSKUMappingNotFound
+		}
+		return fmt.Errorf("error deleting channel SKU mapping: %w", err)
+	}
+	return nil
+}
+
+// ListMappings lists the SKU mappings of a sales channel
+func (u *SalesChannelUseCase) ListMappings(ctx context.Context, channelID uint) ([]entity.ChannelSKUMapping, error) {
+	if _, err := u.GetChannel(ctx, channelID); err != nil {
+		return nil, err
+	}
+	mappings, err := u.repo.ListMappings(ctx, channelID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing channel SKU mappings: %w", err)
+	}
+	return mappings, nil
+}
+
+// ListSyncRuns lists the latest sync runs of a sales channel
+func (u *SalesChannelUseCase) ListSyncRuns(ctx context.Context, channelID uint) ([]entity.ChannelSyncRun, error) {
+	if _, err := u.GetChannel(ctx, channelID); err != nil {
+		return nil, err
+	}
+	runs, err := u.repo.ListSyncRuns(ctx, channelID, syncRunHistory)
+	if err != nil {
+		return nil, fmt.Errorf("error listing channel sync runs: %w", err)
+	}
+	return runs, nil
+}
+
+// connect returns the connector of an active sales channel
+func (u *SalesChannelUseCase) connect(ctx context.Context, id uint) (*entity.SalesChannel, ecommerce.Connector, error) {
+	channel, err := u.GetChannel(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !channel.Active {
+		return nil, nil, ErrSalesChannelInactive
+	}
+	connector, err := ecommerce.NewConnector(channel)
+	if err != nil {
+		return nil, nil, entity.WrapError(entity.ErrCodeFailedPrecondition, err)
+	}
+	return channel, connector, nil
+}
+
+// SyncOrders ingests the orders created on a sales channel since the last
+// sync as draft sales orders created by the given user. Each order is
+// fulfilled from the store linked to its location, or the channel's store,
+// and its lines are matched to SKUs by the channel's mappings, or by SKU
+// code. Orders already ingested are left as they are, and an order that
+// fails is fetched again on the next sync.
+func (u *SalesChannelUseCase) SyncOrders(ctx context.Context, id uint, userID uint, triggeredBy string) (*entity.ChannelSyncRun, error) {
+	channel, connector, err := u.connect(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	run := &entity.ChannelSyncRun{
+		ChannelID:   channel.ID,
+		Kind:        entity.ChannelSyncOrders,
+		TriggeredBy: triggeredBy,
+		StartedAt:   time.Now(),
+	}
+	orders, err := connector.FetchOrders(ctx, channel.OrdersSyncedAt)
+	if err != nil {
+		return u.finishRun(ctx, run, []string{err.Error()}, false)
+	}
+
+	skuIDs, err := u.orderSKUs(ctx, channel.ID, orders)
+	if err != nil {
+		return nil, err
+	}
+	stores := make(map[string]string, len(channel.Warehouses))
+	for _, w := range channel.Warehouses {
+		if w.LocationID != "" {
+			stores[w.LocationID] = w.StoreID
+		}
+	}
+
+	var failures []string
+	var latest, firstFailed *time.Time
+	for i := range orders {
+		order := &orders[i]
+		external, err := channelSalesOrder(channel, order, skuIDs, stores)
+		if err == nil {
+			_, err = u.orderUC.IngestExternalOrder(ctx, external, userID)
+		}
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("order %s: %v", order.Number, err))
+			if firstFailed == nil || order.CreatedAt.Before(*firstFailed) {
+				firstFailed = &order.CreatedAt
+			}
+			continue
+		}
+		run.Processed++
+		if latest == nil || order.CreatedAt.After(*latest) {
+			latest = &order.CreatedAt
+		}
+	}
+
+	next := latest
+	if firstFailed != nil {
+		next = firstFailed
+	}
+	if next != nil {
+		if err := u.repo.SetOrdersSyncedAt(ctx, channel.ID, *next); err != nil {
+			return nil, fmt.Errorf("error recording sales channel sync: %w", err)
+		}
+	}
+	return u.finishRun(ctx, run, failures, true)
+}
+
+// orderSKUs returns the IDs of the SKUs the lines of channel orders are for,
+// by external SKU. Listings without a mapping are matched by SKU code.
+func (u *SalesChannelUseCase) orderSKUs(ctx context.Context, channelID uint, orders []entity.ChannelOrder) (map[string]string, error) {
+	mappings, err := u.repo.ListMappings(ctx, channelID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing channel SKU mappings: %w", err)
+	}
+	skuIDs := make(map[string]string, len(mappings))
+	for _, mapping := range mappings {
+		skuIDs[mapping.ExternalSKU] = mapping.SKUID
+	}
+
+	var unmapped []string
+	for _, order := range orders {
+		for _, line := range order.Lines {
+			if _, ok := skuIDs[line.ExternalSKU]; !ok && line.ExternalSKU != "" {
+				unmapped = append(unmapped, line.ExternalSKU)
+			}
+		}
+	}
+	byCode, err := u.repo.SKUIDsByCode(ctx, unmapped)
+	if err != nil {
+		return nil, fmt.Errorf("error matching SKU codes: %w", err)
+	}
+	for code, id := range byCode {
+		skuIDs[code] = id
+	}
+	return skuIDs, nil
+}
+
+// channelSalesOrder converts a channel order to the external sales order it
+// is ingested as
+func channelSalesOrder(channel *entity.SalesChannel, order *entity.ChannelOrder, skuIDs, stores map[string]string) (*entity.ExternalSalesOrder, error) {
+	items := make([]entity.SalesOrderItem, 0, len(order.Lines))
+	for _, line := range order.Lines {
+		skuID, ok := skuIDs[line.ExternalSKU]
+		if !ok {
+			return nil, fmt.Errorf("%w %q (%s)", ErrChannelSKUUnmapped, line.ExternalSKU, line.Title)
+		}
+		item := entity.SalesOrderItem{
+			SKUID:       skuID,
+			Quantity:    line.Quantity,
+			UnitPrice:   line.UnitPrice,
+			Description: line.Title,
+		}
+		if gross := line.Quantity * line.UnitPrice; gross > 0 {
+			item.Discount = line.Discount / gross * 100
+		}
+		items = append(items, item)
+	}
+
+	storeID := channel.StoreID
+	if linked, ok := stores[order.LocationID]; ok {
+		storeID = linked
+	}
+	currency := order.CurrencyCode
+	if currency == "" {
+		currency = channel.CurrencyCode
+	}
+	notes := fmt.Sprintf("%s order %s", channel.Name, order.Number)
+	if order.Notes != "" {
+		notes += ": " + order.Notes
+	}
+
+	return &entity.ExternalSalesOrder{
+		Source:          channel.Code,
+		Reference:       order.Reference,
+		ClientID:        channel.ClientID,
+		StoreID:         storeID,
+		Items:           items,
+		CurrencyCode:    currency,
+		ShippingAddress: order.ShippingAddress,
+		BillingAddress:  order.BillingAddress,
+		Notes:           notes,
+	}, nil
+}
+
+// PushInventory pushes the stock available in the stores linked to a sales
+// channel as the inventory levels of its mapped listings. The stores linked
+// to one location add up; a channel without linked stores mirrors its own
+// store. Listings without an external ID are not pushed.
+func (u *SalesChannelUseCase) PushInventory(ctx context.Context, id uint, triggeredBy string) (*entity.ChannelSyncRun, error) {
+	channel, connector, err := u.connect(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	mappings, err := u.repo.ListMappings(ctx, channel.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing channel SKU mappings: %w", err)
+	}
+	var listings []entity.ChannelSKUMapping
+	var skuIDs []string
+	for _, mapping := range mappings {
+		if mapping.ExternalID != "" {
+			listings = append(listings, mapping)
+			skuIDs = append(skuIDs, mapping.SKUID)
+		}
+	}
+
+	warehouses := channel.Warehouses
+	if len(warehouses) == 0 {
+		warehouses = []entity.SalesChannelWarehouse{{StoreID: channel.StoreID}}
+	}
+	levels := make(map[string]map[string]float64)
+	for _, w := range warehouses {
+		available, err := u.repo.AvailableQuantities(ctx, w.StoreID, skuIDs)
+		if err != nil {
+			return nil, fmt.Errorf("error getting available stock: %w", err)
+		}
+		if levels[w.LocationID] == nil {
+			levels[w.LocationID] = make(map[string]float64, len(available))
+		}
+		for skuID, quantity := range available {
+			levels[w.LocationID][skuID] += quantity
+		}
+	}
+	locations := make([]string, 0, len(levels))
+	for location := range levels {
+		locations = append(locations, location)
+	}
+	sort.Strings(locations)
+
+	run := &entity.ChannelSyncRun{
+		ChannelID:   channel.ID,
+		Kind:        entity.ChannelSyncInventory,
+		TriggeredBy: triggeredBy,
+		StartedAt:   time.Now(),
+	}
+	var failures []string
+	for _, location := range locations {
+		for _, listing := range listings {
+			level := entity.ChannelInventoryLevel{
+				LocationID:  location,
+				ExternalSKU: listing.ExternalSKU,
+				ExternalID:  listing.ExternalID,
+				Available:   math.Max(0, levels[location][listing.SKUID]),
+			}
+			if err := connector.PushInventory(ctx, level); err != nil {
+				failures = append(failures, fmt.Sprintf("listing %s at location %q: %v", listing.ExternalSKU, location, err))
+				continue
+			}
+			run.Processed++
+		}
+	}
+
+	if run.Processed > 0 {
+		if err := u.repo.SetInventorySyncedAt(ctx, channel.ID, time.Now()); err != nil {
+			return nil, fmt.Errorf("error recording sales channel sync: %w", err)
+		}
+	}
+	return u.finishRun(ctx, run, failures, run.Processed > 0 || len(failures) == 0)
+}
+
+// finishRun records the outcome of a sync run. A run that did not reach the
+// channel fails; one with failures is partial.
+func (u *SalesChannelUseCase) finishRun(ctx context.Context, run *entity.ChannelSyncRun, failures []string, reached bool) (*entity.ChannelSyncRun, error) {
+	run.Failed = len(failures)
+	run.Errors = strings.Join(failures, "\n")
+	run.FinishedAt = time.Now()
+	switch {
+	case !reached:
+		run.Status = entity.ChannelSyncFailed
+	case run.Failed > 0:
+		run.Status = entity.ChannelSyncPartial
+	default:
+		run.Status = entity.ChannelSyncSucceeded
+	}
+
+	if err := u.repo.CreateSyncRun(ctx, run); err != nil {
+		return nil, fmt.Errorf("error recording sales channel sync: %w", err)
+	}
+	return run, nil
+}
+
+// SyncAll ingests the orders of every active sales channel and pushes their
+// inventory levels, for the scheduled sync. A channel that cannot be synced
+// is logged and skipped. The runs recorded are returned.
+func (u *SalesChannelUseCase) SyncAll(ctx context.Context, userID uint) ([]entity.ChannelSyncRun, error) {
+	channels, err := u.repo.ListChannels(ctx, true)
+	if err != nil {
+		return nil, fmt.Errorf("error listing sales channels: %w", err)
+	}
+
+	var runs []entity.ChannelSyncRun
+	for _, channel := range channels {
+		run, err := u.SyncOrders(ctx, channel.ID, userID, "scheduler")
+		if err != nil {
+			logging.FromContext(ctx).Error("sales channel order sync failed", "channel", channel.Code, "error", err)
+			continue
+		}
+		runs = append(runs, *run)
+
+		if !channel.PushInventory {
+			continue
+		}
+		run, err = u.PushInventory(ctx, channel.ID, "scheduler")
+		if err != nil {
+			logging.FromContext(ctx).Error("sales channel inventory push failed", "channel", channel.Code, "error", err)
+			continue
+		}
+		runs = append(runs, *run)
+	}
+	return runs, nil
+}
@@ -0,0 +1,146 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
+)
+
+var (
+	ErrSavedViewNotFound    = entity.NewError(entity.ErrCodeNotFound, "saved view not found")
+	ErrSavedViewNotOwner    = entity.NewError(entity.ErrCodePermissionDenied, "only the user who saved a view can change it")
+	ErrSavedViewWrongModule = entity.NewError(entity.ErrCodeInvalidArgument, "the saved view belongs to another list")
+)
+
+// SavedViewUseCase handles the filter combinations users save for the lists
+// of a module and share with roles
+type SavedViewUseCase struct {
+	repo     *repository.SavedViewRepository
+	roleRepo *repository.RoleRepository
+}
+
+// NewSavedViewUseCase creates a new saved view use case
+func NewSavedViewUseCase(repo *repository.SavedViewRepository, roleRepo *repository.RoleRepository) *SavedViewUseCase {
+	return &SavedViewUseCase{
+		repo:     repo,
+		roleRepo: roleRepo,
+	}
+}
+
+// CreateView saves a view for a user
+func (u *SavedViewUseCase) CreateView(ctx context.Context, userID uint, req *entity.SavedViewRequest) (*entity.SavedView, error) {
+	view := &entity.SavedView{UserID: userID}
+	if err := u.applyRequest(view, req); err != nil {
+		return nil, err
+	}
+	if err := u.repo.Create(ctx, view); err != nil {
+		return nil, fmt.Errorf("error creating saved view: %w", err)
+	}
+	return view, nil
+}
+
+// ListViews lists the views of a module, or of every module when it is
+// empty, that a user of a role saved or can apply
+func (u *SavedViewUseCase) ListViews(ctx context.Context, userID uint, role, module string) ([]entity.SavedView, error) {
+	return u.repo.ListVisible(ctx, userID, role, module)
+}
+
+// GetView gets a view a user of a role saved or can apply
+func (u *SavedViewUseCase) GetView(ctx context.Context, userID uint, role string, id uint) (*entity.SavedView, error) {
+	view, err := u.repo.GetByID(ctx, id)
+	if errors.Is(err, repository.ErrRecordNotFound) {
+		return nil, ErrSavedViewNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Views shared with other roles are not revealed
+	if !view.VisibleTo(userID, role) {
+		return nil, ErrSavedViewNotFound
+	}
+	return view, nil
+}
+
+// UpdateView changes a view the user saved
+func (u *SavedViewUseCase) UpdateView(ctx context.Context, userID uint, role string, id uint, req *entity.SavedViewRequest) (*entity.SavedView, error) {
+	view, err := u.GetView(ctx, userID, role, id)
+	if err != nil {
+		return nil, err
+	}
+	if view.UserID != userID {
+		return nil, ErrSavedViewNotOwner
+	}
+	if err := u.applyRequest(view, req); err != nil {
+		return nil, err
+	}
+	if err := u.repo.Update(ctx, view); err != nil {
+		return nil, fmt.Errorf("error updating saved view: %w", err)
+	}
+	return view, nil
+}
+
+// DeleteView removes a view the user saved
+func (u *SavedViewUseCase) DeleteView(ctx context.Context, userID uint, role string, id uint) error {
+	view, err := u.GetView(ctx, userID, role, id)
+	if err != nil {
+		return err
+	}
+	if view.UserID != userID {
+		return ErrSavedViewNotOwner
+	}
+	err = u.repo.Delete(ctx, id)
+	if errors.Is(err, repository.ErrRecordNotFound) {
+		return ErrSavedViewNotFound
+	}
+	return err
+}
+
+// ViewCriteria returns the criteria of a view a user of a role applies to
+// the list served at a route, as query parameters
+func (u *SavedViewUseCase) ViewCriteria(ctx context.Context, userID uint, role string, id uint, route string) (map[string]string, error) {
+	view, err := u.GetView(ctx, userID, role, id)
+	if err != nil {
+		return nil, err
+	}
+	if module, ok := entity.SavedViewModuleOf(route); !ok || module != view.Module {
+		return nil, ErrSavedViewWrongModule
+	}
+
+	criteria := make(map[string]string, len(view.Criteria))
+	for param, value := range view.Criteria {
+		criteria[param] = fmt.Sprint(value)
+	}
+	return criteria, nil
+}
+
+// applyRequest copies a request onto a view, checking its module and the
+// roles it is shared with
+func (u *SavedViewUseCase) applyRequest(view *entity.SavedView, req *entity.SavedViewRequest) error {
+	if _, ok := entity.SavedViewModules[req.Module]; !ok {
+		return entity.NewError(entity.ErrCodeInvalidArgument, fmt.Sprintf("views cannot be saved for %q", req.Module))
+	}
+	criteria := make(entity.JSONMap, len(req.Criteria))
+	for param, value := range req.Criteria {
+		if param == "" || param == entity.SavedViewParam {
+			return entity.NewError(entity.ErrCodeInvalidArgument, fmt.Sprintf("invalid criterion %q", param))
+		}
+		criteria[param] = value
+	}
+	roles := make([]string, 0, len(req.SharedRoles))
+	for _, name := range req.SharedRoles {
+		if _, err := u.roleRepo.FindByName(name); err != nil {
+			return entity.NewError(entity.ErrCodeInvalidArgument, fmt.Sprintf("unknown role %q", name))
+		}
+		roles = append(roles, name)
+	}
+
+	view.Module = req.Module
+	view.Name = strings.TrimSpace(req.Name)
+	view.Criteria = criteria
+	view.SharedRoles = roles
+	return nil
+}
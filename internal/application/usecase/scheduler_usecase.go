@@ -0,0 +1,404 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/cron"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/logging"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
+)
+
+var (
+	ErrScheduledTaskNotFound = entity.NewError(entity.ErrCodeNotFound, "scheduled task not found")
+	ErrScheduledTaskRunning  = entity.NewError(entity.ErrCodeConflict, "the task is already running on this instance")
+)
+
+const (
+	// schedulerTick is how often due tasks are looked for
+	schedulerTick = time.Second
+	// schedulerLeaseInterval is how often an instance tries to take the
+	// scheduler lease, and the one holding it checks it still does
+	schedulerLeaseInterval = 15 * time.Second
+)
+
+// scheduledTask is a recurring task registered with the scheduler
+type scheduledTask struct {
+	name        string
+	description string
+	spec        string
+	schedule    cron.Schedule
+	immediately bool // run when the instance starts running the schedules
+	run         func(ctx context.Context) error
+
+	next    time.Time // guarded by the scheduler's mutex, as is running
+	running bool
+}
+
+// SchedulerUseCase runs the recurring background tasks, such as the report
+// schedules and dunning, on cron schedules. Of the server instances, only the
+// one holding the scheduler lease runs them; another takes over within
+// seconds when it stops. Every run is recorded, and tasks can be run by hand
+// on any instance.
+type SchedulerUseCase struct {
+	runRepo   *repository.ScheduledRunRepository
+	instance  string
+	enabled   bool              // this instance may run the schedules
+	overrides map[string]string // schedules configured in place of the defaults, by task
+	tasks     map[string]*scheduledTask
+	names     []string // in order of registration
+
+	ctx      context.Context // of the runs, canceled once the shutdown deadline passes
+	mu       sync.Mutex      // guards lease and the tasks' next and running
+	lease    *repository.SchedulerLease
+	stop     chan struct{} // closed by Stop
+	stopOnce sync.Once
+	loop     sync.WaitGroup
+	runs     sync.WaitGroup
+}
+
+// NewSchedulerUseCase creates a new scheduler use case. overrides replace the
+// schedules of tasks by name; an instance that is not enabled only runs tasks
+// by hand.
+func NewSchedulerUseCase(runRepo *repository.ScheduledRunRepository, enabled bool, overrides map[string]string) (*SchedulerUseCase, error) {
+	for name, spec := range overrides {
+		if _, err := cron.Parse(spec); err != nil {
+			return nil, fmt.Errorf("invalid schedule of task %s: %w", name, err)
+		}
+	}
+	hostname, _ := os.Hostname()
+	return &SchedulerUseCase{
+		runRepo:   runRepo,
+		instance:  fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+		enabled:   enabled,
+		overrides: overrides,
+		tasks:     make(map[string]*scheduledTask),
+		ctx:       context.Background(),
+		stop:      make(chan struct{}),
+	}, nil
+}
+
+// Register adds a task running on a schedule, a cron expression or
+// "@every <interval>", unless one is configured for it instead. A task run
+// immediately also runs when an instance starts running the schedules. Tasks
+// are registered before Start.
+func (u *SchedulerUseCase) Register(name, description, spec string, immediately bool, run func(ctx context.Context) error) {
+	if override, ok := u.overrides[name]; ok {
+		spec = override
+	}
+	schedule, err := cron.Parse(spec)
+	if err != nil {
+		panic(fmt.Sprintf("invalid schedule of task %s: %v", name, err))
+	}
+	if _, ok := u.tasks[name]; !ok {
+		u.names = append(u.names, name)
+	}
+	u.tasks[name] = &scheduledTask{
+		name:        name,
+		description: description,
+		spec:        spec,
+		schedule:    schedule,
+		immediately: immediately,
+		run:         run,
+	}
+}
+
+// Start runs the schedules in the background while this instance holds the
+// scheduler lease, trying to take it every few seconds. The runs get ctx.
+func (u *SchedulerUseCase) Start(ctx context.Context) {
+	u.ctx = ctx
+	for name := range u.overrides {
+		if _, ok := u.tasks[name]; !ok {
+			slog.Warn("schedule configured for unknown task", "task", name)
+		}
+	}
+	if !u.enabled || len(u.tasks) == 0 {
+		return
+	}
+
+	u.loop.Add(1)
+	go func() {
+		defer u.loop.Done()
+		tick := time.NewTicker(schedulerTick)
+		defer tick.Stop()
+
+		u.checkLease()
+		lastLeaseCheck := time.Now()
+		for {
+			select {
+			case now := <-tick.C:
+				if now.Sub(lastLeaseCheck) >= schedulerLeaseInterval {
+					u.checkLease()
+					lastLeaseCheck = now
+				}
+				u.runDue(now)
+			case <-u.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops running the schedules, waits for the runs in progress to finish
+// and gives the scheduler lease up for another instance
+func (u *SchedulerUseCase) Stop(ctx context.Context) error {
+	u.stopOnce.Do(func() { close(u.stop) })
+	u.loop.Wait()
+
+	done := make(chan struct{})
+	go func() {
+		u.runs.Wait()
+		close(done)
+	}()
+	var err error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.lease != nil {
+		if releaseErr := u.lease.Release(context.WithoutCancel(ctx)); releaseErr != nil {
+			slog.Error("error releasing scheduler lease", "error", releaseErr)
+		}
+		u.lease = nil
+	}
+	return err
+}
+
+// checkLease takes the scheduler lease when no instance holds it, and notices
+// when this instance lost it
+func (u *SchedulerUseCase) checkLease() {
+	ctx, cancel := context.WithTimeout(context.Background(), schedulerLeaseInterval)
+	defer cancel()
+
+	u.mu.Lock()
+	lease := u.lease
+	u.mu.Unlock()
+
+	if lease != nil {
+		if err := lease.Check(ctx); err != nil {
+			slog.Warn("lost scheduler lease", "instance", u.instance, "error", err)
+			lease.Release(ctx)
+			u.mu.Lock()
+			u.lease = nil
+			u.mu.Unlock()
+		}
+		return
+	}
+
+	lease, err := u.runRepo.TryLead(ctx)
+	if err != nil {
+		slog.Error("error taking scheduler lease", "error", err)
+		return
+	}
+	if lease == nil {
+		return
+	}
+	slog.Info("running the schedules", "instance", u.instance)
+	if failed, err := u.runRepo.FailAbandoned(ctx, time.Now()); err != nil {
+		slog.Error("error recording abandoned scheduled runs", "error", err)
+	} else if failed > 0 {
+		slog.Warn("recorded abandoned scheduled runs as failed", "runs", failed)
+	}
+
+	now := time.Now()
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.lease = lease
+	for _, task := range u.tasks {
+		if task.immediately {
+			task.next = now
+		} else {
+			task.next = task.schedule.Next(now)
+		}
+	}
+}
+
+// runDue starts the due tasks not already running while this instance holds
+// the scheduler lease
+func (u *SchedulerUseCase) runDue(now time.Time) {
+	u.mu.Lock()
+	var due []*scheduledTask
+	if u.lease != nil {
+		for _, name := range u.names {
+			task := u.tasks[name]
+			if task.next.IsZero() || now.Before(task.next) {
+				continue
+			}
+			task.next = task.schedule.Next(now)
+			if !task.running {
+				due = append(due, task)
+			}
+		}
+	}
+	u.mu.Unlock()
+
+	for _, task := range due {
+		if _, err := u.start(task, entity.ScheduledRunBySchedule, nil); err != nil {
+			slog.Error("error starting scheduled task", "task", task.name, "error", err)
+		}
+	}
+}
+
+// Trigger runs a task by hand on this instance, in the background. It
+// returns the run, which is recorded like the scheduled ones.
+func (u *SchedulerUseCase) Trigger(ctx context.Context, name string, userID *uint) (*entity.ScheduledRun, error) {
+	task, ok := u.tasks[name]
+	if !ok {
+		return nil, ErrScheduledTaskNotFound
+	}
+	return u.start(task, entity.ScheduledRunManually, userID)
+}
+
+// start records a run of a task and runs it in the background, unless it is
+// running already
+func (u *SchedulerUseCase) start(task *scheduledTask, trigger entity.ScheduledRunTrigger, userID *uint) (*entity.ScheduledRun, error) {
+	u.mu.Lock()
+	if task.running {
+		u.mu.Unlock()
+		return nil, ErrScheduledTaskRunning
+	}
+	task.running = true
+	u.mu.Unlock()
+
+	run := &entity.ScheduledRun{
+		Task:        task.name,
+		Trigger:     trigger,
+		Status:      entity.ScheduledRunRunning,
+		Instance:    u.instance,
+		TriggeredBy: userID,
+		StartedAt:   time.Now(),
+	}
+	if err := u.runRepo.Create(context.Background(), run); err != nil {
+		u.mu.Lock()
+		task.running = false
+		u.mu.Unlock()
+		return nil, fmt.Errorf("error recording scheduled run: %w", err)
+	}
+
+	recorded := *run
+	u.runs.Add(1)
+	go func() {
+		defer u.runs.Done()
+		defer func() {
+			u.mu.Lock()
+			task.running = false
+			u.mu.Unlock()
+		}()
+
+		ctx := logging.With(u.ctx, "task", task.name, "scheduled_run_id", run.ID)
+		logger := logging.FromContext(ctx)
+		err := runScheduledTask(ctx, task)
+		now := time.Now()
+		run.FinishedAt = &now
+		if err != nil {
+			logger.Error("scheduled task failed", "error", err)
+			run.Status = entity.ScheduledRunFailed
+			run.Error = err.Error()
+		} else {
+			run.Status = entity.ScheduledRunSucceeded
+		}
+		if err := u.runRepo.Finish(context.Background(), run); err != nil {
+			logger.Error("error recording scheduled run outcome", "error", err)
+		}
+	}()
+	return &recorded, nil
+}
+
+// runScheduledTask runs a task, turning a panic into an error so that one task
+// cannot stop the server
+func runScheduledTask(ctx context.Context, task *scheduledTask) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("task panicked: %v", r)
+		}
+	}()
+	return task.run(ctx)
+}
+
+// Status lists the tasks with their last run, and tells whether this
+// instance runs the schedules
+func (u *SchedulerUseCase) Status(ctx context.Context) (*entity.SchedulerStatus, error) {
+	lastRuns, err := u.runRepo.LastRuns(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error getting last scheduled runs: %w", err)
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	status := &entity.SchedulerStatus{
+		Instance: u.instance,
+		Leader:   u.lease != nil,
+		Tasks:    make([]entity.ScheduledTask, 0, len(u.names)),
+	}
+	for _, name := range u.names {
+		task := u.tasks[name]
+		info := entity.ScheduledTask{
+			Name:        task.name,
+			Description: task.description,
+			Schedule:    task.spec,
+			Running:     task.running,
+		}
+		if status.Leader && !task.next.IsZero() {
+			next := task.next
+			info.NextRunAt = &next
+		}
+		if last, ok := lastRuns[name]; ok {
+			info.LastRun = &last
+		}
+		status.Tasks = append(status.Tasks, info)
+	}
+	return status, nil
+}
+
+// ListRuns lists the recorded runs matching a filter, latest first
+func (u *SchedulerUseCase) ListRuns(ctx context.Context, filter *entity.ScheduledRunFilter) ([]entity.ScheduledRun, int64, error) {
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+	if filter.PageSize <= 0 {
+		filter.PageSize = 20
+	}
+	runs, total, err := u.runRepo.List(ctx, filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error listing scheduled runs: %w", err)
+	}
+	return runs, total, nil
+}
+
+// PurgeRuns removes the records of the runs started more than the given
+// number of days ago
+func (u *SchedulerUseCase) PurgeRuns(ctx context.Context, days int) (int64, error) {
+	purged, err := u.runRepo.Purge(ctx, time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		return 0, fmt.Errorf("error purging scheduled runs: %w", err)
+	}
+	return purged, nil
+}
+
+// ParseSchedules reads the schedules configured in place of the defaults,
+// as "<task>=<schedule>" entries separated by semicolons, e.g.
+// "dunning=0 6 * * *;report_schedules=*/5 * * * *"
+func ParseSchedules(value string) (map[string]string, error) {
+	schedules := make(map[string]string)
+	for _, entry := range strings.Split(value, ";") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		name, spec, ok := strings.Cut(entry, "=")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid schedule %q, expected <task>=<schedule>", entry)
+		}
+		schedules[strings.TrimSpace(name)] = strings.TrimSpace(spec)
+	}
+	return schedules, nil
+}
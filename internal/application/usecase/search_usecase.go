@@ -0,0 +1,112 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
+)
+
+// minSearchLength is the shortest search text, in characters; trigrams of
+// shorter texts match almost anything
+const minSearchLength = 2
+
+var (
+	ErrSearchTextTooShort = entity.NewError(entity.ErrCodeInvalidArgument, "search text must be at least 2 characters")
+	ErrSearchTypeInvalid  = entity.NewError(entity.ErrCodeInvalidArgument, "invalid search type")
+)
+
+// SearchUseCase searches across SKUs, vendor items, clients, vendors and orders
+type SearchUseCase struct {
+	searchRepo *repository.SearchRepository
+}
+
+// NewSearchUseCase creates a new search use case
+func NewSearchUseCase(searchRepo *repository.SearchRepository) *SearchUseCase {
+	return &SearchUseCase{searchRepo: searchRepo}
+}
+
+// Search finds the records matching a query among the types the caller may
+// read, best match first. The facets count the matches of each readable type.
+func (u *SearchUseCase) Search(ctx context.Context, query entity.SearchQuery, readable []entity.SearchEntityType) (*entity.SearchResult, error) {
+	query.Text = strings.TrimSpace(query.Text)
+	if utf8.RuneCountInString(query.Text) < minSearchLength {
+		return nil, ErrSearchTextTooShort
+	}
+	for _, t := range query.Types {
+		if !isSearchEntityType(t) {
+			return nil, fmt.Errorf("%w: %s", ErrSearchTypeInvalid, t)
+		}
+	}
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.PageSize < 1 || query.PageSize > 100 {
+		query.PageSize = 20
+	}
+
+	hitTypes := readable
+	if len(query.Types) > 0 {
+		hitTypes = nil
+		for _, t := range query.Types {
+			if containsSearchType(readable, t) {
+				hitTypes = append(hitTypes, t)
+			}
+		}
+	}
+
+	hits, facets, err := u.searchRepo.Search(ctx, query.Text, readable, hitTypes, query.PageSize, (query.Page-1)*query.PageSize)
+	if err != nil {
+		return nil, fmt.Errorf("error searching: %w", err)
+	}
+
+	result := &entity.SearchResult{
+		Query:    query.Text,
+		Hits:     hits,
+		Facets:   facets,
+		Page:     query.Page,
+		PageSize: query.PageSize,
+	}
+	if result.Hits == nil {
+		result.Hits = []entity.SearchHit{}
+	}
+	for _, t := range hitTypes {
+		result.Total += facets[t]
+	}
+	return result, nil
+}
+
+// Reindex rebuilds the search indexes of the given types, or of all types,
+// e.g. after a bulk import or when searches slow down as the indexes bloat
+func (u *SearchUseCase) Reindex(ctx context.Context, types []entity.SearchEntityType) error {
+	if len(types) == 0 {
+		types = entity.SearchEntityTypes
+	}
+	for _, t := range types {
+		if !isSearchEntityType(t) {
+			return fmt.Errorf("%w: %s", ErrSearchTypeInvalid, t)
+		}
+	}
+	for _, t := range types {
+		if err := u.searchRepo.Reindex(ctx, t); err != nil {
+			return fmt.Errorf("error reindexing %s: %w", t, err)
+		}
+	}
+	return nil
+}
+
+func isSearchEntityType(t entity.SearchEntityType) bool {
+	return containsSearchType(entity.SearchEntityTypes, t)
+}
+
+func containsSearchType(types []entity.SearchEntityType, t entity.SearchEntityType) bool {
+	for _, candidate := range types {
+		if candidate == t {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,245 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/cache"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
+)
+
+var ErrSKUClass = entity.NewError(entity.ErrCodeInvalidArgument, "ABC class must be A, B or C and XYZ class X, Y or Z")
+
+// Coefficients of variation of monthly demand up to which SKUs are X and Y
+const (
+	xyzSteadyVariation  = 0.5
+	xyzVaryingVariation = 1.0
+)
+
+// abcClasses and xyzClasses list the classes from best to worst
+var (
+	abcClasses = []entity.ABCClass{entity.ABCClassA, entity.ABCClassB, entity.ABCClassC}
+	xyzClasses = []entity.XYZClass{entity.XYZClassX, entity.XYZClassY, entity.XYZClassZ}
+)
+
+// SKUClassUseCase classifies SKUs by consumption value (ABC) and demand
+// variability (XYZ) and schedules cycle counts by ABC class
+type SKUClassUseCase struct {
+	repo           *repository.SKUClassRepository
+	lookbackMonths int
+	countDays      map[entity.ABCClass]int
+	skuCache       *cache.Namespace // SKUs by ID, which carry their classes
+}
+
+// NewSKUClassUseCase creates a new SKU class use case. SKUs are classified by
+// the stock they issued over the last lookbackMonths closed months, and their
+// stocks counted every countDays of their ABC class, unclassified SKUs as C.
+func NewSKUClassUseCase(repo *repository.SKUClassRepository, lookbackMonths int, countDays map[entity.ABCClass]int, c *cache.Cache) *SKUClassUseCase {
+	if lookbackMonths <= 0 {
+		lookbackMonths = 12
+	}
+	days := map[entity.ABCClass]int{entity.ABCClassA: 30, entity.ABCClassB: 90, entity.ABCClassC: 180}
+	for class, n := range countDays {
+		if n > 0 {
+			days[class] = n
+		}
+	}
+	return &SKUClassUseCase{
+		repo:           repo,
+		lookbackMonths: lookbackMonths,
+		countDays:      days,
+		skuCache:       c.Namespace(cache.NamespaceSKUs),
+	}
+}
+
+// Run classifies every SKU not archived and stores its classes. The units
+// issued over the window valued at the SKU price rank SKUs by ABC: those
+// making up the first 80% of the value are A, the next 15% B and the rest C.
+// The coefficient of variation of the units issued each month, months
+// without issues counting as zero, gives the XYZ class.
+func (u *SKUClassUseCase) Run(ctx context.Context) (*entity.SKUClassRunResult, error) {
+	now := time.Now()
+	last := monthStart(now)
+	first := last.AddDate(0, -u.lookbackMonths, 0)
+
+	issues, err := u.repo.ListMonthlyIssues(ctx, first, last)
+	if err != nil {
+		return nil, fmt.Errorf("error summarizing stock issues: %w", err)
+	}
+	prices, err := u.repo.ListSKUPrices(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error listing SKUs: %w", err)
+	}
+
+	monthly := make(map[string]map[string]float64)
+	for _, issue := range issues {
+		if _, ok := prices[issue.SKUID]; !ok {
+			continue
+		}
+		if monthly[issue.SKUID] == nil {
+			monthly[issue.SKUID] = make(map[string]float64)
+		}
+		monthly[issue.SKUID][issue.Period] += issue.Quantity
+	}
+
+	values := make(map[string]float64, len(monthly))
+	for skuID, periods := range monthly {
+		for _, quantity := range periods {
+			values[skuID] += quantity * prices[skuID]
+		}
+	}
+	// The ABC split is the velocity split of putaway, over value instead of units
+	velocity := velocityClasses(values)
+
+	classifications := make([]entity.SKUClassification, 0, len(prices))
+	summaries := make(map[[2]string]*entity.SKUClassSummary)
+	for skuID := range prices {
+		classification := entity.SKUClassification{
+			SKUID:            skuID,
+			ABCClass:         entity.ABCClassC,
+			ConsumptionValue: roundAmount(values[skuID]),
+			Variation:        demandVariation(monthly[skuID], u.lookbackMonths),
+		}
+		switch velocity[skuID] {
+		case entity.VelocityFast:
+			classification.ABCClass = entity.ABCClassA
+		case entity.VelocityMedium:
+			classification.ABCClass = entity.ABCClassB
+		}
+		switch {
+		case len(monthly[skuID]) == 0 || classification.Variation > xyzVaryingVariation:
+			classification.XYZClass = entity.XYZClassZ
+		case classification.Variation > xyzSteadyVariation:
+			classification.XYZClass = entity.XYZClassY
+		default:
+			classification.XYZClass = entity.XYZClassX
+		}
+		classifications = append(classifications, classification)
+
+		key := [2]string{string(classification.ABCClass), string(classification.XYZClass)}
+		summary, ok := summaries[key]
+		if !ok {
+			summary = &entity.SKUClassSummary{ABCClass: classification.ABCClass, XYZClass: classification.XYZClass}
+			summaries[key] = summary
+		}
+		summary.SKUs++
+		summary.ConsumptionValue = roundAmount(summary.ConsumptionValue + classification.ConsumptionValue)
+	}
+
+	if err := u.repo.SaveClasses(ctx, classifications, now); err != nil {
+		return nil, fmt.Errorf("error saving SKU classes: %w", err)
+	}
+	ids := make([]string, len(classifications))
+	for i, classification := range classifications {
+		ids[i] = classification.SKUID
+	}
+	u.skuCache.Invalidate(ctx, ids...)
+
+	result := &entity.SKUClassRunResult{
+		FirstPeriod: first.Format(periodLayout),
+		LastPeriod:  last.AddDate(0, -1, 0).Format(periodLayout),
+		Classified:  len(classifications),
+		Classes:     []entity.SKUClassSummary{},
+		ComputedAt:  now,
+	}
+	for _, abc := range abcClasses {
+		for _, xyz := range xyzClasses {
+			if summary, ok := summaries[[2]string{string(abc), string(xyz)}]; ok {
+				result.Classes = append(result.Classes, *summary)
+			}
+		}
+	}
+	return result, nil
+}
+
+// demandVariation returns the coefficient of variation of the units issued
+// each month over the given number of months, or 0 without issues
+func demandVariation(periods map[string]float64, months int) float64 {
+	var total float64
+	for _, quantity := range periods {
+		total += quantity
+	}
+	mean := total / float64(months)
+	if mean <= 0 {
+		return 0
+	}
+
+	// Months without issues are months of zero demand
+	squares := float64(months-len(periods)) * mean * mean
+	for _, quantity := range periods {
+		squares += (quantity - mean) * (quantity - mean)
+	}
+	return math.Round(math.Sqrt(squares/float64(months))/mean*1000) / 1000
+}
+
+// CycleCounts lists the stocks due for a cycle count on or before the filter
+// date, most overdue first. A stock is due every interval of its SKU's ABC
+// class after its last adjustment or, when never adjusted, after it was first
+// stocked.
+func (u *SKUClassUseCase) CycleCounts(ctx context.Context, filter *entity.CycleCountFilter) ([]entity.CycleCountLine, error) {
+	if err := checkSKUClasses(filter.ABCClass, ""); err != nil {
+		return nil, err
+	}
+	if filter.StoreID != "" {
+		if err := entity.AccessScopeFromContext(ctx).CheckStore(filter.StoreID); err != nil {
+			return nil, err
+		}
+	}
+	date := truncateDay(time.Now())
+	if filter.Date != nil {
+		date = truncateDay(*filter.Date)
+	}
+
+	stocks, err := u.repo.ListCountableStocks(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("error listing stocks: %w", err)
+	}
+
+	lines := []entity.CycleCountLine{}
+	for _, line := range stocks {
+		class := line.ABCClass
+		if class == "" {
+			class = entity.ABCClassC
+		}
+		line.IntervalDays = u.countDays[class]
+		counted := line.StockedAt
+		if line.LastCountedAt != nil {
+			counted = *line.LastCountedAt
+		}
+		line.DueDate = truncateDay(counted).AddDate(0, 0, line.IntervalDays)
+		if line.DueDate.After(date) {
+			continue
+		}
+		line.DaysOverdue = daysBetween(line.DueDate, date)
+		lines = append(lines, line)
+	}
+	sort.SliceStable(lines, func(i, j int) bool {
+		if !lines[i].DueDate.Equal(lines[j].DueDate) {
+			return lines[i].DueDate.Before(lines[j].DueDate)
+		}
+		if lines[i].StoreID != lines[j].StoreID {
+			return lines[i].StoreID < lines[j].StoreID
+		}
+		return lines[i].SKUCode < lines[j].SKUCode
+	})
+	return lines, nil
+}
+
+// checkSKUClasses validates the ABC and XYZ classes a list is filtered by
+func checkSKUClasses(abc entity.ABCClass, xyz entity.XYZClass) error {
+	switch abc {
+	case "", entity.ABCClassA, entity.ABCClassB, entity.ABCClassC:
+	default:
+		return ErrSKUClass
+	}
+	switch xyz {
+	case "", entity.XYZClassX, entity.XYZClassY, entity.XYZClassZ:
+	default:
+		return ErrSKUClass
+	}
+	return nil
+}
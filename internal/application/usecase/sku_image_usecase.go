@@ -0,0 +1,227 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/cache"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/filestore"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/logging"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
+)
+
+var (
+	ErrSKUImageNotFound = entity.NewError(entity.ErrCodeNotFound, "SKU image not found")
+	ErrTooManySKUImages = entity.NewError(entity.ErrCodeFailedPrecondition, fmt.Sprintf("a SKU can have at most %d images", entity.MaxSKUImages))
+	ErrSKUImageOrder    = entity.NewError(entity.ErrCodeInvalidArgument, "image_ids must list every image of the SKU once")
+)
+
+// SKUImageUseCase keeps the pictures of SKUs and their thumbnails in the file
+// store
+type SKUImageUseCase struct {
+	imageRepo     *repository.SKUImageRepository
+	skuRepo       *repository.SKURepository
+	files         filestore.Store
+	limits        filestore.Limits
+	thumbnailSize int
+	fileURLTTL    time.Duration
+	skuCache      *cache.Namespace // SKUs by ID, which carry their images
+}
+
+// NewSKUImageUseCase creates a new SKU image use case. Uploads are held to the
+// limits, and to the image types thumbnails can be made of; thumbnails fit in
+// a square of thumbnailSize pixels and image URLs stay valid for fileURLTTL.
+func NewSKUImageUseCase(imageRepo *repository.SKUImageRepository, skuRepo *repository.SKURepository, files filestore.Store, limits filestore.Limits, thumbnailSize int, fileURLTTL time.Duration, c *cache.Cache) *SKUImageUseCase {
+	limits.ContentTypes = filestore.ImageTypes
+	return &SKUImageUseCase{
+		imageRepo:     imageRepo,
+		skuRepo:       skuRepo,
+		files:         files,
+		limits:        limits,
+		thumbnailSize: thumbnailSize,
+		fileURLTTL:    fileURLTTL,
+		skuCache:      c.Namespace(cache.NamespaceSKUs),
+	}
+}
+
+// Limits returns the limits uploads are held to
+func (u *SKUImageUseCase) Limits() filestore.Limits {
+	return u.limits
+}
+
+// Upload stores an image read from r with its thumbnail and adds it after
+// the other images of a SKU. A missing or generic content type is sniffed
+// from the image itself.
+func (u *SKUImageUseCase) Upload(ctx context.Context, skuID, fileName, contentType string, r io.Reader, userID *uint) (*entity.SKUImage, error) {
+	images, err := u.ListImages(ctx, skuID)
+	if err != nil {
+		return nil, err
+	}
+	if len(images) >= entity.MaxSKUImages {
+		return nil, ErrTooManySKUImages
+	}
+
+	data, err := io.ReadAll(u.limits.Reader(r))
+	if err != nil {
+		return nil, err
+	}
+	contentType, err = u.limits.Check(filestore.SniffContentType(contentType, data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	thumbnail, err := filestore.MakeThumbnail(data, u.thumbnailSize)
+	if err != nil {
+		return nil, err
+	}
+
+	token := make([]byte, 8)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+	fileName = path.Base(strings.ReplaceAll(fileName, "\\", "/"))
+	prefix := fmt.Sprintf("skus/%s/images/%s", fileSlug(skuID), hex.EncodeToString(token))
+	key := fmt.Sprintf("%s.%s", prefix, strings.TrimPrefix(contentType, "image/"))
+	thumbnailKey := fmt.Sprintf("%s-thumb.%s", prefix, strings.TrimPrefix(thumbnail.ContentType, "image/"))
+
+	if err := u.files.Put(ctx, key, contentType, bytes.NewReader(data)); err != nil {
+		return nil, fmt.Errorf("error storing SKU image: %w", err)
+	}
+	if err := u.files.Put(ctx, thumbnailKey, thumbnail.ContentType, bytes.NewReader(thumbnail.Data)); err != nil {
+		u.deleteFiles(ctx, key)
+		return nil, fmt.Errorf("error storing SKU image thumbnail: %w", err)
+	}
+
+	image := &entity.SKUImage{
+		SKUID:        skuID,
+		FileName:     fileName,
+		ContentType:  contentType,
+		Size:         int64(len(data)),
+		Width:        thumbnail.Width,
+		Height:       thumbnail.Height,
+		FileKey:      key,
+		ThumbnailKey: thumbnailKey,
+		UploadedBy:   userID,
+	}
+	for _, other := range images {
+		image.Position = max(image.Position, other.Position+1)
+	}
+	if err := u.imageRepo.Create(ctx, image); err != nil {
+		u.deleteFiles(ctx, key, thumbnailKey)
+		return nil, fmt.Errorf("error recording SKU image: %w", err)
+	}
+	u.skuCache.Invalidate(ctx, skuID)
+	u.signImage(ctx, image)
+	return image, nil
+}
+
+// ListImages retrieves the images of a SKU in the order they are shown in
+func (u *SKUImageUseCase) ListImages(ctx context.Context, skuID string) ([]entity.SKUImage, error) {
+	if _, err := u.skuRepo.GetSKUByID(ctx, skuID); err != nil {
+		return nil, ErrSKUNotFound
+	}
+	images, err := u.imageRepo.ListBySKU(ctx, skuID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing SKU images: %w", err)
+	}
+	u.signImages(ctx, images)
+	return images, nil
+}
+
+// ReorderImages shows the images of a SKU in the order of their IDs in the
+// request, which lists every one of them
+func (u *SKUImageUseCase) ReorderImages(ctx context.Context, skuID string, req *entity.SKUImageOrderRequest) ([]entity.SKUImage, error) {
+	images, err := u.ListImages(ctx, skuID)
+	if err != nil {
+		return nil, err
+	}
+	if len(req.ImageIDs) != len(images) {
+		return nil, ErrSKUImageOrder
+	}
+	remaining := make(map[uint64]bool, len(images))
+	for _, image := range images {
+		remaining[image.ID] = true
+	}
+	for _, id := range req.ImageIDs {
+		if !remaining[id] {
+			return nil, ErrSKUImageOrder
+		}
+		delete(remaining, id)
+	}
+
+	if err := u.imageRepo.Reorder(ctx, skuID, req.ImageIDs); err != nil {
+		return nil, fmt.Errorf("error reordering SKU images: %w", err)
+	}
+	u.skuCache.Invalidate(ctx, skuID)
+	return u.ListImages(ctx, skuID)
+}
+
+// DeleteImage deletes an image of a SKU with its files
+func (u *SKUImageUseCase) DeleteImage(ctx context.Context, skuID string, id uint64) error {
+	image, err := u.imageRepo.Get(ctx, skuID, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return ErrSKUImageNotFound
+		}
+		return fmt.Errorf("error getting SKU image: %w", err)
+	}
+	if err := u.imageRepo.Delete(ctx, id); err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return ErrSKUImageNotFound
+		}
+		return fmt.Errorf("error deleting SKU image: %w", err)
+	}
+	u.skuCache.Invalidate(ctx, skuID)
+	u.deleteFiles(ctx, image.FileKey, image.ThumbnailKey)
+	return nil
+}
+
+// signSKUs sets the URLs of the images of SKUs
+func (u *SKUImageUseCase) signSKUs(ctx context.Context, skus []entity.SKU) {
+	for _, sku := range skus {
+		u.signImages(ctx, sku.Images)
+	}
+}
+
+// signImages sets the URLs of images
+func (u *SKUImageUseCase) signImages(ctx context.Context, images []entity.SKUImage) {
+	for i := range images {
+		u.signImage(ctx, &images[i])
+	}
+}
+
+// signImage sets the URLs the image and its thumbnail can be downloaded from
+func (u *SKUImageUseCase) signImage(ctx context.Context, image *entity.SKUImage) {
+	expires := time.Now().Add(u.fileURLTTL)
+	fileURL, err := u.files.URL(ctx, image.FileKey, u.fileURLTTL)
+	if err != nil {
+		logging.FromContext(ctx).Error("error signing SKU image URL", "image_id", image.ID, "error", err)
+		return
+	}
+	thumbnailURL, err := u.files.URL(ctx, image.ThumbnailKey, u.fileURLTTL)
+	if err != nil {
+		logging.FromContext(ctx).Error("error signing SKU image thumbnail URL", "image_id", image.ID, "error", err)
+		return
+	}
+	image.URL = fileURL
+	image.ThumbnailURL = thumbnailURL
+	image.URLExpires = &expires
+}
+
+// deleteFiles removes files of SKU images, logging the ones that could not be
+// removed
+func (u *SKUImageUseCase) deleteFiles(ctx context.Context, keys ...string) {
+	for _, key := range keys {
+		if err := u.files.Delete(ctx, key); err != nil {
+			logging.FromContext(ctx).Error("error deleting SKU image file", "file_key", key, "error", err)
+		}
+	}
+}
@@ -2,28 +2,70 @@ package usecase
 
 import (
 	"context"
-	"errors"
+	"encoding/json"
 	"fmt"
 	"regexp"
+	"strings"
 
 	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/cache"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/export"
 	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
 )
 
 var (
-	ErrInvalidSKUCode    = errors.New("invalid SKU code format")
-	ErrDuplicateSKUCode  = errors.New("SKU code already exists")
-	ErrSKUNotFound       = errors.New("SKU not found")
-	ErrCategoryNotFound  = errors.New("category not found")
-	ErrInvalidPriceRange = errors.New("invalid price range")
+	ErrInvalidSKUCode    = entity.NewError(entity.ErrCodeInvalidArgument, "invalid SKU code format")
+	ErrDuplicateSKUCode  = entity.NewError(entity.ErrCodeConflict, "SKU code already exists")
+	ErrSKUNotFound       = entity.NewError(entity.ErrCodeNotFound, "SKU not found")
+	ErrCategoryNotFound  = entity.NewError(entity.ErrCodeNotFound, "category not found")
+	ErrInvalidPriceRange = entity.NewError(entity.ErrCodeInvalidArgument, "invalid price range")
+	ErrSKUIsVariant      = entity.NewError(entity.ErrCodeFailedPrecondition, "a variant cannot have variants of its own")
+	ErrSKUHasVariants    = entity.NewError(entity.ErrCodeFailedPrecondition, "SKU has variants; delete them first")
+	ErrVariantDimensions = entity.NewError(entity.ErrCodeFailedPrecondition, "the parent item already has variants in other dimensions")
+	ErrVariantOptions    = entity.NewError(entity.ErrCodeInvalidArgument, "variant options must give one of the values of each dimension of the parent item")
+	ErrVariantExists     = entity.NewError(entity.ErrCodeConflict, "the parent item already has a variant with these options")
+	ErrTooManyVariants   = entity.NewError(entity.ErrCodeInvalidArgument, fmt.Sprintf("at most %d variants can be generated at once", entity.MaxSKUVariants))
+	ErrNestedKit         = entity.NewError(entity.ErrCodeFailedPrecondition, "kits cannot hold kits, nor be components of kits")
+	ErrSKUInKit          = entity.NewError(entity.ErrCodeFailedPrecondition, "SKU is a component of a kit; remove it from the kit first")
+	ErrCustomsData       = entity.NewError(entity.ErrCodeInvalidArgument, "HS code must be 6 to 10 digits, country of origin a 2-letter country code and net weight not negative")
 )
 
+var variantCodeSeparator = regexp.MustCompile(`[^A-Z0-9]+`)
+
+var (
+	hsCodePattern      = regexp.MustCompile(`^[0-9]{6,10}$`)
+	countryCodePattern = regexp.MustCompile(`^[A-Z]{2}$`)
+)
+
+// skuExportLimit caps the SKUs of one export
+const skuExportLimit = 10000
+
+// categoryTreeKey is the cache key of the SKU category tree
+const categoryTreeKey = "tree"
+
 type SKUUseCase struct {
-	repo *repository.SKURepository
+	repo          *repository.SKURepository
+	searchRepo    *repository.SearchRepository
+	jobUC         *JobUseCase
+	customFieldUC *CustomFieldUseCase
+	imageUC       *SKUImageUseCase
+	skuCache      *cache.Namespace // SKUs by ID
+	categoryCache *cache.Namespace // the category tree
 }
 
-func NewSKUUseCase(repo *repository.SKURepository) *SKUUseCase {
-	return &SKUUseCase{repo: repo}
+func NewSKUUseCase(repo *repository.SKURepository, searchRepo *repository.SearchRepository, jobUC *JobUseCase, customFieldUC *CustomFieldUseCase, imageUC *SKUImageUseCase, c *cache.Cache) *SKUUseCase {
+	u := &SKUUseCase{
+		repo:          repo,
+		searchRepo:    searchRepo,
+		jobUC:         jobUC,
+		customFieldUC: customFieldUC,
+		imageUC:       imageUC,
+		skuCache:      c.Namespace(cache.NamespaceSKUs),
+		categoryCache: c.Namespace(cache.NamespaceCategories),
+	}
+	jobUC.Register(entity.JobSKUBulkCreate, u.runBulkCreateJob)
+	jobUC.Register(entity.JobSKUBulkUpdate, u.runBulkUpdateJob)
+	return u
 }
 
 // validateSKU validates SKU data
@@ -46,7 +88,93 @@ func (u *SKUUseCase) validateSKU(ctx context.Context, sku *entity.SKU) error {
 	if sku.Price < 0 {
 		return ErrInvalidPriceRange
 	}
+	if err := checkCustomsData(&sku.CustomsData); err != nil {
+		return err
+	}
+
+	if sku.ID == "" {
+		if err := u.checkVariant(ctx, sku); err != nil {
+			return err
+		}
+	}
+
+	return u.checkCustomFields(ctx, sku)
+}
+
+// checkVariant validates the variant settings of a new SKU: a parent item
+// sets well-formed dimensions, and a variant gives a value of each dimension
+// of its parent not taken by another variant
+func (u *SKUUseCase) checkVariant(ctx context.Context, sku *entity.SKU) error {
+	if err := sku.VariantDimensions.Validate(); err != nil {
+		return err
+	}
+	if !sku.IsVariant() {
+		sku.VariantOptions = nil
+		return nil
+	}
+	if len(sku.VariantDimensions) > 0 {
+		return ErrSKUIsVariant
+	}
+
+	parent, err := u.repo.GetSKUByID(ctx, *sku.ParentID)
+	if err != nil {
+		return entity.NewError(entity.ErrCodeNotFound, "parent item not found")
+	}
+	if parent.IsVariant() {
+		return ErrSKUIsVariant
+	}
+	if !parent.VariantDimensions.Matches(sku.VariantOptions) {
+		return ErrVariantOptions
+	}
+	variants, err := u.repo.ListVariants(ctx, parent.ID)
+	if err != nil {
+		return fmt.Errorf("error listing variants: %w", err)
+	}
+	key := parent.VariantDimensions.Key(sku.VariantOptions)
+	for _, variant := range variants {
+		if parent.VariantDimensions.Key(variant.VariantOptions) == key {
+			return ErrVariantExists
+		}
+	}
+	return nil
+}
+
+// keepManaged keeps the variant and kit settings and the classes of a SKU as
+// they are saved, as they only change through the variant and kit endpoints
+// and the classification runs
+func keepManaged(sku, existing *entity.SKU) {
+	sku.ParentID = existing.ParentID
+	sku.VariantDimensions = existing.VariantDimensions
+	sku.VariantOptions = existing.VariantOptions
+	sku.IsKit = existing.IsKit
+	sku.SKUClasses = existing.SKUClasses
+}
 
+// checkCustomsData validates the customs classification of a SKU, writing
+// its codes without spaces and its country in capitals
+func checkCustomsData(data *entity.CustomsData) error {
+	data.HSCode = strings.ReplaceAll(strings.ReplaceAll(data.HSCode, " ", ""), ".", "")
+	data.CountryOfOrigin = strings.ToUpper(strings.TrimSpace(data.CountryOfOrigin))
+	if data.HSCode != "" && !hsCodePattern.MatchString(data.HSCode) {
+		return ErrCustomsData
+	}
+	if data.CountryOfOrigin != "" && !countryCodePattern.MatchString(data.CountryOfOrigin) {
+		return ErrCustomsData
+	}
+	if data.NetWeight < 0 {
+		return ErrCustomsData
+	}
+	return nil
+}
+
+// checkCustomFields validates the custom field values of a SKU, keeping them
+// in the form they are stored in
+func (u *SKUUseCase) checkCustomFields(ctx context.Context, sku *entity.SKU) error {
+	values, err := u.customFieldUC.CheckValues(ctx, entity.CustomFieldEntitySKU, sku.CustomFields)
+	if err != nil {
+		return err
+	}
+	sku.CustomFields = values
 	return nil
 }
 
@@ -65,6 +193,7 @@ func (u *SKUUseCase) UpdateSKU(ctx context.Context, sku *entity.SKU) error {
 	if err != nil {
 		return ErrSKUNotFound
 	}
+	keepManaged(sku, existingSKU)
 
 	// If SKU code is being changed, validate the new SKU code
 	if existingSKU.SKUCode != sku.SKUCode {
@@ -76,17 +205,30 @@ func (u *SKUUseCase) UpdateSKU(ctx context.Context, sku *entity.SKU) error {
 		if sku.Price < 0 {
 			return ErrInvalidPriceRange
 		}
+		if err := checkCustomsData(&sku.CustomsData); err != nil {
+			return err
+		}
+		if err := u.checkCustomFields(ctx, sku); err != nil {
+			return err
+		}
 	}
 
-	return u.repo.UpdateSKU(ctx, sku)
+	if err := u.repo.UpdateSKU(ctx, sku); err != nil {
+		return err
+	}
+	u.skuCache.Invalidate(ctx, sku.ID)
+	return nil
 }
 
-// GetSKU gets a SKU by ID
+// GetSKU gets a SKU by ID, from the cache when it holds it
 func (u *SKUUseCase) GetSKU(ctx context.Context, id string) (*entity.SKU, error) {
-	sku, err := u.repo.GetSKUByID(ctx, id)
+	sku, err := cache.Load(ctx, u.skuCache, id, func() (*entity.SKU, error) {
+		return u.repo.GetSKUByID(ctx, id)
+	})
 	if err != nil {
 		return nil, ErrSKUNotFound
 	}
+	u.imageUC.signImages(ctx, sku.Images)
 	return sku, nil
 }
 
@@ -96,17 +238,241 @@ func (u *SKUUseCase) GetSKUBySKUCode(ctx context.Context, skuCode string) (*enti
 	if err != nil {
 		return nil, ErrSKUNotFound
 	}
+	u.imageUC.signImages(ctx, sku.Images)
 	return sku, nil
 }
 
 // DeleteSKU deletes a SKU by ID
 func (u *SKUUseCase) DeleteSKU(ctx context.Context, id string) error {
 	// Check if SKU exists
-	_, err := u.repo.GetSKUByID(ctx, id)
+	sku, err := u.repo.GetSKUByID(ctx, id)
 	if err != nil {
 		return ErrSKUNotFound
 	}
-	return u.repo.DeleteSKU(ctx, id)
+	variants, err := u.repo.CountVariants(ctx, id)
+	if err != nil {
+		return fmt.Errorf("error counting variants: %w", err)
+	}
+	if variants > 0 {
+		return ErrSKUHasVariants
+	}
+	inKit, err := u.repo.IsKitComponent(ctx, id)
+	if err != nil {
+		return fmt.Errorf("error checking kits: %w", err)
+	}
+	if inKit {
+		return ErrSKUInKit
+	}
+	if err := u.repo.DeleteSKU(ctx, id); err != nil {
+		return err
+	}
+	u.skuCache.Invalidate(ctx, id)
+
+	// The database drops the images with the SKU; their files go with them
+	for _, image := range sku.Images {
+		u.imageUC.deleteFiles(ctx, image.FileKey, image.ThumbnailKey)
+	}
+	return nil
+}
+
+// GetKitComponents gets the components of a kit with their SKUs
+func (u *SKUUseCase) GetKitComponents(ctx context.Context, kitID string) ([]entity.KitComponent, error) {
+	if _, err := u.repo.GetSKUByID(ctx, kitID); err != nil {
+		return nil, ErrSKUNotFound
+	}
+	return u.repo.ListKitComponents(ctx, kitID)
+}
+
+// SetKitComponents makes a SKU a kit of the given components, or a plain SKU
+// again without any. Kits do not nest: components cannot be kits, and a kit
+// cannot be a component. Orders already placed keep the components they
+// were placed with.
+func (u *SKUUseCase) SetKitComponents(ctx context.Context, kitID string, req *entity.KitComponentsRequest) ([]entity.KitComponent, error) {
+	if _, err := u.repo.GetSKUByID(ctx, kitID); err != nil {
+		return nil, ErrSKUNotFound
+	}
+
+	components := make([]entity.KitComponent, 0, len(req.Components))
+	if len(req.Components) > 0 {
+		inKit, err := u.repo.IsKitComponent(ctx, kitID)
+		if err != nil {
+			return nil, fmt.Errorf("error checking kits: %w", err)
+		}
+		if inKit {
+			return nil, ErrNestedKit
+		}
+	}
+	seen := make(map[string]bool, len(req.Components))
+	for _, line := range req.Components {
+		if line.SKUID == kitID || seen[line.SKUID] {
+			return nil, entity.NewError(entity.ErrCodeInvalidArgument, fmt.Sprintf("component %s is the kit or repeated", line.SKUID))
+		}
+		seen[line.SKUID] = true
+		component, err := u.repo.GetSKUByID(ctx, line.SKUID)
+		if err != nil {
+			return nil, entity.NewError(entity.ErrCodeNotFound, fmt.Sprintf("component %s not found", line.SKUID))
+		}
+		if component.IsKit {
+			return nil, ErrNestedKit
+		}
+		components = append(components, entity.KitComponent{KitID: kitID, ComponentID: line.SKUID, Quantity: line.Quantity})
+	}
+
+	if err := u.repo.ReplaceKitComponents(ctx, kitID, components); err != nil {
+		return nil, fmt.Errorf("error saving kit components: %w", err)
+	}
+	u.skuCache.Invalidate(ctx, kitID)
+	return u.repo.ListKitComponents(ctx, kitID)
+}
+
+// GetVariantMatrix gets a parent item with its dimensions and variants
+func (u *SKUUseCase) GetVariantMatrix(ctx context.Context, parentID string) (*entity.SKUVariantMatrix, error) {
+	parent, err := u.repo.GetSKUByID(ctx, parentID)
+	if err != nil {
+		return nil, ErrSKUNotFound
+	}
+	if parent.IsVariant() {
+		return nil, ErrSKUIsVariant
+	}
+	variants, err := u.repo.ListVariants(ctx, parent.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing variants: %w", err)
+	}
+	return &entity.SKUVariantMatrix{Parent: parent, Dimensions: parent.VariantDimensions, Variants: variants}, nil
+}
+
+// GenerateVariants generates the variants of a parent item for every
+// combination of the values of its dimensions it has no variant for yet.
+// Variants take the parent's details, a code made of the parent's code and
+// their values, e.g. TSHIRT-M-RED, and its name followed by their values. A
+// parent with variants can gain values, but not change dimensions.
+func (u *SKUUseCase) GenerateVariants(ctx context.Context, parentID string, req *entity.SKUVariantRequest) (*entity.SKUVariantMatrix, error) {
+	parent, err := u.repo.GetSKUByID(ctx, parentID)
+	if err != nil {
+		return nil, ErrSKUNotFound
+	}
+	if parent.IsVariant() {
+		return nil, ErrSKUIsVariant
+	}
+	dimensions := entity.SKUVariantDimensions(req.Dimensions)
+	if err := dimensions.Validate(); err != nil {
+		return nil, err
+	}
+	if req.Price != nil && *req.Price < 0 {
+		return nil, ErrInvalidPriceRange
+	}
+
+	existing, err := u.repo.ListVariants(ctx, parent.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing variants: %w", err)
+	}
+	if len(existing) > 0 {
+		if dimensions, err = mergeVariantDimensions(parent.VariantDimensions, dimensions); err != nil {
+			return nil, err
+		}
+	}
+	taken := make(map[string]bool, len(existing))
+	for _, variant := range existing {
+		taken[dimensions.Key(variant.VariantOptions)] = true
+	}
+
+	var variants []*entity.SKU
+	var codes []string
+	for _, options := range dimensions.Combinations() {
+		if taken[dimensions.Key(options)] {
+			continue
+		}
+		variant, err := newVariant(parent, dimensions, options, req.Price)
+		if err != nil {
+			return nil, err
+		}
+		variants = append(variants, variant)
+		codes = append(codes, variant.SKUCode)
+	}
+	if len(variants) > entity.MaxSKUVariants {
+		return nil, ErrTooManyVariants
+	}
+	if len(codes) > 0 {
+		clashing, err := u.repo.GetSKUsBySKUCodes(ctx, codes)
+		if err != nil {
+			return nil, fmt.Errorf("error checking SKU codes: %w", err)
+		}
+		if len(clashing) > 0 {
+			return nil, entity.NewError(entity.ErrCodeConflict, fmt.Sprintf("SKU code %s already exists", clashing[0].SKUCode))
+		}
+	}
+
+	parent.VariantDimensions = dimensions
+	if err := u.repo.CreateVariants(ctx, parent, variants); err != nil {
+		return nil, fmt.Errorf("error creating variants: %w", err)
+	}
+	u.skuCache.Invalidate(ctx, parent.ID)
+	return u.GetVariantMatrix(ctx, parent.ID)
+}
+
+// mergeVariantDimensions adds the values of requested dimensions to those of
+// a parent item with variants, which must be the same dimensions in the same
+// order
+func mergeVariantDimensions(current, requested entity.SKUVariantDimensions) (entity.SKUVariantDimensions, error) {
+	if len(current) != len(requested) {
+		return nil, ErrVariantDimensions
+	}
+	merged := make(entity.SKUVariantDimensions, len(current))
+	for i, dimension := range current {
+		if requested[i].Name != dimension.Name {
+			return nil, ErrVariantDimensions
+		}
+		values := append([]string(nil), dimension.Values...)
+		for _, value := range requested[i].Values {
+			known := false
+			for _, v := range dimension.Values {
+				known = known || v == value
+			}
+			if !known {
+				values = append(values, value)
+			}
+		}
+		merged[i] = entity.SKUVariantDimension{Name: dimension.Name, Values: values}
+	}
+	return merged, nil
+}
+
+// newVariant makes the variant of a parent item with the given options
+func newVariant(parent *entity.SKU, dimensions entity.SKUVariantDimensions, options entity.JSONMap, price *float64) (*entity.SKU, error) {
+	code := parent.SKUCode
+	labels := make([]string, len(dimensions))
+	for i, dimension := range dimensions {
+		value := options[dimension.Name].(string)
+		part := strings.Trim(variantCodeSeparator.ReplaceAllString(strings.ToUpper(value), "-"), "-")
+		if part == "" {
+			return nil, entity.NewError(entity.ErrCodeInvalidArgument, fmt.Sprintf("variant value %q makes no SKU code", value))
+		}
+		code += "-" + part
+		labels[i] = value
+	}
+
+	variant := &entity.SKU{
+		SKUCode:        code,
+		Name:           fmt.Sprintf("%s (%s)", parent.Name, strings.Join(labels, ", ")),
+		Description:    parent.Description,
+		UnitOfMeasure:  parent.UnitOfMeasure,
+		Price:          parent.Price,
+		ReorderPoint:   parent.ReorderPoint,
+		Category:       parent.Category,
+		TechnicalSpecs: parent.TechnicalSpecs,
+		ManufacturerID: parent.ManufacturerID,
+		VendorID:       parent.VendorID,
+		ImageURL:       parent.ImageURL,
+		Status:         parent.Status,
+		CustomFields:   parent.CustomFields,
+		ParentID:       &parent.ID,
+		VariantOptions: options,
+		CustomsData:    parent.CustomsData,
+	}
+	if price != nil {
+		variant.Price = *price
+	}
+	return variant, nil
 }
 
 // ListSKUs lists SKUs with filters
@@ -119,17 +485,59 @@ func (u *SKUUseCase) ListSKUs(ctx context.Context, filter *entity.SKUFilter, pag
 		pageSize = 10
 	}
 
+	if err := u.checkFilter(ctx, filter); err != nil {
+		return nil, 0, err
+	}
+
+	skus, total, err := u.repo.ListSKUs(ctx, filter, page, pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+	u.imageUC.signSKUs(ctx, skus)
+	return skus, total, nil
+}
+
+// ExportSKUs lays the SKUs matching a filter out for an export, with a column
+// for each custom field defined on SKUs
+func (u *SKUUseCase) ExportSKUs(ctx context.Context, filter *entity.SKUFilter) ([]export.Column, [][]interface{}, error) {
+	if err := u.checkFilter(ctx, filter); err != nil {
+		return nil, nil, err
+	}
+	skus, _, err := u.repo.ListSKUs(ctx, filter, 1, skuExportLimit)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error listing SKUs: %w", err)
+	}
+	return customFieldTable(ctx, u.customFieldUC, entity.CustomFieldEntitySKU, skus, func(sku *entity.SKU) entity.JSONMap {
+		return sku.CustomFields
+	})
+}
+
+// checkFilter validates the price range of a SKU filter and parses the custom
+// field values it holds
+func (u *SKUUseCase) checkFilter(ctx context.Context, filter *entity.SKUFilter) error {
+	if filter == nil {
+		return nil
+	}
 	// Validate price range if provided
-	if filter != nil && filter.MinPrice != nil && filter.MaxPrice != nil {
+	if filter.MinPrice != nil && filter.MaxPrice != nil {
 		if *filter.MinPrice > *filter.MaxPrice {
-			return nil, 0, ErrInvalidPriceRange
+			return ErrInvalidPriceRange
 		}
 	}
+	if err := checkSKUClasses(filter.ABCClass, filter.XYZClass); err != nil {
+		return err
+	}
 
-	return u.repo.ListSKUs(ctx, filter, page, pageSize)
+	values, err := u.customFieldUC.ParseFilter(ctx, entity.CustomFieldEntitySKU, filter.CustomFields)
+	if err != nil {
+		return err
+	}
+	filter.CustomFields = values
+	return nil
 }
 
-// SearchSKUs searches for SKUs based on a search term
+// SearchSKUs searches SKUs by code, name and description, best match first.
+// Misspelt words still match; without a search term every SKU is listed.
 func (u *SKUUseCase) SearchSKUs(ctx context.Context, searchTerm string, page, pageSize int) ([]entity.SKU, int64, error) {
 	// Validate page and pageSize
 	if page < 1 {
@@ -139,7 +547,38 @@ func (u *SKUUseCase) SearchSKUs(ctx context.Context, searchTerm string, page, pa
 		pageSize = 10
 	}
 
-	return u.repo.SearchSKUs(ctx, searchTerm, page, pageSize)
+	searchTerm = strings.TrimSpace(searchTerm)
+	if searchTerm == "" {
+		return u.ListSKUs(ctx, nil, page, pageSize)
+	}
+
+	types := []entity.SearchEntityType{entity.SearchSKU}
+	hits, facets, err := u.searchRepo.Search(ctx, searchTerm, types, types, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error searching SKUs: %w", err)
+	}
+	ids := make([]string, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.ID
+	}
+	found, err := u.repo.GetSKUsByIDs(ctx, ids)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error getting SKUs: %w", err)
+	}
+
+	// Keep the order of relevance
+	byID := make(map[string]entity.SKU, len(found))
+	for _, sku := range found {
+		byID[sku.ID] = sku
+	}
+	skus := make([]entity.SKU, 0, len(ids))
+	for _, id := range ids {
+		if sku, ok := byID[id]; ok {
+			skus = append(skus, sku)
+		}
+	}
+	u.imageUC.signSKUs(ctx, skus)
+	return skus, facets[entity.SearchSKU], nil
 }
 
 // CreateSKUCategory creates a new SKU category
@@ -152,7 +591,11 @@ func (u *SKUUseCase) CreateSKUCategory(ctx context.Context, category *entity.SKU
 		}
 	}
 
-	return u.repo.CreateSKUCategory(ctx, category)
+	if err := u.repo.CreateSKUCategory(ctx, category); err != nil {
+		return err
+	}
+	u.categoryCache.Invalidate(ctx, categoryTreeKey)
+	return nil
 }
 
 // UpdateSKUCategory updates an existing SKU category
@@ -167,7 +610,7 @@ func (u *SKUUseCase) UpdateSKUCategory(ctx context.Context, category *entity.SKU
 	if category.ParentID != nil && *category.ParentID != "" {
 		// Prevent circular reference
 		if *category.ParentID == category.ID {
-			return errors.New("category cannot be its own parent")
+			return entity.NewError(entity.ErrCodeInvalidArgument, "category cannot be its own parent")
 		}
 
 		_, err := u.repo.GetSKUCategoryByID(ctx, *category.ParentID)
@@ -176,7 +619,11 @@ func (u *SKUUseCase) UpdateSKUCategory(ctx context.Context, category *entity.SKU
 		}
 	}
 
-	return u.repo.UpdateSKUCategory(ctx, category)
+	if err := u.repo.UpdateSKUCategory(ctx, category); err != nil {
+		return err
+	}
+	u.categoryCache.Invalidate(ctx, categoryTreeKey)
+	return nil
 }
 
 // GetSKUCategory gets a SKU category by ID
@@ -197,7 +644,11 @@ func (u *SKUUseCase) DeleteSKUCategory(ctx context.Context, id string) error {
 	}
 
 	// Repository will check if category has children or is used by SKUs
-	return u.repo.DeleteSKUCategory(ctx, id)
+	if err := u.repo.DeleteSKUCategory(ctx, id); err != nil {
+		return err
+	}
+	u.categoryCache.Invalidate(ctx, categoryTreeKey)
+	return nil
 }
 
 // ListSKUCategories lists all SKU categories
@@ -205,9 +656,16 @@ func (u *SKUUseCase) ListSKUCategories(ctx context.Context) ([]entity.SKUCategor
 	return u.repo.ListSKUCategories(ctx)
 }
 
-// GetSKUCategoriesTree gets SKU categories in a hierarchical structure
+// GetSKUCategoriesTree gets SKU categories in a hierarchical structure, from
+// the cache when it holds it
 func (u *SKUUseCase) GetSKUCategoriesTree(ctx context.Context) ([]entity.SKUCategory, error) {
-	return u.repo.GetSKUCategoriesTree(ctx)
+	categories, err := cache.Load(ctx, u.categoryCache, categoryTreeKey, func() ([]entity.SKUCategory, error) {
+		return u.repo.GetSKUCategoriesTree(ctx)
+	})
+	if categories == nil && err == nil {
+		categories = []entity.SKUCategory{}
+	}
+	return categories, err
 }
 
 // GetSKUsByCategory gets SKUs by category
@@ -226,38 +684,123 @@ func (u *SKUUseCase) GetSKUsByCategory(ctx context.Context, categoryID string, p
 		pageSize = 10
 	}
 
-	return u.repo.GetSKUsByCategory(ctx, categoryID, page, pageSize)
+	skus, total, err := u.repo.GetSKUsByCategory(ctx, categoryID, page, pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+	u.imageUC.signSKUs(ctx, skus)
+	return skus, total, nil
 }
 
 // BulkCreateSKUs creates multiple SKUs in a single transaction
 func (u *SKUUseCase) BulkCreateSKUs(ctx context.Context, skus []*entity.SKU) error {
-	// Validate all SKUs first
+	if err := u.validateBulkCreate(ctx, skus); err != nil {
+		return err
+	}
+	return u.repo.BulkCreateSKUs(ctx, skus)
+}
+
+// BulkUpdateSKUs updates multiple SKUs in a single transaction
+func (u *SKUUseCase) BulkUpdateSKUs(ctx context.Context, skus []*entity.SKU) error {
+	if err := u.validateBulkUpdate(ctx, skus); err != nil {
+		return err
+	}
+	if err := u.repo.BulkUpdateSKUs(ctx, skus); err != nil {
+		return err
+	}
+	u.invalidateSKUs(ctx, skus)
+	return nil
+}
+
+// QueueBulkCreateSKUs validates SKUs and queues the job creating them
+func (u *SKUUseCase) QueueBulkCreateSKUs(ctx context.Context, skus []*entity.SKU, userID *uint) (*entity.Job, error) {
+	if err := u.validateBulkCreate(ctx, skus); err != nil {
+		return nil, err
+	}
+	return u.jobUC.Enqueue(ctx, entity.JobSKUBulkCreate, skus, userID)
+}
+
+// QueueBulkUpdateSKUs validates SKUs and queues the job updating them
+func (u *SKUUseCase) QueueBulkUpdateSKUs(ctx context.Context, skus []*entity.SKU, userID *uint) (*entity.Job, error) {
+	if err := u.validateBulkUpdate(ctx, skus); err != nil {
+		return nil, err
+	}
+	return u.jobUC.Enqueue(ctx, entity.JobSKUBulkUpdate, skus, userID)
+}
+
+// validateBulkCreate validates all SKUs of a bulk creation
+func (u *SKUUseCase) validateBulkCreate(ctx context.Context, skus []*entity.SKU) error {
 	for i, sku := range skus {
 		if err := u.validateSKU(ctx, sku); err != nil {
 			return fmt.Errorf("validation failed for SKU at index %d: %w", i, err)
 		}
 	}
-
-	return u.repo.BulkCreateSKUs(ctx, skus)
+	return nil
 }
 
-// BulkUpdateSKUs updates multiple SKUs in a single transaction
-func (u *SKUUseCase) BulkUpdateSKUs(ctx context.Context, skus []*entity.SKU) error {
-	// Validate all SKUs first
+// validateBulkUpdate validates all SKUs of a bulk update
+func (u *SKUUseCase) validateBulkUpdate(ctx context.Context, skus []*entity.SKU) error {
 	for i, sku := range skus {
 		// Check if SKU exists
-		_, err := u.repo.GetSKUByID(ctx, sku.ID)
+		existing, err := u.repo.GetSKUByID(ctx, sku.ID)
 		if err != nil {
-			return fmt.Errorf("SKU at index %d not found: %s", i, sku.ID)
+			return entity.NewError(entity.ErrCodeNotFound, fmt.Sprintf("SKU at index %d not found: %s", i, sku.ID))
 		}
+		keepManaged(sku, existing)
 
 		// Validate SKU data
 		if sku.Price < 0 {
-			return fmt.Errorf("invalid price for SKU at index %d: %s", i, sku.ID)
+			return entity.NewError(entity.ErrCodeInvalidArgument, fmt.Sprintf("invalid price for SKU at index %d: %s", i, sku.ID))
+		}
+		if err := checkCustomsData(&sku.CustomsData); err != nil {
+			return fmt.Errorf("validation failed for SKU at index %d: %w", i, err)
 		}
+		if err := u.checkCustomFields(ctx, sku); err != nil {
+			return fmt.Errorf("validation failed for SKU at index %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// runBulkCreateJob creates the SKUs of a sku.bulk_create job. SKUs that became
+// invalid since the job was queued fail the job without a retry.
+func (u *SKUUseCase) runBulkCreateJob(ctx context.Context, job *entity.Job, progress func(int)) (interface{}, error) {
+	var skus []*entity.SKU
+	if err := json.Unmarshal(job.Payload, &skus); err != nil {
+		return nil, permanent(fmt.Errorf("invalid job payload: %w", err))
+	}
+	if err := u.validateBulkCreate(ctx, skus); err != nil {
+		return nil, permanent(err)
 	}
+	if err := u.repo.BulkCreateSKUs(ctx, skus); err != nil {
+		return nil, err
+	}
+	return map[string]int{"created": len(skus)}, nil
+}
 
-	return u.repo.BulkUpdateSKUs(ctx, skus)
+// runBulkUpdateJob updates the SKUs of a sku.bulk_update job
+func (u *SKUUseCase) runBulkUpdateJob(ctx context.Context, job *entity.Job, progress func(int)) (interface{}, error) {
+	var skus []*entity.SKU
+	if err := json.Unmarshal(job.Payload, &skus); err != nil {
+		return nil, permanent(fmt.Errorf("invalid job payload: %w", err))
+	}
+	if err := u.validateBulkUpdate(ctx, skus); err != nil {
+		return nil, permanent(err)
+	}
+	if err := u.repo.BulkUpdateSKUs(ctx, skus); err != nil {
+		return nil, err
+	}
+	u.invalidateSKUs(ctx, skus)
+	return map[string]int{"updated": len(skus)}, nil
+}
+
+// invalidateSKUs drops updated SKUs from the cache
+func (u *SKUUseCase) invalidateSKUs(ctx context.Context, skus []*entity.SKU) {
+	ids := make([]string, len(skus))
+	for i, sku := range skus {
+		ids[i] = sku.ID
+	}
+	u.skuCache.Invalidate(ctx, ids...)
 }
 
 // GetSKUsByIDs gets SKUs by their IDs
@@ -0,0 +1,131 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
+)
+
+var (
+	ErrNoStockSnapshot   = entity.NewError(entity.ErrCodeFailedPrecondition, "no stock snapshot was taken on or before the date")
+	ErrStockSnapshotDate = entity.NewError(entity.ErrCodeInvalidArgument, "stock snapshots are of past days")
+)
+
+// maxSnapshotBackfillDays caps the missed days a snapshot run catches up on
+const maxSnapshotBackfillDays = 31
+
+// StockSnapshotUseCase takes the daily closing stock snapshots per SKU and
+// store that point-in-time stock reports are read from
+type StockSnapshotUseCase struct {
+	repo *repository.StockSnapshotRepository
+}
+
+// NewStockSnapshotUseCase creates a new stock snapshot use case
+func NewStockSnapshotUseCase(repo *repository.StockSnapshotRepository) *StockSnapshotUseCase {
+	return &StockSnapshotUseCase{repo: repo}
+}
+
+// TakeSnapshot stores the closing stock of a past day per SKU and store,
+// replacing the snapshot already taken of it. The stock is the current stock
+// less the changes recorded since the day ended, valued at the SKU price.
+func (u *StockSnapshotUseCase) TakeSnapshot(ctx context.Context, day time.Time) (*entity.StockSnapshotResult, error) {
+	day = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.Local)
+	end := day.AddDate(0, 0, 1)
+	if end.After(time.Now()) {
+		return nil, ErrStockSnapshotDate
+	}
+
+	snapshots, err := u.repo.ClosingStock(ctx, end)
+	if err != nil {
+		return nil, fmt.Errorf("error computing closing stock: %w", err)
+	}
+	result := &entity.StockSnapshotResult{SnapshotDate: day, Lines: len(snapshots)}
+	for i := range snapshots {
+		snapshots[i].SnapshotDate = day
+		snapshots[i].Value = roundAmount(snapshots[i].Quantity * snapshots[i].UnitCost)
+		result.TotalValue += snapshots[i].Value
+	}
+	result.TotalValue = roundAmount(result.TotalValue)
+
+	if err := u.repo.ReplaceSnapshot(ctx, day, snapshots); err != nil {
+		return nil, fmt.Errorf("error saving stock snapshot: %w", err)
+	}
+	return result, nil
+}
+
+// SnapshotMissing takes the snapshots of the days closed since the latest
+// snapshot, up to yesterday and at most the last 31 days. The first run takes
+// yesterday only.
+func (u *StockSnapshotUseCase) SnapshotMissing(ctx context.Context) ([]entity.StockSnapshotResult, error) {
+	now := time.Now()
+	yesterday := time.Date(now.Year(), now.Month(), now.Day()-1, 0, 0, 0, 0, time.Local)
+
+	latest, err := u.repo.LatestDate(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error getting latest stock snapshot: %w", err)
+	}
+	from := yesterday
+	if latest != nil {
+		from = time.Date(latest.Year(), latest.Month(), latest.Day()+1, 0, 0, 0, 0, time.Local)
+	}
+	if earliest := yesterday.AddDate(0, 0, 1-maxSnapshotBackfillDays); from.Before(earliest) {
+		from = earliest
+	}
+
+	var results []entity.StockSnapshotResult
+	for day := from; !day.After(yesterday); day = day.AddDate(0, 0, 1) {
+		result, err := u.TakeSnapshot(ctx, day)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, *result)
+	}
+	return results, nil
+}
+
+// Report returns the stock per SKU and store at the close of a past day,
+// yesterday by default, from the latest snapshot taken on or before it
+func (u *StockSnapshotUseCase) Report(ctx context.Context, filter *entity.StockSnapshotFilter) (*entity.StockSnapshotReport, error) {
+	now := time.Now()
+	if filter.Date.IsZero() {
+		filter.Date = time.Date(now.Year(), now.Month(), now.Day()-1, 0, 0, 0, 0, time.Local)
+	}
+	if filter.Date.Format("2006-01-02") >= now.Format("2006-01-02") {
+		return nil, ErrStockSnapshotDate
+	}
+	if filter.StoreID != "" {
+		if err := entity.AccessScopeFromContext(ctx).CheckStore(filter.StoreID); err != nil {
+			return nil, err
+		}
+	}
+
+	lines, snapshotDate, err := u.repo.ListSnapshot(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("error listing stock snapshot: %w", err)
+	}
+	if snapshotDate == nil {
+		return nil, ErrNoStockSnapshot
+	}
+
+	report := &entity.StockSnapshotReport{
+		Date:         filter.Date,
+		SnapshotDate: *snapshotDate,
+		StoreID:      filter.StoreID,
+		SKUID:        filter.SKUID,
+		Lines:        lines,
+	}
+	if report.Lines == nil {
+		report.Lines = []entity.StockSnapshot{}
+	}
+	for _, line := range lines {
+		report.TotalQuantity += line.Quantity
+		report.TotalValue += line.Value
+	}
+	report.TotalQuantity = math.Round(report.TotalQuantity*1000) / 1000
+	report.TotalValue = roundAmount(report.TotalValue)
+	return report, nil
+}
@@ -2,32 +2,83 @@ package usecase
 
 import (
 	"context"
+	"fmt"
+	"math"
+	"time"
 
 	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/eventbus"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/export"
 	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
 )
 
+var ErrInvalidLedgerPeriod = entity.NewError(entity.ErrCodeInvalidArgument, "start date must not be after end date")
+
+// ledgerDocumentPaths are the API paths of the source documents of stock
+// movements, by document type
+var ledgerDocumentPaths = map[string]string{
+	entity.LedgerDocumentPurchaseReceipt: "/api/purchase/receipts/%s",
+	entity.LedgerDocumentDeliveryOrder:   "/api/v1/orders/deliveries/%s",
+	entity.LedgerDocumentSalesOrder:      "/api/v1/orders/%s",
+	entity.LedgerDocumentProductionOrder: "/api/v1/manufacturing/orders/%s/issues",
+}
+
 type StocksUseCase struct {
 	repo      *repository.StocksRepository
 	storeRepo *repository.StoreRepository
+	skuRepo   *repository.SKURepository
+	fiscalUC  *FiscalUseCase
+	bus       *eventbus.Bus
 }
 
-func NewStocksUseCase(repo *repository.StocksRepository, storeRepo *repository.StoreRepository) *StocksUseCase {
+func NewStocksUseCase(repo *repository.StocksRepository, storeRepo *repository.StoreRepository, skuRepo *repository.SKURepository, fiscalUC *FiscalUseCase, bus *eventbus.Bus) *StocksUseCase {
 	return &StocksUseCase{
 		repo:      repo,
 		storeRepo: storeRepo,
+		skuRepo:   skuRepo,
+		fiscalUC:  fiscalUC,
+		bus:       bus,
 	}
 }
 
 func (u *StocksUseCase) GetStock(ctx context.Context, id string) (*entity.Stock, error) {
-	return u.repo.GetByID(ctx, id)
+	stock, err := u.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := entity.AccessScopeFromContext(ctx).CheckStore(stock.StoreID); err != nil {
+		return nil, err
+	}
+	return stock, nil
 }
 
 func (u *StocksUseCase) ListStocks(ctx context.Context, filter *entity.StockFilter) ([]entity.Stock, error) {
 	return u.repo.List(ctx, filter)
 }
 
+// ListStockEntries lists the stock entries matching a filter by page number
+func (u *StocksUseCase) ListStockEntries(ctx context.Context, filter *entity.StockEntryFilter) ([]entity.StockEntry, int64, error) {
+	return u.repo.ListStockEntries(ctx, filter)
+}
+
+// ListStockEntriesPage lists the page of stock entries following a cursor,
+// with the cursor of the next page when there is one
+func (u *StocksUseCase) ListStockEntriesPage(ctx context.Context, filter *entity.StockEntryFilter, page entity.CursorPage) ([]entity.StockEntry, *entity.Cursor, error) {
+	return u.repo.ListStockEntriesPage(ctx, filter, page)
+}
+
 func (u *StocksUseCase) ProcessStockEntry(ctx context.Context, entry *entity.StockEntry, userID string) error {
+	if entry.ConsignmentVendorID != nil {
+		return ErrConsignmentStockEntry
+	}
+	if err := entity.AccessScopeFromContext(ctx).CheckStore(entry.StoreID); err != nil {
+		return err
+	}
+	// Stock entries are posted today
+	if err := u.fiscalUC.CheckOpen(ctx, time.Now()); err != nil {
+		return err
+	}
+
 	// Validate store exists and is active
 	store, err := u.storeRepo.GetByID(ctx, entry.StoreID)
 	if err != nil {
@@ -38,10 +89,18 @@ func (u *StocksUseCase) ProcessStockEntry(ctx context.Context, entry *entity.Sto
 	}
 
 	// Process stock entry with transaction
-	return u.repo.ProcessStockEntry(ctx, entry, userID)
+	if err := u.repo.ProcessStockEntry(ctx, entry, userID); err != nil {
+		return err
+	}
+
+	u.bus.Publish(ctx, entity.StockEntryCreated{Entries: []entity.StockEntry{*entry}})
+	return nil
 }
 
 func (u *StocksUseCase) CheckStock(ctx context.Context, skuID string, storeID string) (*entity.Stock, error) {
+	if err := entity.AccessScopeFromContext(ctx).CheckStore(storeID); err != nil {
+		return nil, err
+	}
 	stock, err := u.repo.GetBySKUAndStore(ctx, skuID, storeID)
 	if err != nil {
 		if err == repository.ErrRecordNotFound {
@@ -57,26 +116,108 @@ func (u *StocksUseCase) CheckStock(ctx context.Context, skuID string, storeID st
 	return stock, nil
 }
 
+// AvailableToPromise returns the quantity of a SKU a store can still promise
+// to new orders. Kits are not stocked: their availability is the whole kits
+// the available stock of their components makes up.
+func (u *StocksUseCase) AvailableToPromise(ctx context.Context, skuID string, storeID string) (*entity.AvailableToPromise, error) {
+	if err := entity.AccessScopeFromContext(ctx).CheckStore(storeID); err != nil {
+		return nil, err
+	}
+	kits, err := u.skuRepo.GetKitComponents(ctx, []string{skuID})
+	if err != nil {
+		return nil, err
+	}
+
+	components := kits[skuID]
+	if len(components) == 0 {
+		return u.availableToPromise(ctx, skuID, storeID)
+	}
+
+	atp := &entity.AvailableToPromise{SKUID: skuID, StoreID: storeID, Kit: true}
+	for _, component := range components {
+		available, err := u.availableToPromise(ctx, component.ComponentID, storeID)
+		if err != nil {
+			return nil, err
+		}
+		atp.Components = append(atp.Components, entity.ComponentAvailable{
+			SKUID:     component.ComponentID,
+			PerKit:    component.Quantity,
+			Available: available.Available,
+			Kits:      math.Max(math.Floor(available.Available/component.Quantity), 0),
+		})
+	}
+	atp.Available = entity.KitsAvailable(atp.Components)
+	return atp, nil
+}
+
+// availableToPromise returns the stock of a SKU in a store less what active
+// allocations reserve
+func (u *StocksUseCase) availableToPromise(ctx context.Context, skuID string, storeID string) (*entity.AvailableToPromise, error) {
+	atp := &entity.AvailableToPromise{SKUID: skuID, StoreID: storeID}
+	stock, err := u.repo.GetBySKUAndStore(ctx, skuID, storeID)
+	if err == repository.ErrRecordNotFound {
+		return atp, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	reserved, err := u.repo.ReservedQuantity(ctx, skuID, storeID)
+	if err != nil {
+		return nil, err
+	}
+
+	atp.OnHand = stock.Available()
+	atp.Reserved = reserved
+	atp.Available = stock.Available() - reserved
+	return atp, nil
+}
+
 func (u *StocksUseCase) UpdateStockLocation(ctx context.Context, id string, binLocation, shelfNumber, zoneCode string) error {
 	stock, err := u.repo.GetByID(ctx, id)
 	if err != nil {
 		return err
 	}
+	if err := entity.AccessScopeFromContext(ctx).CheckStore(stock.StoreID); err != nil {
+		return err
+	}
 
-	stock.BinLocation = binLocation
-	stock.ShelfNumber = shelfNumber
-	stock.ZoneCode = zoneCode
-
-	return u.repo.CreateOrUpdateStock(ctx, stock)
+	return u.repo.UpdateLocation(ctx, stock.ID, binLocation, shelfNumber, zoneCode)
 }
 
 func (u *StocksUseCase) BatchStockEntry(ctx context.Context, entries []entity.StockEntry, userID string) error {
-	// Process each entry in a separate transaction
+	if err := u.fiscalUC.CheckOpen(ctx, time.Now()); err != nil {
+		return err
+	}
+
+	// Validate each referenced store once
+	scope := entity.AccessScopeFromContext(ctx)
+	checked := make(map[string]bool)
 	for _, entry := range entries {
-		if err := u.ProcessStockEntry(ctx, &entry, userID); err != nil {
+		if entry.ConsignmentVendorID != nil {
+			return ErrConsignmentStockEntry
+		}
+		if checked[entry.StoreID] {
+			continue
+		}
+		if err := scope.CheckStore(entry.StoreID); err != nil {
+			return err
+		}
+		store, err := u.storeRepo.GetByID(ctx, entry.StoreID)
+		if err != nil {
 			return err
 		}
+		if store.Status != entity.StoreStatusActive {
+			return repository.ErrInvalidData
+		}
+		checked[entry.StoreID] = true
 	}
+
+	// Process all entries in a single transaction with bulk inserts
+	if err := u.repo.ProcessStockEntries(ctx, entries, userID); err != nil {
+		return err
+	}
+
+	u.bus.Publish(ctx, entity.StockEntryCreated{Entries: entries})
 	return nil
 }
 
@@ -86,6 +227,118 @@ func (u *StocksUseCase) GetStockHistory(ctx context.Context, stockID string) ([]
 	return []entity.StockHistory{}, nil
 }
 
+// GetStockLedger lists the movements of a SKU in a store between the filter
+// dates, both included, with the balance after each one. The opening balance
+// is the current stock less the movements recorded since the start date.
+func (u *StocksUseCase) GetStockLedger(ctx context.Context, filter *entity.StockLedgerFilter) (*entity.StockLedger, error) {
+	if err := entity.AccessScopeFromContext(ctx).CheckStore(filter.StoreID); err != nil {
+		return nil, err
+	}
+	if filter.From != nil && filter.To != nil && filter.From.After(*filter.To) {
+		return nil, ErrInvalidLedgerPeriod
+	}
+	sku, err := u.skuRepo.GetSKUByID(ctx, filter.SKUID)
+	if err != nil {
+		return nil, ErrSKUNotFound
+	}
+	store, err := u.storeRepo.GetByID(ctx, filter.StoreID)
+	if err != nil {
+		return nil, err
+	}
+
+	quantity, err := u.repo.SumQuantity(ctx, filter.SKUID, filter.StoreID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting stock: %w", err)
+	}
+	movements, err := u.repo.ListMovements(ctx, filter.SKUID, filter.StoreID, filter.From)
+	if err != nil {
+		return nil, fmt.Errorf("error listing stock movements: %w", err)
+	}
+
+	ledger := &entity.StockLedger{
+		SKUID:     sku.ID,
+		SKUCode:   sku.SKUCode,
+		SKUName:   sku.Name,
+		StoreID:   store.ID,
+		StoreName: store.Name,
+		From:      filter.From,
+		To:        filter.To,
+		Movements: []entity.StockMovement{},
+	}
+	balance := quantity
+	for _, movement := range movements {
+		balance -= movement.Change
+	}
+	ledger.OpeningBalance = math.Round(balance*1000) / 1000
+
+	var end time.Time
+	if filter.To != nil {
+		end = filter.To.AddDate(0, 0, 1)
+	}
+	for _, movement := range movements {
+		if !end.IsZero() && !movement.CreatedAt.Before(end) {
+			break
+		}
+		balance += movement.Change
+		movement.Balance = math.Round(balance*1000) / 1000
+		movement.Cost = roundAmount(movement.Change * movement.UnitCost)
+		if path, ok := ledgerDocumentPaths[movement.DocumentType]; ok {
+			movement.DocumentURL = fmt.Sprintf(path, movement.DocumentID)
+		}
+		if movement.Change > 0 {
+			ledger.TotalIn += movement.Change
+		} else {
+			ledger.TotalOut -= movement.Change
+		}
+		ledger.Movements = append(ledger.Movements, movement)
+	}
+	ledger.TotalIn = math.Round(ledger.TotalIn*1000) / 1000
+	ledger.TotalOut = math.Round(ledger.TotalOut*1000) / 1000
+	ledger.ClosingBalance = math.Round(balance*1000) / 1000
+	return ledger, nil
+}
+
+// ExportStockLedger returns the stock ledger of a SKU in a store as a table,
+// its movements between an opening and a closing balance row
+func (u *StocksUseCase) ExportStockLedger(ctx context.Context, filter *entity.StockLedgerFilter) ([]export.Column, [][]interface{}, error) {
+	ledger, err := u.GetStockLedger(ctx, filter)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	columns := []export.Column{
+		{Key: "date", Title: "Date"},
+		{Key: "type", Title: "Type"},
+		{Key: "reference", Title: "Reference"},
+		{Key: "document_type", Title: "Document Type"},
+		{Key: "document_id", Title: "Document ID"},
+		{Key: "batch_number", Title: "Batch"},
+		{Key: "change", Title: "Change"},
+		{Key: "balance", Title: "Balance"},
+		{Key: "unit_cost", Title: "Unit Cost"},
+		{Key: "cost", Title: "Cost"},
+		{Key: "note", Title: "Note"},
+		{Key: "created_by", Title: "Created By"},
+	}
+	day := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.Format("2006-01-02")
+	}
+
+	rows := make([][]interface{}, 0, len(ledger.Movements)+2)
+	rows = append(rows, []interface{}{day(ledger.From), "OPENING", "", "", "", "", "", ledger.OpeningBalance, "", "", "", ""})
+	for _, m := range ledger.Movements {
+		rows = append(rows, []interface{}{
+			m.CreatedAt.Format(time.RFC3339), m.Type, m.Reference, m.DocumentType, m.DocumentID, m.BatchNumber,
+			m.Change, m.Balance, m.UnitCost, m.Cost, m.Note, m.CreatedBy,
+		})
+	}
+	rows = append(rows, []interface{}{day(ledger.To), "CLOSING", "", "", "", "", "", ledger.ClosingBalance, "", "", "", ""})
+	return columns, rows, nil
+}
+
 func (u *StocksUseCase) ValidateStockEntry(entry *entity.StockEntry) error {
 	if entry.Quantity <= 0 {
 		return repository.ErrInvalidData
@@ -16,6 +16,10 @@ func NewStoreUseCase(repo *repository.StoreRepository) *StoreUseCase {
 }
 
 func (u *StoreUseCase) CreateStore(ctx context.Context, store *entity.Store) error {
+	// A new store is outside every store scope
+	if entity.AccessScopeFromContext(ctx).RestrictsStores() {
+		return entity.ErrOutsideAccessScope
+	}
 	if store.Status == "" {
 		store.Status = entity.StoreStatusActive
 	}
@@ -23,6 +27,9 @@ func (u *StoreUseCase) CreateStore(ctx context.Context, store *entity.Store) err
 }
 
 func (u *StoreUseCase) UpdateStore(ctx context.Context, store *entity.Store) error {
+	if err := entity.AccessScopeFromContext(ctx).CheckStore(store.ID); err != nil {
+		return err
+	}
 	existing, err := u.repo.GetByID(ctx, store.ID)
 	if err != nil {
 		return err
@@ -40,6 +47,9 @@ func (u *StoreUseCase) UpdateStore(ctx context.Context, store *entity.Store) err
 }
 
 func (u *StoreUseCase) GetStore(ctx context.Context, id string) (*entity.Store, error) {
+	if err := entity.AccessScopeFromContext(ctx).CheckStore(id); err != nil {
+		return nil, err
+	}
 	return u.repo.GetByID(ctx, id)
 }
 
@@ -48,6 +58,9 @@ func (u *StoreUseCase) ListStores(ctx context.Context, filter *entity.StoreFilte
 }
 
 func (u *StoreUseCase) DeleteStore(ctx context.Context, id string) error {
+	if err := entity.AccessScopeFromContext(ctx).CheckStore(id); err != nil {
+		return err
+	}
 	// Could add additional checks here (e.g., ensure store is empty)
 	return u.repo.Delete(ctx, id)
 }
@@ -55,10 +68,16 @@ func (u *StoreUseCase) DeleteStore(ctx context.Context, id string) error {
 // Additional business logic methods
 
 func (u *StoreUseCase) GetStoreStocks(ctx context.Context, storeID string) ([]entity.Stock, error) {
+	if err := entity.AccessScopeFromContext(ctx).CheckStore(storeID); err != nil {
+		return nil, err
+	}
 	return u.repo.GetStoreStocks(ctx, storeID)
 }
 
 func (u *StoreUseCase) GetStoreStockValue(ctx context.Context, storeID string) (float64, error) {
+	if err := entity.AccessScopeFromContext(ctx).CheckStore(storeID); err != nil {
+		return 0, err
+	}
 	return u.repo.GetStoreStockValue(ctx, storeID)
 }
 
@@ -67,9 +86,15 @@ func (u *StoreUseCase) GetStoresWithLowStock(ctx context.Context, skuIDs []strin
 }
 
 func (u *StoreUseCase) AssignManager(ctx context.Context, storeID string, managerID uint) error {
+	if err := entity.AccessScopeFromContext(ctx).CheckStore(storeID); err != nil {
+		return err
+	}
 	return u.repo.AssignManager(ctx, storeID, managerID)
 }
 
 func (u *StoreUseCase) UpdateStatus(ctx context.Context, storeID string, status entity.StoreStatus) error {
+	if err := entity.AccessScopeFromContext(ctx).CheckStore(storeID); err != nil {
+		return err
+	}
 	return u.repo.UpdateStatus(ctx, storeID, status)
 }
@@ -0,0 +1,102 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/cache"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
+)
+
+const (
+	defaultSlowQueryLimit = 20
+	maxSlowQueryLimit     = 200
+)
+
+var ErrSandboxDisabled = entity.NewError(entity.ErrCodeFailedPrecondition, "sandbox mode is not enabled")
+
+// SystemUseCase handles administrative diagnostics of the platform
+type SystemUseCase struct {
+	systemRepo  *repository.SystemRepository
+	sandboxRepo *repository.SandboxRepository
+	schema      string
+	cache       *cache.Cache
+}
+
+// NewSystemUseCase creates a new system use case. sandboxRepo is nil when sandbox mode is disabled.
+func NewSystemUseCase(systemRepo *repository.SystemRepository, sandboxRepo *repository.SandboxRepository, sandboxSchema string, c *cache.Cache) *SystemUseCase {
+	return &SystemUseCase{
+		systemRepo:  systemRepo,
+		sandboxRepo: sandboxRepo,
+		schema:      sandboxSchema,
+		cache:       c,
+	}
+}
+
+// GetSlowQueries returns the slowest captured statements for query plan review
+func (u *SystemUseCase) GetSlowQueries(ctx context.Context, filter *entity.SlowQueryFilter) ([]entity.SlowQuery, error) {
+	if filter == nil {
+		filter = &entity.SlowQueryFilter{}
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = defaultSlowQueryLimit
+	}
+	if filter.Limit > maxSlowQueryLimit {
+		filter.Limit = maxSlowQueryLimit
+	}
+	if filter.MinMeanTimeMs < 0 {
+		filter.MinMeanTimeMs = 0
+	}
+
+	queries, err := u.systemRepo.GetSlowQueries(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("error getting slow queries: %w", err)
+	}
+	return queries, nil
+}
+
+// GetPoolStats returns connection pool usage so saturation can be monitored
+func (u *SystemUseCase) GetPoolStats(ctx context.Context) (*entity.DBPoolStats, error) {
+	stats, err := u.systemRepo.GetPoolStats(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error getting pool stats: %w", err)
+	}
+	return stats, nil
+}
+
+// GetCacheStats returns the hits and misses of the master data cache, so its
+// effect on database load can be monitored
+func (u *SystemUseCase) GetCacheStats(ctx context.Context) *entity.CacheStats {
+	return u.cache.Stats()
+}
+
+// ProvisionSandbox creates the sandbox schema and any tables it is missing
+func (u *SystemUseCase) ProvisionSandbox(ctx context.Context) error {
+	if u.sandboxRepo == nil {
+		return ErrSandboxDisabled
+	}
+	if err := u.sandboxRepo.Provision(ctx); err != nil {
+		return fmt.Errorf("error provisioning sandbox: %w", err)
+	}
+	return nil
+}
+
+// ResetSandbox discards all sandbox documents and reloads master data from the live schema
+func (u *SystemUseCase) ResetSandbox(ctx context.Context) (*entity.SandboxResetResult, error) {
+	if u.sandboxRepo == nil {
+		return nil, ErrSandboxDisabled
+	}
+
+	copied, err := u.sandboxRepo.Reset(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error resetting sandbox: %w", err)
+	}
+
+	return &entity.SandboxResetResult{
+		Schema:     u.schema,
+		CopiedRows: copied,
+		ResetAt:    time.Now(),
+	}, nil
+}
@@ -0,0 +1,240 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
+)
+
+// failedLoginSourceLimit caps the emails and IP addresses listed in a failed login report
+const failedLoginSourceLimit = 10
+
+var ErrInvalidInactiveDays = entity.NewError(entity.ErrCodeInvalidArgument, "inactive days must be positive")
+
+// UserActivityUseCase summarizes sign-ins and audited actions per user for
+// security review
+type UserActivityUseCase struct {
+	repo         *repository.UserActivityRepository
+	userRepo     entity.UserRepository
+	inactiveDays int
+}
+
+// NewUserActivityUseCase creates a new user activity use case. Active users who
+// have not signed in for inactiveDays are reported as eligible for deactivation.
+func NewUserActivityUseCase(repo *repository.UserActivityRepository, userRepo entity.UserRepository, inactiveDays int) *UserActivityUseCase {
+	if inactiveDays <= 0 {
+		inactiveDays = 90
+	}
+	return &UserActivityUseCase{
+		repo:         repo,
+		userRepo:     userRepo,
+		inactiveDays: inactiveDays,
+	}
+}
+
+// RecordLogin records a sign-in attempt for the given email, linked to the user
+// it belongs to if any. A failed attempt carries the reason it was refused.
+func (u *UserActivityUseCase) RecordLogin(ctx context.Context, email, ip, userAgent string, loginErr error) error {
+	attempt := &entity.LoginAttempt{
+		Email:     strings.ToLower(strings.TrimSpace(email)),
+		Success:   loginErr == nil,
+		IP:        ip,
+		UserAgent: userAgent,
+		CreatedAt: time.Now(),
+	}
+	if loginErr != nil {
+		attempt.Reason = loginErr.Error()
+	}
+	if user, err := u.userRepo.FindByEmail(email); err == nil {
+		attempt.UserID = &user.ID
+	}
+
+	if err := u.repo.CreateLoginAttempt(ctx, attempt); err != nil {
+		return fmt.Errorf("error recording login attempt: %w", err)
+	}
+	return nil
+}
+
+// ListLoginAttempts lists the sign-in attempts matching a filter
+func (u *UserActivityUseCase) ListLoginAttempts(ctx context.Context, filter *entity.LoginAttemptFilter) ([]entity.LoginAttempt, int64, error) {
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+	if filter.PageSize <= 0 {
+		filter.PageSize = 20
+	}
+
+	attempts, total, err := u.repo.ListLoginAttempts(ctx, filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error listing login attempts: %w", err)
+	}
+	return attempts, total, nil
+}
+
+// FailedLogins reports the failed sign-ins matching a filter, with the emails
+// and IP addresses they came from most often
+func (u *UserActivityUseCase) FailedLogins(ctx context.Context, filter *entity.LoginAttemptFilter) (*entity.FailedLoginReport, error) {
+	failed := false
+	filter.Success = &failed
+
+	attempts, total, err := u.ListLoginAttempts(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	byEmail, err := u.repo.TopLoginSources(ctx, filter, "email", failedLoginSourceLimit)
+	if err != nil {
+		return nil, fmt.Errorf("error counting failed logins by email: %w", err)
+	}
+	byIP, err := u.repo.TopLoginSources(ctx, filter, "ip", failedLoginSourceLimit)
+	if err != nil {
+		return nil, fmt.Errorf("error counting failed logins by IP: %w", err)
+	}
+
+	return &entity.FailedLoginReport{
+		Total:     total,
+		ByEmail:   byEmail,
+		ByIP:      byIP,
+		Attempts:  attempts,
+		Page:      filter.Page,
+		PageSize:  filter.PageSize,
+		StartDate: filter.StartDate,
+		EndDate:   filter.EndDate,
+	}, nil
+}
+
+// ActivitySummary summarizes the sign-ins and audited actions of every user, or
+// of the filtered one, in a period, most recently seen first. Users never seen
+// are listed last.
+func (u *UserActivityUseCase) ActivitySummary(ctx context.Context, filter *entity.UserActivityFilter) ([]entity.UserActivitySummary, error) {
+	users, err := u.repo.ListUsers(ctx, filter.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing users: %w", err)
+	}
+
+	logins, err := u.repo.CountLogins(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("error counting logins: %w", err)
+	}
+	modules, err := u.repo.CountModuleActivity(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("error counting user actions: %w", err)
+	}
+
+	summaries := make([]entity.UserActivitySummary, len(users))
+	index := make(map[uint]int, len(users))
+	for i, user := range users {
+		summaries[i] = entity.UserActivitySummary{
+			UserID:    user.ID,
+			Username:  user.Username,
+			Email:     user.Email,
+			Status:    user.Status,
+			LastLogin: user.LastLogin,
+			Modules:   make(map[string]int64),
+		}
+		if user.Role != nil {
+			summaries[i].Role = user.Role.Name
+		}
+		// Sign-ins before login attempts were recorded only show on the user
+		if last := user.LastLogin; last != nil &&
+			(filter.StartDate == nil || !last.Before(*filter.StartDate)) &&
+			(filter.EndDate == nil || !last.After(*filter.EndDate)) {
+			markSeen(&summaries[i], *last)
+		}
+		index[user.ID] = i
+	}
+
+	for _, count := range logins {
+		i, ok := index[count.UserID]
+		if !ok {
+			continue
+		}
+		if count.Success {
+			summaries[i].Logins += count.Count
+			markSeen(&summaries[i], count.Last)
+		} else {
+			summaries[i].FailedLogins += count.Count
+		}
+	}
+	for _, activity := range modules {
+		i, ok := index[activity.UserID]
+		if !ok {
+			continue
+		}
+		module := activity.Module
+		if module == "" {
+			module = "other"
+		}
+		summaries[i].Modules[module] += activity.Actions
+		summaries[i].Actions += activity.Actions
+		markSeen(&summaries[i], activity.Last)
+	}
+
+	sort.SliceStable(summaries, func(i, j int) bool {
+		a, b := summaries[i].LastSeen, summaries[j].LastSeen
+		if a == nil || b == nil {
+			return a != nil
+		}
+		return a.After(*b)
+	})
+	return summaries, nil
+}
+
+// InactiveAccounts lists the active users who have not signed in for the given
+// number of days, or the configured threshold when zero, longest inactive first
+func (u *UserActivityUseCase) InactiveAccounts(ctx context.Context, days int) ([]entity.InactiveAccount, error) {
+	if days < 0 {
+		return nil, ErrInvalidInactiveDays
+	}
+	if days == 0 {
+		days = u.inactiveDays
+	}
+
+	now := time.Now()
+	users, err := u.repo.ListInactiveUsers(ctx, now.AddDate(0, 0, -days))
+	if err != nil {
+		return nil, fmt.Errorf("error listing inactive users: %w", err)
+	}
+
+	accounts := make([]entity.InactiveAccount, 0, len(users))
+	for _, user := range users {
+		since := user.CreatedAt
+		if user.LastLogin != nil {
+			since = *user.LastLogin
+		}
+		account := entity.InactiveAccount{
+			UserID:       user.ID,
+			Username:     user.Username,
+			Email:        user.Email,
+			LastLogin:    user.LastLogin,
+			CreatedAt:    user.CreatedAt,
+			InactiveDays: int(math.Floor(now.Sub(since).Hours() / 24)),
+		}
+		if user.Role != nil {
+			account.Role = user.Role.Name
+		}
+		accounts = append(accounts, account)
+	}
+	return accounts, nil
+}
+
+// InactiveDays returns the configured inactivity threshold in days
+func (u *UserActivityUseCase) InactiveDays() int {
+	return u.inactiveDays
+}
+
+// markSeen moves a summary's last seen time forward to at
+func markSeen(summary *entity.UserActivitySummary, at time.Time) {
+	if at.IsZero() {
+		return
+	}
+	if summary.LastSeen == nil || at.After(*summary.LastSeen) {
+		summary.LastSeen = &at
+	}
+}
@@ -0,0 +1,500 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/mail"
+	"strconv"
+	"strings"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// maxUserImportRows caps the users in one CSV import
+const maxUserImportRows = 5000
+
+var (
+	ErrInvalidUserImport       = entity.NewError(entity.ErrCodeInvalidArgument, "user import must be a CSV file with an email column")
+	ErrUserImportTooLarge      = entity.NewError(entity.ErrCodeInvalidArgument, "user import has too many rows")
+	ErrProvisionInvalidEmail   = entity.NewError(entity.ErrCodeInvalidArgument, "a valid email is required")
+	ErrProvisionNoRole         = entity.NewError(entity.ErrCodeFailedPrecondition, "no role is mapped to the user's groups and no default role is configured")
+	ErrProvisionIdentityTaken  = entity.NewError(entity.ErrCodeConflict, "username or email belongs to another user")
+	ErrProvisionedUserNotFound = entity.NewError(entity.ErrCodeNotFound, "user not found")
+	ErrProvisionedUserExists   = entity.NewError(entity.ErrCodeConflict, "user already exists")
+	ErrRoleMappingNotFound     = entity.NewError(entity.ErrCodeNotFound, "role mapping not found")
+	ErrRoleMappingExists       = entity.NewError(entity.ErrCodeConflict, "directory group is already mapped to a role")
+	ErrRoleMappingRole         = entity.NewError(entity.ErrCodeInvalidArgument, "role not found")
+)
+
+// UserProvisioningUseCase creates, updates and deactivates users from the
+// corporate directory, through CSV imports or SCIM, assigning roles from the
+// directory groups they belong to
+type UserProvisioningUseCase struct {
+	repo        *repository.UserProvisioningRepository
+	roleRepo    entity.RoleRepository
+	defaultRole string
+	jobUC       *JobUseCase
+}
+
+// NewUserProvisioningUseCase creates a new user provisioning use case. New users
+// whose groups map to no role get defaultRole, when set. CSV imports run as
+// jobs of jobUC.
+func NewUserProvisioningUseCase(repo *repository.UserProvisioningRepository, roleRepo entity.RoleRepository, defaultRole string, jobUC *JobUseCase) *UserProvisioningUseCase {
+	u := &UserProvisioningUseCase{
+		repo:        repo,
+		roleRepo:    roleRepo,
+		defaultRole: strings.TrimSpace(defaultRole),
+		jobUC:       jobUC,
+	}
+	jobUC.Register(entity.JobUserImport, u.runImportJob)
+	return u
+}
+
+// CreateRoleMapping maps a directory group to a role
+func (u *UserProvisioningUseCase) CreateRoleMapping(ctx context.Context, req *entity.RoleMappingRequest) (*entity.RoleMapping, error) {
+	role, err := u.roleRepo.FindByID(req.RoleID)
+	if err != nil {
+		return nil, ErrRoleMappingRole
+	}
+
+	mapping := &entity.RoleMapping{
+		ExternalGroup: strings.TrimSpace(req.ExternalGroup),
+		RoleID:        role.ID,
+	}
+	if err := u.repo.CreateRoleMapping(ctx, mapping); err != nil {
+		if errors.Is(err, repository.ErrDuplicateEntry) {
+			return nil, ErrRoleMappingExists
+		}
+		return nil, fmt.Errorf("error creating role mapping: %w", err)
+	}
+	mapping.Role = role
+	return mapping, nil
+}
+
+// DeleteRoleMapping removes a role mapping. Users keep the role it gave them
+// until they are next provisioned.
+func (u *UserProvisioningUseCase) DeleteRoleMapping(ctx context.Context, id uint) error {
+	if err := u.repo.DeleteRoleMapping(ctx, id); err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return ErrRoleMappingNotFound
+		}
+		return fmt.Errorf("error deleting role mapping: %w", err)
+	}
+	return nil
+}
+
+// ListRoleMappings lists the directory group to role mappings
+func (u *UserProvisioningUseCase) ListRoleMappings(ctx context.Context) ([]entity.RoleMapping, error) {
+	mappings, err := u.repo.ListRoleMappings(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error listing role mappings: %w", err)
+	}
+	return mappings, nil
+}
+
+// CreateUser creates the directory user, refusing one that matches an existing
+// user by directory ID or email
+func (u *UserProvisioningUseCase) CreateUser(ctx context.Context, input *entity.ProvisionUserInput) (*entity.User, error) {
+	_, err := u.repo.FindUser(ctx, strings.TrimSpace(input.ExternalID), strings.TrimSpace(input.Email))
+	if err == nil {
+		return nil, ErrProvisionedUserExists
+	}
+	if !errors.Is(err, repository.ErrRecordNotFound) {
+		return nil, fmt.Errorf("error finding user: %w", err)
+	}
+	user, _, err := u.apply(ctx, nil, input, false)
+	return user, err
+}
+
+// ReplaceUser sets an existing user's directory attributes, role and status
+func (u *UserProvisioningUseCase) ReplaceUser(ctx context.Context, id uint, input *entity.ProvisionUserInput) (*entity.User, error) {
+	user, err := u.GetUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	user, _, err = u.apply(ctx, user, input, false)
+	return user, err
+}
+
+// DeactivateUser deprovisions a user, keeping the account for the audit trail
+func (u *UserProvisioningUseCase) DeactivateUser(ctx context.Context, id uint) error {
+	user, err := u.GetUser(ctx, id)
+	if err != nil {
+		return err
+	}
+	if user.Status == entity.StatusInactive {
+		return nil
+	}
+	user.Status = entity.StatusInactive
+	if err := u.repo.UpdateUser(ctx, user); err != nil {
+		return fmt.Errorf("error deactivating user: %w", err)
+	}
+	return nil
+}
+
+// GetUser retrieves a user with their role
+func (u *UserProvisioningUseCase) GetUser(ctx context.Context, id uint) (*entity.User, error) {
+	user, err := u.repo.GetUser(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, ErrProvisionedUserNotFound
+		}
+		return nil, fmt.Errorf("error getting user: %w", err)
+	}
+	return user, nil
+}
+
+// ListUsers lists the users matching a filter
+func (u *UserProvisioningUseCase) ListUsers(ctx context.Context, filter *entity.ProvisionedUserFilter) ([]entity.User, int64, error) {
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = 100
+	}
+
+	users, total, err := u.repo.ListUsers(ctx, filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error listing users: %w", err)
+	}
+	return users, total, nil
+}
+
+// ImportCSV provisions the users in a CSV file with a header row. The email
+// column is required; username, external_id, groups (separated by ";" or "|"),
+// role and active columns are optional. Rows are applied one by one, and a row
+// that fails does not stop the others. A dry run only reports what would change.
+func (u *UserProvisioningUseCase) ImportCSV(ctx context.Context, r io.Reader, dryRun bool) (*entity.UserImportReport, error) {
+	records, field, err := parseUserImport(r)
+	if err != nil {
+		return nil, err
+	}
+	return u.importRecords(ctx, records, field, dryRun, nil), nil
+}
+
+// userImportJobPayload is the payload of user import jobs
+type userImportJobPayload struct {
+	CSV string `json:"csv"`
+}
+
+// QueueImportCSV checks a CSV file as ImportCSV does and queues the job
+// importing it. The job's result is the import report.
+func (u *UserProvisioningUseCase) QueueImportCSV(ctx context.Context, r io.Reader, userID *uint) (*entity.Job, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidUserImport, err)
+	}
+	if _, _, err := parseUserImport(bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	return u.jobUC.Enqueue(ctx, entity.JobUserImport, userImportJobPayload{CSV: string(data)}, userID)
+}
+
+// runImportJob imports the CSV file of a user.import job
+func (u *UserProvisioningUseCase) runImportJob(ctx context.Context, job *entity.Job, progress func(int)) (interface{}, error) {
+	var payload userImportJobPayload
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return nil, permanent(fmt.Errorf("invalid job payload: %w", err))
+	}
+	records, field, err := parseUserImport(strings.NewReader(payload.CSV))
+	if err != nil {
+		return nil, permanent(err)
+	}
+	return u.importRecords(ctx, records, field, false, progress), nil
+}
+
+// parseUserImport reads the rows of a user import CSV file, returning them
+// with a function getting a row's value of a named column
+func parseUserImport(r io.Reader) ([][]string, func(record []string, name string) string, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, ErrInvalidUserImport
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		columns[strings.ReplaceAll(name, " ", "_")] = i
+	}
+	if _, ok := columns["email"]; !ok {
+		return nil, nil, ErrInvalidUserImport
+	}
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	// Read the whole file first so a malformed or oversized one changes nothing
+	var records [][]string
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %w", ErrInvalidUserImport, err)
+		}
+		if len(records) == maxUserImportRows {
+			return nil, nil, fmt.Errorf("%w: at most %d users can be imported at once", ErrUserImportTooLarge, maxUserImportRows)
+		}
+		records = append(records, record)
+	}
+	return records, field, nil
+}
+
+// importRecords applies the rows of a user import, reporting the percentage
+// done through progress, when set
+func (u *UserProvisioningUseCase) importRecords(ctx context.Context, records [][]string, field func(record []string, name string) string, dryRun bool, progress func(int)) *entity.UserImportReport {
+	report := &entity.UserImportReport{DryRun: dryRun, Rows: len(records), Results: []entity.UserImportResult{}}
+	seen := make(map[string]int)
+	for i, record := range records {
+		row := i + 2 // the header is row 1
+		input := &entity.ProvisionUserInput{
+			ExternalID: field(record, "external_id"),
+			Username:   field(record, "username"),
+			Email:      field(record, "email"),
+			Groups:     splitGroups(field(record, "groups")),
+			Active:     true,
+		}
+		// An explicit role takes precedence over the groups
+		if role := field(record, "role"); role != "" {
+			input.Groups = append([]string{role}, input.Groups...)
+		}
+		result := entity.UserImportResult{Row: row, Email: input.Email, Username: input.Username}
+
+		active, err := parseActive(field(record, "active"), field(record, "status"))
+		if err == nil {
+			input.Active = active
+			key := strings.ToLower(input.Email)
+			if first, ok := seen[key]; ok {
+				err = fmt.Errorf("email already imported on row %d", first)
+			} else {
+				seen[key] = row
+			}
+		}
+
+		var user *entity.User
+		var action entity.UserImportAction
+		if err == nil {
+			user, err = u.repo.FindUser(ctx, input.ExternalID, input.Email)
+			if errors.Is(err, repository.ErrRecordNotFound) {
+				err = nil
+			}
+		}
+		if err == nil {
+			user, action, err = u.apply(ctx, user, input, dryRun)
+		}
+
+		if err != nil {
+			result.Action = entity.UserImportFailed
+			result.Error = err.Error()
+			report.Failed++
+		} else {
+			result.Action = action
+			result.UserID = user.ID
+			result.Username = user.Username
+			if user.Role != nil {
+				result.Role = user.Role.Name
+			}
+			switch action {
+			case entity.UserImportCreated:
+				report.Created++
+			case entity.UserImportUpdated:
+				report.Updated++
+			case entity.UserImportDeactivated:
+				report.Deactivated++
+			default:
+				report.Unchanged++
+			}
+		}
+		report.Results = append(report.Results, result)
+		if progress != nil {
+			progress((i + 1) * 100 / len(records))
+		}
+	}
+	return report
+}
+
+// apply creates the user described by input, or updates user when it is not
+// nil. A dry run validates the change without saving it.
+func (u *UserProvisioningUseCase) apply(ctx context.Context, user *entity.User, input *entity.ProvisionUserInput, dryRun bool) (*entity.User, entity.UserImportAction, error) {
+	email := strings.TrimSpace(input.Email)
+	if address, err := mail.ParseAddress(email); err != nil || address.Address != email {
+		return nil, "", ErrProvisionInvalidEmail
+	}
+	username := strings.TrimSpace(input.Username)
+	if username == "" {
+		username = email
+	}
+	status := entity.StatusActive
+	if !input.Active {
+		status = entity.StatusInactive
+	}
+
+	var role *entity.Role
+	if user == nil || len(input.Groups) > 0 {
+		var err error
+		if role, err = u.resolveRole(ctx, input.Groups); err != nil {
+			return nil, "", err
+		}
+	}
+
+	var userID uint
+	if user != nil {
+		userID = user.ID
+	}
+	taken, err := u.repo.IdentityTaken(ctx, userID, username, email)
+	if err != nil {
+		return nil, "", fmt.Errorf("error checking user identity: %w", err)
+	}
+	if taken {
+		return nil, "", ErrProvisionIdentityTaken
+	}
+
+	if user == nil {
+		user = &entity.User{
+			ExternalID: strings.TrimSpace(input.ExternalID),
+			Username:   username,
+			Email:      email,
+			RoleID:     role.ID,
+			Role:       role,
+			Status:     status,
+		}
+		if dryRun {
+			return user, entity.UserImportCreated, nil
+		}
+		// Directory users sign in through a password reset until they set their own
+		password, err := bcrypt.GenerateFromPassword([]byte(randomPassword()), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, "", err
+		}
+		user.Password = string(password)
+		if err := u.repo.CreateUser(ctx, user); err != nil {
+			return nil, "", fmt.Errorf("error creating user: %w", err)
+		}
+		return user, entity.UserImportCreated, nil
+	}
+
+	updated := *user
+	if externalID := strings.TrimSpace(input.ExternalID); externalID != "" {
+		updated.ExternalID = externalID
+	}
+	updated.Username = username
+	updated.Email = email
+	// A locked account stays locked until an administrator unlocks it
+	if status == entity.StatusInactive || user.Status != entity.StatusLocked {
+		updated.Status = status
+	}
+	if role != nil {
+		updated.RoleID = role.ID
+		updated.Role = role
+	}
+
+	action := entity.UserImportUpdated
+	switch {
+	case updated.ExternalID == user.ExternalID && updated.Username == user.Username &&
+		updated.Email == user.Email && updated.RoleID == user.RoleID && updated.Status == user.Status:
+		return user, entity.UserImportUnchanged, nil
+	case updated.Status == entity.StatusInactive && user.Status != entity.StatusInactive:
+		action = entity.UserImportDeactivated
+	}
+	if dryRun {
+		return &updated, action, nil
+	}
+	if err := u.repo.UpdateUser(ctx, &updated); err != nil {
+		return nil, "", fmt.Errorf("error updating user: %w", err)
+	}
+	return &updated, action, nil
+}
+
+// resolveRole picks the role of the first group with a role mapping, then of the
+// first group named after a role, then the default role
+func (u *UserProvisioningUseCase) resolveRole(ctx context.Context, groups []string) (*entity.Role, error) {
+	mappings, err := u.repo.FindRoleMappings(ctx, groups)
+	if err != nil {
+		return nil, fmt.Errorf("error finding role mappings: %w", err)
+	}
+	for _, group := range groups {
+		for _, mapping := range mappings {
+			if strings.EqualFold(mapping.ExternalGroup, group) && mapping.Role != nil {
+				return mapping.Role, nil
+			}
+		}
+	}
+
+	roles, err := u.repo.FindRolesByName(ctx, groups)
+	if err != nil {
+		return nil, fmt.Errorf("error finding roles: %w", err)
+	}
+	for _, group := range groups {
+		for i := range roles {
+			if strings.EqualFold(roles[i].Name, group) {
+				return &roles[i], nil
+			}
+		}
+	}
+
+	if u.defaultRole != "" {
+		if role, err := u.roleRepo.FindByName(u.defaultRole); err == nil {
+			return role, nil
+		}
+	}
+	return nil, ErrProvisionNoRole
+}
+
+// splitGroups splits a list of directory groups separated by ";" or "|"
+func splitGroups(value string) []string {
+	var groups []string
+	for _, group := range strings.FieldsFunc(value, func(r rune) bool { return r == ';' || r == '|' }) {
+		if group = strings.TrimSpace(group); group != "" {
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}
+
+// parseActive reads an import row's active flag, or else its status. Rows with
+// neither are active.
+func parseActive(active, status string) (bool, error) {
+	if active != "" {
+		switch strings.ToLower(active) {
+		case "yes", "y":
+			return true, nil
+		case "no", "n":
+			return false, nil
+		}
+		value, err := strconv.ParseBool(active)
+		if err != nil {
+			return false, fmt.Errorf("invalid active value %q", active)
+		}
+		return value, nil
+	}
+	switch entity.UserStatus(strings.ToLower(status)) {
+	case "", entity.StatusActive:
+		return true, nil
+	case entity.StatusInactive:
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid status %q", status)
+}
+
+// randomPassword returns an unguessable password for accounts created without one
+func randomPassword() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -3,7 +3,8 @@ package usecase
 import (
 	"crypto/rand"
 	"encoding/hex"
-	"errors"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
@@ -78,6 +79,34 @@ func (uc *UserUseCase) UpdateUser(input *UpdateUserInput) (*entity.User, error)
 	return user, nil
 }
 
+// FindUser finds a user by ID, email or username
+func (uc *UserUseCase) FindUser(login string) (*entity.User, error) {
+	if id, err := strconv.ParseUint(login, 10, 32); err == nil {
+		return uc.userRepo.FindByID(uint(id))
+	}
+	if strings.Contains(login, "@") {
+		return uc.userRepo.FindByEmail(login)
+	}
+	return uc.userRepo.FindByUsername(login)
+}
+
+// AssignRole gives a user a role in place of theirs
+func (uc *UserUseCase) AssignRole(userID uint, role *entity.Role) (*entity.User, error) {
+	user, err := uc.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Saved with the user, the role would otherwise set its previous ID back
+	user.RoleID = role.ID
+	user.Role = role
+	if err := uc.userRepo.Update(user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
 func (uc *UserUseCase) Delete(id uint) error {
 	user, err := uc.userRepo.FindByID(id)
 	if err != nil {
@@ -85,7 +114,7 @@ func (uc *UserUseCase) Delete(id uint) error {
 	}
 
 	if user.Role != nil && user.Role.Name == "admin" {
-		return errors.New("cannot delete admin user")
+		return entity.NewError(entity.ErrCodeFailedPrecondition, "cannot delete admin user")
 	}
 
 	return uc.userRepo.Delete(id)
@@ -98,7 +127,7 @@ func (uc *UserUseCase) ChangePassword(input *ChangePasswordInput) error {
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(input.OldPassword)); err != nil {
-		return errors.New("invalid old password")
+		return entity.NewError(entity.ErrCodeInvalidArgument, "invalid old password")
 	}
 
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(input.NewPassword), bcrypt.DefaultCost)
@@ -112,15 +141,15 @@ func (uc *UserUseCase) ChangePassword(input *ChangePasswordInput) error {
 func (uc *UserUseCase) ValidateCredentials(email, password string) (*entity.User, error) {
 	user, err := uc.userRepo.FindByEmail(email)
 	if err != nil {
-		return nil, errors.New("invalid credentials")
+		return nil, entity.NewError(entity.ErrCodeUnauthenticated, "invalid credentials")
 	}
 
 	if !user.IsActive() {
-		return nil, errors.New("user account is not active")
+		return nil, entity.NewError(entity.ErrCodeUnauthenticated, "user account is not active")
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-		return nil, errors.New("invalid credentials")
+		return nil, entity.NewError(entity.ErrCodeUnauthenticated, "invalid credentials")
 	}
 
 	if err := uc.userRepo.UpdateLastLogin(user.ID); err != nil {
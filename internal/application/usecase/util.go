@@ -0,0 +1,24 @@
+package usecase
+
+import (
+	"math"
+	"time"
+)
+
+// periodLayout formats accounting periods as YYYY-MM
+const periodLayout = "2006-01"
+
+// truncateDay returns midnight of the day t falls on, in t's location
+func truncateDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// daysBetween returns the number of calendar days from one date to another
+func daysBetween(from, to time.Time) int {
+	return int(math.Round(truncateDay(to).Sub(truncateDay(from)).Hours() / 24))
+}
+
+// roundAmount rounds to two decimal places
+func roundAmount(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,175 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
+)
+
+var (
+	ErrPriceListNotFound       = entity.NewError(entity.ErrCodeNotFound, "vendor price list not found")
+	ErrPriceListVendorNotFound = entity.NewError(entity.ErrCodeNotFound, "vendor not found")
+	ErrPriceListValidity       = entity.NewError(entity.ErrCodeInvalidArgument, "valid_to must not be before valid_from")
+	ErrPriceListItems          = entity.NewError(entity.ErrCodeInvalidArgument, "a price list gives one price per SKU and minimum quantity, for existing SKUs")
+	ErrVendorPriceNotFound     = entity.NewError(entity.ErrCodeNotFound, "the vendor has no price for the SKU and quantity on that day")
+)
+
+// VendorPriceListUseCase manages the price lists of vendors and quotes prices from them
+type VendorPriceListUseCase struct {
+	priceListRepo *repository.VendorPriceListRepository
+	vendorRepo    *repository.VendorRepository
+	skuRepo       *repository.SKURepository
+}
+
+// NewVendorPriceListUseCase creates a new vendor price list use case
+func NewVendorPriceListUseCase(priceListRepo *repository.VendorPriceListRepository, vendorRepo *repository.VendorRepository, skuRepo *repository.SKURepository) *VendorPriceListUseCase {
+	return &VendorPriceListUseCase{
+		priceListRepo: priceListRepo,
+		vendorRepo:    vendorRepo,
+		skuRepo:       skuRepo,
+	}
+}
+
+// CreatePriceList creates a price list of a vendor
+func (u *VendorPriceListUseCase) CreatePriceList(ctx context.Context, vendorID uint, req *entity.VendorPriceListRequest) (*entity.VendorPriceList, error) {
+	vendor, err := u.vendorRepo.FindByID(ctx, vendorID)
+	if err != nil {
+		return nil, ErrPriceListVendorNotFound
+	}
+
+	priceList := &entity.VendorPriceList{VendorID: vendorID}
+	if err := u.apply(ctx, priceList, vendor, req); err != nil {
+		return nil, err
+	}
+	if err := u.priceListRepo.Create(ctx, priceList); err != nil {
+		return nil, fmt.Errorf("error creating price list: %w", err)
+	}
+	return priceList, nil
+}
+
+// UpdatePriceList replaces the validity, currency and prices of a price list
+func (u *VendorPriceListUseCase) UpdatePriceList(ctx context.Context, id uint, req *entity.VendorPriceListRequest) (*entity.VendorPriceList, error) {
+	priceList, err := u.GetPriceList(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	vendor, err := u.vendorRepo.FindByID(ctx, priceList.VendorID)
+	if err != nil {
+		return nil, ErrPriceListVendorNotFound
+	}
+
+	if err := u.apply(ctx, priceList, vendor, req); err != nil {
+		return nil, err
+	}
+	if err := u.priceListRepo.Update(ctx, priceList); err != nil {
+		return nil, fmt.Errorf("error updating price list: %w", err)
+	}
+	return priceList, nil
+}
+
+// apply validates a price list request and sets it on the price list. The
+// currency defaults to the vendor's.
+func (u *VendorPriceListUseCase) apply(ctx context.Context, priceList *entity.VendorPriceList, vendor *entity.Vendor, req *entity.VendorPriceListRequest) error {
+	validFrom := truncateDay(req.ValidFrom)
+	var validTo *time.Time
+	if req.ValidTo != nil {
+		day := truncateDay(*req.ValidTo)
+		if day.Before(validFrom) {
+			return ErrPriceListValidity
+		}
+		validTo = &day
+	}
+
+	skuIDs := make([]string, 0, len(req.Items))
+	seen := make(map[string]bool, len(req.Items))
+	items := make([]entity.VendorPriceListItem, 0, len(req.Items))
+	for _, item := range req.Items {
+		key := fmt.Sprintf("%s/%g", item.SKUID, item.MinQuantity)
+		if seen[key] {
+			return ErrPriceListItems
+		}
+		seen[key] = true
+		skuIDs = append(skuIDs, item.SKUID)
+		items = append(items, entity.VendorPriceListItem{
+			SKUID:       item.SKUID,
+			MinQuantity: item.MinQuantity,
+			UnitPrice:   item.UnitPrice,
+		})
+	}
+	skus, err := u.skuRepo.GetSKUsByIDs(ctx, skuIDs)
+	if err != nil {
+		return fmt.Errorf("error checking SKUs: %w", err)
+	}
+	found := make(map[string]bool, len(skus))
+	for _, sku := range skus {
+		found[sku.ID] = true
+	}
+	for _, skuID := range skuIDs {
+		if !found[skuID] {
+			return ErrPriceListItems
+		}
+	}
+
+	currency := strings.ToUpper(strings.TrimSpace(req.CurrencyCode))
+	if currency == "" {
+		currency = strings.ToUpper(vendor.Currency)
+	}
+
+	priceList.Name = req.Name
+	priceList.CurrencyCode = currency
+	priceList.ValidFrom = validFrom
+	priceList.ValidTo = validTo
+	priceList.Items = items
+	return nil
+}
+
+// GetPriceList retrieves a price list with its prices
+func (u *VendorPriceListUseCase) GetPriceList(ctx context.Context, id uint) (*entity.VendorPriceList, error) {
+	priceList, err := u.priceListRepo.Get(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, ErrPriceListNotFound
+		}
+		return nil, fmt.Errorf("error getting price list: %w", err)
+	}
+	return priceList, nil
+}
+
+// ListPriceLists lists the price lists of a vendor, those valid on the given
+// day only when one is given
+func (u *VendorPriceListUseCase) ListPriceLists(ctx context.Context, vendorID uint, validOn *time.Time) ([]entity.VendorPriceList, error) {
+	priceLists, err := u.priceListRepo.List(ctx, vendorID, validOn)
+	if err != nil {
+		return nil, fmt.Errorf("error listing price lists: %w", err)
+	}
+	return priceLists, nil
+}
+
+// DeletePriceList deletes a price list. Purchase orders priced from it keep
+// their prices.
+func (u *VendorPriceListUseCase) DeletePriceList(ctx context.Context, id uint) error {
+	if err := u.priceListRepo.Delete(ctx, id); err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return ErrPriceListNotFound
+		}
+		return fmt.Errorf("error deleting price list: %w", err)
+	}
+	return nil
+}
+
+// QuotePrice returns the price a vendor's lists give a SKU for a quantity on a day
+func (u *VendorPriceListUseCase) QuotePrice(ctx context.Context, vendorID uint, skuID string, quantity float64, day time.Time) (*entity.VendorPrice, error) {
+	price, err := u.priceListRepo.FindPrice(ctx, vendorID, skuID, quantity, truncateDay(day))
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, ErrVendorPriceNotFound
+		}
+		return nil, fmt.Errorf("error finding vendor price: %w", err)
+	}
+	return price, nil
+}
@@ -0,0 +1,448 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/eventbus"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
+)
+
+var (
+	ErrVendorRiskAlertNotFound     = entity.NewError(entity.ErrCodeNotFound, "vendor risk alert not found")
+	ErrVendorRiskAlertAcknowledged = entity.NewError(entity.ErrCodeFailedPrecondition, "vendor risk alert is already acknowledged")
+)
+
+// Vendor risk weights, applied to component scores on a 0-100 scale
+const (
+	vendorRiskExposureWeight   = 0.4
+	vendorRiskDependencyWeight = 0.3
+	vendorRiskDeliveryWeight   = 0.3
+)
+
+// VendorRiskSettings tunes vendor risk scoring
+type VendorRiskSettings struct {
+	LookbackMonths    int     // purchase history used for dependency and delivery performance
+	Threshold         float64 // score at which a vendor is at risk and an alert is raised
+	ExposureLimit     float64 // open order value plus prepayments, in the base currency, that scores 100
+	SingleSourceLimit int     // single-source SKUs that score 100
+}
+
+// VendorRiskUseCase scores the supply risk of vendors and raises alerts when a
+// vendor crosses the risk threshold
+type VendorRiskUseCase struct {
+	riskRepo *repository.VendorRiskRepository
+	vendorUC *VendorUseCase
+	settings VendorRiskSettings
+	bus      *eventbus.Bus
+}
+
+// NewVendorRiskUseCase creates a new vendor risk use case
+func NewVendorRiskUseCase(riskRepo *repository.VendorRiskRepository, vendorUC *VendorUseCase, settings VendorRiskSettings, bus *eventbus.Bus) *VendorRiskUseCase {
+	if settings.LookbackMonths <= 0 {
+		settings.LookbackMonths = 12
+	}
+	if settings.Threshold <= 0 {
+		settings.Threshold = 60
+	}
+	if settings.ExposureLimit <= 0 {
+		settings.ExposureLimit = 100000
+	}
+	if settings.SingleSourceLimit <= 0 {
+		settings.SingleSourceLimit = 5
+	}
+	return &VendorRiskUseCase{
+		riskRepo: riskRepo,
+		vendorUC: vendorUC,
+		settings: settings,
+		bus:      bus,
+	}
+}
+
+// Run rescores every vendor with purchase activity in the lookback window or open
+// orders, replaces the stored scores and raises an alert for each vendor whose
+// score reached the threshold since the previous run
+func (u *VendorRiskUseCase) Run(ctx context.Context) (*entity.VendorRiskRunResult, error) {
+	now := time.Now()
+	since := truncateDay(now).AddDate(0, -u.settings.LookbackMonths, 0)
+
+	vendors, err := u.vendorUC.ListVendors(ctx, entity.VendorFilter{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing vendors: %w", err)
+	}
+	scorecards, err := u.vendorUC.computeScorecards(ctx, vendors, entity.VendorScorecardFilter{StartDate: &since})
+	if err != nil {
+		return nil, fmt.Errorf("error computing vendor scorecards: %w", err)
+	}
+	exposures, err := u.exposures(ctx)
+	if err != nil {
+		return nil, err
+	}
+	singleSource, err := u.singleSourceSKUs(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := u.riskRepo.ListScores(ctx, entity.VendorRiskFilter{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing vendor risk scores: %w", err)
+	}
+	previousScores := make(map[uint]float64, len(previous))
+	for _, score := range previous {
+		previousScores[score.VendorID] = score.Score
+	}
+
+	result := &entity.VendorRiskRunResult{
+		Threshold:  u.settings.Threshold,
+		Alerts:     []entity.VendorRiskAlert{},
+		ComputedAt: now,
+	}
+	scores := make([]entity.VendorRiskScore, 0, len(scorecards))
+	for _, sc := range scorecards {
+		exposure := exposures[sc.VendorID]
+		if sc.OrderCount == 0 && exposure.OpenOrderCount == 0 {
+			continue
+		}
+
+		score := u.score(sc, exposure, singleSource[sc.VendorID])
+		score.ComputedAt = now
+		scores = append(scores, score)
+		if score.Level != entity.VendorRiskHigh {
+			continue
+		}
+
+		result.AtRisk++
+		if prev, scored := previousScores[score.VendorID]; scored && prev >= u.settings.Threshold {
+			continue
+		}
+		result.Alerts = append(result.Alerts, entity.VendorRiskAlert{
+			VendorID:      score.VendorID,
+			VendorName:    score.VendorName,
+			Score:         score.Score,
+			PreviousScore: previousScores[score.VendorID],
+			Threshold:     u.settings.Threshold,
+			Reasons:       vendorRiskReasons(score),
+		})
+	}
+	result.Vendors = len(scores)
+
+	if err := u.riskRepo.ReplaceScores(ctx, scores); err != nil {
+		return nil, fmt.Errorf("error storing vendor risk scores: %w", err)
+	}
+	if err := u.riskRepo.CreateAlerts(ctx, result.Alerts); err != nil {
+		return nil, fmt.Errorf("error storing vendor risk alerts: %w", err)
+	}
+	for i := range result.Alerts {
+		u.bus.Publish(ctx, entity.VendorRiskAlerted{Alert: &result.Alerts[i]})
+	}
+	return result, nil
+}
+
+// ListScores lists the stored vendor risk scores, riskiest first
+func (u *VendorRiskUseCase) ListScores(ctx context.Context, filter entity.VendorRiskFilter) ([]entity.VendorRiskScore, error) {
+	scores, err := u.riskRepo.ListScores(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("error listing vendor risk scores: %w", err)
+	}
+	return scores, nil
+}
+
+// ListAtRisk lists the vendors whose stored score is at or above the threshold
+func (u *VendorRiskUseCase) ListAtRisk(ctx context.Context) ([]entity.VendorRiskScore, error) {
+	return u.ListScores(ctx, entity.VendorRiskFilter{MinScore: u.settings.Threshold})
+}
+
+// Threshold returns the score at which a vendor is at risk
+func (u *VendorRiskUseCase) Threshold() float64 {
+	return u.settings.Threshold
+}
+
+// ListAlerts lists vendor risk alerts
+func (u *VendorRiskUseCase) ListAlerts(ctx context.Context, filter *entity.VendorRiskAlertFilter) ([]entity.VendorRiskAlert, int64, error) {
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+	if filter.PageSize <= 0 {
+		filter.PageSize = 20
+	}
+
+	alerts, total, err := u.riskRepo.ListAlerts(ctx, filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error listing vendor risk alerts: %w", err)
+	}
+	return alerts, total, nil
+}
+
+// AcknowledgeAlert marks a vendor risk alert as handled
+func (u *VendorRiskUseCase) AcknowledgeAlert(ctx context.Context, id uint, userID *uint) (*entity.VendorRiskAlert, error) {
+	alert, err := u.riskRepo.GetAlert(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, ErrVendorRiskAlertNotFound
+		}
+		return nil, fmt.Errorf("error getting vendor risk alert: %w", err)
+	}
+	if alert.AcknowledgedAt != nil {
+		return nil, ErrVendorRiskAlertAcknowledged
+	}
+
+	now := time.Now()
+	alert.AcknowledgedAt = &now
+	alert.AcknowledgedBy = userID
+	if err := u.riskRepo.UpdateAlert(ctx, alert); err != nil {
+		return nil, fmt.Errorf("error acknowledging vendor risk alert: %w", err)
+	}
+	return alert, nil
+}
+
+// score combines a vendor's exposure, single-source dependency and delivery
+// performance into its risk score
+func (u *VendorRiskUseCase) score(sc entity.VendorScorecard, exposure entity.VendorExposure, singleSourceSKUs int) entity.VendorRiskScore {
+	score := entity.VendorRiskScore{
+		VendorID:           sc.VendorID,
+		VendorCode:         sc.VendorCode,
+		VendorName:         sc.VendorName,
+		OpenOrderCount:     exposure.OpenOrderCount,
+		OpenOrderValue:     roundAmount(exposure.OpenOrderValue),
+		Prepayments:        roundAmount(exposure.Prepayments),
+		SingleSourceSKUs:   singleSourceSKUs,
+		OnTimeDeliveryRate: sc.OnTimeDeliveryRate,
+		RejectionRate:      sc.RejectionRate,
+	}
+
+	score.ExposureScore = roundAmount(math.Min(100, (exposure.OpenOrderValue+exposure.Prepayments)/u.settings.ExposureLimit*100))
+	score.DependencyScore = roundAmount(math.Min(100, float64(singleSourceSKUs)/float64(u.settings.SingleSourceLimit)*100))
+	// Vendors that never delivered carry no delivery risk yet
+	if sc.ReceiptCount > 0 {
+		quality := math.Max(0, 100-sc.RejectionRate)
+		score.DeliveryScore = roundAmount(100 - (sc.OnTimeDeliveryRate*0.6 + quality*0.4))
+	}
+
+	score.Score = roundAmount(score.ExposureScore*vendorRiskExposureWeight +
+		score.DependencyScore*vendorRiskDependencyWeight +
+		score.DeliveryScore*vendorRiskDeliveryWeight)
+	switch {
+	case score.Score >= u.settings.Threshold:
+		score.Level = entity.VendorRiskHigh
+	case score.Score >= u.settings.Threshold/2:
+		score.Level = entity.VendorRiskMedium
+	default:
+		score.Level = entity.VendorRiskLow
+	}
+	return score
+}
+
+// exposures sums, per vendor, the base currency value of open purchase orders not
+// yet received and the payments made on them beyond the value received
+func (u *VendorRiskUseCase) exposures(ctx context.Context) (map[uint]entity.VendorExposure, error) {
+	orders, err := u.riskRepo.ListOpenOrders(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error listing open purchase orders: %w", err)
+	}
+
+	orderIDs := make([]string, len(orders))
+	for i, order := range orders {
+		orderIDs[i] = order.ID
+	}
+	receipts, err := u.riskRepo.ListReceipts(ctx, orderIDs)
+	if err != nil {
+		return nil, fmt.Errorf("error listing purchase receipts: %w", err)
+	}
+	paid, err := u.riskRepo.SumPayments(ctx, orderIDs)
+	if err != nil {
+		return nil, fmt.Errorf("error summing purchase payments: %w", err)
+	}
+
+	received := make(map[string]float64, len(orders))
+	for _, receipt := range receipts {
+		for _, item := range receipt.Items {
+			received[receipt.PurchaseOrderID] += item.ReceivedQuantity * item.UnitPrice
+		}
+	}
+
+	exposures := make(map[uint]entity.VendorExposure)
+	for _, order := range orders {
+		rate := order.ExchangeRate
+		if rate <= 0 {
+			rate = 1
+		}
+		receivedBase := received[order.ID] * rate
+
+		exposure := exposures[order.VendorID]
+		exposure.VendorID = order.VendorID
+		exposure.OpenOrderCount++
+		exposure.OpenOrderValue += math.Max(0, order.BaseGrandTotal-receivedBase)
+		exposure.Prepayments += math.Max(0, paid[order.ID]*rate-receivedBase)
+		exposures[order.VendorID] = exposure
+	}
+	return exposures, nil
+}
+
+// singleSourceSKUs counts, per vendor, the SKUs it is the single source of
+func (u *VendorRiskUseCase) singleSourceSKUs(ctx context.Context, since time.Time) (map[uint]int, error) {
+	skus, err := u.singleSources(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[uint]int)
+	for _, sku := range skus {
+		counts[sku.VendorID]++
+	}
+	return counts, nil
+}
+
+// singleSources finds the SKUs with one source of supply since the given date.
+// A SKU's sources are its designated vendor and every vendor it was ordered from.
+func (u *VendorRiskUseCase) singleSources(ctx context.Context, since time.Time) ([]entity.SingleSourceSKU, error) {
+	orders, err := u.riskRepo.ListSourcingOrders(ctx, since)
+	if err != nil {
+		return nil, fmt.Errorf("error listing purchase orders: %w", err)
+	}
+
+	// Latest order date per SKU and vendor
+	ordered := make(map[string]map[uint]time.Time)
+	for _, order := range orders {
+		for _, item := range order.Items {
+			if ordered[item.SKUID] == nil {
+				ordered[item.SKUID] = make(map[uint]time.Time)
+			}
+			if order.OrderDate.After(ordered[item.SKUID][order.VendorID]) {
+				ordered[item.SKUID][order.VendorID] = order.OrderDate
+			}
+		}
+	}
+
+	skuIDs := make([]string, 0, len(ordered))
+	for skuID, vendors := range ordered {
+		if len(vendors) == 1 {
+			skuIDs = append(skuIDs, skuID)
+		}
+	}
+	skus, err := u.riskRepo.ListSourcedSKUs(ctx, skuIDs)
+	if err != nil {
+		return nil, fmt.Errorf("error listing SKUs: %w", err)
+	}
+
+	var singles []entity.SingleSourceSKU
+	for _, sku := range skus {
+		vendors := ordered[sku.ID]
+		if sku.VendorID != nil {
+			if _, orderedFrom := vendors[*sku.VendorID]; !orderedFrom && len(vendors) > 0 {
+				continue
+			}
+		}
+		if len(vendors) > 1 {
+			continue
+		}
+
+		single := entity.SingleSourceSKU{
+			SKUID:    sku.ID,
+			SKUCode:  sku.SKUCode,
+			SKUName:  sku.Name,
+			Category: sku.Category,
+		}
+		if sku.VendorID != nil {
+			single.VendorID = *sku.VendorID
+			single.Designated = true
+		}
+		for vendorID, lastOrder := range vendors {
+			single.VendorID = vendorID
+			single.LastOrderDate = &lastOrder
+		}
+		singles = append(singles, single)
+	}
+	return singles, nil
+}
+
+// SingleSourceReport lists the SKUs with one source of supply over the lookback
+// window and the open sales demand against them, largest demand first
+func (u *VendorRiskUseCase) SingleSourceReport(ctx context.Context, filter entity.SingleSourceFilter) ([]entity.SingleSourceSKU, error) {
+	since := truncateDay(time.Now()).AddDate(0, -u.settings.LookbackMonths, 0)
+	singles, err := u.singleSources(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+
+	salesOrders, err := u.riskRepo.ListOpenSalesOrders(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error listing open sales orders: %w", err)
+	}
+	demand := make(map[string]float64)
+	demandOrders := make(map[string]int)
+	for i := range salesOrders {
+		for skuID, qty := range outstandingQuantities(&salesOrders[i]) {
+			demand[skuID] += qty
+			demandOrders[skuID]++
+		}
+	}
+
+	vendors, err := u.vendorUC.ListVendors(ctx, entity.VendorFilter{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing vendors: %w", err)
+	}
+	vendorsByID := make(map[uint]entity.Vendor, len(vendors))
+	for _, vendor := range vendors {
+		vendorsByID[vendor.ID] = vendor
+	}
+
+	report := make([]entity.SingleSourceSKU, 0, len(singles))
+	skuIDs := make([]string, 0, len(singles))
+	for _, single := range singles {
+		if filter.VendorID != 0 && single.VendorID != filter.VendorID {
+			continue
+		}
+		single.OpenDemand = demand[single.SKUID]
+		single.OpenSalesOrders = demandOrders[single.SKUID]
+		if filter.WithDemand && single.OpenDemand <= 0 {
+			continue
+		}
+		single.VendorCode = vendorsByID[single.VendorID].Code
+		single.VendorName = vendorsByID[single.VendorID].Name
+		report = append(report, single)
+		skuIDs = append(skuIDs, single.SKUID)
+	}
+
+	onHand, err := u.riskRepo.SumOnHand(ctx, skuIDs)
+	if err != nil {
+		return nil, fmt.Errorf("error summing stock on hand: %w", err)
+	}
+	for i := range report {
+		report[i].OnHand = onHand[report[i].SKUID]
+	}
+
+	sort.SliceStable(report, func(i, j int) bool {
+		return report[i].OpenDemand > report[j].OpenDemand
+	})
+	return report, nil
+}
+
+// LookbackMonths returns the purchase history used to find a SKU's vendors
+func (u *VendorRiskUseCase) LookbackMonths() int {
+	return u.settings.LookbackMonths
+}
+
+// vendorRiskReasons describes the components driving a vendor's risk score
+func vendorRiskReasons(score entity.VendorRiskScore) string {
+	var reasons []string
+	if score.ExposureScore >= 50 {
+		reasons = append(reasons, fmt.Sprintf("exposure of %.2f on %d open orders, %.2f prepaid",
+			score.OpenOrderValue, score.OpenOrderCount, score.Prepayments))
+	}
+	if score.DependencyScore >= 50 {
+		reasons = append(reasons, fmt.Sprintf("sole source of %d SKUs", score.SingleSourceSKUs))
+	}
+	if score.DeliveryScore >= 50 {
+		reasons = append(reasons, fmt.Sprintf("%.2f%% on-time delivery, %.2f%% rejected",
+			score.OnTimeDeliveryRate, score.RejectionRate))
+	}
+	if len(reasons) == 0 {
+		reasons = append(reasons, "combined exposure, dependency and delivery risk")
+	}
+	return strings.Join(reasons, "; ")
+}
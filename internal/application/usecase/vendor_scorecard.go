@@ -0,0 +1,193 @@
+package usecase
+
+import (
+	"context"
+	"math"
+	"sort"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+)
+
+// Scorecard weights, applied to KPIs normalised to a 0-100 scale
+const (
+	scorecardOnTimeWeight  = 0.4
+	scorecardQualityWeight = 0.3
+	scorecardPriceWeight   = 0.2
+	scorecardRatingWeight  = 0.1
+)
+
+// GetVendorScorecard computes the delivery and quality KPIs of a single vendor
+func (u *VendorUseCase) GetVendorScorecard(ctx context.Context, vendorID uint, filter entity.VendorScorecardFilter) (*entity.VendorScorecard, error) {
+	vendor, err := u.repo.FindByID(ctx, vendorID)
+	if err != nil {
+		return nil, err
+	}
+
+	filter.VendorIDs = []uint{vendorID}
+	scorecards, err := u.computeScorecards(ctx, []entity.Vendor{*vendor}, filter)
+	if err != nil {
+		return nil, err
+	}
+	return &scorecards[0], nil
+}
+
+// RankVendors computes scorecards for all vendors with purchase activity in the
+// period and ranks them by their weighted score
+func (u *VendorUseCase) RankVendors(ctx context.Context, filter entity.VendorScorecardFilter) ([]entity.VendorScorecard, error) {
+	vendors, err := u.repo.List(ctx, entity.VendorFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	scorecards, err := u.computeScorecards(ctx, vendors, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	ranked := make([]entity.VendorScorecard, 0, len(scorecards))
+	for _, sc := range scorecards {
+		if sc.OrderCount > 0 {
+			ranked = append(ranked, sc)
+		}
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].Score != ranked[j].Score {
+			return ranked[i].Score > ranked[j].Score
+		}
+		return ranked[i].OrderCount > ranked[j].OrderCount
+	})
+	for i := range ranked {
+		ranked[i].Rank = i + 1
+	}
+
+	if filter.Limit > 0 && len(ranked) > filter.Limit {
+		ranked = ranked[:filter.Limit]
+	}
+	return ranked, nil
+}
+
+// computeScorecards builds one scorecard per vendor from the orders, receipts and
+// sales returns in the filter period
+func (u *VendorUseCase) computeScorecards(ctx context.Context, vendors []entity.Vendor, filter entity.VendorScorecardFilter) ([]entity.VendorScorecard, error) {
+	orders, err := u.repo.ListScorecardOrders(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	orderIDs := make([]string, 0, len(orders))
+	ordersByVendor := make(map[uint][]entity.PurchaseOrder)
+	for _, order := range orders {
+		orderIDs = append(orderIDs, order.ID)
+		ordersByVendor[order.VendorID] = append(ordersByVendor[order.VendorID], order)
+	}
+
+	receipts, err := u.repo.ListReceiptsByOrderIDs(ctx, orderIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	receiptsByOrder := make(map[string][]entity.PurchaseReceipt)
+	for _, receipt := range receipts {
+		receiptsByOrder[receipt.PurchaseOrderID] = append(receiptsByOrder[receipt.PurchaseOrderID], receipt)
+	}
+
+	returned, err := u.repo.SumReturnedQuantities(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	scorecards := make([]entity.VendorScorecard, 0, len(vendors))
+	for _, vendor := range vendors {
+		scorecards = append(scorecards, buildScorecard(vendor, ordersByVendor[vendor.ID], receiptsByOrder, returned[vendor.ID]))
+	}
+	return scorecards, nil
+}
+
+// buildScorecard derives the KPIs of a vendor:
+//   - on-time delivery rate: share of receipts dated on or before the order's expected date
+//   - rejection rate: rejected quantity as a share of received quantity
+//   - return rate: quantity of the vendor's SKUs returned by clients as a share of received quantity
+//   - price variance: received value at receipt prices against the same quantities at order prices
+//   - average lead time: days from order date to the first receipt
+//
+// Rates are percentages. Rejection rate, return rate and price variance of zero mean no data or no deviation.
+// Returns count against quality alongside rejections.
+func buildScorecard(vendor entity.Vendor, orders []entity.PurchaseOrder, receiptsByOrder map[string][]entity.PurchaseReceipt, returnedQty float64) entity.VendorScorecard {
+	sc := entity.VendorScorecard{
+		VendorID:   vendor.ID,
+		VendorCode: vendor.Code,
+		VendorName: vendor.Name,
+		OrderCount: len(orders),
+		Rating:     vendor.Rating,
+	}
+
+	var (
+		datedReceipts, onTimeReceipts int
+		receivedQty, rejectedQty      float64
+		orderedValue, receivedValue   float64
+		leadTimeDays                  float64
+		leadTimeOrders                int
+	)
+
+	for _, order := range orders {
+		receipts := receiptsByOrder[order.ID]
+		if len(receipts) == 0 {
+			continue
+		}
+		sc.ReceiptCount += len(receipts)
+
+		// Receipts are ordered by date, so the first one closes the lead time
+		leadTimeDays += receipts[0].ReceiptDate.Sub(order.OrderDate).Hours() / 24
+		leadTimeOrders++
+
+		orderPrices := make(map[string]float64, len(order.Items))
+		for _, item := range order.Items {
+			orderPrices[item.SKUID] = item.UnitPrice
+		}
+
+		for _, receipt := range receipts {
+			if !order.ExpectedDate.IsZero() {
+				datedReceipts++
+				if !truncateDay(receipt.ReceiptDate).After(truncateDay(order.ExpectedDate)) {
+					onTimeReceipts++
+				}
+			}
+
+			for _, item := range receipt.Items {
+				receivedQty += item.ReceivedQuantity
+				rejectedQty += item.RejectedQuantity
+
+				if price, ok := orderPrices[item.SKUID]; ok {
+					orderedValue += price * item.ReceivedQuantity
+					receivedValue += item.UnitPrice * item.ReceivedQuantity
+				}
+			}
+		}
+	}
+
+	if datedReceipts > 0 {
+		sc.OnTimeDeliveryRate = roundAmount(float64(onTimeReceipts) / float64(datedReceipts) * 100)
+	}
+	if receivedQty > 0 {
+		sc.RejectionRate = roundAmount(rejectedQty / receivedQty * 100)
+		sc.ReturnRate = roundAmount(returnedQty / receivedQty * 100)
+	}
+	if orderedValue > 0 {
+		sc.PriceVariance = roundAmount((receivedValue - orderedValue) / orderedValue * 100)
+	}
+	if leadTimeOrders > 0 {
+		sc.AverageLeadTimeDays = roundAmount(leadTimeDays / float64(leadTimeOrders))
+	}
+
+	if sc.ReceiptCount > 0 {
+		quality := math.Max(0, 100-sc.RejectionRate-sc.ReturnRate)
+		price := math.Max(0, 100-math.Abs(sc.PriceVariance))
+		sc.Score = roundAmount(sc.OnTimeDeliveryRate*scorecardOnTimeWeight +
+			quality*scorecardQualityWeight +
+			price*scorecardPriceWeight +
+			vendor.Rating*20*scorecardRatingWeight)
+	}
+
+	return sc
+}
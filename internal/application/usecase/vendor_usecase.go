@@ -4,27 +4,46 @@ import (
 	"context"
 
 	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/export"
 	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
 )
 
 type VendorUseCase struct {
-	repo *repository.VendorRepository
+	repo          *repository.VendorRepository
+	customFieldUC *CustomFieldUseCase
 }
 
-func NewVendorUseCase(repo *repository.VendorRepository) *VendorUseCase {
-	return &VendorUseCase{repo: repo}
+func NewVendorUseCase(repo *repository.VendorRepository, customFieldUC *CustomFieldUseCase) *VendorUseCase {
+	return &VendorUseCase{repo: repo, customFieldUC: customFieldUC}
 }
 
 // CreateVendor creates a new vendor
 func (u *VendorUseCase) CreateVendor(ctx context.Context, vendor *entity.Vendor) error {
+	if err := u.checkCustomFields(ctx, vendor); err != nil {
+		return err
+	}
 	return u.repo.Create(ctx, vendor)
 }
 
 // UpdateVendor updates an existing vendor
 func (u *VendorUseCase) UpdateVendor(ctx context.Context, vendor *entity.Vendor) error {
+	if err := u.checkCustomFields(ctx, vendor); err != nil {
+		return err
+	}
 	return u.repo.Update(ctx, vendor)
 }
 
+// checkCustomFields validates the custom field values of a vendor, keeping
+// them in the form they are stored in
+func (u *VendorUseCase) checkCustomFields(ctx context.Context, vendor *entity.Vendor) error {
+	values, err := u.customFieldUC.CheckValues(ctx, entity.CustomFieldEntityVendor, vendor.CustomFields)
+	if err != nil {
+		return err
+	}
+	vendor.CustomFields = values
+	return nil
+}
+
 // GetVendor gets a vendor by ID
 func (u *VendorUseCase) GetVendor(ctx context.Context, id uint) (*entity.Vendor, error) {
 	return u.repo.FindByID(ctx, id)
@@ -37,9 +56,26 @@ func (u *VendorUseCase) DeleteVendor(ctx context.Context, id uint) error {
 
 // ListVendors lists vendors with filters
 func (u *VendorUseCase) ListVendors(ctx context.Context, filter entity.VendorFilter) ([]entity.Vendor, error) {
+	values, err := u.customFieldUC.ParseFilter(ctx, entity.CustomFieldEntityVendor, filter.CustomFields)
+	if err != nil {
+		return nil, err
+	}
+	filter.CustomFields = values
 	return u.repo.List(ctx, filter)
 }
 
+// ExportVendors lays the vendors matching a filter out for an export, with a
+// column for each custom field defined on vendors
+func (u *VendorUseCase) ExportVendors(ctx context.Context, filter entity.VendorFilter) ([]export.Column, [][]interface{}, error) {
+	vendors, err := u.ListVendors(ctx, filter)
+	if err != nil {
+		return nil, nil, err
+	}
+	return customFieldTable(ctx, u.customFieldUC, entity.CustomFieldEntityVendor, vendors, func(vendor *entity.Vendor) entity.JSONMap {
+		return vendor.CustomFields
+	})
+}
+
 // CreateProduct creates a new product
 func (u *VendorUseCase) CreateProduct(ctx context.Context, product *entity.Product) error {
 	return u.repo.CreateProduct(ctx, product)
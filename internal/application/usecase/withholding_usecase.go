@@ -0,0 +1,156 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
+)
+
+var (
+	ErrWithholdingRateNotFound = entity.NewError(entity.ErrCodeNotFound, "withholding tax rate not found")
+	ErrWithholdingRateExists   = entity.NewError(entity.ErrCodeConflict, "a withholding tax rate is already set for this vendor or country")
+	ErrWithholdingRateTarget   = entity.NewError(entity.ErrCodeInvalidArgument, "a withholding tax rate applies to either a vendor or a country")
+	ErrWithholdingVendor       = entity.NewError(entity.ErrCodeNotFound, "vendor not found")
+	ErrWithholdingPeriod       = entity.NewError(entity.ErrCodeInvalidArgument, "the start date must not be after the end date")
+)
+
+// WithholdingUseCase handles the withholding tax rates of vendors and the
+// certificates of the tax withheld from their payments. The tax itself is
+// withheld by the finance use case as invoices and payments are recorded.
+type WithholdingUseCase struct {
+	withholdingRepo *repository.WithholdingRepository
+	vendorRepo      *repository.VendorRepository
+	currencyUC      *CurrencyUseCase
+}
+
+// NewWithholdingUseCase creates a new withholding use case
+func NewWithholdingUseCase(withholdingRepo *repository.WithholdingRepository, vendorRepo *repository.VendorRepository, currencyUC *CurrencyUseCase) *WithholdingUseCase {
+	return &WithholdingUseCase{
+		withholdingRepo: withholdingRepo,
+		vendorRepo:      vendorRepo,
+		currencyUC:      currencyUC,
+	}
+}
+
+// CreateRate sets the withholding tax rate of a vendor or a country. Only
+// purchase invoices issued afterwards withhold it.
+func (u *WithholdingUseCase) CreateRate(ctx context.Context, req *entity.WithholdingTaxRateRequest) (*entity.WithholdingTaxRate, error) {
+	rate := &entity.WithholdingTaxRate{Active: true}
+	if err := u.applyRate(ctx, rate, req); err != nil {
+		return nil, err
+	}
+	if err := u.withholdingRepo.CreateRate(ctx, rate); err != nil {
+		if errors.Is(err, repository.ErrDuplicateEntry) {
+			return nil, ErrWithholdingRateExists
+		}
+		return nil, fmt.Errorf("error creating withholding tax rate: %w", err)
+	}
+	return u.GetRate(ctx, rate.ID)
+}
+
+// UpdateRate changes a withholding tax rate, or deactivates it. Invoices
+// already issued keep the rate they were issued with.
+func (u *WithholdingUseCase) UpdateRate(ctx context.Context, id uint, req *entity.WithholdingTaxRateRequest) (*entity.WithholdingTaxRate, error) {
+	rate, err := u.GetRate(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := u.applyRate(ctx, rate, req); err != nil {
+		return nil, err
+	}
+	if err := u.withholdingRepo.UpdateRate(ctx, rate); err != nil {
+		if errors.Is(err, repository.ErrDuplicateEntry) {
+			return nil, ErrWithholdingRateExists
+		}
+		return nil, fmt.Errorf("error updating withholding tax rate: %w", err)
+	}
+	return u.GetRate(ctx, rate.ID)
+}
+
+// applyRate sets the fields of a rate from a request, which names either an
+// existing vendor or a country
+func (u *WithholdingUseCase) applyRate(ctx context.Context, rate *entity.WithholdingTaxRate, req *entity.WithholdingTaxRateRequest) error {
+	country := strings.TrimSpace(req.Country)
+	if (req.VendorID == nil) == (country == "") {
+		return ErrWithholdingRateTarget
+	}
+	if req.VendorID != nil {
+		if _, err := u.vendorRepo.FindByID(ctx, *req.VendorID); err != nil {
+			return ErrWithholdingVendor
+		}
+	}
+
+	rate.VendorID = req.VendorID
+	rate.Vendor = nil
+	rate.Country = country
+	rate.Rate = req.Rate
+	rate.Description = req.Description
+	if req.Active != nil {
+		rate.Active = *req.Active
+	}
+	return nil
+}
+
+// GetRate retrieves a withholding tax rate
+func (u *WithholdingUseCase) GetRate(ctx context.Context, id uint) (*entity.WithholdingTaxRate, error) {
+	rate, err := u.withholdingRepo.GetRate(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, ErrWithholdingRateNotFound
+		}
+		return nil, fmt.Errorf("error getting withholding tax rate: %w", err)
+	}
+	return rate, nil
+}
+
+// ListRates lists the active withholding tax rates, or all of them
+func (u *WithholdingUseCase) ListRates(ctx context.Context, all bool) ([]entity.WithholdingTaxRate, error) {
+	rates, err := u.withholdingRepo.ListRates(ctx, all)
+	if err != nil {
+		return nil, fmt.Errorf("error listing withholding tax rates: %w", err)
+	}
+	return rates, nil
+}
+
+// GetCertificates states the tax withheld from the completed payments made
+// to each vendor between two dates, the last one included, or to a single
+// vendor. Vendors nothing was withheld from get no certificate.
+func (u *WithholdingUseCase) GetCertificates(ctx context.Context, vendorID *uint, startDate, endDate time.Time) ([]entity.WithholdingCertificate, error) {
+	if startDate.After(endDate) {
+		return nil, ErrWithholdingPeriod
+	}
+
+	payments, err := u.withholdingRepo.WithheldPayments(ctx, vendorID, startDate, endOfDay(endDate))
+	if err != nil {
+		return nil, fmt.Errorf("error listing withheld payments: %w", err)
+	}
+
+	certificates := []entity.WithholdingCertificate{}
+	for _, payment := range payments {
+		n := len(certificates)
+		if n == 0 || certificates[n-1].VendorID != payment.VendorID {
+			certificates = append(certificates, entity.WithholdingCertificate{
+				VendorID:     payment.VendorID,
+				VendorCode:   payment.VendorCode,
+				VendorName:   payment.VendorName,
+				TaxID:        payment.TaxID,
+				Country:      payment.Country,
+				StartDate:    startDate,
+				EndDate:      endDate,
+				CurrencyCode: u.currencyUC.BaseCurrency(),
+			})
+			n++
+		}
+		certificate := &certificates[n-1]
+		certificate.Payments = append(certificate.Payments, payment.WithholdingCertificateLine)
+		certificate.GrossAmount = roundAmount(certificate.GrossAmount + payment.BaseGrossAmount)
+		certificate.WithheldAmount = roundAmount(certificate.WithheldAmount + payment.BaseWithheldAmount)
+		certificate.NetAmount = roundAmount(certificate.GrossAmount - certificate.WithheldAmount)
+	}
+	return certificates, nil
+}
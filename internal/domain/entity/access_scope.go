@@ -0,0 +1,89 @@
+package entity
+
+import (
+	"context"
+)
+
+// ErrOutsideAccessScope is returned for records of a store or department the
+// user's role does not cover
+var ErrOutsideAccessScope = NewError(ErrCodePermissionDenied, "the record is outside your access scope")
+
+// AccessScope restricts a user to the records of some stores (warehouses)
+// and departments, as given by their role. An empty list leaves that
+// dimension unrestricted, so roles without a scope see everything.
+type AccessScope struct {
+	StoreIDs      []string `json:"store_ids,omitempty"`
+	DepartmentIDs []uint   `json:"department_ids,omitempty"`
+}
+
+// RestrictsStores tells whether the scope limits the stores
+func (s AccessScope) RestrictsStores() bool {
+	return len(s.StoreIDs) > 0
+}
+
+// RestrictsDepartments tells whether the scope limits the departments
+func (s AccessScope) RestrictsDepartments() bool {
+	return len(s.DepartmentIDs) > 0
+}
+
+// AllowsStore tells whether the scope covers a store
+func (s AccessScope) AllowsStore(storeID string) bool {
+	if !s.RestrictsStores() {
+		return true
+	}
+	for _, id := range s.StoreIDs {
+		if id == storeID {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsDepartment tells whether the scope covers a department. Records of
+// no department are only covered when departments are unrestricted.
+func (s AccessScope) AllowsDepartment(departmentID *uint) bool {
+	if !s.RestrictsDepartments() {
+		return true
+	}
+	if departmentID == nil {
+		return false
+	}
+	for _, id := range s.DepartmentIDs {
+		if id == *departmentID {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckStore returns ErrOutsideAccessScope unless the scope covers a store
+func (s AccessScope) CheckStore(storeID string) error {
+	if !s.AllowsStore(storeID) {
+		return ErrOutsideAccessScope
+	}
+	return nil
+}
+
+// CheckDepartment returns ErrOutsideAccessScope unless the scope covers a
+// department
+func (s AccessScope) CheckDepartment(departmentID *uint) error {
+	if !s.AllowsDepartment(departmentID) {
+		return ErrOutsideAccessScope
+	}
+	return nil
+}
+
+type accessScopeKey struct{}
+
+// WithAccessScope returns a context restricted to a scope, which the use
+// cases and repositories it reaches apply to what they read and change
+func WithAccessScope(ctx context.Context, scope AccessScope) context.Context {
+	return context.WithValue(ctx, accessScopeKey{}, scope)
+}
+
+// AccessScopeFromContext returns the scope of ctx, which is unrestricted when
+// there is none, as for background jobs
+func AccessScopeFromContext(ctx context.Context) AccessScope {
+	scope, _ := ctx.Value(accessScopeKey{}).(AccessScope)
+	return scope
+}
@@ -0,0 +1,73 @@
+package entity
+
+import "time"
+
+// AccountingExportFormat is a file format finance records are exported to
+// for an external accounting system
+type AccountingExportFormat string
+
+const (
+	AccountingExportIIF  AccountingExportFormat = "QUICKBOOKS_IIF" // QuickBooks Desktop import file
+	AccountingExportXero AccountingExportFormat = "XERO_CSV"       // zip of Xero import templates
+	AccountingExportJSON AccountingExportFormat = "JSON"           // generic document of the records and journal entries
+)
+
+// Kinds of records an accounting export holds
+const (
+	AccountingRecordInvoice      = "INVOICE"      // finance invoice
+	AccountingRecordPayment      = "PAYMENT"      // completed finance payment
+	AccountingRecordDepreciation = "DEPRECIATION" // asset depreciation entry
+	AccountingRecordProvision    = "PROVISION"    // inventory provision entry
+)
+
+// AccountingExportRequest represents the request to export the finance
+// records of a date range not yet exported in a format
+type AccountingExportRequest struct {
+	Format   AccountingExportFormat `json:"format" binding:"required"`
+	FromDate time.Time              `json:"from_date" binding:"required"`
+	ToDate   time.Time              `json:"to_date" binding:"required"` // last day included
+}
+
+// AccountingExportRun records an export of finance records and keeps the file
+// it produced
+type AccountingExportRun struct {
+	ID             uint                   `json:"id" gorm:"primaryKey"`
+	Format         AccountingExportFormat `json:"format" gorm:"type:varchar(20);not null"`
+	FromDate       time.Time              `json:"from_date" gorm:"type:date;not null"`
+	ToDate         time.Time              `json:"to_date" gorm:"type:date;not null"`
+	Invoices       int                    `json:"invoices"`
+	Payments       int                    `json:"payments"`
+	JournalEntries int                    `json:"journal_entries"`
+	FileName       string                 `json:"file_name" gorm:"not null"`
+	ContentType    string                 `json:"content_type" gorm:"not null"`
+	Content        []byte                 `json:"-" gorm:"type:bytea;not null"`
+	CreatedBy      uint                   `json:"created_by"`
+	CreatedAt      time.Time              `json:"created_at"`
+}
+
+// AccountingExportRecord flags a finance record as exported in a format, so
+// later exports in the format leave it out
+type AccountingExportRecord struct {
+	ID         uint                   `json:"id" gorm:"primaryKey"`
+	RunID      uint                   `json:"run_id" gorm:"not null;index"`
+	Format     AccountingExportFormat `json:"format" gorm:"type:varchar(20);not null;uniqueIndex:idx_accounting_export_records_record"`
+	RecordType string                 `json:"record_type" gorm:"type:varchar(20);not null;uniqueIndex:idx_accounting_export_records_record"`
+	RecordID   string                 `json:"record_id" gorm:"not null;uniqueIndex:idx_accounting_export_records_record"`
+	CreatedAt  time.Time              `json:"created_at"`
+}
+
+// AccountingJournalEntry is a balanced journal entry of postings made outside
+// invoices and payments, such as depreciation and inventory provisions
+type AccountingJournalEntry struct {
+	Reference string                  `json:"reference"`
+	Date      time.Time               `json:"date"`
+	Memo      string                  `json:"memo"`
+	Lines     []AccountingJournalLine `json:"lines"`
+}
+
+// AccountingJournalLine debits or credits an account in a journal entry
+type AccountingJournalLine struct {
+	Account string  `json:"account"`
+	Debit   float64 `json:"debit,omitempty"`
+	Credit  float64 `json:"credit,omitempty"`
+}
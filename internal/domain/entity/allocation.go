@@ -0,0 +1,89 @@
+package entity
+
+import "time"
+
+// AllocationPolicy decides which confirmed orders get scarce stock first
+type AllocationPolicy string
+
+const (
+	// AllocationPolicyPriority serves clients by loyalty tier, highest first, then by order date
+	AllocationPolicyPriority AllocationPolicy = "PRIORITY"
+	// AllocationPolicyFIFO serves orders by order date, oldest first
+	AllocationPolicyFIFO AllocationPolicy = "FIFO"
+	// AllocationPolicyProportional shares stock in proportion to the quantity each order still needs
+	AllocationPolicyProportional AllocationPolicy = "PROPORTIONAL"
+)
+
+// StockAllocationStatus represents the status of a stock reservation
+type StockAllocationStatus string
+
+const (
+	StockAllocationActive    StockAllocationStatus = "ACTIVE"    // stock is reserved for the order
+	StockAllocationReleased  StockAllocationStatus = "RELEASED"  // replaced by a later run or the order was cancelled
+	StockAllocationFulfilled StockAllocationStatus = "FULFILLED" // the reserved stock was shipped
+)
+
+// StockAllocation reserves stock in a store for a sales order line. Active
+// allocations are not available to new sales orders.
+type StockAllocation struct {
+	ID                uint                  `json:"id" gorm:"primaryKey"`
+	RunID             string                `json:"run_id" gorm:"type:uuid;not null;index"`
+	Policy            AllocationPolicy      `json:"policy" gorm:"type:varchar(20);not null"`
+	SalesOrderID      string                `json:"sales_order_id" gorm:"type:uuid;not null;index"`
+	SKUID             string                `json:"sku_id" gorm:"type:uuid;not null"`
+	StoreID           string                `json:"store_id" gorm:"type:uuid;not null"`
+	RequestedQuantity float64               `json:"requested_quantity" gorm:"type:decimal(15,2);not null"`
+	AllocatedQuantity float64               `json:"allocated_quantity" gorm:"type:decimal(15,2);not null"`
+	Status            StockAllocationStatus `json:"status" gorm:"type:varchar(20);not null;default:'ACTIVE'"`
+	CreatedByID       uint                  `json:"created_by_id"`
+	CreatedAt         time.Time             `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt         time.Time             `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// StockAllocationFilter represents filters for listing stock allocations
+type StockAllocationFilter struct {
+	SalesOrderID string                `json:"sales_order_id,omitempty"`
+	StoreID      string                `json:"store_id,omitempty"`
+	SKUID        string                `json:"sku_id,omitempty"`
+	Status       StockAllocationStatus `json:"status,omitempty"`
+}
+
+// AllocationRunRequest represents a request to allocate a store's stock across confirmed orders
+type AllocationRunRequest struct {
+	StoreID string           `json:"store_id" binding:"required"`
+	Policy  AllocationPolicy `json:"policy" binding:"required,oneof=PRIORITY FIFO PROPORTIONAL"`
+	SKUIDs  []string         `json:"sku_ids"` // limit the run to these SKUs; all SKUs when empty
+	DryRun  bool             `json:"dry_run"` // compute the allocation without reserving stock
+}
+
+// AllocationLine is the quantity allocated to one SKU of one order
+type AllocationLine struct {
+	SalesOrderID string    `json:"sales_order_id"`
+	OrderNumber  string    `json:"order_number"`
+	ClientID     uint      `json:"client_id"`
+	OrderDate    time.Time `json:"order_date"`
+	SKUID        string    `json:"sku_id"`
+	Requested    float64   `json:"requested"`
+	Allocated    float64   `json:"allocated"`
+	Shortfall    float64   `json:"shortfall"`
+}
+
+// ShortedOrder is an order that could not be allocated all it needs
+type ShortedOrder struct {
+	SalesOrderID string           `json:"sales_order_id"`
+	OrderNumber  string           `json:"order_number"`
+	ClientID     uint             `json:"client_id"`
+	Lines        []AllocationLine `json:"lines"`
+}
+
+// AllocationRunResult reports the outcome of an allocation run
+type AllocationRunResult struct {
+	RunID         string             `json:"run_id,omitempty"`
+	Policy        AllocationPolicy   `json:"policy"`
+	StoreID       string             `json:"store_id"`
+	DryRun        bool               `json:"dry_run"`
+	Available     map[string]float64 `json:"available"`
+	Lines         []AllocationLine   `json:"lines"`
+	ShortedOrders []ShortedOrder     `json:"shorted_orders"`
+	RunAt         time.Time          `json:"run_at"`
+}
@@ -0,0 +1,66 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// APIKey lets an external system call the API without user credentials. It
+// acts on behalf of the user who created it, with the permissions it was
+// given, which are at most that user's, and the creator's access scope. Only
+// a hash of the key is kept; the key itself is shown once, when it is
+// created or rotated.
+type APIKey struct {
+	ID            uint                `json:"id" gorm:"primaryKey"`
+	Name          string              `json:"name" gorm:"type:varchar(100);not null"`
+	Prefix        string              `json:"prefix" gorm:"type:varchar(20);not null"` // start of the key, to tell keys apart
+	KeyHash       string              `json:"-" gorm:"type:varchar(64);not null;uniqueIndex"`
+	Permissions   GormPermissionSlice `json:"permissions" gorm:"type:text[];not null"`
+	StoreIDs      pq.StringArray      `json:"store_ids,omitempty" gorm:"type:text[]"`        // access scope of the creator when the key was created
+	DepartmentIDs pq.Int64Array       `json:"department_ids,omitempty" gorm:"type:bigint[]"` // access scope of the creator when the key was created
+	CreatedBy     uint                `json:"created_by" gorm:"not null;index"`
+	Creator       *User               `json:"creator,omitempty" gorm:"foreignKey:CreatedBy"`
+	ExpiresAt     *time.Time          `json:"expires_at,omitempty"`
+	LastUsedAt    *time.Time          `json:"last_used_at,omitempty"`
+	LastUsedIP    string              `json:"last_used_ip,omitempty" gorm:"type:varchar(45)"`
+	RotatedAt     *time.Time          `json:"rotated_at,omitempty"`
+	RevokedAt     *time.Time          `json:"revoked_at,omitempty"`
+	RevokedBy     *uint               `json:"revoked_by,omitempty"`
+	CreatedAt     time.Time           `json:"created_at"`
+	UpdatedAt     time.Time           `json:"updated_at"`
+}
+
+// ActiveAt reports whether the key is accepted at the given time
+func (k *APIKey) ActiveAt(at time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || k.ExpiresAt.After(at))
+}
+
+// AccessScope returns the stores and departments the key is limited to
+func (k *APIKey) AccessScope() AccessScope {
+	scope := AccessScope{StoreIDs: k.StoreIDs}
+	for _, id := range k.DepartmentIDs {
+		scope.DepartmentIDs = append(scope.DepartmentIDs, uint(id))
+	}
+	return scope
+}
+
+// APIKeyRequest represents the request to create an API key
+type APIKeyRequest struct {
+	Name          string       `json:"name" binding:"required,max=100"`
+	Permissions   []Permission `json:"permissions" binding:"required,min=1"`
+	ExpiresInDays int          `json:"expires_in_days" binding:"gte=0"` // 0 for a key that does not expire
+}
+
+// APIKeySecret is an API key with its secret, answered once when the key is
+// created or rotated
+type APIKeySecret struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+// APIKeyFilter represents filters for listing API keys
+type APIKeyFilter struct {
+	CreatedBy      uint `json:"created_by,omitempty"`
+	IncludeRevoked bool `json:"include_revoked,omitempty"`
+}
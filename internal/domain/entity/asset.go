@@ -0,0 +1,106 @@
+package entity
+
+import "time"
+
+// DepreciationMethod decides how an asset's cost is spread over its useful life
+type DepreciationMethod string
+
+const (
+	// DepreciationStraightLine charges the same amount every month
+	DepreciationStraightLine DepreciationMethod = "STRAIGHT_LINE"
+	// DepreciationDecliningBalance charges twice the straight-line rate on the
+	// remaining book value, switching to straight line once that charges more
+	DepreciationDecliningBalance DepreciationMethod = "DECLINING_BALANCE"
+)
+
+// FixedAssetStatus represents the status of a fixed asset
+type FixedAssetStatus string
+
+const (
+	FixedAssetStatusActive           FixedAssetStatus = "ACTIVE"
+	FixedAssetStatusFullyDepreciated FixedAssetStatus = "FULLY_DEPRECIATED"
+	FixedAssetStatusDisposed         FixedAssetStatus = "DISPOSED"
+)
+
+// AssetTerms marks a purchase order line as a capital purchase. Received
+// quantities of the line are registered as fixed assets instead of stock.
+type AssetTerms struct {
+	Category         string             `json:"category"`
+	Method           DepreciationMethod `json:"method"`
+	UsefulLifeMonths int                `json:"useful_life_months"`
+	SalvageValue     float64            `json:"salvage_value"` // per unit, in the order currency
+}
+
+// FixedAsset is an entry in the fixed asset register. Amounts are in the base currency.
+type FixedAsset struct {
+	ID                      uint               `json:"id" gorm:"primaryKey"`
+	AssetNumber             string             `json:"asset_number" gorm:"uniqueIndex;not null"`
+	Name                    string             `json:"name" gorm:"not null"`
+	Category                string             `json:"category"`
+	SKUID                   string             `json:"sku_id" gorm:"type:uuid"`
+	PurchaseOrderID         string             `json:"purchase_order_id" gorm:"type:uuid;index"`
+	PurchaseReceiptID       string             `json:"purchase_receipt_id" gorm:"type:uuid"`
+	VendorID                uint               `json:"vendor_id"`
+	StoreID                 string             `json:"store_id"`
+	Quantity                float64            `json:"quantity" gorm:"type:decimal(15,2);not null"`
+	AcquisitionDate         time.Time          `json:"acquisition_date" gorm:"not null"`
+	AcquisitionCost         float64            `json:"acquisition_cost" gorm:"type:decimal(15,2);not null"`
+	SalvageValue            float64            `json:"salvage_value" gorm:"type:decimal(15,2);default:0"`
+	Method                  DepreciationMethod `json:"method" gorm:"type:varchar(20);not null"`
+	UsefulLifeMonths        int                `json:"useful_life_months" gorm:"not null"`
+	AccumulatedDepreciation float64            `json:"accumulated_depreciation" gorm:"type:decimal(15,2);default:0"`
+	BookValue               float64            `json:"book_value" gorm:"type:decimal(15,2);not null"`
+	DepreciatedThrough      string             `json:"depreciated_through"` // last period posted, YYYY-MM
+	Status                  FixedAssetStatus   `json:"status" gorm:"type:varchar(20);not null;default:'ACTIVE'"`
+	DisposalDate            *time.Time         `json:"disposal_date,omitempty"`
+	DisposalProceeds        float64            `json:"disposal_proceeds" gorm:"type:decimal(15,2);default:0"`
+	DisposalGainLoss        float64            `json:"disposal_gain_loss" gorm:"type:decimal(15,2);default:0"`
+	DisposalNotes           string             `json:"disposal_notes" gorm:"type:text"`
+	CreatedAt               time.Time          `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt               time.Time          `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// AssetDepreciationEntry is one month of depreciation posted for an asset
+type AssetDepreciationEntry struct {
+	ID                      uint      `json:"id" gorm:"primaryKey"`
+	AssetID                 uint      `json:"asset_id" gorm:"not null;uniqueIndex:idx_asset_depreciation_period"`
+	Period                  string    `json:"period" gorm:"type:varchar(7);not null;uniqueIndex:idx_asset_depreciation_period"`
+	Amount                  float64   `json:"amount" gorm:"type:decimal(15,2);not null"`
+	AccumulatedDepreciation float64   `json:"accumulated_depreciation" gorm:"type:decimal(15,2);not null"`
+	BookValue               float64   `json:"book_value" gorm:"type:decimal(15,2);not null"`
+	PostedBy                *uint     `json:"posted_by"`
+	PostedAt                time.Time `json:"posted_at"`
+}
+
+// DepreciationScheduleLine is one month of an asset's depreciation schedule
+type DepreciationScheduleLine struct {
+	Period                  string  `json:"period"`
+	Depreciation            float64 `json:"depreciation"`
+	AccumulatedDepreciation float64 `json:"accumulated_depreciation"`
+	BookValue               float64 `json:"book_value"`
+	Posted                  bool    `json:"posted"`
+}
+
+// FixedAssetFilter represents filters for listing fixed assets
+type FixedAssetFilter struct {
+	Category        string           `json:"category,omitempty"`
+	Status          FixedAssetStatus `json:"status,omitempty"`
+	PurchaseOrderID string           `json:"purchase_order_id,omitempty"`
+	Page            int              `json:"page,omitempty"`
+	PageSize        int              `json:"page_size,omitempty"`
+}
+
+// DisposeAssetRequest represents a request to dispose of a fixed asset
+type DisposeAssetRequest struct {
+	DisposalDate string  `json:"disposal_date" binding:"required"` // YYYY-MM-DD
+	Proceeds     float64 `json:"proceeds" binding:"min=0"`         // in the base currency
+	Notes        string  `json:"notes"`
+}
+
+// DepreciationRunResult reports the outcome of posting depreciation for a period
+type DepreciationRunResult struct {
+	Period       string                   `json:"period"`
+	Assets       int                      `json:"assets"`
+	Depreciation float64                  `json:"depreciation"`
+	Entries      []AssetDepreciationEntry `json:"entries"`
+}
@@ -0,0 +1,29 @@
+package entity
+
+import "time"
+
+// AttachmentOwnerType names the kind of document a file is attached to
+type AttachmentOwnerType string
+
+const (
+	AttachmentPurchaseRequest AttachmentOwnerType = "purchase_request"
+	AttachmentPurchaseOrder   AttachmentOwnerType = "purchase_order"
+	AttachmentPurchaseReceipt AttachmentOwnerType = "purchase_receipt"
+	AttachmentExpenseClaim    AttachmentOwnerType = "expense_claim" // receipts of the expenses claimed
+)
+
+// Attachment is a file, such as a quote or a delivery note, uploaded to a
+// document. The file itself is kept in the file store under FileKey.
+type Attachment struct {
+	ID          uint64              `json:"id" gorm:"primaryKey"`
+	OwnerType   AttachmentOwnerType `json:"owner_type" gorm:"type:varchar(30);not null"`
+	OwnerID     string              `json:"owner_id" gorm:"type:varchar(64);not null"`
+	FileName    string              `json:"file_name" gorm:"type:varchar(255);not null"`
+	ContentType string              `json:"content_type" gorm:"type:varchar(100);not null"`
+	Size        int64               `json:"size" gorm:"not null"` // in bytes
+	FileKey     string              `json:"-" gorm:"type:varchar(255);not null"`
+	FileURL     string              `json:"file_url,omitempty" gorm:"-"`
+	FileExpires *time.Time          `json:"file_url_expires_at,omitempty" gorm:"-"`
+	UploadedBy  *uint               `json:"uploaded_by,omitempty"`
+	CreatedAt   time.Time           `json:"created_at"`
+}
@@ -1,6 +1,11 @@
 package entity
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
 	"time"
 )
 
@@ -16,15 +21,103 @@ const (
 )
 
 type AuditLog struct {
-	ID        uint       `json:"id" gorm:"primaryKey"`
-	UserID    uint       `json:"user_id"`
-	User      *User      `json:"user" gorm:"foreignKey:UserID"`
-	Action    ActionType `json:"action" gorm:"type:varchar(20)"`
-	Resource  string     `json:"resource" gorm:"type:varchar(50)"`
-	Detail    string     `json:"detail" gorm:"type:text"`
-	IP        string     `json:"ip" gorm:"type:varchar(45)"`
-	UserAgent string     `json:"user_agent" gorm:"type:text"`
-	CreatedAt time.Time  `json:"created_at"`
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	UserID         uint       `json:"user_id"`
+	User           *User      `json:"user" gorm:"foreignKey:UserID"`
+	ImpersonatorID *uint      `json:"impersonator_id,omitempty" gorm:"index"` // admin who acted as the user in an impersonation session
+	Action         ActionType `json:"action" gorm:"type:varchar(20)"`
+	Resource       string     `json:"resource" gorm:"type:varchar(50)"`
+	Detail         string     `json:"detail" gorm:"type:text"`
+	IP             string     `json:"ip" gorm:"type:varchar(45)"`
+	UserAgent      string     `json:"user_agent" gorm:"type:text"`
+	CreatedAt      time.Time  `json:"created_at"`
+	PrevHash       string     `json:"prev_hash,omitempty" gorm:"type:varchar(64)"` // hash of the log written before, chaining the logs
+	Hash           string     `json:"hash,omitempty" gorm:"type:varchar(64)"`      // empty for logs written before chaining
+}
+
+// ChainHash returns the SHA-256 hash of the log's content and previous hash,
+// hex encoded. Any change to a chained log, or removal of the log before it,
+// no longer matches its hash or previous hash. The impersonator is only
+// hashed when set, so logs chained before impersonation keep their hashes.
+func (l *AuditLog) ChainHash() string {
+	fields := []string{
+		l.PrevHash,
+		strconv.FormatUint(uint64(l.UserID), 10),
+		string(l.Action),
+		l.Resource,
+		l.Detail,
+		l.IP,
+		l.UserAgent,
+		l.CreatedAt.UTC().Format(time.RFC3339Nano),
+	}
+	if l.ImpersonatorID != nil {
+		fields = append(fields, strconv.FormatUint(uint64(*l.ImpersonatorID), 10))
+	}
+	content, _ := json.Marshal(fields)
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// AuditLogArchive records audit logs past their retention exported to the file
+// store as gzipped JSON lines, oldest first, and removed from the database.
+// The hash of its last log anchors the chain of the logs kept.
+type AuditLogArchive struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	FileKey        string    `json:"file_key" gorm:"not null"`
+	Checksum       string    `json:"checksum" gorm:"type:varchar(64);not null"` // SHA-256 of the file
+	Cutoff         time.Time `json:"cutoff" gorm:"not null"`                    // logs written before this were exported
+	FirstID        uint      `json:"first_id" gorm:"not null"`
+	LastID         uint      `json:"last_id" gorm:"not null;index"`
+	FirstCreatedAt time.Time `json:"first_created_at"`
+	LastCreatedAt  time.Time `json:"last_created_at"`
+	Rows           int64     `json:"rows" gorm:"not null"`
+	LastHash       string    `json:"last_hash" gorm:"type:varchar(64)"`
+	CreatedAt      time.Time `json:"created_at" gorm:"autoCreateTime"`
+	DownloadURL    string    `json:"download_url,omitempty" gorm:"-"`
+}
+
+// AuditRetentionResult summarizes an audit log retention run
+type AuditRetentionResult struct {
+	Cutoff   time.Time        `json:"cutoff"`
+	Exported int64            `json:"exported"`
+	Archive  *AuditLogArchive `json:"archive,omitempty"`
+}
+
+// AuditChainBreak is a log that does not match the hash chain
+type AuditChainBreak struct {
+	LogID  uint   `json:"log_id"`
+	Reason string `json:"reason"`
+}
+
+// AuditChainVerification is the result of checking the hash chain of the
+// audit logs written in a period, live and archived
+type AuditChainVerification struct {
+	StartDate  *time.Time        `json:"start_date,omitempty"`
+	EndDate    *time.Time        `json:"end_date,omitempty"`
+	Checked    int64             `json:"checked"`
+	Unhashed   int64             `json:"unhashed"` // logs written before chaining, which cannot be verified
+	FirstID    uint              `json:"first_id,omitempty"`
+	LastID     uint              `json:"last_id,omitempty"`
+	Valid      bool              `json:"valid"`
+	BreakCount int64             `json:"break_count"`
+	Breaks     []AuditChainBreak `json:"breaks"` // the first breaks found
+	VerifiedAt time.Time         `json:"verified_at"`
+}
+
+// AuditLogFilter represents filters for searching audit logs
+type AuditLogFilter struct {
+	UserID         uint       `json:"user_id,omitempty"`
+	ImpersonatorID uint       `json:"impersonator_id,omitempty"` // requests the admin made as other users
+	Impersonated   bool       `json:"impersonated,omitempty"`    // requests made in any impersonation session
+	Action         ActionType `json:"action,omitempty"`
+	Resource       string     `json:"resource,omitempty"` // part of the resource path, e.g. an entity type such as "skus"
+	IP             string     `json:"ip,omitempty"`
+	Query          string     `json:"query,omitempty"` // free text matched against resource, detail and user agent
+	StartDate      *time.Time `json:"start_date,omitempty"`
+	EndDate        *time.Time `json:"end_date,omitempty"`
+	Archived       bool       `json:"archived,omitempty"` // query the archive table instead of the live one
+	Page           int        `json:"page,omitempty"`
+	PageSize       int        `json:"page_size,omitempty"`
 }
 
 type AuditLogRepository interface {
@@ -34,4 +127,12 @@ type AuditLogRepository interface {
 	FindByDateRange(start, end time.Time, limit, offset int) ([]AuditLog, error)
 	List(limit, offset int) ([]AuditLog, error)
 	Count(filter map[string]interface{}) (int64, error)
+	Search(filter *AuditLogFilter) ([]AuditLog, int64, error)
+	SearchPage(filter *AuditLogFilter, page CursorPage) ([]AuditLog, *Cursor, error)
+	ChainRows(ctx context.Context, start, end time.Time, afterID uint, limit int) ([]AuditLog, error)
+	PreviousHash(ctx context.Context, beforeID uint) (string, bool, error)
+	CountBefore(ctx context.Context, cutoff time.Time) (int64, error)
+	DeleteBefore(ctx context.Context, cutoff time.Time, lastID uint) (int64, error)
+	CreateArchive(ctx context.Context, archive *AuditLogArchive) error
+	ListArchives(ctx context.Context, page, pageSize int) ([]AuditLogArchive, int64, error)
 }
@@ -0,0 +1,123 @@
+package entity
+
+import "time"
+
+// BankAccount is a bank account whose transactions are imported from a bank
+// feed provider, to be matched against open invoices
+type BankAccount struct {
+	ID           uint       `json:"id" gorm:"primaryKey"`
+	Name         string     `json:"name" gorm:"not null"`
+	Provider     string     `json:"provider" gorm:"type:varchar(20);not null"` // adapter fetching the account's transactions, such as OPEN_BANKING
+	BaseURL      string     `json:"base_url" gorm:"not null"`                  // address of the provider's API
+	ExternalID   string     `json:"external_id" gorm:"not null"`               // account as the provider identifies it
+	AccessToken  string     `json:"-"`                                         // consent token the provider is read with
+	CurrencyCode string     `json:"currency_code" gorm:"type:varchar(3);not null"`
+	Active       bool       `json:"active" gorm:"default:true"`
+	SyncedAt     *time.Time `json:"synced_at,omitempty"` // time of the last import; the next one fetches from a lookback before it
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// BankAccountRequest represents the request to create or update a bank
+// account. An access token left empty on update is kept.
+type BankAccountRequest struct {
+	Name         string `json:"name" binding:"required"`
+	Provider     string `json:"provider" binding:"required"`
+	BaseURL      string `json:"base_url" binding:"required,url"`
+	ExternalID   string `json:"external_id" binding:"required"`
+	AccessToken  string `json:"access_token"`
+	CurrencyCode string `json:"currency_code" binding:"required,len=3"`
+	Active       *bool  `json:"active"`
+}
+
+// BankTransactionStatus represents how far a bank transaction is reconciled
+type BankTransactionStatus string
+
+const (
+	BankTransactionUnmatched BankTransactionStatus = "UNMATCHED"
+	BankTransactionMatched   BankTransactionStatus = "MATCHED" // settled an invoice through the payment it created
+	BankTransactionIgnored   BankTransactionStatus = "IGNORED" // not for an invoice, such as bank charges
+)
+
+// BankTransaction is a booked transaction imported from a bank feed
+type BankTransaction struct {
+	ID           uint                  `json:"id" gorm:"primaryKey"`
+	AccountID    uint                  `json:"account_id" gorm:"not null;uniqueIndex:idx_bank_transactions_external"`
+	ExternalID   string                `json:"external_id" gorm:"not null;uniqueIndex:idx_bank_transactions_external"` // transaction as the provider identifies it
+	BookedAt     time.Time             `json:"booked_at" gorm:"not null;index"`
+	Amount       float64               `json:"amount" gorm:"type:decimal(15,2);not null"` // positive for money received, negative for money paid out
+	CurrencyCode string                `json:"currency_code" gorm:"type:varchar(3);not null"`
+	Description  string                `json:"description,omitempty"`
+	Counterparty string                `json:"counterparty,omitempty"` // payer of money received, payee of money paid out
+	Reference    string                `json:"reference,omitempty"`
+	Status       BankTransactionStatus `json:"status" gorm:"type:varchar(20);not null;index"`
+	PaymentID    *int64                `json:"payment_id,omitempty" gorm:"index"` // finance payment created by the match
+	MatchedBy    *uint                 `json:"matched_by,omitempty"`
+	MatchedAt    *time.Time            `json:"matched_at,omitempty"`
+	CreatedAt    time.Time             `json:"created_at"`
+	UpdatedAt    time.Time             `json:"updated_at"`
+}
+
+// BankTransactionFilter represents filters for listing bank transactions
+type BankTransactionFilter struct {
+	AccountID uint                  `json:"account_id,omitempty"`
+	Status    BankTransactionStatus `json:"status,omitempty"`
+	StartDate *time.Time            `json:"start_date,omitempty"`
+	EndDate   *time.Time            `json:"end_date,omitempty"`
+	Page      int                   `json:"page,omitempty"`
+	PageSize  int                   `json:"page_size,omitempty"`
+}
+
+// BankFeedTransaction is a booked transaction fetched from a bank feed
+// provider, normalized by its adapter
+type BankFeedTransaction struct {
+	ExternalID   string    `json:"external_id"`
+	BookedAt     time.Time `json:"booked_at"`
+	Amount       float64   `json:"amount"` // positive for money received, negative for money paid out
+	CurrencyCode string    `json:"currency_code"`
+	Description  string    `json:"description,omitempty"`
+	Counterparty string    `json:"counterparty,omitempty"`
+	Reference    string    `json:"reference,omitempty"`
+}
+
+// BankImportStatus represents the outcome of a bank feed import
+type BankImportStatus string
+
+const (
+	BankImportSucceeded BankImportStatus = "SUCCEEDED"
+	BankImportFailed    BankImportStatus = "FAILED" // the provider could not be read
+)
+
+// BankImportRun records an import of the transactions of a bank account
+type BankImportRun struct {
+	ID          uint             `json:"id" gorm:"primaryKey"`
+	AccountID   uint             `json:"account_id" gorm:"not null;index"`
+	Status      BankImportStatus `json:"status" gorm:"type:varchar(20);not null"`
+	Fetched     int              `json:"fetched"`
+	Imported    int              `json:"imported"` // fetched transactions that were not imported before
+	Error       string           `json:"error,omitempty" gorm:"type:text"`
+	TriggeredBy string           `json:"triggered_by,omitempty"`
+	StartedAt   time.Time        `json:"started_at" gorm:"not null"`
+	FinishedAt  time.Time        `json:"finished_at"`
+}
+
+// BankMatchSuggestion is an open invoice a bank transaction may settle, with
+// the reasons it was suggested. Higher scores are likelier matches.
+type BankMatchSuggestion struct {
+	InvoiceID     int64              `json:"invoice_id"`
+	InvoiceNumber string             `json:"invoice_number"`
+	InvoiceType   FinanceInvoiceType `json:"invoice_type"`
+	EntityName    string             `json:"entity_name"`
+	DueDate       time.Time          `json:"due_date"`
+	AmountDue     float64            `json:"amount_due"`
+	CurrencyCode  string             `json:"currency_code"`
+	Score         int                `json:"score"`
+	Reasons       []string           `json:"reasons"`
+}
+
+// BankMatchRequest represents the request to settle an invoice with a bank
+// transaction
+type BankMatchRequest struct {
+	InvoiceID int64   `json:"invoice_id" binding:"required"`
+	Amount    float64 `json:"amount" binding:"omitempty,gt=0"` // part of the transaction settling the invoice, the whole of it by default
+}
@@ -2,14 +2,28 @@ package entity
 
 import "time"
 
-// BillOfMaterial represents a bill of materials for a product
+// BillOfMaterial represents a version of the bill of materials for a product. A
+// product's versions cover consecutive periods: the version in effect on a date
+// is the latest one effective from or before it that has not ended. A component
+// that has a bill of materials of its own is a sub-assembly.
 type BillOfMaterial struct {
-	ID        uint      `json:"id" gorm:"primaryKey"`
-	ProductID uint      `json:"product_id" gorm:"not null"`
-	Name      string    `json:"name" gorm:"not null"`
-	Version   string    `json:"version" gorm:"not null"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID            uint       `json:"id" gorm:"primaryKey"`
+	ProductID     uint       `json:"product_id" gorm:"not null"`
+	Name          string     `json:"name" gorm:"not null"`
+	Version       string     `json:"version" gorm:"not null"`
+	EffectiveFrom time.Time  `json:"effective_from" gorm:"not null"`
+	EffectiveTo   *time.Time `json:"effective_to,omitempty"`         // exclusive; open-ended when empty
+	LaborHours    float64    `json:"labor_hours" gorm:"default:0"`   // per unit produced
+	LaborRate     float64    `json:"labor_rate" gorm:"default:0"`    // cost per labor hour
+	OverheadRate  float64    `json:"overhead_rate" gorm:"default:0"` // overhead absorbed per labor hour
+	Items         []BOMItem  `json:"items,omitempty" gorm:"foreignKey:BOMID"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// EffectiveAt reports whether the version is in effect at the given time
+func (b *BillOfMaterial) EffectiveAt(at time.Time) bool {
+	return !b.EffectiveFrom.After(at) && (b.EffectiveTo == nil || b.EffectiveTo.After(at))
 }
 
 // BOMItem represents an item in the bill of materials
@@ -30,6 +44,7 @@ type MRPCalculation struct {
 	ProductionID  uint      `json:"production_id" gorm:"not null"`
 	MaterialID    uint      `json:"material_id" gorm:"not null"`
 	RequiredQty   float64   `json:"required_qty" gorm:"not null"`
+	ForecastQty   float64   `json:"forecast_qty" gorm:"default:0"` // this month's forecast demand from the active demand forecast
 	AvailableQty  float64   `json:"available_qty"`
 	ShortageQty   float64   `json:"shortage_qty"`
 	UnitOfMeasure string    `json:"unit_of_measure"`
@@ -37,3 +52,49 @@ type MRPCalculation struct {
 	CreatedAt     time.Time `json:"created_at"`
 	UpdatedAt     time.Time `json:"updated_at"`
 }
+
+// MaterialCost is the standard unit cost of a purchased component. Components
+// with a bill of materials of their own are costed by rolling it up instead.
+type MaterialCost struct {
+	MaterialID uint      `json:"material_id" gorm:"primaryKey;autoIncrement:false"`
+	UnitCost   float64   `json:"unit_cost" gorm:"not null"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// BOMCostSource tells where the unit cost of a rolled-up component came from
+type BOMCostSource string
+
+const (
+	BOMCostFromMaterial BOMCostSource = "material_cost" // the component's standard cost
+	BOMCostFromBOM      BOMCostSource = "bom"           // rolled up from the sub-assembly's bill of materials
+	BOMCostMissing      BOMCostSource = "missing"       // neither is set; costed at zero
+)
+
+// BOMCostLine is a component of a cost roll-up, per unit of its parent
+type BOMCostLine struct {
+	MaterialID    uint           `json:"material_id"`
+	Quantity      float64        `json:"quantity"`
+	UnitOfMeasure string         `json:"unit_of_measure"`
+	UnitCost      float64        `json:"unit_cost"`
+	ExtendedCost  float64        `json:"extended_cost"`
+	Source        BOMCostSource  `json:"source"`
+	SubAssembly   *BOMCostRollup `json:"sub_assembly,omitempty"`
+}
+
+// BOMCostRollup is the standard cost of a product from the bill of materials in
+// effect on a date: its components, sub-assemblies rolled up level by level,
+// plus labor and overhead at the BOM rates
+type BOMCostRollup struct {
+	ProductID    uint          `json:"product_id"`
+	BOMID        uint          `json:"bom_id"`
+	BOMVersion   string        `json:"bom_version"`
+	Date         time.Time     `json:"date"`
+	MaterialCost float64       `json:"material_cost"` // per unit
+	LaborCost    float64       `json:"labor_cost"`    // per unit
+	OverheadCost float64       `json:"overhead_cost"` // per unit
+	UnitCost     float64       `json:"unit_cost"`
+	Quantity     float64       `json:"quantity,omitempty"`
+	TotalCost    float64       `json:"total_cost,omitempty"` // unit cost times quantity
+	Components   []BOMCostLine `json:"components"`
+	MissingCosts []uint        `json:"missing_costs,omitempty"` // components at any level without a cost
+}
@@ -0,0 +1,170 @@
+package entity
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// BrandingID is the ID of the single branding row
+const BrandingID = 1
+
+// VATNumber is a VAT or tax registration number of the company in a country
+type VATNumber struct {
+	Country string `json:"country" binding:"required,len=2"` // ISO 3166-1 alpha-2
+	Number  string `json:"number" binding:"required"`
+}
+
+// VATNumbers represents the VAT numbers of the company, stored as JSON
+type VATNumbers []VATNumber
+
+// Scan implements the sql.Scanner interface
+func (v *VATNumbers) Scan(value interface{}) error {
+	if value == nil {
+		*v = make(VATNumbers, 0)
+		return nil
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("failed to scan VATNumbers: value is not []byte")
+	}
+	return json.Unmarshal(bytes, v)
+}
+
+// Value implements the driver.Valuer interface
+func (v VATNumbers) Value() (driver.Value, error) {
+	if v == nil {
+		return nil, nil
+	}
+	return json.Marshal(v)
+}
+
+// Branding is the company identity printed on generated documents and emails:
+// the logo, address block, bank details, VAT numbers and invoice footer
+type Branding struct {
+	ID              uint       `json:"-" gorm:"primaryKey"`
+	CompanyName     string     `json:"company_name"`
+	AddressLine1    string     `json:"address_line1"`
+	AddressLine2    string     `json:"address_line2"`
+	City            string     `json:"city"`
+	PostalCode      string     `json:"postal_code"`
+	Country         string     `json:"country"`
+	Email           string     `json:"email"`
+	Phone           string     `json:"phone"`
+	Website         string     `json:"website"`
+	BankName        string     `json:"bank_name"`
+	BankAccountName string     `json:"bank_account_name"`
+	BankAccountNo   string     `json:"bank_account_no"`
+	IBAN            string     `json:"iban"`
+	BIC             string     `json:"bic"`
+	VATNumbers      VATNumbers `json:"vat_numbers" gorm:"type:jsonb"`
+	InvoiceFooter   string     `json:"invoice_footer" gorm:"type:text"`
+	LogoData        []byte     `json:"-"`
+	LogoContentType string     `json:"logo_content_type,omitempty"`
+	LogoUpdatedAt   *time.Time `json:"logo_updated_at,omitempty"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+	UpdatedBy       *uint      `json:"updated_by,omitempty"`
+}
+
+// TableName specifies the table name for Branding
+func (Branding) TableName() string {
+	return "branding"
+}
+
+// HasLogo reports whether a logo has been uploaded
+func (b *Branding) HasLogo() bool {
+	return len(b.LogoData) > 0
+}
+
+// AddressBlock returns the company name and postal address, one line each
+func (b *Branding) AddressBlock() []string {
+	cityLine := strings.TrimSpace(b.PostalCode + " " + b.City)
+	return nonEmptyLines(b.CompanyName, b.AddressLine1, b.AddressLine2, cityLine, b.Country)
+}
+
+// BankDetails returns the bank account payments should be made to, one line each
+func (b *Branding) BankDetails() []string {
+	lines := []string{}
+	for _, field := range []struct{ label, value string }{
+		{"Bank", b.BankName},
+		{"Account name", b.BankAccountName},
+		{"Account no", b.BankAccountNo},
+		{"IBAN", b.IBAN},
+		{"BIC", b.BIC},
+	} {
+		if field.value != "" {
+			lines = append(lines, field.label+": "+field.value)
+		}
+	}
+	return lines
+}
+
+// VATLine returns the VAT numbers on a single line
+func (b *Branding) VATLine() string {
+	numbers := make([]string, len(b.VATNumbers))
+	for i, vat := range b.VATNumbers {
+		numbers[i] = "VAT " + vat.Country + " " + vat.Number
+	}
+	return strings.Join(numbers, " · ")
+}
+
+// FooterLines returns the footer of invoices and other documents: the footer
+// text, followed by the contact details, VAT numbers and bank details
+func (b *Branding) FooterLines() []string {
+	lines := nonEmptyLines(strings.Split(b.InvoiceFooter, "\n")...)
+
+	var contact []string
+	for _, value := range []string{b.Email, b.Phone, b.Website} {
+		if value != "" {
+			contact = append(contact, value)
+		}
+	}
+	lines = append(lines, nonEmptyLines(strings.Join(contact, " · "), b.VATLine())...)
+	return append(lines, b.BankDetails()...)
+}
+
+// nonEmptyLines returns the given lines, trimmed, without the empty ones
+func nonEmptyLines(values ...string) []string {
+	lines := []string{}
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			lines = append(lines, value)
+		}
+	}
+	return lines
+}
+
+// BrandingRequest represents the request to update the branding. The logo is
+// uploaded separately.
+type BrandingRequest struct {
+	CompanyName     string      `json:"company_name" binding:"required"`
+	AddressLine1    string      `json:"address_line1"`
+	AddressLine2    string      `json:"address_line2"`
+	City            string      `json:"city"`
+	PostalCode      string      `json:"postal_code"`
+	Country         string      `json:"country"`
+	Email           string      `json:"email" binding:"omitempty,email"`
+	Phone           string      `json:"phone"`
+	Website         string      `json:"website"`
+	BankName        string      `json:"bank_name"`
+	BankAccountName string      `json:"bank_account_name"`
+	BankAccountNo   string      `json:"bank_account_no"`
+	IBAN            string      `json:"iban"`
+	BIC             string      `json:"bic"`
+	VATNumbers      []VATNumber `json:"vat_numbers" binding:"dive"`
+	InvoiceFooter   string      `json:"invoice_footer"`
+}
+
+// DocumentBranding is the branding laid out for a PDF renderer or email
+// template: the header and footer lines and where to fetch the logo
+type DocumentBranding struct {
+	CompanyName  string   `json:"company_name"`
+	LogoURL      string   `json:"logo_url,omitempty"`
+	AddressBlock []string `json:"address_block"`
+	BankDetails  []string `json:"bank_details"`
+	VATNumbers   []string `json:"vat_numbers"`
+	FooterLines  []string `json:"footer_lines"`
+}
@@ -0,0 +1,262 @@
+package entity
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// maxCalendarDays bounds the search for the next working day of a calendar
+// without working days
+const maxCalendarDays = 366
+
+// Weekdays represents the working days of a calendar, 0 for Sunday to 6 for
+// Saturday, stored as JSON
+type Weekdays []int
+
+// Scan implements the sql.Scanner interface
+func (w *Weekdays) Scan(value interface{}) error {
+	if value == nil {
+		*w = make(Weekdays, 0)
+		return nil
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("failed to scan Weekdays: value is not []byte")
+	}
+	return json.Unmarshal(bytes, w)
+}
+
+// Value implements the driver.Valuer interface
+func (w Weekdays) Value() (driver.Value, error) {
+	if w == nil {
+		return nil, nil
+	}
+	return json.Marshal(w)
+}
+
+// CalendarShift is a working shift, in the calendar's time zone. A shift ending
+// at or before its start runs past midnight.
+type CalendarShift struct {
+	Name  string `json:"name" binding:"required"`
+	Start string `json:"start" binding:"required"` // HH:MM
+	End   string `json:"end" binding:"required"`   // HH:MM
+}
+
+// bounds returns the start and end of the shift on the given day
+func (s CalendarShift) bounds(day time.Time) (time.Time, time.Time, bool) {
+	start, err := time.Parse("15:04", s.Start)
+	if err != nil {
+		return time.Time{}, time.Time{}, false
+	}
+	end, err := time.Parse("15:04", s.End)
+	if err != nil {
+		return time.Time{}, time.Time{}, false
+	}
+	from := time.Date(day.Year(), day.Month(), day.Day(), start.Hour(), start.Minute(), 0, 0, day.Location())
+	to := time.Date(day.Year(), day.Month(), day.Day(), end.Hour(), end.Minute(), 0, 0, day.Location())
+	if !to.After(from) {
+		to = to.AddDate(0, 0, 1)
+	}
+	return from, to, true
+}
+
+// CalendarShifts represents the shifts of a calendar, stored as JSON
+type CalendarShifts []CalendarShift
+
+// Scan implements the sql.Scanner interface
+func (s *CalendarShifts) Scan(value interface{}) error {
+	if value == nil {
+		*s = make(CalendarShifts, 0)
+		return nil
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("failed to scan CalendarShifts: value is not []byte")
+	}
+	return json.Unmarshal(bytes, s)
+}
+
+// Value implements the driver.Valuer interface
+func (s CalendarShifts) Value() (driver.Value, error) {
+	if s == nil {
+		return nil, nil
+	}
+	return json.Marshal(s)
+}
+
+// WorkingCalendar defines the business days, holidays and shifts of the company
+// or of a warehouse. Lead times, promised dates, SLA timers and schedules count
+// only working time. A calendar without shifts works the whole of its business days.
+type WorkingCalendar struct {
+	ID          uint              `json:"id" gorm:"primaryKey"`
+	Name        string            `json:"name" gorm:"not null"`
+	StoreID     *string           `json:"store_id,omitempty" gorm:"uniqueIndex"` // empty for the company calendar
+	Timezone    string            `json:"timezone" gorm:"not null;default:'UTC'"`
+	WorkingDays Weekdays          `json:"working_days" gorm:"type:jsonb;not null"`
+	Shifts      CalendarShifts    `json:"shifts" gorm:"type:jsonb"`
+	Holidays    []CalendarHoliday `json:"holidays,omitempty" gorm:"foreignKey:CalendarID"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+}
+
+// DefaultWorkingCalendar returns the calendar used when none is configured:
+// Monday to Friday in UTC, without holidays or shifts
+func DefaultWorkingCalendar() *WorkingCalendar {
+	return &WorkingCalendar{
+		Name:        "Default",
+		Timezone:    "UTC",
+		WorkingDays: Weekdays{1, 2, 3, 4, 5},
+		Shifts:      CalendarShifts{},
+	}
+}
+
+// Location returns the calendar's time zone, UTC when it is unknown
+func (c *WorkingCalendar) Location() *time.Location {
+	loc, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// IsBusinessDay reports whether the calendar day of t, in the calendar's time
+// zone, is a working day that is not a holiday
+func (c *WorkingCalendar) IsBusinessDay(t time.Time) bool {
+	local := t.In(c.Location())
+	working := false
+	for _, day := range c.WorkingDays {
+		if time.Weekday(day) == local.Weekday() {
+			working = true
+			break
+		}
+	}
+	if !working {
+		return false
+	}
+
+	date := local.Format("2006-01-02")
+	for _, holiday := range c.Holidays {
+		if holiday.Date.Format("2006-01-02") == date {
+			return false
+		}
+	}
+	return true
+}
+
+// NextWorkingTime returns t if it falls within working time, otherwise the
+// start of the next working time: the next shift on a business day, or the
+// start of the next business day for calendars without shifts
+func (c *WorkingCalendar) NextWorkingTime(t time.Time) time.Time {
+	local := t.In(c.Location())
+	today := startOfDay(local)
+
+	// A shift of the previous day may still be running past midnight
+	for i := -1; i <= maxCalendarDays; i++ {
+		day := today.AddDate(0, 0, i)
+		if !c.IsBusinessDay(day) {
+			continue
+		}
+		if len(c.Shifts) == 0 {
+			switch {
+			case i < 0:
+				continue
+			case i == 0:
+				return t
+			}
+			return day
+		}
+
+		var next *time.Time
+		for _, shift := range c.Shifts {
+			start, end, ok := shift.bounds(day)
+			if !ok {
+				continue
+			}
+			if !local.Before(start) && local.Before(end) {
+				return t
+			}
+			if start.After(local) && (next == nil || start.Before(*next)) {
+				next = &start
+			}
+		}
+		if next != nil {
+			return *next
+		}
+	}
+	return t
+}
+
+// AddBusinessDays returns the date the given number of business days after
+// the next working time from t, in the calendar's time zone. Zero days returns
+// the date of the next working time itself.
+func (c *WorkingCalendar) AddBusinessDays(t time.Time, days int) time.Time {
+	day := startOfDay(c.NextWorkingTime(t).In(c.Location()))
+	for i := 0; days > 0 && i <= days+maxCalendarDays; i++ {
+		day = day.AddDate(0, 0, 1)
+		if c.IsBusinessDay(day) {
+			days--
+		}
+	}
+	return day
+}
+
+// BusinessDaysBetween counts the business days after the day of from up to and
+// including the day of to
+func (c *WorkingCalendar) BusinessDaysBetween(from, to time.Time) int {
+	loc := c.Location()
+	day := startOfDay(from.In(loc))
+	last := startOfDay(to.In(loc))
+	count := 0
+	for day.Before(last) {
+		day = day.AddDate(0, 0, 1)
+		if c.IsBusinessDay(day) {
+			count++
+		}
+	}
+	return count
+}
+
+// startOfDay returns midnight of the day of t in its location
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// CalendarHoliday is a non-working date of a calendar
+type CalendarHoliday struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	CalendarID uint      `json:"calendar_id" gorm:"not null;uniqueIndex:idx_calendar_holidays_date"`
+	Date       time.Time `json:"date" gorm:"type:date;not null;uniqueIndex:idx_calendar_holidays_date"`
+	Name       string    `json:"name" gorm:"not null"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// WorkingCalendarRequest represents the request to create or update a working calendar
+type WorkingCalendarRequest struct {
+	Name        string          `json:"name" binding:"required"`
+	StoreID     *string         `json:"store_id"` // omit for the company calendar
+	Timezone    string          `json:"timezone"` // IANA time zone, UTC when empty
+	WorkingDays []int           `json:"working_days" binding:"required,min=1,dive,min=0,max=6"`
+	Shifts      []CalendarShift `json:"shifts" binding:"dive"`
+}
+
+// CalendarHolidayRequest represents the request to add a holiday to a calendar
+type CalendarHolidayRequest struct {
+	Date string `json:"date" binding:"required"` // YYYY-MM-DD
+	Name string `json:"name" binding:"required"`
+}
+
+// CalendarCalculation is the result of business day arithmetic on the calendar
+// that applies to a warehouse
+type CalendarCalculation struct {
+	CalendarID      uint      `json:"calendar_id,omitempty"` // empty for the default calendar
+	CalendarName    string    `json:"calendar_name"`
+	From            time.Time `json:"from"`
+	IsBusinessDay   bool      `json:"is_business_day"`
+	NextWorkingTime time.Time `json:"next_working_time"`
+	BusinessDays    int       `json:"business_days"`
+	Date            time.Time `json:"date"` // business_days after the next working time
+}
@@ -20,10 +20,12 @@ type Client struct {
 	LoyaltyTier   ClientLoyaltyTier `json:"loyalty_tier" gorm:"not null;default:'STANDARD'"`
 	LoyaltyPoints int               `json:"loyalty_points" gorm:"default:0"`
 	Notes         string            `json:"notes" gorm:"type:text"`
+	AnonymizedAt  *time.Time        `json:"anonymized_at,omitempty"` // when its personal data was scrubbed
 	CreatedAt     time.Time         `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt     time.Time         `json:"updated_at" gorm:"autoUpdateTime"`
 	Addresses     []ClientAddress   `json:"addresses,omitempty" gorm:"foreignKey:ClientID"`
 	Orders        []SalesOrder      `json:"orders,omitempty" gorm:"foreignKey:ClientID"`
+	RFM           *ClientRFMScore   `json:"rfm,omitempty" gorm:"foreignKey:ClientID"`
 }
 
 // ClientLoyaltyTier represents the loyalty tier of a client
@@ -71,6 +73,7 @@ type ClientFilter struct {
 	LoyaltyTier *ClientLoyaltyTier `json:"loyalty_tier,omitempty"`
 	City        string             `json:"city,omitempty"`
 	Country     string             `json:"country,omitempty"`
+	RFM         RFMFilter          `json:"rfm,omitempty"`
 }
 
 // ClientOrderHistory represents a client's order history summary
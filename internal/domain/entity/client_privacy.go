@@ -0,0 +1,62 @@
+package entity
+
+import (
+	"fmt"
+	"time"
+)
+
+// ClientPrivacyRequestType names what was done with a client's personal data
+type ClientPrivacyRequestType string
+
+const (
+	ClientPrivacyExport    ClientPrivacyRequestType = "EXPORT"
+	ClientPrivacyAnonymize ClientPrivacyRequestType = "ANONYMIZE"
+)
+
+// ClientPrivacyRequest records an export or anonymization of a client's
+// personal data, who asked for it and why. The records outlive the audit
+// log's retention and hold no personal data themselves.
+type ClientPrivacyRequest struct {
+	ID          uint                     `json:"id" gorm:"primaryKey"`
+	ClientID    uint                     `json:"client_id" gorm:"not null;index"`
+	Type        ClientPrivacyRequestType `json:"type" gorm:"type:varchar(20);not null"`
+	Format      string                   `json:"format,omitempty" gorm:"type:varchar(10)"` // of exports
+	Reason      string                   `json:"reason" gorm:"type:text"`
+	RequestedBy *uint                    `json:"requested_by,omitempty"`
+	CreatedAt   time.Time                `json:"created_at"`
+}
+
+// ClientAnonymizeRequest represents the request to anonymize a client
+type ClientAnonymizeRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// ClientDataExport is the personal data held on a client, with the
+// documents that carry it, including archived ones
+type ClientDataExport struct {
+	Client          *Client                `json:"client"`
+	SalesOrders     []SalesOrder           `json:"sales_orders"`
+	DeliveryOrders  []DeliveryOrder        `json:"delivery_orders"`
+	Invoices        []Invoice              `json:"invoices"`
+	SalesReturns    []SalesReturn          `json:"sales_returns"`
+	FinanceInvoices []FinanceInvoice       `json:"finance_invoices"`
+	FinancePayments []FinancePayment       `json:"finance_payments"`
+	PrivacyRequests []ClientPrivacyRequest `json:"privacy_requests"`
+	ExportedAt      time.Time              `json:"exported_at"`
+}
+
+// ClientAnonymization holds the values that replace a client's personal
+// data when it is anonymized
+type ClientAnonymization struct {
+	Name  string
+	Email string
+}
+
+// NewClientAnonymization returns the placeholders of an anonymized client,
+// which keep its code so its documents still tell clients apart
+func NewClientAnonymization(client *Client) ClientAnonymization {
+	return ClientAnonymization{
+		Name:  "Anonymized client " + client.Code,
+		Email: fmt.Sprintf("anonymized-%d@anonymized.invalid", client.ID),
+	}
+}
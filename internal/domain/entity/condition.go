@@ -0,0 +1,173 @@
+package entity
+
+import (
+	"strconv"
+	"time"
+)
+
+// ConditionMetric is an environmental condition measured in a warehouse
+type ConditionMetric string
+
+const (
+	ConditionTemperature ConditionMetric = "TEMPERATURE" // degrees Celsius
+	ConditionHumidity    ConditionMetric = "HUMIDITY"    // relative humidity, percent
+)
+
+// ConditionSource tells how a reading was taken
+type ConditionSource string
+
+const (
+	ConditionSourceManual ConditionSource = "MANUAL" // entered by a user
+	ConditionSourceDevice ConditionSource = "DEVICE" // pushed by a sensor
+)
+
+// ConditionReading is a measurement of a condition in a store, or in one zone
+// of it
+type ConditionReading struct {
+	ID          uint            `json:"id" gorm:"primaryKey"`
+	StoreID     string          `json:"store_id" gorm:"type:uuid;not null;index"`
+	ZoneCode    string          `json:"zone_code,omitempty"`
+	Metric      ConditionMetric `json:"metric" gorm:"type:varchar(20);not null"`
+	Value       float64         `json:"value" gorm:"not null"`
+	Source      ConditionSource `json:"source" gorm:"type:varchar(10);not null"`
+	DeviceID    string          `json:"device_id,omitempty"`
+	RecordedAt  time.Time       `json:"recorded_at" gorm:"not null;index"`
+	RecordedBy  string          `json:"recorded_by,omitempty"`
+	ExcursionID *uint           `json:"excursion_id,omitempty" gorm:"index"` // excursion the reading is outside the limits of
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+// ConditionReadingRequest is a reading to record. Readings naming a device
+// are recorded as pushed by it; without a time they are taken now.
+type ConditionReadingRequest struct {
+	StoreID    string          `json:"store_id" binding:"required"`
+	ZoneCode   string          `json:"zone_code"`
+	Metric     ConditionMetric `json:"metric" binding:"required,oneof=TEMPERATURE HUMIDITY"`
+	Value      *float64        `json:"value" binding:"required"`
+	DeviceID   string          `json:"device_id"`
+	RecordedAt *time.Time      `json:"recorded_at"`
+}
+
+// ConditionReadingsRequest records readings together, such as a sensor
+// gateway's batch
+type ConditionReadingsRequest struct {
+	Readings []ConditionReadingRequest `json:"readings" binding:"required,min=1,dive"`
+}
+
+// ConditionReadingFilter represents filters for listing condition readings
+type ConditionReadingFilter struct {
+	StoreID  string          `json:"store_id,omitempty"`
+	ZoneCode string          `json:"zone_code,omitempty"`
+	Metric   ConditionMetric `json:"metric,omitempty"`
+	From     *time.Time      `json:"from,omitempty"`
+	To       *time.Time      `json:"to,omitempty"`
+	Page     int             `json:"page,omitempty"`
+	PageSize int             `json:"page_size,omitempty"`
+}
+
+// ConditionThreshold is the range a condition must stay in, in a store or one
+// zone of it. A reading outside the range opens an excursion.
+type ConditionThreshold struct {
+	ID        uint            `json:"id" gorm:"primaryKey"`
+	StoreID   string          `json:"store_id" gorm:"type:uuid;not null;index"`
+	ZoneCode  string          `json:"zone_code,omitempty"` // empty for every zone of the store
+	Metric    ConditionMetric `json:"metric" gorm:"type:varchar(20);not null"`
+	MinValue  *float64        `json:"min_value,omitempty"`
+	MaxValue  *float64        `json:"max_value,omitempty"`
+	HoldStock bool            `json:"hold_stock" gorm:"default:true"` // put the stock exposed to an excursion on quality hold
+	Active    bool            `json:"active" gorm:"default:true"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// Applies reports whether the threshold watches a reading
+func (t *ConditionThreshold) Applies(reading *ConditionReading) bool {
+	return t.Active && t.StoreID == reading.StoreID && t.Metric == reading.Metric &&
+		(t.ZoneCode == "" || t.ZoneCode == reading.ZoneCode)
+}
+
+// Deviation returns how far a value is outside the threshold's range, 0
+// within it
+func (t *ConditionThreshold) Deviation(value float64) float64 {
+	if t.MinValue != nil && value < *t.MinValue {
+		return *t.MinValue - value
+	}
+	if t.MaxValue != nil && value > *t.MaxValue {
+		return value - *t.MaxValue
+	}
+	return 0
+}
+
+// Limits describes the threshold's range, such as "2 to 8"
+func (t *ConditionThreshold) Limits() string {
+	format := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+	switch {
+	case t.MinValue != nil && t.MaxValue != nil:
+		return format(*t.MinValue) + " to " + format(*t.MaxValue)
+	case t.MinValue != nil:
+		return "at least " + format(*t.MinValue)
+	case t.MaxValue != nil:
+		return "at most " + format(*t.MaxValue)
+	}
+	return ""
+}
+
+// ConditionThresholdRequest represents the request to create or update a
+// condition threshold. It needs a minimum, a maximum or both.
+type ConditionThresholdRequest struct {
+	StoreID   string          `json:"store_id" binding:"required"`
+	ZoneCode  string          `json:"zone_code"`
+	Metric    ConditionMetric `json:"metric" binding:"required,oneof=TEMPERATURE HUMIDITY"`
+	MinValue  *float64        `json:"min_value"`
+	MaxValue  *float64        `json:"max_value"`
+	HoldStock *bool           `json:"hold_stock"`
+	Active    *bool           `json:"active"`
+}
+
+// ExcursionStatus represents the status of a condition excursion
+type ExcursionStatus string
+
+const (
+	ExcursionOpen   ExcursionStatus = "OPEN"   // the condition is still outside the limits
+	ExcursionClosed ExcursionStatus = "CLOSED" // a later reading was back within the limits
+)
+
+// ConditionExcursion is a period a condition of a zone spent outside the
+// limits of a threshold, with the lots stored there when it started
+type ConditionExcursion struct {
+	ID          uint                    `json:"id" gorm:"primaryKey"`
+	ThresholdID uint                    `json:"threshold_id" gorm:"not null;index"`
+	StoreID     string                  `json:"store_id" gorm:"type:uuid;not null;index"`
+	ZoneCode    string                  `json:"zone_code,omitempty"`
+	Metric      ConditionMetric         `json:"metric" gorm:"type:varchar(20);not null"`
+	Limits      string                  `json:"limits"`     // range breached, as the threshold had it
+	PeakValue   float64                 `json:"peak_value"` // reading furthest outside the limits
+	Readings    int                     `json:"readings" gorm:"not null;default:1"`
+	Status      ExcursionStatus         `json:"status" gorm:"type:varchar(10);not null;default:'OPEN';index"`
+	StartedAt   time.Time               `json:"started_at" gorm:"not null"`
+	EndedAt     *time.Time              `json:"ended_at,omitempty"`
+	Lots        []ConditionExcursionLot `json:"lots,omitempty" gorm:"foreignKey:ExcursionID"`
+	CreatedAt   time.Time               `json:"created_at"`
+	UpdatedAt   time.Time               `json:"updated_at"`
+}
+
+// ConditionExcursionLot is a lot stored in the zone of an excursion when it
+// started, and the quality inspection holding it
+type ConditionExcursionLot struct {
+	ID           uint    `json:"id" gorm:"primaryKey"`
+	ExcursionID  uint    `json:"excursion_id" gorm:"not null;index"`
+	StockID      string  `json:"stock_id" gorm:"type:uuid;not null"`
+	SKUID        string  `json:"sku_id" gorm:"column:sku_id;type:uuid;not null"`
+	BatchNumber  string  `json:"batch_number,omitempty"`
+	LotNumber    string  `json:"lot_number,omitempty"`
+	ZoneCode     string  `json:"zone_code,omitempty"`
+	Quantity     float64 `json:"quantity" gorm:"not null"` // available quantity exposed
+	InspectionID *uint   `json:"inspection_id,omitempty"`  // quality hold, when the threshold holds stock
+}
+
+// ConditionExcursionFilter represents filters for listing condition excursions
+type ConditionExcursionFilter struct {
+	StoreID string          `json:"store_id,omitempty"`
+	Status  ExcursionStatus `json:"status,omitempty"`
+	Metric  ConditionMetric `json:"metric,omitempty"`
+}
@@ -0,0 +1,83 @@
+package entity
+
+import "time"
+
+// ConsignmentStock is the part of the stock of a SKU in a store a vendor
+// still owns. It is included in the stock's quantity; the stock's consigned
+// quantity is the total of its vendors.
+type ConsignmentStock struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	VendorID  uint      `json:"vendor_id" gorm:"not null;uniqueIndex:idx_consignment_stock"`
+	SKUID     string    `json:"sku_id" gorm:"column:sku_id;type:uuid;not null;uniqueIndex:idx_consignment_stock"`
+	StoreID   string    `json:"store_id" gorm:"type:uuid;not null;uniqueIndex:idx_consignment_stock"`
+	Quantity  float64   `json:"quantity" gorm:"not null;default:0"`
+	UnitCost  float64   `json:"unit_cost" gorm:"type:decimal(15,2);not null;default:0"` // average cost the vendor charges once consumed
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Vendor    *Vendor   `json:"vendor,omitempty" gorm:"foreignKey:VendorID"`
+	SKU       *SKU      `json:"sku,omitempty" gorm:"foreignKey:SKUID"`
+}
+
+// ConsignmentConsumption records consigned stock that became owned stock when
+// it was shipped, consumed or counted short, and the purchase invoice
+// billing it to the vendor once there is one
+type ConsignmentConsumption struct {
+	ID               uint      `json:"id" gorm:"primaryKey"`
+	VendorID         uint      `json:"vendor_id" gorm:"not null;index"`
+	SKUID            string    `json:"sku_id" gorm:"column:sku_id;type:uuid;not null"`
+	StoreID          string    `json:"store_id" gorm:"type:uuid;not null"`
+	Quantity         float64   `json:"quantity" gorm:"not null"`
+	UnitCost         float64   `json:"unit_cost" gorm:"type:decimal(15,2);not null"`
+	Amount           float64   `json:"amount" gorm:"type:decimal(15,2);not null"`
+	Reference        string    `json:"reference"` // stock entry or stock history record that consumed it
+	FinanceInvoiceID *int64    `json:"finance_invoice_id,omitempty" gorm:"index"`
+	CreatedAt        time.Time `json:"created_at"`
+	SKU              *SKU      `json:"sku,omitempty" gorm:"foreignKey:SKUID"`
+}
+
+// ConsignmentReceiptRequest represents goods a vendor placed in a store on
+// consignment, or takes back when returned
+type ConsignmentReceiptRequest struct {
+	VendorID  uint                     `json:"vendor_id" binding:"required"`
+	StoreID   string                   `json:"store_id" binding:"required"`
+	Reference string                   `json:"reference"`
+	Note      string                   `json:"note"`
+	Items     []ConsignmentReceiptLine `json:"items" binding:"required,min=1,dive"`
+}
+
+// ConsignmentReceiptLine is a SKU of a consignment receipt or return. The unit
+// cost is only read on receipts.
+type ConsignmentReceiptLine struct {
+	SKUID    string  `json:"sku_id" binding:"required"`
+	Quantity float64 `json:"quantity" binding:"required,gt=0"`
+	UnitCost float64 `json:"unit_cost" binding:"gte=0"`
+}
+
+// ConsignmentFilter represents filters for consignment balances and
+// consumptions
+type ConsignmentFilter struct {
+	VendorID *uint  `json:"vendor_id,omitempty"`
+	StoreID  string `json:"store_id,omitempty"`
+	SKUID    string `json:"sku_id,omitempty"`
+	Unbilled bool   `json:"unbilled,omitempty"` // only consumptions not billed yet
+}
+
+// ConsignmentBalance is the consignment position with a vendor: the stock it
+// still owns in the stores and the consumed stock not billed yet
+type ConsignmentBalance struct {
+	VendorID       uint               `json:"vendor_id"`
+	VendorCode     string             `json:"vendor_code"`
+	VendorName     string             `json:"vendor_name"`
+	Currency       string             `json:"currency"`
+	Quantity       float64            `json:"quantity"`
+	Value          float64            `json:"value"`
+	UnbilledAmount float64            `json:"unbilled_amount"`
+	Stocks         []ConsignmentStock `json:"stocks"`
+}
+
+// ConsignmentBillingResult summarizes a billing of consumed consignment stock
+type ConsignmentBillingResult struct {
+	Invoices     []FinanceInvoice `json:"invoices"`
+	Consumptions int              `json:"consumptions"`
+	Errors       []string         `json:"errors,omitempty"`
+}
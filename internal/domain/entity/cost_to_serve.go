@@ -0,0 +1,62 @@
+package entity
+
+import "time"
+
+// CostToServeRates are the activity costs, in the base currency, charged to
+// customers in the cost-to-serve report
+type CostToServeRates struct {
+	PickCost    float64 `json:"pick_cost"`    // per delivery picked
+	LineCost    float64 `json:"line_cost"`    // per delivery line picked
+	ReturnCost  float64 `json:"return_cost"`  // per return handled
+	CapitalRate float64 `json:"capital_rate"` // annual percentage charged on amounts paid late
+}
+
+// CustomerCostToServe is the profitability of a customer after the freight,
+// handling, returns and late payments it caused. Amounts are in the base currency.
+type CustomerCostToServe struct {
+	ClientID           uint    `json:"client_id"`
+	ClientName         string  `json:"client_name"`
+	OrderCount         int     `json:"order_count"`
+	Revenue            float64 `json:"revenue"`       // sales net of tax, less returns
+	CostOfGoods        float64 `json:"cost_of_goods"` // units kept by the customer at average purchase price
+	GrossMargin        float64 `json:"gross_margin"`
+	GrossMarginPercent float64 `json:"gross_margin_percent"`
+	DeliveryCount      int     `json:"delivery_count"`
+	LineCount          int     `json:"line_count"`
+	FreightCost        float64 `json:"freight_cost"`
+	HandlingCost       float64 `json:"handling_cost"`
+	ReturnCount        int     `json:"return_count"`
+	ReturnedValue      float64 `json:"returned_value"`
+	ReturnCost         float64 `json:"return_cost"`
+	AverageDaysToPay   float64 `json:"average_days_to_pay"` // from invoice issue to payment, weighted by amount
+	LatePaymentDays    float64 `json:"late_payment_days"`   // past the due date, weighted by amount
+	OverdueAmount      float64 `json:"overdue_amount"`      // still due and past the due date at the end of the period
+	PaymentCost        float64 `json:"payment_cost"`        // capital cost of amounts paid or outstanding after the due date
+	CostToServe        float64 `json:"cost_to_serve"`
+	NetMargin          float64 `json:"net_margin"`
+	NetMarginPercent   float64 `json:"net_margin_percent"`
+}
+
+// CostToServeReport lists customer profitability after the cost to serve them,
+// least profitable first
+type CostToServeReport struct {
+	StartDate   time.Time             `json:"start_date"`
+	EndDate     time.Time             `json:"end_date"`
+	Rates       CostToServeRates      `json:"rates"`
+	Customers   []CustomerCostToServe `json:"customers"`
+	GeneratedAt time.Time             `json:"generated_at"`
+}
+
+// CostToServeFilter represents the period and customer of the cost-to-serve report
+type CostToServeFilter struct {
+	StartDate *time.Time
+	EndDate   *time.Time
+	ClientID  *uint
+}
+
+// CostToServeDelivery is a picked delivery with the customer it was made for
+type CostToServeDelivery struct {
+	ClientID    uint
+	Items       DeliveryOrderItems
+	FreightCost float64
+}
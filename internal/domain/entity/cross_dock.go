@@ -0,0 +1,8 @@
+package entity
+
+// CrossDockResult is a purchase receipt received in cross-dock mode with the
+// pending deliveries its lines shipped
+type CrossDockResult struct {
+	Receipt    *PurchaseReceipt `json:"receipt"`
+	Deliveries []DeliveryOrder  `json:"deliveries"`
+}
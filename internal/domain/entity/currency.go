@@ -0,0 +1,40 @@
+package entity
+
+import "time"
+
+// ExchangeRate is the daily rate converting one unit of a currency into the base currency
+type ExchangeRate struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	FromCurrency string    `json:"from_currency" gorm:"type:varchar(3);not null;uniqueIndex:idx_exchange_rates_pair_date"`
+	ToCurrency   string    `json:"to_currency" gorm:"type:varchar(3);not null;uniqueIndex:idx_exchange_rates_pair_date"`
+	RateDate     time.Time `json:"rate_date" gorm:"type:date;not null;uniqueIndex:idx_exchange_rates_pair_date"`
+	Rate         float64   `json:"rate" gorm:"type:decimal(18,8);not null"`
+	Source       string    `json:"source"`
+	CreatedAt    time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt    time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// ExchangeRateFilter represents filters for listing exchange rates
+type ExchangeRateFilter struct {
+	Currency  string     `json:"currency,omitempty"`
+	StartDate *time.Time `json:"start_date,omitempty"`
+	EndDate   *time.Time `json:"end_date,omitempty"`
+}
+
+// SetExchangeRateRequest represents a request to record the rate of a currency for a day
+type SetExchangeRateRequest struct {
+	Currency string    `json:"currency" binding:"required,len=3"`
+	RateDate time.Time `json:"rate_date" binding:"required"`
+	Rate     float64   `json:"rate" binding:"required,gt=0"`
+	Source   string    `json:"source"`
+}
+
+// CurrencyConversion is the result of converting an amount into the base currency
+type CurrencyConversion struct {
+	Currency     string    `json:"currency"`
+	Amount       float64   `json:"amount"`
+	BaseCurrency string    `json:"base_currency"`
+	BaseAmount   float64   `json:"base_amount"`
+	Rate         float64   `json:"rate"`
+	RateDate     time.Time `json:"rate_date"`
+}
@@ -0,0 +1,183 @@
+package entity
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Tables of the entities custom fields can be defined on, used as the entity
+// type of their definitions
+const (
+	CustomFieldEntitySKU        = "skus"
+	CustomFieldEntitySalesOrder = "sales_orders"
+	CustomFieldEntityVendor     = "vendors"
+)
+
+// CustomFieldEntities lists the entity types custom fields can be defined on
+var CustomFieldEntities = []string{
+	CustomFieldEntitySKU,
+	CustomFieldEntitySalesOrder,
+	CustomFieldEntityVendor,
+}
+
+// CustomFieldType is the type of the values of a custom field
+type CustomFieldType string
+
+const (
+	CustomFieldText    CustomFieldType = "TEXT"
+	CustomFieldNumber  CustomFieldType = "NUMBER"
+	CustomFieldBoolean CustomFieldType = "BOOLEAN"
+	CustomFieldDate    CustomFieldType = "DATE" // YYYY-MM-DD
+	CustomFieldSelect  CustomFieldType = "SELECT"
+)
+
+var customFieldKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,49}$`)
+
+// CustomFieldDefinition defines a user-defined attribute of an entity type.
+// The values of an entity's custom fields are kept by key in its
+// custom_fields JSONB column, so fields are added without schema changes.
+type CustomFieldDefinition struct {
+	ID         uint            `json:"id" gorm:"primaryKey"`
+	EntityType string          `json:"entity_type" gorm:"type:varchar(50);not null;uniqueIndex:idx_custom_field_definitions_entity_key"`
+	Key        string          `json:"key" gorm:"type:varchar(50);not null;uniqueIndex:idx_custom_field_definitions_entity_key"`
+	Label      string          `json:"label" gorm:"type:varchar(100);not null"`
+	Type       CustomFieldType `json:"type" gorm:"type:varchar(20);not null"`
+	Required   bool            `json:"required" gorm:"not null;default:false"`
+	Options    pq.StringArray  `json:"options,omitempty" gorm:"type:text[]"` // values of SELECT fields
+	Min        *float64        `json:"min,omitempty"`                        // smallest NUMBER
+	Max        *float64        `json:"max,omitempty"`                        // largest NUMBER
+	MaxLength  int             `json:"max_length,omitempty" gorm:"not null;default:0"`
+	Pattern    string          `json:"pattern,omitempty" gorm:"type:varchar(255)"` // regular expression TEXT values must match
+	Position   int             `json:"position" gorm:"not null;default:0"`         // order of the field in forms and exports
+	CreatedAt  time.Time       `json:"created_at"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+}
+
+// Validate checks the settings of the definition
+func (d *CustomFieldDefinition) Validate() error {
+	known := false
+	for _, entityType := range CustomFieldEntities {
+		known = known || entityType == d.EntityType
+	}
+	if !known {
+		return NewError(ErrCodeInvalidArgument, fmt.Sprintf("custom fields cannot be defined on %q", d.EntityType))
+	}
+	if !customFieldKeyPattern.MatchString(d.Key) {
+		return NewError(ErrCodeInvalidArgument, "the key must start with a lowercase letter and hold lowercase letters, digits and underscores, up to 50")
+	}
+	switch d.Type {
+	case CustomFieldText, CustomFieldNumber, CustomFieldBoolean, CustomFieldDate:
+	case CustomFieldSelect:
+		if len(d.Options) == 0 {
+			return NewError(ErrCodeInvalidArgument, "select fields need options")
+		}
+	default:
+		return NewError(ErrCodeInvalidArgument, fmt.Sprintf("unknown custom field type %q", d.Type))
+	}
+	if d.Min != nil && d.Max != nil && *d.Min > *d.Max {
+		return NewError(ErrCodeInvalidArgument, "min cannot exceed max")
+	}
+	if d.MaxLength < 0 {
+		return NewError(ErrCodeInvalidArgument, "max length cannot be negative")
+	}
+	if d.Pattern != "" {
+		if _, err := regexp.Compile(d.Pattern); err != nil {
+			return NewError(ErrCodeInvalidArgument, fmt.Sprintf("invalid pattern: %v", err))
+		}
+	}
+	return nil
+}
+
+// Check validates a value of the field, returning it in the form it is kept
+// in: a string, a float64 or a bool. Numbers and booleans may be given as
+// text, as they are in query strings.
+func (d *CustomFieldDefinition) Check(value interface{}) (interface{}, error) {
+	invalid := func(reason string) error {
+		return NewError(ErrCodeInvalidArgument, fmt.Sprintf("custom field %s %s", d.Key, reason))
+	}
+
+	switch d.Type {
+	case CustomFieldNumber:
+		var n float64
+		switch v := value.(type) {
+		case float64:
+			n = v
+		case string:
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				return nil, invalid("must be a number")
+			}
+			n = parsed
+		default:
+			return nil, invalid("must be a number")
+		}
+		if d.Min != nil && n < *d.Min {
+			return nil, invalid(fmt.Sprintf("must be at least %v", *d.Min))
+		}
+		if d.Max != nil && n > *d.Max {
+			return nil, invalid(fmt.Sprintf("must be at most %v", *d.Max))
+		}
+		return n, nil
+
+	case CustomFieldBoolean:
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case string:
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return nil, invalid("must be true or false")
+			}
+			return b, nil
+		}
+		return nil, invalid("must be true or false")
+	}
+
+	s, ok := value.(string)
+	if !ok {
+		return nil, invalid("must be text")
+	}
+	switch d.Type {
+	case CustomFieldDate:
+		if _, err := time.Parse("2006-01-02", s); err != nil {
+			return nil, invalid("must be a date (YYYY-MM-DD)")
+		}
+	case CustomFieldSelect:
+		for _, option := range d.Options {
+			if option == s {
+				return s, nil
+			}
+		}
+		return nil, invalid("must be one of " + strings.Join(d.Options, ", "))
+	default:
+		if d.MaxLength > 0 && len([]rune(s)) > d.MaxLength {
+			return nil, invalid(fmt.Sprintf("must be at most %d characters", d.MaxLength))
+		}
+		if d.Pattern != "" && !regexp.MustCompile(d.Pattern).MatchString(s) {
+			return nil, invalid("does not match the pattern " + d.Pattern)
+		}
+	}
+	return s, nil
+}
+
+// CustomFieldDefinitionRequest represents the request to define a custom
+// field. The entity type, key and type of a field cannot be changed once
+// defined, as stored values would no longer fit.
+type CustomFieldDefinitionRequest struct {
+	EntityType string          `json:"entity_type" binding:"required"`
+	Key        string          `json:"key" binding:"required"`
+	Label      string          `json:"label" binding:"required,max=100"`
+	Type       CustomFieldType `json:"type" binding:"required"`
+	Required   bool            `json:"required"`
+	Options    []string        `json:"options"`
+	Min        *float64        `json:"min"`
+	Max        *float64        `json:"max"`
+	MaxLength  int             `json:"max_length"`
+	Pattern    string          `json:"pattern" binding:"max=255"`
+	Position   int             `json:"position"`
+}
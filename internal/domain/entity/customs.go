@@ -0,0 +1,101 @@
+package entity
+
+import "time"
+
+// CustomsData is the customs classification of goods crossing a border. SKUs
+// hold it as master data and order lines copy it when the order is placed.
+type CustomsData struct {
+	HSCode          string  `json:"hs_code,omitempty" gorm:"type:varchar(10)"`                // commodity code, the 6-digit HS code or a national extension of it
+	CountryOfOrigin string  `json:"country_of_origin,omitempty" gorm:"type:varchar(2)"`       // ISO 3166 alpha-2 code of the country the goods were made in
+	NetWeight       float64 `json:"net_weight,omitempty" gorm:"type:decimal(15,3);default:0"` // kilograms per unit, packaging excluded
+}
+
+// Fill sets the fields of the customs data left blank from the given data
+func (c *CustomsData) Fill(from CustomsData) {
+	if c.HSCode == "" {
+		c.HSCode = from.HSCode
+	}
+	if c.CountryOfOrigin == "" {
+		c.CountryOfOrigin = from.CountryOfOrigin
+	}
+	if c.NetWeight == 0 {
+		c.NetWeight = from.NetWeight
+	}
+}
+
+// CustomsFlow is the direction goods crossed the border in
+type CustomsFlow string
+
+const (
+	CustomsFlowArrival  CustomsFlow = "ARRIVAL"  // received from a vendor abroad
+	CustomsFlowDispatch CustomsFlow = "DISPATCH" // shipped to a customer abroad
+)
+
+// CustomsRegime is the declaration a movement is reported on
+type CustomsRegime string
+
+const (
+	CustomsRegimeIntrastat CustomsRegime = "INTRASTAT" // trade with another member state of the union
+	CustomsRegimeCustoms   CustomsRegime = "CUSTOMS"   // trade with a country outside the union
+)
+
+// CustomsReportLine is the monthly movement of one commodity with one partner
+// country. Values are in the base currency, net of tax.
+type CustomsReportLine struct {
+	Flow            CustomsFlow   `json:"flow"`
+	Regime          CustomsRegime `json:"regime"`
+	CommodityCode   string        `json:"commodity_code"`
+	PartnerCountry  string        `json:"partner_country"`
+	CountryOfOrigin string        `json:"country_of_origin"`
+	Quantity        float64       `json:"quantity"`
+	NetMass         float64       `json:"net_mass"` // kilograms
+	Value           float64       `json:"value"`
+	Movements       int           `json:"movements"` // receipt and delivery lines behind the line
+}
+
+// CustomsIncompleteLine is a receipt or delivery line reported without all
+// the data its declaration needs
+type CustomsIncompleteLine struct {
+	Flow     CustomsFlow `json:"flow"`
+	Document string      `json:"document"` // receipt or delivery number
+	SKUID    string      `json:"sku_id"`
+	Missing  []string    `json:"missing"` // hs_code, country_of_origin, net_weight or partner_country
+}
+
+// CustomsReport aggregates the cross-border arrivals and dispatches of a
+// month by commodity code, for the Intrastat and customs declarations
+type CustomsReport struct {
+	Period           string                  `json:"period"` // YYYY-MM
+	ReportingCountry string                  `json:"reporting_country"`
+	BaseCurrency     string                  `json:"base_currency"`
+	Lines            []CustomsReportLine     `json:"lines"`
+	Incomplete       []CustomsIncompleteLine `json:"incomplete,omitempty"`
+	GeneratedAt      time.Time               `json:"generated_at"`
+}
+
+// CustomsReportFilter selects the month, flow and regime of the customs report
+type CustomsReportFilter struct {
+	Period string
+	Flow   CustomsFlow
+	Regime CustomsRegime
+}
+
+// CustomsArrival is a purchase receipt with the order it was received
+// against and the country of its vendor
+type CustomsArrival struct {
+	ReceiptNumber string
+	ReceiptItems  PurchaseReceiptItems
+	OrderItems    PurchaseOrderItems
+	ExchangeRate  float64
+	Country       string
+}
+
+// CustomsDispatch is a shipped delivery with the order it was shipped for and
+// the country of the customer's shipping address
+type CustomsDispatch struct {
+	DeliveryNumber string
+	DeliveryItems  DeliveryOrderItems
+	OrderItems     SalesOrderItems
+	ExchangeRate   float64
+	Country        string
+}
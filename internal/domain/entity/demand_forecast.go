@@ -0,0 +1,105 @@
+package entity
+
+import "time"
+
+// DemandForecastModel is the method used to forecast SKU demand
+type DemandForecastModel string
+
+const (
+	DemandModelSMA           DemandForecastModel = "SMA"            // simple moving average of the last window months
+	DemandModelEWMA          DemandForecastModel = "EWMA"           // exponentially weighted moving average with smoothing factor alpha
+	DemandModelSeasonalNaive DemandForecastModel = "SEASONAL_NAIVE" // the same month of the previous year
+)
+
+// DemandForecastRequest represents the parameters of a SKU demand forecast
+type DemandForecastRequest struct {
+	Model         DemandForecastModel `json:"model"`          // defaults to SMA
+	Horizon       int                 `json:"horizon"`        // months to forecast, starting with the current month
+	Window        int                 `json:"window"`         // months in the moving average (SMA)
+	Alpha         float64             `json:"alpha"`          // smoothing factor between 0 and 1 (EWMA)
+	HistoryMonths int                 `json:"history_months"` // closed months of sales history used
+	SKUIDs        []string            `json:"sku_ids"`        // limits the forecast to these SKUs
+}
+
+// DemandForecastLine is the forecast quantity of one SKU for one month
+type DemandForecastLine struct {
+	ID        uint    `json:"id,omitempty" gorm:"primaryKey"`
+	VersionID uint    `json:"version_id,omitempty" gorm:"not null;index:idx_demand_forecast_lines_lookup"`
+	SKUID     string  `json:"sku_id" gorm:"not null;index:idx_demand_forecast_lines_lookup"`
+	Period    string  `json:"period" gorm:"type:varchar(7);not null;index:idx_demand_forecast_lines_lookup"`
+	Quantity  float64 `json:"quantity" gorm:"type:decimal(15,3);not null"`
+}
+
+// DemandForecastResult is a monthly demand forecast per SKU
+type DemandForecastResult struct {
+	Model       DemandForecastModel  `json:"model"`
+	Window      int                  `json:"window,omitempty"`
+	Alpha       float64              `json:"alpha,omitempty"`
+	Periods     []string             `json:"periods"`
+	Lines       []DemandForecastLine `json:"lines"`
+	Totals      map[string]float64   `json:"totals"` // forecast quantity per period across all SKUs
+	GeneratedAt time.Time            `json:"generated_at"`
+}
+
+// DemandForecastVersion is a stored demand forecast. The active version feeds
+// replenishment suggestions and MRP.
+type DemandForecastVersion struct {
+	ID            uint                 `json:"id" gorm:"primaryKey"`
+	Model         DemandForecastModel  `json:"model" gorm:"type:varchar(20);not null"`
+	Window        int                  `json:"window" gorm:"column:window_months"`
+	Alpha         float64              `json:"alpha" gorm:"type:decimal(4,3)"`
+	HistoryMonths int                  `json:"history_months"`
+	FirstPeriod   string               `json:"first_period" gorm:"type:varchar(7);not null"`
+	LastPeriod    string               `json:"last_period" gorm:"type:varchar(7);not null"`
+	Notes         string               `json:"notes" gorm:"type:text"`
+	Active        bool                 `json:"active" gorm:"default:false;index"`
+	CreatedBy     *uint                `json:"created_by,omitempty"`
+	CreatedAt     time.Time            `json:"created_at"`
+	Lines         []DemandForecastLine `json:"lines,omitempty" gorm:"foreignKey:VersionID"`
+}
+
+// DemandForecastVersionRequest represents the request to store a demand forecast version
+type DemandForecastVersionRequest struct {
+	DemandForecastRequest
+	Notes    string `json:"notes"`
+	Activate bool   `json:"activate"` // make the new version the one replenishment and MRP use
+}
+
+// ReplenishmentLine suggests how much of a SKU to order so that stock covers
+// forecast demand until a new delivery arrives and over the review period
+type ReplenishmentLine struct {
+	SKUID             string   `json:"sku_id"`
+	SKUCode           string   `json:"sku_code"`
+	Name              string   `json:"name"`
+	ABCClass          ABCClass `json:"abc_class,omitempty"`
+	XYZClass          XYZClass `json:"xyz_class,omitempty"`
+	VendorID          *uint    `json:"vendor_id,omitempty"`
+	LeadTimeDays      int      `json:"lead_time_days"`
+	CoverDays         int      `json:"cover_days"`         // lead time plus review period
+	DailyDemand       float64  `json:"daily_demand"`       // forecast quantity per day
+	ForecastDemand    float64  `json:"forecast_demand"`    // forecast quantity over the cover days
+	SafetyStock       float64  `json:"safety_stock"`       // buffer held for the variability of demand
+	AvailableQuantity float64  `json:"available_quantity"` // on hand less quarantine
+	SuggestedQuantity float64  `json:"suggested_quantity"`
+}
+
+// ReplenishmentPlan lists the SKUs whose available stock does not cover their
+// forecast demand, from the active demand forecast version
+type ReplenishmentPlan struct {
+	VersionID   uint                `json:"version_id"`
+	StoreID     string              `json:"store_id,omitempty"`
+	ReviewDays  int                 `json:"review_days,omitempty"` // requested review period; without one each SKU is reviewed by its ABC class
+	Lines       []ReplenishmentLine `json:"lines"`
+	GeneratedAt time.Time           `json:"generated_at"`
+}
+
+// SKUSupply holds the SKU details replenishment needs
+type SKUSupply struct {
+	SKUID        string
+	SKUCode      string
+	Name         string
+	ABCClass     ABCClass
+	XYZClass     XYZClass
+	VendorID     *uint
+	LeadTimeDays int
+}
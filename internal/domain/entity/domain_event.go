@@ -0,0 +1,206 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// DomainEvent is a change a use case made that the rest of the system may react
+// to. Use cases publish events to the event bus once the change is persisted;
+// consumers such as the event log, notifications, extension hooks and
+// WebSocket events subscribe to the events they handle.
+type DomainEvent interface {
+	// EventName identifies the kind of event
+	EventName() string
+}
+
+// Domain event names
+const (
+	EventOrderConfirmed     = "order.confirmed"
+	EventOrderStatusChanged = "order.status_changed"
+	EventDeliveryShipped    = "delivery.shipped"
+	EventInvoiceIssued      = "invoice.issued"
+	EventPurchaseOrderSent  = "purchase_order.sent"
+	EventReceiptPosted      = "receipt.posted"
+	EventStockEntryCreated  = "stock.entry_created"
+	EventStockBelowReorder  = "stock.below_reorder"
+	EventPaymentConfirmed   = "payment.confirmed"
+	EventApprovalRequested  = "approval.requested"
+	EventElevationReviewed  = "access.elevation_reviewed"
+	EventVendorRiskAlerted  = "vendor.risk_alerted"
+	EventDunningReminder    = "dunning.reminder_sent"
+	EventConditionExcursion = "condition.excursion_opened"
+	EventFulfillmentBreach  = "fulfillment.sla_breached"
+	EventImpersonation      = "access.impersonation_started"
+	EventImpersonationEnded = "access.impersonation_ended"
+)
+
+// DomainEvents lists the domain event names
+var DomainEvents = []string{
+	EventOrderConfirmed,
+	EventOrderStatusChanged,
+	EventDeliveryShipped,
+	EventInvoiceIssued,
+	EventPurchaseOrderSent,
+	EventReceiptPosted,
+	EventStockEntryCreated,
+	EventStockBelowReorder,
+	EventPaymentConfirmed,
+	EventApprovalRequested,
+	EventElevationReviewed,
+	EventVendorRiskAlerted,
+	EventDunningReminder,
+	EventConditionExcursion,
+	EventFulfillmentBreach,
+	EventImpersonation,
+	EventImpersonationEnded,
+}
+
+// OrderConfirmed is published when a draft sales order is confirmed
+type OrderConfirmed struct {
+	Order *SalesOrder `json:"order"`
+}
+
+func (OrderConfirmed) EventName() string { return EventOrderConfirmed }
+
+// OrderStatusChanged is published when a sales order moves to a new status
+type OrderStatusChanged struct {
+	Order          *SalesOrder      `json:"order"`
+	PreviousStatus SalesOrderStatus `json:"previous_status"`
+}
+
+func (OrderStatusChanged) EventName() string { return EventOrderStatusChanged }
+
+// DeliveryShipped is published when a delivery order is shipped and its items
+// are taken out of stock
+type DeliveryShipped struct {
+	Delivery *DeliveryOrder `json:"delivery"`
+}
+
+func (DeliveryShipped) EventName() string { return EventDeliveryShipped }
+
+// InvoiceIssued is published when a draft sales invoice is issued
+type InvoiceIssued struct {
+	Invoice *Invoice `json:"invoice"`
+}
+
+func (InvoiceIssued) EventName() string { return EventInvoiceIssued }
+
+// PurchaseOrderSent is published when an approved purchase order is sent to
+// its vendor
+type PurchaseOrderSent struct {
+	Order *PurchaseOrder `json:"order"`
+}
+
+func (PurchaseOrderSent) EventName() string { return EventPurchaseOrderSent }
+
+// ReceiptPosted is published when a purchase receipt is posted to stock
+type ReceiptPosted struct {
+	Receipt *PurchaseReceipt `json:"receipt"`
+}
+
+func (ReceiptPosted) EventName() string { return EventReceiptPosted }
+
+// StockEntryCreated is published for the stock entries created together
+type StockEntryCreated struct {
+	Entries []StockEntry `json:"entries"`
+}
+
+func (StockEntryCreated) EventName() string { return EventStockEntryCreated }
+
+// EventName makes a stock falling below its reorder point a domain event
+func (StockBelowReorderEvent) EventName() string { return EventStockBelowReorder }
+
+// PaymentConfirmed is published when a pending finance payment is confirmed
+type PaymentConfirmed struct {
+	Payment *FinancePayment `json:"payment"`
+}
+
+func (PaymentConfirmed) EventName() string { return EventPaymentConfirmed }
+
+// ApprovalRequested is published when a document is submitted for approval
+type ApprovalRequested struct {
+	ApprovalPendingEvent
+	Approver Permission  `json:"approver"` // permission needed to approve the document
+	Record   interface{} `json:"record"`   // *PurchaseRequest, *PurchaseOrder, *ElevatedAccessGrant or *ExpenseClaim
+}
+
+func (ApprovalRequested) EventName() string { return EventApprovalRequested }
+
+// ElevationReviewed is published when an elevated access grant is approved,
+// rejected or revoked
+type ElevationReviewed struct {
+	Grant *ElevatedAccessGrant `json:"grant"`
+}
+
+func (ElevationReviewed) EventName() string { return EventElevationReviewed }
+
+// VendorRiskAlerted is published for each new vendor risk alert
+type VendorRiskAlerted struct {
+	Alert *VendorRiskAlert `json:"alert"`
+}
+
+func (VendorRiskAlerted) EventName() string { return EventVendorRiskAlerted }
+
+// DunningReminderSent is published for each dunning reminder sent
+type DunningReminderSent struct {
+	Reminder *DunningReminder `json:"reminder"`
+}
+
+func (DunningReminderSent) EventName() string { return EventDunningReminder }
+
+// ConditionExcursionOpened is published for each condition excursion opened by
+// a reading outside a threshold, once its exposed lots are recorded
+type ConditionExcursionOpened struct {
+	Excursion *ConditionExcursion `json:"excursion"`
+	Value     float64             `json:"value"` // the reading that opened it
+}
+
+func (ConditionExcursionOpened) EventName() string { return EventConditionExcursion }
+
+// FulfillmentSLABreached is published for each new fulfillment SLA alert
+type FulfillmentSLABreached struct {
+	Alert *FulfillmentSLAAlert `json:"alert"`
+}
+
+func (FulfillmentSLABreached) EventName() string { return EventFulfillmentBreach }
+
+// ImpersonationStarted is published when an admin starts impersonating a user
+type ImpersonationStarted struct {
+	Session *ImpersonationSession `json:"session"`
+}
+
+func (ImpersonationStarted) EventName() string { return EventImpersonation }
+
+// ImpersonationEnded is published when an impersonation session is ended
+// before it expires
+type ImpersonationEnded struct {
+	Session *ImpersonationSession `json:"session"`
+}
+
+func (ImpersonationEnded) EventName() string { return EventImpersonationEnded }
+
+// BrokerEvent is the message a domain event is published to the message
+// broker as, on the topic named after the event
+type BrokerEvent struct {
+	Name       string          `json:"name"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Payload    json.RawMessage `json:"payload"`
+}
+
+// EventLogEntry is a published domain event kept in the event log
+type EventLogEntry struct {
+	ID         uint64          `json:"id" gorm:"primaryKey"`
+	Name       string          `json:"name" gorm:"type:varchar(50);not null"`
+	Payload    json.RawMessage `json:"payload" gorm:"type:jsonb"`
+	OccurredAt time.Time       `json:"occurred_at" gorm:"not null"`
+}
+
+// EventLogFilter represents filters for listing the event log
+type EventLogFilter struct {
+	Name      string     `json:"name,omitempty"`
+	StartDate *time.Time `json:"start_date,omitempty"`
+	EndDate   *time.Time `json:"end_date,omitempty"`
+	Page      int        `json:"page,omitempty"`
+	PageSize  int        `json:"page_size,omitempty"`
+}
@@ -0,0 +1,9 @@
+package entity
+
+// DropShipShipmentRequest represents a vendor's confirmation that it shipped
+// a drop-ship order to the customer
+type DropShipShipmentRequest struct {
+	TrackingNumber string `json:"tracking_number"`
+	ShippingMethod string `json:"shipping_method"`
+	Notes          string `json:"notes"`
+}
@@ -0,0 +1,82 @@
+package entity
+
+import "time"
+
+// DunningEscalation is the action a dunning level asks for beyond the reminder itself
+type DunningEscalation string
+
+const (
+	DunningEscalationNone           DunningEscalation = "NONE"
+	DunningEscalationAccountManager DunningEscalation = "ACCOUNT_MANAGER" // involve the customer's account manager
+	DunningEscalationCreditHold     DunningEscalation = "CREDIT_HOLD"     // stop new orders until settled
+	DunningEscalationCollections    DunningEscalation = "COLLECTIONS"     // hand over to collections
+)
+
+// DunningLevel is a stage of the reminder process for overdue sales invoices,
+// reached once an invoice is the given number of days past due. The template may
+// use the placeholders {{invoice_number}}, {{entity_name}}, {{amount_due}},
+// {{currency}}, {{due_date}} and {{days_overdue}}.
+type DunningLevel struct {
+	ID          uint              `json:"id" gorm:"primaryKey"`
+	Name        string            `json:"name" gorm:"not null"`
+	DaysOverdue int               `json:"days_overdue" gorm:"not null;uniqueIndex"`
+	Template    string            `json:"template" gorm:"type:text"`
+	Escalation  DunningEscalation `json:"escalation" gorm:"type:varchar(20);not null;default:'NONE'"`
+	Active      bool              `json:"active" gorm:"not null;default:true"`
+	CreatedAt   time.Time         `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt   time.Time         `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// DunningReminder records a reminder sent for an overdue invoice at a dunning level
+type DunningReminder struct {
+	ID            uint              `json:"id" gorm:"primaryKey"`
+	InvoiceID     int64             `json:"invoice_id" gorm:"not null;uniqueIndex:idx_dunning_reminders_invoice_level"`
+	InvoiceNumber string            `json:"invoice_number"`
+	EntityID      int64             `json:"entity_id" gorm:"index"`
+	EntityName    string            `json:"entity_name"`
+	LevelID       uint              `json:"level_id" gorm:"not null;uniqueIndex:idx_dunning_reminders_invoice_level"`
+	LevelName     string            `json:"level_name"`
+	DaysOverdue   int               `json:"days_overdue"`
+	AmountDue     float64           `json:"amount_due" gorm:"type:decimal(15,2)"`
+	CurrencyCode  string            `json:"currency_code"`
+	Escalation    DunningEscalation `json:"escalation" gorm:"type:varchar(20)"`
+	Message       string            `json:"message" gorm:"type:text"`
+	SentAt        time.Time         `json:"sent_at" gorm:"not null"`
+	SentBy        *uint             `json:"sent_by,omitempty"` // unset for scheduler runs
+}
+
+// CreateDunningLevelRequest represents the request to create a dunning level
+type CreateDunningLevelRequest struct {
+	Name        string            `json:"name" binding:"required"`
+	DaysOverdue int               `json:"days_overdue" binding:"min=1"`
+	Template    string            `json:"template"`
+	Escalation  DunningEscalation `json:"escalation" binding:"omitempty,oneof=NONE ACCOUNT_MANAGER CREDIT_HOLD COLLECTIONS"`
+}
+
+// UpdateDunningLevelRequest represents the request to update a dunning level
+type UpdateDunningLevelRequest struct {
+	Name        string            `json:"name"`
+	DaysOverdue *int              `json:"days_overdue" binding:"omitempty,min=1"`
+	Template    *string           `json:"template"`
+	Escalation  DunningEscalation `json:"escalation" binding:"omitempty,oneof=NONE ACCOUNT_MANAGER CREDIT_HOLD COLLECTIONS"`
+	Active      *bool             `json:"active"`
+}
+
+// DunningReminderFilter represents filters for the reminder history
+type DunningReminderFilter struct {
+	InvoiceID int64      `json:"invoice_id,omitempty"`
+	EntityID  int64      `json:"entity_id,omitempty"`
+	LevelID   uint       `json:"level_id,omitempty"`
+	StartDate *time.Time `json:"start_date,omitempty"`
+	EndDate   *time.Time `json:"end_date,omitempty"`
+	Page      int        `json:"page,omitempty"`
+	PageSize  int        `json:"page_size,omitempty"`
+}
+
+// DunningRunResult summarizes a dunning run
+type DunningRunResult struct {
+	AsOf      time.Time         `json:"as_of"`
+	Overdue   int               `json:"overdue"` // sales invoices past due with an amount outstanding
+	Flagged   int               `json:"flagged"` // invoices newly marked OVERDUE
+	Reminders []DunningReminder `json:"reminders"`
+}
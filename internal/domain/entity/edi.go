@@ -0,0 +1,148 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// TradingPartner configures the EDI exchange with a vendor: the IDs both
+// sides are known by in the X12 envelopes, and whether purchase orders are
+// sent to the vendor as 850 documents
+type TradingPartner struct {
+	ID                 uint      `json:"id" gorm:"primaryKey"`
+	VendorID           uint      `json:"vendor_id" gorm:"uniqueIndex;not null"`
+	ISAQualifier       string    `json:"isa_qualifier" gorm:"type:varchar(2);not null"` // qualifier of the partner's interchange ID, such as ZZ or 01
+	ISAID              string    `json:"isa_id" gorm:"type:varchar(15);not null;index"` // partner's interchange ID, matched against the sender of inbound documents
+	GSID               string    `json:"gs_id" gorm:"type:varchar(15);not null"`        // partner's application code in functional groups
+	OurISAQualifier    string    `json:"our_isa_qualifier" gorm:"type:varchar(2);not null"`
+	OurISAID           string    `json:"our_isa_id" gorm:"type:varchar(15);not null"`
+	OurGSID            string    `json:"our_gs_id" gorm:"type:varchar(15);not null"`
+	Test               bool      `json:"test"`                                     // interchanges are flagged as test data
+	SendPurchaseOrders bool      `json:"send_purchase_orders" gorm:"default:true"` // generate the 850 of purchase orders as they are sent
+	LastControlNumber  int64     `json:"last_control_number"`                      // control number of the last interchange generated for the partner
+	Active             bool      `json:"active" gorm:"default:true"`
+	Vendor             *Vendor   `json:"vendor,omitempty" gorm:"foreignKey:VendorID"`
+	CreatedAt          time.Time `json:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at"`
+}
+
+// TradingPartnerRequest represents the request to create or update a trading
+// partner
+type TradingPartnerRequest struct {
+	VendorID           uint   `json:"vendor_id" binding:"required"`
+	ISAQualifier       string `json:"isa_qualifier" binding:"required,len=2"`
+	ISAID              string `json:"isa_id" binding:"required,max=15"`
+	GSID               string `json:"gs_id" binding:"required,max=15"`
+	OurISAQualifier    string `json:"our_isa_qualifier" binding:"required,len=2"`
+	OurISAID           string `json:"our_isa_id" binding:"required,max=15"`
+	OurGSID            string `json:"our_gs_id" binding:"required,max=15"`
+	Test               bool   `json:"test"`
+	SendPurchaseOrders *bool  `json:"send_purchase_orders"`
+	Active             *bool  `json:"active"`
+}
+
+// EDI transaction sets exchanged with trading partners
+const (
+	EDITypePurchaseOrder = "850" // purchase order, sent to the vendor
+	EDITypeShipNotice    = "856" // advance ship notice, received from the vendor
+	EDITypeInvoice       = "810" // invoice, received from the vendor
+)
+
+// EDIDirection tells whether an EDI document was sent or received
+type EDIDirection string
+
+const (
+	EDIOutbound EDIDirection = "OUT"
+	EDIInbound  EDIDirection = "IN"
+)
+
+// EDIDocumentStatus represents where an EDI document stands
+type EDIDocumentStatus string
+
+const (
+	EDIDocumentGenerated EDIDocumentStatus = "GENERATED" // outbound, waiting to be delivered to the partner
+	EDIDocumentDelivered EDIDocumentStatus = "DELIVERED" // outbound, delivered to the partner
+	EDIDocumentApplied   EDIDocumentStatus = "APPLIED"   // inbound, mapped into the purchase entities
+	EDIDocumentFailed    EDIDocumentStatus = "FAILED"    // inbound, could not be mapped
+	EDIDocumentDuplicate EDIDocumentStatus = "DUPLICATE" // inbound, a document of the same reference was already applied
+)
+
+// EDIDocument archives a transaction set exchanged with a trading partner,
+// with the X12 interchange it travelled in and what it was mapped to
+type EDIDocument struct {
+	ID               uint              `json:"id" gorm:"primaryKey"`
+	PartnerID        uint              `json:"partner_id" gorm:"not null;index"`
+	Direction        EDIDirection      `json:"direction" gorm:"type:varchar(3);not null"`
+	Type             string            `json:"type" gorm:"type:varchar(3);not null"` // transaction set, such as 850
+	ControlNumber    string            `json:"control_number" gorm:"type:varchar(9)"`
+	Reference        string            `json:"reference" gorm:"index"` // PO, shipment or invoice number of the document
+	PurchaseOrderID  *string           `json:"purchase_order_id,omitempty" gorm:"type:uuid;index"`
+	ReceiptID        *string           `json:"receipt_id,omitempty" gorm:"type:uuid"` // receipt posted from a ship notice
+	FinanceInvoiceID *int64            `json:"finance_invoice_id,omitempty"`          // purchase invoice raised from an invoice
+	Content          string            `json:"-" gorm:"type:text;not null"`           // X12 interchange
+	Data             json.RawMessage   `json:"data,omitempty" gorm:"type:jsonb"`      // transaction set translated to an EDIShipNotice or EDIInvoice
+	Status           EDIDocumentStatus `json:"status" gorm:"type:varchar(20);not null"`
+	Error            string            `json:"error,omitempty" gorm:"type:text"`
+	Partner          *TradingPartner   `json:"partner,omitempty" gorm:"foreignKey:PartnerID"`
+	CreatedAt        time.Time         `json:"created_at"`
+	UpdatedAt        time.Time         `json:"updated_at"`
+}
+
+// EDIDocumentFilter represents the filter for listing EDI documents
+type EDIDocumentFilter struct {
+	PartnerID       *uint              `form:"partner_id"`
+	Direction       *EDIDirection      `form:"direction"`
+	Type            string             `form:"type"`
+	Status          *EDIDocumentStatus `form:"status"`
+	PurchaseOrderID string             `form:"purchase_order_id"`
+}
+
+// EDIInboundResult lists the documents an inbound interchange was split into
+type EDIInboundResult struct {
+	Documents []EDIDocument `json:"documents"`
+}
+
+// EDIShipNotice is an 856 advance ship notice translated from X12
+type EDIShipNotice struct {
+	ShipmentID          string              `json:"shipment_id"`
+	ShipDate            *time.Time          `json:"ship_date,omitempty"`
+	DeliveryDate        *time.Time          `json:"delivery_date,omitempty"` // date the shipment is expected at the dock
+	Carrier             string              `json:"carrier,omitempty"`
+	TrackingNumber      string              `json:"tracking_number,omitempty"`
+	PurchaseOrderNumber string              `json:"purchase_order_number"`
+	Lines               []EDIShipNoticeLine `json:"lines"`
+}
+
+// EDIShipNoticeLine is an item shipped in an advance ship notice
+type EDIShipNoticeLine struct {
+	SKUCode  string  `json:"sku_code"`
+	Quantity float64 `json:"quantity"`
+	Unit     string  `json:"unit,omitempty"`
+}
+
+// EDIReceiveRequest receives the goods of an advance ship notice into a store
+type EDIReceiveRequest struct {
+	StoreID string `json:"store_id" binding:"required"`
+	Notes   string `json:"notes"`
+}
+
+// EDIInvoice is an 810 invoice translated from X12
+type EDIInvoice struct {
+	InvoiceNumber       string           `json:"invoice_number"`
+	InvoiceDate         time.Time        `json:"invoice_date"`
+	PurchaseOrderNumber string           `json:"purchase_order_number"`
+	CurrencyCode        string           `json:"currency_code,omitempty"`
+	NetDays             int              `json:"net_days,omitempty"` // days the invoice is due in after its date
+	Lines               []EDIInvoiceLine `json:"lines"`
+	TaxAmount           float64          `json:"tax_amount,omitempty"`
+	Allowance           float64          `json:"allowance,omitempty"` // amount taken off the invoice
+	Total               float64          `json:"total"`               // amount the vendor states is due
+}
+
+// EDIInvoiceLine is a line of an invoice
+type EDIInvoiceLine struct {
+	SKUCode     string  `json:"sku_code"`
+	Description string  `json:"description,omitempty"`
+	Quantity    float64 `json:"quantity"`
+	UnitPrice   float64 `json:"unit_price"`
+}
@@ -0,0 +1,93 @@
+package entity
+
+import "time"
+
+// ElevationStatus represents the state of a temporary elevated access grant
+type ElevationStatus string
+
+const (
+	ElevationPending  ElevationStatus = "PENDING"
+	ElevationApproved ElevationStatus = "APPROVED" // active until it expires or is revoked
+	ElevationRejected ElevationStatus = "REJECTED"
+	ElevationRevoked  ElevationStatus = "REVOKED"
+	ElevationExpired  ElevationStatus = "EXPIRED"
+)
+
+// ElevatedAccessGrant is a request for temporary ("break-glass") permissions on
+// top of a user's role, such as month-end finance access. Once approved it is
+// active for DurationHours and then expires on its own.
+type ElevatedAccessGrant struct {
+	ID            uint                `json:"id" gorm:"primaryKey"`
+	UserID        uint                `json:"user_id" gorm:"not null;index"`
+	User          *User               `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	Permissions   GormPermissionSlice `json:"permissions" gorm:"type:text[];not null"`
+	Reason        string              `json:"reason" gorm:"type:text;not null"`
+	DurationHours int                 `json:"duration_hours" gorm:"not null"`
+	Status        ElevationStatus     `json:"status" gorm:"type:varchar(20);not null;index"`
+	RequestedAt   time.Time           `json:"requested_at" gorm:"not null"`
+	ReviewedBy    *uint               `json:"reviewed_by,omitempty"`
+	ReviewedAt    *time.Time          `json:"reviewed_at,omitempty"`
+	ReviewNote    string              `json:"review_note,omitempty" gorm:"type:text"`
+	ExpiresAt     *time.Time          `json:"expires_at,omitempty" gorm:"index"`
+	RevokedBy     *uint               `json:"revoked_by,omitempty"`
+	RevokedAt     *time.Time          `json:"revoked_at,omitempty"`
+	CreatedAt     time.Time           `json:"created_at"`
+	UpdatedAt     time.Time           `json:"updated_at"`
+}
+
+// ActiveAt reports whether the grant gives its permissions at the given time
+func (g *ElevatedAccessGrant) ActiveAt(at time.Time) bool {
+	return g.Status == ElevationApproved && g.ExpiresAt != nil && g.ExpiresAt.After(at)
+}
+
+// ElevationRequest represents the request for temporary elevated access
+type ElevationRequest struct {
+	Permissions   []Permission `json:"permissions" binding:"required,min=1"`
+	Reason        string       `json:"reason" binding:"required"`
+	DurationHours int          `json:"duration_hours" binding:"required,gt=0"`
+}
+
+// ElevationReviewRequest represents an approver's decision note
+type ElevationReviewRequest struct {
+	Note string `json:"note"`
+}
+
+// ElevatedAction records a request a user could only make through an elevated
+// access grant
+type ElevatedAction struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	GrantID    uint       `json:"grant_id" gorm:"not null;index"`
+	UserID     uint       `json:"user_id" gorm:"not null;index"`
+	Permission Permission `json:"permission" gorm:"type:varchar(100);not null"`
+	Method     string     `json:"method" gorm:"type:varchar(10);not null"`
+	Path       string     `json:"path" gorm:"type:text;not null"`
+	StatusCode int        `json:"status_code"`
+	IP         string     `json:"ip" gorm:"type:varchar(45)"`
+	CreatedAt  time.Time  `json:"created_at" gorm:"index"`
+}
+
+// ElevationFilter represents filters for listing elevated access grants
+type ElevationFilter struct {
+	UserID    uint            `json:"user_id,omitempty"`
+	Status    ElevationStatus `json:"status,omitempty"`
+	StartDate *time.Time      `json:"start_date,omitempty"` // requested on or after
+	EndDate   *time.Time      `json:"end_date,omitempty"`   // requested on or before
+	Page      int             `json:"page,omitempty"`
+	PageSize  int             `json:"page_size,omitempty"`
+}
+
+// ElevationReviewEntry is an approved grant with the elevated actions taken under it
+type ElevationReviewEntry struct {
+	Grant   ElevatedAccessGrant `json:"grant"`
+	Actions []ElevatedAction    `json:"actions"`
+}
+
+// ElevationReviewReport lists the grants that were active in a period and every
+// elevated action taken under them, for periodic access review
+type ElevationReviewReport struct {
+	StartDate    time.Time              `json:"start_date"`
+	EndDate      time.Time              `json:"end_date"`
+	TotalGrants  int                    `json:"total_grants"`
+	TotalActions int                    `json:"total_actions"`
+	Entries      []ElevationReviewEntry `json:"entries"`
+}
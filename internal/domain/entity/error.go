@@ -0,0 +1,56 @@
+package entity
+
+// ErrorCode is the machine-readable kind of an error, which clients can act
+// on without parsing its message. The API maps each code to an HTTP status.
+type ErrorCode string
+
+const (
+	ErrCodeInvalidArgument      ErrorCode = "INVALID_ARGUMENT"    // malformed or invalid input
+	ErrCodeUnauthenticated      ErrorCode = "UNAUTHENTICATED"     // missing or invalid credentials
+	ErrCodePermissionDenied     ErrorCode = "PERMISSION_DENIED"   // the caller may not do this
+	ErrCodeNotFound             ErrorCode = "NOT_FOUND"           // the record does not exist
+	ErrCodeConflict             ErrorCode = "CONFLICT"            // clashes with an existing record or a request in progress
+	ErrCodeFailedPrecondition   ErrorCode = "FAILED_PRECONDITION" // valid input the current state of the records does not allow
+	ErrCodeGone                 ErrorCode = "GONE"                // existed but has expired
+	ErrCodePayloadTooLarge      ErrorCode = "PAYLOAD_TOO_LARGE"
+	ErrCodeUnsupportedMediaType ErrorCode = "UNSUPPORTED_MEDIA_TYPE"
+	ErrCodeResourceExhausted    ErrorCode = "RESOURCE_EXHAUSTED"
+	ErrCodeTimeout              ErrorCode = "TIMEOUT"     // the request took too long
+	ErrCodeUnavailable          ErrorCode = "UNAVAILABLE" // a dependency is missing or overloaded, retry later
+	ErrCodeInternal             ErrorCode = "INTERNAL"    // a bug or an unexpected failure
+)
+
+// FieldError tells which field of the input is invalid and why
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error is an error with a code, for the API to answer with the right status
+// and clients to tell errors apart. Sentinel errors of the use cases are
+// Errors, so wrapping them with fmt.Errorf and %w keeps their code.
+type Error struct {
+	Code    ErrorCode
+	Message string
+	Fields  []FieldError
+	Err     error // the cause, if any
+}
+
+// NewError creates an error with a code and a message
+func NewError(code ErrorCode, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// WrapError gives err a code, keeping its message and letting errors.Is and
+// errors.As reach it
+func WrapError(code ErrorCode, err error) *Error {
+	return &Error{Code: code, Message: err.Error(), Err: err}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
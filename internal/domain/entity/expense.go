@@ -0,0 +1,126 @@
+package entity
+
+import "time"
+
+// ExpenseClaimStatus represents the status of an expense claim
+type ExpenseClaimStatus string
+
+const (
+	ExpenseClaimDraft      ExpenseClaimStatus = "DRAFT"
+	ExpenseClaimSubmitted  ExpenseClaimStatus = "SUBMITTED"
+	ExpenseClaimApproved   ExpenseClaimStatus = "APPROVED" // owed to the employee by a purchase invoice
+	ExpenseClaimRejected   ExpenseClaimStatus = "REJECTED"
+	ExpenseClaimReimbursed ExpenseClaimStatus = "REIMBURSED" // paid by a finance payment
+)
+
+// ExpenseEntityType is the entity type of the finance invoices and payments
+// reimbursing expense claims. Their entity ID is the employee's user ID.
+const ExpenseEntityType = "EMPLOYEE"
+
+// ExpenseCategory classifies the lines of expense claims, e.g. travel or meals
+type ExpenseCategory struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	Code        string    `json:"code" gorm:"type:varchar(30);not null;uniqueIndex"`
+	Name        string    `json:"name" gorm:"not null"`
+	Description string    `json:"description,omitempty"`
+	Active      bool      `json:"active" gorm:"not null;default:true"` // inactive categories are kept for past claims only
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// ExpenseCategoryRequest represents the request to create or update an expense category
+type ExpenseCategoryRequest struct {
+	Code        string `json:"code" binding:"required,max=30"`
+	Name        string `json:"name" binding:"required,max=255"`
+	Description string `json:"description"`
+	Active      *bool  `json:"active"` // true by default
+}
+
+// ExpenseClaim is an employee's claim for expenses paid out of pocket. Once
+// approved, the company owes the employee its base total through a purchase
+// invoice, which a finance payment settles on reimbursement.
+type ExpenseClaim struct {
+	ID               uint               `json:"id" gorm:"primaryKey"`
+	ClaimNumber      string             `json:"claim_number" gorm:"type:varchar(50);not null;uniqueIndex"`
+	EmployeeID       uint               `json:"employee_id" gorm:"not null;index"` // user claiming the expenses
+	Title            string             `json:"title" gorm:"not null"`
+	CurrencyCode     string             `json:"currency_code" gorm:"type:varchar(3);not null"`
+	Total            float64            `json:"total" gorm:"type:decimal(15,2);not null"`
+	BaseTotal        float64            `json:"base_total" gorm:"type:decimal(15,2);not null"` // in the base currency, at the rates of the expense dates
+	Status           ExpenseClaimStatus `json:"status" gorm:"type:varchar(20);not null;index"`
+	Lines            []ExpenseClaimLine `json:"lines,omitempty" gorm:"foreignKey:ClaimID"`
+	SubmittedAt      *time.Time         `json:"submitted_at,omitempty"`
+	ReviewerID       *uint              `json:"reviewer_id,omitempty"`
+	OnBehalfOfID     *uint              `json:"on_behalf_of_id,omitempty"` // approver away the reviewer acted for
+	ReviewedAt       *time.Time         `json:"reviewed_at,omitempty"`
+	ReviewNotes      string             `json:"review_notes,omitempty"`
+	FinanceInvoiceID *int64             `json:"finance_invoice_id,omitempty"`
+	FinancePaymentID *int64             `json:"finance_payment_id,omitempty"`
+	ReimbursedAt     *time.Time         `json:"reimbursed_at,omitempty"`
+	CreatedAt        time.Time          `json:"created_at"`
+	UpdatedAt        time.Time          `json:"updated_at"`
+}
+
+// ExpenseClaimLine is an expense of a claim, backed by a receipt attached to
+// the claim
+type ExpenseClaimLine struct {
+	ID           uint             `json:"id" gorm:"primaryKey"`
+	ClaimID      uint             `json:"claim_id" gorm:"not null;index"`
+	CategoryID   uint             `json:"category_id" gorm:"not null;index"`
+	Category     *ExpenseCategory `json:"category,omitempty" gorm:"foreignKey:CategoryID"`
+	ExpenseDate  time.Time        `json:"expense_date" gorm:"type:date;not null"`
+	Description  string           `json:"description"`
+	Amount       float64          `json:"amount" gorm:"type:decimal(15,2);not null"` // in the claim currency
+	ExchangeRate float64          `json:"exchange_rate" gorm:"type:decimal(18,8);not null"`
+	BaseAmount   float64          `json:"base_amount" gorm:"type:decimal(15,2);not null"`
+}
+
+// ExpenseClaimRequest represents the request to create or update a draft expense claim
+type ExpenseClaimRequest struct {
+	Title        string                    `json:"title" binding:"required,max=255"`
+	CurrencyCode string                    `json:"currency_code" binding:"omitempty,len=3"` // the base currency by default
+	Lines        []ExpenseClaimLineRequest `json:"lines" binding:"required,min=1,dive"`
+}
+
+// ExpenseClaimLineRequest represents an expense of an expense claim request
+type ExpenseClaimLineRequest struct {
+	CategoryID  uint      `json:"category_id" binding:"required"`
+	ExpenseDate time.Time `json:"expense_date" binding:"required"`
+	Description string    `json:"description"`
+	Amount      float64   `json:"amount" binding:"required,gt=0"`
+}
+
+// ReimburseExpenseClaimRequest represents the request to pay an approved expense claim
+type ReimburseExpenseClaimRequest struct {
+	PaymentDate     time.Time            `json:"payment_date" binding:"required"`
+	PaymentMethod   FinancePaymentMethod `json:"payment_method" binding:"required"` // CASH for petty cash
+	ReferenceNumber string               `json:"reference_number"`
+}
+
+// ExpenseClaimFilter represents filters for querying expense claims
+type ExpenseClaimFilter struct {
+	EmployeeID *uint              `json:"employee_id,omitempty"`
+	Status     ExpenseClaimStatus `json:"status,omitempty"`
+	StartDate  *time.Time         `json:"start_date,omitempty"` // claims with an expense on or after
+	EndDate    *time.Time         `json:"end_date,omitempty"`   // claims with an expense on or before
+	Page       int                `json:"page,omitempty"`
+	PageSize   int                `json:"page_size,omitempty"`
+}
+
+// ExpenseCategoryTotal is the approved expenses of a category over a period
+type ExpenseCategoryTotal struct {
+	CategoryID uint    `json:"category_id"`
+	Code       string  `json:"code"`
+	Name       string  `json:"name"`
+	Lines      int     `json:"lines"`
+	Amount     float64 `json:"amount"` // in the base currency
+}
+
+// ExpenseReport is the approved and reimbursed expenses of a period by
+// category, the expenses of the profit and loss report
+type ExpenseReport struct {
+	StartDate  time.Time              `json:"start_date"`
+	EndDate    time.Time              `json:"end_date"`
+	Categories []ExpenseCategoryTotal `json:"categories"`
+	Total      float64                `json:"total"`
+}
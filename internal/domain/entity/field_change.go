@@ -0,0 +1,57 @@
+package entity
+
+import (
+	"context"
+	"time"
+)
+
+// Tables of the entities whose field changes are recorded, used as the
+// entity type of their changes
+const (
+	ChangeEntityPurchaseOrder  = "purchase_orders"
+	ChangeEntitySalesOrder     = "sales_orders"
+	ChangeEntityInvoice        = "invoices"
+	ChangeEntityFinanceInvoice = "finance_invoices"
+	ChangeEntityStock          = "stocks"
+)
+
+// ChangeEntities lists the entity types whose field changes are recorded
+var ChangeEntities = []string{
+	ChangeEntityPurchaseOrder,
+	ChangeEntitySalesOrder,
+	ChangeEntityInvoice,
+	ChangeEntityFinanceInvoice,
+	ChangeEntityStock,
+}
+
+// FieldChange records the value of one field of a critical entity before and
+// after an update, unlike the audit log which records requests. Values are
+// kept as text; nil stands for NULL.
+type FieldChange struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	EntityType string    `json:"entity_type" gorm:"type:varchar(50);not null;index:idx_field_changes_entity"`
+	EntityID   string    `json:"entity_id" gorm:"type:varchar(64);not null;index:idx_field_changes_entity"`
+	Field      string    `json:"field" gorm:"type:varchar(100);not null"`
+	OldValue   *string   `json:"old_value"`
+	NewValue   *string   `json:"new_value"`
+	ChangedBy  *uint     `json:"changed_by,omitempty"` // nil for changes made by background jobs
+	User       *User     `json:"user,omitempty" gorm:"foreignKey:ChangedBy"`
+	ChangedAt  time.Time `json:"changed_at" gorm:"not null"`
+}
+
+type actorKey struct{}
+
+// WithActor returns a context acting for a user, to whom the changes made
+// with it are attributed
+func WithActor(ctx context.Context, userID uint) context.Context {
+	return context.WithValue(ctx, actorKey{}, userID)
+}
+
+// ActorFromContext returns the user ctx acts for, or nil when there is none,
+// as for background jobs
+func ActorFromContext(ctx context.Context) *uint {
+	if userID, ok := ctx.Value(actorKey{}).(uint); ok {
+		return &userID
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package entity
+
+import (
+	"context"
+	"time"
+)
+
+// FiscalPeriodType tells how a fiscal year is divided into periods
+type FiscalPeriodType string
+
+const (
+	FiscalPeriodMonthly   FiscalPeriodType = "MONTHLY"   // 12 periods of a month
+	FiscalPeriodQuarterly FiscalPeriodType = "QUARTERLY" // 4 periods of three months
+)
+
+// FiscalPeriodStatus represents whether postings may be dated in a fiscal period
+type FiscalPeriodStatus string
+
+const (
+	FiscalPeriodOpen   FiscalPeriodStatus = "OPEN"
+	FiscalPeriodClosed FiscalPeriodStatus = "CLOSED"
+)
+
+// FiscalYear is a financial year of the company, divided into periods that
+// finance closes once their books are final
+type FiscalYear struct {
+	ID         uint             `json:"id" gorm:"primaryKey"`
+	Name       string           `json:"name" gorm:"not null;uniqueIndex"`
+	StartDate  time.Time        `json:"start_date" gorm:"type:date;not null"`
+	EndDate    time.Time        `json:"end_date" gorm:"type:date;not null"` // last day of the year
+	PeriodType FiscalPeriodType `json:"period_type" gorm:"type:varchar(20);not null"`
+	Periods    []FiscalPeriod   `json:"periods,omitempty" gorm:"foreignKey:FiscalYearID"`
+	CreatedBy  uint             `json:"created_by" gorm:"not null"`
+	CreatedAt  time.Time        `json:"created_at"`
+	UpdatedAt  time.Time        `json:"updated_at"`
+}
+
+// FiscalPeriod is a period of a fiscal year. Invoices, payments and stock
+// adjustments dated in a closed period are refused, unless the user holds
+// finance:period:override.
+type FiscalPeriod struct {
+	ID           uint               `json:"id" gorm:"primaryKey"`
+	FiscalYearID uint               `json:"fiscal_year_id" gorm:"not null;uniqueIndex:idx_fiscal_periods_number"`
+	Number       int                `json:"number" gorm:"not null;uniqueIndex:idx_fiscal_periods_number"` // 1 for the first period of the year
+	Name         string             `json:"name" gorm:"not null"`                                         // e.g. FY2025-P01
+	StartDate    time.Time          `json:"start_date" gorm:"type:date;not null"`
+	EndDate      time.Time          `json:"end_date" gorm:"type:date;not null"` // last day of the period
+	Status       FiscalPeriodStatus `json:"status" gorm:"type:varchar(20);not null;default:'OPEN'"`
+	ClosedAt     *time.Time         `json:"closed_at,omitempty"`
+	ClosedBy     *uint              `json:"closed_by,omitempty"`
+	CreatedAt    time.Time          `json:"created_at"`
+	UpdatedAt    time.Time          `json:"updated_at"`
+}
+
+// Closed tells whether postings dated in the period are refused
+func (p *FiscalPeriod) Closed() bool {
+	return p.Status == FiscalPeriodClosed
+}
+
+// CreateFiscalYearRequest represents the request to set up a fiscal year and
+// its periods
+type CreateFiscalYearRequest struct {
+	Name       string           `json:"name" binding:"required,max=50"`                          // e.g. FY2025
+	StartDate  string           `json:"start_date" binding:"required"`                           // YYYY-MM-DD; the year runs for twelve months
+	PeriodType FiscalPeriodType `json:"period_type" binding:"omitempty,oneof=MONTHLY QUARTERLY"` // MONTHLY by default
+}
+
+type closedPeriodOverrideKey struct{}
+
+// WithClosedPeriodOverride returns a context whose postings may be dated in
+// closed fiscal periods, for users holding finance:period:override
+func WithClosedPeriodOverride(ctx context.Context) context.Context {
+	return context.WithValue(ctx, closedPeriodOverrideKey{}, true)
+}
+
+// ClosedPeriodOverride tells whether the postings of ctx may be dated in
+// closed fiscal periods
+func ClosedPeriodOverride(ctx context.Context) bool {
+	override, _ := ctx.Value(closedPeriodOverrideKey{}).(bool)
+	return override
+}
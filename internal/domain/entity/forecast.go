@@ -0,0 +1,66 @@
+package entity
+
+import "time"
+
+// ForecastDimension is what sales forecasts are broken down by
+type ForecastDimension string
+
+const (
+	ForecastDimensionCustomer ForecastDimension = "CUSTOMER"
+	ForecastDimensionCategory ForecastDimension = "CATEGORY"
+)
+
+// SalesForecast is the forecast revenue of one customer or SKU category for one
+// month, in the base currency. Snapshots of forecasts are stored so they can be
+// compared with actual sales once the month closes.
+type SalesForecast struct {
+	ID            uint              `json:"id,omitempty" gorm:"primaryKey"`
+	Dimension     ForecastDimension `json:"dimension" gorm:"type:varchar(20);not null;index:idx_sales_forecasts_lookup"`
+	Key           string            `json:"key" gorm:"not null;index:idx_sales_forecasts_lookup"` // client ID or SKU category
+	Name          string            `json:"name"`
+	Period        string            `json:"period" gorm:"type:varchar(7);not null;index:idx_sales_forecasts_lookup"`
+	Forecast      float64           `json:"forecast" gorm:"type:decimal(15,2);not null"`
+	SeasonalIndex float64           `json:"seasonal_index" gorm:"type:decimal(8,4);not null"`
+	GeneratedFor  string            `json:"generated_for,omitempty" gorm:"type:varchar(7)"` // month the snapshot was taken in
+	GeneratedAt   time.Time         `json:"generated_at"`
+}
+
+// SalesForecastRequest represents the parameters of a sales forecast
+type SalesForecastRequest struct {
+	Dimension     ForecastDimension `json:"dimension"`
+	Horizon       int               `json:"horizon"`        // months to forecast, starting with the current month
+	Window        int               `json:"window"`         // months in the moving average
+	HistoryMonths int               `json:"history_months"` // closed months of sales history used
+}
+
+// SalesForecastResult is a monthly revenue forecast per customer or category
+type SalesForecastResult struct {
+	Dimension   ForecastDimension  `json:"dimension"`
+	Window      int                `json:"window"`
+	Periods     []string           `json:"periods"`
+	Forecasts   []SalesForecast    `json:"forecasts"`
+	Totals      map[string]float64 `json:"totals"` // forecast revenue per period across all keys
+	GeneratedAt time.Time          `json:"generated_at"`
+}
+
+// ForecastAccuracyLine compares the forecast of one key and month with actual sales
+type ForecastAccuracyLine struct {
+	Key                  string   `json:"key"`
+	Name                 string   `json:"name"`
+	Period               string   `json:"period"`
+	Forecast             float64  `json:"forecast"`
+	Actual               float64  `json:"actual"`
+	Error                float64  `json:"error"`                            // actual less forecast
+	AbsolutePercentError *float64 `json:"absolute_percent_error,omitempty"` // unset when there were no sales
+}
+
+// ForecastAccuracy reports how well stored forecasts matched actual sales
+type ForecastAccuracy struct {
+	Dimension ForecastDimension      `json:"dimension"`
+	From      string                 `json:"from"`
+	To        string                 `json:"to"`
+	Lines     []ForecastAccuracyLine `json:"lines"`
+	MAPE      float64                `json:"mape"` // mean absolute percent error over lines with sales
+	WAPE      float64                `json:"wape"` // total absolute error as a percent of total sales
+	Bias      float64                `json:"bias"` // total forecast less total sales, as a percent of total sales
+}
@@ -0,0 +1,34 @@
+package entity
+
+// FormField describes an input of a form, generated from the request type's
+// JSON and validation tags
+type FormField struct {
+	Name             string      `json:"name"`             // JSON key
+	Type             string      `json:"type"`             // string, integer, number, boolean, date-time, object or array
+	Format           string      `json:"format,omitempty"` // email, uri, uuid ...
+	Required         bool        `json:"required"`
+	Enum             []string    `json:"enum,omitempty"`
+	Minimum          *float64    `json:"minimum,omitempty"`
+	Maximum          *float64    `json:"maximum,omitempty"`
+	ExclusiveMinimum bool        `json:"exclusive_minimum,omitempty"` // the minimum itself is not allowed
+	ExclusiveMaximum bool        `json:"exclusive_maximum,omitempty"`
+	MinLength        *int        `json:"min_length,omitempty"` // characters for strings, items for arrays
+	MaxLength        *int        `json:"max_length,omitempty"`
+	Example          string      `json:"example,omitempty"`
+	Help             string      `json:"help,omitempty"`
+	Fields           []FormField `json:"fields,omitempty"` // of objects, and of the items of arrays of objects
+	ItemType         string      `json:"item_type,omitempty"`
+}
+
+// FormSchema describes the request body of the endpoint creating an entity, so
+// user interfaces can generate their forms from the validation the API applies
+type FormSchema struct {
+	Entity     string      `json:"entity"`
+	Title      string      `json:"title"`
+	Help       string      `json:"help,omitempty"`
+	Method     string      `json:"method"`
+	Path       string      `json:"path"`
+	Permission Permission  `json:"permission,omitempty"` // needed to submit the form
+	Permitted  bool        `json:"permitted"`            // whether the current user holds the permission
+	Fields     []FormField `json:"fields"`
+}
@@ -0,0 +1,160 @@
+package entity
+
+import "time"
+
+// FulfillmentSLAMetric is a fulfillment service level measured per warehouse
+type FulfillmentSLAMetric string
+
+const (
+	FulfillmentLeadTime FulfillmentSLAMetric = "LEAD_TIME" // average days from order to shipment, breached above the SLA ship days
+	FulfillmentFillRate FulfillmentSLAMetric = "FILL_RATE" // percentage of units shipped by the committed date, breached below the target
+)
+
+// OpenOrderLine is a line of an open sales order with what is left to ship
+type OpenOrderLine struct {
+	SKUID             string  `json:"sku_id"`
+	SKUCode           string  `json:"sku_code"`
+	Name              string  `json:"name"`
+	OrderedQuantity   float64 `json:"ordered_quantity"`
+	ShippedQuantity   float64 `json:"shipped_quantity"`
+	RemainingQuantity float64 `json:"remaining_quantity"`
+	OpenValue         float64 `json:"open_value"` // remaining quantity at the line price, in the base currency
+	Late              bool    `json:"late"`       // not fully shipped by the committed date
+}
+
+// OpenOrder is a sales order with lines left to ship
+type OpenOrder struct {
+	OrderID       string           `json:"order_id"`
+	OrderNumber   string           `json:"order_number"`
+	ClientID      uint             `json:"client_id"`
+	ClientName    string           `json:"client_name"`
+	OrderDate     time.Time        `json:"order_date"`
+	CommittedDate time.Time        `json:"committed_date"` // promised ship date, or the order date plus the SLA ship days for orders without one
+	Status        SalesOrderStatus `json:"status"`
+	OpenValue     float64          `json:"open_value"`
+	LateLines     int              `json:"late_lines"`
+	DaysLate      int              `json:"days_late"` // days past the committed date, for orders with late lines
+	Lines         []OpenOrderLine  `json:"lines"`
+	OrderURL      string           `json:"order_url,omitempty"`
+}
+
+// OpenOrderBook lists the sales orders with lines left to ship, earliest
+// committed date first. Amounts are in the base currency.
+type OpenOrderBook struct {
+	Orders      []OpenOrder `json:"orders"`
+	OrderCount  int         `json:"order_count"`
+	LateOrders  int         `json:"late_orders"`
+	LateLines   int         `json:"late_lines"`
+	OpenValue   float64     `json:"open_value"`
+	LateValue   float64     `json:"late_value"` // open value of the late lines
+	GeneratedAt time.Time   `json:"generated_at"`
+}
+
+// OpenOrderFilter represents filters on the open order book
+type OpenOrderFilter struct {
+	ClientID uint
+	LateOnly bool // only orders with late lines
+}
+
+// WarehouseFulfillment is the fulfillment performance of a warehouse over a
+// period against the SLA
+type WarehouseFulfillment struct {
+	StoreID           string  `json:"store_id"` // empty for drop-ship deliveries
+	StoreName         string  `json:"store_name"`
+	Shipments         int     `json:"shipments"`            // deliveries shipped in the period
+	AvgLeadTimeDays   float64 `json:"avg_lead_time_days"`   // order to shipment
+	LateShipments     int     `json:"late_shipments"`       // shipped more than the SLA ship days after the order
+	OnTimeShipPercent float64 `json:"on_time_ship_percent"` // shipments within the SLA ship days
+	OrderedQuantity   float64 `json:"ordered_quantity"`     // units the warehouse was to ship for orders committed in the period
+	ShippedOnTime     float64 `json:"shipped_on_time"`      // of those, units shipped by the committed date
+	FillRate          float64 `json:"fill_rate"`
+	LeadTimeBreached  bool    `json:"lead_time_breached"`
+	FillRateBreached  bool    `json:"fill_rate_breached"`
+}
+
+// FulfillmentSLAReport is the fulfillment performance of each warehouse over
+// a period. Lead times are measured on the deliveries shipped in the period,
+// fill rates on the orders whose committed date falls in it.
+type FulfillmentSLAReport struct {
+	StartDate       time.Time              `json:"start_date"`
+	EndDate         time.Time              `json:"end_date"`
+	SLAShipDays     int                    `json:"sla_ship_days"`
+	FillRateTarget  float64                `json:"fill_rate_target"`
+	Warehouses      []WarehouseFulfillment `json:"warehouses"`
+	Shipments       int                    `json:"shipments"`
+	AvgLeadTimeDays float64                `json:"avg_lead_time_days"`
+	OrderedQuantity float64                `json:"ordered_quantity"` // units of the order lines committed in the period
+	ShippedOnTime   float64                `json:"shipped_on_time"`
+	FillRate        float64                `json:"fill_rate"`
+	GeneratedAt     time.Time              `json:"generated_at"`
+}
+
+// FulfillmentSLAFilter represents the period of the fulfillment SLA report
+type FulfillmentSLAFilter struct {
+	StartDate *time.Time
+	EndDate   *time.Time
+}
+
+// FulfillmentSLAAlert is raised when a warehouse breaches a fulfillment SLA
+// it was meeting, and resolved once it meets it again
+type FulfillmentSLAAlert struct {
+	ID             uint                 `json:"id" gorm:"primaryKey"`
+	StoreID        string               `json:"store_id" gorm:"index"`
+	StoreName      string               `json:"store_name"`
+	Metric         FulfillmentSLAMetric `json:"metric" gorm:"type:varchar(20);not null"`
+	Value          float64              `json:"value" gorm:"type:decimal(10,2);not null"` // lead time in days or fill rate percentage
+	Threshold      float64              `json:"threshold" gorm:"type:decimal(10,2);not null"`
+	PeriodStart    time.Time            `json:"period_start"`
+	PeriodEnd      time.Time            `json:"period_end"`
+	ResolvedAt     *time.Time           `json:"resolved_at,omitempty"`
+	AcknowledgedAt *time.Time           `json:"acknowledged_at,omitempty"`
+	AcknowledgedBy *uint                `json:"acknowledged_by,omitempty"`
+	CreatedAt      time.Time            `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName specifies the table name for FulfillmentSLAAlert
+func (FulfillmentSLAAlert) TableName() string {
+	return "fulfillment_sla_alerts"
+}
+
+// FulfillmentSLAAlertFilter represents filters for listing fulfillment SLA alerts
+type FulfillmentSLAAlertFilter struct {
+	StoreID      string               `json:"store_id,omitempty"`
+	Metric       FulfillmentSLAMetric `json:"metric,omitempty"`
+	Resolved     *bool                `json:"resolved,omitempty"`
+	Acknowledged *bool                `json:"acknowledged,omitempty"`
+	Page         int                  `json:"page,omitempty"`
+	PageSize     int                  `json:"page_size,omitempty"`
+}
+
+// FulfillmentSLARunResult summarizes a fulfillment SLA check
+type FulfillmentSLARunResult struct {
+	Warehouses int                   `json:"warehouses"`
+	Breaching  int                   `json:"breaching"` // warehouses breaching an SLA
+	Alerts     []FulfillmentSLAAlert `json:"alerts"`    // new breaches
+	Resolved   int                   `json:"resolved"`  // alerts resolved
+	CheckedAt  time.Time             `json:"checked_at"`
+}
+
+// FulfillmentSalesOrder is a sales order of the fulfillment reports
+type FulfillmentSalesOrder struct {
+	ID           string
+	OrderNumber  string
+	ClientID     uint
+	OrderDate    time.Time
+	PromisedDate *time.Time
+	Status       SalesOrderStatus
+	Items        SalesOrderItems
+	ExchangeRate float64
+}
+
+// FulfillmentDelivery is a delivery of the fulfillment reports. Deliveries not
+// shipped yet have no ship time.
+type FulfillmentDelivery struct {
+	ID           string
+	SalesOrderID string
+	StoreID      string
+	Status       DeliveryOrderStatus
+	ShippedAt    *time.Time
+	Items        DeliveryOrderItems
+}
@@ -0,0 +1,24 @@
+package entity
+
+import "time"
+
+// IdempotencyKey records a mutating request sent with an Idempotency-Key
+// header, so a client retrying it gets the original response instead of a
+// second change. Keys are scoped to the user sending them.
+type IdempotencyKey struct {
+	UserID      uint       `json:"user_id" gorm:"primaryKey"`
+	Key         string     `json:"key" gorm:"primaryKey;type:varchar(255)"`
+	Method      string     `json:"method" gorm:"type:varchar(10);not null"`
+	Path        string     `json:"path" gorm:"type:varchar(500);not null"`
+	RequestHash string     `json:"request_hash" gorm:"type:varchar(64);not null"` // SHA-256 of the method, path and body
+	StatusCode  int        `json:"status_code" gorm:"not null;default:0"`         // 0 while the request is in progress
+	ContentType string     `json:"content_type" gorm:"type:varchar(100)"`
+	Response    []byte     `json:"-" gorm:"type:bytea"`
+	CreatedAt   time.Time  `json:"created_at" gorm:"not null"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// Completed reports whether the request finished and its response is kept
+func (k *IdempotencyKey) Completed() bool {
+	return k.CompletedAt != nil
+}
@@ -0,0 +1,45 @@
+package entity
+
+import "time"
+
+// ImpersonationSession lets an admin act as another user for troubleshooting
+// until it expires or is ended. Requests made in it are audited as the user
+// with the admin as impersonator.
+type ImpersonationSession struct {
+	ID              uint       `json:"id" gorm:"primaryKey"`
+	ImpersonatorID  uint       `json:"impersonator_id" gorm:"not null;index"`
+	Impersonator    *User      `json:"impersonator,omitempty" gorm:"foreignKey:ImpersonatorID"`
+	UserID          uint       `json:"user_id" gorm:"not null;index"`
+	User            *User      `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	Reason          string     `json:"reason" gorm:"type:text;not null"`
+	DurationMinutes int        `json:"duration_minutes" gorm:"not null"`
+	IP              string     `json:"ip" gorm:"type:varchar(45)"`
+	StartedAt       time.Time  `json:"started_at" gorm:"not null;index"`
+	ExpiresAt       time.Time  `json:"expires_at" gorm:"not null"`
+	EndedAt         *time.Time `json:"ended_at,omitempty"`
+	EndedBy         *uint      `json:"ended_by,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// ActiveAt reports whether the session can be acted in at the given time
+func (s *ImpersonationSession) ActiveAt(at time.Time) bool {
+	return s.EndedAt == nil && s.ExpiresAt.After(at)
+}
+
+// ImpersonationRequest represents the request to start impersonating a user
+type ImpersonationRequest struct {
+	UserID          uint   `json:"user_id" binding:"required"`
+	Reason          string `json:"reason" binding:"required"`
+	DurationMinutes int    `json:"duration_minutes" binding:"required,gt=0"`
+}
+
+// ImpersonationFilter represents filters for listing impersonation sessions
+type ImpersonationFilter struct {
+	ImpersonatorID uint       `json:"impersonator_id,omitempty"`
+	UserID         uint       `json:"user_id,omitempty"`
+	Active         *bool      `json:"active,omitempty"`
+	StartDate      *time.Time `json:"start_date,omitempty"` // started on or after
+	EndDate        *time.Time `json:"end_date,omitempty"`   // started on or before
+	Page           int        `json:"page,omitempty"`
+	PageSize       int        `json:"page_size,omitempty"`
+}
@@ -0,0 +1,127 @@
+package entity
+
+import "time"
+
+// DockDoor is a door of a warehouse where inbound deliveries are unloaded
+type DockDoor struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	StoreID   string    `json:"store_id" gorm:"type:uuid;not null;uniqueIndex:idx_dock_doors_store_name"`
+	Name      string    `json:"name" gorm:"type:varchar(50);not null;uniqueIndex:idx_dock_doors_store_name"`
+	Active    bool      `json:"active" gorm:"default:true"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// DockDoorRequest represents the request to create or update a dock door
+type DockDoorRequest struct {
+	StoreID string `json:"store_id" binding:"required"`
+	Name    string `json:"name" binding:"required"`
+	Active  *bool  `json:"active"`
+}
+
+// DockAppointmentStatus represents where a dock appointment stands
+type DockAppointmentStatus string
+
+const (
+	DockAppointmentBooked    DockAppointmentStatus = "BOOKED"
+	DockAppointmentArrived   DockAppointmentStatus = "ARRIVED" // the delivery checked in at the door
+	DockAppointmentCancelled DockAppointmentStatus = "CANCELLED"
+)
+
+// DockAppointment books a time slot at a dock door for an inbound delivery
+type DockAppointment struct {
+	ID              uint                  `json:"id" gorm:"primaryKey"`
+	DoorID          uint                  `json:"door_id" gorm:"not null;index"`
+	StoreID         string                `json:"store_id" gorm:"type:uuid;not null;index"`
+	PurchaseOrderID *string               `json:"purchase_order_id,omitempty" gorm:"type:uuid;index"`
+	ShipNoticeID    *uint                 `json:"ship_notice_id,omitempty"` // EDI 856 document announcing the delivery
+	Carrier         string                `json:"carrier,omitempty"`
+	StartsAt        time.Time             `json:"starts_at" gorm:"not null"`
+	EndsAt          time.Time             `json:"ends_at" gorm:"not null"`
+	Status          DockAppointmentStatus `json:"status" gorm:"type:varchar(20);not null;default:'BOOKED'"`
+	ArrivedAt       *time.Time            `json:"arrived_at,omitempty"`
+	Notes           string                `json:"notes,omitempty" gorm:"type:text"`
+	BookedByID      uint                  `json:"booked_by_id" gorm:"not null"`
+	CreatedAt       time.Time             `json:"created_at"`
+	UpdatedAt       time.Time             `json:"updated_at"`
+	Door            *DockDoor             `json:"door,omitempty" gorm:"foreignKey:DoorID"`
+}
+
+// DockAppointmentRequest represents the request to book a dock door slot.
+// A ship notice gives the purchase order it ships.
+type DockAppointmentRequest struct {
+	DoorID          uint      `json:"door_id" binding:"required"`
+	PurchaseOrderID string    `json:"purchase_order_id"`
+	ShipNoticeID    *uint     `json:"ship_notice_id"`
+	Carrier         string    `json:"carrier"`
+	StartsAt        time.Time `json:"starts_at" binding:"required"`
+	EndsAt          time.Time `json:"ends_at" binding:"required"`
+	Notes           string    `json:"notes"`
+}
+
+// DockAppointmentFilter represents filters for listing dock appointments
+type DockAppointmentFilter struct {
+	StoreID         string                `json:"store_id,omitempty"`
+	DoorID          uint                  `json:"door_id,omitempty"`
+	PurchaseOrderID string                `json:"purchase_order_id,omitempty"`
+	Status          DockAppointmentStatus `json:"status,omitempty"`
+	From            *time.Time            `json:"from,omitempty"` // slots ending after
+	To              *time.Time            `json:"to,omitempty"`   // slots starting before
+}
+
+// InboundKind tells what announced an inbound delivery
+type InboundKind string
+
+const (
+	InboundPurchaseOrder InboundKind = "PURCHASE_ORDER" // an open purchase order, on its expected date
+	InboundShipNotice    InboundKind = "SHIP_NOTICE"    // an advance ship notice not received yet, on its delivery date
+)
+
+// InboundEntry is a delivery expected at a warehouse on a day
+type InboundEntry struct {
+	Date            time.Time           `json:"date"`
+	Kind            InboundKind         `json:"kind"`
+	StoreID         string              `json:"store_id,omitempty"`
+	PurchaseOrderID string              `json:"purchase_order_id"`
+	OrderNumber     string              `json:"order_number"`
+	OrderStatus     PurchaseOrderStatus `json:"order_status"`
+	VendorID        uint                `json:"vendor_id"`
+	VendorName      string              `json:"vendor_name"`
+	ExpectedDate    time.Time           `json:"expected_date"` // expected date of the purchase order
+	ShipNoticeID    *uint               `json:"ship_notice_id,omitempty"`
+	ShipmentID      string              `json:"shipment_id,omitempty"`
+	Carrier         string              `json:"carrier,omitempty"`
+	Overdue         bool                `json:"overdue"` // the order is still open past its expected date
+	DaysOverdue     int                 `json:"days_overdue,omitempty"`
+	Appointments    []DockAppointment   `json:"appointments,omitempty"`
+}
+
+// InboundCalendarFilter represents filters for the expected receipts calendar
+type InboundCalendarFilter struct {
+	StartDate *time.Time `json:"start_date,omitempty"`
+	EndDate   *time.Time `json:"end_date,omitempty"`
+	StoreID   string     `json:"store_id,omitempty"`
+}
+
+// InboundCalendarDay is the deliveries expected at the warehouses on a day
+type InboundCalendarDay struct {
+	Date    time.Time      `json:"date"`
+	Entries []InboundEntry `json:"entries"`
+}
+
+// InboundCalendar is the deliveries expected over a period, by day
+type InboundCalendar struct {
+	StartDate time.Time            `json:"start_date"`
+	EndDate   time.Time            `json:"end_date"`
+	StoreID   string               `json:"store_id,omitempty"`
+	Days      []InboundCalendarDay `json:"days"`
+}
+
+// InboundOrderFilter represents filters for listing the open purchase orders
+// expected at the warehouses
+type InboundOrderFilter struct {
+	StoreID        string     `json:"store_id,omitempty"` // orders delivered to the store or booked at one of its doors
+	IDs            []string   `json:"ids,omitempty"`
+	ExpectedFrom   *time.Time `json:"expected_from,omitempty"`
+	ExpectedBefore *time.Time `json:"expected_before,omitempty"`
+}
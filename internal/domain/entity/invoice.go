@@ -91,11 +91,23 @@ type FinanceInvoice struct {
 	Total          float64              `json:"total" db:"total"`
 	AmountPaid     float64              `json:"amount_paid" db:"amount_paid"`
 	AmountDue      float64              `json:"amount_due" db:"amount_due"`
+	CurrencyCode   string               `json:"currency_code" db:"currency_code"`
+	ExchangeRate   float64              `json:"exchange_rate" db:"exchange_rate"`
+	BaseTotal      float64              `json:"base_total" db:"base_total"`
 	Status         FinanceInvoiceStatus `json:"status" db:"status"`
 	Notes          string               `json:"notes" db:"notes"`
 	CreatedBy      int64                `json:"created_by" db:"created_by"`
 	CreatedAt      time.Time            `json:"created_at" db:"created_at"`
 	UpdatedAt      time.Time            `json:"updated_at" db:"updated_at"`
+
+	// Tax withheld from the payments to the supplier, a percentage of the
+	// subtotal after discount
+	WithholdingRate   float64 `json:"withholding_rate" db:"withholding_rate"`
+	WithholdingAmount float64 `json:"withholding_amount" db:"withholding_amount"`
+
+	// Prepayments of the order referenced applied to the invoice, part of
+	// AmountPaid
+	PrepaidAmount float64 `json:"prepaid_amount" db:"prepaid_amount"`
 }
 
 // FinanceInvoiceFilter represents filters for querying finance invoices
@@ -110,6 +122,7 @@ type FinanceInvoiceFilter struct {
 	EndDate       *time.Time           `json:"end_date,omitempty"`
 	Page          int                  `json:"page,omitempty"`
 	PageSize      int                  `json:"page_size,omitempty"`
+	Archived      bool                 `json:"archived,omitempty"` // query the archive table instead of the live one
 }
 
 // CreateFinanceInvoiceRequest represents the request to create a new finance invoice
@@ -122,7 +135,12 @@ type CreateFinanceInvoiceRequest struct {
 	DueDate        time.Time           `json:"due_date" binding:"required"`
 	Items          FinanceInvoiceItems `json:"items" binding:"required,dive"`
 	DiscountAmount float64             `json:"discount_amount"`
+	CurrencyCode   string              `json:"currency_code" binding:"omitempty,len=3"`
 	Notes          string              `json:"notes"`
+
+	// WithholdingRate overrides the withholding tax rate of the supplier of a
+	// purchase invoice, 0 withholding nothing
+	WithholdingRate *float64 `json:"withholding_rate" binding:"omitempty,gte=0,lte=100"`
 }
 
 // UpdateFinanceInvoiceRequest represents the request to update a finance invoice
@@ -133,6 +151,9 @@ type UpdateFinanceInvoiceRequest struct {
 	DiscountAmount float64              `json:"discount_amount"`
 	Notes          string               `json:"notes"`
 	Status         FinanceInvoiceStatus `json:"status"`
+
+	// WithholdingRate changes the withholding tax rate of a purchase invoice
+	WithholdingRate *float64 `json:"withholding_rate" binding:"omitempty,gte=0,lte=100"`
 }
 
 // FinanceInvoiceResponse represents the response for finance invoice operations
@@ -159,6 +180,13 @@ type FinanceReport struct {
 	GrossProfit  float64   `json:"gross_profit"`
 	TotalTax     float64   `json:"total_tax"`
 	NetProfit    float64   `json:"net_profit"`
+
+	// Amounts are in the base currency. Realized FX gains come from payments settled
+	// at a different rate than their invoice; unrealized gains revalue open
+	// foreign-currency balances at the rate on the report end date.
+	BaseCurrency     string  `json:"base_currency"`
+	RealizedFXGain   float64 `json:"realized_fx_gain"`
+	UnrealizedFXGain float64 `json:"unrealized_fx_gain"`
 }
 
 // FinanceReportRequest represents a request for a financial report
@@ -0,0 +1,62 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// JobStatus represents the state of a background job
+type JobStatus string
+
+const (
+	JobQueued    JobStatus = "QUEUED"
+	JobRunning   JobStatus = "RUNNING"
+	JobSucceeded JobStatus = "SUCCEEDED"
+	JobFailed    JobStatus = "FAILED" // failed on its last attempt
+	JobCanceled  JobStatus = "CANCELED"
+)
+
+// Job types
+const (
+	JobReportGenerate = "report.generate"
+	JobReportExport   = "report.export"
+	JobSKUBulkCreate  = "sku.bulk_create"
+	JobSKUBulkUpdate  = "sku.bulk_update"
+	JobUserImport     = "user.import"
+	JobEmailDocument  = "email.document"
+)
+
+// Job is work queued to run in the background rather than while a request is
+// served. Workers take queued jobs in order of RunAt; a failed job is retried
+// until MaxAttempts is reached.
+type Job struct {
+	ID          uint64          `json:"id" gorm:"primaryKey"`
+	Type        string          `json:"type" gorm:"type:varchar(50);not null"`
+	Status      JobStatus       `json:"status" gorm:"type:varchar(20);not null"`
+	Payload     json.RawMessage `json:"payload,omitempty" gorm:"type:jsonb"`
+	Result      json.RawMessage `json:"result,omitempty" gorm:"type:jsonb"`
+	Error       string          `json:"error,omitempty" gorm:"type:text"`
+	Progress    int             `json:"progress" gorm:"not null;default:0"` // percentage done
+	Attempts    int             `json:"attempts" gorm:"not null;default:0"`
+	MaxAttempts int             `json:"max_attempts" gorm:"not null"`
+	RunAt       time.Time       `json:"run_at" gorm:"not null"`
+	StartedAt   *time.Time      `json:"started_at,omitempty"`
+	FinishedAt  *time.Time      `json:"finished_at,omitempty"`
+	CreatedBy   *uint           `json:"created_by,omitempty"` // nil for jobs the server queued itself
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+// Finished reports whether the job will not run again
+func (j *Job) Finished() bool {
+	return j.Status == JobSucceeded || j.Status == JobFailed || j.Status == JobCanceled
+}
+
+// JobFilter represents filters for listing jobs
+type JobFilter struct {
+	Type      string    `json:"type,omitempty"`
+	Status    JobStatus `json:"status,omitempty"`
+	CreatedBy *uint     `json:"created_by,omitempty"`
+	Page      int       `json:"page,omitempty"`
+	PageSize  int       `json:"page_size,omitempty"`
+}
@@ -0,0 +1,89 @@
+package entity
+
+import "math"
+
+// KitComponent is a SKU a kit is made of, with the quantity of it in one kit.
+// Kits are not stocked themselves: selling a kit reserves and ships its
+// components, and its availability follows from theirs.
+type KitComponent struct {
+	ID          uint    `json:"id" gorm:"primaryKey"`
+	KitID       string  `json:"kit_id" gorm:"type:uuid;not null;uniqueIndex:idx_kit_components_kit_component"`
+	ComponentID string  `json:"component_id" gorm:"type:uuid;not null;uniqueIndex:idx_kit_components_kit_component"`
+	Quantity    float64 `json:"quantity" gorm:"type:decimal(15,3);not null"`
+	Component   *SKU    `json:"component,omitempty" gorm:"foreignKey:ComponentID"`
+}
+
+// KitComponentLine represents a component of a kit in a request
+type KitComponentLine struct {
+	SKUID    string  `json:"sku_id" binding:"required"`
+	Quantity float64 `json:"quantity" binding:"required,gt=0"`
+}
+
+// KitComponentsRequest represents the request to set the components of a
+// kit, none to make it a plain SKU again
+type KitComponentsRequest struct {
+	Components []KitComponentLine `json:"components" binding:"dive"`
+}
+
+// SalesOrderComponent is the quantity of a component a kit line of a sales
+// order takes, recorded when the order is placed
+type SalesOrderComponent struct {
+	SKUID    string  `json:"sku_id"`
+	Quantity float64 `json:"quantity"`
+}
+
+// StockQuantities returns the quantity of each SKU the items take from
+// stock: the components of kit lines, and the SKU of other lines. Drop-ship
+// lines take nothing, their vendor ships them.
+func (items SalesOrderItems) StockQuantities() map[string]float64 {
+	quantities := make(map[string]float64)
+	for _, item := range items {
+		if item.DropShip {
+			continue
+		}
+		if len(item.Components) == 0 {
+			quantities[item.SKUID] += item.Quantity
+			continue
+		}
+		for _, component := range item.Components {
+			quantities[component.SKUID] += component.Quantity
+		}
+	}
+	return quantities
+}
+
+// AvailableToPromise is the quantity of a SKU a store can still promise to
+// new orders: the stock outside quarantine less what allocation runs
+// reserved for confirmed orders. A kit's is the number of whole kits its
+// components make up.
+type AvailableToPromise struct {
+	SKUID      string               `json:"sku_id"`
+	StoreID    string               `json:"store_id"`
+	OnHand     float64              `json:"on_hand"`
+	Reserved   float64              `json:"reserved"`
+	Available  float64              `json:"available"`
+	Kit        bool                 `json:"kit"`
+	Components []ComponentAvailable `json:"components,omitempty"`
+}
+
+// ComponentAvailable is the availability of a component of a kit, and the
+// kits it is enough for
+type ComponentAvailable struct {
+	SKUID     string  `json:"sku_id"`
+	PerKit    float64 `json:"per_kit"`
+	Available float64 `json:"available"`
+	Kits      float64 `json:"kits"`
+}
+
+// KitsAvailable returns the whole kits the available components make up,
+// the fewest any component is enough for
+func KitsAvailable(components []ComponentAvailable) float64 {
+	if len(components) == 0 {
+		return 0
+	}
+	kits := math.Inf(1)
+	for _, component := range components {
+		kits = math.Min(kits, component.Kits)
+	}
+	return kits
+}
@@ -0,0 +1,82 @@
+package entity
+
+import "time"
+
+// MarginDimension is what the gross margin report groups sales by
+type MarginDimension string
+
+const (
+	MarginByProduct     MarginDimension = "product"
+	MarginByCategory    MarginDimension = "category"
+	MarginByCustomer    MarginDimension = "customer"
+	MarginBySalesperson MarginDimension = "salesperson" // user who entered the order
+	MarginByWarehouse   MarginDimension = "warehouse"   // store the line was delivered from
+	MarginByMonth       MarginDimension = "month"       // month of the order date
+)
+
+// MarginDimensions are the dimensions the gross margin report groups by
+var MarginDimensions = []MarginDimension{
+	MarginByProduct, MarginByCategory, MarginByCustomer, MarginBySalesperson, MarginByWarehouse, MarginByMonth,
+}
+
+// MarginRow is the gross margin of the sales falling in one group. Amounts
+// are in the base currency.
+type MarginRow struct {
+	Key                string  `json:"key"`   // SKU, category, client, user or store ID, or month (YYYY-MM)
+	Label              string  `json:"label"` // name of the group
+	OrderCount         int     `json:"order_count"`
+	Quantity           float64 `json:"quantity"`
+	Revenue            float64 `json:"revenue"`       // sales net of tax
+	CostOfGoods        float64 `json:"cost_of_goods"` // units sold at average purchase price, drop-ship lines at the vendor's price
+	GrossMargin        float64 `json:"gross_margin"`
+	GrossMarginPercent float64 `json:"gross_margin_percent"`
+	OrdersURL          string  `json:"orders_url,omitempty"` // drill-down to the orders of the group
+}
+
+// MarginReport is the gross margin of the sales orders placed in a period,
+// grouped by a dimension, highest margin first
+type MarginReport struct {
+	StartDate   time.Time       `json:"start_date"`
+	EndDate     time.Time       `json:"end_date"`
+	GroupBy     MarginDimension `json:"group_by"`
+	Rows        []MarginRow     `json:"rows"`
+	Total       MarginRow       `json:"total"`
+	GeneratedAt time.Time       `json:"generated_at"`
+}
+
+// MarginOrder is the part of a sales order falling in a group of the gross
+// margin report
+type MarginOrder struct {
+	OrderID            string    `json:"order_id"`
+	OrderNumber        string    `json:"order_number"`
+	OrderDate          time.Time `json:"order_date"`
+	ClientID           uint      `json:"client_id"`
+	Quantity           float64   `json:"quantity"`
+	Revenue            float64   `json:"revenue"`
+	CostOfGoods        float64   `json:"cost_of_goods"`
+	GrossMargin        float64   `json:"gross_margin"`
+	GrossMarginPercent float64   `json:"gross_margin_percent"`
+	OrderURL           string    `json:"order_url,omitempty"`
+}
+
+// MarginFilter represents the period and grouping of the gross margin report,
+// and the group drilled down into
+type MarginFilter struct {
+	StartDate *time.Time
+	EndDate   *time.Time
+	GroupBy   MarginDimension
+	Key       string // group whose orders are listed
+}
+
+// MarginSalesOrder is a sales order of the margin report with the stores its
+// SKUs were delivered from
+type MarginSalesOrder struct {
+	ID           string
+	OrderNumber  string
+	ClientID     uint
+	OrderDate    time.Time
+	Items        SalesOrderItems
+	ExchangeRate float64
+	CreatedByID  uint
+	Stores       map[string]string `gorm:"-"` // store of the first delivery of each SKU
+}
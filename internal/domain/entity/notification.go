@@ -0,0 +1,133 @@
+package entity
+
+import "time"
+
+// NotificationChannel is a way of delivering notifications to a user
+type NotificationChannel string
+
+const (
+	ChannelInApp NotificationChannel = "IN_APP" // the user's inbox
+	ChannelEmail NotificationChannel = "EMAIL"
+	ChannelSMS   NotificationChannel = "SMS" // to the user's phone number
+)
+
+// NotificationChannels lists the channels in the order they are delivered
+var NotificationChannels = []NotificationChannel{ChannelInApp, ChannelEmail, ChannelSMS}
+
+// NotificationType identifies what a notification is about. Preferences and
+// templates are kept per type.
+type NotificationType string
+
+const (
+	NotificationApprovalPending NotificationType = "APPROVAL_PENDING"    // a document or access request awaits approval
+	NotificationInvoiceOverdue  NotificationType = "INVOICE_OVERDUE"     // a dunning reminder was sent for a sales invoice
+	NotificationStockLow        NotificationType = "STOCK_LOW"           // a store's stock of a SKU fell below its reorder point
+	NotificationExcursion       NotificationType = "CONDITION_EXCURSION" // a warehouse condition went outside its threshold
+	NotificationSLABreach       NotificationType = "SLA_BREACH"          // a warehouse breached a fulfillment SLA
+	NotificationImpersonation   NotificationType = "IMPERSONATION"       // an admin started acting as the user
+)
+
+// NotificationTypes lists the notification types
+var NotificationTypes = []NotificationType{NotificationApprovalPending, NotificationInvoiceOverdue, NotificationStockLow, NotificationExcursion, NotificationSLABreach, NotificationImpersonation}
+
+// Document email types. Documents are emailed to vendors, customers and report
+// recipients rather than to users, so they have email templates but no
+// preferences.
+const (
+	DocumentEmailPurchaseOrder NotificationType = "PURCHASE_ORDER_EMAIL" // a purchase order sent to its vendor
+	DocumentEmailInvoice       NotificationType = "INVOICE_EMAIL"        // a sales invoice issued to its customer
+	DocumentEmailReport        NotificationType = "REPORT_EMAIL"         // a scheduled report delivered to its recipients
+)
+
+// DocumentEmailTypes lists the document email types
+var DocumentEmailTypes = []NotificationType{DocumentEmailPurchaseOrder, DocumentEmailInvoice, DocumentEmailReport}
+
+// Notification is a message in a user's in-app inbox
+type Notification struct {
+	ID            uint             `json:"id" gorm:"primaryKey"`
+	UserID        uint             `json:"user_id" gorm:"not null;index"`
+	Type          NotificationType `json:"type" gorm:"type:varchar(30);not null"`
+	Title         string           `json:"title" gorm:"not null"`
+	Body          string           `json:"body" gorm:"type:text"`
+	ReferenceType string           `json:"reference_type,omitempty" gorm:"type:varchar(30)"` // kind of document the notification is about
+	ReferenceID   string           `json:"reference_id,omitempty"`
+	ReadAt        *time.Time       `json:"read_at,omitempty"`
+	CreatedAt     time.Time        `json:"created_at"`
+}
+
+// NotificationPreference turns a channel on or off for a type of notification.
+// Without a preference, in-app and email notifications are sent and SMS are not.
+type NotificationPreference struct {
+	UserID    uint                `json:"-" gorm:"primaryKey"`
+	Type      NotificationType    `json:"type" gorm:"primaryKey;type:varchar(30)"`
+	Channel   NotificationChannel `json:"channel" gorm:"primaryKey;type:varchar(10)"`
+	Enabled   bool                `json:"enabled" gorm:"not null"`
+	UpdatedAt time.Time           `json:"updated_at"`
+}
+
+// DefaultEnabled reports whether a channel is used for a user who has not set a preference
+func (c NotificationChannel) DefaultEnabled() bool {
+	return c != ChannelSMS
+}
+
+// NotificationTemplate overrides the built-in subject and body of a type of
+// notification on a channel. Both may use the placeholders of the notification
+// type, such as {{number}}, written in double braces.
+type NotificationTemplate struct {
+	Type      NotificationType    `json:"type" gorm:"primaryKey;type:varchar(30)"`
+	Channel   NotificationChannel `json:"channel" gorm:"primaryKey;type:varchar(10)"`
+	Subject   string              `json:"subject"` // the inbox title and email subject; unused for SMS
+	Body      string              `json:"body" gorm:"type:text;not null"`
+	Custom    bool                `json:"custom" gorm:"-"` // false for the built-in template
+	UpdatedBy *uint               `json:"updated_by,omitempty"`
+	UpdatedAt time.Time           `json:"updated_at"`
+}
+
+// NotificationEvent is raised by the use cases to notify the users holding a
+// permission, or the given users, through the channels they have enabled
+type NotificationEvent struct {
+	Type          NotificationType
+	Permission    Permission // notify the active users whose role holds it
+	UserIDs       []uint     // notify these users as well
+	ExceptUserIDs []uint     // do not notify these users, e.g. approvers away who delegated their approvals
+	ReferenceType string
+	ReferenceID   string
+	Data          map[string]string // placeholder values for the templates
+}
+
+// NotificationFilter represents filters for listing a user's inbox
+type NotificationFilter struct {
+	UserID   uint             `json:"-"`
+	Type     NotificationType `json:"type,omitempty"`
+	Unread   bool             `json:"unread,omitempty"`
+	Page     int              `json:"page,omitempty"`
+	PageSize int              `json:"page_size,omitempty"`
+}
+
+// NotificationSettings are a user's phone number and channel choices per
+// notification type, defaults included
+type NotificationSettings struct {
+	Phone       string                   `json:"phone"`
+	Preferences []NotificationPreference `json:"preferences"`
+}
+
+// UpdateNotificationSettingsRequest represents the request to change a user's
+// phone number or channel choices. Preferences not listed are left unchanged.
+type UpdateNotificationSettingsRequest struct {
+	Phone       *string                       `json:"phone" binding:"omitempty,e164"`
+	Preferences []NotificationPreferenceInput `json:"preferences" binding:"dive"`
+}
+
+// NotificationPreferenceInput turns a channel on or off for a notification type
+type NotificationPreferenceInput struct {
+	Type    NotificationType    `json:"type" binding:"required,oneof=APPROVAL_PENDING INVOICE_OVERDUE STOCK_LOW"`
+	Channel NotificationChannel `json:"channel" binding:"required,oneof=IN_APP EMAIL SMS"`
+	Enabled bool                `json:"enabled"`
+}
+
+// UpdateNotificationTemplateRequest represents the request to override a
+// notification template
+type UpdateNotificationTemplateRequest struct {
+	Subject string `json:"subject"`
+	Body    string `json:"body" binding:"required"`
+}
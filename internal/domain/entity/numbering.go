@@ -0,0 +1,191 @@
+package entity
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DocumentType names a kind of document numbered by a sequence
+type DocumentType string
+
+const (
+	DocumentSalesOrder             DocumentType = "sales_order"
+	DocumentDeliveryOrder          DocumentType = "delivery_order"
+	DocumentInvoice                DocumentType = "invoice"
+	DocumentPurchaseRequest        DocumentType = "purchase_request"
+	DocumentPurchaseOrder          DocumentType = "purchase_order"
+	DocumentPurchaseReceipt        DocumentType = "purchase_receipt"
+	DocumentPurchasePayment        DocumentType = "purchase_payment"
+	DocumentFinanceSalesInvoice    DocumentType = "finance_sales_invoice"
+	DocumentFinancePurchaseInvoice DocumentType = "finance_purchase_invoice"
+	DocumentFinancePayment         DocumentType = "finance_payment"
+	DocumentExpenseClaim           DocumentType = "expense_claim"
+	DocumentPrepayment             DocumentType = "prepayment"
+)
+
+// NumberingReset tells when the counter of a numbering scheme starts over
+type NumberingReset string
+
+const (
+	NumberingResetNever      NumberingReset = "NEVER"
+	NumberingResetFiscalYear NumberingReset = "FISCAL_YEAR"
+)
+
+// DefaultNumberingPattern is the pattern of schemes that do not set one
+const DefaultNumberingPattern = "{prefix}-{yyyy}{mm}{dd}-{seq}"
+
+// DefaultNumberingSchemes number the documents of a type without a scheme
+// configured for it
+var DefaultNumberingSchemes = map[DocumentType]NumberingScheme{
+	DocumentSalesOrder:             {Prefix: "SO"},
+	DocumentDeliveryOrder:          {Prefix: "DO"},
+	DocumentInvoice:                {Prefix: "INV"},
+	DocumentPurchaseRequest:        {Prefix: "PR"},
+	DocumentPurchaseOrder:          {Prefix: "PO"},
+	DocumentPurchaseReceipt:        {Prefix: "GRN"},
+	DocumentPurchasePayment:        {Prefix: "PAY"},
+	DocumentFinanceSalesInvoice:    {Prefix: "SINV"},
+	DocumentFinancePurchaseInvoice: {Prefix: "PINV"},
+	DocumentFinancePayment:         {Prefix: "FPAY"},
+	DocumentExpenseClaim:           {Prefix: "EXP"},
+	DocumentPrepayment:             {Prefix: "DEP"},
+}
+
+// NumberingScheme configures the numbers given to the documents of a type,
+// optionally only to those of one store (warehouse). The pattern holds the
+// placeholders {prefix}, {store} (the code of the scheme's store), {yyyy},
+// {yy}, {mm}, {dd}, {fy} (the year the fiscal year starts in) and {seq}, the
+// counter padded with zeros to Padding digits.
+type NumberingScheme struct {
+	ID                   uint           `json:"id" gorm:"primaryKey"`
+	DocumentType         DocumentType   `json:"document_type" gorm:"type:varchar(50);not null;uniqueIndex:idx_numbering_schemes_document_store"`
+	StoreID              string         `json:"store_id,omitempty" gorm:"type:varchar(64);not null;default:'';uniqueIndex:idx_numbering_schemes_document_store"` // empty for every store without a scheme of its own
+	StoreCode            string         `json:"store_code,omitempty" gorm:"type:varchar(50);not null;default:''"`                                                // code of the store when the scheme was saved
+	Prefix               string         `json:"prefix" gorm:"type:varchar(20);not null"`
+	Pattern              string         `json:"pattern" gorm:"type:varchar(100);not null"`
+	Padding              int            `json:"padding" gorm:"not null"`
+	Reset                NumberingReset `json:"reset" gorm:"type:varchar(20);not null"`
+	FiscalYearStartMonth int            `json:"fiscal_year_start_month" gorm:"not null"` // 1 to 12
+	CreatedAt            time.Time      `json:"created_at"`
+	UpdatedAt            time.Time      `json:"updated_at"`
+}
+
+// ApplyDefaults fills the settings left empty
+func (s *NumberingScheme) ApplyDefaults() {
+	if s.Pattern == "" {
+		s.Pattern = DefaultNumberingPattern
+	}
+	if s.Padding == 0 {
+		s.Padding = 6
+	}
+	if s.Reset == "" {
+		s.Reset = NumberingResetNever
+	}
+	if s.FiscalYearStartMonth == 0 {
+		s.FiscalYearStartMonth = 1
+	}
+}
+
+// Validate checks the settings of the scheme
+func (s *NumberingScheme) Validate() error {
+	if _, ok := DefaultNumberingSchemes[s.DocumentType]; !ok {
+		return NewError(ErrCodeInvalidArgument, fmt.Sprintf("unknown document type %q", s.DocumentType))
+	}
+	if !strings.Contains(s.Pattern, "{seq}") {
+		return NewError(ErrCodeInvalidArgument, "the pattern must hold {seq}")
+	}
+	if s.StoreID == "" && strings.Contains(s.Pattern, "{store}") {
+		return NewError(ErrCodeInvalidArgument, "only the scheme of a store can hold {store}")
+	}
+	if s.Reset == NumberingResetFiscalYear && !s.holdsFiscalYear() {
+		return NewError(ErrCodeInvalidArgument, "a pattern reset every fiscal year must hold {fy}, or {yyyy} or {yy} when the fiscal year starts in January")
+	}
+	if s.Padding < 1 || s.Padding > 12 {
+		return NewError(ErrCodeInvalidArgument, "padding must be between 1 and 12")
+	}
+	if s.Reset != NumberingResetNever && s.Reset != NumberingResetFiscalYear {
+		return NewError(ErrCodeInvalidArgument, fmt.Sprintf("unknown reset %q", s.Reset))
+	}
+	if s.FiscalYearStartMonth < 1 || s.FiscalYearStartMonth > 12 {
+		return NewError(ErrCodeInvalidArgument, "fiscal year start month must be between 1 and 12")
+	}
+	return nil
+}
+
+// holdsFiscalYear tells whether the pattern renders the fiscal year, so that
+// numbers drawn again from 1 after a reset differ from those of the year before
+func (s *NumberingScheme) holdsFiscalYear() bool {
+	if strings.Contains(s.Pattern, "{fy}") {
+		return true
+	}
+	return s.FiscalYearStartMonth == 1 && (strings.Contains(s.Pattern, "{yyyy}") || strings.Contains(s.Pattern, "{yy}"))
+}
+
+// Overlaps tells whether the scheme may give a number another scheme of the
+// same document type gives too, as the schemes count apart. Schemes differ
+// by the {store} of one of them, or by prefixes of their own both render.
+func (s *NumberingScheme) Overlaps(other *NumberingScheme) bool {
+	if strings.Contains(s.Pattern, "{store}") || strings.Contains(other.Pattern, "{store}") {
+		return false
+	}
+	if !strings.Contains(s.Pattern, "{prefix}") || !strings.Contains(other.Pattern, "{prefix}") {
+		return true
+	}
+	return s.Prefix == other.Prefix
+}
+
+// FiscalYear returns the year the fiscal year of a date starts in
+func (s *NumberingScheme) FiscalYear(at time.Time) int {
+	if int(at.Month()) < s.FiscalYearStartMonth {
+		return at.Year() - 1
+	}
+	return at.Year()
+}
+
+// CounterKey names the counter the numbers of the scheme are drawn from at a
+// date. Schemes of a store count apart from the others, and schemes reset
+// every fiscal year count apart per year.
+func (s *NumberingScheme) CounterKey(at time.Time) string {
+	key := string(s.DocumentType)
+	if s.StoreID != "" {
+		key += ":" + s.StoreID
+	}
+	if s.Reset == NumberingResetFiscalYear {
+		key += fmt.Sprintf(":FY%d", s.FiscalYear(at))
+	}
+	return key
+}
+
+// Format renders the number of a document from the scheme, its date and its
+// counter value
+func (s *NumberingScheme) Format(at time.Time, seq uint) string {
+	return strings.NewReplacer(
+		"{prefix}", s.Prefix,
+		"{store}", s.StoreCode,
+		"{yyyy}", at.Format("2006"),
+		"{yy}", at.Format("06"),
+		"{mm}", at.Format("01"),
+		"{dd}", at.Format("02"),
+		"{fy}", fmt.Sprint(s.FiscalYear(at)),
+		"{seq}", fmt.Sprintf("%0*d", s.Padding, seq),
+	).Replace(s.Pattern)
+}
+
+// NumberingSchemeRequest represents the request to configure the numbering of
+// a document type, or of its documents of one store
+type NumberingSchemeRequest struct {
+	DocumentType         DocumentType   `json:"document_type" binding:"required"`
+	StoreID              string         `json:"store_id"`
+	Prefix               string         `json:"prefix" binding:"max=20"`
+	Pattern              string         `json:"pattern" binding:"max=100"`
+	Padding              int            `json:"padding"`
+	Reset                NumberingReset `json:"reset"`
+	FiscalYearStartMonth int            `json:"fiscal_year_start_month"`
+}
+
+// NumberPreview is the number the next document of a scheme would be given
+type NumberPreview struct {
+	Scheme NumberingScheme `json:"scheme"`
+	Next   string          `json:"next"`
+}
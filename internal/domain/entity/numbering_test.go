@@ -0,0 +1,91 @@
+package entity
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNumberingSchemeValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		scheme  NumberingScheme
+		wantErr bool
+	}{
+		{"default", NumberingScheme{DocumentType: DocumentDeliveryOrder}, false},
+		{"store placeholder of a store", NumberingScheme{DocumentType: DocumentDeliveryOrder, StoreID: "s1", Pattern: "{prefix}-{store}-{seq}"}, false},
+		{"store placeholder of every store", NumberingScheme{DocumentType: DocumentDeliveryOrder, Pattern: "{prefix}-{store}-{seq}"}, true},
+		{"yearly reset with fiscal year", NumberingScheme{DocumentType: DocumentInvoice, Pattern: "{prefix}/{fy}/{seq}", Reset: NumberingResetFiscalYear, FiscalYearStartMonth: 4}, false},
+		{"yearly reset with calendar year from January", NumberingScheme{DocumentType: DocumentInvoice, Pattern: "{prefix}/{yy}/{seq}", Reset: NumberingResetFiscalYear}, false},
+		{"yearly reset with calendar year from April", NumberingScheme{DocumentType: DocumentInvoice, Pattern: "{prefix}/{yyyy}/{seq}", Reset: NumberingResetFiscalYear, FiscalYearStartMonth: 4}, true},
+		{"yearly reset without year", NumberingScheme{DocumentType: DocumentInvoice, Pattern: "{prefix}-{mm}-{seq}", Reset: NumberingResetFiscalYear}, true},
+		{"no counter", NumberingScheme{DocumentType: DocumentInvoice, Pattern: "{prefix}-{yyyy}"}, true},
+		{"unknown document type", NumberingScheme{DocumentType: "memo"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheme := tt.scheme
+			scheme.ApplyDefaults()
+			if err := scheme.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNumberingSchemeOverlaps(t *testing.T) {
+	typeScheme := NumberingScheme{DocumentType: DocumentDeliveryOrder, Prefix: "DO"}
+	typeScheme.ApplyDefaults()
+
+	tests := []struct {
+		name   string
+		scheme NumberingScheme
+		want   bool
+	}{
+		{"same prefix", NumberingScheme{StoreID: "s1", Prefix: "DO", Pattern: DefaultNumberingPattern}, true},
+		{"prefix of its own", NumberingScheme{StoreID: "s1", Prefix: "DOH", Pattern: DefaultNumberingPattern}, false},
+		{"prefix not rendered", NumberingScheme{StoreID: "s1", Prefix: "DOH", Pattern: "{yyyy}-{seq}"}, true},
+		{"store rendered", NumberingScheme{StoreID: "s1", StoreCode: "HAN", Prefix: "DO", Pattern: "{prefix}-{store}-{seq}"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.scheme.Overlaps(&typeScheme); got != tt.want {
+				t.Errorf("Overlaps() = %v, want %v", got, tt.want)
+			}
+			if got := typeScheme.Overlaps(&tt.scheme); got != tt.want {
+				t.Errorf("Overlaps() reversed = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNumberingSchemeFormat(t *testing.T) {
+	at := time.Date(2027, time.February, 3, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		scheme NumberingScheme
+		want   string
+	}{
+		{"default", NumberingScheme{Prefix: "DO"}, "DO-20270203-000042"},
+		{"store", NumberingScheme{Prefix: "DO", StoreID: "s1", StoreCode: "HAN", Pattern: "{prefix}-{store}-{seq}", Padding: 4}, "DO-HAN-0042"},
+		{"fiscal year from April", NumberingScheme{Prefix: "INV", Pattern: "{prefix}/{fy}/{seq}", FiscalYearStartMonth: 4}, "INV/2026/000042"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheme := tt.scheme
+			scheme.ApplyDefaults()
+			if got := scheme.Format(at, 42); got != tt.want {
+				t.Errorf("Format() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNumberingSchemeCounterKey(t *testing.T) {
+	scheme := NumberingScheme{DocumentType: DocumentInvoice, StoreID: "s1", Reset: NumberingResetFiscalYear, FiscalYearStartMonth: 4}
+	if got, want := scheme.CounterKey(time.Date(2027, time.March, 31, 0, 0, 0, 0, time.UTC)), "invoice:s1:FY2026"; got != want {
+		t.Errorf("CounterKey() = %q, want %q", got, want)
+	}
+	if got, want := scheme.CounterKey(time.Date(2027, time.April, 1, 0, 0, 0, 0, time.UTC)), "invoice:s1:FY2027"; got != want {
+		t.Errorf("CounterKey() = %q, want %q", got, want)
+	}
+}
@@ -56,15 +56,21 @@ const (
 
 // SalesOrderItem represents an item in a sales order
 type SalesOrderItem struct {
-	SKUID       string  `json:"sku_id" gorm:"not null"`
-	Quantity    float64 `json:"quantity" gorm:"not null"`
-	UnitPrice   float64 `json:"unit_price" gorm:"type:decimal(15,2);not null"`
-	Discount    float64 `json:"discount" gorm:"type:decimal(15,2);default:0"`
-	TaxRate     float64 `json:"tax_rate" gorm:"type:decimal(5,2);default:0"`
-	TaxAmount   float64 `json:"tax_amount" gorm:"type:decimal(15,2);default:0"`
-	TotalPrice  float64 `json:"total_price" gorm:"type:decimal(15,2);not null"`
-	Description string  `json:"description"`
-	SKU         *SKU    `json:"sku,omitempty" gorm:"foreignKey:SKUID"`
+	SKUID           string                `json:"sku_id" gorm:"not null"`
+	Quantity        float64               `json:"quantity" gorm:"not null"`
+	UnitPrice       float64               `json:"unit_price" gorm:"type:decimal(15,2);not null"`
+	Discount        float64               `json:"discount" gorm:"type:decimal(15,2);default:0"`
+	TaxRate         float64               `json:"tax_rate" gorm:"type:decimal(5,2);default:0"`
+	TaxAmount       float64               `json:"tax_amount" gorm:"type:decimal(15,2);default:0"`
+	TotalPrice      float64               `json:"total_price" gorm:"type:decimal(15,2);not null"`
+	Description     string                `json:"description"`
+	Components      []SalesOrderComponent `json:"components,omitempty"`        // quantities of its components a kit line takes, recorded when the order is placed
+	DropShip        bool                  `json:"drop_ship,omitempty"`         // shipped to the customer by a vendor under a purchase order generated for it, bypassing the warehouses
+	VendorID        *uint                 `json:"vendor_id,omitempty"`         // vendor shipping a drop-ship line, the SKU's vendor by default
+	PurchasePrice   float64               `json:"purchase_price,omitempty"`    // unit price paid to the vendor of a drop-ship line
+	PurchaseOrderID string                `json:"purchase_order_id,omitempty"` // purchase order generated for a drop-ship line
+	SKU             *SKU                  `json:"sku,omitempty" gorm:"foreignKey:SKUID"`
+	CustomsData                           // customs classification of the SKU when the order was placed
 }
 
 // Scan implements the sql.Scanner interface for SalesOrderItems
@@ -102,17 +108,23 @@ type SalesOrder struct {
 	OrderNumber     string           `json:"order_number" gorm:"uniqueIndex;not null"`
 	ClientID        uint             `json:"client_id" gorm:"not null"`
 	OrderDate       time.Time        `json:"order_date" gorm:"not null"`
+	PromisedDate    *time.Time       `json:"promised_date,omitempty" gorm:"type:date"` // order date plus the promise days on the warehouse calendar
 	Items           SalesOrderItems  `json:"items" gorm:"type:jsonb;not null"`
 	SubTotal        float64          `json:"sub_total" gorm:"type:decimal(15,2);not null"`
 	TaxTotal        float64          `json:"tax_total" gorm:"type:decimal(15,2);default:0"`
 	DiscountTotal   float64          `json:"discount_total" gorm:"type:decimal(15,2);default:0"`
 	GrandTotal      float64          `json:"grand_total" gorm:"type:decimal(15,2);not null"`
+	CurrencyCode    string           `json:"currency_code" gorm:"default:'USD'"`
+	ExchangeRate    float64          `json:"exchange_rate" gorm:"type:decimal(18,8);default:1"`
+	BaseGrandTotal  float64          `json:"base_grand_total" gorm:"type:decimal(15,2);default:0"`
 	Status          SalesOrderStatus `json:"status" gorm:"not null;default:'DRAFT'"`
 	PaymentMethod   PaymentMethod    `json:"payment_method"`
 	PaymentStatus   PaymentStatus    `json:"payment_status" gorm:"not null;default:'PENDING'"`
 	ShippingAddress string           `json:"shipping_address" gorm:"type:text"`
 	BillingAddress  string           `json:"billing_address" gorm:"type:text"`
 	Notes           string           `json:"notes" gorm:"type:text"`
+	ExternalRef     *string          `json:"external_ref,omitempty" gorm:"type:varchar(150)"` // source and reference of orders ingested from external systems
+	CustomFields    JSONMap          `json:"custom_fields" gorm:"type:jsonb"`                 // values of the custom fields defined on sales orders, by key
 	CreatedByID     uint             `json:"created_by_id" gorm:"not null"`
 	CreatedAt       time.Time        `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt       time.Time        `json:"updated_at" gorm:"autoUpdateTime"`
@@ -129,6 +141,7 @@ type DeliveryOrderItem struct {
 	ShippedQuantity   float64 `json:"shipped_quantity" gorm:"not null"`
 	RemainingQuantity float64 `json:"remaining_quantity" gorm:"default:0"`
 	Notes             string  `json:"notes"`
+	KitSKUID          string  `json:"kit_sku_id,omitempty"` // kit the item is a component of, for the components of kit lines
 	SKU               *SKU    `json:"sku,omitempty" gorm:"foreignKey:SKUID"`
 }
 
@@ -163,41 +176,52 @@ type DeliveryOrderItems []DeliveryOrderItem
 
 // DeliveryOrder represents a delivery of goods from a sales order
 type DeliveryOrder struct {
-	ID              string              `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
-	DeliveryNumber  string              `json:"delivery_number" gorm:"uniqueIndex;not null"`
-	SalesOrderID    string              `json:"sales_order_id" gorm:"type:uuid;not null"`
-	DeliveryDate    time.Time           `json:"delivery_date" gorm:"not null"`
-	Items           DeliveryOrderItems  `json:"items" gorm:"type:jsonb;not null"`
-	ShippingAddress string              `json:"shipping_address" gorm:"type:text;not null"`
-	Status          DeliveryOrderStatus `json:"status" gorm:"not null;default:'PENDING'"`
-	TrackingNumber  string              `json:"tracking_number"`
-	ShippingMethod  string              `json:"shipping_method"`
-	StoreID         string              `json:"store_id" gorm:"not null"`
-	Notes           string              `json:"notes" gorm:"type:text"`
-	CreatedByID     uint                `json:"created_by_id" gorm:"not null"`
-	CreatedAt       time.Time           `json:"created_at" gorm:"autoCreateTime"`
-	UpdatedAt       time.Time           `json:"updated_at" gorm:"autoUpdateTime"`
-	SalesOrder      *SalesOrder         `json:"sales_order,omitempty" gorm:"foreignKey:SalesOrderID"`
-	CreatedBy       *User               `json:"created_by,omitempty" gorm:"foreignKey:CreatedByID"`
+	ID                 string              `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	DeliveryNumber     string              `json:"delivery_number" gorm:"uniqueIndex;not null"`
+	SalesOrderID       string              `json:"sales_order_id" gorm:"type:uuid;not null"`
+	DeliveryDate       time.Time           `json:"delivery_date" gorm:"not null"`
+	ShippedAt          *time.Time          `json:"shipped_at,omitempty"` // when its stock left the store, or the vendor shipped it for drop-ship deliveries
+	Items              DeliveryOrderItems  `json:"items" gorm:"type:jsonb;not null"`
+	ShippingAddress    string              `json:"shipping_address" gorm:"type:text;not null"`
+	Status             DeliveryOrderStatus `json:"status" gorm:"not null;default:'PENDING'"`
+	TrackingNumber     string              `json:"tracking_number"`
+	ShippingMethod     string              `json:"shipping_method"`
+	FreightCost        float64             `json:"freight_cost" gorm:"type:decimal(15,2);default:0"` // carrier charge in the base currency
+	StoreID            string              `json:"store_id" gorm:"not null"`
+	PurchaseOrderID    *string             `json:"purchase_order_id,omitempty" gorm:"type:uuid;index"`     // drop-ship purchase order a vendor shipped the delivery under
+	CrossDockReceiptID *string             `json:"cross_dock_receipt_id,omitempty" gorm:"type:uuid;index"` // purchase receipt the delivery was shipped from without putaway
+	Notes              string              `json:"notes" gorm:"type:text"`
+	CreatedByID        uint                `json:"created_by_id" gorm:"not null"`
+	CreatedAt          time.Time           `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt          time.Time           `json:"updated_at" gorm:"autoUpdateTime"`
+	SalesOrder         *SalesOrder         `json:"sales_order,omitempty" gorm:"foreignKey:SalesOrderID"`
+	CreatedBy          *User               `json:"created_by,omitempty" gorm:"foreignKey:CreatedByID"`
 }
 
 // Invoice represents an invoice for a sales order
 type Invoice struct {
-	ID            string        `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
-	InvoiceNumber string        `json:"invoice_number" gorm:"uniqueIndex;not null"`
-	SalesOrderID  string        `json:"sales_order_id" gorm:"type:uuid;not null"`
-	IssueDate     time.Time     `json:"issue_date" gorm:"not null"`
-	DueDate       time.Time     `json:"due_date" gorm:"not null"`
-	Amount        float64       `json:"amount" gorm:"type:decimal(15,2);not null"`
-	TaxAmount     float64       `json:"tax_amount" gorm:"type:decimal(15,2);default:0"`
-	TotalAmount   float64       `json:"total_amount" gorm:"type:decimal(15,2);not null"`
-	Status        InvoiceStatus `json:"status" gorm:"not null;default:'DRAFT'"`
-	Notes         string        `json:"notes" gorm:"type:text"`
-	CreatedByID   uint          `json:"created_by_id" gorm:"not null"`
-	CreatedAt     time.Time     `json:"created_at" gorm:"autoCreateTime"`
-	UpdatedAt     time.Time     `json:"updated_at" gorm:"autoUpdateTime"`
-	SalesOrder    *SalesOrder   `json:"sales_order,omitempty" gorm:"foreignKey:SalesOrderID"`
-	CreatedBy     *User         `json:"created_by,omitempty" gorm:"foreignKey:CreatedByID"`
+	ID              string        `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	InvoiceNumber   string        `json:"invoice_number" gorm:"uniqueIndex;not null"`
+	SalesOrderID    string        `json:"sales_order_id" gorm:"type:uuid;not null"`
+	IssueDate       time.Time     `json:"issue_date" gorm:"not null"`
+	DueDate         time.Time     `json:"due_date" gorm:"not null"`
+	Amount          float64       `json:"amount" gorm:"type:decimal(15,2);not null"`
+	TaxAmount       float64       `json:"tax_amount" gorm:"type:decimal(15,2);default:0"`
+	TotalAmount     float64       `json:"total_amount" gorm:"type:decimal(15,2);not null"`
+	CurrencyCode    string        `json:"currency_code" gorm:"default:'USD'"`
+	ExchangeRate    float64       `json:"exchange_rate" gorm:"type:decimal(18,8);default:1"`
+	BaseTotalAmount float64       `json:"base_total_amount" gorm:"type:decimal(15,2);default:0"`
+	Status          InvoiceStatus `json:"status" gorm:"not null;default:'DRAFT'"`
+	Notes           string        `json:"notes" gorm:"type:text"`
+	CreatedByID     uint          `json:"created_by_id" gorm:"not null"`
+	CreatedAt       time.Time     `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt       time.Time     `json:"updated_at" gorm:"autoUpdateTime"`
+	SalesOrder      *SalesOrder   `json:"sales_order,omitempty" gorm:"foreignKey:SalesOrderID"`
+	CreatedBy       *User         `json:"created_by,omitempty" gorm:"foreignKey:CreatedByID"`
+
+	// Deposits of the order applied to the invoice and what remains to be paid
+	PrepaidAmount float64 `json:"prepaid_amount" gorm:"type:decimal(15,2);not null;default:0"`
+	AmountDue     float64 `json:"amount_due" gorm:"type:decimal(15,2);not null;default:0"`
 }
 
 // SalesOrderFilter represents filters for searching sales orders
@@ -209,12 +233,15 @@ type SalesOrderFilter struct {
 	StartDate     *time.Time        `json:"start_date,omitempty"`
 	EndDate       *time.Time        `json:"end_date,omitempty"`
 	SKUID         string            `json:"sku_id,omitempty"`
+	Archived      bool              `json:"archived,omitempty"`      // query the archive table instead of the live one
+	CustomFields  JSONMap           `json:"custom_fields,omitempty"` // custom field values the orders hold
 }
 
 // DeliveryOrderFilter represents filters for searching delivery orders
 type DeliveryOrderFilter struct {
 	DeliveryNumber string               `json:"delivery_number,omitempty"`
 	SalesOrderID   string               `json:"sales_order_id,omitempty"`
+	ClientID       *uint                `json:"client_id,omitempty"`
 	Status         *DeliveryOrderStatus `json:"status,omitempty"`
 	StartDate      *time.Time           `json:"start_date,omitempty"`
 	EndDate        *time.Time           `json:"end_date,omitempty"`
@@ -225,7 +252,24 @@ type DeliveryOrderFilter struct {
 type InvoiceFilter struct {
 	InvoiceNumber string         `json:"invoice_number,omitempty"`
 	SalesOrderID  string         `json:"sales_order_id,omitempty"`
+	ClientID      *uint          `json:"client_id,omitempty"`
 	Status        *InvoiceStatus `json:"status,omitempty"`
 	StartDate     *time.Time     `json:"start_date,omitempty"`
 	EndDate       *time.Time     `json:"end_date,omitempty"`
+	Archived      bool           `json:"archived,omitempty"` // query the archive table instead of the live one
+}
+
+// ExternalSalesOrder is a sales order an external system, such as a web shop,
+// hands in through the message broker
+type ExternalSalesOrder struct {
+	Source          string           `json:"source"`    // system the order comes from
+	Reference       string           `json:"reference"` // order number in the source system
+	ClientID        uint             `json:"client_id"`
+	StoreID         string           `json:"store_id"` // store the order is fulfilled from
+	Items           []SalesOrderItem `json:"items"`
+	CurrencyCode    string           `json:"currency_code,omitempty"`
+	PaymentMethod   PaymentMethod    `json:"payment_method,omitempty"`
+	ShippingAddress string           `json:"shipping_address,omitempty"`
+	BillingAddress  string           `json:"billing_address,omitempty"`
+	Notes           string           `json:"notes,omitempty"`
 }
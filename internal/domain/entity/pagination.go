@@ -0,0 +1,56 @@
+package entity
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"time"
+)
+
+const (
+	DefaultCursorLimit = 50  // rows of a cursor page when no limit is asked for
+	MaxCursorLimit     = 500 // largest cursor page
+)
+
+var ErrInvalidCursor = NewError(ErrCodeInvalidArgument, "invalid cursor")
+
+// Cursor marks the last row of a page of a listing ordered latest first. Rows
+// are ordered by creation time and then ID, so a cursor keeps its position
+// while rows are added, unlike a page number.
+type Cursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        string    `json:"id"`
+}
+
+// CursorPage asks for the rows following a cursor
+type CursorPage struct {
+	After *Cursor // the first page when nil
+	Limit int
+}
+
+// Encode returns the cursor as an opaque token for the next_cursor of a response
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor reads a token returned by Cursor.Encode
+func DecodeCursor(token string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor Cursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID == "" || cursor.CreatedAt.IsZero() {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
+
+// NextCursorToken returns the token of the next page's cursor, empty on the
+// last page
+func NextCursorToken(next *Cursor) string {
+	if next == nil {
+		return ""
+	}
+	return next.Encode()
+}
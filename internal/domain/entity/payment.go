@@ -39,12 +39,20 @@ type FinancePayment struct {
 	PaymentDate     time.Time            `json:"payment_date" db:"payment_date"`
 	PaymentMethod   FinancePaymentMethod `json:"payment_method" db:"payment_method"`
 	Amount          float64              `json:"amount" db:"amount"`
+	CurrencyCode    string               `json:"currency_code" db:"currency_code"`
+	ExchangeRate    float64              `json:"exchange_rate" db:"exchange_rate"`
+	BaseAmount      float64              `json:"base_amount" db:"base_amount"`
 	Status          FinancePaymentStatus `json:"status" db:"status"`
 	Notes           string               `json:"notes" db:"notes"`
 	ReferenceNumber string               `json:"reference_number" db:"reference_number"`
 	CreatedBy       int64                `json:"created_by" db:"created_by"`
 	CreatedAt       time.Time            `json:"created_at" db:"created_at"`
 	UpdatedAt       time.Time            `json:"updated_at" db:"updated_at"`
+
+	// Amount is what the payment settles of the invoice: NetAmount paid to
+	// the supplier plus WithheldAmount kept for the tax authority
+	WithheldAmount float64 `json:"withheld_amount" db:"withheld_amount"`
+	NetAmount      float64 `json:"net_amount" db:"net_amount"`
 }
 
 // FinancePaymentFilter represents filters for querying payments
@@ -0,0 +1,65 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// PaymentGatewayEventType is the kind of a payment gateway event, as normalized by
+// the provider adapter
+type PaymentGatewayEventType string
+
+const (
+	PaymentGatewayEventSucceeded PaymentGatewayEventType = "payment.succeeded"
+	PaymentGatewayEventRefunded  PaymentGatewayEventType = "payment.refunded"
+)
+
+// PaymentGatewayEvent is a verified payment event received from a payment gateway.
+// PaymentReference is the gateway's ID of the payment and becomes the reference
+// number of the finance payment it settles.
+type PaymentGatewayEvent struct {
+	ID               string                  `json:"id"`
+	Type             PaymentGatewayEventType `json:"type"`
+	PaymentReference string                  `json:"payment_reference"`
+	InvoiceID        int64                   `json:"invoice_id,omitempty"`
+	InvoiceNumber    string                  `json:"invoice_number,omitempty"`
+	Amount           float64                 `json:"amount"`
+	CurrencyCode     string                  `json:"currency_code"`
+	OccurredAt       time.Time               `json:"occurred_at"`
+}
+
+// PaymentWebhookStatus represents the processing outcome of a webhook event
+type PaymentWebhookStatus string
+
+const (
+	PaymentWebhookReceived  PaymentWebhookStatus = "RECEIVED"  // being processed
+	PaymentWebhookProcessed PaymentWebhookStatus = "PROCESSED" // applied to the finance payment
+	PaymentWebhookIgnored   PaymentWebhookStatus = "IGNORED"   // event type not handled
+	PaymentWebhookFailed    PaymentWebhookStatus = "FAILED"    // processed again when redelivered
+)
+
+// PaymentWebhookEvent records a webhook event received from a payment gateway. The
+// provider and event ID are its idempotency key, so redelivered events are
+// applied once.
+type PaymentWebhookEvent struct {
+	ID               uint                 `json:"id" gorm:"primaryKey"`
+	Provider         string               `json:"provider" gorm:"not null;uniqueIndex:idx_payment_webhook_events_provider_event"`
+	EventID          string               `json:"event_id" gorm:"not null;uniqueIndex:idx_payment_webhook_events_provider_event"`
+	EventType        string               `json:"event_type"`
+	PaymentReference string               `json:"payment_reference" gorm:"index"`
+	InvoiceID        *int64               `json:"invoice_id,omitempty"`
+	PaymentID        *int64               `json:"payment_id,omitempty"`
+	Status           PaymentWebhookStatus `json:"status" gorm:"type:varchar(20);not null;index"`
+	Error            string               `json:"error,omitempty" gorm:"type:text"`
+	Payload          json.RawMessage      `json:"-" gorm:"type:jsonb"` // the verified PaymentGatewayEvent, for failed events to be replayed
+	ReceivedAt       time.Time            `json:"received_at" gorm:"not null"`
+	ProcessedAt      *time.Time           `json:"processed_at,omitempty"`
+}
+
+// PaymentWebhookEventFilter represents filters for listing webhook events
+type PaymentWebhookEventFilter struct {
+	Provider string               `json:"provider,omitempty"`
+	Status   PaymentWebhookStatus `json:"status,omitempty"`
+	Page     int                  `json:"page,omitempty"`
+	PageSize int                  `json:"page_size,omitempty"`
+}
@@ -9,6 +9,9 @@ const (
 	UserRead   Permission = "user:read"
 	UserUpdate Permission = "user:update"
 	UserDelete Permission = "user:delete"
+
+	UserProvision   Permission = "user:provision"
+	UserImpersonate Permission = "user:impersonate" // act as another user for troubleshooting; cannot be granted through elevated access
 )
 
 // Role permissions
@@ -34,6 +37,24 @@ const (
 
 	StockEntryCreate Permission = "stock:entry:create"
 	StockEntryRead   Permission = "stock:entry:read"
+
+	StockProvisionRead   Permission = "stock:provision:read"
+	StockProvisionManage Permission = "stock:provision:manage"
+
+	StockConsignmentRead   Permission = "stock:consignment:read"
+	StockConsignmentManage Permission = "stock:consignment:manage"
+
+	StockPutawayRead    Permission = "stock:putaway:read"
+	StockPutawayConfirm Permission = "stock:putaway:confirm"
+	StockPutawayManage  Permission = "stock:putaway:manage"
+
+	StockInboundRead     Permission = "stock:inbound:read"
+	StockInboundSchedule Permission = "stock:inbound:schedule"
+	StockInboundManage   Permission = "stock:inbound:manage"
+
+	StockClassRun Permission = "stock:class:run"
+
+	StockSnapshotRun Permission = "stock:snapshot:run"
 )
 
 // Vendor permissions
@@ -43,6 +64,9 @@ const (
 	VendorUpdate Permission = "vendor:update"
 	VendorDelete Permission = "vendor:delete"
 
+	VendorRiskRead Permission = "vendor:risk:read"
+	VendorRiskRun  Permission = "vendor:risk:run"
+
 	ProductCreate Permission = "product:create"
 	ProductRead   Permission = "product:read"
 	ProductUpdate Permission = "product:update"
@@ -75,6 +99,20 @@ const (
 	BOMDelete Permission = "manufacturing:bom:delete"
 )
 
+// Quality control permissions
+const (
+	QualityPlanManage       Permission = "quality:plan:manage"
+	QualityInspectionRead   Permission = "quality:inspection:read"
+	QualityInspectionRecord Permission = "quality:inspection:record"
+)
+
+// Warehouse condition permissions
+const (
+	ConditionRead   Permission = "condition:read"
+	ConditionRecord Permission = "condition:record"
+	ConditionManage Permission = "condition:manage"
+)
+
 // Purchase permissions
 const (
 	PurchaseRequestCreate  Permission = "purchase:request:create"
@@ -96,6 +134,10 @@ const (
 	PurchasePaymentCreate Permission = "purchase:payment:create"
 	PurchasePaymentRead   Permission = "purchase:payment:read"
 	PurchasePaymentUpdate Permission = "purchase:payment:update"
+
+	PurchaseEDIRead     Permission = "purchase:edi:read"
+	PurchaseEDIManage   Permission = "purchase:edi:manage"
+	PurchaseEDIExchange Permission = "purchase:edi:exchange"
 )
 
 // Client permissions
@@ -115,6 +157,13 @@ const (
 
 	ClientLoyaltyRead   Permission = "client:loyalty:read"
 	ClientLoyaltyUpdate Permission = "client:loyalty:update"
+
+	ClientRFMRead Permission = "client:rfm:read"
+	ClientRFMRun  Permission = "client:rfm:run"
+
+	ClientPortalTokenIssue Permission = "client:portal:token:issue"
+
+	ClientPrivacyManage Permission = "client:privacy:manage"
 )
 
 // Sales Order permissions
@@ -126,6 +175,14 @@ const (
 	SalesOrderConfirm Permission = "sales:order:confirm"
 	SalesOrderCancel  Permission = "sales:order:cancel"
 
+	SalesOrderAllocate Permission = "sales:order:allocate"
+
+	SalesFulfillmentManage Permission = "sales:fulfillment:manage" // run the fulfillment SLA check and acknowledge its alerts
+
+	SalesChannelRead   Permission = "sales:channel:read"
+	SalesChannelManage Permission = "sales:channel:manage"
+	SalesChannelSync   Permission = "sales:channel:sync"
+
 	DeliveryOrderCreate  Permission = "delivery:order:create"
 	DeliveryOrderRead    Permission = "delivery:order:read"
 	DeliveryOrderUpdate  Permission = "delivery:order:update"
@@ -151,6 +208,39 @@ const (
 	FinancePaymentProcess Permission = "finance:payment:process"
 
 	FinanceReportRead Permission = "finance:report:read"
+
+	FinanceCurrencyRead   Permission = "finance:currency:read"
+	FinanceCurrencyManage Permission = "finance:currency:manage"
+
+	FinanceAssetRead   Permission = "finance:asset:read"
+	FinanceAssetManage Permission = "finance:asset:manage"
+
+	FinanceDunningRead   Permission = "finance:dunning:read"
+	FinanceDunningManage Permission = "finance:dunning:manage"
+
+	FinanceExportRead Permission = "finance:export:read"
+	FinanceExportRun  Permission = "finance:export:run"
+
+	FinanceBankRead      Permission = "finance:bank:read"
+	FinanceBankManage    Permission = "finance:bank:manage"
+	FinanceBankReconcile Permission = "finance:bank:reconcile"
+
+	FinancePeriodRead     Permission = "finance:period:read"
+	FinancePeriodManage   Permission = "finance:period:manage"   // set up fiscal years, close and reopen periods
+	FinancePeriodOverride Permission = "finance:period:override" // post invoices, payments and stock adjustments dated in closed periods
+
+	FinanceExpenseCreate    Permission = "finance:expense:create" // claim one's own expenses
+	FinanceExpenseRead      Permission = "finance:expense:read"   // read every employee's claims and the expense report
+	FinanceExpenseApprove   Permission = "finance:expense:approve"
+	FinanceExpenseReimburse Permission = "finance:expense:reimburse"
+	FinanceExpenseManage    Permission = "finance:expense:manage" // set up expense categories
+
+	FinanceWithholdingRead   Permission = "finance:withholding:read" // rates and withholding certificates
+	FinanceWithholdingManage Permission = "finance:withholding:manage"
+
+	FinancePrepaymentCreate Permission = "finance:prepayment:create" // record customer deposits and vendor advances
+	FinancePrepaymentRead   Permission = "finance:prepayment:read"
+	FinancePrepaymentCancel Permission = "finance:prepayment:cancel"
 )
 
 // Report permissions
@@ -169,5 +259,39 @@ const (
 
 // Audit permissions
 const (
-	AuditLogRead Permission = "audit:log:read"
+	AuditLogRead   Permission = "audit:log:read"
+	AuditLogExport Permission = "audit:log:export"
+	AuditLogVerify Permission = "audit:log:verify"
+	AuditLogRetain Permission = "audit:log:retain"
+)
+
+// Elevated access permissions
+const (
+	AccessElevationRead    Permission = "access:elevation:read"
+	AccessElevationApprove Permission = "access:elevation:approve" // approve, reject and revoke grants; cannot itself be granted
+)
+
+// Approval delegation permissions
+const (
+	ApprovalDelegationManage Permission = "approval:delegation:manage" // list, create and cancel the delegations of all users
+)
+
+// System permissions
+const (
+	SystemDatabaseRead Permission = "system:database:read"
+
+	SystemSandboxUse     Permission = "system:sandbox:use"
+	SystemSandboxReset   Permission = "system:sandbox:reset"
+	SystemSandboxEnforce Permission = "system:sandbox:enforce" // always routes the holder's requests to the sandbox
+
+	SystemArchiveRun Permission = "system:archive:run"
+
+	SystemJobRead   Permission = "system:job:read"   // view the background jobs of all users
+	SystemJobManage Permission = "system:job:manage" // cancel the background jobs of all users
+
+	SystemSettingsRead   Permission = "system:settings:read"
+	SystemSettingsUpdate Permission = "system:settings:update"
+
+	SystemAPIKeyRead   Permission = "system:apikey:read"
+	SystemAPIKeyManage Permission = "system:apikey:manage" // create, rotate and revoke API keys; cannot itself be given to a key
 )
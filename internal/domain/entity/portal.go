@@ -0,0 +1,26 @@
+package entity
+
+import "time"
+
+// PortalPaymentStatus summarizes a client's invoice and payment position for the customer portal
+type PortalPaymentStatus struct {
+	ClientID         uint                   `json:"client_id"`
+	PaymentStatus    PaymentStatus          `json:"payment_status"`
+	TotalInvoiced    float64                `json:"total_invoiced"`
+	TotalPaid        float64                `json:"total_paid"`
+	TotalOutstanding float64                `json:"total_outstanding"`
+	TotalOverdue     float64                `json:"total_overdue"`
+	OpenInvoices     []PortalInvoiceSummary `json:"open_invoices"`
+	GeneratedAt      time.Time              `json:"generated_at"`
+}
+
+// PortalInvoiceSummary represents an unpaid invoice shown in the customer portal
+type PortalInvoiceSummary struct {
+	InvoiceID     string        `json:"invoice_id"`
+	InvoiceNumber string        `json:"invoice_number"`
+	SalesOrderID  string        `json:"sales_order_id"`
+	DueDate       time.Time     `json:"due_date"`
+	TotalAmount   float64       `json:"total_amount"`
+	Status        InvoiceStatus `json:"status"`
+	IsOverdue     bool          `json:"is_overdue"`
+}
@@ -0,0 +1,102 @@
+package entity
+
+import "time"
+
+// PrepaymentType tells whether a prepayment was received from a customer or
+// paid to a vendor
+type PrepaymentType string
+
+const (
+	PrepaymentCustomerDeposit PrepaymentType = "CUSTOMER_DEPOSIT" // against a sales order
+	PrepaymentVendorAdvance   PrepaymentType = "VENDOR_ADVANCE"   // against a purchase order
+)
+
+// PrepaymentStatus represents the status of a prepayment
+type PrepaymentStatus string
+
+const (
+	PrepaymentOpen      PrepaymentStatus = "OPEN"    // with an unapplied balance
+	PrepaymentApplied   PrepaymentStatus = "APPLIED" // fully applied to invoices
+	PrepaymentCancelled PrepaymentStatus = "CANCELLED"
+)
+
+// Invoice types prepayments are applied to
+const (
+	PrepaymentInvoiceSales   = "SALES_INVOICE"   // invoice of a sales order
+	PrepaymentInvoiceFinance = "FINANCE_INVOICE" // finance invoice referencing the order
+)
+
+// Prepayment is a deposit a customer paid against a sales order, or an
+// advance paid to a vendor against a purchase order, before the order was
+// invoiced. Its unapplied balance settles the invoices of the order as they
+// are created.
+type Prepayment struct {
+	ID               uint                    `json:"id" gorm:"primaryKey"`
+	PrepaymentNumber string                  `json:"prepayment_number" gorm:"type:varchar(50);not null;uniqueIndex"`
+	Type             PrepaymentType          `json:"type" gorm:"type:varchar(20);not null"`
+	OrderID          string                  `json:"order_id" gorm:"type:uuid;not null;index"` // the sales or purchase order
+	OrderNumber      string                  `json:"order_number" gorm:"type:varchar(50);not null"`
+	EntityType       string                  `json:"entity_type" gorm:"type:varchar(20);not null"` // "CUSTOMER" or "SUPPLIER"
+	EntityID         uint                    `json:"entity_id" gorm:"not null;index"`              // client or vendor ID
+	PaymentDate      time.Time               `json:"payment_date" gorm:"type:date;not null"`
+	PaymentMethod    FinancePaymentMethod    `json:"payment_method" gorm:"type:varchar(20);not null"`
+	ReferenceNumber  string                  `json:"reference_number,omitempty"`
+	Amount           float64                 `json:"amount" gorm:"type:decimal(15,2);not null"` // in the order currency
+	AppliedAmount    float64                 `json:"applied_amount" gorm:"type:decimal(15,2);not null;default:0"`
+	UnappliedAmount  float64                 `json:"unapplied_amount" gorm:"type:decimal(15,2);not null"`
+	CurrencyCode     string                  `json:"currency_code" gorm:"type:varchar(3);not null"`
+	ExchangeRate     float64                 `json:"exchange_rate" gorm:"type:decimal(18,8);not null"`
+	BaseAmount       float64                 `json:"base_amount" gorm:"type:decimal(15,2);not null"`
+	Status           PrepaymentStatus        `json:"status" gorm:"type:varchar(20);not null;index"`
+	Notes            string                  `json:"notes,omitempty"`
+	Applications     []PrepaymentApplication `json:"applications,omitempty" gorm:"foreignKey:PrepaymentID"`
+	CreatedByID      uint                    `json:"created_by_id" gorm:"not null"`
+	CreatedAt        time.Time               `json:"created_at"`
+	UpdatedAt        time.Time               `json:"updated_at"`
+}
+
+// PrepaymentApplication is the part of a prepayment applied to an invoice
+type PrepaymentApplication struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	PrepaymentID  uint      `json:"prepayment_id" gorm:"not null;index"`
+	InvoiceType   string    `json:"invoice_type" gorm:"type:varchar(20);not null;index:idx_prepayment_applications_invoice"`
+	InvoiceID     string    `json:"invoice_id" gorm:"type:varchar(64);not null;index:idx_prepayment_applications_invoice"`
+	InvoiceNumber string    `json:"invoice_number" gorm:"type:varchar(50);not null"`
+	Amount        float64   `json:"amount" gorm:"type:decimal(15,2);not null"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// CreatePrepaymentRequest represents the request to record a customer deposit
+// or a vendor advance
+type CreatePrepaymentRequest struct {
+	Type            PrepaymentType       `json:"type" binding:"required,oneof=CUSTOMER_DEPOSIT VENDOR_ADVANCE"`
+	OrderID         string               `json:"order_id" binding:"required"`
+	PaymentDate     time.Time            `json:"payment_date" binding:"required"`
+	PaymentMethod   FinancePaymentMethod `json:"payment_method" binding:"required"`
+	Amount          float64              `json:"amount" binding:"required,gt=0"` // in the order currency
+	ReferenceNumber string               `json:"reference_number"`
+	Notes           string               `json:"notes"`
+}
+
+// PrepaymentFilter represents filters for querying prepayments
+type PrepaymentFilter struct {
+	Type       PrepaymentType   `json:"type,omitempty"`
+	OrderID    string           `json:"order_id,omitempty"`
+	EntityType string           `json:"entity_type,omitempty"`
+	EntityID   uint             `json:"entity_id,omitempty"`
+	Status     PrepaymentStatus `json:"status,omitempty"`
+	Page       int              `json:"page,omitempty"`
+	PageSize   int              `json:"page_size,omitempty"`
+}
+
+// PrepaymentBalance is the unapplied prepayments of a customer or vendor in
+// a currency
+type PrepaymentBalance struct {
+	Type            PrepaymentType `json:"type"`
+	EntityType      string         `json:"entity_type"`
+	EntityID        uint           `json:"entity_id"`
+	CurrencyCode    string         `json:"currency_code"`
+	Prepayments     int            `json:"prepayments"`
+	UnappliedAmount float64        `json:"unapplied_amount"`
+	BaseAmount      float64        `json:"base_amount"` // at the rates of the payment dates
+}
@@ -1,6 +1,9 @@
 package entity
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 type ProductionOrderStatus string
 
@@ -11,17 +14,83 @@ const (
 	OrderStatusCancelled ProductionOrderStatus = "cancelled"
 )
 
+// MaterialIssueMode is how a production order's components are taken from stock
+type MaterialIssueMode string
+
+const (
+	MaterialIssueBackflush MaterialIssueMode = "backflush" // consumed at the BOM quantity as output is reported
+	MaterialIssueManual    MaterialIssueMode = "manual"    // issued explicitly against the order
+)
+
 type ProductionOrder struct {
-	ID           uint                  `json:"id" gorm:"primaryKey"`
-	ProductID    uint                  `json:"product_id" gorm:"not null"`
-	Quantity     int                   `json:"quantity" gorm:"not null"`
-	StartDate    time.Time             `json:"start_date"`
-	Deadline     time.Time             `json:"deadline" gorm:"not null"`
-	Status       ProductionOrderStatus `json:"status" gorm:"not null;default:'pending'"`
-	FacilityID   uint                  `json:"facility_id" gorm:"not null"`
-	CompletedQty int                   `json:"completed_qty" gorm:"default:0"`
-	DefectQty    int                   `json:"defect_qty" gorm:"default:0"`
-	Notes        string                `json:"notes"`
-	CreatedAt    time.Time             `json:"created_at"`
-	UpdatedAt    time.Time             `json:"updated_at"`
+	ID             uint                  `json:"id" gorm:"primaryKey"`
+	ProductID      uint                  `json:"product_id" gorm:"not null"`
+	Quantity       int                   `json:"quantity" gorm:"not null"`
+	StartDate      time.Time             `json:"start_date"`
+	Deadline       time.Time             `json:"deadline" gorm:"not null"`
+	Status         ProductionOrderStatus `json:"status" gorm:"not null;default:'pending'"`
+	FacilityID     uint                  `json:"facility_id" gorm:"not null"`
+	IssueMode      MaterialIssueMode     `json:"issue_mode" gorm:"type:varchar(20);not null;default:'backflush'"`
+	SourceStoreID  string                `json:"source_store_id"` // warehouse components are consumed from
+	TargetStoreID  string                `json:"target_store_id"` // warehouse finished goods are received into
+	WorkCenterID   *uint                 `json:"work_center_id,omitempty" gorm:"index"`
+	ScheduledStart *time.Time            `json:"scheduled_start,omitempty" gorm:"type:date"` // first day planned at the work center
+	ScheduledEnd   *time.Time            `json:"scheduled_end,omitempty" gorm:"type:date"`   // last day planned at the work center
+	PlannedHours   float64               `json:"planned_hours" gorm:"default:0"`             // work center hours the schedule reserves
+	CompletedQty   int                   `json:"completed_qty" gorm:"default:0"`
+	DefectQty      int                   `json:"defect_qty" gorm:"default:0"`
+	Notes          string                `json:"notes"`
+	CreatedAt      time.Time             `json:"created_at"`
+	UpdatedAt      time.Time             `json:"updated_at"`
+}
+
+// Reference identifies the production order on the stock entries it posts
+func (o *ProductionOrder) Reference() string {
+	return fmt.Sprintf("PRD-%d", o.ID)
+}
+
+// ProductionMaterialIssue records a quantity of a component taken from stock for
+// a production order
+type ProductionMaterialIssue struct {
+	ID                uint              `json:"id" gorm:"primaryKey"`
+	ProductionOrderID uint              `json:"production_order_id" gorm:"not null;index"`
+	MaterialID        uint              `json:"material_id" gorm:"not null"`
+	StoreID           string            `json:"store_id" gorm:"not null"`
+	Quantity          float64           `json:"quantity" gorm:"not null"`
+	Mode              MaterialIssueMode `json:"mode" gorm:"type:varchar(20);not null"`
+	Note              string            `json:"note"`
+	IssuedAt          time.Time         `json:"issued_at" gorm:"not null"`
+	IssuedBy          string            `json:"issued_by"`
+}
+
+// MaterialIssueRequest represents the request to issue components to a production order
+type MaterialIssueRequest struct {
+	StoreID string              `json:"store_id"` // defaults to the order's source store
+	Items   []MaterialIssueItem `json:"items" binding:"required,min=1,dive"`
+	Note    string              `json:"note"`
+}
+
+// MaterialIssueItem is a component quantity to issue
+type MaterialIssueItem struct {
+	MaterialID uint    `json:"material_id" binding:"required"`
+	Quantity   float64 `json:"quantity" binding:"required,gt=0"`
+}
+
+// MaterialVariance compares a component's consumption on a production order with
+// the BOM standard for the units produced, good and defective
+type MaterialVariance struct {
+	MaterialID      uint    `json:"material_id"`
+	UnitOfMeasure   string  `json:"unit_of_measure"`
+	StandardQty     float64 `json:"standard_qty"`
+	ActualQty       float64 `json:"actual_qty"`
+	VarianceQty     float64 `json:"variance_qty"`     // actual less standard; positive is overconsumption
+	VariancePercent float64 `json:"variance_percent"` // of the standard quantity
+}
+
+// ProductionVarianceReport is the material variance of a production order
+type ProductionVarianceReport struct {
+	ProductionOrderID uint               `json:"production_order_id"`
+	IssueMode         MaterialIssueMode  `json:"issue_mode"`
+	ProducedQty       int                `json:"produced_qty"` // completed and defective units
+	Materials         []MaterialVariance `json:"materials"`
 }
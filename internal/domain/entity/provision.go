@@ -0,0 +1,92 @@
+package entity
+
+import "time"
+
+// WriteDownRule provisions a percentage of the value of stock held longer than MinAgeDays.
+// When several rules match, the one with the highest MinAgeDays applies.
+type WriteDownRule struct {
+	ID               uint      `json:"id" gorm:"primaryKey"`
+	Name             string    `json:"name" gorm:"not null"`
+	MinAgeDays       int       `json:"min_age_days" gorm:"not null"`
+	ProvisionPercent float64   `json:"provision_percent" gorm:"type:decimal(5,2);not null"`
+	Active           bool      `json:"active" gorm:"not null;default:true"`
+	CreatedAt        time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt        time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// WriteDownRuleRequest represents a request to create or update a write-down rule
+type WriteDownRuleRequest struct {
+	Name             string  `json:"name" binding:"required"`
+	MinAgeDays       int     `json:"min_age_days" binding:"required,gt=0"`
+	ProvisionPercent float64 `json:"provision_percent" binding:"required,gt=0,lte=100"`
+	Active           *bool   `json:"active"`
+}
+
+// ProvisionEntryType represents the type of an inventory provision ledger entry
+type ProvisionEntryType string
+
+const (
+	ProvisionEntryProvision ProvisionEntryType = "PROVISION" // provision raised as stock ages
+	ProvisionEntryRelease   ProvisionEntryType = "RELEASE"   // provision released as aged stock sells
+)
+
+// InventoryProvisionEntry is a posting to the inventory provision ledger. Amount is
+// positive for provisions and negative for releases, so the provision held against
+// a stock is the sum of its entries.
+type InventoryProvisionEntry struct {
+	ID        uint               `json:"id" gorm:"primaryKey"`
+	Period    string             `json:"period" gorm:"type:varchar(7);not null;index"` // YYYY-MM
+	StockID   string             `json:"stock_id" gorm:"type:uuid;not null;index"`
+	SKUID     string             `json:"sku_id" gorm:"type:uuid;not null"`
+	StoreID   string             `json:"store_id" gorm:"type:uuid;not null"`
+	Type      ProvisionEntryType `json:"type" gorm:"type:varchar(20);not null"`
+	Quantity  float64            `json:"quantity" gorm:"type:decimal(15,2);not null"`
+	Value     float64            `json:"value" gorm:"type:decimal(15,2);not null"`
+	Required  float64            `json:"required" gorm:"type:decimal(15,2);not null"`
+	Amount    float64            `json:"amount" gorm:"type:decimal(15,2);not null"`
+	PostedBy  *uint              `json:"posted_by"`
+	PostedAt  time.Time          `json:"posted_at" gorm:"not null"`
+	CreatedAt time.Time          `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// InventoryProvisionFilter represents filters for listing provision ledger entries
+type InventoryProvisionFilter struct {
+	Period   string             `json:"period,omitempty"`
+	StockID  string             `json:"stock_id,omitempty"`
+	Type     ProvisionEntryType `json:"type,omitempty"`
+	Page     int                `json:"page,omitempty"`
+	PageSize int                `json:"page_size,omitempty"`
+}
+
+// StockAgeLayer is a quantity of a stock received on the same day, used to age stock first-in first-out
+type StockAgeLayer struct {
+	Quantity   float64   `json:"quantity"`
+	ReceivedAt time.Time `json:"received_at"`
+	AgeDays    int       `json:"age_days"`
+	Percent    float64   `json:"provision_percent"`
+	Provision  float64   `json:"provision"`
+}
+
+// StockProvision is the provision required against a stock compared with the provision already held
+type StockProvision struct {
+	StockID  string          `json:"stock_id"`
+	SKUID    string          `json:"sku_id"`
+	SKUCode  string          `json:"sku_code"`
+	StoreID  string          `json:"store_id"`
+	Quantity float64         `json:"quantity"`
+	UnitCost float64         `json:"unit_cost"`
+	Value    float64         `json:"value"`
+	Layers   []StockAgeLayer `json:"layers,omitempty"`
+	Required float64         `json:"required"`
+	Held     float64         `json:"held"`
+	Change   float64         `json:"change"`
+}
+
+// ProvisionRunResult reports the ledger entries posted for a period
+type ProvisionRunResult struct {
+	Period      string                    `json:"period"`
+	AsOf        time.Time                 `json:"as_of"`
+	Provisioned float64                   `json:"provisioned"`
+	Released    float64                   `json:"released"`
+	Entries     []InventoryProvisionEntry `json:"entries"`
+}
@@ -95,6 +95,7 @@ type PurchaseRequest struct {
 	ApproverID      *uint                 `json:"approver_id"`
 	ApprovalDate    *time.Time            `json:"approval_date"`
 	ApprovalNotes   string                `json:"approval_notes" gorm:"type:text"`
+	OnBehalfOfID    *uint                 `json:"on_behalf_of_id,omitempty"` // approver away the approver stood in for under a delegation
 	DepartmentID    *uint                 `json:"department_id"`
 	TotalEstimated  float64               `json:"total_estimated" gorm:"type:decimal(15,2)"`
 	CurrencyCode    string                `json:"currency_code" gorm:"default:'USD'"`
@@ -109,15 +110,23 @@ type PurchaseRequest struct {
 
 // PurchaseOrderItem represents an item in a purchase order
 type PurchaseOrderItem struct {
-	SKUID       string  `json:"sku_id" gorm:"not null"`
-	Quantity    float64 `json:"quantity" gorm:"not null"`
-	UnitPrice   float64 `json:"unit_price" gorm:"type:decimal(15,2);not null"`
-	TaxRate     float64 `json:"tax_rate" gorm:"type:decimal(5,2);default:0"`
-	TaxAmount   float64 `json:"tax_amount" gorm:"type:decimal(15,2);default:0"`
-	Discount    float64 `json:"discount" gorm:"type:decimal(15,2);default:0"`
-	TotalPrice  float64 `json:"total_price" gorm:"type:decimal(15,2);not null"`
-	Description string  `json:"description"`
-	SKU         *SKU    `json:"sku,omitempty" gorm:"foreignKey:SKUID"`
+	SKUID                string              `json:"sku_id" gorm:"not null"`
+	Quantity             float64             `json:"quantity" gorm:"not null"`
+	UnitPrice            float64             `json:"unit_price" gorm:"type:decimal(15,2);not null"`
+	TaxRate              float64             `json:"tax_rate" gorm:"type:decimal(5,2);default:0"`
+	TaxAmount            float64             `json:"tax_amount" gorm:"type:decimal(15,2);default:0"`
+	Discount             float64             `json:"discount" gorm:"type:decimal(15,2);default:0"`
+	TotalPrice           float64             `json:"total_price" gorm:"type:decimal(15,2);not null"`
+	Description          string              `json:"description"`
+	Asset                *AssetTerms         `json:"asset,omitempty"`                  // set to capitalize the line as fixed assets
+	PriceSource          PurchasePriceSource `json:"price_source,omitempty"`           // where the price of a line generated from a request came from
+	PriceListID          *uint               `json:"price_list_id,omitempty"`          // vendor price list the line was priced from
+	LastPrice            float64             `json:"last_price,omitempty"`             // unit price the SKU was last ordered at, in the order currency
+	PriceVariance        float64             `json:"price_variance,omitempty"`         // percent the unit price is above the last price, negative when below
+	PriceVarianceFlagged bool                `json:"price_variance_flagged,omitempty"` // variance beyond the purchasing threshold
+	Sources              []RequestSource     `json:"sources,omitempty"`                // purchase requests a consolidated line orders for
+	SKU                  *SKU                `json:"sku,omitempty" gorm:"foreignKey:SKUID"`
+	CustomsData                              // customs classification of the SKU when the order was placed
 }
 
 // Scan implements the sql.Scanner interface for PurchaseOrderItems
@@ -162,15 +171,20 @@ type PurchaseOrder struct {
 	DiscountTotal    float64             `json:"discount_total" gorm:"type:decimal(15,2);default:0"`
 	GrandTotal       float64             `json:"grand_total" gorm:"type:decimal(15,2);not null"`
 	CurrencyCode     string              `json:"currency_code" gorm:"default:'USD'"`
+	ExchangeRate     float64             `json:"exchange_rate" gorm:"type:decimal(18,8);default:1"`
+	BaseGrandTotal   float64             `json:"base_grand_total" gorm:"type:decimal(15,2);default:0"`
 	PaymentTerms     string              `json:"payment_terms"`
 	Status           PurchaseOrderStatus `json:"status" gorm:"not null;default:'DRAFT'"`
 	PaymentStatus    PaymentStatus       `json:"payment_status" gorm:"not null;default:'PENDING'"`
 	ShippingAddress  string              `json:"shipping_address" gorm:"type:text"`
 	ShippingMethod   string              `json:"shipping_method"`
+	SalesOrderID     *string             `json:"sales_order_id,omitempty" gorm:"type:uuid;index"` // sales order a drop-ship order is shipped to the customer of
+	StoreID          *string             `json:"store_id,omitempty" gorm:"type:uuid;index"`       // warehouse the goods are delivered to
 	Notes            string              `json:"notes" gorm:"type:text"`
 	AttachmentURLs   []string            `json:"attachment_urls" gorm:"type:text[]"`
 	CreatedByID      uint                `json:"created_by_id" gorm:"not null"`
 	ApprovedByID     *uint               `json:"approved_by_id"`
+	OnBehalfOfID     *uint               `json:"on_behalf_of_id,omitempty"` // approver away the approver stood in for under a delegation
 	ApprovalDate     *time.Time          `json:"approval_date"`
 	CreatedAt        time.Time           `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt        time.Time           `json:"updated_at" gorm:"autoUpdateTime"`
@@ -182,14 +196,24 @@ type PurchaseOrder struct {
 
 // PurchaseReceiptItem represents an item in a purchase receipt
 type PurchaseReceiptItem struct {
-	SKUID            string  `json:"sku_id" gorm:"not null"`
-	OrderedQuantity  float64 `json:"ordered_quantity" gorm:"not null"`
-	ReceivedQuantity float64 `json:"received_quantity" gorm:"not null"`
-	RejectedQuantity float64 `json:"rejected_quantity" gorm:"default:0"`
-	UnitPrice        float64 `json:"unit_price" gorm:"type:decimal(15,2);not null"`
-	TotalPrice       float64 `json:"total_price" gorm:"type:decimal(15,2);not null"`
-	Notes            string  `json:"notes"`
-	SKU              *SKU    `json:"sku,omitempty" gorm:"foreignKey:SKUID"`
+	SKUID            string          `json:"sku_id" gorm:"not null"`
+	OrderedQuantity  float64         `json:"ordered_quantity" gorm:"not null"`
+	ReceivedQuantity float64         `json:"received_quantity" gorm:"not null"`
+	RejectedQuantity float64         `json:"rejected_quantity" gorm:"default:0"`
+	UnitPrice        float64         `json:"unit_price" gorm:"type:decimal(15,2);not null"`
+	TotalPrice       float64         `json:"total_price" gorm:"type:decimal(15,2);not null"`
+	Notes            string          `json:"notes"`
+	CrossDocks       []CrossDockLink `json:"cross_docks,omitempty"` // deliveries the line shipped to straight from the dock
+	SKU              *SKU            `json:"sku,omitempty" gorm:"foreignKey:SKUID"`
+}
+
+// CrossDockLink is the quantity of a receipt line shipped to a delivery order
+// without putaway
+type CrossDockLink struct {
+	DeliveryOrderID string  `json:"delivery_order_id"`
+	DeliveryNumber  string  `json:"delivery_number"`
+	SalesOrderID    string  `json:"sales_order_id"`
+	Quantity        float64 `json:"quantity"`
 }
 
 // Scan implements the sql.Scanner interface for PurchaseReceiptItems
@@ -229,6 +253,7 @@ type PurchaseReceipt struct {
 	ReceiptDate     time.Time            `json:"receipt_date" gorm:"not null"`
 	Items           PurchaseReceiptItems `json:"items" gorm:"type:jsonb;not null"`
 	StoreID         string               `json:"store_id" gorm:"not null"`
+	CrossDock       bool                 `json:"cross_dock" gorm:"not null;default:false"` // received in cross-dock mode, shipping lines to pending deliveries
 	ReceivedByID    uint                 `json:"received_by_id" gorm:"not null"`
 	Notes           string               `json:"notes" gorm:"type:text"`
 	AttachmentURLs  []string             `json:"attachment_urls" gorm:"type:text[]"`
@@ -274,4 +299,5 @@ type PurchaseOrderFilter struct {
 	StartDate     *time.Time           `json:"start_date,omitempty"`
 	EndDate       *time.Time           `json:"end_date,omitempty"`
 	SKUID         string               `json:"sku_id,omitempty"`
+	Archived      bool                 `json:"archived,omitempty"` // query the archive table instead of the live one
 }
@@ -0,0 +1,57 @@
+package entity
+
+import "time"
+
+// PurchaseOrderRequest links a purchase order to a purchase request it was
+// generated from. A request split between vendors links to an order each.
+type PurchaseOrderRequest struct {
+	PurchaseOrderID   string    `json:"purchase_order_id" gorm:"primaryKey;type:uuid"`
+	PurchaseRequestID string    `json:"purchase_request_id" gorm:"primaryKey;type:uuid;index"`
+	CreatedAt         time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// RequestSource is the quantity of a purchase order line a purchase request
+// asked for
+type RequestSource struct {
+	PurchaseRequestID string  `json:"purchase_request_id"`
+	RequestNumber     string  `json:"request_number"`
+	Quantity          float64 `json:"quantity"`
+}
+
+// ConsolidationLine is a quantity of a SKU a purchase request asks for,
+// assigned to the order of a vendor
+type ConsolidationLine struct {
+	PurchaseRequestID string  `json:"purchase_request_id" binding:"required"`
+	RequestNumber     string  `json:"request_number,omitempty"`
+	SKUID             string  `json:"sku_id" binding:"required"`
+	Quantity          float64 `json:"quantity" binding:"gt=0"`
+	Description       string  `json:"description,omitempty"`
+}
+
+// ConsolidationGroup is the request lines to order from one vendor
+type ConsolidationGroup struct {
+	VendorID   uint                `json:"vendor_id"` // 0 in a plan for the lines whose SKU has no preferred vendor
+	VendorName string              `json:"vendor_name,omitempty"`
+	Lines      []ConsolidationLine `json:"lines" binding:"required,min=1,dive"`
+}
+
+// ConsolidationPlanRequest represents the approved purchase requests to plan
+// the orders of
+type ConsolidationPlanRequest struct {
+	RequestIDs []string `json:"request_ids" binding:"required,min=1"`
+}
+
+// ConsolidationPlan is the lines of purchase requests grouped by the
+// preferred vendor of their SKU, for the buyer to adjust before ordering
+type ConsolidationPlan struct {
+	RequestIDs []string             `json:"request_ids"`
+	Groups     []ConsolidationGroup `json:"groups"`
+}
+
+// ConsolidationRequest represents the grouping of the lines of purchase
+// requests to order, one purchase order per vendor. A request line may be
+// split between vendors by quantity.
+type ConsolidationRequest struct {
+	Groups []ConsolidationGroup `json:"groups" binding:"required,min=1,dive"`
+	Notes  string               `json:"notes"`
+}
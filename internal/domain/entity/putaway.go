@@ -0,0 +1,101 @@
+package entity
+
+import "time"
+
+// PutawayRuleType is the way a putaway rule suggests a destination bin
+type PutawayRuleType string
+
+const (
+	PutawayRuleFixedSlot   PutawayRuleType = "FIXED_SLOT"  // a SKU always goes to its own slot
+	PutawayRuleConsolidate PutawayRuleType = "CONSOLIDATE" // a SKU goes where the store already keeps it
+	PutawayRuleVelocity    PutawayRuleType = "VELOCITY"    // SKUs of a velocity class go to the zone set for it
+)
+
+// VelocityClass ranks the SKUs of a store by the units it issued recently
+type VelocityClass string
+
+const (
+	VelocityFast   VelocityClass = "FAST"   // SKUs making up the first 80% of units issued
+	VelocityMedium VelocityClass = "MEDIUM" // SKUs making up the next 15%
+	VelocitySlow   VelocityClass = "SLOW"   // the rest, and SKUs not issued at all
+)
+
+// PutawayRule suggests where received goods of a store are put away. Rules are
+// tried by priority, lowest first, and the first one that applies to a SKU
+// names its destination.
+type PutawayRule struct {
+	ID            uint            `json:"id" gorm:"primaryKey"`
+	StoreID       string          `json:"store_id" gorm:"type:uuid;not null;index"`
+	Type          PutawayRuleType `json:"type" gorm:"type:varchar(20);not null"`
+	Priority      int             `json:"priority" gorm:"not null;default:0"`
+	SKUID         string          `json:"sku_id,omitempty" gorm:"column:sku_id;type:uuid"`  // SKU of a fixed slot
+	VelocityClass VelocityClass   `json:"velocity_class,omitempty" gorm:"type:varchar(10)"` // class of a velocity zone
+	ZoneCode      string          `json:"zone_code,omitempty"`
+	ShelfNumber   string          `json:"shelf_number,omitempty"`
+	BinLocation   string          `json:"bin_location,omitempty"`
+	Active        bool            `json:"active" gorm:"default:true"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+}
+
+// PutawayRuleRequest represents the request to create or update a putaway rule
+type PutawayRuleRequest struct {
+	StoreID       string          `json:"store_id" binding:"required"`
+	Type          PutawayRuleType `json:"type" binding:"required,oneof=FIXED_SLOT CONSOLIDATE VELOCITY"`
+	Priority      int             `json:"priority"`
+	SKUID         string          `json:"sku_id"`
+	VelocityClass VelocityClass   `json:"velocity_class" binding:"omitempty,oneof=FAST MEDIUM SLOW"`
+	ZoneCode      string          `json:"zone_code"`
+	ShelfNumber   string          `json:"shelf_number"`
+	BinLocation   string          `json:"bin_location"`
+	Active        *bool           `json:"active"`
+}
+
+// PutawayTaskStatus represents the status of a putaway task
+type PutawayTaskStatus string
+
+const (
+	PutawayTaskPending   PutawayTaskStatus = "PENDING"   // the goods wait at the dock
+	PutawayTaskConfirmed PutawayTaskStatus = "CONFIRMED" // the goods were put away
+)
+
+// PutawayTask asks to move a SKU received on a purchase receipt from the dock
+// to a bin, suggested by the putaway rules of the store
+type PutawayTask struct {
+	ID             uint              `json:"id" gorm:"primaryKey"`
+	ReceiptID      string            `json:"receipt_id" gorm:"type:uuid;not null;index"`
+	ReceiptNumber  string            `json:"receipt_number" gorm:"not null"`
+	StoreID        string            `json:"store_id" gorm:"type:uuid;not null;index"`
+	SKUID          string            `json:"sku_id" gorm:"column:sku_id;type:uuid;not null"`
+	Quantity       float64           `json:"quantity" gorm:"not null"`
+	RuleID         *uint             `json:"rule_id,omitempty"`
+	RuleType       PutawayRuleType   `json:"rule_type,omitempty" gorm:"type:varchar(20)"` // rule that suggested the bin; empty when none applied
+	SuggestedZone  string            `json:"suggested_zone,omitempty"`
+	SuggestedShelf string            `json:"suggested_shelf,omitempty"`
+	SuggestedBin   string            `json:"suggested_bin,omitempty"`
+	ZoneCode       string            `json:"zone_code,omitempty"` // where the goods were put away
+	ShelfNumber    string            `json:"shelf_number,omitempty"`
+	BinLocation    string            `json:"bin_location,omitempty"`
+	Status         PutawayTaskStatus `json:"status" gorm:"type:varchar(20);not null;default:'PENDING'"`
+	ConfirmedBy    string            `json:"confirmed_by,omitempty"`
+	ConfirmedAt    *time.Time        `json:"confirmed_at,omitempty"`
+	CreatedAt      time.Time         `json:"created_at"`
+	UpdatedAt      time.Time         `json:"updated_at"`
+	SKU            *SKU              `json:"sku,omitempty" gorm:"foreignKey:SKUID"`
+}
+
+// PutawayTaskFilter represents filters for listing putaway tasks
+type PutawayTaskFilter struct {
+	StoreID   string            `json:"store_id,omitempty"`
+	ReceiptID string            `json:"receipt_id,omitempty"`
+	SKUID     string            `json:"sku_id,omitempty"`
+	Status    PutawayTaskStatus `json:"status,omitempty"`
+}
+
+// PutawayConfirmRequest confirms a putaway task with the location scanned at
+// the bin. Without a bin location the suggested location is taken.
+type PutawayConfirmRequest struct {
+	BinLocation string `json:"bin_location"`
+	ShelfNumber string `json:"shelf_number"`
+	ZoneCode    string `json:"zone_code"`
+}
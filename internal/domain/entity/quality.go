@@ -0,0 +1,157 @@
+package entity
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// InspectionSource is the stock movement that put goods on inspection hold
+type InspectionSource string
+
+const (
+	InspectionSourceReceipt    InspectionSource = "RECEIPT"    // purchase receipt
+	InspectionSourceProduction InspectionSource = "PRODUCTION" // production output
+	InspectionSourceReturn     InspectionSource = "RETURN"     // restocked sales return
+	InspectionSourceExcursion  InspectionSource = "EXCURSION"  // stock exposed to a condition excursion
+)
+
+// InspectionStatus represents the status of a quality inspection
+type InspectionStatus string
+
+const (
+	InspectionStatusPending InspectionStatus = "PENDING" // goods are quarantined awaiting the result
+	InspectionStatusPassed  InspectionStatus = "PASSED"
+	InspectionStatusFailed  InspectionStatus = "FAILED"
+	InspectionStatusPartial InspectionStatus = "PARTIAL" // some units passed, some were rejected
+)
+
+// InspectionPlan makes receipts or production output of a SKU subject to a
+// quality inspection before the goods can be picked
+type InspectionPlan struct {
+	ID                uint      `json:"id" gorm:"primaryKey"`
+	SKUID             string    `json:"sku_id" gorm:"not null;uniqueIndex"`
+	Name              string    `json:"name" gorm:"not null"`
+	InspectReceipts   bool      `json:"inspect_receipts" gorm:"default:true"`
+	InspectProduction bool      `json:"inspect_production" gorm:"default:true"`
+	SampleSize        int       `json:"sample_size" gorm:"default:0"` // units to test per lot; 0 tests every unit
+	Instructions      string    `json:"instructions" gorm:"type:text"`
+	Active            bool      `json:"active" gorm:"default:true"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// Inspects reports whether goods from the given source are held for the plan.
+// Restocked returns are inspected like receipts.
+func (p *InspectionPlan) Inspects(source InspectionSource) bool {
+	if !p.Active {
+		return false
+	}
+	if source == InspectionSourceReceipt || source == InspectionSourceReturn {
+		return p.InspectReceipts
+	}
+	return p.InspectProduction
+}
+
+// InspectionPlanRequest represents the request to create or update an inspection plan
+type InspectionPlanRequest struct {
+	SKUID             string `json:"sku_id" binding:"required"`
+	Name              string `json:"name" binding:"required"`
+	InspectReceipts   bool   `json:"inspect_receipts"`
+	InspectProduction bool   `json:"inspect_production"`
+	SampleSize        int    `json:"sample_size" binding:"gte=0"`
+	Instructions      string `json:"instructions"`
+	Active            *bool  `json:"active"`
+}
+
+// DefectCode classifies why inspected units were rejected
+type DefectCode struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	Code        string    `json:"code" gorm:"type:varchar(50);uniqueIndex;not null"`
+	Description string    `json:"description" gorm:"not null"`
+	Active      bool      `json:"active" gorm:"default:true"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// DefectCodeRequest represents the request to create a defect code
+type DefectCodeRequest struct {
+	Code        string `json:"code" binding:"required"`
+	Description string `json:"description" binding:"required"`
+}
+
+// InspectionDefect is a quantity of rejected units with a defect code
+type InspectionDefect struct {
+	Code     string  `json:"code" binding:"required"`
+	Quantity float64 `json:"quantity" binding:"required,gt=0"`
+	Notes    string  `json:"notes"`
+}
+
+// InspectionDefects represents the defects found by an inspection, stored as JSON
+type InspectionDefects []InspectionDefect
+
+// Scan implements the sql.Scanner interface
+func (d *InspectionDefects) Scan(value interface{}) error {
+	if value == nil {
+		*d = make(InspectionDefects, 0)
+		return nil
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("failed to scan InspectionDefects: value is not []byte")
+	}
+	return json.Unmarshal(bytes, d)
+}
+
+// Value implements the driver.Valuer interface
+func (d InspectionDefects) Value() (driver.Value, error) {
+	if d == nil {
+		return nil, nil
+	}
+	return json.Marshal(d)
+}
+
+// QualityInspection holds a quantity of received or produced goods in quarantine
+// until its result is recorded. Passed units are released for picking and
+// rejected units are written off.
+type QualityInspection struct {
+	ID               uint              `json:"id" gorm:"primaryKey"`
+	PlanID           *uint             `json:"plan_id,omitempty" gorm:"index"` // none for excursion holds of SKUs without a plan
+	SKUID            string            `json:"sku_id" gorm:"not null;index"`
+	StoreID          string            `json:"store_id" gorm:"not null"`
+	Source           InspectionSource  `json:"source" gorm:"type:varchar(20);not null"`
+	Reference        string            `json:"reference" gorm:"not null;index"` // receipt number, production order reference, sales order number or excursion reference
+	Quantity         float64           `json:"quantity" gorm:"not null"`
+	SampleSize       int               `json:"sample_size"`
+	Status           InspectionStatus  `json:"status" gorm:"type:varchar(20);not null;default:'PENDING';index"`
+	PassedQuantity   float64           `json:"passed_quantity" gorm:"default:0"`
+	RejectedQuantity float64           `json:"rejected_quantity" gorm:"default:0"`
+	Defects          InspectionDefects `json:"defects" gorm:"type:jsonb"`
+	Notes            string            `json:"notes" gorm:"type:text"`
+	InspectedBy      *uint             `json:"inspected_by,omitempty"`
+	InspectedAt      *time.Time        `json:"inspected_at,omitempty"`
+	CreatedAt        time.Time         `json:"created_at"`
+	UpdatedAt        time.Time         `json:"updated_at"`
+}
+
+// InspectionResultRequest represents the request to record an inspection result.
+// Passed and rejected quantities must add up to the inspected quantity.
+type InspectionResultRequest struct {
+	PassedQuantity   float64            `json:"passed_quantity" binding:"gte=0"`
+	RejectedQuantity float64            `json:"rejected_quantity" binding:"gte=0"`
+	Defects          []InspectionDefect `json:"defects" binding:"dive"`
+	Notes            string             `json:"notes"`
+}
+
+// QualityInspectionFilter represents filters for listing inspections
+type QualityInspectionFilter struct {
+	Status    InspectionStatus
+	Source    InspectionSource
+	SKUID     string
+	StoreID   string
+	Reference string
+	Page      int
+	PageSize  int
+}
@@ -0,0 +1,98 @@
+package entity
+
+import "time"
+
+// RealtimeTopic identifies a stream of events pushed to WebSocket clients
+type RealtimeTopic string
+
+const (
+	TopicStockBelowReorder  RealtimeTopic = "stock.below_reorder"
+	TopicOrderStatusChanged RealtimeTopic = "order.status_changed"
+	TopicApprovalPending    RealtimeTopic = "approval.pending"
+	TopicNotification       RealtimeTopic = "notification.created" // in-app notifications, each to its own user
+
+	// TopicImpersonationEnded tells the gateway to close the connections of
+	// an ended impersonation session; it is not delivered to clients
+	TopicImpersonationEnded RealtimeTopic = "impersonation.ended"
+)
+
+// RealtimeTopics lists the topics WebSocket clients can subscribe to
+var RealtimeTopics = []RealtimeTopic{
+	TopicStockBelowReorder,
+	TopicOrderStatusChanged,
+	TopicApprovalPending,
+	TopicNotification,
+}
+
+// RealtimeTopicPermissions lists the permissions allowing users to subscribe
+// to a topic; holding any one of them is enough. Every user may subscribe to
+// the topics not listed.
+var RealtimeTopicPermissions = map[RealtimeTopic][]Permission{
+	TopicStockBelowReorder:  {StockRead},
+	TopicOrderStatusChanged: {SalesOrderRead},
+	TopicApprovalPending:    {PurchaseRequestApprove, PurchaseOrderApprove, AccessElevationApprove},
+}
+
+// ValidRealtimeTopic reports whether clients can subscribe to the topic
+func ValidRealtimeTopic(topic RealtimeTopic) bool {
+	for _, t := range RealtimeTopics {
+		if t == topic {
+			return true
+		}
+	}
+	return false
+}
+
+// RealtimeEvent is a domain event the server hands to the gateway for delivery
+// to the WebSocket clients subscribed to its topic
+type RealtimeEvent struct {
+	Topic      RealtimeTopic `json:"topic"`
+	Permission Permission    `json:"permission,omitempty"` // only users holding it receive the event
+	UserIDs    []uint        `json:"user_ids,omitempty"`   // when set, only these users receive the event
+	StoreID    string        `json:"store_id,omitempty"`   // when set, only users whose access scope covers the store receive the event
+	Data       interface{}   `json:"data"`
+	Timestamp  time.Time     `json:"timestamp"`
+}
+
+// StockBelowReorderEvent reports a store stock whose available quantity fell
+// below its SKU's reorder point
+type StockBelowReorderEvent struct {
+	SKUID             string  `json:"sku_id"`
+	SKUCode           string  `json:"sku_code"`
+	Name              string  `json:"name"`
+	StoreID           string  `json:"store_id"`
+	AvailableQuantity float64 `json:"available_quantity"`
+	ReorderPoint      float64 `json:"reorder_point"`
+}
+
+// OrderStatusEvent reports a sales order moving to a new status
+type OrderStatusEvent struct {
+	OrderID        string           `json:"order_id"`
+	OrderNumber    string           `json:"order_number"`
+	ClientID       uint             `json:"client_id"`
+	PreviousStatus SalesOrderStatus `json:"previous_status"`
+	Status         SalesOrderStatus `json:"status"`
+}
+
+// ImpersonationEndedEvent reports an impersonation session ended early
+type ImpersonationEndedEvent struct {
+	SessionID uint `json:"session_id"`
+}
+
+// ApprovalDocument identifies the kind of document waiting for approval
+type ApprovalDocument string
+
+const (
+	ApprovalPurchaseRequest ApprovalDocument = "PURCHASE_REQUEST"
+	ApprovalPurchaseOrder   ApprovalDocument = "PURCHASE_ORDER"
+	ApprovalElevatedAccess  ApprovalDocument = "ELEVATED_ACCESS"
+	ApprovalExpenseClaim    ApprovalDocument = "EXPENSE_CLAIM"
+)
+
+// ApprovalPendingEvent reports a document submitted for approval
+type ApprovalPendingEvent struct {
+	Document    ApprovalDocument `json:"document"`
+	DocumentID  string           `json:"document_id"`
+	Number      string           `json:"number,omitempty"`
+	RequestedBy uint             `json:"requested_by,omitempty"`
+}
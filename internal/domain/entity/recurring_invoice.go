@@ -0,0 +1,138 @@
+package entity
+
+import "time"
+
+// RecurrenceFrequency is the unit a recurring invoice repeats in
+type RecurrenceFrequency string
+
+const (
+	RecurrenceWeekly    RecurrenceFrequency = "WEEKLY"
+	RecurrenceMonthly   RecurrenceFrequency = "MONTHLY"
+	RecurrenceQuarterly RecurrenceFrequency = "QUARTERLY"
+	RecurrenceYearly    RecurrenceFrequency = "YEARLY"
+)
+
+// RecurringInvoiceStatus represents the status of a recurring invoice template
+type RecurringInvoiceStatus string
+
+const (
+	RecurringInvoiceActive RecurringInvoiceStatus = "ACTIVE"
+	RecurringInvoicePaused RecurringInvoiceStatus = "PAUSED"
+	RecurringInvoiceEnded  RecurringInvoiceStatus = "ENDED"
+)
+
+// RecurringInvoiceRunStatus records what happened to one occurrence of a recurring invoice
+type RecurringInvoiceRunStatus string
+
+const (
+	RecurringInvoiceRunGenerated RecurringInvoiceRunStatus = "GENERATED"
+	RecurringInvoiceRunSkipped   RecurringInvoiceRunStatus = "SKIPPED"
+)
+
+// RecurringInvoice is a template the scheduler materializes into a finance invoice
+// on every occurrence. Occurrences fall every IntervalCount units of the frequency
+// from the start date; unit prices rise by the escalation percent on each
+// anniversary of the start date.
+type RecurringInvoice struct {
+	ID                uint                   `json:"id" gorm:"primaryKey"`
+	Name              string                 `json:"name" gorm:"not null"`
+	Type              FinanceInvoiceType     `json:"type" gorm:"type:varchar(20);not null"`
+	EntityID          int64                  `json:"entity_id" gorm:"not null"`
+	EntityType        string                 `json:"entity_type" gorm:"type:varchar(20);not null"`
+	EntityName        string                 `json:"entity_name"`
+	Items             FinanceInvoiceItems    `json:"items" gorm:"type:jsonb;not null"`
+	DiscountAmount    float64                `json:"discount_amount" gorm:"type:decimal(15,2);default:0"`
+	CurrencyCode      string                 `json:"currency_code" gorm:"type:varchar(3)"`
+	Notes             string                 `json:"notes" gorm:"type:text"`
+	Frequency         RecurrenceFrequency    `json:"frequency" gorm:"type:varchar(20);not null"`
+	IntervalCount     int                    `json:"interval_count" gorm:"not null;default:1"`
+	PaymentTermsDays  int                    `json:"payment_terms_days" gorm:"not null;default:30"`
+	EscalationPercent float64                `json:"escalation_percent" gorm:"type:decimal(7,4);default:0"`
+	StartDate         time.Time              `json:"start_date" gorm:"not null"`
+	EndDate           *time.Time             `json:"end_date,omitempty"`
+	Occurrences       int                    `json:"occurrences" gorm:"not null;default:0"` // occurrences invoiced or skipped so far
+	NextRunDate       *time.Time             `json:"next_run_date,omitempty" gorm:"index"`  // unset once the template has ended
+	Status            RecurringInvoiceStatus `json:"status" gorm:"type:varchar(20);not null;default:'ACTIVE';index"`
+	LastInvoiceID     *int64                 `json:"last_invoice_id,omitempty"`
+	CreatedBy         int64                  `json:"created_by"`
+	CreatedAt         time.Time              `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt         time.Time              `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// RecurringInvoiceRun records one occurrence of a recurring invoice, either the
+// invoice it generated or that it was skipped
+type RecurringInvoiceRun struct {
+	ID                 uint                      `json:"id" gorm:"primaryKey"`
+	RecurringInvoiceID uint                      `json:"recurring_invoice_id" gorm:"not null;uniqueIndex:idx_recurring_invoice_runs_occurrence"`
+	Occurrence         int                       `json:"occurrence" gorm:"not null;uniqueIndex:idx_recurring_invoice_runs_occurrence"`
+	ScheduledDate      time.Time                 `json:"scheduled_date" gorm:"not null"`
+	Status             RecurringInvoiceRunStatus `json:"status" gorm:"type:varchar(20);not null"`
+	InvoiceID          *int64                    `json:"invoice_id,omitempty"`
+	InvoiceNumber      string                    `json:"invoice_number,omitempty"`
+	EscalationFactor   float64                   `json:"escalation_factor" gorm:"type:decimal(10,6);not null;default:1"`
+	Total              float64                   `json:"total" gorm:"type:decimal(15,2);default:0"`
+	CreatedBy          *uint                     `json:"created_by,omitempty"` // unset for scheduler runs
+	CreatedAt          time.Time                 `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// CreateRecurringInvoiceRequest represents the request to create a recurring invoice template
+type CreateRecurringInvoiceRequest struct {
+	Name              string              `json:"name" binding:"required"`
+	Type              FinanceInvoiceType  `json:"type" binding:"required,oneof=SALES PURCHASE"`
+	EntityID          int64               `json:"entity_id" binding:"required"`
+	EntityType        string              `json:"entity_type" binding:"required,oneof=CUSTOMER SUPPLIER"`
+	EntityName        string              `json:"entity_name"`
+	Items             FinanceInvoiceItems `json:"items" binding:"required,min=1,dive"`
+	DiscountAmount    float64             `json:"discount_amount" binding:"min=0"`
+	CurrencyCode      string              `json:"currency_code" binding:"omitempty,len=3"`
+	Notes             string              `json:"notes"`
+	Frequency         RecurrenceFrequency `json:"frequency" binding:"required,oneof=WEEKLY MONTHLY QUARTERLY YEARLY"`
+	IntervalCount     int                 `json:"interval_count" binding:"min=0"`
+	PaymentTermsDays  int                 `json:"payment_terms_days" binding:"min=0"`
+	EscalationPercent float64             `json:"escalation_percent" binding:"min=0"`
+	StartDate         time.Time           `json:"start_date" binding:"required"`
+	EndDate           *time.Time          `json:"end_date"`
+}
+
+// UpdateRecurringInvoiceRequest represents the request to update a recurring invoice
+// template. Changes apply to occurrences not yet invoiced.
+type UpdateRecurringInvoiceRequest struct {
+	Name              string              `json:"name"`
+	Items             FinanceInvoiceItems `json:"items" binding:"omitempty,dive"`
+	DiscountAmount    *float64            `json:"discount_amount" binding:"omitempty,min=0"`
+	Notes             string              `json:"notes"`
+	PaymentTermsDays  *int                `json:"payment_terms_days" binding:"omitempty,min=0"`
+	EscalationPercent *float64            `json:"escalation_percent" binding:"omitempty,min=0"`
+	EndDate           *time.Time          `json:"end_date"`
+}
+
+// RecurringInvoiceFilter represents filters for querying recurring invoice templates
+type RecurringInvoiceFilter struct {
+	Status     RecurringInvoiceStatus `json:"status,omitempty"`
+	Type       FinanceInvoiceType     `json:"type,omitempty"`
+	EntityID   int64                  `json:"entity_id,omitempty"`
+	EntityType string                 `json:"entity_type,omitempty"`
+	Page       int                    `json:"page,omitempty"`
+	PageSize   int                    `json:"page_size,omitempty"`
+}
+
+// RecurringInvoicePreviewLine is an upcoming occurrence of a recurring invoice
+type RecurringInvoicePreviewLine struct {
+	Occurrence       int       `json:"occurrence"`
+	IssueDate        time.Time `json:"issue_date"`
+	DueDate          time.Time `json:"due_date"`
+	EscalationFactor float64   `json:"escalation_factor"`
+	Subtotal         float64   `json:"subtotal"`
+	TaxTotal         float64   `json:"tax_total"`
+	Total            float64   `json:"total"`
+	CurrencyCode     string    `json:"currency_code"`
+}
+
+// RecurringInvoiceBatchResult summarizes a scheduler pass over due recurring invoices
+type RecurringInvoiceBatchResult struct {
+	AsOf      time.Time `json:"as_of"`
+	Templates int       `json:"templates"` // templates with occurrences due
+	Invoices  int       `json:"invoices"`  // invoices generated
+	Ended     int       `json:"ended"`     // templates that passed their end date
+	Errors    []string  `json:"errors,omitempty"`
+}
@@ -90,7 +90,9 @@ type Report struct {
 	CreatedBy   uint             `json:"created_by" gorm:"not null"`
 	CreatedAt   time.Time        `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt   time.Time        `json:"updated_at" gorm:"autoUpdateTime"`
-	FileURL     string           `json:"file_url"`
+	FileKey     string           `json:"-" gorm:"type:varchar(255)"`  // key of the last export in the file store
+	FileURL     string           `json:"file_url,omitempty" gorm:"-"` // signed download URL of the last export
+	FileExpires *time.Time       `json:"file_url_expires_at,omitempty" gorm:"-"`
 	Format      ReportFormat     `json:"format"`
 	Status      ReportStatus     `json:"status" gorm:"not null;default:'PENDING'"`
 }
@@ -113,6 +115,46 @@ type ReportSchedule struct {
 	NextRunAt   *time.Time              `json:"next_run_at"`
 }
 
+// ReportRunStatus represents the state of a run of a report schedule
+type ReportRunStatus string
+
+const (
+	ReportRunQueued    ReportRunStatus = "QUEUED" // waiting for a job worker, or for a retry after failing
+	ReportRunRunning   ReportRunStatus = "RUNNING"
+	ReportRunSucceeded ReportRunStatus = "SUCCEEDED"
+	ReportRunFailed    ReportRunStatus = "FAILED"
+)
+
+// ReportRun records an execution of a report schedule: the report generated
+// for a period and, for a schedule with recipients, the export emailed to them
+type ReportRun struct {
+	ID          uint64          `json:"id" gorm:"primaryKey"`
+	ScheduleID  string          `json:"schedule_id" gorm:"type:uuid;not null"`
+	ReportID    *string         `json:"report_id,omitempty" gorm:"type:uuid"`
+	JobID       *uint64         `json:"job_id,omitempty"`
+	RerunOf     *uint64         `json:"rerun_of,omitempty"`     // run this one generates again
+	TriggeredBy *uint           `json:"triggered_by,omitempty"` // user who re-ran the report, nil for scheduled runs
+	Status      ReportRunStatus `json:"status" gorm:"type:varchar(20);not null"`
+	PeriodStart time.Time       `json:"period_start" gorm:"not null"`
+	PeriodEnd   time.Time       `json:"period_end" gorm:"not null"`
+	RowCount    *int            `json:"row_count,omitempty"`
+	FileKey     string          `json:"-" gorm:"type:varchar(255)"`  // key of the export in the file store
+	FileURL     string          `json:"file_url,omitempty" gorm:"-"` // signed download URL of the export
+	FileExpires *time.Time      `json:"file_url_expires_at,omitempty" gorm:"-"`
+	Error       string          `json:"error,omitempty" gorm:"type:text"`
+	Attempts    int             `json:"attempts" gorm:"not null;default:0"`
+	CreatedAt   time.Time       `json:"created_at" gorm:"autoCreateTime"`
+	StartedAt   *time.Time      `json:"started_at,omitempty"`
+	FinishedAt  *time.Time      `json:"finished_at,omitempty"`
+}
+
+// ReportRunFilter represents filters for listing the runs of a report schedule
+type ReportRunFilter struct {
+	Status   ReportRunStatus `json:"status,omitempty"`
+	Page     int             `json:"page,omitempty"`
+	PageSize int             `json:"page_size,omitempty"`
+}
+
 // InventoryAgeReport represents an inventory age report item
 type InventoryAgeReport struct {
 	ProductID       string    `json:"product_id"`
@@ -129,29 +171,16 @@ type InventoryAgeReport struct {
 
 // InventoryValueReport represents an inventory value report item
 type InventoryValueReport struct {
-	ProductID     string  `json:"product_id"`
-	ProductName   string  `json:"product_name"`
-	SKU           string  `json:"sku"`
-	WarehouseID   string  `json:"warehouse_id"`
-	WarehouseName string  `json:"warehouse_name"`
-	Quantity      float64 `json:"quantity"`
-	UnitOfMeasure string  `json:"unit_of_measure"`
-	UnitCost      float64 `json:"unit_cost"`
-	TotalValue    float64 `json:"total_value"`
-}
-
-// ProductSalesReport represents a product sales report item
-type ProductSalesReport struct {
-	ProductID     string  `json:"product_id"`
-	ProductName   string  `json:"product_name"`
-	SKU           string  `json:"sku"`
-	Category      string  `json:"category"`
-	QuantitySold  float64 `json:"quantity_sold"`
-	UnitOfMeasure string  `json:"unit_of_measure"`
-	Revenue       float64 `json:"revenue"`
-	Cost          float64 `json:"cost"`
-	Profit        float64 `json:"profit"`
-	ProfitMargin  float64 `json:"profit_margin"`
+	ProductID     string     `json:"product_id"`
+	ProductName   string     `json:"product_name"`
+	SKU           string     `json:"sku"`
+	WarehouseID   string     `json:"warehouse_id"`
+	WarehouseName string     `json:"warehouse_name"`
+	Quantity      float64    `json:"quantity"`
+	UnitOfMeasure string     `json:"unit_of_measure"`
+	UnitCost      float64    `json:"unit_cost"`
+	TotalValue    float64    `json:"total_value"`
+	SnapshotDate  *time.Time `json:"snapshot_date,omitempty"` // day of the snapshot a past date is reported from
 }
 
 // CustomerSalesReport represents a customer sales report item
@@ -183,6 +212,23 @@ type ProfitAndLossReport struct {
 	Expenses     float64   `json:"expenses"`
 	NetProfit    float64   `json:"net_profit"`
 	ProfitMargin float64   `json:"profit_margin"`
+
+	Periods []ProfitAndLossPeriod `json:"periods,omitempty"` // breakdown by the fiscal periods the report spans
+}
+
+// ProfitAndLossPeriod is the profit and loss of the part of a fiscal period
+// a profit and loss report spans
+type ProfitAndLossPeriod struct {
+	FiscalPeriodID uint               `json:"fiscal_period_id"`
+	Name           string             `json:"name"`
+	Status         FiscalPeriodStatus `json:"status"` // figures of closed periods are final
+	StartDate      time.Time          `json:"start_date"`
+	EndDate        time.Time          `json:"end_date"`
+	Revenue        float64            `json:"revenue"`
+	CostOfGoods    float64            `json:"cost_of_goods"`
+	GrossProfit    float64            `json:"gross_profit"`
+	Expenses       float64            `json:"expenses"`
+	NetProfit      float64            `json:"net_profit"`
 }
 
 // DashboardMetrics represents key metrics for the dashboard
@@ -203,6 +249,36 @@ type DashboardMetrics struct {
 		Revenue     float64 `json:"revenue"`
 	} `json:"top_selling_products"`
 	RevenueByMonth map[string]float64 `json:"revenue_by_month"`
+	Period         string             `json:"period"`
+	ComputedAt     time.Time          `json:"computed_at"`
+}
+
+// DashboardPeriods are the periods dashboard metrics are aggregated over
+var DashboardPeriods = []string{"day", "week", "month", "quarter", "year"}
+
+// DashboardMetricSnapshot holds the dashboard metrics of a period, aggregated
+// ahead of the requests reading them. FilterKey identifies the RFM filter the
+// sales figures are restricted by and is empty for all clients.
+type DashboardMetricSnapshot struct {
+	Period        string     `gorm:"primaryKey;type:varchar(16)"`
+	FilterKey     string     `gorm:"primaryKey;type:varchar(100)"`
+	Metrics       string     `gorm:"type:text"` // DashboardMetrics as JSON
+	ComputedAt    time.Time  // when the aggregation started
+	InvalidatedAt *time.Time // when data the metrics are aggregated from last changed
+}
+
+// TableName specifies the table name for DashboardMetricSnapshot
+func (DashboardMetricSnapshot) TableName() string {
+	return "dashboard_metric_snapshots"
+}
+
+// Fresh reports whether the snapshot was computed after its data last changed
+// and less than maxAge ago
+func (s DashboardMetricSnapshot) Fresh(maxAge time.Duration, now time.Time) bool {
+	if s.InvalidatedAt != nil && !s.ComputedAt.After(*s.InvalidatedAt) {
+		return false
+	}
+	return now.Sub(s.ComputedAt) < maxAge
 }
 
 // ReportFilter represents filters for searching reports
@@ -0,0 +1,79 @@
+package entity
+
+import "time"
+
+// SalesReturn is a quantity of a SKU a client sent back against a sales order.
+// Its reason code is one of the quality defect codes, so returns and inspection
+// rejections are classified alike.
+type SalesReturn struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	SalesOrderID string    `json:"sales_order_id" gorm:"type:uuid;not null;index"`
+	ClientID     uint      `json:"client_id" gorm:"not null"`
+	SKUID        string    `json:"sku_id" gorm:"type:uuid;not null;index"`
+	StoreID      string    `json:"store_id,omitempty"` // store the goods were restocked into
+	LotNumber    string    `json:"lot_number"`
+	Quantity     float64   `json:"quantity" gorm:"type:decimal(15,3);not null"`
+	ReasonCode   string    `json:"reason_code" gorm:"type:varchar(50);not null;index"`
+	Notes        string    `json:"notes" gorm:"type:text"`
+	Restocked    bool      `json:"restocked" gorm:"default:false"`
+	CreatedBy    *uint     `json:"created_by,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// SalesReturnRequest represents the request to record a sales return. Restocked
+// goods go back into the store, on inspection hold when the SKU's plan inspects
+// receipts.
+type SalesReturnRequest struct {
+	SKUID      string  `json:"sku_id" binding:"required"`
+	Quantity   float64 `json:"quantity" binding:"required,gt=0"`
+	ReasonCode string  `json:"reason_code" binding:"required"`
+	LotNumber  string  `json:"lot_number"`
+	Notes      string  `json:"notes"`
+	Restock    bool    `json:"restock"`
+	StoreID    string  `json:"store_id"` // required when restocking
+}
+
+// ReturnsGroupBy is the dimension the returns and quality report is grouped by
+type ReturnsGroupBy string
+
+const (
+	ReturnsGroupBySKU    ReturnsGroupBy = "SKU"
+	ReturnsGroupByLot    ReturnsGroupBy = "LOT"
+	ReturnsGroupByVendor ReturnsGroupBy = "VENDOR"
+	ReturnsGroupByReason ReturnsGroupBy = "REASON"
+)
+
+// ReturnsQualityRow sets the returns of a SKU, lot, vendor or reason code against
+// its quality inspection rejections. Rates are percentages: the return rate of
+// the quantity sold and the rejection rate of the quantity inspected.
+type ReturnsQualityRow struct {
+	Key               string             `json:"key"`
+	Name              string             `json:"name,omitempty"`
+	SKUID             string             `json:"sku_id,omitempty"` // lot rows
+	SoldQuantity      float64            `json:"sold_quantity"`
+	ReturnedQuantity  float64            `json:"returned_quantity"`
+	ReturnRate        float64            `json:"return_rate"`
+	InspectedQuantity float64            `json:"inspected_quantity"`
+	RejectedQuantity  float64            `json:"rejected_quantity"`
+	RejectionRate     float64            `json:"rejection_rate"`
+	Reasons           map[string]float64 `json:"reasons,omitempty"` // returned and rejected quantity per reason code
+	Flagged           bool               `json:"flagged"`           // SKU return rate above the engineering review threshold
+}
+
+// ReturnsQualityReport correlates sales returns with quality inspection
+// rejections over a period
+type ReturnsQualityReport struct {
+	GroupBy     ReturnsGroupBy      `json:"group_by"`
+	StartDate   time.Time           `json:"start_date"`
+	EndDate     time.Time           `json:"end_date"`
+	Threshold   float64             `json:"threshold"`
+	Rows        []ReturnsQualityRow `json:"rows"`
+	GeneratedAt time.Time           `json:"generated_at"`
+}
+
+// ReturnsQualityFilter represents the period and grouping of the returns and quality report
+type ReturnsQualityFilter struct {
+	StartDate *time.Time
+	EndDate   *time.Time
+	GroupBy   ReturnsGroupBy
+}
@@ -0,0 +1,75 @@
+package entity
+
+import "time"
+
+// RFMSegment groups clients by their recency and frequency scores
+type RFMSegment string
+
+const (
+	RFMSegmentChampions         RFMSegment = "CHAMPIONS"          // bought recently and often
+	RFMSegmentLoyal             RFMSegment = "LOYAL"              // buy often, but not as recently
+	RFMSegmentPotentialLoyalist RFMSegment = "POTENTIAL_LOYALIST" // recent buyers with a few orders
+	RFMSegmentNew               RFMSegment = "NEW"                // recent buyers with a single order
+	RFMSegmentNeedsAttention    RFMSegment = "NEEDS_ATTENTION"    // average recency, few orders
+	RFMSegmentAtRisk            RFMSegment = "AT_RISK"            // used to buy often, not lately
+	RFMSegmentHibernating       RFMSegment = "HIBERNATING"        // few orders, a while ago
+	RFMSegmentLost              RFMSegment = "LOST"               // few orders, long ago
+)
+
+// ClientRFMScore is the recency, frequency and monetary score of a client over
+// its sales history. Each score runs from 1 to 5, relative to the other clients
+// scored in the same run.
+type ClientRFMScore struct {
+	ClientID       uint       `json:"client_id" gorm:"primaryKey;autoIncrement:false"`
+	LastOrderDate  time.Time  `json:"last_order_date"`
+	RecencyDays    int        `json:"recency_days"`
+	OrderCount     int        `json:"order_count"`
+	MonetaryValue  float64    `json:"monetary_value" gorm:"type:decimal(15,2)"`
+	RecencyScore   int        `json:"recency_score"`
+	FrequencyScore int        `json:"frequency_score"`
+	MonetaryScore  int        `json:"monetary_score"`
+	Score          string     `json:"score" gorm:"type:varchar(3)"` // e.g. "545"
+	Segment        RFMSegment `json:"segment" gorm:"type:varchar(30);index"`
+	ComputedAt     time.Time  `json:"computed_at"`
+}
+
+// TableName specifies the table name for ClientRFMScore
+func (ClientRFMScore) TableName() string {
+	return "client_rfm_scores"
+}
+
+// ClientSalesSummary is a client's sales within the RFM lookback window
+type ClientSalesSummary struct {
+	ClientID      uint      `json:"client_id"`
+	LastOrderDate time.Time `json:"last_order_date"`
+	OrderCount    int       `json:"order_count"`
+	MonetaryValue float64   `json:"monetary_value"`
+}
+
+// RFMFilter represents filters on RFM scores, shared by the client list and the sales dashboard
+type RFMFilter struct {
+	Segment           RFMSegment `json:"segment,omitempty"`
+	MinRecencyScore   int        `json:"min_recency_score,omitempty"`
+	MinFrequencyScore int        `json:"min_frequency_score,omitempty"`
+	MinMonetaryScore  int        `json:"min_monetary_score,omitempty"`
+}
+
+// IsEmpty reports whether the filter restricts anything
+func (f RFMFilter) IsEmpty() bool {
+	return f.Segment == "" && f.MinRecencyScore == 0 && f.MinFrequencyScore == 0 && f.MinMonetaryScore == 0
+}
+
+// RFMSegmentSummary counts the clients of a segment and their sales
+type RFMSegmentSummary struct {
+	Segment       RFMSegment `json:"segment"`
+	Clients       int        `json:"clients"`
+	MonetaryValue float64    `json:"monetary_value"`
+}
+
+// RFMRunResult reports the outcome of an RFM scoring run
+type RFMRunResult struct {
+	Since      time.Time           `json:"since"`
+	Scored     int                 `json:"scored"`
+	Segments   []RFMSegmentSummary `json:"segments"`
+	ComputedAt time.Time           `json:"computed_at"`
+}
@@ -45,11 +45,22 @@ func (p *GormPermissionSlice) Scan(value interface{}) error {
 }
 
 type Role struct {
-	ID          uint                `json:"id" gorm:"primaryKey"`
-	Name        string              `json:"name" gorm:"unique;not null"`
-	Permissions GormPermissionSlice `json:"permissions" gorm:"type:text[]"`
-	CreatedAt   time.Time           `json:"created_at"`
-	UpdatedAt   time.Time           `json:"updated_at"`
+	ID            uint                `json:"id" gorm:"primaryKey"`
+	Name          string              `json:"name" gorm:"unique;not null"`
+	Permissions   GormPermissionSlice `json:"permissions" gorm:"type:text[]"`
+	StoreIDs      pq.StringArray      `json:"store_ids" gorm:"type:text[]"`        // stores (warehouses) the role's users are limited to, all when empty
+	DepartmentIDs pq.Int64Array       `json:"department_ids" gorm:"type:bigint[]"` // departments the role's users are limited to, all when empty
+	CreatedAt     time.Time           `json:"created_at"`
+	UpdatedAt     time.Time           `json:"updated_at"`
+}
+
+// AccessScope returns the stores and departments the role is limited to
+func (r *Role) AccessScope() AccessScope {
+	scope := AccessScope{StoreIDs: r.StoreIDs}
+	for _, id := range r.DepartmentIDs {
+		scope.DepartmentIDs = append(scope.DepartmentIDs, uint(id))
+	}
+	return scope
 }
 
 type RoleRepository interface {
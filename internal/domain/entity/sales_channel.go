@@ -0,0 +1,149 @@
+package entity
+
+import "time"
+
+// SalesChannel is a web shop on an e-commerce platform whose orders are
+// ingested as sales orders and whose inventory levels mirror the warehouses
+// linked to it
+type SalesChannel struct {
+	ID                uint                    `json:"id" gorm:"primaryKey"`
+	Code              string                  `json:"code" gorm:"type:varchar(50);uniqueIndex;not null"` // source of the orders ingested from the channel
+	Name              string                  `json:"name" gorm:"not null"`
+	Platform          string                  `json:"platform" gorm:"type:varchar(20);not null"` // adapter connecting to the channel, such as SHOPIFY or WOOCOMMERCE
+	BaseURL           string                  `json:"base_url" gorm:"not null"`                  // address of the shop
+	APIKey            string                  `json:"-"`                                         // consumer key, for platforms signing in with a key pair
+	APISecret         string                  `json:"-"`                                         // consumer secret, for platforms signing in with a key pair
+	AccessToken       string                  `json:"-"`                                         // admin API token, for platforms signing in with a token
+	ClientID          uint                    `json:"client_id" gorm:"not null"`                 // client the channel's orders are booked to
+	StoreID           string                  `json:"store_id" gorm:"type:uuid;not null"`        // store orders are fulfilled from when their location is not linked
+	CurrencyCode      string                  `json:"currency_code,omitempty" gorm:"type:varchar(3)"`
+	PushInventory     bool                    `json:"push_inventory" gorm:"default:true"`
+	Active            bool                    `json:"active" gorm:"default:true"`
+	OrdersSyncedAt    *time.Time              `json:"orders_synced_at,omitempty"`    // creation time orders are fetched from on the next sync
+	InventorySyncedAt *time.Time              `json:"inventory_synced_at,omitempty"` // last time inventory levels were pushed
+	Warehouses        []SalesChannelWarehouse `json:"warehouses,omitempty" gorm:"foreignKey:ChannelID"`
+	CreatedAt         time.Time               `json:"created_at"`
+	UpdatedAt         time.Time               `json:"updated_at"`
+}
+
+// SalesChannelRequest represents the request to create or update a sales
+// channel. Credentials left empty on update are kept.
+type SalesChannelRequest struct {
+	Code          string `json:"code" binding:"required"`
+	Name          string `json:"name" binding:"required"`
+	Platform      string `json:"platform" binding:"required"`
+	BaseURL       string `json:"base_url" binding:"required,url"`
+	APIKey        string `json:"api_key"`
+	APISecret     string `json:"api_secret"`
+	AccessToken   string `json:"access_token"`
+	ClientID      uint   `json:"client_id" binding:"required"`
+	StoreID       string `json:"store_id" binding:"required"`
+	CurrencyCode  string `json:"currency_code"`
+	PushInventory *bool  `json:"push_inventory"`
+	Active        *bool  `json:"active"`
+}
+
+// SalesChannelWarehouse links a store to a location of a sales channel. The
+// orders of the location are fulfilled from the store, and the location's
+// inventory levels are the stock available in the stores linked to it.
+type SalesChannelWarehouse struct {
+	ID         uint   `json:"id" gorm:"primaryKey"`
+	ChannelID  uint   `json:"channel_id" gorm:"not null;index"`
+	StoreID    string `json:"store_id" gorm:"type:uuid;not null"`
+	LocationID string `json:"location_id,omitempty"` // empty on platforms with a single location
+}
+
+// SalesChannelWarehousesRequest replaces the stores linked to a sales channel
+type SalesChannelWarehousesRequest struct {
+	Warehouses []SalesChannelWarehouseRequest `json:"warehouses" binding:"dive"`
+}
+
+// SalesChannelWarehouseRequest links a store to a location of a sales channel
+type SalesChannelWarehouseRequest struct {
+	StoreID    string `json:"store_id" binding:"required"`
+	LocationID string `json:"location_id"`
+}
+
+// ChannelSKUMapping maps a SKU to the listing selling it on a sales channel.
+// Order lines with a SKU code and no mapping are matched to the SKU of the
+// same code.
+type ChannelSKUMapping struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	ChannelID   uint      `json:"channel_id" gorm:"not null;uniqueIndex:idx_channel_sku_mappings_external"`
+	SKUID       string    `json:"sku_id" gorm:"column:sku_id;type:uuid;not null;index"`
+	ExternalSKU string    `json:"external_sku" gorm:"not null;uniqueIndex:idx_channel_sku_mappings_external"` // SKU code of the listing on the channel
+	ExternalID  string    `json:"external_id,omitempty"`                                                      // listing the inventory level is pushed to, as the platform identifies it
+	SKU         *SKU      `json:"sku,omitempty" gorm:"foreignKey:SKUID"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// ChannelSKUMappingRequest represents the request to map a SKU to a listing
+type ChannelSKUMappingRequest struct {
+	SKUID       string `json:"sku_id" binding:"required"`
+	ExternalSKU string `json:"external_sku" binding:"required"`
+	ExternalID  string `json:"external_id"`
+}
+
+// ChannelOrder is an order fetched from a sales channel, normalized by its
+// platform adapter
+type ChannelOrder struct {
+	Reference       string             `json:"reference"` // order ID on the channel
+	Number          string             `json:"number"`    // order number shown to the customer
+	CreatedAt       time.Time          `json:"created_at"`
+	CurrencyCode    string             `json:"currency_code,omitempty"`
+	LocationID      string             `json:"location_id,omitempty"` // location fulfilling the order, when the platform assigns one
+	ShippingAddress string             `json:"shipping_address,omitempty"`
+	BillingAddress  string             `json:"billing_address,omitempty"`
+	Notes           string             `json:"notes,omitempty"`
+	Lines           []ChannelOrderLine `json:"lines"`
+}
+
+// ChannelOrderLine is a line of a channel order
+type ChannelOrderLine struct {
+	ExternalSKU string  `json:"external_sku"`
+	Title       string  `json:"title,omitempty"`
+	Quantity    float64 `json:"quantity"`
+	UnitPrice   float64 `json:"unit_price"`
+	Discount    float64 `json:"discount,omitempty"` // amount taken off the line
+}
+
+// ChannelInventoryLevel is the quantity of a listing available at a location
+// of a sales channel
+type ChannelInventoryLevel struct {
+	LocationID  string  `json:"location_id,omitempty"`
+	ExternalSKU string  `json:"external_sku"`
+	ExternalID  string  `json:"external_id"`
+	Available   float64 `json:"available"`
+}
+
+// ChannelSyncKind is what a sales channel sync exchanges
+type ChannelSyncKind string
+
+const (
+	ChannelSyncOrders    ChannelSyncKind = "ORDERS"    // orders fetched from the channel
+	ChannelSyncInventory ChannelSyncKind = "INVENTORY" // inventory levels pushed to the channel
+)
+
+// ChannelSyncStatus represents the outcome of a sales channel sync
+type ChannelSyncStatus string
+
+const (
+	ChannelSyncSucceeded ChannelSyncStatus = "SUCCEEDED"
+	ChannelSyncPartial   ChannelSyncStatus = "PARTIAL" // some orders or levels failed
+	ChannelSyncFailed    ChannelSyncStatus = "FAILED"  // the channel could not be reached
+)
+
+// ChannelSyncRun records a sync with a sales channel
+type ChannelSyncRun struct {
+	ID          uint              `json:"id" gorm:"primaryKey"`
+	ChannelID   uint              `json:"channel_id" gorm:"not null;index"`
+	Kind        ChannelSyncKind   `json:"kind" gorm:"type:varchar(20);not null"`
+	Status      ChannelSyncStatus `json:"status" gorm:"type:varchar(20);not null"`
+	Processed   int               `json:"processed"` // orders ingested or levels pushed
+	Failed      int               `json:"failed"`
+	Errors      string            `json:"errors,omitempty" gorm:"type:text"` // one line per order or level failed
+	TriggeredBy string            `json:"triggered_by,omitempty"`
+	StartedAt   time.Time         `json:"started_at" gorm:"not null"`
+	FinishedAt  time.Time         `json:"finished_at"`
+}
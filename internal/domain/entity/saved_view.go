@@ -0,0 +1,77 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// SavedViewParam is the query parameter list endpoints take the ID of a saved
+// view in, to be filtered by its criteria
+const SavedViewParam = "view_id"
+
+// SavedViewModules maps the modules views can be saved for to the routes of
+// their lists, which accept a view ID
+var SavedViewModules = map[string][]string{
+	"purchase_requests": {"/api/purchase/requests"},
+	"purchase_orders":   {"/api/purchase/orders"},
+	"sales_orders":      {"/api/v1/orders", "/api/v1/orders/export"},
+	"delivery_orders":   {"/api/v1/orders/deliveries"},
+	"invoices":          {"/api/v1/orders/invoices"},
+	"finance_invoices":  {"/api/v1/finance/invoices"},
+	"skus":              {"/api/v1/skus", "/api/v1/skus/export"},
+	"vendors":           {"/api/v1/vendors", "/api/v1/vendors/export"},
+	"clients":           {"/api/v1/clients"},
+	"stocks":            {"/api/v1/stocks"},
+	"stock_entries":     {"/api/v1/stocks/stock-entries"},
+}
+
+// SavedViewModuleOf returns the module whose list is served at a route, if
+// views can be saved for it
+func SavedViewModuleOf(route string) (string, bool) {
+	for module, routes := range SavedViewModules {
+		for _, r := range routes {
+			if r == route {
+				return module, true
+			}
+		}
+	}
+	return "", false
+}
+
+// SavedView is a named combination of list filters of a module, e.g. the
+// overdue purchase orders of a vendor. Its criteria are the query parameters
+// of the list; a list requested with the view's ID is filtered by them,
+// under the parameters the request gives itself. A view belongs to the user
+// who saved it and can be shared with roles, whose users may then apply it.
+type SavedView struct {
+	ID          uint           `json:"id" gorm:"primaryKey"`
+	UserID      uint           `json:"user_id" gorm:"not null;index"`
+	Module      string         `json:"module" gorm:"type:varchar(50);not null;index"`
+	Name        string         `json:"name" gorm:"type:varchar(100);not null"`
+	Criteria    JSONMap        `json:"criteria" gorm:"type:jsonb"`
+	SharedRoles pq.StringArray `json:"shared_roles" gorm:"type:text[]"` // names of the roles whose users can apply the view
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+}
+
+// VisibleTo reports whether a user of a role can list and apply the view
+func (v *SavedView) VisibleTo(userID uint, role string) bool {
+	if v.UserID == userID {
+		return true
+	}
+	for _, shared := range v.SharedRoles {
+		if shared == role {
+			return true
+		}
+	}
+	return false
+}
+
+// SavedViewRequest represents the request to save a view
+type SavedViewRequest struct {
+	Module      string            `json:"module" binding:"required"`
+	Name        string            `json:"name" binding:"required,max=100"`
+	Criteria    map[string]string `json:"criteria" binding:"required"`
+	SharedRoles []string          `json:"shared_roles"`
+}
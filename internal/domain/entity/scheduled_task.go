@@ -0,0 +1,59 @@
+package entity
+
+import "time"
+
+// ScheduledRunStatus represents the state of a run of a scheduled task
+type ScheduledRunStatus string
+
+const (
+	ScheduledRunRunning   ScheduledRunStatus = "RUNNING"
+	ScheduledRunSucceeded ScheduledRunStatus = "SUCCEEDED"
+	ScheduledRunFailed    ScheduledRunStatus = "FAILED"
+)
+
+// ScheduledRunTrigger tells what started a run of a scheduled task
+type ScheduledRunTrigger string
+
+const (
+	ScheduledRunBySchedule ScheduledRunTrigger = "SCHEDULE"
+	ScheduledRunManually   ScheduledRunTrigger = "MANUAL"
+)
+
+// ScheduledRun records a run of a recurring background task, such as the
+// report schedules or dunning, and which server instance ran it
+type ScheduledRun struct {
+	ID          uint64              `json:"id" gorm:"primaryKey"`
+	Task        string              `json:"task" gorm:"type:varchar(50);not null"`
+	Trigger     ScheduledRunTrigger `json:"trigger" gorm:"type:varchar(20);not null"`
+	Status      ScheduledRunStatus  `json:"status" gorm:"type:varchar(20);not null"`
+	Instance    string              `json:"instance" gorm:"type:varchar(255);not null"`
+	Error       string              `json:"error,omitempty" gorm:"type:text"`
+	TriggeredBy *uint               `json:"triggered_by,omitempty"` // user who ran the task by hand
+	StartedAt   time.Time           `json:"started_at" gorm:"not null"`
+	FinishedAt  *time.Time          `json:"finished_at,omitempty"`
+}
+
+// ScheduledRunFilter represents filters for listing scheduled task runs
+type ScheduledRunFilter struct {
+	Task     string             `json:"task,omitempty"`
+	Status   ScheduledRunStatus `json:"status,omitempty"`
+	Page     int                `json:"page,omitempty"`
+	PageSize int                `json:"page_size,omitempty"`
+}
+
+// ScheduledTask describes a recurring background task and when it runs next
+type ScheduledTask struct {
+	Name        string        `json:"name"`
+	Description string        `json:"description"`
+	Schedule    string        `json:"schedule"`              // cron expression, or @every <interval>
+	NextRunAt   *time.Time    `json:"next_run_at,omitempty"` // only known on the instance running the schedules
+	Running     bool          `json:"running"`               // running on this instance
+	LastRun     *ScheduledRun `json:"last_run,omitempty"`
+}
+
+// SchedulerStatus lists the scheduled tasks as seen by a server instance
+type SchedulerStatus struct {
+	Instance string          `json:"instance"`
+	Leader   bool            `json:"leader"` // this instance runs the schedules
+	Tasks    []ScheduledTask `json:"tasks"`
+}
@@ -0,0 +1,46 @@
+package entity
+
+// SearchEntityType is a kind of record the search covers
+type SearchEntityType string
+
+const (
+	SearchSKU           SearchEntityType = "sku"
+	SearchVendorItem    SearchEntityType = "vendor_item"
+	SearchClient        SearchEntityType = "client"
+	SearchVendor        SearchEntityType = "vendor"
+	SearchSalesOrder    SearchEntityType = "sales_order"
+	SearchPurchaseOrder SearchEntityType = "purchase_order"
+)
+
+// SearchEntityTypes lists the kinds of records the search covers
+var SearchEntityTypes = []SearchEntityType{
+	SearchSKU, SearchVendorItem, SearchClient, SearchVendor, SearchSalesOrder, SearchPurchaseOrder,
+}
+
+// SearchQuery asks for the records matching a text, best match first
+type SearchQuery struct {
+	Text     string             `json:"q"`
+	Types    []SearchEntityType `json:"types,omitempty"` // only return hits of these types; any type when empty
+	Page     int                `json:"page"`
+	PageSize int                `json:"page_size"`
+}
+
+// SearchHit is a record matching a search
+type SearchHit struct {
+	Type     SearchEntityType `json:"type"`
+	ID       string           `json:"id"`
+	Title    string           `json:"title"`              // the name, or the number of an order
+	Subtitle string           `json:"subtitle,omitempty"` // the code of the record
+	Score    float64          `json:"score"`              // relevance, higher is better
+}
+
+// SearchResult holds a page of hits and the number of matches of each type,
+// counted before the types of the query are applied
+type SearchResult struct {
+	Query    string                     `json:"q"`
+	Hits     []SearchHit                `json:"hits"`
+	Total    int64                      `json:"total"`
+	Facets   map[SearchEntityType]int64 `json:"facets"`
+	Page     int                        `json:"page"`
+	PageSize int                        `json:"page_size"`
+}
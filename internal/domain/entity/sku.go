@@ -9,22 +9,36 @@ import (
 
 // SKU represents a stock keeping unit in the system
 type SKU struct {
-	ID             string    `json:"id" gorm:"primaryKey;type:uuid"`
-	SKUCode        string    `json:"sku_code" gorm:"uniqueIndex;not null"`
-	Name           string    `json:"name" gorm:"not null"`
-	Description    string    `json:"description"`
-	UnitOfMeasure  string    `json:"unit_of_measure" gorm:"not null"`
-	Price          float64   `json:"price" gorm:"default:0"`
-	Category       string    `json:"category"`
-	TechnicalSpecs JSONMap   `json:"technical_specs" gorm:"type:jsonb"`
-	ManufacturerID *uint     `json:"manufacturer_id"`
-	VendorID       *uint     `json:"vendor_id"`
-	ImageURL       string    `json:"image_url"`
-	Status         SKUStatus `json:"status" gorm:"default:'ACTIVE'"`
-	CreatedAt      time.Time `json:"created_at" gorm:"autoCreateTime"`
-	UpdatedAt      time.Time `json:"updated_at" gorm:"autoUpdateTime"`
-	Manufacturer   *Vendor   `json:"manufacturer,omitempty" gorm:"foreignKey:ManufacturerID"`
-	Vendor         *Vendor   `json:"vendor,omitempty" gorm:"foreignKey:VendorID"`
+	ID                string               `json:"id" gorm:"primaryKey;type:uuid"`
+	SKUCode           string               `json:"sku_code" gorm:"uniqueIndex;not null"`
+	Name              string               `json:"name" gorm:"not null"`
+	Description       string               `json:"description"`
+	UnitOfMeasure     string               `json:"unit_of_measure" gorm:"not null"`
+	Price             float64              `json:"price" gorm:"default:0"`
+	ReorderPoint      float64              `json:"reorder_point" gorm:"type:decimal(15,3);default:0"` // available stock in a store below which a low-stock event is published, 0 to disable
+	Category          string               `json:"category"`
+	TechnicalSpecs    JSONMap              `json:"technical_specs" gorm:"type:jsonb"`
+	ManufacturerID    *uint                `json:"manufacturer_id"`
+	VendorID          *uint                `json:"vendor_id"`
+	ImageURL          string               `json:"image_url"`
+	Status            SKUStatus            `json:"status" gorm:"default:'ACTIVE'"`
+	CustomFields      JSONMap              `json:"custom_fields" gorm:"type:jsonb"`                // values of the custom fields defined on SKUs, by key
+	ParentID          *string              `json:"parent_id,omitempty" gorm:"type:uuid;index"`     // parent item of a variant
+	VariantDimensions SKUVariantDimensions `json:"variant_dimensions,omitempty" gorm:"type:jsonb"` // dimensions the variants of a parent item vary in
+	VariantOptions    JSONMap              `json:"variant_options,omitempty" gorm:"type:jsonb"`    // value of each dimension of a variant
+	IsKit             bool                 `json:"is_kit" gorm:"not null;default:false"`           // sold as a bundle of its kit components
+	CreatedAt         time.Time            `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt         time.Time            `json:"updated_at" gorm:"autoUpdateTime"`
+	Manufacturer      *Vendor              `json:"manufacturer,omitempty" gorm:"foreignKey:ManufacturerID"`
+	Vendor            *Vendor              `json:"vendor,omitempty" gorm:"foreignKey:VendorID"`
+	Images            []SKUImage           `json:"images,omitempty" gorm:"foreignKey:SKUID"` // uploaded pictures, in the order they are shown in
+	CustomsData                            // classification declared when the SKU crosses a border
+	SKUClasses                             // ABC/XYZ classes from the last classification run
+}
+
+// IsVariant reports whether the SKU is a variant of a parent item
+func (s *SKU) IsVariant() bool {
+	return s.ParentID != nil
 }
 
 // SKUStatus represents the status of a SKU
@@ -88,4 +102,10 @@ type SKUFilter struct {
 	Status         *SKUStatus `json:"status,omitempty"`
 	MinPrice       *float64   `json:"min_price,omitempty"`
 	MaxPrice       *float64   `json:"max_price,omitempty"`
+	CustomFields   JSONMap    `json:"custom_fields,omitempty"`   // custom field values the SKUs hold
+	ParentID       string     `json:"parent_id,omitempty"`       // variants of this parent item
+	NoVariants     bool       `json:"no_variants,omitempty"`     // parent items and SKUs without variants only
+	VariantOptions JSONMap    `json:"variant_options,omitempty"` // dimension values the variants hold
+	ABCClass       ABCClass   `json:"abc_class,omitempty"`
+	XYZClass       XYZClass   `json:"xyz_class,omitempty"`
 }
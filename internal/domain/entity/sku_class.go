@@ -0,0 +1,92 @@
+package entity
+
+import "time"
+
+// ABCClass ranks SKUs by consumption value, the units issued over the
+// analysis window at their price
+type ABCClass string
+
+const (
+	ABCClassA ABCClass = "A" // SKUs making up the first 80% of consumption value
+	ABCClassB ABCClass = "B" // SKUs making up the next 15%
+	ABCClassC ABCClass = "C" // the rest, and SKUs not issued at all
+)
+
+// XYZClass ranks SKUs by how much their monthly demand varies, from the
+// coefficient of variation of the units issued each month
+type XYZClass string
+
+const (
+	XYZClassX XYZClass = "X" // steady demand, varying by at most 50%
+	XYZClassY XYZClass = "Y" // varying demand, by at most 100%
+	XYZClassZ XYZClass = "Z" // erratic demand, and SKUs not issued at all
+)
+
+// SKUClasses are the ABC and XYZ classes of a SKU. They are set by the
+// classification analysis only.
+type SKUClasses struct {
+	ABCClass     ABCClass   `json:"abc_class,omitempty" gorm:"type:varchar(1);index"`
+	XYZClass     XYZClass   `json:"xyz_class,omitempty" gorm:"type:varchar(1);index"`
+	ClassifiedAt *time.Time `json:"classified_at,omitempty"`
+}
+
+// SKUIssue is the quantity of a SKU issued from stock in a month
+type SKUIssue struct {
+	SKUID    string `gorm:"column:sku_id"`
+	Period   string
+	Quantity float64
+}
+
+// SKUClassification is the outcome of classifying one SKU
+type SKUClassification struct {
+	SKUID            string   `json:"sku_id"`
+	ABCClass         ABCClass `json:"abc_class"`
+	XYZClass         XYZClass `json:"xyz_class"`
+	ConsumptionValue float64  `json:"consumption_value"` // units issued over the window at the SKU price
+	Variation        float64  `json:"variation"`         // coefficient of variation of the monthly units issued
+}
+
+// SKUClassSummary counts the SKUs of an ABC/XYZ class pair
+type SKUClassSummary struct {
+	ABCClass         ABCClass `json:"abc_class"`
+	XYZClass         XYZClass `json:"xyz_class"`
+	SKUs             int      `json:"skus"`
+	ConsumptionValue float64  `json:"consumption_value"`
+}
+
+// SKUClassRunResult reports the outcome of an ABC/XYZ classification run
+type SKUClassRunResult struct {
+	FirstPeriod string            `json:"first_period"`
+	LastPeriod  string            `json:"last_period"`
+	Classified  int               `json:"classified"`
+	Classes     []SKUClassSummary `json:"classes"`
+	ComputedAt  time.Time         `json:"computed_at"`
+}
+
+// CycleCountFilter represents filters for listing the stocks due for a
+// cycle count
+type CycleCountFilter struct {
+	StoreID  string     `json:"store_id,omitempty"`
+	ABCClass ABCClass   `json:"abc_class,omitempty"`
+	Date     *time.Time `json:"date,omitempty"` // stocks due on or before, defaults to today
+}
+
+// CycleCountLine is a stock due for a cycle count. Stocks are counted every
+// interval of their SKU's ABC class, from their last count or, when never
+// counted, from when they were first stocked.
+type CycleCountLine struct {
+	StockID       string     `json:"stock_id"`
+	StoreID       string     `json:"store_id"`
+	SKUID         string     `json:"sku_id" gorm:"column:sku_id"`
+	SKUCode       string     `json:"sku_code"`
+	Name          string     `json:"name"`
+	ABCClass      ABCClass   `json:"abc_class,omitempty"`
+	ZoneCode      string     `json:"zone_code,omitempty"`
+	BinLocation   string     `json:"bin_location,omitempty"`
+	Quantity      float64    `json:"quantity"`
+	IntervalDays  int        `json:"interval_days"`
+	LastCountedAt *time.Time `json:"last_counted_at,omitempty"`
+	StockedAt     time.Time  `json:"stocked_at"`
+	DueDate       time.Time  `json:"due_date"`
+	DaysOverdue   int        `json:"days_overdue"`
+}
@@ -0,0 +1,33 @@
+package entity
+
+import "time"
+
+// MaxSKUImages caps the images of a SKU
+const MaxSKUImages = 20
+
+// SKUImage is a picture of a SKU, shown in the order of Position. The image
+// and its thumbnail are kept in the file store under FileKey and
+// ThumbnailKey.
+type SKUImage struct {
+	ID           uint64     `json:"id" gorm:"primaryKey"`
+	SKUID        string     `json:"sku_id" gorm:"type:uuid;not null;index"`
+	Position     int        `json:"position" gorm:"not null;default:0"`
+	FileName     string     `json:"file_name" gorm:"type:varchar(255);not null"`
+	ContentType  string     `json:"content_type" gorm:"type:varchar(100);not null"`
+	Size         int64      `json:"size" gorm:"not null"` // in bytes
+	Width        int        `json:"width" gorm:"not null"`
+	Height       int        `json:"height" gorm:"not null"`
+	FileKey      string     `json:"-" gorm:"type:varchar(255);not null"`
+	ThumbnailKey string     `json:"-" gorm:"type:varchar(255);not null"`
+	URL          string     `json:"url,omitempty" gorm:"-"`
+	ThumbnailURL string     `json:"thumbnail_url,omitempty" gorm:"-"`
+	URLExpires   *time.Time `json:"url_expires_at,omitempty" gorm:"-"`
+	UploadedBy   *uint      `json:"uploaded_by,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// SKUImageOrderRequest represents the request to reorder the images of a
+// SKU, giving the IDs of all of them in the order to show them in
+type SKUImageOrderRequest struct {
+	ImageIDs []uint64 `json:"image_ids" binding:"required"`
+}
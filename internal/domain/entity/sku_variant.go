@@ -0,0 +1,141 @@
+package entity
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// MaxSKUVariants caps the variants generated for a parent item at once
+const MaxSKUVariants = 500
+
+var variantDimensionPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,49}$`)
+
+// SKUVariantDimension is an attribute the variants of a parent item vary in,
+// such as size or color, with the values it takes
+type SKUVariantDimension struct {
+	Name   string   `json:"name" binding:"required"`
+	Values []string `json:"values" binding:"required,min=1"`
+}
+
+// SKUVariantDimensions are the dimensions of a parent item, in the order
+// their values make up the codes and names of its variants
+type SKUVariantDimensions []SKUVariantDimension
+
+// Scan implements the sql.Scanner interface for SKUVariantDimensions
+func (d *SKUVariantDimensions) Scan(value interface{}) error {
+	if value == nil {
+		*d = nil
+		return nil
+	}
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("failed to scan SKUVariantDimensions: value is not []byte")
+	}
+	return json.Unmarshal(bytes, d)
+}
+
+// Value implements the driver.Valuer interface for SKUVariantDimensions
+func (d SKUVariantDimensions) Value() (driver.Value, error) {
+	if d == nil {
+		return nil, nil
+	}
+	return json.Marshal(d)
+}
+
+// Validate checks that dimensions are named like keys, are not repeated, and
+// hold distinct non-empty values
+func (d SKUVariantDimensions) Validate() error {
+	names := make(map[string]bool, len(d))
+	for _, dimension := range d {
+		if !variantDimensionPattern.MatchString(dimension.Name) {
+			return NewError(ErrCodeInvalidArgument, fmt.Sprintf("invalid variant dimension %q: use lowercase letters, digits and underscores", dimension.Name))
+		}
+		if names[dimension.Name] {
+			return NewError(ErrCodeInvalidArgument, fmt.Sprintf("variant dimension %s is repeated", dimension.Name))
+		}
+		names[dimension.Name] = true
+
+		values := make(map[string]bool, len(dimension.Values))
+		for _, value := range dimension.Values {
+			if strings.TrimSpace(value) == "" || values[value] {
+				return NewError(ErrCodeInvalidArgument, fmt.Sprintf("variant dimension %s has an empty or repeated value", dimension.Name))
+			}
+			values[value] = true
+		}
+	}
+	return nil
+}
+
+// Matches reports whether variant options give a value of each dimension,
+// and nothing else
+func (d SKUVariantDimensions) Matches(options JSONMap) bool {
+	if len(options) != len(d) {
+		return false
+	}
+	for _, dimension := range d {
+		value, ok := options[dimension.Name].(string)
+		if !ok {
+			return false
+		}
+		found := false
+		for _, v := range dimension.Values {
+			found = found || v == value
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// Combinations returns the options of every variant the dimensions make up,
+// varying the last dimension fastest
+func (d SKUVariantDimensions) Combinations() []JSONMap {
+	if len(d) == 0 {
+		return nil
+	}
+	combinations := []JSONMap{{}}
+	for _, dimension := range d {
+		next := make([]JSONMap, 0, len(combinations)*len(dimension.Values))
+		for _, combination := range combinations {
+			for _, value := range dimension.Values {
+				options := make(JSONMap, len(combination)+1)
+				for k, v := range combination {
+					options[k] = v
+				}
+				options[dimension.Name] = value
+				next = append(next, options)
+			}
+		}
+		combinations = next
+	}
+	return combinations
+}
+
+// Key identifies the options of a variant, their values in dimension order
+func (d SKUVariantDimensions) Key(options JSONMap) string {
+	values := make([]string, len(d))
+	for i, dimension := range d {
+		values[i] = fmt.Sprint(options[dimension.Name])
+	}
+	return strings.Join(values, "\x00")
+}
+
+// SKUVariantRequest represents the request to generate the variants of a
+// parent item from its dimensions. Price defaults to the parent's.
+type SKUVariantRequest struct {
+	Dimensions []SKUVariantDimension `json:"dimensions" binding:"required,min=1,dive"`
+	Price      *float64              `json:"price"`
+}
+
+// SKUVariantMatrix is a parent item with its dimensions and variants, each
+// variant holding its value of every dimension in variant_options
+type SKUVariantMatrix struct {
+	Parent     *SKU                 `json:"parent"`
+	Dimensions SKUVariantDimensions `json:"dimensions"`
+	Variants   []SKU                `json:"variants"`
+}
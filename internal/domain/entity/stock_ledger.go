@@ -0,0 +1,56 @@
+package entity
+
+import "time"
+
+// Source documents stock movements are linked to
+const (
+	LedgerDocumentPurchaseReceipt = "purchase_receipt"
+	LedgerDocumentDeliveryOrder   = "delivery_order"
+	LedgerDocumentSalesOrder      = "sales_order"
+	LedgerDocumentProductionOrder = "production_order"
+)
+
+// StockLedgerFilter represents filters for the stock ledger of a SKU in a store
+type StockLedgerFilter struct {
+	SKUID   string     `json:"sku_id"`
+	StoreID string     `json:"store_id"`
+	From    *time.Time `json:"from,omitempty"` // movements on or after this day
+	To      *time.Time `json:"to,omitempty"`   // movements on or before this day
+}
+
+// StockMovement is a change to the stock of a SKU in a store, with the
+// source document it came from and the balance it left
+type StockMovement struct {
+	ID           string    `json:"id"`
+	CreatedAt    time.Time `json:"created_at"`
+	Type         string    `json:"type"` // IN, OUT, ADJUST
+	Change       float64   `json:"change"`
+	Balance      float64   `json:"balance"`
+	UnitCost     float64   `json:"unit_cost"`
+	Cost         float64   `json:"cost"`
+	EntryID      string    `json:"entry_id,omitempty"`
+	Reference    string    `json:"reference,omitempty"`
+	BatchNumber  string    `json:"batch_number,omitempty"`
+	DocumentType string    `json:"document_type,omitempty"`
+	DocumentID   string    `json:"document_id,omitempty"`
+	DocumentURL  string    `json:"document_url,omitempty"`
+	Note         string    `json:"note,omitempty"`
+	CreatedBy    string    `json:"created_by"`
+}
+
+// StockLedger lists the movements of a SKU in a store over a date range with
+// the running balance after each one
+type StockLedger struct {
+	SKUID          string          `json:"sku_id"`
+	SKUCode        string          `json:"sku_code"`
+	SKUName        string          `json:"sku_name"`
+	StoreID        string          `json:"store_id"`
+	StoreName      string          `json:"store_name"`
+	From           *time.Time      `json:"from,omitempty"`
+	To             *time.Time      `json:"to,omitempty"`
+	OpeningBalance float64         `json:"opening_balance"`
+	TotalIn        float64         `json:"total_in"`
+	TotalOut       float64         `json:"total_out"`
+	ClosingBalance float64         `json:"closing_balance"`
+	Movements      []StockMovement `json:"movements"`
+}
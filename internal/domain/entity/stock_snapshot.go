@@ -0,0 +1,49 @@
+package entity
+
+import "time"
+
+// StockSnapshot is the closing stock of a SKU in a store on a day, valued at
+// the SKU price when the snapshot was taken
+type StockSnapshot struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	SnapshotDate time.Time `json:"snapshot_date" gorm:"type:date;not null;uniqueIndex:idx_stock_snapshots_day"`
+	StoreID      string    `json:"store_id" gorm:"type:uuid;not null;uniqueIndex:idx_stock_snapshots_day"`
+	SKUID        string    `json:"sku_id" gorm:"column:sku_id;type:uuid;not null;uniqueIndex:idx_stock_snapshots_day;index"`
+	Quantity     float64   `json:"quantity" gorm:"type:decimal(15,3);not null"`
+	UnitCost     float64   `json:"unit_cost" gorm:"type:decimal(15,2);not null;default:0"`
+	Value        float64   `json:"value" gorm:"type:decimal(15,2);not null;default:0"`
+	CreatedAt    time.Time `json:"created_at"`
+	SKU          *SKU      `json:"sku,omitempty" gorm:"foreignKey:SKUID"`
+	Store        *Store    `json:"store,omitempty" gorm:"foreignKey:StoreID"`
+}
+
+// StockSnapshotFilter represents filters for reading the stock on a day
+type StockSnapshotFilter struct {
+	Date    time.Time `json:"date"`
+	StoreID string    `json:"store_id,omitempty"`
+	SKUID   string    `json:"sku_id,omitempty"`
+}
+
+// StockSnapshotReport is the stock of the SKUs in the stores at the close of
+// a day, from the latest snapshot taken on or before it
+type StockSnapshotReport struct {
+	Date          time.Time       `json:"date"`
+	SnapshotDate  time.Time       `json:"snapshot_date"`
+	StoreID       string          `json:"store_id,omitempty"`
+	SKUID         string          `json:"sku_id,omitempty"`
+	Lines         []StockSnapshot `json:"lines"`
+	TotalQuantity float64         `json:"total_quantity"`
+	TotalValue    float64         `json:"total_value"`
+}
+
+// StockSnapshotRequest represents the request to take the snapshot of a past day
+type StockSnapshotRequest struct {
+	Date string `json:"date" binding:"required"` // YYYY-MM-DD
+}
+
+// StockSnapshotResult reports the outcome of taking the snapshot of a day
+type StockSnapshotResult struct {
+	SnapshotDate time.Time `json:"snapshot_date"`
+	Lines        int       `json:"lines"`
+	TotalValue   float64   `json:"total_value"`
+}
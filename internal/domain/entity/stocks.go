@@ -6,40 +6,53 @@ import (
 
 // Stock represents stock of an item in a store
 type Stock struct {
-	ID              string    `json:"id" gorm:"primaryKey;type:uuid"`
-	SKUID           string    `json:"sku_id" gorm:"not null"`
-	StoreID         string    `json:"store_id" gorm:"not null"`
-	Quantity        float64   `json:"quantity" gorm:"not null;default:0"`
-	BinLocation     string    `json:"bin_location"`
-	ShelfNumber     string    `json:"shelf_number"`
-	ZoneCode        string    `json:"zone_code"`
-	BatchNumber     string    `json:"batch_number"`
-	LotNumber       string    `json:"lot_number"`
-	ManufactureDate time.Time `json:"manufacture_date"`
-	ExpiryDate      time.Time `json:"expiry_date"`
-	CreatedAt       time.Time `json:"created_at" gorm:"autoCreateTime"`
-	UpdatedAt       time.Time `json:"updated_at" gorm:"autoUpdateTime"`
-	SKU             *SKU      `json:"sku,omitempty" gorm:"foreignKey:SKUID"`
-	Store           *Store    `json:"store,omitempty" gorm:"foreignKey:StoreID"`
+	ID                  string    `json:"id" gorm:"primaryKey;type:uuid"`
+	SKUID               string    `json:"sku_id" gorm:"column:sku_id;not null"`
+	StoreID             string    `json:"store_id" gorm:"not null"`
+	Quantity            float64   `json:"quantity" gorm:"not null;default:0"`
+	QuarantinedQuantity float64   `json:"quarantined_quantity" gorm:"not null;default:0"` // part of the quantity on quality hold, which cannot be picked
+	ConsignedQuantity   float64   `json:"consigned_quantity" gorm:"not null;default:0"`   // part of the quantity vendors still own on consignment
+	BinLocation         string    `json:"bin_location"`
+	ShelfNumber         string    `json:"shelf_number"`
+	ZoneCode            string    `json:"zone_code"`
+	BatchNumber         string    `json:"batch_number"`
+	LotNumber           string    `json:"lot_number"`
+	ManufactureDate     time.Time `json:"manufacture_date"`
+	ExpiryDate          time.Time `json:"expiry_date"`
+	CreatedAt           time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt           time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+	SKU                 *SKU      `json:"sku,omitempty" gorm:"foreignKey:SKUID"`
+	Store               *Store    `json:"store,omitempty" gorm:"foreignKey:StoreID"`
+}
+
+// Available returns the quantity that can be picked, excluding quarantined stock
+func (s *Stock) Available() float64 {
+	return s.Quantity - s.QuarantinedQuantity
+}
+
+// Owned returns the quantity the company owns, excluding consignment stock
+func (s *Stock) Owned() float64 {
+	return s.Quantity - s.ConsignedQuantity
 }
 
 // StockEntry represents a stock movement entry
 type StockEntry struct {
-	ID              string    `json:"id" gorm:"primaryKey;type:uuid"`
-	SKUID           string    `json:"sku_id" gorm:"not null"`
-	StoreID         string    `json:"store_id" gorm:"not null"`
-	Type            string    `json:"type" gorm:"not null"` // IN, OUT
-	Quantity        float64   `json:"quantity" gorm:"not null"`
-	BatchNumber     string    `json:"batch_number"`
-	LotNumber       string    `json:"lot_number"`
-	ManufactureDate time.Time `json:"manufacture_date"`
-	ExpiryDate      time.Time `json:"expiry_date"`
-	Reference       string    `json:"reference"`
-	Note            string    `json:"note"`
-	CreatedAt       time.Time `json:"created_at" gorm:"autoCreateTime"`
-	CreatedBy       string    `json:"created_by" gorm:"not null"`
-	SKU             *SKU      `json:"sku,omitempty" gorm:"foreignKey:SKUID"`
-	Store           *Store    `json:"store,omitempty" gorm:"foreignKey:StoreID"`
+	ID                  string    `json:"id" gorm:"primaryKey;type:uuid"`
+	SKUID               string    `json:"sku_id" gorm:"column:sku_id;not null"`
+	StoreID             string    `json:"store_id" gorm:"not null"`
+	Type                string    `json:"type" gorm:"not null"` // IN, OUT
+	Quantity            float64   `json:"quantity" gorm:"not null"`
+	BatchNumber         string    `json:"batch_number"`
+	LotNumber           string    `json:"lot_number"`
+	ManufactureDate     time.Time `json:"manufacture_date"`
+	ExpiryDate          time.Time `json:"expiry_date"`
+	Reference           string    `json:"reference"`
+	Note                string    `json:"note"`
+	ConsignmentVendorID *uint     `json:"consignment_vendor_id,omitempty"` // vendor whose consignment stock is received or returned
+	CreatedAt           time.Time `json:"created_at" gorm:"autoCreateTime"`
+	CreatedBy           string    `json:"created_by" gorm:"not null"`
+	SKU                 *SKU      `json:"sku,omitempty" gorm:"foreignKey:SKUID"`
+	Store               *Store    `json:"store,omitempty" gorm:"foreignKey:StoreID"`
 }
 
 // StockHistory represents a history of stock changes
@@ -57,6 +70,11 @@ type StockHistory struct {
 	Stock       *Stock    `json:"stock,omitempty" gorm:"foreignKey:StockID"`
 }
 
+// TableName specifies the table name for StockHistory
+func (StockHistory) TableName() string {
+	return "stock_history"
+}
+
 // StockFilter represents filters for searching stocks
 type StockFilter struct {
 	SKUID          string    `json:"sku_id,omitempty"`
@@ -71,3 +89,15 @@ type StockFilter struct {
 	ExpiryDateFrom time.Time `json:"expiry_date_from,omitempty"`
 	ExpiryDateTo   time.Time `json:"expiry_date_to,omitempty"`
 }
+
+// StockEntryFilter represents filters for searching stock entries
+type StockEntryFilter struct {
+	SKUID     string     `json:"sku_id,omitempty"`
+	StoreID   string     `json:"store_id,omitempty"`
+	Type      string     `json:"type,omitempty"` // IN, OUT
+	Reference string     `json:"reference,omitempty"`
+	StartDate *time.Time `json:"start_date,omitempty"`
+	EndDate   *time.Time `json:"end_date,omitempty"`
+	Page      int        `json:"page,omitempty"`
+	PageSize  int        `json:"page_size,omitempty"`
+}
@@ -0,0 +1,68 @@
+package entity
+
+import "time"
+
+// SlowQuery represents an aggregated statement captured by pg_stat_statements
+type SlowQuery struct {
+	Query           string  `json:"query"`
+	Calls           int64   `json:"calls"`
+	TotalTimeMs     float64 `json:"total_time_ms"`
+	MeanTimeMs      float64 `json:"mean_time_ms"`
+	MaxTimeMs       float64 `json:"max_time_ms"`
+	Rows            int64   `json:"rows"`
+	SharedBlksHit   int64   `json:"shared_blks_hit"`
+	SharedBlksRead  int64   `json:"shared_blks_read"`
+	CacheHitPercent float64 `json:"cache_hit_percent"`
+}
+
+// SlowQueryFilter represents filters for querying slow statements
+type SlowQueryFilter struct {
+	MinMeanTimeMs float64 `json:"min_mean_time_ms,omitempty"`
+	Limit         int     `json:"limit,omitempty"`
+}
+
+// DBPoolStats represents connection pool usage of the application database
+type DBPoolStats struct {
+	MaxOpenConnections int     `json:"max_open_connections"`
+	OpenConnections    int     `json:"open_connections"`
+	InUse              int     `json:"in_use"`
+	Idle               int     `json:"idle"`
+	WaitCount          int64   `json:"wait_count"`
+	WaitDurationMs     int64   `json:"wait_duration_ms"`
+	MaxIdleClosed      int64   `json:"max_idle_closed"`
+	MaxIdleTimeClosed  int64   `json:"max_idle_time_closed"`
+	MaxLifetimeClosed  int64   `json:"max_lifetime_closed"`
+	SaturationPercent  float64 `json:"saturation_percent"`
+}
+
+// CacheStats represents the use of the master data cache since the server started
+type CacheStats struct {
+	Driver     string                `json:"driver"` // empty when caching is off
+	TTLSeconds int                   `json:"ttl_seconds"`
+	Namespaces []CacheNamespaceStats `json:"namespaces"`
+}
+
+// CacheNamespaceStats represents the use of the cached values of one kind
+type CacheNamespaceStats struct {
+	Name           string  `json:"name"`
+	Hits           int64   `json:"hits"`
+	Misses         int64   `json:"misses"`
+	Errors         int64   `json:"errors"`
+	Invalidations  int64   `json:"invalidations"`
+	HitRatePercent float64 `json:"hit_rate_percent"`
+}
+
+// SandboxResetResult reports the master data copied into a freshly reset sandbox
+type SandboxResetResult struct {
+	Schema     string           `json:"schema"`
+	CopiedRows map[string]int64 `json:"copied_rows"`
+	ResetAt    time.Time        `json:"reset_at"`
+}
+
+// ArchiveRunResult reports the rows moved to the archive tables by one archival run
+type ArchiveRunResult struct {
+	Cutoff     time.Time        `json:"cutoff"`
+	MovedRows  map[string]int64 `json:"moved_rows"`
+	StartedAt  time.Time        `json:"started_at"`
+	FinishedAt time.Time        `json:"finished_at"`
+}
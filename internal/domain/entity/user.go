@@ -14,8 +14,10 @@ const (
 
 type User struct {
 	ID                 uint       `json:"id" gorm:"primaryKey"`
+	ExternalID         string     `json:"external_id,omitempty" gorm:"type:varchar(255);index"` // ID in the corporate directory
 	Username           string     `json:"username" gorm:"unique;not null"`
 	Email              string     `json:"email" gorm:"unique;not null"`
+	Phone              string     `json:"phone,omitempty" gorm:"type:varchar(20)"` // E.164 number for SMS notifications
 	Password           string     `json:"-" gorm:"not null"`
 	RoleID             uint       `json:"role_id" gorm:"not null"`
 	Role               *Role      `json:"role" gorm:"foreignKey:RoleID"`
@@ -0,0 +1,84 @@
+package entity
+
+import "time"
+
+// LoginAttempt records a sign-in attempt, successful or not. UserID is set when
+// the email belongs to a user.
+type LoginAttempt struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    *uint     `json:"user_id,omitempty" gorm:"index"`
+	Email     string    `json:"email" gorm:"type:varchar(255);not null;index"`
+	Success   bool      `json:"success" gorm:"not null"`
+	Reason    string    `json:"reason,omitempty" gorm:"type:varchar(100)"` // why a failed attempt was refused
+	IP        string    `json:"ip" gorm:"type:varchar(45);index"`
+	UserAgent string    `json:"user_agent" gorm:"type:text"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+}
+
+// LoginAttemptFilter represents filters for listing login attempts
+type LoginAttemptFilter struct {
+	UserID    uint       `json:"user_id,omitempty"`
+	Email     string     `json:"email,omitempty"`
+	IP        string     `json:"ip,omitempty"`
+	Success   *bool      `json:"success,omitempty"`
+	StartDate *time.Time `json:"start_date,omitempty"`
+	EndDate   *time.Time `json:"end_date,omitempty"`
+	Page      int        `json:"page,omitempty"`
+	PageSize  int        `json:"page_size,omitempty"`
+}
+
+// UserActivityFilter represents filters for summarizing user activity
+type UserActivityFilter struct {
+	UserID    uint       `json:"user_id,omitempty"`
+	StartDate *time.Time `json:"start_date,omitempty"`
+	EndDate   *time.Time `json:"end_date,omitempty"`
+}
+
+// UserActivitySummary is a user's sign-ins and audited actions over a period.
+// LastSeen is the latest sign-in or audited request, whichever is later.
+type UserActivitySummary struct {
+	UserID       uint             `json:"user_id"`
+	Username     string           `json:"username"`
+	Email        string           `json:"email"`
+	Role         string           `json:"role,omitempty"`
+	Status       UserStatus       `json:"status"`
+	LastLogin    *time.Time       `json:"last_login,omitempty"`
+	LastSeen     *time.Time       `json:"last_seen,omitempty"`
+	Logins       int64            `json:"logins"`
+	FailedLogins int64            `json:"failed_logins"`
+	Actions      int64            `json:"actions"`
+	Modules      map[string]int64 `json:"modules"` // audited actions per API module, e.g. "skus"
+}
+
+// FailedLoginSource counts the failed sign-ins from an email or IP address
+type FailedLoginSource struct {
+	Key         string    `json:"key"`
+	Attempts    int64     `json:"attempts"`
+	LastAttempt time.Time `json:"last_attempt"`
+}
+
+// FailedLoginReport is the failed sign-ins over a period for security review,
+// with the emails and IP addresses they came from most often
+type FailedLoginReport struct {
+	Total     int64               `json:"total"`
+	ByEmail   []FailedLoginSource `json:"by_email"`
+	ByIP      []FailedLoginSource `json:"by_ip"`
+	Attempts  []LoginAttempt      `json:"attempts"`
+	Page      int                 `json:"page"`
+	PageSize  int                 `json:"page_size"`
+	StartDate *time.Time          `json:"start_date,omitempty"`
+	EndDate   *time.Time          `json:"end_date,omitempty"`
+}
+
+// InactiveAccount is an active user who has not signed in for longer than the
+// inactivity threshold and is eligible for deactivation. Users who never signed
+// in are measured from their creation.
+type InactiveAccount struct {
+	UserID       uint       `json:"user_id"`
+	Username     string     `json:"username"`
+	Email        string     `json:"email"`
+	Role         string     `json:"role,omitempty"`
+	LastLogin    *time.Time `json:"last_login,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	InactiveDays int        `json:"inactive_days"`
+}
@@ -0,0 +1,74 @@
+package entity
+
+import "time"
+
+// RoleMapping assigns users in a corporate directory group to a role when they
+// are provisioned
+type RoleMapping struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	ExternalGroup string    `json:"external_group" gorm:"type:varchar(255);uniqueIndex;not null"`
+	RoleID        uint      `json:"role_id" gorm:"not null"`
+	Role          *Role     `json:"role,omitempty" gorm:"foreignKey:RoleID"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// RoleMappingRequest represents the request to map a directory group to a role
+type RoleMappingRequest struct {
+	ExternalGroup string `json:"external_group" binding:"required"`
+	RoleID        uint   `json:"role_id" binding:"required"`
+}
+
+// ProvisionUserInput is a user as described by the corporate directory. Groups
+// are matched against the role mappings, then against role names.
+type ProvisionUserInput struct {
+	ExternalID string
+	Username   string
+	Email      string
+	Groups     []string
+	Active     bool
+}
+
+// UserImportAction is what a user import did, or would do, with a row
+type UserImportAction string
+
+const (
+	UserImportCreated     UserImportAction = "CREATED"
+	UserImportUpdated     UserImportAction = "UPDATED"
+	UserImportDeactivated UserImportAction = "DEACTIVATED"
+	UserImportUnchanged   UserImportAction = "UNCHANGED"
+	UserImportFailed      UserImportAction = "FAILED"
+)
+
+// UserImportResult is the outcome of one row of a user import
+type UserImportResult struct {
+	Row      int              `json:"row"`
+	Email    string           `json:"email"`
+	Username string           `json:"username,omitempty"`
+	Role     string           `json:"role,omitempty"`
+	UserID   uint             `json:"user_id,omitempty"`
+	Action   UserImportAction `json:"action"`
+	Error    string           `json:"error,omitempty"`
+}
+
+// UserImportReport summarizes a bulk user import. A dry run reports what the
+// import would do without changing any user.
+type UserImportReport struct {
+	DryRun      bool               `json:"dry_run"`
+	Rows        int                `json:"rows"`
+	Created     int                `json:"created"`
+	Updated     int                `json:"updated"`
+	Deactivated int                `json:"deactivated"`
+	Unchanged   int                `json:"unchanged"`
+	Failed      int                `json:"failed"`
+	Results     []UserImportResult `json:"results"`
+}
+
+// ProvisionedUserFilter represents filters for listing users through SCIM
+type ProvisionedUserFilter struct {
+	ExternalID string
+	Username   string
+	Email      string
+	Offset     int
+	Limit      int
+}
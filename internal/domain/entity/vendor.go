@@ -9,7 +9,7 @@ import (
 
 // Error definitions
 var (
-	ErrInvalidRating = errors.New("rating must be between 0 and 5")
+	ErrInvalidRating = NewError(ErrCodeInvalidArgument, "rating must be between 0 and 5")
 )
 
 // Vendor represents a supplier of goods or services
@@ -26,8 +26,10 @@ type Vendor struct {
 	TaxID         string         `json:"tax_id"`
 	PaymentMethod string         `json:"payment_method"`
 	PaymentDays   int            `json:"payment_days"`
+	LeadTimeDays  int            `json:"lead_time_days" gorm:"default:0"` // business days from order to delivery
 	Currency      string         `json:"currency"`
 	Rating        float64        `json:"rating" gorm:"type:decimal(3,2);default:0"`
+	CustomFields  JSONMap        `json:"custom_fields" gorm:"type:jsonb"` // values of the custom fields defined on vendors, by key
 	CreatedAt     time.Time      `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt     time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
 	Products      []Product      `json:"products,omitempty" gorm:"many2many:vendor_products"`
@@ -116,12 +118,13 @@ func (vc VendorContacts) Value() (driver.Value, error) {
 
 // VendorFilter represents filters for searching vendors
 type VendorFilter struct {
-	Code       string   `json:"code,omitempty"`
-	Name       string   `json:"name,omitempty"`
-	Type       string   `json:"type,omitempty"`
-	Country    string   `json:"country,omitempty"`
-	ProductIDs []uint   `json:"product_ids,omitempty"`
-	MinRating  *float64 `json:"min_rating,omitempty"`
+	Code         string   `json:"code,omitempty"`
+	Name         string   `json:"name,omitempty"`
+	Type         string   `json:"type,omitempty"`
+	Country      string   `json:"country,omitempty"`
+	ProductIDs   []uint   `json:"product_ids,omitempty"`
+	MinRating    *float64 `json:"min_rating,omitempty"`
+	CustomFields JSONMap  `json:"custom_fields,omitempty"` // custom field values the vendors hold
 }
 
 // VendorRepository defines the interface for vendor data access
@@ -154,3 +157,29 @@ type VendorRepository interface {
 	GetVendorRatings(vendorID uint) ([]VendorRating, error)
 	GetVendorAverageRating(vendorID uint) (float64, error)
 }
+
+// VendorScorecard holds delivery and quality KPIs computed from a vendor's
+// purchase orders and receipts
+type VendorScorecard struct {
+	VendorID            uint    `json:"vendor_id"`
+	VendorCode          string  `json:"vendor_code"`
+	VendorName          string  `json:"vendor_name"`
+	OrderCount          int     `json:"order_count"`
+	ReceiptCount        int     `json:"receipt_count"`
+	OnTimeDeliveryRate  float64 `json:"on_time_delivery_rate"`
+	RejectionRate       float64 `json:"rejection_rate"`
+	ReturnRate          float64 `json:"return_rate"` // client returns of the vendor's SKUs against received quantity
+	PriceVariance       float64 `json:"price_variance"`
+	AverageLeadTimeDays float64 `json:"average_lead_time_days"`
+	Rating              float64 `json:"rating"`
+	Score               float64 `json:"score"`
+	Rank                int     `json:"rank,omitempty"`
+}
+
+// VendorScorecardFilter represents filters for computing vendor scorecards
+type VendorScorecardFilter struct {
+	StartDate *time.Time `json:"start_date,omitempty"`
+	EndDate   *time.Time `json:"end_date,omitempty"`
+	VendorIDs []uint     `json:"vendor_ids,omitempty"`
+	Limit     int        `json:"limit,omitempty"`
+}
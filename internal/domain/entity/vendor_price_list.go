@@ -0,0 +1,73 @@
+package entity
+
+import "time"
+
+// VendorPriceList is the prices a vendor quotes for SKUs over a validity
+// period, with quantity breaks. Where several lists of a vendor are valid on
+// a day, the one valid from the latest date applies.
+type VendorPriceList struct {
+	ID           uint                  `json:"id" gorm:"primaryKey"`
+	VendorID     uint                  `json:"vendor_id" gorm:"not null;index"`
+	Name         string                `json:"name" gorm:"type:varchar(100);not null"`
+	CurrencyCode string                `json:"currency_code" gorm:"type:varchar(3);not null"`
+	ValidFrom    time.Time             `json:"valid_from" gorm:"type:date;not null"`
+	ValidTo      *time.Time            `json:"valid_to,omitempty" gorm:"type:date"` // open-ended when empty
+	Items        []VendorPriceListItem `json:"items,omitempty" gorm:"foreignKey:PriceListID"`
+	CreatedAt    time.Time             `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt    time.Time             `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// VendorPriceListItem is the unit price of a SKU from a minimum quantity ordered
+type VendorPriceListItem struct {
+	ID          uint    `json:"id" gorm:"primaryKey"`
+	PriceListID uint    `json:"price_list_id" gorm:"not null;uniqueIndex:idx_vendor_price_list_items_break"`
+	SKUID       string  `json:"sku_id" gorm:"type:uuid;not null;uniqueIndex:idx_vendor_price_list_items_break"`
+	MinQuantity float64 `json:"min_quantity" gorm:"type:decimal(15,3);not null;default:0;uniqueIndex:idx_vendor_price_list_items_break"`
+	UnitPrice   float64 `json:"unit_price" gorm:"type:decimal(15,2);not null"`
+}
+
+// VendorPriceListRequest represents the data of a vendor price list
+type VendorPriceListRequest struct {
+	Name         string                       `json:"name" binding:"required"`
+	CurrencyCode string                       `json:"currency_code"` // the vendor's currency by default
+	ValidFrom    time.Time                    `json:"valid_from" binding:"required"`
+	ValidTo      *time.Time                   `json:"valid_to,omitempty"`
+	Items        []VendorPriceListItemRequest `json:"items" binding:"required,min=1,dive"`
+}
+
+// VendorPriceListItemRequest represents a price break of a vendor price list
+type VendorPriceListItemRequest struct {
+	SKUID       string  `json:"sku_id" binding:"required"`
+	MinQuantity float64 `json:"min_quantity" binding:"min=0"`
+	UnitPrice   float64 `json:"unit_price" binding:"min=0"`
+}
+
+// PurchasePriceSource is where the price of a purchase order line generated
+// from a request came from
+type PurchasePriceSource string
+
+const (
+	PurchasePriceList         PurchasePriceSource = "PRICE_LIST"    // the vendor's price list
+	PurchasePriceLastPurchase PurchasePriceSource = "LAST_PURCHASE" // the price last paid to the vendor, without a list price
+	PurchasePriceSKU          PurchasePriceSource = "SKU"           // the SKU's price, without a list price or earlier purchase
+)
+
+// VendorPrice is the unit price a vendor's price list gives a SKU for a
+// quantity on a day
+type VendorPrice struct {
+	VendorID     uint    `json:"vendor_id"`
+	SKUID        string  `json:"sku_id"`
+	Quantity     float64 `json:"quantity"`
+	UnitPrice    float64 `json:"unit_price"`
+	CurrencyCode string  `json:"currency_code"`
+	PriceListID  uint    `json:"price_list_id"`
+	MinQuantity  float64 `json:"min_quantity"` // quantity break the price applies from
+}
+
+// LastPurchasePrice is the unit price a SKU was last ordered at
+type LastPurchasePrice struct {
+	PurchaseOrderID string    `json:"purchase_order_id"`
+	OrderDate       time.Time `json:"order_date"`
+	UnitPrice       float64   `json:"unit_price"`
+	BaseUnitPrice   float64   `json:"base_unit_price"` // at the order rate
+}
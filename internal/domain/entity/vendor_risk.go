@@ -0,0 +1,109 @@
+package entity
+
+import "time"
+
+// VendorRiskLevel grades a vendor's risk score against the alert threshold
+type VendorRiskLevel string
+
+const (
+	VendorRiskLow    VendorRiskLevel = "LOW"    // below half the threshold
+	VendorRiskMedium VendorRiskLevel = "MEDIUM" // at least half the threshold
+	VendorRiskHigh   VendorRiskLevel = "HIGH"   // at or above the threshold
+)
+
+// VendorRiskScore is the supply risk of a vendor, combining financial exposure,
+// single-source dependency and delivery performance. Component scores and the
+// overall score run from 0 (no risk) to 100. Amounts are in the base currency.
+type VendorRiskScore struct {
+	VendorID           uint            `json:"vendor_id" gorm:"primaryKey;autoIncrement:false"`
+	VendorCode         string          `json:"vendor_code"`
+	VendorName         string          `json:"vendor_name"`
+	OpenOrderCount     int             `json:"open_order_count"`
+	OpenOrderValue     float64         `json:"open_order_value" gorm:"type:decimal(15,2)"` // ordered but not yet received
+	Prepayments        float64         `json:"prepayments" gorm:"type:decimal(15,2)"`      // paid beyond the value received
+	SingleSourceSKUs   int             `json:"single_source_skus"`                         // SKUs with no other source
+	OnTimeDeliveryRate float64         `json:"on_time_delivery_rate"`
+	RejectionRate      float64         `json:"rejection_rate"`
+	ExposureScore      float64         `json:"exposure_score"`
+	DependencyScore    float64         `json:"dependency_score"`
+	DeliveryScore      float64         `json:"delivery_score"`
+	Score              float64         `json:"score" gorm:"index"`
+	Level              VendorRiskLevel `json:"level" gorm:"type:varchar(10);index"`
+	ComputedAt         time.Time       `json:"computed_at"`
+}
+
+// TableName specifies the table name for VendorRiskScore
+func (VendorRiskScore) TableName() string {
+	return "vendor_risk_scores"
+}
+
+// VendorRiskAlert is raised when a vendor's risk score reaches the threshold
+// after having been below it
+type VendorRiskAlert struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	VendorID       uint       `json:"vendor_id" gorm:"not null;index"`
+	VendorName     string     `json:"vendor_name"`
+	Score          float64    `json:"score" gorm:"type:decimal(5,2);not null"`
+	PreviousScore  float64    `json:"previous_score" gorm:"type:decimal(5,2)"`
+	Threshold      float64    `json:"threshold" gorm:"type:decimal(5,2);not null"`
+	Reasons        string     `json:"reasons" gorm:"type:text"`
+	AcknowledgedAt *time.Time `json:"acknowledged_at,omitempty"`
+	AcknowledgedBy *uint      `json:"acknowledged_by,omitempty"`
+	CreatedAt      time.Time  `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// VendorRiskFilter represents filters on vendor risk scores
+type VendorRiskFilter struct {
+	Level    VendorRiskLevel `json:"level,omitempty"`
+	MinScore float64         `json:"min_score,omitempty"`
+}
+
+// VendorRiskAlertFilter represents filters for listing vendor risk alerts
+type VendorRiskAlertFilter struct {
+	VendorID     uint  `json:"vendor_id,omitempty"`
+	Acknowledged *bool `json:"acknowledged,omitempty"`
+	Page         int   `json:"page,omitempty"`
+	PageSize     int   `json:"page_size,omitempty"`
+}
+
+// VendorExposure is the value a vendor has been ordered but not yet delivered,
+// and paid for but not yet delivered, across its open purchase orders
+type VendorExposure struct {
+	VendorID       uint    `json:"vendor_id"`
+	OpenOrderCount int     `json:"open_order_count"`
+	OpenOrderValue float64 `json:"open_order_value"`
+	Prepayments    float64 `json:"prepayments"`
+}
+
+// VendorRiskRunResult summarizes a vendor risk scoring run
+type VendorRiskRunResult struct {
+	Vendors    int               `json:"vendors"`
+	AtRisk     int               `json:"at_risk"`
+	Threshold  float64           `json:"threshold"`
+	Alerts     []VendorRiskAlert `json:"alerts"`
+	ComputedAt time.Time         `json:"computed_at"`
+}
+
+// SingleSourceSKU is a SKU with one source of supply: its designated vendor with
+// no purchases from others, or the only vendor it was ordered from in the lookback
+// window. Open demand is the quantity confirmed and processing sales orders still need.
+type SingleSourceSKU struct {
+	SKUID           string     `json:"sku_id"`
+	SKUCode         string     `json:"sku_code"`
+	SKUName         string     `json:"sku_name"`
+	Category        string     `json:"category"`
+	VendorID        uint       `json:"vendor_id"`
+	VendorCode      string     `json:"vendor_code"`
+	VendorName      string     `json:"vendor_name"`
+	Designated      bool       `json:"designated"`                // the vendor is set on the SKU
+	LastOrderDate   *time.Time `json:"last_order_date,omitempty"` // unset when not ordered in the lookback window
+	OpenDemand      float64    `json:"open_demand"`
+	OpenSalesOrders int        `json:"open_sales_orders"`
+	OnHand          float64    `json:"on_hand"`
+}
+
+// SingleSourceFilter represents filters on the single-source dependency report
+type SingleSourceFilter struct {
+	VendorID   uint `json:"vendor_id,omitempty"`
+	WithDemand bool `json:"with_demand,omitempty"` // only SKUs with open demand
+}
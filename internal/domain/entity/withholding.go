@@ -0,0 +1,63 @@
+package entity
+
+import "time"
+
+// WithholdingTaxRate is the percentage of the net amount of supplier invoices
+// withheld from the payments to a vendor, for a single vendor or for every
+// vendor of a country. A vendor's own rate takes precedence over its
+// country's.
+type WithholdingTaxRate struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	VendorID    *uint     `json:"vendor_id,omitempty" gorm:"uniqueIndex"`
+	Vendor      *Vendor   `json:"vendor,omitempty" gorm:"foreignKey:VendorID"`
+	Country     string    `json:"country,omitempty" gorm:"type:varchar(100);index"` // matched against the vendor's country, case-insensitively
+	Rate        float64   `json:"rate" gorm:"type:decimal(5,2);not null"`           // percentage
+	Description string    `json:"description,omitempty"`
+	Active      bool      `json:"active" gorm:"not null;default:true"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// WithholdingTaxRateRequest represents the request to create or update a
+// withholding tax rate, for either a vendor or a country
+type WithholdingTaxRateRequest struct {
+	VendorID    *uint   `json:"vendor_id"`
+	Country     string  `json:"country" binding:"max=100"`
+	Rate        float64 `json:"rate" binding:"gt=0,lte=100"`
+	Description string  `json:"description"`
+	Active      *bool   `json:"active"` // true by default
+}
+
+// WithholdingCertificateLine is a payment to a vendor tax was withheld from
+type WithholdingCertificateLine struct {
+	PaymentID          int64     `json:"payment_id"`
+	PaymentNumber      string    `json:"payment_number"`
+	PaymentDate        time.Time `json:"payment_date"`
+	InvoiceID          int64     `json:"invoice_id"`
+	InvoiceNumber      string    `json:"invoice_number"`
+	WithholdingRate    float64   `json:"withholding_rate"`
+	CurrencyCode       string    `json:"currency_code"`
+	GrossAmount        float64   `json:"gross_amount"` // the invoice amount settled by the payment
+	WithheldAmount     float64   `json:"withheld_amount"`
+	NetAmount          float64   `json:"net_amount"` // paid to the vendor
+	BaseGrossAmount    float64   `json:"base_gross_amount"`
+	BaseWithheldAmount float64   `json:"base_withheld_amount"`
+}
+
+// WithholdingCertificate states the tax withheld from the completed payments
+// to a vendor over a period, in the base currency, for the vendor to claim
+// it back from the tax authority
+type WithholdingCertificate struct {
+	VendorID       uint                         `json:"vendor_id"`
+	VendorCode     string                       `json:"vendor_code"`
+	VendorName     string                       `json:"vendor_name"`
+	TaxID          string                       `json:"tax_id"`
+	Country        string                       `json:"country"`
+	StartDate      time.Time                    `json:"start_date"`
+	EndDate        time.Time                    `json:"end_date"`
+	CurrencyCode   string                       `json:"currency_code"`
+	Payments       []WithholdingCertificateLine `json:"payments"`
+	GrossAmount    float64                      `json:"gross_amount"`
+	WithheldAmount float64                      `json:"withheld_amount"`
+	NetAmount      float64                      `json:"net_amount"`
+}
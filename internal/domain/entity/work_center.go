@@ -0,0 +1,108 @@
+package entity
+
+import "time"
+
+// WorkCenter is a machine, line or cell in a manufacturing facility that
+// production orders are scheduled on. Its daily capacity is its shifts times the
+// hours per shift.
+type WorkCenter struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	FacilityID    uint      `json:"facility_id" gorm:"not null;index"`
+	Code          string    `json:"code" gorm:"type:varchar(50);uniqueIndex;not null"`
+	Name          string    `json:"name" gorm:"not null"`
+	ShiftsPerDay  int       `json:"shifts_per_day" gorm:"not null"`
+	HoursPerShift float64   `json:"hours_per_shift" gorm:"not null"`
+	WorksWeekends bool      `json:"works_weekends" gorm:"default:false"`
+	Active        bool      `json:"active" gorm:"default:true"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// DailyCapacity returns the hours the work center can produce on a working day
+func (w *WorkCenter) DailyCapacity() float64 {
+	return float64(w.ShiftsPerDay) * w.HoursPerShift
+}
+
+// WorksOn reports whether the given day is a working day of the work center
+func (w *WorkCenter) WorksOn(day time.Time) bool {
+	if w.WorksWeekends {
+		return true
+	}
+	weekday := day.Weekday()
+	return weekday != time.Saturday && weekday != time.Sunday
+}
+
+// WorkCenterRequest represents the request to create or update a work center
+type WorkCenterRequest struct {
+	FacilityID    uint    `json:"facility_id" binding:"required"`
+	Code          string  `json:"code" binding:"required"`
+	Name          string  `json:"name" binding:"required"`
+	ShiftsPerDay  int     `json:"shifts_per_day" binding:"required,gt=0"`
+	HoursPerShift float64 `json:"hours_per_shift" binding:"required,gt=0"`
+	WorksWeekends bool    `json:"works_weekends"`
+	Active        *bool   `json:"active"`
+}
+
+// ScheduleRequest represents the request to schedule or reschedule a production
+// order on a work center
+type ScheduleRequest struct {
+	WorkCenterID uint    `json:"work_center_id" binding:"required"`
+	StartDate    string  `json:"start_date"`                    // YYYY-MM-DD; after the work center's last scheduled order when empty
+	PlannedHours float64 `json:"planned_hours" binding:"gte=0"` // defaults to the BOM labor hours of the remaining quantity
+}
+
+// ScheduleFilter represents filters for the production schedule
+type ScheduleFilter struct {
+	FacilityID   uint
+	WorkCenterID uint
+	StartDate    time.Time
+	EndDate      time.Time
+}
+
+// ScheduledOrder is a production order's bar on the schedule
+type ScheduledOrder struct {
+	ProductionOrderID uint                  `json:"production_order_id"`
+	ProductID         uint                  `json:"product_id"`
+	Quantity          int                   `json:"quantity"`
+	CompletedQty      int                   `json:"completed_qty"`
+	Status            ProductionOrderStatus `json:"status"`
+	Start             time.Time             `json:"start"`
+	End               time.Time             `json:"end"` // last day, inclusive
+	PlannedHours      float64               `json:"planned_hours"`
+	Deadline          time.Time             `json:"deadline"`
+	Late              bool                  `json:"late"`       // ends after its deadline
+	Overloaded        bool                  `json:"overloaded"` // runs on a day the work center is overloaded
+}
+
+// WorkCenterLoad is a work center's planned hours against its capacity on a day
+type WorkCenterLoad struct {
+	Date               time.Time `json:"date"`
+	CapacityHours      float64   `json:"capacity_hours"`
+	LoadHours          float64   `json:"load_hours"`
+	UtilizationPercent float64   `json:"utilization_percent"`
+	Overloaded         bool      `json:"overloaded"`
+	ProductionOrderIDs []uint    `json:"production_order_ids"`
+}
+
+// WorkCenterSchedule is the schedule of one work center
+type WorkCenterSchedule struct {
+	WorkCenter     WorkCenter       `json:"work_center"`
+	Orders         []ScheduledOrder `json:"orders"`
+	Load           []WorkCenterLoad `json:"load"`
+	OverloadedDays int              `json:"overloaded_days"`
+}
+
+// ProductionSchedule is a Gantt-style feed of the production orders scheduled on
+// work centers in a period, with their daily load
+type ProductionSchedule struct {
+	StartDate   time.Time            `json:"start_date"`
+	EndDate     time.Time            `json:"end_date"`
+	WorkCenters []WorkCenterSchedule `json:"work_centers"`
+}
+
+// ScheduleResult is a scheduled production order with the overloaded days of its
+// work center while it runs
+type ScheduleResult struct {
+	Order     *ProductionOrder `json:"order"`
+	Overloads []WorkCenterLoad `json:"overloads"`
+}
@@ -0,0 +1,106 @@
+// Package accounting renders finance invoices, payments and journal entries
+// to the import files of external accounting systems. Every format posts the
+// records to the accounts of an Accounts chart, so the export use case only
+// decides which records go into a file.
+package accounting
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+)
+
+var ErrUnknownFormat = errors.New("unknown accounting export format")
+
+// Accounts names the accounts of the target chart of accounts records are
+// posted to, by name for QuickBooks or by code for Xero
+type Accounts struct {
+	Receivable              string
+	Payable                 string
+	Sales                   string
+	Purchases               string
+	SalesTax                string
+	PurchaseTax             string
+	Discounts               string
+	Bank                    string
+	WithholdingTax          string
+	DepreciationExpense     string
+	AccumulatedDepreciation string
+	ProvisionExpense        string
+	InventoryProvision      string
+
+	// Tax types of Xero import lines
+	XeroSalesTax    string
+	XeroPurchaseTax string
+	XeroJournalTax  string
+}
+
+// Batch is the set of records exported to one file
+type Batch struct {
+	Invoices []entity.FinanceInvoice
+	Payments []entity.FinancePayment
+	Journals []entity.AccountingJournalEntry
+}
+
+// File is a rendered export
+type File struct {
+	Name        string
+	ContentType string
+	Content     []byte
+}
+
+// Render writes a batch in a format to a file named after the given base name
+func Render(format entity.AccountingExportFormat, batch *Batch, accounts Accounts, name string) (*File, error) {
+	switch format {
+	case entity.AccountingExportIIF:
+		content, err := renderIIF(batch, accounts)
+		if err != nil {
+			return nil, err
+		}
+		return &File{Name: name + ".iif", ContentType: "text/plain; charset=utf-8", Content: content}, nil
+	case entity.AccountingExportXero:
+		content, err := renderXero(batch, accounts)
+		if err != nil {
+			return nil, err
+		}
+		return &File{Name: name + ".zip", ContentType: "application/zip", Content: content}, nil
+	case entity.AccountingExportJSON:
+		content, err := renderJSON(batch)
+		if err != nil {
+			return nil, err
+		}
+		return &File{Name: name + ".json", ContentType: "application/json", Content: content}, nil
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnknownFormat, format)
+	}
+}
+
+// ValidFormat tells whether a format can be rendered
+func ValidFormat(format entity.AccountingExportFormat) bool {
+	switch format {
+	case entity.AccountingExportIIF, entity.AccountingExportXero, entity.AccountingExportJSON:
+		return true
+	}
+	return false
+}
+
+// round rounds an amount to cents
+func round(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// itemName describes an invoice line, falling back to its product ID
+func itemName(item entity.FinanceInvoiceItem) string {
+	if name := strings.TrimSpace(item.ProductName); name != "" {
+		return name
+	}
+	return fmt.Sprintf("Product %d", item.ProductID)
+}
+
+// isSupplier tells whether a payment or invoice is with a supplier
+func isSupplier(entityType string) bool {
+	return strings.EqualFold(entityType, "SUPPLIER")
+}
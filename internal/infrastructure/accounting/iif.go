@@ -0,0 +1,124 @@
+package accounting
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+)
+
+// iifSplit is a line of an IIF transaction. The first line of a transaction
+// is its TRNS row, the others its SPL rows; amounts are positive for debits
+// and add up to zero.
+type iifSplit struct {
+	account string
+	name    string
+	amount  float64
+	memo    string
+}
+
+// renderIIF writes a batch as a QuickBooks Desktop IIF file: sales invoices
+// as INVOICE, purchase invoices as BILL, payments as PAYMENT or BILLPMT and
+// journal entries as GENERAL JOURNAL transactions
+func renderIIF(batch *Batch, accounts Accounts) ([]byte, error) {
+	var buf bytes.Buffer
+	row := func(fields ...string) {
+		for i, field := range fields {
+			if i > 0 {
+				buf.WriteByte('\t')
+			}
+			buf.WriteString(iifField(field))
+		}
+		buf.WriteString("\r\n")
+	}
+	columns := []string{"TRNSTYPE", "DATE", "ACCNT", "NAME", "AMOUNT", "DOCNUM", "MEMO"}
+	row(append([]string{"!TRNS", "TRNSID"}, columns...)...)
+	row(append([]string{"!SPL", "SPLID"}, columns...)...)
+	row("!ENDTRNS")
+
+	transaction := func(trnsType string, date time.Time, docNum string, splits []iifSplit) {
+		// The first line takes what balances the others, so the transaction
+		// adds up to zero once every line is rounded to cents
+		var balance float64
+		for i := 1; i < len(splits); i++ {
+			splits[i].amount = round(splits[i].amount)
+			balance += splits[i].amount
+		}
+		splits[0].amount = round(-balance)
+
+		for i, split := range splits {
+			kind := "SPL"
+			if i == 0 {
+				kind = "TRNS"
+			}
+			row(kind, "", trnsType, date.Format("01/02/2006"), split.account, split.name,
+				strconv.FormatFloat(split.amount, 'f', 2, 64), docNum, split.memo)
+		}
+		row("ENDTRNS")
+	}
+
+	for _, invoice := range batch.Invoices {
+		supplier := invoice.Type == entity.FinancePurchaseInvoice
+		sign, trnsType, control := -1.0, "INVOICE", accounts.Receivable
+		line, tax := accounts.Sales, accounts.SalesTax
+		if supplier {
+			sign, trnsType, control = 1.0, "BILL", accounts.Payable
+			line, tax = accounts.Purchases, accounts.PurchaseTax
+		}
+
+		splits := []iifSplit{{account: control, name: invoice.EntityName, memo: invoice.Notes}}
+		for _, item := range invoice.Items {
+			splits = append(splits, iifSplit{account: line, name: invoice.EntityName, amount: sign * item.Subtotal, memo: itemName(item)})
+		}
+		if invoice.TaxTotal != 0 {
+			splits = append(splits, iifSplit{account: tax, name: invoice.EntityName, amount: sign * invoice.TaxTotal, memo: "Tax"})
+		}
+		if invoice.DiscountAmount != 0 {
+			splits = append(splits, iifSplit{account: accounts.Discounts, name: invoice.EntityName, amount: -sign * invoice.DiscountAmount, memo: "Discount"})
+		}
+		transaction(trnsType, invoice.IssueDate, invoice.InvoiceNumber, splits)
+	}
+
+	for _, payment := range batch.Payments {
+		memo := "Payment of " + payment.InvoiceNumber
+		if isSupplier(payment.EntityType) {
+			// The bank pays the net amount, the withheld tax is owed to the
+			// tax authority
+			splits := []iifSplit{
+				{account: accounts.Bank, name: payment.EntityName, memo: memo},
+				{account: accounts.Payable, name: payment.EntityName, amount: payment.Amount, memo: memo},
+			}
+			if payment.WithheldAmount != 0 {
+				splits = append(splits, iifSplit{account: accounts.WithholdingTax, name: payment.EntityName, amount: -payment.WithheldAmount, memo: "Withholding tax"})
+			}
+			transaction("BILLPMT", payment.PaymentDate, payment.PaymentNumber, splits)
+			continue
+		}
+		transaction("PAYMENT", payment.PaymentDate, payment.PaymentNumber, []iifSplit{
+			{account: accounts.Bank, name: payment.EntityName, memo: memo},
+			{account: accounts.Receivable, name: payment.EntityName, amount: -payment.Amount, memo: memo},
+		})
+	}
+
+	for _, journal := range batch.Journals {
+		splits := []iifSplit{{memo: journal.Memo}}
+		for i, line := range journal.Lines {
+			split := iifSplit{account: line.Account, amount: line.Debit - line.Credit, memo: journal.Memo}
+			if i == 0 {
+				splits[0].account = split.account
+				continue
+			}
+			splits = append(splits, split)
+		}
+		transaction("GENERAL JOURNAL", journal.Date, journal.Reference, splits)
+	}
+	return buf.Bytes(), nil
+}
+
+// iifField keeps tabs and line breaks, which delimit IIF fields and rows, out
+// of a value
+func iifField(value string) string {
+	return strings.NewReplacer("\t", " ", "\r", " ", "\n", " ").Replace(value)
+}
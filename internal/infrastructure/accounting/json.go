@@ -0,0 +1,35 @@
+package accounting
+
+import (
+	"encoding/json"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+)
+
+// jsonDocument is the generic export, for systems without an import format
+// of their own
+type jsonDocument struct {
+	Invoices       []entity.FinanceInvoice         `json:"invoices"`
+	Payments       []entity.FinancePayment         `json:"payments"`
+	JournalEntries []entity.AccountingJournalEntry `json:"journal_entries"`
+}
+
+// renderJSON writes a batch as one JSON document of its records as they are
+// kept
+func renderJSON(batch *Batch) ([]byte, error) {
+	doc := jsonDocument{
+		Invoices:       batch.Invoices,
+		Payments:       batch.Payments,
+		JournalEntries: batch.Journals,
+	}
+	if doc.Invoices == nil {
+		doc.Invoices = []entity.FinanceInvoice{}
+	}
+	if doc.Payments == nil {
+		doc.Payments = []entity.FinancePayment{}
+	}
+	if doc.JournalEntries == nil {
+		doc.JournalEntries = []entity.AccountingJournalEntry{}
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
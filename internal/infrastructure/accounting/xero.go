@@ -0,0 +1,119 @@
+package accounting
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"strconv"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+)
+
+// xeroDate is the date layout of Xero import templates for organisations
+// using day-first dates
+const xeroDate = "02/01/2006"
+
+// xeroInvoiceColumns are the columns of the sales invoice and bill templates
+var xeroInvoiceColumns = []string{
+	"*ContactName", "*InvoiceNumber", "Reference", "*InvoiceDate", "*DueDate",
+	"*Description", "*Quantity", "*UnitAmount", "Discount", "*AccountCode",
+	"*TaxType", "TaxAmount", "Currency",
+}
+
+// renderXero writes a batch as a zip of Xero import templates: sales invoices
+// and bills, payments as bank statement lines to reconcile against them, and
+// journal entries as manual journals. Templates without rows are left out.
+func renderXero(batch *Batch, accounts Accounts) ([]byte, error) {
+	var sales, bills, payments, journals [][]string
+
+	for _, invoice := range batch.Invoices {
+		rows := &sales
+		account, taxType := accounts.Sales, accounts.XeroSalesTax
+		if invoice.Type == entity.FinancePurchaseInvoice {
+			rows = &bills
+			account, taxType = accounts.Purchases, accounts.XeroPurchaseTax
+		}
+		// Xero takes discounts as a percentage of each line, so the invoice
+		// discount is spread over the lines in proportion
+		var discount string
+		if invoice.DiscountAmount != 0 && invoice.Subtotal != 0 {
+			discount = formatAmount(invoice.DiscountAmount / invoice.Subtotal * 100)
+		}
+		for _, item := range invoice.Items {
+			*rows = append(*rows, []string{
+				invoice.EntityName, invoice.InvoiceNumber, invoice.ReferenceID,
+				invoice.IssueDate.Format(xeroDate), invoice.DueDate.Format(xeroDate),
+				itemName(item), formatAmount(item.Quantity), formatAmount(item.UnitPrice), discount, account,
+				taxType, formatAmount(round(item.TaxAmount)), invoice.CurrencyCode,
+			})
+		}
+	}
+
+	for _, payment := range batch.Payments {
+		amount := payment.Amount
+		if isSupplier(payment.EntityType) {
+			amount = -(payment.Amount - payment.WithheldAmount)
+			if payment.WithheldAmount != 0 {
+				// The withheld tax settles the rest of the bill, owed to the
+				// tax authority instead
+				memo := "Withholding tax on " + payment.InvoiceNumber
+				withheld := round(payment.WithheldAmount)
+				journals = append(journals,
+					[]string{memo, payment.PaymentDate.Format(xeroDate), payment.PaymentNumber, accounts.Payable, accounts.XeroJournalTax, formatAmount(withheld)},
+					[]string{memo, payment.PaymentDate.Format(xeroDate), payment.PaymentNumber, accounts.WithholdingTax, accounts.XeroJournalTax, formatAmount(-withheld)},
+				)
+			}
+		}
+		payments = append(payments, []string{
+			payment.PaymentDate.Format(xeroDate), formatAmount(round(amount)), payment.EntityName,
+			"Payment of " + payment.InvoiceNumber, payment.PaymentNumber,
+		})
+	}
+
+	for _, journal := range batch.Journals {
+		for _, line := range journal.Lines {
+			journals = append(journals, []string{
+				journal.Memo, journal.Date.Format(xeroDate), journal.Reference, line.Account,
+				accounts.XeroJournalTax, formatAmount(round(line.Debit - line.Credit)),
+			})
+		}
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	templates := []struct {
+		name    string
+		columns []string
+		rows    [][]string
+	}{
+		{"SalesInvoices.csv", xeroInvoiceColumns, sales},
+		{"Bills.csv", xeroInvoiceColumns, bills},
+		{"BankStatement.csv", []string{"*Date", "*Amount", "Payee", "Description", "Reference"}, payments},
+		{"ManualJournals.csv", []string{"*Narration", "*Date", "Description", "*AccountCode", "*TaxRate", "*Amount"}, journals},
+	}
+	for _, template := range templates {
+		if len(template.rows) == 0 {
+			continue
+		}
+		file, err := archive.Create(template.name)
+		if err != nil {
+			return nil, err
+		}
+		w := csv.NewWriter(file)
+		if err := w.Write(template.columns); err != nil {
+			return nil, err
+		}
+		if err := w.WriteAll(template.rows); err != nil {
+			return nil, err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// formatAmount writes an amount without trailing zeros
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', -1, 64)
+}
@@ -44,3 +44,41 @@ func GetRoleFromContext(c *gin.Context) string {
 	}
 	return role.(string)
 }
+
+// GetClientIDFromContext extracts the portal client ID from the Gin context
+func GetClientIDFromContext(c *gin.Context) uint {
+	clientID, exists := c.Get("client_id")
+	if !exists {
+		return 0
+	}
+	id, _ := clientID.(uint)
+	return id
+}
+
+// Context keys of requests made with an impersonation token
+const (
+	ImpersonatorIDKey  = "impersonator_id"  // the admin acting as the user
+	ImpersonationIDKey = "impersonation_id" // the impersonation session
+)
+
+// GetImpersonatorIDFromContext extracts the admin acting as the user from the
+// Gin context, 0 when the request is not impersonated
+func GetImpersonatorIDFromContext(c *gin.Context) uint {
+	impersonatorID, exists := c.Get(ImpersonatorIDKey)
+	if !exists {
+		return 0
+	}
+	id, _ := impersonatorID.(uint)
+	return id
+}
+
+// GetImpersonationIDFromContext extracts the impersonation session from the
+// Gin context, 0 when the request is not impersonated
+func GetImpersonationIDFromContext(c *gin.Context) uint {
+	impersonationID, exists := c.Get(ImpersonationIDKey)
+	if !exists {
+		return 0
+	}
+	id, _ := impersonationID.(uint)
+	return id
+}
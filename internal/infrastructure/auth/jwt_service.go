@@ -14,12 +14,25 @@ type JWTService struct {
 	refreshTokenSecret []byte
 }
 
+// Token types distinguish staff access tokens from scoped customer portal tokens
+const (
+	TokenTypeAccess = "access"
+	TokenTypePortal = "portal"
+)
+
 type Claims struct {
 	jwt.RegisteredClaims
 	UserID      uint                `json:"user_id"`
 	Username    string              `json:"username"`
 	Role        string              `json:"role"`
 	Permissions []entity.Permission `json:"permissions"`
+	TokenType   string              `json:"token_type,omitempty"`
+	ClientID    uint                `json:"client_id,omitempty"`
+	// ImpersonatorID is the admin acting as the user in the impersonation
+	// session ImpersonationID
+	ImpersonatorID  uint `json:"impersonator_id,omitempty"`
+	ImpersonationID uint `json:"impersonation_id,omitempty"`
+	entity.AccessScope
 }
 
 func NewJWTService(accessSecret, refreshSecret string) *JWTService {
@@ -43,12 +56,66 @@ func (s *JWTService) GenerateAccessToken(user *entity.User) (string, error) {
 		Username:    user.Username,
 		Role:        user.Role.Name,
 		Permissions: user.Role.Permissions,
+		TokenType:   TokenTypeAccess,
+		AccessScope: user.Role.AccessScope(),
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString(s.accessTokenSecret)
 }
 
+// GenerateImpersonationToken issues an access token acting as the session's
+// user on behalf of its impersonator, valid until the session expires. It
+// comes without a refresh token.
+func (s *JWTService) GenerateImpersonationToken(session *entity.ImpersonationSession) (string, error) {
+	user := session.User
+	if user == nil || user.Role == nil || !user.IsActive() {
+		return "", errors.New("inactive user cannot be impersonated")
+	}
+
+	claims := &Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(session.ExpiresAt),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+		UserID:          user.ID,
+		Username:        user.Username,
+		Role:            user.Role.Name,
+		Permissions:     user.Role.Permissions,
+		TokenType:       TokenTypeAccess,
+		AccessScope:     user.Role.AccessScope(),
+		ImpersonatorID:  session.ImpersonatorID,
+		ImpersonationID: session.ID,
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(s.accessTokenSecret)
+}
+
+// GeneratePortalToken issues a read-only token scoped to a single client for the customer portal
+func (s *JWTService) GeneratePortalToken(client *entity.Client) (string, time.Time, error) {
+	expiry := time.Now().Add(24 * time.Hour)
+
+	claims := &Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expiry),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			Subject:   fmt.Sprintf("client:%d", client.ID),
+		},
+		Username:  client.Name,
+		TokenType: TokenTypePortal,
+		ClientID:  client.ID,
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	portalToken, err := token.SignedString(s.accessTokenSecret)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return portalToken, expiry, nil
+}
+
 func (s *JWTService) GenerateRefreshToken(user *entity.User) (string, time.Time, error) {
 	expiry := time.Now().Add(30 * 24 * time.Hour) // 7 days
 
@@ -0,0 +1,165 @@
+package bankfeed
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+)
+
+// openBankingDateTime is the ISO 8601 layout of Open Banking query parameters
+const openBankingDateTime = "2006-01-02T15:04:05"
+
+// OpenBankingProvider reads an account through the Account and Transaction
+// API of the UK Open Banking standard (v3.1), with an access token the
+// account holder consented to
+type OpenBankingProvider struct {
+	baseURL   string
+	accountID string
+	token     string
+	client    *http.Client
+}
+
+// NewOpenBankingProvider creates an adapter reading the account of the given
+// ID from the API at the given address
+func NewOpenBankingProvider(baseURL, accountID, token string, client *http.Client) *OpenBankingProvider {
+	return &OpenBankingProvider{
+		baseURL:   baseURL,
+		accountID: accountID,
+		token:     token,
+		client:    client,
+	}
+}
+
+type openBankingParty struct {
+	Name string `json:"Name"`
+}
+
+type openBankingTransaction struct {
+	TransactionID          string `json:"TransactionId"`
+	TransactionReference   string `json:"TransactionReference"`
+	CreditDebitIndicator   string `json:"CreditDebitIndicator"`
+	Status                 string `json:"Status"`
+	BookingDateTime        string `json:"BookingDateTime"`
+	TransactionInformation string `json:"TransactionInformation"`
+	Amount                 struct {
+		Amount   string `json:"Amount"`
+		Currency string `json:"Currency"`
+	} `json:"Amount"`
+	CreditorAccount *openBankingParty `json:"CreditorAccount"`
+	DebtorAccount   *openBankingParty `json:"DebtorAccount"`
+	MerchantDetails *struct {
+		MerchantName string `json:"MerchantName"`
+	} `json:"MerchantDetails"`
+}
+
+// Name returns OPEN_BANKING
+func (p *OpenBankingProvider) Name() string {
+	return ProviderOpenBanking
+}
+
+// FetchTransactions pages through the account's transactions booked since the
+// given time
+func (p *OpenBankingProvider) FetchTransactions(ctx context.Context, since time.Time) ([]entity.BankFeedTransaction, error) {
+	query := url.Values{"fromBookingDateTime": {since.UTC().Format(openBankingDateTime)}}
+	next := p.baseURL + "/accounts/" + url.PathEscape(p.accountID) + "/transactions?" + query.Encode()
+
+	var transactions []entity.BankFeedTransaction
+	for next != "" {
+		var page struct {
+			Data struct {
+				Transaction []openBankingTransaction `json:"Transaction"`
+			} `json:"Data"`
+			Links struct {
+				Next string `json:"Next"`
+			} `json:"Links"`
+		}
+		if err := p.get(ctx, next, &page); err != nil {
+			return nil, err
+		}
+		for _, raw := range page.Data.Transaction {
+			if !strings.EqualFold(raw.Status, "Booked") {
+				continue
+			}
+			transaction, err := raw.normalize()
+			if err != nil {
+				return nil, err
+			}
+			transactions = append(transactions, transaction)
+		}
+		next = page.Links.Next
+	}
+
+	sort.SliceStable(transactions, func(i, j int) bool {
+		return transactions[i].BookedAt.Before(transactions[j].BookedAt)
+	})
+	return transactions, nil
+}
+
+// normalize converts an Open Banking transaction to a bank feed transaction.
+// Debits are money paid out of the account, so their amount is negative.
+func (t *openBankingTransaction) normalize() (entity.BankFeedTransaction, error) {
+	if t.TransactionID == "" {
+		return entity.BankFeedTransaction{}, fmt.Errorf("%s transaction without an ID", ProviderOpenBanking)
+	}
+	amount, err := strconv.ParseFloat(t.Amount.Amount, 64)
+	if err != nil {
+		return entity.BankFeedTransaction{}, fmt.Errorf("invalid %s amount %q of transaction %s", ProviderOpenBanking, t.Amount.Amount, t.TransactionID)
+	}
+	bookedAt, err := time.Parse(time.RFC3339, t.BookingDateTime)
+	if err != nil {
+		return entity.BankFeedTransaction{}, fmt.Errorf("invalid %s booking time %q of transaction %s", ProviderOpenBanking, t.BookingDateTime, t.TransactionID)
+	}
+
+	counterparty := t.DebtorAccount
+	if strings.EqualFold(t.CreditDebitIndicator, "Debit") {
+		amount = -amount
+		counterparty = t.CreditorAccount
+	}
+	transaction := entity.BankFeedTransaction{
+		ExternalID:   t.TransactionID,
+		BookedAt:     bookedAt,
+		Amount:       amount,
+		CurrencyCode: t.Amount.Currency,
+		Description:  t.TransactionInformation,
+		Reference:    t.TransactionReference,
+	}
+	switch {
+	case counterparty != nil && counterparty.Name != "":
+		transaction.Counterparty = counterparty.Name
+	case t.MerchantDetails != nil:
+		transaction.Counterparty = t.MerchantDetails.MerchantName
+	}
+	return transaction, nil
+}
+
+// get sends a GET request to the API, decoding the response into out
+func (p *OpenBankingProvider) get(ctx context.Context, endpoint string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+p.token)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error calling %s: %w", ProviderOpenBanking, err)
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse(ProviderOpenBanking, resp); err != nil {
+		return err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("error decoding %s response: %w", ProviderOpenBanking, err)
+	}
+	return nil
+}
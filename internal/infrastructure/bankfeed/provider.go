@@ -0,0 +1,66 @@
+// Package bankfeed adapts the APIs of bank feed providers. A provider fetches
+// the booked transactions of one bank account in its own format and
+// normalizes them, so the bank feed use cases never see it. A provider is
+// added by writing its adapter and naming it in NewProvider.
+package bankfeed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+)
+
+// Providers with an adapter
+const (
+	ProviderOpenBanking = "OPEN_BANKING"
+)
+
+// requestTimeout bounds the time spent on one request to a provider
+const requestTimeout = 30 * time.Second
+
+var (
+	ErrUnknownProvider = errors.New("unknown bank feed provider")
+	ErrCredentials     = errors.New("missing bank account credentials")
+)
+
+// Provider reads the transactions of one bank account
+type Provider interface {
+	// Name returns the provider the adapter talks to
+	Name() string
+	// FetchTransactions returns the transactions booked since the given
+	// time, oldest first. Pending transactions are left out.
+	FetchTransactions(ctx context.Context, since time.Time) ([]entity.BankFeedTransaction, error)
+}
+
+// NewProvider returns the adapter of a bank account's provider, reading with
+// the account's credentials
+func NewProvider(account *entity.BankAccount) (Provider, error) {
+	client := &http.Client{Timeout: requestTimeout}
+	baseURL := strings.TrimRight(account.BaseURL, "/")
+
+	switch strings.ToUpper(account.Provider) {
+	case ProviderOpenBanking:
+		if account.AccessToken == "" {
+			return nil, fmt.Errorf("%w: %s needs an access token", ErrCredentials, ProviderOpenBanking)
+		}
+		return NewOpenBankingProvider(baseURL, account.ExternalID, account.AccessToken, client), nil
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnknownProvider, account.Provider)
+	}
+}
+
+// checkResponse returns an error for responses outside the 2xx range, quoting
+// the start of the body the provider explained it with
+func checkResponse(provider string, resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("%s responded with status %d: %s", provider, resp.StatusCode, strings.TrimSpace(string(body)))
+}
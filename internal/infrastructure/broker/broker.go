@@ -0,0 +1,74 @@
+// Package broker connects the server and the gateway to an external message
+// broker. Domain events are published to its topics and external systems,
+// such as web shops, hand their events in through it, so the services do not
+// need to call each other while a request is served.
+package broker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Broker drivers
+const (
+	DriverNATS  = "nats"
+	DriverKafka = "kafka"
+)
+
+// ErrClosed is returned when using a closed broker connection
+var ErrClosed = errors.New("broker connection closed")
+
+// Message is a message taken from or handed to a topic
+type Message struct {
+	Topic string
+	Key   string // partition key; messages with the same key keep their order
+	Value []byte
+}
+
+// Handler processes a message consumed from a topic
+type Handler func(ctx context.Context, msg *Message) error
+
+// Broker publishes messages to topics and consumes the messages of topics
+type Broker interface {
+	// Publish hands a message to the broker, returning once it was accepted
+	Publish(ctx context.Context, msg *Message) error
+	// Subscribe consumes the topic's messages in the background, sharing them
+	// with the other members of the consumer group. Handler errors are logged
+	// and the message is skipped.
+	Subscribe(topic string, handler Handler) error
+	// Close stops consuming and releases the connection
+	Close() error
+}
+
+// Config holds the settings of the broker connection
+type Config struct {
+	Driver   string // DriverNATS or DriverKafka; no broker is used when empty
+	URL      string // nats://host:4222 for NATS, the REST proxy base URL for Kafka
+	Username string
+	Password string
+	Group    string // consumer group sharing the messages of subscribed topics
+	Latest   bool   // a new consumer group starts at the newest message rather than the oldest
+}
+
+// New connects to the configured broker. It returns nil without a driver.
+func New(cfg Config) (Broker, error) {
+	switch cfg.Driver {
+	case "":
+		return nil, nil
+	case DriverNATS:
+		n, err := DialNATS(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return n, nil
+	case DriverKafka:
+		k, err := NewKafka(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return k, nil
+	default:
+		return nil, fmt.Errorf("unknown broker driver %q", cfg.Driver)
+	}
+}
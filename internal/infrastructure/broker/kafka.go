@@ -0,0 +1,269 @@
+package broker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	kafkaBinaryType   = "application/vnd.kafka.binary.v2+json"
+	kafkaV2Type       = "application/vnd.kafka.v2+json"
+	kafkaTimeout      = 30 * time.Second
+	kafkaPollInterval = time.Second
+	// kafkaMaxBackoff bounds the wait after a failed poll
+	kafkaMaxBackoff = 30 * time.Second
+)
+
+// Kafka reaches a Kafka cluster through its REST proxy. Messages are published
+// as raw bytes, so consumers using native clients read the JSON as it was
+// sent. Each subscription is a consumer instance of the consumer group whose
+// offsets are committed once a batch was handled, so messages are delivered at
+// least once.
+type Kafka struct {
+	url      string
+	username string
+	password string
+	group    string
+	reset    string // where a new consumer group starts reading
+	client   *http.Client
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// kafkaRecord is a record as the REST proxy exchanges it, the key and the
+// value being base64 encoded
+type kafkaRecord struct {
+	Topic     string `json:"topic,omitempty"`
+	Key       []byte `json:"key,omitempty"`
+	Value     []byte `json:"value"`
+	Partition int    `json:"partition,omitempty"`
+	Offset    int64  `json:"offset,omitempty"`
+}
+
+// kafkaConsumer is a consumer instance created on the REST proxy
+type kafkaConsumer struct {
+	InstanceID string `json:"instance_id"`
+	BaseURI    string `json:"base_uri"`
+}
+
+// NewKafka creates a client of the Kafka REST proxy at the configured URL
+func NewKafka(cfg Config) (*Kafka, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("invalid Kafka REST proxy URL %q", cfg.URL)
+	}
+	if cfg.Group == "" {
+		return nil, fmt.Errorf("a consumer group is required for Kafka")
+	}
+
+	reset := "earliest"
+	if cfg.Latest {
+		reset = "latest"
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Kafka{
+		url:      strings.TrimRight(cfg.URL, "/"),
+		username: cfg.Username,
+		password: cfg.Password,
+		group:    cfg.Group,
+		reset:    reset,
+		client:   &http.Client{Timeout: kafkaTimeout},
+		ctx:      ctx,
+		cancel:   cancel,
+	}, nil
+}
+
+// Publish produces a record to the topic
+func (k *Kafka) Publish(ctx context.Context, msg *Message) error {
+	record := kafkaRecord{Value: msg.Value}
+	if msg.Key != "" {
+		record.Key = []byte(msg.Key)
+	}
+	var result struct {
+		Offsets []struct {
+			ErrorCode *int   `json:"error_code"`
+			Error     string `json:"error"`
+		} `json:"offsets"`
+	}
+	err := k.do(ctx, http.MethodPost, k.url+"/topics/"+url.PathEscape(msg.Topic), kafkaBinaryType,
+		map[string]interface{}{"records": []kafkaRecord{record}}, &result)
+	if err != nil {
+		return err
+	}
+	for _, offset := range result.Offsets {
+		if offset.ErrorCode != nil {
+			return fmt.Errorf("kafka rejected the record: %s", offset.Error)
+		}
+	}
+	return nil
+}
+
+// Subscribe creates a consumer instance for the topic and polls it in the
+// background until the client is closed
+func (k *Kafka) Subscribe(topic string, handler Handler) error {
+	if k.ctx.Err() != nil {
+		return ErrClosed
+	}
+	consumer, err := k.createConsumer(topic)
+	if err != nil {
+		return err
+	}
+
+	k.wg.Add(1)
+	go func() {
+		defer k.wg.Done()
+		k.poll(topic, consumer, handler)
+	}()
+	return nil
+}
+
+// createConsumer creates a consumer instance of the group subscribed to the topic
+func (k *Kafka) createConsumer(topic string) (*kafkaConsumer, error) {
+	var consumer kafkaConsumer
+	err := k.do(k.ctx, http.MethodPost, k.url+"/consumers/"+url.PathEscape(k.group), kafkaV2Type, map[string]string{
+		"format":             "binary",
+		"auto.offset.reset":  k.reset,
+		"auto.commit.enable": "false",
+	}, &consumer)
+	if err != nil {
+		return nil, fmt.Errorf("error creating Kafka consumer: %w", err)
+	}
+
+	err = k.do(k.ctx, http.MethodPost, consumer.BaseURI+"/subscription", kafkaV2Type,
+		map[string][]string{"topics": {topic}}, nil)
+	if err != nil {
+		k.deleteConsumer(&consumer)
+		return nil, fmt.Errorf("error subscribing to %s: %w", topic, err)
+	}
+	return &consumer, nil
+}
+
+// poll fetches and handles the records of a consumer instance, replacing the
+// instance when the proxy lost it
+func (k *Kafka) poll(topic string, consumer *kafkaConsumer, handler Handler) {
+	backoff := kafkaPollInterval
+	for {
+		err := k.pollOnce(consumer, handler)
+		if k.ctx.Err() != nil {
+			k.deleteConsumer(consumer)
+			return
+		}
+		if err != nil {
+			slog.Error("error polling Kafka topic", "topic", topic, "error", err)
+			k.deleteConsumer(consumer)
+			for {
+				select {
+				case <-k.ctx.Done():
+					return
+				case <-time.After(backoff):
+				}
+				if backoff *= 2; backoff > kafkaMaxBackoff {
+					backoff = kafkaMaxBackoff
+				}
+				if consumer, err = k.createConsumer(topic); err == nil {
+					break
+				}
+				slog.Error("error creating Kafka consumer", "topic", topic, "error", err)
+			}
+			continue
+		}
+		backoff = kafkaPollInterval
+
+		select {
+		case <-k.ctx.Done():
+		case <-time.After(kafkaPollInterval):
+		}
+	}
+}
+
+// pollOnce handles one batch of records and commits their offsets
+func (k *Kafka) pollOnce(consumer *kafkaConsumer, handler Handler) error {
+	var records []kafkaRecord
+	if err := k.do(k.ctx, http.MethodGet, consumer.BaseURI+"/records", kafkaBinaryType, nil, &records); err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return nil
+	}
+
+	for _, record := range records {
+		msg := &Message{Topic: record.Topic, Key: string(record.Key), Value: record.Value}
+		if err := handler(k.ctx, msg); err != nil {
+			slog.Error("error handling Kafka record", "topic", record.Topic, "partition", record.Partition, "offset", record.Offset, "error", err)
+		}
+	}
+
+	// Without offsets the proxy commits those of all records fetched
+	return k.do(k.ctx, http.MethodPost, consumer.BaseURI+"/offsets", kafkaV2Type, nil, nil)
+}
+
+// deleteConsumer removes a consumer instance, leaving the group
+func (k *Kafka) deleteConsumer(consumer *kafkaConsumer) {
+	ctx, cancel := context.WithTimeout(context.Background(), kafkaTimeout)
+	defer cancel()
+	if err := k.do(ctx, http.MethodDelete, consumer.BaseURI, kafkaV2Type, nil, nil); err != nil {
+		slog.Error("error deleting Kafka consumer", "consumer", consumer.InstanceID, "error", err)
+	}
+}
+
+// Close stops polling and deletes the consumer instances
+func (k *Kafka) Close() error {
+	k.cancel()
+	k.wg.Wait()
+	return nil
+}
+
+// do sends a request to the REST proxy, decoding the response into out. The
+// content type doubles as the accepted type of the response.
+func (k *Kafka) do(ctx context.Context, method, endpoint, contentType string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		encoded, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", contentType)
+	if method != http.MethodGet {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if k.username != "" {
+		req.SetBasicAuth(k.username, k.password)
+	}
+
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var proxyErr struct {
+			Message string `json:"message"`
+		}
+		json.NewDecoder(io.LimitReader(resp.Body, 4096)).Decode(&proxyErr)
+		return fmt.Errorf("kafka REST proxy responded with status %d: %s", resp.StatusCode, proxyErr.Message)
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
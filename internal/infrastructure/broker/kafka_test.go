@@ -0,0 +1,449 @@
+package broker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeKafka is a Kafka REST proxy keeping the produced records per topic and
+// handing each record to one consumer instance of the group
+type fakeKafka struct {
+	srv *httptest.Server
+
+	mu         sync.Mutex
+	records    map[string][]kafkaRecord // undelivered records per topic
+	instances  map[string]string        // consumer instance to its subscribed topic
+	configs    []map[string]string      // configs of the created consumer instances
+	fetched    map[string]int           // records fetched by an instance and not yet committed
+	committed  int
+	deleted    []string
+	nextID     int
+	reject     string // error of the produced records, if any
+	failStatus int    // status of every request, if set
+}
+
+func newFakeKafka(t *testing.T) *fakeKafka {
+	t.Helper()
+	f := &fakeKafka{
+		records:   map[string][]kafkaRecord{},
+		instances: map[string]string{},
+		fetched:   map[string]int{},
+	}
+	f.srv = httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.srv.Close)
+	return f
+}
+
+func (f *fakeKafka) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	reply := func(status int, body interface{}) {
+		w.Header().Set("Content-Type", kafkaV2Type)
+		w.WriteHeader(status)
+		if body != nil {
+			json.NewEncoder(w).Encode(body)
+		}
+	}
+	if f.failStatus != 0 {
+		reply(f.failStatus, map[string]interface{}{"error_code": f.failStatus, "message": "proxy unavailable"})
+		return
+	}
+	if user, pass, _ := r.BasicAuth(); user != "app" || pass != "secret" {
+		reply(http.StatusUnauthorized, map[string]interface{}{"error_code": 40101, "message": "Unauthorized"})
+		return
+	}
+	wantType := kafkaV2Type
+	if strings.HasPrefix(r.URL.Path, "/topics/") || strings.HasSuffix(r.URL.Path, "/records") {
+		wantType = kafkaBinaryType
+	}
+	if r.Header.Get("Accept") != wantType || (r.Method != http.MethodGet && r.Header.Get("Content-Type") != wantType) {
+		reply(http.StatusNotAcceptable, map[string]interface{}{"error_code": 40601, "message": "wrong content type"})
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case len(parts) == 2 && parts[0] == "topics" && r.Method == http.MethodPost:
+		var body struct {
+			Records []kafkaRecord `json:"records"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		offsets := []map[string]interface{}{}
+		for _, record := range body.Records {
+			if f.reject != "" {
+				offsets = append(offsets, map[string]interface{}{"error_code": 50002, "error": f.reject})
+				continue
+			}
+			record.Topic = parts[1]
+			record.Offset = int64(len(f.records[parts[1]]))
+			f.records[parts[1]] = append(f.records[parts[1]], record)
+			offsets = append(offsets, map[string]interface{}{"partition": 0, "offset": record.Offset})
+		}
+		reply(http.StatusOK, map[string]interface{}{"offsets": offsets})
+
+	case len(parts) == 2 && parts[0] == "consumers" && r.Method == http.MethodPost:
+		var config map[string]string
+		json.NewDecoder(r.Body).Decode(&config)
+		f.configs = append(f.configs, config)
+		f.nextID++
+		id := fmt.Sprintf("consumer-%d", f.nextID)
+		f.instances[id] = ""
+		reply(http.StatusOK, kafkaConsumer{InstanceID: id, BaseURI: f.srv.URL + "/consumers/" + parts[1] + "/instances/" + id})
+
+	case len(parts) >= 4 && parts[0] == "consumers" && parts[2] == "instances":
+		id := parts[3]
+		topic, ok := f.instances[id]
+		if !ok {
+			reply(http.StatusNotFound, map[string]interface{}{"error_code": 40403, "message": "Consumer instance not found."})
+			return
+		}
+		action := ""
+		if len(parts) == 5 {
+			action = parts[4]
+		}
+		switch {
+		case action == "" && r.Method == http.MethodDelete:
+			delete(f.instances, id)
+			f.deleted = append(f.deleted, id)
+			reply(http.StatusNoContent, nil)
+		case action == "subscription" && r.Method == http.MethodPost:
+			var body struct {
+				Topics []string `json:"topics"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			f.instances[id] = strings.Join(body.Topics, ",")
+			reply(http.StatusNoContent, nil)
+		case action == "records" && r.Method == http.MethodGet:
+			records := f.records[topic]
+			delete(f.records, topic)
+			f.fetched[id] += len(records)
+			if records == nil {
+				records = []kafkaRecord{}
+			}
+			reply(http.StatusOK, records)
+		case action == "offsets" && r.Method == http.MethodPost:
+			f.committed += f.fetched[id]
+			f.fetched[id] = 0
+			reply(http.StatusNoContent, nil)
+		default:
+			reply(http.StatusNotFound, map[string]interface{}{"error_code": 404, "message": "HTTP 404 Not Found"})
+		}
+
+	default:
+		reply(http.StatusNotFound, map[string]interface{}{"error_code": 404, "message": "HTTP 404 Not Found"})
+	}
+}
+
+func (f *fakeKafka) state(fn func()) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	fn()
+}
+
+// produce adds a record to a topic as another producer would
+func (f *fakeKafka) produce(topic, key, value string) {
+	f.state(func() {
+		record := kafkaRecord{Topic: topic, Value: []byte(value), Offset: int64(len(f.records[topic]))}
+		if key != "" {
+			record.Key = []byte(key)
+		}
+		f.records[topic] = append(f.records[topic], record)
+	})
+}
+
+func newFakeKafkaClient(t *testing.T, f *fakeKafka, cfg Config) *Kafka {
+	t.Helper()
+	cfg.URL = f.srv.URL + "/"
+	cfg.Username, cfg.Password = "app", "secret"
+	if cfg.Group == "" {
+		cfg.Group = "erp"
+	}
+	k, err := NewKafka(cfg)
+	if err != nil {
+		t.Fatalf("creating client: %v", err)
+	}
+	t.Cleanup(func() { k.Close() })
+	return k
+}
+
+func TestNewKafkaConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		err  string
+	}{
+		{"valid", Config{URL: "http://proxy:8082", Group: "erp"}, ""},
+		{"https", Config{URL: "https://proxy", Group: "erp"}, ""},
+		{"no scheme", Config{URL: "proxy:8082", Group: "erp"}, "invalid Kafka REST proxy URL"},
+		{"kafka scheme", Config{URL: "kafka://proxy:9092", Group: "erp"}, "invalid Kafka REST proxy URL"},
+		{"no host", Config{URL: "http://", Group: "erp"}, "invalid Kafka REST proxy URL"},
+		{"no group", Config{URL: "http://proxy:8082"}, "a consumer group is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k, err := NewKafka(tt.cfg)
+			if tt.err == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				k.Close()
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("got error %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestKafkaPublish(t *testing.T) {
+	f := newFakeKafka(t)
+	k := newFakeKafkaClient(t, f, Config{})
+	ctx := context.Background()
+
+	messages := []*Message{
+		{Topic: "stock.changed", Key: "SKU-1", Value: []byte(`{"sku":"SKU-1","quantity":3}`)},
+		{Topic: "stock.changed", Value: []byte(`{"sku":"SKU-2"}`)},
+		{Topic: "orders.eu_v2-1", Key: "SO-1", Value: []byte("not json \x00")},
+	}
+	for _, msg := range messages {
+		if err := k.Publish(ctx, msg); err != nil {
+			t.Fatalf("Publish %s: %v", msg.Topic, err)
+		}
+	}
+
+	f.state(func() {
+		for _, msg := range messages {
+			records := f.records[msg.Topic]
+			if len(records) == 0 {
+				t.Fatalf("no record in %s", msg.Topic)
+			}
+			record := records[0]
+			f.records[msg.Topic] = records[1:]
+			if string(record.Key) != msg.Key || string(record.Value) != string(msg.Value) {
+				t.Errorf("%s: got key %q value %q, want %q %q", msg.Topic, record.Key, record.Value, msg.Key, msg.Value)
+			}
+		}
+	})
+}
+
+// The records are sent as base64, leaving out a missing key so the proxy
+// does not produce an empty one
+func TestKafkaRecordEncoding(t *testing.T) {
+	encoded, err := json.Marshal([]kafkaRecord{{Key: []byte("k"), Value: []byte("v")}, {Value: []byte("v")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `[{"key":"aw==","value":"dg=="},{"value":"dg=="}]`; string(encoded) != want {
+		t.Errorf("got %s, want %s", encoded, want)
+	}
+}
+
+func TestKafkaPublishErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		prepare func(f *fakeKafka, cfg *Config)
+		err     string
+	}{
+		{"record rejected", func(f *fakeKafka, cfg *Config) {
+			f.reject = "Leader not available"
+		}, "kafka rejected the record: Leader not available"},
+		{"proxy error", func(f *fakeKafka, cfg *Config) {
+			f.failStatus = http.StatusServiceUnavailable
+		}, "status 503: proxy unavailable"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeKafka(t)
+			var cfg Config
+			f.state(func() { tt.prepare(f, &cfg) })
+			k := newFakeKafkaClient(t, f, cfg)
+
+			err := k.Publish(context.Background(), &Message{Topic: "orders", Value: []byte("{}")})
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("got error %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestKafkaPublishUnauthorized(t *testing.T) {
+	f := newFakeKafka(t)
+	k, err := NewKafka(Config{URL: f.srv.URL, Group: "erp", Username: "app", Password: "wrong"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer k.Close()
+
+	err = k.Publish(context.Background(), &Message{Topic: "orders", Value: []byte("{}")})
+	if err == nil || !strings.Contains(err.Error(), "status 401: Unauthorized") {
+		t.Fatalf("got error %v, want the proxy's 401", err)
+	}
+}
+
+func TestKafkaPublishUnreachable(t *testing.T) {
+	f := newFakeKafka(t)
+	k := newFakeKafkaClient(t, f, Config{})
+	f.srv.Close()
+
+	if err := k.Publish(context.Background(), &Message{Topic: "orders", Value: []byte("{}")}); err == nil {
+		t.Fatal("Publish succeeded without a proxy")
+	}
+}
+
+func TestKafkaSubscribe(t *testing.T) {
+	tests := []struct {
+		name  string
+		cfg   Config
+		reset string
+	}{
+		{"earliest", Config{}, "earliest"},
+		{"latest", Config{Latest: true}, "latest"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeKafka(t)
+			k := newFakeKafkaClient(t, f, tt.cfg)
+			f.produce("orders", "SO-1", `{"id":1}`)
+			f.produce("orders", "", `{"id":2}`)
+			f.produce("invoices", "", `{"id":3}`)
+
+			msgs := make(chan *Message, 10)
+			if err := k.Subscribe("orders", func(ctx context.Context, msg *Message) error {
+				msgs <- msg
+				return nil
+			}); err != nil {
+				t.Fatalf("Subscribe: %v", err)
+			}
+
+			for _, want := range []Message{
+				{Topic: "orders", Key: "SO-1", Value: []byte(`{"id":1}`)},
+				{Topic: "orders", Value: []byte(`{"id":2}`)},
+			} {
+				if msg := receive(t, msgs); !reflect.DeepEqual(*msg, want) {
+					t.Errorf("received %+v, want %+v", *msg, want)
+				}
+			}
+			waitFor(t, "the offsets to be committed", func() bool {
+				committed := 0
+				f.state(func() { committed = f.committed })
+				return committed == 2
+			})
+
+			f.state(func() {
+				want := map[string]string{"format": "binary", "auto.offset.reset": tt.reset, "auto.commit.enable": "false"}
+				if len(f.configs) != 1 || !reflect.DeepEqual(f.configs[0], want) {
+					t.Errorf("consumer configs %v, want one %v", f.configs, want)
+				}
+				if topic := f.instances["consumer-1"]; topic != "orders" {
+					t.Errorf("consumer subscribed to %q, want orders", topic)
+				}
+				if len(f.records["invoices"]) != 1 {
+					t.Error("the invoices record was consumed")
+				}
+			})
+		})
+	}
+}
+
+// A record whose handler fails is logged and committed with the batch, so
+// it does not block the topic
+func TestKafkaHandlerError(t *testing.T) {
+	f := newFakeKafka(t)
+	k := newFakeKafkaClient(t, f, Config{})
+	f.produce("orders", "", "1")
+	f.produce("orders", "", "2")
+
+	msgs := make(chan *Message, 10)
+	k.Subscribe("orders", func(ctx context.Context, msg *Message) error {
+		msgs <- msg
+		return errors.New("handler failed")
+	})
+	receive(t, msgs)
+	receive(t, msgs)
+	waitFor(t, "the offsets to be committed", func() bool {
+		committed := 0
+		f.state(func() { committed = f.committed })
+		return committed == 2
+	})
+}
+
+func TestKafkaSubscribeErrors(t *testing.T) {
+	f := newFakeKafka(t)
+	k := newFakeKafkaClient(t, f, Config{})
+	f.state(func() { f.failStatus = http.StatusInternalServerError })
+
+	err := k.Subscribe("orders", func(context.Context, *Message) error { return nil })
+	if err == nil || !strings.Contains(err.Error(), "error creating Kafka consumer") {
+		t.Fatalf("got error %v, want a consumer creation error", err)
+	}
+}
+
+// When the proxy lost the consumer instance, e.g. on a restart, a new one is
+// created and consumption goes on
+func TestKafkaReplacesLostConsumer(t *testing.T) {
+	if testing.Short() {
+		t.Skip("waits for the polling backoff")
+	}
+	f := newFakeKafka(t)
+	k := newFakeKafkaClient(t, f, Config{})
+
+	msgs := make(chan *Message, 10)
+	k.Subscribe("orders", func(ctx context.Context, msg *Message) error {
+		msgs <- msg
+		return nil
+	})
+	waitFor(t, "the consumer", func() bool {
+		subscribed := false
+		f.state(func() { subscribed = f.instances["consumer-1"] == "orders" })
+		return subscribed
+	})
+
+	f.state(func() { delete(f.instances, "consumer-1") })
+	f.produce("orders", "", "after restart")
+
+	if msg := receive(t, msgs); string(msg.Value) != "after restart" {
+		t.Errorf("received %q, want %q", msg.Value, "after restart")
+	}
+	f.state(func() {
+		if len(f.configs) != 2 || f.instances["consumer-2"] != "orders" {
+			t.Errorf("%d consumers created, instances %v; want consumer-2 subscribed to orders", len(f.configs), f.instances)
+		}
+	})
+}
+
+func TestKafkaClose(t *testing.T) {
+	f := newFakeKafka(t)
+	k := newFakeKafkaClient(t, f, Config{})
+
+	for _, topic := range []string{"orders", "invoices"} {
+		if err := k.Subscribe(topic, func(context.Context, *Message) error { return nil }); err != nil {
+			t.Fatalf("Subscribe %s: %v", topic, err)
+		}
+	}
+	if err := k.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	// Closing leaves the group, deleting the consumer instances
+	f.state(func() {
+		if len(f.instances) != 0 || len(f.deleted) != 2 {
+			t.Errorf("instances %v left and %v deleted, want both deleted", f.instances, f.deleted)
+		}
+	})
+	if err := k.Subscribe("orders", func(context.Context, *Message) error { return nil }); !errors.Is(err, ErrClosed) {
+		t.Errorf("Subscribe after Close: got %v, want ErrClosed", err)
+	}
+}
@@ -0,0 +1,364 @@
+package broker
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	natsDefaultPort  = "4222"
+	natsDialTimeout  = 5 * time.Second
+	natsWriteTimeout = 5 * time.Second
+	// The server pings idle clients every two minutes, so a longer silence
+	// means the connection is gone
+	natsReadTimeout = 5 * time.Minute
+	// natsMaxBackoff bounds the wait between reconnection attempts
+	natsMaxBackoff = 30 * time.Second
+	// natsPending is the number of messages buffered per subscription
+	natsPending = 256
+)
+
+// NATS talks the NATS client protocol over a plain TCP connection. Messages
+// are delivered at most once and the key is ignored; subscriptions join the
+// consumer group as a queue group. The connection is reestablished when it
+// drops, and the subscriptions are renewed.
+type NATS struct {
+	addr  string
+	user  string
+	pass  string
+	group string
+
+	mu      sync.Mutex // guards the fields below
+	conn    net.Conn
+	w       *bufio.Writer
+	subs    map[string]*natsSubscription
+	nextSID int
+	closed  bool
+
+	done chan struct{}
+}
+
+type natsSubscription struct {
+	topic   string
+	handler Handler
+	msgs    chan *Message
+}
+
+// DialNATS connects to the NATS server at the configured nats:// URL.
+// Credentials may be given in the URL or in the config.
+func DialNATS(cfg Config) (*NATS, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil || u.Host == "" || (u.Scheme != "nats" && u.Scheme != "") {
+		return nil, fmt.Errorf("invalid NATS URL %q", cfg.URL)
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), natsDefaultPort)
+	}
+
+	n := &NATS{
+		addr:  addr,
+		user:  cfg.Username,
+		pass:  cfg.Password,
+		group: cfg.Group,
+		subs:  make(map[string]*natsSubscription),
+		done:  make(chan struct{}),
+	}
+	if u.User != nil {
+		n.user = u.User.Username()
+		n.pass, _ = u.User.Password()
+	}
+
+	r, err := n.connect()
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to NATS at %s: %w", addr, err)
+	}
+	go n.run(r)
+	return n, nil
+}
+
+// connect opens the connection, completes the handshake and renews the
+// subscriptions, returning the reader of the connection
+func (n *NATS) connect() (*bufio.Reader, error) {
+	conn, err := net.DialTimeout("tcp", n.addr, natsDialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+
+	handshake := func() error {
+		conn.SetDeadline(time.Now().Add(natsDialTimeout))
+		defer conn.SetDeadline(time.Time{})
+
+		line, err := readLine(r)
+		if err != nil {
+			return err
+		}
+		if !strings.HasPrefix(line, "INFO ") {
+			return fmt.Errorf("unexpected greeting %q", line)
+		}
+
+		options, err := json.Marshal(map[string]interface{}{
+			"verbose":  false,
+			"pedantic": false,
+			"lang":     "go",
+			"name":     "erp-warehouse",
+			"protocol": 1,
+			"user":     n.user,
+			"pass":     n.pass,
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "CONNECT %s\r\nPING\r\n", options)
+		if err := w.Flush(); err != nil {
+			return err
+		}
+
+		for {
+			line, err := readLine(r)
+			if err != nil {
+				return err
+			}
+			switch {
+			case line == "PONG":
+				return nil
+			case strings.HasPrefix(line, "-ERR"):
+				return errors.New(strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+			}
+		}
+	}
+	if err := handshake(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.closed {
+		conn.Close()
+		return nil, ErrClosed
+	}
+	n.conn = conn
+	n.w = w
+	for sid, sub := range n.subs {
+		n.writeSub(sid, sub.topic)
+	}
+	if err := n.w.Flush(); err != nil {
+		conn.Close()
+		n.conn = nil
+		return nil, err
+	}
+	return r, nil
+}
+
+// run reads the connection until the broker is closed, reconnecting whenever
+// the connection drops
+func (n *NATS) run(r *bufio.Reader) {
+	for {
+		err := n.read(r)
+
+		n.mu.Lock()
+		closed := n.closed
+		if n.conn != nil {
+			n.conn.Close()
+			n.conn = nil
+		}
+		n.mu.Unlock()
+		if closed {
+			return
+		}
+		slog.Warn("NATS connection lost", "error", err)
+
+		backoff := time.Second
+		for {
+			select {
+			case <-n.done:
+				return
+			case <-time.After(backoff):
+			}
+			if r, err = n.connect(); err == nil {
+				slog.Info("reconnected to NATS", "addr", n.addr)
+				break
+			}
+			slog.Error("error reconnecting to NATS", "addr", n.addr, "error", err)
+			if backoff *= 2; backoff > natsMaxBackoff {
+				backoff = natsMaxBackoff
+			}
+		}
+	}
+}
+
+// read handles the protocol messages of the server until the connection fails
+func (n *NATS) read(r *bufio.Reader) error {
+	for {
+		n.mu.Lock()
+		conn := n.conn
+		n.mu.Unlock()
+		if conn == nil {
+			return ErrClosed
+		}
+		conn.SetReadDeadline(time.Now().Add(natsReadTimeout))
+
+		line, err := readLine(r)
+		if err != nil {
+			return err
+		}
+		switch {
+		case line == "PING":
+			n.mu.Lock()
+			if n.w != nil {
+				n.w.WriteString("PONG\r\n")
+				err = n.w.Flush()
+			}
+			n.mu.Unlock()
+			if err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "MSG "):
+			if err := n.deliver(r, strings.Fields(line)); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			slog.Error("NATS error", "error", strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		}
+	}
+}
+
+// deliver reads the payload of a MSG and hands it to its subscription
+func (n *NATS) deliver(r *bufio.Reader, fields []string) error {
+	// MSG <subject> <sid> [reply-to] <#bytes>
+	if len(fields) < 4 || len(fields) > 5 {
+		return fmt.Errorf("malformed message header %q", strings.Join(fields, " "))
+	}
+	size, err := strconv.Atoi(fields[len(fields)-1])
+	if err != nil {
+		return fmt.Errorf("malformed message size %q", fields[len(fields)-1])
+	}
+	payload := make([]byte, size+2)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return err
+	}
+
+	n.mu.Lock()
+	sub := n.subs[fields[2]]
+	n.mu.Unlock()
+	if sub == nil {
+		return nil
+	}
+
+	select {
+	case sub.msgs <- &Message{Topic: fields[1], Value: payload[:size]}:
+	case <-n.done:
+	}
+	return nil
+}
+
+// Publish hands a message to the server
+func (n *NATS) Publish(ctx context.Context, msg *Message) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.closed {
+		return ErrClosed
+	}
+	if n.conn == nil {
+		return errors.New("not connected to NATS")
+	}
+
+	deadline := time.Now().Add(natsWriteTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	n.conn.SetWriteDeadline(deadline)
+	fmt.Fprintf(n.w, "PUB %s %d\r\n", msg.Topic, len(msg.Value))
+	n.w.Write(msg.Value)
+	n.w.WriteString("\r\n")
+	return n.w.Flush()
+}
+
+// Subscribe consumes the topic's messages as a member of the queue group
+func (n *NATS) Subscribe(topic string, handler Handler) error {
+	if strings.ContainsAny(topic, " \t\r\n") {
+		return fmt.Errorf("invalid NATS subject %q", topic)
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.closed {
+		return ErrClosed
+	}
+	n.nextSID++
+	sid := strconv.Itoa(n.nextSID)
+	sub := &natsSubscription{topic: topic, handler: handler, msgs: make(chan *Message, natsPending)}
+	n.subs[sid] = sub
+	go n.consume(sub)
+
+	// The subscription is sent on reconnection while disconnected
+	if n.conn == nil {
+		return nil
+	}
+	n.conn.SetWriteDeadline(time.Now().Add(natsWriteTimeout))
+	n.writeSub(sid, topic)
+	return n.w.Flush()
+}
+
+// consume runs a subscription's handler on its messages in arrival order
+func (n *NATS) consume(sub *natsSubscription) {
+	for {
+		select {
+		case msg := <-sub.msgs:
+			if err := sub.handler(context.Background(), msg); err != nil {
+				slog.Error("error handling NATS message", "topic", msg.Topic, "error", err)
+			}
+		case <-n.done:
+			return
+		}
+	}
+}
+
+// Close stops consuming and closes the connection
+func (n *NATS) Close() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.closed {
+		return nil
+	}
+	n.closed = true
+	close(n.done)
+	if n.conn == nil {
+		return nil
+	}
+	n.conn.SetWriteDeadline(time.Now().Add(natsWriteTimeout))
+	n.w.Flush()
+	return n.conn.Close()
+}
+
+// writeSub buffers a SUB for the queue group, if any. The caller holds the lock.
+func (n *NATS) writeSub(sid, topic string) {
+	if n.group != "" {
+		fmt.Fprintf(n.w, "SUB %s %s %s\r\n", topic, n.group, sid)
+		return
+	}
+	fmt.Fprintf(n.w, "SUB %s %s\r\n", topic, sid)
+}
+
+// readLine reads a protocol line without its CRLF
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
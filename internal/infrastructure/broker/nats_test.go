@@ -0,0 +1,538 @@
+package broker
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeNATS is a NATS server routing the messages published by its clients to
+// their subscriptions, one member of each queue group receiving a message
+type fakeNATS struct {
+	ln   net.Listener
+	user string // required credentials, if any
+	pass string
+
+	mu       sync.Mutex
+	conns    []*fakeNATSConn
+	connects []map[string]interface{}
+	pongs    int
+	accepted int
+}
+
+type fakeNATSConn struct {
+	conn net.Conn
+	subs map[string][2]string // sid to subject and queue group; guarded by the server's mu
+	wmu  sync.Mutex
+	w    *bufio.Writer
+}
+
+func newFakeNATS(t *testing.T, user, pass string) *fakeNATS {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeNATS{ln: ln, user: user, pass: pass}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			c := &fakeNATSConn{conn: conn, subs: map[string][2]string{}, w: bufio.NewWriter(conn)}
+			s.mu.Lock()
+			s.conns = append(s.conns, c)
+			s.accepted++
+			s.mu.Unlock()
+			go s.serveConn(c)
+		}
+	}()
+	t.Cleanup(func() {
+		ln.Close()
+		s.dropConns()
+	})
+	return s
+}
+
+func (s *fakeNATS) url(userinfo string) string {
+	return "nats://" + userinfo + s.ln.Addr().String()
+}
+
+func (c *fakeNATSConn) send(format string, args ...interface{}) {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	fmt.Fprintf(c.w, format, args...)
+	c.w.Flush()
+}
+
+func (s *fakeNATS) serveConn(c *fakeNATSConn) {
+	defer c.conn.Close()
+	c.send("INFO {\"server_id\":\"fake\",\"version\":\"2.10.0\",\"max_payload\":1048576}\r\n")
+	r := bufio.NewReader(c.conn)
+	for {
+		line, err := readLine(r)
+		if err != nil {
+			return
+		}
+		op, args, _ := strings.Cut(line, " ")
+		fields := strings.Fields(args)
+		switch op {
+		case "CONNECT":
+			var options map[string]interface{}
+			if err := json.Unmarshal([]byte(args), &options); err != nil {
+				c.send("-ERR 'Invalid Connect'\r\n")
+				return
+			}
+			s.mu.Lock()
+			s.connects = append(s.connects, options)
+			s.mu.Unlock()
+			if s.user != "" && (options["user"] != s.user || options["pass"] != s.pass) {
+				c.send("-ERR 'Authorization Violation'\r\n")
+				return
+			}
+		case "PING":
+			c.send("PONG\r\n")
+		case "PONG":
+			s.mu.Lock()
+			s.pongs++
+			s.mu.Unlock()
+		case "SUB":
+			// SUB <subject> [queue group] <sid>
+			sub := [2]string{fields[0], ""}
+			if len(fields) == 3 {
+				sub[1] = fields[1]
+			}
+			s.mu.Lock()
+			c.subs[fields[len(fields)-1]] = sub
+			s.mu.Unlock()
+		case "PUB":
+			// PUB <subject> [reply-to] <#bytes>
+			size, err := strconv.Atoi(fields[len(fields)-1])
+			if err != nil {
+				c.send("-ERR 'Unknown Protocol Operation'\r\n")
+				return
+			}
+			payload := make([]byte, size+2)
+			if _, err := io.ReadFull(r, payload); err != nil {
+				return
+			}
+			s.route(&Message{Topic: fields[0], Value: payload[:size]})
+		}
+	}
+}
+
+// route sends a published message to the matching subscriptions
+func (s *fakeNATS) route(msg *Message) {
+	s.mu.Lock()
+	type target struct {
+		conn *fakeNATSConn
+		sid  string
+	}
+	var targets []target
+	groups := map[string]bool{}
+	for _, c := range s.conns {
+		for sid, sub := range c.subs {
+			if sub[0] != msg.Topic || (sub[1] != "" && groups[sub[1]]) {
+				continue
+			}
+			groups[sub[1]] = sub[1] != ""
+			targets = append(targets, target{c, sid})
+		}
+	}
+	s.mu.Unlock()
+
+	for _, t := range targets {
+		t.conn.send("MSG %s %s %d\r\n%s\r\n", msg.Topic, t.sid, len(msg.Value), msg.Value)
+	}
+}
+
+// dropConns closes the server side of the open connections
+func (s *fakeNATS) dropConns() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, c := range s.conns {
+		c.conn.Close()
+	}
+	s.conns = nil
+}
+
+// ping sends a PING to the clients
+func (s *fakeNATS) ping() {
+	s.mu.Lock()
+	conns := append([]*fakeNATSConn(nil), s.conns...)
+	s.mu.Unlock()
+	for _, c := range conns {
+		c.send("PING\r\n")
+	}
+}
+
+// subscriptions lists the subject and queue group of each subscription on the
+// open connections
+func (s *fakeNATS) subscriptions() [][2]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var subs [][2]string
+	for _, c := range s.conns {
+		for _, sub := range c.subs {
+			subs = append(subs, sub)
+		}
+	}
+	return subs
+}
+
+func (s *fakeNATS) state(f func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f()
+}
+
+// waitFor polls cond until it holds, failing the test after five seconds
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// receive waits for a message handed to a handler
+func receive(t *testing.T, msgs <-chan *Message) *Message {
+	t.Helper()
+	select {
+	case msg := <-msgs:
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a message")
+		return nil
+	}
+}
+
+func dialFakeNATS(t *testing.T, s *fakeNATS, cfg Config) *NATS {
+	t.Helper()
+	if cfg.URL == "" {
+		cfg.URL = s.url("")
+	}
+	n, err := DialNATS(cfg)
+	if err != nil {
+		t.Fatalf("dialing: %v", err)
+	}
+	t.Cleanup(func() { n.Close() })
+	return n
+}
+
+func TestDialNATSInvalidURL(t *testing.T) {
+	for _, rawURL := range []string{
+		"",
+		"http://localhost:4222",
+		"nats://",
+		"nats://%zz",
+	} {
+		if _, err := DialNATS(Config{URL: rawURL}); err == nil || !strings.Contains(err.Error(), "invalid NATS URL") {
+			t.Errorf("DialNATS(%q): got error %v, want an invalid URL", rawURL, err)
+		}
+	}
+}
+
+func TestDialNATSCredentials(t *testing.T) {
+	tests := []struct {
+		name     string
+		userinfo string
+		cfg      Config
+	}{
+		{"from the config", "", Config{Username: "app", Password: "secret"}},
+		{"from the URL", "app:secret@", Config{Username: "other", Password: "other"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newFakeNATS(t, "app", "secret")
+			tt.cfg.URL = s.url(tt.userinfo)
+			dialFakeNATS(t, s, tt.cfg)
+
+			s.state(func() {
+				if len(s.connects) != 1 {
+					t.Fatalf("got %d CONNECTs, want 1", len(s.connects))
+				}
+				options := s.connects[0]
+				if options["verbose"] != false || options["protocol"] != float64(1) {
+					t.Errorf("CONNECT options %v, want verbose off and protocol 1", options)
+				}
+			})
+		})
+	}
+}
+
+func TestDialNATSRejected(t *testing.T) {
+	s := newFakeNATS(t, "app", "secret")
+	_, err := DialNATS(Config{URL: s.url("app:wrong@")})
+	if err == nil || !strings.Contains(err.Error(), "Authorization Violation") {
+		t.Fatalf("got error %v, want the authorization error", err)
+	}
+}
+
+func TestDialNATSUnexpectedGreeting(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.Write([]byte("HTTP/1.1 400 Bad Request\r\n"))
+		io.Copy(io.Discard, conn)
+	}()
+
+	_, err = DialNATS(Config{URL: "nats://" + ln.Addr().String()})
+	if err == nil || !strings.Contains(err.Error(), "unexpected greeting") {
+		t.Fatalf("got error %v, want an unexpected greeting", err)
+	}
+}
+
+func TestNATSPublishSubscribe(t *testing.T) {
+	s := newFakeNATS(t, "", "")
+	n := dialFakeNATS(t, s, Config{Group: "erp"})
+
+	msgs := make(chan *Message, 10)
+	if err := n.Subscribe("stock.changed", func(ctx context.Context, msg *Message) error {
+		msgs <- msg
+		return nil
+	}); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	waitFor(t, "the subscription", func() bool { return len(s.subscriptions()) == 1 })
+	if sub := s.subscriptions()[0]; sub != [2]string{"stock.changed", "erp"} {
+		t.Errorf("subscribed %v, want stock.changed in queue group erp", sub)
+	}
+
+	values := []string{`{"sku":"A"}`, "line\r\nbreak", ""}
+	for _, value := range values {
+		if err := n.Publish(context.Background(), &Message{Topic: "stock.changed", Key: "ignored", Value: []byte(value)}); err != nil {
+			t.Fatalf("Publish: %v", err)
+		}
+	}
+	for _, value := range values {
+		msg := receive(t, msgs)
+		if msg.Topic != "stock.changed" || string(msg.Value) != value {
+			t.Errorf("received %s %q, want stock.changed %q", msg.Topic, msg.Value, value)
+		}
+	}
+}
+
+func TestNATSSubscribeWithoutGroup(t *testing.T) {
+	s := newFakeNATS(t, "", "")
+	n := dialFakeNATS(t, s, Config{})
+
+	if err := n.Subscribe("orders", func(context.Context, *Message) error { return nil }); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	waitFor(t, "the subscription", func() bool { return len(s.subscriptions()) == 1 })
+	if sub := s.subscriptions()[0]; sub != [2]string{"orders", ""} {
+		t.Errorf("subscribed %v, want orders without a queue group", sub)
+	}
+}
+
+func TestNATSSubscribeInvalidSubject(t *testing.T) {
+	s := newFakeNATS(t, "", "")
+	n := dialFakeNATS(t, s, Config{})
+
+	if err := n.Subscribe("stock changed", func(context.Context, *Message) error { return nil }); err == nil {
+		t.Error("Subscribe accepted a subject with a space")
+	}
+}
+
+// A failing handler is logged and the next message still handled
+func TestNATSHandlerError(t *testing.T) {
+	s := newFakeNATS(t, "", "")
+	n := dialFakeNATS(t, s, Config{})
+
+	msgs := make(chan *Message, 10)
+	n.Subscribe("orders", func(ctx context.Context, msg *Message) error {
+		msgs <- msg
+		return errors.New("handler failed")
+	})
+	waitFor(t, "the subscription", func() bool { return len(s.subscriptions()) == 1 })
+
+	for _, value := range []string{"1", "2"} {
+		if err := n.Publish(context.Background(), &Message{Topic: "orders", Value: []byte(value)}); err != nil {
+			t.Fatalf("Publish: %v", err)
+		}
+		if msg := receive(t, msgs); string(msg.Value) != value {
+			t.Errorf("received %q, want %q", msg.Value, value)
+		}
+	}
+}
+
+func TestNATSAnswersServerPing(t *testing.T) {
+	s := newFakeNATS(t, "", "")
+	dialFakeNATS(t, s, Config{})
+
+	s.ping()
+	waitFor(t, "the PONG", func() bool {
+		pongs := 0
+		s.state(func() { pongs = s.pongs })
+		return pongs == 1
+	})
+}
+
+func TestNATSDeliver(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  *Message
+		err   string
+	}{
+		{"message", "MSG orders 1 5\r\nhello\r\n", &Message{Topic: "orders", Value: []byte("hello")}, ""},
+		{"with reply subject", "MSG orders 1 _INBOX.x 2\r\nhi\r\n", &Message{Topic: "orders", Value: []byte("hi")}, ""},
+		{"empty payload", "MSG orders 1 0\r\n\r\n", &Message{Topic: "orders", Value: []byte{}}, ""},
+		{"unknown subscription", "MSG orders 9 5\r\nhello\r\n", nil, ""},
+		{"missing size", "MSG orders 1\r\n", nil, "malformed message header"},
+		{"too many fields", "MSG orders 1 a b 5\r\n", nil, "malformed message header"},
+		{"invalid size", "MSG orders 1 five\r\n", nil, "malformed message size"},
+		{"truncated payload", "MSG orders 1 5\r\nhel", nil, "unexpected EOF"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := &natsSubscription{topic: "orders", msgs: make(chan *Message, 1)}
+			n := &NATS{subs: map[string]*natsSubscription{"1": sub}, done: make(chan struct{})}
+
+			input := tt.input
+			if tt.err == "" {
+				input += "NEXT\r\n"
+			}
+			r := bufio.NewReader(strings.NewReader(input))
+			header, _ := readLine(r)
+			err := n.deliver(r, strings.Fields(header))
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("got error %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			// The payload is consumed whether or not it was delivered
+			if next, _ := readLine(r); next != "NEXT" {
+				t.Errorf("next line %q, want the payload consumed", next)
+			}
+			select {
+			case msg := <-sub.msgs:
+				if tt.want == nil || msg.Topic != tt.want.Topic || string(msg.Value) != string(tt.want.Value) {
+					t.Errorf("delivered %+v, want %+v", msg, tt.want)
+				}
+			default:
+				if tt.want != nil {
+					t.Errorf("nothing delivered, want %+v", tt.want)
+				}
+			}
+		})
+	}
+}
+
+// The connection is reestablished after it dropped, the subscriptions renewed
+// and publishing fails in between
+func TestNATSReconnects(t *testing.T) {
+	if testing.Short() {
+		t.Skip("waits for the reconnection backoff")
+	}
+	s := newFakeNATS(t, "app", "secret")
+	n := dialFakeNATS(t, s, Config{URL: s.url("app:secret@"), Group: "erp"})
+
+	msgs := make(chan *Message, 10)
+	n.Subscribe("orders", func(ctx context.Context, msg *Message) error {
+		msgs <- msg
+		return nil
+	})
+	n.Subscribe("invoices", func(ctx context.Context, msg *Message) error {
+		msgs <- msg
+		return nil
+	})
+	waitFor(t, "the subscriptions", func() bool { return len(s.subscriptions()) == 2 })
+
+	s.dropConns()
+	waitFor(t, "publishing to fail", func() bool {
+		return n.Publish(context.Background(), &Message{Topic: "orders", Value: []byte("lost")}) != nil
+	})
+
+	waitFor(t, "the subscriptions to be renewed", func() bool { return len(s.subscriptions()) == 2 })
+	s.state(func() {
+		if s.accepted != 2 || len(s.connects) != 2 {
+			t.Errorf("%d connections and %d CONNECTs, want 2 of each", s.accepted, len(s.connects))
+		}
+		if s.connects[1]["user"] != "app" || s.connects[1]["pass"] != "secret" {
+			t.Errorf("reconnected with %v, want the credentials", s.connects[1])
+		}
+	})
+
+	if err := n.Publish(context.Background(), &Message{Topic: "invoices", Value: []byte("after")}); err != nil {
+		t.Fatalf("Publish after reconnecting: %v", err)
+	}
+	if msg := receive(t, msgs); msg.Topic != "invoices" || string(msg.Value) != "after" {
+		t.Errorf("received %s %q, want invoices %q", msg.Topic, msg.Value, "after")
+	}
+}
+
+// A subscription made while disconnected is sent on reconnection
+func TestNATSSubscribeWhileDisconnected(t *testing.T) {
+	if testing.Short() {
+		t.Skip("waits for the reconnection backoff")
+	}
+	s := newFakeNATS(t, "", "")
+	n := dialFakeNATS(t, s, Config{})
+
+	s.dropConns()
+	waitFor(t, "the disconnection", func() bool {
+		n.mu.Lock()
+		defer n.mu.Unlock()
+		return n.conn == nil
+	})
+	if err := n.Subscribe("orders", func(context.Context, *Message) error { return nil }); err != nil {
+		t.Fatalf("Subscribe while disconnected: %v", err)
+	}
+	waitFor(t, "the subscription", func() bool { return len(s.subscriptions()) == 1 })
+}
+
+func TestNATSClose(t *testing.T) {
+	s := newFakeNATS(t, "", "")
+	n := dialFakeNATS(t, s, Config{})
+
+	if err := n.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if err := n.Close(); err != nil {
+		t.Errorf("second Close: %v", err)
+	}
+	if err := n.Publish(context.Background(), &Message{Topic: "orders"}); !errors.Is(err, ErrClosed) {
+		t.Errorf("Publish after Close: got %v, want ErrClosed", err)
+	}
+	if err := n.Subscribe("orders", func(context.Context, *Message) error { return nil }); !errors.Is(err, ErrClosed) {
+		t.Errorf("Subscribe after Close: got %v, want ErrClosed", err)
+	}
+
+	// No reconnection is attempted once closed
+	time.Sleep(50 * time.Millisecond)
+	s.state(func() {
+		if s.accepted != 1 {
+			t.Errorf("%d connections, want 1", s.accepted)
+		}
+	})
+}
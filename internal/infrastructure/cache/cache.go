@@ -0,0 +1,238 @@
+// Package cache keeps hot master data, such as SKUs, the category tree,
+// permission sets and exchange rates, out of the database. Values are cached
+// for a limited time and the use cases changing them invalidate them, so a
+// shared Redis cache stays consistent across server instances.
+package cache
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/database"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/logging"
+)
+
+// Cache drivers
+const (
+	DriverMemory = "memory"
+	DriverRedis  = "redis"
+)
+
+// Namespaces of the cached master data
+const (
+	NamespaceSKUs        = "skus"
+	NamespaceCategories  = "sku_categories"
+	NamespacePermissions = "elevated_permissions"
+	NamespaceRates       = "exchange_rates"
+	NamespaceSessions    = "impersonation_sessions"
+)
+
+const defaultTTL = 5 * time.Minute
+
+func init() {
+	// Custom fields and technical specs hold decoded JSON values
+	gob.Register(map[string]interface{}{})
+	gob.Register([]interface{}{})
+}
+
+// Backend stores values by key for a limited time
+type Backend interface {
+	// Get returns the value of a key and whether it was found
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores the value of a key for the given time
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete removes keys; missing keys are ignored
+	Delete(ctx context.Context, keys ...string) error
+	// Close releases the connections of the backend
+	Close() error
+}
+
+// Config holds the settings of the cache
+type Config struct {
+	Driver string // DriverMemory or DriverRedis; nothing is cached when empty
+	URL    string // redis://[user:password@]host:6379/db for Redis
+	Prefix string // prepended to every key
+	TTL    time.Duration
+}
+
+// Cache caches values in namespaces on a backend, counting the hits and misses
+// of each. A nil Cache, or one without a driver, caches nothing.
+type Cache struct {
+	backend Backend
+	driver  string
+	prefix  string
+	ttl     time.Duration
+
+	mu         sync.Mutex // guards namespaces
+	namespaces []*Namespace
+}
+
+// New sets up the configured cache, connecting to Redis when it is the driver
+func New(cfg Config) (*Cache, error) {
+	c := &Cache{driver: cfg.Driver, prefix: cfg.Prefix, ttl: cfg.TTL}
+	if c.ttl <= 0 {
+		c.ttl = defaultTTL
+	}
+	switch cfg.Driver {
+	case "":
+	case DriverMemory:
+		c.backend = NewMemory()
+	case DriverRedis:
+		r, err := DialRedis(cfg.URL)
+		if err != nil {
+			return nil, err
+		}
+		c.backend = r
+	default:
+		return nil, fmt.Errorf("unknown cache driver %q", cfg.Driver)
+	}
+	return c, nil
+}
+
+// Namespace returns the namespace of the given name, whose keys are kept
+// apart from the other namespaces' and whose use is counted on its own
+func (c *Cache) Namespace(name string) *Namespace {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, ns := range c.namespaces {
+		if ns.name == name {
+			return ns
+		}
+	}
+	ns := &Namespace{cache: c, name: name}
+	c.namespaces = append(c.namespaces, ns)
+	return ns
+}
+
+// Shared reports whether the cache is shared by the server instances, so an
+// invalidation on one is seen by all. The memory cache is each process's own.
+func (c *Cache) Shared() bool {
+	return c != nil && c.driver == DriverRedis
+}
+
+// Stats returns the hits, misses and errors of each namespace since the server started
+func (c *Cache) Stats() *entity.CacheStats {
+	stats := &entity.CacheStats{Namespaces: []entity.CacheNamespaceStats{}}
+	if c == nil {
+		return stats
+	}
+	stats.Driver = c.driver
+	stats.TTLSeconds = int(c.ttl / time.Second)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, ns := range c.namespaces {
+		s := entity.CacheNamespaceStats{
+			Name:          ns.name,
+			Hits:          ns.hits.Load(),
+			Misses:        ns.misses.Load(),
+			Errors:        ns.errors.Load(),
+			Invalidations: ns.invalidations.Load(),
+		}
+		if lookups := s.Hits + s.Misses; lookups > 0 {
+			s.HitRatePercent = math.Round(float64(s.Hits)/float64(lookups)*10000) / 100
+		}
+		stats.Namespaces = append(stats.Namespaces, s)
+	}
+	return stats
+}
+
+// Close releases the connections of the backend
+func (c *Cache) Close() error {
+	if c == nil || c.backend == nil {
+		return nil
+	}
+	return c.backend.Close()
+}
+
+// Namespace is a group of cached values of one kind
+type Namespace struct {
+	cache *Cache
+	name  string
+
+	hits          atomic.Int64
+	misses        atomic.Int64
+	errors        atomic.Int64
+	invalidations atomic.Int64
+}
+
+// Enabled reports whether values are cached for the context. Sandbox requests
+// read another schema, so they always go to the database.
+func (n *Namespace) Enabled(ctx context.Context) bool {
+	return n != nil && n.cache.backend != nil && !database.IsSandbox(ctx)
+}
+
+func (n *Namespace) key(key string) string {
+	return n.cache.prefix + n.name + ":" + key
+}
+
+// Invalidate removes cached values after the data they were loaded from
+// changed. Failures are logged; the values then expire with their TTL.
+func (n *Namespace) Invalidate(ctx context.Context, keys ...string) {
+	if n == nil || n.cache.backend == nil || len(keys) == 0 {
+		return
+	}
+	full := make([]string, len(keys))
+	for i, key := range keys {
+		full[i] = n.key(key)
+	}
+	n.invalidations.Add(int64(len(keys)))
+	if err := n.cache.backend.Delete(ctx, full...); err != nil {
+		n.errors.Add(1)
+		logging.FromContext(ctx).Error("error invalidating cached values", "namespace", n.name, "keys", keys, "error", err)
+	}
+}
+
+// Load returns the value cached under key in the namespace, calling load and
+// caching what it returns on a miss. Values are stored in gob encoding, so
+// every caller gets a copy of its own. Cache failures are logged and the value
+// is loaded from the database instead.
+func Load[T any](ctx context.Context, n *Namespace, key string, load func() (T, error)) (T, error) {
+	if !n.Enabled(ctx) {
+		return load()
+	}
+
+	full := n.key(key)
+	data, found, err := n.cache.backend.Get(ctx, full)
+	if err != nil {
+		n.errors.Add(1)
+		logging.FromContext(ctx).Error("error reading cached value", "namespace", n.name, "key", key, "error", err)
+	}
+	if found {
+		var value T
+		err := gob.NewDecoder(bytes.NewReader(data)).Decode(&value)
+		if err == nil {
+			n.hits.Add(1)
+			return value, nil
+		}
+		n.errors.Add(1)
+		logging.FromContext(ctx).Error("error decoding cached value", "namespace", n.name, "key", key, "error", err)
+	}
+	n.misses.Add(1)
+
+	value, err := load()
+	if err != nil {
+		return value, err
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(value); err != nil {
+		n.errors.Add(1)
+		logging.FromContext(ctx).Error("error encoding value to cache", "namespace", n.name, "key", key, "error", err)
+		return value, nil
+	}
+	if err := n.cache.backend.Set(ctx, full, buf.Bytes(), n.cache.ttl); err != nil {
+		n.errors.Add(1)
+		logging.FromContext(ctx).Error("error caching value", "namespace", n.name, "key", key, "error", err)
+	}
+	return value, nil
+}
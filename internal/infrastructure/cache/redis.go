@@ -0,0 +1,293 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	redisDefaultPort = "6379"
+	redisDialTimeout = 2 * time.Second
+	// redisTimeout bounds a command, so a slow cache falls back to the
+	// database rather than holding up the request
+	redisTimeout = time.Second
+	// redisIdleConns is the number of idle connections kept for reuse
+	redisIdleConns = 16
+)
+
+// ErrRedisClosed is returned when using a closed Redis client
+var ErrRedisClosed = errors.New("redis client closed")
+
+// redisError is an error reply of the server. The connection stays usable.
+type redisError string
+
+func (e redisError) Error() string { return string(e) }
+
+// Redis talks the Redis protocol (RESP) over plain TCP connections, taken
+// from a small pool of idle ones for each command
+type Redis struct {
+	addr     string
+	username string
+	password string
+	db       int
+
+	mu     sync.Mutex // guards the fields below
+	idle   []*redisConn
+	closed bool
+}
+
+type redisConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	w    *bufio.Writer
+}
+
+// DialRedis connects to the Redis server at the redis:// URL, checking that
+// it answers and accepts the credentials and database number of the URL
+func DialRedis(rawURL string) (*Redis, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" || u.Scheme != "redis" {
+		return nil, fmt.Errorf("invalid Redis URL %q", rawURL)
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), redisDefaultPort)
+	}
+
+	r := &Redis{addr: addr}
+	if u.User != nil {
+		r.password, _ = u.User.Password()
+		if r.password == "" {
+			// redis://:password@host and redis://password@host both give the password
+			r.password = u.User.Username()
+		} else {
+			r.username = u.User.Username()
+		}
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		if r.db, err = strconv.Atoi(db); err != nil {
+			return nil, fmt.Errorf("invalid Redis database %q", db)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisDialTimeout)
+	defer cancel()
+	if _, err := r.do(ctx, "PING"); err != nil {
+		return nil, fmt.Errorf("error connecting to Redis at %s: %w", addr, err)
+	}
+	return r, nil
+}
+
+func (r *Redis) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	reply, err := r.do(ctx, "GET", key)
+	if err != nil {
+		return nil, false, err
+	}
+	value, ok := reply.([]byte)
+	return value, ok, nil
+}
+
+func (r *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	_, err := r.do(ctx, "SET", key, string(value), "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	return err
+}
+
+func (r *Redis) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	_, err := r.do(ctx, "DEL", keys...)
+	return err
+}
+
+// Close closes the idle connections; connections in use are closed when
+// their command completes
+func (r *Redis) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closed = true
+	for _, c := range r.idle {
+		c.conn.Close()
+	}
+	r.idle = nil
+	return nil
+}
+
+// do runs a command on a pooled connection and returns its reply. Replies are
+// strings for status replies, []byte or nil for bulk strings, int64 for
+// integers and []interface{} for arrays. An idle connection the server has
+// closed meanwhile, e.g. on a restart, is replaced by a new one and the
+// command sent again, which the cache's commands are safe for.
+func (r *Redis) do(ctx context.Context, command string, args ...string) (interface{}, error) {
+	c, pooled, err := r.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(redisTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	c.conn.SetDeadline(deadline)
+
+	reply, err := c.roundTrip(command, args...)
+	if pooled && isClosedConn(err) {
+		c.conn.Close()
+		if c, err = r.dial(ctx); err != nil {
+			return nil, err
+		}
+		c.conn.SetDeadline(deadline)
+		reply, err = c.roundTrip(command, args...)
+	}
+	var replyErr redisError
+	if err != nil && !errors.As(err, &replyErr) {
+		c.conn.Close()
+		return nil, err
+	}
+	r.put(c)
+	return reply, err
+}
+
+// isClosedConn tells whether err shows the server closed the connection
+func isClosedConn(err error) bool {
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE)
+}
+
+// get takes an idle connection or opens a new one, telling which
+func (r *Redis) get(ctx context.Context) (*redisConn, bool, error) {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return nil, false, ErrRedisClosed
+	}
+	if n := len(r.idle); n > 0 {
+		c := r.idle[n-1]
+		r.idle = r.idle[:n-1]
+		r.mu.Unlock()
+		return c, true, nil
+	}
+	r.mu.Unlock()
+	c, err := r.dial(ctx)
+	return c, false, err
+}
+
+// put returns a connection to the pool, closing it when the pool is full
+func (r *Redis) put(c *redisConn) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed || len(r.idle) >= redisIdleConns {
+		c.conn.Close()
+		return
+	}
+	r.idle = append(r.idle, c)
+}
+
+// dial opens a connection, authenticating and selecting the database
+func (r *Redis) dial(ctx context.Context) (*redisConn, error) {
+	dialer := net.Dialer{Timeout: redisDialTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", r.addr)
+	if err != nil {
+		return nil, err
+	}
+	c := &redisConn{conn: conn, r: bufio.NewReader(conn), w: bufio.NewWriter(conn)}
+
+	conn.SetDeadline(time.Now().Add(redisDialTimeout))
+	defer conn.SetDeadline(time.Time{})
+	if r.password != "" {
+		args := []string{r.password}
+		if r.username != "" {
+			args = []string{r.username, r.password}
+		}
+		if _, err := c.roundTrip("AUTH", args...); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("error authenticating: %w", err)
+		}
+	}
+	if r.db != 0 {
+		if _, err := c.roundTrip("SELECT", strconv.Itoa(r.db)); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("error selecting database %d: %w", r.db, err)
+		}
+	}
+	return c, nil
+}
+
+// roundTrip writes a command as an array of bulk strings and reads its reply
+func (c *redisConn) roundTrip(command string, args ...string) (interface{}, error) {
+	fmt.Fprintf(c.w, "*%d\r\n$%d\r\n%s\r\n", len(args)+1, len(command), command)
+	for _, arg := range args {
+		fmt.Fprintf(c.w, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if err := c.w.Flush(); err != nil {
+		return nil, err
+	}
+	return readReply(c.r)
+}
+
+// readReply reads a RESP reply. Error replies are returned as redisError.
+func readReply(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" {
+		return nil, fmt.Errorf("empty reply line")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid bulk string length %q", line)
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return buf[:size], nil
+	case '*':
+		count, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid array length %q", line)
+		}
+		if count < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, count)
+		for i := range items {
+			item, err := readReply(r)
+			var replyErr redisError
+			if errors.As(err, &replyErr) {
+				// Keep reading so the connection stays in step
+				item = replyErr
+			} else if err != nil {
+				return nil, err
+			}
+			items[i] = item
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("unexpected reply %q", line)
+	}
+}
@@ -0,0 +1,529 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestReadReply(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  interface{}
+		err   string
+	}{
+		{"status", "+OK\r\n", "OK", ""},
+		{"error", "-ERR unknown command\r\n", nil, "ERR unknown command"},
+		{"integer", ":42\r\n", int64(42), ""},
+		{"negative integer", ":-1\r\n", int64(-1), ""},
+		{"bulk string", "$5\r\nhello\r\n", []byte("hello"), ""},
+		{"bulk string with CRLF", "$4\r\na\r\nb\r\n", []byte("a\r\nb"), ""},
+		{"empty bulk string", "$0\r\n\r\n", []byte{}, ""},
+		{"nil bulk string", "$-1\r\n", nil, ""},
+		{"array", "*2\r\n$3\r\nfoo\r\n:7\r\n", []interface{}{[]byte("foo"), int64(7)}, ""},
+		{"nested array", "*2\r\n*1\r\n+a\r\n$-1\r\n", []interface{}{[]interface{}{"a"}, nil}, ""},
+		{"array with error", "*2\r\n-ERR no\r\n+OK\r\n", []interface{}{redisError("ERR no"), "OK"}, ""},
+		{"empty array", "*0\r\n", []interface{}{}, ""},
+		{"nil array", "*-1\r\n", nil, ""},
+		{"empty line", "\r\n", nil, "empty reply line"},
+		{"unknown type", "?what\r\n", nil, `unexpected reply "?what"`},
+		{"invalid integer", ":x\r\n", nil, "invalid syntax"},
+		{"invalid bulk length", "$x\r\n", nil, `invalid bulk string length "$x"`},
+		{"invalid array length", "*x\r\n", nil, `invalid array length "*x"`},
+		{"truncated line", "+OK", nil, "EOF"},
+		{"truncated bulk string", "$5\r\nhel", nil, "unexpected EOF"},
+		{"truncated array", "*2\r\n+OK\r\n", nil, "EOF"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readReply(bufio.NewReader(strings.NewReader(tt.input)))
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("got error %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadReplyErrorIsRedisError(t *testing.T) {
+	_, err := readReply(bufio.NewReader(strings.NewReader("-WRONGTYPE wrong kind of value\r\n")))
+	var replyErr redisError
+	if !errors.As(err, &replyErr) {
+		t.Fatalf("got %T %v, want a redisError", err, err)
+	}
+}
+
+// fakeRedis is a Redis server keeping strings in memory. handle, when set,
+// answers a command before the defaults; an empty answer falls through and
+// noReply leaves the command unanswered.
+type fakeRedis struct {
+	ln     net.Listener
+	handle func(cmd []string) string
+
+	mu       sync.Mutex
+	data     map[string]string
+	commands [][]string
+	conns    []net.Conn
+	accepted int
+}
+
+const noReply = "\x00"
+
+func newFakeRedis(t *testing.T, handle func(cmd []string) string) *fakeRedis {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeRedis{handle: handle, data: map[string]string{}}
+	s.serve(ln)
+	t.Cleanup(s.stop)
+	return s
+}
+
+func (s *fakeRedis) url(userinfo, db string) string {
+	return "redis://" + userinfo + s.ln.Addr().String() + db
+}
+
+func (s *fakeRedis) serve(ln net.Listener) {
+	s.ln = ln
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.conns = append(s.conns, conn)
+			s.accepted++
+			s.mu.Unlock()
+			go s.serveConn(conn)
+		}
+	}()
+}
+
+func (s *fakeRedis) serveConn(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		// Commands are arrays of bulk strings, which readReply parses too
+		reply, err := readReply(r)
+		if err != nil {
+			return
+		}
+		items, _ := reply.([]interface{})
+		cmd := make([]string, len(items))
+		for i, item := range items {
+			arg, _ := item.([]byte)
+			cmd[i] = string(arg)
+		}
+		if len(cmd) == 0 {
+			return
+		}
+		s.mu.Lock()
+		s.commands = append(s.commands, cmd)
+		s.mu.Unlock()
+
+		answer := ""
+		if s.handle != nil {
+			answer = s.handle(cmd)
+		}
+		if answer == "" {
+			answer = s.answer(cmd)
+		}
+		if answer == noReply {
+			continue
+		}
+		if _, err := conn.Write([]byte(answer)); err != nil {
+			return
+		}
+	}
+}
+
+func (s *fakeRedis) answer(cmd []string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch strings.ToUpper(cmd[0]) {
+	case "PING":
+		return "+PONG\r\n"
+	case "AUTH", "SELECT":
+		return "+OK\r\n"
+	case "GET":
+		value, ok := s.data[cmd[1]]
+		if !ok {
+			return "$-1\r\n"
+		}
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+	case "SET":
+		s.data[cmd[1]] = cmd[2]
+		return "+OK\r\n"
+	case "DEL":
+		deleted := 0
+		for _, key := range cmd[1:] {
+			if _, ok := s.data[key]; ok {
+				delete(s.data, key)
+				deleted++
+			}
+		}
+		return fmt.Sprintf(":%d\r\n", deleted)
+	default:
+		return fmt.Sprintf("-ERR unknown command '%s'\r\n", cmd[0])
+	}
+}
+
+// dropConns closes the server side of the open connections
+func (s *fakeRedis) dropConns() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+	s.conns = nil
+}
+
+func (s *fakeRedis) stop() {
+	s.ln.Close()
+	s.dropConns()
+}
+
+func (s *fakeRedis) received() [][]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([][]string(nil), s.commands...)
+}
+
+func (s *fakeRedis) connections() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.accepted
+}
+
+func dialFake(t *testing.T, s *fakeRedis) *Redis {
+	t.Helper()
+	client, err := DialRedis(s.url("", ""))
+	if err != nil {
+		t.Fatalf("dialing: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestDialRedisURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		userinfo string
+		db       string
+		want     [][]string
+	}{
+		{"plain", "", "", [][]string{{"PING"}}},
+		{"password", ":secret@", "", [][]string{{"AUTH", "secret"}, {"PING"}}},
+		{"password as user", "secret@", "", [][]string{{"AUTH", "secret"}, {"PING"}}},
+		{"username and password", "app:secret@", "", [][]string{{"AUTH", "app", "secret"}, {"PING"}}},
+		{"database", "", "/3", [][]string{{"SELECT", "3"}, {"PING"}}},
+		{"database 0", "", "/0", [][]string{{"PING"}}},
+		{"everything", "app:secret@", "/2", [][]string{{"AUTH", "app", "secret"}, {"SELECT", "2"}, {"PING"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newFakeRedis(t, nil)
+			client, err := DialRedis(s.url(tt.userinfo, tt.db))
+			if err != nil {
+				t.Fatalf("dialing: %v", err)
+			}
+			defer client.Close()
+			if got := s.received(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("server received %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDialRedisInvalidURL(t *testing.T) {
+	for _, rawURL := range []string{
+		"localhost:6379",
+		"http://localhost:6379",
+		"redis://",
+		"redis://localhost:6379/cache",
+		"redis://%zz",
+	} {
+		if _, err := DialRedis(rawURL); err == nil {
+			t.Errorf("DialRedis(%q) succeeded", rawURL)
+		}
+	}
+}
+
+func TestDialRedisRejected(t *testing.T) {
+	tests := []struct {
+		name   string
+		db     string
+		handle func(cmd []string) string
+		err    string
+	}{
+		{"wrong password", "", func(cmd []string) string {
+			if cmd[0] == "AUTH" {
+				return "-WRONGPASS invalid username-password pair\r\n"
+			}
+			return ""
+		}, "error authenticating: WRONGPASS"},
+		{"database out of range", "/99", func(cmd []string) string {
+			if cmd[0] == "SELECT" {
+				return "-ERR DB index is out of range\r\n"
+			}
+			return ""
+		}, "error selecting database 99"},
+		{"ping refused", "", func(cmd []string) string {
+			if cmd[0] == "PING" {
+				return "-LOADING Redis is loading the dataset in memory\r\n"
+			}
+			return ""
+		}, "LOADING"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newFakeRedis(t, tt.handle)
+			_, err := DialRedis(s.url(":secret@", tt.db))
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("got error %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestDialRedisUnreachable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	if _, err := DialRedis("redis://" + addr); err == nil || !strings.Contains(err.Error(), "error connecting to Redis") {
+		t.Fatalf("got error %v, want a connection error", err)
+	}
+}
+
+func TestRedisCommands(t *testing.T) {
+	s := newFakeRedis(t, nil)
+	client := dialFake(t, s)
+	ctx := context.Background()
+
+	if _, ok, err := client.Get(ctx, "missing"); err != nil || ok {
+		t.Fatalf("Get of a missing key: ok %v, error %v", ok, err)
+	}
+
+	value := []byte("binary\r\n\x00value")
+	if err := client.Set(ctx, "key", value, 90*time.Second); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	got, ok, err := client.Get(ctx, "key")
+	if err != nil || !ok || string(got) != string(value) {
+		t.Fatalf("Get: got %q, ok %v, error %v", got, ok, err)
+	}
+
+	if err := client.Delete(ctx, "key", "missing"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, ok, _ := client.Get(ctx, "key"); ok {
+		t.Error("key still set after Delete")
+	}
+	if err := client.Delete(ctx); err != nil {
+		t.Fatalf("Delete without keys: %v", err)
+	}
+
+	want := [][]string{
+		{"PING"},
+		{"GET", "missing"},
+		{"SET", "key", string(value), "PX", "90000"},
+		{"GET", "key"},
+		{"DEL", "key", "missing"},
+		{"GET", "key"},
+	}
+	if got := s.received(); !reflect.DeepEqual(got, want) {
+		t.Errorf("server received %q, want %q", got, want)
+	}
+	if n := s.connections(); n != 1 {
+		t.Errorf("commands used %d connections, want 1", n)
+	}
+}
+
+// An error reply leaves the connection in step, so it is reused
+func TestRedisErrorReplyKeepsConnection(t *testing.T) {
+	s := newFakeRedis(t, func(cmd []string) string {
+		if cmd[0] == "GET" && cmd[1] == "list" {
+			return "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"
+		}
+		return ""
+	})
+	client := dialFake(t, s)
+	ctx := context.Background()
+
+	_, _, err := client.Get(ctx, "list")
+	var replyErr redisError
+	if !errors.As(err, &replyErr) || !strings.HasPrefix(string(replyErr), "WRONGTYPE") {
+		t.Fatalf("got error %v, want the WRONGTYPE reply", err)
+	}
+	if err := client.Set(ctx, "key", []byte("value"), time.Minute); err != nil {
+		t.Fatalf("Set after an error reply: %v", err)
+	}
+	if n := s.connections(); n != 1 {
+		t.Errorf("commands used %d connections, want 1", n)
+	}
+}
+
+func TestRedisPoolsConnections(t *testing.T) {
+	s := newFakeRedis(t, nil)
+	client := dialFake(t, s)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4*redisIdleConns; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			key := fmt.Sprintf("key-%d", i)
+			if err := client.Set(ctx, key, []byte(key), time.Minute); err != nil {
+				t.Errorf("Set: %v", err)
+			}
+			if got, ok, err := client.Get(ctx, key); err != nil || !ok || string(got) != key {
+				t.Errorf("Get %s: got %q, ok %v, error %v", key, got, ok, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	client.mu.Lock()
+	idle := len(client.idle)
+	client.mu.Unlock()
+	if idle < 1 || idle > redisIdleConns {
+		t.Errorf("%d idle connections, want 1 to %d", idle, redisIdleConns)
+	}
+}
+
+// An idle connection the server dropped is replaced without failing the
+// command
+func TestRedisReconnectsDroppedConnection(t *testing.T) {
+	s := newFakeRedis(t, nil)
+	client := dialFake(t, s)
+	ctx := context.Background()
+
+	if err := client.Set(ctx, "key", []byte("value"), time.Minute); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	s.dropConns()
+
+	got, ok, err := client.Get(ctx, "key")
+	if err != nil || !ok || string(got) != "value" {
+		t.Fatalf("Get after the connection dropped: got %q, ok %v, error %v", got, ok, err)
+	}
+	if n := s.connections(); n != 2 {
+		t.Errorf("commands used %d connections, want 2", n)
+	}
+}
+
+// Commands fail while the server is down and succeed once it is back
+func TestRedisReconnectsAfterRestart(t *testing.T) {
+	s := newFakeRedis(t, nil)
+	client := dialFake(t, s)
+	ctx := context.Background()
+
+	addr := s.ln.Addr().String()
+	s.stop()
+	if _, _, err := client.Get(ctx, "key"); err == nil {
+		t.Fatal("Get succeeded while the server is down")
+	}
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Skipf("listening again on %s: %v", addr, err)
+	}
+	s.serve(ln)
+	if err := client.Set(ctx, "key", []byte("value"), time.Minute); err != nil {
+		t.Fatalf("Set after the restart: %v", err)
+	}
+}
+
+func TestRedisTimeout(t *testing.T) {
+	s := newFakeRedis(t, func(cmd []string) string {
+		if cmd[0] == "GET" && cmd[1] == "slow" {
+			return noReply
+		}
+		return ""
+	})
+	client := dialFake(t, s)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, _, err := client.Get(ctx, "slow")
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Fatalf("got error %v, want a timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > redisTimeout {
+		t.Errorf("Get took %v, past the context deadline", elapsed)
+	}
+
+	// The timed out connection is out of step, so it is not reused
+	if err := client.Set(context.Background(), "key", []byte("value"), time.Minute); err != nil {
+		t.Fatalf("Set after a timeout: %v", err)
+	}
+	if n := s.connections(); n != 2 {
+		t.Errorf("commands used %d connections, want 2", n)
+	}
+}
+
+func TestRedisTimeoutWithoutDeadline(t *testing.T) {
+	if testing.Short() {
+		t.Skip("waits for the command timeout")
+	}
+	s := newFakeRedis(t, func(cmd []string) string {
+		if cmd[0] == "GET" {
+			return noReply
+		}
+		return ""
+	})
+	client := dialFake(t, s)
+
+	start := time.Now()
+	if _, _, err := client.Get(context.Background(), "key"); err == nil {
+		t.Fatal("Get succeeded without a reply")
+	}
+	if elapsed := time.Since(start); elapsed < redisTimeout || elapsed > 2*redisTimeout {
+		t.Errorf("Get took %v, want about %v", elapsed, redisTimeout)
+	}
+}
+
+func TestRedisClose(t *testing.T) {
+	s := newFakeRedis(t, nil)
+	client := dialFake(t, s)
+	ctx := context.Background()
+
+	if err := client.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if _, _, err := client.Get(ctx, "key"); !errors.Is(err, ErrRedisClosed) {
+		t.Errorf("Get after Close: got %v, want ErrRedisClosed", err)
+	}
+	if err := client.Set(ctx, "key", nil, time.Minute); !errors.Is(err, ErrRedisClosed) {
+		t.Errorf("Set after Close: got %v, want ErrRedisClosed", err)
+	}
+}
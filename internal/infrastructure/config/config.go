@@ -10,20 +10,82 @@ type Config struct {
 	Server     ServerConfig
 	Database   DatabaseConfig
 	JWT        JWTConfig
+	Finance    FinanceConfig
+	Sandbox    SandboxConfig
+	Archive    ArchiveConfig
+	Audit      AuditConfig
+	WriteDown  WriteDownConfig
+	RFM        RFMConfig
+	Dashboard  DashboardConfig
+	Assets     AssetsConfig
+	Forecast   ForecastConfig
+	Recurring  RecurringInvoicesConfig
+	VendorRisk VendorRiskConfig
+	Purchasing PurchasingConfig
+	Dunning    DunningConfig
+	Payments   PaymentsConfig
+	Security   SecurityConfig
+	RateLimits RateLimitsConfig
+	Provision  ProvisioningConfig
+	Calendar   CalendarConfig
+	Quality    QualityConfig
+	Putaway    PutawayConfig
+	SKUClasses SKUClassesConfig
+	Snapshots  StockSnapshotsConfig
+	Channels   SalesChannelsConfig
+	Accounting AccountingConfig
+	BankFeeds  BankFeedsConfig
+	CostServe  CostToServeConfig
+	Fulfill    FulfillmentConfig
+	Customs    CustomsConfig
+	Realtime   RealtimeConfig
+	Notify     NotificationsConfig
+	Broker     BrokerConfig
+	Cache      CacheConfig
+	Jobs       JobsConfig
+	Scheduler  SchedulerConfig
+	Files      FilesConfig
+	Search     SearchConfig
+	Tracing    TracingConfig
+	Logging    LoggingConfig
 	APIGateway APIGatewayConfig
 }
 
 type ServerConfig struct {
-	Port string
-	Mode string // "debug" or "release"
+	Port                string
+	Mode                string // "debug" or "release"
+	IdempotencyKeyHours int    // hours the response of a request sent with an Idempotency-Key is replayed
+	ShutdownSeconds     int    // longest a shutdown waits for requests in flight and running jobs
+	CompressMinBytes    int    // smallest response gzip compressed; 0 turns compression off
 }
 
 type DatabaseConfig struct {
-	Host     string
-	Port     string
-	User     string
-	Password string
-	DBName   string
+	Host            string
+	Port            string
+	User            string
+	Password        string
+	DBName          string
+	CreateBatchSize int  // rows per INSERT when bulk creating line items
+	AutoMigrate     bool // apply pending migrations at startup
+
+	// Connection pool settings
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime int // seconds
+	ConnMaxIdleTime int // seconds
+
+	// Per-request query timeouts, in seconds
+	QueryTimeout       int
+	ReportQueryTimeout int
+
+	// Maximum number of report requests running at once
+	ReportMaxConcurrency int
+
+	// Read replica the report and dashboard reads run on, e.g.
+	// "host=replica port=5432 user=postgres password=postgres dbname=erp_db sslmode=disable"
+	ReplicaDSN          string
+	ReplicaMaxLag       int // seconds the replica may be behind before reads fall back to the primary
+	ReplicaCheckSeconds int // seconds between replica lag checks
 }
 
 type JWTConfig struct {
@@ -31,6 +93,257 @@ type JWTConfig struct {
 	RefreshSecret string
 }
 
+type FinanceConfig struct {
+	BaseCurrency string // ISO 4217 code that ledgers and reports are kept in
+}
+
+type SandboxConfig struct {
+	Enabled      bool
+	Schema       string   // shadow schema sandbox requests run against
+	MaxOpenConns int      // size of the separate sandbox connection pool
+	SeedTables   []string // master data tables copied from the live schema on reset
+}
+
+type ArchiveConfig struct {
+	Enabled       bool // run the archival job in the background
+	RetentionDays int  // closed documents untouched for longer than this are archived
+	IntervalHours int  // hours between background archival runs
+	BatchSize     int  // documents moved per statement
+}
+
+type AuditConfig struct {
+	RetentionDays int // days audit logs are kept in the database before export to the file store; 0 keeps them
+	IntervalHours int // hours between background retention runs
+}
+
+type WriteDownConfig struct {
+	Enabled bool // post the previous month's inventory provisions in the background
+}
+
+type RFMConfig struct {
+	Enabled        bool // rescore clients in the background
+	IntervalHours  int  // hours between background scoring runs
+	LookbackMonths int  // only orders placed within this many months count
+}
+
+type DashboardConfig struct {
+	Enabled        bool // pre-aggregate dashboard metrics in the background
+	RefreshMinutes int  // minutes between background aggregation runs
+	MaxAgeMinutes  int  // minutes pre-aggregated metrics are served before they are aggregated again on request
+}
+
+type AssetsConfig struct {
+	AutoDepreciate bool // post the previous month's depreciation in the background
+}
+
+type ForecastConfig struct {
+	AutoSnapshot bool // store each month's sales forecast in the background
+}
+
+type RecurringInvoicesConfig struct {
+	Enabled bool // generate due recurring invoices in the background
+}
+
+type VendorRiskConfig struct {
+	Enabled           bool    // rescore vendors in the background
+	IntervalHours     int     // hours between background scoring runs
+	LookbackMonths    int     // purchase history used for dependency and delivery performance
+	Threshold         float64 // risk score at which a vendor is at risk and an alert is raised
+	ExposureLimit     float64 // open order value plus prepayments, in the base currency, that scores 100
+	SingleSourceLimit int     // single-source SKUs that score 100
+}
+
+type PurchasingConfig struct {
+	PriceVariancePercent float64 // percent a purchase order price may differ from the last price paid before its line is flagged
+}
+
+type DunningConfig struct {
+	Enabled bool // send dunning reminders in the background
+}
+
+type PaymentsConfig struct {
+	Provider                  string // payment gateway adapter for the webhook
+	WebhookSecret             string // signing secret shared with the gateway; the webhook is disabled without it
+	SignatureToleranceSeconds int    // maximum age of a webhook signature
+}
+
+type SecurityConfig struct {
+	InactiveAccountDays int // days without sign-in after which an active account is eligible for deactivation
+	MaxElevationHours   int // longest temporary elevated access that can be requested
+
+	MaxImpersonationMinutes int // longest an admin can impersonate a user in one session
+}
+
+type RateLimitsConfig struct {
+	PerMinute        int // requests a user or API key may make per minute to the authenticated routes; unlimited when 0
+	Burst            int // requests they may make at once
+	ReportsPerMinute int // requests per minute to the report, forecast and dashboard routes, on top of the overall limit
+	ReportsBurst     int
+	SearchPerMinute  int // requests per minute to the search routes, on top of the overall limit
+	SearchBurst      int
+}
+
+type ProvisioningConfig struct {
+	SCIMToken   string // bearer token the identity provider calls the SCIM endpoints with; they are disabled without it
+	DefaultRole string // role of provisioned users whose groups map to no role; such users are refused when empty
+}
+
+type CalendarConfig struct {
+	PromiseDays int // business days after the order date that sales orders are promised for
+}
+
+type CostToServeConfig struct {
+	PickCost    float64 // handling cost of picking a delivery, in the base currency
+	LineCost    float64 // handling cost of picking a delivery line, in the base currency
+	ReturnCost  float64 // cost of handling a sales return, in the base currency
+	CapitalRate float64 // annual percentage charged on customer payments made or outstanding after the due date
+}
+
+type FulfillmentConfig struct {
+	SLAShipDays    int     // days from order to shipment, and to the committed date of orders without a promised date
+	FillRateTarget float64 // percentage of ordered units to ship by the committed date
+	LookbackDays   int     // days the SLA report and check measure by default
+	AlertsEnabled  bool    // check the SLA in the background and alert on new breaches
+	IntervalHours  int     // hours between background SLA checks
+}
+
+type CustomsConfig struct {
+	Country        string   // ISO 3166 alpha-2 code of the country the company declares its movements in
+	UnionCountries []string // members of the customs union, whose trade among themselves is reported on Intrastat
+}
+
+type QualityConfig struct {
+	ReturnRateThreshold float64 // percentage of units sold returned above which a SKU is flagged for engineering review
+}
+
+type PutawayConfig struct {
+	VelocityDays int // days of stock issues SKUs are ranked by for velocity putaway rules
+}
+
+type SKUClassesConfig struct {
+	Enabled        bool // reclassify SKUs in the background
+	IntervalHours  int  // hours between background classification runs
+	LookbackMonths int  // closed months of stock issues SKUs are classified by
+	CountDaysA     int  // days between cycle counts of class A SKUs
+	CountDaysB     int  // days between cycle counts of class B SKUs
+	CountDaysC     int  // days between cycle counts of class C and unclassified SKUs
+}
+
+type StockSnapshotsConfig struct {
+	Enabled bool // snapshot the closing stock of each day in the background
+}
+
+type SalesChannelsConfig struct {
+	SyncEnabled     bool // ingest orders and push inventory levels of active sales channels in the background
+	IntervalMinutes int  // minutes between background syncs
+	SyncUserID      uint // user recorded as creating the orders of background syncs; they are not run without it
+}
+
+type AccountingConfig struct {
+	Receivable              string // account names for QuickBooks exports, account codes for Xero exports
+	Payable                 string
+	Sales                   string
+	Purchases               string
+	SalesTax                string
+	PurchaseTax             string
+	Discounts               string // discounts given on sales and taken on purchases
+	Bank                    string // account payments are made from and into
+	WithholdingTax          string // tax withheld from supplier payments, owed to the tax authority
+	DepreciationExpense     string
+	AccumulatedDepreciation string
+	ProvisionExpense        string // inventory write-downs
+	InventoryProvision      string // provision held against aged stock
+	XeroSalesTax            string // Xero tax type of sales invoice lines
+	XeroPurchaseTax         string // Xero tax type of bill lines
+	XeroJournalTax          string // Xero tax rate of manual journal lines
+}
+
+type BankFeedsConfig struct {
+	ImportEnabled   bool // import the transactions of active bank accounts in the background
+	IntervalMinutes int  // minutes between background imports
+	LookbackDays    int  // days before the last import transactions are fetched again from, to pick up late bookings; also the history of a first import
+}
+
+type RealtimeConfig struct {
+	GatewayURL   string // base URL of the API gateway the server hands WebSocket events to; events are not published without it
+	Secret       string // shared secret the server sends events with; the gateway refuses events without it
+	StreamBuffer int    // recent events kept for Server-Sent Events clients resuming with Last-Event-ID
+}
+
+type NotificationsConfig struct {
+	SMTPHost      string // email notifications are not sent without it
+	SMTPPort      int
+	SMTPUsername  string // SMTP authentication is skipped when empty
+	SMTPPassword  string
+	EmailFrom     string // sender address of notification and document emails
+	EmailAPIURL   string // email provider API emails are posted to instead of the SMTP server
+	EmailAPIToken string // bearer token sent to the email provider
+	SMSWebhookURL string // SMS gateway messages are posted to; SMS notifications are not sent without it
+	SMSToken      string // bearer token sent to the SMS gateway
+}
+
+type BrokerConfig struct {
+	Driver       string // "nats" or "kafka"; events stay in the process without it
+	URL          string // nats://host:4222, or the base URL of the Kafka REST proxy
+	Username     string
+	Password     string
+	Group        string // consumer group the server instances share ingested events in
+	TopicPrefix  string // prepended to every topic name
+	IngestUserID uint   // user recorded as creating ingested documents; external orders are not ingested without it
+}
+
+type CacheConfig struct {
+	Driver     string // "memory" or "redis"; master data is read from the database every time without it
+	URL        string // redis://[user:password@]host:6379/db
+	Prefix     string // prepended to every key, so instances of different environments can share a server
+	TTLSeconds int    // longest a value is cached; changes made through the API invalidate it sooner
+}
+
+type JobsConfig struct {
+	Workers        int // background jobs run at once by each server instance
+	TimeoutMinutes int // longest a job may run before it is abandoned and queued again
+	MaxAttempts    int // runs of a failing job before it is marked failed
+}
+
+type SchedulerConfig struct {
+	Enabled     bool   // the instance may run the schedules; one of the enabled instances does
+	Cron        string // schedules replacing the defaults, as "<task>=<schedule>;..."
+	HistoryDays int    // days the records of the scheduled runs are kept
+}
+
+type FilesConfig struct {
+	Driver       string   // "local" or "s3"
+	Dir          string   // directory the local file store keeps files in
+	PublicURL    string   // base URL of the API as clients reach it, for download URLs of local files
+	URLSecret    string   // key download URLs of local files are signed with; the JWT access secret when empty
+	URLMinutes   int      // minutes a download URL stays valid
+	S3Endpoint   string   // e.g. https://s3.eu-west-1.amazonaws.com or http://minio:9000
+	S3Region     string   // region requests to the bucket are signed for
+	S3Bucket     string   // bucket files are kept in
+	S3AccessKey  string   // access key ID of the bucket's credentials
+	S3SecretKey  string   // secret access key of the bucket's credentials
+	S3PathStyle  bool     // address the bucket in the URL path, as MinIO needs
+	MaxUploadMB  int      // largest file users may upload, in megabytes
+	AllowedTypes []string // content types users may upload, e.g. "application/pdf" or "image/*"
+	ThumbnailPx  int      // longest side of the thumbnails of SKU images, in pixels
+}
+
+type SearchConfig struct {
+	Similarity float64 // word similarity from 0 to 1 above which a misspelt word still matches; lower tolerates more typos
+}
+
+type TracingConfig struct {
+	Enabled               bool    // record OpenTelemetry spans of requests, queries and outbound calls
+	Endpoint              string  // base URL of the OTLP/HTTP collector, e.g. http://localhost:4318
+	SampleRatio           float64 // share of new traces recorded, from 0 to 1
+	ExportIntervalSeconds int     // seconds between exports of finished spans
+}
+
+type LoggingConfig struct {
+	Level  string // lowest level logged: "debug", "info", "warn" or "error"
+	Format string // "text" for people reading a terminal, "json" for log collectors in production
+}
+
 type APIGatewayConfig struct {
 	Enabled      bool
 	Port         string
@@ -63,16 +376,215 @@ type CircuitBreakConfig struct {
 func LoadConfig() (*Config, error) {
 	viper.SetDefault("server.port", "8080")
 	viper.SetDefault("server.mode", "debug")
+	viper.SetDefault("server.idempotency_key_hours", 24)
+	viper.SetDefault("server.shutdown_seconds", 30)
+	viper.SetDefault("server.compress_min_bytes", 1024)
 
 	viper.SetDefault("database.host", "localhost")
 	viper.SetDefault("database.port", "5432")
 	viper.SetDefault("database.user", "postgres")
 	viper.SetDefault("database.password", "postgres")
 	viper.SetDefault("database.dbname", "erp_db")
+	viper.SetDefault("database.create_batch_size", 500)
+	viper.SetDefault("database.auto_migrate", true)
+	viper.SetDefault("database.max_open_conns", 25)
+	viper.SetDefault("database.max_idle_conns", 10)
+	viper.SetDefault("database.conn_max_lifetime", 1800)
+	viper.SetDefault("database.conn_max_idle_time", 300)
+	viper.SetDefault("database.query_timeout", 30)
+	viper.SetDefault("database.report_query_timeout", 120)
+	viper.SetDefault("database.report_max_concurrency", 5)
+	viper.SetDefault("database.replica_dsn", "")
+	viper.SetDefault("database.replica_max_lag", 30)
+	viper.SetDefault("database.replica_check_seconds", 10)
 
 	viper.SetDefault("jwt.access_secret", "your-access-secret-key")
 	viper.SetDefault("jwt.refresh_secret", "your-refresh-secret-key")
 
+	viper.SetDefault("finance.base_currency", "USD")
+
+	viper.SetDefault("sandbox.enabled", false)
+	viper.SetDefault("sandbox.schema", "sandbox")
+	viper.SetDefault("sandbox.max_open_conns", 5)
+	viper.SetDefault("sandbox.seed_tables", []string{
+		"roles", "users", "stores", "sku_categories", "skus", "vendors", "vendor_skus",
+		"clients", "client_addresses", "stocks", "exchange_rates", "finance_tax_rates",
+	})
+
+	viper.SetDefault("archive.enabled", false)
+	viper.SetDefault("archive.retention_days", 365)
+	viper.SetDefault("archive.interval_hours", 24)
+	viper.SetDefault("archive.batch_size", 1000)
+
+	viper.SetDefault("audit.retention_days", 0)
+	viper.SetDefault("audit.interval_hours", 24)
+
+	viper.SetDefault("write_down.enabled", false)
+
+	viper.SetDefault("rfm.enabled", false)
+	viper.SetDefault("rfm.interval_hours", 24)
+	viper.SetDefault("rfm.lookback_months", 24)
+
+	viper.SetDefault("dashboard.enabled", false)
+	viper.SetDefault("dashboard.refresh_minutes", 10)
+	viper.SetDefault("dashboard.max_age_minutes", 15)
+
+	viper.SetDefault("assets.auto_depreciate", false)
+
+	viper.SetDefault("forecast.auto_snapshot", false)
+
+	viper.SetDefault("recurring_invoices.enabled", false)
+
+	viper.SetDefault("vendor_risk.enabled", false)
+	viper.SetDefault("vendor_risk.interval_hours", 24)
+	viper.SetDefault("vendor_risk.lookback_months", 12)
+	viper.SetDefault("vendor_risk.threshold", 60)
+	viper.SetDefault("vendor_risk.exposure_limit", 100000)
+	viper.SetDefault("vendor_risk.single_source_limit", 5)
+
+	viper.SetDefault("purchasing.price_variance_percent", 5)
+
+	viper.SetDefault("fulfillment.sla_ship_days", 2)
+	viper.SetDefault("fulfillment.fill_rate_target", 95)
+	viper.SetDefault("fulfillment.lookback_days", 30)
+	viper.SetDefault("fulfillment.alerts_enabled", false)
+	viper.SetDefault("fulfillment.interval_hours", 24)
+
+	viper.SetDefault("dunning.enabled", false)
+
+	viper.SetDefault("payments.provider", "generic")
+	viper.SetDefault("payments.webhook_secret", "")
+	viper.SetDefault("payments.signature_tolerance_seconds", 300)
+
+	viper.SetDefault("security.inactive_account_days", 90)
+	viper.SetDefault("security.max_elevation_hours", 72)
+	viper.SetDefault("security.max_impersonation_minutes", 60)
+	viper.SetDefault("rate_limits.per_minute", 600)
+	viper.SetDefault("rate_limits.burst", 100)
+	viper.SetDefault("rate_limits.reports_per_minute", 60)
+	viper.SetDefault("rate_limits.reports_burst", 10)
+	viper.SetDefault("rate_limits.search_per_minute", 120)
+	viper.SetDefault("rate_limits.search_burst", 20)
+
+	viper.SetDefault("provisioning.scim_token", "")
+	viper.SetDefault("provisioning.default_role", "")
+
+	viper.SetDefault("calendar.promise_days", 2)
+
+	viper.SetDefault("quality.return_rate_threshold", 5)
+
+	viper.SetDefault("putaway.velocity_days", 90)
+	viper.SetDefault("sku_classes.enabled", false)
+	viper.SetDefault("sku_classes.interval_hours", 24)
+	viper.SetDefault("sku_classes.lookback_months", 12)
+	viper.SetDefault("sku_classes.count_days_a", 30)
+	viper.SetDefault("sku_classes.count_days_b", 90)
+	viper.SetDefault("sku_classes.count_days_c", 180)
+	viper.SetDefault("stock_snapshots.enabled", true)
+	viper.SetDefault("sales_channels.sync_enabled", false)
+	viper.SetDefault("sales_channels.interval_minutes", 15)
+	viper.SetDefault("sales_channels.sync_user_id", 0)
+
+	viper.SetDefault("accounting.receivable", "Accounts Receivable")
+	viper.SetDefault("accounting.payable", "Accounts Payable")
+	viper.SetDefault("accounting.sales", "Sales")
+	viper.SetDefault("accounting.purchases", "Cost of Goods Sold")
+	viper.SetDefault("accounting.sales_tax", "Sales Tax Payable")
+	viper.SetDefault("accounting.purchase_tax", "Sales Tax Payable")
+	viper.SetDefault("accounting.discounts", "Discounts")
+	viper.SetDefault("accounting.bank", "Checking")
+	viper.SetDefault("accounting.withholding_tax", "Withholding Tax Payable")
+	viper.SetDefault("accounting.depreciation_expense", "Depreciation Expense")
+	viper.SetDefault("accounting.accumulated_depreciation", "Accumulated Depreciation")
+	viper.SetDefault("accounting.provision_expense", "Inventory Write-Down")
+	viper.SetDefault("accounting.inventory_provision", "Inventory Provision")
+	viper.SetDefault("accounting.xero_sales_tax", "OUTPUT")
+	viper.SetDefault("accounting.xero_purchase_tax", "INPUT")
+	viper.SetDefault("accounting.xero_journal_tax", "Tax Exempt")
+
+	viper.SetDefault("bank_feeds.import_enabled", false)
+	viper.SetDefault("bank_feeds.interval_minutes", 60)
+	viper.SetDefault("bank_feeds.lookback_days", 7)
+
+	viper.SetDefault("cost_to_serve.pick_cost", 2)
+	viper.SetDefault("cost_to_serve.line_cost", 0.5)
+	viper.SetDefault("cost_to_serve.return_cost", 10)
+	viper.SetDefault("cost_to_serve.capital_rate", 8)
+
+	viper.SetDefault("customs.country", "")
+	viper.SetDefault("customs.union_countries", []string{
+		"AT", "BE", "BG", "CY", "CZ", "DE", "DK", "EE", "ES", "FI", "FR", "GR", "HR", "HU",
+		"IE", "IT", "LT", "LU", "LV", "MT", "NL", "PL", "PT", "RO", "SE", "SI", "SK",
+	})
+
+	viper.SetDefault("realtime.gateway_url", "")
+	viper.SetDefault("realtime.secret", "")
+	viper.SetDefault("realtime.stream_buffer", 1000)
+
+	viper.SetDefault("notifications.smtp_host", "")
+	viper.SetDefault("notifications.smtp_port", 587)
+	viper.SetDefault("notifications.smtp_username", "")
+	viper.SetDefault("notifications.smtp_password", "")
+	viper.SetDefault("notifications.email_from", "")
+	viper.SetDefault("notifications.email_api_url", "")
+	viper.SetDefault("notifications.email_api_token", "")
+	viper.SetDefault("notifications.sms_webhook_url", "")
+	viper.SetDefault("notifications.sms_token", "")
+
+	viper.SetDefault("broker.driver", "")
+	viper.SetDefault("broker.url", "")
+	viper.SetDefault("broker.username", "")
+	viper.SetDefault("broker.password", "")
+	viper.SetDefault("broker.group", "erp-warehouse")
+	viper.SetDefault("broker.topic_prefix", "erp.")
+	viper.SetDefault("broker.ingest_user_id", 0)
+	viper.SetDefault("cache.driver", "")
+	viper.SetDefault("cache.url", "")
+	viper.SetDefault("cache.prefix", "erp:")
+	viper.SetDefault("cache.ttl_seconds", 300)
+
+	viper.SetDefault("jobs.workers", 2)
+	viper.SetDefault("jobs.timeout_minutes", 60)
+	viper.SetDefault("jobs.max_attempts", 3)
+	viper.SetDefault("scheduler.enabled", true)
+	viper.SetDefault("scheduler.cron", "")
+	viper.SetDefault("scheduler.history_days", 90)
+
+	viper.SetDefault("files.driver", "local")
+	viper.SetDefault("files.dir", "data/files")
+	viper.SetDefault("files.public_url", "http://localhost:8080/api/v1")
+	viper.SetDefault("files.url_secret", "")
+	viper.SetDefault("files.url_minutes", 60)
+	viper.SetDefault("files.s3_endpoint", "")
+	viper.SetDefault("files.s3_region", "us-east-1")
+	viper.SetDefault("files.s3_bucket", "")
+	viper.SetDefault("files.s3_access_key", "")
+	viper.SetDefault("files.s3_secret_key", "")
+	viper.SetDefault("files.s3_path_style", false)
+	viper.SetDefault("files.max_upload_mb", 10)
+	viper.SetDefault("files.allowed_types", []string{
+		"application/pdf",
+		"image/*",
+		"text/plain",
+		"text/csv",
+		"application/zip",
+		"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+		"application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	})
+	viper.SetDefault("files.thumbnail_px", 256)
+
+	viper.SetDefault("search.similarity", 0.4)
+
+	// Tracing defaults
+	viper.SetDefault("tracing.enabled", false)
+	viper.SetDefault("tracing.endpoint", "http://localhost:4318")
+	viper.SetDefault("tracing.sample_ratio", 1.0)
+	viper.SetDefault("tracing.export_interval_seconds", 5)
+
+	// Logging defaults
+	viper.SetDefault("logging.level", "info")
+	viper.SetDefault("logging.format", "text")
+
 	// API Gateway defaults
 	viper.SetDefault("apigateway.enabled", true)
 	viper.SetDefault("apigateway.port", "8000")
@@ -141,20 +653,252 @@ func LoadConfig() (*Config, error) {
 
 	cfg := &Config{
 		Server: ServerConfig{
-			Port: viper.GetString("server.port"),
-			Mode: viper.GetString("server.mode"),
+			Port:                viper.GetString("server.port"),
+			Mode:                viper.GetString("server.mode"),
+			IdempotencyKeyHours: viper.GetInt("server.idempotency_key_hours"),
+			ShutdownSeconds:     viper.GetInt("server.shutdown_seconds"),
+			CompressMinBytes:    viper.GetInt("server.compress_min_bytes"),
 		},
 		Database: DatabaseConfig{
-			Host:     viper.GetString("database.host"),
-			Port:     viper.GetString("database.port"),
-			User:     viper.GetString("database.user"),
-			Password: viper.GetString("database.password"),
-			DBName:   viper.GetString("database.dbname"),
+			Host:            viper.GetString("database.host"),
+			Port:            viper.GetString("database.port"),
+			User:            viper.GetString("database.user"),
+			Password:        viper.GetString("database.password"),
+			DBName:          viper.GetString("database.dbname"),
+			CreateBatchSize: viper.GetInt("database.create_batch_size"),
+			AutoMigrate:     viper.GetBool("database.auto_migrate"),
+
+			MaxOpenConns:    viper.GetInt("database.max_open_conns"),
+			MaxIdleConns:    viper.GetInt("database.max_idle_conns"),
+			ConnMaxLifetime: viper.GetInt("database.conn_max_lifetime"),
+			ConnMaxIdleTime: viper.GetInt("database.conn_max_idle_time"),
+
+			QueryTimeout:       viper.GetInt("database.query_timeout"),
+			ReportQueryTimeout: viper.GetInt("database.report_query_timeout"),
+
+			ReportMaxConcurrency: viper.GetInt("database.report_max_concurrency"),
+
+			ReplicaDSN:          viper.GetString("database.replica_dsn"),
+			ReplicaMaxLag:       viper.GetInt("database.replica_max_lag"),
+			ReplicaCheckSeconds: viper.GetInt("database.replica_check_seconds"),
 		},
 		JWT: JWTConfig{
 			AccessSecret:  viper.GetString("jwt.access_secret"),
 			RefreshSecret: viper.GetString("jwt.refresh_secret"),
 		},
+		Finance: FinanceConfig{
+			BaseCurrency: viper.GetString("finance.base_currency"),
+		},
+		Sandbox: SandboxConfig{
+			Enabled:      viper.GetBool("sandbox.enabled"),
+			Schema:       viper.GetString("sandbox.schema"),
+			MaxOpenConns: viper.GetInt("sandbox.max_open_conns"),
+			SeedTables:   viper.GetStringSlice("sandbox.seed_tables"),
+		},
+		Archive: ArchiveConfig{
+			Enabled:       viper.GetBool("archive.enabled"),
+			RetentionDays: viper.GetInt("archive.retention_days"),
+			IntervalHours: viper.GetInt("archive.interval_hours"),
+			BatchSize:     viper.GetInt("archive.batch_size"),
+		},
+		Audit: AuditConfig{
+			RetentionDays: viper.GetInt("audit.retention_days"),
+			IntervalHours: viper.GetInt("audit.interval_hours"),
+		},
+		WriteDown: WriteDownConfig{
+			Enabled: viper.GetBool("write_down.enabled"),
+		},
+		RFM: RFMConfig{
+			Enabled:        viper.GetBool("rfm.enabled"),
+			IntervalHours:  viper.GetInt("rfm.interval_hours"),
+			LookbackMonths: viper.GetInt("rfm.lookback_months"),
+		},
+		Dashboard: DashboardConfig{
+			Enabled:        viper.GetBool("dashboard.enabled"),
+			RefreshMinutes: viper.GetInt("dashboard.refresh_minutes"),
+			MaxAgeMinutes:  viper.GetInt("dashboard.max_age_minutes"),
+		},
+		Assets: AssetsConfig{
+			AutoDepreciate: viper.GetBool("assets.auto_depreciate"),
+		},
+		Forecast: ForecastConfig{
+			AutoSnapshot: viper.GetBool("forecast.auto_snapshot"),
+		},
+		Recurring: RecurringInvoicesConfig{
+			Enabled: viper.GetBool("recurring_invoices.enabled"),
+		},
+		VendorRisk: VendorRiskConfig{
+			Enabled:           viper.GetBool("vendor_risk.enabled"),
+			IntervalHours:     viper.GetInt("vendor_risk.interval_hours"),
+			LookbackMonths:    viper.GetInt("vendor_risk.lookback_months"),
+			Threshold:         viper.GetFloat64("vendor_risk.threshold"),
+			ExposureLimit:     viper.GetFloat64("vendor_risk.exposure_limit"),
+			SingleSourceLimit: viper.GetInt("vendor_risk.single_source_limit"),
+		},
+		Purchasing: PurchasingConfig{
+			PriceVariancePercent: viper.GetFloat64("purchasing.price_variance_percent"),
+		},
+		Dunning: DunningConfig{
+			Enabled: viper.GetBool("dunning.enabled"),
+		},
+		Payments: PaymentsConfig{
+			Provider:                  viper.GetString("payments.provider"),
+			WebhookSecret:             viper.GetString("payments.webhook_secret"),
+			SignatureToleranceSeconds: viper.GetInt("payments.signature_tolerance_seconds"),
+		},
+		Security: SecurityConfig{
+			InactiveAccountDays: viper.GetInt("security.inactive_account_days"),
+			MaxElevationHours:   viper.GetInt("security.max_elevation_hours"),
+
+			MaxImpersonationMinutes: viper.GetInt("security.max_impersonation_minutes"),
+		},
+		RateLimits: RateLimitsConfig{
+			PerMinute:        viper.GetInt("rate_limits.per_minute"),
+			Burst:            viper.GetInt("rate_limits.burst"),
+			ReportsPerMinute: viper.GetInt("rate_limits.reports_per_minute"),
+			ReportsBurst:     viper.GetInt("rate_limits.reports_burst"),
+			SearchPerMinute:  viper.GetInt("rate_limits.search_per_minute"),
+			SearchBurst:      viper.GetInt("rate_limits.search_burst"),
+		},
+		Provision: ProvisioningConfig{
+			SCIMToken:   viper.GetString("provisioning.scim_token"),
+			DefaultRole: viper.GetString("provisioning.default_role"),
+		},
+		Calendar: CalendarConfig{
+			PromiseDays: viper.GetInt("calendar.promise_days"),
+		},
+		Quality: QualityConfig{
+			ReturnRateThreshold: viper.GetFloat64("quality.return_rate_threshold"),
+		},
+		Putaway: PutawayConfig{
+			VelocityDays: viper.GetInt("putaway.velocity_days"),
+		},
+		SKUClasses: SKUClassesConfig{
+			Enabled:        viper.GetBool("sku_classes.enabled"),
+			IntervalHours:  viper.GetInt("sku_classes.interval_hours"),
+			LookbackMonths: viper.GetInt("sku_classes.lookback_months"),
+			CountDaysA:     viper.GetInt("sku_classes.count_days_a"),
+			CountDaysB:     viper.GetInt("sku_classes.count_days_b"),
+			CountDaysC:     viper.GetInt("sku_classes.count_days_c"),
+		},
+		Snapshots: StockSnapshotsConfig{
+			Enabled: viper.GetBool("stock_snapshots.enabled"),
+		},
+		Channels: SalesChannelsConfig{
+			SyncEnabled:     viper.GetBool("sales_channels.sync_enabled"),
+			IntervalMinutes: viper.GetInt("sales_channels.interval_minutes"),
+			SyncUserID:      viper.GetUint("sales_channels.sync_user_id"),
+		},
+		Accounting: AccountingConfig{
+			Receivable:              viper.GetString("accounting.receivable"),
+			Payable:                 viper.GetString("accounting.payable"),
+			Sales:                   viper.GetString("accounting.sales"),
+			Purchases:               viper.GetString("accounting.purchases"),
+			SalesTax:                viper.GetString("accounting.sales_tax"),
+			PurchaseTax:             viper.GetString("accounting.purchase_tax"),
+			Discounts:               viper.GetString("accounting.discounts"),
+			Bank:                    viper.GetString("accounting.bank"),
+			WithholdingTax:          viper.GetString("accounting.withholding_tax"),
+			DepreciationExpense:     viper.GetString("accounting.depreciation_expense"),
+			AccumulatedDepreciation: viper.GetString("accounting.accumulated_depreciation"),
+			ProvisionExpense:        viper.GetString("accounting.provision_expense"),
+			InventoryProvision:      viper.GetString("accounting.inventory_provision"),
+			XeroSalesTax:            viper.GetString("accounting.xero_sales_tax"),
+			XeroPurchaseTax:         viper.GetString("accounting.xero_purchase_tax"),
+			XeroJournalTax:          viper.GetString("accounting.xero_journal_tax"),
+		},
+		BankFeeds: BankFeedsConfig{
+			ImportEnabled:   viper.GetBool("bank_feeds.import_enabled"),
+			IntervalMinutes: viper.GetInt("bank_feeds.interval_minutes"),
+			LookbackDays:    viper.GetInt("bank_feeds.lookback_days"),
+		},
+		CostServe: CostToServeConfig{
+			PickCost:    viper.GetFloat64("cost_to_serve.pick_cost"),
+			LineCost:    viper.GetFloat64("cost_to_serve.line_cost"),
+			ReturnCost:  viper.GetFloat64("cost_to_serve.return_cost"),
+			CapitalRate: viper.GetFloat64("cost_to_serve.capital_rate"),
+		},
+		Fulfill: FulfillmentConfig{
+			SLAShipDays:    viper.GetInt("fulfillment.sla_ship_days"),
+			FillRateTarget: viper.GetFloat64("fulfillment.fill_rate_target"),
+			LookbackDays:   viper.GetInt("fulfillment.lookback_days"),
+			AlertsEnabled:  viper.GetBool("fulfillment.alerts_enabled"),
+			IntervalHours:  viper.GetInt("fulfillment.interval_hours"),
+		},
+		Customs: CustomsConfig{
+			Country:        viper.GetString("customs.country"),
+			UnionCountries: viper.GetStringSlice("customs.union_countries"),
+		},
+		Realtime: RealtimeConfig{
+			GatewayURL:   viper.GetString("realtime.gateway_url"),
+			Secret:       viper.GetString("realtime.secret"),
+			StreamBuffer: viper.GetInt("realtime.stream_buffer"),
+		},
+		Notify: NotificationsConfig{
+			SMTPHost:      viper.GetString("notifications.smtp_host"),
+			SMTPPort:      viper.GetInt("notifications.smtp_port"),
+			SMTPUsername:  viper.GetString("notifications.smtp_username"),
+			SMTPPassword:  viper.GetString("notifications.smtp_password"),
+			EmailFrom:     viper.GetString("notifications.email_from"),
+			EmailAPIURL:   viper.GetString("notifications.email_api_url"),
+			EmailAPIToken: viper.GetString("notifications.email_api_token"),
+			SMSWebhookURL: viper.GetString("notifications.sms_webhook_url"),
+			SMSToken:      viper.GetString("notifications.sms_token"),
+		},
+		Broker: BrokerConfig{
+			Driver:       viper.GetString("broker.driver"),
+			URL:          viper.GetString("broker.url"),
+			Username:     viper.GetString("broker.username"),
+			Password:     viper.GetString("broker.password"),
+			Group:        viper.GetString("broker.group"),
+			TopicPrefix:  viper.GetString("broker.topic_prefix"),
+			IngestUserID: viper.GetUint("broker.ingest_user_id"),
+		},
+		Cache: CacheConfig{
+			Driver:     viper.GetString("cache.driver"),
+			URL:        viper.GetString("cache.url"),
+			Prefix:     viper.GetString("cache.prefix"),
+			TTLSeconds: viper.GetInt("cache.ttl_seconds"),
+		},
+		Jobs: JobsConfig{
+			Workers:        viper.GetInt("jobs.workers"),
+			TimeoutMinutes: viper.GetInt("jobs.timeout_minutes"),
+			MaxAttempts:    viper.GetInt("jobs.max_attempts"),
+		},
+		Scheduler: SchedulerConfig{
+			Enabled:     viper.GetBool("scheduler.enabled"),
+			Cron:        viper.GetString("scheduler.cron"),
+			HistoryDays: viper.GetInt("scheduler.history_days"),
+		},
+		Files: FilesConfig{
+			Driver:       viper.GetString("files.driver"),
+			Dir:          viper.GetString("files.dir"),
+			PublicURL:    viper.GetString("files.public_url"),
+			URLSecret:    viper.GetString("files.url_secret"),
+			URLMinutes:   viper.GetInt("files.url_minutes"),
+			S3Endpoint:   viper.GetString("files.s3_endpoint"),
+			S3Region:     viper.GetString("files.s3_region"),
+			S3Bucket:     viper.GetString("files.s3_bucket"),
+			S3AccessKey:  viper.GetString("files.s3_access_key"),
+			S3SecretKey:  viper.GetString("files.s3_secret_key"),
+			S3PathStyle:  viper.GetBool("files.s3_path_style"),
+			MaxUploadMB:  viper.GetInt("files.max_upload_mb"),
+			AllowedTypes: viper.GetStringSlice("files.allowed_types"),
+			ThumbnailPx:  viper.GetInt("files.thumbnail_px"),
+		},
+		Search: SearchConfig{
+			Similarity: viper.GetFloat64("search.similarity"),
+		},
+		Tracing: TracingConfig{
+			Enabled:               viper.GetBool("tracing.enabled"),
+			Endpoint:              viper.GetString("tracing.endpoint"),
+			SampleRatio:           viper.GetFloat64("tracing.sample_ratio"),
+			ExportIntervalSeconds: viper.GetInt("tracing.export_interval_seconds"),
+		},
+		Logging: LoggingConfig{
+			Level:  viper.GetString("logging.level"),
+			Format: viper.GetString("logging.format"),
+		},
 		APIGateway: APIGatewayConfig{
 			Enabled:  viper.GetBool("apigateway.enabled"),
 			Port:     viper.GetString("apigateway.port"),
@@ -0,0 +1,185 @@
+// Package cron parses cron expressions and computes when they next fall due.
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule tells when a recurring task is due
+type Schedule interface {
+	// Next returns the first time the schedule falls due after t
+	Next(t time.Time) time.Time
+}
+
+// Every is a schedule falling due at a fixed interval after the last run
+type Every time.Duration
+
+// Next returns t plus the interval
+func (e Every) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(e))
+}
+
+// Expression is a schedule of the standard five cron fields: minute, hour,
+// day of month, month and day of week. Its times are in the location of the
+// time Next is given.
+type Expression struct {
+	minute, hour, dom, month, dow uint64 // bit sets of the values allowed
+	anyDOM, anyDOW                bool
+}
+
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Parse reads a schedule: five cron fields, e.g. "*/15 * * * *" or
+// "0 6 * * 1-5", one of @yearly, @monthly, @weekly, @daily and @hourly, or
+// "@every <duration>", e.g. "@every 6h"
+func Parse(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if rest, ok := strings.CutPrefix(spec, "@every "); ok {
+		interval, err := time.ParseDuration(strings.TrimSpace(rest))
+		if err != nil || interval < time.Second {
+			return nil, fmt.Errorf("invalid interval in %q", spec)
+		}
+		return Every(interval), nil
+	}
+	if expanded, ok := macros[spec]; ok {
+		spec = expanded
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("%q does not have the five fields minute, hour, day of month, month and day of week", spec)
+	}
+	var e Expression
+	var err error
+	if e.minute, err = parseField(fields[0], 0, 59); err != nil {
+		return nil, fmt.Errorf("invalid minute in %q: %w", spec, err)
+	}
+	if e.hour, err = parseField(fields[1], 0, 23); err != nil {
+		return nil, fmt.Errorf("invalid hour in %q: %w", spec, err)
+	}
+	if e.dom, err = parseField(fields[2], 1, 31); err != nil {
+		return nil, fmt.Errorf("invalid day of month in %q: %w", spec, err)
+	}
+	if e.month, err = parseField(fields[3], 1, 12); err != nil {
+		return nil, fmt.Errorf("invalid month in %q: %w", spec, err)
+	}
+	if e.dow, err = parseField(fields[4], 0, 7); err != nil {
+		return nil, fmt.Errorf("invalid day of week in %q: %w", spec, err)
+	}
+	// Sunday is 0 or 7
+	if e.dow&(1<<7) != 0 {
+		e.dow |= 1
+	}
+	// As in cron, a day field starting with * counts as unrestricted when
+	// combining the two, even with a step such as */2
+	e.anyDOM = strings.HasPrefix(fields[2], "*")
+	e.anyDOW = strings.HasPrefix(fields[4], "*")
+	return &e, nil
+}
+
+// parseField reads a comma separated list of values, ranges (a-b) and steps
+// (*/n, a-b/n) between min and max
+func parseField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepPart); err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step %q", stepPart)
+			}
+		}
+
+		low, high := min, max
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			from, to, _ := strings.Cut(rangePart, "-")
+			var err error
+			if low, err = parseValue(from, min, max); err != nil {
+				return 0, err
+			}
+			if high, err = parseValue(to, min, max); err != nil {
+				return 0, err
+			}
+			if low > high {
+				return 0, fmt.Errorf("invalid range %q", rangePart)
+			}
+		default:
+			value, err := parseValue(rangePart, min, max)
+			if err != nil {
+				return 0, err
+			}
+			low = value
+			if !hasStep {
+				high = value
+			}
+		}
+		for v := low; v <= high; v += step {
+			bits |= 1 << v
+		}
+	}
+	return bits, nil
+}
+
+func parseValue(s string, min, max int) (int, error) {
+	value, err := strconv.Atoi(s)
+	if err != nil || value < min || value > max {
+		return 0, fmt.Errorf("%q is not a number from %d to %d", s, min, max)
+	}
+	return value, nil
+}
+
+// Next returns the first minute after t the expression matches. It returns
+// the zero time when it matches none in the next five years, as for the 30th
+// of February. A time skipped when the clocks go forward is not due that
+// day, and one repeated when they go back is due the first time only.
+func (e *Expression) Next(t time.Time) time.Time {
+	// The wall clock is walked in UTC, which has no daylight saving changes,
+	// so each step moves forward
+	w := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, time.UTC).Add(time.Minute)
+	limit := w.AddDate(5, 0, 0)
+	for w.Before(limit) {
+		switch {
+		case e.month&(1<<uint(w.Month())) == 0:
+			w = time.Date(w.Year(), w.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		case !e.matchesDay(w):
+			w = time.Date(w.Year(), w.Month(), w.Day()+1, 0, 0, 0, 0, time.UTC)
+		case e.hour&(1<<uint(w.Hour())) == 0:
+			w = w.Truncate(time.Hour).Add(time.Hour)
+		case e.minute&(1<<uint(w.Minute())) == 0:
+			w = w.Add(time.Minute)
+		default:
+			next := time.Date(w.Year(), w.Month(), w.Day(), w.Hour(), w.Minute(), 0, 0, t.Location())
+			if next.After(t) && next.Hour() == w.Hour() && next.Minute() == w.Minute() {
+				return next
+			}
+			w = w.Add(time.Minute)
+		}
+	}
+	return time.Time{}
+}
+
+// matchesDay reports whether the day of t matches. As in cron, when both the
+// day of month and the day of week are restricted, either may match;
+// otherwise both must.
+func (e *Expression) matchesDay(t time.Time) bool {
+	dom := e.dom&(1<<uint(t.Day())) != 0
+	dow := e.dow&(1<<uint(t.Weekday())) != 0
+	if e.anyDOM || e.anyDOW {
+		return dom && dow
+	}
+	return dom || dow
+}
@@ -0,0 +1,369 @@
+package cron
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// values lists the values set in a field's bit set
+func values(bits uint64) []int {
+	var list []int
+	for v := 0; v < 64; v++ {
+		if bits&(1<<v) != 0 {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+func TestParseField(t *testing.T) {
+	tests := []struct {
+		field    string
+		min, max int
+		want     []int
+		err      string
+	}{
+		{"*", 0, 5, []int{0, 1, 2, 3, 4, 5}, ""},
+		{"3", 0, 59, []int{3}, ""},
+		{"0", 0, 59, []int{0}, ""},
+		{"59", 0, 59, []int{59}, ""},
+		{"1,5,9", 0, 59, []int{1, 5, 9}, ""},
+		{"9,1,1", 0, 59, []int{1, 9}, ""},
+		{"10-14", 0, 59, []int{10, 11, 12, 13, 14}, ""},
+		{"4-4", 0, 59, []int{4}, ""},
+		{"*/15", 0, 59, []int{0, 15, 30, 45}, ""},
+		{"*/7", 1, 31, []int{1, 8, 15, 22, 29}, ""},
+		{"*/100", 0, 59, []int{0}, ""},
+		{"10-30/10", 0, 59, []int{10, 20, 30}, ""},
+		{"10-35/10", 0, 59, []int{10, 20, 30}, ""},
+		{"50/5", 0, 59, []int{50, 55}, ""},
+		{"1-3,20-22/2,40", 0, 59, []int{1, 2, 3, 20, 22, 40}, ""},
+		{"*/2", 0, 7, []int{0, 2, 4, 6}, ""},
+		{"", 0, 59, nil, `"" is not a number from 0 to 59`},
+		{"60", 0, 59, nil, `"60" is not a number from 0 to 59`},
+		{"0", 1, 31, nil, `"0" is not a number from 1 to 31`},
+		{"-1", 0, 59, nil, `"" is not a number`},
+		{"1,,2", 0, 59, nil, `"" is not a number`},
+		{"1,", 0, 59, nil, `"" is not a number`},
+		{"5-1", 0, 59, nil, `invalid range "5-1"`},
+		{"1-2-3", 0, 59, nil, `"2-3" is not a number`},
+		{"1-60", 0, 59, nil, `"60" is not a number`},
+		{"*/0", 0, 59, nil, `invalid step "0"`},
+		{"*/-5", 0, 59, nil, `invalid step "-5"`},
+		{"*/", 0, 59, nil, `invalid step ""`},
+		{"*/x", 0, 59, nil, `invalid step "x"`},
+		{"1/2/3", 0, 59, nil, `invalid step "2/3"`},
+		{"a", 0, 59, nil, `"a" is not a number`},
+		{"MON", 0, 7, nil, `"MON" is not a number`},
+		{"**", 0, 59, nil, `"**" is not a number`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			bits, err := parseField(tt.field, tt.min, tt.max)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("got error %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := values(bits); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		spec                          string
+		minute, hour, dom, month, dow []int
+		anyDOM, anyDOW                bool
+	}{
+		{"0 6 * * 1-5", []int{0}, []int{6}, nil, nil, []int{1, 2, 3, 4, 5}, true, false},
+		{"  */30  0,12 1 */3 *  ", []int{0, 30}, []int{0, 12}, []int{1}, []int{1, 4, 7, 10}, nil, false, true},
+		// Sunday is 0 or 7
+		{"0 0 * * 7", []int{0}, []int{0}, nil, nil, []int{0, 7}, true, false},
+		{"0 0 * * 5-7", []int{0}, []int{0}, nil, nil, []int{0, 5, 6, 7}, true, false},
+		{"0 0 * * 0", []int{0}, []int{0}, nil, nil, []int{0}, true, false},
+		// A day field starting with * is unrestricted, even with a step
+		{"0 0 */2 * 1", []int{0}, []int{0}, []int{1, 3, 5, 7, 9, 11, 13, 15, 17, 19, 21, 23, 25, 27, 29, 31}, nil, []int{1}, true, false},
+		{"0 0 1-7 * */2", []int{0}, []int{0}, []int{1, 2, 3, 4, 5, 6, 7}, nil, []int{0, 2, 4, 6}, false, true},
+		{"@daily", []int{0}, []int{0}, nil, nil, nil, true, true},
+		{"@weekly", []int{0}, []int{0}, nil, nil, []int{0}, true, false},
+		{"@yearly", []int{0}, []int{0}, []int{1}, []int{1}, nil, false, true},
+	}
+
+	all := func(min, max int) []int {
+		var list []int
+		for v := min; v <= max; v++ {
+			list = append(list, v)
+		}
+		return list
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			schedule, err := Parse(tt.spec)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			e, ok := schedule.(*Expression)
+			if !ok {
+				t.Fatalf("got %T, want an *Expression", schedule)
+			}
+			// nil stands for the whole range of a field
+			for _, field := range []struct {
+				name     string
+				bits     uint64
+				want     []int
+				min, max int
+			}{
+				{"minute", e.minute, tt.minute, 0, 59},
+				{"hour", e.hour, tt.hour, 0, 23},
+				{"day of month", e.dom, tt.dom, 1, 31},
+				{"month", e.month, tt.month, 1, 12},
+				{"day of week", e.dow, tt.dow, 0, 7},
+			} {
+				want := field.want
+				if want == nil {
+					want = all(field.min, field.max)
+				}
+				if got := values(field.bits); !reflect.DeepEqual(got, want) {
+					t.Errorf("%s: got %v, want %v", field.name, got, want)
+				}
+			}
+			if e.anyDOM != tt.anyDOM || e.anyDOW != tt.anyDOW {
+				t.Errorf("unrestricted days of month %v and of week %v, want %v and %v", e.anyDOM, e.anyDOW, tt.anyDOM, tt.anyDOW)
+			}
+		})
+	}
+}
+
+func TestParseEvery(t *testing.T) {
+	tests := map[string]Every{
+		"@every 6h":        Every(6 * time.Hour),
+		"@every 1m30s":     Every(90 * time.Second),
+		"@every 1s":        Every(time.Second),
+		" @every  15m ":    Every(15 * time.Minute),
+		"@every 2h45m10s ": Every(2*time.Hour + 45*time.Minute + 10*time.Second),
+	}
+	for spec, want := range tests {
+		schedule, err := Parse(spec)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", spec, err)
+			continue
+		}
+		if schedule != want {
+			t.Errorf("Parse(%q) = %v, want %v", spec, schedule, want)
+		}
+	}
+
+	from := time.Date(2024, 1, 15, 10, 7, 30, 0, time.UTC)
+	if got, want := Every(time.Hour).Next(from), from.Add(time.Hour); !got.Equal(want) {
+		t.Errorf("Next = %v, want %v", got, want)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := map[string]string{
+		"":                       "does not have the five fields",
+		"* * * *":                "does not have the five fields",
+		"* * * * * *":            "does not have the five fields",
+		"60 * * * *":             "invalid minute",
+		"* 24 * * *":             "invalid hour",
+		"* * 0 * *":              "invalid day of month",
+		"* * 32 * *":             "invalid day of month",
+		"* * * 0 *":              "invalid month",
+		"* * * 13 *":             "invalid month",
+		"* * * * 8":              "invalid day of week",
+		"* * * JAN *":            "invalid month",
+		"* * * * MON":            "invalid day of week",
+		"5-1 * * * *":            "invalid minute",
+		"*/0 * * * *":            "invalid minute",
+		"@every 500ms":           "invalid interval",
+		"@every -1h":             "invalid interval",
+		"@every soon":            "invalid interval",
+		"@every":                 "does not have the five fields",
+		"@fortnightly":           "does not have the five fields",
+		"@daily 0":               "does not have the five fields",
+		"0 0 * * * # nightly":    "does not have the five fields",
+		"0\t0\t*\t*\t*\t*":       "does not have the five fields",
+		"1-2-3 * * * *":          "invalid minute",
+		"0 0 1,,15 * *":          "invalid day of month",
+		"0 0 * * 1-5/0":          "invalid day of week",
+		"0 0 L * *":              "invalid day of month",
+		"0 0 ? * 1":              "invalid day of month",
+		"0 0 15W * *":            "invalid day of month",
+		"0 0 * * 5#3":            "invalid day of week",
+		"* * * * * 2024":         "does not have the five fields",
+		"0-59/15 0-23 1-31 1-12": "does not have the five fields",
+	}
+	for spec, want := range tests {
+		if _, err := Parse(spec); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Parse(%q): got error %v, want %q", spec, err, want)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	// Monday 15 January 2024, 10:07:30
+	from := time.Date(2024, 1, 15, 10, 7, 30, 0, time.UTC)
+	date := func(year int, month time.Month, day, hour, minute int) time.Time {
+		return time.Date(year, month, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		spec string
+		from time.Time
+		want time.Time
+	}{
+		{"* * * * *", from, date(2024, 1, 15, 10, 8)},
+		{"* * * * *", date(2024, 1, 15, 10, 8), date(2024, 1, 15, 10, 9)},
+		{"*/15 * * * *", from, date(2024, 1, 15, 10, 15)},
+		{"5,10,50 * * * *", from, date(2024, 1, 15, 10, 10)},
+		{"0 * * * *", from, date(2024, 1, 15, 11, 0)},
+		{"7 10 * * *", from, date(2024, 1, 16, 10, 7)},
+		{"8 10 * * *", from, date(2024, 1, 15, 10, 8)},
+		{"0 9-17/4 * * *", from, date(2024, 1, 15, 13, 0)},
+		{"59 23 * * *", from, date(2024, 1, 15, 23, 59)},
+		{"30 6 * * 1-5", from, date(2024, 1, 16, 6, 30)},
+		{"30 6 * * 1-5", date(2024, 1, 19, 7, 0), date(2024, 1, 22, 6, 30)},
+		{"0 0 * * 0", from, date(2024, 1, 21, 0, 0)},
+		{"0 0 * * 7", from, date(2024, 1, 21, 0, 0)},
+		{"0 0 * * 6,7", from, date(2024, 1, 20, 0, 0)},
+		{"0 0 1 * *", from, date(2024, 2, 1, 0, 0)},
+		{"0 0 31 * *", from, date(2024, 1, 31, 0, 0)},
+		{"0 0 31 * *", date(2024, 2, 1, 0, 0), date(2024, 3, 31, 0, 0)},
+		{"0 0 31 * *", date(2024, 4, 1, 0, 0), date(2024, 5, 31, 0, 0)},
+		{"0 0 1 */3 *", from, date(2024, 4, 1, 0, 0)},
+		{"0 0 1 1 *", from, date(2025, 1, 1, 0, 0)},
+		{"59 23 31 12 *", from, date(2024, 12, 31, 23, 59)},
+		{"* * * * *", date(2024, 12, 31, 23, 59), date(2025, 1, 1, 0, 0)},
+		{"0 0 29 2 *", from, date(2024, 2, 29, 0, 0)},
+		{"0 0 29 2 *", date(2024, 3, 1, 0, 0), date(2028, 2, 29, 0, 0)},
+		// Both days restricted: the 13th or a Friday
+		{"0 12 13 * 5", from, date(2024, 1, 19, 12, 0)},
+		{"0 12 16 * 5", from, date(2024, 1, 16, 12, 0)},
+		{"0 12 13 * 5", date(2024, 9, 7, 0, 0), date(2024, 9, 13, 12, 0)},
+		// A day of month starting with * combines with the day of week: odd
+		// days that are Fridays
+		{"0 12 */2 * 5", from, date(2024, 1, 19, 12, 0)},
+		{"0 12 */2 * 5", date(2024, 1, 20, 0, 0), date(2024, 2, 9, 12, 0)},
+		{"0 12 1-7 * */7", from, date(2024, 2, 4, 12, 0)},
+		{"@hourly", from, date(2024, 1, 15, 11, 0)},
+		{"@daily", from, date(2024, 1, 16, 0, 0)},
+		{"@weekly", from, date(2024, 1, 21, 0, 0)},
+		{"@monthly", from, date(2024, 2, 1, 0, 0)},
+		{"@yearly", from, date(2025, 1, 1, 0, 0)},
+		// Never due
+		{"0 0 30 2 *", from, time.Time{}},
+		{"0 0 31 4,6,9,11 *", from, time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec+" from "+tt.from.Format("2006-01-02 15:04"), func(t *testing.T) {
+			schedule, err := Parse(tt.spec)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := schedule.Next(tt.from); !got.Equal(tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// Next is strictly after the time given, so a task run on the minute it was
+// due is not due again on the same minute
+func TestNextAdvances(t *testing.T) {
+	schedule, _ := Parse("*/5 * * * *")
+	at := time.Date(2024, 1, 15, 10, 5, 0, 0, time.UTC)
+	if got := schedule.Next(at); !got.Equal(at.Add(5 * time.Minute)) {
+		t.Errorf("got %v, want %v", got, at.Add(5*time.Minute))
+	}
+	if got := schedule.Next(at.Add(59 * time.Second)); !got.Equal(at.Add(5 * time.Minute)) {
+		t.Errorf("got %v, want %v", got, at.Add(5*time.Minute))
+	}
+
+	t.Run("sequence", func(t *testing.T) {
+		schedule, _ := Parse("0 8,17 * * 1-5")
+		next := time.Date(2024, 1, 19, 9, 0, 0, 0, time.UTC) // Friday
+		want := []string{"Fri 17:00", "Mon 08:00", "Mon 17:00", "Tue 08:00"}
+		for _, w := range want {
+			next = schedule.Next(next)
+			if got := next.Format("Mon 15:04"); got != w {
+				t.Errorf("got %s, want %s", got, w)
+			}
+		}
+	})
+}
+
+func TestNextInLocation(t *testing.T) {
+	hanoi := time.FixedZone("ICT", 7*3600)
+	schedule, _ := Parse("0 6 * * *")
+
+	// 05:00 in Hanoi is 22:00 UTC the day before
+	from := time.Date(2024, 1, 15, 5, 0, 0, 0, hanoi)
+	got := schedule.Next(from)
+	if want := time.Date(2024, 1, 15, 6, 0, 0, 0, hanoi); !got.Equal(want) || got.Location() != hanoi {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got := schedule.Next(from.UTC()); !got.Equal(time.Date(2024, 1, 15, 6, 0, 0, 0, time.UTC)) {
+		t.Errorf("in UTC got %v, want 06:00 UTC", got)
+	}
+}
+
+// Daily tasks keep their wall clock time across daylight saving changes. A
+// time the clocks skip is not due that day and one they repeat is due once.
+func TestNextAcrossDaylightSaving(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("no time zone data: %v", err)
+	}
+	// Havana moves its clocks at midnight, so some days have no midnight
+	havana, err := time.LoadLocation("America/Havana")
+	if err != nil {
+		t.Skipf("no time zone data: %v", err)
+	}
+	local := func(loc *time.Location, month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2024, month, day, hour, minute, 0, 0, loc)
+	}
+	// 01:00 to 02:00 is repeated on 3 November, first in EDT and then in EST
+	secondPass := local(newYork, time.November, 3, 1, 0).Add(time.Hour)
+
+	tests := []struct {
+		spec string
+		from time.Time
+		want time.Time
+	}{
+		// Clocks go forward from 02:00 to 03:00 on 10 March
+		{"0 6 * * *", local(newYork, time.March, 9, 7, 0), local(newYork, time.March, 10, 6, 0)},
+		{"0 6 * * *", local(newYork, time.March, 10, 7, 0), local(newYork, time.March, 11, 6, 0)},
+		{"0 * * * *", local(newYork, time.March, 10, 1, 30), local(newYork, time.March, 10, 3, 0)},
+		{"30 2 * * *", local(newYork, time.March, 10, 0, 0), local(newYork, time.March, 11, 2, 30)},
+		{"*/20 * * * *", local(newYork, time.March, 10, 1, 50), local(newYork, time.March, 10, 3, 0)},
+		// Clocks go back from 02:00 to 01:00 on 3 November
+		{"0 6 * * *", local(newYork, time.November, 2, 7, 0), local(newYork, time.November, 3, 6, 0)},
+		{"0 6 * * *", local(newYork, time.November, 3, 7, 0), local(newYork, time.November, 4, 6, 0)},
+		{"30 1 * * *", local(newYork, time.November, 3, 0, 0), local(newYork, time.November, 3, 1, 30)},
+		{"30 1 * * *", local(newYork, time.November, 3, 1, 30), local(newYork, time.November, 4, 1, 30)},
+		{"30 1 * * *", secondPass, local(newYork, time.November, 4, 1, 30)},
+		{"0 * * * *", local(newYork, time.November, 3, 0, 30), local(newYork, time.November, 3, 1, 0)},
+		{"0 * * * *", local(newYork, time.November, 3, 1, 0), local(newYork, time.November, 3, 2, 0)},
+		// Clocks go forward from midnight to 01:00 on 10 March in Havana
+		{"0 0 * * *", local(havana, time.March, 9, 12, 0), local(havana, time.March, 11, 0, 0)},
+		{"0 6 * * *", local(havana, time.March, 9, 12, 0), local(havana, time.March, 10, 6, 0)},
+		{"0 0 10 3 *", local(havana, time.March, 1, 0, 0), time.Date(2025, time.March, 10, 0, 0, 0, 0, havana)},
+	}
+	for _, tt := range tests {
+		schedule, _ := Parse(tt.spec)
+		if got := schedule.Next(tt.from); !got.Equal(tt.want) {
+			t.Errorf("%s from %v: got %v, want %v", tt.spec, tt.from, got, tt.want)
+		}
+	}
+}
@@ -1,42 +1,88 @@
 package database
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
+	"time"
 
 	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
 	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/config"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/tracing"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
-func NewDatabase(cfg *config.Config) (*gorm.DB, error) {
-	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
+// dataSourceName returns the connection string of the configured database
+func dataSourceName(cfg *config.Config) string {
+	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
 		cfg.Database.Host,
 		cfg.Database.Port,
 		cfg.Database.User,
 		cfg.Database.Password,
 		cfg.Database.DBName,
 	)
+}
 
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+// Open connects to the configured database, with the pool settings, query
+// tracing and field change history, without migrating it
+func Open(cfg *config.Config) (*gorm.DB, error) {
+	db, err := gorm.Open(postgres.Open(dataSourceName(cfg)), &gorm.Config{
+		CreateBatchSize: cfg.Database.CreateBatchSize,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
-	// Auto migrate the schema
-	if err := db.AutoMigrate(
-		&entity.Role{},
-		&entity.User{},
-		&entity.AuditLog{},
-		&entity.Store{},
-		&entity.Stock{},
-		&entity.StockEntry{},
-		&entity.StockHistory{},
-		&entity.Client{},
-		&entity.ClientAddress{},
-	); err != nil {
-		return nil, fmt.Errorf("failed to migrate database: %w", err)
+	// Trace the queries of traced requests
+	if cfg.Tracing.Enabled {
+		if err := db.Use(tracing.GORMPlugin()); err != nil {
+			return nil, fmt.Errorf("failed to trace database queries: %w", err)
+		}
+	}
+
+	// Record the field changes of critical entities
+	if err := db.Use(HistoryPlugin(entity.ChangeEntities...)); err != nil {
+		return nil, fmt.Errorf("failed to record field changes: %w", err)
+	}
+
+	// Configure the connection pool
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database handle: %w", err)
+	}
+	sqlDB.SetMaxOpenConns(cfg.Database.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.Database.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(time.Duration(cfg.Database.ConnMaxLifetime) * time.Second)
+	sqlDB.SetConnMaxIdleTime(time.Duration(cfg.Database.ConnMaxIdleTime) * time.Second)
+	return db, nil
+}
+
+// NewDatabase opens the configured database, applies the pending migrations
+// unless database.auto_migrate is off and creates the admin role and user
+func NewDatabase(cfg *config.Config) (*gorm.DB, error) {
+	db, err := Open(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Database.AutoMigrate {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get database handle: %w", err)
+		}
+		migrator, err := NewMigrator(sqlDB)
+		if err != nil {
+			return nil, err
+		}
+		applied, err := migrator.Up(context.Background())
+		for _, migration := range applied {
+			slog.Info("applied migration", "version", migration.Version, "name", migration.Name)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to migrate database: %w", err)
+		}
 	}
 
 	// Create default admin role if not exists
@@ -49,12 +95,30 @@ func NewDatabase(cfg *config.Config) (*gorm.DB, error) {
 				entity.UserRead,
 				entity.UserUpdate,
 				entity.UserDelete,
+				entity.UserProvision,
+				entity.UserImpersonate,
 				entity.RoleCreate,
 				entity.RoleRead,
 				entity.RoleUpdate,
 				entity.RoleDelete,
 				entity.AuditLogRead,
+				entity.AuditLogExport,
+				entity.AuditLogVerify,
+				entity.AuditLogRetain,
+				entity.AccessElevationRead,
+				entity.AccessElevationApprove,
+				entity.ApprovalDelegationManage,
 				entity.ModuleIntegrate,
+				entity.SystemDatabaseRead,
+				entity.SystemSandboxUse,
+				entity.SystemSandboxReset,
+				entity.SystemArchiveRun,
+				entity.SystemJobRead,
+				entity.SystemJobManage,
+				entity.SystemSettingsRead,
+				entity.SystemSettingsUpdate,
+				entity.SystemAPIKeyRead,
+				entity.SystemAPIKeyManage,
 
 				// Store permissions
 				entity.StoreCreate,
@@ -67,6 +131,23 @@ func NewDatabase(cfg *config.Config) (*gorm.DB, error) {
 				entity.StockUpdate,
 				entity.StockEntryCreate,
 				entity.StockEntryRead,
+				entity.StockProvisionRead,
+				entity.StockProvisionManage,
+				entity.StockConsignmentRead,
+				entity.StockConsignmentManage,
+				entity.StockPutawayRead,
+				entity.StockPutawayConfirm,
+				entity.StockPutawayManage,
+				entity.StockInboundRead,
+				entity.StockInboundSchedule,
+				entity.StockInboundManage,
+				entity.StockClassRun,
+				entity.StockSnapshotRun,
+
+				// Warehouse condition permissions
+				entity.ConditionRead,
+				entity.ConditionRecord,
+				entity.ConditionManage,
 
 				// Client permissions
 				entity.ClientCreate,
@@ -81,6 +162,71 @@ func NewDatabase(cfg *config.Config) (*gorm.DB, error) {
 				entity.ClientDebtUpdate,
 				entity.ClientLoyaltyRead,
 				entity.ClientLoyaltyUpdate,
+				entity.ClientRFMRead,
+				entity.ClientRFMRun,
+				entity.ClientPortalTokenIssue,
+				entity.ClientPrivacyManage,
+
+				// Fulfillment SLA permissions
+				entity.SalesFulfillmentManage,
+
+				// Sales channel permissions
+				entity.SalesChannelRead,
+				entity.SalesChannelManage,
+				entity.SalesChannelSync,
+
+				// Purchase permissions
+				entity.PurchaseRequestCreate,
+				entity.PurchaseRequestRead,
+				entity.PurchaseRequestUpdate,
+				entity.PurchaseRequestDelete,
+				entity.PurchaseRequestApprove,
+				entity.PurchaseOrderCreate,
+				entity.PurchaseOrderRead,
+				entity.PurchaseOrderUpdate,
+				entity.PurchaseOrderDelete,
+				entity.PurchaseOrderApprove,
+				entity.PurchaseReceiptCreate,
+				entity.PurchaseReceiptRead,
+				entity.PurchaseReceiptUpdate,
+				entity.PurchasePaymentCreate,
+				entity.PurchasePaymentRead,
+				entity.PurchasePaymentUpdate,
+
+				// EDI permissions
+				entity.PurchaseEDIRead,
+				entity.PurchaseEDIManage,
+				entity.PurchaseEDIExchange,
+
+				// Accounting export permissions
+				entity.FinanceExportRead,
+				entity.FinanceExportRun,
+
+				// Bank feed permissions
+				entity.FinanceBankRead,
+				entity.FinanceBankManage,
+				entity.FinanceBankReconcile,
+
+				// Fiscal period permissions
+				entity.FinancePeriodRead,
+				entity.FinancePeriodManage,
+				entity.FinancePeriodOverride,
+
+				// Expense claim permissions
+				entity.FinanceExpenseCreate,
+				entity.FinanceExpenseRead,
+				entity.FinanceExpenseApprove,
+				entity.FinanceExpenseReimburse,
+				entity.FinanceExpenseManage,
+
+				// Withholding tax permissions
+				entity.FinanceWithholdingRead,
+				entity.FinanceWithholdingManage,
+
+				// Prepayment permissions
+				entity.FinancePrepaymentCreate,
+				entity.FinancePrepaymentRead,
+				entity.FinancePrepaymentCancel,
 			},
 		}
 
@@ -109,5 +255,19 @@ func NewDatabase(cfg *config.Config) (*gorm.DB, error) {
 		}
 	}
 
+	// Route sandbox requests to the shadow schema
+	if cfg.Sandbox.Enabled {
+		if err := enableSandbox(db, dataSourceName(cfg), cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	// Route report reads to the read replica
+	if cfg.Database.ReplicaDSN != "" {
+		if err := enableReplica(db, cfg); err != nil {
+			return nil, err
+		}
+	}
+
 	return db, nil
 }
@@ -0,0 +1,201 @@
+package database
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"gorm.io/gorm"
+)
+
+// models are the entities kept in tables of their own, checked against the
+// schema the migrations create
+var models = []interface{}{
+	&entity.APIKey{},
+	&entity.AccountingExportRecord{},
+	&entity.AccountingExportRun{},
+	&entity.ApprovalDelegation{},
+	&entity.AssetDepreciationEntry{},
+	&entity.Attachment{},
+	&entity.AuditLog{},
+	&entity.AuditLogArchive{},
+	&entity.BOMItem{},
+	&entity.BankAccount{},
+	&entity.BankImportRun{},
+	&entity.BankTransaction{},
+	&entity.BillOfMaterial{},
+	&entity.Branding{},
+	&entity.CalendarHoliday{},
+	&entity.ChannelSKUMapping{},
+	&entity.ChannelSyncRun{},
+	&entity.Client{},
+	&entity.ClientAddress{},
+	&entity.ClientPrivacyRequest{},
+	&entity.ClientRFMScore{},
+	&entity.ConditionExcursion{},
+	&entity.ConditionExcursionLot{},
+	&entity.ConditionReading{},
+	&entity.ConditionThreshold{},
+	&entity.ConsignmentConsumption{},
+	&entity.ConsignmentStock{},
+	&entity.Contract{},
+	&entity.CustomFieldDefinition{},
+	&entity.DashboardMetricSnapshot{},
+	&entity.DefectCode{},
+	&entity.DeliveryOrder{},
+	&entity.DemandForecastLine{},
+	&entity.DemandForecastVersion{},
+	&entity.DockAppointment{},
+	&entity.DockDoor{},
+	&entity.DunningLevel{},
+	&entity.DunningReminder{},
+	&entity.EDIDocument{},
+	&entity.ElevatedAccessGrant{},
+	&entity.ElevatedAction{},
+	&entity.EventLogEntry{},
+	&entity.ExchangeRate{},
+	&entity.ExpenseCategory{},
+	&entity.ExpenseClaim{},
+	&entity.ExpenseClaimLine{},
+	&entity.FieldChange{},
+	&entity.FinancePayment{},
+	&entity.FiscalPeriod{},
+	&entity.FiscalYear{},
+	&entity.FixedAsset{},
+	&entity.FulfillmentSLAAlert{},
+	&entity.IdempotencyKey{},
+	&entity.ImpersonationSession{},
+	&entity.InspectionPlan{},
+	&entity.InventoryProvisionEntry{},
+	&entity.Invoice{},
+	&entity.Job{},
+	&entity.KitComponent{},
+	&entity.LoginAttempt{},
+	&entity.ManufacturingFacility{},
+	&entity.MaterialCost{},
+	&entity.Notification{},
+	&entity.NotificationPreference{},
+	&entity.NotificationTemplate{},
+	&entity.NumberingScheme{},
+	&entity.PaymentWebhookEvent{},
+	&entity.Prepayment{},
+	&entity.PrepaymentApplication{},
+	&entity.ProductionMaterialIssue{},
+	&entity.ProductionOrder{},
+	&entity.PurchaseOrder{},
+	&entity.PurchaseOrderRequest{},
+	&entity.PurchasePayment{},
+	&entity.PurchaseReceipt{},
+	&entity.PurchaseRequest{},
+	&entity.PutawayRule{},
+	&entity.PutawayTask{},
+	&entity.QualityInspection{},
+	&entity.RecurringInvoice{},
+	&entity.RecurringInvoiceRun{},
+	&entity.Report{},
+	&entity.ReportRun{},
+	&entity.ReportSchedule{},
+	&entity.Role{},
+	&entity.RoleMapping{},
+	&entity.SKU{},
+	&entity.SKUCategory{},
+	&entity.SKUImage{},
+	&entity.SalesChannel{},
+	&entity.SalesChannelWarehouse{},
+	&entity.SalesForecast{},
+	&entity.SalesOrder{},
+	&entity.SalesReturn{},
+	&entity.SavedView{},
+	&entity.ScheduledRun{},
+	&entity.Stock{},
+	&entity.StockAllocation{},
+	&entity.StockEntry{},
+	&entity.StockHistory{},
+	&entity.StockSnapshot{},
+	&entity.Store{},
+	&entity.TradingPartner{},
+	&entity.User{},
+	&entity.Vendor{},
+	&entity.VendorPriceList{},
+	&entity.VendorPriceListItem{},
+	&entity.VendorRating{},
+	&entity.VendorRiskAlert{},
+	&entity.VendorRiskScore{},
+	&entity.WithholdingTaxRate{},
+	&entity.WorkCenter{},
+	&entity.WorkingCalendar{},
+	&entity.WriteDownRule{},
+}
+
+// DriftIssue is a difference between a model and the table the migrations
+// created for it
+type DriftIssue struct {
+	Model  string
+	Table  string
+	Column string
+	Issue  string
+}
+
+func (d DriftIssue) String() string {
+	if d.Column == "" {
+		return fmt.Sprintf("%s: table %s %s", d.Model, d.Table, d.Issue)
+	}
+	return fmt.Sprintf("%s: column %s.%s %s", d.Model, d.Table, d.Column, d.Issue)
+}
+
+// CheckDrift compares the models with the database schema and reports the
+// tables and columns a model uses that the database lacks, and the NOT NULL
+// columns without a default a model does not fill, on which its inserts would
+// fail. Run against a database migrated from scratch, it finds the model
+// changes a migration was not written for.
+func CheckDrift(db *gorm.DB) ([]DriftIssue, error) {
+	var issues []DriftIssue
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return nil, fmt.Errorf("failed to parse model %T: %w", model, err)
+		}
+		name := stmt.Schema.Name
+		table := stmt.Schema.Table
+
+		if !db.Migrator().HasTable(table) {
+			issues = append(issues, DriftIssue{Model: name, Table: table, Issue: "is missing"})
+			continue
+		}
+		columnTypes, err := db.Migrator().ColumnTypes(table)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read columns of %s: %w", table, err)
+		}
+
+		columns := make(map[string]gorm.ColumnType, len(columnTypes))
+		for _, column := range columnTypes {
+			columns[column.Name()] = column
+		}
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName == "" || field.IgnoreMigration {
+				continue
+			}
+			if _, ok := columns[field.DBName]; !ok {
+				issues = append(issues, DriftIssue{Model: name, Table: table, Column: field.DBName, Issue: "is missing"})
+			}
+		}
+		for columnName, column := range columns {
+			if _, ok := stmt.Schema.FieldsByDBName[columnName]; ok {
+				continue
+			}
+			nullable, _ := column.Nullable()
+			_, hasDefault := column.DefaultValue()
+			if !nullable && !hasDefault {
+				issues = append(issues, DriftIssue{Model: name, Table: table, Column: columnName, Issue: "is NOT NULL without a default and unknown to the model"})
+			}
+		}
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].Table != issues[j].Table {
+			return issues[i].Table < issues[j].Table
+		}
+		return issues[i].Column < issues[j].Column
+	})
+	return issues, nil
+}
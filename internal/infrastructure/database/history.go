@@ -0,0 +1,184 @@
+package database
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/logging"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	historyBeforeKey = "history:before"
+	// historyRowLimit caps the rows of one update whose changes are recorded,
+	// so that bulk updates do not read whole tables
+	historyRowLimit = 500
+)
+
+// historyIgnoredFields are not recorded, as every update changes them
+var historyIgnoredFields = map[string]bool{
+	"created_at": true,
+	"updated_at": true,
+}
+
+// historyPlugin records the fields an update changes on the rows of some
+// tables, with the user of the statement's context, as entity.FieldChange
+type historyPlugin struct {
+	tables map[string]bool
+}
+
+// HistoryPlugin returns the plugin recording the field changes of the rows of
+// some tables, for db.Use
+func HistoryPlugin(tables ...string) gorm.Plugin {
+	p := historyPlugin{tables: make(map[string]bool, len(tables))}
+	for _, table := range tables {
+		p.tables[table] = true
+	}
+	return p
+}
+
+func (historyPlugin) Name() string {
+	return "history"
+}
+
+func (p historyPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback().Update()
+	if err := cb.Before("gorm:update").Register("history:before_update", p.before); err != nil {
+		return err
+	}
+	return cb.After("gorm:update").Register("history:after_update", p.after)
+}
+
+// before reads the rows the update is about to change
+func (p historyPlugin) before(db *gorm.DB) {
+	if db.Error != nil || db.DryRun || !p.tables[db.Statement.Table] || db.Statement.Schema == nil {
+		return
+	}
+	pk := db.Statement.Schema.PrioritizedPrimaryField
+	if pk == nil {
+		return
+	}
+
+	query := db.Session(&gorm.Session{NewDB: true}).Table(db.Statement.Table)
+	conditions := false
+	if where, ok := db.Statement.Clauses["WHERE"]; ok && where.Expression != nil {
+		query = query.Clauses(where.Expression)
+		conditions = true
+	}
+	// Saving a record adds its primary key to the conditions later on
+	if db.Statement.ReflectValue.Kind() == reflect.Struct {
+		if value, zero := pk.ValueOf(db.Statement.Context, db.Statement.ReflectValue); !zero {
+			query = query.Where(clause.Eq{Column: clause.Column{Name: pk.DBName}, Value: value})
+			conditions = true
+		}
+	}
+	if !conditions {
+		return
+	}
+
+	var rows []map[string]interface{}
+	if err := query.Limit(historyRowLimit).Find(&rows).Error; err != nil {
+		logging.FromContext(db.Statement.Context).Error("failed to read rows before update",
+			"table", db.Statement.Table, "error", err)
+		return
+	}
+	if len(rows) > 0 {
+		db.InstanceSet(historyBeforeKey, rows)
+	}
+}
+
+// after compares the rows read before the update with their new values and
+// records the fields that differ
+func (p historyPlugin) after(db *gorm.DB) {
+	value, ok := db.InstanceGet(historyBeforeKey)
+	if !ok || db.Error != nil || db.RowsAffected == 0 {
+		return
+	}
+	before := value.([]map[string]interface{})
+	pk := db.Statement.Schema.PrioritizedPrimaryField.DBName
+	ctx := db.Statement.Context
+
+	ids := make([]interface{}, 0, len(before))
+	for _, row := range before {
+		ids = append(ids, row[pk])
+	}
+	var after []map[string]interface{}
+	session := db.Session(&gorm.Session{NewDB: true})
+	if err := session.Table(db.Statement.Table).Where(clause.IN{Column: clause.Column{Name: pk}, Values: ids}).Find(&after).Error; err != nil {
+		logging.FromContext(ctx).Error("failed to read rows after update",
+			"table", db.Statement.Table, "error", err)
+		return
+	}
+	current := make(map[string]map[string]interface{}, len(after))
+	for _, row := range after {
+		current[fmt.Sprint(row[pk])] = row
+	}
+
+	now := time.Now()
+	changedBy := entity.ActorFromContext(ctx)
+	var changes []entity.FieldChange
+	for _, old := range before {
+		id := fmt.Sprint(old[pk])
+		row, ok := current[id]
+		if !ok {
+			continue
+		}
+		fields := make([]string, 0, len(row))
+		for field := range row {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		for _, field := range fields {
+			if historyIgnoredFields[field] {
+				continue
+			}
+			oldValue, newValue := historyValue(old[field]), historyValue(row[field])
+			if equalHistoryValues(oldValue, newValue) {
+				continue
+			}
+			changes = append(changes, entity.FieldChange{
+				EntityType: db.Statement.Table,
+				EntityID:   id,
+				Field:      field,
+				OldValue:   oldValue,
+				NewValue:   newValue,
+				ChangedBy:  changedBy,
+				ChangedAt:  now,
+			})
+		}
+	}
+	if len(changes) == 0 {
+		return
+	}
+	if err := session.Omit("User").Create(&changes).Error; err != nil {
+		logging.FromContext(ctx).Error("failed to record field changes",
+			"table", db.Statement.Table, "error", err)
+	}
+}
+
+// historyValue renders a column value as text, or nil for NULL
+func historyValue(value interface{}) *string {
+	var s string
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case time.Time:
+		s = v.UTC().Format(time.RFC3339Nano)
+	case []byte:
+		s = string(v)
+	default:
+		s = fmt.Sprint(v)
+	}
+	return &s
+}
+
+func equalHistoryValues(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,399 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var updateChecksums = flag.Bool("update-checksums", false, "record the checksums of the migrations in testdata/migrations.sum")
+
+// testDSNEnv names the variable holding the DSN of a disposable PostgreSQL
+// database the database tests run against. They are skipped without it.
+const testDSNEnv = "ERP_TEST_DATABASE_DSN"
+
+func migrationFS(files map[string]string) fstest.MapFS {
+	fsys := fstest.MapFS{}
+	for name, content := range files {
+		fsys["migrations/"+name] = &fstest.MapFile{Data: []byte(content)}
+	}
+	return fsys
+}
+
+func TestLoadMigrations(t *testing.T) {
+	fsys := migrationFS(map[string]string{
+		"000010_add_c.up.sql":      "c up",
+		"000010_add_c.down.sql":    "c down",
+		"000002_add_b.down.sql":    "b down",
+		"000002_add_b.up.sql":      "b up",
+		"000009_add_a.up.sql":      "a up",
+		"000009_add_a.down.sql":    "a down",
+		"README.md":                "not a migration",
+		"000011_add_d.up.sql.orig": "not a migration either",
+	})
+
+	migrations, err := loadMigrations(fsys, "migrations")
+	if err != nil {
+		t.Fatalf("loadMigrations() error = %v", err)
+	}
+	want := []Migration{
+		{Version: 2, Name: "add_b", Up: "b up", Down: "b down"},
+		{Version: 9, Name: "add_a", Up: "a up", Down: "a down"},
+		{Version: 10, Name: "add_c", Up: "c up", Down: "c down"},
+	}
+	if len(migrations) != len(want) {
+		t.Fatalf("loadMigrations() = %+v, want %+v", migrations, want)
+	}
+	for i := range want {
+		if migrations[i] != want[i] {
+			t.Errorf("migration %d = %+v, want %+v", i, migrations[i], want[i])
+		}
+	}
+}
+
+func TestLoadMigrationsErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{"missing down", map[string]string{"000001_a.up.sql": "up"}, "needs both an up and a down file"},
+		{"missing up", map[string]string{"000001_a.down.sql": "down"}, "needs both an up and a down file"},
+		{"two names", map[string]string{"000001_a.up.sql": "up", "000001_b.down.sql": "down"}, "is named both"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadMigrations(migrationFS(tt.files), "migrations")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("loadMigrations() error = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+// The embedded migrations load in increasing version order
+func TestEmbeddedMigrations(t *testing.T) {
+	migrations, err := loadMigrations(migrationFiles, "migrations")
+	if err != nil {
+		t.Fatalf("loadMigrations() error = %v", err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("no migrations embedded")
+	}
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version <= migrations[i-1].Version {
+			t.Errorf("migration %d_%s follows %d_%s", migrations[i].Version, migrations[i].Name, migrations[i-1].Version, migrations[i-1].Name)
+		}
+	}
+}
+
+// Applied migrations are never edited: the checksum of each must match the
+// one recorded when it was added. Record those of new migrations with
+// go test ./internal/infrastructure/database -run TestMigrationChecksums -update-checksums
+func TestMigrationChecksums(t *testing.T) {
+	migrations, err := loadMigrations(migrationFiles, "migrations")
+	if err != nil {
+		t.Fatalf("loadMigrations() error = %v", err)
+	}
+	sumFile := filepath.Join("testdata", "migrations.sum")
+
+	var lines []string
+	for _, m := range migrations {
+		lines = append(lines, fmt.Sprintf("%06d_%s %x %x", m.Version, m.Name, sha256.Sum256([]byte(m.Up)), sha256.Sum256([]byte(m.Down))))
+	}
+
+	if *updateChecksums {
+		recorded := readChecksums(t, sumFile)
+		for _, line := range lines {
+			name, _, _ := strings.Cut(line, " ")
+			if sums, ok := recorded[name]; ok && name+" "+sums != line {
+				t.Fatalf("migration %s was edited after its checksum was recorded", name)
+			}
+		}
+		if err := os.WriteFile(sumFile, []byte(strings.Join(lines, "\n")+"\n"), 0o644); err != nil {
+			t.Fatalf("writing %s: %v", sumFile, err)
+		}
+		return
+	}
+
+	recorded := readChecksums(t, sumFile)
+	for _, line := range lines {
+		name, sums, _ := strings.Cut(line, " ")
+		want, ok := recorded[name]
+		switch {
+		case !ok:
+			t.Errorf("migration %s has no recorded checksum; run the test with -update-checksums", name)
+		case sums != want:
+			t.Errorf("migration %s was edited after it was added; write a new migration instead", name)
+		}
+		delete(recorded, name)
+	}
+	for name := range recorded {
+		t.Errorf("migration %s was removed", name)
+	}
+}
+
+func readChecksums(t *testing.T, path string) map[string]string {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]string{}
+	}
+	if err != nil {
+		t.Fatalf("reading %s: %v", path, err)
+	}
+	recorded := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+		if name, sums, ok := strings.Cut(line, " "); ok {
+			recorded[name] = sums
+		}
+	}
+	return recorded
+}
+
+// openTestSchema connects to the test database with a schema of its own as
+// the search path, dropped when the test ends, so that migrations run from
+// scratch
+func openTestSchema(t *testing.T) *sql.DB {
+	t.Helper()
+	dsn := os.Getenv(testDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", testDSNEnv)
+	}
+
+	admin, err := sql.Open("pgx", dsn)
+	if err != nil {
+		t.Fatalf("opening test database: %v", err)
+	}
+	t.Cleanup(func() { admin.Close() })
+	schema := "migrate_test_" + uuid.New().String()[:8]
+	if _, err := admin.Exec("CREATE SCHEMA " + schema); err != nil {
+		t.Fatalf("creating schema: %v", err)
+	}
+	t.Cleanup(func() { admin.Exec("DROP SCHEMA " + schema + " CASCADE") })
+
+	// pgx passes unknown DSN settings on as run-time parameters. Extensions
+	// such as pg_trgm stay installed in public.
+	searchPath := schema + ",public"
+	if strings.Contains(dsn, "://") {
+		if strings.Contains(dsn, "?") {
+			dsn += "&search_path=" + searchPath
+		} else {
+			dsn += "?search_path=" + searchPath
+		}
+	} else {
+		dsn += " search_path=" + searchPath
+	}
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		t.Fatalf("opening test schema: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func testMigrator(t *testing.T, db *sql.DB, files map[string]string) *Migrator {
+	t.Helper()
+	migrations, err := loadMigrations(migrationFS(files), "migrations")
+	if err != nil {
+		t.Fatalf("loadMigrations() error = %v", err)
+	}
+	return &Migrator{db: db, migrations: migrations}
+}
+
+func tableExists(t *testing.T, db *sql.DB, table string) bool {
+	t.Helper()
+	var exists bool
+	if err := db.QueryRow("SELECT to_regclass($1) IS NOT NULL", table).Scan(&exists); err != nil {
+		t.Fatalf("checking table %s: %v", table, err)
+	}
+	return exists
+}
+
+func assertVersion(t *testing.T, m *Migrator, want uint) {
+	t.Helper()
+	version, dirty, err := m.Version(context.Background())
+	if err != nil {
+		t.Fatalf("Version() error = %v", err)
+	}
+	if version != want || dirty {
+		t.Errorf("Version() = %d, dirty %v, want %d, clean", version, dirty, want)
+	}
+}
+
+var testMigrations = map[string]string{
+	"000001_create_a.up.sql":   "CREATE TABLE a (id INTEGER);",
+	"000001_create_a.down.sql": "DROP TABLE a;",
+	"000002_create_b.up.sql":   "CREATE TABLE b (id INTEGER); INSERT INTO b SELECT id FROM missing;",
+	"000002_create_b.down.sql": "DROP TABLE b;",
+	"000003_create_c.up.sql":   "CREATE TABLE c (id INTEGER);",
+	"000003_create_c.down.sql": "DROP TABLE c;",
+}
+
+// A failing migration is rolled back whole, leaving the database clean at
+// the version before it, and the migrations after it unapplied
+func TestMigratorPartialFailure(t *testing.T) {
+	db := openTestSchema(t)
+	ctx := context.Background()
+
+	m := testMigrator(t, db, testMigrations)
+	applied, err := m.Up(ctx)
+	if err == nil || !strings.Contains(err.Error(), "2_create_b") {
+		t.Fatalf("Up() error = %v, want the failure of 2_create_b", err)
+	}
+	if len(applied) != 1 || applied[0].Version != 1 {
+		t.Errorf("Up() applied %+v, want 1_create_a", applied)
+	}
+	assertVersion(t, m, 1)
+	if !tableExists(t, db, "a") || tableExists(t, db, "b") || tableExists(t, db, "c") {
+		t.Error("want table a only")
+	}
+
+	// Once the migration is fixed the rest apply
+	fixed := make(map[string]string)
+	for name, script := range testMigrations {
+		fixed[name] = script
+	}
+	fixed["000002_create_b.up.sql"] = "CREATE TABLE b (id INTEGER);"
+	m = testMigrator(t, db, fixed)
+	if applied, err = m.Up(ctx); err != nil || len(applied) != 2 {
+		t.Fatalf("Up() = %+v, %v, want 2 and 3 applied", applied, err)
+	}
+	assertVersion(t, m, 3)
+
+	// Reverting steps back through the versions
+	reverted, err := m.Down(ctx, 2)
+	if err != nil || len(reverted) != 2 || reverted[0].Version != 3 || reverted[1].Version != 2 {
+		t.Fatalf("Down(2) = %+v, %v, want 3 and 2 reverted", reverted, err)
+	}
+	assertVersion(t, m, 1)
+	if tableExists(t, db, "b") || tableExists(t, db, "c") {
+		t.Error("tables b and c remain after reverting")
+	}
+}
+
+// A database a migration failed halfway in under another tool is left alone
+// until it is forced to a version
+func TestMigratorDirtyDatabase(t *testing.T) {
+	db := openTestSchema(t)
+	ctx := context.Background()
+	m := testMigrator(t, db, map[string]string{
+		"000001_create_a.up.sql":   "CREATE TABLE a (id INTEGER);",
+		"000001_create_a.down.sql": "DROP TABLE a;",
+	})
+
+	if err := ensureVersionTable(ctx, db); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("INSERT INTO schema_migrations (version, dirty) VALUES (1, true)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(ctx); !errors.Is(err, ErrDirtyDatabase) {
+		t.Fatalf("Up() error = %v, want %v", err, ErrDirtyDatabase)
+	}
+
+	if err := m.Force(ctx, 0); err != nil {
+		t.Fatalf("Force(0) error = %v", err)
+	}
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatalf("Up() after Force error = %v", err)
+	}
+	assertVersion(t, m, 1)
+}
+
+// Instances migrating at once apply each migration once
+func TestMigratorConcurrentUp(t *testing.T) {
+	db := openTestSchema(t)
+	files := map[string]string{
+		"000001_create_a.up.sql":   "CREATE TABLE a (id INTEGER);",
+		"000001_create_a.down.sql": "DROP TABLE a;",
+	}
+
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		total int
+	)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			applied, err := testMigrator(t, db, files).Up(context.Background())
+			if err != nil {
+				t.Errorf("Up() error = %v", err)
+			}
+			mu.Lock()
+			total += len(applied)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	if total != 1 {
+		t.Errorf("migrations applied %d times, want once", total)
+	}
+}
+
+// The embedded migrations build the schema the models expect, and changes
+// made outside them show as drift
+func TestCheckDrift(t *testing.T) {
+	db := openTestSchema(t)
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issues, err := CheckDrift(gormDB)
+	if err != nil {
+		t.Fatalf("CheckDrift() error = %v", err)
+	}
+	for _, issue := range issues {
+		t.Errorf("drift after migrating: %s", issue)
+	}
+
+	for _, stmt := range []string{
+		"ALTER TABLE vendors DROP COLUMN website",
+		"ALTER TABLE vendors ADD COLUMN region TEXT NOT NULL",
+		"DROP TABLE saved_views",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	issues, err = CheckDrift(gormDB)
+	if err != nil {
+		t.Fatalf("CheckDrift() error = %v", err)
+	}
+	want := []string{
+		"SavedView: table saved_views is missing",
+		"Vendor: column vendors.region is NOT NULL without a default and unknown to the model",
+		"Vendor: column vendors.website is missing",
+	}
+	var got []string
+	for _, issue := range issues {
+		got = append(got, issue.String())
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("CheckDrift() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
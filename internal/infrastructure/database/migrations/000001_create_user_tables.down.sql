@@ -0,0 +1,4 @@
+-- Drop the user tables
+DROP TABLE IF EXISTS audit_logs;
+DROP TABLE IF EXISTS users;
+DROP TABLE IF EXISTS roles;
@@ -0,0 +1,35 @@
+-- Create roles table, the permission sets users are given
+CREATE TABLE IF NOT EXISTS roles (
+	id BIGSERIAL PRIMARY KEY,
+	name TEXT NOT NULL UNIQUE,
+	permissions TEXT[],
+	created_at TIMESTAMP WITH TIME ZONE,
+	updated_at TIMESTAMP WITH TIME ZONE
+);
+-- Create users table
+CREATE TABLE IF NOT EXISTS users (
+	id BIGSERIAL PRIMARY KEY,
+	username TEXT NOT NULL UNIQUE,
+	email TEXT NOT NULL UNIQUE,
+	password TEXT NOT NULL,
+	role_id BIGINT NOT NULL REFERENCES roles(id),
+	status VARCHAR(20) DEFAULT 'active',
+	last_login TIMESTAMP WITH TIME ZONE,
+	refresh_token TEXT,
+	refresh_token_expiry TIMESTAMP WITH TIME ZONE,
+	password_reset_token VARCHAR(100),
+	reset_token_expiry TIMESTAMP WITH TIME ZONE,
+	created_at TIMESTAMP WITH TIME ZONE,
+	updated_at TIMESTAMP WITH TIME ZONE
+);
+-- Create audit_logs table, the record of the changes users make
+CREATE TABLE IF NOT EXISTS audit_logs (
+	id BIGSERIAL PRIMARY KEY,
+	user_id BIGINT REFERENCES users(id),
+	action VARCHAR(20),
+	resource VARCHAR(50),
+	detail TEXT,
+	ip VARCHAR(45),
+	user_agent TEXT,
+	created_at TIMESTAMP WITH TIME ZONE
+);
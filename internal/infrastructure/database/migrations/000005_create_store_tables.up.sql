@@ -5,7 +5,7 @@ CREATE TABLE IF NOT EXISTS stores (
 	name VARCHAR(255) NOT NULL,
 	address TEXT,
 	type store_type NOT NULL,
-	manager_id UUID NOT NULL REFERENCES users(id),
+	manager_id INTEGER NOT NULL REFERENCES users(id),
 	contact VARCHAR(255),
 	status store_status NOT NULL DEFAULT 'ACTIVE',
 	created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
@@ -39,7 +39,7 @@ CREATE TABLE IF NOT EXISTS stock_entries (
 	reference VARCHAR(100),
 	note TEXT,
 	created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
-	created_by UUID NOT NULL REFERENCES users(id)
+	created_by VARCHAR(255) NOT NULL
 );
 CREATE TABLE IF NOT EXISTS stock_history (
 	id UUID PRIMARY KEY,
@@ -51,7 +51,7 @@ CREATE TABLE IF NOT EXISTS stock_history (
 	reference UUID REFERENCES stock_entries(id),
 	note TEXT,
 	created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
-	created_by UUID NOT NULL REFERENCES users(id)
+	created_by VARCHAR(255) NOT NULL
 );
 CREATE INDEX idx_stocks_store_id ON stocks(store_id);
 CREATE INDEX idx_stocks_sku_id ON stocks(sku_id);
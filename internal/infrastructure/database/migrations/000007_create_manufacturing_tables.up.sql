@@ -21,8 +21,7 @@ CREATE TABLE production_orders (
 	notes TEXT,
 	created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
 	updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
-	FOREIGN KEY (facility_id) REFERENCES manufacturing_facilities(id),
-	FOREIGN KEY (product_id) REFERENCES inventory_items(id)
+	FOREIGN KEY (facility_id) REFERENCES manufacturing_facilities(id)
 );
 CREATE TABLE bill_of_materials (
 	id SERIAL PRIMARY KEY,
@@ -30,8 +29,7 @@ CREATE TABLE bill_of_materials (
 	name VARCHAR(255) NOT NULL,
 	version VARCHAR(50) NOT NULL,
 	created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
-	updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
-	FOREIGN KEY (product_id) REFERENCES inventory_items(id)
+	updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
 );
 CREATE TABLE bom_items (
 	id SERIAL PRIMARY KEY,
@@ -42,8 +40,7 @@ CREATE TABLE bom_items (
 	notes TEXT,
 	created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
 	updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
-	FOREIGN KEY (bom_id) REFERENCES bill_of_materials(id),
-	FOREIGN KEY (material_id) REFERENCES inventory_items(id)
+	FOREIGN KEY (bom_id) REFERENCES bill_of_materials(id)
 );
 CREATE TABLE mrp_calculations (
 	id SERIAL PRIMARY KEY,
@@ -56,8 +53,7 @@ CREATE TABLE mrp_calculations (
 	calculated_at TIMESTAMP WITH TIME ZONE NOT NULL,
 	created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
 	updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
-	FOREIGN KEY (production_id) REFERENCES production_orders(id),
-	FOREIGN KEY (material_id) REFERENCES inventory_items(id)
+	FOREIGN KEY (production_id) REFERENCES production_orders(id)
 );
 -- Add indexes for better query performance
 CREATE INDEX idx_production_orders_facility ON production_orders(facility_id);
@@ -46,7 +46,7 @@ CREATE TABLE IF NOT EXISTS sku_price_history (
 	price DECIMAL(15, 2) NOT NULL,
 	effective_from TIMESTAMP WITH TIME ZONE NOT NULL,
 	effective_to TIMESTAMP WITH TIME ZONE,
-	created_by UUID REFERENCES users(id),
+	created_by INTEGER REFERENCES users(id),
 	created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
 );
 -- Create table for SKU-Store relationships (additional attributes specific to SKU in a store)
@@ -23,7 +23,7 @@ CREATE TABLE IF NOT EXISTS purchase_requests (
 CREATE TABLE IF NOT EXISTS purchase_orders (
 	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
 	order_number VARCHAR(50) NOT NULL UNIQUE,
-	supplier_id INTEGER NOT NULL REFERENCES suppliers(id),
+	supplier_id INTEGER NOT NULL REFERENCES vendors(id),
 	order_date TIMESTAMP NOT NULL,
 	expected_date TIMESTAMP,
 	items JSONB NOT NULL,
@@ -55,7 +55,7 @@ CREATE TABLE IF NOT EXISTS purchase_receipts (
 	purchase_order_id UUID NOT NULL REFERENCES purchase_orders(id),
 	receipt_date TIMESTAMP NOT NULL,
 	items JSONB NOT NULL,
-	warehouse_id UUID NOT NULL REFERENCES warehouses(id),
+	warehouse_id UUID NOT NULL REFERENCES stores(id),
 	received_by_id INTEGER NOT NULL REFERENCES users(id),
 	notes TEXT,
 	attachment_urls TEXT [],
@@ -43,7 +43,7 @@ CREATE TABLE IF NOT EXISTS delivery_orders (
 	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
 	updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
 	FOREIGN KEY (sales_order_id) REFERENCES sales_orders(id),
-	FOREIGN KEY (warehouse_id) REFERENCES warehouses(id),
+	FOREIGN KEY (warehouse_id) REFERENCES stores(id),
 	FOREIGN KEY (created_by_id) REFERENCES users(id)
 );
 -- Create invoices table
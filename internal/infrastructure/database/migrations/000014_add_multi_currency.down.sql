@@ -0,0 +1,23 @@
+-- Drop currency columns
+ALTER TABLE finance_payments
+	DROP COLUMN IF EXISTS base_amount,
+	DROP COLUMN IF EXISTS exchange_rate,
+	DROP COLUMN IF EXISTS currency_code;
+ALTER TABLE finance_invoices
+	DROP COLUMN IF EXISTS base_total,
+	DROP COLUMN IF EXISTS exchange_rate,
+	DROP COLUMN IF EXISTS currency_code;
+ALTER TABLE purchase_orders
+	DROP COLUMN IF EXISTS base_grand_total,
+	DROP COLUMN IF EXISTS exchange_rate;
+ALTER TABLE invoices
+	DROP COLUMN IF EXISTS base_total_amount,
+	DROP COLUMN IF EXISTS exchange_rate,
+	DROP COLUMN IF EXISTS currency_code;
+ALTER TABLE sales_orders
+	DROP COLUMN IF EXISTS base_grand_total,
+	DROP COLUMN IF EXISTS exchange_rate,
+	DROP COLUMN IF EXISTS currency_code;
+-- Drop exchange_rates table
+DROP INDEX IF EXISTS idx_exchange_rates_pair_date;
+DROP TABLE IF EXISTS exchange_rates;
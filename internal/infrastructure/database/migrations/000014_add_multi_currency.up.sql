@@ -0,0 +1,38 @@
+-- Create exchange_rates table, one rate per currency pair per day
+CREATE TABLE IF NOT EXISTS exchange_rates (
+	id SERIAL PRIMARY KEY,
+	from_currency VARCHAR(3) NOT NULL,
+	to_currency VARCHAR(3) NOT NULL,
+	rate_date DATE NOT NULL,
+	rate DECIMAL(18, 8) NOT NULL,
+	source VARCHAR(100),
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_exchange_rates_pair_date ON exchange_rates(from_currency, to_currency, rate_date);
+-- Record transaction currency, rate and base currency amounts on documents.
+-- Existing documents are assumed to be in the base currency.
+ALTER TABLE sales_orders
+	ADD COLUMN IF NOT EXISTS currency_code VARCHAR(3) DEFAULT 'USD',
+	ADD COLUMN IF NOT EXISTS exchange_rate DECIMAL(18, 8) DEFAULT 1,
+	ADD COLUMN IF NOT EXISTS base_grand_total DECIMAL(15, 2) DEFAULT 0;
+UPDATE sales_orders SET base_grand_total = grand_total;
+ALTER TABLE invoices
+	ADD COLUMN IF NOT EXISTS currency_code VARCHAR(3) DEFAULT 'USD',
+	ADD COLUMN IF NOT EXISTS exchange_rate DECIMAL(18, 8) DEFAULT 1,
+	ADD COLUMN IF NOT EXISTS base_total_amount DECIMAL(15, 2) DEFAULT 0;
+UPDATE invoices SET base_total_amount = total_amount;
+ALTER TABLE purchase_orders
+	ADD COLUMN IF NOT EXISTS exchange_rate DECIMAL(18, 8) DEFAULT 1,
+	ADD COLUMN IF NOT EXISTS base_grand_total DECIMAL(15, 2) DEFAULT 0;
+UPDATE purchase_orders SET base_grand_total = grand_total;
+ALTER TABLE finance_invoices
+	ADD COLUMN IF NOT EXISTS currency_code VARCHAR(3) NOT NULL DEFAULT 'USD',
+	ADD COLUMN IF NOT EXISTS exchange_rate DECIMAL(18, 8) NOT NULL DEFAULT 1,
+	ADD COLUMN IF NOT EXISTS base_total DECIMAL(15, 2) NOT NULL DEFAULT 0;
+UPDATE finance_invoices SET base_total = total;
+ALTER TABLE finance_payments
+	ADD COLUMN IF NOT EXISTS currency_code VARCHAR(3) NOT NULL DEFAULT 'USD',
+	ADD COLUMN IF NOT EXISTS exchange_rate DECIMAL(18, 8) NOT NULL DEFAULT 1,
+	ADD COLUMN IF NOT EXISTS base_amount DECIMAL(15, 2) NOT NULL DEFAULT 0;
+UPDATE finance_payments SET base_amount = amount;
@@ -0,0 +1,5 @@
+-- Drop inventory provision tables
+DROP INDEX IF EXISTS idx_inventory_provision_entries_stock_id;
+DROP INDEX IF EXISTS idx_inventory_provision_entries_period;
+DROP TABLE IF EXISTS inventory_provision_entries;
+DROP TABLE IF EXISTS write_down_rules;
@@ -0,0 +1,28 @@
+-- Create write_down_rules table
+CREATE TABLE IF NOT EXISTS write_down_rules (
+	id SERIAL PRIMARY KEY,
+	name VARCHAR(100) NOT NULL,
+	min_age_days INTEGER NOT NULL,
+	provision_percent DECIMAL(5, 2) NOT NULL,
+	active BOOLEAN NOT NULL DEFAULT TRUE,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+-- Create inventory_provision_entries table, the ledger of provisions and releases
+CREATE TABLE IF NOT EXISTS inventory_provision_entries (
+	id SERIAL PRIMARY KEY,
+	period VARCHAR(7) NOT NULL,
+	stock_id UUID NOT NULL,
+	sku_id UUID NOT NULL,
+	store_id UUID NOT NULL,
+	type VARCHAR(20) NOT NULL,
+	quantity DECIMAL(15, 2) NOT NULL,
+	value DECIMAL(15, 2) NOT NULL,
+	required DECIMAL(15, 2) NOT NULL,
+	amount DECIMAL(15, 2) NOT NULL,
+	posted_by INTEGER REFERENCES users(id),
+	posted_at TIMESTAMP NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_inventory_provision_entries_period ON inventory_provision_entries(period);
+CREATE INDEX IF NOT EXISTS idx_inventory_provision_entries_stock_id ON inventory_provision_entries(stock_id);
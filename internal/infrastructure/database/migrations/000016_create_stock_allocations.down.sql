@@ -0,0 +1,5 @@
+-- Drop stock_allocations table
+DROP INDEX IF EXISTS idx_stock_allocations_sku_store_status;
+DROP INDEX IF EXISTS idx_stock_allocations_sales_order_id;
+DROP INDEX IF EXISTS idx_stock_allocations_run_id;
+DROP TABLE IF EXISTS stock_allocations;
@@ -0,0 +1,18 @@
+-- Create stock_allocations table, stock reserved for sales orders by allocation runs
+CREATE TABLE IF NOT EXISTS stock_allocations (
+	id SERIAL PRIMARY KEY,
+	run_id UUID NOT NULL,
+	policy VARCHAR(20) NOT NULL,
+	sales_order_id UUID NOT NULL,
+	sku_id UUID NOT NULL,
+	store_id UUID NOT NULL,
+	requested_quantity DECIMAL(15, 2) NOT NULL,
+	allocated_quantity DECIMAL(15, 2) NOT NULL,
+	status VARCHAR(20) NOT NULL DEFAULT 'ACTIVE',
+	created_by_id INTEGER,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_stock_allocations_run_id ON stock_allocations(run_id);
+CREATE INDEX IF NOT EXISTS idx_stock_allocations_sales_order_id ON stock_allocations(sales_order_id);
+CREATE INDEX IF NOT EXISTS idx_stock_allocations_sku_store_status ON stock_allocations(sku_id, store_id, status);
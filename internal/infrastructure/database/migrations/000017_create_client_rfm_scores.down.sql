@@ -0,0 +1,3 @@
+-- Drop client_rfm_scores table
+DROP INDEX IF EXISTS idx_client_rfm_scores_segment;
+DROP TABLE IF EXISTS client_rfm_scores;
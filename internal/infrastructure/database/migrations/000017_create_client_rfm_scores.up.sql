@@ -0,0 +1,15 @@
+-- Create client_rfm_scores table, the latest recency/frequency/monetary score of each client
+CREATE TABLE IF NOT EXISTS client_rfm_scores (
+	client_id INTEGER PRIMARY KEY REFERENCES clients(id) ON DELETE CASCADE,
+	last_order_date TIMESTAMP NOT NULL,
+	recency_days INTEGER NOT NULL,
+	order_count INTEGER NOT NULL,
+	monetary_value DECIMAL(15, 2) NOT NULL,
+	recency_score INTEGER NOT NULL,
+	frequency_score INTEGER NOT NULL,
+	monetary_score INTEGER NOT NULL,
+	score VARCHAR(3) NOT NULL,
+	segment VARCHAR(30) NOT NULL,
+	computed_at TIMESTAMP NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_client_rfm_scores_segment ON client_rfm_scores(segment);
@@ -0,0 +1,7 @@
+-- Drop fixed asset tables
+DROP INDEX IF EXISTS idx_asset_depreciation_entries_period;
+DROP INDEX IF EXISTS idx_asset_depreciation_period;
+DROP TABLE IF EXISTS asset_depreciation_entries;
+DROP INDEX IF EXISTS idx_fixed_assets_status;
+DROP INDEX IF EXISTS idx_fixed_assets_purchase_order_id;
+DROP TABLE IF EXISTS fixed_assets;
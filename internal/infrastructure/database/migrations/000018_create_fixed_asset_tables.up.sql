@@ -0,0 +1,43 @@
+-- Create fixed_assets table, the fixed asset register
+CREATE TABLE IF NOT EXISTS fixed_assets (
+	id SERIAL PRIMARY KEY,
+	asset_number VARCHAR(50) NOT NULL UNIQUE,
+	name VARCHAR(255) NOT NULL,
+	category VARCHAR(100),
+	sku_id UUID,
+	purchase_order_id UUID,
+	purchase_receipt_id UUID,
+	vendor_id INTEGER,
+	store_id VARCHAR(255),
+	quantity DECIMAL(15, 2) NOT NULL,
+	acquisition_date TIMESTAMP NOT NULL,
+	acquisition_cost DECIMAL(15, 2) NOT NULL,
+	salvage_value DECIMAL(15, 2) DEFAULT 0,
+	method VARCHAR(20) NOT NULL,
+	useful_life_months INTEGER NOT NULL,
+	accumulated_depreciation DECIMAL(15, 2) DEFAULT 0,
+	book_value DECIMAL(15, 2) NOT NULL,
+	depreciated_through VARCHAR(7),
+	status VARCHAR(20) NOT NULL DEFAULT 'ACTIVE',
+	disposal_date TIMESTAMP,
+	disposal_proceeds DECIMAL(15, 2) DEFAULT 0,
+	disposal_gain_loss DECIMAL(15, 2) DEFAULT 0,
+	disposal_notes TEXT,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_fixed_assets_purchase_order_id ON fixed_assets(purchase_order_id);
+CREATE INDEX IF NOT EXISTS idx_fixed_assets_status ON fixed_assets(status);
+-- Create asset_depreciation_entries table, the monthly depreciation posted per asset
+CREATE TABLE IF NOT EXISTS asset_depreciation_entries (
+	id SERIAL PRIMARY KEY,
+	asset_id INTEGER NOT NULL REFERENCES fixed_assets(id),
+	period VARCHAR(7) NOT NULL,
+	amount DECIMAL(15, 2) NOT NULL,
+	accumulated_depreciation DECIMAL(15, 2) NOT NULL,
+	book_value DECIMAL(15, 2) NOT NULL,
+	posted_by INTEGER REFERENCES users(id),
+	posted_at TIMESTAMP NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_asset_depreciation_period ON asset_depreciation_entries(asset_id, period);
+CREATE INDEX IF NOT EXISTS idx_asset_depreciation_entries_period ON asset_depreciation_entries(period);
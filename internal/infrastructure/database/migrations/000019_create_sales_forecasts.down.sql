@@ -0,0 +1,4 @@
+-- Drop sales_forecasts table
+DROP INDEX IF EXISTS idx_sales_forecasts_generated_for;
+DROP INDEX IF EXISTS idx_sales_forecasts_lookup;
+DROP TABLE IF EXISTS sales_forecasts;
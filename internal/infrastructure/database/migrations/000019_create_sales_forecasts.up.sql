@@ -0,0 +1,14 @@
+-- Create sales_forecasts table, monthly revenue forecast snapshots per customer or category
+CREATE TABLE IF NOT EXISTS sales_forecasts (
+	id SERIAL PRIMARY KEY,
+	dimension VARCHAR(20) NOT NULL,
+	key VARCHAR(255) NOT NULL,
+	name VARCHAR(255),
+	period VARCHAR(7) NOT NULL,
+	forecast DECIMAL(15, 2) NOT NULL,
+	seasonal_index DECIMAL(8, 4) NOT NULL,
+	generated_for VARCHAR(7),
+	generated_at TIMESTAMP NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_sales_forecasts_lookup ON sales_forecasts(dimension, key, period);
+CREATE INDEX IF NOT EXISTS idx_sales_forecasts_generated_for ON sales_forecasts(dimension, generated_for);
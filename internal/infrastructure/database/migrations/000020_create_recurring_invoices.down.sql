@@ -0,0 +1,6 @@
+-- Drop recurring invoice tables
+DROP INDEX IF EXISTS idx_recurring_invoice_runs_occurrence;
+DROP TABLE IF EXISTS recurring_invoice_runs;
+DROP INDEX IF EXISTS idx_recurring_invoices_next_run_date;
+DROP INDEX IF EXISTS idx_recurring_invoices_status;
+DROP TABLE IF EXISTS recurring_invoices;
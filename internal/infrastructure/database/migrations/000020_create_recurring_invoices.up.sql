@@ -0,0 +1,43 @@
+-- Create recurring_invoices table, templates the scheduler turns into finance invoices
+CREATE TABLE IF NOT EXISTS recurring_invoices (
+	id SERIAL PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	type VARCHAR(20) NOT NULL,
+	entity_id BIGINT NOT NULL,
+	entity_type VARCHAR(20) NOT NULL,
+	entity_name VARCHAR(255),
+	items JSONB NOT NULL,
+	discount_amount DECIMAL(15, 2) DEFAULT 0,
+	currency_code VARCHAR(3),
+	notes TEXT,
+	frequency VARCHAR(20) NOT NULL,
+	interval_count INTEGER NOT NULL DEFAULT 1,
+	payment_terms_days INTEGER NOT NULL DEFAULT 30,
+	escalation_percent DECIMAL(7, 4) DEFAULT 0,
+	start_date TIMESTAMP NOT NULL,
+	end_date TIMESTAMP,
+	occurrences INTEGER NOT NULL DEFAULT 0,
+	next_run_date TIMESTAMP,
+	status VARCHAR(20) NOT NULL DEFAULT 'ACTIVE',
+	last_invoice_id BIGINT REFERENCES finance_invoices(id),
+	created_by BIGINT,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_recurring_invoices_status ON recurring_invoices(status);
+CREATE INDEX IF NOT EXISTS idx_recurring_invoices_next_run_date ON recurring_invoices(next_run_date);
+-- Create recurring_invoice_runs table, the occurrences invoiced or skipped per template
+CREATE TABLE IF NOT EXISTS recurring_invoice_runs (
+	id SERIAL PRIMARY KEY,
+	recurring_invoice_id INTEGER NOT NULL REFERENCES recurring_invoices(id),
+	occurrence INTEGER NOT NULL,
+	scheduled_date TIMESTAMP NOT NULL,
+	status VARCHAR(20) NOT NULL,
+	invoice_id BIGINT REFERENCES finance_invoices(id),
+	invoice_number VARCHAR(50),
+	escalation_factor DECIMAL(10, 6) NOT NULL DEFAULT 1,
+	total DECIMAL(15, 2) DEFAULT 0,
+	created_by INTEGER REFERENCES users(id),
+	created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_recurring_invoice_runs_occurrence ON recurring_invoice_runs(recurring_invoice_id, occurrence);
@@ -0,0 +1,6 @@
+-- Drop vendor risk tables
+DROP INDEX IF EXISTS idx_vendor_risk_alerts_vendor_id;
+DROP TABLE IF EXISTS vendor_risk_alerts;
+DROP INDEX IF EXISTS idx_vendor_risk_scores_level;
+DROP INDEX IF EXISTS idx_vendor_risk_scores_score;
+DROP TABLE IF EXISTS vendor_risk_scores;
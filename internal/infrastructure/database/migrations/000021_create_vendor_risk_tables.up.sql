@@ -0,0 +1,34 @@
+-- Create vendor_risk_scores table, the latest supply risk score per vendor
+CREATE TABLE IF NOT EXISTS vendor_risk_scores (
+	vendor_id INTEGER PRIMARY KEY REFERENCES vendors(id),
+	vendor_code VARCHAR(50),
+	vendor_name VARCHAR(255),
+	open_order_count INTEGER NOT NULL DEFAULT 0,
+	open_order_value DECIMAL(15, 2) DEFAULT 0,
+	prepayments DECIMAL(15, 2) DEFAULT 0,
+	single_source_skus INTEGER NOT NULL DEFAULT 0,
+	on_time_delivery_rate DECIMAL(5, 2) DEFAULT 0,
+	rejection_rate DECIMAL(5, 2) DEFAULT 0,
+	exposure_score DECIMAL(5, 2) DEFAULT 0,
+	dependency_score DECIMAL(5, 2) DEFAULT 0,
+	delivery_score DECIMAL(5, 2) DEFAULT 0,
+	score DECIMAL(5, 2) NOT NULL DEFAULT 0,
+	level VARCHAR(10) NOT NULL,
+	computed_at TIMESTAMP NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_vendor_risk_scores_score ON vendor_risk_scores(score);
+CREATE INDEX IF NOT EXISTS idx_vendor_risk_scores_level ON vendor_risk_scores(level);
+-- Create vendor_risk_alerts table, raised when a vendor crosses the risk threshold
+CREATE TABLE IF NOT EXISTS vendor_risk_alerts (
+	id SERIAL PRIMARY KEY,
+	vendor_id INTEGER NOT NULL REFERENCES vendors(id),
+	vendor_name VARCHAR(255),
+	score DECIMAL(5, 2) NOT NULL,
+	previous_score DECIMAL(5, 2),
+	threshold DECIMAL(5, 2) NOT NULL,
+	reasons TEXT,
+	acknowledged_at TIMESTAMP,
+	acknowledged_by INTEGER REFERENCES users(id),
+	created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_vendor_risk_alerts_vendor_id ON vendor_risk_alerts(vendor_id);
@@ -0,0 +1,6 @@
+-- Drop dunning tables
+DROP INDEX IF EXISTS idx_dunning_reminders_sent_at;
+DROP INDEX IF EXISTS idx_dunning_reminders_entity_id;
+DROP INDEX IF EXISTS idx_dunning_reminders_invoice_level;
+DROP TABLE IF EXISTS dunning_reminders;
+DROP TABLE IF EXISTS dunning_levels;
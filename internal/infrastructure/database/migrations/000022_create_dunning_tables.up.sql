@@ -0,0 +1,31 @@
+-- Create dunning_levels table, the reminder stages for overdue sales invoices
+CREATE TABLE IF NOT EXISTS dunning_levels (
+	id SERIAL PRIMARY KEY,
+	name VARCHAR(100) NOT NULL,
+	days_overdue INTEGER NOT NULL UNIQUE,
+	template TEXT,
+	escalation VARCHAR(20) NOT NULL DEFAULT 'NONE',
+	active BOOLEAN NOT NULL DEFAULT TRUE,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+-- Create dunning_reminders table, the history of reminders sent per invoice and level
+CREATE TABLE IF NOT EXISTS dunning_reminders (
+	id SERIAL PRIMARY KEY,
+	invoice_id BIGINT NOT NULL REFERENCES finance_invoices(id),
+	invoice_number VARCHAR(50),
+	entity_id BIGINT,
+	entity_name VARCHAR(255),
+	level_id INTEGER NOT NULL REFERENCES dunning_levels(id),
+	level_name VARCHAR(100),
+	days_overdue INTEGER NOT NULL,
+	amount_due DECIMAL(15, 2) DEFAULT 0,
+	currency_code VARCHAR(3),
+	escalation VARCHAR(20),
+	message TEXT,
+	sent_at TIMESTAMP NOT NULL,
+	sent_by INTEGER REFERENCES users(id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_dunning_reminders_invoice_level ON dunning_reminders(invoice_id, level_id);
+CREATE INDEX IF NOT EXISTS idx_dunning_reminders_entity_id ON dunning_reminders(entity_id);
+CREATE INDEX IF NOT EXISTS idx_dunning_reminders_sent_at ON dunning_reminders(sent_at);
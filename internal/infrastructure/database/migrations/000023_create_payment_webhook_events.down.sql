@@ -0,0 +1,6 @@
+-- Drop payment webhook events table
+DROP INDEX IF EXISTS idx_finance_payments_reference_number;
+DROP INDEX IF EXISTS idx_payment_webhook_events_status;
+DROP INDEX IF EXISTS idx_payment_webhook_events_payment_reference;
+DROP INDEX IF EXISTS idx_payment_webhook_events_provider_event;
+DROP TABLE IF EXISTS payment_webhook_events;
@@ -0,0 +1,18 @@
+-- Create payment_webhook_events table, the idempotency log of payment gateway events
+CREATE TABLE IF NOT EXISTS payment_webhook_events (
+	id SERIAL PRIMARY KEY,
+	provider VARCHAR(50) NOT NULL,
+	event_id VARCHAR(255) NOT NULL,
+	event_type VARCHAR(100),
+	payment_reference VARCHAR(255),
+	invoice_id BIGINT,
+	payment_id BIGINT,
+	status VARCHAR(20) NOT NULL,
+	error TEXT,
+	received_at TIMESTAMP NOT NULL,
+	processed_at TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_payment_webhook_events_provider_event ON payment_webhook_events(provider, event_id);
+CREATE INDEX IF NOT EXISTS idx_payment_webhook_events_payment_reference ON payment_webhook_events(payment_reference);
+CREATE INDEX IF NOT EXISTS idx_payment_webhook_events_status ON payment_webhook_events(status);
+CREATE INDEX IF NOT EXISTS idx_finance_payments_reference_number ON finance_payments(reference_number);
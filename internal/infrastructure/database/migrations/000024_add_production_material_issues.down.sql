@@ -0,0 +1,7 @@
+-- Drop production material issues
+DROP INDEX IF EXISTS idx_production_material_issues_production_order_id;
+DROP TABLE IF EXISTS production_material_issues;
+ALTER TABLE production_orders
+	DROP COLUMN IF EXISTS target_store_id,
+	DROP COLUMN IF EXISTS source_store_id,
+	DROP COLUMN IF EXISTS issue_mode;
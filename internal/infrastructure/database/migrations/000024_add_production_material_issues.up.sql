@@ -0,0 +1,18 @@
+-- Add material issue mode and warehouses to production orders
+ALTER TABLE production_orders
+	ADD COLUMN IF NOT EXISTS issue_mode VARCHAR(20) NOT NULL DEFAULT 'backflush',
+	ADD COLUMN IF NOT EXISTS source_store_id VARCHAR(255),
+	ADD COLUMN IF NOT EXISTS target_store_id VARCHAR(255);
+-- Create production_material_issues table, the components taken from stock per production order
+CREATE TABLE IF NOT EXISTS production_material_issues (
+	id SERIAL PRIMARY KEY,
+	production_order_id INTEGER NOT NULL REFERENCES production_orders(id),
+	material_id INTEGER NOT NULL,
+	store_id VARCHAR(255) NOT NULL,
+	quantity DECIMAL(15, 4) NOT NULL,
+	mode VARCHAR(20) NOT NULL,
+	note TEXT,
+	issued_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	issued_by VARCHAR(255)
+);
+CREATE INDEX IF NOT EXISTS idx_production_material_issues_production_order_id ON production_material_issues(production_order_id);
@@ -0,0 +1,11 @@
+-- Drop BOM versions and material costs
+DROP TABLE IF EXISTS material_costs;
+DROP INDEX IF EXISTS idx_bom_items_bom;
+DROP INDEX IF EXISTS idx_bill_of_materials_product_effective;
+DROP INDEX IF EXISTS idx_bill_of_materials_product_version;
+ALTER TABLE bill_of_materials
+	DROP COLUMN IF EXISTS overhead_rate,
+	DROP COLUMN IF EXISTS labor_rate,
+	DROP COLUMN IF EXISTS labor_hours,
+	DROP COLUMN IF EXISTS effective_to,
+	DROP COLUMN IF EXISTS effective_from;
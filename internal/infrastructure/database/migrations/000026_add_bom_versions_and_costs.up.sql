@@ -0,0 +1,18 @@
+-- Add effective periods and labor rates to bills of materials
+ALTER TABLE bill_of_materials
+	ADD COLUMN IF NOT EXISTS effective_from TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	ADD COLUMN IF NOT EXISTS effective_to TIMESTAMP WITH TIME ZONE,
+	ADD COLUMN IF NOT EXISTS labor_hours DECIMAL(10, 4) NOT NULL DEFAULT 0,
+	ADD COLUMN IF NOT EXISTS labor_rate DECIMAL(15, 2) NOT NULL DEFAULT 0,
+	ADD COLUMN IF NOT EXISTS overhead_rate DECIMAL(15, 2) NOT NULL DEFAULT 0;
+-- Existing BOMs take effect from their creation
+UPDATE bill_of_materials SET effective_from = created_at WHERE created_at IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_bill_of_materials_product_version ON bill_of_materials(product_id, version);
+CREATE INDEX IF NOT EXISTS idx_bill_of_materials_product_effective ON bill_of_materials(product_id, effective_from);
+CREATE INDEX IF NOT EXISTS idx_bom_items_bom ON bom_items(bom_id);
+-- Create material_costs table, the standard unit cost of purchased components
+CREATE TABLE IF NOT EXISTS material_costs (
+	material_id INTEGER PRIMARY KEY,
+	unit_cost DECIMAL(15, 4) NOT NULL,
+	updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,9 @@
+-- Drop elevated access tables
+DROP INDEX IF EXISTS idx_elevated_actions_created_at;
+DROP INDEX IF EXISTS idx_elevated_actions_user_id;
+DROP INDEX IF EXISTS idx_elevated_actions_grant_id;
+DROP INDEX IF EXISTS idx_elevated_access_grants_expires_at;
+DROP INDEX IF EXISTS idx_elevated_access_grants_status;
+DROP INDEX IF EXISTS idx_elevated_access_grants_user_id;
+DROP TABLE IF EXISTS elevated_actions;
+DROP TABLE IF EXISTS elevated_access_grants;
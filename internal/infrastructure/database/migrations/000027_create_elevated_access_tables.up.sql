@@ -0,0 +1,36 @@
+-- Create elevated_access_grants table, temporary permissions on top of a user's role
+CREATE TABLE IF NOT EXISTS elevated_access_grants (
+	id SERIAL PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users(id),
+	permissions TEXT [] NOT NULL,
+	reason TEXT NOT NULL,
+	duration_hours INTEGER NOT NULL,
+	status VARCHAR(20) NOT NULL DEFAULT 'PENDING',
+	requested_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	reviewed_by INTEGER REFERENCES users(id),
+	reviewed_at TIMESTAMP,
+	review_note TEXT,
+	expires_at TIMESTAMP,
+	revoked_by INTEGER REFERENCES users(id),
+	revoked_at TIMESTAMP,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+-- Create elevated_actions table, the requests made through a grant
+CREATE TABLE IF NOT EXISTS elevated_actions (
+	id SERIAL PRIMARY KEY,
+	grant_id INTEGER NOT NULL REFERENCES elevated_access_grants(id),
+	user_id INTEGER NOT NULL REFERENCES users(id),
+	permission VARCHAR(100) NOT NULL,
+	method VARCHAR(10) NOT NULL,
+	path TEXT NOT NULL,
+	status_code INTEGER,
+	ip VARCHAR(45),
+	created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_elevated_access_grants_user_id ON elevated_access_grants(user_id);
+CREATE INDEX IF NOT EXISTS idx_elevated_access_grants_status ON elevated_access_grants(status);
+CREATE INDEX IF NOT EXISTS idx_elevated_access_grants_expires_at ON elevated_access_grants(expires_at);
+CREATE INDEX IF NOT EXISTS idx_elevated_actions_grant_id ON elevated_actions(grant_id);
+CREATE INDEX IF NOT EXISTS idx_elevated_actions_user_id ON elevated_actions(user_id);
+CREATE INDEX IF NOT EXISTS idx_elevated_actions_created_at ON elevated_actions(created_at);
@@ -0,0 +1,5 @@
+-- Drop user provisioning tables and columns
+DROP INDEX IF EXISTS idx_role_mappings_external_group;
+DROP TABLE IF EXISTS role_mappings;
+DROP INDEX IF EXISTS idx_users_external_id;
+ALTER TABLE users DROP COLUMN IF EXISTS external_id;
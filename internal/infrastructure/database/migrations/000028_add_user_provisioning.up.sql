@@ -0,0 +1,12 @@
+-- Add the corporate directory ID of provisioned users
+ALTER TABLE users ADD COLUMN IF NOT EXISTS external_id VARCHAR(255);
+CREATE INDEX IF NOT EXISTS idx_users_external_id ON users(external_id);
+-- Create role_mappings table, the role given to users in a directory group
+CREATE TABLE IF NOT EXISTS role_mappings (
+	id SERIAL PRIMARY KEY,
+	external_group VARCHAR(255) NOT NULL,
+	role_id INTEGER NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_role_mappings_external_group ON role_mappings(LOWER(external_group));
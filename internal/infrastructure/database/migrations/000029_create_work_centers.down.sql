@@ -0,0 +1,9 @@
+-- Drop production scheduling columns and work center table
+DROP INDEX IF EXISTS idx_production_orders_work_center_schedule;
+ALTER TABLE production_orders
+	DROP COLUMN IF EXISTS planned_hours,
+	DROP COLUMN IF EXISTS scheduled_end,
+	DROP COLUMN IF EXISTS scheduled_start,
+	DROP COLUMN IF EXISTS work_center_id;
+DROP INDEX IF EXISTS idx_work_centers_facility_id;
+DROP TABLE IF EXISTS work_centers;
@@ -0,0 +1,21 @@
+-- Create work_centers table, the machines, lines and cells production is scheduled on
+CREATE TABLE IF NOT EXISTS work_centers (
+	id SERIAL PRIMARY KEY,
+	facility_id INTEGER NOT NULL REFERENCES manufacturing_facilities(id),
+	code VARCHAR(50) NOT NULL UNIQUE,
+	name VARCHAR(255) NOT NULL,
+	shifts_per_day INTEGER NOT NULL,
+	hours_per_shift DECIMAL(5, 2) NOT NULL,
+	works_weekends BOOLEAN NOT NULL DEFAULT FALSE,
+	active BOOLEAN NOT NULL DEFAULT TRUE,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_work_centers_facility_id ON work_centers(facility_id);
+-- Add the work center and days production orders are scheduled on
+ALTER TABLE production_orders
+	ADD COLUMN IF NOT EXISTS work_center_id INTEGER REFERENCES work_centers(id),
+	ADD COLUMN IF NOT EXISTS scheduled_start DATE,
+	ADD COLUMN IF NOT EXISTS scheduled_end DATE,
+	ADD COLUMN IF NOT EXISTS planned_hours DECIMAL(10, 2) NOT NULL DEFAULT 0;
+CREATE INDEX IF NOT EXISTS idx_production_orders_work_center_schedule ON production_orders(work_center_id, scheduled_start, scheduled_end);
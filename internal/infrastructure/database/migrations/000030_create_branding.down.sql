@@ -0,0 +1,2 @@
+-- Drop branding table
+DROP TABLE IF EXISTS branding;
@@ -0,0 +1,25 @@
+-- Create branding table, the single row of company details printed on documents and emails
+CREATE TABLE IF NOT EXISTS branding (
+	id INTEGER PRIMARY KEY CHECK (id = 1),
+	company_name VARCHAR(255) NOT NULL DEFAULT '',
+	address_line1 VARCHAR(255) NOT NULL DEFAULT '',
+	address_line2 VARCHAR(255) NOT NULL DEFAULT '',
+	city VARCHAR(100) NOT NULL DEFAULT '',
+	postal_code VARCHAR(20) NOT NULL DEFAULT '',
+	country VARCHAR(100) NOT NULL DEFAULT '',
+	email VARCHAR(255) NOT NULL DEFAULT '',
+	phone VARCHAR(50) NOT NULL DEFAULT '',
+	website VARCHAR(255) NOT NULL DEFAULT '',
+	bank_name VARCHAR(255) NOT NULL DEFAULT '',
+	bank_account_name VARCHAR(255) NOT NULL DEFAULT '',
+	bank_account_no VARCHAR(50) NOT NULL DEFAULT '',
+	iban VARCHAR(34) NOT NULL DEFAULT '',
+	bic VARCHAR(11) NOT NULL DEFAULT '',
+	vat_numbers JSONB NOT NULL DEFAULT '[]',
+	invoice_footer TEXT NOT NULL DEFAULT '',
+	logo_data BYTEA,
+	logo_content_type VARCHAR(50) NOT NULL DEFAULT '',
+	logo_updated_at TIMESTAMP,
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_by INTEGER REFERENCES users(id) ON DELETE SET NULL
+);
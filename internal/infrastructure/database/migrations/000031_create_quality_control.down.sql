@@ -0,0 +1,5 @@
+-- Drop quality control tables and the quarantined stock column
+DROP TABLE IF EXISTS quality_inspections;
+DROP TABLE IF EXISTS defect_codes;
+DROP TABLE IF EXISTS inspection_plans;
+ALTER TABLE stocks DROP COLUMN IF EXISTS quarantined_quantity;
@@ -0,0 +1,50 @@
+-- Add the part of the stock on quality hold, which cannot be picked
+ALTER TABLE stocks ADD COLUMN IF NOT EXISTS quarantined_quantity DECIMAL(15, 3) NOT NULL DEFAULT 0;
+-- Create inspection_plans table, the SKUs whose receipts or output are inspected
+CREATE TABLE IF NOT EXISTS inspection_plans (
+	id SERIAL PRIMARY KEY,
+	sku_id VARCHAR(255) NOT NULL,
+	name VARCHAR(255) NOT NULL,
+	inspect_receipts BOOLEAN NOT NULL DEFAULT TRUE,
+	inspect_production BOOLEAN NOT NULL DEFAULT TRUE,
+	sample_size INTEGER NOT NULL DEFAULT 0,
+	instructions TEXT,
+	active BOOLEAN NOT NULL DEFAULT TRUE,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_inspection_plans_sku_id ON inspection_plans(sku_id);
+-- Create defect_codes table
+CREATE TABLE IF NOT EXISTS defect_codes (
+	id SERIAL PRIMARY KEY,
+	code VARCHAR(50) NOT NULL,
+	description VARCHAR(255) NOT NULL,
+	active BOOLEAN NOT NULL DEFAULT TRUE,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_defect_codes_code ON defect_codes(code);
+-- Create quality_inspections table, the goods held in quarantine and their results
+CREATE TABLE IF NOT EXISTS quality_inspections (
+	id SERIAL PRIMARY KEY,
+	plan_id INTEGER NOT NULL REFERENCES inspection_plans(id),
+	sku_id VARCHAR(255) NOT NULL,
+	store_id VARCHAR(255) NOT NULL,
+	source VARCHAR(20) NOT NULL,
+	reference VARCHAR(255) NOT NULL,
+	quantity DECIMAL(15, 3) NOT NULL,
+	sample_size INTEGER NOT NULL DEFAULT 0,
+	status VARCHAR(20) NOT NULL DEFAULT 'PENDING',
+	passed_quantity DECIMAL(15, 3) NOT NULL DEFAULT 0,
+	rejected_quantity DECIMAL(15, 3) NOT NULL DEFAULT 0,
+	defects JSONB NOT NULL DEFAULT '[]',
+	notes TEXT,
+	inspected_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+	inspected_at TIMESTAMP,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_quality_inspections_plan_id ON quality_inspections(plan_id);
+CREATE INDEX IF NOT EXISTS idx_quality_inspections_sku_id ON quality_inspections(sku_id);
+CREATE INDEX IF NOT EXISTS idx_quality_inspections_reference ON quality_inspections(reference);
+CREATE INDEX IF NOT EXISTS idx_quality_inspections_status ON quality_inspections(status);
@@ -0,0 +1,5 @@
+-- Drop working calendar tables, promised dates and vendor lead times
+ALTER TABLE vendors DROP COLUMN IF EXISTS lead_time_days;
+ALTER TABLE sales_orders DROP COLUMN IF EXISTS promised_date;
+DROP TABLE IF EXISTS calendar_holidays;
+DROP TABLE IF EXISTS working_calendars;
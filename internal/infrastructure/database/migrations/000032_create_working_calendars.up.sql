@@ -0,0 +1,26 @@
+-- Create working_calendars table, the business days and shifts of the company or a warehouse
+CREATE TABLE IF NOT EXISTS working_calendars (
+	id SERIAL PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	store_id VARCHAR(255),
+	timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+	working_days JSONB NOT NULL DEFAULT '[1, 2, 3, 4, 5]',
+	shifts JSONB NOT NULL DEFAULT '[]',
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_working_calendars_store_id ON working_calendars(store_id);
+-- The company calendar is the one without a warehouse
+CREATE UNIQUE INDEX IF NOT EXISTS idx_working_calendars_company ON working_calendars((store_id IS NULL)) WHERE store_id IS NULL;
+-- Create calendar_holidays table, the non-working dates of a calendar
+CREATE TABLE IF NOT EXISTS calendar_holidays (
+	id SERIAL PRIMARY KEY,
+	calendar_id INTEGER NOT NULL REFERENCES working_calendars(id) ON DELETE CASCADE,
+	date DATE NOT NULL,
+	name VARCHAR(255) NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_calendar_holidays_date ON calendar_holidays(calendar_id, date);
+-- Add the date sales orders are promised for and the vendor lead time in business days
+ALTER TABLE sales_orders ADD COLUMN IF NOT EXISTS promised_date DATE;
+ALTER TABLE vendors ADD COLUMN IF NOT EXISTS lead_time_days INTEGER NOT NULL DEFAULT 0;
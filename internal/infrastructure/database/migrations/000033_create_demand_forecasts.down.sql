@@ -0,0 +1,4 @@
+-- Drop demand forecast tables and the MRP forecast column
+ALTER TABLE mrp_calculations DROP COLUMN IF EXISTS forecast_qty;
+DROP TABLE IF EXISTS demand_forecast_lines;
+DROP TABLE IF EXISTS demand_forecast_versions;
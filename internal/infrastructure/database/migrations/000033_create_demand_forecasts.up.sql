@@ -0,0 +1,27 @@
+-- Create demand_forecast_versions table, stored SKU demand forecasts
+CREATE TABLE IF NOT EXISTS demand_forecast_versions (
+	id SERIAL PRIMARY KEY,
+	model VARCHAR(20) NOT NULL,
+	window_months INTEGER NOT NULL DEFAULT 0,
+	alpha DECIMAL(4, 3) NOT NULL DEFAULT 0,
+	history_months INTEGER NOT NULL DEFAULT 0,
+	first_period VARCHAR(7) NOT NULL,
+	last_period VARCHAR(7) NOT NULL,
+	notes TEXT,
+	active BOOLEAN NOT NULL DEFAULT FALSE,
+	created_by INTEGER,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+-- Only one version feeds replenishment and MRP
+CREATE UNIQUE INDEX IF NOT EXISTS idx_demand_forecast_versions_active ON demand_forecast_versions(active) WHERE active;
+-- Create demand_forecast_lines table, the monthly forecast quantity per SKU of a version
+CREATE TABLE IF NOT EXISTS demand_forecast_lines (
+	id SERIAL PRIMARY KEY,
+	version_id INTEGER NOT NULL REFERENCES demand_forecast_versions(id) ON DELETE CASCADE,
+	sku_id VARCHAR(255) NOT NULL,
+	period VARCHAR(7) NOT NULL,
+	quantity DECIMAL(15, 3) NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_demand_forecast_lines_lookup ON demand_forecast_lines(version_id, sku_id, period);
+-- Add the forecast independent demand MRP plans for
+ALTER TABLE mrp_calculations ADD COLUMN IF NOT EXISTS forecast_qty DECIMAL(15, 3) NOT NULL DEFAULT 0;
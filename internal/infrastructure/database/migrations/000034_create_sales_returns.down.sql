@@ -0,0 +1,2 @@
+-- Drop sales_returns table
+DROP TABLE IF EXISTS sales_returns;
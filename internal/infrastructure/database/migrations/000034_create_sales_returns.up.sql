@@ -0,0 +1,19 @@
+-- Create sales_returns table, goods clients sent back against sales orders
+CREATE TABLE IF NOT EXISTS sales_returns (
+	id SERIAL PRIMARY KEY,
+	sales_order_id UUID NOT NULL,
+	client_id INTEGER NOT NULL,
+	sku_id UUID NOT NULL,
+	store_id VARCHAR(255),
+	lot_number VARCHAR(255),
+	quantity DECIMAL(15, 3) NOT NULL,
+	reason_code VARCHAR(50) NOT NULL,
+	notes TEXT,
+	restocked BOOLEAN NOT NULL DEFAULT FALSE,
+	created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_sales_returns_sales_order_id ON sales_returns(sales_order_id);
+CREATE INDEX IF NOT EXISTS idx_sales_returns_sku_id ON sales_returns(sku_id);
+CREATE INDEX IF NOT EXISTS idx_sales_returns_reason_code ON sales_returns(reason_code);
+CREATE INDEX IF NOT EXISTS idx_sales_returns_created_at ON sales_returns(created_at);
@@ -0,0 +1,2 @@
+-- Drop the delivery freight cost
+ALTER TABLE delivery_orders DROP COLUMN IF EXISTS freight_cost;
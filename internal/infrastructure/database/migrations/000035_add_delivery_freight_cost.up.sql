@@ -0,0 +1,2 @@
+-- Add the carrier charge of a delivery, in the base currency, for the cost-to-serve report
+ALTER TABLE delivery_orders ADD COLUMN IF NOT EXISTS freight_cost DECIMAL(15, 2) NOT NULL DEFAULT 0;
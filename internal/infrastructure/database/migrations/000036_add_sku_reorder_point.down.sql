@@ -0,0 +1,2 @@
+-- Drop the SKU reorder point
+ALTER TABLE skus DROP COLUMN IF EXISTS reorder_point;
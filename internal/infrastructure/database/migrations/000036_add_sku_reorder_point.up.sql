@@ -0,0 +1,2 @@
+-- Add the SKU reorder point below which low-stock events are published
+ALTER TABLE skus ADD COLUMN IF NOT EXISTS reorder_point DECIMAL(15, 3) NOT NULL DEFAULT 0;
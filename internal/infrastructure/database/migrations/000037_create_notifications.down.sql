@@ -0,0 +1,5 @@
+-- Drop notification tables and the user phone number
+DROP TABLE IF EXISTS notification_templates;
+DROP TABLE IF EXISTS notification_preferences;
+DROP TABLE IF EXISTS notifications;
+ALTER TABLE users DROP COLUMN IF EXISTS phone;
@@ -0,0 +1,35 @@
+-- Add the phone number SMS notifications are sent to
+ALTER TABLE users ADD COLUMN IF NOT EXISTS phone VARCHAR(20);
+-- Create notifications table, the in-app inbox of each user
+CREATE TABLE IF NOT EXISTS notifications (
+	id SERIAL PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	type VARCHAR(30) NOT NULL,
+	title VARCHAR(255) NOT NULL,
+	body TEXT,
+	reference_type VARCHAR(30),
+	reference_id VARCHAR(255),
+	read_at TIMESTAMP,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_notifications_user ON notifications(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_notifications_unread ON notifications(user_id) WHERE read_at IS NULL;
+-- Create notification_preferences table, the channels each user turned on or off per notification type
+CREATE TABLE IF NOT EXISTS notification_preferences (
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	type VARCHAR(30) NOT NULL,
+	channel VARCHAR(10) NOT NULL,
+	enabled BOOLEAN NOT NULL,
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	PRIMARY KEY (user_id, type, channel)
+);
+-- Create notification_templates table, overrides of the built-in notification templates
+CREATE TABLE IF NOT EXISTS notification_templates (
+	type VARCHAR(30) NOT NULL,
+	channel VARCHAR(10) NOT NULL,
+	subject VARCHAR(255),
+	body TEXT NOT NULL,
+	updated_by INTEGER,
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	PRIMARY KEY (type, channel)
+);
@@ -0,0 +1,2 @@
+-- Drop the event log
+DROP TABLE IF EXISTS event_log_entries;
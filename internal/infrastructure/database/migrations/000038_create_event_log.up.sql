@@ -0,0 +1,9 @@
+-- Create event_log_entries table, the domain events published by the use cases
+CREATE TABLE IF NOT EXISTS event_log_entries (
+	id BIGSERIAL PRIMARY KEY,
+	name VARCHAR(50) NOT NULL,
+	payload JSONB,
+	occurred_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_event_log_entries_name ON event_log_entries(name, occurred_at DESC);
+CREATE INDEX IF NOT EXISTS idx_event_log_entries_occurred_at ON event_log_entries(occurred_at DESC);
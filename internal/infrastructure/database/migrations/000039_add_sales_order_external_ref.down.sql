@@ -0,0 +1,3 @@
+-- Drop the external reference of sales orders
+DROP INDEX IF EXISTS idx_sales_orders_external_ref;
+ALTER TABLE sales_orders DROP COLUMN IF EXISTS external_ref;
@@ -0,0 +1,4 @@
+-- Reference of sales orders ingested from external systems such as web shops,
+-- so an order handed in twice by the message broker is only created once
+ALTER TABLE sales_orders ADD COLUMN IF NOT EXISTS external_ref VARCHAR(150);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_sales_orders_external_ref ON sales_orders(external_ref) WHERE external_ref IS NOT NULL;
@@ -0,0 +1,2 @@
+-- Drop the idempotency keys
+DROP TABLE IF EXISTS idempotency_keys;
@@ -0,0 +1,16 @@
+-- Create idempotency_keys table, the responses of mutating requests sent with
+-- an Idempotency-Key header, replayed when the client retries them
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	key VARCHAR(255) NOT NULL,
+	method VARCHAR(10) NOT NULL,
+	path VARCHAR(500) NOT NULL,
+	request_hash VARCHAR(64) NOT NULL,
+	status_code INTEGER NOT NULL DEFAULT 0,
+	content_type VARCHAR(100),
+	response BYTEA,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	completed_at TIMESTAMP,
+	PRIMARY KEY (user_id, key)
+);
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_created_at ON idempotency_keys(created_at);
@@ -0,0 +1,2 @@
+-- Drop the job queue
+DROP TABLE IF EXISTS jobs;
@@ -0,0 +1,3 @@
+-- Drop the file key of reports, restoring their file URL
+ALTER TABLE reports ADD COLUMN IF NOT EXISTS file_url TEXT;
+ALTER TABLE reports DROP COLUMN IF EXISTS file_key;
@@ -0,0 +1,4 @@
+-- Report exports are kept in the file store and downloaded through signed URLs
+-- that expire, so reports keep the key of their file rather than a URL
+ALTER TABLE reports ADD COLUMN IF NOT EXISTS file_key VARCHAR(255);
+ALTER TABLE reports DROP COLUMN IF EXISTS file_url;
@@ -0,0 +1,13 @@
+-- Create attachments table, the files uploaded to documents such as purchase orders
+CREATE TABLE IF NOT EXISTS attachments (
+	id BIGSERIAL PRIMARY KEY,
+	owner_type VARCHAR(30) NOT NULL,
+	owner_id VARCHAR(64) NOT NULL,
+	file_name VARCHAR(255) NOT NULL,
+	content_type VARCHAR(100) NOT NULL,
+	size BIGINT NOT NULL,
+	file_key VARCHAR(255) NOT NULL,
+	uploaded_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_attachments_owner ON attachments(owner_type, owner_id, created_at);
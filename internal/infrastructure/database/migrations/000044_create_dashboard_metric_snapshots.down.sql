@@ -0,0 +1,2 @@
+-- Drop dashboard_metric_snapshots table
+DROP TABLE IF EXISTS dashboard_metric_snapshots;
@@ -0,0 +1,9 @@
+-- Create dashboard_metric_snapshots table, the dashboard metrics of each period aggregated ahead of the requests reading them
+CREATE TABLE IF NOT EXISTS dashboard_metric_snapshots (
+	period VARCHAR(16) NOT NULL,
+	filter_key VARCHAR(100) NOT NULL DEFAULT '',
+	metrics TEXT NOT NULL,
+	computed_at TIMESTAMP NOT NULL,
+	invalidated_at TIMESTAMP,
+	PRIMARY KEY (period, filter_key)
+);
@@ -0,0 +1,5 @@
+-- Drop the cursor pagination indexes
+DROP INDEX IF EXISTS idx_stock_entries_created_at_id;
+DROP INDEX IF EXISTS idx_audit_logs_created_at_id;
+DROP INDEX IF EXISTS idx_finance_invoices_created_at_id;
+DROP INDEX IF EXISTS idx_sales_orders_created_at_id;
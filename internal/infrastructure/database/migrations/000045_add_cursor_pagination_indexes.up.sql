@@ -0,0 +1,6 @@
+-- Index the listings paged by cursor in their order, latest first, so a page
+-- deep into the listing is read without scanning the rows before it
+CREATE INDEX IF NOT EXISTS idx_sales_orders_created_at_id ON sales_orders(created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_finance_invoices_created_at_id ON finance_invoices(created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_created_at_id ON audit_logs(created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_stock_entries_created_at_id ON stock_entries(created_at DESC, id DESC);
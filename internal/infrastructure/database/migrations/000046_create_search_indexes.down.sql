@@ -0,0 +1,13 @@
+-- Drop the search indexes; the pg_trgm extension is left installed
+DROP INDEX IF EXISTS idx_purchase_orders_search_trgm;
+DROP INDEX IF EXISTS idx_purchase_orders_search;
+DROP INDEX IF EXISTS idx_sales_orders_search_trgm;
+DROP INDEX IF EXISTS idx_sales_orders_search;
+DROP INDEX IF EXISTS idx_vendors_search_trgm;
+DROP INDEX IF EXISTS idx_vendors_search;
+DROP INDEX IF EXISTS idx_clients_search_trgm;
+DROP INDEX IF EXISTS idx_clients_search;
+DROP INDEX IF EXISTS idx_vendor_skus_search_trgm;
+DROP INDEX IF EXISTS idx_vendor_skus_search;
+DROP INDEX IF EXISTS idx_skus_search_trgm;
+DROP INDEX IF EXISTS idx_skus_search;
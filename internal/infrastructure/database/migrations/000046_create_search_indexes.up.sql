@@ -0,0 +1,22 @@
+-- Index the records the search covers for full-text search, and their
+-- trigrams for matching misspelt words. The indexed expressions must match
+-- the ones the search repository queries.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS idx_skus_search ON skus USING GIN (to_tsvector('simple', sku_code || ' ' || name || ' ' || COALESCE(description, '')));
+CREATE INDEX IF NOT EXISTS idx_skus_search_trgm ON skus USING GIN ((sku_code || ' ' || name || ' ' || COALESCE(description, '')) gin_trgm_ops);
+
+CREATE INDEX IF NOT EXISTS idx_vendor_skus_search ON vendor_skus USING GIN (to_tsvector('simple', code || ' ' || name || ' ' || COALESCE(description, '')));
+CREATE INDEX IF NOT EXISTS idx_vendor_skus_search_trgm ON vendor_skus USING GIN ((code || ' ' || name || ' ' || COALESCE(description, '')) gin_trgm_ops);
+
+CREATE INDEX IF NOT EXISTS idx_clients_search ON clients USING GIN (to_tsvector('simple', code || ' ' || name || ' ' || COALESCE(email, '')));
+CREATE INDEX IF NOT EXISTS idx_clients_search_trgm ON clients USING GIN ((code || ' ' || name || ' ' || COALESCE(email, '')) gin_trgm_ops);
+
+CREATE INDEX IF NOT EXISTS idx_vendors_search ON vendors USING GIN (to_tsvector('simple', code || ' ' || name || ' ' || COALESCE(email, '')));
+CREATE INDEX IF NOT EXISTS idx_vendors_search_trgm ON vendors USING GIN ((code || ' ' || name || ' ' || COALESCE(email, '')) gin_trgm_ops);
+
+CREATE INDEX IF NOT EXISTS idx_sales_orders_search ON sales_orders USING GIN (to_tsvector('simple', order_number));
+CREATE INDEX IF NOT EXISTS idx_sales_orders_search_trgm ON sales_orders USING GIN (order_number gin_trgm_ops);
+
+CREATE INDEX IF NOT EXISTS idx_purchase_orders_search ON purchase_orders USING GIN (to_tsvector('simple', order_number));
+CREATE INDEX IF NOT EXISTS idx_purchase_orders_search_trgm ON purchase_orders USING GIN (order_number gin_trgm_ops);
@@ -0,0 +1,5 @@
+-- Drop the columns the server used to create at startup
+ALTER TABLE stock_entries DROP COLUMN IF EXISTS expiry_date;
+ALTER TABLE stock_entries DROP COLUMN IF EXISTS manufacture_date;
+DROP INDEX IF EXISTS idx_stores_code;
+ALTER TABLE stores DROP COLUMN IF EXISTS code;
@@ -0,0 +1,6 @@
+-- Add the columns the server used to create at startup, before the schema
+-- was left to the migrations
+ALTER TABLE stores ADD COLUMN IF NOT EXISTS code VARCHAR(50);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_stores_code ON stores(code);
+ALTER TABLE stock_entries ADD COLUMN IF NOT EXISTS manufacture_date TIMESTAMP WITH TIME ZONE;
+ALTER TABLE stock_entries ADD COLUMN IF NOT EXISTS expiry_date TIMESTAMP WITH TIME ZONE;
@@ -0,0 +1,3 @@
+-- Drop the access scope of roles
+ALTER TABLE roles DROP COLUMN IF EXISTS department_ids;
+ALTER TABLE roles DROP COLUMN IF EXISTS store_ids;
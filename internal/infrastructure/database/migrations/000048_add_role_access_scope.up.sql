@@ -0,0 +1,4 @@
+-- Add the stores (warehouses) and departments a role's users are limited to,
+-- empty for roles without a scope
+ALTER TABLE roles ADD COLUMN IF NOT EXISTS store_ids TEXT[];
+ALTER TABLE roles ADD COLUMN IF NOT EXISTS department_ids BIGINT[];
@@ -0,0 +1,2 @@
+-- Drop field_changes table
+DROP TABLE IF EXISTS field_changes;
@@ -0,0 +1,13 @@
+-- Create field_changes table, the values of the fields of critical entities
+-- before and after each update
+CREATE TABLE IF NOT EXISTS field_changes (
+	id SERIAL PRIMARY KEY,
+	entity_type VARCHAR(50) NOT NULL,
+	entity_id VARCHAR(64) NOT NULL,
+	field VARCHAR(100) NOT NULL,
+	old_value TEXT,
+	new_value TEXT,
+	changed_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+	changed_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_field_changes_entity ON field_changes(entity_type, entity_id);
@@ -0,0 +1,4 @@
+-- Drop approval delegations
+ALTER TABLE purchase_orders DROP COLUMN IF EXISTS on_behalf_of_id;
+ALTER TABLE purchase_requests DROP COLUMN IF EXISTS on_behalf_of_id;
+DROP TABLE IF EXISTS approval_delegations;
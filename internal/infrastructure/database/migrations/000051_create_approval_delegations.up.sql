@@ -0,0 +1,21 @@
+-- Create approval_delegations table, approvers handing their approval
+-- authority to a delegate for a period, e.g. while out of office
+CREATE TABLE IF NOT EXISTS approval_delegations (
+	id SERIAL PRIMARY KEY,
+	delegator_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	delegate_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	starts_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	ends_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	reason TEXT,
+	created_by INTEGER NOT NULL REFERENCES users(id),
+	cancelled_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+	cancelled_at TIMESTAMP WITH TIME ZONE,
+	created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_approval_delegations_delegator_id ON approval_delegations(delegator_id);
+CREATE INDEX IF NOT EXISTS idx_approval_delegations_delegate_id ON approval_delegations(delegate_id);
+
+-- Record the approver away a delegate approved on behalf of
+ALTER TABLE purchase_requests ADD COLUMN IF NOT EXISTS on_behalf_of_id INTEGER REFERENCES users(id);
+ALTER TABLE purchase_orders ADD COLUMN IF NOT EXISTS on_behalf_of_id INTEGER REFERENCES users(id);
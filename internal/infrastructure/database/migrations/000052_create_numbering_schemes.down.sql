@@ -0,0 +1,3 @@
+-- Drop numbering_schemes table; the counters of stores and fiscal years are
+-- kept, as they may not fit the former length
+DROP TABLE IF EXISTS numbering_schemes;
@@ -0,0 +1,18 @@
+-- Create numbering_schemes table, the patterns document numbers follow per
+-- document type and store
+CREATE TABLE IF NOT EXISTS numbering_schemes (
+	id SERIAL PRIMARY KEY,
+	document_type VARCHAR(50) NOT NULL,
+	store_id VARCHAR(64) NOT NULL DEFAULT '',
+	prefix VARCHAR(20) NOT NULL,
+	pattern VARCHAR(100) NOT NULL,
+	padding INTEGER NOT NULL,
+	reset VARCHAR(20) NOT NULL,
+	fiscal_year_start_month INTEGER NOT NULL,
+	created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_numbering_schemes_document_store ON numbering_schemes(document_type, store_id);
+
+-- Counters are kept per store and fiscal year, which takes longer names
+ALTER TABLE sequences ALTER COLUMN id TYPE VARCHAR(100);
@@ -0,0 +1,8 @@
+-- Drop the custom field values and definitions
+DROP INDEX IF EXISTS idx_vendors_custom_fields;
+DROP INDEX IF EXISTS idx_sales_orders_custom_fields;
+DROP INDEX IF EXISTS idx_skus_custom_fields;
+ALTER TABLE vendors DROP COLUMN IF EXISTS custom_fields;
+ALTER TABLE sales_orders DROP COLUMN IF EXISTS custom_fields;
+ALTER TABLE skus DROP COLUMN IF EXISTS custom_fields;
+DROP TABLE IF EXISTS custom_field_definitions;
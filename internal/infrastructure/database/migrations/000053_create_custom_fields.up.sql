@@ -0,0 +1,27 @@
+-- Create custom_field_definitions table, the user-defined attributes of SKUs,
+-- sales orders and vendors
+CREATE TABLE IF NOT EXISTS custom_field_definitions (
+	id SERIAL PRIMARY KEY,
+	entity_type VARCHAR(50) NOT NULL,
+	key VARCHAR(50) NOT NULL,
+	label VARCHAR(100) NOT NULL,
+	type VARCHAR(20) NOT NULL,
+	required BOOLEAN NOT NULL DEFAULT FALSE,
+	options TEXT[],
+	min DOUBLE PRECISION,
+	max DOUBLE PRECISION,
+	max_length INTEGER NOT NULL DEFAULT 0,
+	pattern VARCHAR(255),
+	position INTEGER NOT NULL DEFAULT 0,
+	created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_custom_field_definitions_entity_key ON custom_field_definitions(entity_type, key);
+
+-- Keep the custom field values of the entities by key, indexed for filtering
+ALTER TABLE skus ADD COLUMN IF NOT EXISTS custom_fields JSONB DEFAULT '{}';
+ALTER TABLE sales_orders ADD COLUMN IF NOT EXISTS custom_fields JSONB DEFAULT '{}';
+ALTER TABLE vendors ADD COLUMN IF NOT EXISTS custom_fields JSONB DEFAULT '{}';
+CREATE INDEX IF NOT EXISTS idx_skus_custom_fields ON skus USING GIN (custom_fields);
+CREATE INDEX IF NOT EXISTS idx_sales_orders_custom_fields ON sales_orders USING GIN (custom_fields);
+CREATE INDEX IF NOT EXISTS idx_vendors_custom_fields ON vendors USING GIN (custom_fields);
@@ -0,0 +1,2 @@
+-- Drop saved_views table
+DROP TABLE IF EXISTS saved_views;
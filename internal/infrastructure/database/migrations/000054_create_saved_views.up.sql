@@ -0,0 +1,13 @@
+-- Create saved_views table, the named list filters users save and share with roles
+CREATE TABLE IF NOT EXISTS saved_views (
+	id SERIAL PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	module VARCHAR(50) NOT NULL,
+	name VARCHAR(100) NOT NULL,
+	criteria JSONB,
+	shared_roles TEXT[],
+	created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_saved_views_user_id ON saved_views(user_id);
+CREATE INDEX IF NOT EXISTS idx_saved_views_module ON saved_views(module);
@@ -0,0 +1,7 @@
+-- Drop SKU variants
+DROP INDEX IF EXISTS idx_skus_parent_variant_options;
+DROP INDEX IF EXISTS idx_skus_variant_options;
+DROP INDEX IF EXISTS idx_skus_parent_id;
+ALTER TABLE skus DROP COLUMN IF EXISTS variant_options;
+ALTER TABLE skus DROP COLUMN IF EXISTS variant_dimensions;
+ALTER TABLE skus DROP COLUMN IF EXISTS parent_id;
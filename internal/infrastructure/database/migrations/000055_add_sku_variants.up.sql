@@ -0,0 +1,10 @@
+-- Add SKU variants: parent items set the dimensions their variants vary in,
+-- and each variant points to its parent with a value of each dimension
+ALTER TABLE skus ADD COLUMN IF NOT EXISTS parent_id UUID REFERENCES skus(id);
+ALTER TABLE skus ADD COLUMN IF NOT EXISTS variant_dimensions JSONB;
+ALTER TABLE skus ADD COLUMN IF NOT EXISTS variant_options JSONB;
+CREATE INDEX IF NOT EXISTS idx_skus_parent_id ON skus(parent_id);
+CREATE INDEX IF NOT EXISTS idx_skus_variant_options ON skus USING GIN (variant_options);
+
+-- A parent item has one variant per combination of values
+CREATE UNIQUE INDEX IF NOT EXISTS idx_skus_parent_variant_options ON skus(parent_id, variant_options) WHERE parent_id IS NOT NULL;
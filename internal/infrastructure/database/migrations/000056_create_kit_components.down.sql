@@ -0,0 +1,3 @@
+-- Drop kit_components table
+DROP TABLE IF EXISTS kit_components;
+ALTER TABLE skus DROP COLUMN IF EXISTS is_kit;
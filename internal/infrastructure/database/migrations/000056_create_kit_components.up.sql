@@ -0,0 +1,12 @@
+-- Create kit_components table: kits are SKUs sold as a bundle of other SKUs,
+-- whose components are reserved and shipped in their place
+ALTER TABLE skus ADD COLUMN IF NOT EXISTS is_kit BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS kit_components (
+	id SERIAL PRIMARY KEY,
+	kit_id UUID NOT NULL REFERENCES skus(id) ON DELETE CASCADE,
+	component_id UUID NOT NULL REFERENCES skus(id),
+	quantity DECIMAL(15,3) NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_kit_components_kit_component ON kit_components(kit_id, component_id);
+CREATE INDEX IF NOT EXISTS idx_kit_components_component_id ON kit_components(component_id);
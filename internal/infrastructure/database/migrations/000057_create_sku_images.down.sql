@@ -0,0 +1,2 @@
+-- Drop sku_images table
+DROP TABLE IF EXISTS sku_images;
@@ -0,0 +1,17 @@
+-- Create sku_images table, the pictures of SKUs kept in the file store with
+-- their thumbnails
+CREATE TABLE IF NOT EXISTS sku_images (
+	id BIGSERIAL PRIMARY KEY,
+	sku_id UUID NOT NULL REFERENCES skus(id) ON DELETE CASCADE,
+	position INTEGER NOT NULL DEFAULT 0,
+	file_name VARCHAR(255) NOT NULL,
+	content_type VARCHAR(100) NOT NULL,
+	size BIGINT NOT NULL,
+	width INTEGER NOT NULL,
+	height INTEGER NOT NULL,
+	file_key VARCHAR(255) NOT NULL,
+	thumbnail_key VARCHAR(255) NOT NULL,
+	uploaded_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_sku_images_sku_id ON sku_images(sku_id, position);
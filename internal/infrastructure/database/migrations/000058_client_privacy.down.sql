@@ -0,0 +1,3 @@
+-- Drop client_privacy_requests table and the anonymization time of clients
+DROP TABLE IF EXISTS client_privacy_requests;
+ALTER TABLE clients DROP COLUMN IF EXISTS anonymized_at;
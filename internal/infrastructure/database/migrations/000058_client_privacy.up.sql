@@ -0,0 +1,15 @@
+-- Record when a client's personal data was scrubbed
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS anonymized_at TIMESTAMP;
+
+-- Create client_privacy_requests table, the exports and anonymizations of
+-- clients' personal data
+CREATE TABLE IF NOT EXISTS client_privacy_requests (
+	id SERIAL PRIMARY KEY,
+	client_id INTEGER NOT NULL REFERENCES clients(id) ON DELETE CASCADE,
+	type VARCHAR(20) NOT NULL,
+	format VARCHAR(10),
+	reason TEXT,
+	requested_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_client_privacy_requests_client_id ON client_privacy_requests(client_id);
@@ -0,0 +1,6 @@
+-- Drop the drop-ship links of purchase and delivery orders
+DROP INDEX IF EXISTS idx_delivery_orders_purchase_order_id;
+ALTER TABLE delivery_orders DROP COLUMN IF EXISTS purchase_order_id;
+
+DROP INDEX IF EXISTS idx_purchase_orders_sales_order_id;
+ALTER TABLE purchase_orders DROP COLUMN IF EXISTS sales_order_id;
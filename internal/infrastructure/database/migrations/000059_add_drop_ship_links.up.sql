@@ -0,0 +1,8 @@
+-- Link drop-ship purchase orders to the sales orders they ship to the
+-- customers of, and the deliveries vendors shipped to those orders. The links
+-- have no foreign keys, as either side may be archived before the other.
+ALTER TABLE purchase_orders ADD COLUMN IF NOT EXISTS sales_order_id UUID;
+CREATE INDEX IF NOT EXISTS idx_purchase_orders_sales_order_id ON purchase_orders(sales_order_id);
+
+ALTER TABLE delivery_orders ADD COLUMN IF NOT EXISTS purchase_order_id UUID;
+CREATE INDEX IF NOT EXISTS idx_delivery_orders_purchase_order_id ON delivery_orders(purchase_order_id);
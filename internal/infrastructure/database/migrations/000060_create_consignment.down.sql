@@ -0,0 +1,5 @@
+-- Drop the consignment tables and the consignment columns of stocks
+DROP TABLE IF EXISTS consignment_consumptions;
+DROP TABLE IF EXISTS consignment_stocks;
+ALTER TABLE stock_entries DROP COLUMN IF EXISTS consignment_vendor_id;
+ALTER TABLE stocks DROP COLUMN IF EXISTS consigned_quantity;
@@ -0,0 +1,36 @@
+-- Track the part of each stock vendors still own on consignment, and tag the
+-- stock entries receiving or returning it with the vendor
+ALTER TABLE stocks ADD COLUMN IF NOT EXISTS consigned_quantity DECIMAL(15, 3) NOT NULL DEFAULT 0;
+ALTER TABLE stock_entries ADD COLUMN IF NOT EXISTS consignment_vendor_id INTEGER;
+
+-- Create consignment_stocks table, the consignment stock of each vendor per
+-- SKU and store
+CREATE TABLE IF NOT EXISTS consignment_stocks (
+	id SERIAL PRIMARY KEY,
+	vendor_id INTEGER NOT NULL REFERENCES vendors(id),
+	sku_id UUID NOT NULL,
+	store_id UUID NOT NULL REFERENCES stores(id),
+	quantity DECIMAL(15, 3) NOT NULL DEFAULT 0,
+	unit_cost DECIMAL(15, 2) NOT NULL DEFAULT 0,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_consignment_stock ON consignment_stocks(vendor_id, sku_id, store_id);
+
+-- Create consignment_consumptions table, the consigned stock converted to
+-- owned stock and the purchase invoices billing it. The references have no
+-- foreign keys, as stock entries and finance invoices are archived.
+CREATE TABLE IF NOT EXISTS consignment_consumptions (
+	id SERIAL PRIMARY KEY,
+	vendor_id INTEGER NOT NULL REFERENCES vendors(id),
+	sku_id UUID NOT NULL,
+	store_id UUID NOT NULL REFERENCES stores(id),
+	quantity DECIMAL(15, 3) NOT NULL,
+	unit_cost DECIMAL(15, 2) NOT NULL,
+	amount DECIMAL(15, 2) NOT NULL,
+	reference VARCHAR(100),
+	finance_invoice_id BIGINT,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_consignment_consumptions_vendor_id ON consignment_consumptions(vendor_id);
+CREATE INDEX IF NOT EXISTS idx_consignment_consumptions_finance_invoice_id ON consignment_consumptions(finance_invoice_id);
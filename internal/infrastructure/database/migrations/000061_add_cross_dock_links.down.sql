@@ -0,0 +1,5 @@
+-- Drop the cross-dock links of delivery orders and purchase receipts
+DROP INDEX IF EXISTS idx_delivery_orders_cross_dock_receipt_id;
+ALTER TABLE delivery_orders DROP COLUMN IF EXISTS cross_dock_receipt_id;
+
+ALTER TABLE purchase_receipts DROP COLUMN IF EXISTS cross_dock;
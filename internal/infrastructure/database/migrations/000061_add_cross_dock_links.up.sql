@@ -0,0 +1,7 @@
+-- Mark purchase receipts received in cross-dock mode and link the deliveries
+-- they shipped without putaway. The link has no foreign key, as either side
+-- may be archived before the other.
+ALTER TABLE purchase_receipts ADD COLUMN IF NOT EXISTS cross_dock BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE delivery_orders ADD COLUMN IF NOT EXISTS cross_dock_receipt_id UUID;
+CREATE INDEX IF NOT EXISTS idx_delivery_orders_cross_dock_receipt_id ON delivery_orders(cross_dock_receipt_id);
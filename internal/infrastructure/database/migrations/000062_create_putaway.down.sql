@@ -0,0 +1,3 @@
+-- Drop the putaway tables
+DROP TABLE IF EXISTS putaway_tasks;
+DROP TABLE IF EXISTS putaway_rules;
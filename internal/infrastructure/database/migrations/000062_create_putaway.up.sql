@@ -0,0 +1,43 @@
+-- Create putaway_rules table, the rules suggesting where received goods of a
+-- store are put away
+CREATE TABLE IF NOT EXISTS putaway_rules (
+	id SERIAL PRIMARY KEY,
+	store_id UUID NOT NULL REFERENCES stores(id),
+	type VARCHAR(20) NOT NULL,
+	priority INTEGER NOT NULL DEFAULT 0,
+	sku_id UUID,
+	velocity_class VARCHAR(10),
+	zone_code VARCHAR(50),
+	shelf_number VARCHAR(50),
+	bin_location VARCHAR(50),
+	active BOOLEAN DEFAULT TRUE,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_putaway_rules_store_id ON putaway_rules(store_id);
+
+-- Create putaway_tasks table, the received goods to move from the dock to a
+-- bin. The receipt has no foreign key, as purchase receipts are archived.
+CREATE TABLE IF NOT EXISTS putaway_tasks (
+	id SERIAL PRIMARY KEY,
+	receipt_id UUID NOT NULL,
+	receipt_number VARCHAR(100) NOT NULL,
+	store_id UUID NOT NULL REFERENCES stores(id),
+	sku_id UUID NOT NULL,
+	quantity DECIMAL(15, 3) NOT NULL,
+	rule_id INTEGER,
+	rule_type VARCHAR(20),
+	suggested_zone VARCHAR(50),
+	suggested_shelf VARCHAR(50),
+	suggested_bin VARCHAR(50),
+	zone_code VARCHAR(50),
+	shelf_number VARCHAR(50),
+	bin_location VARCHAR(50),
+	status VARCHAR(20) NOT NULL DEFAULT 'PENDING',
+	confirmed_by VARCHAR(255),
+	confirmed_at TIMESTAMP,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_putaway_tasks_receipt_id ON putaway_tasks(receipt_id);
+CREATE INDEX IF NOT EXISTS idx_putaway_tasks_store_id ON putaway_tasks(store_id);
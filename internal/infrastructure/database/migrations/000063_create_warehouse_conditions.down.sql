@@ -0,0 +1,8 @@
+-- Drop the warehouse condition tables, with the excursion holds that have no
+-- inspection plan
+DROP TABLE IF EXISTS condition_readings;
+DROP TABLE IF EXISTS condition_excursion_lots;
+DROP TABLE IF EXISTS condition_excursions;
+DROP TABLE IF EXISTS condition_thresholds;
+DELETE FROM quality_inspections WHERE plan_id IS NULL;
+ALTER TABLE quality_inspections ALTER COLUMN plan_id SET NOT NULL;
@@ -0,0 +1,74 @@
+-- Create condition_thresholds table, the range a condition must stay in, in
+-- a store or one zone of it
+CREATE TABLE IF NOT EXISTS condition_thresholds (
+	id SERIAL PRIMARY KEY,
+	store_id UUID NOT NULL REFERENCES stores(id),
+	zone_code VARCHAR(50),
+	metric VARCHAR(20) NOT NULL,
+	min_value DOUBLE PRECISION,
+	max_value DOUBLE PRECISION,
+	hold_stock BOOLEAN DEFAULT TRUE,
+	active BOOLEAN DEFAULT TRUE,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_condition_thresholds_store_id ON condition_thresholds(store_id);
+
+-- Create condition_excursions table, the periods a condition spent outside a
+-- threshold. A zone has at most one open excursion per threshold.
+CREATE TABLE IF NOT EXISTS condition_excursions (
+	id SERIAL PRIMARY KEY,
+	threshold_id INTEGER NOT NULL REFERENCES condition_thresholds(id),
+	store_id UUID NOT NULL REFERENCES stores(id),
+	zone_code VARCHAR(50) NOT NULL DEFAULT '',
+	metric VARCHAR(20) NOT NULL,
+	limits VARCHAR(100),
+	peak_value DOUBLE PRECISION,
+	readings INTEGER NOT NULL DEFAULT 1,
+	status VARCHAR(10) NOT NULL DEFAULT 'OPEN',
+	started_at TIMESTAMP NOT NULL,
+	ended_at TIMESTAMP,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_condition_excursions_threshold_id ON condition_excursions(threshold_id);
+CREATE INDEX IF NOT EXISTS idx_condition_excursions_store_id ON condition_excursions(store_id);
+CREATE INDEX IF NOT EXISTS idx_condition_excursions_status ON condition_excursions(status);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_condition_excursions_open ON condition_excursions(threshold_id, zone_code) WHERE status = 'OPEN';
+
+-- Create condition_excursion_lots table, the lots stored in the zone of an
+-- excursion when it started and the inspections holding them
+CREATE TABLE IF NOT EXISTS condition_excursion_lots (
+	id SERIAL PRIMARY KEY,
+	excursion_id INTEGER NOT NULL REFERENCES condition_excursions(id) ON DELETE CASCADE,
+	stock_id UUID NOT NULL,
+	sku_id UUID NOT NULL,
+	batch_number VARCHAR(100),
+	lot_number VARCHAR(100),
+	zone_code VARCHAR(50),
+	quantity DECIMAL(15, 3) NOT NULL,
+	inspection_id INTEGER REFERENCES quality_inspections(id)
+);
+CREATE INDEX IF NOT EXISTS idx_condition_excursion_lots_excursion_id ON condition_excursion_lots(excursion_id);
+
+-- Create condition_readings table, the readings entered by hand or pushed by
+-- sensors
+CREATE TABLE IF NOT EXISTS condition_readings (
+	id SERIAL PRIMARY KEY,
+	store_id UUID NOT NULL REFERENCES stores(id),
+	zone_code VARCHAR(50) NOT NULL DEFAULT '',
+	metric VARCHAR(20) NOT NULL,
+	value DOUBLE PRECISION NOT NULL,
+	source VARCHAR(10) NOT NULL,
+	device_id VARCHAR(100),
+	recorded_at TIMESTAMP NOT NULL,
+	recorded_by VARCHAR(255),
+	excursion_id INTEGER REFERENCES condition_excursions(id),
+	created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_condition_readings_store_id ON condition_readings(store_id);
+CREATE INDEX IF NOT EXISTS idx_condition_readings_recorded_at ON condition_readings(recorded_at);
+CREATE INDEX IF NOT EXISTS idx_condition_readings_excursion_id ON condition_readings(excursion_id);
+
+-- Excursions hold stock of SKUs without an inspection plan
+ALTER TABLE quality_inspections ALTER COLUMN plan_id DROP NOT NULL;
@@ -0,0 +1,5 @@
+-- Drop the sales channel tables
+DROP TABLE IF EXISTS channel_sync_runs;
+DROP TABLE IF EXISTS channel_sku_mappings;
+DROP TABLE IF EXISTS sales_channel_warehouses;
+DROP TABLE IF EXISTS sales_channels;
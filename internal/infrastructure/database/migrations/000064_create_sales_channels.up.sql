@@ -0,0 +1,61 @@
+-- Create sales_channels table, the web shops of e-commerce platforms whose
+-- orders are ingested as sales orders
+CREATE TABLE IF NOT EXISTS sales_channels (
+	id SERIAL PRIMARY KEY,
+	code VARCHAR(50) NOT NULL UNIQUE,
+	name VARCHAR(255) NOT NULL,
+	platform VARCHAR(20) NOT NULL,
+	base_url VARCHAR(255) NOT NULL,
+	api_key VARCHAR(255),
+	api_secret VARCHAR(255),
+	access_token VARCHAR(255),
+	client_id INTEGER NOT NULL REFERENCES clients(id),
+	store_id UUID NOT NULL REFERENCES stores(id),
+	currency_code VARCHAR(3),
+	push_inventory BOOLEAN DEFAULT TRUE,
+	active BOOLEAN DEFAULT TRUE,
+	orders_synced_at TIMESTAMP,
+	inventory_synced_at TIMESTAMP,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Create sales_channel_warehouses table, the stores linked to the locations
+-- of a sales channel
+CREATE TABLE IF NOT EXISTS sales_channel_warehouses (
+	id SERIAL PRIMARY KEY,
+	channel_id INTEGER NOT NULL REFERENCES sales_channels(id) ON DELETE CASCADE,
+	store_id UUID NOT NULL REFERENCES stores(id),
+	location_id VARCHAR(100)
+);
+CREATE INDEX IF NOT EXISTS idx_sales_channel_warehouses_channel_id ON sales_channel_warehouses(channel_id);
+
+-- Create channel_sku_mappings table, the SKUs the listings of a sales channel
+-- sell
+CREATE TABLE IF NOT EXISTS channel_sku_mappings (
+	id SERIAL PRIMARY KEY,
+	channel_id INTEGER NOT NULL REFERENCES sales_channels(id) ON DELETE CASCADE,
+	sku_id UUID NOT NULL REFERENCES skus(id),
+	external_sku VARCHAR(100) NOT NULL,
+	external_id VARCHAR(100),
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_channel_sku_mappings_external ON channel_sku_mappings(channel_id, external_sku);
+CREATE INDEX IF NOT EXISTS idx_channel_sku_mappings_sku_id ON channel_sku_mappings(sku_id);
+
+-- Create channel_sync_runs table, the order and inventory syncs with sales
+-- channels
+CREATE TABLE IF NOT EXISTS channel_sync_runs (
+	id SERIAL PRIMARY KEY,
+	channel_id INTEGER NOT NULL REFERENCES sales_channels(id) ON DELETE CASCADE,
+	kind VARCHAR(20) NOT NULL,
+	status VARCHAR(20) NOT NULL,
+	processed INTEGER NOT NULL DEFAULT 0,
+	failed INTEGER NOT NULL DEFAULT 0,
+	errors TEXT,
+	triggered_by VARCHAR(100),
+	started_at TIMESTAMP NOT NULL,
+	finished_at TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_channel_sync_runs_channel_id ON channel_sync_runs(channel_id);
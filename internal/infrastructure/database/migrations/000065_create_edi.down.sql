@@ -0,0 +1,3 @@
+-- Drop the EDI tables
+DROP TABLE IF EXISTS edi_documents;
+DROP TABLE IF EXISTS trading_partners;
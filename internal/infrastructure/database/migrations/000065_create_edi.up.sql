@@ -0,0 +1,42 @@
+-- Create trading_partners table, the vendors purchase documents are exchanged
+-- with as X12 EDI
+CREATE TABLE IF NOT EXISTS trading_partners (
+	id SERIAL PRIMARY KEY,
+	vendor_id INTEGER NOT NULL UNIQUE REFERENCES vendors(id),
+	isa_qualifier VARCHAR(2) NOT NULL,
+	isa_id VARCHAR(15) NOT NULL,
+	gs_id VARCHAR(15) NOT NULL,
+	our_isa_qualifier VARCHAR(2) NOT NULL,
+	our_isa_id VARCHAR(15) NOT NULL,
+	our_gs_id VARCHAR(15) NOT NULL,
+	test BOOLEAN DEFAULT FALSE,
+	send_purchase_orders BOOLEAN DEFAULT TRUE,
+	last_control_number BIGINT NOT NULL DEFAULT 0,
+	active BOOLEAN DEFAULT TRUE,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_trading_partners_isa_id ON trading_partners(isa_id);
+
+-- Create edi_documents table, the archive of the transaction sets exchanged
+-- with trading partners and what they were mapped to
+CREATE TABLE IF NOT EXISTS edi_documents (
+	id SERIAL PRIMARY KEY,
+	partner_id INTEGER NOT NULL REFERENCES trading_partners(id),
+	direction VARCHAR(3) NOT NULL,
+	type VARCHAR(3) NOT NULL,
+	control_number VARCHAR(9),
+	reference VARCHAR(255),
+	purchase_order_id UUID REFERENCES purchase_orders(id),
+	receipt_id UUID REFERENCES purchase_receipts(id),
+	finance_invoice_id BIGINT,
+	content TEXT NOT NULL,
+	data JSONB,
+	status VARCHAR(20) NOT NULL,
+	error TEXT,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_edi_documents_partner_id ON edi_documents(partner_id);
+CREATE INDEX IF NOT EXISTS idx_edi_documents_reference ON edi_documents(reference);
+CREATE INDEX IF NOT EXISTS idx_edi_documents_purchase_order_id ON edi_documents(purchase_order_id);
@@ -0,0 +1,3 @@
+-- Drop the accounting export tables
+DROP TABLE IF EXISTS accounting_export_records;
+DROP TABLE IF EXISTS accounting_export_runs;
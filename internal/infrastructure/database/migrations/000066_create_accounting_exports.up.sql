@@ -0,0 +1,29 @@
+-- Create accounting_export_runs table, the history of finance records exported
+-- to external accounting systems with the files produced
+CREATE TABLE IF NOT EXISTS accounting_export_runs (
+	id SERIAL PRIMARY KEY,
+	format VARCHAR(20) NOT NULL,
+	from_date DATE NOT NULL,
+	to_date DATE NOT NULL,
+	invoices INTEGER NOT NULL DEFAULT 0,
+	payments INTEGER NOT NULL DEFAULT 0,
+	journal_entries INTEGER NOT NULL DEFAULT 0,
+	file_name VARCHAR(255) NOT NULL,
+	content_type VARCHAR(255) NOT NULL,
+	content BYTEA NOT NULL,
+	created_by INTEGER,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Create accounting_export_records table, flagging each finance record as
+-- exported once per format
+CREATE TABLE IF NOT EXISTS accounting_export_records (
+	id SERIAL PRIMARY KEY,
+	run_id INTEGER NOT NULL REFERENCES accounting_export_runs(id) ON DELETE CASCADE,
+	format VARCHAR(20) NOT NULL,
+	record_type VARCHAR(20) NOT NULL,
+	record_id VARCHAR(255) NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_accounting_export_records_run_id ON accounting_export_records(run_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_accounting_export_records_record ON accounting_export_records(format, record_type, record_id);
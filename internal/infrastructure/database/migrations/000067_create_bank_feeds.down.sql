@@ -0,0 +1,4 @@
+-- Drop the bank feed tables
+DROP TABLE IF EXISTS bank_import_runs;
+DROP TABLE IF EXISTS bank_transactions;
+DROP TABLE IF EXISTS bank_accounts;
@@ -0,0 +1,53 @@
+-- Create bank_accounts table, the bank accounts whose transactions are
+-- imported from bank feed providers
+CREATE TABLE IF NOT EXISTS bank_accounts (
+	id SERIAL PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	provider VARCHAR(20) NOT NULL,
+	base_url VARCHAR(255) NOT NULL,
+	external_id VARCHAR(255) NOT NULL,
+	access_token TEXT,
+	currency_code VARCHAR(3) NOT NULL,
+	active BOOLEAN DEFAULT TRUE,
+	synced_at TIMESTAMP,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Create bank_transactions table, the booked transactions imported from bank
+-- feeds and the payments they were matched to
+CREATE TABLE IF NOT EXISTS bank_transactions (
+	id SERIAL PRIMARY KEY,
+	account_id INTEGER NOT NULL REFERENCES bank_accounts(id),
+	external_id VARCHAR(255) NOT NULL,
+	booked_at TIMESTAMP NOT NULL,
+	amount DECIMAL(15,2) NOT NULL,
+	currency_code VARCHAR(3) NOT NULL,
+	description TEXT,
+	counterparty VARCHAR(255),
+	reference VARCHAR(255),
+	status VARCHAR(20) NOT NULL,
+	payment_id BIGINT,
+	matched_by INTEGER,
+	matched_at TIMESTAMP,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_bank_transactions_external ON bank_transactions(account_id, external_id);
+CREATE INDEX IF NOT EXISTS idx_bank_transactions_booked_at ON bank_transactions(booked_at);
+CREATE INDEX IF NOT EXISTS idx_bank_transactions_status ON bank_transactions(status);
+CREATE INDEX IF NOT EXISTS idx_bank_transactions_payment_id ON bank_transactions(payment_id);
+
+-- Create bank_import_runs table, the history of bank feed imports
+CREATE TABLE IF NOT EXISTS bank_import_runs (
+	id SERIAL PRIMARY KEY,
+	account_id INTEGER NOT NULL REFERENCES bank_accounts(id),
+	status VARCHAR(20) NOT NULL,
+	fetched INTEGER NOT NULL DEFAULT 0,
+	imported INTEGER NOT NULL DEFAULT 0,
+	error TEXT,
+	triggered_by VARCHAR(255),
+	started_at TIMESTAMP NOT NULL,
+	finished_at TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_bank_import_runs_account_id ON bank_import_runs(account_id);
@@ -0,0 +1,4 @@
+-- Drop the customs classification of SKUs
+ALTER TABLE skus DROP COLUMN IF EXISTS net_weight;
+ALTER TABLE skus DROP COLUMN IF EXISTS country_of_origin;
+ALTER TABLE skus DROP COLUMN IF EXISTS hs_code;
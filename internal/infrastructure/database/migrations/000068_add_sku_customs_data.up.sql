@@ -0,0 +1,5 @@
+-- Add the customs classification of SKUs: commodity code, country of origin
+-- and net weight per unit, declared on Intrastat and customs reports
+ALTER TABLE skus ADD COLUMN IF NOT EXISTS hs_code VARCHAR(10);
+ALTER TABLE skus ADD COLUMN IF NOT EXISTS country_of_origin VARCHAR(2);
+ALTER TABLE skus ADD COLUMN IF NOT EXISTS net_weight DECIMAL(15,3) DEFAULT 0;
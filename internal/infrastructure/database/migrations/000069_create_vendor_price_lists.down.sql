@@ -0,0 +1,3 @@
+-- Drop vendor price list tables
+DROP TABLE IF EXISTS vendor_price_list_items;
+DROP TABLE IF EXISTS vendor_price_lists;
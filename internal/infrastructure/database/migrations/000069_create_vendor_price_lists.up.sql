@@ -0,0 +1,26 @@
+-- Create vendor_price_lists table: the prices a vendor quotes over a
+-- validity period, with quantity breaks, that purchase orders generated
+-- from requests are priced from
+CREATE TABLE IF NOT EXISTS vendor_price_lists (
+	id SERIAL PRIMARY KEY,
+	vendor_id INTEGER NOT NULL REFERENCES vendors(id) ON DELETE CASCADE,
+	name VARCHAR(100) NOT NULL,
+	currency_code VARCHAR(3) NOT NULL,
+	valid_from DATE NOT NULL,
+	valid_to DATE,
+	created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+	updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_vendor_price_lists_vendor_id ON vendor_price_lists(vendor_id);
+
+-- Create vendor_price_list_items table: the unit price of a SKU from a
+-- minimum quantity ordered
+CREATE TABLE IF NOT EXISTS vendor_price_list_items (
+	id SERIAL PRIMARY KEY,
+	price_list_id INTEGER NOT NULL REFERENCES vendor_price_lists(id) ON DELETE CASCADE,
+	sku_id UUID NOT NULL REFERENCES skus(id) ON DELETE CASCADE,
+	min_quantity DECIMAL(15,3) NOT NULL DEFAULT 0,
+	unit_price DECIMAL(15,2) NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_vendor_price_list_items_break ON vendor_price_list_items(price_list_id, sku_id, min_quantity);
+CREATE INDEX IF NOT EXISTS idx_vendor_price_list_items_sku_id ON vendor_price_list_items(sku_id);
@@ -0,0 +1,2 @@
+-- Drop purchase_order_requests table
+DROP TABLE IF EXISTS purchase_order_requests;
@@ -0,0 +1,15 @@
+-- Create purchase_order_requests table: the purchase requests each purchase
+-- order was generated from, a request split between vendors linking to an
+-- order each
+CREATE TABLE IF NOT EXISTS purchase_order_requests (
+	purchase_order_id UUID NOT NULL REFERENCES purchase_orders(id) ON DELETE CASCADE,
+	purchase_request_id UUID NOT NULL REFERENCES purchase_requests(id) ON DELETE CASCADE,
+	created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+	PRIMARY KEY (purchase_order_id, purchase_request_id)
+);
+CREATE INDEX IF NOT EXISTS idx_purchase_order_requests_purchase_request_id ON purchase_order_requests(purchase_request_id);
+
+-- Link the requests already ordered to their order
+INSERT INTO purchase_order_requests (purchase_order_id, purchase_request_id)
+SELECT purchase_order_id, id FROM purchase_requests WHERE purchase_order_id IS NOT NULL
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,7 @@
+-- Drop dock scheduling tables
+DROP TABLE IF EXISTS dock_appointments;
+DROP TABLE IF EXISTS dock_doors;
+
+-- Drop the warehouse of purchase orders
+DROP INDEX IF EXISTS idx_purchase_orders_store_id;
+ALTER TABLE purchase_orders DROP COLUMN IF EXISTS store_id;
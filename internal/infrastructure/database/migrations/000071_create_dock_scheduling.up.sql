@@ -0,0 +1,37 @@
+-- Add the warehouse purchase orders are delivered to
+ALTER TABLE purchase_orders ADD COLUMN IF NOT EXISTS store_id UUID REFERENCES stores(id);
+CREATE INDEX IF NOT EXISTS idx_purchase_orders_store_id ON purchase_orders(store_id);
+
+-- Create dock_doors table: the doors of a warehouse where inbound
+-- deliveries are unloaded
+CREATE TABLE IF NOT EXISTS dock_doors (
+	id SERIAL PRIMARY KEY,
+	store_id UUID NOT NULL REFERENCES stores(id) ON DELETE CASCADE,
+	name VARCHAR(50) NOT NULL,
+	active BOOLEAN DEFAULT TRUE,
+	created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+	updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_dock_doors_store_name ON dock_doors(store_id, name);
+
+-- Create dock_appointments table: the time slots booked at dock doors for
+-- inbound deliveries
+CREATE TABLE IF NOT EXISTS dock_appointments (
+	id SERIAL PRIMARY KEY,
+	door_id INTEGER NOT NULL REFERENCES dock_doors(id) ON DELETE CASCADE,
+	store_id UUID NOT NULL REFERENCES stores(id) ON DELETE CASCADE,
+	purchase_order_id UUID REFERENCES purchase_orders(id) ON DELETE SET NULL,
+	ship_notice_id INTEGER REFERENCES edi_documents(id) ON DELETE SET NULL,
+	carrier VARCHAR(255),
+	starts_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	ends_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	status VARCHAR(20) NOT NULL DEFAULT 'BOOKED',
+	arrived_at TIMESTAMP WITH TIME ZONE,
+	notes TEXT,
+	booked_by_id INTEGER NOT NULL REFERENCES users(id),
+	created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+	updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_dock_appointments_door_id ON dock_appointments(door_id, starts_at);
+CREATE INDEX IF NOT EXISTS idx_dock_appointments_store_id ON dock_appointments(store_id);
+CREATE INDEX IF NOT EXISTS idx_dock_appointments_purchase_order_id ON dock_appointments(purchase_order_id);
@@ -0,0 +1,6 @@
+-- Drop the ABC/XYZ classes of SKUs
+DROP INDEX IF EXISTS idx_skus_xyz_class;
+DROP INDEX IF EXISTS idx_skus_abc_class;
+ALTER TABLE skus DROP COLUMN IF EXISTS classified_at;
+ALTER TABLE skus DROP COLUMN IF EXISTS xyz_class;
+ALTER TABLE skus DROP COLUMN IF EXISTS abc_class;
@@ -0,0 +1,7 @@
+-- Add the ABC/XYZ classes of SKUs: ABC ranks them by consumption value and
+-- XYZ by how much their monthly demand varies
+ALTER TABLE skus ADD COLUMN IF NOT EXISTS abc_class VARCHAR(1);
+ALTER TABLE skus ADD COLUMN IF NOT EXISTS xyz_class VARCHAR(1);
+ALTER TABLE skus ADD COLUMN IF NOT EXISTS classified_at TIMESTAMP WITH TIME ZONE;
+CREATE INDEX IF NOT EXISTS idx_skus_abc_class ON skus(abc_class);
+CREATE INDEX IF NOT EXISTS idx_skus_xyz_class ON skus(xyz_class);
@@ -0,0 +1,2 @@
+-- Drop stock snapshots table
+DROP TABLE IF EXISTS stock_snapshots;
@@ -0,0 +1,14 @@
+-- Create stock_snapshots table: the closing stock of each SKU in each store
+-- per day, valued at the SKU price when the snapshot was taken
+CREATE TABLE IF NOT EXISTS stock_snapshots (
+	id SERIAL PRIMARY KEY,
+	snapshot_date DATE NOT NULL,
+	store_id UUID NOT NULL REFERENCES stores(id) ON DELETE CASCADE,
+	sku_id UUID NOT NULL REFERENCES skus(id) ON DELETE CASCADE,
+	quantity DECIMAL(15,3) NOT NULL,
+	unit_cost DECIMAL(15,2) NOT NULL DEFAULT 0,
+	value DECIMAL(15,2) NOT NULL DEFAULT 0,
+	created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_stock_snapshots_day ON stock_snapshots(snapshot_date, store_id, sku_id);
+CREATE INDEX IF NOT EXISTS idx_stock_snapshots_sku_id ON stock_snapshots(sku_id);
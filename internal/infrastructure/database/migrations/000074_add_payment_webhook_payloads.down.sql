@@ -0,0 +1,2 @@
+-- Drop the payload of payment gateway events
+ALTER TABLE payment_webhook_events DROP COLUMN IF EXISTS payload;
@@ -0,0 +1,3 @@
+-- Keep the verified payload of payment gateway events, for failed events to
+-- be replayed
+ALTER TABLE payment_webhook_events ADD COLUMN IF NOT EXISTS payload JSONB;
@@ -0,0 +1,2 @@
+-- Drop scheduled_runs table
+DROP TABLE IF EXISTS scheduled_runs;
@@ -0,0 +1,15 @@
+-- Create scheduled_runs table, the history of the runs of the recurring
+-- background tasks
+CREATE TABLE IF NOT EXISTS scheduled_runs (
+	id BIGSERIAL PRIMARY KEY,
+	task VARCHAR(50) NOT NULL,
+	trigger VARCHAR(20) NOT NULL,
+	status VARCHAR(20) NOT NULL,
+	instance VARCHAR(255) NOT NULL,
+	error TEXT,
+	triggered_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+	started_at TIMESTAMP NOT NULL,
+	finished_at TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_scheduled_runs_task ON scheduled_runs(task, started_at DESC);
+CREATE INDEX IF NOT EXISTS idx_scheduled_runs_started_at ON scheduled_runs(started_at);
@@ -0,0 +1,2 @@
+-- Drop report_runs table
+DROP TABLE IF EXISTS report_runs;
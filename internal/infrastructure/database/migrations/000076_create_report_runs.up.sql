@@ -0,0 +1,20 @@
+-- Create report_runs table, the history of the runs of the report schedules
+CREATE TABLE IF NOT EXISTS report_runs (
+	id BIGSERIAL PRIMARY KEY,
+	schedule_id UUID NOT NULL REFERENCES report_schedules(id) ON DELETE CASCADE,
+	report_id UUID REFERENCES reports(id) ON DELETE SET NULL,
+	job_id BIGINT REFERENCES jobs(id) ON DELETE SET NULL,
+	rerun_of BIGINT REFERENCES report_runs(id) ON DELETE SET NULL,
+	triggered_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+	status VARCHAR(20) NOT NULL,
+	period_start TIMESTAMP NOT NULL,
+	period_end TIMESTAMP NOT NULL,
+	row_count INTEGER,
+	file_key VARCHAR(255),
+	error TEXT,
+	attempts INTEGER NOT NULL DEFAULT 0,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	started_at TIMESTAMP,
+	finished_at TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_report_runs_schedule ON report_runs(schedule_id, created_at DESC);
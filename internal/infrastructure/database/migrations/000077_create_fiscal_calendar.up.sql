@@ -0,0 +1,28 @@
+-- Create fiscal_years table, the financial years of the company
+CREATE TABLE IF NOT EXISTS fiscal_years (
+	id SERIAL PRIMARY KEY,
+	name VARCHAR(50) NOT NULL,
+	start_date DATE NOT NULL,
+	end_date DATE NOT NULL,
+	period_type VARCHAR(20) NOT NULL,
+	created_by INTEGER NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_fiscal_years_name ON fiscal_years(name);
+-- Create fiscal_periods table, the periods finance closes once their books are final
+CREATE TABLE IF NOT EXISTS fiscal_periods (
+	id SERIAL PRIMARY KEY,
+	fiscal_year_id INTEGER NOT NULL REFERENCES fiscal_years(id) ON DELETE CASCADE,
+	number INTEGER NOT NULL,
+	name VARCHAR(255) NOT NULL,
+	start_date DATE NOT NULL,
+	end_date DATE NOT NULL,
+	status VARCHAR(20) NOT NULL DEFAULT 'OPEN',
+	closed_at TIMESTAMP,
+	closed_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_fiscal_periods_number ON fiscal_periods(fiscal_year_id, number);
+CREATE INDEX IF NOT EXISTS idx_fiscal_periods_dates ON fiscal_periods(start_date, end_date);
@@ -0,0 +1,4 @@
+-- Drop expense claim tables
+DROP TABLE IF EXISTS expense_claim_lines;
+DROP TABLE IF EXISTS expense_claims;
+DROP TABLE IF EXISTS expense_categories;
@@ -0,0 +1,49 @@
+-- Create expense_categories table, classifying the lines of expense claims
+CREATE TABLE IF NOT EXISTS expense_categories (
+	id SERIAL PRIMARY KEY,
+	code VARCHAR(30) NOT NULL,
+	name VARCHAR(255) NOT NULL,
+	description TEXT,
+	active BOOLEAN NOT NULL DEFAULT TRUE,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_expense_categories_code ON expense_categories(code);
+-- Create expense_claims table, the expenses employees claim back
+CREATE TABLE IF NOT EXISTS expense_claims (
+	id SERIAL PRIMARY KEY,
+	claim_number VARCHAR(50) NOT NULL,
+	employee_id INTEGER NOT NULL REFERENCES users(id),
+	title VARCHAR(255) NOT NULL,
+	currency_code VARCHAR(3) NOT NULL,
+	total DECIMAL(15,2) NOT NULL,
+	base_total DECIMAL(15,2) NOT NULL,
+	status VARCHAR(20) NOT NULL,
+	submitted_at TIMESTAMP,
+	reviewer_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+	on_behalf_of_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+	reviewed_at TIMESTAMP,
+	review_notes TEXT,
+	finance_invoice_id BIGINT,
+	finance_payment_id BIGINT,
+	reimbursed_at TIMESTAMP,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_expense_claims_claim_number ON expense_claims(claim_number);
+CREATE INDEX IF NOT EXISTS idx_expense_claims_employee_id ON expense_claims(employee_id);
+CREATE INDEX IF NOT EXISTS idx_expense_claims_status ON expense_claims(status);
+-- Create expense_claim_lines table, the expenses of a claim
+CREATE TABLE IF NOT EXISTS expense_claim_lines (
+	id SERIAL PRIMARY KEY,
+	claim_id INTEGER NOT NULL REFERENCES expense_claims(id) ON DELETE CASCADE,
+	category_id INTEGER NOT NULL REFERENCES expense_categories(id),
+	expense_date DATE NOT NULL,
+	description TEXT,
+	amount DECIMAL(15,2) NOT NULL,
+	exchange_rate DECIMAL(18,8) NOT NULL,
+	base_amount DECIMAL(15,2) NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_expense_claim_lines_claim_id ON expense_claim_lines(claim_id);
+CREATE INDEX IF NOT EXISTS idx_expense_claim_lines_category_id ON expense_claim_lines(category_id);
+CREATE INDEX IF NOT EXISTS idx_expense_claim_lines_expense_date ON expense_claim_lines(expense_date);
@@ -0,0 +1,8 @@
+-- Drop withholding tax
+ALTER TABLE finance_payments
+	DROP COLUMN IF EXISTS net_amount,
+	DROP COLUMN IF EXISTS withheld_amount;
+ALTER TABLE finance_invoices
+	DROP COLUMN IF EXISTS withholding_amount,
+	DROP COLUMN IF EXISTS withholding_rate;
+DROP TABLE IF EXISTS withholding_tax_rates;
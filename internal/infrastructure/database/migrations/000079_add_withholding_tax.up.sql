@@ -0,0 +1,21 @@
+-- Create withholding_tax_rates table, the tax withheld from payments to
+-- a vendor or to the vendors of a country
+CREATE TABLE IF NOT EXISTS withholding_tax_rates (
+	id SERIAL PRIMARY KEY,
+	vendor_id INTEGER REFERENCES vendors(id) ON DELETE CASCADE,
+	country VARCHAR(100),
+	rate DECIMAL(5,2) NOT NULL,
+	description TEXT,
+	active BOOLEAN NOT NULL DEFAULT TRUE,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_withholding_tax_rates_vendor_id ON withholding_tax_rates(vendor_id);
+CREATE INDEX IF NOT EXISTS idx_withholding_tax_rates_country ON withholding_tax_rates(country);
+ALTER TABLE finance_invoices
+	ADD COLUMN IF NOT EXISTS withholding_rate DECIMAL(5, 2) NOT NULL DEFAULT 0,
+	ADD COLUMN IF NOT EXISTS withholding_amount DECIMAL(15, 2) NOT NULL DEFAULT 0;
+ALTER TABLE finance_payments
+	ADD COLUMN IF NOT EXISTS withheld_amount DECIMAL(15, 2) NOT NULL DEFAULT 0,
+	ADD COLUMN IF NOT EXISTS net_amount DECIMAL(15, 2) NOT NULL DEFAULT 0;
+UPDATE finance_payments SET net_amount = amount;
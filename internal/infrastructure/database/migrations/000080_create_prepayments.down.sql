@@ -0,0 +1,7 @@
+-- Drop prepayments
+ALTER TABLE finance_invoices DROP COLUMN IF EXISTS prepaid_amount;
+ALTER TABLE invoices
+	DROP COLUMN IF EXISTS amount_due,
+	DROP COLUMN IF EXISTS prepaid_amount;
+DROP TABLE IF EXISTS prepayment_applications;
+DROP TABLE IF EXISTS prepayments;
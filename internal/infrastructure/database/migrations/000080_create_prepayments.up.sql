@@ -0,0 +1,49 @@
+-- Create prepayments table, the customer deposits against sales orders and
+-- the vendor advances against purchase orders. Orders have no foreign keys,
+-- as they are archived.
+CREATE TABLE IF NOT EXISTS prepayments (
+	id SERIAL PRIMARY KEY,
+	prepayment_number VARCHAR(50) NOT NULL,
+	type VARCHAR(20) NOT NULL,
+	order_id UUID NOT NULL,
+	order_number VARCHAR(50) NOT NULL,
+	entity_type VARCHAR(20) NOT NULL,
+	entity_id INTEGER NOT NULL,
+	payment_date DATE NOT NULL,
+	payment_method VARCHAR(20) NOT NULL,
+	reference_number VARCHAR(255),
+	amount DECIMAL(15,2) NOT NULL,
+	applied_amount DECIMAL(15,2) NOT NULL DEFAULT 0,
+	unapplied_amount DECIMAL(15,2) NOT NULL,
+	currency_code VARCHAR(3) NOT NULL,
+	exchange_rate DECIMAL(18,8) NOT NULL,
+	base_amount DECIMAL(15,2) NOT NULL,
+	status VARCHAR(20) NOT NULL,
+	notes TEXT,
+	created_by_id INTEGER NOT NULL REFERENCES users(id),
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_prepayments_prepayment_number ON prepayments(prepayment_number);
+CREATE INDEX IF NOT EXISTS idx_prepayments_order_id ON prepayments(order_id);
+CREATE INDEX IF NOT EXISTS idx_prepayments_entity_id ON prepayments(entity_id);
+CREATE INDEX IF NOT EXISTS idx_prepayments_status ON prepayments(status);
+-- Create prepayment_applications table, the parts of prepayments applied
+-- to invoices
+CREATE TABLE IF NOT EXISTS prepayment_applications (
+	id SERIAL PRIMARY KEY,
+	prepayment_id INTEGER NOT NULL REFERENCES prepayments(id) ON DELETE CASCADE,
+	invoice_type VARCHAR(20) NOT NULL,
+	invoice_id VARCHAR(64) NOT NULL,
+	invoice_number VARCHAR(50) NOT NULL,
+	amount DECIMAL(15,2) NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_prepayment_applications_prepayment_id ON prepayment_applications(prepayment_id);
+CREATE INDEX IF NOT EXISTS idx_prepayment_applications_invoice ON prepayment_applications(invoice_type, invoice_id);
+ALTER TABLE invoices
+	ADD COLUMN IF NOT EXISTS prepaid_amount DECIMAL(15, 2) NOT NULL DEFAULT 0,
+	ADD COLUMN IF NOT EXISTS amount_due DECIMAL(15, 2) NOT NULL DEFAULT 0;
+UPDATE invoices SET amount_due = total_amount WHERE status NOT IN ('PAID', 'CANCELLED');
+ALTER TABLE finance_invoices
+	ADD COLUMN IF NOT EXISTS prepaid_amount DECIMAL(15, 2) NOT NULL DEFAULT 0;
@@ -0,0 +1,5 @@
+-- Drop the fulfillment SLA alerts and the ship time of deliveries
+DROP TABLE IF EXISTS fulfillment_sla_alerts;
+
+DROP INDEX IF EXISTS idx_delivery_orders_shipped_at;
+ALTER TABLE delivery_orders DROP COLUMN IF EXISTS shipped_at;
@@ -0,0 +1,25 @@
+-- Record when deliveries ship, for the order-to-ship lead time. Deliveries
+-- shipped before take the time of their last update.
+ALTER TABLE delivery_orders ADD COLUMN IF NOT EXISTS shipped_at TIMESTAMP;
+UPDATE delivery_orders SET shipped_at = updated_at
+WHERE shipped_at IS NULL AND status IN ('IN_TRANSIT', 'DELIVERED', 'RETURNED');
+CREATE INDEX IF NOT EXISTS idx_delivery_orders_shipped_at ON delivery_orders(shipped_at);
+
+-- Create fulfillment_sla_alerts table, the breaches of the fulfillment SLA
+-- by warehouse. Stores have no foreign key, as drop-ship deliveries have none.
+CREATE TABLE IF NOT EXISTS fulfillment_sla_alerts (
+	id SERIAL PRIMARY KEY,
+	store_id VARCHAR(64),
+	store_name VARCHAR(255),
+	metric VARCHAR(20) NOT NULL,
+	value DECIMAL(10,2) NOT NULL,
+	threshold DECIMAL(10,2) NOT NULL,
+	period_start TIMESTAMP,
+	period_end TIMESTAMP,
+	resolved_at TIMESTAMP,
+	acknowledged_at TIMESTAMP,
+	acknowledged_by INTEGER REFERENCES users(id),
+	created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_fulfillment_sla_alerts_store_id ON fulfillment_sla_alerts(store_id);
+CREATE INDEX IF NOT EXISTS idx_fulfillment_sla_alerts_resolved_at ON fulfillment_sla_alerts(resolved_at);
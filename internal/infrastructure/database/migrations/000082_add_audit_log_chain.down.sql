@@ -0,0 +1,7 @@
+-- Drop the audit log archives and the hash chain of audit logs
+DROP TABLE IF EXISTS audit_log_archives;
+
+ALTER TABLE IF EXISTS archive_audit_logs DROP COLUMN IF EXISTS hash;
+ALTER TABLE IF EXISTS archive_audit_logs DROP COLUMN IF EXISTS prev_hash;
+ALTER TABLE audit_logs DROP COLUMN IF EXISTS hash;
+ALTER TABLE audit_logs DROP COLUMN IF EXISTS prev_hash;
@@ -0,0 +1,21 @@
+-- Chain audit logs by hash for tamper evidence. Logs written before have no
+-- hash and cannot be verified.
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS prev_hash VARCHAR(64);
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS hash VARCHAR(64);
+
+-- Create audit_log_archives table, the audit logs past their retention
+-- exported to the file store and removed from the database
+CREATE TABLE IF NOT EXISTS audit_log_archives (
+	id SERIAL PRIMARY KEY,
+	file_key TEXT NOT NULL,
+	checksum VARCHAR(64) NOT NULL,
+	cutoff TIMESTAMPTZ NOT NULL,
+	first_id BIGINT NOT NULL,
+	last_id BIGINT NOT NULL,
+	first_created_at TIMESTAMPTZ,
+	last_created_at TIMESTAMPTZ,
+	rows BIGINT NOT NULL,
+	last_hash VARCHAR(64),
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_audit_log_archives_last_id ON audit_log_archives(last_id);
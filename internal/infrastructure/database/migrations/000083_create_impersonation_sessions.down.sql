@@ -0,0 +1,6 @@
+-- Drop the impersonator of audit logs and the impersonation sessions
+DROP INDEX IF EXISTS idx_audit_logs_impersonator_id;
+ALTER TABLE IF EXISTS archive_audit_logs DROP COLUMN IF EXISTS impersonator_id;
+ALTER TABLE audit_logs DROP COLUMN IF EXISTS impersonator_id;
+
+DROP TABLE IF EXISTS impersonation_sessions;
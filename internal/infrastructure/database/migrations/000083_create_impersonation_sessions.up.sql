@@ -0,0 +1,22 @@
+-- Create impersonation_sessions table, the time-boxed sessions in which admins
+-- act as other users for troubleshooting
+CREATE TABLE IF NOT EXISTS impersonation_sessions (
+	id SERIAL PRIMARY KEY,
+	impersonator_id INTEGER NOT NULL REFERENCES users(id),
+	user_id INTEGER NOT NULL REFERENCES users(id),
+	reason TEXT NOT NULL,
+	duration_minutes INTEGER NOT NULL,
+	ip VARCHAR(45),
+	started_at TIMESTAMP NOT NULL,
+	expires_at TIMESTAMP NOT NULL,
+	ended_at TIMESTAMP,
+	ended_by INTEGER REFERENCES users(id),
+	created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_impersonation_sessions_impersonator_id ON impersonation_sessions(impersonator_id);
+CREATE INDEX IF NOT EXISTS idx_impersonation_sessions_user_id ON impersonation_sessions(user_id);
+CREATE INDEX IF NOT EXISTS idx_impersonation_sessions_started_at ON impersonation_sessions(started_at);
+
+-- Record the admin behind the requests made in a session with the user
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS impersonator_id INTEGER;
+CREATE INDEX IF NOT EXISTS idx_audit_logs_impersonator_id ON audit_logs(impersonator_id);
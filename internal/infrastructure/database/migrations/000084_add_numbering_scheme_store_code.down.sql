@@ -0,0 +1,2 @@
+-- Drop the store codes of numbering schemes
+ALTER TABLE numbering_schemes DROP COLUMN IF EXISTS store_code;
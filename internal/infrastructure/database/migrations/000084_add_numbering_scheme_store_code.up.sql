@@ -0,0 +1,3 @@
+-- Keep the code of the store a numbering scheme belongs to, rendered by the
+-- {store} placeholder so that the numbers of stores differ
+ALTER TABLE numbering_schemes ADD COLUMN IF NOT EXISTS store_code VARCHAR(50) NOT NULL DEFAULT '';
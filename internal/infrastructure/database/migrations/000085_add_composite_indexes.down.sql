@@ -0,0 +1,9 @@
+-- Drop the composite indexes of the hot list and report filters
+DROP INDEX IF EXISTS idx_stock_entries_sku_store;
+DROP INDEX IF EXISTS idx_stocks_sku_store;
+DROP INDEX IF EXISTS idx_finance_payments_entity;
+DROP INDEX IF EXISTS idx_finance_invoices_entity;
+DROP INDEX IF EXISTS idx_finance_invoices_due_date_status;
+DROP INDEX IF EXISTS idx_invoices_due_date_status;
+DROP INDEX IF EXISTS idx_purchase_orders_order_date_status;
+DROP INDEX IF EXISTS idx_sales_orders_order_date_status;
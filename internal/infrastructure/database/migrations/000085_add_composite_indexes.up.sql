@@ -0,0 +1,10 @@
+-- Composite indexes of the hot list and report filters. Databases migrated
+-- before they had a migration of their own have them already.
+CREATE INDEX IF NOT EXISTS idx_sales_orders_order_date_status ON sales_orders(order_date, status);
+CREATE INDEX IF NOT EXISTS idx_purchase_orders_order_date_status ON purchase_orders(order_date, status);
+CREATE INDEX IF NOT EXISTS idx_invoices_due_date_status ON invoices(due_date, status);
+CREATE INDEX IF NOT EXISTS idx_finance_invoices_due_date_status ON finance_invoices(due_date, status);
+CREATE INDEX IF NOT EXISTS idx_finance_invoices_entity ON finance_invoices(entity_id, entity_type);
+CREATE INDEX IF NOT EXISTS idx_finance_payments_entity ON finance_payments(entity_id, entity_type);
+CREATE INDEX IF NOT EXISTS idx_stocks_sku_store ON stocks(sku_id, store_id);
+CREATE INDEX IF NOT EXISTS idx_stock_entries_sku_store ON stock_entries(sku_id, store_id);
@@ -0,0 +1,156 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/config"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// ErrNoReplica is returned when checking the lag of a database without a read replica
+var ErrNoReplica = errors.New("no read replica is configured")
+
+type replicaContextKey struct{}
+
+// WithReplica marks the context so the read-only queries made with it may run
+// on the read replica
+func WithReplica(ctx context.Context) context.Context {
+	return context.WithValue(ctx, replicaContextKey{}, true)
+}
+
+// IsReplica reports whether the context is marked for the read replica
+func IsReplica(ctx context.Context) bool {
+	replica, _ := ctx.Value(replicaContextKey{}).(bool)
+	return replica
+}
+
+// replicaLagQuery returns how far in seconds the replica's replay is behind
+// the primary. A replica that replayed everything it received is not behind,
+// however long ago the primary last wrote.
+const replicaLagQuery = `
+	SELECT CASE
+		WHEN NOT pg_is_in_recovery() OR pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+		ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+	END`
+
+// replicaConnPool routes the SELECT queries whose context is marked with
+// WithReplica to the read replica while it is reachable and not lagging, and
+// everything else, transactions included, to the primary pool. A query the
+// replica fails to answer is retried on the primary.
+type replicaConnPool struct {
+	primary gorm.ConnPool
+	replica *sql.DB
+	maxLag  time.Duration
+	healthy atomic.Bool
+}
+
+// useReplica reports whether a query may run on the replica
+func (p *replicaConnPool) useReplica(ctx context.Context, query string) bool {
+	if !IsReplica(ctx) || IsSandbox(ctx) || !p.healthy.Load() {
+		return false
+	}
+	query = strings.ToUpper(strings.TrimSpace(query))
+	return strings.HasPrefix(query, "SELECT") && !strings.Contains(query, " FOR UPDATE") && !strings.Contains(query, " FOR SHARE")
+}
+
+func (p *replicaConnPool) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return p.primary.PrepareContext(ctx, query)
+}
+
+func (p *replicaConnPool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return p.primary.ExecContext(ctx, query, args...)
+}
+
+func (p *replicaConnPool) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if p.useReplica(ctx, query) {
+		rows, err := p.replica.QueryContext(ctx, query, args...)
+		if err == nil || ctx.Err() != nil {
+			return rows, err
+		}
+		p.healthy.Store(false)
+	}
+	return p.primary.QueryContext(ctx, query, args...)
+}
+
+func (p *replicaConnPool) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	if p.useReplica(ctx, query) {
+		return p.replica.QueryRowContext(ctx, query, args...)
+	}
+	return p.primary.QueryRowContext(ctx, query, args...)
+}
+
+func (p *replicaConnPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	beginner, ok := p.primary.(gorm.TxBeginner)
+	if !ok {
+		return nil, gorm.ErrInvalidTransaction
+	}
+	return beginner.BeginTx(ctx, opts)
+}
+
+// GetDBConn returns the primary pool so pool configuration and stats keep working
+func (p *replicaConnPool) GetDBConn() (*sql.DB, error) {
+	if db, ok := p.primary.(*sql.DB); ok {
+		return db, nil
+	}
+	if connector, ok := p.primary.(gorm.GetDBConnector); ok {
+		return connector.GetDBConn()
+	}
+	return nil, gorm.ErrInvalidDB
+}
+
+// check measures the replica lag and routes reads to the replica only while
+// it answers and is at most the configured lag behind
+func (p *replicaConnPool) check(ctx context.Context) (time.Duration, error) {
+	var seconds float64
+	if err := p.replica.QueryRowContext(ctx, replicaLagQuery).Scan(&seconds); err != nil {
+		p.healthy.Store(false)
+		return 0, err
+	}
+	lag := time.Duration(seconds * float64(time.Second))
+	p.healthy.Store(lag <= p.maxLag)
+	return lag, nil
+}
+
+// enableReplica installs the replica-aware connection pool on db, on top of
+// the sandbox one when sandbox mode is on. Reads stay on the primary until the
+// first lag check passes, so a replica down at startup does not stop the server.
+func enableReplica(db *gorm.DB, cfg *config.Config) error {
+	replicaDB, err := gorm.Open(postgres.Open(cfg.Database.ReplicaDSN), &gorm.Config{DisableAutomaticPing: true})
+	if err != nil {
+		return fmt.Errorf("failed to connect to read replica: %w", err)
+	}
+	replicaSQL, err := replicaDB.DB()
+	if err != nil {
+		return fmt.Errorf("failed to get read replica handle: %w", err)
+	}
+	replicaSQL.SetMaxOpenConns(cfg.Database.MaxOpenConns)
+	replicaSQL.SetMaxIdleConns(cfg.Database.MaxIdleConns)
+	replicaSQL.SetConnMaxLifetime(time.Duration(cfg.Database.ConnMaxLifetime) * time.Second)
+	replicaSQL.SetConnMaxIdleTime(time.Duration(cfg.Database.ConnMaxIdleTime) * time.Second)
+
+	pool := &replicaConnPool{
+		primary: db.ConnPool,
+		replica: replicaSQL,
+		maxLag:  time.Duration(cfg.Database.ReplicaMaxLag) * time.Second,
+	}
+	db.ConnPool = pool
+	db.Statement.ConnPool = pool
+	return nil
+}
+
+// CheckReplica measures the lag of the read replica of db and routes the
+// marked reads to it only while it answers within the configured lag
+func CheckReplica(ctx context.Context, db *gorm.DB) (time.Duration, error) {
+	pool, ok := db.ConnPool.(*replicaConnPool)
+	if !ok {
+		return 0, ErrNoReplica
+	}
+	return pool.check(ctx)
+}
@@ -0,0 +1,101 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/config"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+type sandboxContextKey struct{}
+
+// WithSandbox marks the context so database calls made with it run against the sandbox schema
+func WithSandbox(ctx context.Context) context.Context {
+	return context.WithValue(ctx, sandboxContextKey{}, true)
+}
+
+// IsSandbox reports whether the context is marked for the sandbox schema
+func IsSandbox(ctx context.Context) bool {
+	sandbox, _ := ctx.Value(sandboxContextKey{}).(bool)
+	return sandbox
+}
+
+// schemaNamePattern matches schema names that are safe to interpolate into SQL
+var schemaNamePattern = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// sandboxConnPool routes statements and transactions to the sandbox connection pool
+// when their context is marked with WithSandbox, and to the primary pool otherwise.
+// Every gorm call carries the request context, so repositories need no changes.
+type sandboxConnPool struct {
+	primary *sql.DB
+	sandbox *sql.DB
+}
+
+func (p *sandboxConnPool) pool(ctx context.Context) *sql.DB {
+	if IsSandbox(ctx) {
+		return p.sandbox
+	}
+	return p.primary
+}
+
+func (p *sandboxConnPool) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return p.pool(ctx).PrepareContext(ctx, query)
+}
+
+func (p *sandboxConnPool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return p.pool(ctx).ExecContext(ctx, query, args...)
+}
+
+func (p *sandboxConnPool) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return p.pool(ctx).QueryContext(ctx, query, args...)
+}
+
+func (p *sandboxConnPool) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return p.pool(ctx).QueryRowContext(ctx, query, args...)
+}
+
+func (p *sandboxConnPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	return p.pool(ctx).BeginTx(ctx, opts)
+}
+
+// GetDBConn returns the primary pool so pool configuration and stats keep working
+func (p *sandboxConnPool) GetDBConn() (*sql.DB, error) {
+	return p.primary, nil
+}
+
+// enableSandbox installs the context-aware connection pool on db. The sandbox pool
+// connects with its search_path set to the sandbox schema, which is provisioned by
+// the sandbox repository.
+func enableSandbox(db *gorm.DB, dsn string, cfg *config.Config) error {
+	schema := cfg.Sandbox.Schema
+	if !schemaNamePattern.MatchString(schema) || schema == "public" {
+		return fmt.Errorf("invalid sandbox schema name %q", schema)
+	}
+
+	sandboxDB, err := gorm.Open(postgres.Open(fmt.Sprintf("%s search_path=%s", dsn, schema)), &gorm.Config{})
+	if err != nil {
+		return fmt.Errorf("failed to connect to sandbox schema: %w", err)
+	}
+	sandboxSQL, err := sandboxDB.DB()
+	if err != nil {
+		return fmt.Errorf("failed to get sandbox database handle: %w", err)
+	}
+	sandboxSQL.SetMaxOpenConns(cfg.Sandbox.MaxOpenConns)
+	sandboxSQL.SetMaxIdleConns(cfg.Sandbox.MaxOpenConns)
+	sandboxSQL.SetConnMaxLifetime(time.Duration(cfg.Database.ConnMaxLifetime) * time.Second)
+	sandboxSQL.SetConnMaxIdleTime(time.Duration(cfg.Database.ConnMaxIdleTime) * time.Second)
+	primarySQL, err := db.DB()
+	if err != nil {
+		return fmt.Errorf("failed to get database handle: %w", err)
+	}
+
+	pool := &sandboxConnPool{primary: primarySQL, sandbox: sandboxSQL}
+	db.ConnPool = pool
+	db.Statement.ConnPool = pool
+	return nil
+}
@@ -0,0 +1,87 @@
+// Package ecommerce adapts the APIs of e-commerce platforms. A connector
+// fetches the orders of one sales channel and pushes inventory levels back to
+// it in the platform's own format, so the sales channel use cases never see
+// it. A platform is added by writing its connector and naming it in
+// NewConnector.
+package ecommerce
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+)
+
+// Platforms with a connector
+const (
+	PlatformShopify     = "SHOPIFY"
+	PlatformWooCommerce = "WOOCOMMERCE"
+)
+
+// requestTimeout bounds the time spent on one request to a platform
+const requestTimeout = 30 * time.Second
+
+var (
+	ErrUnknownPlatform = errors.New("unknown e-commerce platform")
+	ErrCredentials     = errors.New("missing channel credentials")
+	ErrListing         = errors.New("listing has no external ID")
+)
+
+// Connector exchanges orders and inventory levels with one sales channel
+type Connector interface {
+	// Platform returns the platform the connector talks to
+	Platform() string
+	// FetchOrders returns the orders created since the given time, or all of
+	// them without one, oldest first. Cancelled orders are left out.
+	FetchOrders(ctx context.Context, since *time.Time) ([]entity.ChannelOrder, error)
+	// PushInventory sets the available quantity of a listing at a location
+	PushInventory(ctx context.Context, level entity.ChannelInventoryLevel) error
+}
+
+// NewConnector returns the connector of a sales channel's platform, signing in
+// with the channel's credentials
+func NewConnector(channel *entity.SalesChannel) (Connector, error) {
+	client := &http.Client{Timeout: requestTimeout}
+	baseURL := strings.TrimRight(channel.BaseURL, "/")
+
+	switch strings.ToUpper(channel.Platform) {
+	case PlatformShopify:
+		if channel.AccessToken == "" {
+			return nil, fmt.Errorf("%w: %s needs an access token", ErrCredentials, PlatformShopify)
+		}
+		return NewShopifyConnector(baseURL, channel.AccessToken, client), nil
+	case PlatformWooCommerce:
+		if channel.APIKey == "" || channel.APISecret == "" {
+			return nil, fmt.Errorf("%w: %s needs an API key and secret", ErrCredentials, PlatformWooCommerce)
+		}
+		return NewWooCommerceConnector(baseURL, channel.APIKey, channel.APISecret, client), nil
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnknownPlatform, channel.Platform)
+	}
+}
+
+// checkResponse returns an error for responses outside the 2xx range, quoting
+// the start of the body the platform explained it with
+func checkResponse(platform string, resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("%s responded with status %d: %s", platform, resp.StatusCode, strings.TrimSpace(string(body)))
+}
+
+// joinAddress joins the non-empty parts of an address with commas
+func joinAddress(parts ...string) string {
+	kept := parts[:0]
+	for _, part := range parts {
+		if part = strings.TrimSpace(part); part != "" {
+			kept = append(kept, part)
+		}
+	}
+	return strings.Join(kept, ", ")
+}
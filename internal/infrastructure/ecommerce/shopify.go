@@ -0,0 +1,201 @@
+package ecommerce
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+)
+
+// shopifyAPIVersion is the Admin REST API version the connector speaks
+const shopifyAPIVersion = "2024-04"
+
+// shopifyNextLink finds the next page in a Link header
+var shopifyNextLink = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+// ShopifyConnector talks to the Admin REST API of a Shopify shop with an
+// access token of a custom app. Listings are identified by the inventory item
+// of their variant and locations by their numeric ID.
+type ShopifyConnector struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+// NewShopifyConnector creates a connector to the shop at the given address
+func NewShopifyConnector(baseURL, token string, client *http.Client) *ShopifyConnector {
+	return &ShopifyConnector{
+		baseURL: baseURL,
+		token:   token,
+		client:  client,
+	}
+}
+
+type shopifyAddress struct {
+	Name     string `json:"name"`
+	Address1 string `json:"address1"`
+	Address2 string `json:"address2"`
+	City     string `json:"city"`
+	Province string `json:"province"`
+	Zip      string `json:"zip"`
+	Country  string `json:"country"`
+}
+
+func (a *shopifyAddress) String() string {
+	if a == nil {
+		return ""
+	}
+	return joinAddress(a.Name, a.Address1, a.Address2, a.City, a.Province, a.Zip, a.Country)
+}
+
+type shopifyOrder struct {
+	ID              int64           `json:"id"`
+	Name            string          `json:"name"`
+	CreatedAt       time.Time       `json:"created_at"`
+	CancelledAt     *time.Time      `json:"cancelled_at"`
+	Currency        string          `json:"currency"`
+	Note            string          `json:"note"`
+	LocationID      *int64          `json:"location_id"`
+	ShippingAddress *shopifyAddress `json:"shipping_address"`
+	BillingAddress  *shopifyAddress `json:"billing_address"`
+	LineItems       []struct {
+		SKU           string `json:"sku"`
+		Title         string `json:"title"`
+		Quantity      int    `json:"quantity"`
+		Price         string `json:"price"`
+		TotalDiscount string `json:"total_discount"`
+	} `json:"line_items"`
+}
+
+// Platform returns SHOPIFY
+func (c *ShopifyConnector) Platform() string {
+	return PlatformShopify
+}
+
+// FetchOrders pages through the shop's orders created since the given time
+func (c *ShopifyConnector) FetchOrders(ctx context.Context, since *time.Time) ([]entity.ChannelOrder, error) {
+	query := url.Values{"status": {"any"}, "limit": {"250"}, "order": {"created_at asc"}}
+	if since != nil {
+		query.Set("created_at_min", since.UTC().Format(time.RFC3339))
+	}
+	next := c.baseURL + "/admin/api/" + shopifyAPIVersion + "/orders.json?" + query.Encode()
+
+	var orders []entity.ChannelOrder
+	for next != "" {
+		var page struct {
+			Orders []shopifyOrder `json:"orders"`
+		}
+		link, err := c.do(ctx, http.MethodGet, next, nil, &page)
+		if err != nil {
+			return nil, err
+		}
+		for _, raw := range page.Orders {
+			if raw.CancelledAt != nil {
+				continue
+			}
+			orders = append(orders, raw.normalize())
+		}
+
+		next = ""
+		if match := shopifyNextLink.FindStringSubmatch(link); match != nil {
+			next = match[1]
+		}
+	}
+	return orders, nil
+}
+
+// normalize converts a Shopify order to a channel order
+func (o *shopifyOrder) normalize() entity.ChannelOrder {
+	order := entity.ChannelOrder{
+		Reference:       strconv.FormatInt(o.ID, 10),
+		Number:          o.Name,
+		CreatedAt:       o.CreatedAt,
+		CurrencyCode:    o.Currency,
+		ShippingAddress: o.ShippingAddress.String(),
+		BillingAddress:  o.BillingAddress.String(),
+		Notes:           o.Note,
+	}
+	if o.LocationID != nil {
+		order.LocationID = strconv.FormatInt(*o.LocationID, 10)
+	}
+	for _, item := range o.LineItems {
+		price, _ := strconv.ParseFloat(item.Price, 64)
+		discount, _ := strconv.ParseFloat(item.TotalDiscount, 64)
+		order.Lines = append(order.Lines, entity.ChannelOrderLine{
+			ExternalSKU: item.SKU,
+			Title:       item.Title,
+			Quantity:    float64(item.Quantity),
+			UnitPrice:   price,
+			Discount:    discount,
+		})
+	}
+	return order
+}
+
+// PushInventory sets the available quantity of an inventory item at a
+// location, rounded down to whole units
+func (c *ShopifyConnector) PushInventory(ctx context.Context, level entity.ChannelInventoryLevel) error {
+	itemID, err := strconv.ParseInt(level.ExternalID, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: %s needs the inventory item ID of %s", ErrListing, PlatformShopify, level.ExternalSKU)
+	}
+	locationID, err := strconv.ParseInt(level.LocationID, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid %s location ID %q", PlatformShopify, level.LocationID)
+	}
+
+	body := map[string]interface{}{
+		"location_id":       locationID,
+		"inventory_item_id": itemID,
+		"available":         int64(math.Max(0, math.Floor(level.Available))),
+	}
+	_, err = c.do(ctx, http.MethodPost, c.baseURL+"/admin/api/"+shopifyAPIVersion+"/inventory_levels/set.json", body, nil)
+	return err
+}
+
+// do sends a request to the shop, decoding the response into out when given,
+// and returns the Link header of the response
+func (c *ShopifyConnector) do(ctx context.Context, method, endpoint string, in, out interface{}) (string, error) {
+	payload := []byte(nil)
+	if in != nil {
+		encoded, err := json.Marshal(in)
+		if err != nil {
+			return "", err
+		}
+		payload = encoded
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Shopify-Access-Token", c.token)
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error calling %s: %w", PlatformShopify, err)
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse(PlatformShopify, resp); err != nil {
+		return "", err
+	}
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return "", fmt.Errorf("error decoding %s response: %w", PlatformShopify, err)
+		}
+	}
+	return resp.Header.Get("Link"), nil
+}
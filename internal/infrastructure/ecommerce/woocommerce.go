@@ -0,0 +1,217 @@
+package ecommerce
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+)
+
+// wooCommercePageSize is the number of orders fetched per page, the most the
+// REST API returns
+const wooCommercePageSize = 100
+
+// wooCommerceTimeLayout is the layout of the GMT times of the REST API
+const wooCommerceTimeLayout = "2006-01-02T15:04:05"
+
+// wooCommerceSkipped are the order statuses left out of the orders fetched
+var wooCommerceSkipped = map[string]bool{
+	"cancelled":      true,
+	"failed":         true,
+	"refunded":       true,
+	"trash":          true,
+	"checkout-draft": true,
+}
+
+// WooCommerceConnector talks to the REST API of a WooCommerce shop with a
+// consumer key pair, sent as basic authentication over HTTPS. The shop has a
+// single location. Listings are identified by their product ID, or by
+// "<product>/<variation>" for the variations of variable products.
+type WooCommerceConnector struct {
+	baseURL string
+	key     string
+	secret  string
+	client  *http.Client
+}
+
+// NewWooCommerceConnector creates a connector to the shop at the given address
+func NewWooCommerceConnector(baseURL, key, secret string, client *http.Client) *WooCommerceConnector {
+	return &WooCommerceConnector{
+		baseURL: baseURL,
+		key:     key,
+		secret:  secret,
+		client:  client,
+	}
+}
+
+type wooCommerceAddress struct {
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Company   string `json:"company"`
+	Address1  string `json:"address_1"`
+	Address2  string `json:"address_2"`
+	City      string `json:"city"`
+	State     string `json:"state"`
+	Postcode  string `json:"postcode"`
+	Country   string `json:"country"`
+}
+
+func (a *wooCommerceAddress) String() string {
+	return joinAddress(strings.TrimSpace(a.FirstName+" "+a.LastName), a.Company, a.Address1, a.Address2, a.City, a.State, a.Postcode, a.Country)
+}
+
+type wooCommerceOrder struct {
+	ID           int64              `json:"id"`
+	Number       string             `json:"number"`
+	Status       string             `json:"status"`
+	Currency     string             `json:"currency"`
+	DateCreated  string             `json:"date_created_gmt"`
+	CustomerNote string             `json:"customer_note"`
+	Billing      wooCommerceAddress `json:"billing"`
+	Shipping     wooCommerceAddress `json:"shipping"`
+	LineItems    []struct {
+		SKU      string  `json:"sku"`
+		Name     string  `json:"name"`
+		Quantity float64 `json:"quantity"`
+		Subtotal string  `json:"subtotal"`
+		Total    string  `json:"total"`
+	} `json:"line_items"`
+}
+
+// Platform returns WOOCOMMERCE
+func (c *WooCommerceConnector) Platform() string {
+	return PlatformWooCommerce
+}
+
+// FetchOrders pages through the shop's orders created since the given time
+func (c *WooCommerceConnector) FetchOrders(ctx context.Context, since *time.Time) ([]entity.ChannelOrder, error) {
+	var orders []entity.ChannelOrder
+	for page := 1; ; page++ {
+		query := url.Values{
+			"per_page": {strconv.Itoa(wooCommercePageSize)},
+			"page":     {strconv.Itoa(page)},
+			"orderby":  {"date"},
+			"order":    {"asc"},
+		}
+		if since != nil {
+			query.Set("after", since.UTC().Format(wooCommerceTimeLayout))
+			query.Set("dates_are_gmt", "true")
+		}
+
+		var raw []wooCommerceOrder
+		if err := c.do(ctx, http.MethodGet, c.baseURL+"/wp-json/wc/v3/orders?"+query.Encode(), nil, &raw); err != nil {
+			return nil, err
+		}
+		for _, order := range raw {
+			if wooCommerceSkipped[order.Status] {
+				continue
+			}
+			normalized, err := order.normalize()
+			if err != nil {
+				return nil, err
+			}
+			orders = append(orders, normalized)
+		}
+		if len(raw) < wooCommercePageSize {
+			return orders, nil
+		}
+	}
+}
+
+// normalize converts a WooCommerce order to a channel order
+func (o *wooCommerceOrder) normalize() (entity.ChannelOrder, error) {
+	createdAt, err := time.Parse(wooCommerceTimeLayout, o.DateCreated)
+	if err != nil {
+		return entity.ChannelOrder{}, fmt.Errorf("error parsing %s order %d creation time: %w", PlatformWooCommerce, o.ID, err)
+	}
+
+	order := entity.ChannelOrder{
+		Reference:       strconv.FormatInt(o.ID, 10),
+		Number:          o.Number,
+		CreatedAt:       createdAt,
+		CurrencyCode:    o.Currency,
+		ShippingAddress: o.Shipping.String(),
+		BillingAddress:  o.Billing.String(),
+		Notes:           o.CustomerNote,
+	}
+	for _, item := range o.LineItems {
+		if item.Quantity <= 0 {
+			continue
+		}
+		subtotal, _ := strconv.ParseFloat(item.Subtotal, 64)
+		total, _ := strconv.ParseFloat(item.Total, 64)
+		order.Lines = append(order.Lines, entity.ChannelOrderLine{
+			ExternalSKU: item.SKU,
+			Title:       item.Name,
+			Quantity:    item.Quantity,
+			UnitPrice:   subtotal / item.Quantity,
+			Discount:    math.Max(0, subtotal-total),
+		})
+	}
+	return order, nil
+}
+
+// PushInventory sets the stock quantity of a product or variation, rounded
+// down to whole units. The location is ignored.
+func (c *WooCommerceConnector) PushInventory(ctx context.Context, level entity.ChannelInventoryLevel) error {
+	if level.ExternalID == "" {
+		return fmt.Errorf("%w: %s needs the product ID of %s", ErrListing, PlatformWooCommerce, level.ExternalSKU)
+	}
+
+	endpoint := c.baseURL + "/wp-json/wc/v3/products/" + url.PathEscape(level.ExternalID)
+	if product, variation, ok := strings.Cut(level.ExternalID, "/"); ok {
+		endpoint = c.baseURL + "/wp-json/wc/v3/products/" + url.PathEscape(product) + "/variations/" + url.PathEscape(variation)
+	}
+
+	body := map[string]interface{}{
+		"manage_stock":   true,
+		"stock_quantity": int64(math.Max(0, math.Floor(level.Available))),
+	}
+	return c.do(ctx, http.MethodPut, endpoint, body, nil)
+}
+
+// do sends a request to the shop, decoding the response into out when given
+func (c *WooCommerceConnector) do(ctx context.Context, method, endpoint string, in, out interface{}) error {
+	payload := []byte(nil)
+	if in != nil {
+		encoded, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		payload = encoded
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(c.key, c.secret)
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error calling %s: %w", PlatformWooCommerce, err)
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse(PlatformWooCommerce, resp); err != nil {
+		return err
+	}
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return fmt.Errorf("error decoding %s response: %w", PlatformWooCommerce, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package edi
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+)
+
+// Invoice translates an 810 transaction set. Allowances taken off the invoice
+// add up to its allowance and charges reduce it; the total is the amount due
+// the vendor states in TDS.
+func Invoice(tx Transaction) (*entity.EDIInvoice, error) {
+	if tx.Type != entity.EDITypeInvoice {
+		return nil, fmt.Errorf("%w: %s is not an invoice", ErrTransaction, tx.Type)
+	}
+
+	invoice := &entity.EDIInvoice{}
+	var line *entity.EDIInvoiceLine
+	total := false
+	for _, segment := range tx.Segments {
+		switch segment.ID() {
+		case "BIG":
+			date, err := parseDate(segment.Element(1))
+			if err != nil {
+				return nil, fmt.Errorf("%w: BIG invoice date %q", ErrTransaction, segment.Element(1))
+			}
+			invoice.InvoiceDate = date
+			invoice.InvoiceNumber = segment.Element(2)
+			invoice.PurchaseOrderNumber = segment.Element(4)
+		case "CUR":
+			invoice.CurrencyCode = segment.Element(2)
+		case "ITD":
+			invoice.NetDays, _ = strconv.Atoi(segment.Element(7))
+		case "IT1":
+			quantity, err := parseNumber(segment.Element(2))
+			if err != nil {
+				return nil, fmt.Errorf("%w: IT1 quantity %q", ErrTransaction, segment.Element(2))
+			}
+			price, err := parseNumber(segment.Element(4))
+			if err != nil {
+				return nil, fmt.Errorf("%w: IT1 unit price %q", ErrTransaction, segment.Element(4))
+			}
+			invoice.Lines = append(invoice.Lines, entity.EDIInvoiceLine{
+				SKUCode:   productID(segment, 6),
+				Quantity:  quantity,
+				UnitPrice: price,
+			})
+			line = &invoice.Lines[len(invoice.Lines)-1]
+		case "PID":
+			if line != nil && line.Description == "" {
+				line.Description = segment.Element(5)
+			}
+		case "TXI":
+			amount, err := parseNumber(segment.Element(2))
+			if err != nil {
+				return nil, fmt.Errorf("%w: TXI amount %q", ErrTransaction, segment.Element(2))
+			}
+			invoice.TaxAmount += amount
+		case "SAC":
+			amount, err := parseImplied(segment.Element(5))
+			if err != nil {
+				return nil, fmt.Errorf("%w: SAC amount %q", ErrTransaction, segment.Element(5))
+			}
+			if segment.Element(1) == "C" {
+				amount = -amount
+			}
+			invoice.Allowance += amount
+		case "TDS":
+			amount, err := parseImplied(segment.Element(1))
+			if err != nil {
+				return nil, fmt.Errorf("%w: TDS amount %q", ErrTransaction, segment.Element(1))
+			}
+			invoice.Total = amount
+			total = true
+		}
+	}
+
+	if invoice.InvoiceNumber == "" {
+		return nil, fmt.Errorf("%w: BIG invoice number missing", ErrTransaction)
+	}
+	if invoice.PurchaseOrderNumber == "" {
+		return nil, fmt.Errorf("%w: invoice %s names no purchase order", ErrTransaction, invoice.InvoiceNumber)
+	}
+	if len(invoice.Lines) == 0 {
+		return nil, fmt.Errorf("%w: invoice %s has no lines", ErrTransaction, invoice.InvoiceNumber)
+	}
+	if !total {
+		return nil, fmt.Errorf("%w: invoice %s has no TDS total", ErrTransaction, invoice.InvoiceNumber)
+	}
+	return invoice, nil
+}
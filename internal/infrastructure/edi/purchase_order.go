@@ -0,0 +1,69 @@
+package edi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+)
+
+// addressLength is the longest address element of N3
+const addressLength = 55
+
+// PurchaseOrder translates a purchase order to an 850 transaction set. Items
+// are identified by the buyer's part number, the SKU code found in skuCodes by
+// SKU ID, and fall back to the SKU ID.
+func PurchaseOrder(order *entity.PurchaseOrder, skuCodes map[string]string) Transaction {
+	tx := Transaction{Type: entity.EDITypePurchaseOrder}
+	add := func(elements ...string) {
+		tx.Segments = append(tx.Segments, Segment(elements))
+	}
+
+	add("BEG", "00", "SA", order.OrderNumber, "", order.OrderDate.Format("20060102"))
+	if order.CurrencyCode != "" {
+		add("CUR", "BY", order.CurrencyCode)
+	}
+	if order.Vendor != nil {
+		add("REF", "VR", order.Vendor.Code)
+		if order.Vendor.PaymentDays > 0 {
+			add("ITD", "05", "3", "", "", "", "", strconv.Itoa(order.Vendor.PaymentDays))
+		}
+	}
+	if !order.ExpectedDate.IsZero() {
+		add("DTM", "002", order.ExpectedDate.Format("20060102"))
+	}
+	if order.ShippingMethod != "" {
+		add("TD5", "", "", "", "", order.ShippingMethod)
+	}
+	if address := strings.TrimSpace(order.ShippingAddress); address != "" {
+		name, street, _ := strings.Cut(address, ",")
+		add("N1", "ST", strings.TrimSpace(name))
+		if street = strings.TrimSpace(street); street != "" {
+			line2 := ""
+			if len(street) > addressLength {
+				street, line2 = street[:addressLength], street[addressLength:]
+				if len(line2) > addressLength {
+					line2 = line2[:addressLength]
+				}
+			}
+			add("N3", street, line2)
+		}
+	}
+
+	var quantity float64
+	for i, item := range order.Items {
+		code := skuCodes[item.SKUID]
+		if code == "" {
+			code = item.SKUID
+		}
+		add("PO1", strconv.Itoa(i+1), formatNumber(item.Quantity), "EA", formatNumber(item.UnitPrice), "", "BP", code)
+		if item.Description != "" {
+			add("PID", "F", "", "", "", item.Description)
+		}
+		quantity += item.Quantity
+	}
+	add("CTT", strconv.Itoa(len(order.Items)), formatNumber(quantity))
+	add("AMT", "TT", fmt.Sprintf("%.2f", order.GrandTotal))
+	return tx
+}
@@ -0,0 +1,99 @@
+package edi
+
+import (
+	"fmt"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+)
+
+// ShipNotice translates an 856 transaction set. The notice must ship the
+// goods of a single purchase order; its items are identified by the buyer's
+// part number, or the first product ID of their LIN segment without one.
+func ShipNotice(tx Transaction) (*entity.EDIShipNotice, error) {
+	if tx.Type != entity.EDITypeShipNotice {
+		return nil, fmt.Errorf("%w: %s is not a ship notice", ErrTransaction, tx.Type)
+	}
+
+	notice := &entity.EDIShipNotice{}
+	var line *entity.EDIShipNoticeLine
+	for _, segment := range tx.Segments {
+		switch segment.ID() {
+		case "BSN":
+			notice.ShipmentID = segment.Element(2)
+			if date, err := parseDate(segment.Element(3)); err == nil {
+				notice.ShipDate = &date
+			}
+		case "DTM":
+			date, err := parseDate(segment.Element(2))
+			if err != nil {
+				continue
+			}
+			switch segment.Element(1) {
+			case "011":
+				notice.ShipDate = &date
+			case "017", "067":
+				notice.DeliveryDate = &date
+			}
+		case "TD5":
+			if notice.Carrier = segment.Element(5); notice.Carrier == "" {
+				notice.Carrier = segment.Element(3)
+			}
+		case "REF":
+			switch segment.Element(1) {
+			case "BM", "CN":
+				if notice.TrackingNumber == "" {
+					notice.TrackingNumber = segment.Element(2)
+				}
+			}
+		case "PRF":
+			number := segment.Element(1)
+			if notice.PurchaseOrderNumber != "" && notice.PurchaseOrderNumber != number {
+				return nil, fmt.Errorf("%w: ship notice %s ships more than one purchase order", ErrTransaction, notice.ShipmentID)
+			}
+			notice.PurchaseOrderNumber = number
+		case "LIN":
+			notice.Lines = append(notice.Lines, entity.EDIShipNoticeLine{SKUCode: productID(segment, 2)})
+			line = &notice.Lines[len(notice.Lines)-1]
+		case "SN1":
+			if line == nil {
+				return nil, fmt.Errorf("%w: SN1 without LIN", ErrTransaction)
+			}
+			quantity, err := parseNumber(segment.Element(2))
+			if err != nil {
+				return nil, fmt.Errorf("%w: SN1 quantity %q", ErrTransaction, segment.Element(2))
+			}
+			line.Quantity += quantity
+			line.Unit = segment.Element(3)
+		}
+	}
+
+	if notice.ShipmentID == "" {
+		return nil, fmt.Errorf("%w: BSN shipment ID missing", ErrTransaction)
+	}
+	if notice.PurchaseOrderNumber == "" {
+		return nil, fmt.Errorf("%w: ship notice %s names no purchase order", ErrTransaction, notice.ShipmentID)
+	}
+	if len(notice.Lines) == 0 {
+		return nil, fmt.Errorf("%w: ship notice %s has no items", ErrTransaction, notice.ShipmentID)
+	}
+	return notice, nil
+}
+
+// productID returns the buyer's part number among the qualifier and ID pairs
+// of a segment starting at the given position, or the first ID without one
+func productID(segment Segment, from int) string {
+	first := ""
+	for i := from; i+1 < len(segment); i += 2 {
+		id := segment.Element(i + 1)
+		if id == "" {
+			continue
+		}
+		if segment.Element(i) == "BP" {
+			return id
+		}
+		if first == "" {
+			first = id
+		}
+	}
+	return first
+}
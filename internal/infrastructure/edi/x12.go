@@ -0,0 +1,287 @@
+// Package edi translates ANSI X12 EDI documents exchanged with trading
+// partners. It reads and writes the ISA/GS/ST envelopes of an interchange and
+// maps the 850 purchase order, 856 ship notice and 810 invoice transaction
+// sets to and from the purchase entities, so the EDI use cases never handle
+// segments themselves.
+package edi
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Envelope versions of the interchanges generated
+const (
+	interchangeVersion = "00401"
+	groupVersion       = "004010"
+)
+
+// Delimiters of the interchanges generated. Inbound interchanges declare
+// their own in the ISA segment.
+const (
+	elementSeparator   = '*'
+	componentSeparator = '>'
+	segmentTerminator  = '~'
+)
+
+// isaLength is the fixed length of an ISA segment, terminator included
+const isaLength = 106
+
+// Functional identifiers of the groups carrying each transaction set
+var functionalIDs = map[string]string{
+	"850": "PO",
+	"856": "SH",
+	"810": "IN",
+}
+
+var (
+	ErrMalformed   = errors.New("malformed X12 interchange")
+	ErrTransaction = errors.New("invalid X12 transaction set")
+)
+
+// Segment is an X12 segment, its ID followed by its elements
+type Segment []string
+
+// ID returns the segment ID, such as BEG
+func (s Segment) ID() string {
+	if len(s) == 0 {
+		return ""
+	}
+	return s[0]
+}
+
+// Element returns the element at the given position, counted from 1 as X12
+// does, or an empty string when the segment is shorter
+func (s Segment) Element(i int) string {
+	if i <= 0 || i >= len(s) {
+		return ""
+	}
+	return strings.TrimSpace(s[i])
+}
+
+// Transaction is a transaction set with the segments between its ST and SE
+type Transaction struct {
+	Type          string
+	ControlNumber string
+	Segments      []Segment
+}
+
+// Interchange is an X12 interchange with one functional group. Inbound
+// interchanges with several groups have their transaction sets joined.
+type Interchange struct {
+	SenderQualifier   string
+	SenderID          string
+	ReceiverQualifier string
+	ReceiverID        string
+	SenderGS          string
+	ReceiverGS        string
+	ControlNumber     int64
+	Date              time.Time
+	Test              bool
+	Transactions      []Transaction
+}
+
+// Parse reads an interchange, checking its envelopes are complete
+func Parse(data []byte) (*Interchange, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) < isaLength || string(data[:3]) != "ISA" {
+		return nil, fmt.Errorf("%w: it does not start with an ISA segment", ErrMalformed)
+	}
+	element := string(data[3])
+	terminator := string(data[isaLength-1])
+
+	ic := &Interchange{}
+	var group *Segment
+	var tx *Transaction
+	groups, transactions := 0, 0
+	for _, raw := range strings.Split(string(data), terminator) {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		segment := Segment(strings.Split(raw, element))
+
+		switch segment.ID() {
+		case "ISA":
+			if len(segment) != 17 {
+				return nil, fmt.Errorf("%w: ISA has %d elements", ErrMalformed, len(segment)-1)
+			}
+			ic.SenderQualifier = segment.Element(5)
+			ic.SenderID = segment.Element(6)
+			ic.ReceiverQualifier = segment.Element(7)
+			ic.ReceiverID = segment.Element(8)
+			ic.ControlNumber, _ = strconv.ParseInt(segment.Element(13), 10, 64)
+			ic.Date, _ = time.Parse("060102", segment.Element(9))
+			ic.Test = segment.Element(15) == "T"
+		case "GS":
+			if group != nil {
+				return nil, fmt.Errorf("%w: GS %s is not closed", ErrMalformed, group.Element(6))
+			}
+			group = &segment
+			groups++
+			transactions = 0
+			if ic.SenderGS == "" {
+				ic.SenderGS = segment.Element(2)
+				ic.ReceiverGS = segment.Element(3)
+			}
+		case "ST":
+			if group == nil || tx != nil {
+				return nil, fmt.Errorf("%w: ST %s is out of place", ErrMalformed, segment.Element(2))
+			}
+			tx = &Transaction{Type: segment.Element(1), ControlNumber: segment.Element(2)}
+		case "SE":
+			if tx == nil {
+				return nil, fmt.Errorf("%w: SE without ST", ErrMalformed)
+			}
+			if segment.Element(2) != tx.ControlNumber {
+				return nil, fmt.Errorf("%w: SE closes %s in ST %s", ErrMalformed, segment.Element(2), tx.ControlNumber)
+			}
+			if count, _ := strconv.Atoi(segment.Element(1)); count != len(tx.Segments)+2 {
+				return nil, fmt.Errorf("%w: ST %s has %d segments, SE counts %s", ErrMalformed, tx.ControlNumber, len(tx.Segments)+2, segment.Element(1))
+			}
+			ic.Transactions = append(ic.Transactions, *tx)
+			tx = nil
+			transactions++
+		case "GE":
+			if group == nil || tx != nil {
+				return nil, fmt.Errorf("%w: GE is out of place", ErrMalformed)
+			}
+			if count, _ := strconv.Atoi(segment.Element(1)); count != transactions {
+				return nil, fmt.Errorf("%w: GS %s has %d transaction sets, GE counts %s", ErrMalformed, group.Element(6), transactions, segment.Element(1))
+			}
+			group = nil
+		case "IEA":
+			if group != nil {
+				return nil, fmt.Errorf("%w: IEA closes an open group", ErrMalformed)
+			}
+			if count, _ := strconv.Atoi(segment.Element(1)); count != groups {
+				return nil, fmt.Errorf("%w: interchange has %d groups, IEA counts %s", ErrMalformed, groups, segment.Element(1))
+			}
+			return ic, nil
+		default:
+			if tx == nil {
+				return nil, fmt.Errorf("%w: %s segment outside a transaction set", ErrMalformed, segment.ID())
+			}
+			tx.Segments = append(tx.Segments, segment)
+		}
+	}
+	return nil, fmt.Errorf("%w: IEA segment missing", ErrMalformed)
+}
+
+// Encode writes an interchange with its transaction sets in one functional
+// group, numbering the group after the interchange and the transaction sets
+// from 0001. All transaction sets must be of the same type.
+func Encode(ic *Interchange) ([]byte, error) {
+	if len(ic.Transactions) == 0 {
+		return nil, fmt.Errorf("%w: interchange has no transaction sets", ErrTransaction)
+	}
+	functionalID, ok := functionalIDs[ic.Transactions[0].Type]
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported type %q", ErrTransaction, ic.Transactions[0].Type)
+	}
+
+	var buf bytes.Buffer
+	write := func(elements ...string) {
+		for i, value := range elements {
+			if i > 0 {
+				buf.WriteByte(elementSeparator)
+			}
+			buf.WriteString(value)
+		}
+		buf.WriteByte(segmentTerminator)
+		buf.WriteByte('\n')
+	}
+
+	usage := "P"
+	if ic.Test {
+		usage = "T"
+	}
+	control := fmt.Sprintf("%09d", ic.ControlNumber)
+	write("ISA", "00", pad("", 10), "00", pad("", 10),
+		pad(ic.SenderQualifier, 2), pad(ic.SenderID, 15),
+		pad(ic.ReceiverQualifier, 2), pad(ic.ReceiverID, 15),
+		ic.Date.Format("060102"), ic.Date.Format("1504"), "U", interchangeVersion,
+		control, "0", usage, string(componentSeparator))
+	write("GS", functionalID, clean(ic.SenderGS), clean(ic.ReceiverGS),
+		ic.Date.Format("20060102"), ic.Date.Format("1504"),
+		strconv.FormatInt(ic.ControlNumber, 10), "X", groupVersion)
+
+	for i, tx := range ic.Transactions {
+		if tx.Type != ic.Transactions[0].Type {
+			return nil, fmt.Errorf("%w: %s and %s in one group", ErrTransaction, ic.Transactions[0].Type, tx.Type)
+		}
+		number := fmt.Sprintf("%04d", i+1)
+		write("ST", tx.Type, number)
+		for _, segment := range tx.Segments {
+			write(trimTrailing(segment)...)
+		}
+		write("SE", strconv.Itoa(len(tx.Segments)+2), number)
+	}
+
+	write("GE", strconv.Itoa(len(ic.Transactions)), strconv.FormatInt(ic.ControlNumber, 10))
+	write("IEA", "1", control)
+	return buf.Bytes(), nil
+}
+
+// pad fits a value to the fixed width of an ISA element
+func pad(value string, width int) string {
+	value = clean(value)
+	if len(value) > width {
+		return value[:width]
+	}
+	return value + strings.Repeat(" ", width-len(value))
+}
+
+// clean replaces the delimiters in a value, which X12 cannot escape
+func clean(value string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case elementSeparator, componentSeparator, segmentTerminator, '\n', '\r':
+			return ' '
+		}
+		return r
+	}, strings.TrimSpace(value))
+}
+
+// trimTrailing drops the empty elements at the end of a segment and cleans
+// the others
+func trimTrailing(segment Segment) []string {
+	elements := make([]string, len(segment))
+	last := 0
+	for i, value := range segment {
+		elements[i] = clean(value)
+		if elements[i] != "" {
+			last = i
+		}
+	}
+	return elements[:last+1]
+}
+
+// formatNumber writes a decimal element without trailing zeros
+func formatNumber(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// parseNumber reads a decimal element, treating an empty one as zero
+func parseNumber(value string) (float64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.ParseFloat(value, 64)
+}
+
+// parseImplied reads a numeric element with two implied decimals, as used
+// for amounts in TDS and SAC
+func parseImplied(value string) (float64, error) {
+	n, err := parseNumber(value)
+	return n / 100, err
+}
+
+// parseDate reads a CCYYMMDD date element
+func parseDate(value string) (time.Time, error) {
+	return time.Parse("20060102", value)
+}
@@ -0,0 +1,63 @@
+// Package eventbus delivers the domain events published by the use cases to the
+// consumers subscribed to them, so reactions such as notifications, WebSocket
+// events and extension hooks are not wired into each use case.
+package eventbus
+
+import (
+	"context"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/logging"
+)
+
+// Handler reacts to a domain event. The event type depends on its name.
+type Handler func(ctx context.Context, event entity.DomainEvent) error
+
+type subscription struct {
+	consumer string
+	handler  Handler
+}
+
+// Bus routes domain events to their consumers. Consumers subscribe while the
+// server starts, before events are published. A nil *Bus is valid and
+// delivers nothing.
+type Bus struct {
+	handlers map[string][]subscription
+	all      []subscription
+}
+
+// New creates a bus without consumers
+func New() *Bus {
+	return &Bus{handlers: make(map[string][]subscription)}
+}
+
+// Subscribe attaches a consumer's handler to the named events, or to every
+// event when no name is given. Handlers run in the order they subscribed.
+func (b *Bus) Subscribe(consumer string, handler Handler, events ...string) {
+	sub := subscription{consumer: consumer, handler: handler}
+	if len(events) == 0 {
+		b.all = append(b.all, sub)
+		return
+	}
+	for _, event := range events {
+		b.handlers[event] = append(b.handlers[event], sub)
+	}
+}
+
+// Publish delivers an event to its consumers before returning, those
+// subscribed to every event first. The change behind the event is already
+// persisted, so consumer errors are logged rather than returned and every
+// consumer gets to run. Consumers may publish further events.
+func (b *Bus) Publish(ctx context.Context, event entity.DomainEvent) {
+	if b == nil {
+		return
+	}
+	name := event.EventName()
+	for _, subs := range [][]subscription{b.all, b.handlers[name]} {
+		for _, sub := range subs {
+			if err := sub.handler(ctx, event); err != nil {
+				logging.FromContext(ctx).Error("event consumer failed", "consumer", sub.consumer, "event", name, "error", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// csvWriter writes a header row of the column keys followed by the rows
+type csvWriter struct {
+	w      *csv.Writer
+	record []string
+}
+
+func newCSVWriter(w io.Writer, doc *Document) (*csvWriter, error) {
+	writer := &csvWriter{w: csv.NewWriter(w), record: make([]string, len(doc.Columns))}
+	for i, column := range doc.Columns {
+		writer.record[i] = column.Key
+	}
+	if err := writer.w.Write(writer.record); err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+func (w *csvWriter) WriteRow(values []interface{}) error {
+	for i := range w.record {
+		w.record[i] = ""
+		if i < len(values) {
+			w.record[i] = formatValue(values[i], -1)
+		}
+	}
+	return w.w.Write(w.record)
+}
+
+func (w *csvWriter) Close() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+// jsonWriter writes an array of objects keyed by the column keys
+type jsonWriter struct {
+	w       io.Writer
+	columns []Column
+	rows    int
+}
+
+func newJSONWriter(w io.Writer, doc *Document) (*jsonWriter, error) {
+	if _, err := io.WriteString(w, "["); err != nil {
+		return nil, err
+	}
+	return &jsonWriter{w: w, columns: doc.Columns}, nil
+}
+
+func (w *jsonWriter) WriteRow(values []interface{}) error {
+	object := make(map[string]interface{}, len(w.columns))
+	for i, column := range w.columns {
+		var value interface{}
+		if i < len(values) {
+			value = values[i]
+		}
+		switch v := value.(type) {
+		case time.Time:
+			value = v.Format(time.RFC3339)
+		case float64:
+			// JSON has no representation of infinities and NaN
+			if math.IsInf(v, 0) || math.IsNaN(v) {
+				value = nil
+			}
+		}
+		object[column.Key] = value
+	}
+	encoded, err := json.Marshal(object)
+	if err != nil {
+		return fmt.Errorf("error encoding row %d: %w", w.rows+1, err)
+	}
+
+	separator := ",\n"
+	if w.rows == 0 {
+		separator = "\n"
+	}
+	w.rows++
+	if _, err := io.WriteString(w.w, separator); err != nil {
+		return err
+	}
+	_, err = w.w.Write(encoded)
+	return err
+}
+
+func (w *jsonWriter) Close() error {
+	_, err := io.WriteString(w.w, "\n]\n")
+	return err
+}
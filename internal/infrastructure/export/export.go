@@ -0,0 +1,280 @@
+// Package export renders tabular data, such as report datasets, to
+// downloadable files. Rows are written one at a time straight to the output,
+// so a large export never has to be held in memory as a whole.
+package export
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Format is a file format rows can be exported to
+type Format string
+
+// Export formats
+const (
+	FormatCSV  Format = "csv"
+	FormatXLSX Format = "xlsx"
+	FormatPDF  Format = "pdf"
+	FormatJSON Format = "json"
+)
+
+// ContentType returns the MIME type of files in the format
+func (f Format) ContentType() string {
+	switch f {
+	case FormatCSV:
+		return "text/csv"
+	case FormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	case FormatPDF:
+		return "application/pdf"
+	default:
+		return "application/json"
+	}
+}
+
+// Extension returns the file name extension of the format, with its dot
+func (f Format) Extension() string {
+	return "." + string(f)
+}
+
+func init() {
+	// Files served by their extension, as file stores do, get the right type
+	for _, f := range []Format{FormatCSV, FormatXLSX, FormatPDF, FormatJSON} {
+		mime.AddExtensionType(f.Extension(), f.ContentType())
+	}
+}
+
+// Column describes a column of the exported rows
+type Column struct {
+	Key   string // machine name, used as the CSV header and the JSON field name
+	Title string // human readable name, used as the spreadsheet and PDF header
+}
+
+// Document describes the file rows are exported to
+type Document struct {
+	Title       string
+	Subtitle    string   // e.g. the period the data covers
+	Header      []string // lines printed above the title of a PDF, such as the company address
+	Footer      []string // lines printed at the bottom of every PDF page
+	GeneratedAt time.Time
+	Columns     []Column
+}
+
+// Writer writes the rows of one export. Values are strings, numbers, booleans,
+// times or nil, in the order of the document's columns.
+type Writer interface {
+	WriteRow(values []interface{}) error
+	// Close completes the file; nothing is written to the output after it
+	Close() error
+}
+
+// NewWriter starts an export of the document in the format to w
+func NewWriter(format Format, w io.Writer, doc *Document) (Writer, error) {
+	if doc.GeneratedAt.IsZero() {
+		doc.GeneratedAt = time.Now()
+	}
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w, doc)
+	case FormatXLSX:
+		return newXLSXWriter(w, doc)
+	case FormatPDF:
+		return newPDFWriter(w, doc, DefaultPDFTemplate)
+	case FormatJSON:
+		return newJSONWriter(w, doc)
+	default:
+		return nil, fmt.Errorf("unsupported export format %q", format)
+	}
+}
+
+// Table turns a struct or a slice of structs into columns and rows, a column
+// for every exported field named by its json tag
+func Table(data interface{}) ([]Column, [][]interface{}) {
+	v := reflect.ValueOf(data)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil, nil
+		}
+		v = v.Elem()
+	}
+
+	var items []reflect.Value
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			item := reflect.Indirect(v.Index(i))
+			if item.Kind() == reflect.Struct {
+				items = append(items, item)
+			}
+		}
+		if len(items) == 0 {
+			return tableColumns(v.Type().Elem()), nil
+		}
+	case reflect.Struct:
+		items = []reflect.Value{v}
+	default:
+		return nil, nil
+	}
+
+	t := items[0].Type()
+	columns := tableColumns(t)
+	fields := tableFields(t)
+	rows := make([][]interface{}, len(items))
+	for i, item := range items {
+		row := make([]interface{}, len(fields))
+		for j, index := range fields {
+			row[j] = tableValue(item.Field(index))
+		}
+		rows[i] = row
+	}
+	return columns, rows
+}
+
+// tableFields returns the indexes of the fields of t exported as columns
+func tableFields(t reflect.Type) []int {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	var fields []int
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() || strings.Split(field.Tag.Get("json"), ",")[0] == "-" {
+			continue
+		}
+		ft := field.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		kind := ft.Kind()
+		// Nested lists, maps and related records do not fit in a cell
+		if kind == reflect.Slice || kind == reflect.Map || kind == reflect.Array ||
+			(kind == reflect.Struct && ft != reflect.TypeOf(time.Time{})) {
+			continue
+		}
+		fields = append(fields, i)
+	}
+	return fields
+}
+
+func tableColumns(t reflect.Type) []Column {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	var columns []Column
+	for _, i := range tableFields(t) {
+		field := t.Field(i)
+		key := strings.Split(field.Tag.Get("json"), ",")[0]
+		if key == "" {
+			key = field.Name
+		}
+		columns = append(columns, Column{Key: key, Title: titleCase(key)})
+	}
+	return columns
+}
+
+// tableValue unwraps a field value into one of the values writers accept
+func tableValue(v reflect.Value) interface{} {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if t, ok := v.Interface().(time.Time); ok {
+		return t
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint()
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
+
+// titleCase turns a snake_case key into a title, e.g. "unit_cost" into "Unit Cost"
+func titleCase(key string) string {
+	words := strings.Split(key, "_")
+	for i, word := range words {
+		switch word {
+		case "id", "sku", "vat":
+			words[i] = strings.ToUpper(word)
+		default:
+			if word != "" {
+				words[i] = strings.ToUpper(word[:1]) + word[1:]
+			}
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// formatValue renders a value as text, floats with up to the given number of
+// decimals and times as RFC 3339 or, at midnight, as a date
+func formatValue(value interface{}, decimals int) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case uint:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', decimals, 32)
+	case float64:
+		return strconv.FormatFloat(v, 'f', decimals, 64)
+	case time.Time:
+		if v.IsZero() {
+			return ""
+		}
+		if v.Hour() == 0 && v.Minute() == 0 && v.Second() == 0 && v.Nanosecond() == 0 {
+			return v.Format("2006-01-02")
+		}
+		return v.Format(time.RFC3339)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// numeric returns a value as a float when it is a number
+func numeric(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,441 @@
+package export
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Page layout of PDF exports, in points on a landscape A4 page
+const (
+	pdfPageWidth  = 842.0
+	pdfPageHeight = 595.0
+	pdfMargin     = 36.0
+	pdfFontSize   = 8.0
+	pdfRowHeight  = 12.0
+	pdfCellPad    = 3.0
+	pdfMinColumn  = 30.0
+	// pdfSampleRows is the number of rows the column widths are measured on
+	pdfSampleRows = 50
+)
+
+// Objects written before the pages
+const (
+	pdfCatalog = iota + 1
+	pdfPages
+	pdfFontRegular
+	pdfFontBold
+	pdfInfo
+)
+
+// PDFTemplate lays out the text around the table of a PDF export. Both
+// fields are text/template templates executed with the PDFPage of each page.
+type PDFTemplate struct {
+	Continued string // heading of the pages after the first
+	Footer    string // last line of every page
+}
+
+// PDFPage is the data PDF templates are executed with
+type PDFPage struct {
+	Title       string
+	Subtitle    string
+	GeneratedAt time.Time
+	Page        int
+}
+
+// DefaultPDFTemplate is the layout of PDF exports
+var DefaultPDFTemplate = PDFTemplate{
+	Continued: `{{.Title}} (continued)`,
+	Footer:    `{{.Title}} · Generated {{.GeneratedAt.Format "2006-01-02 15:04"}} · Page {{.Page}}`,
+}
+
+// pdfWriter lays the rows out as a table over as many pages as they need,
+// writing each page as soon as it is full. It uses the standard Helvetica
+// fonts, so characters outside Windows-1252 are printed as question marks.
+type pdfWriter struct {
+	w         *countingWriter
+	doc       *Document
+	continued *template.Template
+	footer    *template.Template
+	offsets   map[int]int64
+	nextObj   int
+	kids      []int
+
+	widths  []float64
+	numeric []bool
+	sample  [][]interface{}
+	rows    int
+
+	page    *bytes.Buffer
+	pageNum int
+	y       float64
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func newPDFWriter(w io.Writer, doc *Document, tmpl PDFTemplate) (*pdfWriter, error) {
+	continued, err := template.New("continued").Parse(tmpl.Continued)
+	if err != nil {
+		return nil, fmt.Errorf("invalid PDF template: %w", err)
+	}
+	footer, err := template.New("footer").Parse(tmpl.Footer)
+	if err != nil {
+		return nil, fmt.Errorf("invalid PDF template: %w", err)
+	}
+
+	p := &pdfWriter{
+		w:         &countingWriter{w: w},
+		doc:       doc,
+		continued: continued,
+		footer:    footer,
+		offsets:   make(map[int]int64),
+		nextObj:   pdfInfo + 1,
+		numeric:   make([]bool, len(doc.Columns)),
+	}
+
+	fmt.Fprint(p.w, "%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	p.object(pdfCatalog, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pdfPages))
+	p.object(pdfFontRegular, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	p.object(pdfFontBold, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	err = p.object(pdfInfo, fmt.Sprintf("<< /Title %s /Producer (erp-warehouse) /CreationDate (D:%s) >>",
+		pdfString(doc.Title), doc.GeneratedAt.UTC().Format("20060102150405Z")))
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *pdfWriter) WriteRow(values []interface{}) error {
+	row := make([]interface{}, len(p.doc.Columns))
+	copy(row, values)
+
+	// The first rows are held back until the column widths are known
+	if p.widths == nil {
+		p.sample = append(p.sample, row)
+		if len(p.sample) < pdfSampleRows {
+			return nil
+		}
+		return p.flushSample()
+	}
+	return p.drawRow(row)
+}
+
+func (p *pdfWriter) Close() error {
+	if p.widths == nil {
+		if err := p.flushSample(); err != nil {
+			return err
+		}
+	}
+	if p.page == nil {
+		p.startPage()
+	}
+	if p.rows == 0 {
+		p.text(pdfMargin, p.y-9, false, pdfFontSize, "No data")
+	}
+	if err := p.finishPage(); err != nil {
+		return err
+	}
+
+	kids := make([]string, len(p.kids))
+	for i, kid := range p.kids {
+		kids[i] = fmt.Sprintf("%d 0 R", kid)
+	}
+	p.object(pdfPages, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(p.kids)))
+
+	xref := p.w.n
+	fmt.Fprintf(p.w, "xref\n0 %d\n0000000000 65535 f \n", p.nextObj)
+	for i := 1; i < p.nextObj; i++ {
+		fmt.Fprintf(p.w, "%010d 00000 n \n", p.offsets[i])
+	}
+	_, err := fmt.Fprintf(p.w, "trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+		p.nextObj, pdfCatalog, pdfInfo, xref)
+	return err
+}
+
+// flushSample sizes the columns to the held back rows and draws them
+func (p *pdfWriter) flushSample() error {
+	p.layoutColumns()
+	for _, row := range p.sample {
+		if err := p.drawRow(row); err != nil {
+			return err
+		}
+	}
+	p.sample = nil
+	return nil
+}
+
+// layoutColumns gives each column the width of its widest sampled value and
+// scales the columns to the width of the page
+func (p *pdfWriter) layoutColumns() {
+	p.widths = make([]float64, len(p.doc.Columns))
+	total := 0.0
+	for i, column := range p.doc.Columns {
+		width := textWidth(column.Title, true, pdfFontSize)
+		isNumeric := len(p.sample) > 0
+		for _, row := range p.sample {
+			if row[i] == nil {
+				continue
+			}
+			if _, ok := numeric(row[i]); !ok {
+				isNumeric = false
+			}
+			if w := textWidth(cellText(row[i]), false, pdfFontSize); w > width {
+				width = w
+			}
+		}
+		width += 2 * pdfCellPad
+		if width < pdfMinColumn {
+			width = pdfMinColumn
+		}
+		p.widths[i] = width
+		p.numeric[i] = isNumeric
+		total += width
+	}
+	if total == 0 {
+		return
+	}
+	scale := (pdfPageWidth - 2*pdfMargin) / total
+	for i := range p.widths {
+		p.widths[i] *= scale
+	}
+}
+
+// drawRow draws a table row, starting a new page when the current one is full
+func (p *pdfWriter) drawRow(row []interface{}) error {
+	if p.page == nil {
+		p.startPage()
+	} else if p.y-pdfRowHeight < p.bottom() {
+		if err := p.finishPage(); err != nil {
+			return err
+		}
+		p.startPage()
+	}
+
+	p.rows++
+	if p.rows%2 == 0 {
+		fmt.Fprintf(p.page, "0.95 g %.2f %.2f %.2f %.2f re f 0 g\n", pdfMargin, p.y-pdfRowHeight, pdfPageWidth-2*pdfMargin, pdfRowHeight)
+	}
+	x := pdfMargin
+	for i, value := range row {
+		p.cell(x, p.y-9, p.widths[i], cellText(value), p.numeric[i], false)
+		x += p.widths[i]
+	}
+	p.y -= pdfRowHeight
+	return nil
+}
+
+// startPage begins a page with its heading and the table header
+func (p *pdfWriter) startPage() {
+	p.page = new(bytes.Buffer)
+	p.pageNum++
+	p.y = pdfPageHeight - pdfMargin
+
+	if p.pageNum == 1 {
+		for _, line := range p.doc.Header {
+			p.y -= 10
+			p.text(pdfMargin, p.y, false, pdfFontSize, line)
+		}
+		if len(p.doc.Header) > 0 {
+			p.y -= 8
+		}
+		p.y -= 16
+		p.text(pdfMargin, p.y, true, 16, p.doc.Title)
+		if p.doc.Subtitle != "" {
+			p.y -= 14
+			p.text(pdfMargin, p.y, false, 10, p.doc.Subtitle)
+		}
+		p.y -= 12
+	} else {
+		p.y -= 12
+		p.text(pdfMargin, p.y, true, 10, p.execute(p.continued))
+		p.y -= 8
+	}
+
+	x := pdfMargin
+	for i, column := range p.doc.Columns {
+		p.cell(x, p.y-10, p.widths[i], column.Title, p.numeric[i], true)
+		x += p.widths[i]
+	}
+	p.y -= 14
+	fmt.Fprintf(p.page, "0.5 w %.2f %.2f m %.2f %.2f l S\n", pdfMargin, p.y, pdfPageWidth-pdfMargin, p.y)
+	p.y -= 2
+}
+
+// finishPage draws the footer and writes the page
+func (p *pdfWriter) finishPage() error {
+	y := pdfMargin - 12
+	p.text(pdfMargin, y, false, 7, p.execute(p.footer))
+	for i := len(p.doc.Footer) - 1; i >= 0; i-- {
+		y += 9
+		p.text(pdfMargin, y, false, 7, p.doc.Footer[i])
+	}
+
+	var content bytes.Buffer
+	zw := zlib.NewWriter(&content)
+	zw.Write(p.page.Bytes())
+	zw.Close()
+
+	contentObj := p.nextObj
+	pageObj := p.nextObj + 1
+	p.nextObj += 2
+	p.kids = append(p.kids, pageObj)
+	p.page = nil
+
+	p.object(contentObj, fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream", content.Len(), content.Bytes()))
+	return p.object(pageObj, fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %.0f %.0f] /Contents %d 0 R /Resources << /Font << /F1 %d 0 R /F2 %d 0 R >> >> >>",
+		pdfPages, pdfPageWidth, pdfPageHeight, contentObj, pdfFontRegular, pdfFontBold))
+}
+
+// bottom returns the lowest point rows may reach above the footer
+func (p *pdfWriter) bottom() float64 {
+	return pdfMargin + 9*float64(len(p.doc.Footer)) + 4
+}
+
+// cell draws text in a table cell, cutting it to the cell's width
+func (p *pdfWriter) cell(x, y, width float64, s string, right, bold bool) {
+	room := width - 2*pdfCellPad
+	if textWidth(s, bold, pdfFontSize) > room {
+		runes := []rune(s)
+		for len(runes) > 0 && textWidth(string(runes)+"..", bold, pdfFontSize) > room {
+			runes = runes[:len(runes)-1]
+		}
+		s = string(runes) + ".."
+		if len(runes) == 0 {
+			s = ""
+		}
+	}
+	if right {
+		x += width - pdfCellPad - textWidth(s, bold, pdfFontSize)
+	} else {
+		x += pdfCellPad
+	}
+	p.text(x, y, bold, pdfFontSize, s)
+}
+
+func (p *pdfWriter) text(x, y float64, bold bool, size float64, s string) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	fmt.Fprintf(p.page, "BT /%s %.1f Tf %.2f %.2f Td %s Tj ET\n", font, size, x, y, pdfString(s))
+}
+
+func (p *pdfWriter) execute(t *template.Template) string {
+	var b strings.Builder
+	err := t.Execute(&b, PDFPage{
+		Title:       p.doc.Title,
+		Subtitle:    p.doc.Subtitle,
+		GeneratedAt: p.doc.GeneratedAt,
+		Page:        p.pageNum,
+	})
+	if err != nil {
+		return p.doc.Title
+	}
+	return b.String()
+}
+
+// object writes an indirect object, recording its offset for the xref table
+func (p *pdfWriter) object(num int, body string) error {
+	p.offsets[num] = p.w.n
+	_, err := fmt.Fprintf(p.w, "%d 0 obj\n%s\nendobj\n", num, body)
+	return err
+}
+
+// cellText renders a value as PDF cell text, numbers with two decimals
+func cellText(value interface{}) string {
+	switch value.(type) {
+	case float32, float64:
+		return formatValue(value, 2)
+	default:
+		return formatValue(value, -1)
+	}
+}
+
+// pdfString encodes text as a PDF literal string in Windows-1252
+func pdfString(s string) string {
+	var b strings.Builder
+	b.WriteByte('(')
+	for _, c := range winAnsi(s) {
+		switch {
+		case c == '(' || c == ')' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < 32 || c > 126:
+			fmt.Fprintf(&b, "\\%03o", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte(')')
+	return b.String()
+}
+
+// winAnsiSpecial maps the characters Windows-1252 places in 0x80-0x9F
+var winAnsiSpecial = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87, 'ˆ': 0x88,
+	'‰': 0x89, 'Š': 0x8A, '‹': 0x8B, 'Œ': 0x8C, 'Ž': 0x8E, '‘': 0x91, '’': 0x92, '“': 0x93,
+	'”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97, '˜': 0x98, '™': 0x99, 'š': 0x9A, '›': 0x9B,
+	'œ': 0x9C, 'ž': 0x9E, 'Ÿ': 0x9F,
+}
+
+// winAnsi converts text to Windows-1252, replacing what it cannot hold
+func winAnsi(s string) []byte {
+	out := make([]byte, 0, len(s))
+	for _, r := range s {
+		switch {
+		case r == '\t' || r == '\n' || r == '\r':
+			out = append(out, ' ')
+		case r < 0x80 || (r >= 0xA0 && r <= 0xFF):
+			out = append(out, byte(r))
+		default:
+			if c, ok := winAnsiSpecial[r]; ok {
+				out = append(out, c)
+			} else {
+				out = append(out, '?')
+			}
+		}
+	}
+	return out
+}
+
+// helveticaWidths are the widths of the printable ASCII characters in
+// Helvetica, in thousandths of the font size
+var helveticaWidths = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+// textWidth estimates the width of text in points. Bold text is taken to be
+// slightly wider than regular text, and characters beyond ASCII as wide as a digit.
+func textWidth(s string, bold bool, size float64) float64 {
+	total := 0
+	for _, c := range winAnsi(s) {
+		if c >= 32 && c <= 126 {
+			total += helveticaWidths[c-32]
+		} else {
+			total += 556
+		}
+	}
+	width := float64(total) * size / 1000
+	if bold {
+		width *= 1.06
+	}
+	return width
+}
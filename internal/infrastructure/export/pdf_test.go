@@ -0,0 +1,318 @@
+package export
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// pdfFile is a written PDF read back through its cross-reference table
+type pdfFile struct {
+	objects map[int]string // body of each object by number
+	info    string
+	pages   [][]string // text shown on each page, in drawing order
+}
+
+var (
+	pdfTrailer   = regexp.MustCompile(`trailer\n<< /Size (\d+) /Root (\d+) 0 R /Info (\d+) 0 R >>\nstartxref\n(\d+)\n%%EOF\n$`)
+	pdfKids      = regexp.MustCompile(`^<< /Type /Pages /Kids \[((?:\d+ 0 R ?)*)\] /Count (\d+) >>$`)
+	pdfContents  = regexp.MustCompile(`/Parent (\d+) 0 R /MediaBox \[0 0 842 595\] /Contents (\d+) 0 R`)
+	pdfStream    = regexp.MustCompile(`(?s)^<< /Length (\d+) /Filter /FlateDecode >>\nstream\n(.*)\nendstream$`)
+	pdfShowText  = regexp.MustCompile(`BT /F[12] [\d.]+ Tf -?[\d.]+ -?[\d.]+ Td \(((?:\\.|[^\\)])*)\) Tj ET`)
+	pdfTextEsc   = regexp.MustCompile(`\\([0-7]{3}|.)`)
+	pdfObjHeader = regexp.MustCompile(`^(\d+) 0 obj\n`)
+)
+
+// readPDF checks the structure of a written PDF: the trailer points at an
+// xref table whose offsets each start the object of that number, the page
+// tree lists every page and each page's content stream inflates to its
+// declared length
+func readPDF(t *testing.T, data []byte) *pdfFile {
+	t.Helper()
+	if !bytes.HasPrefix(data, []byte("%PDF-1.4\n")) {
+		t.Fatalf("missing PDF header: %q", data[:min(len(data), 16)])
+	}
+	m := pdfTrailer.FindSubmatch(data)
+	if m == nil {
+		t.Fatalf("missing trailer: %q", data[max(0, len(data)-120):])
+	}
+	size, _ := strconv.Atoi(string(m[1]))
+	root, _ := strconv.Atoi(string(m[2]))
+	infoNum, _ := strconv.Atoi(string(m[3]))
+	xref, _ := strconv.Atoi(string(m[4]))
+
+	table := string(data[xref:])
+	wantHead := fmt.Sprintf("xref\n0 %d\n0000000000 65535 f \n", size)
+	if !strings.HasPrefix(table, wantHead) {
+		t.Fatalf("startxref %d does not point at an xref table of %d entries: %q", xref, size, table[:min(len(table), 40)])
+	}
+	entries := table[len(wantHead):]
+
+	f := &pdfFile{objects: map[int]string{}}
+	for num := 1; num < size; num++ {
+		// Entries are exactly 20 bytes, the end of line included
+		entry := entries[(num-1)*20 : num*20]
+		if !strings.HasSuffix(entry, " 00000 n \n") {
+			t.Fatalf("malformed xref entry %d: %q", num, entry)
+		}
+		offset, err := strconv.Atoi(entry[:10])
+		if err != nil {
+			t.Fatalf("malformed xref offset %d: %q", num, entry)
+		}
+		rest := data[offset:]
+		header := pdfObjHeader.FindSubmatch(rest)
+		if header == nil || string(header[1]) != strconv.Itoa(num) {
+			t.Fatalf("xref offset %d of object %d points at %q", offset, num, rest[:min(len(rest), 20)])
+		}
+		end := bytes.Index(rest, []byte("\nendobj\n"))
+		if end < 0 {
+			t.Fatalf("object %d is not ended", num)
+		}
+		f.objects[num] = string(rest[len(header[0]):end])
+	}
+
+	if catalog := f.objects[root]; catalog != fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pdfPages) {
+		t.Fatalf("catalog %q", catalog)
+	}
+	f.info = f.objects[infoNum]
+
+	kids := pdfKids.FindStringSubmatch(f.objects[pdfPages])
+	if kids == nil {
+		t.Fatalf("malformed page tree %q", f.objects[pdfPages])
+	}
+	refs := strings.Fields(strings.ReplaceAll(kids[1], "0 R", ""))
+	if count, _ := strconv.Atoi(kids[2]); count != len(refs) || count == 0 {
+		t.Fatalf("page tree counts %d pages and lists %d", count, len(refs))
+	}
+	for _, ref := range refs {
+		num, _ := strconv.Atoi(ref)
+		page := pdfContents.FindStringSubmatch(f.objects[num])
+		if page == nil || page[1] != strconv.Itoa(pdfPages) {
+			t.Fatalf("malformed page %d: %q", num, f.objects[num])
+		}
+		contentNum, _ := strconv.Atoi(page[2])
+		stream := pdfStream.FindStringSubmatch(f.objects[contentNum])
+		if stream == nil {
+			t.Fatalf("malformed content stream %d", contentNum)
+		}
+		if length, _ := strconv.Atoi(stream[1]); length != len(stream[2]) {
+			t.Fatalf("content stream %d declares %d bytes and holds %d", contentNum, length, len(stream[2]))
+		}
+		zr, err := zlib.NewReader(strings.NewReader(stream[2]))
+		if err != nil {
+			t.Fatalf("inflating content stream %d: %v", contentNum, err)
+		}
+		content, err := io.ReadAll(zr)
+		if err != nil {
+			t.Fatalf("inflating content stream %d: %v", contentNum, err)
+		}
+
+		var texts []string
+		for _, shown := range pdfShowText.FindAllStringSubmatch(string(content), -1) {
+			texts = append(texts, unescapePDF(shown[1]))
+		}
+		f.pages = append(f.pages, texts)
+	}
+	return f
+}
+
+// unescapePDF decodes the escapes of a PDF literal string
+func unescapePDF(s string) string {
+	return pdfTextEsc.ReplaceAllStringFunc(s, func(esc string) string {
+		if len(esc) == 4 {
+			c, _ := strconv.ParseUint(esc[1:], 8, 8)
+			return string([]byte{byte(c)})
+		}
+		return esc[1:]
+	})
+}
+
+func writePDF(t *testing.T, doc *Document, tmpl PDFTemplate, rows [][]interface{}) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := newPDFWriter(&buf, doc, tmpl)
+	if err != nil {
+		t.Fatalf("newPDFWriter: %v", err)
+	}
+	for _, row := range rows {
+		if err := w.WriteRow(row); err != nil {
+			t.Fatalf("WriteRow: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestPDFPages(t *testing.T) {
+	doc := &Document{
+		Title:       "Stock Movements",
+		Subtitle:    "March 2024",
+		Header:      []string{"ERP Warehouse Ltd", "1 Harbour Road"},
+		Footer:      []string{"Confidential"},
+		GeneratedAt: time.Date(2024, 4, 1, 9, 30, 0, 0, time.UTC),
+		Columns:     []Column{{Key: "sku", Title: "SKU"}, {Key: "quantity", Title: "Quantity"}, {Key: "cost", Title: "Cost"}},
+	}
+	const rows = 200
+	data := make([][]interface{}, rows)
+	for i := range data {
+		data[i] = []interface{}{fmt.Sprintf("SKU-%04d", i), i, float64(i) / 4}
+	}
+	f := readPDF(t, writePDF(t, doc, DefaultPDFTemplate, data))
+
+	if len(f.pages) < 3 {
+		t.Fatalf("%d rows fit on %d pages, want them spread over at least 3", rows, len(f.pages))
+	}
+	if !strings.Contains(f.info, "/Title (Stock Movements)") || !strings.Contains(f.info, "/CreationDate (D:20240401093000Z)") {
+		t.Errorf("info %q, want the title and creation date", f.info)
+	}
+
+	first := strings.Join(f.pages[0], "|")
+	if !strings.HasPrefix(first, "ERP Warehouse Ltd|1 Harbour Road|Stock Movements|March 2024|SKU|Quantity|Cost|SKU-0000|0|0.00|") {
+		t.Errorf("first page starts %q", first[:min(len(first), 120)])
+	}
+
+	seen := 0
+	for i, page := range f.pages {
+		if i > 0 {
+			if page[0] != "Stock Movements (continued)" || page[1] != "SKU" {
+				t.Errorf("page %d starts %q, want the continued heading and the table header", i+1, page[:2])
+			}
+		}
+		footer := fmt.Sprintf("Stock Movements \xb7 Generated 2024-04-01 09:30 \xb7 Page %d", i+1)
+		if n := len(page); page[n-2] != footer || page[n-1] != "Confidential" {
+			t.Errorf("page %d ends %q, want the footer %q", i+1, page[n-2:], footer)
+		}
+		for _, text := range page {
+			if text == fmt.Sprintf("SKU-%04d", seen) {
+				seen++
+			}
+		}
+	}
+	if seen != rows {
+		t.Errorf("found %d rows in order, want %d", seen, rows)
+	}
+}
+
+func TestPDFWithoutRows(t *testing.T) {
+	doc := &Document{Title: "Empty", Columns: []Column{{Key: "sku", Title: "SKU"}}}
+	f := readPDF(t, writePDF(t, doc, DefaultPDFTemplate, nil))
+
+	if len(f.pages) != 1 {
+		t.Fatalf("got %d pages, want 1", len(f.pages))
+	}
+	if got := strings.Join(f.pages[0][:3], "|"); got != "Empty|SKU|No data" {
+		t.Errorf("page shows %q, want the title, header and No data", got)
+	}
+}
+
+// Rows held back to size the columns are all written, whether or not the
+// sample filled up
+func TestPDFSampleRows(t *testing.T) {
+	for _, rows := range []int{1, pdfSampleRows - 1, pdfSampleRows, pdfSampleRows + 1} {
+		t.Run(strconv.Itoa(rows), func(t *testing.T) {
+			doc := &Document{Title: "Sample", Columns: []Column{{Key: "n", Title: "N"}}}
+			data := make([][]interface{}, rows)
+			for i := range data {
+				data[i] = []interface{}{fmt.Sprintf("row %d", i)}
+			}
+			f := readPDF(t, writePDF(t, doc, DefaultPDFTemplate, data))
+
+			found := 0
+			for _, page := range f.pages {
+				for _, text := range page {
+					if strings.HasPrefix(text, "row ") {
+						found++
+					}
+				}
+			}
+			if found != rows {
+				t.Errorf("found %d rows, want %d", found, rows)
+			}
+		})
+	}
+}
+
+func TestPDFText(t *testing.T) {
+	doc := &Document{
+		Title:   "Café (Nord) \\ Süd",
+		Columns: []Column{{Key: "name", Title: "Name"}, {Key: "price", Title: "Price"}},
+	}
+	f := readPDF(t, writePDF(t, doc, DefaultPDFTemplate, [][]interface{}{
+		{"€ 5 – “quoted”", 5.5},
+		{"Kho hàng 中", nil},
+		{"tab\there", int64(3)},
+	}))
+
+	texts := strings.Join(f.pages[0], "|")
+	for _, want := range []string{
+		"Caf\xe9 (Nord) \\ S\xfcd",
+		"\x80 5 \x96 \x93quoted\x94|5.50",
+		"Kho h\xe0ng ?|",
+		"tab here|3",
+	} {
+		if !strings.Contains(texts, want) {
+			t.Errorf("page text %q lacks %q", texts, want)
+		}
+	}
+}
+
+// Text too wide for its cell is cut with two dots
+func TestPDFCellsCutLongText(t *testing.T) {
+	doc := &Document{Title: "Notes", Columns: []Column{{Key: "id", Title: "ID"}, {Key: "note", Title: "Note"}}}
+	long := strings.Repeat("W", 400)
+	f := readPDF(t, writePDF(t, doc, DefaultPDFTemplate, [][]interface{}{{1, long}}))
+
+	var cell string
+	for _, text := range f.pages[0] {
+		if strings.HasPrefix(text, "WWW") {
+			cell = text
+		}
+	}
+	if !strings.HasSuffix(cell, "..") || len(cell) >= len(long) {
+		t.Fatalf("got cell %q, want the text cut", cell)
+	}
+	if width := textWidth(cell, false, pdfFontSize); width > pdfPageWidth-2*pdfMargin {
+		t.Errorf("cut text is %.0f points wide, wider than the page", width)
+	}
+}
+
+func TestPDFTemplate(t *testing.T) {
+	doc := &Document{Title: "Report", Subtitle: "Q1", Columns: []Column{{Key: "n", Title: "N"}}}
+	tmpl := PDFTemplate{Continued: "{{.Title}} - {{.Subtitle}}", Footer: "p. {{.Page}} {{.Missing}}"}
+	f := readPDF(t, writePDF(t, doc, tmpl, [][]interface{}{{1}}))
+
+	// A template failing at execution falls back to the title
+	if page := f.pages[0]; page[len(page)-1] != "Report" {
+		t.Errorf("footer %q, want the title", page[len(page)-1])
+	}
+
+	var buf bytes.Buffer
+	if _, err := newPDFWriter(&buf, doc, PDFTemplate{Footer: "{{.Page"}); err == nil || !strings.Contains(err.Error(), "invalid PDF template") {
+		t.Errorf("got error %v, want an invalid template", err)
+	}
+}
+
+func TestPDFString(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"plain", "(plain)"},
+		{"a (b) c", `(a \(b\) c)`},
+		{`back\slash`, `(back\\slash)`},
+		{"é€", `(\351\200)`},
+		{"line\nbreak\ttab", "(line break tab)"},
+		{"中文", "(??)"},
+		{"\x01", `(\001)`},
+	}
+	for _, tt := range tests {
+		if got := pdfString(tt.in); got != tt.want {
+			t.Errorf("pdfString(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
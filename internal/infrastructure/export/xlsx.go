@@ -0,0 +1,177 @@
+package export
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cell styles defined in xlsxStyles
+const (
+	xlsxStyleHeader   = 1
+	xlsxStyleDate     = 2
+	xlsxStyleDateTime = 3
+	xlsxStyleDecimal  = 4
+)
+
+// xlsxEpoch is day zero of spreadsheet dates
+var xlsxEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+var xlsxParts = []struct{ name, content string }{
+	{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`},
+	{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`},
+	{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/><Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/></Relationships>`},
+	{"xl/styles.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts><fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills><borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders><cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs><cellXfs count="5"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/><xf numFmtId="14" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/><xf numFmtId="22" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/><xf numFmtId="4" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/></cellXfs></styleSheet>`},
+}
+
+// xlsxWriter streams the rows into the single worksheet of an Office Open XML
+// workbook. Strings are stored inline, so the workbook needs no shared string
+// table built from all rows.
+type xlsxWriter struct {
+	zip  *zip.Writer
+	w    *bufio.Writer
+	row  int
+	cols int
+}
+
+func newXLSXWriter(w io.Writer, doc *Document) (*xlsxWriter, error) {
+	z := zip.NewWriter(w)
+	for _, part := range xlsxParts {
+		f, err := z.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(f, part.content); err != nil {
+			return nil, err
+		}
+	}
+
+	f, err := z.Create("xl/workbook.xml")
+	if err != nil {
+		return nil, err
+	}
+	_, err = fmt.Fprintf(f, `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets></workbook>`, escapeXML(sheetName(doc.Title)))
+	if err != nil {
+		return nil, err
+	}
+
+	f, err = z.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	writer := &xlsxWriter{zip: z, w: bufio.NewWriter(f), cols: len(doc.Columns)}
+	// Keep the header row in view while scrolling
+	writer.w.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews><sheetData>`)
+
+	header := make([]interface{}, len(doc.Columns))
+	for i, column := range doc.Columns {
+		header[i] = column.Title
+	}
+	if err := writer.writeRow(header, xlsxStyleHeader); err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+func (w *xlsxWriter) WriteRow(values []interface{}) error {
+	return w.writeRow(values, 0)
+}
+
+// writeRow writes a row, strings in the given style and other values in the
+// style of their type
+func (w *xlsxWriter) writeRow(values []interface{}, style int) error {
+	w.row++
+	fmt.Fprintf(w.w, `<row r="%d">`, w.row)
+	for i := 0; i < w.cols && i < len(values); i++ {
+		ref := columnName(i) + strconv.Itoa(w.row)
+		switch v := values[i].(type) {
+		case nil:
+		case bool:
+			b := 0
+			if v {
+				b = 1
+			}
+			fmt.Fprintf(w.w, `<c r="%s" t="b"><v>%d</v></c>`, ref, b)
+		case time.Time:
+			if v.IsZero() {
+				continue
+			}
+			// Spreadsheets have no time zones, so the wall clock time is kept
+			wall := time.Date(v.Year(), v.Month(), v.Day(), v.Hour(), v.Minute(), v.Second(), v.Nanosecond(), time.UTC)
+			serial := float64(wall.Sub(xlsxEpoch)) / float64(24*time.Hour)
+			cellStyle := xlsxStyleDateTime
+			if v.Hour() == 0 && v.Minute() == 0 && v.Second() == 0 {
+				cellStyle = xlsxStyleDate
+			}
+			fmt.Fprintf(w.w, `<c r="%s" s="%d"><v>%s</v></c>`, ref, cellStyle, strconv.FormatFloat(serial, 'f', -1, 64))
+		default:
+			if n, ok := numeric(v); ok {
+				if math.IsInf(n, 0) || math.IsNaN(n) {
+					continue
+				}
+				cellStyle := 0
+				if n != math.Trunc(n) {
+					cellStyle = xlsxStyleDecimal
+				}
+				fmt.Fprintf(w.w, `<c r="%s" s="%d"><v>%s</v></c>`, ref, cellStyle, strconv.FormatFloat(n, 'f', -1, 64))
+				continue
+			}
+			fmt.Fprintf(w.w, `<c r="%s" t="inlineStr" s="%d"><is><t xml:space="preserve">%s</t></is></c>`, ref, style, escapeXML(formatValue(v, -1)))
+		}
+	}
+	_, err := w.w.WriteString("</row>")
+	return err
+}
+
+func (w *xlsxWriter) Close() error {
+	w.w.WriteString("</sheetData></worksheet>")
+	if err := w.w.Flush(); err != nil {
+		return err
+	}
+	return w.zip.Close()
+}
+
+// columnName returns the letters of a zero-based column index, e.g. "AB" for 27
+func columnName(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
+
+// sheetName makes a title a valid worksheet name
+func sheetName(title string) string {
+	name := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return ' '
+		}
+		return r
+	}, title)
+	if runes := []rune(strings.TrimSpace(name)); len(runes) > 31 {
+		name = string(runes[:31])
+	}
+	if name = strings.TrimSpace(name); name == "" {
+		return "Report"
+	}
+	return name
+}
+
+// escapeXML escapes text for XML, replacing characters XML cannot hold
+func escapeXML(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
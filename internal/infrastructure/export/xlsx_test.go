@@ -0,0 +1,261 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// xlsxSheet is the part of a worksheet the writer fills
+type xlsxSheet struct {
+	Pane struct {
+		YSplit string `xml:"ySplit,attr"`
+		State  string `xml:"state,attr"`
+	} `xml:"sheetViews>sheetView>pane"`
+	Rows []struct {
+		R     int `xml:"r,attr"`
+		Cells []struct {
+			R      string `xml:"r,attr"`
+			T      string `xml:"t,attr"`
+			S      string `xml:"s,attr"`
+			V      string `xml:"v"`
+			Inline string `xml:"is>t"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// openXLSX reopens a written workbook, checking every part is well-formed XML
+// and every part the package refers to exists
+func openXLSX(t *testing.T, data []byte) map[string][]byte {
+	t.Helper()
+	z, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("reading the workbook as a zip: %v", err)
+	}
+	parts := map[string][]byte{}
+	for _, f := range z.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatalf("opening %s: %v", f.Name, err)
+		}
+		content, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatalf("reading %s: %v", f.Name, err)
+		}
+		dec := xml.NewDecoder(bytes.NewReader(content))
+		for {
+			if _, err := dec.Token(); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("%s is not well-formed XML: %v", f.Name, err)
+			}
+		}
+		parts[f.Name] = content
+	}
+
+	var types struct {
+		Overrides []struct {
+			PartName string `xml:"PartName,attr"`
+		} `xml:"Override"`
+	}
+	if err := xml.Unmarshal(parts["[Content_Types].xml"], &types); err != nil {
+		t.Fatalf("parsing the content types: %v", err)
+	}
+	for _, override := range types.Overrides {
+		if _, ok := parts[strings.TrimPrefix(override.PartName, "/")]; !ok {
+			t.Errorf("content type of the missing part %s", override.PartName)
+		}
+	}
+
+	for rels, dir := range map[string]string{"_rels/.rels": "", "xl/_rels/workbook.xml.rels": "xl"} {
+		var relationships struct {
+			Targets []struct {
+				Target string `xml:"Target,attr"`
+			} `xml:"Relationship"`
+		}
+		if err := xml.Unmarshal(parts[rels], &relationships); err != nil {
+			t.Fatalf("parsing %s: %v", rels, err)
+		}
+		for _, rel := range relationships.Targets {
+			if _, ok := parts[path.Join(dir, rel.Target)]; !ok {
+				t.Errorf("%s refers to the missing part %s", rels, rel.Target)
+			}
+		}
+	}
+	return parts
+}
+
+func readXLSXSheet(t *testing.T, parts map[string][]byte) *xlsxSheet {
+	t.Helper()
+	var sheet xlsxSheet
+	if err := xml.Unmarshal(parts["xl/worksheets/sheet1.xml"], &sheet); err != nil {
+		t.Fatalf("parsing the worksheet: %v", err)
+	}
+	return &sheet
+}
+
+func TestXLSXRoundTrip(t *testing.T) {
+	doc := &Document{
+		Title: "Stock: A/B [2024]",
+		Columns: []Column{
+			{Key: "name", Title: "Name <&>"},
+			{Key: "quantity", Title: "Quantity"},
+			{Key: "cost", Title: "Cost"},
+			{Key: "active", Title: "Active"},
+			{Key: "date", Title: "Date"},
+			{Key: "updated_at", Title: "Updated At"},
+			{Key: "note", Title: "Note"},
+		},
+	}
+	var buf bytes.Buffer
+	w, err := NewWriter(FormatXLSX, &buf, doc)
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	plus7 := time.FixedZone("UTC+7", 7*3600)
+	rows := [][]interface{}{
+		{"  Bolts & <nuts>  ", 12, 1.5, true, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 3, 1, 18, 0, 0, 0, plus7), "line\nbreak"},
+		{"Tab\tand \x01 control", int64(-3), float32(0.25), false, time.Time{}, nil, "Đồng ✓"},
+		{"NaN cost", uint(0), math.NaN(), nil, nil, nil},
+		{"extra values", uint64(1 << 40), math.Inf(1), true, nil, nil, "x", "dropped"},
+	}
+	for _, row := range rows {
+		if err := w.WriteRow(row); err != nil {
+			t.Fatalf("WriteRow: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	parts := openXLSX(t, buf.Bytes())
+	var workbook struct {
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := xml.Unmarshal(parts["xl/workbook.xml"], &workbook); err != nil {
+		t.Fatalf("parsing the workbook: %v", err)
+	}
+	if len(workbook.Sheets) != 1 || workbook.Sheets[0].Name != "Stock  A B  2024" {
+		t.Errorf("sheets %+v, want one named %q", workbook.Sheets, "Stock  A B  2024")
+	}
+
+	sheet := readXLSXSheet(t, parts)
+	if sheet.Pane.YSplit != "1" || sheet.Pane.State != "frozen" {
+		t.Errorf("pane %+v, want the header row frozen", sheet.Pane)
+	}
+
+	// Each cell as reference, type, style and value
+	want := [][]string{
+		{"A1 inlineStr 1 Name <&>", "B1 inlineStr 1 Quantity", "C1 inlineStr 1 Cost", "D1 inlineStr 1 Active", "E1 inlineStr 1 Date", "F1 inlineStr 1 Updated At", "G1 inlineStr 1 Note"},
+		{"A2 inlineStr 0   Bolts & <nuts>  ", "B2  0 12", "C2  4 1.5", "D2 b  1", "E2  2 45352", "F2  3 45352.75", "G2 inlineStr 0 line\nbreak"},
+		{"A3 inlineStr 0 Tab\tand \uFFFD control", "B3  0 -3", "C3  4 0.25", "D3 b  0", "G3 inlineStr 0 Đồng ✓"},
+		{"A4 inlineStr 0 NaN cost", "B4  0 0"},
+		{"A5 inlineStr 0 extra values", "B5  0 1099511627776", "D5 b  1", "G5 inlineStr 0 x"},
+	}
+	if len(sheet.Rows) != len(want) {
+		t.Fatalf("got %d rows, want %d", len(sheet.Rows), len(want))
+	}
+	for i, row := range sheet.Rows {
+		if row.R != i+1 {
+			t.Errorf("row %d numbered %d", i+1, row.R)
+		}
+		var got []string
+		for _, c := range row.Cells {
+			value := c.V
+			if c.T == "inlineStr" {
+				value = c.Inline
+			}
+			got = append(got, fmt.Sprintf("%s %s %s %s", c.R, c.T, c.S, value))
+		}
+		if !reflect.DeepEqual(got, want[i]) {
+			t.Errorf("row %d:\ngot  %q\nwant %q", i+1, got, want[i])
+		}
+	}
+}
+
+// Numbers with a fraction get the decimal style, whole ones the general one
+func TestXLSXNumberStyles(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(FormatXLSX, &buf, &Document{Title: "Numbers", Columns: []Column{{Key: "n", Title: "N"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range []interface{}{2.0, 2.5, -0.001, 1e15} {
+		w.WriteRow([]interface{}{n})
+	}
+	w.Close()
+
+	sheet := readXLSXSheet(t, openXLSX(t, buf.Bytes()))
+	want := []string{"0 2", "4 2.5", "4 -0.001", "0 1000000000000000"}
+	for i, row := range sheet.Rows[1:] {
+		if got := row.Cells[0].S + " " + row.Cells[0].V; got != want[i] {
+			t.Errorf("row %d: got %q, want %q", i+2, got, want[i])
+		}
+	}
+}
+
+func TestXLSXManyRows(t *testing.T) {
+	var buf bytes.Buffer
+	doc := &Document{Title: "Movements", Columns: []Column{{Key: "id", Title: "ID"}, {Key: "sku", Title: "SKU"}}}
+	w, err := NewWriter(FormatXLSX, &buf, doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const rows = 10000
+	for i := 0; i < rows; i++ {
+		if err := w.WriteRow([]interface{}{i, fmt.Sprintf("SKU-%05d", i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	sheet := readXLSXSheet(t, openXLSX(t, buf.Bytes()))
+	if len(sheet.Rows) != rows+1 {
+		t.Fatalf("got %d rows, want %d", len(sheet.Rows), rows+1)
+	}
+	last := sheet.Rows[rows]
+	if last.R != rows+1 || last.Cells[1].R != fmt.Sprintf("B%d", rows+1) || last.Cells[1].Inline != "SKU-09999" {
+		t.Errorf("last row %+v", last)
+	}
+}
+
+func TestColumnName(t *testing.T) {
+	tests := map[int]string{0: "A", 1: "B", 25: "Z", 26: "AA", 27: "AB", 51: "AZ", 52: "BA", 701: "ZZ", 702: "AAA", 16383: "XFD"}
+	for i, want := range tests {
+		if got := columnName(i); got != want {
+			t.Errorf("columnName(%d) = %q, want %q", i, got, want)
+		}
+	}
+}
+
+func TestSheetName(t *testing.T) {
+	tests := []struct{ title, want string }{
+		{"Stock Levels", "Stock Levels"},
+		{"  Sales  ", "Sales"},
+		{"a[b]c:d*e?f/g\\h", "a b c d e f g h"},
+		{"", "Report"},
+		{"///", "Report"},
+		{"[Sales]", "Sales"},
+		{"A title that is longer than 30 characters", "A title that is longer than 30"},
+		{"A title that is longer than thirty one characters", "A title that is longer than thi"},
+		{"Tồn kho theo kho hàng và theo tháng", "Tồn kho theo kho hàng và theo t"},
+	}
+	for _, tt := range tests {
+		if got := sheetName(tt.title); got != tt.want {
+			t.Errorf("sheetName(%q) = %q, want %q", tt.title, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+// Package extension lets compiled-in, per-deployment extensions hook document
+// lifecycle events and register extra routes without forking the core use cases.
+//
+// An extension registers itself from an init function and is enabled by
+// blank-importing its package from the server binary:
+//
+//	func init() {
+//		extension.Register(&myExtension{})
+//	}
+package extension
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/logging"
+)
+
+// Event identifies a lifecycle point that hooks can attach to
+type Event string
+
+// Lifecycle events. Before hooks run prior to the state change and can veto it
+// by returning an error; after hooks run once the change is persisted.
+const (
+	// Payload: *entity.SalesOrder
+	EventBeforeOrderConfirm Event = "order.confirm.before"
+	EventAfterOrderConfirm  Event = "order.confirm.after"
+
+	// Payload: *entity.PurchaseReceipt
+	EventBeforeReceiptPost Event = "receipt.post.before"
+	EventAfterReceiptPost  Event = "receipt.post.after"
+
+	// Payload: *entity.Invoice
+	EventBeforeInvoiceIssue Event = "invoice.issue.before"
+	EventAfterInvoiceIssue  Event = "invoice.issue.after"
+
+	// Payload: *entity.VendorRiskAlert
+	EventAfterVendorRiskAlert Event = "vendor.risk.alert.after"
+
+	// Payload: *entity.DunningReminder
+	EventAfterDunningReminder Event = "dunning.reminder.after"
+
+	// Payload: *entity.ElevatedAccessGrant, raised when access is requested and
+	// when a grant is approved, rejected or revoked
+	EventAfterElevationRequest Event = "access.elevation.request.after"
+	EventAfterElevationReview  Event = "access.elevation.review.after"
+)
+
+// HookFunc handles a lifecycle event. The payload type depends on the event.
+type HookFunc func(ctx context.Context, event Event, payload interface{}) error
+
+// RouteFunc registers extra routes on the authenticated API group
+type RouteFunc func(router *gin.RouterGroup)
+
+// Extension is a compiled-in extension
+type Extension interface {
+	// Name uniquely identifies the extension
+	Name() string
+	// Init attaches the extension's hooks and routes
+	Init(hooks *Hooks) error
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Extension)
+)
+
+// Register makes an extension available to the server.
+// It panics if an extension with the same name is already registered.
+func Register(ext Extension) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if ext == nil {
+		panic("extension: Register extension is nil")
+	}
+	if _, dup := registry[ext.Name()]; dup {
+		panic("extension: Register called twice for extension " + ext.Name())
+	}
+	registry[ext.Name()] = ext
+}
+
+// Registered returns the names of the registered extensions in sorted order
+func Registered() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Hooks holds the hooks and routes attached by extensions.
+// A nil *Hooks is valid and fires nothing.
+type Hooks struct {
+	hooks  map[Event][]HookFunc
+	routes []RouteFunc
+}
+
+// NewHooks creates an empty hook set
+func NewHooks() *Hooks {
+	return &Hooks{hooks: make(map[Event][]HookFunc)}
+}
+
+// Load initializes every registered extension, in name order, into a new hook set
+func Load() (*Hooks, error) {
+	names := Registered()
+
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	hooks := NewHooks()
+	for _, name := range names {
+		if err := registry[name].Init(hooks); err != nil {
+			return nil, fmt.Errorf("failed to initialize extension %s: %w", name, err)
+		}
+	}
+	return hooks, nil
+}
+
+// On attaches a hook to an event. Hooks run in the order they were attached.
+func (h *Hooks) On(event Event, fn HookFunc) {
+	h.hooks[event] = append(h.hooks[event], fn)
+}
+
+// Route adds extra routes to be registered on the authenticated API group
+func (h *Hooks) Route(fn RouteFunc) {
+	h.routes = append(h.routes, fn)
+}
+
+// Before runs the hooks of a before event and stops at the first error
+func (h *Hooks) Before(ctx context.Context, event Event, payload interface{}) error {
+	if h == nil {
+		return nil
+	}
+	for _, fn := range h.hooks[event] {
+		if err := fn(ctx, event, payload); err != nil {
+			return fmt.Errorf("%s hook rejected: %w", event, err)
+		}
+	}
+	return nil
+}
+
+// After runs the hooks of an after event. The change is already persisted, so
+// errors are logged rather than returned and every hook gets to run.
+func (h *Hooks) After(ctx context.Context, event Event, payload interface{}) {
+	if h == nil {
+		return
+	}
+	for _, fn := range h.hooks[event] {
+		if err := fn(ctx, event, payload); err != nil {
+			logging.FromContext(ctx).Error("extension hook failed", "event", event, "error", err)
+		}
+	}
+}
+
+// RegisterRoutes registers the extension routes on the given group
+func (h *Hooks) RegisterRoutes(router *gin.RouterGroup) {
+	if h == nil {
+		return
+	}
+	for _, fn := range h.routes {
+		fn(router)
+	}
+}
@@ -0,0 +1,140 @@
+// Package filestore keeps generated and uploaded files, such as report exports
+// and document attachments, on the server's disk or in an S3 compatible
+// bucket, and hands out download URLs that expire. Files are addressed by slash separated keys
+// like "reports/<id>/sales.pdf".
+package filestore
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// File store drivers
+const (
+	DriverLocal = "local"
+	DriverS3    = "s3"
+)
+
+var (
+	ErrNotFound         = errors.New("file not found")
+	ErrInvalidKey       = errors.New("invalid file key")
+	ErrInvalidSignature = errors.New("invalid download signature")
+	ErrURLExpired       = errors.New("download URL expired")
+)
+
+// Object is a stored file opened for reading
+type Object struct {
+	Body        io.ReadCloser
+	ContentType string
+	Size        int64
+	ModTime     time.Time
+}
+
+// Store keeps files by key
+type Store interface {
+	// Put stores the file read from r under the key, replacing any file
+	// stored under it
+	Put(ctx context.Context, key, contentType string, r io.Reader) error
+	// Open opens the file stored under the key; the caller closes its body
+	Open(ctx context.Context, key string) (*Object, error)
+	// Delete removes the file stored under the key, if any
+	Delete(ctx context.Context, key string) error
+	// URL returns a URL the file can be downloaded from without signing in
+	// until the time to live has passed
+	URL(ctx context.Context, key string, ttl time.Duration) (string, error)
+}
+
+// Config holds the settings of the file store
+type Config struct {
+	Driver    string // DriverLocal or DriverS3
+	Dir       string // directory local files are kept in
+	PublicURL string // base URL of the API the download route is served under
+	Secret    string // key download URLs of local files are signed with
+	S3        S3Config
+}
+
+// New creates the configured file store
+func New(cfg Config) (Store, error) {
+	switch cfg.Driver {
+	case "", DriverLocal:
+		local, err := NewLocal(cfg.Dir, NewSigner(cfg.Secret, cfg.PublicURL))
+		if err != nil {
+			return nil, err
+		}
+		return local, nil
+	case DriverS3:
+		s3, err := NewS3(cfg.S3)
+		if err != nil {
+			return nil, err
+		}
+		return s3, nil
+	default:
+		return nil, fmt.Errorf("unknown file store driver %q", cfg.Driver)
+	}
+}
+
+// CleanKey validates a key, returning it without redundant separators
+func CleanKey(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return "", ErrInvalidKey
+	}
+	cleaned := path.Clean(key)
+	if cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", ErrInvalidKey
+	}
+	return cleaned, nil
+}
+
+// Signer signs the download URLs of files served by the API, so that the
+// download route can serve them without a session
+type Signer struct {
+	secret  []byte
+	baseURL string
+}
+
+// NewSigner creates a signer of URLs under baseURL, the URL the API's /files
+// route is served under
+func NewSigner(secret, baseURL string) *Signer {
+	return &Signer{secret: []byte(secret), baseURL: strings.TrimRight(baseURL, "/")}
+}
+
+// Sign returns the URL of a file valid until expires
+func (s *Signer) Sign(key string, expires time.Time) string {
+	unix := strconv.FormatInt(expires.Unix(), 10)
+	query := url.Values{
+		"expires":   {unix},
+		"signature": {s.signature(key, unix)},
+	}
+	return s.baseURL + "/files/" + (&url.URL{Path: key}).EscapedPath() + "?" + query.Encode()
+}
+
+// Verify checks the expiry and signature taken from a download URL
+func (s *Signer) Verify(key, expires, signature string, now time.Time) error {
+	if !hmac.Equal([]byte(signature), []byte(s.signature(key, expires))) {
+		return ErrInvalidSignature
+	}
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if now.Unix() > unix {
+		return ErrURLExpired
+	}
+	return nil
+}
+
+func (s *Signer) signature(key, expires string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(key + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,109 @@
+package filestore
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+var (
+	ErrFileTooLarge       = errors.New("file is too large")
+	ErrFileEmpty          = errors.New("file is empty")
+	ErrContentTypeInvalid = errors.New("file type is not allowed")
+)
+
+// Limits restricts the files users may upload
+type Limits struct {
+	MaxSize      int64    // largest file in bytes; no limit when 0
+	ContentTypes []string // allowed types, e.g. "application/pdf" or "image/*"; any type when empty
+}
+
+// Check validates the size and content type of a file, returning the content
+// type without its parameters
+func (l Limits) Check(contentType string, size int64) (string, error) {
+	if size == 0 {
+		return "", ErrFileEmpty
+	}
+	if l.MaxSize > 0 && size > l.MaxSize {
+		return "", fmt.Errorf("%w: it must not be larger than %s", ErrFileTooLarge, FormatSize(l.MaxSize))
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", ErrContentTypeInvalid
+	}
+	if !l.Allows(mediaType) {
+		return "", fmt.Errorf("%w: %s", ErrContentTypeInvalid, mediaType)
+	}
+	return mediaType, nil
+}
+
+// Allows reports whether a media type is among the allowed types
+func (l Limits) Allows(mediaType string) bool {
+	if len(l.ContentTypes) == 0 {
+		return true
+	}
+	mediaType = strings.ToLower(mediaType)
+	for _, allowed := range l.ContentTypes {
+		allowed = strings.ToLower(strings.TrimSpace(allowed))
+		if allowed == mediaType || allowed == "*/*" {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(allowed, "/*"); ok && strings.HasPrefix(mediaType, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Reader returns a reader failing with ErrFileTooLarge once more than the
+// maximum size has been read from r, for uploads of unknown length
+func (l Limits) Reader(r io.Reader) io.Reader {
+	if l.MaxSize <= 0 {
+		return r
+	}
+	return &limitedReader{r: r, remaining: l.MaxSize + 1}
+}
+
+type limitedReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if int64(len(p)) > l.remaining {
+		p = p[:l.remaining]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining <= 0 {
+		return n, ErrFileTooLarge
+	}
+	return n, err
+}
+
+// SniffContentType tells the type of a file from its first bytes, for uploads
+// whose declared type is missing or generic. It returns the declared type
+// when that is specific.
+func SniffContentType(declared string, head []byte) string {
+	mediaType, _, err := mime.ParseMediaType(declared)
+	if err == nil && mediaType != "application/octet-stream" {
+		return declared
+	}
+	return http.DetectContentType(head)
+}
+
+// FormatSize renders a size in bytes for messages, e.g. "10MB"
+func FormatSize(size int64) string {
+	switch {
+	case size >= 1<<20 && size%(1<<20) == 0:
+		return fmt.Sprintf("%dMB", size>>20)
+	case size >= 1<<10 && size%(1<<10) == 0:
+		return fmt.Sprintf("%dKB", size>>10)
+	default:
+		return fmt.Sprintf("%d bytes", size)
+	}
+}
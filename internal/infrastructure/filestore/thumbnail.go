@@ -0,0 +1,94 @@
+package filestore
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	_ "image/gif" // register the GIF decoder
+	"image/jpeg"
+	"image/png"
+)
+
+// ImageTypes are the content types thumbnails can be made of
+var ImageTypes = []string{"image/jpeg", "image/png", "image/gif"}
+
+var ErrImageInvalid = errors.New("file is not a readable JPEG, PNG or GIF image")
+
+// Thumbnail is a scaled down copy of an image
+type Thumbnail struct {
+	Data        []byte
+	ContentType string
+	Width       int // of the original image
+	Height      int // of the original image
+}
+
+// MakeThumbnail scales an image down to fit in a square of size pixels,
+// averaging the pixels each thumbnail pixel covers. Images that already fit
+// keep their size. JPEG images give JPEG thumbnails; PNG and GIF images,
+// which may be transparent, give PNG ones.
+func MakeThumbnail(data []byte, size int) (*Thumbnail, error) {
+	src, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrImageInvalid
+	}
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width == 0 || height == 0 {
+		return nil, ErrImageInvalid
+	}
+
+	thumbWidth, thumbHeight := width, height
+	if width > size || height > size {
+		if width >= height {
+			thumbWidth, thumbHeight = size, max(height*size/width, 1)
+		} else {
+			thumbWidth, thumbHeight = max(width*size/height, 1), size
+		}
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, thumbWidth, thumbHeight))
+	for y := 0; y < thumbHeight; y++ {
+		y0, y1 := bounds.Min.Y+y*height/thumbHeight, bounds.Min.Y+(y+1)*height/thumbHeight
+		for x := 0; x < thumbWidth; x++ {
+			x0, x1 := bounds.Min.X+x*width/thumbWidth, bounds.Min.X+(x+1)*width/thumbWidth
+			dst.SetNRGBA(x, y, averageColor(src, x0, y0, max(x1, x0+1), max(y1, y0+1)))
+		}
+	}
+
+	thumbnail := &Thumbnail{Width: width, Height: height}
+	var buf bytes.Buffer
+	if format == "jpeg" {
+		err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 85})
+		thumbnail.ContentType = "image/jpeg"
+	} else {
+		err = png.Encode(&buf, dst)
+		thumbnail.ContentType = "image/png"
+	}
+	if err != nil {
+		return nil, err
+	}
+	thumbnail.Data = buf.Bytes()
+	return thumbnail, nil
+}
+
+// averageColor averages the pixels of src within [x0,x1) x [y0,y1),
+// weighting colors by their opacity
+func averageColor(src image.Image, x0, y0, x1, y1 int) color.NRGBA {
+	var r, g, b, a uint64
+	for y := y0; y < y1; y++ {
+		for x := x0; x < x1; x++ {
+			pr, pg, pb, pa := src.At(x, y).RGBA()
+			r, g, b, a = r+uint64(pr), g+uint64(pg), b+uint64(pb), a+uint64(pa)
+		}
+	}
+	if a == 0 {
+		return color.NRGBA{}
+	}
+	n := uint64((x1 - x0) * (y1 - y0))
+	return color.NRGBA{
+		R: uint8(r * 0xff / a),
+		G: uint8(g * 0xff / a),
+		B: uint8(b * 0xff / a),
+		A: uint8(a / n >> 8),
+	}
+}
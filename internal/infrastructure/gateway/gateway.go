@@ -2,18 +2,27 @@ package gateway
 
 import (
 	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
+	"os"
+	"slices"
 	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
 	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/auth"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/broker"
 	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/config"
 	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/gateway/middleware"
 	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/gateway/proxy"
 	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/gateway/websocket"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/realtime"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/tracing"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
@@ -26,6 +35,9 @@ type Gateway struct {
 	jwtService *auth.JWTService
 	server     *http.Server
 	wsHub      *websocket.Hub
+	broker     broker.Broker
+	// Secret the server posts WebSocket events with; events are refused without it
+	eventSecret string
 }
 
 // NewGateway creates a new API Gateway
@@ -39,6 +51,11 @@ func NewGateway(cfg *config.Config) (*Gateway, error) {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
+	// Record OpenTelemetry spans, if enabled
+	if err := tracing.InitFromConfig(cfg.Tracing, "erp-warehouse-gateway"); err != nil {
+		return nil, err
+	}
+
 	// Initialize router
 	router := gin.New()
 
@@ -52,19 +69,45 @@ func NewGateway(cfg *config.Config) (*Gateway, error) {
 	wsHub := websocket.NewHub()
 	go wsHub.Run()
 
+	// Receive the server's WebSocket events from the message broker, if one is
+	// configured. Every gateway instance pushes events to its own clients, so
+	// each consumes all of them in a group of its own.
+	hostname, _ := os.Hostname()
+	messageBroker, err := broker.New(broker.Config{
+		Driver:   cfg.Broker.Driver,
+		URL:      cfg.Broker.URL,
+		Username: cfg.Broker.Username,
+		Password: cfg.Broker.Password,
+		Group:    fmt.Sprintf("%s-gateway-%s-%d", cfg.Broker.Group, hostname, os.Getpid()),
+		Latest:   true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	// Create gateway
 	gateway := &Gateway{
-		config:     &cfg.APIGateway,
-		router:     router,
-		proxy:      serviceProxy,
-		jwtService: jwtService,
-		wsHub:      wsHub,
+		config:      &cfg.APIGateway,
+		router:      router,
+		proxy:       serviceProxy,
+		jwtService:  jwtService,
+		wsHub:       wsHub,
+		broker:      messageBroker,
+		eventSecret: cfg.Realtime.Secret,
 		server: &http.Server{
 			Addr:    fmt.Sprintf(":%s", cfg.APIGateway.Port),
 			Handler: router,
 		},
 	}
 
+	if messageBroker != nil {
+		topic := cfg.Broker.TopicPrefix + realtime.BrokerTopic
+		if err := messageBroker.Subscribe(topic, gateway.consumeEvent); err != nil {
+			messageBroker.Close()
+			return nil, fmt.Errorf("error subscribing to %s: %w", topic, err)
+		}
+	}
+
 	// Setup routes and middleware
 	gateway.setupMiddleware()
 	gateway.setupRoutes()
@@ -77,7 +120,14 @@ func (g *Gateway) setupMiddleware() {
 	// Recovery middleware
 	g.router.Use(gin.Recovery())
 
-	// Logger middleware
+	// Tracing middleware: OpenTelemetry spans continued by the proxied
+	// services, and request IDs
+	g.router.Use(tracing.Middleware())
+	if g.config.Tracing {
+		g.router.Use(middleware.Tracing())
+	}
+
+	// Logger middleware, inside the tracing one for its request ID
 	if g.config.Logging {
 		g.router.Use(middleware.Logger())
 	}
@@ -87,11 +137,6 @@ func (g *Gateway) setupMiddleware() {
 
 	// Rate limiting middleware
 	g.router.Use(middleware.RateLimit(g.config.RateLimit.RequestsPerSecond, g.config.RateLimit.Burst))
-
-	// Tracing middleware
-	if g.config.Tracing {
-		g.router.Use(middleware.Tracing())
-	}
 }
 
 // setupRoutes configures routes for the API Gateway
@@ -107,10 +152,13 @@ func (g *Gateway) setupRoutes() {
 	// Swagger documentation
 	g.router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
-	// WebSocket endpoint
-	g.router.GET("/ws", func(c *gin.Context) {
-		websocket.ServeWs(g.wsHub, c.Writer, c.Request)
-	})
+	// WebSocket endpoint, for users with a valid access token
+	g.router.GET("/ws", g.serveWs)
+
+	// Domain events posted by the server for the WebSocket clients
+	if g.eventSecret != "" {
+		g.router.POST(realtime.EventsPath, g.publishEvent)
+	}
 
 	// API routes
 	api := g.router.Group("/api")
@@ -293,6 +341,23 @@ func (g *Gateway) setupRoutes() {
 				skus.PUT("/bulk", g.proxy.ProxyRequest("sku", "/api/v1/skus/bulk"))
 			}
 
+			// Aggregated routes, answering in one document what takes the
+			// client several requests to the services
+			overview := protected.Group("/overview")
+			{
+				overview.GET("/order/:id", g.orderOverview)
+			}
+
+			// WebSocket connection statistics of this gateway instance
+			protected.GET("/system/websocket", g.websocketStats)
+
+			// Server-Sent Events streams, for clients that cannot open a WebSocket
+			events := protected.Group("/events")
+			{
+				events.GET("/orders", g.proxy.ProxyStream("order", "/api/v1/events/orders"))
+				events.GET("/stocks", g.proxy.ProxyStream("stock", "/api/v1/events/stocks"))
+			}
+
 			// SKU category routes
 			skuCategories := protected.Group("/sku-categories")
 			{
@@ -308,16 +373,146 @@ func (g *Gateway) setupRoutes() {
 	}
 }
 
+// serveWs opens a WebSocket connection for a user with a valid access token,
+// refusing the tokens the API refuses: customer portal tokens, and
+// impersonation tokens whose session has ended, which the auth service is
+// asked about. Browsers cannot set headers on the handshake, so the token may
+// also be passed as the token query parameter.
+func (g *Gateway) serveWs(c *gin.Context) {
+	token := auth.ExtractTokenFromHeader(c.GetHeader("Authorization"))
+	if token == "" {
+		token = c.Query("token")
+	}
+	if token == "" {
+		g.wsHub.Reject()
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Access token is required"})
+		return
+	}
+	claims, err := g.jwtService.ValidateAccessToken(token)
+	if err != nil {
+		g.wsHub.Reject()
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		return
+	}
+	// Portal tokens are scoped to the customer portal
+	if claims.TokenType == auth.TokenTypePortal {
+		g.wsHub.Reject()
+		c.JSON(http.StatusForbidden, gin.H{"error": "Portal token cannot access this resource"})
+		return
+	}
+	// The sessions are the server's: it answers impersonation tokens with 401
+	// once theirs has ended. Connections opened before are closed when the
+	// server hands over the session's end.
+	if claims.ImpersonationID != 0 {
+		c.Request.Header.Set("Authorization", "Bearer "+token)
+		resp, err := g.proxy.Get(c, "auth", "/api/v1/auth/permissions/me")
+		if err == nil && resp.StatusCode == http.StatusUnauthorized {
+			g.wsHub.Reject()
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Impersonation session has ended"})
+			return
+		}
+		if err != nil || resp.StatusCode != http.StatusOK {
+			if err == nil {
+				err = fmt.Errorf("status %d", resp.StatusCode)
+			}
+			slog.ErrorContext(c.Request.Context(), "error checking impersonation session", "impersonation_id", claims.ImpersonationID, "error", err)
+			g.wsHub.Reject()
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to check impersonation session"})
+			return
+		}
+	}
+	websocket.ServeWs(g.wsHub, c.Writer, c.Request, claims)
+}
+
+// websocketStats answers the WebSocket connection statistics of the hub, for
+// users allowed to read the system diagnostics
+func (g *Gateway) websocketStats(c *gin.Context) {
+	permissions, _ := c.Get("permissions")
+	granted, _ := permissions.([]entity.Permission)
+	if !slices.Contains(granted, entity.SystemDatabaseRead) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
+		return
+	}
+	c.JSON(http.StatusOK, g.wsHub.Stats())
+}
+
+// publishEvent pushes a domain event posted by the server to the subscribed
+// WebSocket clients
+func (g *Gateway) publishEvent(c *gin.Context) {
+	if subtle.ConstantTimeCompare([]byte(c.GetHeader(realtime.SecretHeader)), []byte(g.eventSecret)) != 1 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid event secret"})
+		return
+	}
+
+	var event entity.RealtimeEvent
+	if err := c.ShouldBindJSON(&event); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := g.dispatch(&event); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusAccepted)
+}
+
+// consumeEvent pushes a domain event the server published to the message
+// broker to the subscribed WebSocket clients
+func (g *Gateway) consumeEvent(ctx context.Context, msg *broker.Message) error {
+	var event entity.RealtimeEvent
+	if err := json.Unmarshal(msg.Value, &event); err != nil {
+		return fmt.Errorf("error decoding event: %w", err)
+	}
+	return g.dispatch(&event)
+}
+
+// dispatch hands an event of the server to the hub: the end of an
+// impersonation session closes its connections, other events go to the
+// subscribed clients
+func (g *Gateway) dispatch(event *entity.RealtimeEvent) error {
+	if event.Topic == entity.TopicImpersonationEnded {
+		data, err := json.Marshal(event.Data)
+		if err != nil {
+			return fmt.Errorf("error decoding event: %w", err)
+		}
+		var ended entity.ImpersonationEndedEvent
+		if err := json.Unmarshal(data, &ended); err != nil || ended.SessionID == 0 {
+			return fmt.Errorf("invalid %s event", event.Topic)
+		}
+		g.wsHub.EndImpersonation(ended.SessionID)
+		return nil
+	}
+	if !entity.ValidRealtimeTopic(event.Topic) {
+		return fmt.Errorf("unknown topic %q", event.Topic)
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
+	g.wsHub.Publish(event)
+	return nil
+}
+
 // Start starts the API Gateway
 func (g *Gateway) Start() error {
-	log.Printf("API Gateway starting on port %s", g.config.Port)
-	return g.server.ListenAndServe()
+	slog.Info("API Gateway starting", "port", g.config.Port)
+	if err := g.server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
 }
 
 // Stop stops the API Gateway
 func (g *Gateway) Stop(ctx context.Context) error {
-	log.Println("Shutting down API Gateway...")
-	return g.server.Shutdown(ctx)
+	slog.Info("shutting down API Gateway")
+	if g.broker != nil {
+		g.broker.Close()
+	}
+	err := g.server.Shutdown(ctx)
+	if traceErr := tracing.Shutdown(ctx); traceErr != nil {
+		slog.Error("error exporting the last spans", "error", traceErr)
+	}
+	return err
 }
 
 // ServiceStatus represents the status of a service
@@ -0,0 +1,167 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	gorilla "github.com/gorilla/websocket"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/auth"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/broker"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/config"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/gateway/proxy"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/gateway/websocket"
+)
+
+// newWsGateway starts a gateway whose auth service answers the impersonation
+// tokens of the sessions with the status of their ID, and 200 for the others
+func newWsGateway(t *testing.T, sessions map[uint]int) (*Gateway, *auth.JWTService, string) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	jwtService := auth.NewJWTService("access-secret", "refresh-secret")
+
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, err := jwtService.ValidateAccessToken(auth.ExtractTokenFromHeader(r.Header.Get("Authorization")))
+		switch {
+		case r.URL.Path != "/api/v1/auth/permissions/me":
+			w.WriteHeader(http.StatusNotFound)
+		case r.Header.Get("Upgrade") != "":
+			w.WriteHeader(http.StatusBadRequest)
+		case err != nil:
+			w.WriteHeader(http.StatusUnauthorized)
+		case sessions[claims.ImpersonationID] != 0:
+			w.WriteHeader(sessions[claims.ImpersonationID])
+		default:
+			w.Write([]byte(`{"permissions":[]}`))
+		}
+	}))
+	t.Cleanup(api.Close)
+
+	hub := websocket.NewHub()
+	go hub.Run()
+	g := &Gateway{
+		router:     gin.New(),
+		proxy:      proxy.NewServiceProxy(map[string]config.ServiceConfig{"auth": {URL: api.URL, Timeout: 5}}),
+		jwtService: jwtService,
+		wsHub:      hub,
+	}
+	g.router.GET("/ws", g.serveWs)
+	srv := httptest.NewServer(g.router)
+	t.Cleanup(srv.Close)
+	return g, jwtService, "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws"
+}
+
+func staffUser() *entity.User {
+	return &entity.User{ID: 7, Username: "ops", Status: entity.StatusActive, Role: &entity.Role{Name: "warehouse"}}
+}
+
+func impersonationToken(t *testing.T, jwtService *auth.JWTService, id uint) string {
+	t.Helper()
+	token, err := jwtService.GenerateImpersonationToken(&entity.ImpersonationSession{
+		ID:             id,
+		ImpersonatorID: 1,
+		User:           staffUser(),
+		ExpiresAt:      time.Now().Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestServeWsTokens(t *testing.T) {
+	g, jwtService, url := newWsGateway(t, map[uint]int{2: http.StatusUnauthorized, 3: http.StatusServiceUnavailable})
+
+	staff, err := jwtService.GenerateAccessToken(staffUser())
+	if err != nil {
+		t.Fatal(err)
+	}
+	portal, _, err := jwtService.GeneratePortalToken(&entity.Client{ID: 3, Name: "Acme"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		token  string
+		status int
+	}{
+		{"staff token", staff, http.StatusSwitchingProtocols},
+		{"no token", "", http.StatusUnauthorized},
+		{"invalid token", "not-a-token", http.StatusUnauthorized},
+		{"portal token", portal, http.StatusForbidden},
+		{"active impersonation", impersonationToken(t, jwtService, 1), http.StatusSwitchingProtocols},
+		{"ended impersonation", impersonationToken(t, jwtService, 2), http.StatusUnauthorized},
+		{"session check fails", impersonationToken(t, jwtService, 3), http.StatusBadGateway},
+	}
+	rejected := int64(0)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.token != "" {
+				header.Set("Authorization", "Bearer "+tt.token)
+			}
+			conn, resp, err := gorilla.DefaultDialer.Dial(url, header)
+			if conn != nil {
+				conn.Close()
+			}
+			if resp == nil {
+				t.Fatalf("no handshake response: %v", err)
+			}
+			if resp.StatusCode != tt.status {
+				t.Errorf("status %d, want %d", resp.StatusCode, tt.status)
+			}
+			if tt.status != http.StatusSwitchingProtocols {
+				rejected++
+			}
+		})
+	}
+	if got := g.wsHub.Stats().Rejected; got != rejected {
+		t.Errorf("rejected %d handshakes, want %d", got, rejected)
+	}
+}
+
+// The token may come as the query parameter, and the connections of a
+// session are closed once the server hands over its end
+func TestServeWsImpersonationEnded(t *testing.T) {
+	g, jwtService, url := newWsGateway(t, nil)
+	conn, _, err := gorilla.DefaultDialer.Dial(url+"?token="+impersonationToken(t, jwtService, 1), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	other, _, err := gorilla.DefaultDialer.Dial(url+"?token="+impersonationToken(t, jwtService, 2), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	for g.wsHub.Stats().Connections < 2 {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	value, err := json.Marshal(&entity.RealtimeEvent{
+		Topic: entity.TopicImpersonationEnded,
+		Data:  entity.ImpersonationEndedEvent{SessionID: 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.consumeEvent(context.Background(), &broker.Message{Value: value}); err != nil {
+		t.Fatal(err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, _, err = conn.ReadMessage()
+	if !gorilla.IsCloseError(err, websocket.CloseSessionEnded) {
+		t.Fatalf("got %v, want close code %d", err, websocket.CloseSessionEnded)
+	}
+	if stats := g.wsHub.Stats(); stats.Ended != 1 || stats.Connections != 1 {
+		t.Errorf("ended %d, %d connections left, want 1 and 1", stats.Ended, stats.Connections)
+	}
+}
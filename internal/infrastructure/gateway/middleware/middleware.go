@@ -1,33 +1,51 @@
 package middleware
 
 import (
-	"fmt"
+	"log/slog"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/auth"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/tracing"
 	"golang.org/x/time/rate"
 )
 
-// Logger returns a middleware that logs request details
+// Logger returns a middleware that logs every request with its request ID
+// and, once authenticated, its user
 func Logger() gin.HandlerFunc {
-	return gin.LoggerWithConfig(gin.LoggerConfig{
-		Formatter: func(param gin.LogFormatterParams) string {
-			return fmt.Sprintf("[API-GATEWAY] %s | %d | %s | %s | %s %s | %s\n",
-				param.TimeStamp.Format(time.RFC3339),
-				param.StatusCode,
-				param.Latency.String(),
-				param.ClientIP,
-				param.Method,
-				param.Path,
-				param.ErrorMessage,
-			)
-		},
-		Output:    gin.DefaultWriter,
-		SkipPaths: []string{"/health"},
-	})
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		if c.Request.URL.Path == "/health" {
+			return
+		}
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+		args := []any{
+			"request_id", c.GetString("request_id"),
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"status", status,
+			"duration_ms", time.Since(start).Milliseconds(),
+			"client_ip", c.ClientIP(),
+		}
+		if userID, ok := c.Get("user_id"); ok {
+			args = append(args, "user_id", userID)
+		}
+		if len(c.Errors) > 0 {
+			args = append(args, "error", c.Errors.String())
+		}
+		slog.Log(c.Request.Context(), level, "request", args...)
+	}
 }
 
 // CORS returns a middleware that handles CORS
@@ -54,7 +72,7 @@ func CORS() gin.HandlerFunc {
 		if allowOrigin != "" {
 			c.Writer.Header().Set("Access-Control-Allow-Origin", allowOrigin)
 			c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-			c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
+			c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, Idempotency-Key, Last-Event-ID")
 			c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
 		}
 
@@ -68,9 +86,15 @@ func CORS() gin.HandlerFunc {
 	}
 }
 
-// Auth returns a middleware that handles authentication
+// Auth returns a middleware that handles authentication. Requests with an
+// API key are passed on for the API, which holds the keys, to authenticate.
 func Auth(jwtService *auth.JWTService) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if c.GetHeader("X-API-Key") != "" {
+			c.Next()
+			return
+		}
+
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header is required"})
@@ -132,12 +156,21 @@ func CircuitBreaker() gin.HandlerFunc {
 // Tracing returns a middleware that adds tracing capabilities
 func Tracing() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Generate a unique request ID
-		requestID := uuid.New().String()
+		// Use the trace ID as the request ID when the request is traced,
+		// otherwise the client's or a unique one
+		requestID := tracing.TraceIDFromContext(c.Request.Context())
+		if requestID == "" {
+			requestID = c.GetHeader("X-Request-ID")
+		}
+		if requestID == "" || len(requestID) > 128 {
+			requestID = uuid.New().String()
+		}
 		c.Set("request_id", requestID)
 		c.Writer.Header().Set("X-Request-ID", requestID)
 
-		// Add the request ID to all outgoing requests
+		// Add the request ID to all outgoing requests, for the services'
+		// log lines to carry it too
+		c.Request.Header.Set("X-Request-ID", requestID)
 		c.Next()
 	}
 }
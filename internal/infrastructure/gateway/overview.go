@@ -0,0 +1,115 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/gateway/proxy"
+)
+
+// overviewPart is a document an aggregate endpoint fetches from a backend
+// service
+type overviewPart struct {
+	name    string
+	service string
+	path    string // empty when there is nothing to fetch
+
+	resp *proxy.Response
+	err  error
+}
+
+// overviewError tells why a part of an aggregate document is missing
+type overviewError struct {
+	Status int    `json:"status"`
+	Error  string `json:"error"`
+}
+
+func (g *Gateway) fetch(c *gin.Context, part *overviewPart) {
+	if part.path != "" {
+		part.resp, part.err = g.proxy.Get(c, part.service, part.path)
+	}
+}
+
+// document returns the part as fetched, or null with the reason when the
+// service failed or refused it
+func (part *overviewPart) document() (json.RawMessage, *overviewError) {
+	switch {
+	case part.path == "":
+		return nil, nil
+	case part.err != nil:
+		return nil, &overviewError{Status: http.StatusBadGateway, Error: part.err.Error()}
+	case part.resp.StatusCode != http.StatusOK:
+		var body struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(part.resp.Body, &body) != nil || body.Error == "" {
+			body.Error = http.StatusText(part.resp.StatusCode)
+		}
+		return nil, &overviewError{Status: part.resp.StatusCode, Error: body.Error}
+	}
+	return part.resp.Body, nil
+}
+
+// orderOverview answers a sales order with its customer, deliveries and
+// invoices in one document, fetched from the services concurrently; the
+// customer once the order names it. A missing or forbidden order is answered
+// as the order service answered it. Other parts the services fail or refuse,
+// e.g. for lack of permission, are null, with the reason under errors.
+func (g *Gateway) orderOverview(c *gin.Context) {
+	id := c.Param("id")
+	order := &overviewPart{name: "order", service: "order", path: "/api/v1/orders/" + url.PathEscape(id)}
+	client := &overviewPart{name: "client", service: "client"}
+	deliveries := &overviewPart{name: "deliveries", service: "order", path: "/api/v1/orders/deliveries?sales_order_id=" + url.QueryEscape(id)}
+	invoices := &overviewPart{name: "invoices", service: "order", path: "/api/v1/orders/invoices?sales_order_id=" + url.QueryEscape(id)}
+
+	var wg sync.WaitGroup
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		g.fetch(c, order)
+		if order.err != nil || order.resp.StatusCode != http.StatusOK {
+			return
+		}
+		var doc struct {
+			ClientID uint `json:"client_id"`
+		}
+		if json.Unmarshal(order.resp.Body, &doc) == nil && doc.ClientID != 0 {
+			client.path = fmt.Sprintf("/api/v1/clients/%d", doc.ClientID)
+			g.fetch(c, client)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		g.fetch(c, deliveries)
+	}()
+	go func() {
+		defer wg.Done()
+		g.fetch(c, invoices)
+	}()
+	wg.Wait()
+
+	if order.err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": order.err.Error()})
+		return
+	}
+	if order.resp.StatusCode != http.StatusOK {
+		c.Data(order.resp.StatusCode, "application/json; charset=utf-8", order.resp.Body)
+		return
+	}
+
+	overview := gin.H{order.name: order.resp.Body}
+	errs := make(map[string]*overviewError)
+	for _, part := range []*overviewPart{client, deliveries, invoices} {
+		doc, err := part.document()
+		overview[part.name] = doc
+		if err != nil {
+			errs[part.name] = err
+		}
+	}
+	overview["errors"] = errs
+	c.JSON(http.StatusOK, overview)
+}
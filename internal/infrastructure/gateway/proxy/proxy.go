@@ -2,6 +2,8 @@ package proxy
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
@@ -10,25 +12,32 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/config"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/tracing"
 )
 
 // ServiceProxy handles proxying requests to backend services
 type ServiceProxy struct {
 	services map[string]config.ServiceConfig
 	client   *http.Client
+	streams  *http.Client // without a timeout, for event streams
 }
 
 // NewServiceProxy creates a new service proxy
 func NewServiceProxy(services map[string]config.ServiceConfig) *ServiceProxy {
+	// Traced, passing the trace on to the backend services
+	transport := tracing.Transport(&http.Transport{
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 20,
+		IdleConnTimeout:     60 * time.Second,
+	})
 	return &ServiceProxy{
 		services: services,
 		client: &http.Client{
-			Timeout: 30 * time.Second,
-			Transport: &http.Transport{
-				MaxIdleConns:        100,
-				MaxIdleConnsPerHost: 20,
-				IdleConnTimeout:     60 * time.Second,
-			},
+			Timeout:   30 * time.Second,
+			Transport: transport,
+		},
+		streams: &http.Client{
+			Transport: transport,
 		},
 	}
 }
@@ -66,7 +75,7 @@ func (p *ServiceProxy) ProxyRequest(serviceName, path string) gin.HandlerFunc {
 			c.Request.Body = io.NopCloser(bytes.NewBuffer(reqBody))
 		}
 
-		req, err := http.NewRequest(c.Request.Method, targetURL, bytes.NewBuffer(reqBody))
+		req, err := http.NewRequestWithContext(c.Request.Context(), c.Request.Method, targetURL, bytes.NewBuffer(reqBody))
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"error": fmt.Sprintf("Failed to create request: %v", err),
@@ -74,36 +83,62 @@ func (p *ServiceProxy) ProxyRequest(serviceName, path string) gin.HandlerFunc {
 			return
 		}
 
-		// Copy headers
-		for key, values := range c.Request.Header {
+		forwardHeaders(c, req)
+
+		// Execute the request
+		resp, err := p.client.Do(req)
+		if err != nil {
+			c.JSON(http.StatusBadGateway, gin.H{
+				"error": fmt.Sprintf("Service %s unavailable: %v", serviceName, err),
+			})
+			return
+		}
+		defer resp.Body.Close()
+
+		// Copy response headers
+		for key, values := range resp.Header {
 			for _, value := range values {
-				req.Header.Add(key, value)
+				c.Writer.Header().Add(key, value)
 			}
 		}
 
-		// Add X-Forwarded headers
-		req.Header.Set("X-Forwarded-For", c.ClientIP())
-		req.Header.Set("X-Forwarded-Proto", c.Request.Proto)
-		req.Header.Set("X-Forwarded-Host", c.Request.Host)
+		// Copy response status code
+		c.Writer.WriteHeader(resp.StatusCode)
 
-		// Add tracing headers if available
-		if requestID, exists := c.Get("request_id"); exists {
-			req.Header.Set("X-Request-ID", requestID.(string))
-		}
+		// Copy response body
+		io.Copy(c.Writer, resp.Body)
+	}
+}
 
-		// Add user context if available
-		if userID, exists := c.Get("user_id"); exists {
-			req.Header.Set("X-User-ID", fmt.Sprintf("%v", userID))
+// ProxyStream returns a handler that proxies a Server-Sent Events stream of a
+// backend service, passing each event on as it arrives. The stream stays open
+// until the client or the service closes it.
+func (p *ServiceProxy) ProxyStream(serviceName, path string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		service, exists := p.services[serviceName]
+		if !exists {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": fmt.Sprintf("Service %s not configured", serviceName),
+			})
+			return
 		}
-		if username, exists := c.Get("username"); exists {
-			req.Header.Set("X-Username", username.(string))
+
+		targetURL := service.URL + path
+		if c.Request.URL.RawQuery != "" {
+			targetURL += "?" + c.Request.URL.RawQuery
 		}
-		if role, exists := c.Get("role"); exists {
-			req.Header.Set("X-Role", role.(string))
+		req, err := http.NewRequestWithContext(c.Request.Context(), http.MethodGet, targetURL, nil)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": fmt.Sprintf("Failed to create request: %v", err),
+			})
+			return
 		}
+		forwardHeaders(c, req)
+		// Events must come as they are sent, not compressed in blocks
+		req.Header.Del("Accept-Encoding")
 
-		// Execute the request
-		resp, err := p.client.Do(req)
+		resp, err := p.streams.Do(req)
 		if err != nil {
 			c.JSON(http.StatusBadGateway, gin.H{
 				"error": fmt.Sprintf("Service %s unavailable: %v", serviceName, err),
@@ -112,18 +147,110 @@ func (p *ServiceProxy) ProxyRequest(serviceName, path string) gin.HandlerFunc {
 		}
 		defer resp.Body.Close()
 
-		// Copy response headers
 		for key, values := range resp.Header {
 			for _, value := range values {
 				c.Writer.Header().Add(key, value)
 			}
 		}
-
-		// Copy response status code
 		c.Writer.WriteHeader(resp.StatusCode)
+		c.Writer.Flush()
 
-		// Copy response body
-		io.Copy(c.Writer, resp.Body)
+		buf := make([]byte, 4096)
+		for {
+			n, err := resp.Body.Read(buf)
+			if n > 0 {
+				if _, werr := c.Writer.Write(buf[:n]); werr != nil {
+					return
+				}
+				c.Writer.Flush()
+			}
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Response is the answer of a backend service to a request the gateway made
+// itself
+type Response struct {
+	StatusCode int
+	Body       json.RawMessage
+}
+
+// Get requests path, which may hold a query string, from a backend service on
+// behalf of the client of c, with its credentials, and reads the answer. The
+// request is bounded by the service's timeout.
+func (p *ServiceProxy) Get(c *gin.Context, serviceName, path string) (*Response, error) {
+	service, exists := p.services[serviceName]
+	if !exists {
+		return nil, fmt.Errorf("service %s not configured", serviceName)
+	}
+
+	ctx := c.Request.Context()
+	if service.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(service.Timeout)*time.Second)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, service.URL+path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	forwardHeaders(c, req)
+	// The body is read here, so it must come as is and in full
+	req.Header.Del("Accept-Encoding")
+	req.Header.Del("If-None-Match")
+	req.Header.Del("Content-Type")
+	req.Header.Del("Content-Length")
+	// Nor does it upgrade the connection, as the WebSocket handshakes asking
+	// the services do
+	for _, header := range []string{"Connection", "Upgrade", "Sec-WebSocket-Key", "Sec-WebSocket-Version", "Sec-WebSocket-Extensions", "Sec-WebSocket-Protocol"} {
+		req.Header.Del(header)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("service %s unavailable: %w", serviceName, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the answer of service %s: %w", serviceName, err)
+	}
+	return &Response{StatusCode: resp.StatusCode, Body: body}, nil
+}
+
+// forwardHeaders copies the headers of the client's request to a request to
+// a backend service, with the client's address and the authenticated user
+func forwardHeaders(c *gin.Context, req *http.Request) {
+	// Copy headers
+	for key, values := range c.Request.Header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+
+	// Add X-Forwarded headers
+	req.Header.Set("X-Forwarded-For", c.ClientIP())
+	req.Header.Set("X-Forwarded-Proto", c.Request.Proto)
+	req.Header.Set("X-Forwarded-Host", c.Request.Host)
+
+	// Add tracing headers if available
+	if requestID, exists := c.Get("request_id"); exists {
+		req.Header.Set("X-Request-ID", requestID.(string))
+	}
+
+	// Add user context if available
+	if userID, exists := c.Get("user_id"); exists {
+		req.Header.Set("X-User-ID", fmt.Sprintf("%v", userID))
+	}
+	if username, exists := c.Get("username"); exists {
+		req.Header.Set("X-Username", username.(string))
+	}
+	if role, exists := c.Get("role"); exists {
+		req.Header.Set("X-Role", role.(string))
 	}
 }
 
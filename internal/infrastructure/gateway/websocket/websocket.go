@@ -1,11 +1,17 @@
 package websocket
 
 import (
-	"log"
+	"encoding/json"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"sort"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/auth"
 )
 
 const (
@@ -20,6 +26,14 @@ const (
 
 	// Maximum message size allowed from peer
 	maxMessageSize = 512
+
+	// CloseTokenExpired closes connections whose access token expired; the
+	// client reconnects with a refreshed token
+	CloseTokenExpired = 4001
+
+	// CloseSessionEnded closes the connections of an impersonation session
+	// once it is ended; reconnecting with its token is refused
+	CloseSessionEnded = 4002
 )
 
 var upgrader = websocket.Upgrader{
@@ -31,11 +45,82 @@ var upgrader = websocket.Upgrader{
 	},
 }
 
+// Client message types
+const (
+	MessageSubscribe     = "subscribe"
+	MessageUnsubscribe   = "unsubscribe"
+	MessageSubscriptions = "subscriptions" // reply listing the client's topics
+	MessageError         = "error"
+)
+
 // Client represents a WebSocket client
 type Client struct {
 	hub  *Hub
 	conn *websocket.Conn
 	send chan []byte
+
+	// User the connection was opened for, with the permissions and access
+	// scope of their token, which expires at expires unless it is zero
+	userID      uint
+	permissions map[entity.Permission]bool
+	scope       entity.AccessScope
+	expires     time.Time
+
+	// Impersonation session of the token, if any
+	impersonationID uint
+
+	// Code of the close message sent once the hub closes send, if any; set
+	// by the hub before closing it
+	closeCode int
+
+	// Subscribed topics, only accessed by the hub
+	topics map[entity.RealtimeTopic]bool
+}
+
+// receives reports whether an event is delivered to the client: it must be
+// subscribed to the topic, hold the event's permission, have the event's
+// store in its access scope and be one of its users
+func (c *Client) receives(event *entity.RealtimeEvent) bool {
+	if !c.topics[event.Topic] {
+		return false
+	}
+	if event.Permission != "" && !c.permissions[event.Permission] {
+		return false
+	}
+	if event.StoreID != "" && !c.scope.AllowsStore(event.StoreID) {
+		return false
+	}
+	if len(event.UserIDs) == 0 {
+		return true
+	}
+	for _, userID := range event.UserIDs {
+		if userID != 0 && userID == c.userID {
+			return true
+		}
+	}
+	return false
+}
+
+// maySubscribe reports whether the client holds one of the permissions the
+// topic requires
+func (c *Client) maySubscribe(topic entity.RealtimeTopic) bool {
+	required := entity.RealtimeTopicPermissions[topic]
+	if len(required) == 0 {
+		return true
+	}
+	for _, permission := range required {
+		if c.permissions[permission] {
+			return true
+		}
+	}
+	return false
+}
+
+// subscription is a request from a client to change its topics
+type subscription struct {
+	client *Client
+	action string
+	topics []entity.RealtimeTopic
 }
 
 // Hub maintains the set of active clients and broadcasts messages to them
@@ -51,15 +136,55 @@ type Hub struct {
 
 	// Unregister requests from clients
 	unregister chan *Client
+
+	// Topic subscription changes from the clients
+	subscriptions chan *subscription
+
+	// Domain events for the subscribed clients
+	events chan *entity.RealtimeEvent
+
+	// Impersonation sessions whose connections are closed
+	endings chan uint
+
+	// Requests for the connection statistics
+	stats chan chan Stats
+
+	// Counters since the hub started
+	opened    atomic.Int64
+	rejected  atomic.Int64
+	expired   atomic.Int64
+	ended     atomic.Int64
+	dropped   atomic.Int64
+	delivered atomic.Int64
+	pongs     atomic.Int64
+}
+
+// Stats describes the WebSocket connections of a gateway instance, now and
+// since it started
+type Stats struct {
+	Connections     int            `json:"connections"`
+	Users           int            `json:"users"`            // distinct users connected
+	Subscriptions   map[string]int `json:"subscriptions"`    // connections subscribed to each topic
+	Opened          int64          `json:"opened"`           // connections accepted
+	Rejected        int64          `json:"rejected"`         // handshakes refused for a missing or refused token
+	Expired         int64          `json:"expired"`          // connections closed as their token expired
+	Ended           int64          `json:"ended"`            // connections closed as their impersonation session ended
+	Dropped         int64          `json:"dropped"`          // connections dropped for not keeping up with their messages
+	EventsDelivered int64          `json:"events_delivered"` // events queued for a connection
+	PongsReceived   int64          `json:"pongs_received"`   // answers to the keepalive pings
 }
 
 // NewHub creates a new hub
 func NewHub() *Hub {
 	return &Hub{
-		broadcast:  make(chan []byte),
-		register:   make(chan *Client),
-		unregister: make(chan *Client),
-		clients:    make(map[*Client]bool),
+		broadcast:     make(chan []byte),
+		register:      make(chan *Client),
+		unregister:    make(chan *Client),
+		subscriptions: make(chan *subscription),
+		events:        make(chan *entity.RealtimeEvent, 256),
+		endings:       make(chan uint, 16),
+		stats:         make(chan chan Stats),
+		clients:       make(map[*Client]bool),
 	}
 }
 
@@ -76,23 +201,156 @@ func (h *Hub) Run() {
 			}
 		case message := <-h.broadcast:
 			for client := range h.clients {
-				select {
-				case client.send <- message:
-				default:
+				h.deliver(client, message)
+			}
+		case sub := <-h.subscriptions:
+			if _, ok := h.clients[sub.client]; ok {
+				h.subscribe(sub)
+			}
+		case event := <-h.events:
+			message, err := json.Marshal(Event{Type: string(event.Topic), Timestamp: event.Timestamp, Data: event.Data})
+			if err != nil {
+				slog.Error("error encoding WebSocket event", "topic", event.Topic, "error", err)
+				continue
+			}
+			for client := range h.clients {
+				if client.receives(event) {
+					h.deliver(client, message)
+					h.delivered.Add(1)
+				}
+			}
+		case id := <-h.endings:
+			for client := range h.clients {
+				if client.impersonationID == id {
+					client.closeCode = CloseSessionEnded
 					close(client.send)
 					delete(h.clients, client)
+					h.ended.Add(1)
 				}
 			}
+		case reply := <-h.stats:
+			reply <- h.collectStats()
 		}
 	}
 }
 
+// collectStats counts the connected clients, their users and subscriptions
+func (h *Hub) collectStats() Stats {
+	stats := Stats{
+		Connections:     len(h.clients),
+		Subscriptions:   make(map[string]int, len(entity.RealtimeTopics)),
+		Opened:          h.opened.Load(),
+		Rejected:        h.rejected.Load(),
+		Expired:         h.expired.Load(),
+		Ended:           h.ended.Load(),
+		Dropped:         h.dropped.Load(),
+		EventsDelivered: h.delivered.Load(),
+		PongsReceived:   h.pongs.Load(),
+	}
+	for _, topic := range entity.RealtimeTopics {
+		stats.Subscriptions[string(topic)] = 0
+	}
+	users := make(map[uint]bool)
+	for client := range h.clients {
+		users[client.userID] = true
+		for topic := range client.topics {
+			stats.Subscriptions[string(topic)]++
+		}
+	}
+	stats.Users = len(users)
+	return stats
+}
+
+// Stats returns the connection statistics of the hub
+func (h *Hub) Stats() Stats {
+	reply := make(chan Stats, 1)
+	h.stats <- reply
+	return <-reply
+}
+
+// Reject counts a handshake refused before the connection was opened
+func (h *Hub) Reject() {
+	h.rejected.Add(1)
+}
+
+// deliver queues a message for a client, dropping the client when it is not
+// keeping up
+func (h *Hub) deliver(client *Client, message []byte) {
+	select {
+	case client.send <- message:
+	default:
+		close(client.send)
+		delete(h.clients, client)
+		h.dropped.Add(1)
+	}
+}
+
+// subscribe applies a subscription change and replies with the client's topics
+func (h *Hub) subscribe(sub *subscription) {
+	reply := Message{Type: MessageSubscriptions}
+	switch sub.action {
+	case MessageSubscribe, MessageUnsubscribe:
+		for _, topic := range sub.topics {
+			if !entity.ValidRealtimeTopic(topic) {
+				reply = Message{Type: MessageError, Payload: fmt.Sprintf("unknown topic %q", topic)}
+				break
+			}
+			if sub.action == MessageSubscribe && !sub.client.maySubscribe(topic) {
+				reply = Message{Type: MessageError, Payload: fmt.Sprintf("not permitted to subscribe to %q", topic)}
+				break
+			}
+		}
+		if reply.Type == MessageError {
+			break
+		}
+		for _, topic := range sub.topics {
+			if sub.action == MessageSubscribe {
+				sub.client.topics[topic] = true
+			} else {
+				delete(sub.client.topics, topic)
+			}
+		}
+	default:
+		reply = Message{Type: MessageError, Payload: fmt.Sprintf("unknown message type %q", sub.action)}
+	}
+
+	if reply.Type == MessageSubscriptions {
+		topics := make([]string, 0, len(sub.client.topics))
+		for topic := range sub.client.topics {
+			topics = append(topics, string(topic))
+		}
+		sort.Strings(topics)
+		reply.Payload = map[string]interface{}{"topics": topics}
+	}
+
+	message, err := json.Marshal(reply)
+	if err != nil {
+		slog.Error("error encoding subscription reply", "user_id", sub.client.userID, "error", err)
+		return
+	}
+	h.deliver(sub.client, message)
+}
+
 // Broadcast sends a message to all connected clients
 func (h *Hub) Broadcast(message []byte) {
 	h.broadcast <- message
 }
 
-// readPump pumps messages from the WebSocket connection to the hub
+// Publish delivers a domain event to the clients subscribed to its topic that
+// hold its permission and, when it names users, belong to one of them
+func (h *Hub) Publish(event *entity.RealtimeEvent) {
+	h.events <- event
+}
+
+// EndImpersonation closes the connections opened with the token of an
+// impersonation session, which was ended
+func (h *Hub) EndImpersonation(id uint) {
+	h.endings <- id
+}
+
+// readPump pumps subscription messages from the WebSocket connection to the
+// hub. Clients send {"type": "subscribe", "payload": {"topics": [...]}} and
+// "unsubscribe" likewise.
 func (c *Client) readPump() {
 	defer func() {
 		c.hub.unregister <- c
@@ -100,33 +358,63 @@ func (c *Client) readPump() {
 	}()
 	c.conn.SetReadLimit(maxMessageSize)
 	c.conn.SetReadDeadline(time.Now().Add(pongWait))
-	c.conn.SetPongHandler(func(string) error { c.conn.SetReadDeadline(time.Now().Add(pongWait)); return nil })
+	c.conn.SetPongHandler(func(string) error {
+		c.hub.pongs.Add(1)
+		c.conn.SetReadDeadline(time.Now().Add(pongWait))
+		return nil
+	})
 	for {
 		_, message, err := c.conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				log.Printf("error: %v", err)
+				slog.Warn("WebSocket connection closed unexpectedly", "user_id", c.userID, "error", err)
 			}
 			break
 		}
-		c.hub.broadcast <- message
+
+		var request struct {
+			Type    string `json:"type"`
+			Payload struct {
+				Topics []entity.RealtimeTopic `json:"topics"`
+			} `json:"payload"`
+		}
+		if err := json.Unmarshal(message, &request); err != nil {
+			request.Type = ""
+		}
+		c.hub.subscriptions <- &subscription{client: c, action: request.Type, topics: request.Payload.Topics}
 	}
 }
 
-// writePump pumps messages from the hub to the WebSocket connection
+// writePump pumps messages from the hub to the WebSocket connection, pinging
+// the client to keep the connection alive until its token expires
 func (c *Client) writePump() {
 	ticker := time.NewTicker(pingPeriod)
 	defer func() {
 		ticker.Stop()
 		c.conn.Close()
 	}()
+	var expired <-chan time.Time
+	if !c.expires.IsZero() {
+		expiry := time.NewTimer(time.Until(c.expires))
+		defer expiry.Stop()
+		expired = expiry.C
+	}
 	for {
 		select {
+		case <-expired:
+			c.hub.expired.Add(1)
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(CloseTokenExpired, "token expired"))
+			return
 		case message, ok := <-c.send:
 			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
 			if !ok {
 				// The hub closed the channel
-				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				message := []byte{}
+				if c.closeCode == CloseSessionEnded {
+					message = websocket.FormatCloseMessage(CloseSessionEnded, "impersonation session ended")
+				}
+				c.conn.WriteMessage(websocket.CloseMessage, message)
 				return
 			}
 
@@ -155,14 +443,33 @@ func (c *Client) writePump() {
 	}
 }
 
-// ServeWs handles WebSocket requests from clients
-func ServeWs(hub *Hub, w http.ResponseWriter, r *http.Request) {
+// ServeWs handles WebSocket requests from clients. Claims are those of the
+// validated access token of the user the connection is opened for; the
+// connection is closed with CloseTokenExpired once the token expires.
+func ServeWs(hub *Hub, w http.ResponseWriter, r *http.Request, claims *auth.Claims) {
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		log.Println(err)
+		slog.Warn("error upgrading WebSocket connection", "error", err)
 		return
 	}
-	client := &Client{hub: hub, conn: conn, send: make(chan []byte, 256)}
+	client := &Client{
+		hub:         hub,
+		conn:        conn,
+		send:        make(chan []byte, 256),
+		userID:      claims.UserID,
+		permissions: make(map[entity.Permission]bool, len(claims.Permissions)),
+		scope:       claims.AccessScope,
+		topics:      make(map[entity.RealtimeTopic]bool),
+
+		impersonationID: claims.ImpersonationID,
+	}
+	for _, p := range claims.Permissions {
+		client.permissions[p] = true
+	}
+	if claims.ExpiresAt != nil {
+		client.expires = claims.ExpiresAt.Time
+	}
+	hub.opened.Add(1)
 	client.hub.register <- client
 
 	// Allow collection of memory referenced by the caller by doing all work in
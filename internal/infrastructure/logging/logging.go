@@ -0,0 +1,57 @@
+// Package logging writes structured log lines with log/slog. Requests and
+// jobs carry a logger in their context, tagged with the IDs of the request,
+// user and entities they concern, so that every line they log can be found
+// by any of them.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/config"
+)
+
+type loggerKey struct{}
+
+// Init makes the default logger write lines of cfg.Level and above in
+// cfg.Format. Lines of the standard log package go through it too, at info
+// level.
+func Init(cfg config.LoggingConfig) error {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(cfg.Level)); err != nil {
+		return fmt.Errorf("unknown log level %q", cfg.Level)
+	}
+	options := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler
+	switch strings.ToLower(cfg.Format) {
+	case "", "text":
+		handler = slog.NewTextHandler(os.Stderr, options)
+	case "json":
+		handler = slog.NewJSONHandler(os.Stderr, options)
+	default:
+		return fmt.Errorf("unknown log format %q", cfg.Format)
+	}
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
+
+// With returns a context whose logger adds args, as slog key-value pairs, to
+// every line logged with it
+func With(ctx context.Context, args ...any) context.Context {
+	return context.WithValue(ctx, loggerKey{}, FromContext(ctx).With(args...))
+}
+
+// FromContext returns the logger of ctx, or the default logger when ctx has
+// none
+func FromContext(ctx context.Context) *slog.Logger {
+	if ctx != nil {
+		if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+			return logger
+		}
+	}
+	return slog.Default()
+}
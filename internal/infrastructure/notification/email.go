@@ -0,0 +1,121 @@
+package notification
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+)
+
+// EmailSender delivers plain text email, with any attachments, through an SMTP
+// server. The connection is upgraded with STARTTLS when the server offers it.
+type EmailSender struct {
+	addr string
+	host string
+	auth smtp.Auth
+	from string
+}
+
+// NewEmailSender creates an email sender for the SMTP server at host:port.
+// Authentication is skipped when the username is empty.
+func NewEmailSender(host string, port int, username, password, from string) *EmailSender {
+	if port == 0 {
+		port = 587
+	}
+	sender := &EmailSender{
+		addr: net.JoinHostPort(host, strconv.Itoa(port)),
+		host: host,
+		from: from,
+	}
+	if username != "" {
+		sender.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return sender
+}
+
+// Channel returns the email channel
+func (s *EmailSender) Channel() entity.NotificationChannel {
+	return entity.ChannelEmail
+}
+
+// Send delivers a message to an email address
+func (s *EmailSender) Send(ctx context.Context, msg *Message) error {
+	if msg.To == "" {
+		return ErrNoAddress
+	}
+
+	// net/smtp takes no context, so the deadline is only honored between calls
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(s.addr, s.auth, s.from, []string{msg.To}, s.compose(msg))
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("error sending email: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// compose builds the RFC 5322 message, a multipart/mixed one when files are
+// attached
+func (s *EmailSender) compose(msg *Message) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + s.from + "\r\n")
+	b.WriteString("To: " + msg.To + "\r\n")
+	b.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", msg.Subject) + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	body := strings.ReplaceAll(msg.Body, "\n", "\r\n")
+	if len(msg.Attachments) == 0 {
+		b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+		b.WriteString("\r\n")
+		b.WriteString(body)
+		return []byte(b.String())
+	}
+
+	parts := multipart.NewWriter(&b)
+	b.WriteString("Content-Type: multipart/mixed; boundary=" + parts.Boundary() + "\r\n")
+	b.WriteString("\r\n")
+
+	text, _ := parts.CreatePart(textproto.MIMEHeader{"Content-Type": {"text/plain; charset=utf-8"}})
+	io.WriteString(text, body)
+	for _, attachment := range msg.Attachments {
+		contentType := attachment.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		file, _ := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {mime.FormatMediaType(contentType, map[string]string{"name": attachment.Name})},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Name})},
+			"Content-Transfer-Encoding": {"base64"},
+		})
+		writeBase64Lines(file, attachment.Data)
+	}
+	parts.Close()
+	return []byte(b.String())
+}
+
+// writeBase64Lines writes data base64 encoded in lines of 76 characters, as
+// RFC 2045 requires
+func writeBase64Lines(w io.Writer, data []byte) {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 76 {
+		io.WriteString(w, encoded[:76]+"\r\n")
+		encoded = encoded[76:]
+	}
+	io.WriteString(w, encoded+"\r\n")
+}
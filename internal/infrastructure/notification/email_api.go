@@ -0,0 +1,92 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+)
+
+// emailAPITimeout bounds the time spent handing one email to the provider
+const emailAPITimeout = 30 * time.Second
+
+// EmailAPISender hands email to a transactional email provider over HTTP
+// instead of an SMTP server. Each email is posted as
+//
+//	{"from": "...", "to": "...", "subject": "...", "text": "...",
+//	 "attachments": [{"filename": "...", "content_type": "...", "content": "<base64>"}]}
+//
+// with the token, when set, as a bearer Authorization header. Providers with
+// another format are reached through a small relay, as for SMS.
+type EmailAPISender struct {
+	url    string
+	token  string
+	from   string
+	client *http.Client
+}
+
+// NewEmailAPISender creates an email sender posting to the given provider URL
+func NewEmailAPISender(url, token, from string) *EmailAPISender {
+	return &EmailAPISender{
+		url:    url,
+		token:  token,
+		from:   from,
+		client: &http.Client{Timeout: emailAPITimeout},
+	}
+}
+
+// Channel returns the email channel
+func (s *EmailAPISender) Channel() entity.NotificationChannel {
+	return entity.ChannelEmail
+}
+
+type emailAPIAttachment struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Content     []byte `json:"content"` // base64 encoded by encoding/json
+}
+
+// Send delivers a message to an email address
+func (s *EmailAPISender) Send(ctx context.Context, msg *Message) error {
+	if msg.To == "" {
+		return ErrNoAddress
+	}
+
+	attachments := make([]emailAPIAttachment, len(msg.Attachments))
+	for i, attachment := range msg.Attachments {
+		attachments[i] = emailAPIAttachment{Filename: attachment.Name, ContentType: attachment.ContentType, Content: attachment.Data}
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"from":        s.from,
+		"to":          msg.To,
+		"subject":     msg.Subject,
+		"text":        msg.Body,
+		"attachments": attachments,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending email: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("email provider responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
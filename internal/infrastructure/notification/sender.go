@@ -0,0 +1,67 @@
+// Package notification adapts the external channels notifications are
+// delivered through. A sender takes a rendered message to one address, so the
+// notification use case never sees how a channel is implemented.
+package notification
+
+import (
+	"context"
+	"errors"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+)
+
+// ErrNoAddress is returned for recipients without an address on a channel
+var ErrNoAddress = errors.New("recipient has no address on the channel")
+
+// Message is a notification rendered for one recipient
+type Message struct {
+	To          string // email address or phone number
+	Subject     string
+	Body        string
+	Attachments []Attachment // files sent along with an email; not sent by SMS
+}
+
+// Attachment is a file attached to an email, such as a purchase order PDF
+type Attachment struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+// Sender delivers messages on one channel
+type Sender interface {
+	// Channel identifies the channel the sender delivers on
+	Channel() entity.NotificationChannel
+	// Send delivers a message, returning once the provider accepted it
+	Send(ctx context.Context, msg *Message) error
+}
+
+// Config holds the settings of the channel adapters. Channels whose settings
+// are missing are disabled.
+type Config struct {
+	SMTPHost      string
+	SMTPPort      int
+	SMTPUsername  string
+	SMTPPassword  string
+	EmailFrom     string
+	EmailAPIURL   string // email provider API emails are posted to instead of the SMTP server
+	EmailAPIToken string
+
+	SMSWebhookURL string
+	SMSToken      string
+}
+
+// NewSenders returns the senders of the configured channels
+func NewSenders(cfg Config) []Sender {
+	var senders []Sender
+	switch {
+	case cfg.EmailAPIURL != "" && cfg.EmailFrom != "":
+		senders = append(senders, NewEmailAPISender(cfg.EmailAPIURL, cfg.EmailAPIToken, cfg.EmailFrom))
+	case cfg.SMTPHost != "" && cfg.EmailFrom != "":
+		senders = append(senders, NewEmailSender(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.EmailFrom))
+	}
+	if cfg.SMSWebhookURL != "" {
+		senders = append(senders, NewSMSSender(cfg.SMSWebhookURL, cfg.SMSToken))
+	}
+	return senders
+}
@@ -0,0 +1,73 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+)
+
+// smsTimeout bounds the time spent handing one message to the SMS gateway
+const smsTimeout = 10 * time.Second
+
+// SMSSender hands text messages to an SMS gateway over HTTP. Each message is
+// posted as
+//
+//	{"to": "+15550100", "body": "..."}
+//
+// with the token, when set, as a bearer Authorization header. Providers with
+// another format are reached through a small relay.
+type SMSSender struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+// NewSMSSender creates an SMS sender posting to the given gateway URL
+func NewSMSSender(url, token string) *SMSSender {
+	return &SMSSender{
+		url:    url,
+		token:  token,
+		client: &http.Client{Timeout: smsTimeout},
+	}
+}
+
+// Channel returns the SMS channel
+func (s *SMSSender) Channel() entity.NotificationChannel {
+	return entity.ChannelSMS
+}
+
+// Send delivers a message to a phone number
+func (s *SMSSender) Send(ctx context.Context, msg *Message) error {
+	if msg.To == "" {
+		return ErrNoAddress
+	}
+
+	body, err := json.Marshal(map[string]string{"to": msg.To, "body": msg.Body})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending SMS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("SMS gateway responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package payment
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+)
+
+// GenericSignatureHeader carries the signature of generic provider webhooks
+const GenericSignatureHeader = "Webhook-Signature"
+
+// GenericProvider handles Stripe-style webhooks. Requests are signed with
+//
+//	Webhook-Signature: t=<unix time>,v1=<hex HMAC-SHA256 of "<t>.<body>">
+//
+// where several v1 entries may be sent while the secret is rotated, and the body is
+//
+//	{"id": "evt_1", "type": "payment.succeeded", "created": 1700000000,
+//	 "data": {"object": {"id": "pay_1", "amount": 12500, "currency": "usd",
+//	   "metadata": {"invoice_id": "42"}}}}
+//
+// with the amount in minor units. The invoice is found by the invoice_id or
+// invoice_number metadata key.
+type GenericProvider struct {
+	secret    []byte
+	tolerance time.Duration
+}
+
+// NewGenericProvider creates a generic provider verifying with the given secret
+func NewGenericProvider(secret string, tolerance time.Duration) *GenericProvider {
+	return &GenericProvider{
+		secret:    []byte(secret),
+		tolerance: tolerance,
+	}
+}
+
+type genericEvent struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Created int64  `json:"created"`
+	Data    struct {
+		Object struct {
+			ID       string            `json:"id"`
+			Amount   int64             `json:"amount"`
+			Currency string            `json:"currency"`
+			Metadata map[string]string `json:"metadata"`
+		} `json:"object"`
+	} `json:"data"`
+}
+
+// Name returns the provider name
+func (p *GenericProvider) Name() string {
+	return "generic"
+}
+
+// ParseEvent verifies and parses a generic webhook request
+func (p *GenericProvider) ParseEvent(header http.Header, body []byte) (*entity.PaymentGatewayEvent, error) {
+	if err := p.verify(header.Get(GenericSignatureHeader), body, time.Now()); err != nil {
+		return nil, err
+	}
+
+	var raw genericEvent
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformedEvent, err)
+	}
+	if raw.ID == "" || raw.Type == "" || raw.Data.Object.ID == "" {
+		return nil, fmt.Errorf("%w: id, type and data.object.id are required", ErrMalformedEvent)
+	}
+
+	object := raw.Data.Object
+	event := &entity.PaymentGatewayEvent{
+		ID:               raw.ID,
+		Type:             entity.PaymentGatewayEventType(raw.Type),
+		PaymentReference: object.ID,
+		InvoiceNumber:    object.Metadata["invoice_number"],
+		Amount:           float64(object.Amount) / 100,
+		CurrencyCode:     strings.ToUpper(object.Currency),
+		OccurredAt:       time.Unix(raw.Created, 0),
+	}
+	if invoiceID := object.Metadata["invoice_id"]; invoiceID != "" {
+		id, err := strconv.ParseInt(invoiceID, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid invoice_id metadata", ErrMalformedEvent)
+		}
+		event.InvoiceID = id
+	}
+	if raw.Created == 0 {
+		event.OccurredAt = time.Now()
+	}
+	return event, nil
+}
+
+// verify checks the signature header against the body
+func (p *GenericProvider) verify(signature string, body []byte, now time.Time) error {
+	if len(p.secret) == 0 || signature == "" {
+		return ErrInvalidSignature
+	}
+
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(signature, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+
+	signedAt, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return ErrInvalidSignature
+	}
+	if p.tolerance > 0 {
+		age := now.Sub(time.Unix(signedAt, 0))
+		if age > p.tolerance || age < -p.tolerance {
+			return ErrInvalidSignature
+		}
+	}
+
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	expected := mac.Sum(nil)
+
+	for _, sig := range signatures {
+		decoded, err := hex.DecodeString(sig)
+		if err == nil && hmac.Equal(decoded, expected) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
@@ -0,0 +1,38 @@
+// Package payment adapts the webhooks of external payment gateways. A provider
+// verifies the signature of a webhook request and normalizes its event, so the
+// finance use cases never see a gateway's own format.
+package payment
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrMalformedEvent   = errors.New("malformed webhook event")
+)
+
+// Provider verifies and parses the webhook requests of one payment gateway
+type Provider interface {
+	// Name identifies the provider in the webhook event log
+	Name() string
+	// ParseEvent verifies the request signature and returns the normalized event.
+	// It returns ErrInvalidSignature or ErrMalformedEvent for rejected requests.
+	ParseEvent(header http.Header, body []byte) (*entity.PaymentGatewayEvent, error)
+}
+
+// NewProvider returns the adapter of the named payment gateway. Signatures older
+// than the tolerance are rejected to prevent replays.
+func NewProvider(name, secret string, tolerance time.Duration) (Provider, error) {
+	switch name {
+	case "", "generic":
+		return NewGenericProvider(secret, tolerance), nil
+	default:
+		return nil, fmt.Errorf("unknown payment provider %q", name)
+	}
+}
@@ -0,0 +1,118 @@
+// Package realtime hands domain events from the server to the API gateway,
+// which pushes them to the WebSocket clients subscribed to their topic. Events
+// are posted to the gateway, or go through the message broker when one is
+// configured.
+package realtime
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/broker"
+)
+
+const (
+	// EventsPath is the gateway endpoint events are posted to
+	EventsPath = "/internal/events"
+
+	// SecretHeader carries the secret shared by the server and the gateway
+	SecretHeader = "X-Realtime-Secret"
+
+	// BrokerTopic is the message broker topic events go through, after the
+	// configured topic prefix
+	BrokerTopic = "realtime"
+
+	// publishTimeout bounds the time spent handing one event to the gateway
+	publishTimeout = 5 * time.Second
+)
+
+// Publisher posts events to the gateway or publishes them to the message
+// broker. A nil *Publisher is valid and publishes nothing.
+type Publisher struct {
+	url    string
+	secret string
+	client *http.Client
+
+	broker broker.Broker
+	topic  string
+}
+
+// NewPublisher creates a publisher for the gateway at the given base URL. It
+// returns nil, disabling publishing, when the URL or the secret is empty.
+func NewPublisher(gatewayURL, secret string) *Publisher {
+	if gatewayURL == "" || secret == "" {
+		return nil
+	}
+	return &Publisher{
+		url:    strings.TrimRight(gatewayURL, "/") + EventsPath,
+		secret: secret,
+		client: &http.Client{Timeout: publishTimeout},
+	}
+}
+
+// NewBrokerPublisher creates a publisher handing events to the gateway through
+// the message broker topic. It returns nil when there is no broker.
+func NewBrokerPublisher(b broker.Broker, topic string) *Publisher {
+	if b == nil {
+		return nil
+	}
+	return &Publisher{broker: b, topic: topic}
+}
+
+// Publish hands an event to the gateway in the background. The change behind
+// the event is already persisted, so delivery is best effort and failures are
+// logged rather than returned.
+func (p *Publisher) Publish(event *entity.RealtimeEvent) {
+	if p == nil {
+		return
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+	body, err := json.Marshal(event)
+	if err != nil {
+		slog.Error("error encoding realtime event", "topic", event.Topic, "error", err)
+		return
+	}
+
+	go func() {
+		if err := p.send(body); err != nil {
+			slog.Error("error publishing realtime event", "topic", event.Topic, "error", err)
+		}
+	}()
+}
+
+// send hands an encoded event to the broker or posts it to the gateway
+func (p *Publisher) send(body []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+	defer cancel()
+
+	if p.broker != nil {
+		return p.broker.Publish(ctx, &broker.Message{Topic: p.topic, Value: body})
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SecretHeader, p.secret)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("gateway responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package realtime
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+)
+
+// DefaultStreamSize is the number of recent events a stream keeps for
+// clients resuming with Last-Event-ID
+const DefaultStreamSize = 1000
+
+// StreamEvent is a realtime event with the ID it was streamed under
+type StreamEvent struct {
+	ID string
+	*entity.RealtimeEvent
+}
+
+// Stream keeps the recent realtime events of the server for Server-Sent
+// Events clients. Events are numbered as they are published, prefixed with
+// the time the stream started, so a client reconnecting with the ID of the
+// last event it got receives the ones it missed. Only the last size events
+// are kept, and each server instance streams its own events. A nil *Stream
+// is valid and keeps nothing.
+type Stream struct {
+	epoch string
+	size  int
+
+	mu     sync.Mutex // guards the fields below
+	seq    uint64     // number of the last event published
+	events []StreamEvent
+	wake   chan struct{} // closed when an event is published
+	done   chan struct{} // closed when the stream is closed
+}
+
+// NewStream creates a stream keeping the last size events
+func NewStream(size int) *Stream {
+	if size <= 0 {
+		size = DefaultStreamSize
+	}
+	return &Stream{
+		epoch: strconv.FormatInt(time.Now().UnixNano(), 36),
+		size:  size,
+		wake:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+}
+
+// Publish numbers an event and wakes the clients waiting for one
+func (s *Stream) Publish(event *entity.RealtimeEvent) {
+	if s == nil {
+		return
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq++
+	s.events = append(s.events, StreamEvent{ID: s.id(s.seq), RealtimeEvent: event})
+	if len(s.events) > s.size {
+		s.events = append(s.events[:0:0], s.events[len(s.events)-s.size:]...)
+	}
+	close(s.wake)
+	s.wake = make(chan struct{})
+}
+
+// Since returns the events published after the event of ID lastID, the ID to
+// ask for the next ones with and a channel closed once there are. A client
+// without an ID starts from now. missed is set when the ID is of an event no
+// longer kept, or of an earlier run of the server, so events may have been
+// lost and the client should reload what it shows.
+func (s *Stream) Since(lastID string) (events []StreamEvent, nextID string, missed bool, wait <-chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	after := s.seq
+	if lastID != "" {
+		epoch, number, _ := strings.Cut(lastID, "-")
+		seq, err := strconv.ParseUint(number, 10, 64)
+		if epoch != s.epoch || err != nil || seq > s.seq {
+			missed = true
+		} else {
+			after = seq
+		}
+	}
+
+	if len(s.events) > 0 {
+		first := s.seq - uint64(len(s.events)) + 1
+		if after+1 < first {
+			missed = lastID != ""
+			after = first - 1
+		}
+		events = append(events, s.events[after+1-first:]...)
+	}
+	return events, s.id(s.seq), missed, s.wake
+}
+
+// Done returns a channel closed when the stream is closed
+func (s *Stream) Done() <-chan struct{} {
+	return s.done
+}
+
+// Close ends the streams of the connected clients, so a shutting down server
+// does not wait for them
+func (s *Stream) Close() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	select {
+	case <-s.done:
+	default:
+		close(s.done)
+	}
+}
+
+func (s *Stream) id(seq uint64) string {
+	return s.epoch + "-" + strconv.FormatUint(seq, 10)
+}
@@ -0,0 +1,26 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"gorm.io/gorm"
+)
+
+// scopeStores restricts a listing to the rows whose store column names a
+// store the access scope of ctx covers
+func scopeStores(ctx context.Context, query *gorm.DB, column string) *gorm.DB {
+	if scope := entity.AccessScopeFromContext(ctx); scope.RestrictsStores() {
+		return query.Where(column+" IN ?", scope.StoreIDs)
+	}
+	return query
+}
+
+// scopeDepartments restricts a listing to the rows whose department column
+// names a department the access scope of ctx covers
+func scopeDepartments(ctx context.Context, query *gorm.DB, column string) *gorm.DB {
+	if scope := entity.AccessScopeFromContext(ctx); scope.RestrictsDepartments() {
+		return query.Where(column+" IN ?", scope.DepartmentIDs)
+	}
+	return query
+}
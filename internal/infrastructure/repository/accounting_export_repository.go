@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"gorm.io/gorm"
+)
+
+// AccountingExportRepository handles database operations for accounting
+// export runs and the finance records they exported
+type AccountingExportRepository struct {
+	db *gorm.DB
+}
+
+func NewAccountingExportRepository(db *gorm.DB) *AccountingExportRepository {
+	return &AccountingExportRepository{db: db}
+}
+
+// notExported keeps the records of a table not yet exported in a format
+func notExported(query *gorm.DB, table, recordType string, format entity.AccountingExportFormat) *gorm.DB {
+	return query.Where("NOT EXISTS (SELECT 1 FROM accounting_export_records x WHERE x.format = ? AND x.record_type = ? AND x.record_id = CAST("+table+".id AS VARCHAR))", format, recordType)
+}
+
+// PendingInvoices retrieves the finance invoices issued in a date range that
+// were not exported in a format. Drafts and cancelled invoices are left out.
+func (r *AccountingExportRepository) PendingInvoices(ctx context.Context, format entity.AccountingExportFormat, from, to time.Time) ([]entity.FinanceInvoice, error) {
+	var invoices []entity.FinanceInvoice
+	query := r.db.WithContext(ctx).Model(&entity.FinanceInvoice{}).
+		Where("issue_date >= ? AND issue_date < ?", from, to).
+		Where("status NOT IN ?", []entity.FinanceInvoiceStatus{entity.FinanceInvoiceDraft, entity.FinanceInvoiceCancelled})
+	if err := notExported(query, "finance_invoices", entity.AccountingRecordInvoice, format).
+		Order("issue_date, id").
+		Find(&invoices).Error; err != nil {
+		return nil, err
+	}
+	return invoices, nil
+}
+
+// PendingPayments retrieves the completed finance payments made in a date
+// range that were not exported in a format
+func (r *AccountingExportRepository) PendingPayments(ctx context.Context, format entity.AccountingExportFormat, from, to time.Time) ([]entity.FinancePayment, error) {
+	var payments []entity.FinancePayment
+	query := r.db.WithContext(ctx).Model(&entity.FinancePayment{}).
+		Where("payment_date >= ? AND payment_date < ?", from, to).
+		Where("status = ?", entity.FinancePaymentCompleted)
+	if err := notExported(query, "finance_payments", entity.AccountingRecordPayment, format).
+		Order("payment_date, id").
+		Find(&payments).Error; err != nil {
+		return nil, err
+	}
+	return payments, nil
+}
+
+// PendingDepreciation retrieves the depreciation entries posted in a date
+// range that were not exported in a format
+func (r *AccountingExportRepository) PendingDepreciation(ctx context.Context, format entity.AccountingExportFormat, from, to time.Time) ([]entity.AssetDepreciationEntry, error) {
+	var entries []entity.AssetDepreciationEntry
+	query := r.db.WithContext(ctx).Model(&entity.AssetDepreciationEntry{}).
+		Where("posted_at >= ? AND posted_at < ?", from, to)
+	if err := notExported(query, "asset_depreciation_entries", entity.AccountingRecordDepreciation, format).
+		Order("period, id").
+		Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// PendingProvisions retrieves the inventory provision entries posted in a
+// date range that were not exported in a format
+func (r *AccountingExportRepository) PendingProvisions(ctx context.Context, format entity.AccountingExportFormat, from, to time.Time) ([]entity.InventoryProvisionEntry, error) {
+	var entries []entity.InventoryProvisionEntry
+	query := r.db.WithContext(ctx).Model(&entity.InventoryProvisionEntry{}).
+		Where("posted_at >= ? AND posted_at < ?", from, to)
+	if err := notExported(query, "inventory_provision_entries", entity.AccountingRecordProvision, format).
+		Order("period, id").
+		Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// CreateRun records an export run and flags its records as exported in one
+// transaction. A record another run exported meanwhile fails the run.
+func (r *AccountingExportRepository) CreateRun(ctx context.Context, run *entity.AccountingExportRun, records []entity.AccountingExportRecord) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(run).Error; err != nil {
+			return err
+		}
+		for i := range records {
+			records[i].RunID = run.ID
+			records[i].Format = run.Format
+		}
+		var count int64
+		for _, record := range records {
+			if err := tx.Model(&entity.AccountingExportRecord{}).
+				Where("format = ? AND record_type = ? AND record_id = ?", record.Format, record.RecordType, record.RecordID).
+				Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				return ErrDuplicateEntry
+			}
+		}
+		return tx.CreateInBatches(records, 500).Error
+	})
+}
+
+// GetRun retrieves an export run with its file
+func (r *AccountingExportRepository) GetRun(ctx context.Context, id uint) (*entity.AccountingExportRun, error) {
+	var run entity.AccountingExportRun
+	if err := r.db.WithContext(ctx).First(&run, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &run, nil
+}
+
+// ListRuns retrieves export runs without their files, latest first
+func (r *AccountingExportRepository) ListRuns(ctx context.Context, page, pageSize int) ([]entity.AccountingExportRun, int64, error) {
+	var runs []entity.AccountingExportRun
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&entity.AccountingExportRun{})
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := query.Omit("content").
+		Order("created_at DESC, id DESC").
+		Offset((page - 1) * pageSize).Limit(pageSize).
+		Find(&runs).Error; err != nil {
+		return nil, 0, err
+	}
+	return runs, total, nil
+}
@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"gorm.io/gorm"
+)
+
+// AllocationRepository handles database operations for stock reservations of sales orders
+type AllocationRepository struct {
+	db *gorm.DB
+}
+
+// NewAllocationRepository creates a new allocation repository
+func NewAllocationRepository(db *gorm.DB) *AllocationRepository {
+	return &AllocationRepository{db: db}
+}
+
+// ListOpenOrders retrieves confirmed and processing sales orders, oldest first,
+// with their delivery orders
+func (r *AllocationRepository) ListOpenOrders(ctx context.Context) ([]entity.SalesOrder, error) {
+	var orders []entity.SalesOrder
+	if err := r.db.WithContext(ctx).
+		Where("status IN ?", []entity.SalesOrderStatus{entity.SalesOrderStatusConfirmed, entity.SalesOrderStatusProcessing}).
+		Preload("DeliveryOrders").
+		Order("order_date ASC, created_at ASC").
+		Find(&orders).Error; err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// GetClientTiers returns the loyalty tier of each of the given clients
+func (r *AllocationRepository) GetClientTiers(ctx context.Context, clientIDs []uint) (map[uint]entity.ClientLoyaltyTier, error) {
+	tiers := make(map[uint]entity.ClientLoyaltyTier, len(clientIDs))
+	if len(clientIDs) == 0 {
+		return tiers, nil
+	}
+
+	var clients []entity.Client
+	if err := r.db.WithContext(ctx).
+		Select("id, loyalty_tier").
+		Where("id IN ?", clientIDs).
+		Find(&clients).Error; err != nil {
+		return nil, err
+	}
+	for _, client := range clients {
+		tiers[client.ID] = client.LoyaltyTier
+	}
+	return tiers, nil
+}
+
+// GetStoreStock returns the quantity on hand per SKU in a store, excluding
+// quarantined stock
+func (r *AllocationRepository) GetStoreStock(ctx context.Context, storeID string) (map[string]float64, error) {
+	var stocks []entity.Stock
+	if err := r.db.WithContext(ctx).
+		Select("sku_id, quantity, quarantined_quantity").
+		Where("store_id = ?", storeID).
+		Find(&stocks).Error; err != nil {
+		return nil, err
+	}
+
+	onHand := make(map[string]float64, len(stocks))
+	for _, stock := range stocks {
+		onHand[stock.SKUID] += stock.Available()
+	}
+	return onHand, nil
+}
+
+// ReplaceAllocations releases the active allocations of the given SKUs in a store and
+// creates the new ones, in a single transaction
+func (r *AllocationRepository) ReplaceAllocations(ctx context.Context, storeID string, skuIDs []string, allocations []entity.StockAllocation) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if len(skuIDs) > 0 {
+			if err := tx.Model(&entity.StockAllocation{}).
+				Where("store_id = ? AND sku_id IN ? AND status = ?", storeID, skuIDs, entity.StockAllocationActive).
+				Update("status", entity.StockAllocationReleased).Error; err != nil {
+				return err
+			}
+		}
+		if len(allocations) == 0 {
+			return nil
+		}
+		return tx.Create(&allocations).Error
+	})
+}
+
+// ListAllocations retrieves stock allocations with filters, newest first
+func (r *AllocationRepository) ListAllocations(ctx context.Context, filter *entity.StockAllocationFilter) ([]entity.StockAllocation, error) {
+	var allocations []entity.StockAllocation
+	query := r.db.WithContext(ctx).Model(&entity.StockAllocation{})
+
+	if filter.SalesOrderID != "" {
+		query = query.Where("sales_order_id = ?", filter.SalesOrderID)
+	}
+	if filter.StoreID != "" {
+		query = query.Where("store_id = ?", filter.StoreID)
+	}
+	if filter.SKUID != "" {
+		query = query.Where("sku_id = ?", filter.SKUID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	if err := query.Order("created_at DESC, id").Find(&allocations).Error; err != nil {
+		return nil, err
+	}
+	return allocations, nil
+}
+
+// reservedQuantity returns the stock of a SKU in a store held by active allocations
+func reservedQuantity(db *gorm.DB, skuID, storeID string) (float64, error) {
+	var reserved float64
+	err := db.Model(&entity.StockAllocation{}).
+		Select("COALESCE(SUM(allocated_quantity), 0)").
+		Where("sku_id = ? AND store_id = ? AND status = ?", skuID, storeID, entity.StockAllocationActive).
+		Scan(&reserved).Error
+	return reserved, err
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"gorm.io/gorm"
+)
+
+type APIKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewAPIKeyRepository(db *gorm.DB) *APIKeyRepository {
+	return &APIKeyRepository{db: db}
+}
+
+// Create creates an API key
+func (r *APIKeyRepository) Create(ctx context.Context, key *entity.APIKey) error {
+	return r.db.WithContext(ctx).Omit("Creator").Create(key).Error
+}
+
+// GetByID retrieves an API key by ID
+func (r *APIKeyRepository) GetByID(ctx context.Context, id uint) (*entity.APIKey, error) {
+	var key entity.APIKey
+	if err := r.db.WithContext(ctx).Preload("Creator").First(&key, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &key, nil
+}
+
+// GetByHash retrieves an API key by the hash of its secret
+func (r *APIKeyRepository) GetByHash(ctx context.Context, hash string) (*entity.APIKey, error) {
+	var key entity.APIKey
+	if err := r.db.WithContext(ctx).Where("key_hash = ?", hash).First(&key).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &key, nil
+}
+
+// Update saves an API key
+func (r *APIKeyRepository) Update(ctx context.Context, key *entity.APIKey) error {
+	return r.db.WithContext(ctx).Omit("Creator").Save(key).Error
+}
+
+// List retrieves the API keys matching a filter, latest first
+func (r *APIKeyRepository) List(ctx context.Context, filter *entity.APIKeyFilter) ([]entity.APIKey, error) {
+	query := r.db.WithContext(ctx).Preload("Creator")
+	if filter.CreatedBy != 0 {
+		query = query.Where("created_by = ?", filter.CreatedBy)
+	}
+	if !filter.IncludeRevoked {
+		query = query.Where("revoked_at IS NULL")
+	}
+
+	var keys []entity.APIKey
+	if err := query.Order("created_at DESC, id DESC").Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// TouchLastUsed records the use of a key, at most once per interval so that
+// busy integrations do not write on every request
+func (r *APIKeyRepository) TouchLastUsed(ctx context.Context, id uint, at time.Time, ip string, interval time.Duration) error {
+	return r.db.WithContext(ctx).
+		Model(&entity.APIKey{}).
+		Where("id = ? AND (last_used_at IS NULL OR last_used_at < ?)", id, at.Add(-interval)).
+		Updates(map[string]interface{}{"last_used_at": at, "last_used_ip": ip}).Error
+}
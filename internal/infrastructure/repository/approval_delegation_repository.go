@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"gorm.io/gorm"
+)
+
+type ApprovalDelegationRepository struct {
+	db *gorm.DB
+}
+
+func NewApprovalDelegationRepository(db *gorm.DB) *ApprovalDelegationRepository {
+	return &ApprovalDelegationRepository{db: db}
+}
+
+// Create creates a delegation
+func (r *ApprovalDelegationRepository) Create(ctx context.Context, delegation *entity.ApprovalDelegation) error {
+	return r.db.WithContext(ctx).Omit("Delegator", "Delegate").Create(delegation).Error
+}
+
+// GetByID retrieves a delegation by ID
+func (r *ApprovalDelegationRepository) GetByID(ctx context.Context, id uint) (*entity.ApprovalDelegation, error) {
+	var delegation entity.ApprovalDelegation
+	if err := r.db.WithContext(ctx).Preload("Delegator").Preload("Delegate").First(&delegation, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &delegation, nil
+}
+
+// Update saves a delegation
+func (r *ApprovalDelegationRepository) Update(ctx context.Context, delegation *entity.ApprovalDelegation) error {
+	return r.db.WithContext(ctx).Omit("Delegator", "Delegate").Save(delegation).Error
+}
+
+// List retrieves the delegations matching a filter, latest start first
+func (r *ApprovalDelegationRepository) List(ctx context.Context, filter *entity.ApprovalDelegationFilter, at time.Time) ([]entity.ApprovalDelegation, error) {
+	query := r.db.WithContext(ctx).Preload("Delegator").Preload("Delegate")
+	if filter.UserID != 0 {
+		query = query.Where("delegator_id = ? OR delegate_id = ?", filter.UserID, filter.UserID)
+	}
+	if filter.ActiveOnly {
+		query = query.Where("cancelled_at IS NULL AND starts_at <= ? AND ends_at > ?", at, at)
+	} else if !filter.IncludeCancelled {
+		query = query.Where("cancelled_at IS NULL")
+	}
+
+	var delegations []entity.ApprovalDelegation
+	if err := query.Order("starts_at DESC, id DESC").Find(&delegations).Error; err != nil {
+		return nil, err
+	}
+	return delegations, nil
+}
+
+// HasOverlap reports whether a delegator has a delegation, not cancelled,
+// overlapping a period
+func (r *ApprovalDelegationRepository) HasOverlap(ctx context.Context, delegatorID uint, startsAt, endsAt time.Time) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&entity.ApprovalDelegation{}).
+		Where("delegator_id = ? AND cancelled_at IS NULL AND starts_at < ? AND ends_at > ?", delegatorID, endsAt, startsAt).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// ListActiveFor retrieves the delegations active at a time from the active
+// users whose role holds a permission, optionally only those to a delegate
+func (r *ApprovalDelegationRepository) ListActiveFor(ctx context.Context, permission entity.Permission, delegateID uint, at time.Time) ([]entity.ApprovalDelegation, error) {
+	query := r.db.WithContext(ctx).
+		Joins("JOIN users ON users.id = approval_delegations.delegator_id").
+		Joins("JOIN roles ON roles.id = users.role_id").
+		Where("users.status = ? AND ? = ANY(roles.permissions)", entity.StatusActive, string(permission)).
+		Where("approval_delegations.cancelled_at IS NULL AND approval_delegations.starts_at <= ? AND approval_delegations.ends_at > ?", at, at)
+	if delegateID != 0 {
+		query = query.Where("approval_delegations.delegate_id = ?", delegateID)
+	}
+
+	var delegations []entity.ApprovalDelegation
+	if err := query.Order("approval_delegations.starts_at, approval_delegations.id").Find(&delegations).Error; err != nil {
+		return nil, err
+	}
+	return delegations, nil
+}
@@ -0,0 +1,246 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// archiveTable returns the name of the table that holds the archived rows of table
+func archiveTable(table string) string {
+	return "archive_" + table
+}
+
+// archiveDependent is a table whose rows reference an archived row and move with it
+type archiveDependent struct {
+	table  string
+	column string // column referencing the archived row's id
+	text   bool   // compare as text, as the column type may differ from the id's
+}
+
+// archivePolicy selects the closed rows of a table that are moved to its archive table.
+// The condition takes the cutoff time as its only argument.
+type archivePolicy struct {
+	table      string
+	condition  string
+	dependents []archiveDependent
+}
+
+// archivePolicies run in order, so invoices are archived before the sales orders
+// they reference and an order is only archived once none of its invoices remain live.
+var archivePolicies = []archivePolicy{
+	{
+		table:     "invoices",
+		condition: "status IN ('PAID', 'CANCELLED') AND updated_at < ?",
+	},
+	{
+		table: "sales_orders",
+		condition: "status IN ('COMPLETED', 'CANCELLED') AND updated_at < ? " +
+			"AND NOT EXISTS (SELECT 1 FROM invoices WHERE invoices.sales_order_id = sales_orders.id)",
+		dependents: []archiveDependent{
+			{table: "delivery_orders", column: "sales_order_id"},
+		},
+	},
+	{
+		table:     "purchase_orders",
+		condition: "status IN ('CLOSED', 'CANCELLED') AND updated_at < ?",
+		dependents: []archiveDependent{
+			{table: "purchase_receipts", column: "purchase_order_id"},
+			{table: "purchase_payments", column: "purchase_order_id"},
+			{table: "purchase_requests", column: "purchase_order_id"},
+		},
+	},
+	{
+		table:     "stock_entries",
+		condition: "created_at < ?",
+		dependents: []archiveDependent{
+			{table: "stock_history", column: "reference", text: true},
+		},
+	},
+	{
+		table:     "finance_invoices",
+		condition: "status IN ('PAID', 'CANCELLED') AND updated_at < ?",
+		dependents: []archiveDependent{
+			{table: "finance_payments", column: "invoice_id"},
+		},
+	},
+	{
+		table:     "audit_logs",
+		condition: "created_at < ?",
+	},
+	{
+		table:     "login_attempts",
+		condition: "created_at < ?",
+	},
+}
+
+// ArchiveRepository moves closed documents out of the operational tables into
+// archive_<table> copies, which keep the live indexes and stay queryable.
+type ArchiveRepository struct {
+	db *gorm.DB
+}
+
+// NewArchiveRepository creates a new archive repository
+func NewArchiveRepository(db *gorm.DB) *ArchiveRepository {
+	return &ArchiveRepository{db: db}
+}
+
+// EnsureTables creates the archive table of every archived table that exists and
+// adds any column added to the live table since. It returns the live column names
+// per table, which are the columns moved by Archive.
+func (r *ArchiveRepository) EnsureTables(ctx context.Context) (map[string][]string, error) {
+	db := r.db.WithContext(ctx)
+	columns := make(map[string][]string)
+
+	for _, policy := range archivePolicies {
+		tables := []string{policy.table}
+		for _, dep := range policy.dependents {
+			tables = append(tables, dep.table)
+		}
+
+		for _, table := range tables {
+			if _, done := columns[table]; done || !db.Migrator().HasTable(table) {
+				continue
+			}
+			cols, err := r.ensureTable(db, table)
+			if err != nil {
+				return nil, err
+			}
+			columns[table] = cols
+		}
+	}
+	return columns, nil
+}
+
+func (r *ArchiveRepository) ensureTable(db *gorm.DB, table string) ([]string, error) {
+	archive := archiveTable(table)
+	if err := db.Exec(fmt.Sprintf("CREATE TABLE IF NOT EXISTS %q (LIKE %q INCLUDING ALL)", archive, table)).Error; err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", archive, err)
+	}
+	if err := db.Exec(fmt.Sprintf("ALTER TABLE %q ADD COLUMN IF NOT EXISTS archived_at TIMESTAMP NOT NULL DEFAULT NOW()", archive)).Error; err != nil {
+		return nil, fmt.Errorf("failed to add archived_at to %s: %w", archive, err)
+	}
+
+	var live []struct {
+		Name string
+		Type string
+	}
+	if err := db.Raw(`
+		SELECT attname AS name, format_type(atttypid, atttypmod) AS type
+		FROM pg_attribute
+		WHERE attrelid = ?::regclass AND attnum > 0 AND NOT attisdropped
+		ORDER BY attnum
+	`, table).Scan(&live).Error; err != nil {
+		return nil, fmt.Errorf("failed to list columns of %s: %w", table, err)
+	}
+
+	columns := make([]string, 0, len(live))
+	for _, col := range live {
+		stmt := fmt.Sprintf("ALTER TABLE %q ADD COLUMN IF NOT EXISTS %q %s", archive, col.Name, col.Type)
+		if err := db.Exec(stmt).Error; err != nil {
+			return nil, fmt.Errorf("failed to add column %s to %s: %w", col.Name, archive, err)
+		}
+		columns = append(columns, col.Name)
+	}
+	return columns, nil
+}
+
+// Archive moves rows closed before the cutoff to the archive tables, batchSize
+// parent rows per statement. Each batch moves the parent rows and their dependents
+// in a single statement, so a batch is archived entirely or not at all.
+// It returns the number of rows moved per table.
+func (r *ArchiveRepository) Archive(ctx context.Context, cutoff time.Time, batchSize int) (map[string]int64, error) {
+	columns, err := r.EnsureTables(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	moved := make(map[string]int64)
+	for _, policy := range archivePolicies {
+		if _, ok := columns[policy.table]; !ok {
+			continue
+		}
+		var dependents []archiveDependent
+		for _, dep := range policy.dependents {
+			if _, ok := columns[dep.table]; ok {
+				dependents = append(dependents, dep)
+			}
+		}
+
+		query := archiveBatchQuery(policy, dependents, columns, batchSize)
+		for {
+			counts, err := r.archiveBatch(ctx, query, len(dependents)+1, cutoff)
+			if err != nil {
+				return nil, fmt.Errorf("failed to archive %s: %w", policy.table, err)
+			}
+			moved[policy.table] += counts[0]
+			for i, dep := range dependents {
+				moved[dep.table] += counts[i+1]
+			}
+			if counts[0] < int64(batchSize) {
+				break
+			}
+		}
+	}
+	return moved, nil
+}
+
+func (r *ArchiveRepository) archiveBatch(ctx context.Context, query string, n int, cutoff time.Time) ([]int64, error) {
+	rows, err := r.db.WithContext(ctx).Raw(query, cutoff).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make([]int64, n)
+	dest := make([]interface{}, n)
+	for i := range counts {
+		dest[i] = &counts[i]
+	}
+	if rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+	}
+	return counts, rows.Err()
+}
+
+// archiveBatchQuery builds a statement that deletes one batch of closed rows and
+// their dependents, inserts them into the archive tables and returns the number
+// of rows moved, parent table first.
+func archiveBatchQuery(policy archivePolicy, dependents []archiveDependent, columns map[string][]string, batchSize int) string {
+	var ctes, counts []string
+	ctes = append(ctes, fmt.Sprintf("batch AS (SELECT id FROM %q WHERE %s ORDER BY id LIMIT %d)", policy.table, policy.condition, batchSize))
+
+	move := func(name, table, where string) {
+		cols := quoteColumns(columns[table])
+		ctes = append(ctes,
+			fmt.Sprintf("%s_del AS (DELETE FROM %q WHERE %s RETURNING *)", name, table, where),
+			fmt.Sprintf("%s_ins AS (INSERT INTO %q (%s) SELECT %s FROM %s_del RETURNING 1)", name, archiveTable(table), cols, cols, name),
+		)
+		counts = append(counts, fmt.Sprintf("(SELECT COUNT(*) FROM %s_ins)", name))
+	}
+
+	// Sub-statements of one statement see the same snapshot and foreign keys are
+	// checked at its end, so the parent rows can be deleted alongside their dependents
+	move("parent", policy.table, "id IN (SELECT id FROM batch)")
+	for i, dep := range dependents {
+		where := fmt.Sprintf("%q IN (SELECT id FROM batch)", dep.column)
+		if dep.text {
+			where = fmt.Sprintf("%q::text IN (SELECT id::text FROM batch)", dep.column)
+		}
+		move(fmt.Sprintf("dep%d", i), dep.table, where)
+	}
+	return fmt.Sprintf("WITH %s SELECT %s", strings.Join(ctes, ", "), strings.Join(counts, ", "))
+}
+
+func quoteColumns(columns []string) string {
+	quoted := make([]string, len(columns))
+	for i, col := range columns {
+		quoted[i] = fmt.Sprintf("%q", col)
+	}
+	return strings.Join(quoted, ", ")
+}
@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"gorm.io/gorm"
+)
+
+// AssetRepository handles database operations for the fixed asset register
+type AssetRepository struct {
+	db                *gorm.DB
+	sequenceGenerator *SequenceGenerator
+}
+
+// NewAssetRepository creates a new asset repository
+func NewAssetRepository(db *gorm.DB) *AssetRepository {
+	return &AssetRepository{
+		db:                db,
+		sequenceGenerator: NewSequenceGenerator(db),
+	}
+}
+
+// CreateAssets registers fixed assets, numbering those without an asset number
+func (r *AssetRepository) CreateAssets(ctx context.Context, assets []entity.FixedAsset) error {
+	if len(assets) == 0 {
+		return nil
+	}
+	for i := range assets {
+		if assets[i].AssetNumber != "" {
+			continue
+		}
+		seq, err := r.sequenceGenerator.NextSequence(ctx, "fixed_asset")
+		if err != nil {
+			return err
+		}
+		assets[i].AssetNumber = fmt.Sprintf("FA-%s-%06d", time.Now().Format("20060102"), seq)
+	}
+	return r.db.WithContext(ctx).Create(&assets).Error
+}
+
+// GetAsset retrieves a fixed asset by ID
+func (r *AssetRepository) GetAsset(ctx context.Context, id uint) (*entity.FixedAsset, error) {
+	var asset entity.FixedAsset
+	if err := r.db.WithContext(ctx).First(&asset, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &asset, nil
+}
+
+// ListAssets retrieves fixed assets with filters and pagination
+func (r *AssetRepository) ListAssets(ctx context.Context, filter *entity.FixedAssetFilter) ([]entity.FixedAsset, int64, error) {
+	var assets []entity.FixedAsset
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&entity.FixedAsset{})
+	if filter.Category != "" {
+		query = query.Where("category = ?", filter.Category)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.PurchaseOrderID != "" {
+		query = query.Where("purchase_order_id = ?", filter.PurchaseOrderID)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (filter.Page - 1) * filter.PageSize
+	if err := query.Order("acquisition_date DESC, id DESC").Limit(filter.PageSize).Offset(offset).Find(&assets).Error; err != nil {
+		return nil, 0, err
+	}
+	return assets, total, nil
+}
+
+// ListDepreciableAssets retrieves the active assets acquired before the given time
+func (r *AssetRepository) ListDepreciableAssets(ctx context.Context, acquiredBefore time.Time) ([]entity.FixedAsset, error) {
+	var assets []entity.FixedAsset
+	if err := r.db.WithContext(ctx).
+		Where("status = ? AND acquisition_date < ?", entity.FixedAssetStatusActive, acquiredBefore).
+		Order("id").
+		Find(&assets).Error; err != nil {
+		return nil, err
+	}
+	return assets, nil
+}
+
+// ListEntries retrieves the depreciation posted for an asset, oldest first
+func (r *AssetRepository) ListEntries(ctx context.Context, assetID uint) ([]entity.AssetDepreciationEntry, error) {
+	var entries []entity.AssetDepreciationEntry
+	if err := r.db.WithContext(ctx).
+		Where("asset_id = ?", assetID).
+		Order("period").
+		Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// PostDepreciation records depreciation entries and saves the updated assets in
+// a single transaction
+func (r *AssetRepository) PostDepreciation(ctx context.Context, assets []entity.FixedAsset, entries []entity.AssetDepreciationEntry) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if len(entries) > 0 {
+			if err := tx.CreateInBatches(&entries, createBatchSize(tx)).Error; err != nil {
+				return err
+			}
+		}
+		for i := range assets {
+			if err := tx.Save(&assets[i]).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// HasEntries reports whether depreciation was posted for the period
+func (r *AssetRepository) HasEntries(ctx context.Context, period string) (bool, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).
+		Model(&entity.AssetDepreciationEntry{}).
+		Where("period = ?", period).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"gorm.io/gorm"
+)
+
+type AttachmentRepository struct {
+	db *gorm.DB
+}
+
+func NewAttachmentRepository(db *gorm.DB) *AttachmentRepository {
+	return &AttachmentRepository{db: db}
+}
+
+// Create records an uploaded attachment
+func (r *AttachmentRepository) Create(ctx context.Context, attachment *entity.Attachment) error {
+	return r.db.WithContext(ctx).Create(attachment).Error
+}
+
+// Get retrieves an attachment by ID
+func (r *AttachmentRepository) Get(ctx context.Context, id uint64) (*entity.Attachment, error) {
+	var attachment entity.Attachment
+	if err := r.db.WithContext(ctx).First(&attachment, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+// ListByOwner retrieves the attachments of a document in upload order
+func (r *AttachmentRepository) ListByOwner(ctx context.Context, ownerType entity.AttachmentOwnerType, ownerID string) ([]entity.Attachment, error) {
+	var attachments []entity.Attachment
+	err := r.db.WithContext(ctx).
+		Where("owner_type = ? AND owner_id = ?", ownerType, ownerID).
+		Order("created_at, id").
+		Find(&attachments).Error
+	return attachments, err
+}
+
+// Delete deletes an attachment
+func (r *AttachmentRepository) Delete(ctx context.Context, id uint64) error {
+	result := r.db.WithContext(ctx).Delete(&entity.Attachment{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
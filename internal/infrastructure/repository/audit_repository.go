@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"context"
+	"strconv"
 	"time"
 
 	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
@@ -15,8 +17,66 @@ func NewAuditLogRepository(db *gorm.DB) *AuditLogRepository {
 	return &AuditLogRepository{db: db}
 }
 
+// auditChainLockKey serializes audit log writes, so each is chained to the
+// log written before it
+const auditChainLockKey = "audit_logs|chain"
+
+// auditChainColumns are the columns of the audit logs their hashes cover
+const auditChainColumns = "id, user_id, impersonator_id, action, resource, detail, ip, user_agent, created_at, prev_hash, hash"
+
+// Create writes an audit log chained to the one written before it: the latest
+// live log, else the latest archived one, else the last log exported.
 func (r *AuditLogRepository) Create(log *entity.AuditLog) error {
-	return r.db.Create(log).Error
+	// The hash covers the time as stored, to the microsecond
+	log.CreatedAt = log.CreatedAt.Truncate(time.Microsecond)
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", auditChainLockKey).Error; err != nil {
+			return err
+		}
+		prevHash, _, err := previousAuditHash(tx, 0)
+		if err != nil {
+			return err
+		}
+		log.PrevHash = prevHash
+		log.Hash = log.ChainHash()
+		return tx.Create(log).Error
+	})
+}
+
+// previousAuditHash returns the hash of the latest log, live or archived,
+// written before the given ID, or of the last log exported before it; zero
+// means before any log. It reports whether there is such a log.
+func previousAuditHash(db *gorm.DB, beforeID uint) (string, bool, error) {
+	for _, table := range []string{"audit_logs", archiveTable("audit_logs")} {
+		var rows []struct{ Hash *string }
+		query := db.Table(table).Select("hash").Order("id DESC").Limit(1)
+		if beforeID != 0 {
+			query = query.Where("id < ?", beforeID)
+		}
+		if err := query.Scan(&rows).Error; err != nil {
+			return "", false, err
+		}
+		if len(rows) > 0 {
+			if rows[0].Hash == nil {
+				return "", true, nil
+			}
+			return *rows[0].Hash, true, nil
+		}
+	}
+
+	var archives []entity.AuditLogArchive
+	query := db.Model(&entity.AuditLogArchive{}).Order("last_id DESC").Limit(1)
+	if beforeID != 0 {
+		query = query.Where("last_id < ?", beforeID)
+	}
+	if err := query.Find(&archives).Error; err != nil {
+		return "", false, err
+	}
+	if len(archives) > 0 {
+		return archives[0].LastHash, true, nil
+	}
+	return "", false, nil
 }
 
 func (r *AuditLogRepository) FindByUserID(userID uint, limit, offset int) ([]entity.AuditLog, error) {
@@ -73,3 +133,148 @@ func (r *AuditLogRepository) Count(filter map[string]interface{}) (int64, error)
 	err := query.Count(&count).Error
 	return count, err
 }
+
+// Search lists the audit logs matching a filter, latest first. A page size of
+// zero or less returns every match.
+func (r *AuditLogRepository) Search(filter *entity.AuditLogFilter) ([]entity.AuditLog, int64, error) {
+	var logs []entity.AuditLog
+	var total int64
+
+	query := r.searchQuery(filter)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	query = query.Preload("User").Order("created_at DESC, id DESC")
+	if filter.PageSize > 0 {
+		query = query.Limit(filter.PageSize).Offset((filter.Page - 1) * filter.PageSize)
+	}
+	if err := query.Find(&logs).Error; err != nil {
+		return nil, 0, err
+	}
+	return logs, total, nil
+}
+
+// SearchPage lists the page of audit logs matching a filter that follows the
+// page's cursor, latest first, with the cursor of the next page when there is one
+func (r *AuditLogRepository) SearchPage(filter *entity.AuditLogFilter, page entity.CursorPage) ([]entity.AuditLog, *entity.Cursor, error) {
+	query, err := cursorQuery(r.searchQuery(filter), page, parseIntID)
+	if err != nil {
+		return nil, nil, err
+	}
+	var logs []entity.AuditLog
+	if err := query.Preload("User").Find(&logs).Error; err != nil {
+		return nil, nil, err
+	}
+	logs, next := cursorRows(logs, page, func(l *entity.AuditLog) entity.Cursor {
+		return entity.Cursor{CreatedAt: l.CreatedAt, ID: strconv.FormatUint(uint64(l.ID), 10)}
+	})
+	return logs, next, nil
+}
+
+// searchQuery selects the audit logs matching a filter
+func (r *AuditLogRepository) searchQuery(filter *entity.AuditLogFilter) *gorm.DB {
+	query := r.db.Model(&entity.AuditLog{})
+	if filter.Archived {
+		query = query.Table(archiveTable("audit_logs"))
+	}
+	if filter.UserID != 0 {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.ImpersonatorID != 0 {
+		query = query.Where("impersonator_id = ?", filter.ImpersonatorID)
+	}
+	if filter.Impersonated {
+		query = query.Where("impersonator_id IS NOT NULL")
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.Resource != "" {
+		query = query.Where("resource ILIKE ?", "%"+filter.Resource+"%")
+	}
+	if filter.IP != "" {
+		query = query.Where("ip = ?", filter.IP)
+	}
+	if filter.Query != "" {
+		like := "%" + filter.Query + "%"
+		query = query.Where("(resource ILIKE ? OR detail ILIKE ? OR user_agent ILIKE ?)", like, like, like)
+	}
+	if filter.StartDate != nil {
+		query = query.Where("created_at >= ?", filter.StartDate)
+	}
+	if filter.EndDate != nil {
+		query = query.Where("created_at <= ?", filter.EndDate)
+	}
+	return query
+}
+
+// ChainRows lists up to limit audit logs, live and archived, written between
+// the given times with IDs after afterID, by ID
+func (r *AuditLogRepository) ChainRows(ctx context.Context, start, end time.Time, afterID uint, limit int) ([]entity.AuditLog, error) {
+	var logs []entity.AuditLog
+	where := " WHERE id > ? AND created_at >= ? AND created_at <= ?"
+	err := r.db.WithContext(ctx).Raw(
+		"SELECT "+auditChainColumns+" FROM audit_logs"+where+
+			" UNION ALL SELECT "+auditChainColumns+" FROM "+archiveTable("audit_logs")+where+
+			" ORDER BY id LIMIT ?",
+		afterID, start, end, afterID, start, end, limit,
+	).Scan(&logs).Error
+	return logs, err
+}
+
+// PreviousHash returns the hash of the log written before the given one, live,
+// archived or exported, and whether there is one
+func (r *AuditLogRepository) PreviousHash(ctx context.Context, beforeID uint) (string, bool, error) {
+	return previousAuditHash(r.db.WithContext(ctx), beforeID)
+}
+
+// CountBefore counts the audit logs, live and archived, written before the cutoff
+func (r *AuditLogRepository) CountBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	var total int64
+	for _, table := range []string{"audit_logs", archiveTable("audit_logs")} {
+		var count int64
+		if err := r.db.WithContext(ctx).Table(table).Where("created_at < ?", cutoff).Count(&count).Error; err != nil {
+			return 0, err
+		}
+		total += count
+	}
+	return total, nil
+}
+
+// DeleteBefore removes the audit logs, live and archived, written before the
+// cutoff up to the given ID, and returns how many were removed
+func (r *AuditLogRepository) DeleteBefore(ctx context.Context, cutoff time.Time, lastID uint) (int64, error) {
+	var deleted int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, table := range []string{"audit_logs", archiveTable("audit_logs")} {
+			result := tx.Table(table).Where("created_at < ? AND id <= ?", cutoff, lastID).Delete(&entity.AuditLog{})
+			if result.Error != nil {
+				return result.Error
+			}
+			deleted += result.RowsAffected
+		}
+		return nil
+	})
+	return deleted, err
+}
+
+// CreateArchive records audit logs exported to the file store
+func (r *AuditLogRepository) CreateArchive(ctx context.Context, archive *entity.AuditLogArchive) error {
+	return r.db.WithContext(ctx).Create(archive).Error
+}
+
+// ListArchives lists the archives of exported audit logs, latest first
+func (r *AuditLogRepository) ListArchives(ctx context.Context, page, pageSize int) ([]entity.AuditLogArchive, int64, error) {
+	var archives []entity.AuditLogArchive
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&entity.AuditLogArchive{})
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := query.Order("last_id DESC").Limit(pageSize).Offset((page - 1) * pageSize).Find(&archives).Error; err != nil {
+		return nil, 0, err
+	}
+	return archives, total, nil
+}
@@ -0,0 +1,229 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// BankFeedRepository handles database operations for bank accounts, the
+// transactions imported from their feeds and the import runs
+type BankFeedRepository struct {
+	db *gorm.DB
+}
+
+func NewBankFeedRepository(db *gorm.DB) *BankFeedRepository {
+	return &BankFeedRepository{db: db}
+}
+
+// CreateAccount creates a bank account
+func (r *BankFeedRepository) CreateAccount(ctx context.Context, account *entity.BankAccount) error {
+	if err := r.checkAccount(ctx, account); err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Create(account).Error
+}
+
+// UpdateAccount saves a bank account
+func (r *BankFeedRepository) UpdateAccount(ctx context.Context, account *entity.BankAccount) error {
+	if err := r.checkAccount(ctx, account); err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Save(account).Error
+}
+
+// checkAccount checks that no other bank account reads the same account of
+// the same provider
+func (r *BankFeedRepository) checkAccount(ctx context.Context, account *entity.BankAccount) error {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&entity.BankAccount{}).
+		Where("provider = ? AND base_url = ? AND external_id = ? AND id <> ?", account.Provider, account.BaseURL, account.ExternalID, account.ID).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrDuplicateEntry
+	}
+	return nil
+}
+
+// GetAccount retrieves a bank account by ID
+func (r *BankFeedRepository) GetAccount(ctx context.Context, id uint) (*entity.BankAccount, error) {
+	var account entity.BankAccount
+	if err := r.db.WithContext(ctx).First(&account, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &account, nil
+}
+
+// ListAccounts retrieves the bank accounts
+func (r *BankFeedRepository) ListAccounts(ctx context.Context, activeOnly bool) ([]entity.BankAccount, error) {
+	var accounts []entity.BankAccount
+	query := r.db.WithContext(ctx)
+	if activeOnly {
+		query = query.Where("active = ?", true)
+	}
+	if err := query.Order("name, id").Find(&accounts).Error; err != nil {
+		return nil, err
+	}
+	return accounts, nil
+}
+
+// SetSyncedAt records the time of a bank account's last import
+func (r *BankFeedRepository) SetSyncedAt(ctx context.Context, accountID uint, syncedAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&entity.BankAccount{}).
+		Where("id = ?", accountID).
+		Update("synced_at", syncedAt).Error
+}
+
+// CreateTransactions stores imported transactions, leaving out the ones
+// imported before, and returns how many were stored
+func (r *BankFeedRepository) CreateTransactions(ctx context.Context, transactions []entity.BankTransaction) (int, error) {
+	if len(transactions) == 0 {
+		return 0, nil
+	}
+	result := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "account_id"}, {Name: "external_id"}},
+		DoNothing: true,
+	}).CreateInBatches(&transactions, 500)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return int(result.RowsAffected), nil
+}
+
+// GetTransaction retrieves a bank transaction by ID
+func (r *BankFeedRepository) GetTransaction(ctx context.Context, id uint) (*entity.BankTransaction, error) {
+	var transaction entity.BankTransaction
+	if err := r.db.WithContext(ctx).First(&transaction, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &transaction, nil
+}
+
+// ListTransactions retrieves bank transactions with filters and pagination,
+// latest first
+func (r *BankFeedRepository) ListTransactions(ctx context.Context, filter *entity.BankTransactionFilter) ([]entity.BankTransaction, int64, error) {
+	var transactions []entity.BankTransaction
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&entity.BankTransaction{})
+	if filter.AccountID != 0 {
+		query = query.Where("account_id = ?", filter.AccountID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.StartDate != nil {
+		query = query.Where("booked_at >= ?", filter.StartDate)
+	}
+	if filter.EndDate != nil {
+		query = query.Where("booked_at <= ?", filter.EndDate)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (filter.Page - 1) * filter.PageSize
+	if err := query.Order("booked_at DESC, id DESC").Limit(filter.PageSize).Offset(offset).Find(&transactions).Error; err != nil {
+		return nil, 0, err
+	}
+	return transactions, total, nil
+}
+
+// SetTransactionStatus moves an unmatched bank transaction to another status,
+// recording who did it. It returns ErrRecordNotFound when the transaction is
+// no longer unmatched, so two users cannot reconcile it at once.
+func (r *BankFeedRepository) SetTransactionStatus(ctx context.Context, id uint, status entity.BankTransactionStatus, userID uint) error {
+	now := time.Now()
+	result := r.db.WithContext(ctx).Model(&entity.BankTransaction{}).
+		Where("id = ? AND status = ?", id, entity.BankTransactionUnmatched).
+		Updates(map[string]interface{}{
+			"status":     status,
+			"matched_by": userID,
+			"matched_at": now,
+			"updated_at": now,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// SetTransactionPayment records the finance payment a matched bank
+// transaction created
+func (r *BankFeedRepository) SetTransactionPayment(ctx context.Context, id uint, paymentID int64) error {
+	return r.db.WithContext(ctx).Model(&entity.BankTransaction{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"payment_id": paymentID,
+			"updated_at": time.Now(),
+		}).Error
+}
+
+// ReleaseTransaction returns a bank transaction to unmatched
+func (r *BankFeedRepository) ReleaseTransaction(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Model(&entity.BankTransaction{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status":     entity.BankTransactionUnmatched,
+			"payment_id": nil,
+			"matched_by": nil,
+			"matched_at": nil,
+			"updated_at": time.Now(),
+		}).Error
+}
+
+// MatchCandidates retrieves the open invoices of a type and currency a bank
+// transaction may settle: those whose amount due is the transaction amount,
+// and those whose number or entity name appears in the transaction's text
+func (r *BankFeedRepository) MatchCandidates(ctx context.Context, invoiceType entity.FinanceInvoiceType, currency string, amount float64, text string, limit int) ([]entity.FinanceInvoice, error) {
+	var invoices []entity.FinanceInvoice
+	text = strings.ToLower(text)
+	if err := r.db.WithContext(ctx).Model(&entity.FinanceInvoice{}).
+		Where("type = ? AND UPPER(currency_code) = UPPER(?) AND amount_due > 0", invoiceType, currency).
+		Where("status IN ?", []entity.FinanceInvoiceStatus{
+			entity.FinanceInvoicePending, entity.FinanceInvoiceApproved,
+			entity.FinanceInvoicePartiallyPaid, entity.FinanceInvoiceOverdue,
+		}).
+		Where("(ABS(amount_due - ?) < 0.005 OR POSITION(LOWER(invoice_number) IN ?) > 0 OR (entity_name <> '' AND POSITION(LOWER(entity_name) IN ?) > 0))",
+			amount, text, text).
+		Order("due_date, id").
+		Limit(limit).
+		Find(&invoices).Error; err != nil {
+		return nil, err
+	}
+	return invoices, nil
+}
+
+// CreateImportRun records an import run
+func (r *BankFeedRepository) CreateImportRun(ctx context.Context, run *entity.BankImportRun) error {
+	return r.db.WithContext(ctx).Create(run).Error
+}
+
+// ListImportRuns retrieves the latest import runs of a bank account
+func (r *BankFeedRepository) ListImportRuns(ctx context.Context, accountID uint, limit int) ([]entity.BankImportRun, error) {
+	var runs []entity.BankImportRun
+	if err := r.db.WithContext(ctx).
+		Where("account_id = ?", accountID).
+		Order("started_at DESC, id DESC").
+		Limit(limit).
+		Find(&runs).Error; err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
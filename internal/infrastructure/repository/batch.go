@@ -0,0 +1,14 @@
+package repository
+
+import "gorm.io/gorm"
+
+// defaultCreateBatchSize is used when the database session has no CreateBatchSize configured
+const defaultCreateBatchSize = 500
+
+// createBatchSize returns the bulk insert batch size configured on the gorm session
+func createBatchSize(db *gorm.DB) int {
+	if db.CreateBatchSize > 0 {
+		return db.CreateBatchSize
+	}
+	return defaultCreateBatchSize
+}
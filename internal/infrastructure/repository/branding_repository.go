@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"gorm.io/gorm"
+)
+
+// BrandingRepository handles database operations for the company branding
+type BrandingRepository struct {
+	db *gorm.DB
+}
+
+// NewBrandingRepository creates a new branding repository
+func NewBrandingRepository(db *gorm.DB) *BrandingRepository {
+	return &BrandingRepository{db: db}
+}
+
+// Get retrieves the branding. The logo image is only loaded when withLogo is set;
+// its content type is always loaded.
+func (r *BrandingRepository) Get(ctx context.Context, withLogo bool) (*entity.Branding, error) {
+	var branding entity.Branding
+	query := r.db.WithContext(ctx)
+	if !withLogo {
+		query = query.Omit("logo_data")
+	}
+	if err := query.First(&branding, entity.BrandingID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &branding, nil
+}
+
+// Save creates or updates the branding, leaving the logo untouched
+func (r *BrandingRepository) Save(ctx context.Context, branding *entity.Branding) error {
+	branding.ID = entity.BrandingID
+	return r.db.WithContext(ctx).Omit("logo_data", "logo_content_type", "logo_updated_at").Save(branding).Error
+}
+
+// SaveLogo saves the branding together with its logo, creating it if it does
+// not exist yet
+func (r *BrandingRepository) SaveLogo(ctx context.Context, branding *entity.Branding) error {
+	branding.ID = entity.BrandingID
+	return r.db.WithContext(ctx).Save(branding).Error
+}
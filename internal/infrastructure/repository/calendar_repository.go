@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"gorm.io/gorm"
+)
+
+// CalendarRepository handles database operations for working calendars and their holidays
+type CalendarRepository struct {
+	db *gorm.DB
+}
+
+// NewCalendarRepository creates a new calendar repository
+func NewCalendarRepository(db *gorm.DB) *CalendarRepository {
+	return &CalendarRepository{db: db}
+}
+
+// Create creates a working calendar. The company and each warehouse have at most one calendar.
+func (r *CalendarRepository) Create(ctx context.Context, calendar *entity.WorkingCalendar) error {
+	if err := r.checkScope(ctx, calendar); err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Omit("Holidays").Create(calendar).Error
+}
+
+// Update saves a working calendar, leaving its holidays untouched
+func (r *CalendarRepository) Update(ctx context.Context, calendar *entity.WorkingCalendar) error {
+	if err := r.checkScope(ctx, calendar); err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Omit("Holidays").Save(calendar).Error
+}
+
+// checkScope rejects a calendar for the company or a warehouse that already has another calendar
+func (r *CalendarRepository) checkScope(ctx context.Context, calendar *entity.WorkingCalendar) error {
+	query := r.db.WithContext(ctx).Model(&entity.WorkingCalendar{}).Where("id <> ?", calendar.ID)
+	if calendar.StoreID == nil {
+		query = query.Where("store_id IS NULL")
+	} else {
+		query = query.Where("store_id = ?", *calendar.StoreID)
+	}
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrDuplicateEntry
+	}
+	return nil
+}
+
+// GetByID retrieves a working calendar with its holidays
+func (r *CalendarRepository) GetByID(ctx context.Context, id uint) (*entity.WorkingCalendar, error) {
+	var calendar entity.WorkingCalendar
+	if err := r.withHolidays(ctx).First(&calendar, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &calendar, nil
+}
+
+// List retrieves the working calendars, the company calendar first
+func (r *CalendarRepository) List(ctx context.Context) ([]entity.WorkingCalendar, error) {
+	var calendars []entity.WorkingCalendar
+	if err := r.withHolidays(ctx).Order("store_id NULLS FIRST, name").Find(&calendars).Error; err != nil {
+		return nil, err
+	}
+	return calendars, nil
+}
+
+// FindForStore retrieves the calendar of a warehouse, or the company calendar
+// when the warehouse has none or storeID is empty
+func (r *CalendarRepository) FindForStore(ctx context.Context, storeID string) (*entity.WorkingCalendar, error) {
+	var calendar entity.WorkingCalendar
+	query := r.withHolidays(ctx)
+	if storeID != "" {
+		query = query.Where("store_id = ? OR store_id IS NULL", storeID)
+	} else {
+		query = query.Where("store_id IS NULL")
+	}
+	if err := query.Order("store_id NULLS LAST").First(&calendar).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &calendar, nil
+}
+
+// withHolidays returns a query that loads calendars with their holidays by date
+func (r *CalendarRepository) withHolidays(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx).Preload("Holidays", func(db *gorm.DB) *gorm.DB {
+		return db.Order("date")
+	})
+}
+
+// Delete deletes a working calendar and its holidays
+func (r *CalendarRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("calendar_id = ?", id).Delete(&entity.CalendarHoliday{}).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(&entity.WorkingCalendar{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+// AddHoliday adds a holiday to a calendar. A date is a holiday at most once per calendar.
+func (r *CalendarRepository) AddHoliday(ctx context.Context, holiday *entity.CalendarHoliday) error {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&entity.CalendarHoliday{}).
+		Where("calendar_id = ? AND date = ?", holiday.CalendarID, holiday.Date).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrDuplicateEntry
+	}
+	return r.db.WithContext(ctx).Create(holiday).Error
+}
+
+// DeleteHoliday deletes a holiday of a calendar
+func (r *CalendarRepository) DeleteHoliday(ctx context.Context, calendarID, holidayID uint) error {
+	result := r.db.WithContext(ctx).
+		Where("id = ? AND calendar_id = ?", holidayID, calendarID).
+		Delete(&entity.CalendarHoliday{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
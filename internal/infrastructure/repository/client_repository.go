@@ -27,7 +27,7 @@ func (r *ClientRepositoryImpl) Create(client *entity.Client) error {
 // FindByID finds a client by ID
 func (r *ClientRepositoryImpl) FindByID(id uint) (*entity.Client, error) {
 	var client entity.Client
-	if err := r.db.Preload("Addresses").First(&client, id).Error; err != nil {
+	if err := r.db.Preload("Addresses").Preload("RFM").First(&client, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, ErrRecordNotFound
 		}
@@ -39,7 +39,7 @@ func (r *ClientRepositoryImpl) FindByID(id uint) (*entity.Client, error) {
 // FindByCode finds a client by code
 func (r *ClientRepositoryImpl) FindByCode(code string) (*entity.Client, error) {
 	var client entity.Client
-	if err := r.db.Preload("Addresses").Where("code = ?", code).First(&client).Error; err != nil {
+	if err := r.db.Preload("Addresses").Preload("RFM").Where("code = ?", code).First(&client).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, ErrRecordNotFound
 		}
@@ -51,7 +51,7 @@ func (r *ClientRepositoryImpl) FindByCode(code string) (*entity.Client, error) {
 // FindByEmail finds a client by email
 func (r *ClientRepositoryImpl) FindByEmail(email string) (*entity.Client, error) {
 	var client entity.Client
-	if err := r.db.Preload("Addresses").Where("email = ?", email).First(&client).Error; err != nil {
+	if err := r.db.Preload("Addresses").Preload("RFM").Where("email = ?", email).First(&client).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, ErrRecordNotFound
 		}
@@ -101,6 +101,11 @@ func (r *ClientRepositoryImpl) List(filter entity.ClientFilter) ([]entity.Client
 		query = query.Where("loyalty_tier = ?", *filter.LoyaltyTier)
 	}
 
+	if !filter.RFM.IsEmpty() {
+		cond, args := rfmClientSubquery("clients.id", filter.RFM)
+		query = query.Where(cond, args...)
+	}
+
 	// Address-related filters
 	if filter.City != "" || filter.Country != "" {
 		query = query.Joins("JOIN client_addresses ON clients.id = client_addresses.client_id")
@@ -113,7 +118,7 @@ func (r *ClientRepositoryImpl) List(filter entity.ClientFilter) ([]entity.Client
 		query = query.Group("clients.id") // Avoid duplicates
 	}
 
-	if err := query.Preload("Addresses").Find(&clients).Error; err != nil {
+	if err := query.Preload("Addresses").Preload("RFM").Find(&clients).Error; err != nil {
 		return nil, fmt.Errorf("failed to list clients: %w", err)
 	}
 	return clients, nil
@@ -0,0 +1,218 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ConditionRepository handles database operations for environmental readings,
+// their thresholds and the excursions outside them
+type ConditionRepository struct {
+	db *gorm.DB
+}
+
+func NewConditionRepository(db *gorm.DB) *ConditionRepository {
+	return &ConditionRepository{db: db}
+}
+
+// CreateThreshold creates a condition threshold
+func (r *ConditionRepository) CreateThreshold(ctx context.Context, threshold *entity.ConditionThreshold) error {
+	return r.db.WithContext(ctx).Create(threshold).Error
+}
+
+// UpdateThreshold saves a condition threshold
+func (r *ConditionRepository) UpdateThreshold(ctx context.Context, threshold *entity.ConditionThreshold) error {
+	return r.db.WithContext(ctx).Save(threshold).Error
+}
+
+// GetThreshold retrieves a condition threshold by ID
+func (r *ConditionRepository) GetThreshold(ctx context.Context, id uint) (*entity.ConditionThreshold, error) {
+	var threshold entity.ConditionThreshold
+	if err := r.db.WithContext(ctx).First(&threshold, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &threshold, nil
+}
+
+// ListThresholds retrieves the condition thresholds of the stores in the
+// access scope, or of one store
+func (r *ConditionRepository) ListThresholds(ctx context.Context, storeID string, activeOnly bool) ([]entity.ConditionThreshold, error) {
+	var thresholds []entity.ConditionThreshold
+	query := scopeStores(ctx, r.db.WithContext(ctx), "store_id")
+	if storeID != "" {
+		query = query.Where("store_id = ?", storeID)
+	}
+	if activeOnly {
+		query = query.Where("active = ?", true)
+	}
+	if err := query.Order("store_id, zone_code, metric, id").Find(&thresholds).Error; err != nil {
+		return nil, err
+	}
+	return thresholds, nil
+}
+
+// RecordReading records a reading and tracks the excursions of the thresholds
+// watching it, in a single transaction. A reading outside a threshold extends
+// the open excursion of its zone or opens one; a reading within it closes the
+// open excursion. The excursions opened are returned.
+func (r *ConditionRepository) RecordReading(ctx context.Context, reading *entity.ConditionReading, thresholds []entity.ConditionThreshold) ([]entity.ConditionExcursion, error) {
+	var opened []entity.ConditionExcursion
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, threshold := range thresholds {
+			if !threshold.Applies(reading) {
+				continue
+			}
+
+			var excursion entity.ConditionExcursion
+			found := true
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("threshold_id = ? AND zone_code = ? AND status = ?", threshold.ID, reading.ZoneCode, entity.ExcursionOpen).
+				First(&excursion).Error; err != nil {
+				if err != gorm.ErrRecordNotFound {
+					return err
+				}
+				found = false
+			}
+
+			deviation := threshold.Deviation(reading.Value)
+			switch {
+			case deviation > 0 && found:
+				updates := map[string]interface{}{"readings": gorm.Expr("readings + 1")}
+				if deviation > threshold.Deviation(excursion.PeakValue) {
+					updates["peak_value"] = reading.Value
+				}
+				if err := tx.Model(&excursion).Updates(updates).Error; err != nil {
+					return err
+				}
+			case deviation > 0:
+				excursion = entity.ConditionExcursion{
+					ThresholdID: threshold.ID,
+					StoreID:     reading.StoreID,
+					ZoneCode:    reading.ZoneCode,
+					Metric:      reading.Metric,
+					Limits:      threshold.Limits(),
+					PeakValue:   reading.Value,
+					Readings:    1,
+					Status:      entity.ExcursionOpen,
+					StartedAt:   reading.RecordedAt,
+				}
+				if err := tx.Create(&excursion).Error; err != nil {
+					return err
+				}
+				opened = append(opened, excursion)
+			case found:
+				if err := tx.Model(&excursion).Updates(map[string]interface{}{
+					"status":   entity.ExcursionClosed,
+					"ended_at": reading.RecordedAt,
+				}).Error; err != nil {
+					return err
+				}
+				continue
+			default:
+				continue
+			}
+
+			if reading.ExcursionID == nil {
+				id := excursion.ID
+				reading.ExcursionID = &id
+			}
+		}
+		return tx.Create(reading).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return opened, nil
+}
+
+// ListReadings retrieves condition readings with filters, latest first
+func (r *ConditionRepository) ListReadings(ctx context.Context, filter *entity.ConditionReadingFilter) ([]entity.ConditionReading, int64, error) {
+	var readings []entity.ConditionReading
+	var total int64
+
+	query := scopeStores(ctx, r.db.WithContext(ctx).Model(&entity.ConditionReading{}), "store_id")
+	if filter.StoreID != "" {
+		query = query.Where("store_id = ?", filter.StoreID)
+	}
+	if filter.ZoneCode != "" {
+		query = query.Where("zone_code = ?", filter.ZoneCode)
+	}
+	if filter.Metric != "" {
+		query = query.Where("metric = ?", filter.Metric)
+	}
+	if filter.From != nil {
+		query = query.Where("recorded_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("recorded_at <= ?", *filter.To)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (filter.Page - 1) * filter.PageSize
+	if err := query.Order("recorded_at DESC, id DESC").Limit(filter.PageSize).Offset(offset).Find(&readings).Error; err != nil {
+		return nil, 0, err
+	}
+	return readings, total, nil
+}
+
+// ListExposedStocks retrieves the stocks of a store, or of one zone of it,
+// with quantity not already quarantined
+func (r *ConditionRepository) ListExposedStocks(ctx context.Context, storeID, zoneCode string) ([]entity.Stock, error) {
+	var stocks []entity.Stock
+	query := r.db.WithContext(ctx).Where("store_id = ? AND quantity > quarantined_quantity", storeID)
+	if zoneCode != "" {
+		query = query.Where("zone_code = ?", zoneCode)
+	}
+	if err := query.Order("sku_id").Find(&stocks).Error; err != nil {
+		return nil, err
+	}
+	return stocks, nil
+}
+
+// CreateExcursionLots records the lots exposed to an excursion
+func (r *ConditionRepository) CreateExcursionLots(ctx context.Context, lots []entity.ConditionExcursionLot) error {
+	if len(lots) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Create(&lots).Error
+}
+
+// GetExcursion retrieves a condition excursion by ID with its lots
+func (r *ConditionRepository) GetExcursion(ctx context.Context, id uint) (*entity.ConditionExcursion, error) {
+	var excursion entity.ConditionExcursion
+	if err := r.db.WithContext(ctx).Preload("Lots").First(&excursion, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &excursion, nil
+}
+
+// ListExcursions retrieves condition excursions with filters, latest first
+func (r *ConditionRepository) ListExcursions(ctx context.Context, filter *entity.ConditionExcursionFilter) ([]entity.ConditionExcursion, error) {
+	var excursions []entity.ConditionExcursion
+	query := scopeStores(ctx, r.db.WithContext(ctx), "store_id")
+	if filter.StoreID != "" {
+		query = query.Where("store_id = ?", filter.StoreID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Metric != "" {
+		query = query.Where("metric = ?", filter.Metric)
+	}
+	if err := query.Preload("Lots").Order("started_at DESC, id DESC").Find(&excursions).Error; err != nil {
+		return nil, err
+	}
+	return excursions, nil
+}
@@ -0,0 +1,274 @@
+package repository
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrConsignmentBilled = entity.NewError(entity.ErrCodeConflict, "consignment consumption is already billed")
+
+// ConsignmentRepository keeps the stock vendors own on consignment and the
+// consigned stock consumed
+type ConsignmentRepository struct {
+	db                *gorm.DB
+	stocksRepo        *StocksRepository
+	sequenceGenerator *SequenceGenerator
+}
+
+func NewConsignmentRepository(db *gorm.DB, stocksRepo *StocksRepository) *ConsignmentRepository {
+	return &ConsignmentRepository{
+		db:                db,
+		stocksRepo:        stocksRepo,
+		sequenceGenerator: NewSequenceGenerator(db),
+	}
+}
+
+// ReceiveStock adds the entries' quantities to a vendor's consignment stock at
+// their unit costs, averaging the cost of stock already held, and posts the
+// entries, in a single transaction
+func (r *ConsignmentRepository) ReceiveStock(ctx context.Context, vendorID uint, entries []entity.StockEntry, unitCosts []float64, userID string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for i, entry := range entries {
+			consigned, err := r.lockStockTx(tx, vendorID, entry.SKUID, entry.StoreID)
+			if err != nil {
+				return err
+			}
+			if consigned == nil {
+				if err := tx.Create(&entity.ConsignmentStock{
+					VendorID: vendorID,
+					SKUID:    entry.SKUID,
+					StoreID:  entry.StoreID,
+					Quantity: entry.Quantity,
+					UnitCost: unitCosts[i],
+				}).Error; err != nil {
+					return err
+				}
+				continue
+			}
+
+			quantity := consigned.Quantity + entry.Quantity
+			cost := math.Round((consigned.Quantity*consigned.UnitCost+entry.Quantity*unitCosts[i])/quantity*100) / 100
+			if err := tx.Model(consigned).Updates(map[string]interface{}{
+				"quantity":  quantity,
+				"unit_cost": cost,
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return r.stocksRepo.ProcessStockEntriesTx(ctx, tx, entries, userID)
+	})
+}
+
+// ReturnStock takes the entries' quantities off a vendor's consignment stock
+// and posts the entries, in a single transaction. The vendor must hold the
+// quantities returned.
+func (r *ConsignmentRepository) ReturnStock(ctx context.Context, vendorID uint, entries []entity.StockEntry, userID string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, entry := range entries {
+			consigned, err := r.lockStockTx(tx, vendorID, entry.SKUID, entry.StoreID)
+			if err != nil {
+				return err
+			}
+			if consigned == nil || consigned.Quantity < entry.Quantity {
+				return ErrInsufficientStock
+			}
+			if err := tx.Model(consigned).Update("quantity", gorm.Expr("quantity - ?", entry.Quantity)).Error; err != nil {
+				return err
+			}
+		}
+		return r.stocksRepo.ProcessStockEntriesTx(ctx, tx, entries, userID)
+	})
+}
+
+// lockStockTx retrieves and locks the consignment stock of a vendor for a SKU
+// in a store, or nil when the vendor has none
+func (r *ConsignmentRepository) lockStockTx(tx *gorm.DB, vendorID uint, skuID, storeID string) (*entity.ConsignmentStock, error) {
+	var consigned entity.ConsignmentStock
+	result := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("vendor_id = ? AND sku_id = ? AND store_id = ?", vendorID, skuID, storeID).
+		Limit(1).
+		Find(&consigned)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return nil, result.Error
+	}
+	return &consigned, nil
+}
+
+// ListStocks retrieves the consignment stock on hand matching a filter, with
+// vendors and SKUs, by vendor
+func (r *ConsignmentRepository) ListStocks(ctx context.Context, filter *entity.ConsignmentFilter) ([]entity.ConsignmentStock, error) {
+	var stocks []entity.ConsignmentStock
+	query := scopeStores(ctx, r.db.WithContext(ctx).Model(&entity.ConsignmentStock{}), "store_id").
+		Preload("Vendor").
+		Preload("SKU").
+		Where("quantity > 0")
+	query = r.applyFilter(query, filter)
+	if err := query.Order("vendor_id, sku_id, store_id").Find(&stocks).Error; err != nil {
+		return nil, err
+	}
+	return stocks, nil
+}
+
+// ListConsumptions retrieves the consigned stock consumed matching a filter,
+// latest first
+func (r *ConsignmentRepository) ListConsumptions(ctx context.Context, filter *entity.ConsignmentFilter) ([]entity.ConsignmentConsumption, error) {
+	var consumptions []entity.ConsignmentConsumption
+	query := scopeStores(ctx, r.db.WithContext(ctx).Model(&entity.ConsignmentConsumption{}), "store_id").
+		Preload("SKU")
+	query = r.applyFilter(query, filter)
+	if filter != nil && filter.Unbilled {
+		query = query.Where("finance_invoice_id IS NULL")
+	}
+	if err := query.Order("created_at DESC, id DESC").Find(&consumptions).Error; err != nil {
+		return nil, err
+	}
+	return consumptions, nil
+}
+
+// UnbilledAmounts returns the amount of consumed consigned stock not billed
+// yet to each vendor matching a filter
+func (r *ConsignmentRepository) UnbilledAmounts(ctx context.Context, filter *entity.ConsignmentFilter) (map[uint]float64, error) {
+	var rows []struct {
+		VendorID uint
+		Amount   float64
+	}
+	query := scopeStores(ctx, r.db.WithContext(ctx).Model(&entity.ConsignmentConsumption{}), "store_id").
+		Select("vendor_id, SUM(amount) AS amount").
+		Where("finance_invoice_id IS NULL")
+	query = r.applyFilter(query, filter)
+	if err := query.Group("vendor_id").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	amounts := make(map[uint]float64, len(rows))
+	for _, row := range rows {
+		amounts[row.VendorID] = row.Amount
+	}
+	return amounts, nil
+}
+
+func (r *ConsignmentRepository) applyFilter(query *gorm.DB, filter *entity.ConsignmentFilter) *gorm.DB {
+	if filter == nil {
+		return query
+	}
+	if filter.VendorID != nil {
+		query = query.Where("vendor_id = ?", *filter.VendorID)
+	}
+	if filter.StoreID != "" {
+		query = query.Where("store_id = ?", filter.StoreID)
+	}
+	if filter.SKUID != "" {
+		query = query.Where("sku_id = ?", filter.SKUID)
+	}
+	return query
+}
+
+// BillConsumptions records the purchase invoice billing consumed consigned
+// stock and links the consumptions to it, in a single transaction. It fails
+// with ErrConsignmentBilled when another invoice billed any of them first.
+func (r *ConsignmentRepository) BillConsumptions(ctx context.Context, consumptionIDs []uint, invoice *entity.FinanceInvoice) error {
+	if invoice.InvoiceNumber == "" {
+		number, err := r.sequenceGenerator.NextNumber(ctx, entity.DocumentFinancePurchaseInvoice, "")
+		if err != nil {
+			return err
+		}
+		invoice.InvoiceNumber = number
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var unbilled []uint
+		if err := tx.Model(&entity.ConsignmentConsumption{}).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id IN ? AND finance_invoice_id IS NULL", consumptionIDs).
+			Pluck("id", &unbilled).Error; err != nil {
+			return err
+		}
+		if len(unbilled) != len(consumptionIDs) {
+			return ErrConsignmentBilled
+		}
+
+		now := time.Now()
+		invoice.CreatedAt = now
+		invoice.UpdatedAt = now
+		invoice.AmountDue = invoice.Total - invoice.AmountPaid
+		if err := tx.Create(invoice).Error; err != nil {
+			return err
+		}
+		return tx.Model(&entity.ConsignmentConsumption{}).
+			Where("id IN ?", consumptionIDs).
+			Update("finance_invoice_id", invoice.ID).Error
+	})
+}
+
+// applyConsignmentTx keeps the consigned quantity of a stock in line with an
+// entry moving it. Consignment receipts and returns move it with the stock.
+// Any other issue consumes consigned stock before owned stock, converting it
+// to owned stock the vendors are owed for.
+func applyConsignmentTx(ctx context.Context, tx *gorm.DB, stock *entity.Stock, entry *entity.StockEntry) error {
+	if entry.ConsignmentVendorID != nil {
+		switch entry.Type {
+		case "IN":
+			stock.ConsignedQuantity += entry.Quantity
+		case "OUT":
+			if entry.Quantity > stock.ConsignedQuantity {
+				return ErrInsufficientStock
+			}
+			stock.ConsignedQuantity -= entry.Quantity
+		}
+		return nil
+	}
+	if entry.Type != "OUT" {
+		return nil
+	}
+	return consumeConsignmentTx(ctx, tx, stock, math.Min(entry.Quantity, stock.ConsignedQuantity), entry.ID)
+}
+
+// consumeConsignmentTx converts a quantity of the consigned stock of a stock
+// record to owned stock, taking it from the vendors that consigned it
+// earliest and recording each vendor's consumption at its unit cost
+func consumeConsignmentTx(ctx context.Context, tx *gorm.DB, stock *entity.Stock, quantity float64, reference string) error {
+	if quantity <= 0 {
+		return nil
+	}
+
+	var consigned []entity.ConsignmentStock
+	if err := tx.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("sku_id = ? AND store_id = ? AND quantity > 0", stock.SKUID, stock.StoreID).
+		Order("created_at, id").
+		Find(&consigned).Error; err != nil {
+		return err
+	}
+
+	remaining := quantity
+	for i := range consigned {
+		if remaining <= 0 {
+			break
+		}
+		taken := math.Min(remaining, consigned[i].Quantity)
+		if err := tx.WithContext(ctx).Model(&consigned[i]).
+			Update("quantity", gorm.Expr("quantity - ?", taken)).Error; err != nil {
+			return err
+		}
+		if err := tx.WithContext(ctx).Create(&entity.ConsignmentConsumption{
+			VendorID:  consigned[i].VendorID,
+			SKUID:     stock.SKUID,
+			StoreID:   stock.StoreID,
+			Quantity:  taken,
+			UnitCost:  consigned[i].UnitCost,
+			Amount:    math.Round(taken*consigned[i].UnitCost*100) / 100,
+			Reference: reference,
+		}).Error; err != nil {
+			return err
+		}
+		remaining -= taken
+	}
+
+	stock.ConsignedQuantity -= quantity
+	return nil
+}
@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"gorm.io/gorm"
+)
+
+// CostToServeRepository reads the deliveries, returns, purchase prices and
+// payments behind the cost-to-serve report
+type CostToServeRepository struct {
+	db *gorm.DB
+}
+
+// NewCostToServeRepository creates a new cost-to-serve repository
+func NewCostToServeRepository(db *gorm.DB) *CostToServeRepository {
+	return &CostToServeRepository{db: db}
+}
+
+// ListDeliveries retrieves the deliveries dated between the given times that were
+// picked, archived deliveries included, with the customer of their sales order
+func (r *CostToServeRepository) ListDeliveries(ctx context.Context, from, to time.Time) ([]entity.CostToServeDelivery, error) {
+	var deliveries []entity.CostToServeDelivery
+	pairs := [][2]string{
+		{"delivery_orders", "sales_orders"},
+		{archiveTable("delivery_orders"), archiveTable("sales_orders")},
+	}
+	for _, pair := range pairs {
+		var batch []entity.CostToServeDelivery
+		if err := r.db.WithContext(ctx).
+			Table(pair[0]+" AS d").
+			Select("so.client_id, d.items, d.freight_cost").
+			Joins("JOIN "+pair[1]+" AS so ON so.id = d.sales_order_id").
+			Where("d.delivery_date >= ? AND d.delivery_date <= ?", from, to).
+			Where("d.status NOT IN ?", []entity.DeliveryOrderStatus{entity.DeliveryOrderStatusPending, entity.DeliveryOrderStatusCancelled}).
+			Scan(&batch).Error; err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, batch...)
+	}
+	return deliveries, nil
+}
+
+// ListReturns retrieves the sales returns recorded between the given times
+func (r *CostToServeRepository) ListReturns(ctx context.Context, from, to time.Time) ([]entity.SalesReturn, error) {
+	var returns []entity.SalesReturn
+	if err := r.db.WithContext(ctx).
+		Where("created_at >= ? AND created_at <= ?", from, to).
+		Find(&returns).Error; err != nil {
+		return nil, err
+	}
+	return returns, nil
+}
+
+// GetOrders retrieves the items and exchange rate of the given sales orders,
+// archived orders included
+func (r *CostToServeRepository) GetOrders(ctx context.Context, orderIDs []string) (map[string]entity.SalesOrder, error) {
+	orders := make(map[string]entity.SalesOrder, len(orderIDs))
+	if len(orderIDs) == 0 {
+		return orders, nil
+	}
+
+	for _, table := range []string{"sales_orders", archiveTable("sales_orders")} {
+		var batch []entity.SalesOrder
+		if err := r.db.WithContext(ctx).
+			Table(table).
+			Select("id, client_id, items, exchange_rate").
+			Where("id IN ?", orderIDs).
+			Find(&batch).Error; err != nil {
+			return nil, err
+		}
+		for _, order := range batch {
+			orders[order.ID] = order
+		}
+	}
+	return orders, nil
+}
+
+// GetUnitCosts returns the average purchase receipt price of the given SKUs,
+// weighted by the quantity received, falling back to the SKU price for SKUs
+// never received
+func (r *CostToServeRepository) GetUnitCosts(ctx context.Context, skuIDs []string) (map[string]float64, error) {
+	costs := make(map[string]float64, len(skuIDs))
+	if len(skuIDs) == 0 {
+		return costs, nil
+	}
+
+	var receipts []entity.PurchaseReceipt
+	if err := r.db.WithContext(ctx).Select("items").Find(&receipts).Error; err != nil {
+		return nil, err
+	}
+	wanted := make(map[string]bool, len(skuIDs))
+	for _, skuID := range skuIDs {
+		wanted[skuID] = true
+	}
+	value := make(map[string]float64)
+	quantity := make(map[string]float64)
+	for _, receipt := range receipts {
+		for _, item := range receipt.Items {
+			if wanted[item.SKUID] && item.ReceivedQuantity > 0 {
+				value[item.SKUID] += item.UnitPrice * item.ReceivedQuantity
+				quantity[item.SKUID] += item.ReceivedQuantity
+			}
+		}
+	}
+	for skuID, qty := range quantity {
+		costs[skuID] = value[skuID] / qty
+	}
+
+	var skus []entity.SKU
+	if err := r.db.WithContext(ctx).Select("id, price").Where("id IN ?", skuIDs).Find(&skus).Error; err != nil {
+		return nil, err
+	}
+	for _, sku := range skus {
+		if _, ok := costs[sku.ID]; !ok {
+			costs[sku.ID] = sku.Price
+		}
+	}
+	return costs, nil
+}
+
+// ListSalesInvoices retrieves the sales invoices issued between the given times,
+// drafts and cancelled invoices left out
+func (r *CostToServeRepository) ListSalesInvoices(ctx context.Context, from, to time.Time) ([]entity.FinanceInvoice, error) {
+	var invoices []entity.FinanceInvoice
+	if err := r.db.WithContext(ctx).
+		Where("type = ? AND issue_date >= ? AND issue_date <= ?", entity.FinanceSalesInvoice, from, to).
+		Where("status NOT IN ?", []entity.FinanceInvoiceStatus{entity.FinanceInvoiceDraft, entity.FinanceInvoiceCancelled}).
+		Find(&invoices).Error; err != nil {
+		return nil, err
+	}
+	return invoices, nil
+}
+
+// ListCompletedPayments retrieves the completed payments of the given invoices
+func (r *CostToServeRepository) ListCompletedPayments(ctx context.Context, invoiceIDs []int64) ([]entity.FinancePayment, error) {
+	var payments []entity.FinancePayment
+	if len(invoiceIDs) == 0 {
+		return payments, nil
+	}
+	if err := r.db.WithContext(ctx).
+		Where("invoice_id IN ? AND status = ?", invoiceIDs, entity.FinancePaymentCompleted).
+		Find(&payments).Error; err != nil {
+		return nil, err
+	}
+	return payments, nil
+}
+
+// GetClientNames returns the name of each of the given clients
+func (r *CostToServeRepository) GetClientNames(ctx context.Context, clientIDs []uint) (map[uint]string, error) {
+	names := make(map[uint]string, len(clientIDs))
+	if len(clientIDs) == 0 {
+		return names, nil
+	}
+
+	var clients []entity.Client
+	if err := r.db.WithContext(ctx).Select("id, name").Where("id IN ?", clientIDs).Find(&clients).Error; err != nil {
+		return nil, err
+	}
+	for _, client := range clients {
+		names[client.ID] = client.Name
+	}
+	return names, nil
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CurrencyRepository handles database operations for exchange rates
+type CurrencyRepository struct {
+	db *gorm.DB
+}
+
+// NewCurrencyRepository creates a new currency repository
+func NewCurrencyRepository(db *gorm.DB) *CurrencyRepository {
+	return &CurrencyRepository{db: db}
+}
+
+// SaveRate stores the rate of a currency pair for a day, replacing any rate already recorded for that day
+func (r *CurrencyRepository) SaveRate(ctx context.Context, rate *entity.ExchangeRate) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "from_currency"}, {Name: "to_currency"}, {Name: "rate_date"}},
+		DoUpdates: clause.AssignmentColumns([]string{"rate", "source", "updated_at"}),
+	}).Create(rate).Error
+}
+
+// FindRate retrieves the most recent rate of a currency pair on or before the given date
+func (r *CurrencyRepository) FindRate(ctx context.Context, from, to string, date time.Time) (*entity.ExchangeRate, error) {
+	var rate entity.ExchangeRate
+	err := r.db.WithContext(ctx).
+		Where("from_currency = ? AND to_currency = ? AND rate_date <= ?", from, to, date).
+		Order("rate_date DESC").
+		First(&rate).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &rate, nil
+}
+
+// ListPairRates retrieves every rate recorded for a currency pair, newest first
+func (r *CurrencyRepository) ListPairRates(ctx context.Context, from, to string) ([]entity.ExchangeRate, error) {
+	var rates []entity.ExchangeRate
+	if err := r.db.WithContext(ctx).
+		Where("from_currency = ? AND to_currency = ?", from, to).
+		Order("rate_date DESC").
+		Find(&rates).Error; err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
+
+// ListRates retrieves exchange rates with filters, newest first
+func (r *CurrencyRepository) ListRates(ctx context.Context, filter *entity.ExchangeRateFilter) ([]entity.ExchangeRate, error) {
+	var rates []entity.ExchangeRate
+	query := r.db.WithContext(ctx).Model(&entity.ExchangeRate{})
+
+	if filter.Currency != "" {
+		query = query.Where("from_currency = ?", filter.Currency)
+	}
+	if filter.StartDate != nil {
+		query = query.Where("rate_date >= ?", filter.StartDate)
+	}
+	if filter.EndDate != nil {
+		query = query.Where("rate_date <= ?", filter.EndDate)
+	}
+
+	if err := query.Order("rate_date DESC, from_currency").Find(&rates).Error; err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"gorm.io/gorm"
+)
+
+type CustomFieldRepository struct {
+	db *gorm.DB
+}
+
+func NewCustomFieldRepository(db *gorm.DB) *CustomFieldRepository {
+	return &CustomFieldRepository{db: db}
+}
+
+// CreateDefinition creates a custom field definition
+func (r *CustomFieldRepository) CreateDefinition(ctx context.Context, definition *entity.CustomFieldDefinition) error {
+	return r.db.WithContext(ctx).Create(definition).Error
+}
+
+// UpdateDefinition updates a custom field definition
+func (r *CustomFieldRepository) UpdateDefinition(ctx context.Context, definition *entity.CustomFieldDefinition) error {
+	return r.db.WithContext(ctx).Save(definition).Error
+}
+
+// GetDefinition retrieves a custom field definition by ID
+func (r *CustomFieldRepository) GetDefinition(ctx context.Context, id uint) (*entity.CustomFieldDefinition, error) {
+	var definition entity.CustomFieldDefinition
+	if err := r.db.WithContext(ctx).First(&definition, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &definition, nil
+}
+
+// ListDefinitions retrieves the custom field definitions of an entity type,
+// or of every type when it is empty, in their position order
+func (r *CustomFieldRepository) ListDefinitions(ctx context.Context, entityType string) ([]entity.CustomFieldDefinition, error) {
+	var definitions []entity.CustomFieldDefinition
+	query := r.db.WithContext(ctx)
+	if entityType != "" {
+		query = query.Where("entity_type = ?", entityType)
+	}
+	if err := query.Order("entity_type, position, id").Find(&definitions).Error; err != nil {
+		return nil, err
+	}
+	return definitions, nil
+}
+
+// DeleteDefinition deletes a custom field definition along with the values
+// the entities of its type hold for it
+func (r *CustomFieldRepository) DeleteDefinition(ctx context.Context, definition *entity.CustomFieldDefinition) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&entity.CustomFieldDefinition{}, definition.ID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrRecordNotFound
+		}
+		// jsonb_exists stands for the ? operator, which would be taken for a placeholder
+		return tx.Exec(fmt.Sprintf("UPDATE %q SET custom_fields = custom_fields - ? WHERE jsonb_exists(custom_fields, ?)", definition.EntityType),
+			definition.Key, definition.Key).Error
+	})
+}
+
+// whereCustomFields restricts a query to the rows whose custom fields hold
+// the given values
+func whereCustomFields(query *gorm.DB, values entity.JSONMap) *gorm.DB {
+	if len(values) == 0 {
+		return query
+	}
+	match, _ := json.Marshal(values)
+	return query.Where("custom_fields @> ?::jsonb", string(match))
+}
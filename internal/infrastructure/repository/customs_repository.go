@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"gorm.io/gorm"
+)
+
+// CustomsRepository reads the purchase receipts and deliveries behind the
+// Intrastat and customs report
+type CustomsRepository struct {
+	db *gorm.DB
+}
+
+func NewCustomsRepository(db *gorm.DB) *CustomsRepository {
+	return &CustomsRepository{db: db}
+}
+
+// ListArrivals retrieves the purchase receipts dated in [from, to), archived
+// receipts included, with the lines and rate of their order and the country
+// of its vendor
+func (r *CustomsRepository) ListArrivals(ctx context.Context, from, to time.Time) ([]entity.CustomsArrival, error) {
+	var arrivals []entity.CustomsArrival
+	pairs := [][2]string{
+		{"purchase_receipts", "purchase_orders"},
+		{archiveTable("purchase_receipts"), archiveTable("purchase_orders")},
+	}
+	for _, pair := range pairs {
+		var batch []entity.CustomsArrival
+		if err := r.db.WithContext(ctx).
+			Table(pair[0]+" AS r").
+			Select("r.receipt_number, r.items AS receipt_items, po.items AS order_items, po.exchange_rate, COALESCE(v.country, '') AS country").
+			Joins("JOIN "+pair[1]+" AS po ON po.id = r.purchase_order_id").
+			Joins("LEFT JOIN vendors AS v ON v.id = po.vendor_id").
+			Where("r.receipt_date >= ? AND r.receipt_date < ?", from, to).
+			Order("r.receipt_date, r.receipt_number").
+			Scan(&batch).Error; err != nil {
+			return nil, err
+		}
+		arrivals = append(arrivals, batch...)
+	}
+	return arrivals, nil
+}
+
+// ListDispatches retrieves the deliveries dated in [from, to) that left the
+// warehouse, archived deliveries included, with the lines and rate of their
+// sales order and the country of the customer's shipping address. Drop-ship
+// deliveries are left out, as the vendor ships them from its own country.
+func (r *CustomsRepository) ListDispatches(ctx context.Context, from, to time.Time) ([]entity.CustomsDispatch, error) {
+	var dispatches []entity.CustomsDispatch
+	pairs := [][2]string{
+		{"delivery_orders", "sales_orders"},
+		{archiveTable("delivery_orders"), archiveTable("sales_orders")},
+	}
+	for _, pair := range pairs {
+		var batch []entity.CustomsDispatch
+		if err := r.db.WithContext(ctx).
+			Table(pair[0]+" AS d").
+			Select("d.delivery_number, d.items AS delivery_items, so.items AS order_items, so.exchange_rate, "+
+				"COALESCE((SELECT ca.country FROM client_addresses AS ca WHERE ca.client_id = so.client_id "+
+				"AND ca.type IN ('SHIPPING', 'BOTH') ORDER BY ca.is_default DESC, ca.id LIMIT 1), '') AS country").
+			Joins("JOIN "+pair[1]+" AS so ON so.id = d.sales_order_id").
+			Where("d.delivery_date >= ? AND d.delivery_date < ?", from, to).
+			Where("d.status IN ?", []entity.DeliveryOrderStatus{entity.DeliveryOrderStatusInTransit, entity.DeliveryOrderStatusDelivered}).
+			Where("d.purchase_order_id IS NULL").
+			Order("d.delivery_date, d.delivery_number").
+			Scan(&batch).Error; err != nil {
+			return nil, err
+		}
+		dispatches = append(dispatches, batch...)
+	}
+	return dispatches, nil
+}
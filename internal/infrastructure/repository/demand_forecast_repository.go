@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"gorm.io/gorm"
+)
+
+// DemandForecastRepository handles database operations for demand forecast versions
+type DemandForecastRepository struct {
+	db *gorm.DB
+}
+
+// NewDemandForecastRepository creates a new demand forecast repository
+func NewDemandForecastRepository(db *gorm.DB) *DemandForecastRepository {
+	return &DemandForecastRepository{db: db}
+}
+
+// CreateVersion stores a demand forecast version with its lines. An active
+// version replaces the previously active one.
+func (r *DemandForecastRepository) CreateVersion(ctx context.Context, version *entity.DemandForecastVersion) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if version.Active {
+			if err := tx.Model(&entity.DemandForecastVersion{}).
+				Where("active = ?", true).
+				Update("active", false).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Omit("Lines").Create(version).Error; err != nil {
+			return err
+		}
+		if len(version.Lines) == 0 {
+			return nil
+		}
+		for i := range version.Lines {
+			version.Lines[i].VersionID = version.ID
+		}
+		return tx.CreateInBatches(&version.Lines, createBatchSize(tx)).Error
+	})
+}
+
+// ListVersions retrieves the demand forecast versions without their lines, newest first
+func (r *DemandForecastRepository) ListVersions(ctx context.Context) ([]entity.DemandForecastVersion, error) {
+	var versions []entity.DemandForecastVersion
+	if err := r.db.WithContext(ctx).Order("id DESC").Find(&versions).Error; err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
+
+// GetVersion retrieves a demand forecast version with its lines
+func (r *DemandForecastRepository) GetVersion(ctx context.Context, id uint) (*entity.DemandForecastVersion, error) {
+	var version entity.DemandForecastVersion
+	if err := r.db.WithContext(ctx).
+		Preload("Lines", func(db *gorm.DB) *gorm.DB {
+			return db.Order("sku_id, period")
+		}).
+		First(&version, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &version, nil
+}
+
+// GetActiveVersion retrieves the active demand forecast version without its lines
+func (r *DemandForecastRepository) GetActiveVersion(ctx context.Context) (*entity.DemandForecastVersion, error) {
+	var version entity.DemandForecastVersion
+	if err := r.db.WithContext(ctx).Where("active = ?", true).First(&version).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &version, nil
+}
+
+// ActivateVersion makes a demand forecast version the active one
+func (r *DemandForecastRepository) ActivateVersion(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&entity.DemandForecastVersion{}).
+			Where("active = ? AND id <> ?", true, id).
+			Update("active", false).Error; err != nil {
+			return err
+		}
+		result := tx.Model(&entity.DemandForecastVersion{}).Where("id = ?", id).Update("active", true)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+// ListVersionLines retrieves the lines of a version for the periods in a range,
+// optionally for a single SKU
+func (r *DemandForecastRepository) ListVersionLines(ctx context.Context, versionID uint, skuID, from, to string) ([]entity.DemandForecastLine, error) {
+	var lines []entity.DemandForecastLine
+	query := r.db.WithContext(ctx).Where("version_id = ? AND period >= ? AND period <= ?", versionID, from, to)
+	if skuID != "" {
+		query = query.Where("sku_id = ?", skuID)
+	}
+	if err := query.Order("sku_id, period").Find(&lines).Error; err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
+
+// GetSKUSupply returns the code, name, designated vendor and vendor lead time of the given SKUs
+func (r *DemandForecastRepository) GetSKUSupply(ctx context.Context, skuIDs []string) (map[string]entity.SKUSupply, error) {
+	supply := make(map[string]entity.SKUSupply, len(skuIDs))
+	if len(skuIDs) == 0 {
+		return supply, nil
+	}
+
+	var rows []entity.SKUSupply
+	if err := r.db.WithContext(ctx).
+		Table("skus").
+		Select("skus.id AS sku_id, skus.sku_code, skus.name, skus.abc_class, skus.xyz_class, skus.vendor_id, COALESCE(vendors.lead_time_days, 0) AS lead_time_days").
+		Joins("LEFT JOIN vendors ON vendors.id = skus.vendor_id").
+		Where("skus.id IN ?", skuIDs).
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		supply[row.SKUID] = row
+	}
+	return supply, nil
+}
+
+// GetAvailableStock sums the stock on hand less quarantine of the given SKUs,
+// in one store or across all stores when storeID is empty
+func (r *DemandForecastRepository) GetAvailableStock(ctx context.Context, skuIDs []string, storeID string) (map[string]float64, error) {
+	available := make(map[string]float64, len(skuIDs))
+	if len(skuIDs) == 0 {
+		return available, nil
+	}
+
+	var rows []struct {
+		SKUID     string
+		Available float64
+	}
+	query := r.db.WithContext(ctx).
+		Model(&entity.Stock{}).
+		Select("sku_id, SUM(quantity - quarantined_quantity) AS available").
+		Where("sku_id IN ?", skuIDs)
+	if storeID != "" {
+		query = query.Where("store_id = ?", storeID)
+	}
+	if err := query.Group("sku_id").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		available[row.SKUID] = row.Available
+	}
+	return available, nil
+}
@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"gorm.io/gorm"
+)
+
+// DunningRepository handles database operations for dunning levels and reminders
+type DunningRepository struct {
+	db *gorm.DB
+}
+
+// NewDunningRepository creates a new dunning repository
+func NewDunningRepository(db *gorm.DB) *DunningRepository {
+	return &DunningRepository{db: db}
+}
+
+// CreateLevel creates a dunning level
+func (r *DunningRepository) CreateLevel(ctx context.Context, level *entity.DunningLevel) error {
+	return r.db.WithContext(ctx).Create(level).Error
+}
+
+// GetLevel retrieves a dunning level by ID
+func (r *DunningRepository) GetLevel(ctx context.Context, id uint) (*entity.DunningLevel, error) {
+	var level entity.DunningLevel
+	if err := r.db.WithContext(ctx).First(&level, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &level, nil
+}
+
+// UpdateLevel saves a dunning level
+func (r *DunningRepository) UpdateLevel(ctx context.Context, level *entity.DunningLevel) error {
+	return r.db.WithContext(ctx).Save(level).Error
+}
+
+// ListLevels retrieves dunning levels in escalation order
+func (r *DunningRepository) ListLevels(ctx context.Context, activeOnly bool) ([]entity.DunningLevel, error) {
+	var levels []entity.DunningLevel
+	query := r.db.WithContext(ctx)
+	if activeOnly {
+		query = query.Where("active = ?", true)
+	}
+	if err := query.Order("days_overdue").Find(&levels).Error; err != nil {
+		return nil, err
+	}
+	return levels, nil
+}
+
+// ListOverdueInvoices retrieves the issued sales invoices due before the given
+// time with an amount still outstanding
+func (r *DunningRepository) ListOverdueInvoices(ctx context.Context, asOf time.Time) ([]entity.FinanceInvoice, error) {
+	var invoices []entity.FinanceInvoice
+	if err := r.db.WithContext(ctx).
+		Where("type = ? AND due_date < ? AND amount_due > 0", entity.FinanceSalesInvoice, asOf).
+		Where("status IN ?", []entity.FinanceInvoiceStatus{
+			entity.FinanceInvoicePending,
+			entity.FinanceInvoiceApproved,
+			entity.FinanceInvoicePartiallyPaid,
+			entity.FinanceInvoiceOverdue,
+		}).
+		Order("due_date, id").
+		Find(&invoices).Error; err != nil {
+		return nil, err
+	}
+	return invoices, nil
+}
+
+// FlagOverdue marks the given invoices OVERDUE unless they are partially paid or
+// already flagged, and returns the number of invoices changed
+func (r *DunningRepository) FlagOverdue(ctx context.Context, invoiceIDs []int64) (int64, error) {
+	if len(invoiceIDs) == 0 {
+		return 0, nil
+	}
+
+	result := r.db.WithContext(ctx).Model(&entity.FinanceInvoice{}).
+		Where("id IN ? AND status IN ?", invoiceIDs, []entity.FinanceInvoiceStatus{
+			entity.FinanceInvoicePending,
+			entity.FinanceInvoiceApproved,
+		}).
+		Updates(map[string]interface{}{
+			"status":     entity.FinanceInvoiceOverdue,
+			"updated_at": time.Now(),
+		})
+	return result.RowsAffected, result.Error
+}
+
+// ListSentReminders retrieves the invoice and days overdue of every reminder
+// already sent for the given invoices
+func (r *DunningRepository) ListSentReminders(ctx context.Context, invoiceIDs []int64) ([]entity.DunningReminder, error) {
+	var reminders []entity.DunningReminder
+	if len(invoiceIDs) == 0 {
+		return reminders, nil
+	}
+
+	err := r.db.WithContext(ctx).
+		Select("invoice_id, days_overdue").
+		Where("invoice_id IN ?", invoiceIDs).
+		Find(&reminders).Error
+	return reminders, err
+}
+
+// CreateReminder records a dunning reminder
+func (r *DunningRepository) CreateReminder(ctx context.Context, reminder *entity.DunningReminder) error {
+	return r.db.WithContext(ctx).Create(reminder).Error
+}
+
+// ListReminders retrieves the reminder history with filters and pagination, latest first
+func (r *DunningRepository) ListReminders(ctx context.Context, filter *entity.DunningReminderFilter) ([]entity.DunningReminder, int64, error) {
+	var reminders []entity.DunningReminder
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&entity.DunningReminder{})
+	if filter.InvoiceID != 0 {
+		query = query.Where("invoice_id = ?", filter.InvoiceID)
+	}
+	if filter.EntityID != 0 {
+		query = query.Where("entity_id = ?", filter.EntityID)
+	}
+	if filter.LevelID != 0 {
+		query = query.Where("level_id = ?", filter.LevelID)
+	}
+	if filter.StartDate != nil {
+		query = query.Where("sent_at >= ?", filter.StartDate)
+	}
+	if filter.EndDate != nil {
+		query = query.Where("sent_at <= ?", filter.EndDate)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (filter.Page - 1) * filter.PageSize
+	if err := query.Order("sent_at DESC, id DESC").Limit(filter.PageSize).Offset(offset).Find(&reminders).Error; err != nil {
+		return nil, 0, err
+	}
+	return reminders, total, nil
+}
@@ -0,0 +1,217 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// EDIRepository handles database operations for trading partners and the EDI
+// documents exchanged with them
+type EDIRepository struct {
+	db                *gorm.DB
+	prepaymentRepo    *PrepaymentRepository
+	sequenceGenerator *SequenceGenerator
+}
+
+func NewEDIRepository(db *gorm.DB, prepaymentRepo *PrepaymentRepository) *EDIRepository {
+	return &EDIRepository{
+		db:                db,
+		prepaymentRepo:    prepaymentRepo,
+		sequenceGenerator: NewSequenceGenerator(db),
+	}
+}
+
+// CreatePartner creates a trading partner, rejecting a vendor that already
+// has one
+func (r *EDIRepository) CreatePartner(ctx context.Context, partner *entity.TradingPartner) error {
+	if err := r.checkVendor(ctx, partner); err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Omit("Vendor").Create(partner).Error
+}
+
+// UpdatePartner saves a trading partner, rejecting a vendor that already has
+// one
+func (r *EDIRepository) UpdatePartner(ctx context.Context, partner *entity.TradingPartner) error {
+	if err := r.checkVendor(ctx, partner); err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Omit("Vendor").Save(partner).Error
+}
+
+// checkVendor checks that no other trading partner is set up for the
+// partner's vendor
+func (r *EDIRepository) checkVendor(ctx context.Context, partner *entity.TradingPartner) error {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&entity.TradingPartner{}).
+		Where("vendor_id = ? AND id <> ?", partner.VendorID, partner.ID).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrDuplicateEntry
+	}
+	return nil
+}
+
+// GetPartner retrieves a trading partner by ID with its vendor
+func (r *EDIRepository) GetPartner(ctx context.Context, id uint) (*entity.TradingPartner, error) {
+	return r.findPartner(ctx, "id = ?", id)
+}
+
+// GetPartnerByVendor retrieves the trading partner of a vendor
+func (r *EDIRepository) GetPartnerByVendor(ctx context.Context, vendorID uint) (*entity.TradingPartner, error) {
+	return r.findPartner(ctx, "vendor_id = ?", vendorID)
+}
+
+// GetPartnerBySender retrieves the active trading partner known by an
+// interchange ID
+func (r *EDIRepository) GetPartnerBySender(ctx context.Context, qualifier, isaID string) (*entity.TradingPartner, error) {
+	return r.findPartner(ctx, "isa_qualifier = ? AND isa_id = ? AND active = ?", qualifier, isaID, true)
+}
+
+func (r *EDIRepository) findPartner(ctx context.Context, query string, args ...interface{}) (*entity.TradingPartner, error) {
+	var partner entity.TradingPartner
+	if err := r.db.WithContext(ctx).Preload("Vendor").Where(query, args...).First(&partner).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &partner, nil
+}
+
+// ListPartners retrieves the trading partners with their vendors
+func (r *EDIRepository) ListPartners(ctx context.Context) ([]entity.TradingPartner, error) {
+	var partners []entity.TradingPartner
+	if err := r.db.WithContext(ctx).Preload("Vendor").Order("id").Find(&partners).Error; err != nil {
+		return nil, err
+	}
+	return partners, nil
+}
+
+// NextControlNumber takes the next interchange control number of a trading
+// partner. Control numbers wrap after the nine digits of the ISA segment.
+func (r *EDIRepository) NextControlNumber(ctx context.Context, partnerID uint) (int64, error) {
+	var number int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var partner entity.TradingPartner
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&partner, partnerID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return ErrRecordNotFound
+			}
+			return err
+		}
+		number = partner.LastControlNumber%999999999 + 1
+		return tx.Model(&partner).Update("last_control_number", number).Error
+	})
+	return number, err
+}
+
+// CreateDocument archives an EDI document
+func (r *EDIRepository) CreateDocument(ctx context.Context, document *entity.EDIDocument) error {
+	return r.db.WithContext(ctx).Omit("Partner").Create(document).Error
+}
+
+// UpdateDocument saves an EDI document
+func (r *EDIRepository) UpdateDocument(ctx context.Context, document *entity.EDIDocument) error {
+	return r.db.WithContext(ctx).Omit("Partner").Save(document).Error
+}
+
+// GetDocument retrieves an EDI document by ID with its trading partner
+func (r *EDIRepository) GetDocument(ctx context.Context, id uint) (*entity.EDIDocument, error) {
+	var document entity.EDIDocument
+	if err := r.db.WithContext(ctx).Preload("Partner.Vendor").First(&document, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &document, nil
+}
+
+// ListDocuments retrieves EDI documents with filters, latest first
+func (r *EDIRepository) ListDocuments(ctx context.Context, filter *entity.EDIDocumentFilter, page, pageSize int) ([]entity.EDIDocument, int64, error) {
+	var documents []entity.EDIDocument
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&entity.EDIDocument{})
+	if filter != nil {
+		if filter.PartnerID != nil {
+			query = query.Where("partner_id = ?", *filter.PartnerID)
+		}
+		if filter.Direction != nil {
+			query = query.Where("direction = ?", *filter.Direction)
+		}
+		if filter.Type != "" {
+			query = query.Where("type = ?", filter.Type)
+		}
+		if filter.Status != nil {
+			query = query.Where("status = ?", *filter.Status)
+		}
+		if filter.PurchaseOrderID != "" {
+			query = query.Where("purchase_order_id = ?", filter.PurchaseOrderID)
+		}
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := query.Offset((page - 1) * pageSize).Limit(pageSize).
+		Order("created_at DESC, id DESC").
+		Find(&documents).Error; err != nil {
+		return nil, 0, err
+	}
+	return documents, total, nil
+}
+
+// HasApplied tells whether an inbound document of a type and reference was
+// already applied for a trading partner
+func (r *EDIRepository) HasApplied(ctx context.Context, partnerID uint, docType, reference string) (bool, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&entity.EDIDocument{}).
+		Where("partner_id = ? AND direction = ? AND type = ? AND reference = ? AND status = ?",
+			partnerID, entity.EDIInbound, docType, reference, entity.EDIDocumentApplied).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// CreateInvoice raises the purchase invoice an inbound 810 document was mapped
+// to, settles it with the unapplied advances of its order and marks the
+// document applied in one transaction
+func (r *EDIRepository) CreateInvoice(ctx context.Context, document *entity.EDIDocument, invoice *entity.FinanceInvoice) error {
+	if invoice.InvoiceNumber == "" {
+		number, err := r.sequenceGenerator.NextNumber(ctx, entity.DocumentFinancePurchaseInvoice, "")
+		if err != nil {
+			return err
+		}
+		invoice.InvoiceNumber = number
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		invoice.CreatedAt = now
+		invoice.UpdatedAt = now
+		invoice.AmountDue = invoice.Total - invoice.AmountPaid
+		if err := tx.Create(invoice).Error; err != nil {
+			return err
+		}
+		if _, err := r.prepaymentRepo.ApplyToFinanceInvoiceTx(tx, invoice); err != nil {
+			return err
+		}
+
+		document.FinanceInvoiceID = &invoice.ID
+		document.Status = entity.EDIDocumentApplied
+		document.Error = ""
+		if document.ID == 0 {
+			return tx.Omit("Partner").Create(document).Error
+		}
+		return tx.Omit("Partner").Save(document).Error
+	})
+}
@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"gorm.io/gorm"
+)
+
+type ElevatedAccessRepository struct {
+	db *gorm.DB
+}
+
+func NewElevatedAccessRepository(db *gorm.DB) *ElevatedAccessRepository {
+	return &ElevatedAccessRepository{db: db}
+}
+
+// CreateGrant creates an elevated access grant
+func (r *ElevatedAccessRepository) CreateGrant(ctx context.Context, grant *entity.ElevatedAccessGrant) error {
+	return r.db.WithContext(ctx).Create(grant).Error
+}
+
+// GetGrant retrieves an elevated access grant by ID
+func (r *ElevatedAccessRepository) GetGrant(ctx context.Context, id uint) (*entity.ElevatedAccessGrant, error) {
+	var grant entity.ElevatedAccessGrant
+	if err := r.db.WithContext(ctx).Preload("User").First(&grant, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &grant, nil
+}
+
+// UpdateGrant saves an elevated access grant
+func (r *ElevatedAccessRepository) UpdateGrant(ctx context.Context, grant *entity.ElevatedAccessGrant) error {
+	return r.db.WithContext(ctx).Omit("User").Save(grant).Error
+}
+
+// ListGrants retrieves the elevated access grants matching a filter, latest first
+func (r *ElevatedAccessRepository) ListGrants(ctx context.Context, filter *entity.ElevationFilter) ([]entity.ElevatedAccessGrant, int64, error) {
+	var grants []entity.ElevatedAccessGrant
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&entity.ElevatedAccessGrant{})
+	if filter.UserID != 0 {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.StartDate != nil {
+		query = query.Where("requested_at >= ?", filter.StartDate)
+	}
+	if filter.EndDate != nil {
+		query = query.Where("requested_at <= ?", filter.EndDate)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (filter.Page - 1) * filter.PageSize
+	if err := query.
+		Preload("User").
+		Order("requested_at DESC, id DESC").
+		Limit(filter.PageSize).
+		Offset(offset).
+		Find(&grants).Error; err != nil {
+		return nil, 0, err
+	}
+	return grants, total, nil
+}
+
+// ListActiveGrants retrieves a user's approved grants that have not expired
+func (r *ElevatedAccessRepository) ListActiveGrants(ctx context.Context, userID uint, at time.Time) ([]entity.ElevatedAccessGrant, error) {
+	var grants []entity.ElevatedAccessGrant
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ? AND status = ? AND expires_at > ?", userID, entity.ElevationApproved, at).
+		Order("id").
+		Find(&grants).Error; err != nil {
+		return nil, err
+	}
+	return grants, nil
+}
+
+// ExpireGrants marks the approved grants past their expiry as expired and
+// returns how many were
+func (r *ElevatedAccessRepository) ExpireGrants(ctx context.Context, at time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Model(&entity.ElevatedAccessGrant{}).
+		Where("status = ? AND expires_at <= ?", entity.ElevationApproved, at).
+		Update("status", entity.ElevationExpired)
+	return result.RowsAffected, result.Error
+}
+
+// ListGrantsActiveBetween retrieves the grants that were approved and active at
+// some point in the period, whatever their status now
+func (r *ElevatedAccessRepository) ListGrantsActiveBetween(ctx context.Context, userID uint, start, end time.Time) ([]entity.ElevatedAccessGrant, error) {
+	query := r.db.WithContext(ctx).
+		Preload("User").
+		Where("reviewed_at IS NOT NULL AND expires_at IS NOT NULL").
+		Where("reviewed_at <= ? AND COALESCE(revoked_at, expires_at) >= ?", end, start)
+	if userID != 0 {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	var grants []entity.ElevatedAccessGrant
+	if err := query.Order("reviewed_at, id").Find(&grants).Error; err != nil {
+		return nil, err
+	}
+	return grants, nil
+}
+
+// CreateActions records elevated actions
+func (r *ElevatedAccessRepository) CreateActions(ctx context.Context, actions []entity.ElevatedAction) error {
+	if len(actions) == 0 {
+		return nil
+	}
+	db := r.db.WithContext(ctx)
+	return db.CreateInBatches(&actions, createBatchSize(db)).Error
+}
+
+// ListActions retrieves the elevated actions taken under the given grants,
+// optionally limited to a period, in the order they were taken
+func (r *ElevatedAccessRepository) ListActions(ctx context.Context, grantIDs []uint, start, end *time.Time) ([]entity.ElevatedAction, error) {
+	var actions []entity.ElevatedAction
+	if len(grantIDs) == 0 {
+		return actions, nil
+	}
+
+	query := r.db.WithContext(ctx).Where("grant_id IN ?", grantIDs)
+	if start != nil {
+		query = query.Where("created_at >= ?", start)
+	}
+	if end != nil {
+		query = query.Where("created_at <= ?", end)
+	}
+	if err := query.Order("created_at, id").Find(&actions).Error; err != nil {
+		return nil, err
+	}
+	return actions, nil
+}
@@ -1,11 +1,13 @@
 package repository
 
-import "errors"
+import "github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
 
 var (
-	ErrRecordNotFound    = errors.New("record not found")
-	ErrInsufficientStock = errors.New("insufficient stock quantity")
-	ErrDuplicateEntry    = errors.New("duplicate entry")
-	ErrInvalidData       = errors.New("invalid data")
-	ErrRoleInUse         = errors.New("role is in use by users")
+	ErrRecordNotFound    = entity.NewError(entity.ErrCodeNotFound, "record not found")
+	ErrInsufficientStock = entity.NewError(entity.ErrCodeFailedPrecondition, "insufficient stock quantity")
+	ErrDuplicateEntry    = entity.NewError(entity.ErrCodeConflict, "duplicate entry")
+	ErrInvalidData       = entity.NewError(entity.ErrCodeInvalidArgument, "invalid data")
+	ErrRoleInUse         = entity.NewError(entity.ErrCodeConflict, "role is in use by users")
+
+	ErrQueryStatsUnavailable = entity.NewError(entity.ErrCodeUnavailable, "pg_stat_statements extension is not installed")
 )
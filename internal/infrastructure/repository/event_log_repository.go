@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"gorm.io/gorm"
+)
+
+type EventLogRepository struct {
+	db *gorm.DB
+}
+
+func NewEventLogRepository(db *gorm.DB) *EventLogRepository {
+	return &EventLogRepository{db: db}
+}
+
+// Create appends an event to the event log
+func (r *EventLogRepository) Create(ctx context.Context, entry *entity.EventLogEntry) error {
+	return r.db.WithContext(ctx).Create(entry).Error
+}
+
+// List retrieves the logged events matching a filter, latest first
+func (r *EventLogRepository) List(ctx context.Context, filter *entity.EventLogFilter) ([]entity.EventLogEntry, int64, error) {
+	var entries []entity.EventLogEntry
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&entity.EventLogEntry{})
+	if filter.Name != "" {
+		query = query.Where("name = ?", filter.Name)
+	}
+	if filter.StartDate != nil {
+		query = query.Where("occurred_at >= ?", filter.StartDate)
+	}
+	if filter.EndDate != nil {
+		query = query.Where("occurred_at <= ?", filter.EndDate)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (filter.Page - 1) * filter.PageSize
+	if err := query.
+		Order("occurred_at DESC, id DESC").
+		Limit(filter.PageSize).
+		Offset(offset).
+		Find(&entries).Error; err != nil {
+		return nil, 0, err
+	}
+	return entries, total, nil
+}
@@ -0,0 +1,241 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrExpenseClaimReviewed = entity.NewError(entity.ErrCodeConflict, "expense claim was reviewed in the meantime")
+
+// ExpenseRepository handles database operations for expense categories and
+// expense claims
+type ExpenseRepository struct {
+	db                *gorm.DB
+	sequenceGenerator *SequenceGenerator
+}
+
+// NewExpenseRepository creates a new expense repository
+func NewExpenseRepository(db *gorm.DB) *ExpenseRepository {
+	return &ExpenseRepository{
+		db:                db,
+		sequenceGenerator: NewSequenceGenerator(db),
+	}
+}
+
+// CreateCategory creates an expense category. Category codes are unique.
+func (r *ExpenseRepository) CreateCategory(ctx context.Context, category *entity.ExpenseCategory) error {
+	if err := r.checkCategoryCode(ctx, category); err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Create(category).Error
+}
+
+// checkCategoryCode returns ErrDuplicateEntry when another category has the
+// code of category
+func (r *ExpenseRepository) checkCategoryCode(ctx context.Context, category *entity.ExpenseCategory) error {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&entity.ExpenseCategory{}).
+		Where("code = ? AND id <> ?", category.Code, category.ID).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrDuplicateEntry
+	}
+	return nil
+}
+
+// GetCategory retrieves an expense category by ID
+func (r *ExpenseRepository) GetCategory(ctx context.Context, id uint) (*entity.ExpenseCategory, error) {
+	var category entity.ExpenseCategory
+	if err := r.db.WithContext(ctx).First(&category, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &category, nil
+}
+
+// UpdateCategory saves an expense category
+func (r *ExpenseRepository) UpdateCategory(ctx context.Context, category *entity.ExpenseCategory) error {
+	if err := r.checkCategoryCode(ctx, category); err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Save(category).Error
+}
+
+// ListCategories retrieves the expense categories by code, only the active
+// ones unless all is set
+func (r *ExpenseRepository) ListCategories(ctx context.Context, all bool) ([]entity.ExpenseCategory, error) {
+	var categories []entity.ExpenseCategory
+	query := r.db.WithContext(ctx).Order("code")
+	if !all {
+		query = query.Where("active = ?", true)
+	}
+	if err := query.Find(&categories).Error; err != nil {
+		return nil, err
+	}
+	return categories, nil
+}
+
+// CreateClaim numbers and creates an expense claim with its lines
+func (r *ExpenseRepository) CreateClaim(ctx context.Context, claim *entity.ExpenseClaim) error {
+	if claim.ClaimNumber == "" {
+		number, err := r.sequenceGenerator.NextNumber(ctx, entity.DocumentExpenseClaim, "")
+		if err != nil {
+			return err
+		}
+		claim.ClaimNumber = number
+	}
+	return r.db.WithContext(ctx).Create(claim).Error
+}
+
+// GetClaim retrieves an expense claim with its lines and their categories
+func (r *ExpenseRepository) GetClaim(ctx context.Context, id uint) (*entity.ExpenseClaim, error) {
+	var claim entity.ExpenseClaim
+	if err := r.db.WithContext(ctx).
+		Preload("Lines", func(db *gorm.DB) *gorm.DB { return db.Order("expense_date, id") }).
+		Preload("Lines.Category").
+		First(&claim, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &claim, nil
+}
+
+// ListClaims retrieves expense claims with filters and pagination, without
+// their lines
+func (r *ExpenseRepository) ListClaims(ctx context.Context, filter *entity.ExpenseClaimFilter) ([]entity.ExpenseClaim, int64, error) {
+	var claims []entity.ExpenseClaim
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&entity.ExpenseClaim{})
+	if filter.EmployeeID != nil {
+		query = query.Where("employee_id = ?", *filter.EmployeeID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.StartDate != nil || filter.EndDate != nil {
+		lines := r.db.Model(&entity.ExpenseClaimLine{}).Select("claim_id")
+		if filter.StartDate != nil {
+			lines = lines.Where("expense_date >= ?", *filter.StartDate)
+		}
+		if filter.EndDate != nil {
+			lines = lines.Where("expense_date <= ?", *filter.EndDate)
+		}
+		query = query.Where("id IN (?)", lines)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (filter.Page - 1) * filter.PageSize
+	if err := query.Order("id DESC").Limit(filter.PageSize).Offset(offset).Find(&claims).Error; err != nil {
+		return nil, 0, err
+	}
+	return claims, total, nil
+}
+
+// UpdateClaim saves an expense claim, replacing its lines, in a single
+// transaction
+func (r *ExpenseRepository) UpdateClaim(ctx context.Context, claim *entity.ExpenseClaim) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("claim_id = ?", claim.ID).Delete(&entity.ExpenseClaimLine{}).Error; err != nil {
+			return err
+		}
+		for i := range claim.Lines {
+			claim.Lines[i].ID = 0
+			claim.Lines[i].ClaimID = claim.ID
+		}
+		if len(claim.Lines) > 0 {
+			if err := tx.Omit("Category").Create(&claim.Lines).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Omit(clause.Associations).Save(claim).Error
+	})
+}
+
+// SaveClaim saves an expense claim without its lines
+func (r *ExpenseRepository) SaveClaim(ctx context.Context, claim *entity.ExpenseClaim) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Save(claim).Error
+}
+
+// ReviewClaim records the review of a submitted expense claim, with the
+// purchase invoice owing the employee its expenses when it is approved, in a
+// single transaction. It fails with ErrExpenseClaimReviewed when the claim
+// is no longer submitted.
+func (r *ExpenseRepository) ReviewClaim(ctx context.Context, claim *entity.ExpenseClaim, invoice *entity.FinanceInvoice) error {
+	if invoice != nil && invoice.InvoiceNumber == "" {
+		number, err := r.sequenceGenerator.NextNumber(ctx, entity.DocumentFinancePurchaseInvoice, "")
+		if err != nil {
+			return err
+		}
+		invoice.InvoiceNumber = number
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var statuses []entity.ExpenseClaimStatus
+		if err := tx.Model(&entity.ExpenseClaim{}).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ?", claim.ID).
+			Pluck("status", &statuses).Error; err != nil {
+			return err
+		}
+		if len(statuses) == 0 || statuses[0] != entity.ExpenseClaimSubmitted {
+			return ErrExpenseClaimReviewed
+		}
+
+		if invoice != nil {
+			now := time.Now()
+			invoice.CreatedAt = now
+			invoice.UpdatedAt = now
+			invoice.AmountDue = invoice.Total - invoice.AmountPaid
+			if err := tx.Create(invoice).Error; err != nil {
+				return err
+			}
+			claim.FinanceInvoiceID = &invoice.ID
+		}
+		return tx.Omit(clause.Associations).Save(claim).Error
+	})
+}
+
+// EmployeeName returns the username of the employee of an expense claim
+func (r *ExpenseRepository) EmployeeName(ctx context.Context, userID uint) (string, error) {
+	var names []string
+	if err := r.db.WithContext(ctx).Model(&entity.User{}).Where("id = ?", userID).Pluck("username", &names).Error; err != nil {
+		return "", err
+	}
+	if len(names) == 0 {
+		return "", ErrRecordNotFound
+	}
+	return names[0], nil
+}
+
+// ExpensesByCategory totals the base amounts of the lines of approved and
+// reimbursed expense claims dated between two dates, by category
+func (r *ExpenseRepository) ExpensesByCategory(ctx context.Context, startDate, endDate time.Time) ([]entity.ExpenseCategoryTotal, error) {
+	var totals []entity.ExpenseCategoryTotal
+	if err := r.db.WithContext(ctx).
+		Table("expense_claim_lines l").
+		Select("c.id AS category_id, c.code, c.name, COUNT(*) AS lines, COALESCE(SUM(l.base_amount), 0) AS amount").
+		Joins("JOIN expense_claims ec ON ec.id = l.claim_id").
+		Joins("JOIN expense_categories c ON c.id = l.category_id").
+		Where("ec.status IN ?", []entity.ExpenseClaimStatus{entity.ExpenseClaimApproved, entity.ExpenseClaimReimbursed}).
+		Where("l.expense_date BETWEEN ? AND ?", startDate, endDate).
+		Group("c.id, c.code, c.name").
+		Order("amount DESC").
+		Scan(&totals).Error; err != nil {
+		return nil, err
+	}
+	return totals, nil
+}
@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"gorm.io/gorm"
+)
+
+type FieldChangeRepository struct {
+	db *gorm.DB
+}
+
+func NewFieldChangeRepository(db *gorm.DB) *FieldChangeRepository {
+	return &FieldChangeRepository{db: db}
+}
+
+// ListByEntity retrieves the field changes of an entity, latest first,
+// optionally only those of one field
+func (r *FieldChangeRepository) ListByEntity(ctx context.Context, entityType, entityID, field string) ([]entity.FieldChange, error) {
+	query := r.db.WithContext(ctx).
+		Preload("User").
+		Where("entity_type = ? AND entity_id = ?", entityType, entityID)
+	if field != "" {
+		query = query.Where("field = ?", field)
+	}
+
+	var changes []entity.FieldChange
+	if err := query.Order("changed_at DESC, id DESC").Find(&changes).Error; err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"strconv"
 	"time"
 
 	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
@@ -10,25 +11,33 @@ import (
 
 // FinanceRepository handles database operations for finance invoices and payments
 type FinanceRepository struct {
-	db *gorm.DB
+	db                *gorm.DB
+	prepaymentRepo    *PrepaymentRepository
+	sequenceGenerator *SequenceGenerator
 }
 
 // NewFinanceRepository creates a new finance repository
-func NewFinanceRepository(db *gorm.DB) *FinanceRepository {
-	return &FinanceRepository{db: db}
+func NewFinanceRepository(db *gorm.DB, prepaymentRepo *PrepaymentRepository) *FinanceRepository {
+	return &FinanceRepository{
+		db:                db,
+		prepaymentRepo:    prepaymentRepo,
+		sequenceGenerator: NewSequenceGenerator(db),
+	}
 }
 
 // CreateInvoice creates a new finance invoice
 func (r *FinanceRepository) CreateInvoice(ctx context.Context, invoice *entity.FinanceInvoice) error {
 	// Generate invoice number if not provided
 	if invoice.InvoiceNumber == "" {
-		prefix := "INV"
-		if invoice.Type == entity.FinanceSalesInvoice {
-			prefix = "SINV"
-		} else if invoice.Type == entity.FinancePurchaseInvoice {
-			prefix = "PINV"
+		documentType := entity.DocumentFinanceSalesInvoice
+		if invoice.Type == entity.FinancePurchaseInvoice {
+			documentType = entity.DocumentFinancePurchaseInvoice
+		}
+		number, err := r.sequenceGenerator.NextNumber(ctx, documentType, "")
+		if err != nil {
+			return err
 		}
-		invoice.InvoiceNumber = prefix + "-" + time.Now().Format("20060102-150405")
+		invoice.InvoiceNumber = number
 	}
 
 	// Set default status if not provided
@@ -44,7 +53,15 @@ func (r *FinanceRepository) CreateInvoice(ctx context.Context, invoice *entity.F
 	// Calculate amount due
 	invoice.AmountDue = invoice.Total - invoice.AmountPaid
 
-	return r.db.WithContext(ctx).Create(invoice).Error
+	// Settle it with the unapplied deposits or advances of the order it
+	// references, or not at all
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(invoice).Error; err != nil {
+			return err
+		}
+		_, err := r.prepaymentRepo.ApplyToFinanceInvoiceTx(tx, invoice)
+		return err
+	})
 }
 
 // GetInvoiceByID retrieves a finance invoice by ID
@@ -107,6 +124,26 @@ func (r *FinanceRepository) UpdateInvoiceStatus(ctx context.Context, id int64, s
 	return nil
 }
 
+// CancelInvoice cancels a finance invoice and returns the prepayments applied
+// to it to their unapplied balances, in one transaction
+func (r *FinanceRepository) CancelInvoice(ctx context.Context, id int64) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&entity.FinanceInvoice{}).
+			Where("id = ?", id).
+			Updates(map[string]interface{}{
+				"status":     entity.FinanceInvoiceCancelled,
+				"updated_at": time.Now(),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrRecordNotFound
+		}
+		return r.prepaymentRepo.ReleaseFinanceInvoiceTx(tx, id)
+	})
+}
+
 // UpdateInvoicePayment updates the payment information of a finance invoice
 func (r *FinanceRepository) UpdateInvoicePayment(ctx context.Context, id int64, amountPaid float64) error {
 	// First get the invoice to calculate the new status
@@ -149,9 +186,56 @@ func (r *FinanceRepository) ListInvoices(ctx context.Context, filter *entity.Fin
 	var invoices []entity.FinanceInvoice
 	var total int64
 
+	query := r.invoiceQuery(ctx, filter)
+
+	// Get total count
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// Apply pagination
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+	if filter.PageSize <= 0 {
+		filter.PageSize = 10
+	}
+	offset := (filter.Page - 1) * filter.PageSize
+
+	// Get invoices
+	if err := query.Order("created_at DESC").Limit(filter.PageSize).Offset(offset).Find(&invoices).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return invoices, total, nil
+}
+
+// ListInvoicesPage lists the page of finance invoices matching a filter that
+// follows the page's cursor, latest first, with the cursor of the next page
+// when there is one
+func (r *FinanceRepository) ListInvoicesPage(ctx context.Context, filter *entity.FinanceInvoiceFilter, page entity.CursorPage) ([]entity.FinanceInvoice, *entity.Cursor, error) {
+	query, err := cursorQuery(r.invoiceQuery(ctx, filter), page, parseIntID)
+	if err != nil {
+		return nil, nil, err
+	}
+	var invoices []entity.FinanceInvoice
+	if err := query.Find(&invoices).Error; err != nil {
+		return nil, nil, err
+	}
+	invoices, next := cursorRows(invoices, page, func(i *entity.FinanceInvoice) entity.Cursor {
+		return entity.Cursor{CreatedAt: i.CreatedAt, ID: strconv.FormatInt(i.ID, 10)}
+	})
+	return invoices, next, nil
+}
+
+// invoiceQuery selects the finance invoices matching a filter
+func (r *FinanceRepository) invoiceQuery(ctx context.Context, filter *entity.FinanceInvoiceFilter) *gorm.DB {
 	query := r.db.WithContext(ctx).Model(&entity.FinanceInvoice{})
 
 	// Apply filters
+	if filter.Archived {
+		query = query.Table(archiveTable("finance_invoices"))
+	}
 	if filter.InvoiceNumber != "" {
 		query = query.Where("invoice_number LIKE ?", "%"+filter.InvoiceNumber+"%")
 	}
@@ -176,34 +260,18 @@ func (r *FinanceRepository) ListInvoices(ctx context.Context, filter *entity.Fin
 	if filter.EndDate != nil {
 		query = query.Where("issue_date <= ?", filter.EndDate)
 	}
-
-	// Get total count
-	if err := query.Count(&total).Error; err != nil {
-		return nil, 0, err
-	}
-
-	// Apply pagination
-	if filter.Page <= 0 {
-		filter.Page = 1
-	}
-	if filter.PageSize <= 0 {
-		filter.PageSize = 10
-	}
-	offset := (filter.Page - 1) * filter.PageSize
-
-	// Get invoices
-	if err := query.Order("created_at DESC").Limit(filter.PageSize).Offset(offset).Find(&invoices).Error; err != nil {
-		return nil, 0, err
-	}
-
-	return invoices, total, nil
+	return query
 }
 
 // CreatePayment creates a new finance payment
 func (r *FinanceRepository) CreatePayment(ctx context.Context, payment *entity.FinancePayment) error {
 	// Generate payment number if not provided
 	if payment.PaymentNumber == "" {
-		payment.PaymentNumber = "PAY-" + time.Now().Format("20060102-150405")
+		number, err := r.sequenceGenerator.NextNumber(ctx, entity.DocumentFinancePayment, "")
+		if err != nil {
+			return err
+		}
+		payment.PaymentNumber = number
 	}
 
 	// Set default status if not provided
@@ -251,6 +319,21 @@ func (r *FinanceRepository) GetPaymentByID(ctx context.Context, id int64) (*enti
 	return &payment, nil
 }
 
+// GetPaymentByReference retrieves the latest finance payment with the given reference number
+func (r *FinanceRepository) GetPaymentByReference(ctx context.Context, reference string) (*entity.FinancePayment, error) {
+	var payment entity.FinancePayment
+	if err := r.db.WithContext(ctx).
+		Where("reference_number = ?", reference).
+		Order("id DESC").
+		First(&payment).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &payment, nil
+}
+
 // UpdatePayment updates a finance payment
 func (r *FinanceRepository) UpdatePayment(ctx context.Context, payment *entity.FinancePayment) error {
 	// First get the current payment to check status change
@@ -428,7 +511,7 @@ func (r *FinanceRepository) GetAccountsReceivable(ctx context.Context, startDate
 			amount_paid,
 			amount_due,
 			CASE
-				WHEN due_date < NOW() AND amount_due > 0 THEN EXTRACT(DAY FROM NOW() - due_date)::int
+				WHEN due_date < ? AND amount_due > 0 THEN ` + dialectFor(r.db).DaysBetween("?", "due_date") + `
 				ELSE 0
 			END as days_overdue,
 			status,
@@ -441,7 +524,8 @@ func (r *FinanceRepository) GetAccountsReceivable(ctx context.Context, startDate
 		WHERE type = 'SALES'
 	`
 
-	args := []interface{}{}
+	now := time.Now()
+	args := []interface{}{now, now}
 	if startDate != nil {
 		query += " AND issue_date >= ?"
 		args = append(args, startDate)
@@ -476,7 +560,7 @@ func (r *FinanceRepository) GetAccountsPayable(ctx context.Context, startDate, e
 			amount_paid,
 			amount_due,
 			CASE
-				WHEN due_date < NOW() AND amount_due > 0 THEN EXTRACT(DAY FROM NOW() - due_date)::int
+				WHEN due_date < ? AND amount_due > 0 THEN ` + dialectFor(r.db).DaysBetween("?", "due_date") + `
 				ELSE 0
 			END as days_overdue,
 			status,
@@ -489,7 +573,8 @@ func (r *FinanceRepository) GetAccountsPayable(ctx context.Context, startDate, e
 		WHERE type = 'PURCHASE'
 	`
 
-	args := []interface{}{}
+	now := time.Now()
+	args := []interface{}{now, now}
 	if startDate != nil {
 		query += " AND issue_date >= ?"
 		args = append(args, startDate)
@@ -517,16 +602,16 @@ func (r *FinanceRepository) GetFinanceReport(ctx context.Context, startDate, end
 	query := `
 		WITH sales_data AS (
 			SELECT
-				COALESCE(SUM(total), 0) as total_revenue,
-				COALESCE(SUM(tax_total), 0) as sales_tax
+				COALESCE(SUM(base_total), 0) as total_revenue,
+				COALESCE(SUM(tax_total * exchange_rate), 0) as sales_tax
 			FROM finance_invoices
 			WHERE type = 'SALES'
 			AND issue_date BETWEEN ? AND ?
 		),
 		purchase_data AS (
 			SELECT
-				COALESCE(SUM(total), 0) as total_cost,
-				COALESCE(SUM(tax_total), 0) as purchase_tax
+				COALESCE(SUM(base_total), 0) as total_cost,
+				COALESCE(SUM(tax_total * exchange_rate), 0) as purchase_tax
 			FROM finance_invoices
 			WHERE type = 'PURCHASE'
 			AND issue_date BETWEEN ? AND ?
@@ -546,3 +631,36 @@ func (r *FinanceRepository) GetFinanceReport(ctx context.Context, startDate, end
 
 	return &report, nil
 }
+
+// GetRealizedFXGain sums the exchange gains of completed payments in the period.
+// A payment settled at a higher rate than its invoice is a gain on sales invoices
+// and a loss on purchase invoices.
+func (r *FinanceRepository) GetRealizedFXGain(ctx context.Context, startDate, endDate time.Time) (float64, error) {
+	var gain float64
+	err := r.db.WithContext(ctx).Raw(`
+		SELECT COALESCE(SUM(
+			CASE WHEN i.type = 'SALES' THEN 1 ELSE -1 END * p.amount * (p.exchange_rate - i.exchange_rate)
+		), 0)
+		FROM finance_payments p
+		JOIN finance_invoices i ON i.id = p.invoice_id
+		WHERE p.status = ?
+		AND p.payment_date BETWEEN ? AND ?
+		AND p.currency_code = i.currency_code
+	`, entity.FinancePaymentCompleted, startDate, endDate).Scan(&gain).Error
+	return gain, err
+}
+
+// ListOpenForeignInvoices retrieves invoices issued by the given date in a currency other
+// than the base currency that still have an amount due
+func (r *FinanceRepository) ListOpenForeignInvoices(ctx context.Context, baseCurrency string, asOf time.Time) ([]entity.FinanceInvoice, error) {
+	var invoices []entity.FinanceInvoice
+	err := r.db.WithContext(ctx).
+		Where("currency_code <> ? AND issue_date <= ? AND amount_due > 0", baseCurrency, asOf).
+		Where("status NOT IN ?", []entity.FinanceInvoiceStatus{
+			entity.FinanceInvoiceDraft,
+			entity.FinanceInvoiceCancelled,
+			entity.FinanceInvoicePaid,
+		}).
+		Find(&invoices).Error
+	return invoices, err
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"gorm.io/gorm"
+)
+
+// FiscalRepository handles database operations for fiscal years and their periods
+type FiscalRepository struct {
+	db *gorm.DB
+}
+
+// NewFiscalRepository creates a new fiscal repository
+func NewFiscalRepository(db *gorm.DB) *FiscalRepository {
+	return &FiscalRepository{db: db}
+}
+
+// CreateYear creates a fiscal year with its periods. Fiscal years do not
+// overlap and their names are unique.
+func (r *FiscalRepository) CreateYear(ctx context.Context, year *entity.FiscalYear) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&entity.FiscalYear{}).
+			Where("(start_date <= ? AND end_date >= ?) OR name = ?", year.EndDate, year.StartDate, year.Name).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrDuplicateEntry
+		}
+		return tx.Create(year).Error
+	})
+}
+
+// GetYear retrieves a fiscal year with its periods
+func (r *FiscalRepository) GetYear(ctx context.Context, id uint) (*entity.FiscalYear, error) {
+	var year entity.FiscalYear
+	if err := r.withPeriods(ctx).First(&year, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &year, nil
+}
+
+// ListYears retrieves the fiscal years with their periods, the latest first
+func (r *FiscalRepository) ListYears(ctx context.Context) ([]entity.FiscalYear, error) {
+	var years []entity.FiscalYear
+	if err := r.withPeriods(ctx).Order("start_date DESC").Find(&years).Error; err != nil {
+		return nil, err
+	}
+	return years, nil
+}
+
+// withPeriods returns a query that loads fiscal years with their periods in order
+func (r *FiscalRepository) withPeriods(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx).Preload("Periods", func(db *gorm.DB) *gorm.DB {
+		return db.Order("number")
+	})
+}
+
+// GetPeriod retrieves a fiscal period
+func (r *FiscalRepository) GetPeriod(ctx context.Context, id uint) (*entity.FiscalPeriod, error) {
+	var period entity.FiscalPeriod
+	if err := r.db.WithContext(ctx).First(&period, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &period, nil
+}
+
+// UpdatePeriod saves a fiscal period
+func (r *FiscalRepository) UpdatePeriod(ctx context.Context, period *entity.FiscalPeriod) error {
+	return r.db.WithContext(ctx).Save(period).Error
+}
+
+// PeriodAt retrieves the fiscal period a date falls in, or nil when no
+// fiscal year covers it
+func (r *FiscalRepository) PeriodAt(ctx context.Context, date time.Time) (*entity.FiscalPeriod, error) {
+	var period entity.FiscalPeriod
+	day := date.Format("2006-01-02")
+	err := r.db.WithContext(ctx).Where("start_date <= ? AND end_date >= ?", day, day).First(&period).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &period, nil
+}
+
+// PeriodsBetween retrieves the fiscal periods overlapping the dates from and
+// to, in order
+func (r *FiscalRepository) PeriodsBetween(ctx context.Context, from, to time.Time) ([]entity.FiscalPeriod, error) {
+	var periods []entity.FiscalPeriod
+	if err := r.db.WithContext(ctx).
+		Where("start_date <= ? AND end_date >= ?", to.Format("2006-01-02"), from.Format("2006-01-02")).
+		Order("start_date").
+		Find(&periods).Error; err != nil {
+		return nil, err
+	}
+	return periods, nil
+}
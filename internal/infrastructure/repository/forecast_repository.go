@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"gorm.io/gorm"
+)
+
+// ForecastRepository handles database operations for sales forecasts
+type ForecastRepository struct {
+	db *gorm.DB
+}
+
+// NewForecastRepository creates a new forecast repository
+func NewForecastRepository(db *gorm.DB) *ForecastRepository {
+	return &ForecastRepository{db: db}
+}
+
+// ListSalesHistory retrieves the sales orders placed from the given date until
+// before the other, archived orders included. Draft and cancelled orders are left out.
+func (r *ForecastRepository) ListSalesHistory(ctx context.Context, from, to time.Time) ([]entity.SalesOrder, error) {
+	var orders []entity.SalesOrder
+	for _, table := range []string{"sales_orders", archiveTable("sales_orders")} {
+		var batch []entity.SalesOrder
+		if err := r.db.WithContext(ctx).
+			Table(table).
+			Select("id, client_id, order_date, items, exchange_rate, base_grand_total").
+			Where("order_date >= ? AND order_date < ?", from, to).
+			Where("status NOT IN ?", []entity.SalesOrderStatus{entity.SalesOrderStatusDraft, entity.SalesOrderStatusCancelled}).
+			Find(&batch).Error; err != nil {
+			return nil, err
+		}
+		orders = append(orders, batch...)
+	}
+	return orders, nil
+}
+
+// GetSKUCategories returns the category of every SKU
+func (r *ForecastRepository) GetSKUCategories(ctx context.Context) (map[string]string, error) {
+	var skus []entity.SKU
+	if err := r.db.WithContext(ctx).Select("id, category").Find(&skus).Error; err != nil {
+		return nil, err
+	}
+
+	categories := make(map[string]string, len(skus))
+	for _, sku := range skus {
+		categories[sku.ID] = sku.Category
+	}
+	return categories, nil
+}
+
+// GetClientNames returns the name of each of the given clients
+func (r *ForecastRepository) GetClientNames(ctx context.Context, clientIDs []uint) (map[uint]string, error) {
+	names := make(map[uint]string, len(clientIDs))
+	if len(clientIDs) == 0 {
+		return names, nil
+	}
+
+	var clients []entity.Client
+	if err := r.db.WithContext(ctx).Select("id, name").Where("id IN ?", clientIDs).Find(&clients).Error; err != nil {
+		return nil, err
+	}
+	for _, client := range clients {
+		names[client.ID] = client.Name
+	}
+	return names, nil
+}
+
+// ReplaceSnapshot replaces the forecasts of a dimension taken in the given month
+func (r *ForecastRepository) ReplaceSnapshot(ctx context.Context, dimension entity.ForecastDimension, generatedFor string, forecasts []entity.SalesForecast) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("dimension = ? AND generated_for = ?", dimension, generatedFor).
+			Delete(&entity.SalesForecast{}).Error; err != nil {
+			return err
+		}
+		if len(forecasts) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(&forecasts, createBatchSize(tx)).Error
+	})
+}
+
+// HasSnapshot reports whether forecasts of a dimension were stored in the given month
+func (r *ForecastRepository) HasSnapshot(ctx context.Context, dimension entity.ForecastDimension, generatedFor string) (bool, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).
+		Model(&entity.SalesForecast{}).
+		Where("dimension = ? AND generated_for = ?", dimension, generatedFor).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// ListSnapshots retrieves the stored forecasts of a dimension for the months in a
+// range that were taken no later than the month they forecast
+func (r *ForecastRepository) ListSnapshots(ctx context.Context, dimension entity.ForecastDimension, from, to string) ([]entity.SalesForecast, error) {
+	var forecasts []entity.SalesForecast
+	if err := r.db.WithContext(ctx).
+		Where("dimension = ? AND period >= ? AND period <= ? AND generated_for <= period", dimension, from, to).
+		Order("period, key, generated_at").
+		Find(&forecasts).Error; err != nil {
+		return nil, err
+	}
+	return forecasts, nil
+}
@@ -0,0 +1,222 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"gorm.io/gorm"
+)
+
+// openSalesOrderStatuses are the statuses of sales orders that may have lines
+// left to ship. Orders are marked shipped or delivered by their first delivery.
+var openSalesOrderStatuses = []entity.SalesOrderStatus{
+	entity.SalesOrderStatusConfirmed,
+	entity.SalesOrderStatusProcessing,
+	entity.SalesOrderStatusShipped,
+	entity.SalesOrderStatusDelivered,
+}
+
+// shippedDeliveryStatuses are the statuses of deliveries whose stock has left
+var shippedDeliveryStatuses = []entity.DeliveryOrderStatus{
+	entity.DeliveryOrderStatusInTransit,
+	entity.DeliveryOrderStatusDelivered,
+	entity.DeliveryOrderStatusReturned,
+}
+
+// FulfillmentRepository reads the sales orders and deliveries behind the open
+// order book and the fulfillment SLA report, and stores SLA breach alerts
+type FulfillmentRepository struct {
+	db *gorm.DB
+}
+
+// NewFulfillmentRepository creates a new fulfillment repository
+func NewFulfillmentRepository(db *gorm.DB) *FulfillmentRepository {
+	return &FulfillmentRepository{db: db}
+}
+
+// ListOpenOrders retrieves the confirmed sales orders not yet completed or
+// cancelled, optionally of one client
+func (r *FulfillmentRepository) ListOpenOrders(ctx context.Context, clientID uint) ([]entity.FulfillmentSalesOrder, error) {
+	var orders []entity.FulfillmentSalesOrder
+	query := r.db.WithContext(ctx).
+		Model(&entity.SalesOrder{}).
+		Select("id, order_number, client_id, order_date, promised_date, status, items, exchange_rate").
+		Where("status IN ?", openSalesOrderStatuses)
+	if clientID != 0 {
+		query = query.Where("client_id = ?", clientID)
+	}
+	if err := query.Order("order_date, id").Scan(&orders).Error; err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// ListCommittedOrders retrieves the sales orders, archived orders included,
+// promised between the given times, or placed between them less the default
+// ship days when they have no promised date. Draft and cancelled orders are
+// left out.
+func (r *FulfillmentRepository) ListCommittedOrders(ctx context.Context, from, to time.Time, shipDays int) ([]entity.FulfillmentSalesOrder, error) {
+	var orders []entity.FulfillmentSalesOrder
+	for _, table := range []string{"sales_orders", archiveTable("sales_orders")} {
+		var batch []entity.FulfillmentSalesOrder
+		if err := r.db.WithContext(ctx).
+			Table(table).
+			Select("id, order_number, client_id, order_date, promised_date, status, items, exchange_rate").
+			Where("(promised_date >= ? AND promised_date <= ?) OR (promised_date IS NULL AND order_date >= ? AND order_date <= ?)",
+				from, to, from.AddDate(0, 0, -shipDays), to.AddDate(0, 0, -shipDays)).
+			Where("status NOT IN ?", []entity.SalesOrderStatus{entity.SalesOrderStatusDraft, entity.SalesOrderStatusCancelled}).
+			Order("order_date, id").
+			Scan(&batch).Error; err != nil {
+			return nil, err
+		}
+		orders = append(orders, batch...)
+	}
+	return orders, nil
+}
+
+// GetOrders retrieves the given sales orders, archived orders included
+func (r *FulfillmentRepository) GetOrders(ctx context.Context, orderIDs []string) ([]entity.FulfillmentSalesOrder, error) {
+	var orders []entity.FulfillmentSalesOrder
+	if len(orderIDs) == 0 {
+		return orders, nil
+	}
+	for _, table := range []string{"sales_orders", archiveTable("sales_orders")} {
+		var batch []entity.FulfillmentSalesOrder
+		if err := r.db.WithContext(ctx).
+			Table(table).
+			Select("id, order_number, client_id, order_date, promised_date, status, items, exchange_rate").
+			Where("id IN ?", orderIDs).
+			Scan(&batch).Error; err != nil {
+			return nil, err
+		}
+		orders = append(orders, batch...)
+	}
+	return orders, nil
+}
+
+// ListOrderDeliveries retrieves the deliveries of the given sales orders that
+// were not cancelled, archived deliveries included
+func (r *FulfillmentRepository) ListOrderDeliveries(ctx context.Context, orderIDs []string) ([]entity.FulfillmentDelivery, error) {
+	var deliveries []entity.FulfillmentDelivery
+	if len(orderIDs) == 0 {
+		return deliveries, nil
+	}
+	for _, table := range []string{"delivery_orders", archiveTable("delivery_orders")} {
+		var batch []entity.FulfillmentDelivery
+		if err := r.db.WithContext(ctx).
+			Table(table).
+			Select("id, sales_order_id, store_id, status, shipped_at, items").
+			Where("sales_order_id IN ? AND status <> ?", orderIDs, entity.DeliveryOrderStatusCancelled).
+			Order("delivery_date, id").
+			Scan(&batch).Error; err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, batch...)
+	}
+	return deliveries, nil
+}
+
+// ListShippedDeliveries retrieves the deliveries shipped between the given
+// times, archived deliveries included
+func (r *FulfillmentRepository) ListShippedDeliveries(ctx context.Context, from, to time.Time) ([]entity.FulfillmentDelivery, error) {
+	var deliveries []entity.FulfillmentDelivery
+	for _, table := range []string{"delivery_orders", archiveTable("delivery_orders")} {
+		var batch []entity.FulfillmentDelivery
+		if err := r.db.WithContext(ctx).
+			Table(table).
+			Select("id, sales_order_id, store_id, status, shipped_at, items").
+			Where("shipped_at >= ? AND shipped_at <= ?", from, to).
+			Where("status IN ?", shippedDeliveryStatuses).
+			Order("shipped_at, id").
+			Scan(&batch).Error; err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, batch...)
+	}
+	return deliveries, nil
+}
+
+// ListUnresolvedAlerts retrieves the fulfillment SLA alerts not yet resolved
+func (r *FulfillmentRepository) ListUnresolvedAlerts(ctx context.Context) ([]entity.FulfillmentSLAAlert, error) {
+	var alerts []entity.FulfillmentSLAAlert
+	if err := r.db.WithContext(ctx).Where("resolved_at IS NULL").Order("id").Find(&alerts).Error; err != nil {
+		return nil, err
+	}
+	return alerts, nil
+}
+
+// CreateAlerts stores fulfillment SLA alerts
+func (r *FulfillmentRepository) CreateAlerts(ctx context.Context, alerts []entity.FulfillmentSLAAlert) error {
+	if len(alerts) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Create(&alerts).Error
+}
+
+// ResolveAlerts marks fulfillment SLA alerts as resolved
+func (r *FulfillmentRepository) ResolveAlerts(ctx context.Context, ids []uint, at time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).
+		Model(&entity.FulfillmentSLAAlert{}).
+		Where("id IN ? AND resolved_at IS NULL", ids).
+		Update("resolved_at", at).
+		Error
+}
+
+// ListAlerts retrieves fulfillment SLA alerts with filters and pagination, latest first
+func (r *FulfillmentRepository) ListAlerts(ctx context.Context, filter *entity.FulfillmentSLAAlertFilter) ([]entity.FulfillmentSLAAlert, int64, error) {
+	var alerts []entity.FulfillmentSLAAlert
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&entity.FulfillmentSLAAlert{})
+	if filter.StoreID != "" {
+		query = query.Where("store_id = ?", filter.StoreID)
+	}
+	if filter.Metric != "" {
+		query = query.Where("metric = ?", filter.Metric)
+	}
+	if filter.Resolved != nil {
+		if *filter.Resolved {
+			query = query.Where("resolved_at IS NOT NULL")
+		} else {
+			query = query.Where("resolved_at IS NULL")
+		}
+	}
+	if filter.Acknowledged != nil {
+		if *filter.Acknowledged {
+			query = query.Where("acknowledged_at IS NOT NULL")
+		} else {
+			query = query.Where("acknowledged_at IS NULL")
+		}
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (filter.Page - 1) * filter.PageSize
+	if err := query.Order("created_at DESC, id DESC").Limit(filter.PageSize).Offset(offset).Find(&alerts).Error; err != nil {
+		return nil, 0, err
+	}
+	return alerts, total, nil
+}
+
+// GetAlert retrieves a fulfillment SLA alert by ID
+func (r *FulfillmentRepository) GetAlert(ctx context.Context, id uint) (*entity.FulfillmentSLAAlert, error) {
+	var alert entity.FulfillmentSLAAlert
+	if err := r.db.WithContext(ctx).First(&alert, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &alert, nil
+}
+
+// UpdateAlert saves a fulfillment SLA alert
+func (r *FulfillmentRepository) UpdateAlert(ctx context.Context, alert *entity.FulfillmentSLAAlert) error {
+	return r.db.WithContext(ctx).Save(alert).Error
+}
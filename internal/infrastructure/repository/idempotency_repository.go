@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IdempotencyRepository struct {
+	db *gorm.DB
+}
+
+func NewIdempotencyRepository(db *gorm.DB) *IdempotencyRepository {
+	return &IdempotencyRepository{db: db}
+}
+
+// Create inserts a key unless the user already used it, reporting whether it was inserted
+func (r *IdempotencyRepository) Create(ctx context.Context, key *entity.IdempotencyKey) (bool, error) {
+	result := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(key)
+	return result.RowsAffected == 1, result.Error
+}
+
+// Get retrieves a user's key
+func (r *IdempotencyRepository) Get(ctx context.Context, userID uint, key string) (*entity.IdempotencyKey, error) {
+	var record entity.IdempotencyKey
+	if err := r.db.WithContext(ctx).Where("user_id = ? AND key = ?", userID, key).First(&record).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &record, nil
+}
+
+// Complete stores the response of a key's request
+func (r *IdempotencyRepository) Complete(ctx context.Context, key *entity.IdempotencyKey) error {
+	return r.db.WithContext(ctx).Model(&entity.IdempotencyKey{}).
+		Where("user_id = ? AND key = ?", key.UserID, key.Key).
+		Updates(map[string]interface{}{
+			"status_code":  key.StatusCode,
+			"content_type": key.ContentType,
+			"response":     key.Response,
+			"completed_at": key.CompletedAt,
+		}).Error
+}
+
+// Delete removes a key, unless it was used again since it was read
+func (r *IdempotencyRepository) Delete(ctx context.Context, key *entity.IdempotencyKey) error {
+	return r.db.WithContext(ctx).
+		Where("user_id = ? AND key = ? AND created_at = ?", key.UserID, key.Key, key.CreatedAt).
+		Delete(&entity.IdempotencyKey{}).Error
+}
+
+// DeleteInProgress removes a key whose request has not completed
+func (r *IdempotencyRepository) DeleteInProgress(ctx context.Context, userID uint, key string) error {
+	return r.db.WithContext(ctx).
+		Where("user_id = ? AND key = ? AND completed_at IS NULL", userID, key).
+		Delete(&entity.IdempotencyKey{}).Error
+}
+
+// DeleteCreatedBefore removes the keys created before the given time and returns how many there were
+func (r *IdempotencyRepository) DeleteCreatedBefore(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Where("created_at < ?", before).Delete(&entity.IdempotencyKey{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"gorm.io/gorm"
+)
+
+type ImpersonationRepository struct {
+	db *gorm.DB
+}
+
+func NewImpersonationRepository(db *gorm.DB) *ImpersonationRepository {
+	return &ImpersonationRepository{db: db}
+}
+
+// CreateSession creates an impersonation session
+func (r *ImpersonationRepository) CreateSession(ctx context.Context, session *entity.ImpersonationSession) error {
+	return r.db.WithContext(ctx).Omit("Impersonator", "User").Create(session).Error
+}
+
+// GetSession retrieves an impersonation session by ID with its impersonator
+// and user
+func (r *ImpersonationRepository) GetSession(ctx context.Context, id uint) (*entity.ImpersonationSession, error) {
+	var session entity.ImpersonationSession
+	if err := r.db.WithContext(ctx).Preload("Impersonator").Preload("User").First(&session, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+// UpdateSession saves an impersonation session
+func (r *ImpersonationRepository) UpdateSession(ctx context.Context, session *entity.ImpersonationSession) error {
+	return r.db.WithContext(ctx).Omit("Impersonator", "User").Save(session).Error
+}
+
+// ListSessions retrieves the impersonation sessions matching a filter, latest first
+func (r *ImpersonationRepository) ListSessions(ctx context.Context, filter *entity.ImpersonationFilter, at time.Time) ([]entity.ImpersonationSession, int64, error) {
+	var sessions []entity.ImpersonationSession
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&entity.ImpersonationSession{})
+	if filter.ImpersonatorID != 0 {
+		query = query.Where("impersonator_id = ?", filter.ImpersonatorID)
+	}
+	if filter.UserID != 0 {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.Active != nil {
+		if *filter.Active {
+			query = query.Where("ended_at IS NULL AND expires_at > ?", at)
+		} else {
+			query = query.Where("ended_at IS NOT NULL OR expires_at <= ?", at)
+		}
+	}
+	if filter.StartDate != nil {
+		query = query.Where("started_at >= ?", filter.StartDate)
+	}
+	if filter.EndDate != nil {
+		query = query.Where("started_at <= ?", filter.EndDate)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (filter.Page - 1) * filter.PageSize
+	if err := query.
+		Preload("Impersonator").
+		Preload("User").
+		Order("started_at DESC, id DESC").
+		Limit(filter.PageSize).
+		Offset(offset).
+		Find(&sessions).Error; err != nil {
+		return nil, 0, err
+	}
+	return sessions, total, nil
+}
@@ -0,0 +1,194 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrDockSlotTaken = entity.NewError(entity.ErrCodeConflict, "the dock door is already booked at that time")
+
+// inboundOrderStatuses are the statuses of purchase orders whose goods are
+// on their way
+var inboundOrderStatuses = []entity.PurchaseOrderStatus{
+	entity.PurchaseOrderStatusSent,
+	entity.PurchaseOrderStatusConfirmed,
+	entity.PurchaseOrderStatusPartial,
+}
+
+// InboundRepository handles database operations for dock doors, dock
+// appointments and the deliveries expected at the warehouses
+type InboundRepository struct {
+	db *gorm.DB
+}
+
+func NewInboundRepository(db *gorm.DB) *InboundRepository {
+	return &InboundRepository{db: db}
+}
+
+// CreateDoor creates a dock door
+func (r *InboundRepository) CreateDoor(ctx context.Context, door *entity.DockDoor) error {
+	return r.db.WithContext(ctx).Create(door).Error
+}
+
+// UpdateDoor saves a dock door
+func (r *InboundRepository) UpdateDoor(ctx context.Context, door *entity.DockDoor) error {
+	return r.db.WithContext(ctx).Save(door).Error
+}
+
+// GetDoor retrieves a dock door by ID
+func (r *InboundRepository) GetDoor(ctx context.Context, id uint) (*entity.DockDoor, error) {
+	var door entity.DockDoor
+	if err := r.db.WithContext(ctx).First(&door, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &door, nil
+}
+
+// ListDoors retrieves the dock doors of the stores in the access scope, or of
+// one store
+func (r *InboundRepository) ListDoors(ctx context.Context, storeID string) ([]entity.DockDoor, error) {
+	var doors []entity.DockDoor
+	query := scopeStores(ctx, r.db.WithContext(ctx), "store_id")
+	if storeID != "" {
+		query = query.Where("store_id = ?", storeID)
+	}
+	if err := query.Order("store_id, name").Find(&doors).Error; err != nil {
+		return nil, err
+	}
+	return doors, nil
+}
+
+// CreateAppointment books a dock door slot. The door is locked while the
+// slot is checked against its other bookings, so two overlapping slots
+// cannot be booked at once; ErrDockSlotTaken is returned on overlap.
+func (r *InboundRepository) CreateAppointment(ctx context.Context, appointment *entity.DockAppointment) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var door entity.DockDoor
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&door, appointment.DoorID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return ErrRecordNotFound
+			}
+			return err
+		}
+
+		var overlapping int64
+		if err := tx.Model(&entity.DockAppointment{}).
+			Where("door_id = ? AND status <> ?", appointment.DoorID, entity.DockAppointmentCancelled).
+			Where("starts_at < ? AND ends_at > ?", appointment.EndsAt, appointment.StartsAt).
+			Count(&overlapping).Error; err != nil {
+			return err
+		}
+		if overlapping > 0 {
+			return ErrDockSlotTaken
+		}
+		return tx.Omit("Door").Create(appointment).Error
+	})
+}
+
+// UpdateAppointment saves a dock appointment
+func (r *InboundRepository) UpdateAppointment(ctx context.Context, appointment *entity.DockAppointment) error {
+	return r.db.WithContext(ctx).Omit("Door").Save(appointment).Error
+}
+
+// GetAppointment retrieves a dock appointment by ID with its door
+func (r *InboundRepository) GetAppointment(ctx context.Context, id uint) (*entity.DockAppointment, error) {
+	var appointment entity.DockAppointment
+	if err := r.db.WithContext(ctx).Preload("Door").First(&appointment, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &appointment, nil
+}
+
+// ListAppointments retrieves the dock appointments of the stores in the
+// access scope with filters, by start time
+func (r *InboundRepository) ListAppointments(ctx context.Context, filter *entity.DockAppointmentFilter) ([]entity.DockAppointment, error) {
+	var appointments []entity.DockAppointment
+	query := scopeStores(ctx, r.db.WithContext(ctx).Preload("Door"), "store_id")
+	if filter.StoreID != "" {
+		query = query.Where("store_id = ?", filter.StoreID)
+	}
+	if filter.DoorID != 0 {
+		query = query.Where("door_id = ?", filter.DoorID)
+	}
+	if filter.PurchaseOrderID != "" {
+		query = query.Where("purchase_order_id = ?", filter.PurchaseOrderID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.From != nil {
+		query = query.Where("ends_at > ?", filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("starts_at < ?", filter.To)
+	}
+	if err := query.Order("starts_at, door_id").Find(&appointments).Error; err != nil {
+		return nil, err
+	}
+	return appointments, nil
+}
+
+// ListOrderAppointments retrieves the dock appointments booked for purchase
+// orders and not cancelled, by start time
+func (r *InboundRepository) ListOrderAppointments(ctx context.Context, orderIDs []string) ([]entity.DockAppointment, error) {
+	var appointments []entity.DockAppointment
+	if err := r.db.WithContext(ctx).Preload("Door").
+		Where("purchase_order_id IN ? AND status <> ?", orderIDs, entity.DockAppointmentCancelled).
+		Order("starts_at, door_id").
+		Find(&appointments).Error; err != nil {
+		return nil, err
+	}
+	return appointments, nil
+}
+
+// ListOpenOrders retrieves the sent, confirmed and partially received
+// purchase orders with an expected date, with their vendor, by expected
+// date. When stores are restricted by the access scope, only the orders
+// delivered to them are listed.
+func (r *InboundRepository) ListOpenOrders(ctx context.Context, filter *entity.InboundOrderFilter) ([]entity.PurchaseOrder, error) {
+	var orders []entity.PurchaseOrder
+	query := r.db.WithContext(ctx).Preload("Vendor").
+		Where("status IN ? AND expected_date > ?", inboundOrderStatuses, time.Time{})
+	query = scopeStores(ctx, query, "store_id")
+	if filter.StoreID != "" {
+		query = query.Where("store_id = ? OR id IN (?)", filter.StoreID, r.db.Model(&entity.DockAppointment{}).
+			Select("purchase_order_id").
+			Where("store_id = ? AND status <> ?", filter.StoreID, entity.DockAppointmentCancelled))
+	}
+	if filter.IDs != nil {
+		query = query.Where("id IN ?", filter.IDs)
+	}
+	if filter.ExpectedFrom != nil {
+		query = query.Where("expected_date >= ?", filter.ExpectedFrom)
+	}
+	if filter.ExpectedBefore != nil {
+		query = query.Where("expected_date < ?", filter.ExpectedBefore)
+	}
+	if err := query.Order("expected_date, order_number").Find(&orders).Error; err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// ListPendingShipNotices retrieves the applied advance ship notices whose
+// goods were not received yet
+func (r *InboundRepository) ListPendingShipNotices(ctx context.Context) ([]entity.EDIDocument, error) {
+	var documents []entity.EDIDocument
+	if err := r.db.WithContext(ctx).
+		Where("type = ? AND status = ? AND receipt_id IS NULL AND purchase_order_id IS NOT NULL", entity.EDITypeShipNotice, entity.EDIDocumentApplied).
+		Order("id").
+		Find(&documents).Error; err != nil {
+		return nil, err
+	}
+	return documents, nil
+}
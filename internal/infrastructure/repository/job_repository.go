@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type JobRepository struct {
+	db *gorm.DB
+}
+
+func NewJobRepository(db *gorm.DB) *JobRepository {
+	return &JobRepository{db: db}
+}
+
+// Create queues a job
+func (r *JobRepository) Create(ctx context.Context, job *entity.Job) error {
+	return r.db.WithContext(ctx).Create(job).Error
+}
+
+// Get retrieves a job by ID
+func (r *JobRepository) Get(ctx context.Context, id uint64) (*entity.Job, error) {
+	var job entity.Job
+	if err := r.db.WithContext(ctx).First(&job, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+// List retrieves the jobs matching a filter, latest first
+func (r *JobRepository) List(ctx context.Context, filter *entity.JobFilter) ([]entity.Job, int64, error) {
+	var jobs []entity.Job
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&entity.Job{})
+	if filter.Type != "" {
+		query = query.Where("type = ?", filter.Type)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.CreatedBy != nil {
+		query = query.Where("created_by = ?", *filter.CreatedBy)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (filter.Page - 1) * filter.PageSize
+	if err := query.
+		Omit("payload", "result").
+		Order("created_at DESC, id DESC").
+		Limit(filter.PageSize).
+		Offset(offset).
+		Find(&jobs).Error; err != nil {
+		return nil, 0, err
+	}
+	return jobs, total, nil
+}
+
+// Claim takes the next due job of the given types for a worker, marking it
+// running. Jobs another worker is claiming are skipped. It returns nil when no
+// job is due.
+func (r *JobRepository) Claim(ctx context.Context, types []string, now time.Time) (*entity.Job, error) {
+	var job entity.Job
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND run_at <= ? AND type IN ?", entity.JobQueued, now, types).
+			Order("run_at, id").
+			Limit(1).
+			Find(&job)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+
+		job.Status = entity.JobRunning
+		job.Attempts++
+		job.Progress = 0
+		job.StartedAt = &now
+		return tx.Model(&job).Updates(map[string]interface{}{
+			"status":     job.Status,
+			"attempts":   job.Attempts,
+			"progress":   job.Progress,
+			"started_at": job.StartedAt,
+			"updated_at": now,
+		}).Error
+	})
+	if err != nil || job.ID == 0 {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// SetProgress records how much of a running job is done
+func (r *JobRepository) SetProgress(ctx context.Context, id uint64, progress int) error {
+	return r.db.WithContext(ctx).Model(&entity.Job{}).
+		Where("id = ? AND status = ?", id, entity.JobRunning).
+		Updates(map[string]interface{}{"progress": progress, "updated_at": time.Now()}).Error
+}
+
+// Finish records the outcome of a job's last attempt
+func (r *JobRepository) Finish(ctx context.Context, id uint64, status entity.JobStatus, result json.RawMessage, errMsg string, finishedAt time.Time) error {
+	updates := map[string]interface{}{
+		"status":      status,
+		"error":       errMsg,
+		"finished_at": finishedAt,
+		"updated_at":  finishedAt,
+	}
+	if status == entity.JobSucceeded {
+		updates["progress"] = 100
+		updates["result"] = result
+	}
+	return r.db.WithContext(ctx).Model(&entity.Job{}).Where("id = ?", id).Updates(updates).Error
+}
+
+// Requeue queues a running job again to run at the given time, keeping the
+// error of the attempt that failed
+func (r *JobRepository) Requeue(ctx context.Context, id uint64, runAt time.Time, errMsg string) error {
+	return r.db.WithContext(ctx).Model(&entity.Job{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":     entity.JobQueued,
+		"run_at":     runAt,
+		"error":      errMsg,
+		"updated_at": time.Now(),
+	}).Error
+}
+
+// Cancel cancels a queued job, reporting whether it was still queued
+func (r *JobRepository) Cancel(ctx context.Context, id uint64, now time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&entity.Job{}).
+		Where("id = ? AND status = ?", id, entity.JobQueued).
+		Updates(map[string]interface{}{
+			"status":      entity.JobCanceled,
+			"finished_at": now,
+			"updated_at":  now,
+		})
+	return result.RowsAffected == 1, result.Error
+}
+
+// RequeueStale queues the jobs running since before the given time again,
+// their worker having stopped without finishing them, and returns how many
+// there were
+func (r *JobRepository) RequeueStale(ctx context.Context, startedBefore time.Time) (int64, error) {
+	now := time.Now()
+	result := r.db.WithContext(ctx).Model(&entity.Job{}).
+		Where("status = ? AND started_at < ?", entity.JobRunning, startedBefore).
+		Updates(map[string]interface{}{
+			"status":     entity.JobQueued,
+			"run_at":     now,
+			"error":      "worker stopped before the job finished",
+			"updated_at": now,
+		})
+	return result.RowsAffected, result.Error
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
 
@@ -9,11 +10,12 @@ import (
 )
 
 type ManufacturingRepository struct {
-	db *gorm.DB
+	db         *gorm.DB
+	stocksRepo *StocksRepository
 }
 
-func NewManufacturingRepository(db *gorm.DB) *ManufacturingRepository {
-	return &ManufacturingRepository{db: db}
+func NewManufacturingRepository(db *gorm.DB, stocksRepo *StocksRepository) *ManufacturingRepository {
+	return &ManufacturingRepository{db: db, stocksRepo: stocksRepo}
 }
 
 // Facility methods
@@ -53,6 +55,9 @@ func (r *ManufacturingRepository) CreateProductionOrder(ctx context.Context, ord
 func (r *ManufacturingRepository) GetProductionOrder(ctx context.Context, id uint) (*entity.ProductionOrder, error) {
 	var order entity.ProductionOrder
 	if err := r.db.WithContext(ctx).First(&order, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRecordNotFound
+		}
 		return nil, err
 	}
 	return &order, nil
@@ -74,14 +79,133 @@ func (r *ManufacturingRepository) UpdateProductionOrder(ctx context.Context, ord
 	return r.db.WithContext(ctx).Save(order).Error
 }
 
+// PostProduction applies a production order's stock entries, records its material
+// issues and saves the order in a single transaction
+func (r *ManufacturingRepository) PostProduction(ctx context.Context, order *entity.ProductionOrder, entries []entity.StockEntry, issues []entity.ProductionMaterialIssue, userID string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := r.stocksRepo.ProcessStockEntriesTx(ctx, tx, entries, userID); err != nil {
+			return err
+		}
+		if len(issues) > 0 {
+			if err := tx.CreateInBatches(&issues, createBatchSize(tx)).Error; err != nil {
+				return err
+			}
+		}
+		if order != nil {
+			return tx.Save(order).Error
+		}
+		return nil
+	})
+}
+
+// ListMaterialIssues retrieves the components issued to a production order
+func (r *ManufacturingRepository) ListMaterialIssues(ctx context.Context, productionOrderID uint) ([]entity.ProductionMaterialIssue, error) {
+	var issues []entity.ProductionMaterialIssue
+	if err := r.db.WithContext(ctx).
+		Where("production_order_id = ?", productionOrderID).
+		Order("issued_at, id").
+		Find(&issues).Error; err != nil {
+		return nil, err
+	}
+	return issues, nil
+}
+
 // BOM methods
 func (r *ManufacturingRepository) CreateBOM(ctx context.Context, bom *entity.BillOfMaterial) error {
 	return r.db.WithContext(ctx).Create(bom).Error
 }
 
+// CreateBOMVersion creates a bill of materials version with its items. The open
+// version it follows ends where it takes effect, and it ends where the next
+// version already on file takes effect.
+func (r *ManufacturingRepository) CreateBOMVersion(ctx context.Context, bom *entity.BillOfMaterial, items []entity.BOMItem) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&entity.BillOfMaterial{}).
+			Where("product_id = ? AND version = ?", bom.ProductID, bom.Version).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrDuplicateEntry
+		}
+
+		var next entity.BillOfMaterial
+		err := tx.Where("product_id = ? AND effective_from > ?", bom.ProductID, bom.EffectiveFrom).
+			Order("effective_from").
+			First(&next).Error
+		switch {
+		case err == nil:
+			if bom.EffectiveTo == nil || bom.EffectiveTo.After(next.EffectiveFrom) {
+				bom.EffectiveTo = &next.EffectiveFrom
+			}
+		case err != gorm.ErrRecordNotFound:
+			return err
+		}
+
+		if err := tx.Model(&entity.BillOfMaterial{}).
+			Where("product_id = ? AND effective_from < ?", bom.ProductID, bom.EffectiveFrom).
+			Where("effective_to IS NULL OR effective_to > ?", bom.EffectiveFrom).
+			Update("effective_to", bom.EffectiveFrom).Error; err != nil {
+			return err
+		}
+
+		bom.Items = nil
+		if err := tx.Create(bom).Error; err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			return nil
+		}
+		for i := range items {
+			items[i].BOMID = bom.ID
+		}
+		if err := tx.CreateInBatches(&items, createBatchSize(tx)).Error; err != nil {
+			return err
+		}
+		bom.Items = items
+		return nil
+	})
+}
+
 func (r *ManufacturingRepository) GetBOM(ctx context.Context, id uint) (*entity.BillOfMaterial, error) {
 	var bom entity.BillOfMaterial
-	if err := r.db.WithContext(ctx).First(&bom, id).Error; err != nil {
+	if err := r.db.WithContext(ctx).Preload("Items").First(&bom, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &bom, nil
+}
+
+// ListBOMs retrieves the bill of materials versions, of one product if given,
+// latest effective first
+func (r *ManufacturingRepository) ListBOMs(ctx context.Context, productID *uint) ([]entity.BillOfMaterial, error) {
+	query := r.db.WithContext(ctx)
+	if productID != nil {
+		query = query.Where("product_id = ?", *productID)
+	}
+
+	var boms []entity.BillOfMaterial
+	if err := query.Order("product_id, effective_from DESC, id DESC").Find(&boms).Error; err != nil {
+		return nil, err
+	}
+	return boms, nil
+}
+
+// GetEffectiveBOM retrieves the bill of materials version of a product in effect
+// at the given time
+func (r *ManufacturingRepository) GetEffectiveBOM(ctx context.Context, productID uint, at time.Time) (*entity.BillOfMaterial, error) {
+	var bom entity.BillOfMaterial
+	if err := r.db.WithContext(ctx).
+		Where("product_id = ? AND effective_from <= ?", productID, at).
+		Where("effective_to IS NULL OR effective_to > ?", at).
+		Order("effective_from DESC, id DESC").
+		First(&bom).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRecordNotFound
+		}
 		return nil, err
 	}
 	return &bom, nil
@@ -91,6 +215,15 @@ func (r *ManufacturingRepository) AddBOMItem(ctx context.Context, item *entity.B
 	return r.db.WithContext(ctx).Create(item).Error
 }
 
+// AddBOMItems bulk inserts BOM items
+func (r *ManufacturingRepository) AddBOMItems(ctx context.Context, items []entity.BOMItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+	db := r.db.WithContext(ctx)
+	return db.CreateInBatches(&items, createBatchSize(db)).Error
+}
+
 func (r *ManufacturingRepository) GetBOMItems(ctx context.Context, bomID uint) ([]entity.BOMItem, error) {
 	var items []entity.BOMItem
 	if err := r.db.WithContext(ctx).Where("bom_id = ?", bomID).Find(&items).Error; err != nil {
@@ -111,3 +244,117 @@ func (r *ManufacturingRepository) GetMRPCalculations(ctx context.Context, produc
 	}
 	return calculations, nil
 }
+
+// Material cost methods
+
+// UpsertMaterialCost sets the standard unit cost of a component
+func (r *ManufacturingRepository) UpsertMaterialCost(ctx context.Context, cost *entity.MaterialCost) error {
+	return r.db.WithContext(ctx).Save(cost).Error
+}
+
+// ListMaterialCosts retrieves the standard costs of the given components, or of
+// all components when none are given
+func (r *ManufacturingRepository) ListMaterialCosts(ctx context.Context, materialIDs []uint) ([]entity.MaterialCost, error) {
+	query := r.db.WithContext(ctx)
+	if len(materialIDs) > 0 {
+		query = query.Where("material_id IN ?", materialIDs)
+	}
+
+	var costs []entity.MaterialCost
+	if err := query.Order("material_id").Find(&costs).Error; err != nil {
+		return nil, err
+	}
+	return costs, nil
+}
+
+// Work center methods
+
+// CreateWorkCenter creates a work center, refusing a duplicate code
+func (r *ManufacturingRepository) CreateWorkCenter(ctx context.Context, workCenter *entity.WorkCenter) error {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&entity.WorkCenter{}).
+		Where("code = ?", workCenter.Code).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrDuplicateEntry
+	}
+	return r.db.WithContext(ctx).Create(workCenter).Error
+}
+
+func (r *ManufacturingRepository) GetWorkCenter(ctx context.Context, id uint) (*entity.WorkCenter, error) {
+	var workCenter entity.WorkCenter
+	if err := r.db.WithContext(ctx).First(&workCenter, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &workCenter, nil
+}
+
+// ListWorkCenters retrieves the work centers, of one facility when given
+func (r *ManufacturingRepository) ListWorkCenters(ctx context.Context, facilityID uint) ([]entity.WorkCenter, error) {
+	query := r.db.WithContext(ctx)
+	if facilityID != 0 {
+		query = query.Where("facility_id = ?", facilityID)
+	}
+
+	var workCenters []entity.WorkCenter
+	if err := query.Order("code").Find(&workCenters).Error; err != nil {
+		return nil, err
+	}
+	return workCenters, nil
+}
+
+// UpdateWorkCenter saves a work center, refusing a code used by another one
+func (r *ManufacturingRepository) UpdateWorkCenter(ctx context.Context, workCenter *entity.WorkCenter) error {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&entity.WorkCenter{}).
+		Where("code = ? AND id <> ?", workCenter.Code, workCenter.ID).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrDuplicateEntry
+	}
+	return r.db.WithContext(ctx).Save(workCenter).Error
+}
+
+// ListScheduledOrders retrieves the open production orders scheduled on the given
+// work centers that run on any day from start to end, in start order
+func (r *ManufacturingRepository) ListScheduledOrders(ctx context.Context, workCenterIDs []uint, start, end time.Time) ([]entity.ProductionOrder, error) {
+	var orders []entity.ProductionOrder
+	if len(workCenterIDs) == 0 {
+		return orders, nil
+	}
+	if err := r.db.WithContext(ctx).
+		Where("work_center_id IN ?", workCenterIDs).
+		Where("status IN ?", []entity.ProductionOrderStatus{entity.OrderStatusPending, entity.OrderStatusInProcess}).
+		Where("scheduled_start <= ? AND scheduled_end >= ?", end, start).
+		Order("scheduled_start, id").
+		Find(&orders).Error; err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// LastScheduledEnd returns the latest day an open production order other than
+// the given one is scheduled to run on a work center, or nil when none is
+func (r *ManufacturingRepository) LastScheduledEnd(ctx context.Context, workCenterID, excludeOrderID uint) (*time.Time, error) {
+	var orders []entity.ProductionOrder
+	if err := r.db.WithContext(ctx).
+		Where("work_center_id = ? AND id <> ?", workCenterID, excludeOrderID).
+		Where("status IN ?", []entity.ProductionOrderStatus{entity.OrderStatusPending, entity.OrderStatusInProcess}).
+		Where("scheduled_end IS NOT NULL").
+		Order("scheduled_end DESC").
+		Limit(1).
+		Find(&orders).Error; err != nil {
+		return nil, err
+	}
+	if len(orders) == 0 {
+		return nil, nil
+	}
+	return orders[0].ScheduledEnd, nil
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"gorm.io/gorm"
+)
+
+// createTestBOM creates a bill of materials of a product no other test uses
+func createTestBOM(t *testing.T, db *gorm.DB) *entity.BillOfMaterial {
+	t.Helper()
+	bom := &entity.BillOfMaterial{
+		ProductID:     uint(time.Now().UnixNano() % 1_000_000_000),
+		Name:          "Test BOM",
+		Version:       "1",
+		EffectiveFrom: time.Now(),
+	}
+	if err := db.Create(bom).Error; err != nil {
+		t.Fatalf("creating bom: %v", err)
+	}
+	return bom
+}
+
+func bomLines(bomID uint, n int) []entity.BOMItem {
+	items := make([]entity.BOMItem, 0, n)
+	for i := 0; i < n; i++ {
+		items = append(items, entity.BOMItem{BOMID: bomID, MaterialID: uint(i + 1), QuantityNeeded: 1, UnitOfMeasure: "PCS"})
+	}
+	return items
+}
+
+func countBOMItems(t *testing.T, db *gorm.DB, bomID uint) int64 {
+	t.Helper()
+	var count int64
+	if err := db.Model(&entity.BOMItem{}).Where("bom_id = ?", bomID).Count(&count).Error; err != nil {
+		t.Fatalf("counting bom items: %v", err)
+	}
+	return count
+}
+
+// A 10k-line BOM is inserted in several batches, all of whose lines persist
+func TestAddBOMItemsLargeBOM(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewManufacturingRepository(db, NewStocksRepository(db))
+	bom := createTestBOM(t, db)
+
+	if err := repo.AddBOMItems(context.Background(), bomLines(bom.ID, 10000)); err != nil {
+		t.Fatalf("adding bom items: %v", err)
+	}
+
+	if got := countBOMItems(t, db, bom.ID); got != 10000 {
+		t.Errorf("got %d bom items, want 10000", got)
+	}
+}
+
+// A batch failing after earlier batches were inserted leaves none of the lines
+func TestAddBOMItemsLargeBOMRollsBack(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewManufacturingRepository(db, NewStocksRepository(db))
+	bom := createTestBOM(t, db)
+
+	// A line of a missing BOM fails the batch holding line 7500
+	items := bomLines(bom.ID, 10000)
+	items[7500].BOMID = 0
+	if err := repo.AddBOMItems(context.Background(), items); err == nil {
+		t.Fatal("adding bom items of a missing bom succeeded")
+	}
+
+	if got := countBOMItems(t, db, bom.ID); got != 0 {
+		t.Errorf("got %d bom items, want none", got)
+	}
+}
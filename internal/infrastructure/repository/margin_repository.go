@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"gorm.io/gorm"
+)
+
+// MarginRepository reads the sales orders and deliveries behind the gross
+// margin report, and the names of what it groups them by
+type MarginRepository struct {
+	db *gorm.DB
+}
+
+// NewMarginRepository creates a new margin repository
+func NewMarginRepository(db *gorm.DB) *MarginRepository {
+	return &MarginRepository{db: db}
+}
+
+// ListOrders retrieves the sales orders placed between the given times,
+// archived orders included, with the store each of their SKUs was first
+// delivered from. Draft and cancelled orders are left out.
+func (r *MarginRepository) ListOrders(ctx context.Context, from, to time.Time) ([]entity.MarginSalesOrder, error) {
+	var orders []entity.MarginSalesOrder
+	for _, table := range []string{"sales_orders", archiveTable("sales_orders")} {
+		var batch []entity.MarginSalesOrder
+		if err := r.db.WithContext(ctx).
+			Table(table).
+			Select("id, order_number, client_id, order_date, items, exchange_rate, created_by_id").
+			Where("order_date >= ? AND order_date <= ?", from, to).
+			Where("status NOT IN ?", []entity.SalesOrderStatus{entity.SalesOrderStatusDraft, entity.SalesOrderStatusCancelled}).
+			Order("order_date, id").
+			Scan(&batch).Error; err != nil {
+			return nil, err
+		}
+		orders = append(orders, batch...)
+	}
+	if len(orders) == 0 {
+		return orders, nil
+	}
+
+	index := make(map[string]int, len(orders))
+	orderIDs := make([]string, 0, len(orders))
+	for i := range orders {
+		orders[i].Stores = make(map[string]string)
+		index[orders[i].ID] = i
+		orderIDs = append(orderIDs, orders[i].ID)
+	}
+
+	for _, table := range []string{"delivery_orders", archiveTable("delivery_orders")} {
+		var deliveries []entity.DeliveryOrder
+		if err := r.db.WithContext(ctx).
+			Table(table).
+			Select("sales_order_id, store_id, items").
+			Where("sales_order_id IN ? AND status <> ?", orderIDs, entity.DeliveryOrderStatusCancelled).
+			Order("delivery_date, id").
+			Find(&deliveries).Error; err != nil {
+			return nil, err
+		}
+		for _, delivery := range deliveries {
+			stores := orders[index[delivery.SalesOrderID]].Stores
+			for _, item := range delivery.Items {
+				if _, ok := stores[item.SKUID]; !ok {
+					stores[item.SKUID] = delivery.StoreID
+				}
+			}
+		}
+	}
+	return orders, nil
+}
+
+// GetSKUs returns the code, name and category of each of the given SKUs
+func (r *MarginRepository) GetSKUs(ctx context.Context, skuIDs []string) (map[string]entity.SKU, error) {
+	skus := make(map[string]entity.SKU, len(skuIDs))
+	if len(skuIDs) == 0 {
+		return skus, nil
+	}
+
+	var batch []entity.SKU
+	if err := r.db.WithContext(ctx).Select("id, sku_code, name, category").Where("id IN ?", skuIDs).Find(&batch).Error; err != nil {
+		return nil, err
+	}
+	for _, sku := range batch {
+		skus[sku.ID] = sku
+	}
+	return skus, nil
+}
+
+// GetUserNames returns the username of each of the given users
+func (r *MarginRepository) GetUserNames(ctx context.Context, userIDs []uint) (map[uint]string, error) {
+	names := make(map[uint]string, len(userIDs))
+	if len(userIDs) == 0 {
+		return names, nil
+	}
+
+	var users []entity.User
+	if err := r.db.WithContext(ctx).Select("id, username").Where("id IN ?", userIDs).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		names[user.ID] = user.Username
+	}
+	return names, nil
+}
+
+// GetStoreNames returns the name of each of the given stores
+func (r *MarginRepository) GetStoreNames(ctx context.Context, storeIDs []string) (map[string]string, error) {
+	names := make(map[string]string, len(storeIDs))
+	if len(storeIDs) == 0 {
+		return names, nil
+	}
+
+	var stores []entity.Store
+	if err := r.db.WithContext(ctx).Select("id, name").Where("id IN ?", storeIDs).Find(&stores).Error; err != nil {
+		return nil, err
+	}
+	for _, store := range stores {
+		names[store.ID] = store.Name
+	}
+	return names, nil
+}
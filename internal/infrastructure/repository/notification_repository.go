@@ -0,0 +1,181 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type NotificationRepository struct {
+	db *gorm.DB
+}
+
+func NewNotificationRepository(db *gorm.DB) *NotificationRepository {
+	return &NotificationRepository{db: db}
+}
+
+// ListRecipients retrieves the active users whose role holds the permission,
+// together with the given users, less the excepted ones
+func (r *NotificationRepository) ListRecipients(ctx context.Context, permission entity.Permission, userIDs, exceptIDs []uint) ([]entity.User, error) {
+	var users []entity.User
+	query := r.db.WithContext(ctx).Model(&entity.User{}).Where("users.status = ?", entity.StatusActive)
+	switch {
+	case permission != "" && len(userIDs) > 0:
+		query = query.Where("users.id IN (?) OR users.role_id IN (?)", userIDs, r.rolesHolding(permission))
+	case permission != "":
+		query = query.Where("users.role_id IN (?)", r.rolesHolding(permission))
+	case len(userIDs) > 0:
+		query = query.Where("users.id IN (?)", userIDs)
+	default:
+		return users, nil
+	}
+	if len(exceptIDs) > 0 {
+		query = query.Where("users.id NOT IN (?)", exceptIDs)
+	}
+	if err := query.Order("users.id").Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// rolesHolding selects the IDs of the roles holding a permission
+func (r *NotificationRepository) rolesHolding(permission entity.Permission) *gorm.DB {
+	return r.db.Model(&entity.Role{}).Select("id").Where("? = ANY(permissions)", string(permission))
+}
+
+// CreateNotifications adds notifications to their users' inboxes
+func (r *NotificationRepository) CreateNotifications(ctx context.Context, notifications []entity.Notification) error {
+	if len(notifications) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Create(&notifications).Error
+}
+
+// ListNotifications retrieves a user's notifications matching a filter, latest first
+func (r *NotificationRepository) ListNotifications(ctx context.Context, filter *entity.NotificationFilter) ([]entity.Notification, int64, error) {
+	var notifications []entity.Notification
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&entity.Notification{}).Where("user_id = ?", filter.UserID)
+	if filter.Type != "" {
+		query = query.Where("type = ?", filter.Type)
+	}
+	if filter.Unread {
+		query = query.Where("read_at IS NULL")
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (filter.Page - 1) * filter.PageSize
+	if err := query.
+		Order("created_at DESC, id DESC").
+		Limit(filter.PageSize).
+		Offset(offset).
+		Find(&notifications).Error; err != nil {
+		return nil, 0, err
+	}
+	return notifications, total, nil
+}
+
+// CountUnread counts a user's unread notifications
+func (r *NotificationRepository) CountUnread(ctx context.Context, userID uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&entity.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Count(&count).Error
+	return count, err
+}
+
+// GetNotification retrieves one of a user's notifications
+func (r *NotificationRepository) GetNotification(ctx context.Context, userID, id uint) (*entity.Notification, error) {
+	var notification entity.Notification
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).First(&notification, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &notification, nil
+}
+
+// SetRead marks one of a user's notifications read at the given time, or
+// unread when it is nil
+func (r *NotificationRepository) SetRead(ctx context.Context, userID, id uint, readAt *time.Time) error {
+	return r.db.WithContext(ctx).Model(&entity.Notification{}).
+		Where("id = ? AND user_id = ?", id, userID).
+		Update("read_at", readAt).Error
+}
+
+// MarkAllRead marks all of a user's unread notifications read and returns how many there were
+func (r *NotificationRepository) MarkAllRead(ctx context.Context, userID uint, readAt time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&entity.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Update("read_at", readAt)
+	return result.RowsAffected, result.Error
+}
+
+// ListPreferences retrieves the preferences of the given users
+func (r *NotificationRepository) ListPreferences(ctx context.Context, userIDs []uint) ([]entity.NotificationPreference, error) {
+	var preferences []entity.NotificationPreference
+	if len(userIDs) == 0 {
+		return preferences, nil
+	}
+	if err := r.db.WithContext(ctx).Where("user_id IN ?", userIDs).Find(&preferences).Error; err != nil {
+		return nil, err
+	}
+	return preferences, nil
+}
+
+// SavePreferences creates or replaces a user's preferences
+func (r *NotificationRepository) SavePreferences(ctx context.Context, preferences []entity.NotificationPreference) error {
+	if len(preferences) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "type"}, {Name: "channel"}},
+		DoUpdates: clause.AssignmentColumns([]string{"enabled", "updated_at"}),
+	}).Create(&preferences).Error
+}
+
+// GetPhone retrieves a user's phone number
+func (r *NotificationRepository) GetPhone(ctx context.Context, userID uint) (string, error) {
+	var user entity.User
+	if err := r.db.WithContext(ctx).Select("id", "phone").First(&user, userID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return "", ErrRecordNotFound
+		}
+		return "", err
+	}
+	return user.Phone, nil
+}
+
+// SetPhone changes a user's phone number
+func (r *NotificationRepository) SetPhone(ctx context.Context, userID uint, phone string) error {
+	return r.db.WithContext(ctx).Model(&entity.User{}).Where("id = ?", userID).Update("phone", phone).Error
+}
+
+// ListTemplates retrieves the template overrides
+func (r *NotificationRepository) ListTemplates(ctx context.Context) ([]entity.NotificationTemplate, error) {
+	var templates []entity.NotificationTemplate
+	if err := r.db.WithContext(ctx).Find(&templates).Error; err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+// SaveTemplate creates or replaces a template override
+func (r *NotificationRepository) SaveTemplate(ctx context.Context, template *entity.NotificationTemplate) error {
+	return r.db.WithContext(ctx).Save(template).Error
+}
+
+// DeleteTemplate removes a template override, restoring the built-in template
+func (r *NotificationRepository) DeleteTemplate(ctx context.Context, notificationType entity.NotificationType, channel entity.NotificationChannel) error {
+	return r.db.WithContext(ctx).
+		Where("type = ? AND channel = ?", notificationType, channel).
+		Delete(&entity.NotificationTemplate{}).Error
+}
@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"gorm.io/gorm"
+)
+
+// SequenceGenerator generates sequential numbers for various document types
+type SequenceGenerator struct {
+	db *gorm.DB
+}
+
+// NewSequenceGenerator creates a new SequenceGenerator
+func NewSequenceGenerator(db *gorm.DB) *SequenceGenerator {
+	return &SequenceGenerator{db: db}
+}
+
+// NextSequence gets the next sequence number for a given sequence type. The
+// counter is created and incremented in one statement, so concurrent first
+// draws of a new counter, e.g. of a store in a new fiscal year, neither fail
+// nor share a number.
+func (sg *SequenceGenerator) NextSequence(ctx context.Context, sequenceType string) (uint, error) {
+	var value uint
+	err := sg.db.WithContext(ctx).Raw(
+		"INSERT INTO sequences (id, value) VALUES (?, 1) ON CONFLICT (id) DO UPDATE SET value = sequences.value + 1 RETURNING value",
+		sequenceType,
+	).Scan(&value).Error
+	if err != nil {
+		return 0, err
+	}
+	return value, nil
+}
+
+// CurrentSequence gets the last number drawn from a sequence, 0 when none was
+func (sg *SequenceGenerator) CurrentSequence(ctx context.Context, sequenceType string) (uint, error) {
+	var values []uint
+	if err := sg.db.WithContext(ctx).Raw("SELECT value FROM sequences WHERE id = ?", sequenceType).Scan(&values).Error; err != nil {
+		return 0, err
+	}
+	if len(values) == 0 {
+		return 0, nil
+	}
+	return values[0], nil
+}
+
+// Scheme gets the numbering scheme of the documents of a type and store: the
+// store's own, else the one of the type, else the built-in default
+func (sg *SequenceGenerator) Scheme(ctx context.Context, documentType entity.DocumentType, storeID string) (*entity.NumberingScheme, error) {
+	var schemes []entity.NumberingScheme
+	if err := sg.db.WithContext(ctx).
+		Where("document_type = ? AND store_id IN ?", documentType, []string{storeID, ""}).
+		Order("store_id DESC").
+		Limit(1).
+		Find(&schemes).Error; err != nil {
+		return nil, err
+	}
+	if len(schemes) > 0 {
+		return &schemes[0], nil
+	}
+
+	scheme := entity.DefaultNumberingSchemes[documentType]
+	scheme.DocumentType = documentType
+	scheme.ApplyDefaults()
+	return &scheme, nil
+}
+
+// NextNumber draws the number of a new document of a type and store from its
+// numbering scheme. Numbers are drawn apart from the document's transaction,
+// so a document that fails to be created leaves a gap.
+func (sg *SequenceGenerator) NextNumber(ctx context.Context, documentType entity.DocumentType, storeID string) (string, error) {
+	scheme, err := sg.Scheme(ctx, documentType, storeID)
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	seq, err := sg.NextSequence(ctx, scheme.CounterKey(now))
+	if err != nil {
+		return "", err
+	}
+	return scheme.Format(now, seq), nil
+}
+
+// NumberingRepository handles the configured numbering schemes
+type NumberingRepository struct {
+	db *gorm.DB
+}
+
+func NewNumberingRepository(db *gorm.DB) *NumberingRepository {
+	return &NumberingRepository{db: db}
+}
+
+// ListSchemes retrieves the configured schemes by document type and store
+func (r *NumberingRepository) ListSchemes(ctx context.Context) ([]entity.NumberingScheme, error) {
+	var schemes []entity.NumberingScheme
+	if err := r.db.WithContext(ctx).Order("document_type, store_id").Find(&schemes).Error; err != nil {
+		return nil, err
+	}
+	return schemes, nil
+}
+
+// GetScheme retrieves a configured scheme by ID
+func (r *NumberingRepository) GetScheme(ctx context.Context, id uint) (*entity.NumberingScheme, error) {
+	var scheme entity.NumberingScheme
+	if err := r.db.WithContext(ctx).First(&scheme, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &scheme, nil
+}
+
+// SaveScheme creates or replaces the scheme of a document type and store
+func (r *NumberingRepository) SaveScheme(ctx context.Context, scheme *entity.NumberingScheme) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing entity.NumberingScheme
+		err := tx.Where("document_type = ? AND store_id = ?", scheme.DocumentType, scheme.StoreID).First(&existing).Error
+		switch {
+		case err == nil:
+			scheme.ID = existing.ID
+			scheme.CreatedAt = existing.CreatedAt
+			return tx.Save(scheme).Error
+		case err == gorm.ErrRecordNotFound:
+			return tx.Create(scheme).Error
+		default:
+			return err
+		}
+	})
+}
+
+// DeleteScheme deletes a configured scheme
+func (r *NumberingRepository) DeleteScheme(ctx context.Context, id uint) error {
+	result := r.db.WithContext(ctx).Delete(&entity.NumberingScheme{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
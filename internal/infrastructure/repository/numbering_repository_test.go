@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// Concurrent draws of a counter that does not exist yet each get a number of
+// their own, without gaps
+func TestNextSequenceConcurrentFirstDraws(t *testing.T) {
+	db := openTestDB(t)
+	sg := NewSequenceGenerator(db)
+	key := "TEST-" + uuid.New().String()[:8]
+
+	const draws = 20
+	values := make([]int, draws)
+	errs := make([]error, draws)
+	var wg sync.WaitGroup
+	for i := 0; i < draws; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			value, err := sg.NextSequence(context.Background(), key)
+			values[i], errs[i] = int(value), err
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("draw %d: %v", i, err)
+		}
+	}
+	sort.Ints(values)
+	for i, value := range values {
+		if value != i+1 {
+			t.Fatalf("drew %v, want 1 to %d once each", values, draws)
+		}
+	}
+	if current, err := sg.CurrentSequence(context.Background(), key); err != nil || current != draws {
+		t.Errorf("CurrentSequence() = %d, %v, want %d", current, err, draws)
+	}
+}
@@ -2,7 +2,6 @@ package repository
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"time"
 
@@ -13,21 +12,23 @@ import (
 
 var (
 	// ErrInvalidOrderStatus is returned when an operation is not allowed for the current order status
-	ErrInvalidOrderStatus = errors.New("invalid order status for this operation")
+	ErrInvalidOrderStatus = entity.NewError(entity.ErrCodeFailedPrecondition, "invalid order status for this operation")
 )
 
 // OrderRepository handles database operations for sales orders and delivery orders
 type OrderRepository struct {
 	db                *gorm.DB
 	stocksRepo        *StocksRepository
+	prepaymentRepo    *PrepaymentRepository
 	sequenceGenerator *SequenceGenerator
 }
 
 // NewOrderRepository creates a new OrderRepository
-func NewOrderRepository(db *gorm.DB, stocksRepo *StocksRepository) *OrderRepository {
+func NewOrderRepository(db *gorm.DB, stocksRepo *StocksRepository, prepaymentRepo *PrepaymentRepository) *OrderRepository {
 	return &OrderRepository{
 		db:                db,
 		stocksRepo:        stocksRepo,
+		prepaymentRepo:    prepaymentRepo,
 		sequenceGenerator: NewSequenceGenerator(db),
 	}
 }
@@ -40,11 +41,11 @@ func (r *OrderRepository) CreateSalesOrder(ctx context.Context, order *entity.Sa
 
 	// Generate order number if not provided
 	if order.OrderNumber == "" {
-		seq, err := r.sequenceGenerator.NextSequence(ctx, "sales_order")
+		number, err := r.sequenceGenerator.NextNumber(ctx, entity.DocumentSalesOrder, "")
 		if err != nil {
 			return err
 		}
-		order.OrderNumber = fmt.Sprintf("SO-%s-%06d", time.Now().Format("20060102"), seq)
+		order.OrderNumber = number
 	}
 
 	return r.db.WithContext(ctx).Create(order).Error
@@ -80,12 +81,54 @@ func (r *OrderRepository) GetSalesOrderByNumber(ctx context.Context, orderNumber
 	return &order, nil
 }
 
+// GetSalesOrderByExternalRef retrieves a sales order ingested from an external system
+func (r *OrderRepository) GetSalesOrderByExternalRef(ctx context.Context, externalRef string) (*entity.SalesOrder, error) {
+	var order entity.SalesOrder
+	if err := r.db.WithContext(ctx).First(&order, "external_ref = ?", externalRef).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &order, nil
+}
+
 // ListSalesOrders retrieves a list of sales orders based on filter
 func (r *OrderRepository) ListSalesOrders(ctx context.Context, filter *entity.SalesOrderFilter) ([]entity.SalesOrder, error) {
 	var orders []entity.SalesOrder
+
+	if err := r.salesOrderQuery(ctx, filter).Order("created_at DESC").Find(&orders).Error; err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// ListSalesOrdersPage retrieves the page of sales orders matching a filter
+// that follows the page's cursor, latest first, with the cursor of the next
+// page when there is one
+func (r *OrderRepository) ListSalesOrdersPage(ctx context.Context, filter *entity.SalesOrderFilter, page entity.CursorPage) ([]entity.SalesOrder, *entity.Cursor, error) {
+	query, err := cursorQuery(r.salesOrderQuery(ctx, filter), page, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	var orders []entity.SalesOrder
+	if err := query.Find(&orders).Error; err != nil {
+		return nil, nil, err
+	}
+	orders, next := cursorRows(orders, page, func(o *entity.SalesOrder) entity.Cursor {
+		return entity.Cursor{CreatedAt: o.CreatedAt, ID: o.ID}
+	})
+	return orders, next, nil
+}
+
+// salesOrderQuery selects the sales orders matching a filter
+func (r *OrderRepository) salesOrderQuery(ctx context.Context, filter *entity.SalesOrderFilter) *gorm.DB {
 	query := r.db.WithContext(ctx)
 
 	if filter != nil {
+		if filter.Archived {
+			query = query.Table(archiveTable("sales_orders"))
+		}
 		if filter.OrderNumber != "" {
 			query = query.Where("order_number LIKE ?", "%"+filter.OrderNumber+"%")
 		}
@@ -108,12 +151,9 @@ func (r *OrderRepository) ListSalesOrders(ctx context.Context, filter *entity.Sa
 			// This requires a more complex query to search in the JSONB items array
 			query = query.Where("items @> ?", fmt.Sprintf(`[{"sku_id": "%s"}]`, filter.SKUID))
 		}
+		query = whereCustomFields(query, filter.CustomFields)
 	}
-
-	if err := query.Order("created_at DESC").Find(&orders).Error; err != nil {
-		return nil, err
-	}
-	return orders, nil
+	return query
 }
 
 // UpdateSalesOrder updates an existing sales order
@@ -138,11 +178,11 @@ func (r *OrderRepository) CreateDeliveryOrder(ctx context.Context, delivery *ent
 
 	// Generate delivery number if not provided
 	if delivery.DeliveryNumber == "" {
-		seq, err := r.sequenceGenerator.NextSequence(ctx, "delivery_order")
+		number, err := r.sequenceGenerator.NextNumber(ctx, entity.DocumentDeliveryOrder, delivery.StoreID)
 		if err != nil {
 			return err
 		}
-		delivery.DeliveryNumber = fmt.Sprintf("DO-%s-%06d", time.Now().Format("20060102"), seq)
+		delivery.DeliveryNumber = number
 	}
 
 	return r.db.WithContext(ctx).Create(delivery).Error
@@ -165,7 +205,7 @@ func (r *OrderRepository) GetDeliveryOrderByID(ctx context.Context, id string) (
 // ListDeliveryOrders retrieves a list of delivery orders based on filter
 func (r *OrderRepository) ListDeliveryOrders(ctx context.Context, filter *entity.DeliveryOrderFilter) ([]entity.DeliveryOrder, error) {
 	var deliveries []entity.DeliveryOrder
-	query := r.db.WithContext(ctx)
+	query := scopeStores(ctx, r.db.WithContext(ctx), "store_id")
 
 	if filter != nil {
 		if filter.DeliveryNumber != "" {
@@ -174,6 +214,9 @@ func (r *OrderRepository) ListDeliveryOrders(ctx context.Context, filter *entity
 		if filter.SalesOrderID != "" {
 			query = query.Where("sales_order_id = ?", filter.SalesOrderID)
 		}
+		if filter.ClientID != nil {
+			query = query.Where("sales_order_id IN (?)", r.db.Model(&entity.SalesOrder{}).Select("id").Where("client_id = ?", *filter.ClientID))
+		}
 		if filter.Status != nil {
 			query = query.Where("status = ?", *filter.Status)
 		}
@@ -194,6 +237,30 @@ func (r *OrderRepository) ListDeliveryOrders(ctx context.Context, filter *entity
 	return deliveries, nil
 }
 
+// ListCrossDockDeliveries retrieves the pending and preparing deliveries of a
+// store, excluding drop-ship ones, earliest delivery date first
+func (r *OrderRepository) ListCrossDockDeliveries(ctx context.Context, storeID string) ([]entity.DeliveryOrder, error) {
+	var deliveries []entity.DeliveryOrder
+	if err := r.db.WithContext(ctx).
+		Where("store_id = ? AND status IN ? AND purchase_order_id IS NULL", storeID,
+			[]entity.DeliveryOrderStatus{entity.DeliveryOrderStatusPending, entity.DeliveryOrderStatusPreparing}).
+		Order("delivery_date, created_at, id").
+		Find(&deliveries).Error; err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// LinkCrossDockReceipt records the purchase receipt deliveries are shipped
+// from without putaway
+func (r *OrderRepository) LinkCrossDockReceipt(ctx context.Context, deliveryIDs []string, receiptID string) error {
+	return r.db.WithContext(ctx).
+		Model(&entity.DeliveryOrder{}).
+		Where("id IN ?", deliveryIDs).
+		Update("cross_dock_receipt_id", receiptID).
+		Error
+}
+
 // UpdateDeliveryOrderStatus updates the status of a delivery order
 func (r *OrderRepository) UpdateDeliveryOrderStatus(ctx context.Context, id string, status entity.DeliveryOrderStatus) error {
 	return r.db.WithContext(ctx).
@@ -217,7 +284,7 @@ func (r *OrderRepository) ProcessDelivery(ctx context.Context, deliveryID string
 
 	// Get delivery order
 	var delivery entity.DeliveryOrder
-	if err := tx.Preload("Items").First(&delivery, "id = ?", deliveryID).Error; err != nil {
+	if err := tx.First(&delivery, "id = ?", deliveryID).Error; err != nil {
 		tx.Rollback()
 		return err
 	}
@@ -228,10 +295,10 @@ func (r *OrderRepository) ProcessDelivery(ctx context.Context, deliveryID string
 		return ErrInvalidOrderStatus
 	}
 
-	// Process each item in the delivery
+	// Build stock entries for all delivered items
+	stockEntries := make([]entity.StockEntry, 0, len(delivery.Items))
 	for _, item := range delivery.Items {
-		// Create stock entry for inventory reduction
-		stockEntry := &entity.StockEntry{
+		stockEntries = append(stockEntries, entity.StockEntry{
 			StoreID:   delivery.StoreID,
 			SKUID:     item.SKUID,
 			Type:      "OUT",
@@ -239,19 +306,28 @@ func (r *OrderRepository) ProcessDelivery(ctx context.Context, deliveryID string
 			Reference: delivery.DeliveryNumber,
 			Note:      fmt.Sprintf("Delivery for Sales Order %s", delivery.SalesOrderID),
 			CreatedBy: userID,
-		}
+		})
+	}
 
-		// Process the stock entry using the inventory repository
-		if err := r.stocksRepo.ProcessStockEntry(ctx, stockEntry, userID); err != nil {
-			tx.Rollback()
-			return err
-		}
+	// Reduce inventory in bulk within the delivery transaction
+	if err := r.stocksRepo.ProcessStockEntriesTx(ctx, tx, stockEntries, userID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// The order's reservations in this store are consumed by the shipment
+	if err := tx.Model(&entity.StockAllocation{}).
+		Where("sales_order_id = ? AND store_id = ? AND status = ?", delivery.SalesOrderID, delivery.StoreID, entity.StockAllocationActive).
+		Update("status", entity.StockAllocationFulfilled).
+		Error; err != nil {
+		tx.Rollback()
+		return err
 	}
 
 	// Update delivery status to in transit
 	if err := tx.Model(&entity.DeliveryOrder{}).
 		Where("id = ?", deliveryID).
-		Update("status", entity.DeliveryOrderStatusInTransit).
+		Updates(map[string]interface{}{"status": entity.DeliveryOrderStatusInTransit, "shipped_at": time.Now()}).
 		Error; err != nil {
 		tx.Rollback()
 		return err
@@ -268,14 +344,32 @@ func (r *OrderRepository) CreateInvoice(ctx context.Context, invoice *entity.Inv
 
 	// Generate invoice number if not provided
 	if invoice.InvoiceNumber == "" {
-		seq, err := r.sequenceGenerator.NextSequence(ctx, "invoice")
+		number, err := r.sequenceGenerator.NextNumber(ctx, entity.DocumentInvoice, "")
 		if err != nil {
 			return err
 		}
-		invoice.InvoiceNumber = fmt.Sprintf("INV-%s-%06d", time.Now().Format("20060102"), seq)
+		invoice.InvoiceNumber = number
 	}
 
-	return r.db.WithContext(ctx).Create(invoice).Error
+	// Settle it with the unapplied deposits of the order, or not at all
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(invoice).Error; err != nil {
+			return err
+		}
+		_, err := r.prepaymentRepo.ApplyToSalesInvoiceTx(tx, invoice)
+		return err
+	})
+}
+
+// CancelInvoice cancels an invoice and returns the deposits applied to it to
+// their unapplied balances, in one transaction
+func (r *OrderRepository) CancelInvoice(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&entity.Invoice{}).Where("id = ?", id).Update("status", entity.InvoiceStatusCancelled).Error; err != nil {
+			return err
+		}
+		return r.prepaymentRepo.ReleaseSalesInvoiceTx(tx, id)
+	})
 }
 
 // GetInvoiceByID retrieves an invoice by ID
@@ -298,12 +392,25 @@ func (r *OrderRepository) ListInvoices(ctx context.Context, filter *entity.Invoi
 	query := r.db.WithContext(ctx)
 
 	if filter != nil {
+		if filter.Archived {
+			query = query.Table(archiveTable("invoices"))
+		}
 		if filter.InvoiceNumber != "" {
 			query = query.Where("invoice_number LIKE ?", "%"+filter.InvoiceNumber+"%")
 		}
 		if filter.SalesOrderID != "" {
 			query = query.Where("sales_order_id = ?", filter.SalesOrderID)
 		}
+		if filter.ClientID != nil {
+			clientOrders := r.db.Model(&entity.SalesOrder{}).Select("id").Where("client_id = ?", *filter.ClientID)
+			if filter.Archived {
+				// The order of an archived invoice may be live or archived itself
+				archivedOrders := r.db.Table(archiveTable("sales_orders")).Select("id").Where("client_id = ?", *filter.ClientID)
+				query = query.Where("sales_order_id IN (?) OR sales_order_id IN (?)", clientOrders, archivedOrders)
+			} else {
+				query = query.Where("sales_order_id IN (?)", clientOrders)
+			}
+		}
 		if filter.Status != nil {
 			query = query.Where("status = ?", *filter.Status)
 		}
@@ -321,87 +428,57 @@ func (r *OrderRepository) ListInvoices(ctx context.Context, filter *entity.Invoi
 	return invoices, nil
 }
 
-// UpdateInvoiceStatus updates the status of an invoice
+// ReleaseOrderAllocations releases the stock reserved for a sales order
+func (r *OrderRepository) ReleaseOrderAllocations(ctx context.Context, salesOrderID string) error {
+	return r.db.WithContext(ctx).
+		Model(&entity.StockAllocation{}).
+		Where("sales_order_id = ? AND status = ?", salesOrderID, entity.StockAllocationActive).
+		Update("status", entity.StockAllocationReleased).Error
+}
+
+// UpdateInvoiceStatus updates the status of an invoice. A paid invoice has
+// nothing left due.
 func (r *OrderRepository) UpdateInvoiceStatus(ctx context.Context, id string, status entity.InvoiceStatus) error {
+	updates := map[string]interface{}{"status": status}
+	if status == entity.InvoiceStatusPaid {
+		updates["amount_due"] = 0
+	}
 	return r.db.WithContext(ctx).
 		Model(&entity.Invoice{}).
 		Where("id = ?", id).
-		Update("status", status).
+		Updates(updates).
 		Error
 }
 
-// CheckStockAvailability checks if there is enough stock for all items in an order
-func (r *OrderRepository) CheckStockAvailability(ctx context.Context, storeID string, items []entity.SalesOrderItem) (bool, map[string]float64, error) {
+// CheckStockAvailability checks if there is enough stock of each SKU for the
+// quantities an order takes
+func (r *OrderRepository) CheckStockAvailability(ctx context.Context, storeID string, quantities map[string]float64) (bool, map[string]float64, error) {
 	insufficientItems := make(map[string]float64)
 
-	for _, item := range items {
+	for skuID, quantity := range quantities {
 		// Get current inventory for this product in the warehouse
-		stock, err := r.stocksRepo.GetBySKUAndStore(ctx, item.SKUID, storeID)
+		stock, err := r.stocksRepo.GetBySKUAndStore(ctx, skuID, storeID)
 		if err != nil {
 			if err == ErrRecordNotFound {
 				// No inventory record means zero quantity
-				insufficientItems[item.SKUID] = 0
+				insufficientItems[skuID] = 0
 				continue
 			}
 			return false, nil, err
 		}
 
-		// Check if there's enough stock
-		if stock.Quantity < item.Quantity {
-			insufficientItems[item.SKUID] = stock.Quantity
-		}
-	}
-
-	return len(insufficientItems) == 0, insufficientItems, nil
-}
-
-// SequenceGenerator generates sequential numbers for various document types
-type SequenceGenerator struct {
-	db *gorm.DB
-}
-
-// NewSequenceGenerator creates a new SequenceGenerator
-func NewSequenceGenerator(db *gorm.DB) *SequenceGenerator {
-	return &SequenceGenerator{db: db}
-}
-
-// NextSequence gets the next sequence number for a given sequence type
-func (sg *SequenceGenerator) NextSequence(ctx context.Context, sequenceType string) (uint, error) {
-	// This is a simplified implementation. In production, you might want to use
-	// database-specific features for sequences or implement a more robust solution.
-	var sequence struct {
-		ID    string `gorm:"primaryKey"`
-		Value uint   `gorm:"not null"`
-	}
-
-	err := sg.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		// Try to get existing sequence
-		result := tx.Raw("SELECT id, value FROM sequences WHERE id = ? FOR UPDATE", sequenceType).Scan(&sequence)
-		if result.Error != nil && result.Error != gorm.ErrRecordNotFound {
-			return result.Error
+		// Stock reserved for confirmed orders by an allocation run or held by a
+		// quality inspection is not available
+		reserved, err := reservedQuantity(r.db.WithContext(ctx), skuID, storeID)
+		if err != nil {
+			return false, nil, err
 		}
 
-		if result.RowsAffected == 0 {
-			// Create new sequence starting at 1
-			sequence.ID = sequenceType
-			sequence.Value = 1
-			if err := tx.Exec("INSERT INTO sequences (id, value) VALUES (?, ?)", sequenceType, sequence.Value).Error; err != nil {
-				return err
-			}
-		} else {
-			// Increment existing sequence
-			sequence.Value++
-			if err := tx.Exec("UPDATE sequences SET value = ? WHERE id = ?", sequence.Value, sequenceType).Error; err != nil {
-				return err
-			}
+		// Check if there's enough stock
+		if available := stock.Available() - reserved; available < quantity {
+			insufficientItems[skuID] = available
 		}
-
-		return nil
-	})
-
-	if err != nil {
-		return 0, err
 	}
 
-	return sequence.Value, nil
+	return len(insufficientItems) == 0, insufficientItems, nil
 }
@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"gorm.io/gorm"
+)
+
+// createTestSalesOrders creates n sales orders of the fixture's user, each
+// with a delivery order and an invoice
+func createTestSalesOrders(tb testing.TB, db *gorm.DB, fixture *testFixture, n int) {
+	tb.Helper()
+	prefix := fixture.StoreID[:8]
+	now := time.Now()
+
+	orders := make([]entity.SalesOrder, 0, n)
+	for i := 0; i < n; i++ {
+		orders = append(orders, entity.SalesOrder{
+			OrderNumber: fmt.Sprintf("SO-%s-%05d", prefix, i),
+			ClientID:    fixture.UserID,
+			OrderDate:   now,
+			Items:       entity.SalesOrderItems{},
+			CreatedByID: fixture.UserID,
+		})
+	}
+	if err := db.Omit("Client", "CreatedBy").CreateInBatches(&orders, 500).Error; err != nil {
+		tb.Fatalf("creating sales orders: %v", err)
+	}
+
+	deliveries := make([]entity.DeliveryOrder, 0, n)
+	invoices := make([]entity.Invoice, 0, n)
+	for i, order := range orders {
+		deliveries = append(deliveries, entity.DeliveryOrder{
+			DeliveryNumber: fmt.Sprintf("DO-%s-%05d", prefix, i),
+			SalesOrderID:   order.ID,
+			DeliveryDate:   now,
+			Items:          entity.DeliveryOrderItems{},
+			StoreID:        fixture.StoreID,
+			CreatedByID:    fixture.UserID,
+		})
+		invoices = append(invoices, entity.Invoice{
+			InvoiceNumber: fmt.Sprintf("INV-%s-%05d", prefix, i),
+			SalesOrderID:  order.ID,
+			IssueDate:     now,
+			DueDate:       now,
+			CreatedByID:   fixture.UserID,
+		})
+	}
+	if err := db.Omit("SalesOrder", "CreatedBy").CreateInBatches(&deliveries, 500).Error; err != nil {
+		tb.Fatalf("creating delivery orders: %v", err)
+	}
+	if err := db.Omit("SalesOrder", "CreatedBy").CreateInBatches(&invoices, 500).Error; err != nil {
+		tb.Fatalf("creating invoices: %v", err)
+	}
+}
+
+// orderListQueries lists the orders, deliveries and invoices of a client,
+// returning the rows of each list
+var orderListQueries = []struct {
+	name string
+	list func(ctx context.Context, repo *OrderRepository, clientID uint) (int, error)
+}{
+	{"ListSalesOrders", func(ctx context.Context, repo *OrderRepository, clientID uint) (int, error) {
+		rows, err := repo.ListSalesOrders(ctx, &entity.SalesOrderFilter{ClientID: &clientID})
+		return len(rows), err
+	}},
+	{"ListDeliveryOrders", func(ctx context.Context, repo *OrderRepository, clientID uint) (int, error) {
+		rows, err := repo.ListDeliveryOrders(ctx, &entity.DeliveryOrderFilter{ClientID: &clientID})
+		return len(rows), err
+	}},
+	{"ListInvoices", func(ctx context.Context, repo *OrderRepository, clientID uint) (int, error) {
+		rows, err := repo.ListInvoices(ctx, &entity.InvoiceFilter{ClientID: &clientID})
+		return len(rows), err
+	}},
+}
+
+// The lists load no relations per row, and preload the ones they return in
+// one query per relation, so the number of queries does not grow with the
+// rows listed
+func TestOrderListsPreloadInBatches(t *testing.T) {
+	db := openTestDB(t)
+	small := createTestFixture(t, db, 0)
+	large := createTestFixture(t, db, 0)
+	createTestSalesOrders(t, db, small, 10)
+	createTestSalesOrders(t, db, large, 1000)
+
+	counted, counter := countQueries(t, db)
+	repo := NewOrderRepository(counted, NewStocksRepository(counted), NewPrepaymentRepository(counted))
+	ctx := context.Background()
+
+	for _, tt := range orderListQueries {
+		t.Run(tt.name, func(t *testing.T) {
+			counter.Count()
+			if rows, err := tt.list(ctx, repo, small.UserID); err != nil || rows != 10 {
+				t.Fatalf("listing 10 rows: got %d rows, error %v", rows, err)
+			}
+			smallQueries := counter.Count()
+
+			if rows, err := tt.list(ctx, repo, large.UserID); err != nil || rows != 1000 {
+				t.Fatalf("listing 1000 rows: got %d rows, error %v", rows, err)
+			}
+			if largeQueries := counter.Count(); largeQueries != smallQueries {
+				t.Errorf("listing 1000 rows ran %d queries, 10 rows %d", largeQueries, smallQueries)
+			}
+		})
+	}
+}
+
+func BenchmarkOrderLists(b *testing.B) {
+	db := openTestDB(b)
+	fixture := createTestFixture(b, db, 0)
+	createTestSalesOrders(b, db, fixture, 1000)
+
+	counted, counter := countQueries(b, db)
+	repo := NewOrderRepository(counted, NewStocksRepository(counted), NewPrepaymentRepository(counted))
+	ctx := context.Background()
+
+	for _, tt := range orderListQueries {
+		b.Run(tt.name, func(b *testing.B) {
+			counter.Count()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := tt.list(ctx, repo, fixture.UserID); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(counter.Count())/float64(b.N), "queries/op")
+		})
+	}
+}
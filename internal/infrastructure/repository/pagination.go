@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"strconv"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"gorm.io/gorm"
+)
+
+// cursorQuery orders a listing latest first and restricts it to the rows
+// following the page's cursor. One row more than the limit is fetched, for
+// cursorRows to tell whether another page follows. parseID converts the
+// cursor's ID for tables with numeric IDs and is nil for UUIDs.
+func cursorQuery(query *gorm.DB, page entity.CursorPage, parseID func(string) (interface{}, error)) (*gorm.DB, error) {
+	if page.After != nil {
+		var id interface{} = page.After.ID
+		if parseID != nil {
+			var err error
+			if id, err = parseID(page.After.ID); err != nil {
+				return nil, entity.ErrInvalidCursor
+			}
+		}
+		query = query.Where("(created_at, id) < (?, ?)", page.After.CreatedAt, id)
+	}
+	return query.Order("created_at DESC, id DESC").Limit(cursorLimit(page) + 1), nil
+}
+
+// cursorRows drops the extra row fetched by cursorQuery, returning the
+// cursor of the next page when there is one
+func cursorRows[T any](rows []T, page entity.CursorPage, key func(*T) entity.Cursor) ([]T, *entity.Cursor) {
+	limit := cursorLimit(page)
+	if len(rows) <= limit {
+		return rows, nil
+	}
+	rows = rows[:limit]
+	next := key(&rows[limit-1])
+	return rows, &next
+}
+
+func cursorLimit(page entity.CursorPage) int {
+	if page.Limit <= 0 {
+		return entity.DefaultCursorLimit
+	}
+	if page.Limit > entity.MaxCursorLimit {
+		return entity.MaxCursorLimit
+	}
+	return page.Limit
+}
+
+// parseIntID converts the cursor ID of a table with integer IDs
+func parseIntID(id string) (interface{}, error) {
+	return strconv.ParseInt(id, 10, 64)
+}
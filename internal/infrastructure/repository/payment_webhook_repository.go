@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"gorm.io/gorm"
+)
+
+// PaymentWebhookRepository handles database operations for payment gateway webhook events
+type PaymentWebhookRepository struct {
+	db *gorm.DB
+}
+
+// NewPaymentWebhookRepository creates a new payment webhook repository
+func NewPaymentWebhookRepository(db *gorm.DB) *PaymentWebhookRepository {
+	return &PaymentWebhookRepository{db: db}
+}
+
+// GetEvent retrieves a webhook event by provider and event ID
+func (r *PaymentWebhookRepository) GetEvent(ctx context.Context, provider, eventID string) (*entity.PaymentWebhookEvent, error) {
+	var event entity.PaymentWebhookEvent
+	if err := r.db.WithContext(ctx).
+		Where("provider = ? AND event_id = ?", provider, eventID).
+		First(&event).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &event, nil
+}
+
+// CreateEvent records a webhook event. It fails if the provider and event ID
+// were already recorded.
+func (r *PaymentWebhookRepository) CreateEvent(ctx context.Context, event *entity.PaymentWebhookEvent) error {
+	return r.db.WithContext(ctx).Create(event).Error
+}
+
+// UpdateEvent saves a webhook event
+func (r *PaymentWebhookRepository) UpdateEvent(ctx context.Context, event *entity.PaymentWebhookEvent) error {
+	return r.db.WithContext(ctx).Save(event).Error
+}
+
+// ListFailedEvents retrieves the failed webhook events, of one provider when
+// given, oldest first
+func (r *PaymentWebhookRepository) ListFailedEvents(ctx context.Context, provider string) ([]entity.PaymentWebhookEvent, error) {
+	var events []entity.PaymentWebhookEvent
+	query := r.db.WithContext(ctx).Where("status = ?", entity.PaymentWebhookFailed)
+	if provider != "" {
+		query = query.Where("provider = ?", provider)
+	}
+	if err := query.Order("received_at, id").Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// ListEvents retrieves webhook events with filters and pagination, latest first
+func (r *PaymentWebhookRepository) ListEvents(ctx context.Context, filter *entity.PaymentWebhookEventFilter) ([]entity.PaymentWebhookEvent, int64, error) {
+	var events []entity.PaymentWebhookEvent
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&entity.PaymentWebhookEvent{})
+	if filter.Provider != "" {
+		query = query.Where("provider = ?", filter.Provider)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (filter.Page - 1) * filter.PageSize
+	if err := query.Order("received_at DESC, id DESC").Limit(filter.PageSize).Offset(offset).Find(&events).Error; err != nil {
+		return nil, 0, err
+	}
+	return events, total, nil
+}
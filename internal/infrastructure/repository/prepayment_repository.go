@@ -0,0 +1,322 @@
+package repository
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrPrepaymentApplied = entity.NewError(entity.ErrCodeFailedPrecondition, "prepayment is already applied to invoices")
+
+// PrepaymentRepository handles database operations for customer deposits and
+// vendor advances, and their application to invoices
+type PrepaymentRepository struct {
+	db                *gorm.DB
+	sequenceGenerator *SequenceGenerator
+}
+
+// NewPrepaymentRepository creates a new prepayment repository
+func NewPrepaymentRepository(db *gorm.DB) *PrepaymentRepository {
+	return &PrepaymentRepository{
+		db:                db,
+		sequenceGenerator: NewSequenceGenerator(db),
+	}
+}
+
+// Create numbers and creates a prepayment
+func (r *PrepaymentRepository) Create(ctx context.Context, prepayment *entity.Prepayment) error {
+	if prepayment.PrepaymentNumber == "" {
+		number, err := r.sequenceGenerator.NextNumber(ctx, entity.DocumentPrepayment, "")
+		if err != nil {
+			return err
+		}
+		prepayment.PrepaymentNumber = number
+	}
+	return r.db.WithContext(ctx).Omit(clause.Associations).Create(prepayment).Error
+}
+
+// GetByID retrieves a prepayment with its applications
+func (r *PrepaymentRepository) GetByID(ctx context.Context, id uint) (*entity.Prepayment, error) {
+	var prepayment entity.Prepayment
+	if err := r.db.WithContext(ctx).
+		Preload("Applications", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		First(&prepayment, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &prepayment, nil
+}
+
+// List retrieves prepayments with filters and pagination, latest first
+func (r *PrepaymentRepository) List(ctx context.Context, filter *entity.PrepaymentFilter) ([]entity.Prepayment, int64, error) {
+	var prepayments []entity.Prepayment
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&entity.Prepayment{})
+	if filter.Type != "" {
+		query = query.Where("type = ?", filter.Type)
+	}
+	if filter.OrderID != "" {
+		query = query.Where("order_id = ?", filter.OrderID)
+	}
+	if filter.EntityType != "" {
+		query = query.Where("entity_type = ?", filter.EntityType)
+	}
+	if filter.EntityID != 0 {
+		query = query.Where("entity_id = ?", filter.EntityID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+	if filter.PageSize <= 0 {
+		filter.PageSize = 10
+	}
+	offset := (filter.Page - 1) * filter.PageSize
+	if err := query.Order("id DESC").Limit(filter.PageSize).Offset(offset).Find(&prepayments).Error; err != nil {
+		return nil, 0, err
+	}
+	return prepayments, total, nil
+}
+
+// OrderTotal totals the prepayments of an order that are not cancelled
+func (r *PrepaymentRepository) OrderTotal(ctx context.Context, orderID string) (float64, error) {
+	var total float64
+	if err := r.db.WithContext(ctx).Model(&entity.Prepayment{}).
+		Select("COALESCE(SUM(amount), 0)").
+		Where("order_id = ? AND status <> ?", orderID, entity.PrepaymentCancelled).
+		Scan(&total).Error; err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// Balances totals the unapplied prepayments by customer or vendor and
+// currency, of one customer or vendor when an entity is given
+func (r *PrepaymentRepository) Balances(ctx context.Context, entityType string, entityID uint) ([]entity.PrepaymentBalance, error) {
+	query := r.db.WithContext(ctx).Model(&entity.Prepayment{}).
+		Select(`type, entity_type, entity_id, currency_code, COUNT(*) AS prepayments,
+			SUM(unapplied_amount) AS unapplied_amount, ROUND(SUM(unapplied_amount * exchange_rate), 2) AS base_amount`).
+		Where("status = ?", entity.PrepaymentOpen)
+	if entityType != "" {
+		query = query.Where("entity_type = ?", entityType)
+	}
+	if entityID != 0 {
+		query = query.Where("entity_id = ?", entityID)
+	}
+
+	var balances []entity.PrepaymentBalance
+	if err := query.Group("type, entity_type, entity_id, currency_code").
+		Order("type, entity_type, entity_id, currency_code").
+		Scan(&balances).Error; err != nil {
+		return nil, err
+	}
+	return balances, nil
+}
+
+// Cancel cancels a prepayment nothing was applied from
+func (r *PrepaymentRepository) Cancel(ctx context.Context, prepayment *entity.Prepayment) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var current entity.Prepayment
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&current, prepayment.ID).Error; err != nil {
+			return err
+		}
+		if current.AppliedAmount > 0 {
+			return ErrPrepaymentApplied
+		}
+
+		prepayment.Status = entity.PrepaymentCancelled
+		prepayment.UnappliedAmount = 0
+		return tx.Model(&entity.Prepayment{}).Where("id = ?", prepayment.ID).Updates(map[string]interface{}{
+			"status":           prepayment.Status,
+			"unapplied_amount": 0,
+			"updated_at":       time.Now(),
+		}).Error
+	})
+}
+
+// ApplyToSalesInvoiceTx applies the open customer deposits of the sales order
+// of an invoice to it within a transaction, oldest first, lowering its amount
+// due. It returns the amount applied.
+func (r *PrepaymentRepository) ApplyToSalesInvoiceTx(tx *gorm.DB, invoice *entity.Invoice) (float64, error) {
+	applied, err := r.apply(tx, entity.PrepaymentCustomerDeposit, invoice.SalesOrderID, invoice.CurrencyCode,
+		entity.PrepaymentInvoiceSales, invoice.ID, invoice.InvoiceNumber, invoice.AmountDue)
+	if err != nil || applied == 0 {
+		return 0, err
+	}
+
+	invoice.PrepaidAmount = round2(invoice.PrepaidAmount + applied)
+	invoice.AmountDue = round2(invoice.AmountDue - applied)
+	if err := tx.Model(&entity.Invoice{}).Where("id = ?", invoice.ID).Updates(map[string]interface{}{
+		"prepaid_amount": invoice.PrepaidAmount,
+		"amount_due":     invoice.AmountDue,
+	}).Error; err != nil {
+		return 0, err
+	}
+	return applied, nil
+}
+
+// ApplyToFinanceInvoiceTx applies the open prepayments of the order a finance
+// invoice references to it within a transaction, oldest first: customer
+// deposits to sales invoices, vendor advances to purchase invoices. They count
+// as paid, so the invoice is paid once they cover it. It returns the amount
+// applied.
+func (r *PrepaymentRepository) ApplyToFinanceInvoiceTx(tx *gorm.DB, invoice *entity.FinanceInvoice) (float64, error) {
+	prepaymentType := entity.PrepaymentCustomerDeposit
+	if invoice.Type == entity.FinancePurchaseInvoice {
+		prepaymentType = entity.PrepaymentVendorAdvance
+	}
+
+	applied, err := r.apply(tx, prepaymentType, invoice.ReferenceID, invoice.CurrencyCode,
+		entity.PrepaymentInvoiceFinance, strconv.FormatInt(invoice.ID, 10), invoice.InvoiceNumber, invoice.AmountDue)
+	if err != nil || applied == 0 {
+		return 0, err
+	}
+
+	invoice.PrepaidAmount = round2(invoice.PrepaidAmount + applied)
+	invoice.AmountPaid = round2(invoice.AmountPaid + applied)
+	invoice.AmountDue = round2(invoice.Total - invoice.AmountPaid)
+	if invoice.AmountPaid >= invoice.Total {
+		invoice.Status = entity.FinanceInvoicePaid
+	} else {
+		invoice.Status = entity.FinanceInvoicePartiallyPaid
+	}
+	if err := tx.Model(&entity.FinanceInvoice{}).Where("id = ?", invoice.ID).Updates(map[string]interface{}{
+		"prepaid_amount": invoice.PrepaidAmount,
+		"amount_paid":    invoice.AmountPaid,
+		"amount_due":     invoice.AmountDue,
+		"status":         invoice.Status,
+		"updated_at":     time.Now(),
+	}).Error; err != nil {
+		return 0, err
+	}
+	return applied, nil
+}
+
+// apply applies the open prepayments of a type of an order in a currency to
+// an invoice, up to its amount due, recording the applications
+func (r *PrepaymentRepository) apply(tx *gorm.DB, prepaymentType entity.PrepaymentType, orderID, currency, invoiceType, invoiceID, invoiceNumber string, due float64) (float64, error) {
+	if orderID == "" || due <= 0 {
+		return 0, nil
+	}
+
+	var prepayments []entity.Prepayment
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("type = ? AND order_id::text = ? AND currency_code = ? AND status = ?", prepaymentType, orderID, currency, entity.PrepaymentOpen).
+		Order("payment_date, id").
+		Find(&prepayments).Error; err != nil {
+		return 0, err
+	}
+
+	var applied float64
+	for _, prepayment := range prepayments {
+		amount := round2(math.Min(prepayment.UnappliedAmount, due-applied))
+		if amount <= 0 {
+			break
+		}
+
+		if err := tx.Create(&entity.PrepaymentApplication{
+			PrepaymentID:  prepayment.ID,
+			InvoiceType:   invoiceType,
+			InvoiceID:     invoiceID,
+			InvoiceNumber: invoiceNumber,
+			Amount:        amount,
+		}).Error; err != nil {
+			return 0, err
+		}
+		if err := r.setApplied(tx, &prepayment, prepayment.AppliedAmount+amount); err != nil {
+			return 0, err
+		}
+		applied = round2(applied + amount)
+	}
+	return applied, nil
+}
+
+// ReleaseSalesInvoiceTx returns the deposits applied to a cancelled sales
+// invoice to their unapplied balances within a transaction
+func (r *PrepaymentRepository) ReleaseSalesInvoiceTx(tx *gorm.DB, invoiceID string) error {
+	released, err := r.release(tx, entity.PrepaymentInvoiceSales, invoiceID)
+	if err != nil || released == 0 {
+		return err
+	}
+	return tx.Model(&entity.Invoice{}).Where("id = ?", invoiceID).Updates(map[string]interface{}{
+		"prepaid_amount": 0,
+		"amount_due":     gorm.Expr("amount_due + ?", released),
+	}).Error
+}
+
+// ReleaseFinanceInvoiceTx returns the prepayments applied to a cancelled
+// finance invoice to their unapplied balances within a transaction
+func (r *PrepaymentRepository) ReleaseFinanceInvoiceTx(tx *gorm.DB, invoiceID int64) error {
+	released, err := r.release(tx, entity.PrepaymentInvoiceFinance, strconv.FormatInt(invoiceID, 10))
+	if err != nil || released == 0 {
+		return err
+	}
+	return tx.Model(&entity.FinanceInvoice{}).Where("id = ?", invoiceID).Updates(map[string]interface{}{
+		"prepaid_amount": 0,
+		"amount_paid":    gorm.Expr("amount_paid - ?", released),
+		"amount_due":     gorm.Expr("amount_due + ?", released),
+		"updated_at":     time.Now(),
+	}).Error
+}
+
+// release deletes the applications of prepayments to an invoice, restoring
+// their unapplied balances, and returns the amount released
+func (r *PrepaymentRepository) release(tx *gorm.DB, invoiceType, invoiceID string) (float64, error) {
+	var applications []entity.PrepaymentApplication
+	if err := tx.Where("invoice_type = ? AND invoice_id = ?", invoiceType, invoiceID).Find(&applications).Error; err != nil {
+		return 0, err
+	}
+
+	var released float64
+	for _, application := range applications {
+		var prepayment entity.Prepayment
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&prepayment, application.PrepaymentID).Error; err != nil {
+			return 0, err
+		}
+		if err := r.setApplied(tx, &prepayment, prepayment.AppliedAmount-application.Amount); err != nil {
+			return 0, err
+		}
+		if err := tx.Delete(&application).Error; err != nil {
+			return 0, err
+		}
+		released = round2(released + application.Amount)
+	}
+	return released, nil
+}
+
+// setApplied saves the amount applied of a prepayment, with its unapplied
+// balance and status
+func (r *PrepaymentRepository) setApplied(tx *gorm.DB, prepayment *entity.Prepayment, applied float64) error {
+	prepayment.AppliedAmount = round2(applied)
+	prepayment.UnappliedAmount = round2(prepayment.Amount - prepayment.AppliedAmount)
+	prepayment.Status = entity.PrepaymentOpen
+	if prepayment.UnappliedAmount <= 0 {
+		prepayment.Status = entity.PrepaymentApplied
+	}
+	return tx.Model(&entity.Prepayment{}).Where("id = ?", prepayment.ID).Updates(map[string]interface{}{
+		"applied_amount":   prepayment.AppliedAmount,
+		"unapplied_amount": prepayment.UnappliedAmount,
+		"status":           prepayment.Status,
+		"updated_at":       time.Now(),
+	}).Error
+}
+
+// round2 rounds an amount to cents
+func round2(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
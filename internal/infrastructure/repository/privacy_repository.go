@@ -0,0 +1,206 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"gorm.io/gorm"
+)
+
+// redactedValue replaces the personal data recorded in field changes
+const redactedValue = "[redacted]"
+
+// PrivacyRepository gathers and scrubs the personal data of clients across
+// the documents that carry it, live and archived
+type PrivacyRepository struct {
+	db *gorm.DB
+}
+
+func NewPrivacyRepository(db *gorm.DB) *PrivacyRepository {
+	return &PrivacyRepository{db: db}
+}
+
+// findWithArchive retrieves the rows of a table and of its archive table, when
+// there is one, matching a condition
+func findWithArchive[T any](db *gorm.DB, table, condition string, args ...interface{}) ([]T, error) {
+	var rows []T
+	if err := db.Table(table).Where(condition, args...).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	if !db.Migrator().HasTable(archiveTable(table)) {
+		return rows, nil
+	}
+	var archived []T
+	if err := db.Table(archiveTable(table)).Where(condition, args...).Find(&archived).Error; err != nil {
+		return nil, err
+	}
+	return append(rows, archived...), nil
+}
+
+// CollectClientData retrieves a client with every document holding its
+// personal data
+func (r *PrivacyRepository) CollectClientData(ctx context.Context, clientID uint) (*entity.ClientDataExport, error) {
+	db := r.db.WithContext(ctx)
+	var client entity.Client
+	if err := db.Preload("Addresses").Preload("RFM").First(&client, clientID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	data := &entity.ClientDataExport{Client: &client, ExportedAt: time.Now()}
+	var err error
+	if data.SalesOrders, err = findWithArchive[entity.SalesOrder](db, "sales_orders", "client_id = ?", clientID); err != nil {
+		return nil, err
+	}
+	orderIDs := make([]string, len(data.SalesOrders))
+	for i, order := range data.SalesOrders {
+		orderIDs[i] = order.ID
+	}
+	if len(orderIDs) > 0 {
+		if data.DeliveryOrders, err = findWithArchive[entity.DeliveryOrder](db, "delivery_orders", "sales_order_id IN ?", orderIDs); err != nil {
+			return nil, err
+		}
+		if data.Invoices, err = findWithArchive[entity.Invoice](db, "invoices", "sales_order_id IN ?", orderIDs); err != nil {
+			return nil, err
+		}
+	}
+	if data.SalesReturns, err = findWithArchive[entity.SalesReturn](db, "sales_returns", "client_id = ?", clientID); err != nil {
+		return nil, err
+	}
+	if data.FinanceInvoices, err = findWithArchive[entity.FinanceInvoice](db, "finance_invoices", "entity_type = 'CUSTOMER' AND entity_id = ?", clientID); err != nil {
+		return nil, err
+	}
+	if data.FinancePayments, err = findWithArchive[entity.FinancePayment](db, "finance_payments", "entity_type = 'CUSTOMER' AND entity_id = ?", clientID); err != nil {
+		return nil, err
+	}
+	if data.PrivacyRequests, err = r.ListRequests(ctx, clientID); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// AnonymizeClient replaces the personal data of a client and of the documents
+// carrying it, live and archived, and records the request, in a single
+// transaction. Amounts, quantities and references are kept, so the books and
+// stock still add up. Field changes recorded of the scrubbed columns are
+// redacted too.
+func (r *PrivacyRepository) AnonymizeClient(ctx context.Context, clientID uint, placeholders entity.ClientAnonymization, request *entity.ClientPrivacyRequest) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Exec(`UPDATE clients SET name = ?, email = ?, phone_number = '', tax_id = '', contacts = NULL, notes = '',
+			anonymized_at = ?, updated_at = ? WHERE id = ?`,
+			placeholders.Name, placeholders.Email, now, now, clientID).Error; err != nil {
+			return fmt.Errorf("failed to anonymize client: %w", err)
+		}
+		if err := tx.Where("client_id = ?", clientID).Delete(&entity.ClientAddress{}).Error; err != nil {
+			return fmt.Errorf("failed to delete client addresses: %w", err)
+		}
+
+		var orderIDs []string
+		for _, table := range r.withArchive(tx, "sales_orders") {
+			var ids []string
+			if err := tx.Table(table).Where("client_id = ?", clientID).Pluck("id", &ids).Error; err != nil {
+				return err
+			}
+			orderIDs = append(orderIDs, ids...)
+			if err := tx.Exec(fmt.Sprintf("UPDATE %q SET shipping_address = '', billing_address = '', notes = '' WHERE client_id = ?", table), clientID).Error; err != nil {
+				return fmt.Errorf("failed to scrub %s: %w", table, err)
+			}
+		}
+		if len(orderIDs) > 0 {
+			for _, table := range r.withArchive(tx, "delivery_orders") {
+				if err := tx.Exec(fmt.Sprintf("UPDATE %q SET shipping_address = '', notes = '' WHERE sales_order_id IN ?", table), orderIDs).Error; err != nil {
+					return fmt.Errorf("failed to scrub %s: %w", table, err)
+				}
+			}
+			for _, table := range r.withArchive(tx, "invoices") {
+				if err := tx.Exec(fmt.Sprintf("UPDATE %q SET notes = '' WHERE sales_order_id IN ?", table), orderIDs).Error; err != nil {
+					return fmt.Errorf("failed to scrub %s: %w", table, err)
+				}
+			}
+		}
+		if err := tx.Exec("UPDATE sales_returns SET notes = '' WHERE client_id = ?", clientID).Error; err != nil {
+			return fmt.Errorf("failed to scrub sales returns: %w", err)
+		}
+
+		var financeInvoiceIDs []string
+		for _, table := range r.withArchive(tx, "finance_invoices") {
+			var ids []string
+			if err := tx.Table(table).Where("entity_type = 'CUSTOMER' AND entity_id = ?", clientID).Pluck("CAST(id AS TEXT)", &ids).Error; err != nil {
+				return err
+			}
+			financeInvoiceIDs = append(financeInvoiceIDs, ids...)
+		}
+		for _, table := range r.withArchive(tx, "finance_invoices", "finance_payments") {
+			if err := tx.Exec(fmt.Sprintf("UPDATE %q SET entity_name = ?, notes = '' WHERE entity_type = 'CUSTOMER' AND entity_id = ?", table),
+				placeholders.Name, clientID).Error; err != nil {
+				return fmt.Errorf("failed to scrub %s: %w", table, err)
+			}
+		}
+
+		// Field changes keep the values columns had before an update
+		redact := func(entityType string, ids []string, fields ...string) error {
+			if len(ids) == 0 {
+				return nil
+			}
+			return tx.Model(&entity.FieldChange{}).
+				Where("entity_type = ? AND entity_id IN ? AND field IN ?", entityType, ids, fields).
+				Updates(map[string]interface{}{
+					"old_value": gorm.Expr("CASE WHEN old_value IS NULL THEN NULL ELSE ? END", redactedValue),
+					"new_value": gorm.Expr("CASE WHEN new_value IS NULL THEN NULL ELSE ? END", redactedValue),
+				}).Error
+		}
+		var invoiceIDs []string
+		if len(orderIDs) > 0 {
+			for _, table := range r.withArchive(tx, "invoices") {
+				var ids []string
+				if err := tx.Table(table).Where("sales_order_id IN ?", orderIDs).Pluck("id", &ids).Error; err != nil {
+					return err
+				}
+				invoiceIDs = append(invoiceIDs, ids...)
+			}
+		}
+		if err := redact(entity.ChangeEntitySalesOrder, orderIDs, "shipping_address", "billing_address", "notes"); err != nil {
+			return fmt.Errorf("failed to redact field changes: %w", err)
+		}
+		if err := redact(entity.ChangeEntityInvoice, invoiceIDs, "notes"); err != nil {
+			return fmt.Errorf("failed to redact field changes: %w", err)
+		}
+		if err := redact(entity.ChangeEntityFinanceInvoice, financeInvoiceIDs, "entity_name", "notes"); err != nil {
+			return fmt.Errorf("failed to redact field changes: %w", err)
+		}
+
+		return tx.Create(request).Error
+	})
+}
+
+// withArchive returns tables followed by those of their archive tables that
+// exist
+func (r *PrivacyRepository) withArchive(db *gorm.DB, tables ...string) []string {
+	result := append([]string(nil), tables...)
+	for _, table := range tables {
+		if db.Migrator().HasTable(archiveTable(table)) {
+			result = append(result, archiveTable(table))
+		}
+	}
+	return result
+}
+
+// CreateRequest records a privacy request
+func (r *PrivacyRepository) CreateRequest(ctx context.Context, request *entity.ClientPrivacyRequest) error {
+	return r.db.WithContext(ctx).Create(request).Error
+}
+
+// ListRequests retrieves the privacy requests of a client, latest first
+func (r *PrivacyRepository) ListRequests(ctx context.Context, clientID uint) ([]entity.ClientPrivacyRequest, error) {
+	var requests []entity.ClientPrivacyRequest
+	err := r.db.WithContext(ctx).
+		Where("client_id = ?", clientID).
+		Order("created_at DESC, id DESC").
+		Find(&requests).Error
+	return requests, err
+}
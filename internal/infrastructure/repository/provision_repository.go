@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"gorm.io/gorm"
+)
+
+// ProvisionRepository handles database operations for inventory write-down rules and the provision ledger
+type ProvisionRepository struct {
+	db *gorm.DB
+}
+
+// NewProvisionRepository creates a new provision repository
+func NewProvisionRepository(db *gorm.DB) *ProvisionRepository {
+	return &ProvisionRepository{db: db}
+}
+
+// CreateRule creates a new write-down rule
+func (r *ProvisionRepository) CreateRule(ctx context.Context, rule *entity.WriteDownRule) error {
+	return r.db.WithContext(ctx).Create(rule).Error
+}
+
+// GetRule retrieves a write-down rule by ID
+func (r *ProvisionRepository) GetRule(ctx context.Context, id uint) (*entity.WriteDownRule, error) {
+	var rule entity.WriteDownRule
+	if err := r.db.WithContext(ctx).First(&rule, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// UpdateRule updates an existing write-down rule
+func (r *ProvisionRepository) UpdateRule(ctx context.Context, rule *entity.WriteDownRule) error {
+	return r.db.WithContext(ctx).Save(rule).Error
+}
+
+// DeleteRule deletes a write-down rule
+func (r *ProvisionRepository) DeleteRule(ctx context.Context, id uint) error {
+	result := r.db.WithContext(ctx).Delete(&entity.WriteDownRule{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// ListRules retrieves write-down rules ordered by age threshold, longest first
+func (r *ProvisionRepository) ListRules(ctx context.Context, activeOnly bool) ([]entity.WriteDownRule, error) {
+	var rules []entity.WriteDownRule
+	query := r.db.WithContext(ctx)
+	if activeOnly {
+		query = query.Where("active = ?", true)
+	}
+	if err := query.Order("min_age_days DESC").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// ListProvisionStocks retrieves the stocks that are on hand or still hold a provision, with their SKU
+func (r *ProvisionRepository) ListProvisionStocks(ctx context.Context) ([]entity.Stock, error) {
+	var stocks []entity.Stock
+	held := r.db.Model(&entity.InventoryProvisionEntry{}).
+		Select("stock_id").
+		Group("stock_id").
+		Having("SUM(amount) <> 0")
+
+	if err := r.db.WithContext(ctx).
+		Where("quantity > 0 OR id IN (?)", held).
+		Preload("SKU").
+		Find(&stocks).Error; err != nil {
+		return nil, err
+	}
+	return stocks, nil
+}
+
+// GetHeldProvisions returns the provision currently held per stock
+func (r *ProvisionRepository) GetHeldProvisions(ctx context.Context) (map[string]float64, error) {
+	var rows []struct {
+		StockID string
+		Held    float64
+	}
+	if err := r.db.WithContext(ctx).
+		Model(&entity.InventoryProvisionEntry{}).
+		Select("stock_id, SUM(amount) AS held").
+		Group("stock_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	held := make(map[string]float64, len(rows))
+	for _, row := range rows {
+		held[row.StockID] = row.Held
+	}
+	return held, nil
+}
+
+// ListReceiptLayers retrieves, per stock on hand, the most recent IN entries up to
+// asOf that together cover its quantity, newest first. Under first-in first-out
+// these are the receipts the remaining stock came from.
+func (r *ProvisionRepository) ListReceiptLayers(ctx context.Context, asOf time.Time) ([]entity.StockEntry, error) {
+	var layers []entity.StockEntry
+	err := r.db.WithContext(ctx).Raw(`
+		SELECT sku_id, store_id, quantity, created_at
+		FROM (
+			SELECT
+				e.sku_id,
+				e.store_id,
+				e.quantity,
+				e.created_at,
+				s.quantity AS on_hand,
+				SUM(e.quantity) OVER (
+					PARTITION BY e.sku_id, e.store_id
+					ORDER BY e.created_at DESC, e.id
+				) - e.quantity AS covered_before
+			FROM stock_entries e
+			JOIN stocks s ON s.sku_id = e.sku_id AND s.store_id = e.store_id
+			WHERE e.type = 'IN' AND e.created_at <= ? AND s.quantity > 0
+		) layers
+		WHERE covered_before < on_hand
+		ORDER BY sku_id, store_id, created_at DESC
+	`, asOf).Scan(&layers).Error
+	if err != nil {
+		return nil, err
+	}
+	return layers, nil
+}
+
+// CreateEntries posts provision ledger entries in a single transaction
+func (r *ProvisionRepository) CreateEntries(ctx context.Context, entries []entity.InventoryProvisionEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return tx.Create(&entries).Error
+	})
+}
+
+// HasEntries reports whether any provision entry has been posted for the period
+func (r *ProvisionRepository) HasEntries(ctx context.Context, period string) (bool, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).
+		Model(&entity.InventoryProvisionEntry{}).
+		Where("period = ?", period).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// ListEntries retrieves provision ledger entries with filters, newest first
+func (r *ProvisionRepository) ListEntries(ctx context.Context, filter *entity.InventoryProvisionFilter) ([]entity.InventoryProvisionEntry, int64, error) {
+	var entries []entity.InventoryProvisionEntry
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&entity.InventoryProvisionEntry{})
+	if filter.Period != "" {
+		query = query.Where("period = ?", filter.Period)
+	}
+	if filter.StockID != "" {
+		query = query.Where("stock_id = ?", filter.StockID)
+	}
+	if filter.Type != "" {
+		query = query.Where("type = ?", filter.Type)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (filter.Page - 1) * filter.PageSize
+	if err := query.Order("posted_at DESC, id DESC").Limit(filter.PageSize).Offset(offset).Find(&entries).Error; err != nil {
+		return nil, 0, err
+	}
+	return entries, total, nil
+}
@@ -3,7 +3,6 @@ package repository
 import (
 	"context"
 	"fmt"
-	"time"
 
 	"github.com/google/uuid"
 	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
@@ -11,11 +10,15 @@ import (
 )
 
 type PurchaseRepository struct {
-	db *gorm.DB
+	db                *gorm.DB
+	sequenceGenerator *SequenceGenerator
 }
 
 func NewPurchaseRepository(db *gorm.DB) *PurchaseRepository {
-	return &PurchaseRepository{db: db}
+	return &PurchaseRepository{
+		db:                db,
+		sequenceGenerator: NewSequenceGenerator(db),
+	}
 }
 
 // Purchase Request methods
@@ -26,7 +29,11 @@ func (r *PurchaseRepository) CreatePurchaseRequest(ctx context.Context, request
 		request.ID = uuid.New().String()
 	}
 	if request.RequestNumber == "" {
-		request.RequestNumber = fmt.Sprintf("PR-%s-%d", time.Now().Format("20060102"), time.Now().UnixNano()%1000)
+		number, err := r.sequenceGenerator.NextNumber(ctx, entity.DocumentPurchaseRequest, "")
+		if err != nil {
+			return err
+		}
+		request.RequestNumber = number
 	}
 	return r.db.WithContext(ctx).Create(request).Error
 }
@@ -62,7 +69,7 @@ func (r *PurchaseRepository) ListPurchaseRequests(ctx context.Context, filter *e
 	var requests []entity.PurchaseRequest
 	var total int64
 
-	query := r.db.WithContext(ctx).Model(&entity.PurchaseRequest{})
+	query := scopeDepartments(ctx, r.db.WithContext(ctx).Model(&entity.PurchaseRequest{}), "department_id")
 
 	if filter != nil {
 		if filter.RequestNumber != "" {
@@ -108,7 +115,11 @@ func (r *PurchaseRepository) CreatePurchaseOrder(ctx context.Context, order *ent
 		order.ID = uuid.New().String()
 	}
 	if order.OrderNumber == "" {
-		order.OrderNumber = fmt.Sprintf("PO-%s-%d", time.Now().Format("20060102"), time.Now().UnixNano()%1000)
+		number, err := r.sequenceGenerator.NextNumber(ctx, entity.DocumentPurchaseOrder, "")
+		if err != nil {
+			return err
+		}
+		order.OrderNumber = number
 	}
 	return r.db.WithContext(ctx).Create(order).Error
 }
@@ -130,6 +141,20 @@ func (r *PurchaseRepository) GetPurchaseOrderByID(ctx context.Context, id string
 	return &order, nil
 }
 
+// GetPurchaseOrderByNumber retrieves a purchase order by its order number
+func (r *PurchaseRepository) GetPurchaseOrderByNumber(ctx context.Context, orderNumber string) (*entity.PurchaseOrder, error) {
+	var order entity.PurchaseOrder
+	if err := r.db.WithContext(ctx).
+		Preload("Vendor").
+		First(&order, "order_number = ?", orderNumber).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &order, nil
+}
+
 // UpdatePurchaseOrder updates an existing purchase order
 func (r *PurchaseRepository) UpdatePurchaseOrder(ctx context.Context, order *entity.PurchaseOrder) error {
 	return r.db.WithContext(ctx).Save(order).Error
@@ -148,6 +173,9 @@ func (r *PurchaseRepository) ListPurchaseOrders(ctx context.Context, filter *ent
 	query := r.db.WithContext(ctx).Model(&entity.PurchaseOrder{})
 
 	if filter != nil {
+		if filter.Archived {
+			query = query.Table(archiveTable("purchase_orders"))
+		}
 		if filter.OrderNumber != "" {
 			query = query.Where("order_number LIKE ?", "%"+filter.OrderNumber+"%")
 		}
@@ -186,6 +214,56 @@ func (r *PurchaseRepository) ListPurchaseOrders(ctx context.Context, filter *ent
 	return orders, total, nil
 }
 
+// ListPurchaseOrdersBySalesOrderID retrieves the drop-ship orders of a sales order
+func (r *PurchaseRepository) ListPurchaseOrdersBySalesOrderID(ctx context.Context, salesOrderID string) ([]entity.PurchaseOrder, error) {
+	var orders []entity.PurchaseOrder
+	err := r.db.WithContext(ctx).
+		Preload("Vendor").
+		Where("sales_order_id = ?", salesOrderID).
+		Order("created_at").
+		Find(&orders).Error
+	return orders, err
+}
+
+// GetLastPurchasePrice returns the unit price a SKU was last ordered at, on
+// purchase orders past draft and not cancelled, from the given vendor unless
+// vendorID is 0, leaving out the given order. It returns ErrRecordNotFound
+// when the SKU was never ordered.
+func (r *PurchaseRepository) GetLastPurchasePrice(ctx context.Context, skuID string, vendorID uint, excludeOrderID string) (*entity.LastPurchasePrice, error) {
+	query := r.db.WithContext(ctx).
+		Where("items @> ?", fmt.Sprintf(`[{"sku_id": "%s"}]`, skuID)).
+		Where("status NOT IN ?", []entity.PurchaseOrderStatus{entity.PurchaseOrderStatusDraft, entity.PurchaseOrderStatusCancelled})
+	if vendorID != 0 {
+		query = query.Where("vendor_id = ?", vendorID)
+	}
+	if excludeOrderID != "" {
+		query = query.Where("id <> ?", excludeOrderID)
+	}
+
+	var order entity.PurchaseOrder
+	if err := query.Order("order_date DESC, created_at DESC").First(&order).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	for _, item := range order.Items {
+		if item.SKUID == skuID {
+			rate := order.ExchangeRate
+			if rate <= 0 {
+				rate = 1
+			}
+			return &entity.LastPurchasePrice{
+				PurchaseOrderID: order.ID,
+				OrderDate:       order.OrderDate,
+				UnitPrice:       item.UnitPrice,
+				BaseUnitPrice:   item.UnitPrice * rate,
+			}, nil
+		}
+	}
+	return nil, ErrRecordNotFound
+}
+
 // Purchase Receipt methods
 
 // CreatePurchaseReceipt creates a new purchase receipt
@@ -194,7 +272,11 @@ func (r *PurchaseRepository) CreatePurchaseReceipt(ctx context.Context, receipt
 		receipt.ID = uuid.New().String()
 	}
 	if receipt.ReceiptNumber == "" {
-		receipt.ReceiptNumber = fmt.Sprintf("GRN-%s-%d", time.Now().Format("20060102"), time.Now().UnixNano()%1000)
+		number, err := r.sequenceGenerator.NextNumber(ctx, entity.DocumentPurchaseReceipt, receipt.StoreID)
+		if err != nil {
+			return err
+		}
+		receipt.ReceiptNumber = number
 	}
 
 	tx := r.db.WithContext(ctx).Begin()
@@ -286,7 +368,11 @@ func (r *PurchaseRepository) CreatePurchasePayment(ctx context.Context, payment
 		payment.ID = uuid.New().String()
 	}
 	if payment.PaymentNumber == "" {
-		payment.PaymentNumber = fmt.Sprintf("PAY-%s-%d", time.Now().Format("20060102"), time.Now().UnixNano()%1000)
+		number, err := r.sequenceGenerator.NextNumber(ctx, entity.DocumentPurchasePayment, "")
+		if err != nil {
+			return err
+		}
+		payment.PaymentNumber = number
 	}
 
 	tx := r.db.WithContext(ctx).Begin()
@@ -379,11 +465,96 @@ func (r *PurchaseRepository) GetTotalPaymentsByOrderID(ctx context.Context, orde
 
 // LinkPurchaseRequestToOrder links a purchase request to a purchase order
 func (r *PurchaseRepository) LinkPurchaseRequestToOrder(ctx context.Context, requestID string, orderID string) error {
-	return r.db.WithContext(ctx).
-		Model(&entity.PurchaseRequest{}).
-		Where("id = ?", requestID).
-		Updates(map[string]interface{}{
-			"purchase_order_id": orderID,
-			"status":            entity.PurchaseRequestStatusOrdered,
-		}).Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&entity.PurchaseRequest{}).
+			Where("id = ?", requestID).
+			Updates(map[string]interface{}{
+				"purchase_order_id": orderID,
+				"status":            entity.PurchaseRequestStatusOrdered,
+			}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&entity.PurchaseOrderRequest{PurchaseOrderID: orderID, PurchaseRequestID: requestID}).Error
+	})
+}
+
+// CreateConsolidatedPurchaseOrders creates purchase orders generated from
+// purchase requests in one transaction, linking each order to the requests
+// its lines list as sources. The requests are marked ordered, pointing to
+// their order when all their lines went to one. It returns ErrInvalidData,
+// creating nothing, when a request is no longer approved or already ordered.
+func (r *PurchaseRepository) CreateConsolidatedPurchaseOrders(ctx context.Context, orders []*entity.PurchaseOrder, requestIDs []string) error {
+	for _, order := range orders {
+		if order.ID == "" {
+			order.ID = uuid.New().String()
+		}
+		if order.OrderNumber == "" {
+			number, err := r.sequenceGenerator.NextNumber(ctx, entity.DocumentPurchaseOrder, "")
+			if err != nil {
+				return err
+			}
+			order.OrderNumber = number
+		}
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&entity.PurchaseRequest{}).
+			Where("id IN ? AND status = ? AND purchase_order_id IS NULL", requestIDs, entity.PurchaseRequestStatusApproved).
+			Update("status", entity.PurchaseRequestStatusOrdered)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != int64(len(requestIDs)) {
+			return ErrInvalidData
+		}
+
+		orderIDs := make(map[string][]string)
+		for _, order := range orders {
+			if err := tx.Create(order).Error; err != nil {
+				return err
+			}
+			linked := make(map[string]bool)
+			for _, item := range order.Items {
+				for _, source := range item.Sources {
+					if linked[source.PurchaseRequestID] {
+						continue
+					}
+					linked[source.PurchaseRequestID] = true
+					orderIDs[source.PurchaseRequestID] = append(orderIDs[source.PurchaseRequestID], order.ID)
+					if err := tx.Create(&entity.PurchaseOrderRequest{
+						PurchaseOrderID:   order.ID,
+						PurchaseRequestID: source.PurchaseRequestID,
+					}).Error; err != nil {
+						return err
+					}
+				}
+			}
+		}
+
+		for requestID, ids := range orderIDs {
+			if len(ids) != 1 {
+				continue
+			}
+			if err := tx.Model(&entity.PurchaseRequest{}).
+				Where("id = ?", requestID).
+				Update("purchase_order_id", ids[0]).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ListPurchaseOrdersByRequestID retrieves the purchase orders generated from
+// a purchase request
+func (r *PurchaseRepository) ListPurchaseOrdersByRequestID(ctx context.Context, requestID string) ([]entity.PurchaseOrder, error) {
+	var orders []entity.PurchaseOrder
+	err := r.db.WithContext(ctx).
+		Preload("Vendor").
+		Where("id IN (?)", r.db.Model(&entity.PurchaseOrderRequest{}).
+			Select("purchase_order_id").
+			Where("purchase_request_id = ?", requestID)).
+		Order("created_at").
+		Find(&orders).Error
+	return orders, err
 }
@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"gorm.io/gorm"
+)
+
+// testPurchases holds a vendor with n approved purchase orders, the first of
+// which has n receipts
+type testPurchases struct {
+	VendorID     uint
+	FirstOrderID string
+}
+
+func createTestPurchases(tb testing.TB, db *gorm.DB, fixture *testFixture, n int) *testPurchases {
+	tb.Helper()
+	prefix := fixture.StoreID[:8]
+	now := time.Now()
+
+	vendor := &entity.Vendor{Code: "V-" + prefix, Name: "Test vendor " + prefix}
+	if err := db.Create(vendor).Error; err != nil {
+		tb.Fatalf("creating vendor: %v", err)
+	}
+
+	orders := make([]entity.PurchaseOrder, 0, n)
+	for i := 0; i < n; i++ {
+		orders = append(orders, entity.PurchaseOrder{
+			OrderNumber:  fmt.Sprintf("PO-%s-%05d", prefix, i),
+			VendorID:     vendor.ID,
+			OrderDate:    now,
+			Items:        entity.PurchaseOrderItems{},
+			CreatedByID:  fixture.UserID,
+			ApprovedByID: &fixture.UserID,
+		})
+	}
+	if err := db.Omit("Vendor", "CreatedBy", "ApprovedBy", "PurchaseRequests").CreateInBatches(&orders, 500).Error; err != nil {
+		tb.Fatalf("creating purchase orders: %v", err)
+	}
+
+	receipts := make([]entity.PurchaseReceipt, 0, n)
+	for i := 0; i < n; i++ {
+		receipts = append(receipts, entity.PurchaseReceipt{
+			ReceiptNumber:   fmt.Sprintf("GR-%s-%05d", prefix, i),
+			PurchaseOrderID: orders[0].ID,
+			ReceiptDate:     now,
+			Items:           entity.PurchaseReceiptItems{},
+			StoreID:         fixture.StoreID,
+			ReceivedByID:    fixture.UserID,
+		})
+	}
+	if err := db.Omit("PurchaseOrder", "ReceivedBy").CreateInBatches(&receipts, 500).Error; err != nil {
+		tb.Fatalf("creating purchase receipts: %v", err)
+	}
+	return &testPurchases{VendorID: vendor.ID, FirstOrderID: orders[0].ID}
+}
+
+// purchaseListQueries lists the purchase orders of a vendor, a page of up to
+// 1000, and the receipts of its first order, returning the rows of each list
+var purchaseListQueries = []struct {
+	name string
+	list func(ctx context.Context, repo *PurchaseRepository, purchases *testPurchases) (int, error)
+}{
+	{"ListPurchaseOrders", func(ctx context.Context, repo *PurchaseRepository, purchases *testPurchases) (int, error) {
+		rows, _, err := repo.ListPurchaseOrders(ctx, &entity.PurchaseOrderFilter{VendorID: &purchases.VendorID}, 1, 1000)
+		return len(rows), err
+	}},
+	{"ListPurchaseReceiptsByOrderID", func(ctx context.Context, repo *PurchaseRepository, purchases *testPurchases) (int, error) {
+		rows, err := repo.ListPurchaseReceiptsByOrderID(ctx, purchases.FirstOrderID)
+		return len(rows), err
+	}},
+}
+
+// The lists load no relations per row, and preload the ones they return in
+// one query per relation, so the number of queries does not grow with the
+// rows listed
+func TestPurchaseListsPreloadInBatches(t *testing.T) {
+	db := openTestDB(t)
+	smallFixture := createTestFixture(t, db, 0)
+	largeFixture := createTestFixture(t, db, 0)
+	small := createTestPurchases(t, db, smallFixture, 10)
+	large := createTestPurchases(t, db, largeFixture, 1000)
+
+	counted, counter := countQueries(t, db)
+	repo := NewPurchaseRepository(counted)
+	ctx := context.Background()
+
+	for _, tt := range purchaseListQueries {
+		t.Run(tt.name, func(t *testing.T) {
+			counter.Count()
+			if rows, err := tt.list(ctx, repo, small); err != nil || rows != 10 {
+				t.Fatalf("listing 10 rows: got %d rows, error %v", rows, err)
+			}
+			smallQueries := counter.Count()
+
+			if rows, err := tt.list(ctx, repo, large); err != nil || rows != 1000 {
+				t.Fatalf("listing 1000 rows: got %d rows, error %v", rows, err)
+			}
+			if largeQueries := counter.Count(); largeQueries != smallQueries {
+				t.Errorf("listing 1000 rows ran %d queries, 10 rows %d", largeQueries, smallQueries)
+			}
+		})
+	}
+}
+
+func BenchmarkPurchaseLists(b *testing.B) {
+	db := openTestDB(b)
+	fixture := createTestFixture(b, db, 0)
+	purchases := createTestPurchases(b, db, fixture, 1000)
+
+	counted, counter := countQueries(b, db)
+	repo := NewPurchaseRepository(counted)
+	ctx := context.Background()
+
+	for _, tt := range purchaseListQueries {
+		b.Run(tt.name, func(b *testing.B) {
+			counter.Count()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := tt.list(ctx, repo, purchases); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(counter.Count())/float64(b.N), "queries/op")
+		})
+	}
+}
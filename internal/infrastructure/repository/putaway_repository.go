@@ -0,0 +1,176 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"gorm.io/gorm"
+)
+
+var ErrPutawayTaskConfirmed = entity.NewError(entity.ErrCodeConflict, "putaway task is already confirmed")
+
+// PutawayRepository handles database operations for putaway rules and tasks
+type PutawayRepository struct {
+	db *gorm.DB
+}
+
+func NewPutawayRepository(db *gorm.DB) *PutawayRepository {
+	return &PutawayRepository{db: db}
+}
+
+// CreateRule creates a putaway rule
+func (r *PutawayRepository) CreateRule(ctx context.Context, rule *entity.PutawayRule) error {
+	return r.db.WithContext(ctx).Create(rule).Error
+}
+
+// UpdateRule saves a putaway rule
+func (r *PutawayRepository) UpdateRule(ctx context.Context, rule *entity.PutawayRule) error {
+	return r.db.WithContext(ctx).Save(rule).Error
+}
+
+// DeleteRule deletes a putaway rule. Tasks it suggested keep their suggestion.
+func (r *PutawayRepository) DeleteRule(ctx context.Context, id uint) error {
+	result := r.db.WithContext(ctx).Delete(&entity.PutawayRule{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// GetRule retrieves a putaway rule by ID
+func (r *PutawayRepository) GetRule(ctx context.Context, id uint) (*entity.PutawayRule, error) {
+	var rule entity.PutawayRule
+	if err := r.db.WithContext(ctx).First(&rule, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// ListRules retrieves the putaway rules of the stores in the access scope, or
+// of one store, in the order they are tried
+func (r *PutawayRepository) ListRules(ctx context.Context, storeID string, activeOnly bool) ([]entity.PutawayRule, error) {
+	var rules []entity.PutawayRule
+	query := scopeStores(ctx, r.db.WithContext(ctx), "store_id")
+	if storeID != "" {
+		query = query.Where("store_id = ?", storeID)
+	}
+	if activeOnly {
+		query = query.Where("active = ?", true)
+	}
+	if err := query.Order("store_id, priority, id").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// IssuedQuantities sums per SKU the units a store issued since a time
+func (r *PutawayRepository) IssuedQuantities(ctx context.Context, storeID string, since time.Time) (map[string]float64, error) {
+	var rows []struct {
+		SKUID    string
+		Quantity float64
+	}
+	if err := r.db.WithContext(ctx).Model(&entity.StockEntry{}).
+		Select("sku_id, SUM(quantity) AS quantity").
+		Where("store_id = ? AND type = ? AND created_at >= ?", storeID, "OUT", since).
+		Group("sku_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	issued := make(map[string]float64, len(rows))
+	for _, row := range rows {
+		issued[row.SKUID] = row.Quantity
+	}
+	return issued, nil
+}
+
+// HasTasks reports whether putaway tasks were generated for a receipt
+func (r *PutawayRepository) HasTasks(ctx context.Context, receiptID string) (bool, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&entity.PutawayTask{}).
+		Where("receipt_id = ?", receiptID).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// CreateTasks creates putaway tasks in a single statement
+func (r *PutawayRepository) CreateTasks(ctx context.Context, tasks []entity.PutawayTask) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Create(&tasks).Error
+}
+
+// GetTask retrieves a putaway task by ID with its SKU
+func (r *PutawayRepository) GetTask(ctx context.Context, id uint) (*entity.PutawayTask, error) {
+	var task entity.PutawayTask
+	if err := r.db.WithContext(ctx).Preload("SKU").First(&task, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &task, nil
+}
+
+// ListTasks retrieves the putaway tasks matching a filter, oldest first
+func (r *PutawayRepository) ListTasks(ctx context.Context, filter *entity.PutawayTaskFilter) ([]entity.PutawayTask, error) {
+	var tasks []entity.PutawayTask
+	query := scopeStores(ctx, r.db.WithContext(ctx), "store_id")
+	if filter.StoreID != "" {
+		query = query.Where("store_id = ?", filter.StoreID)
+	}
+	if filter.ReceiptID != "" {
+		query = query.Where("receipt_id = ?", filter.ReceiptID)
+	}
+	if filter.SKUID != "" {
+		query = query.Where("sku_id = ?", filter.SKUID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if err := query.Preload("SKU").Order("created_at, id").Find(&tasks).Error; err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// ConfirmTask marks a pending putaway task confirmed at its location and moves
+// the stock of its SKU in the store there, in a single transaction. It fails
+// with ErrPutawayTaskConfirmed when the task was confirmed meanwhile.
+func (r *PutawayRepository) ConfirmTask(ctx context.Context, task *entity.PutawayTask) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&entity.PutawayTask{}).
+			Where("id = ? AND status = ?", task.ID, entity.PutawayTaskPending).
+			Updates(map[string]interface{}{
+				"zone_code":    task.ZoneCode,
+				"shelf_number": task.ShelfNumber,
+				"bin_location": task.BinLocation,
+				"status":       task.Status,
+				"confirmed_by": task.ConfirmedBy,
+				"confirmed_at": task.ConfirmedAt,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrPutawayTaskConfirmed
+		}
+
+		return tx.Model(&entity.Stock{}).
+			Where("sku_id = ? AND store_id = ?", task.SKUID, task.StoreID).
+			Updates(map[string]interface{}{
+				"zone_code":    task.ZoneCode,
+				"shelf_number": task.ShelfNumber,
+				"bin_location": task.BinLocation,
+			}).Error
+	})
+}
@@ -0,0 +1,224 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"gorm.io/gorm"
+)
+
+// QualityRepository handles database operations for inspection plans, defect
+// codes and quality inspections
+type QualityRepository struct {
+	db         *gorm.DB
+	stocksRepo *StocksRepository
+}
+
+// NewQualityRepository creates a new quality repository
+func NewQualityRepository(db *gorm.DB, stocksRepo *StocksRepository) *QualityRepository {
+	return &QualityRepository{db: db, stocksRepo: stocksRepo}
+}
+
+// CreatePlan creates an inspection plan. A SKU has at most one plan.
+func (r *QualityRepository) CreatePlan(ctx context.Context, plan *entity.InspectionPlan) error {
+	if err := r.checkPlanSKU(ctx, plan); err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Create(plan).Error
+}
+
+// UpdatePlan saves an inspection plan
+func (r *QualityRepository) UpdatePlan(ctx context.Context, plan *entity.InspectionPlan) error {
+	if err := r.checkPlanSKU(ctx, plan); err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Save(plan).Error
+}
+
+// checkPlanSKU rejects a plan for a SKU that already has another plan
+func (r *QualityRepository) checkPlanSKU(ctx context.Context, plan *entity.InspectionPlan) error {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&entity.InspectionPlan{}).
+		Where("sku_id = ? AND id <> ?", plan.SKUID, plan.ID).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrDuplicateEntry
+	}
+	return nil
+}
+
+// GetPlan retrieves an inspection plan by ID
+func (r *QualityRepository) GetPlan(ctx context.Context, id uint) (*entity.InspectionPlan, error) {
+	var plan entity.InspectionPlan
+	if err := r.db.WithContext(ctx).First(&plan, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &plan, nil
+}
+
+// ListPlans retrieves inspection plans ordered by SKU
+func (r *QualityRepository) ListPlans(ctx context.Context, skuID string) ([]entity.InspectionPlan, error) {
+	var plans []entity.InspectionPlan
+	query := r.db.WithContext(ctx)
+	if skuID != "" {
+		query = query.Where("sku_id = ?", skuID)
+	}
+	if err := query.Order("sku_id").Find(&plans).Error; err != nil {
+		return nil, err
+	}
+	return plans, nil
+}
+
+// FindActivePlans retrieves the active inspection plans of the given SKUs, keyed by SKU ID
+func (r *QualityRepository) FindActivePlans(ctx context.Context, skuIDs []string) (map[string]entity.InspectionPlan, error) {
+	plans := make(map[string]entity.InspectionPlan)
+	if len(skuIDs) == 0 {
+		return plans, nil
+	}
+
+	var found []entity.InspectionPlan
+	if err := r.db.WithContext(ctx).
+		Where("sku_id IN ? AND active = ?", skuIDs, true).
+		Find(&found).Error; err != nil {
+		return nil, err
+	}
+	for _, plan := range found {
+		plans[plan.SKUID] = plan
+	}
+	return plans, nil
+}
+
+// CreateDefectCode creates a defect code
+func (r *QualityRepository) CreateDefectCode(ctx context.Context, code *entity.DefectCode) error {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&entity.DefectCode{}).
+		Where("code = ?", code.Code).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrDuplicateEntry
+	}
+	return r.db.WithContext(ctx).Create(code).Error
+}
+
+// ListDefectCodes retrieves defect codes ordered by code
+func (r *QualityRepository) ListDefectCodes(ctx context.Context, activeOnly bool) ([]entity.DefectCode, error) {
+	var codes []entity.DefectCode
+	query := r.db.WithContext(ctx)
+	if activeOnly {
+		query = query.Where("active = ?", true)
+	}
+	if err := query.Order("code").Find(&codes).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// HoldForInspection creates pending inspections and quarantines their
+// quantities in the stock they were received into, in a single transaction
+func (r *QualityRepository) HoldForInspection(ctx context.Context, inspections []entity.QualityInspection) error {
+	if len(inspections) == 0 {
+		return nil
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&inspections).Error; err != nil {
+			return err
+		}
+		for _, inspection := range inspections {
+			if err := tx.Model(&entity.Stock{}).
+				Where("sku_id = ? AND store_id = ?", inspection.SKUID, inspection.StoreID).
+				Update("quarantined_quantity", gorm.Expr("quarantined_quantity + ?", inspection.Quantity)).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetInspection retrieves a quality inspection by ID
+func (r *QualityRepository) GetInspection(ctx context.Context, id uint) (*entity.QualityInspection, error) {
+	var inspection entity.QualityInspection
+	if err := r.db.WithContext(ctx).First(&inspection, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &inspection, nil
+}
+
+// ListInspections retrieves quality inspections with filters, oldest pending first
+func (r *QualityRepository) ListInspections(ctx context.Context, filter *entity.QualityInspectionFilter) ([]entity.QualityInspection, int64, error) {
+	var inspections []entity.QualityInspection
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&entity.QualityInspection{})
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Source != "" {
+		query = query.Where("source = ?", filter.Source)
+	}
+	if filter.SKUID != "" {
+		query = query.Where("sku_id = ?", filter.SKUID)
+	}
+	if filter.StoreID != "" {
+		query = query.Where("store_id = ?", filter.StoreID)
+	}
+	if filter.Reference != "" {
+		query = query.Where("reference = ?", filter.Reference)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (filter.Page - 1) * filter.PageSize
+	if err := query.Order("created_at, id").Limit(filter.PageSize).Offset(offset).Find(&inspections).Error; err != nil {
+		return nil, 0, err
+	}
+	return inspections, total, nil
+}
+
+// RecordResult closes a pending inspection: its quantity is released from
+// quarantine and the rejected units are written off with the given stock
+// entries, in a single transaction. It returns ErrInvalidData when the
+// inspection is no longer pending.
+func (r *QualityRepository) RecordResult(ctx context.Context, inspection *entity.QualityInspection, entries []entity.StockEntry, userID string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&entity.QualityInspection{}).
+			Where("id = ? AND status = ?", inspection.ID, entity.InspectionStatusPending).
+			Updates(map[string]interface{}{
+				"status":            inspection.Status,
+				"passed_quantity":   inspection.PassedQuantity,
+				"rejected_quantity": inspection.RejectedQuantity,
+				"defects":           inspection.Defects,
+				"notes":             inspection.Notes,
+				"inspected_by":      inspection.InspectedBy,
+				"inspected_at":      inspection.InspectedAt,
+				"updated_at":        time.Now(),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvalidData
+		}
+
+		if err := tx.Model(&entity.Stock{}).
+			Where("sku_id = ? AND store_id = ?", inspection.SKUID, inspection.StoreID).
+			Update("quarantined_quantity", gorm.Expr("GREATEST(quarantined_quantity - ?, 0)", inspection.Quantity)).Error; err != nil {
+			return err
+		}
+
+		return r.stocksRepo.ProcessStockEntriesTx(ctx, tx, entries, userID)
+	})
+}
@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"gorm.io/gorm"
+)
+
+// RecurringInvoiceRepository handles database operations for recurring invoice templates
+type RecurringInvoiceRepository struct {
+	db                *gorm.DB
+	sequenceGenerator *SequenceGenerator
+}
+
+// NewRecurringInvoiceRepository creates a new recurring invoice repository
+func NewRecurringInvoiceRepository(db *gorm.DB) *RecurringInvoiceRepository {
+	return &RecurringInvoiceRepository{
+		db:                db,
+		sequenceGenerator: NewSequenceGenerator(db),
+	}
+}
+
+// Create creates a recurring invoice template
+func (r *RecurringInvoiceRepository) Create(ctx context.Context, template *entity.RecurringInvoice) error {
+	return r.db.WithContext(ctx).Create(template).Error
+}
+
+// Get retrieves a recurring invoice template by ID
+func (r *RecurringInvoiceRepository) Get(ctx context.Context, id uint) (*entity.RecurringInvoice, error) {
+	var template entity.RecurringInvoice
+	if err := r.db.WithContext(ctx).First(&template, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &template, nil
+}
+
+// List retrieves recurring invoice templates with filters and pagination
+func (r *RecurringInvoiceRepository) List(ctx context.Context, filter *entity.RecurringInvoiceFilter) ([]entity.RecurringInvoice, int64, error) {
+	var templates []entity.RecurringInvoice
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&entity.RecurringInvoice{})
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Type != "" {
+		query = query.Where("type = ?", filter.Type)
+	}
+	if filter.EntityID != 0 {
+		query = query.Where("entity_id = ?", filter.EntityID)
+	}
+	if filter.EntityType != "" {
+		query = query.Where("entity_type = ?", filter.EntityType)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (filter.Page - 1) * filter.PageSize
+	if err := query.Order("id DESC").Limit(filter.PageSize).Offset(offset).Find(&templates).Error; err != nil {
+		return nil, 0, err
+	}
+	return templates, total, nil
+}
+
+// Update saves a recurring invoice template
+func (r *RecurringInvoiceRepository) Update(ctx context.Context, template *entity.RecurringInvoice) error {
+	return r.db.WithContext(ctx).Save(template).Error
+}
+
+// ListDue retrieves the active templates with an occurrence due on or before the given time
+func (r *RecurringInvoiceRepository) ListDue(ctx context.Context, asOf time.Time) ([]entity.RecurringInvoice, error) {
+	var templates []entity.RecurringInvoice
+	if err := r.db.WithContext(ctx).
+		Where("status = ? AND next_run_date <= ?", entity.RecurringInvoiceActive, asOf).
+		Order("next_run_date, id").
+		Find(&templates).Error; err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+// RecordRun records an occurrence of a recurring invoice and saves the template
+// advanced past it, in one transaction. When an invoice is given it is numbered
+// and created with the run, so an occurrence is never invoiced twice.
+func (r *RecurringInvoiceRepository) RecordRun(ctx context.Context, template *entity.RecurringInvoice, run *entity.RecurringInvoiceRun, invoice *entity.FinanceInvoice) error {
+	if invoice != nil && invoice.InvoiceNumber == "" {
+		seq, err := r.sequenceGenerator.NextSequence(ctx, "recurring_invoice")
+		if err != nil {
+			return err
+		}
+		prefix := "SINV"
+		if invoice.Type == entity.FinancePurchaseInvoice {
+			prefix = "PINV"
+		}
+		invoice.InvoiceNumber = fmt.Sprintf("%s-R-%s-%06d", prefix, invoice.IssueDate.Format("200601"), seq)
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if invoice != nil {
+			now := time.Now()
+			invoice.CreatedAt = now
+			invoice.UpdatedAt = now
+			invoice.AmountDue = invoice.Total - invoice.AmountPaid
+			if err := tx.Create(invoice).Error; err != nil {
+				return err
+			}
+			run.InvoiceID = &invoice.ID
+			run.InvoiceNumber = invoice.InvoiceNumber
+			template.LastInvoiceID = &invoice.ID
+		}
+		if err := tx.Create(run).Error; err != nil {
+			return err
+		}
+		return tx.Save(template).Error
+	})
+}
+
+// ListRuns retrieves the recorded occurrences of a recurring invoice, latest first
+func (r *RecurringInvoiceRepository) ListRuns(ctx context.Context, templateID uint) ([]entity.RecurringInvoiceRun, error) {
+	var runs []entity.RecurringInvoiceRun
+	if err := r.db.WithContext(ctx).
+		Where("recurring_invoice_id = ?", templateID).
+		Order("occurrence DESC").
+		Find(&runs).Error; err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
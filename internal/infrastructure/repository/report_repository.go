@@ -2,10 +2,13 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"time"
 
 	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // ReportRepository handles database operations for reports and analytics
@@ -210,51 +213,129 @@ func (r *ReportRepository) UpdateScheduleNextRun(ctx context.Context, id string,
 	return nil
 }
 
-// GetInventoryValueReport generates an inventory value report
-func (r *ReportRepository) GetInventoryValueReport(ctx context.Context, warehouseID string, asOfDate time.Time) ([]entity.InventoryValueReport, error) {
+// CreateReportRun records a run of a report schedule
+func (r *ReportRepository) CreateReportRun(ctx context.Context, run *entity.ReportRun) error {
+	return r.db.WithContext(ctx).Create(run).Error
+}
+
+// GetReportRun retrieves a run of a report schedule by ID
+func (r *ReportRepository) GetReportRun(ctx context.Context, id uint64) (*entity.ReportRun, error) {
+	var run entity.ReportRun
+	if err := r.db.WithContext(ctx).First(&run, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &run, nil
+}
+
+// UpdateReportRun updates a run of a report schedule
+func (r *ReportRepository) UpdateReportRun(ctx context.Context, run *entity.ReportRun) error {
+	return r.db.WithContext(ctx).Save(run).Error
+}
+
+// ListReportRuns lists the runs of a report schedule, latest first
+func (r *ReportRepository) ListReportRuns(ctx context.Context, scheduleID string, filter *entity.ReportRunFilter) ([]entity.ReportRun, int64, error) {
+	var runs []entity.ReportRun
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&entity.ReportRun{}).Where("schedule_id = ?", scheduleID)
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (filter.Page - 1) * filter.PageSize
+	if err := query.Order("created_at DESC, id DESC").Offset(offset).Limit(filter.PageSize).Find(&runs).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return runs, total, nil
+}
+
+// GetInventoryValueReport generates an inventory value report of the stock
+// on hand now, valued at the SKU price
+func (r *ReportRepository) GetInventoryValueReport(ctx context.Context, warehouseID string) ([]entity.InventoryValueReport, error) {
 	var report []entity.InventoryValueReport
 
 	query := `
-		SELECT 
-			i.product_id,
-			it.name AS product_name,
-			it.sku,
-			i.warehouse_id,
-			w.name AS warehouse_name,
-			i.quantity,
-			it.unit_of_measure,
-			COALESCE(po.unit_price, it.price) AS unit_cost,
-			i.quantity * COALESCE(po.unit_price, it.price) AS total_value
-		FROM 
-			inventories i
-		JOIN 
-			items it ON i.product_id = it.id
-		JOIN 
-			warehouses w ON i.warehouse_id = w.id
-		LEFT JOIN (
-			SELECT 
-				pri.item_id,
-				AVG(pri.unit_price) AS unit_price
-			FROM 
-				purchase_receipts pr
-			JOIN 
-				purchase_receipt_items pri ON pr.id = pri.purchase_receipt_id
-			WHERE 
-				pr.receipt_date <= ?
-			GROUP BY 
-				pri.item_id
-		) po ON i.product_id = po.item_id
-		WHERE 
-			i.quantity > 0
+		SELECT
+			s.sku_id AS product_id,
+			k.name AS product_name,
+			k.sku_code AS sku,
+			s.store_id AS warehouse_id,
+			st.name AS warehouse_name,
+			SUM(s.quantity) AS quantity,
+			k.unit_of_measure,
+			k.price AS unit_cost,
+			SUM(s.quantity) * k.price AS total_value
+		FROM stocks s
+		JOIN skus k ON k.id = s.sku_id
+		JOIN stores st ON st.id = s.store_id
+		WHERE s.quantity > 0
 	`
 
-	args := []interface{}{asOfDate}
+	var args []interface{}
 	if warehouseID != "" {
-		query += " AND i.warehouse_id = ?"
+		query += " AND s.store_id = ?"
+		args = append(args, warehouseID)
+	}
+
+	query += " GROUP BY s.sku_id, k.name, k.sku_code, s.store_id, st.name, k.unit_of_measure, k.price ORDER BY k.name, st.name"
+
+	if err := r.db.WithContext(ctx).Raw(query, args...).Scan(&report).Error; err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// GetInventoryValueSnapshot generates an inventory value report of the stock
+// at the close of a day, from the latest snapshot taken on or before it. It
+// returns ErrRecordNotFound when no snapshot was taken by then.
+func (r *ReportRepository) GetInventoryValueSnapshot(ctx context.Context, warehouseID string, asOfDate time.Time) ([]entity.InventoryValueReport, error) {
+	var latest entity.StockSnapshot
+	if err := r.db.WithContext(ctx).
+		Where("snapshot_date <= ?", asOfDate.Format("2006-01-02")).
+		Order("snapshot_date DESC").
+		Limit(1).
+		Find(&latest).Error; err != nil {
+		return nil, err
+	}
+	if latest.ID == 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	var report []entity.InventoryValueReport
+	query := `
+		SELECT
+			ss.sku_id AS product_id,
+			k.name AS product_name,
+			k.sku_code AS sku,
+			ss.store_id AS warehouse_id,
+			st.name AS warehouse_name,
+			ss.quantity,
+			k.unit_of_measure,
+			ss.unit_cost,
+			ss.value AS total_value,
+			ss.snapshot_date
+		FROM stock_snapshots ss
+		JOIN skus k ON k.id = ss.sku_id
+		JOIN stores st ON st.id = ss.store_id
+		WHERE ss.snapshot_date = ? AND ss.quantity > 0
+	`
+
+	args := []interface{}{latest.SnapshotDate.Format("2006-01-02")}
+	if warehouseID != "" {
+		query += " AND ss.store_id = ?"
 		args = append(args, warehouseID)
 	}
 
-	query += " ORDER BY it.name"
+	query += " ORDER BY k.name, st.name"
 
 	if err := r.db.WithContext(ctx).Raw(query, args...).Scan(&report).Error; err != nil {
 		return nil, err
@@ -266,6 +347,7 @@ func (r *ReportRepository) GetInventoryValueReport(ctx context.Context, warehous
 // GetInventoryAgeReport generates an inventory age report
 func (r *ReportRepository) GetInventoryAgeReport(ctx context.Context, warehouseID string, asOfDate time.Time) ([]entity.InventoryAgeReport, error) {
 	var report []entity.InventoryAgeReport
+	dialect := dialectFor(r.db)
 
 	query := `
 		SELECT 
@@ -277,7 +359,7 @@ func (r *ReportRepository) GetInventoryAgeReport(ctx context.Context, warehouseI
 			i.quantity,
 			it.unit_of_measure,
 			COALESCE(se.created_at, i.created_at) AS receipt_date,
-			EXTRACT(DAY FROM ? - COALESCE(se.created_at, i.created_at))::int AS days_in_inventory,
+			` + dialect.DaysBetween("?", "COALESCE(se.created_at, i.created_at)") + ` AS days_in_inventory,
 			i.quantity * COALESCE(po.unit_price, it.price) AS value
 		FROM 
 			inventories i
@@ -329,59 +411,6 @@ func (r *ReportRepository) GetInventoryAgeReport(ctx context.Context, warehouseI
 	return report, nil
 }
 
-// GetProductSalesReport generates a product sales report
-func (r *ReportRepository) GetProductSalesReport(ctx context.Context, startDate, endDate time.Time) ([]entity.ProductSalesReport, error) {
-	var report []entity.ProductSalesReport
-
-	query := `
-		SELECT 
-			soi.item_id AS product_id,
-			it.name AS product_name,
-			it.sku,
-			it.category,
-			SUM(soi.quantity) AS quantity_sold,
-			it.unit_of_measure,
-			SUM(soi.total_price) AS revenue,
-			SUM(soi.quantity * COALESCE(po.unit_price, it.price)) AS cost,
-			SUM(soi.total_price) - SUM(soi.quantity * COALESCE(po.unit_price, it.price)) AS profit,
-			CASE 
-				WHEN SUM(soi.total_price) > 0 THEN 
-					(SUM(soi.total_price) - SUM(soi.quantity * COALESCE(po.unit_price, it.price))) / SUM(soi.total_price) * 100
-				ELSE 0
-			END AS profit_margin
-		FROM 
-			sales_orders so
-		JOIN 
-			sales_order_items soi ON so.id = soi.sales_order_id
-		JOIN 
-			items it ON soi.item_id = it.id
-		LEFT JOIN (
-			SELECT 
-				pri.item_id,
-				AVG(pri.unit_price) AS unit_price
-			FROM 
-				purchase_receipts pr
-			JOIN 
-				purchase_receipt_items pri ON pr.id = pri.purchase_receipt_id
-			GROUP BY 
-				pri.item_id
-		) po ON soi.item_id = po.item_id
-		WHERE 
-			so.order_date BETWEEN ? AND ?
-			AND so.status NOT IN ('CANCELLED', 'DRAFT')
-		GROUP BY 
-			soi.item_id, it.name, it.sku, it.category, it.unit_of_measure
-		ORDER BY 
-			profit DESC
-	`
-
-	if err := r.db.WithContext(ctx).Raw(query, startDate, endDate).Scan(&report).Error; err != nil {
-		return nil, err
-	}
-
-	return report, nil
-}
-
 // GetCustomerSalesReport generates a customer sales report
 func (r *ReportRepository) GetCustomerSalesReport(ctx context.Context, startDate, endDate time.Time) ([]entity.CustomerSalesReport, error) {
 	var report []entity.CustomerSalesReport
@@ -541,16 +570,13 @@ func (r *ReportRepository) GetProfitAndLossReport(ctx context.Context, startDate
 		return nil, err
 	}
 
-	// Get expenses (from purchase orders not related to inventory)
+	// Get expenses (from approved and reimbursed expense claims, in the base currency)
 	expensesQuery := `
-		SELECT COALESCE(SUM(grand_total), 0) AS expenses
-		FROM purchase_orders
-		WHERE order_date BETWEEN ? AND ?
-		AND status NOT IN ('CANCELLED', 'DRAFT')
-		AND id NOT IN (
-			SELECT DISTINCT purchase_order_id 
-			FROM purchase_receipts
-		)
+		SELECT COALESCE(SUM(l.base_amount), 0) AS expenses
+		FROM expense_claim_lines l
+		JOIN expense_claims ec ON ec.id = l.claim_id
+		WHERE l.expense_date BETWEEN ? AND ?
+		AND ec.status IN ('APPROVED', 'REIMBURSED')
 	`
 	if err := r.db.WithContext(ctx).Raw(expensesQuery, startDate, endDate).Scan(&report.Expenses).Error; err != nil {
 		return nil, err
@@ -570,13 +596,105 @@ func (r *ReportRepository) GetProfitAndLossReport(ctx context.Context, startDate
 	return &report, nil
 }
 
-// GetDashboardMetrics generates dashboard metrics
-func (r *ReportRepository) GetDashboardMetrics(ctx context.Context, period string) (*entity.DashboardMetrics, error) {
-	var metrics entity.DashboardMetrics
+// GetDashboardMetrics reads the dashboard metrics of a period from their
+// snapshot. The sales figures can be restricted to the clients matching an
+// RFM filter. A snapshot that is missing, older than maxAge or aggregated
+// before its data last changed is aggregated again first.
+func (r *ReportRepository) GetDashboardMetrics(ctx context.Context, period string, rfm entity.RFMFilter, maxAge time.Duration) (*entity.DashboardMetrics, error) {
+	period = dashboardPeriod(period)
+	var snapshot entity.DashboardMetricSnapshot
+	err := r.db.WithContext(ctx).
+		Where("period = ? AND filter_key = ?", period, dashboardFilterKey(rfm)).
+		Take(&snapshot).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	if err == nil && snapshot.Fresh(maxAge, time.Now()) {
+		var metrics entity.DashboardMetrics
+		if err := json.Unmarshal([]byte(snapshot.Metrics), &metrics); err == nil {
+			return &metrics, nil
+		}
+	}
+	return r.aggregateDashboardMetrics(ctx, period, rfm)
+}
+
+// RefreshDashboardMetrics aggregates the dashboard metrics of every period for
+// all clients into their snapshots, and drops the snapshots of RFM filters
+// aggregated longer than maxAge ago, which would be aggregated again on read
+func (r *ReportRepository) RefreshDashboardMetrics(ctx context.Context, maxAge time.Duration) error {
+	for _, period := range entity.DashboardPeriods {
+		if _, err := r.aggregateDashboardMetrics(ctx, period, entity.RFMFilter{}); err != nil {
+			return err
+		}
+	}
+	return r.db.WithContext(ctx).
+		Where("filter_key <> '' AND computed_at < ?", time.Now().Add(-maxAge)).
+		Delete(&entity.DashboardMetricSnapshot{}).Error
+}
+
+// InvalidateDashboardMetrics marks every dashboard metric snapshot as
+// aggregated before its data changed
+func (r *ReportRepository) InvalidateDashboardMetrics(ctx context.Context) error {
+	return r.db.WithContext(ctx).
+		Model(&entity.DashboardMetricSnapshot{}).
+		Where("1 = 1").
+		Update("invalidated_at", time.Now()).Error
+}
+
+// aggregateDashboardMetrics computes the dashboard metrics of a period and
+// stores them as its snapshot
+func (r *ReportRepository) aggregateDashboardMetrics(ctx context.Context, period string, rfm entity.RFMFilter) (*entity.DashboardMetrics, error) {
+	metrics, err := r.computeDashboardMetrics(ctx, period, rfm, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(metrics)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := entity.DashboardMetricSnapshot{
+		Period:     period,
+		FilterKey:  dashboardFilterKey(rfm),
+		Metrics:    string(data),
+		ComputedAt: metrics.ComputedAt,
+	}
+	err = r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "period"}, {Name: "filter_key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"metrics", "computed_at"}),
+	}).Create(&snapshot).Error
+	if err != nil {
+		return nil, err
+	}
+	return metrics, nil
+}
+
+// dashboardPeriod returns the known period a dashboard request is for, the
+// month by default
+func dashboardPeriod(period string) string {
+	for _, known := range entity.DashboardPeriods {
+		if period == known {
+			return period
+		}
+	}
+	return "month"
+}
+
+// dashboardFilterKey identifies the snapshot of an RFM filter; it is empty
+// for all clients
+func dashboardFilterKey(rfm entity.RFMFilter) string {
+	if rfm.IsEmpty() {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d:%d:%d", rfm.Segment, rfm.MinRecencyScore, rfm.MinFrequencyScore, rfm.MinMonetaryScore)
+}
+
+// computeDashboardMetrics runs the queries behind the dashboard metrics of a
+// period ending now
+func (r *ReportRepository) computeDashboardMetrics(ctx context.Context, period string, rfm entity.RFMFilter, now time.Time) (*entity.DashboardMetrics, error) {
+	metrics := entity.DashboardMetrics{Period: period, ComputedAt: now}
 	var startDate time.Time
 
 	// Determine start date based on period
-	now := time.Now()
 	switch period {
 	case "day":
 		startDate = now.AddDate(0, 0, -1)
@@ -592,14 +710,28 @@ func (r *ReportRepository) GetDashboardMetrics(ctx context.Context, period strin
 		startDate = now.AddDate(0, -1, 0) // Default to month
 	}
 
+	// Restrict sales queries to the clients in the RFM filter
+	var clientCond, soClientCond string
+	var clientArgs []interface{}
+	if !rfm.IsEmpty() {
+		var cond string
+		cond, clientArgs = rfmClientSubquery("client_id", rfm)
+		clientCond = " AND " + cond
+		cond, _ = rfmClientSubquery("so.client_id", rfm)
+		soClientCond = " AND " + cond
+	}
+	salesArgs := func(args ...interface{}) []interface{} {
+		return append(args, clientArgs...)
+	}
+
 	// Get revenue
 	revenueQuery := `
 		SELECT COALESCE(SUM(grand_total), 0) AS total_revenue
 		FROM sales_orders
 		WHERE order_date BETWEEN ? AND ?
-		AND status NOT IN ('CANCELLED', 'DRAFT')
+		AND status NOT IN ('CANCELLED', 'DRAFT')` + clientCond + `
 	`
-	if err := r.db.WithContext(ctx).Raw(revenueQuery, startDate, now).Scan(&metrics.TotalRevenue).Error; err != nil {
+	if err := r.db.WithContext(ctx).Raw(revenueQuery, salesArgs(startDate, now)...).Scan(&metrics.TotalRevenue).Error; err != nil {
 		return nil, err
 	}
 
@@ -657,13 +789,13 @@ func (r *ReportRepository) GetDashboardMetrics(ctx context.Context, period strin
 			COUNT(CASE WHEN status IN ('DRAFT', 'CONFIRMED', 'PROCESSING') THEN 1 END) AS pending_orders,
 			COUNT(CASE WHEN status = 'COMPLETED' THEN 1 END) AS completed_orders
 		FROM sales_orders
-		WHERE order_date BETWEEN ? AND ?
+		WHERE order_date BETWEEN ? AND ?` + clientCond + `
 	`
 	var orderCounts struct {
 		PendingOrders   int `gorm:"column:pending_orders"`
 		CompletedOrders int `gorm:"column:completed_orders"`
 	}
-	if err := r.db.WithContext(ctx).Raw(orderCountQuery, startDate, now).Scan(&orderCounts).Error; err != nil {
+	if err := r.db.WithContext(ctx).Raw(orderCountQuery, salesArgs(startDate, now)...).Scan(&orderCounts).Error; err != nil {
 		return nil, err
 	}
 	metrics.PendingOrders = orderCounts.PendingOrders
@@ -695,29 +827,30 @@ func (r *ReportRepository) GetDashboardMetrics(ctx context.Context, period strin
 			items it ON soi.item_id = it.id
 		WHERE 
 			so.order_date BETWEEN ? AND ?
-			AND so.status NOT IN ('CANCELLED', 'DRAFT')
+			AND so.status NOT IN ('CANCELLED', 'DRAFT')` + soClientCond + `
 		GROUP BY 
 			soi.item_id, it.name
 		ORDER BY 
 			revenue DESC
 		LIMIT 5
 	`
-	if err := r.db.WithContext(ctx).Raw(topProductsQuery, startDate, now).Scan(&metrics.TopSellingProducts).Error; err != nil {
+	if err := r.db.WithContext(ctx).Raw(topProductsQuery, salesArgs(startDate, now)...).Scan(&metrics.TopSellingProducts).Error; err != nil {
 		return nil, err
 	}
 
 	// Get revenue by month
+	yearMonth := dialectFor(r.db).YearMonth("order_date")
 	revenueByMonthQuery := `
 		SELECT 
-			TO_CHAR(order_date, 'YYYY-MM') AS month,
+			` + yearMonth + ` AS month,
 			SUM(grand_total) AS revenue
 		FROM 
 			sales_orders
 		WHERE 
 			order_date BETWEEN ? AND ?
-			AND status NOT IN ('CANCELLED', 'DRAFT')
+			AND status NOT IN ('CANCELLED', 'DRAFT')` + clientCond + `
 		GROUP BY 
-			TO_CHAR(order_date, 'YYYY-MM')
+			` + yearMonth + `
 		ORDER BY 
 			month
 	`
@@ -725,7 +858,7 @@ func (r *ReportRepository) GetDashboardMetrics(ctx context.Context, period strin
 		Month   string  `gorm:"column:month"`
 		Revenue float64 `gorm:"column:revenue"`
 	}
-	if err := r.db.WithContext(ctx).Raw(revenueByMonthQuery, startDate.AddDate(0, -11, 0), now).Scan(&monthlyRevenue).Error; err != nil {
+	if err := r.db.WithContext(ctx).Raw(revenueByMonthQuery, salesArgs(startDate.AddDate(0, -11, 0), now)...).Scan(&monthlyRevenue).Error; err != nil {
 		return nil, err
 	}
 
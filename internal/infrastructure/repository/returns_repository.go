@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"gorm.io/gorm"
+)
+
+// ReturnsRepository handles database operations for sales returns and the
+// returns and quality report
+type ReturnsRepository struct {
+	db *gorm.DB
+}
+
+// NewReturnsRepository creates a new returns repository
+func NewReturnsRepository(db *gorm.DB) *ReturnsRepository {
+	return &ReturnsRepository{db: db}
+}
+
+// Create records a sales return
+func (r *ReturnsRepository) Create(ctx context.Context, ret *entity.SalesReturn) error {
+	return r.db.WithContext(ctx).Create(ret).Error
+}
+
+// ListByOrder retrieves the returns of a sales order, oldest first
+func (r *ReturnsRepository) ListByOrder(ctx context.Context, salesOrderID string) ([]entity.SalesReturn, error) {
+	var returns []entity.SalesReturn
+	if err := r.db.WithContext(ctx).
+		Where("sales_order_id = ?", salesOrderID).
+		Order("created_at ASC").
+		Find(&returns).Error; err != nil {
+		return nil, err
+	}
+	return returns, nil
+}
+
+// ListReturns retrieves the returns recorded between the given times
+func (r *ReturnsRepository) ListReturns(ctx context.Context, from, to time.Time) ([]entity.SalesReturn, error) {
+	var returns []entity.SalesReturn
+	if err := r.db.WithContext(ctx).
+		Where("created_at >= ? AND created_at <= ?", from, to).
+		Find(&returns).Error; err != nil {
+		return nil, err
+	}
+	return returns, nil
+}
+
+// ListClosedInspections retrieves the inspections whose result was recorded
+// between the given times
+func (r *ReturnsRepository) ListClosedInspections(ctx context.Context, from, to time.Time) ([]entity.QualityInspection, error) {
+	var inspections []entity.QualityInspection
+	if err := r.db.WithContext(ctx).
+		Where("status <> ?", entity.InspectionStatusPending).
+		Where("inspected_at >= ? AND inspected_at <= ?", from, to).
+		Find(&inspections).Error; err != nil {
+		return nil, err
+	}
+	return inspections, nil
+}
+
+// GetReceiptVendors returns the vendor of the purchase order each of the given receipts was received against
+func (r *ReturnsRepository) GetReceiptVendors(ctx context.Context, receiptNumbers []string) (map[string]uint, error) {
+	vendors := make(map[string]uint, len(receiptNumbers))
+	if len(receiptNumbers) == 0 {
+		return vendors, nil
+	}
+
+	var rows []struct {
+		ReceiptNumber string
+		VendorID      uint
+	}
+	if err := r.db.WithContext(ctx).
+		Table("purchase_receipts").
+		Select("purchase_receipts.receipt_number, purchase_orders.vendor_id").
+		Joins("JOIN purchase_orders ON purchase_orders.id = purchase_receipts.purchase_order_id").
+		Where("purchase_receipts.receipt_number IN ?", receiptNumbers).
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		vendors[row.ReceiptNumber] = row.VendorID
+	}
+	return vendors, nil
+}
+
+// GetSKUs returns the code, name and designated vendor of the given SKUs
+func (r *ReturnsRepository) GetSKUs(ctx context.Context, skuIDs []string) (map[string]entity.SKU, error) {
+	skus := make(map[string]entity.SKU, len(skuIDs))
+	if len(skuIDs) == 0 {
+		return skus, nil
+	}
+
+	var rows []entity.SKU
+	if err := r.db.WithContext(ctx).
+		Select("id, sku_code, name, vendor_id").
+		Where("id IN ?", skuIDs).
+		Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, sku := range rows {
+		skus[sku.ID] = sku
+	}
+	return skus, nil
+}
+
+// GetVendorNames returns the name of each of the given vendors
+func (r *ReturnsRepository) GetVendorNames(ctx context.Context, vendorIDs []uint) (map[uint]string, error) {
+	names := make(map[uint]string, len(vendorIDs))
+	if len(vendorIDs) == 0 {
+		return names, nil
+	}
+
+	var vendors []entity.Vendor
+	if err := r.db.WithContext(ctx).Select("id, name").Where("id IN ?", vendorIDs).Find(&vendors).Error; err != nil {
+		return nil, err
+	}
+	for _, vendor := range vendors {
+		names[vendor.ID] = vendor.Name
+	}
+	return names, nil
+}
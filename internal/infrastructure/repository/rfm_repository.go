@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"gorm.io/gorm"
+)
+
+// RFMRepository handles database operations for client RFM scores
+type RFMRepository struct {
+	db *gorm.DB
+}
+
+// NewRFMRepository creates a new RFM repository
+func NewRFMRepository(db *gorm.DB) *RFMRepository {
+	return &RFMRepository{db: db}
+}
+
+// ListClientSales summarizes the sales orders of each client placed since the given
+// date, archived orders included. Draft and cancelled orders are ignored and amounts
+// are in the base currency.
+func (r *RFMRepository) ListClientSales(ctx context.Context, since time.Time) ([]entity.ClientSalesSummary, error) {
+	query := `
+		SELECT
+			so.client_id,
+			MAX(so.order_date) AS last_order_date,
+			COUNT(*) AS order_count,
+			COALESCE(SUM(so.base_grand_total), 0) AS monetary_value
+		FROM (
+			SELECT client_id, order_date, base_grand_total, status FROM sales_orders
+			UNION ALL
+			SELECT client_id, order_date, base_grand_total, status FROM ` + archiveTable("sales_orders") + `
+		) so
+		JOIN clients c ON c.id = so.client_id
+		WHERE so.order_date >= ?
+			AND so.status NOT IN ('CANCELLED', 'DRAFT')
+		GROUP BY so.client_id
+	`
+
+	var summaries []entity.ClientSalesSummary
+	if err := r.db.WithContext(ctx).Raw(query, since).Scan(&summaries).Error; err != nil {
+		return nil, err
+	}
+	return summaries, nil
+}
+
+// ReplaceScores replaces all stored RFM scores with the given ones in a single transaction
+func (r *RFMRepository) ReplaceScores(ctx context.Context, scores []entity.ClientRFMScore) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("1 = 1").Delete(&entity.ClientRFMScore{}).Error; err != nil {
+			return err
+		}
+		if len(scores) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(&scores, createBatchSize(tx)).Error
+	})
+}
+
+// ListScores retrieves RFM scores with filters, best scores first
+func (r *RFMRepository) ListScores(ctx context.Context, filter entity.RFMFilter) ([]entity.ClientRFMScore, error) {
+	var scores []entity.ClientRFMScore
+	query := r.db.WithContext(ctx).Model(&entity.ClientRFMScore{})
+
+	if filter.Segment != "" {
+		query = query.Where("segment = ?", filter.Segment)
+	}
+	if filter.MinRecencyScore > 0 {
+		query = query.Where("recency_score >= ?", filter.MinRecencyScore)
+	}
+	if filter.MinFrequencyScore > 0 {
+		query = query.Where("frequency_score >= ?", filter.MinFrequencyScore)
+	}
+	if filter.MinMonetaryScore > 0 {
+		query = query.Where("monetary_score >= ?", filter.MinMonetaryScore)
+	}
+
+	if err := query.Order("score DESC, monetary_value DESC, client_id").Find(&scores).Error; err != nil {
+		return nil, err
+	}
+	return scores, nil
+}
+
+// rfmClientSubquery returns the SQL condition and arguments that restrict a client ID
+// column to the clients matching an RFM filter
+func rfmClientSubquery(column string, filter entity.RFMFilter) (string, []interface{}) {
+	cond := column + " IN (SELECT client_id FROM client_rfm_scores WHERE 1 = 1"
+	var args []interface{}
+	if filter.Segment != "" {
+		cond += " AND segment = ?"
+		args = append(args, filter.Segment)
+	}
+	if filter.MinRecencyScore > 0 {
+		cond += " AND recency_score >= ?"
+		args = append(args, filter.MinRecencyScore)
+	}
+	if filter.MinFrequencyScore > 0 {
+		cond += " AND frequency_score >= ?"
+		args = append(args, filter.MinFrequencyScore)
+	}
+	if filter.MinMonetaryScore > 0 {
+		cond += " AND monetary_score >= ?"
+		args = append(args, filter.MinMonetaryScore)
+	}
+	return cond + ")", args
+}
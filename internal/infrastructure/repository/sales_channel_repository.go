@@ -0,0 +1,208 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"gorm.io/gorm"
+)
+
+// SalesChannelRepository handles database operations for sales channels,
+// their SKU mappings and sync runs
+type SalesChannelRepository struct {
+	db *gorm.DB
+}
+
+func NewSalesChannelRepository(db *gorm.DB) *SalesChannelRepository {
+	return &SalesChannelRepository{db: db}
+}
+
+// CreateChannel creates a sales channel, rejecting a code already taken
+func (r *SalesChannelRepository) CreateChannel(ctx context.Context, channel *entity.SalesChannel) error {
+	if err := r.checkCode(ctx, channel); err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Omit("Warehouses").Create(channel).Error
+}
+
+// UpdateChannel saves a sales channel, rejecting a code already taken
+func (r *SalesChannelRepository) UpdateChannel(ctx context.Context, channel *entity.SalesChannel) error {
+	if err := r.checkCode(ctx, channel); err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Omit("Warehouses").Save(channel).Error
+}
+
+// checkCode checks that no other sales channel has the channel's code
+func (r *SalesChannelRepository) checkCode(ctx context.Context, channel *entity.SalesChannel) error {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&entity.SalesChannel{}).
+		Where("code = ? AND id <> ?", channel.Code, channel.ID).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrDuplicateEntry
+	}
+	return nil
+}
+
+// GetChannel retrieves a sales channel by ID with its linked stores
+func (r *SalesChannelRepository) GetChannel(ctx context.Context, id uint) (*entity.SalesChannel, error) {
+	var channel entity.SalesChannel
+	if err := r.db.WithContext(ctx).Preload("Warehouses").First(&channel, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &channel, nil
+}
+
+// ListChannels retrieves the sales channels with their linked stores
+func (r *SalesChannelRepository) ListChannels(ctx context.Context, activeOnly bool) ([]entity.SalesChannel, error) {
+	var channels []entity.SalesChannel
+	query := r.db.WithContext(ctx).Preload("Warehouses")
+	if activeOnly {
+		query = query.Where("active = ?", true)
+	}
+	if err := query.Order("code").Find(&channels).Error; err != nil {
+		return nil, err
+	}
+	return channels, nil
+}
+
+// ReplaceWarehouses replaces the stores linked to a sales channel
+func (r *SalesChannelRepository) ReplaceWarehouses(ctx context.Context, channelID uint, warehouses []entity.SalesChannelWarehouse) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("channel_id = ?", channelID).Delete(&entity.SalesChannelWarehouse{}).Error; err != nil {
+			return err
+		}
+		if len(warehouses) == 0 {
+			return nil
+		}
+		return tx.Create(&warehouses).Error
+	})
+}
+
+// SetOrdersSyncedAt moves the time orders are fetched from on the next sync
+func (r *SalesChannelRepository) SetOrdersSyncedAt(ctx context.Context, channelID uint, syncedAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&entity.SalesChannel{}).
+		Where("id = ?", channelID).
+		Update("orders_synced_at", syncedAt).Error
+}
+
+// SetInventorySyncedAt records the time inventory levels were pushed
+func (r *SalesChannelRepository) SetInventorySyncedAt(ctx context.Context, channelID uint, syncedAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&entity.SalesChannel{}).
+		Where("id = ?", channelID).
+		Update("inventory_synced_at", syncedAt).Error
+}
+
+// SaveMapping creates or updates the mapping of a listing of a sales channel,
+// keyed by its external SKU
+func (r *SalesChannelRepository) SaveMapping(ctx context.Context, mapping *entity.ChannelSKUMapping) error {
+	var existing entity.ChannelSKUMapping
+	err := r.db.WithContext(ctx).
+		Where("channel_id = ? AND external_sku = ?", mapping.ChannelID, mapping.ExternalSKU).
+		First(&existing).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return err
+	}
+	if err == nil {
+		mapping.ID = existing.ID
+		mapping.CreatedAt = existing.CreatedAt
+	}
+	return r.db.WithContext(ctx).Omit("SKU").Save(mapping).Error
+}
+
+// DeleteMapping deletes a SKU mapping of a sales channel
+func (r *SalesChannelRepository) DeleteMapping(ctx context.Context, channelID, id uint) error {
+	result := r.db.WithContext(ctx).Where("channel_id = ?", channelID).Delete(&entity.ChannelSKUMapping{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// ListMappings retrieves the SKU mappings of a sales channel with their SKUs
+func (r *SalesChannelRepository) ListMappings(ctx context.Context, channelID uint) ([]entity.ChannelSKUMapping, error) {
+	var mappings []entity.ChannelSKUMapping
+	if err := r.db.WithContext(ctx).Preload("SKU").
+		Where("channel_id = ?", channelID).
+		Order("external_sku").
+		Find(&mappings).Error; err != nil {
+		return nil, err
+	}
+	return mappings, nil
+}
+
+// SKUIDsByCode returns the IDs of the SKUs with the given codes, by code
+func (r *SalesChannelRepository) SKUIDsByCode(ctx context.Context, codes []string) (map[string]string, error) {
+	var skus []entity.SKU
+	if len(codes) > 0 {
+		if err := r.db.WithContext(ctx).Select("id, sku_code").Where("sku_code IN ?", codes).Find(&skus).Error; err != nil {
+			return nil, err
+		}
+	}
+	ids := make(map[string]string, len(skus))
+	for _, sku := range skus {
+		ids[sku.SKUCode] = sku.ID
+	}
+	return ids, nil
+}
+
+// AvailableQuantities returns the stock of SKUs in a store neither quarantined
+// nor reserved by active allocations, by SKU
+func (r *SalesChannelRepository) AvailableQuantities(ctx context.Context, storeID string, skuIDs []string) (map[string]float64, error) {
+	available := make(map[string]float64, len(skuIDs))
+	if len(skuIDs) == 0 {
+		return available, nil
+	}
+
+	var stocks []entity.Stock
+	if err := r.db.WithContext(ctx).Where("store_id = ? AND sku_id IN ?", storeID, skuIDs).Find(&stocks).Error; err != nil {
+		return nil, err
+	}
+	for _, stock := range stocks {
+		available[stock.SKUID] += stock.Available()
+	}
+
+	var reserved []struct {
+		SKUID    string `gorm:"column:sku_id"`
+		Quantity float64
+	}
+	if err := r.db.WithContext(ctx).Model(&entity.StockAllocation{}).
+		Select("sku_id, SUM(allocated_quantity) AS quantity").
+		Where("store_id = ? AND sku_id IN ? AND status = ?", storeID, skuIDs, entity.StockAllocationActive).
+		Group("sku_id").
+		Scan(&reserved).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range reserved {
+		available[row.SKUID] -= row.Quantity
+	}
+	return available, nil
+}
+
+// CreateSyncRun records a sync with a sales channel
+func (r *SalesChannelRepository) CreateSyncRun(ctx context.Context, run *entity.ChannelSyncRun) error {
+	return r.db.WithContext(ctx).Create(run).Error
+}
+
+// ListSyncRuns retrieves the latest sync runs of a sales channel
+func (r *SalesChannelRepository) ListSyncRuns(ctx context.Context, channelID uint, limit int) ([]entity.ChannelSyncRun, error) {
+	var runs []entity.ChannelSyncRun
+	if err := r.db.WithContext(ctx).
+		Where("channel_id = ?", channelID).
+		Order("started_at DESC, id DESC").
+		Limit(limit).
+		Find(&runs).Error; err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
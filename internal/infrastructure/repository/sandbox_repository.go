@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// SandboxRepository maintains the shadow schema that sandbox requests run against.
+// Statements here always run on the primary connection, with schema-qualified names.
+type SandboxRepository struct {
+	db         *gorm.DB
+	schema     string
+	seedTables []string
+}
+
+// NewSandboxRepository creates a new sandbox repository. The schema name must
+// already be validated, as it is interpolated into DDL.
+func NewSandboxRepository(db *gorm.DB, schema string, seedTables []string) *SandboxRepository {
+	return &SandboxRepository{db: db, schema: schema, seedTables: seedTables}
+}
+
+// Provision creates the sandbox schema and any live table it does not have yet.
+// Tables are copied with LIKE ... INCLUDING ALL, so defaults, indexes and
+// constraints match but foreign keys do not.
+func (r *SandboxRepository) Provision(ctx context.Context) error {
+	return r.provision(r.db.WithContext(ctx))
+}
+
+// Reset drops all sandbox data, recreates the sandbox tables from the current live
+// structure and copies the configured master data tables. It returns the number
+// of rows copied per table.
+func (r *SandboxRepository) Reset(ctx context.Context) (map[string]int64, error) {
+	copied := make(map[string]int64)
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", r.schema)).Error; err != nil {
+			return err
+		}
+		if err := r.provision(tx); err != nil {
+			return err
+		}
+
+		for _, table := range r.seedTables {
+			if !tx.Migrator().HasTable(table) {
+				continue
+			}
+			result := tx.Exec(fmt.Sprintf("INSERT INTO %s.%q SELECT * FROM public.%q", r.schema, table, table))
+			if result.Error != nil {
+				return fmt.Errorf("failed to copy %s: %w", table, result.Error)
+			}
+			copied[table] = result.RowsAffected
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return copied, nil
+}
+
+func (r *SandboxRepository) provision(db *gorm.DB) error {
+	if err := db.Exec(fmt.Sprintf("CREATE SCHEMA IF NOT EXISTS %s", r.schema)).Error; err != nil {
+		return fmt.Errorf("failed to create sandbox schema: %w", err)
+	}
+
+	var tables []string
+	if err := db.Raw(`
+		SELECT table_name FROM information_schema.tables
+		WHERE table_schema = 'public' AND table_type = 'BASE TABLE' AND table_name <> 'schema_migrations'
+	`).Scan(&tables).Error; err != nil {
+		return fmt.Errorf("failed to list tables: %w", err)
+	}
+
+	for _, table := range tables {
+		stmt := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s.%q (LIKE public.%q INCLUDING ALL)", r.schema, table, table)
+		if err := db.Exec(stmt).Error; err != nil {
+			return fmt.Errorf("failed to create sandbox table %s: %w", table, err)
+		}
+	}
+	return nil
+}
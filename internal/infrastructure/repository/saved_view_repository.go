@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"gorm.io/gorm"
+)
+
+type SavedViewRepository struct {
+	db *gorm.DB
+}
+
+func NewSavedViewRepository(db *gorm.DB) *SavedViewRepository {
+	return &SavedViewRepository{db: db}
+}
+
+// Create creates a saved view
+func (r *SavedViewRepository) Create(ctx context.Context, view *entity.SavedView) error {
+	return r.db.WithContext(ctx).Create(view).Error
+}
+
+// Update saves a saved view
+func (r *SavedViewRepository) Update(ctx context.Context, view *entity.SavedView) error {
+	return r.db.WithContext(ctx).Save(view).Error
+}
+
+// GetByID retrieves a saved view by ID
+func (r *SavedViewRepository) GetByID(ctx context.Context, id uint) (*entity.SavedView, error) {
+	var view entity.SavedView
+	if err := r.db.WithContext(ctx).First(&view, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &view, nil
+}
+
+// ListVisible retrieves the views of a module, or of every module when it is
+// empty, that a user saved or that are shared with their role, by name
+func (r *SavedViewRepository) ListVisible(ctx context.Context, userID uint, role, module string) ([]entity.SavedView, error) {
+	query := r.db.WithContext(ctx).Where("user_id = ? OR ? = ANY(shared_roles)", userID, role)
+	if module != "" {
+		query = query.Where("module = ?", module)
+	}
+
+	var views []entity.SavedView
+	if err := query.Order("module, name, id").Find(&views).Error; err != nil {
+		return nil, err
+	}
+	return views, nil
+}
+
+// Delete deletes a saved view
+func (r *SavedViewRepository) Delete(ctx context.Context, id uint) error {
+	result := r.db.WithContext(ctx).Delete(&entity.SavedView{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"gorm.io/gorm"
+)
+
+// schedulerLockKey is the advisory lock held by the server instance running
+// the scheduled tasks
+const schedulerLockKey = 4207359002
+
+// ScheduledRunRepository handles database operations for the runs of
+// scheduled tasks, and elects the instance running them
+type ScheduledRunRepository struct {
+	db *gorm.DB
+}
+
+// NewScheduledRunRepository creates a new scheduled run repository
+func NewScheduledRunRepository(db *gorm.DB) *ScheduledRunRepository {
+	return &ScheduledRunRepository{db: db}
+}
+
+// Create records a run
+func (r *ScheduledRunRepository) Create(ctx context.Context, run *entity.ScheduledRun) error {
+	return r.db.WithContext(ctx).Create(run).Error
+}
+
+// Finish records the outcome of a run
+func (r *ScheduledRunRepository) Finish(ctx context.Context, run *entity.ScheduledRun) error {
+	return r.db.WithContext(ctx).Model(run).Updates(map[string]interface{}{
+		"status":      run.Status,
+		"error":       run.Error,
+		"finished_at": run.FinishedAt,
+	}).Error
+}
+
+// List retrieves the runs matching a filter, latest first
+func (r *ScheduledRunRepository) List(ctx context.Context, filter *entity.ScheduledRunFilter) ([]entity.ScheduledRun, int64, error) {
+	var runs []entity.ScheduledRun
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&entity.ScheduledRun{})
+	if filter.Task != "" {
+		query = query.Where("task = ?", filter.Task)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (filter.Page - 1) * filter.PageSize
+	if err := query.Order("started_at DESC, id DESC").Limit(filter.PageSize).Offset(offset).Find(&runs).Error; err != nil {
+		return nil, 0, err
+	}
+	return runs, total, nil
+}
+
+// LastRuns retrieves the latest run of each task
+func (r *ScheduledRunRepository) LastRuns(ctx context.Context) (map[string]entity.ScheduledRun, error) {
+	var runs []entity.ScheduledRun
+	if err := r.db.WithContext(ctx).
+		Raw("SELECT DISTINCT ON (task) * FROM scheduled_runs ORDER BY task, started_at DESC, id DESC").
+		Scan(&runs).Error; err != nil {
+		return nil, err
+	}
+	last := make(map[string]entity.ScheduledRun, len(runs))
+	for _, run := range runs {
+		last[run.Task] = run
+	}
+	return last, nil
+}
+
+// FailAbandoned marks the scheduled runs still running as failed, reporting
+// how many there were. It is called by an instance taking the scheduler lease,
+// as the runs were left by the instance that lost it.
+func (r *ScheduledRunRepository) FailAbandoned(ctx context.Context, now time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&entity.ScheduledRun{}).
+		Where("trigger = ? AND status = ?", entity.ScheduledRunBySchedule, entity.ScheduledRunRunning).
+		Updates(map[string]interface{}{
+			"status":      entity.ScheduledRunFailed,
+			"error":       "the instance running the schedules stopped during the run",
+			"finished_at": now,
+		})
+	return result.RowsAffected, result.Error
+}
+
+// Purge removes the runs started before a time, reporting how many there were
+func (r *ScheduledRunRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Where("started_at < ? AND status <> ?", before, entity.ScheduledRunRunning).Delete(&entity.ScheduledRun{})
+	return result.RowsAffected, result.Error
+}
+
+// SchedulerLease is the advisory lock electing the server instance running
+// the scheduled tasks. It is held by a database session, so it is lost when
+// the connection is, e.g. when the instance dies.
+type SchedulerLease struct {
+	conn *sql.Conn
+}
+
+// TryLead takes the scheduler lease unless another instance holds it, in
+// which case it returns nil
+func (r *ScheduledRunRepository) TryLead(ctx context.Context) (*SchedulerLease, error) {
+	sqlDB, err := r.db.DB()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var locked bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", schedulerLockKey).Scan(&locked); err != nil || !locked {
+		conn.Close()
+		return nil, err
+	}
+	return &SchedulerLease{conn: conn}, nil
+}
+
+// Check returns an error when the session holding the lease was lost
+func (l *SchedulerLease) Check(ctx context.Context) error {
+	_, err := l.conn.ExecContext(ctx, "SELECT 1")
+	return err
+}
+
+// Release gives the lease up for another instance to take
+func (l *SchedulerLease) Release(ctx context.Context) error {
+	_, err := l.conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", schedulerLockKey)
+	if closeErr := l.conn.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"gorm.io/gorm"
+)
+
+// searchSource is a table the search covers. text is the expression the
+// table's search indexes are built on in migration 000046; queries must use
+// the very same expression for the indexes to apply.
+type searchSource struct {
+	table    string
+	title    string
+	subtitle string
+	key      string // column an exact match of the search text ranks first on
+	text     string
+}
+
+var searchSources = map[entity.SearchEntityType]searchSource{
+	entity.SearchSKU:           {"skus", "name", "sku_code", "sku_code", "sku_code || ' ' || name || ' ' || COALESCE(description, '')"},
+	entity.SearchVendorItem:    {"vendor_skus", "name", "code", "code", "code || ' ' || name || ' ' || COALESCE(description, '')"},
+	entity.SearchClient:        {"clients", "name", "code", "code", "code || ' ' || name || ' ' || COALESCE(email, '')"},
+	entity.SearchVendor:        {"vendors", "name", "code", "code", "code || ' ' || name || ' ' || COALESCE(email, '')"},
+	entity.SearchSalesOrder:    {"sales_orders", "order_number", "status::text", "order_number", "order_number"},
+	entity.SearchPurchaseOrder: {"purchase_orders", "order_number", "status::text", "order_number", "order_number"},
+}
+
+// SearchRepository searches SKUs, vendor items, clients, vendors and orders
+// with PostgreSQL full-text search, and tolerates typos by matching
+// trigrams as well
+type SearchRepository struct {
+	db         *gorm.DB
+	similarity float64 // word similarity from 0 to 1 above which a misspelt word still matches
+}
+
+// NewSearchRepository creates a new search repository
+func NewSearchRepository(db *gorm.DB, similarity float64) *SearchRepository {
+	return &SearchRepository{db: db, similarity: similarity}
+}
+
+// Search finds the records of the given types matching a text. It returns the
+// page of hits of hitTypes, best match first, and the number of matches of
+// each of types.
+func (r *SearchRepository) Search(ctx context.Context, text string, types, hitTypes []entity.SearchEntityType, limit, offset int) ([]entity.SearchHit, map[entity.SearchEntityType]int64, error) {
+	facets := make(map[entity.SearchEntityType]int64)
+	tsQuery := searchTSQuery(text)
+	if tsQuery == "" || len(types) == 0 {
+		return nil, facets, nil
+	}
+	args := map[string]interface{}{
+		"term":    text,
+		"tsquery": tsQuery,
+		"limit":   limit,
+		"offset":  offset,
+	}
+
+	var hits []entity.SearchHit
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// The threshold of the <% operator, for this transaction only
+		if err := tx.Exec("SELECT set_config('pg_trgm.word_similarity_threshold', ?, true)",
+			strconv.FormatFloat(r.similarity, 'f', -1, 64)).Error; err != nil {
+			return err
+		}
+
+		var counts []struct {
+			Type  entity.SearchEntityType
+			Count int64
+		}
+		if err := tx.Raw("SELECT type, COUNT(*) AS count FROM ("+searchMatches(types)+") matches GROUP BY type", args).
+			Scan(&counts).Error; err != nil {
+			return err
+		}
+		for _, count := range counts {
+			facets[count.Type] = count.Count
+		}
+
+		if len(hitTypes) == 0 {
+			return nil
+		}
+		return tx.Raw("SELECT type, id, title, subtitle, score FROM ("+searchMatches(hitTypes)+") matches "+
+			"ORDER BY score DESC, title LIMIT @limit OFFSET @offset", args).
+			Scan(&hits).Error
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return hits, facets, nil
+}
+
+// Reindex rebuilds the search indexes of the records of a type, without
+// blocking writes, and refreshes the table's statistics the planner chooses
+// the indexes by
+func (r *SearchRepository) Reindex(ctx context.Context, t entity.SearchEntityType) error {
+	source, ok := searchSources[t]
+	if !ok {
+		return nil
+	}
+	db := r.db.WithContext(ctx)
+	for _, index := range []string{"idx_" + source.table + "_search", "idx_" + source.table + "_search_trgm"} {
+		if err := db.Exec("REINDEX INDEX CONCURRENTLY " + index).Error; err != nil {
+			return err
+		}
+	}
+	return db.Exec("ANALYZE " + source.table).Error
+}
+
+// searchMatches renders the query of the records of the given types matching
+// the search, each scored by full-text rank, by similarity to the search
+// text, and first when its code or number is the search text
+func searchMatches(types []entity.SearchEntityType) string {
+	selects := make([]string, 0, len(types))
+	for _, t := range types {
+		source, ok := searchSources[t]
+		if !ok {
+			continue
+		}
+		selects = append(selects, `
+			SELECT '`+string(t)+`' AS type, id::text AS id, `+source.title+` AS title, `+source.subtitle+` AS subtitle,
+				ts_rank(to_tsvector('simple', `+source.text+`), to_tsquery('simple', @tsquery)) * 2
+				+ word_similarity(@term, `+source.text+`)
+				+ CASE WHEN LOWER(`+source.key+`) = LOWER(@term) THEN 1 ELSE 0 END AS score
+			FROM `+source.table+`
+			WHERE to_tsvector('simple', `+source.text+`) @@ to_tsquery('simple', @tsquery)
+				OR @term <% (`+source.text+`)`)
+	}
+	return strings.Join(selects, "\nUNION ALL")
+}
+
+// searchTSQuery turns a search text into a full-text query matching records
+// holding every word of it, or a word it begins, e.g. "blue wid" into
+// "blue:* & wid:*". It is empty when the text has no words.
+func searchTSQuery(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, word := range words {
+		words[i] = word + ":*"
+	}
+	return strings.Join(words, " & ")
+}
@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"gorm.io/gorm"
+)
+
+// SKUClassRepository handles database operations for the ABC/XYZ
+// classification of SKUs and the cycle counts it drives
+type SKUClassRepository struct {
+	db *gorm.DB
+}
+
+func NewSKUClassRepository(db *gorm.DB) *SKUClassRepository {
+	return &SKUClassRepository{db: db}
+}
+
+// ListMonthlyIssues sums per SKU and month the units issued from stock from
+// the given time until before the other, archived stock entries included
+func (r *SKUClassRepository) ListMonthlyIssues(ctx context.Context, from, to time.Time) ([]entity.SKUIssue, error) {
+	var issues []entity.SKUIssue
+	period := dialectFor(r.db).YearMonth("created_at")
+	for _, table := range []string{"stock_entries", archiveTable("stock_entries")} {
+		db := r.db.WithContext(ctx)
+		if table != "stock_entries" && !db.Migrator().HasTable(table) {
+			continue
+		}
+		var batch []entity.SKUIssue
+		if err := db.Table(table).
+			Select("sku_id, "+period+" AS period, SUM(quantity) AS quantity").
+			Where("type = ? AND created_at >= ? AND created_at < ?", "OUT", from, to).
+			Group("sku_id, " + period).
+			Scan(&batch).Error; err != nil {
+			return nil, err
+		}
+		issues = append(issues, batch...)
+	}
+	return issues, nil
+}
+
+// ListSKUPrices returns the price of every SKU not archived
+func (r *SKUClassRepository) ListSKUPrices(ctx context.Context) (map[string]float64, error) {
+	var skus []entity.SKU
+	if err := r.db.WithContext(ctx).
+		Select("id, price").
+		Where("status <> ?", entity.SKUStatusArchived).
+		Find(&skus).Error; err != nil {
+		return nil, err
+	}
+
+	prices := make(map[string]float64, len(skus))
+	for _, sku := range skus {
+		prices[sku.ID] = sku.Price
+	}
+	return prices, nil
+}
+
+// SaveClasses stores the classes of SKUs in a single transaction, one
+// statement per class pair
+func (r *SKUClassRepository) SaveClasses(ctx context.Context, classifications []entity.SKUClassification, classifiedAt time.Time) error {
+	type pair struct {
+		abc entity.ABCClass
+		xyz entity.XYZClass
+	}
+	skuIDs := make(map[pair][]string)
+	for _, classification := range classifications {
+		key := pair{classification.ABCClass, classification.XYZClass}
+		skuIDs[key] = append(skuIDs[key], classification.SKUID)
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for key, ids := range skuIDs {
+			if err := tx.Model(&entity.SKU{}).
+				Where("id IN ?", ids).
+				UpdateColumns(map[string]interface{}{
+					"abc_class":     key.abc,
+					"xyz_class":     key.xyz,
+					"classified_at": classifiedAt,
+				}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ListCountableStocks retrieves the stocks on hand of the stores in the access
+// scope, or of one store, with their SKU's ABC class and when they were last
+// counted, an adjustment of the stock being a count
+func (r *SKUClassRepository) ListCountableStocks(ctx context.Context, filter *entity.CycleCountFilter) ([]entity.CycleCountLine, error) {
+	var lines []entity.CycleCountLine
+	query := r.db.WithContext(ctx).
+		Table("stocks").
+		Select("stocks.id AS stock_id, stocks.store_id, stocks.sku_id, skus.sku_code, skus.name, skus.abc_class, " +
+			"stocks.zone_code, stocks.bin_location, stocks.quantity, stocks.created_at AS stocked_at, " +
+			"(SELECT MAX(h.created_at) FROM stock_history h WHERE h.stock_id = stocks.id AND h.type = 'ADJUST') AS last_counted_at").
+		Joins("JOIN skus ON skus.id = stocks.sku_id").
+		Where("stocks.quantity > 0")
+	query = scopeStores(ctx, query, "stocks.store_id")
+	if filter.StoreID != "" {
+		query = query.Where("stocks.store_id = ?", filter.StoreID)
+	}
+	if filter.ABCClass != "" {
+		query = query.Where("skus.abc_class = ?", filter.ABCClass)
+	}
+	if err := query.Scan(&lines).Error; err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
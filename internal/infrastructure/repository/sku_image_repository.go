@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"gorm.io/gorm"
+)
+
+type SKUImageRepository struct {
+	db *gorm.DB
+}
+
+func NewSKUImageRepository(db *gorm.DB) *SKUImageRepository {
+	return &SKUImageRepository{db: db}
+}
+
+// Create records an uploaded SKU image
+func (r *SKUImageRepository) Create(ctx context.Context, image *entity.SKUImage) error {
+	return r.db.WithContext(ctx).Create(image).Error
+}
+
+// Get retrieves an image of a SKU by ID
+func (r *SKUImageRepository) Get(ctx context.Context, skuID string, id uint64) (*entity.SKUImage, error) {
+	var image entity.SKUImage
+	if err := r.db.WithContext(ctx).Where("sku_id = ?", skuID).First(&image, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &image, nil
+}
+
+// ListBySKU retrieves the images of a SKU in the order they are shown in
+func (r *SKUImageRepository) ListBySKU(ctx context.Context, skuID string) ([]entity.SKUImage, error) {
+	var images []entity.SKUImage
+	err := r.db.WithContext(ctx).
+		Where("sku_id = ?", skuID).
+		Order("position, id").
+		Find(&images).Error
+	return images, err
+}
+
+// Reorder sets the positions of the images of a SKU to their order in ids,
+// in a single transaction
+func (r *SKUImageRepository) Reorder(ctx context.Context, skuID string, ids []uint64) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for position, id := range ids {
+			if err := tx.Model(&entity.SKUImage{}).
+				Where("id = ? AND sku_id = ?", id, skuID).
+				Update("position", position).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Delete deletes an image
+func (r *SKUImageRepository) Delete(ctx context.Context, id uint64) error {
+	result := r.db.WithContext(ctx).Delete(&entity.SKUImage{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
@@ -2,7 +2,7 @@ package repository
 
 import (
 	"context"
-	"fmt"
+	"encoding/json"
 
 	"github.com/google/uuid"
 	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
@@ -22,12 +22,12 @@ func (r *SKURepository) CreateSKU(ctx context.Context, sku *entity.SKU) error {
 	if sku.ID == "" {
 		sku.ID = uuid.New().String()
 	}
-	return r.db.WithContext(ctx).Create(sku).Error
+	return r.db.WithContext(ctx).Omit("Images").Create(sku).Error
 }
 
 // UpdateSKU updates an existing SKU
 func (r *SKURepository) UpdateSKU(ctx context.Context, sku *entity.SKU) error {
-	return r.db.WithContext(ctx).Save(sku).Error
+	return r.db.WithContext(ctx).Omit("Images").Save(sku).Error
 }
 
 // GetSKUByID retrieves a SKU by ID
@@ -36,6 +36,7 @@ func (r *SKURepository) GetSKUByID(ctx context.Context, id string) (*entity.SKU,
 	if err := r.db.WithContext(ctx).
 		Preload("Manufacturer").
 		Preload("Vendor").
+		Preload("Images", orderSKUImages).
 		First(&sku, "id = ?", id).Error; err != nil {
 		return nil, err
 	}
@@ -48,6 +49,7 @@ func (r *SKURepository) GetSKUBySKUCode(ctx context.Context, skuCode string) (*e
 	if err := r.db.WithContext(ctx).
 		Preload("Manufacturer").
 		Preload("Vendor").
+		Preload("Images", orderSKUImages).
 		First(&sku, "sku_code = ?", skuCode).Error; err != nil {
 		return nil, err
 	}
@@ -92,6 +94,23 @@ func (r *SKURepository) ListSKUs(ctx context.Context, filter *entity.SKUFilter,
 		if filter.MaxPrice != nil {
 			query = query.Where("price <= ?", filter.MaxPrice)
 		}
+		query = whereCustomFields(query, filter.CustomFields)
+		if filter.ParentID != "" {
+			query = query.Where("parent_id = ?", filter.ParentID)
+		}
+		if filter.NoVariants {
+			query = query.Where("parent_id IS NULL")
+		}
+		if len(filter.VariantOptions) > 0 {
+			match, _ := json.Marshal(filter.VariantOptions)
+			query = query.Where("variant_options @> ?::jsonb", string(match))
+		}
+		if filter.ABCClass != "" {
+			query = query.Where("abc_class = ?", filter.ABCClass)
+		}
+		if filter.XYZClass != "" {
+			query = query.Where("xyz_class = ?", filter.XYZClass)
+		}
 	}
 
 	// Count total SKUs
@@ -104,6 +123,7 @@ func (r *SKURepository) ListSKUs(ctx context.Context, filter *entity.SKUFilter,
 	if err := query.Offset(offset).Limit(pageSize).
 		Preload("Manufacturer").
 		Preload("Vendor").
+		Preload("Images", orderSKUImages).
 		Find(&skus).Error; err != nil {
 		return nil, 0, err
 	}
@@ -111,30 +131,89 @@ func (r *SKURepository) ListSKUs(ctx context.Context, filter *entity.SKUFilter,
 	return skus, total, nil
 }
 
-// SearchSKUs searches for SKUs based on a search term
-func (r *SKURepository) SearchSKUs(ctx context.Context, searchTerm string, page, pageSize int) ([]entity.SKU, int64, error) {
+// orderSKUImages preloads the images of SKUs in the order they are shown in
+func orderSKUImages(db *gorm.DB) *gorm.DB {
+	return db.Order("position, id")
+}
+
+// ListVariants retrieves the variants of a parent item, by SKU code
+func (r *SKURepository) ListVariants(ctx context.Context, parentID string) ([]entity.SKU, error) {
 	var skus []entity.SKU
-	var total int64
+	if err := r.db.WithContext(ctx).Where("parent_id = ?", parentID).Order("sku_code").Find(&skus).Error; err != nil {
+		return nil, err
+	}
+	return skus, nil
+}
 
-	query := r.db.WithContext(ctx).Model(&entity.SKU{}).
-		Where("sku_code ILIKE ? OR name ILIKE ? OR description ILIKE ?",
-			"%"+searchTerm+"%", "%"+searchTerm+"%", "%"+searchTerm+"%")
+// CountVariants counts the variants of a parent item
+func (r *SKURepository) CountVariants(ctx context.Context, parentID string) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&entity.SKU{}).Where("parent_id = ?", parentID).Count(&count).Error
+	return count, err
+}
 
-	// Count total skus
-	if err := query.Count(&total).Error; err != nil {
-		return nil, 0, err
+// CreateVariants saves the dimensions of a parent item and creates variants
+// of it in a single transaction
+func (r *SKURepository) CreateVariants(ctx context.Context, parent *entity.SKU, variants []*entity.SKU) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(parent).Update("variant_dimensions", parent.VariantDimensions).Error; err != nil {
+			return err
+		}
+		for _, variant := range variants {
+			if variant.ID == "" {
+				variant.ID = uuid.New().String()
+			}
+			if err := tx.Omit("Manufacturer", "Vendor").Create(variant).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ListKitComponents retrieves the components of a kit with their SKUs
+func (r *SKURepository) ListKitComponents(ctx context.Context, kitID string) ([]entity.KitComponent, error) {
+	var components []entity.KitComponent
+	if err := r.db.WithContext(ctx).Preload("Component").Where("kit_id = ?", kitID).Order("id").Find(&components).Error; err != nil {
+		return nil, err
 	}
+	return components, nil
+}
 
-	// Apply pagination
-	offset := (page - 1) * pageSize
-	if err := query.Offset(offset).Limit(pageSize).
-		Preload("Manufacturer").
-		Preload("Vendor").
-		Find(&skus).Error; err != nil {
-		return nil, 0, err
+// GetKitComponents retrieves the components of the kits among SKUs, by kit
+func (r *SKURepository) GetKitComponents(ctx context.Context, skuIDs []string) (map[string][]entity.KitComponent, error) {
+	var components []entity.KitComponent
+	if err := r.db.WithContext(ctx).Where("kit_id IN ?", skuIDs).Order("id").Find(&components).Error; err != nil {
+		return nil, err
+	}
+	byKit := make(map[string][]entity.KitComponent)
+	for _, component := range components {
+		byKit[component.KitID] = append(byKit[component.KitID], component)
 	}
+	return byKit, nil
+}
 
-	return skus, total, nil
+// IsKitComponent reports whether a SKU is a component of any kit
+func (r *SKURepository) IsKitComponent(ctx context.Context, skuID string) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&entity.KitComponent{}).Where("component_id = ?", skuID).Count(&count).Error
+	return count > 0, err
+}
+
+// ReplaceKitComponents replaces the components of a kit and marks it a kit
+// when it has any, in a single transaction
+func (r *SKURepository) ReplaceKitComponents(ctx context.Context, kitID string, components []entity.KitComponent) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("kit_id = ?", kitID).Delete(&entity.KitComponent{}).Error; err != nil {
+			return err
+		}
+		if len(components) > 0 {
+			if err := tx.Omit("Component").Create(&components).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&entity.SKU{}).Where("id = ?", kitID).Update("is_kit", len(components) > 0).Error
+	})
 }
 
 // CreateSKUCategory creates a new SKU category
@@ -171,7 +250,7 @@ func (r *SKURepository) DeleteSKUCategory(ctx context.Context, id string) error
 		return err
 	}
 	if count > 0 {
-		return fmt.Errorf("cannot delete category with children")
+		return entity.NewError(entity.ErrCodeFailedPrecondition, "cannot delete category with children")
 	}
 
 	// Check if category is used by SKUs
@@ -180,7 +259,7 @@ func (r *SKURepository) DeleteSKUCategory(ctx context.Context, id string) error
 		return err
 	}
 	if count > 0 {
-		return fmt.Errorf("cannot delete category used by SKUs")
+		return entity.NewError(entity.ErrCodeFailedPrecondition, "cannot delete category used by SKUs")
 	}
 
 	return r.db.WithContext(ctx).Delete(&entity.SKUCategory{}, "id = ?", id).Error
@@ -227,6 +306,7 @@ func (r *SKURepository) GetSKUsByCategory(ctx context.Context, categoryID string
 	if err := query.Offset(offset).Limit(pageSize).
 		Preload("Manufacturer").
 		Preload("Vendor").
+		Preload("Images", orderSKUImages).
 		Find(&skus).Error; err != nil {
 		return nil, 0, err
 	}
@@ -241,7 +321,7 @@ func (r *SKURepository) BulkCreateSKUs(ctx context.Context, skus []*entity.SKU)
 			if sku.ID == "" {
 				sku.ID = uuid.New().String()
 			}
-			if err := tx.Create(sku).Error; err != nil {
+			if err := tx.Omit("Images").Create(sku).Error; err != nil {
 				return err
 			}
 		}
@@ -253,7 +333,7 @@ func (r *SKURepository) BulkCreateSKUs(ctx context.Context, skus []*entity.SKU)
 func (r *SKURepository) BulkUpdateSKUs(ctx context.Context, skus []*entity.SKU) error {
 	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		for _, sku := range skus {
-			if err := tx.Save(sku).Error; err != nil {
+			if err := tx.Omit("Images").Save(sku).Error; err != nil {
 				return err
 			}
 		}
@@ -267,6 +347,7 @@ func (r *SKURepository) GetSKUsByIDs(ctx context.Context, ids []string) ([]entit
 	if err := r.db.WithContext(ctx).
 		Preload("Manufacturer").
 		Preload("Vendor").
+		Preload("Images", orderSKUImages).
 		Where("id IN ?", ids).
 		Find(&skus).Error; err != nil {
 		return nil, err
@@ -274,12 +355,33 @@ func (r *SKURepository) GetSKUsByIDs(ctx context.Context, ids []string) ([]entit
 	return skus, nil
 }
 
+// GetCustomsData returns the customs classification of the given SKUs, by SKU ID
+func (r *SKURepository) GetCustomsData(ctx context.Context, ids []string) (map[string]entity.CustomsData, error) {
+	data := make(map[string]entity.CustomsData, len(ids))
+	if len(ids) == 0 {
+		return data, nil
+	}
+
+	var skus []entity.SKU
+	if err := r.db.WithContext(ctx).
+		Select("id, hs_code, country_of_origin, net_weight").
+		Where("id IN ?", ids).
+		Find(&skus).Error; err != nil {
+		return nil, err
+	}
+	for _, sku := range skus {
+		data[sku.ID] = sku.CustomsData
+	}
+	return data, nil
+}
+
 // GetSKUsBySKUCodes retrieves SKUs by their SKU codes
 func (r *SKURepository) GetSKUsBySKUCodes(ctx context.Context, skuCodes []string) ([]entity.SKU, error) {
 	var skus []entity.SKU
 	if err := r.db.WithContext(ctx).
 		Preload("Manufacturer").
 		Preload("Vendor").
+		Preload("Images", orderSKUImages).
 		Where("sku_code IN ?", skuCodes).
 		Find(&skus).Error; err != nil {
 		return nil, err
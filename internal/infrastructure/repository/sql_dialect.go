@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// Supported SQL dialect names, as reported by the gorm dialector
+const (
+	DialectPostgres = "postgres"
+	DialectMySQL    = "mysql"
+	DialectSQLite   = "sqlite"
+)
+
+// sqlDialect renders the date and time fragments of raw report queries that
+// differ between databases. Postgres is the production target and the only
+// database the queries are run against in tests; the MySQL and SQLite
+// variants are only checked as rendered. Unknown dialects fall back to
+// Postgres syntax.
+type sqlDialect struct {
+	name string
+}
+
+// dialectFor returns the SQL dialect of the given database session
+func dialectFor(db *gorm.DB) sqlDialect {
+	if db == nil || db.Dialector == nil {
+		return sqlDialect{name: DialectPostgres}
+	}
+	return sqlDialect{name: db.Dialector.Name()}
+}
+
+// DaysBetween renders the whole number of days from earlier to later as an integer expression
+func (d sqlDialect) DaysBetween(later, earlier string) string {
+	switch d.name {
+	case DialectSQLite:
+		return fmt.Sprintf("CAST(julianday(%s) - julianday(%s) AS INTEGER)", later, earlier)
+	case DialectMySQL:
+		return fmt.Sprintf("DATEDIFF(%s, %s)", later, earlier)
+	default:
+		return fmt.Sprintf("CAST(EXTRACT(DAY FROM (%s) - (%s)) AS INTEGER)", later, earlier)
+	}
+}
+
+// YearMonth renders a timestamp column formatted as YYYY-MM
+func (d sqlDialect) YearMonth(column string) string {
+	switch d.name {
+	case DialectSQLite:
+		return fmt.Sprintf("strftime('%%Y-%%m', %s)", column)
+	case DialectMySQL:
+		return fmt.Sprintf("DATE_FORMAT(%s, '%%Y-%%m')", column)
+	default:
+		return fmt.Sprintf("TO_CHAR(%s, 'YYYY-MM')", column)
+	}
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestDialectFor(t *testing.T) {
+	if got := dialectFor(nil).name; got != DialectPostgres {
+		t.Errorf("dialectFor(nil) = %q, want %q", got, DialectPostgres)
+	}
+	db := &gorm.DB{Config: &gorm.Config{Dialector: postgres.New(postgres.Config{})}}
+	if got := dialectFor(db).name; got != DialectPostgres {
+		t.Errorf("dialectFor(postgres) = %q, want %q", got, DialectPostgres)
+	}
+}
+
+func TestSQLDialectFragments(t *testing.T) {
+	tests := []struct {
+		dialect     string
+		daysBetween string
+		yearMonth   string
+	}{
+		{DialectPostgres, "CAST(EXTRACT(DAY FROM (?) - (due_date)) AS INTEGER)", "TO_CHAR(order_date, 'YYYY-MM')"},
+		{DialectMySQL, "DATEDIFF(?, due_date)", "DATE_FORMAT(order_date, '%Y-%m')"},
+		{DialectSQLite, "CAST(julianday(?) - julianday(due_date) AS INTEGER)", "strftime('%Y-%m', order_date)"},
+		{"sqlserver", "CAST(EXTRACT(DAY FROM (?) - (due_date)) AS INTEGER)", "TO_CHAR(order_date, 'YYYY-MM')"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dialect, func(t *testing.T) {
+			d := sqlDialect{name: tt.dialect}
+			if got := d.DaysBetween("?", "due_date"); got != tt.daysBetween {
+				t.Errorf("DaysBetween() = %q, want %q", got, tt.daysBetween)
+			}
+			if got := d.YearMonth("order_date"); got != tt.yearMonth {
+				t.Errorf("YearMonth() = %q, want %q", got, tt.yearMonth)
+			}
+		})
+	}
+}
+
+// The Postgres fragments evaluate to whole days and YYYY-MM months
+func TestSQLDialectPostgres(t *testing.T) {
+	db := openTestDB(t)
+	dialect := dialectFor(db)
+	earlier := time.Date(2027, time.January, 30, 18, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		later time.Time
+		want  int
+	}{
+		{"same day", earlier.Add(time.Hour), 0},
+		{"partial days round down", earlier.Add(47 * time.Hour), 1},
+		{"across a month", time.Date(2027, time.March, 2, 18, 0, 0, 0, time.UTC), 31},
+		{"earlier", earlier.Add(-72 * time.Hour), -3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var days int
+			if err := db.Raw("SELECT "+dialect.DaysBetween("CAST(? AS TIMESTAMP)", "CAST(? AS TIMESTAMP)"), tt.later, earlier).Scan(&days).Error; err != nil {
+				t.Fatalf("DaysBetween: %v", err)
+			}
+			if days != tt.want {
+				t.Errorf("DaysBetween() = %d, want %d", days, tt.want)
+			}
+		})
+	}
+
+	var month string
+	if err := db.Raw("SELECT "+dialect.YearMonth("CAST(? AS TIMESTAMP)"), earlier).Scan(&month).Error; err != nil {
+		t.Fatalf("YearMonth: %v", err)
+	}
+	if month != "2027-01" {
+		t.Errorf("YearMonth() = %q, want %q", month, "2027-01")
+	}
+}
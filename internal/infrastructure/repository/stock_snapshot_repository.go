@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"gorm.io/gorm"
+)
+
+// StockSnapshotRepository handles database operations for the daily closing
+// stock snapshots
+type StockSnapshotRepository struct {
+	db *gorm.DB
+}
+
+func NewStockSnapshotRepository(db *gorm.DB) *StockSnapshotRepository {
+	return &StockSnapshotRepository{db: db}
+}
+
+// LatestDate returns the day of the latest snapshot, or nil before the first
+func (r *StockSnapshotRepository) LatestDate(ctx context.Context) (*time.Time, error) {
+	var snapshot entity.StockSnapshot
+	if err := r.db.WithContext(ctx).Order("snapshot_date DESC").Limit(1).Find(&snapshot).Error; err != nil {
+		return nil, err
+	}
+	if snapshot.ID == 0 {
+		return nil, nil
+	}
+	return &snapshot.SnapshotDate, nil
+}
+
+// ClosingStock sums per store and SKU the stock on hand before the given
+// time: the current quantity less the changes the stock history recorded
+// since. Each line carries the current SKU price as its unit cost.
+func (r *StockSnapshotRepository) ClosingStock(ctx context.Context, before time.Time) ([]entity.StockSnapshot, error) {
+	var snapshots []entity.StockSnapshot
+	if err := r.db.WithContext(ctx).Raw(`
+		SELECT
+			s.store_id,
+			s.sku_id,
+			SUM(s.quantity - COALESCE(h.change, 0)) AS quantity,
+			MAX(k.price) AS unit_cost
+		FROM stocks s
+		JOIN skus k ON k.id = s.sku_id
+		LEFT JOIN (
+			SELECT stock_id, SUM(new_qty - previous_qty) AS change
+			FROM stock_history
+			WHERE created_at >= ?
+			GROUP BY stock_id
+		) h ON h.stock_id = s.id
+		GROUP BY s.store_id, s.sku_id
+		HAVING SUM(s.quantity - COALESCE(h.change, 0)) <> 0
+		ORDER BY s.store_id, s.sku_id
+	`, before).Scan(&snapshots).Error; err != nil {
+		return nil, err
+	}
+	return snapshots, nil
+}
+
+// ReplaceSnapshot replaces the snapshot of a day in a single transaction, so
+// a day taken again is not counted twice
+func (r *StockSnapshotRepository) ReplaceSnapshot(ctx context.Context, day time.Time, snapshots []entity.StockSnapshot) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("snapshot_date = ?", day.Format("2006-01-02")).Delete(&entity.StockSnapshot{}).Error; err != nil {
+			return err
+		}
+		if len(snapshots) == 0 {
+			return nil
+		}
+		return tx.Omit("SKU", "Store").CreateInBatches(&snapshots, createBatchSize(tx)).Error
+	})
+}
+
+// ListSnapshot retrieves the lines of the latest snapshot taken on or before
+// a day, of the stores in the access scope, with their SKU and store. The day
+// of that snapshot is returned too, or nil when none was taken by then.
+func (r *StockSnapshotRepository) ListSnapshot(ctx context.Context, filter *entity.StockSnapshotFilter) ([]entity.StockSnapshot, *time.Time, error) {
+	var latest entity.StockSnapshot
+	if err := r.db.WithContext(ctx).
+		Where("snapshot_date <= ?", filter.Date.Format("2006-01-02")).
+		Order("snapshot_date DESC").
+		Limit(1).
+		Find(&latest).Error; err != nil {
+		return nil, nil, err
+	}
+	if latest.ID == 0 {
+		return nil, nil, nil
+	}
+
+	var snapshots []entity.StockSnapshot
+	query := r.db.WithContext(ctx).Preload("SKU").Preload("Store").
+		Where("snapshot_date = ?", latest.SnapshotDate.Format("2006-01-02"))
+	query = scopeStores(ctx, query, "store_id")
+	if filter.StoreID != "" {
+		query = query.Where("store_id = ?", filter.StoreID)
+	}
+	if filter.SKUID != "" {
+		query = query.Where("sku_id = ?", filter.SKUID)
+	}
+	if err := query.Order("store_id, sku_id").Find(&snapshots).Error; err != nil {
+		return nil, nil, err
+	}
+	return snapshots, &latest.SnapshotDate, nil
+}
@@ -2,10 +2,15 @@ package repository
 
 import (
 	"context"
+	"math"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type StocksRepository struct {
@@ -43,10 +48,15 @@ func (r *StocksRepository) GetBySKUAndStore(ctx context.Context, skuID, storeID
 }
 
 // List retrieves stocks with filtering
+// ReservedQuantity returns the stock of a SKU in a store held by active
+// allocations
+func (r *StocksRepository) ReservedQuantity(ctx context.Context, skuID, storeID string) (float64, error) {
+	return reservedQuantity(r.db.WithContext(ctx), skuID, storeID)
+}
+
 func (r *StocksRepository) List(ctx context.Context, filter *entity.StockFilter) ([]entity.Stock, error) {
 	var stocks []entity.Stock
-	query := r.db.WithContext(ctx).
-		Model(&entity.Stock{})
+	query := scopeStores(ctx, r.db.WithContext(ctx).Model(&entity.Stock{}), "store_id")
 
 	// Apply filters if provided
 	if filter != nil {
@@ -123,6 +133,18 @@ func (r *StocksRepository) CreateOrUpdateStock(ctx context.Context, stock *entit
 	return r.db.WithContext(ctx).Save(stock).Error
 }
 
+// UpdateLocation updates where a stock record is kept, leaving its quantities
+// to the stock entries that may change them meanwhile
+func (r *StocksRepository) UpdateLocation(ctx context.Context, id string, binLocation, shelfNumber, zoneCode string) error {
+	return r.db.WithContext(ctx).Model(&entity.Stock{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"bin_location": binLocation,
+			"shelf_number": shelfNumber,
+			"zone_code":    zoneCode,
+		}).Error
+}
+
 // ProcessStockEntry handles a stock entry and updates inventory accordingly
 func (r *StocksRepository) ProcessStockEntry(ctx context.Context, entry *entity.StockEntry, userID string) error {
 	// Generate ID if not provided
@@ -146,13 +168,17 @@ func (r *StocksRepository) ProcessStockEntry(ctx context.Context, entry *entity.
 		case "IN":
 			newQty = previousQty + entry.Quantity
 		case "OUT":
+			// Quarantined stock cannot be picked
 			newQty = previousQty - entry.Quantity
-			if newQty < 0 {
+			if newQty < stock.QuarantinedQuantity || newQty < 0 {
 				return ErrInsufficientStock
 			}
 		default:
 			return ErrInvalidData
 		}
+		if err := applyConsignmentTx(ctx, tx, stock, entry); err != nil {
+			return err
+		}
 
 		// Create stock entry
 		if err := tx.Create(entry).Error; err != nil {
@@ -195,30 +221,229 @@ func (r *StocksRepository) ProcessStockEntry(ctx context.Context, entry *entity.
 	})
 }
 
-// getOrCreateStockTx gets or creates a stock record within a transaction
-func (r *StocksRepository) getOrCreateStockTx(ctx context.Context, tx *gorm.DB, skuID, storeID string) (*entity.Stock, error) {
-	var stock entity.Stock
-	result := tx.WithContext(ctx).
-		Where("sku_id = ? AND store_id = ?", skuID, storeID).
-		First(&stock)
+// ProcessStockEntries applies a batch of stock entries in a single transaction.
+// Entry and history rows are bulk inserted and each affected stock record is
+// updated once, instead of one transaction per line item.
+func (r *StocksRepository) ProcessStockEntries(ctx context.Context, entries []entity.StockEntry, userID string) error {
+	if len(entries) == 0 {
+		return nil
+	}
 
-	if result.Error != nil {
-		if result.Error == gorm.ErrRecordNotFound {
-			// Create new stock record
-			stock = entity.Stock{
-				ID:       uuid.New().String(),
-				SKUID:    skuID,
-				StoreID:  storeID,
-				Quantity: 0,
-			}
-			if err := tx.Create(&stock).Error; err != nil {
-				return nil, err
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return r.ProcessStockEntriesTx(ctx, tx, entries, userID)
+	})
+}
+
+// ListStockEntries lists the stock entries matching a filter, latest first,
+// with the number of matches
+func (r *StocksRepository) ListStockEntries(ctx context.Context, filter *entity.StockEntryFilter) ([]entity.StockEntry, int64, error) {
+	var entries []entity.StockEntry
+	var total int64
+
+	query := r.stockEntryQuery(ctx, filter)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+	if filter.PageSize <= 0 {
+		filter.PageSize = 10
+	}
+	if err := query.
+		Order("created_at DESC, id DESC").
+		Limit(filter.PageSize).
+		Offset((filter.Page - 1) * filter.PageSize).
+		Find(&entries).Error; err != nil {
+		return nil, 0, err
+	}
+	return entries, total, nil
+}
+
+// ListStockEntriesPage lists the page of stock entries matching a filter that
+// follows the page's cursor, latest first, with the cursor of the next page
+// when there is one
+func (r *StocksRepository) ListStockEntriesPage(ctx context.Context, filter *entity.StockEntryFilter, page entity.CursorPage) ([]entity.StockEntry, *entity.Cursor, error) {
+	query, err := cursorQuery(r.stockEntryQuery(ctx, filter), page, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	var entries []entity.StockEntry
+	if err := query.Find(&entries).Error; err != nil {
+		return nil, nil, err
+	}
+	entries, next := cursorRows(entries, page, func(e *entity.StockEntry) entity.Cursor {
+		return entity.Cursor{CreatedAt: e.CreatedAt, ID: e.ID}
+	})
+	return entries, next, nil
+}
+
+// stockEntryQuery selects the stock entries matching a filter
+func (r *StocksRepository) stockEntryQuery(ctx context.Context, filter *entity.StockEntryFilter) *gorm.DB {
+	query := scopeStores(ctx, r.db.WithContext(ctx).Model(&entity.StockEntry{}), "store_id")
+	if filter.SKUID != "" {
+		query = query.Where("sku_id = ?", filter.SKUID)
+	}
+	if filter.StoreID != "" {
+		query = query.Where("store_id = ?", filter.StoreID)
+	}
+	if filter.Type != "" {
+		query = query.Where("type = ?", filter.Type)
+	}
+	if filter.Reference != "" {
+		query = query.Where("reference = ?", filter.Reference)
+	}
+	if filter.StartDate != nil {
+		query = query.Where("created_at >= ?", filter.StartDate)
+	}
+	if filter.EndDate != nil {
+		query = query.Where("created_at <= ?", filter.EndDate)
+	}
+	return query
+}
+
+// ProcessStockEntriesTx applies a batch of stock entries within an existing transaction
+func (r *StocksRepository) ProcessStockEntriesTx(ctx context.Context, tx *gorm.DB, entries []entity.StockEntry, userID string) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	// Lock the stock records in key order, so batches touching the same
+	// records wait for each other instead of deadlocking
+	stocks := make(map[string]*entity.Stock)
+	var stockOrder []string
+	for _, entry := range entries {
+		key := entry.SKUID + "|" + entry.StoreID
+		if _, ok := stocks[key]; !ok {
+			stocks[key] = nil
+			stockOrder = append(stockOrder, key)
+		}
+	}
+	sort.Strings(stockOrder)
+	for _, key := range stockOrder {
+		skuID, storeID, _ := strings.Cut(key, "|")
+		stock, err := r.getOrCreateStockTx(ctx, tx, skuID, storeID)
+		if err != nil {
+			return err
+		}
+		stocks[key] = stock
+	}
+
+	histories := make([]entity.StockHistory, 0, len(entries))
+
+	for i := range entries {
+		entry := &entries[i]
+		if entry.ID == "" {
+			entry.ID = uuid.New().String()
+		}
+
+		stock := stocks[entry.SKUID+"|"+entry.StoreID]
+
+		previousQty := stock.Quantity
+		var newQty float64
+
+		switch entry.Type {
+		case "IN":
+			newQty = previousQty + entry.Quantity
+		case "OUT":
+			// Quarantined stock cannot be picked
+			newQty = previousQty - entry.Quantity
+			if newQty < stock.QuarantinedQuantity || newQty < 0 {
+				return ErrInsufficientStock
 			}
-		} else {
-			return nil, result.Error
+		default:
+			return ErrInvalidData
+		}
+		if err := applyConsignmentTx(ctx, tx, stock, entry); err != nil {
+			return err
+		}
+
+		stock.Quantity = newQty
+		if entry.BatchNumber != "" {
+			stock.BatchNumber = entry.BatchNumber
+		}
+		if entry.LotNumber != "" {
+			stock.LotNumber = entry.LotNumber
+		}
+		if !entry.ManufactureDate.IsZero() {
+			stock.ManufactureDate = entry.ManufactureDate
+		}
+		if !entry.ExpiryDate.IsZero() {
+			stock.ExpiryDate = entry.ExpiryDate
 		}
+
+		histories = append(histories, entity.StockHistory{
+			ID:          uuid.New().String(),
+			StockID:     stock.ID,
+			Type:        entry.Type,
+			Quantity:    entry.Quantity,
+			PreviousQty: previousQty,
+			NewQty:      newQty,
+			Reference:   entry.ID,
+			Note:        entry.Note,
+			CreatedBy:   userID,
+		})
 	}
 
+	batchSize := createBatchSize(tx)
+
+	if err := tx.WithContext(ctx).CreateInBatches(&entries, batchSize).Error; err != nil {
+		return err
+	}
+
+	for _, key := range stockOrder {
+		if err := tx.WithContext(ctx).Save(stocks[key]).Error; err != nil {
+			return err
+		}
+	}
+
+	return tx.WithContext(ctx).CreateInBatches(&histories, batchSize).Error
+}
+
+// getOrCreateStockTx gets or creates a stock record within a transaction and
+// locks it until the transaction ends, so concurrent entries against the same
+// stock apply one after the other instead of overwriting each other's
+// quantities. Creating a record takes a transaction lock on the SKU and store
+// first, so concurrent first receipts create a single record.
+func (r *StocksRepository) getOrCreateStockTx(ctx context.Context, tx *gorm.DB, skuID, storeID string) (*entity.Stock, error) {
+	stock, err := lockStockTx(ctx, tx, skuID, storeID)
+	if err != gorm.ErrRecordNotFound {
+		return stock, err
+	}
+
+	if err := tx.WithContext(ctx).Exec("SELECT pg_advisory_xact_lock(hashtext(?))", "stocks|"+skuID+"|"+storeID).Error; err != nil {
+		return nil, err
+	}
+	// Another transaction may have created the record while this one waited
+	stock, err = lockStockTx(ctx, tx, skuID, storeID)
+	if err != gorm.ErrRecordNotFound {
+		return stock, err
+	}
+
+	// Create new stock record
+	stock = &entity.Stock{
+		ID:       uuid.New().String(),
+		SKUID:    skuID,
+		StoreID:  storeID,
+		Quantity: 0,
+	}
+	if err := tx.WithContext(ctx).Create(stock).Error; err != nil {
+		return nil, err
+	}
+	return stock, nil
+}
+
+// lockStockTx retrieves the stock record of a SKU in a store with a row lock
+// held until the transaction ends, or gorm.ErrRecordNotFound
+func lockStockTx(ctx context.Context, tx *gorm.DB, skuID, storeID string) (*entity.Stock, error) {
+	var stock entity.Stock
+	if err := tx.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("sku_id = ? AND store_id = ?", skuID, storeID).
+		First(&stock).Error; err != nil {
+		return nil, err
+	}
 	return &stock, nil
 }
 
@@ -244,8 +469,7 @@ func (r *StocksRepository) GetStockLevels(ctx context.Context, storeID string) (
 // GetSKUsWithLowStock retrieves SKUs with stock level below threshold
 func (r *StocksRepository) GetSKUsWithLowStock(ctx context.Context, threshold float64) ([]entity.Stock, error) {
 	var stocks []entity.Stock
-	if err := r.db.WithContext(ctx).
-		Model(&entity.Stock{}).
+	if err := scopeStores(ctx, r.db.WithContext(ctx).Model(&entity.Stock{}), "store_id").
 		Where("quantity <= ?", threshold).
 		Find(&stocks).Error; err != nil {
 		return nil, err
@@ -253,6 +477,24 @@ func (r *StocksRepository) GetSKUsWithLowStock(ctx context.Context, threshold fl
 	return stocks, nil
 }
 
+// ListBelowReorderPoint retrieves the stock of the given SKUs in a store whose
+// available quantity is below the SKU's reorder point, with the SKU
+func (r *StocksRepository) ListBelowReorderPoint(ctx context.Context, storeID string, skuIDs []string) ([]entity.Stock, error) {
+	var stocks []entity.Stock
+	if len(skuIDs) == 0 {
+		return stocks, nil
+	}
+	if err := r.db.WithContext(ctx).
+		Preload("SKU").
+		Joins("JOIN skus ON stocks.sku_id = skus.id").
+		Where("stocks.store_id = ? AND stocks.sku_id IN ?", storeID, skuIDs).
+		Where("skus.reorder_point > 0 AND stocks.quantity - stocks.quarantined_quantity < skus.reorder_point").
+		Find(&stocks).Error; err != nil {
+		return nil, err
+	}
+	return stocks, nil
+}
+
 // GetStockMovements retrieves stock movements for a specific SKU
 func (r *StocksRepository) GetStockMovements(ctx context.Context, skuID string, fromDate, toDate string) ([]entity.StockHistory, error) {
 	var histories []entity.StockHistory
@@ -290,25 +532,36 @@ func (r *StocksRepository) GetStockHistoryByStockID(ctx context.Context, stockID
 // AdjustStock adjusts a stock level directly (e.g., after physical count)
 func (r *StocksRepository) AdjustStock(ctx context.Context, stockID string, newQuantity float64, note string, userID string) error {
 	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		// Get current stock record
+		// Get current stock record, locked against concurrent entries
 		var stock entity.Stock
-		if err := tx.First(&stock, "id = ?", stockID).Error; err != nil {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&stock, "id = ?", stockID).Error; err != nil {
 			return err
 		}
 
 		previousQty := stock.Quantity
 		diff := newQuantity - previousQty
+		historyID := uuid.New().String()
+
+		// Consigned stock counted short is owed to its vendors
+		if newQuantity < stock.ConsignedQuantity {
+			if err := consumeConsignmentTx(ctx, tx, &stock, stock.ConsignedQuantity-math.Max(newQuantity, 0), historyID); err != nil {
+				return err
+			}
+		}
 
 		// Update stock quantity
 		if err := tx.Model(&entity.Stock{}).
 			Where("id = ?", stockID).
-			Update("quantity", newQuantity).Error; err != nil {
+			Updates(map[string]interface{}{
+				"quantity":           newQuantity,
+				"consigned_quantity": stock.ConsignedQuantity,
+			}).Error; err != nil {
 			return err
 		}
 
 		// Create history record for the adjustment
 		history := &entity.StockHistory{
-			ID:          uuid.New().String(),
+			ID:          historyID,
 			StockID:     stockID,
 			Type:        "ADJUST",
 			Quantity:    diff,
@@ -321,3 +574,88 @@ func (r *StocksRepository) AdjustStock(ctx context.Context, stockID string, newQ
 		return tx.Create(history).Error
 	})
 }
+
+// SumQuantity returns the stock of a SKU in a store, across its batches
+func (r *StocksRepository) SumQuantity(ctx context.Context, skuID, storeID string) (float64, error) {
+	var quantity float64
+	if err := r.db.WithContext(ctx).Model(&entity.Stock{}).
+		Select("COALESCE(SUM(quantity), 0)").
+		Where("sku_id = ? AND store_id = ?", skuID, storeID).
+		Scan(&quantity).Error; err != nil {
+		return 0, err
+	}
+	return quantity, nil
+}
+
+// ListMovements retrieves the stock history of a SKU in a store recorded
+// from the given time on, or ever without one, archived history included,
+// oldest first. Each movement carries the purchase receipt, delivery, sales
+// or production order its stock entry references, and is costed at the
+// receipt line price for receipts and at the SKU price otherwise.
+func (r *StocksRepository) ListMovements(ctx context.Context, skuID, storeID string, from *time.Time) ([]entity.StockMovement, error) {
+	var movements []entity.StockMovement
+	for _, table := range []string{"stock_history", archiveTable("stock_history")} {
+		db := r.db.WithContext(ctx)
+		if table != "stock_history" && !db.Migrator().HasTable(table) {
+			continue
+		}
+		entries := "stock_entries"
+		if table != "stock_history" {
+			entries = archiveTable(entries)
+		}
+
+		query := `
+			SELECT
+				h.id,
+				h.created_at,
+				h.type,
+				h.new_qty - h.previous_qty AS change,
+				h.reference AS entry_id,
+				e.reference,
+				e.batch_number,
+				h.note,
+				h.created_by,
+				CASE
+					WHEN pr.id IS NOT NULL THEN '` + entity.LedgerDocumentPurchaseReceipt + `'
+					WHEN d.id IS NOT NULL THEN '` + entity.LedgerDocumentDeliveryOrder + `'
+					WHEN so.id IS NOT NULL THEN '` + entity.LedgerDocumentSalesOrder + `'
+					WHEN po.id IS NOT NULL THEN '` + entity.LedgerDocumentProductionOrder + `'
+				END AS document_type,
+				COALESCE(pr.id::text, d.id::text, so.id::text, po.id::text) AS document_id,
+				COALESCE((
+					SELECT (item->>'unit_price')::numeric
+					FROM jsonb_array_elements(pr.items) item
+					WHERE item->>'sku_id' = s.sku_id::text
+					LIMIT 1
+				), k.price) AS unit_cost
+			FROM ` + table + ` h
+			JOIN stocks s ON s.id = h.stock_id
+			JOIN skus k ON k.id = s.sku_id
+			LEFT JOIN ` + entries + ` e ON e.id::text = h.reference
+			LEFT JOIN purchase_receipts pr ON pr.receipt_number = e.reference
+			LEFT JOIN delivery_orders d ON d.delivery_number = e.reference
+			LEFT JOIN sales_orders so ON so.order_number = e.reference
+			LEFT JOIN production_orders po ON 'PRD-' || po.id = e.reference
+			WHERE s.sku_id = ? AND s.store_id = ?
+		`
+		args := []interface{}{skuID, storeID}
+		if from != nil {
+			query += " AND h.created_at >= ?"
+			args = append(args, *from)
+		}
+
+		var batch []entity.StockMovement
+		if err := db.Raw(query, args...).Scan(&batch).Error; err != nil {
+			return nil, err
+		}
+		movements = append(movements, batch...)
+	}
+
+	sort.SliceStable(movements, func(i, j int) bool {
+		if !movements[i].CreatedAt.Equal(movements[j].CreatedAt) {
+			return movements[i].CreatedAt.Before(movements[j].CreatedAt)
+		}
+		return movements[i].ID < movements[j].ID
+	})
+	return movements, nil
+}
@@ -0,0 +1,272 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"gorm.io/gorm"
+)
+
+// runConcurrently runs n documents at once and returns the errors they fail with
+func runConcurrently(n int, document func(i int) error) []error {
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		errs  []error
+		start = make(chan struct{})
+	)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			if err := document(i); err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+	return errs
+}
+
+func stockEntry(skuID, storeID, entryType string, quantity float64) entity.StockEntry {
+	return entity.StockEntry{SKUID: skuID, StoreID: storeID, Type: entryType, Quantity: quantity, CreatedBy: "test"}
+}
+
+// assertStock checks the quantity of a stock record and the number of history
+// rows written against it
+func assertStock(t *testing.T, db *gorm.DB, skuID, storeID string, wantQty float64, wantHistory int64) {
+	t.Helper()
+	var stocks []entity.Stock
+	if err := db.Where("sku_id = ? AND store_id = ?", skuID, storeID).Find(&stocks).Error; err != nil {
+		t.Fatalf("loading stock: %v", err)
+	}
+	if len(stocks) != 1 {
+		t.Fatalf("got %d stock records, want 1", len(stocks))
+	}
+	if stocks[0].Quantity != wantQty {
+		t.Errorf("quantity = %v, want %v", stocks[0].Quantity, wantQty)
+	}
+
+	var history int64
+	if err := db.Model(&entity.StockHistory{}).Where("stock_id = ?", stocks[0].ID).Count(&history).Error; err != nil {
+		t.Fatalf("counting history: %v", err)
+	}
+	if history != wantHistory {
+		t.Errorf("history rows = %d, want %d", history, wantHistory)
+	}
+}
+
+// Concurrent entries at one stock record apply one after the other, so none
+// of their quantity changes is lost
+func TestProcessStockEntriesConcurrentSameStock(t *testing.T) {
+	db := openTestDB(t)
+	fixture := createTestFixture(t, db, 1)
+	repo := NewStocksRepository(db)
+	skuID, storeID := fixture.SKUIDs[0], fixture.StoreID
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	if err := repo.ProcessStockEntries(ctx, []entity.StockEntry{stockEntry(skuID, storeID, "IN", 1000)}, "test"); err != nil {
+		t.Fatalf("opening stock: %v", err)
+	}
+
+	const documents = 40
+	errs := runConcurrently(documents, func(i int) error {
+		entry := stockEntry(skuID, storeID, "IN", 5)
+		if i%2 == 1 {
+			entry = stockEntry(skuID, storeID, "OUT", 3)
+		}
+		return repo.ProcessStockEntries(ctx, []entity.StockEntry{entry}, "test")
+	})
+	for _, err := range errs {
+		t.Errorf("processing entry: %v", err)
+	}
+
+	assertStock(t, db, skuID, storeID, 1000+documents/2*5-documents/2*3, documents+1)
+}
+
+// Concurrent first receipts of a SKU in a store create a single stock record,
+// the advisory lock making all but one wait for it
+func TestProcessStockEntriesConcurrentFirstReceipt(t *testing.T) {
+	db := openTestDB(t)
+	fixture := createTestFixture(t, db, 1)
+	repo := NewStocksRepository(db)
+	skuID, storeID := fixture.SKUIDs[0], fixture.StoreID
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	const documents = 20
+	errs := runConcurrently(documents, func(int) error {
+		return repo.ProcessStockEntries(ctx, []entity.StockEntry{stockEntry(skuID, storeID, "IN", 2)}, "test")
+	})
+	for _, err := range errs {
+		t.Errorf("processing entry: %v", err)
+	}
+
+	assertStock(t, db, skuID, storeID, documents*2, documents)
+}
+
+// Documents listing the same SKUs in opposite orders lock their stock records
+// in key order, so they wait for each other instead of deadlocking
+func TestProcessStockEntriesConcurrentCrossingSKUs(t *testing.T) {
+	db := openTestDB(t)
+	fixture := createTestFixture(t, db, 2)
+	repo := NewStocksRepository(db)
+	a, b, storeID := fixture.SKUIDs[0], fixture.SKUIDs[1], fixture.StoreID
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	opening := []entity.StockEntry{stockEntry(a, storeID, "IN", 500), stockEntry(b, storeID, "IN", 500)}
+	if err := repo.ProcessStockEntries(ctx, opening, "test"); err != nil {
+		t.Fatalf("opening stock: %v", err)
+	}
+
+	// Each document moves one unit from one SKU to the other, alternating the
+	// order its lines are listed in
+	const documents = 40
+	errs := runConcurrently(documents, func(i int) error {
+		entries := []entity.StockEntry{stockEntry(a, storeID, "OUT", 1), stockEntry(b, storeID, "IN", 1)}
+		if i%2 == 1 {
+			entries = []entity.StockEntry{stockEntry(b, storeID, "OUT", 1), stockEntry(a, storeID, "IN", 1)}
+		}
+		// Hold the locks a moment so that the documents overlap
+		return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := repo.ProcessStockEntriesTx(ctx, tx, entries, "test"); err != nil {
+				return err
+			}
+			return tx.Exec("SELECT pg_sleep(0.01)").Error
+		})
+	})
+	for _, err := range errs {
+		t.Errorf("processing document: %v", err)
+	}
+
+	assertStock(t, db, a, storeID, 500, documents+1)
+	assertStock(t, db, b, storeID, 500, documents+1)
+}
+
+// An entry taking a stock record below zero fails the whole document, leaving
+// the records it already changed as they were
+func TestProcessStockEntriesInsufficientStockRollsBack(t *testing.T) {
+	db := openTestDB(t)
+	fixture := createTestFixture(t, db, 2)
+	repo := NewStocksRepository(db)
+	a, b, storeID := fixture.SKUIDs[0], fixture.SKUIDs[1], fixture.StoreID
+	ctx := context.Background()
+
+	if err := repo.ProcessStockEntries(ctx, []entity.StockEntry{stockEntry(a, storeID, "IN", 10), stockEntry(b, storeID, "IN", 1)}, "test"); err != nil {
+		t.Fatalf("opening stock: %v", err)
+	}
+
+	err := repo.ProcessStockEntries(ctx, []entity.StockEntry{stockEntry(a, storeID, "OUT", 4), stockEntry(b, storeID, "OUT", 2)}, "test")
+	if err != ErrInsufficientStock {
+		t.Fatalf("error = %v, want %v", err, ErrInsufficientStock)
+	}
+
+	assertStock(t, db, a, storeID, 10, 1)
+	assertStock(t, db, b, storeID, 1, 1)
+}
+
+func BenchmarkProcessStockEntriesContended(b *testing.B) {
+	db := openTestDB(b)
+	fixture := createTestFixture(b, db, 1)
+	repo := NewStocksRepository(db)
+	ctx := context.Background()
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			entry := stockEntry(fixture.SKUIDs[0], fixture.StoreID, "IN", 1)
+			if err := repo.ProcessStockEntries(ctx, []entity.StockEntry{entry}, "test"); err != nil {
+				b.Error(err)
+			}
+		}
+	})
+}
+
+// largeDocument returns the receipt lines of a 10k-line document spread over
+// the SKUs of a fixture, one unit each
+func largeDocument(fixture *testFixture) []entity.StockEntry {
+	entries := make([]entity.StockEntry, 0, 10000)
+	for i := 0; i < 10000; i++ {
+		entries = append(entries, stockEntry(fixture.SKUIDs[i%len(fixture.SKUIDs)], fixture.StoreID, "IN", 1))
+	}
+	return entries
+}
+
+// countRows counts the stock entries and history rows of the SKUs of a fixture
+func countRows(t *testing.T, db *gorm.DB, fixture *testFixture) (entries, history int64) {
+	t.Helper()
+	if err := db.Model(&entity.StockEntry{}).Where("sku_id IN ?", fixture.SKUIDs).Count(&entries).Error; err != nil {
+		t.Fatalf("counting entries: %v", err)
+	}
+	if err := db.Model(&entity.StockHistory{}).
+		Joins("JOIN stocks ON stocks.id = stock_history.stock_id").
+		Where("stocks.sku_id IN ?", fixture.SKUIDs).
+		Count(&history).Error; err != nil {
+		t.Fatalf("counting history: %v", err)
+	}
+	return entries, history
+}
+
+// A document larger than the insert batch size is written in several batches,
+// all of whose lines persist
+func TestProcessStockEntriesLargeDocument(t *testing.T) {
+	db := openTestDB(t)
+	fixture := createTestFixture(t, db, 50)
+	repo := NewStocksRepository(db)
+
+	entries := largeDocument(fixture)
+	if err := repo.ProcessStockEntries(context.Background(), entries, "test"); err != nil {
+		t.Fatalf("processing document: %v", err)
+	}
+
+	gotEntries, gotHistory := countRows(t, db, fixture)
+	if gotEntries != 10000 || gotHistory != 10000 {
+		t.Errorf("got %d entries and %d history rows, want 10000 of each", gotEntries, gotHistory)
+	}
+	for _, skuID := range fixture.SKUIDs {
+		stock, err := repo.GetBySKUAndStore(context.Background(), skuID, fixture.StoreID)
+		if err != nil {
+			t.Fatalf("loading stock: %v", err)
+		}
+		if stock.Quantity != 200 {
+			t.Errorf("quantity of %s = %v, want 200", skuID, stock.Quantity)
+		}
+	}
+}
+
+// A batch failing after earlier batches were inserted rolls the whole
+// document back, stock quantities included
+func TestProcessStockEntriesLargeDocumentRollsBack(t *testing.T) {
+	db := openTestDB(t)
+	fixture := createTestFixture(t, db, 50)
+	repo := NewStocksRepository(db)
+
+	// The repeated ID fails the batch holding line 7500
+	entries := largeDocument(fixture)
+	entries[0].ID = "5d2f1a44-3c3e-4f0e-9a51-2b7f3c1d9e01"
+	entries[7500].ID = entries[0].ID
+	if err := repo.ProcessStockEntries(context.Background(), entries, "test"); err == nil {
+		t.Fatal("processing document with a repeated line ID succeeded")
+	}
+
+	gotEntries, gotHistory := countRows(t, db, fixture)
+	if gotEntries != 0 || gotHistory != 0 {
+		t.Errorf("got %d entries and %d history rows, want none", gotEntries, gotHistory)
+	}
+	var stocks int64
+	if err := db.Model(&entity.Stock{}).Where("sku_id IN ?", fixture.SKUIDs).Count(&stocks).Error; err != nil {
+		t.Fatalf("counting stock: %v", err)
+	}
+	if stocks != 0 {
+		t.Errorf("got %d stock records, want none", stocks)
+	}
+}
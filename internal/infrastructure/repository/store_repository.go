@@ -61,7 +61,7 @@ func (r *StoreRepository) Delete(ctx context.Context, id string) error {
 // List lists stores with optional filtering
 func (r *StoreRepository) List(ctx context.Context, filter *entity.StoreFilter) ([]entity.Store, error) {
 	var stores []entity.Store
-	query := r.db.WithContext(ctx).Model(&entity.Store{})
+	query := scopeStores(ctx, r.db.WithContext(ctx).Model(&entity.Store{}), "id")
 
 	// Apply filters if provided
 	if filter != nil {
@@ -124,7 +124,7 @@ func (r *StoreRepository) GetStoreStockValue(ctx context.Context, storeID string
 // GetStoresWithLowStock finds stores with stock levels below threshold for specified SKUs
 func (r *StoreRepository) GetStoresWithLowStock(ctx context.Context, skuIDs []string, threshold float64) (map[string][]entity.Stock, error) {
 	var stocks []entity.Stock
-	err := r.db.WithContext(ctx).
+	err := scopeStores(ctx, r.db.WithContext(ctx), "store_id").
 		Where("sku_id IN ? AND quantity <= ?", skuIDs, threshold).
 		Find(&stocks).Error
 
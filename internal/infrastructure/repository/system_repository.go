@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"gorm.io/gorm"
+)
+
+// SystemRepository provides database diagnostics for administrators
+type SystemRepository struct {
+	db *gorm.DB
+}
+
+// NewSystemRepository creates a new system repository
+func NewSystemRepository(db *gorm.DB) *SystemRepository {
+	return &SystemRepository{db: db}
+}
+
+// HasQueryStats reports whether pg_stat_statements is installed in the current database
+func (r *SystemRepository) HasQueryStats(ctx context.Context) (bool, error) {
+	var exists bool
+	if err := r.db.WithContext(ctx).
+		Raw("SELECT EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'pg_stat_statements')").
+		Scan(&exists).Error; err != nil {
+		return false, err
+	}
+	return exists, nil
+}
+
+// GetSlowQueries retrieves the slowest statements by mean execution time from pg_stat_statements
+func (r *SystemRepository) GetSlowQueries(ctx context.Context, filter *entity.SlowQueryFilter) ([]entity.SlowQuery, error) {
+	exists, err := r.HasQueryStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrQueryStatsUnavailable
+	}
+
+	query := `
+		SELECT
+			s.query AS query,
+			s.calls AS calls,
+			s.total_exec_time AS total_time_ms,
+			s.mean_exec_time AS mean_time_ms,
+			s.max_exec_time AS max_time_ms,
+			s.rows AS rows,
+			s.shared_blks_hit AS shared_blks_hit,
+			s.shared_blks_read AS shared_blks_read,
+			CASE WHEN s.shared_blks_hit + s.shared_blks_read = 0 THEN 100
+				ELSE 100.0 * s.shared_blks_hit / (s.shared_blks_hit + s.shared_blks_read)
+			END AS cache_hit_percent
+		FROM pg_stat_statements s
+		JOIN pg_database d ON d.oid = s.dbid
+		WHERE d.datname = current_database()
+			AND s.mean_exec_time >= ?
+		ORDER BY s.mean_exec_time DESC
+		LIMIT ?
+	`
+
+	var queries []entity.SlowQuery
+	if err := r.db.WithContext(ctx).Raw(query, filter.MinMeanTimeMs, filter.Limit).Scan(&queries).Error; err != nil {
+		return nil, err
+	}
+	return queries, nil
+}
+
+// GetPoolStats retrieves connection pool statistics from the underlying sql.DB
+func (r *SystemRepository) GetPoolStats(ctx context.Context) (*entity.DBPoolStats, error) {
+	sqlDB, err := r.db.WithContext(ctx).DB()
+	if err != nil {
+		return nil, err
+	}
+
+	stats := sqlDB.Stats()
+	poolStats := &entity.DBPoolStats{
+		MaxOpenConnections: stats.MaxOpenConnections,
+		OpenConnections:    stats.OpenConnections,
+		InUse:              stats.InUse,
+		Idle:               stats.Idle,
+		WaitCount:          stats.WaitCount,
+		WaitDurationMs:     stats.WaitDuration.Milliseconds(),
+		MaxIdleClosed:      stats.MaxIdleClosed,
+		MaxIdleTimeClosed:  stats.MaxIdleTimeClosed,
+		MaxLifetimeClosed:  stats.MaxLifetimeClosed,
+	}
+	if stats.MaxOpenConnections > 0 {
+		poolStats.SaturationPercent = float64(stats.InUse) * 100 / float64(stats.MaxOpenConnections)
+	}
+
+	return poolStats, nil
+}
@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/database"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testDSNEnv names the variable holding the DSN of a disposable PostgreSQL
+// database the repository tests run against. They are skipped without it.
+const testDSNEnv = "ERP_TEST_DATABASE_DSN"
+
+var (
+	testDBOnce sync.Once
+	testDB     *gorm.DB
+	testDBErr  error
+)
+
+// openTestDB connects to the test database, migrating it on first use
+func openTestDB(tb testing.TB) *gorm.DB {
+	tb.Helper()
+	dsn := os.Getenv(testDSNEnv)
+	if dsn == "" {
+		tb.Skipf("%s is not set", testDSNEnv)
+	}
+
+	testDBOnce.Do(func() {
+		testDB, testDBErr = gorm.Open(postgres.Open(dsn), &gorm.Config{
+			Logger: logger.Default.LogMode(logger.Silent),
+		})
+		if testDBErr != nil {
+			return
+		}
+		sqlDB, err := testDB.DB()
+		if err != nil {
+			testDBErr = err
+			return
+		}
+		sqlDB.SetMaxOpenConns(50)
+		migrator, err := database.NewMigrator(sqlDB)
+		if err != nil {
+			testDBErr = err
+			return
+		}
+		_, testDBErr = migrator.Up(context.Background())
+	})
+	if testDBErr != nil {
+		tb.Fatalf("opening test database: %v", testDBErr)
+	}
+	return testDB
+}
+
+// testFixture holds the rows the stock of a test is kept against. Names are
+// unique so that tests sharing the database do not collide.
+type testFixture struct {
+	UserID  uint
+	StoreID string
+	SKUIDs  []string
+}
+
+// createTestFixture creates a user managing a new store and skus new SKUs
+func createTestFixture(tb testing.TB, db *gorm.DB, skus int) *testFixture {
+	tb.Helper()
+	suffix := uuid.New().String()[:8]
+
+	role := &entity.Role{Name: "test-" + suffix}
+	if err := db.Create(role).Error; err != nil {
+		tb.Fatalf("creating role: %v", err)
+	}
+	user := &entity.User{
+		Username: "test-" + suffix,
+		Email:    "test-" + suffix + "@example.com",
+		Password: "x",
+		RoleID:   role.ID,
+		Status:   entity.StatusActive,
+	}
+	if err := db.Create(user).Error; err != nil {
+		tb.Fatalf("creating user: %v", err)
+	}
+	store := &entity.Store{
+		ID:        uuid.New().String(),
+		Name:      "Test " + suffix,
+		Code:      "T" + suffix,
+		Type:      entity.StoreTypeFinished,
+		ManagerID: user.ID,
+		Status:    entity.StoreStatusActive,
+	}
+	if err := db.Create(store).Error; err != nil {
+		tb.Fatalf("creating store: %v", err)
+	}
+
+	fixture := &testFixture{UserID: user.ID, StoreID: store.ID}
+	for i := 0; i < skus; i++ {
+		sku := &entity.SKU{
+			ID:            uuid.New().String(),
+			SKUCode:       "T-" + suffix + "-" + uuid.New().String()[:8],
+			Name:          "Test item",
+			UnitOfMeasure: "PCS",
+		}
+		if err := db.Create(sku).Error; err != nil {
+			tb.Fatalf("creating sku: %v", err)
+		}
+		fixture.SKUIDs = append(fixture.SKUIDs, sku.ID)
+	}
+	return fixture
+}
+
+// queryCounter counts the SELECT statements run through a database session
+type queryCounter struct {
+	queries atomic.Int64
+}
+
+// countQueries returns a session of db counting its queries, preloads
+// included, with the counter. The session has callbacks of its own, leaving
+// those of db alone.
+func countQueries(tb testing.TB, db *gorm.DB) (*gorm.DB, *queryCounter) {
+	tb.Helper()
+	sqlDB, err := db.DB()
+	if err != nil {
+		tb.Fatalf("getting database handle: %v", err)
+	}
+	counted, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		tb.Fatalf("opening counted session: %v", err)
+	}
+
+	counter := &queryCounter{}
+	count := func(*gorm.DB) { counter.queries.Add(1) }
+	if err := counted.Callback().Query().Before("gorm:query").Register("test:count_queries", count); err != nil {
+		tb.Fatalf("registering query counter: %v", err)
+	}
+	if err := counted.Callback().Row().Before("gorm:row").Register("test:count_rows", count); err != nil {
+		tb.Fatalf("registering row counter: %v", err)
+	}
+	return counted, counter
+}
+
+// Count returns the queries counted since the last reset and resets the count
+func (c *queryCounter) Count() int64 {
+	return c.queries.Swap(0)
+}
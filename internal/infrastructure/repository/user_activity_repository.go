@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"gorm.io/gorm"
+)
+
+// UserLoginCount is the number of a user's sign-ins with one outcome
+type UserLoginCount struct {
+	UserID  uint
+	Success bool
+	Count   int64
+	Last    time.Time
+}
+
+// UserModuleActivity is the number of a user's audited requests to one API module
+type UserModuleActivity struct {
+	UserID  uint
+	Module  string
+	Actions int64
+	Last    time.Time
+}
+
+type UserActivityRepository struct {
+	db *gorm.DB
+}
+
+func NewUserActivityRepository(db *gorm.DB) *UserActivityRepository {
+	return &UserActivityRepository{db: db}
+}
+
+// CreateLoginAttempt records a sign-in attempt
+func (r *UserActivityRepository) CreateLoginAttempt(ctx context.Context, attempt *entity.LoginAttempt) error {
+	return r.db.WithContext(ctx).Create(attempt).Error
+}
+
+// ListLoginAttempts retrieves the sign-in attempts matching a filter, latest first
+func (r *UserActivityRepository) ListLoginAttempts(ctx context.Context, filter *entity.LoginAttemptFilter) ([]entity.LoginAttempt, int64, error) {
+	var attempts []entity.LoginAttempt
+	var total int64
+
+	query := r.loginAttemptQuery(ctx, filter)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (filter.Page - 1) * filter.PageSize
+	if err := query.
+		Order("created_at DESC, id DESC").
+		Limit(filter.PageSize).
+		Offset(offset).
+		Find(&attempts).Error; err != nil {
+		return nil, 0, err
+	}
+	return attempts, total, nil
+}
+
+// TopLoginSources counts the sign-in attempts matching a filter per value of the
+// given column, email or ip, most attempts first
+func (r *UserActivityRepository) TopLoginSources(ctx context.Context, filter *entity.LoginAttemptFilter, column string, limit int) ([]entity.FailedLoginSource, error) {
+	var sources []entity.FailedLoginSource
+	if err := r.loginAttemptQuery(ctx, filter).
+		Select(column + " AS key, COUNT(*) AS attempts, MAX(created_at) AS last_attempt").
+		Where(column + " <> ''").
+		Group(column).
+		Order("attempts DESC, last_attempt DESC").
+		Limit(limit).
+		Scan(&sources).Error; err != nil {
+		return nil, err
+	}
+	return sources, nil
+}
+
+// CountLogins counts the successful and failed sign-ins of each user in a period
+func (r *UserActivityRepository) CountLogins(ctx context.Context, filter *entity.UserActivityFilter) ([]UserLoginCount, error) {
+	query := r.db.WithContext(ctx).
+		Model(&entity.LoginAttempt{}).
+		Select("user_id, success, COUNT(*) AS count, MAX(created_at) AS last").
+		Where("user_id IS NOT NULL")
+	query = activityPeriod(query, filter)
+
+	var counts []UserLoginCount
+	if err := query.Group("user_id, success").Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// CountModuleActivity counts the audited requests of each user per API module in
+// a period. The module is the path segment after the API version, so requests
+// to /api/v1/skus/... count towards "skus".
+func (r *UserActivityRepository) CountModuleActivity(ctx context.Context, filter *entity.UserActivityFilter) ([]UserModuleActivity, error) {
+	query := r.db.WithContext(ctx).
+		Model(&entity.AuditLog{}).
+		Select("user_id, SPLIT_PART(resource, '/', 4) AS module, COUNT(*) AS actions, MAX(created_at) AS last")
+	query = activityPeriod(query, filter)
+
+	var activity []UserModuleActivity
+	if err := query.Group("user_id, module").Scan(&activity).Error; err != nil {
+		return nil, err
+	}
+	return activity, nil
+}
+
+// ListUsers retrieves the users with their role, or only the given one
+func (r *UserActivityRepository) ListUsers(ctx context.Context, userID uint) ([]entity.User, error) {
+	query := r.db.WithContext(ctx).Preload("Role")
+	if userID != 0 {
+		query = query.Where("id = ?", userID)
+	}
+
+	var users []entity.User
+	if err := query.Order("id").Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// ListInactiveUsers retrieves the active users who have not signed in since the
+// cutoff, or were created before it and never signed in
+func (r *UserActivityRepository) ListInactiveUsers(ctx context.Context, cutoff time.Time) ([]entity.User, error) {
+	var users []entity.User
+	if err := r.db.WithContext(ctx).
+		Preload("Role").
+		Where("status = ?", entity.StatusActive).
+		Where("COALESCE(last_login, created_at) < ?", cutoff).
+		Order("COALESCE(last_login, created_at), id").
+		Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (r *UserActivityRepository) loginAttemptQuery(ctx context.Context, filter *entity.LoginAttemptFilter) *gorm.DB {
+	query := r.db.WithContext(ctx).Model(&entity.LoginAttempt{})
+	if filter.UserID != 0 {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.Email != "" {
+		query = query.Where("email ILIKE ?", "%"+filter.Email+"%")
+	}
+	if filter.IP != "" {
+		query = query.Where("ip = ?", filter.IP)
+	}
+	if filter.Success != nil {
+		query = query.Where("success = ?", *filter.Success)
+	}
+	if filter.StartDate != nil {
+		query = query.Where("created_at >= ?", filter.StartDate)
+	}
+	if filter.EndDate != nil {
+		query = query.Where("created_at <= ?", filter.EndDate)
+	}
+	return query
+}
+
+func activityPeriod(query *gorm.DB, filter *entity.UserActivityFilter) *gorm.DB {
+	if filter.UserID != 0 {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.StartDate != nil {
+		query = query.Where("created_at >= ?", filter.StartDate)
+	}
+	if filter.EndDate != nil {
+		query = query.Where("created_at <= ?", filter.EndDate)
+	}
+	return query
+}
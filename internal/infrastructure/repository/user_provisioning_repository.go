@@ -0,0 +1,199 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"gorm.io/gorm"
+)
+
+type UserProvisioningRepository struct {
+	db *gorm.DB
+}
+
+func NewUserProvisioningRepository(db *gorm.DB) *UserProvisioningRepository {
+	return &UserProvisioningRepository{db: db}
+}
+
+// CreateRoleMapping maps a directory group to a role
+func (r *UserProvisioningRepository) CreateRoleMapping(ctx context.Context, mapping *entity.RoleMapping) error {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&entity.RoleMapping{}).
+		Where("LOWER(external_group) = LOWER(?)", mapping.ExternalGroup).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrDuplicateEntry
+	}
+	return r.db.WithContext(ctx).Omit("Role").Create(mapping).Error
+}
+
+// DeleteRoleMapping removes a role mapping
+func (r *UserProvisioningRepository) DeleteRoleMapping(ctx context.Context, id uint) error {
+	result := r.db.WithContext(ctx).Delete(&entity.RoleMapping{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// ListRoleMappings retrieves all role mappings with their roles
+func (r *UserProvisioningRepository) ListRoleMappings(ctx context.Context) ([]entity.RoleMapping, error) {
+	var mappings []entity.RoleMapping
+	if err := r.db.WithContext(ctx).
+		Preload("Role").
+		Order("external_group").
+		Find(&mappings).Error; err != nil {
+		return nil, err
+	}
+	return mappings, nil
+}
+
+// FindRoleMappings retrieves the role mappings of the given groups, matched
+// case-insensitively
+func (r *UserProvisioningRepository) FindRoleMappings(ctx context.Context, groups []string) ([]entity.RoleMapping, error) {
+	if len(groups) == 0 {
+		return nil, nil
+	}
+	lowered := make([]string, len(groups))
+	for i, group := range groups {
+		lowered[i] = strings.ToLower(group)
+	}
+
+	var mappings []entity.RoleMapping
+	if err := r.db.WithContext(ctx).
+		Preload("Role").
+		Where("LOWER(external_group) IN ?", lowered).
+		Find(&mappings).Error; err != nil {
+		return nil, err
+	}
+	return mappings, nil
+}
+
+// FindRolesByName retrieves the roles with the given names, matched
+// case-insensitively
+func (r *UserProvisioningRepository) FindRolesByName(ctx context.Context, names []string) ([]entity.Role, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	lowered := make([]string, len(names))
+	for i, name := range names {
+		lowered[i] = strings.ToLower(name)
+	}
+
+	var roles []entity.Role
+	if err := r.db.WithContext(ctx).
+		Where("LOWER(name) IN ?", lowered).
+		Find(&roles).Error; err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+// GetUser retrieves a user with their role
+func (r *UserProvisioningRepository) GetUser(ctx context.Context, id uint) (*entity.User, error) {
+	var user entity.User
+	if err := r.db.WithContext(ctx).Preload("Role").First(&user, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// FindUser retrieves the user with the given directory ID or, failing that, the
+// given email
+func (r *UserProvisioningRepository) FindUser(ctx context.Context, externalID, email string) (*entity.User, error) {
+	var user entity.User
+	if externalID != "" {
+		err := r.db.WithContext(ctx).Preload("Role").Where("external_id = ?", externalID).First(&user).Error
+		if err == nil {
+			return &user, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+	if email != "" {
+		err := r.db.WithContext(ctx).Preload("Role").Where("LOWER(email) = LOWER(?)", email).First(&user).Error
+		if err == nil {
+			return &user, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+	return nil, ErrRecordNotFound
+}
+
+// IdentityTaken reports whether a user other than the given one already has the
+// username or email
+func (r *UserProvisioningRepository) IdentityTaken(ctx context.Context, userID uint, username, email string) (bool, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&entity.User{}).
+		Where("id <> ?", userID).
+		Where("LOWER(username) = LOWER(?) OR LOWER(email) = LOWER(?)", username, email).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// ListUsers retrieves the users matching a filter, in ID order
+func (r *UserProvisioningRepository) ListUsers(ctx context.Context, filter *entity.ProvisionedUserFilter) ([]entity.User, int64, error) {
+	var users []entity.User
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&entity.User{})
+	if filter.ExternalID != "" {
+		query = query.Where("external_id = ?", filter.ExternalID)
+	}
+	if filter.Username != "" {
+		query = query.Where("LOWER(username) = LOWER(?)", filter.Username)
+	}
+	if filter.Email != "" {
+		query = query.Where("LOWER(email) = LOWER(?)", filter.Email)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if err := query.
+		Preload("Role").
+		Order("id").
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Find(&users).Error; err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
+// CreateUser creates a provisioned user
+func (r *UserProvisioningRepository) CreateUser(ctx context.Context, user *entity.User) error {
+	return r.db.WithContext(ctx).Omit("Role").Create(user).Error
+}
+
+// UpdateUser saves a provisioned user's directory attributes, role and status.
+// Deactivating a user also ends their sessions.
+func (r *UserProvisioningRepository) UpdateUser(ctx context.Context, user *entity.User) error {
+	updates := map[string]interface{}{
+		"external_id": user.ExternalID,
+		"username":    user.Username,
+		"email":       user.Email,
+		"role_id":     user.RoleID,
+		"status":      user.Status,
+	}
+	if user.Status != entity.StatusActive {
+		updates["refresh_token"] = ""
+	}
+	return r.db.WithContext(ctx).Model(&entity.User{}).Where("id = ?", user.ID).Updates(updates).Error
+}
@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"gorm.io/gorm"
+)
+
+// VendorPriceListRepository handles database operations for vendor price
+// lists and their quantity breaks
+type VendorPriceListRepository struct {
+	db *gorm.DB
+}
+
+func NewVendorPriceListRepository(db *gorm.DB) *VendorPriceListRepository {
+	return &VendorPriceListRepository{db: db}
+}
+
+// Create creates a price list with its items
+func (r *VendorPriceListRepository) Create(ctx context.Context, priceList *entity.VendorPriceList) error {
+	return r.db.WithContext(ctx).Create(priceList).Error
+}
+
+// Update saves a price list, replacing its items
+func (r *VendorPriceListRepository) Update(ctx context.Context, priceList *entity.VendorPriceList) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("price_list_id = ?", priceList.ID).Delete(&entity.VendorPriceListItem{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Omit("Items").Save(priceList).Error; err != nil {
+			return err
+		}
+		for i := range priceList.Items {
+			priceList.Items[i].ID = 0
+			priceList.Items[i].PriceListID = priceList.ID
+		}
+		if len(priceList.Items) == 0 {
+			return nil
+		}
+		return tx.Create(&priceList.Items).Error
+	})
+}
+
+// Delete deletes a price list with its items
+func (r *VendorPriceListRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("price_list_id = ?", id).Delete(&entity.VendorPriceListItem{}).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(&entity.VendorPriceList{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+// Get retrieves a price list by ID with its items
+func (r *VendorPriceListRepository) Get(ctx context.Context, id uint) (*entity.VendorPriceList, error) {
+	var priceList entity.VendorPriceList
+	if err := r.db.WithContext(ctx).
+		Preload("Items", func(db *gorm.DB) *gorm.DB {
+			return db.Order("sku_id, min_quantity")
+		}).
+		First(&priceList, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &priceList, nil
+}
+
+// List retrieves the price lists of a vendor without their items, latest
+// first, only those valid on the given day when one is given
+func (r *VendorPriceListRepository) List(ctx context.Context, vendorID uint, validOn *time.Time) ([]entity.VendorPriceList, error) {
+	var priceLists []entity.VendorPriceList
+	query := r.db.WithContext(ctx).Where("vendor_id = ?", vendorID)
+	if validOn != nil {
+		query = query.Where("valid_from <= ? AND (valid_to IS NULL OR valid_to >= ?)", validOn, validOn)
+	}
+	if err := query.Order("valid_from DESC, id DESC").Find(&priceLists).Error; err != nil {
+		return nil, err
+	}
+	return priceLists, nil
+}
+
+// FindPrice returns the price a vendor's price lists valid on the day give a
+// SKU for a quantity: the highest quantity break the quantity reaches, on
+// the list valid from the latest date
+func (r *VendorPriceListRepository) FindPrice(ctx context.Context, vendorID uint, skuID string, quantity float64, day time.Time) (*entity.VendorPrice, error) {
+	var price entity.VendorPrice
+	result := r.db.WithContext(ctx).
+		Table("vendor_price_list_items AS i").
+		Select("l.vendor_id, i.sku_id, i.unit_price, l.currency_code, l.id AS price_list_id, i.min_quantity").
+		Joins("JOIN vendor_price_lists AS l ON l.id = i.price_list_id").
+		Where("l.vendor_id = ? AND i.sku_id = ? AND i.min_quantity <= ?", vendorID, skuID, quantity).
+		Where("l.valid_from <= ? AND (l.valid_to IS NULL OR l.valid_to >= ?)", day, day).
+		Order("l.valid_from DESC, l.id DESC, i.min_quantity DESC").
+		Limit(1).
+		Scan(&price)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrRecordNotFound
+	}
+	price.Quantity = quantity
+	return &price, nil
+}
@@ -69,6 +69,7 @@ func (r *VendorRepository) List(ctx context.Context, filter entity.VendorFilter)
 	if filter.MinRating != nil {
 		query = query.Where("rating >= ?", *filter.MinRating)
 	}
+	query = whereCustomFields(query, filter.CustomFields)
 	if len(filter.ProductIDs) > 0 {
 		query = query.Joins("JOIN vendor_products ON vendor_products.vendor_id = vendors.id").
 			Where("vendor_products.product_id IN ?", filter.ProductIDs).
@@ -224,3 +225,76 @@ func (r *VendorRepository) GetVendorAverageRating(ctx context.Context, vendorID
 		Scan(&avgRating).Error
 	return avgRating, err
 }
+
+// ListScorecardOrders retrieves the purchase orders that count towards vendor scorecards.
+// Draft and cancelled orders are excluded since they never reached the vendor.
+func (r *VendorRepository) ListScorecardOrders(ctx context.Context, filter entity.VendorScorecardFilter) ([]entity.PurchaseOrder, error) {
+	var orders []entity.PurchaseOrder
+	query := r.db.WithContext(ctx).Model(&entity.PurchaseOrder{}).
+		Where("status NOT IN ?", []entity.PurchaseOrderStatus{
+			entity.PurchaseOrderStatusDraft,
+			entity.PurchaseOrderStatusCancelled,
+		})
+
+	if len(filter.VendorIDs) > 0 {
+		query = query.Where("vendor_id IN ?", filter.VendorIDs)
+	}
+	if filter.StartDate != nil {
+		query = query.Where("order_date >= ?", filter.StartDate)
+	}
+	if filter.EndDate != nil {
+		query = query.Where("order_date <= ?", filter.EndDate)
+	}
+
+	if err := query.Find(&orders).Error; err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// ListReceiptsByOrderIDs retrieves the receipts of the given purchase orders, oldest first
+func (r *VendorRepository) ListReceiptsByOrderIDs(ctx context.Context, orderIDs []string) ([]entity.PurchaseReceipt, error) {
+	var receipts []entity.PurchaseReceipt
+	if len(orderIDs) == 0 {
+		return receipts, nil
+	}
+
+	err := r.db.WithContext(ctx).
+		Where("purchase_order_id IN ?", orderIDs).
+		Order("receipt_date ASC").
+		Find(&receipts).Error
+	return receipts, err
+}
+
+// SumReturnedQuantities sums the sales returns of each vendor's designated SKUs,
+// recorded in the filter period
+func (r *VendorRepository) SumReturnedQuantities(ctx context.Context, filter entity.VendorScorecardFilter) (map[uint]float64, error) {
+	var rows []struct {
+		VendorID uint
+		Quantity float64
+	}
+	query := r.db.WithContext(ctx).
+		Table("sales_returns").
+		Select("skus.vendor_id, SUM(sales_returns.quantity) AS quantity").
+		Joins("JOIN skus ON skus.id = sales_returns.sku_id").
+		Where("skus.vendor_id IS NOT NULL")
+
+	if len(filter.VendorIDs) > 0 {
+		query = query.Where("skus.vendor_id IN ?", filter.VendorIDs)
+	}
+	if filter.StartDate != nil {
+		query = query.Where("sales_returns.created_at >= ?", filter.StartDate)
+	}
+	if filter.EndDate != nil {
+		query = query.Where("sales_returns.created_at <= ?", filter.EndDate)
+	}
+
+	if err := query.Group("skus.vendor_id").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	returned := make(map[uint]float64, len(rows))
+	for _, row := range rows {
+		returned[row.VendorID] = row.Quantity
+	}
+	return returned, nil
+}
@@ -0,0 +1,234 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"gorm.io/gorm"
+)
+
+// openPurchaseOrderStatuses are the purchase order statuses committed to a vendor
+// but not yet fully received
+var openPurchaseOrderStatuses = []entity.PurchaseOrderStatus{
+	entity.PurchaseOrderStatusApproved,
+	entity.PurchaseOrderStatusSent,
+	entity.PurchaseOrderStatusConfirmed,
+	entity.PurchaseOrderStatusPartial,
+}
+
+// VendorRiskRepository handles database operations for vendor risk scores and alerts
+type VendorRiskRepository struct {
+	db *gorm.DB
+}
+
+// NewVendorRiskRepository creates a new vendor risk repository
+func NewVendorRiskRepository(db *gorm.DB) *VendorRiskRepository {
+	return &VendorRiskRepository{db: db}
+}
+
+// ListOpenOrders retrieves the purchase orders committed to vendors but not yet fully received
+func (r *VendorRiskRepository) ListOpenOrders(ctx context.Context) ([]entity.PurchaseOrder, error) {
+	var orders []entity.PurchaseOrder
+	if err := r.db.WithContext(ctx).
+		Select("id, vendor_id, exchange_rate, base_grand_total").
+		Where("status IN ?", openPurchaseOrderStatuses).
+		Find(&orders).Error; err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// ListReceipts retrieves the receipts of the given purchase orders
+func (r *VendorRiskRepository) ListReceipts(ctx context.Context, orderIDs []string) ([]entity.PurchaseReceipt, error) {
+	var receipts []entity.PurchaseReceipt
+	if len(orderIDs) == 0 {
+		return receipts, nil
+	}
+
+	err := r.db.WithContext(ctx).
+		Select("id, purchase_order_id, items").
+		Where("purchase_order_id IN ?", orderIDs).
+		Find(&receipts).Error
+	return receipts, err
+}
+
+// SumPayments returns the amount paid against each of the given purchase orders,
+// in the order currency
+func (r *VendorRiskRepository) SumPayments(ctx context.Context, orderIDs []string) (map[string]float64, error) {
+	paid := make(map[string]float64, len(orderIDs))
+	if len(orderIDs) == 0 {
+		return paid, nil
+	}
+
+	var rows []struct {
+		PurchaseOrderID string
+		Amount          float64
+	}
+	if err := r.db.WithContext(ctx).
+		Model(&entity.PurchasePayment{}).
+		Select("purchase_order_id, SUM(amount) AS amount").
+		Where("purchase_order_id IN ?", orderIDs).
+		Group("purchase_order_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		paid[row.PurchaseOrderID] = row.Amount
+	}
+	return paid, nil
+}
+
+// ListSourcingOrders retrieves the vendor, date and lines of the purchase orders
+// placed since the given date. Draft and cancelled orders are left out.
+func (r *VendorRiskRepository) ListSourcingOrders(ctx context.Context, since time.Time) ([]entity.PurchaseOrder, error) {
+	var orders []entity.PurchaseOrder
+	if err := r.db.WithContext(ctx).
+		Select("id, vendor_id, order_date, items").
+		Where("order_date >= ?", since).
+		Where("status NOT IN ?", []entity.PurchaseOrderStatus{entity.PurchaseOrderStatusDraft, entity.PurchaseOrderStatusCancelled}).
+		Find(&orders).Error; err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// ListSourcedSKUs retrieves the SKUs that are not archived and either have a
+// designated vendor or are among the given IDs
+func (r *VendorRiskRepository) ListSourcedSKUs(ctx context.Context, skuIDs []string) ([]entity.SKU, error) {
+	var skus []entity.SKU
+	query := r.db.WithContext(ctx).
+		Select("id, sku_code, name, category, vendor_id").
+		Where("status <> ?", entity.SKUStatusArchived)
+	if len(skuIDs) > 0 {
+		query = query.Where("(vendor_id IS NOT NULL OR id IN ?)", skuIDs)
+	} else {
+		query = query.Where("vendor_id IS NOT NULL")
+	}
+
+	if err := query.Order("sku_code").Find(&skus).Error; err != nil {
+		return nil, err
+	}
+	return skus, nil
+}
+
+// ListOpenSalesOrders retrieves the confirmed and processing sales orders with
+// their delivery orders
+func (r *VendorRiskRepository) ListOpenSalesOrders(ctx context.Context) ([]entity.SalesOrder, error) {
+	var orders []entity.SalesOrder
+	if err := r.db.WithContext(ctx).
+		Where("status IN ?", []entity.SalesOrderStatus{entity.SalesOrderStatusConfirmed, entity.SalesOrderStatusProcessing}).
+		Preload("DeliveryOrders").
+		Find(&orders).Error; err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// SumOnHand returns the quantity on hand of each of the given SKUs across all stores
+func (r *VendorRiskRepository) SumOnHand(ctx context.Context, skuIDs []string) (map[string]float64, error) {
+	onHand := make(map[string]float64, len(skuIDs))
+	if len(skuIDs) == 0 {
+		return onHand, nil
+	}
+
+	var rows []struct {
+		SKUID    string `gorm:"column:sku_id"`
+		Quantity float64
+	}
+	if err := r.db.WithContext(ctx).
+		Model(&entity.Stock{}).
+		Select("sku_id, SUM(quantity) AS quantity").
+		Where("sku_id IN ?", skuIDs).
+		Group("sku_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		onHand[row.SKUID] = row.Quantity
+	}
+	return onHand, nil
+}
+
+// ReplaceScores replaces all stored vendor risk scores with the given ones
+func (r *VendorRiskRepository) ReplaceScores(ctx context.Context, scores []entity.VendorRiskScore) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("1 = 1").Delete(&entity.VendorRiskScore{}).Error; err != nil {
+			return err
+		}
+		if len(scores) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(&scores, createBatchSize(tx)).Error
+	})
+}
+
+// ListScores retrieves the stored vendor risk scores matching a filter, riskiest first
+func (r *VendorRiskRepository) ListScores(ctx context.Context, filter entity.VendorRiskFilter) ([]entity.VendorRiskScore, error) {
+	var scores []entity.VendorRiskScore
+	query := r.db.WithContext(ctx).Model(&entity.VendorRiskScore{})
+	if filter.Level != "" {
+		query = query.Where("level = ?", filter.Level)
+	}
+	if filter.MinScore > 0 {
+		query = query.Where("score >= ?", filter.MinScore)
+	}
+
+	if err := query.Order("score DESC, vendor_id").Find(&scores).Error; err != nil {
+		return nil, err
+	}
+	return scores, nil
+}
+
+// CreateAlerts stores vendor risk alerts
+func (r *VendorRiskRepository) CreateAlerts(ctx context.Context, alerts []entity.VendorRiskAlert) error {
+	if len(alerts) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Create(&alerts).Error
+}
+
+// ListAlerts retrieves vendor risk alerts with filters and pagination, latest first
+func (r *VendorRiskRepository) ListAlerts(ctx context.Context, filter *entity.VendorRiskAlertFilter) ([]entity.VendorRiskAlert, int64, error) {
+	var alerts []entity.VendorRiskAlert
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&entity.VendorRiskAlert{})
+	if filter.VendorID != 0 {
+		query = query.Where("vendor_id = ?", filter.VendorID)
+	}
+	if filter.Acknowledged != nil {
+		if *filter.Acknowledged {
+			query = query.Where("acknowledged_at IS NOT NULL")
+		} else {
+			query = query.Where("acknowledged_at IS NULL")
+		}
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (filter.Page - 1) * filter.PageSize
+	if err := query.Order("created_at DESC, id DESC").Limit(filter.PageSize).Offset(offset).Find(&alerts).Error; err != nil {
+		return nil, 0, err
+	}
+	return alerts, total, nil
+}
+
+// GetAlert retrieves a vendor risk alert by ID
+func (r *VendorRiskRepository) GetAlert(ctx context.Context, id uint) (*entity.VendorRiskAlert, error) {
+	var alert entity.VendorRiskAlert
+	if err := r.db.WithContext(ctx).First(&alert, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &alert, nil
+}
+
+// UpdateAlert saves a vendor risk alert
+func (r *VendorRiskRepository) UpdateAlert(ctx context.Context, alert *entity.VendorRiskAlert) error {
+	return r.db.WithContext(ctx).Save(alert).Error
+}
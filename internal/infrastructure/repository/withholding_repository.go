@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"gorm.io/gorm"
+)
+
+// WithholdingRepository handles database operations for withholding tax rates
+// and the tax withheld from supplier payments
+type WithholdingRepository struct {
+	db *gorm.DB
+}
+
+// NewWithholdingRepository creates a new withholding repository
+func NewWithholdingRepository(db *gorm.DB) *WithholdingRepository {
+	return &WithholdingRepository{db: db}
+}
+
+// CreateRate creates a withholding tax rate. A vendor or a country has a
+// single rate.
+func (r *WithholdingRepository) CreateRate(ctx context.Context, rate *entity.WithholdingTaxRate) error {
+	if err := r.checkRate(ctx, rate); err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Omit("Vendor").Create(rate).Error
+}
+
+// UpdateRate saves a withholding tax rate
+func (r *WithholdingRepository) UpdateRate(ctx context.Context, rate *entity.WithholdingTaxRate) error {
+	if err := r.checkRate(ctx, rate); err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Omit("Vendor").Save(rate).Error
+}
+
+// checkRate returns ErrDuplicateEntry when another rate is set for the vendor
+// or the country of rate
+func (r *WithholdingRepository) checkRate(ctx context.Context, rate *entity.WithholdingTaxRate) error {
+	query := r.db.WithContext(ctx).Model(&entity.WithholdingTaxRate{}).Where("id <> ?", rate.ID)
+	if rate.VendorID != nil {
+		query = query.Where("vendor_id = ?", *rate.VendorID)
+	} else {
+		query = query.Where("vendor_id IS NULL AND UPPER(country) = ?", strings.ToUpper(rate.Country))
+	}
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrDuplicateEntry
+	}
+	return nil
+}
+
+// GetRate retrieves a withholding tax rate by ID
+func (r *WithholdingRepository) GetRate(ctx context.Context, id uint) (*entity.WithholdingTaxRate, error) {
+	var rate entity.WithholdingTaxRate
+	if err := r.db.WithContext(ctx).Preload("Vendor").First(&rate, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &rate, nil
+}
+
+// ListRates retrieves the withholding tax rates, the countries' first, only
+// the active ones unless all is set
+func (r *WithholdingRepository) ListRates(ctx context.Context, all bool) ([]entity.WithholdingTaxRate, error) {
+	var rates []entity.WithholdingTaxRate
+	query := r.db.WithContext(ctx).Preload("Vendor").Order("vendor_id NULLS FIRST, country, id")
+	if !all {
+		query = query.Where("active = ?", true)
+	}
+	if err := query.Find(&rates).Error; err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
+
+// RateFor returns the active withholding tax rate of a vendor: its own, or
+// else the rate of its country. It returns nil when no tax is withheld from
+// the vendor.
+func (r *WithholdingRepository) RateFor(ctx context.Context, vendorID uint) (*entity.WithholdingTaxRate, error) {
+	var rates []entity.WithholdingTaxRate
+	if err := r.db.WithContext(ctx).
+		Where("active = ?", true).
+		Where("vendor_id = ? OR (vendor_id IS NULL AND UPPER(country) = (SELECT UPPER(country) FROM vendors WHERE id = ?))", vendorID, vendorID).
+		Order("vendor_id NULLS LAST").
+		Limit(1).
+		Find(&rates).Error; err != nil {
+		return nil, err
+	}
+	if len(rates) == 0 {
+		return nil, nil
+	}
+	return &rates[0], nil
+}
+
+// WithheldOnInvoice totals the tax withheld from the pending and completed
+// payments of an invoice, except the given payment
+func (r *WithholdingRepository) WithheldOnInvoice(ctx context.Context, invoiceID, exceptPaymentID int64) (float64, error) {
+	var withheld float64
+	if err := r.db.WithContext(ctx).Model(&entity.FinancePayment{}).
+		Select("COALESCE(SUM(withheld_amount), 0)").
+		Where("invoice_id = ? AND id <> ?", invoiceID, exceptPaymentID).
+		Where("status IN ?", []entity.FinancePaymentStatus{entity.FinancePaymentPending, entity.FinancePaymentCompleted}).
+		Scan(&withheld).Error; err != nil {
+		return 0, err
+	}
+	return withheld, nil
+}
+
+// WithholdingPayment is a completed supplier payment tax was withheld from,
+// with its vendor
+type WithholdingPayment struct {
+	entity.WithholdingCertificateLine
+	VendorID   uint
+	VendorCode string
+	VendorName string
+	TaxID      string
+	Country    string
+}
+
+// WithheldPayments lists the completed payments to vendors made between two
+// dates that tax was withheld from, by vendor and date, for every vendor
+// unless one is given
+func (r *WithholdingRepository) WithheldPayments(ctx context.Context, vendorID *uint, startDate, endDate time.Time) ([]WithholdingPayment, error) {
+	query := r.db.WithContext(ctx).
+		Table("finance_payments p").
+		Select(`p.id AS payment_id, p.payment_number, p.payment_date, p.invoice_id, p.invoice_number,
+			i.withholding_rate, p.currency_code, p.amount AS gross_amount, p.withheld_amount, p.net_amount,
+			p.base_amount AS base_gross_amount, ROUND(p.withheld_amount * p.exchange_rate, 2) AS base_withheld_amount,
+			v.id AS vendor_id, v.code AS vendor_code, v.name AS vendor_name, v.tax_id, v.country`).
+		Joins("JOIN finance_invoices i ON i.id = p.invoice_id").
+		Joins("JOIN vendors v ON v.id = p.entity_id").
+		Where("p.entity_type = ? AND p.status = ? AND p.withheld_amount > 0", "SUPPLIER", entity.FinancePaymentCompleted).
+		Where("p.payment_date BETWEEN ? AND ?", startDate, endDate)
+	if vendorID != nil {
+		query = query.Where("p.entity_id = ?", *vendorID)
+	}
+
+	var payments []WithholdingPayment
+	if err := query.Order("v.name, v.id, p.payment_date, p.id").Scan(&payments).Error; err != nil {
+		return nil, err
+	}
+	return payments, nil
+}
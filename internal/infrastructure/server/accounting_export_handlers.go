@@ -0,0 +1,163 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/server/middleware"
+)
+
+// AccountingExportHandlers handles accounting export HTTP requests
+type AccountingExportHandlers struct {
+	exportUseCase *usecase.AccountingExportUseCase
+}
+
+// NewAccountingExportHandlers creates a new accounting export handlers instance
+func NewAccountingExportHandlers(exportUseCase *usecase.AccountingExportUseCase) *AccountingExportHandlers {
+	return &AccountingExportHandlers{
+		exportUseCase: exportUseCase,
+	}
+}
+
+// RegisterRoutes registers accounting export routes
+func (h *AccountingExportHandlers) RegisterRoutes(router *gin.RouterGroup) {
+	exportRouter := router.Group("/finance/exports")
+	{
+		exportRouter.POST("", middleware.PermissionMiddleware(entity.FinanceExportRun), h.Export)
+		exportRouter.GET("", middleware.PermissionMiddleware(entity.FinanceExportRead), h.ListRuns)
+		exportRouter.GET("/:id", middleware.PermissionMiddleware(entity.FinanceExportRead), h.GetRun)
+		exportRouter.GET("/:id/download", middleware.PermissionMiddleware(entity.FinanceExportRead), h.Download)
+	}
+}
+
+// Export handles exporting finance records to an accounting system format
+// @Summary Export to accounting system
+// @Description Export the finance invoices, completed payments, depreciation and inventory provisions of a date range not yet exported in the format, as a QuickBooks IIF file, a zip of Xero CSV templates or JSON
+// @Tags finance
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param export body entity.AccountingExportRequest true "Format and date range"
+// @Success 201 {object} entity.AccountingExportRun
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse "Records were exported by another run meanwhile"
+// @Failure 422 {object} ErrorResponse "Nothing left to export in the range"
+// @Failure 500 {object} ErrorResponse
+// @Router /finance/exports [post]
+func (h *AccountingExportHandlers) Export(c *gin.Context) {
+	userID := currentUserID(c)
+	if userID == nil {
+		c.Error(entity.NewError(entity.ErrCodeUnauthenticated, "unauthorized"))
+		return
+	}
+	var req entity.AccountingExportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	run, err := h.exportUseCase.Export(c.Request.Context(), &req, *userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, run)
+}
+
+// ListRuns handles listing the accounting export history
+// @Summary List accounting exports
+// @Description List accounting export runs, latest first
+// @Tags finance
+// @Security BearerAuth
+// @Produce json
+// @Param page query int false "Page number"
+// @Param page_size query int false "Page size"
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} ErrorResponse
+// @Router /finance/exports [get]
+func (h *AccountingExportHandlers) ListRuns(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+
+	runs, total, err := h.exportUseCase.ListRuns(c.Request.Context(), page, pageSize)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"exports":   runs,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+// GetRun handles getting an accounting export run
+// @Summary Get accounting export
+// @Description Get an accounting export run
+// @Tags finance
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Export ID"
+// @Success 200 {object} entity.AccountingExportRun
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /finance/exports/{id} [get]
+func (h *AccountingExportHandlers) GetRun(c *gin.Context) {
+	id, ok := parseAccountingExportID(c)
+	if !ok {
+		return
+	}
+
+	run, err := h.exportUseCase.GetRun(c.Request.Context(), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, run)
+}
+
+// Download handles downloading the file of an accounting export run
+// @Summary Download accounting export
+// @Description Download the file an accounting export run produced, to import into the accounting system
+// @Tags finance
+// @Security BearerAuth
+// @Produce octet-stream
+// @Param id path int true "Export ID"
+// @Success 200 {file} file
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /finance/exports/{id}/download [get]
+func (h *AccountingExportHandlers) Download(c *gin.Context) {
+	id, ok := parseAccountingExportID(c)
+	if !ok {
+		return
+	}
+
+	run, err := h.exportUseCase.GetRun(c.Request.Context(), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", run.FileName))
+	c.Data(http.StatusOK, run.ContentType, run.Content)
+}
+
+func parseAccountingExportID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid ID"))
+		return 0, false
+	}
+	return uint(id), true
+}
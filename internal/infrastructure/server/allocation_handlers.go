@@ -0,0 +1,90 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/auth"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/server/middleware"
+)
+
+// AllocationHandlers handles stock allocation HTTP requests
+type AllocationHandlers struct {
+	allocationUseCase *usecase.AllocationUseCase
+}
+
+// NewAllocationHandlers creates a new allocation handlers instance
+func NewAllocationHandlers(allocationUseCase *usecase.AllocationUseCase) *AllocationHandlers {
+	return &AllocationHandlers{
+		allocationUseCase: allocationUseCase,
+	}
+}
+
+// RegisterRoutes registers stock allocation routes
+func (h *AllocationHandlers) RegisterRoutes(router *gin.RouterGroup) {
+	allocationRouter := router.Group("/orders/allocations")
+	{
+		allocationRouter.POST("/run", middleware.PermissionMiddleware(entity.SalesOrderAllocate), h.RunAllocation)
+		allocationRouter.GET("", middleware.PermissionMiddleware(entity.SalesOrderRead), h.ListAllocations)
+	}
+}
+
+// RunAllocation handles allocating scarce stock across confirmed orders
+// @Summary Run stock allocation
+// @Description Allocate a store's stock across confirmed orders by customer priority, order date (FIFO) or in proportion to demand, reserve it and report shorted orders
+// @Tags orders
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body entity.AllocationRunRequest true "Allocation run"
+// @Success 200 {object} entity.AllocationRunResult
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /orders/allocations/run [post]
+func (h *AllocationHandlers) RunAllocation(c *gin.Context) {
+	var req entity.AllocationRunRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	result, err := h.allocationUseCase.Run(c.Request.Context(), &req, auth.GetUserIDFromContext(c))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// ListAllocations handles listing stock allocations
+// @Summary List stock allocations
+// @Description List stock reserved for sales orders by allocation runs
+// @Tags orders
+// @Security BearerAuth
+// @Produce json
+// @Param sales_order_id query string false "Sales order ID"
+// @Param store_id query string false "Store ID"
+// @Param sku_id query string false "SKU ID"
+// @Param status query string false "Status (ACTIVE/RELEASED/FULFILLED)"
+// @Success 200 {array} entity.StockAllocation
+// @Failure 500 {object} ErrorResponse
+// @Router /orders/allocations [get]
+func (h *AllocationHandlers) ListAllocations(c *gin.Context) {
+	filter := &entity.StockAllocationFilter{
+		SalesOrderID: c.Query("sales_order_id"),
+		StoreID:      c.Query("store_id"),
+		SKUID:        c.Query("sku_id"),
+		Status:       entity.StockAllocationStatus(c.Query("status")),
+	}
+
+	allocations, err := h.allocationUseCase.ListAllocations(c.Request.Context(), filter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, allocations)
+}
@@ -0,0 +1,190 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/server/middleware"
+)
+
+// APIKeyHandlers handles API key management HTTP requests
+type APIKeyHandlers struct {
+	apiKeyUseCase *usecase.APIKeyUseCase
+}
+
+// NewAPIKeyHandlers creates a new API key handlers instance
+func NewAPIKeyHandlers(apiKeyUseCase *usecase.APIKeyUseCase) *APIKeyHandlers {
+	return &APIKeyHandlers{
+		apiKeyUseCase: apiKeyUseCase,
+	}
+}
+
+// RegisterRoutes registers API key routes
+func (h *APIKeyHandlers) RegisterRoutes(router *gin.RouterGroup) {
+	// Keys would outlive an impersonation session, so none are managed in one
+	keys := router.Group("/api-keys", middleware.DenyImpersonationMiddleware())
+	{
+		keys.POST("", middleware.PermissionMiddleware(entity.SystemAPIKeyManage), h.CreateKey)
+		keys.GET("", middleware.PermissionMiddleware(entity.SystemAPIKeyRead), h.ListKeys)
+		keys.GET("/:id", middleware.PermissionMiddleware(entity.SystemAPIKeyRead), h.GetKey)
+		keys.POST("/:id/rotate", middleware.PermissionMiddleware(entity.SystemAPIKeyManage), h.RotateKey)
+		keys.POST("/:id/revoke", middleware.PermissionMiddleware(entity.SystemAPIKeyManage), h.RevokeKey)
+	}
+}
+
+// CreateKey handles creating an API key
+// @Summary Create API key
+// @Description Create an API key acting on behalf of the caller, with some of the permissions of the caller's role and the caller's access scope. The key is only answered now; keep it, as only its prefix can be read later.
+// @Tags api-keys
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body entity.APIKeyRequest true "Name, permissions and expiry"
+// @Success 201 {object} entity.APIKeySecret
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Router /api-keys [post]
+func (h *APIKeyHandlers) CreateKey(c *gin.Context) {
+	userID := currentUserID(c)
+	if userID == nil {
+		c.Error(entity.NewError(entity.ErrCodeUnauthenticated, "User not found in context"))
+		return
+	}
+
+	var req entity.APIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	// Elevated access is temporary, so it cannot be handed on to a key
+	key, err := h.apiKeyUseCase.CreateKey(c.Request.Context(), *userID, middleware.RolePermissions(c), &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, key)
+}
+
+// ListKeys handles listing API keys
+// @Summary List API keys
+// @Description List API keys, latest first, without their secrets
+// @Tags api-keys
+// @Security BearerAuth
+// @Produce json
+// @Param created_by query int false "Creator user ID"
+// @Param include_revoked query bool false "Include revoked keys"
+// @Success 200 {array} entity.APIKey
+// @Failure 500 {object} ErrorResponse
+// @Router /api-keys [get]
+func (h *APIKeyHandlers) ListKeys(c *gin.Context) {
+	filter := &entity.APIKeyFilter{
+		IncludeRevoked: c.Query("include_revoked") == "true",
+	}
+	if createdBy, err := strconv.ParseUint(c.Query("created_by"), 10, 32); err == nil {
+		filter.CreatedBy = uint(createdBy)
+	}
+
+	keys, err := h.apiKeyUseCase.ListKeys(c.Request.Context(), filter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, keys)
+}
+
+// GetKey handles getting an API key
+// @Summary Get API key
+// @Description Get an API key, without its secret
+// @Tags api-keys
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "API key ID"
+// @Success 200 {object} entity.APIKey
+// @Failure 404 {object} ErrorResponse
+// @Router /api-keys/{id} [get]
+func (h *APIKeyHandlers) GetKey(c *gin.Context) {
+	id, ok := parseAPIKeyID(c)
+	if !ok {
+		return
+	}
+
+	key, err := h.apiKeyUseCase.GetKey(c.Request.Context(), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, key)
+}
+
+// RotateKey handles rotating an API key
+// @Summary Rotate API key
+// @Description Replace the secret of an API key, keeping its permissions and expiry. The previous secret stops working at once.
+// @Tags api-keys
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "API key ID"
+// @Success 200 {object} entity.APIKeySecret
+// @Failure 404 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Router /api-keys/{id}/rotate [post]
+func (h *APIKeyHandlers) RotateKey(c *gin.Context) {
+	id, ok := parseAPIKeyID(c)
+	if !ok {
+		return
+	}
+
+	key, err := h.apiKeyUseCase.RotateKey(c.Request.Context(), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, key)
+}
+
+// RevokeKey handles revoking an API key
+// @Summary Revoke API key
+// @Description Stop accepting an API key
+// @Tags api-keys
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "API key ID"
+// @Success 200 {object} entity.APIKey
+// @Failure 404 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Router /api-keys/{id}/revoke [post]
+func (h *APIKeyHandlers) RevokeKey(c *gin.Context) {
+	userID := currentUserID(c)
+	if userID == nil {
+		c.Error(entity.NewError(entity.ErrCodeUnauthenticated, "User not found in context"))
+		return
+	}
+	id, ok := parseAPIKeyID(c)
+	if !ok {
+		return
+	}
+
+	key, err := h.apiKeyUseCase.RevokeKey(c.Request.Context(), id, *userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, key)
+}
+
+func parseAPIKeyID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid API key ID"))
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -0,0 +1,187 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/server/middleware"
+)
+
+// ApprovalDelegationHandlers handles approval delegation HTTP requests
+type ApprovalDelegationHandlers struct {
+	delegationUseCase *usecase.ApprovalDelegationUseCase
+}
+
+// NewApprovalDelegationHandlers creates a new approval delegation handlers instance
+func NewApprovalDelegationHandlers(delegationUseCase *usecase.ApprovalDelegationUseCase) *ApprovalDelegationHandlers {
+	return &ApprovalDelegationHandlers{
+		delegationUseCase: delegationUseCase,
+	}
+}
+
+// RegisterRoutes registers approval delegation routes. Every user manages the
+// delegations of their own approvals; approval:delegation:manage manages
+// those of all users.
+func (h *ApprovalDelegationHandlers) RegisterRoutes(router *gin.RouterGroup) {
+	delegations := router.Group("/approval-delegations")
+	{
+		delegations.POST("", h.CreateDelegation)
+		delegations.GET("", h.ListDelegations)
+		delegations.GET("/:id", h.GetDelegation)
+		delegations.POST("/:id/cancel", h.CancelDelegation)
+	}
+}
+
+// CreateDelegation handles delegating approvals
+// @Summary Delegate approvals
+// @Description Hand the approval authority of the caller, or of delegator_id with approval:delegation:manage, to a delegate from starts_at until ends_at. Pending purchase request and order approvals are routed to the delegate meanwhile.
+// @Tags approval-delegations
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body entity.ApprovalDelegationRequest true "Delegate and period"
+// @Success 201 {object} entity.ApprovalDelegation
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Router /approval-delegations [post]
+func (h *ApprovalDelegationHandlers) CreateDelegation(c *gin.Context) {
+	userID := currentUserID(c)
+	if userID == nil {
+		c.Error(entity.NewError(entity.ErrCodeUnauthenticated, "User not found in context"))
+		return
+	}
+
+	var req entity.ApprovalDelegationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	delegation, err := h.delegationUseCase.CreateDelegation(c.Request.Context(), *userID, middleware.HasPermission(c, entity.ApprovalDelegationManage), &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, delegation)
+}
+
+// ListDelegations handles listing approval delegations
+// @Summary List approval delegations
+// @Description List the delegations the caller gave or received, latest start first. With approval:delegation:manage, those of user_id, or of all users without it.
+// @Tags approval-delegations
+// @Security BearerAuth
+// @Produce json
+// @Param user_id query int false "Delegations given or received by this user"
+// @Param active_only query bool false "Only the delegations active now"
+// @Param include_cancelled query bool false "Include cancelled delegations"
+// @Success 200 {array} entity.ApprovalDelegation
+// @Failure 500 {object} ErrorResponse
+// @Router /approval-delegations [get]
+func (h *ApprovalDelegationHandlers) ListDelegations(c *gin.Context) {
+	userID := currentUserID(c)
+	if userID == nil {
+		c.Error(entity.NewError(entity.ErrCodeUnauthenticated, "User not found in context"))
+		return
+	}
+
+	filter := &entity.ApprovalDelegationFilter{
+		UserID:           *userID,
+		ActiveOnly:       c.Query("active_only") == "true",
+		IncludeCancelled: c.Query("include_cancelled") == "true",
+	}
+	if middleware.HasPermission(c, entity.ApprovalDelegationManage) {
+		filter.UserID = 0
+		if id, err := strconv.ParseUint(c.Query("user_id"), 10, 32); err == nil {
+			filter.UserID = uint(id)
+		}
+	}
+
+	delegations, err := h.delegationUseCase.ListDelegations(c.Request.Context(), filter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, delegations)
+}
+
+// GetDelegation handles getting an approval delegation
+// @Summary Get approval delegation
+// @Description Get a delegation the caller gave or received, or any with approval:delegation:manage
+// @Tags approval-delegations
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Delegation ID"
+// @Success 200 {object} entity.ApprovalDelegation
+// @Failure 404 {object} ErrorResponse
+// @Router /approval-delegations/{id} [get]
+func (h *ApprovalDelegationHandlers) GetDelegation(c *gin.Context) {
+	userID := currentUserID(c)
+	if userID == nil {
+		c.Error(entity.NewError(entity.ErrCodeUnauthenticated, "User not found in context"))
+		return
+	}
+	id, ok := parseDelegationID(c)
+	if !ok {
+		return
+	}
+
+	delegation, err := h.delegationUseCase.GetDelegation(c.Request.Context(), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	// Other users' delegations are not disclosed
+	if delegation.DelegatorID != *userID && delegation.DelegateID != *userID && !middleware.HasPermission(c, entity.ApprovalDelegationManage) {
+		c.Error(usecase.ErrDelegationNotFound)
+		return
+	}
+
+	c.JSON(http.StatusOK, delegation)
+}
+
+// CancelDelegation handles cancelling an approval delegation
+// @Summary Cancel approval delegation
+// @Description End a delegation now, or drop it before it starts. Only its delegator can, or any user with approval:delegation:manage.
+// @Tags approval-delegations
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Delegation ID"
+// @Success 200 {object} entity.ApprovalDelegation
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Router /approval-delegations/{id}/cancel [post]
+func (h *ApprovalDelegationHandlers) CancelDelegation(c *gin.Context) {
+	userID := currentUserID(c)
+	if userID == nil {
+		c.Error(entity.NewError(entity.ErrCodeUnauthenticated, "User not found in context"))
+		return
+	}
+	id, ok := parseDelegationID(c)
+	if !ok {
+		return
+	}
+
+	delegation, err := h.delegationUseCase.CancelDelegation(c.Request.Context(), id, *userID, middleware.HasPermission(c, entity.ApprovalDelegationManage))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, delegation)
+}
+
+func parseDelegationID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid delegation ID"))
+		return 0, false
+	}
+	return uint(id), true
+}
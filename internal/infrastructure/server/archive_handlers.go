@@ -0,0 +1,49 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/server/middleware"
+)
+
+// ArchiveHandlers handles document archival HTTP requests
+type ArchiveHandlers struct {
+	archiveUseCase *usecase.ArchiveUseCase
+}
+
+// NewArchiveHandlers creates a new archive handlers instance
+func NewArchiveHandlers(archiveUseCase *usecase.ArchiveUseCase) *ArchiveHandlers {
+	return &ArchiveHandlers{
+		archiveUseCase: archiveUseCase,
+	}
+}
+
+// RegisterRoutes registers archive-related routes
+func (h *ArchiveHandlers) RegisterRoutes(router *gin.RouterGroup) {
+	archiveRouter := router.Group("/system/archive")
+	{
+		archiveRouter.POST("/run", middleware.PermissionMiddleware(entity.SystemArchiveRun), h.RunArchive)
+	}
+}
+
+// RunArchive handles running document archival on demand
+// @Summary Run document archival
+// @Description Move closed/cancelled orders, paid invoices and stock entries older than the retention period to the archive tables
+// @Tags system
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} entity.ArchiveRunResult
+// @Failure 500 {object} ErrorResponse
+// @Router /system/archive/run [post]
+func (h *ArchiveHandlers) RunArchive(c *gin.Context) {
+	result, err := h.archiveUseCase.Run(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
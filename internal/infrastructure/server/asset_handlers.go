@@ -0,0 +1,250 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/auth"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/server/middleware"
+)
+
+// AssetHandlers handles fixed asset register HTTP requests
+type AssetHandlers struct {
+	assetUseCase *usecase.AssetUseCase
+}
+
+// NewAssetHandlers creates a new asset handlers instance
+func NewAssetHandlers(assetUseCase *usecase.AssetUseCase) *AssetHandlers {
+	return &AssetHandlers{
+		assetUseCase: assetUseCase,
+	}
+}
+
+// RegisterRoutes registers fixed asset routes
+func (h *AssetHandlers) RegisterRoutes(router *gin.RouterGroup) {
+	assetRouter := router.Group("/finance/assets")
+	{
+		assetRouter.GET("", middleware.PermissionMiddleware(entity.FinanceAssetRead), h.ListAssets)
+		assetRouter.GET("/:id", middleware.PermissionMiddleware(entity.FinanceAssetRead), h.GetAsset)
+		assetRouter.GET("/:id/schedule", middleware.PermissionMiddleware(entity.FinanceAssetRead), h.GetSchedule)
+		assetRouter.GET("/:id/depreciation", middleware.PermissionMiddleware(entity.FinanceAssetRead), h.ListEntries)
+		assetRouter.POST("/:id/dispose", middleware.PermissionMiddleware(entity.FinanceAssetManage), h.DisposeAsset)
+		assetRouter.POST("/depreciation/post", middleware.PermissionMiddleware(entity.FinanceAssetManage), h.PostDepreciation)
+	}
+}
+
+// ListAssets handles listing the fixed asset register
+// @Summary List fixed assets
+// @Description List fixed assets registered from capital purchases
+// @Tags finance
+// @Security BearerAuth
+// @Produce json
+// @Param category query string false "Asset category"
+// @Param status query string false "Status (ACTIVE/FULLY_DEPRECIATED/DISPOSED)"
+// @Param purchase_order_id query string false "Purchase order ID"
+// @Param page query int false "Page number"
+// @Param page_size query int false "Page size"
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} ErrorResponse
+// @Router /finance/assets [get]
+func (h *AssetHandlers) ListAssets(c *gin.Context) {
+	filter := &entity.FixedAssetFilter{
+		Category:        c.Query("category"),
+		Status:          entity.FixedAssetStatus(c.Query("status")),
+		PurchaseOrderID: c.Query("purchase_order_id"),
+	}
+
+	if page, err := strconv.Atoi(c.Query("page")); err == nil {
+		filter.Page = page
+	}
+
+	if pageSize, err := strconv.Atoi(c.Query("page_size")); err == nil {
+		filter.PageSize = pageSize
+	}
+
+	assets, total, err := h.assetUseCase.ListAssets(c.Request.Context(), filter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"assets":    assets,
+		"total":     total,
+		"page":      filter.Page,
+		"page_size": filter.PageSize,
+	})
+}
+
+// GetAsset handles getting a fixed asset
+// @Summary Get fixed asset
+// @Description Get a fixed asset with its accumulated depreciation and book value
+// @Tags finance
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Asset ID"
+// @Success 200 {object} entity.FixedAsset
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /finance/assets/{id} [get]
+func (h *AssetHandlers) GetAsset(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid asset ID"))
+		return
+	}
+
+	asset, err := h.assetUseCase.GetAsset(c.Request.Context(), uint(id))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, asset)
+}
+
+// GetSchedule handles getting the depreciation schedule of a fixed asset
+// @Summary Get depreciation schedule
+// @Description Get the monthly depreciation of a fixed asset over its useful life, flagging the months already posted
+// @Tags finance
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Asset ID"
+// @Success 200 {array} entity.DepreciationScheduleLine
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /finance/assets/{id}/schedule [get]
+func (h *AssetHandlers) GetSchedule(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid asset ID"))
+		return
+	}
+
+	schedule, err := h.assetUseCase.GetSchedule(c.Request.Context(), uint(id))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, schedule)
+}
+
+// ListEntries handles listing the depreciation posted for a fixed asset
+// @Summary List depreciation entries
+// @Description List the monthly depreciation posted for a fixed asset
+// @Tags finance
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Asset ID"
+// @Success 200 {array} entity.AssetDepreciationEntry
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /finance/assets/{id}/depreciation [get]
+func (h *AssetHandlers) ListEntries(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid asset ID"))
+		return
+	}
+
+	entries, err := h.assetUseCase.ListEntries(c.Request.Context(), uint(id))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
+
+// DisposeAsset handles disposing of a fixed asset
+// @Summary Dispose of fixed asset
+// @Description Catch up depreciation to the month before disposal, then retire the asset and record the gain or loss on disposal
+// @Tags finance
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Asset ID"
+// @Param request body entity.DisposeAssetRequest true "Disposal details"
+// @Success 200 {object} entity.FixedAsset
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /finance/assets/{id}/dispose [post]
+func (h *AssetHandlers) DisposeAsset(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid asset ID"))
+		return
+	}
+
+	var req entity.DisposeAssetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	asset, err := h.assetUseCase.Dispose(c.Request.Context(), uint(id), &req, currentUserID(c))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, asset)
+}
+
+// PostDepreciation handles posting monthly depreciation
+// @Summary Post depreciation
+// @Description Post depreciation for all active fixed assets through a month, catching up months not yet posted
+// @Tags finance
+// @Security BearerAuth
+// @Produce json
+// @Param period query string false "Period (YYYY-MM), defaults to the current month"
+// @Success 200 {object} entity.DepreciationRunResult
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /finance/assets/depreciation/post [post]
+func (h *AssetHandlers) PostDepreciation(c *gin.Context) {
+	period := c.DefaultQuery("period", time.Now().Format("2006-01"))
+
+	result, err := h.assetUseCase.Post(c.Request.Context(), period, currentUserID(c))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// handleError maps fixed asset errors to HTTP responses
+func (h *AssetHandlers) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, usecase.ErrAssetNotFound):
+		c.Error(entity.WrapError(entity.ErrCodeNotFound, err))
+	case errors.Is(err, usecase.ErrAssetDisposed),
+		errors.Is(err, usecase.ErrInvalidDisposalDate),
+		errors.Is(err, usecase.ErrInvalidPeriod),
+		errors.Is(err, usecase.ErrDepreciationPeriodInFuture):
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+	default:
+		c.Error(err)
+	}
+}
+
+// currentUserID returns the authenticated user's ID, or nil when it is not numeric
+func currentUserID(c *gin.Context) *uint {
+	userID, err := strconv.ParseUint(auth.GetUserIDFromContext(c), 10, 32)
+	if err != nil {
+		return nil
+	}
+	id := uint(userID)
+	return &id
+}
@@ -0,0 +1,240 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/filestore"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/server/middleware"
+)
+
+// attachmentPermissions are the permissions to read a kind of document, and
+// to add or remove its attachments
+var attachmentPermissions = map[entity.AttachmentOwnerType]struct{ read, update entity.Permission }{
+	entity.AttachmentPurchaseRequest: {entity.PurchaseRequestRead, entity.PurchaseRequestUpdate},
+	entity.AttachmentPurchaseOrder:   {entity.PurchaseOrderRead, entity.PurchaseOrderUpdate},
+	entity.AttachmentPurchaseReceipt: {entity.PurchaseReceiptRead, entity.PurchaseReceiptUpdate},
+	entity.AttachmentExpenseClaim:    {entity.FinanceExpenseRead, entity.FinanceExpenseCreate},
+}
+
+// AttachmentHandlers handles HTTP requests for files attached to documents
+type AttachmentHandlers struct {
+	attachmentUseCase *usecase.AttachmentUseCase
+}
+
+// NewAttachmentHandlers creates a new attachment handlers instance
+func NewAttachmentHandlers(attachmentUseCase *usecase.AttachmentUseCase) *AttachmentHandlers {
+	return &AttachmentHandlers{
+		attachmentUseCase: attachmentUseCase,
+	}
+}
+
+// RegisterRoutes registers attachment routes. Access follows the permissions
+// on the document a file is attached to.
+func (h *AttachmentHandlers) RegisterRoutes(router *gin.RouterGroup) {
+	attachments := router.Group("/attachments")
+	{
+		attachments.GET("", h.ListAttachments)
+		attachments.POST("", h.UploadAttachment)
+		attachments.GET("/:id", h.GetAttachment)
+		attachments.DELETE("/:id", h.DeleteAttachment)
+	}
+}
+
+// ListAttachments handles listing the attachments of a document
+// @Summary List attachments
+// @Description List the files attached to a document, each with a download URL that expires
+// @Tags attachments
+// @Security BearerAuth
+// @Produce json
+// @Param owner_type query string true "Document type (purchase_request/purchase_order/purchase_receipt/expense_claim)"
+// @Param owner_id query string true "Document ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /attachments [get]
+func (h *AttachmentHandlers) ListAttachments(c *gin.Context) {
+	ownerType := entity.AttachmentOwnerType(c.Query("owner_type"))
+	ownerID := c.Query("owner_id")
+	if ownerID == "" {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "owner_id is required"))
+		return
+	}
+	if !h.authorize(c, ownerType, false) {
+		return
+	}
+
+	attachments, err := h.attachmentUseCase.ListAttachments(c.Request.Context(), ownerType, ownerID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"attachments": attachments, "total": len(attachments)})
+}
+
+// UploadAttachment handles attaching a file to a document
+// @Summary Upload attachment
+// @Description Attach a file, such as a quote or a delivery note, to a document. The size and type of the file are checked against the configured upload limits.
+// @Tags attachments
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param owner_type formData string true "Document type (purchase_request/purchase_order/purchase_receipt/expense_claim)"
+// @Param owner_id formData string true "Document ID"
+// @Param file formData file true "File to attach"
+// @Success 201 {object} entity.Attachment
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 413 {object} ErrorResponse
+// @Failure 415 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /attachments [post]
+func (h *AttachmentHandlers) UploadAttachment(c *gin.Context) {
+	if maxSize := h.attachmentUseCase.Limits().MaxSize; maxSize > 0 {
+		// Leave room for the multipart envelope around the file
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize+64<<10)
+	}
+	header, err := c.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			h.handleError(c, filestore.ErrFileTooLarge)
+			return
+		}
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "File is required"))
+		return
+	}
+
+	ownerType := entity.AttachmentOwnerType(c.PostForm("owner_type"))
+	ownerID := c.PostForm("owner_id")
+	if ownerID == "" {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "owner_id is required"))
+		return
+	}
+	if !h.authorize(c, ownerType, true) {
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid file"))
+		return
+	}
+	defer file.Close()
+
+	attachment, err := h.attachmentUseCase.Upload(c.Request.Context(), ownerType, ownerID,
+		header.Filename, header.Header.Get("Content-Type"), header.Size, file, currentUserID(c))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, attachment)
+}
+
+// GetAttachment handles getting an attachment
+// @Summary Get attachment
+// @Description Get an attachment with a download URL that expires
+// @Tags attachments
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Attachment ID"
+// @Success 200 {object} entity.Attachment
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /attachments/{id} [get]
+func (h *AttachmentHandlers) GetAttachment(c *gin.Context) {
+	attachment, ok := h.attachment(c, false)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, attachment)
+}
+
+// DeleteAttachment handles removing an attachment
+// @Summary Delete attachment
+// @Description Remove a file from the document it is attached to
+// @Tags attachments
+// @Security BearerAuth
+// @Param id path int true "Attachment ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /attachments/{id} [delete]
+func (h *AttachmentHandlers) DeleteAttachment(c *gin.Context) {
+	attachment, ok := h.attachment(c, true)
+	if !ok {
+		return
+	}
+	if err := h.attachmentUseCase.DeleteAttachment(c.Request.Context(), attachment.ID); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// attachment loads the attachment named by the path, writing the response
+// when it is missing or the caller may not access its document
+func (h *AttachmentHandlers) attachment(c *gin.Context, update bool) (*entity.Attachment, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid attachment ID"))
+		return nil, false
+	}
+	attachment, err := h.attachmentUseCase.GetAttachment(c.Request.Context(), id)
+	if err != nil {
+		h.handleError(c, err)
+		return nil, false
+	}
+	if !h.authorize(c, attachment.OwnerType, update) {
+		return nil, false
+	}
+	return attachment, true
+}
+
+// authorize checks the caller's permission on a kind of document, writing the
+// response when it is missing
+func (h *AttachmentHandlers) authorize(c *gin.Context, ownerType entity.AttachmentOwnerType, update bool) bool {
+	permissions, ok := attachmentPermissions[ownerType]
+	if !ok {
+		h.handleError(c, usecase.ErrAttachmentOwnerType)
+		return false
+	}
+	permission := permissions.read
+	if update {
+		permission = permissions.update
+	}
+	if !middleware.HasPermission(c, permission) {
+		c.Error(entity.NewError(entity.ErrCodePermissionDenied, "Insufficient permissions"))
+		return false
+	}
+	return true
+}
+
+func (h *AttachmentHandlers) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, usecase.ErrAttachmentNotFound), errors.Is(err, usecase.ErrAttachmentOwnerNotFound):
+		c.Error(entity.WrapError(entity.ErrCodeNotFound, err))
+	case errors.Is(err, usecase.ErrAttachmentOwnerType), errors.Is(err, filestore.ErrFileEmpty):
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+	case errors.Is(err, filestore.ErrFileTooLarge):
+		c.Error(entity.WrapError(entity.ErrCodePayloadTooLarge, err))
+	case errors.Is(err, filestore.ErrContentTypeInvalid):
+		c.Error(entity.WrapError(entity.ErrCodeUnsupportedMediaType, err))
+	default:
+		c.Error(err)
+	}
+}
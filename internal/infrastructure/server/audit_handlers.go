@@ -1,10 +1,14 @@
 package server
 
 import (
+	"encoding/csv"
+	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
 )
 
 // @Summary Get user audit logs
@@ -22,7 +26,7 @@ import (
 func (s *Server) handleUserAuditLogs(c *gin.Context) {
 	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid user ID"))
 		return
 	}
 
@@ -31,41 +35,248 @@ func (s *Server) handleUserAuditLogs(c *gin.Context) {
 
 	logs, err := s.auditService.GetUserAuditLogs(uint(userID), page, pageSize)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
 	c.JSON(http.StatusOK, logs)
 }
 
-// @Summary List all audit logs
-// @Description Get a paginated list of all audit logs
+// @Summary Search audit logs
+// @Description Get a paginated list of audit logs, latest first, filtered by user, action, resource, IP, date range or free text
 // @Tags audit
 // @Security BearerAuth
 // @Produce json
+// @Param user_id query int false "User ID"
+// @Param impersonator_id query int false "Admin who made the requests while impersonating the user"
+// @Param impersonated query bool false "Only requests made in impersonation sessions"
+// @Param action query string false "Action (create/read/update/delete/login/logout)"
+// @Param resource query string false "Part of the resource path, e.g. an entity type"
+// @Param ip query string false "Client IP address"
+// @Param q query string false "Free text matched against resource, detail and user agent"
+// @Param start_date query string false "Start date (YYYY-MM-DD)"
+// @Param end_date query string false "End date (YYYY-MM-DD)"
+// @Param archived query bool false "Search the archived logs instead of the live ones"
 // @Param page query int false "Page number"
 // @Param page_size query int false "Page size"
+// @Param cursor query string false "Return the logs after this next_cursor of a previous page, instead of paging by number"
+// @Param limit query int false "Logs per page when paging by cursor (default 50, at most 500)"
 // @Success 200 {object} AuditLogResponse
 // @Failure 500 {object} ErrorResponse "Server error"
 // @Router /audit/logs [get]
 func (s *Server) handleListAuditLogs(c *gin.Context) {
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	filter := auditLogFilter(c)
+
+	page, err := parseCursorPage(c)
+	if err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+	if page != nil {
+		logs, next, err := s.auditService.SearchAuditLogsPage(filter, *page)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		c.JSON(http.StatusOK, cursorPageResponse("logs", logs, page, next))
+		return
+	}
+
+	if page, err := strconv.Atoi(c.Query("page")); err == nil {
+		filter.Page = page
+	}
+
+	if pageSize, err := strconv.Atoi(c.Query("page_size")); err == nil {
+		filter.PageSize = pageSize
+	}
+
+	logs, total, err := s.auditService.SearchAuditLogs(filter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, AuditLogResponse{
+		Total:    total,
+		Page:     filter.Page,
+		PageSize: filter.PageSize,
+		Logs:     logs,
+	})
+}
 
-	logs, err := s.auditService.ListAuditLogs(page, pageSize)
+// @Summary Export audit logs
+// @Description Download the audit logs matching the filters as CSV, latest first. The X-Total-Count header holds the number of matches, which may exceed the rows exported.
+// @Tags audit
+// @Security BearerAuth
+// @Produce text/csv
+// @Param user_id query int false "User ID"
+// @Param impersonator_id query int false "Admin who made the requests while impersonating the user"
+// @Param impersonated query bool false "Only requests made in impersonation sessions"
+// @Param action query string false "Action (create/read/update/delete/login/logout)"
+// @Param resource query string false "Part of the resource path, e.g. an entity type"
+// @Param ip query string false "Client IP address"
+// @Param q query string false "Free text matched against resource, detail and user agent"
+// @Param start_date query string false "Start date (YYYY-MM-DD)"
+// @Param end_date query string false "End date (YYYY-MM-DD)"
+// @Param archived query bool false "Export the archived logs instead of the live ones"
+// @Success 200 {file} file
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /audit/logs/export [get]
+func (s *Server) handleExportAuditLogs(c *gin.Context) {
+	filter := auditLogFilter(c)
+
+	logs, total, err := s.auditService.ExportAuditLogs(filter)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
-	count, err := s.auditService.GetAuditLogsCount(nil)
+	c.Header("Content-Type", "text/csv")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=audit-logs-%s.csv", time.Now().Format("20060102-150405")))
+	c.Header("X-Total-Count", strconv.FormatInt(total, 10))
+	c.Status(http.StatusOK)
+
+	w := csv.NewWriter(c.Writer)
+	_ = w.Write([]string{"id", "created_at", "user_id", "username", "impersonator_id", "action", "resource", "ip", "user_agent", "detail"})
+	for _, log := range logs {
+		username := ""
+		if log.User != nil {
+			username = log.User.Username
+		}
+		impersonatorID := ""
+		if log.ImpersonatorID != nil {
+			impersonatorID = strconv.FormatUint(uint64(*log.ImpersonatorID), 10)
+		}
+		_ = w.Write([]string{
+			strconv.FormatUint(uint64(log.ID), 10),
+			log.CreatedAt.Format(time.RFC3339),
+			strconv.FormatUint(uint64(log.UserID), 10),
+			username,
+			impersonatorID,
+			string(log.Action),
+			log.Resource,
+			log.IP,
+			log.UserAgent,
+			log.Detail,
+		})
+	}
+	w.Flush()
+}
+
+// @Summary Verify audit log chain
+// @Description Check the hash chain of the audit logs written in a period, live and archived, all of them by default. A break is a log whose content no longer matches its hash, or whose previous hash does not match the log before it because that log was removed or changed. Logs written before chaining are counted as unhashed.
+// @Tags audit
+// @Security BearerAuth
+// @Produce json
+// @Param start_date query string false "Start date (YYYY-MM-DD)"
+// @Param end_date query string false "End date (YYYY-MM-DD)"
+// @Success 200 {object} entity.AuditChainVerification
+// @Failure 400 {object} ErrorResponse "Invalid date range"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /audit/logs/verify [get]
+func (s *Server) handleVerifyAuditLogs(c *gin.Context) {
+	var startDate, endDate *time.Time
+	if date, err := time.Parse("2006-01-02", c.Query("start_date")); err == nil {
+		startDate = &date
+	}
+	if date, err := time.Parse("2006-01-02", c.Query("end_date")); err == nil {
+		date = date.AddDate(0, 0, 1).Add(-time.Nanosecond)
+		endDate = &date
+	}
+
+	verification, err := s.auditService.VerifyChain(c.Request.Context(), startDate, endDate)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, verification)
+}
+
+// @Summary Run audit log retention
+// @Description Export the audit logs written before the retention days to the file store as gzipped JSON lines and remove them from the database
+// @Tags audit
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} entity.AuditRetentionResult
+// @Failure 422 {object} ErrorResponse "Retention not configured"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /audit/logs/retention/run [post]
+func (s *Server) handleRunAuditRetention(c *gin.Context) {
+	result, err := s.auditService.RunRetention(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// @Summary List audit log archives
+// @Description List the exports of audit logs past their retention, latest first, with a download URL for each
+// @Tags audit
+// @Security BearerAuth
+// @Produce json
+// @Param page query int false "Page number"
+// @Param page_size query int false "Page size"
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /audit/logs/archives [get]
+func (s *Server) handleListAuditArchives(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+
+	archives, total, err := s.auditService.ListArchives(c.Request.Context(), page, pageSize)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"total": count,
-		"logs":  logs,
+		"archives":  archives,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
 	})
 }
+
+// auditLogFilter reads the audit log search filters from the query string
+func auditLogFilter(c *gin.Context) *entity.AuditLogFilter {
+	filter := &entity.AuditLogFilter{
+		Action:   entity.ActionType(c.Query("action")),
+		Resource: c.Query("resource"),
+		IP:       c.Query("ip"),
+		Query:    c.Query("q"),
+	}
+
+	if userID, err := strconv.ParseUint(c.Query("user_id"), 10, 32); err == nil {
+		filter.UserID = uint(userID)
+	}
+
+	if impersonatorID, err := strconv.ParseUint(c.Query("impersonator_id"), 10, 32); err == nil {
+		filter.ImpersonatorID = uint(impersonatorID)
+	}
+
+	if impersonated, err := strconv.ParseBool(c.Query("impersonated")); err == nil {
+		filter.Impersonated = impersonated
+	}
+
+	if startDateStr := c.Query("start_date"); startDateStr != "" {
+		if startDate, err := time.Parse("2006-01-02", startDateStr); err == nil {
+			filter.StartDate = &startDate
+		}
+	}
+
+	if endDateStr := c.Query("end_date"); endDateStr != "" {
+		if endDate, err := time.Parse("2006-01-02", endDateStr); err == nil {
+			endDate = endDate.AddDate(0, 0, 1).Add(-time.Nanosecond)
+			filter.EndDate = &endDate
+		}
+	}
+
+	if archived, err := strconv.ParseBool(c.Query("archived")); err == nil {
+		filter.Archived = archived
+	}
+
+	return filter
+}
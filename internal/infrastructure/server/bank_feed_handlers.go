@@ -0,0 +1,405 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/auth"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/server/middleware"
+)
+
+// BankFeedHandlers handles bank feed HTTP requests
+type BankFeedHandlers struct {
+	bankFeedUseCase *usecase.BankFeedUseCase
+}
+
+// NewBankFeedHandlers creates a new bank feed handlers instance
+func NewBankFeedHandlers(bankFeedUseCase *usecase.BankFeedUseCase) *BankFeedHandlers {
+	return &BankFeedHandlers{
+		bankFeedUseCase: bankFeedUseCase,
+	}
+}
+
+// RegisterRoutes registers bank feed routes
+func (h *BankFeedHandlers) RegisterRoutes(router *gin.RouterGroup) {
+	bankRouter := router.Group("/finance/bank")
+	{
+		bankRouter.POST("/accounts", middleware.PermissionMiddleware(entity.FinanceBankManage), h.CreateAccount)
+		bankRouter.GET("/accounts", middleware.PermissionMiddleware(entity.FinanceBankRead), h.ListAccounts)
+		bankRouter.GET("/accounts/:id", middleware.PermissionMiddleware(entity.FinanceBankRead), h.GetAccount)
+		bankRouter.PUT("/accounts/:id", middleware.PermissionMiddleware(entity.FinanceBankManage), h.UpdateAccount)
+		bankRouter.POST("/accounts/:id/import", middleware.PermissionMiddleware(entity.FinanceBankManage), h.ImportTransactions)
+		bankRouter.GET("/accounts/:id/imports", middleware.PermissionMiddleware(entity.FinanceBankRead), h.ListImportRuns)
+
+		bankRouter.GET("/transactions", middleware.PermissionMiddleware(entity.FinanceBankRead), h.ListTransactions)
+		bankRouter.GET("/transactions/:id", middleware.PermissionMiddleware(entity.FinanceBankRead), h.GetTransaction)
+		bankRouter.GET("/transactions/:id/suggestions", middleware.PermissionMiddleware(entity.FinanceBankRead), h.SuggestMatches)
+		bankRouter.POST("/transactions/:id/match", middleware.PermissionMiddleware(entity.FinanceBankReconcile), h.MatchTransaction)
+		bankRouter.POST("/transactions/:id/ignore", middleware.PermissionMiddleware(entity.FinanceBankReconcile), h.IgnoreTransaction)
+	}
+}
+
+// CreateAccount handles creating a bank account
+// @Summary Create bank account
+// @Description Set up a bank account whose transactions are imported from a bank feed provider
+// @Tags finance
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param account body entity.BankAccountRequest true "Bank account details"
+// @Success 201 {object} entity.BankAccount
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse "The provider account is already set up"
+// @Failure 500 {object} ErrorResponse
+// @Router /finance/bank/accounts [post]
+func (h *BankFeedHandlers) CreateAccount(c *gin.Context) {
+	var req entity.BankAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	account, err := h.bankFeedUseCase.CreateAccount(c.Request.Context(), &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, account)
+}
+
+// ListAccounts handles listing bank accounts
+// @Summary List bank accounts
+// @Description List the bank accounts imported from bank feeds
+// @Tags finance
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} entity.BankAccount
+// @Failure 500 {object} ErrorResponse
+// @Router /finance/bank/accounts [get]
+func (h *BankFeedHandlers) ListAccounts(c *gin.Context) {
+	accounts, err := h.bankFeedUseCase.ListAccounts(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, accounts)
+}
+
+// GetAccount handles getting a bank account
+// @Summary Get bank account
+// @Description Get a bank account
+// @Tags finance
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Bank account ID"
+// @Success 200 {object} entity.BankAccount
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /finance/bank/accounts/{id} [get]
+func (h *BankFeedHandlers) GetAccount(c *gin.Context) {
+	id, ok := parseBankFeedID(c)
+	if !ok {
+		return
+	}
+
+	account, err := h.bankFeedUseCase.GetAccount(c.Request.Context(), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, account)
+}
+
+// UpdateAccount handles updating a bank account
+// @Summary Update bank account
+// @Description Update a bank account. An access token left empty is kept.
+// @Tags finance
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Bank account ID"
+// @Param account body entity.BankAccountRequest true "Bank account details"
+// @Success 200 {object} entity.BankAccount
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse "The provider account is already set up"
+// @Failure 500 {object} ErrorResponse
+// @Router /finance/bank/accounts/{id} [put]
+func (h *BankFeedHandlers) UpdateAccount(c *gin.Context) {
+	id, ok := parseBankFeedID(c)
+	if !ok {
+		return
+	}
+	var req entity.BankAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	account, err := h.bankFeedUseCase.UpdateAccount(c.Request.Context(), id, &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, account)
+}
+
+// ImportTransactions handles importing the transactions of a bank account
+// @Summary Import bank transactions
+// @Description Import the transactions booked on a bank account since its last import, as the scheduled import does
+// @Tags finance
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Bank account ID"
+// @Success 200 {object} entity.BankImportRun
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse "Bank account is inactive"
+// @Failure 500 {object} ErrorResponse
+// @Router /finance/bank/accounts/{id}/import [post]
+func (h *BankFeedHandlers) ImportTransactions(c *gin.Context) {
+	id, ok := parseBankFeedID(c)
+	if !ok {
+		return
+	}
+
+	run, err := h.bankFeedUseCase.ImportTransactions(c.Request.Context(), id, auth.GetUserIDFromContext(c))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, run)
+}
+
+// ListImportRuns handles listing the import runs of a bank account
+// @Summary List bank imports
+// @Description List the latest import runs of a bank account
+// @Tags finance
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Bank account ID"
+// @Success 200 {array} entity.BankImportRun
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /finance/bank/accounts/{id}/imports [get]
+func (h *BankFeedHandlers) ListImportRuns(c *gin.Context) {
+	id, ok := parseBankFeedID(c)
+	if !ok {
+		return
+	}
+
+	runs, err := h.bankFeedUseCase.ListImportRuns(c.Request.Context(), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, runs)
+}
+
+// ListTransactions handles listing imported bank transactions
+// @Summary List bank transactions
+// @Description List imported bank transactions, latest first
+// @Tags finance
+// @Security BearerAuth
+// @Produce json
+// @Param account_id query int false "Bank account ID"
+// @Param status query string false "Status (UNMATCHED, MATCHED, IGNORED)"
+// @Param start_date query string false "Booked from (YYYY-MM-DD)"
+// @Param end_date query string false "Booked until (YYYY-MM-DD)"
+// @Param page query int false "Page number"
+// @Param page_size query int false "Page size"
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} ErrorResponse
+// @Router /finance/bank/transactions [get]
+func (h *BankFeedHandlers) ListTransactions(c *gin.Context) {
+	filter := &entity.BankTransactionFilter{
+		Status: entity.BankTransactionStatus(c.Query("status")),
+	}
+
+	if accountID, err := strconv.ParseUint(c.Query("account_id"), 10, 32); err == nil {
+		filter.AccountID = uint(accountID)
+	}
+
+	if startDateStr := c.Query("start_date"); startDateStr != "" {
+		if startDate, err := time.Parse("2006-01-02", startDateStr); err == nil {
+			filter.StartDate = &startDate
+		}
+	}
+
+	if endDateStr := c.Query("end_date"); endDateStr != "" {
+		if endDate, err := time.Parse("2006-01-02", endDateStr); err == nil {
+			endDate = endDate.AddDate(0, 0, 1).Add(-time.Nanosecond)
+			filter.EndDate = &endDate
+		}
+	}
+
+	if page, err := strconv.Atoi(c.Query("page")); err == nil {
+		filter.Page = page
+	}
+
+	if pageSize, err := strconv.Atoi(c.Query("page_size")); err == nil {
+		filter.PageSize = pageSize
+	}
+
+	transactions, total, err := h.bankFeedUseCase.ListTransactions(c.Request.Context(), filter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"transactions": transactions,
+		"total":        total,
+		"page":         filter.Page,
+		"page_size":    filter.PageSize,
+	})
+}
+
+// GetTransaction handles getting a bank transaction
+// @Summary Get bank transaction
+// @Description Get an imported bank transaction
+// @Tags finance
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Bank transaction ID"
+// @Success 200 {object} entity.BankTransaction
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /finance/bank/transactions/{id} [get]
+func (h *BankFeedHandlers) GetTransaction(c *gin.Context) {
+	id, ok := parseBankFeedID(c)
+	if !ok {
+		return
+	}
+
+	transaction, err := h.bankFeedUseCase.GetTransaction(c.Request.Context(), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, transaction)
+}
+
+// SuggestMatches handles suggesting the invoices a bank transaction may settle
+// @Summary Suggest bank matches
+// @Description Suggest the open sales invoices money received may settle, or the open purchase invoices money paid out may settle, best first
+// @Tags finance
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Bank transaction ID"
+// @Success 200 {array} entity.BankMatchSuggestion
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse "Transaction is already matched or ignored"
+// @Failure 500 {object} ErrorResponse
+// @Router /finance/bank/transactions/{id}/suggestions [get]
+func (h *BankFeedHandlers) SuggestMatches(c *gin.Context) {
+	id, ok := parseBankFeedID(c)
+	if !ok {
+		return
+	}
+
+	suggestions, err := h.bankFeedUseCase.SuggestMatches(c.Request.Context(), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, suggestions)
+}
+
+// MatchTransaction handles confirming that a bank transaction settles an invoice
+// @Summary Match bank transaction
+// @Description Confirm that a bank transaction settles an invoice, recording it as a completed bank transfer payment of the invoice
+// @Tags finance
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Bank transaction ID"
+// @Param match body entity.BankMatchRequest true "Invoice settled"
+// @Success 200 {object} entity.BankTransaction
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse "Transaction is reconciled, or does not fit the invoice"
+// @Failure 500 {object} ErrorResponse
+// @Router /finance/bank/transactions/{id}/match [post]
+func (h *BankFeedHandlers) MatchTransaction(c *gin.Context) {
+	userID := currentUserID(c)
+	if userID == nil {
+		c.Error(entity.NewError(entity.ErrCodeUnauthenticated, "unauthorized"))
+		return
+	}
+	id, ok := parseBankFeedID(c)
+	if !ok {
+		return
+	}
+	var req entity.BankMatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	transaction, err := h.bankFeedUseCase.MatchTransaction(c.Request.Context(), id, &req, *userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, transaction)
+}
+
+// IgnoreTransaction handles setting aside a bank transaction
+// @Summary Ignore bank transaction
+// @Description Set aside an unmatched bank transaction that settles no invoice, such as bank charges
+// @Tags finance
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Bank transaction ID"
+// @Success 200 {object} entity.BankTransaction
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse "Transaction is already matched or ignored"
+// @Failure 500 {object} ErrorResponse
+// @Router /finance/bank/transactions/{id}/ignore [post]
+func (h *BankFeedHandlers) IgnoreTransaction(c *gin.Context) {
+	userID := currentUserID(c)
+	if userID == nil {
+		c.Error(entity.NewError(entity.ErrCodeUnauthenticated, "unauthorized"))
+		return
+	}
+	id, ok := parseBankFeedID(c)
+	if !ok {
+		return
+	}
+
+	transaction, err := h.bankFeedUseCase.IgnoreTransaction(c.Request.Context(), id, *userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, transaction)
+}
+
+func parseBankFeedID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid ID"))
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -0,0 +1,224 @@
+package server
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/server/middleware"
+)
+
+// brandingLogoPath is where document renderers fetch the logo from
+const brandingLogoPath = "/api/v1/settings/branding/logo"
+
+// BrandingHandlers handles company branding HTTP requests
+type BrandingHandlers struct {
+	brandingUseCase *usecase.BrandingUseCase
+}
+
+// NewBrandingHandlers creates a new branding handlers instance
+func NewBrandingHandlers(brandingUseCase *usecase.BrandingUseCase) *BrandingHandlers {
+	return &BrandingHandlers{
+		brandingUseCase: brandingUseCase,
+	}
+}
+
+// RegisterRoutes registers branding routes
+func (h *BrandingHandlers) RegisterRoutes(router *gin.RouterGroup) {
+	branding := router.Group("/settings/branding")
+	{
+		branding.GET("", middleware.PermissionMiddleware(entity.SystemSettingsRead), h.GetBranding)
+		branding.PUT("", middleware.PermissionMiddleware(entity.SystemSettingsUpdate), h.UpdateBranding)
+		branding.GET("/document", middleware.PermissionMiddleware(entity.SystemSettingsRead), h.GetDocumentBranding)
+		branding.GET("/logo", middleware.PermissionMiddleware(entity.SystemSettingsRead), h.GetLogo)
+		branding.PUT("/logo", middleware.PermissionMiddleware(entity.SystemSettingsUpdate), h.UploadLogo)
+		branding.DELETE("/logo", middleware.PermissionMiddleware(entity.SystemSettingsUpdate), h.DeleteLogo)
+	}
+}
+
+// GetBranding handles getting the company branding
+// @Summary Get branding
+// @Description Get the company name, address, bank details, VAT numbers and invoice footer printed on documents and emails
+// @Tags settings
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} entity.Branding
+// @Failure 500 {object} ErrorResponse
+// @Router /settings/branding [get]
+func (h *BrandingHandlers) GetBranding(c *gin.Context) {
+	branding, err := h.brandingUseCase.Get(c.Request.Context())
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, branding)
+}
+
+// UpdateBranding handles updating the company branding
+// @Summary Update branding
+// @Description Replace the company branding details. The logo is uploaded separately and kept.
+// @Tags settings
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body entity.BrandingRequest true "Branding details"
+// @Success 200 {object} entity.Branding
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /settings/branding [put]
+func (h *BrandingHandlers) UpdateBranding(c *gin.Context) {
+	var req entity.BrandingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	branding, err := h.brandingUseCase.Update(c.Request.Context(), &req, currentUserID(c))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, branding)
+}
+
+// GetDocumentBranding handles getting the branding laid out for documents
+// @Summary Get document branding
+// @Description Get the header address block, bank details, VAT numbers, footer lines and logo URL used when rendering PDFs and emails
+// @Tags settings
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} entity.DocumentBranding
+// @Failure 500 {object} ErrorResponse
+// @Router /settings/branding/document [get]
+func (h *BrandingHandlers) GetDocumentBranding(c *gin.Context) {
+	document, err := h.brandingUseCase.Document(c.Request.Context(), brandingLogoPath)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, document)
+}
+
+// GetLogo handles downloading the company logo
+// @Summary Get logo
+// @Description Download the company logo image
+// @Tags settings
+// @Security BearerAuth
+// @Produce png,jpeg,gif,webp,image/svg+xml
+// @Success 200 {file} file
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /settings/branding/logo [get]
+func (h *BrandingHandlers) GetLogo(c *gin.Context) {
+	branding, err := h.brandingUseCase.GetLogo(c.Request.Context())
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	// SVG logos may carry scripts; never let the browser run them
+	c.Header("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'")
+	c.Header("X-Content-Type-Options", "nosniff")
+	if branding.LogoUpdatedAt != nil {
+		c.Header("Last-Modified", branding.LogoUpdatedAt.UTC().Format(http.TimeFormat))
+	}
+	c.Data(http.StatusOK, branding.LogoContentType, branding.LogoData)
+}
+
+// UploadLogo handles replacing the company logo
+// @Summary Upload logo
+// @Description Replace the company logo with a PNG, JPEG, GIF, WebP or SVG image of at most 1MB, uploaded as the "file" form field or as the raw request body
+// @Tags settings
+// @Security BearerAuth
+// @Accept multipart/form-data,png,jpeg,gif,webp,image/svg+xml
+// @Produce json
+// @Param file formData file false "Logo image"
+// @Success 200 {object} entity.Branding
+// @Failure 400 {object} ErrorResponse
+// @Failure 413 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /settings/branding/logo [put]
+func (h *BrandingHandlers) UploadLogo(c *gin.Context) {
+	// Leave room for the multipart envelope around the image
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, usecase.MaxLogoBytes+64<<10)
+	var body io.Reader = c.Request.Body
+	if c.ContentType() == "multipart/form-data" {
+		header, err := c.FormFile("file")
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				h.handleError(c, usecase.ErrLogoTooLarge)
+				return
+			}
+			c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Logo file is required"))
+			return
+		}
+		file, err := header.Open()
+		if err != nil {
+			c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid logo file"))
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+
+	data, err := io.ReadAll(io.LimitReader(body, usecase.MaxLogoBytes+1))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			h.handleError(c, usecase.ErrLogoTooLarge)
+			return
+		}
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid logo file"))
+		return
+	}
+	if len(data) == 0 {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Logo file is required"))
+		return
+	}
+
+	branding, err := h.brandingUseCase.SetLogo(c.Request.Context(), data, currentUserID(c))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, branding)
+}
+
+// DeleteLogo handles removing the company logo
+// @Summary Delete logo
+// @Description Remove the company logo from documents and emails
+// @Tags settings
+// @Security BearerAuth
+// @Success 204 "No Content"
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /settings/branding/logo [delete]
+func (h *BrandingHandlers) DeleteLogo(c *gin.Context) {
+	if err := h.brandingUseCase.DeleteLogo(c.Request.Context(), currentUserID(c)); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *BrandingHandlers) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, usecase.ErrLogoNotFound):
+		c.Error(entity.WrapError(entity.ErrCodeNotFound, err))
+	case errors.Is(err, usecase.ErrLogoTooLarge):
+		c.Error(entity.WrapError(entity.ErrCodePayloadTooLarge, err))
+	case errors.Is(err, usecase.ErrLogoUnsupported):
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+	default:
+		c.Error(err)
+	}
+}
@@ -0,0 +1,309 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/server/middleware"
+)
+
+// CalendarHandlers handles working calendar HTTP requests
+type CalendarHandlers struct {
+	calendarUseCase *usecase.CalendarUseCase
+}
+
+// NewCalendarHandlers creates a new calendar handlers instance
+func NewCalendarHandlers(calendarUseCase *usecase.CalendarUseCase) *CalendarHandlers {
+	return &CalendarHandlers{
+		calendarUseCase: calendarUseCase,
+	}
+}
+
+// RegisterRoutes registers working calendar routes
+func (h *CalendarHandlers) RegisterRoutes(router *gin.RouterGroup) {
+	calendars := router.Group("/settings/calendars")
+	{
+		calendars.POST("", middleware.PermissionMiddleware(entity.SystemSettingsUpdate), h.CreateCalendar)
+		calendars.GET("", middleware.PermissionMiddleware(entity.SystemSettingsRead), h.ListCalendars)
+		calendars.GET("/calculate", middleware.PermissionMiddleware(entity.SystemSettingsRead), h.Calculate)
+		calendars.GET("/:id", middleware.PermissionMiddleware(entity.SystemSettingsRead), h.GetCalendar)
+		calendars.PUT("/:id", middleware.PermissionMiddleware(entity.SystemSettingsUpdate), h.UpdateCalendar)
+		calendars.DELETE("/:id", middleware.PermissionMiddleware(entity.SystemSettingsUpdate), h.DeleteCalendar)
+		calendars.POST("/:id/holidays", middleware.PermissionMiddleware(entity.SystemSettingsUpdate), h.AddHoliday)
+		calendars.DELETE("/:id/holidays/:holidayId", middleware.PermissionMiddleware(entity.SystemSettingsUpdate), h.DeleteHoliday)
+	}
+}
+
+// CreateCalendar handles creating a working calendar
+// @Summary Create working calendar
+// @Description Create the company working calendar, or one for a warehouse when store_id is set. Working days are 0 (Sunday) to 6 (Saturday); a shift ending at or before its start runs past midnight.
+// @Tags settings
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body entity.WorkingCalendarRequest true "Working calendar"
+// @Success 201 {object} entity.WorkingCalendar
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /settings/calendars [post]
+func (h *CalendarHandlers) CreateCalendar(c *gin.Context) {
+	var req entity.WorkingCalendarRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	calendar, err := h.calendarUseCase.Create(c.Request.Context(), &req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, calendar)
+}
+
+// ListCalendars handles listing working calendars
+// @Summary List working calendars
+// @Description List the company and warehouse working calendars with their holidays
+// @Tags settings
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} entity.WorkingCalendar
+// @Failure 500 {object} ErrorResponse
+// @Router /settings/calendars [get]
+func (h *CalendarHandlers) ListCalendars(c *gin.Context) {
+	calendars, err := h.calendarUseCase.List(c.Request.Context())
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, calendars)
+}
+
+// GetCalendar handles getting a working calendar
+// @Summary Get working calendar
+// @Description Get a working calendar with its holidays
+// @Tags settings
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Calendar ID"
+// @Success 200 {object} entity.WorkingCalendar
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /settings/calendars/{id} [get]
+func (h *CalendarHandlers) GetCalendar(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid calendar ID"))
+		return
+	}
+
+	calendar, err := h.calendarUseCase.Get(c.Request.Context(), uint(id))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, calendar)
+}
+
+// UpdateCalendar handles updating a working calendar
+// @Summary Update working calendar
+// @Description Replace the working days, shifts and time zone of a calendar. Its holidays are kept.
+// @Tags settings
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Calendar ID"
+// @Param request body entity.WorkingCalendarRequest true "Working calendar"
+// @Success 200 {object} entity.WorkingCalendar
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /settings/calendars/{id} [put]
+func (h *CalendarHandlers) UpdateCalendar(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid calendar ID"))
+		return
+	}
+
+	var req entity.WorkingCalendarRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	calendar, err := h.calendarUseCase.Update(c.Request.Context(), uint(id), &req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, calendar)
+}
+
+// DeleteCalendar handles deleting a working calendar
+// @Summary Delete working calendar
+// @Description Delete a working calendar and its holidays. The warehouse falls back to the company calendar.
+// @Tags settings
+// @Security BearerAuth
+// @Param id path int true "Calendar ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /settings/calendars/{id} [delete]
+func (h *CalendarHandlers) DeleteCalendar(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid calendar ID"))
+		return
+	}
+
+	if err := h.calendarUseCase.Delete(c.Request.Context(), uint(id)); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// AddHoliday handles adding a holiday to a working calendar
+// @Summary Add holiday
+// @Description Mark a date as a non-working day of a calendar
+// @Tags settings
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Calendar ID"
+// @Param request body entity.CalendarHolidayRequest true "Holiday"
+// @Success 201 {object} entity.CalendarHoliday
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /settings/calendars/{id}/holidays [post]
+func (h *CalendarHandlers) AddHoliday(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid calendar ID"))
+		return
+	}
+
+	var req entity.CalendarHolidayRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	holiday, err := h.calendarUseCase.AddHoliday(c.Request.Context(), uint(id), &req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, holiday)
+}
+
+// DeleteHoliday handles removing a holiday from a working calendar
+// @Summary Delete holiday
+// @Description Make a holiday a working day of the calendar again
+// @Tags settings
+// @Security BearerAuth
+// @Param id path int true "Calendar ID"
+// @Param holidayId path int true "Holiday ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /settings/calendars/{id}/holidays/{holidayId} [delete]
+func (h *CalendarHandlers) DeleteHoliday(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid calendar ID"))
+		return
+	}
+	holidayID, err := strconv.ParseUint(c.Param("holidayId"), 10, 32)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid holiday ID"))
+		return
+	}
+
+	if err := h.calendarUseCase.DeleteHoliday(c.Request.Context(), uint(id), uint(holidayID)); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// Calculate handles business day arithmetic on a warehouse calendar
+// @Summary Calculate business days
+// @Description Get the next working time from a moment and the date a number of business days later, on the calendar of a warehouse or the company
+// @Tags settings
+// @Security BearerAuth
+// @Produce json
+// @Param store_id query string false "Warehouse ID; the company calendar when empty"
+// @Param from query string false "Start time (RFC 3339), now when empty"
+// @Param business_days query int false "Business days to add"
+// @Success 200 {object} entity.CalendarCalculation
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /settings/calendars/calculate [get]
+func (h *CalendarHandlers) Calculate(c *gin.Context) {
+	from := time.Now()
+	if value := c.Query("from"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid from time, expected RFC 3339"))
+			return
+		}
+		from = parsed
+	}
+
+	days := 0
+	if value := c.Query("business_days"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid business_days"))
+			return
+		}
+		days = parsed
+	}
+
+	result, err := h.calendarUseCase.Calculate(c.Request.Context(), c.Query("store_id"), from, days)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// handleError maps working calendar errors to HTTP responses
+func (h *CalendarHandlers) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, usecase.ErrCalendarNotFound),
+		errors.Is(err, usecase.ErrHolidayNotFound):
+		c.Error(entity.WrapError(entity.ErrCodeNotFound, err))
+	case errors.Is(err, usecase.ErrCalendarExists),
+		errors.Is(err, usecase.ErrHolidayExists):
+		c.Error(entity.WrapError(entity.ErrCodeConflict, err))
+	case errors.Is(err, usecase.ErrInvalidTimezone),
+		errors.Is(err, usecase.ErrInvalidShift),
+		errors.Is(err, usecase.ErrInvalidHoliday):
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+	default:
+		c.Error(err)
+	}
+}
@@ -58,12 +58,12 @@ func (h *ClientHandler) RegisterRoutes(rg *gin.RouterGroup) {
 func (h *ClientHandler) CreateClient(c *gin.Context) {
 	var client entity.Client
 	if err := c.ShouldBindJSON(&client); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 
 	if err := h.clientUC.CreateClient(&client); err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -83,13 +83,13 @@ func (h *ClientHandler) CreateClient(c *gin.Context) {
 func (h *ClientHandler) GetClient(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid client ID"})
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "invalid client ID"))
 		return
 	}
 
 	client, err := h.clientUC.GetClientByID(uint(id))
 	if err != nil {
-		c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeNotFound, err))
 		return
 	}
 
@@ -111,20 +111,19 @@ func (h *ClientHandler) GetClient(c *gin.Context) {
 func (h *ClientHandler) UpdateClient(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid client ID"})
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "invalid client ID"))
 		return
 	}
 
 	var client entity.Client
 	if err := c.ShouldBindJSON(&client); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 	client.ID = uint(id)
 
 	if err := h.clientUC.UpdateClient(&client); err != nil {
-		statusCode := http.StatusInternalServerError
-		c.JSON(statusCode, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -144,12 +143,12 @@ func (h *ClientHandler) UpdateClient(c *gin.Context) {
 func (h *ClientHandler) DeleteClient(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid client ID"})
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "invalid client ID"))
 		return
 	}
 
 	if err := h.clientUC.DeleteClient(uint(id)); err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -169,7 +168,12 @@ func (h *ClientHandler) DeleteClient(c *gin.Context) {
 // @Param country query string false "Client country"
 // @Param type query string false "Client type"
 // @Param loyalty_tier query string false "Client loyalty tier"
+// @Param rfm_segment query string false "RFM segment"
+// @Param min_recency_score query int false "Minimum RFM recency score (1-5)"
+// @Param min_frequency_score query int false "Minimum RFM frequency score (1-5)"
+// @Param min_monetary_score query int false "Minimum RFM monetary score (1-5)"
 // @Success 200 {array} entity.Client
+// @Failure 400 {object} ErrorResponse "Invalid RFM filter"
 // @Failure 500 {object} ErrorResponse "Server error"
 // @Router /clients [get]
 func (h *ClientHandler) ListClients(c *gin.Context) {
@@ -191,9 +195,16 @@ func (h *ClientHandler) ListClients(c *gin.Context) {
 		filter.LoyaltyTier = &loyaltyTier
 	}
 
+	rfm, err := parseRFMFilter(c)
+	if err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+	filter.RFM = rfm
+
 	clients, err := h.clientUC.ListClients(filter)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -215,19 +226,19 @@ func (h *ClientHandler) ListClients(c *gin.Context) {
 func (h *ClientHandler) CreateAddress(c *gin.Context) {
 	clientID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid client ID"})
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "invalid client ID"))
 		return
 	}
 
 	var address entity.ClientAddress
 	if err := c.ShouldBindJSON(&address); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 	address.ClientID = uint(clientID)
 
 	if err := h.clientUC.CreateAddress(&address); err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -247,13 +258,13 @@ func (h *ClientHandler) CreateAddress(c *gin.Context) {
 func (h *ClientHandler) GetAddresses(c *gin.Context) {
 	clientID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid client ID"})
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "invalid client ID"))
 		return
 	}
 
 	addresses, err := h.clientUC.GetAddressesByClientID(uint(clientID))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -276,26 +287,26 @@ func (h *ClientHandler) GetAddresses(c *gin.Context) {
 func (h *ClientHandler) UpdateAddress(c *gin.Context) {
 	clientID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid client ID"})
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "invalid client ID"))
 		return
 	}
 
 	addressID, err := strconv.ParseUint(c.Param("addressId"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid address ID"})
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "invalid address ID"))
 		return
 	}
 
 	var address entity.ClientAddress
 	if err := c.ShouldBindJSON(&address); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 	address.ID = uint(addressID)
 	address.ClientID = uint(clientID)
 
 	if err := h.clientUC.UpdateAddress(&address); err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -316,12 +327,12 @@ func (h *ClientHandler) UpdateAddress(c *gin.Context) {
 func (h *ClientHandler) DeleteAddress(c *gin.Context) {
 	addressID, err := strconv.ParseUint(c.Param("addressId"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid address ID"})
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "invalid address ID"))
 		return
 	}
 
 	if err := h.clientUC.DeleteAddress(uint(addressID)); err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -341,13 +352,13 @@ func (h *ClientHandler) DeleteAddress(c *gin.Context) {
 func (h *ClientHandler) GetOrderHistory(c *gin.Context) {
 	clientID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid client ID"})
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "invalid client ID"))
 		return
 	}
 
 	history, err := h.clientUC.GetOrderHistory(uint(clientID))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -0,0 +1,216 @@
+package server
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/export"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/server/middleware"
+)
+
+// ClientPrivacyHandlers handles HTTP requests for the personal data of clients
+type ClientPrivacyHandlers struct {
+	privacyUseCase *usecase.ClientPrivacyUseCase
+}
+
+// NewClientPrivacyHandlers creates a new client privacy handlers instance
+func NewClientPrivacyHandlers(privacyUseCase *usecase.ClientPrivacyUseCase) *ClientPrivacyHandlers {
+	return &ClientPrivacyHandlers{
+		privacyUseCase: privacyUseCase,
+	}
+}
+
+// RegisterRoutes registers client privacy routes
+func (h *ClientPrivacyHandlers) RegisterRoutes(router *gin.RouterGroup) {
+	clients := router.Group("/clients/:id")
+	{
+		clients.GET("/export", middleware.PermissionMiddleware(entity.ClientPrivacyManage), h.ExportClientData)
+		clients.POST("/anonymize", middleware.PermissionMiddleware(entity.ClientPrivacyManage), h.AnonymizeClient)
+		clients.GET("/privacy-requests", middleware.PermissionMiddleware(entity.ClientPrivacyManage), h.ListRequests)
+	}
+}
+
+// ExportClientData handles exporting the personal data held on a client
+// @Summary Export client personal data
+// @Description Download the personal data held on a client with the sales orders, deliveries, invoices, returns and finance documents carrying it, archived ones included. JSON gives a single document; CSV gives a ZIP archive with a file per section. Every export is recorded as a privacy request.
+// @Tags clients
+// @Security BearerAuth
+// @Produce json,application/zip
+// @Param id path int true "Client ID"
+// @Param format query string false "json (default) or csv"
+// @Success 200 {object} entity.ClientDataExport
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /clients/{id}/export [get]
+func (h *ClientPrivacyHandlers) ExportClientData(c *gin.Context) {
+	id, ok := parseClientID(c)
+	if !ok {
+		return
+	}
+	format := export.Format(c.DefaultQuery("format", string(export.FormatJSON)))
+	if format != export.FormatJSON && format != export.FormatCSV {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "format must be json or csv"))
+		return
+	}
+
+	data, err := h.privacyUseCase.ExportClientData(c.Request.Context(), id, string(format), currentUserID(c))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	name := fmt.Sprintf("client-%d-data-%s", id, data.ExportedAt.Format("20060102-150405"))
+	if format == export.FormatJSON {
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.json", name))
+		c.JSON(http.StatusOK, data)
+		return
+	}
+
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.zip", name))
+	c.Status(http.StatusOK)
+	if err := writeClientDataArchive(c.Writer, data); err != nil {
+		c.Error(err)
+	}
+}
+
+// AnonymizeClient handles scrubbing the personal data of a client
+// @Summary Anonymize client
+// @Description Replace the name and email of a client with placeholders, clear its phone number, tax ID, contacts and notes, delete its addresses and clear the addresses and notes of its documents, archived ones included. Documents, amounts and quantities are kept. Recorded field changes of the scrubbed columns are redacted. The request is recorded with its reason.
+// @Tags clients
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Client ID"
+// @Param request body entity.ClientAnonymizeRequest true "Reason"
+// @Success 200 {object} entity.ClientPrivacyRequest
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse "Client is already anonymized"
+// @Failure 500 {object} ErrorResponse
+// @Router /clients/{id}/anonymize [post]
+func (h *ClientPrivacyHandlers) AnonymizeClient(c *gin.Context) {
+	id, ok := parseClientID(c)
+	if !ok {
+		return
+	}
+	var req entity.ClientAnonymizeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	request, err := h.privacyUseCase.AnonymizeClient(c.Request.Context(), id, &req, currentUserID(c))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, request)
+}
+
+// ListRequests handles listing the privacy requests of a client
+// @Summary List client privacy requests
+// @Description List the exports and anonymizations of a client's personal data, latest first
+// @Tags clients
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Client ID"
+// @Success 200 {array} entity.ClientPrivacyRequest
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /clients/{id}/privacy-requests [get]
+func (h *ClientPrivacyHandlers) ListRequests(c *gin.Context) {
+	id, ok := parseClientID(c)
+	if !ok {
+		return
+	}
+
+	requests, err := h.privacyUseCase.ListRequests(c.Request.Context(), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, requests)
+}
+
+// parseClientID reads the client ID path parameter
+func parseClientID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "invalid client ID"))
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// writeClientDataArchive writes the sections of a client data export as CSV
+// files in a ZIP archive. Nested values, such as order lines, get files of
+// their own.
+func writeClientDataArchive(w io.Writer, data *entity.ClientDataExport) error {
+	type section struct {
+		name    string
+		columns []export.Column
+		rows    [][]interface{}
+	}
+	var sections []section
+	add := func(name string, records interface{}) {
+		columns, rows := export.Table(records)
+		sections = append(sections, section{name, columns, rows})
+	}
+
+	columns, rows := export.Table(data.Client)
+	columns = append(columns, export.Column{Key: "contacts", Title: "Contacts"})
+	for i := range rows {
+		rows[i] = append(rows[i], string(data.Client.Contacts))
+	}
+	sections = append(sections, section{"client", columns, rows})
+	add("client_addresses", data.Client.Addresses)
+	add("sales_orders", data.SalesOrders)
+
+	itemColumns, _ := export.Table([]entity.SalesOrderItem{})
+	items := section{name: "sales_order_items", columns: append([]export.Column{{Key: "sales_order_id", Title: "Sales Order ID"}}, itemColumns...)}
+	for _, order := range data.SalesOrders {
+		_, itemRows := export.Table([]entity.SalesOrderItem(order.Items))
+		for _, row := range itemRows {
+			items.rows = append(items.rows, append([]interface{}{order.ID}, row...))
+		}
+	}
+	sections = append(sections, items)
+
+	add("delivery_orders", data.DeliveryOrders)
+	add("invoices", data.Invoices)
+	add("sales_returns", data.SalesReturns)
+	add("finance_invoices", data.FinanceInvoices)
+	add("finance_payments", data.FinancePayments)
+	add("privacy_requests", data.PrivacyRequests)
+
+	archive := zip.NewWriter(w)
+	for _, s := range sections {
+		f, err := archive.CreateHeader(&zip.FileHeader{Name: s.name + export.FormatCSV.Extension(), Method: zip.Deflate, Modified: data.ExportedAt})
+		if err != nil {
+			return err
+		}
+		cw, err := export.NewWriter(export.FormatCSV, f, &export.Document{Title: s.name, Columns: s.columns, GeneratedAt: data.ExportedAt})
+		if err != nil {
+			return err
+		}
+		for _, row := range s.rows {
+			if err := cw.WriteRow(row); err != nil {
+				return err
+			}
+		}
+		if err := cw.Close(); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
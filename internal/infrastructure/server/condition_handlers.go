@@ -0,0 +1,263 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/auth"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/server/middleware"
+)
+
+// ConditionHandlers handles HTTP requests for warehouse environmental
+// readings, thresholds and excursions
+type ConditionHandlers struct {
+	conditionUseCase *usecase.ConditionUseCase
+}
+
+// NewConditionHandlers creates a new condition handlers instance
+func NewConditionHandlers(conditionUseCase *usecase.ConditionUseCase) *ConditionHandlers {
+	return &ConditionHandlers{
+		conditionUseCase: conditionUseCase,
+	}
+}
+
+// RegisterRoutes registers warehouse condition routes
+func (h *ConditionHandlers) RegisterRoutes(router *gin.RouterGroup) {
+	conditions := router.Group("/conditions")
+	{
+		conditions.POST("/readings", middleware.PermissionMiddleware(entity.ConditionRecord), h.RecordReadings)
+		conditions.GET("/readings", middleware.PermissionMiddleware(entity.ConditionRead), h.ListReadings)
+		conditions.POST("/thresholds", middleware.PermissionMiddleware(entity.ConditionManage), h.CreateThreshold)
+		conditions.GET("/thresholds", middleware.PermissionMiddleware(entity.ConditionRead), h.ListThresholds)
+		conditions.PUT("/thresholds/:id", middleware.PermissionMiddleware(entity.ConditionManage), h.UpdateThreshold)
+		conditions.GET("/excursions", middleware.PermissionMiddleware(entity.ConditionRead), h.ListExcursions)
+		conditions.GET("/excursions/:id", middleware.PermissionMiddleware(entity.ConditionRead), h.GetExcursion)
+	}
+}
+
+// RecordReadings handles recording environmental readings
+// @Summary Record condition readings
+// @Description Record temperature or humidity readings of a store or zone, entered by hand or pushed by sensors with a device ID. A reading outside an active threshold opens an excursion, holding the lots stored in the zone for inspection.
+// @Tags conditions
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body entity.ConditionReadingsRequest true "Readings"
+// @Success 201 {array} entity.ConditionReading
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /conditions/readings [post]
+func (h *ConditionHandlers) RecordReadings(c *gin.Context) {
+	var req entity.ConditionReadingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	readings, err := h.conditionUseCase.RecordReadings(c.Request.Context(), &req, auth.GetUserIDFromContext(c))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, readings)
+}
+
+// ListReadings handles listing environmental readings
+// @Summary List condition readings
+// @Description List condition readings, latest first
+// @Tags conditions
+// @Security BearerAuth
+// @Produce json
+// @Param store_id query string false "Store ID"
+// @Param zone_code query string false "Zone code"
+// @Param metric query string false "TEMPERATURE or HUMIDITY"
+// @Param from query string false "Recorded from (RFC 3339)"
+// @Param to query string false "Recorded until (RFC 3339)"
+// @Param page query int false "Page number"
+// @Param page_size query int false "Page size"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /conditions/readings [get]
+func (h *ConditionHandlers) ListReadings(c *gin.Context) {
+	filter := &entity.ConditionReadingFilter{
+		StoreID:  c.Query("store_id"),
+		ZoneCode: c.Query("zone_code"),
+		Metric:   entity.ConditionMetric(c.Query("metric")),
+	}
+	for name, target := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+		if value := c.Query(name); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid "+name+" time, expected RFC 3339"))
+				return
+			}
+			*target = &parsed
+		}
+	}
+
+	if page, err := strconv.Atoi(c.Query("page")); err == nil {
+		filter.Page = page
+	}
+
+	if pageSize, err := strconv.Atoi(c.Query("page_size")); err == nil {
+		filter.PageSize = pageSize
+	}
+
+	readings, total, err := h.conditionUseCase.ListReadings(c.Request.Context(), filter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"readings":  readings,
+		"total":     total,
+		"page":      filter.Page,
+		"page_size": filter.PageSize,
+	})
+}
+
+// CreateThreshold handles creating a condition threshold
+// @Summary Create condition threshold
+// @Description Set the range a condition must stay in, in a store or one zone of it
+// @Tags conditions
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body entity.ConditionThresholdRequest true "Threshold"
+// @Success 201 {object} entity.ConditionThreshold
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /conditions/thresholds [post]
+func (h *ConditionHandlers) CreateThreshold(c *gin.Context) {
+	var req entity.ConditionThresholdRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	threshold, err := h.conditionUseCase.CreateThreshold(c.Request.Context(), &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, threshold)
+}
+
+// ListThresholds handles listing condition thresholds
+// @Summary List condition thresholds
+// @Description List the condition thresholds, optionally of a store
+// @Tags conditions
+// @Security BearerAuth
+// @Produce json
+// @Param store_id query string false "Store ID"
+// @Success 200 {array} entity.ConditionThreshold
+// @Failure 500 {object} ErrorResponse
+// @Router /conditions/thresholds [get]
+func (h *ConditionHandlers) ListThresholds(c *gin.Context) {
+	thresholds, err := h.conditionUseCase.ListThresholds(c.Request.Context(), c.Query("store_id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, thresholds)
+}
+
+// UpdateThreshold handles updating a condition threshold
+// @Summary Update condition threshold
+// @Description Update a condition threshold. Open excursions close once a reading is within the new limits.
+// @Tags conditions
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Threshold ID"
+// @Param request body entity.ConditionThresholdRequest true "Threshold"
+// @Success 200 {object} entity.ConditionThreshold
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /conditions/thresholds/{id} [put]
+func (h *ConditionHandlers) UpdateThreshold(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid threshold ID"))
+		return
+	}
+	var req entity.ConditionThresholdRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	threshold, err := h.conditionUseCase.UpdateThreshold(c.Request.Context(), uint(id), &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, threshold)
+}
+
+// ListExcursions handles listing condition excursions
+// @Summary List condition excursions
+// @Description List condition excursions with the lots exposed, latest first
+// @Tags conditions
+// @Security BearerAuth
+// @Produce json
+// @Param store_id query string false "Store ID"
+// @Param status query string false "OPEN or CLOSED"
+// @Param metric query string false "TEMPERATURE or HUMIDITY"
+// @Success 200 {array} entity.ConditionExcursion
+// @Failure 500 {object} ErrorResponse
+// @Router /conditions/excursions [get]
+func (h *ConditionHandlers) ListExcursions(c *gin.Context) {
+	excursions, err := h.conditionUseCase.ListExcursions(c.Request.Context(), &entity.ConditionExcursionFilter{
+		StoreID: c.Query("store_id"),
+		Status:  entity.ExcursionStatus(c.Query("status")),
+		Metric:  entity.ConditionMetric(c.Query("metric")),
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, excursions)
+}
+
+// GetExcursion handles getting a condition excursion
+// @Summary Get condition excursion
+// @Description Get a condition excursion with the lots exposed and the inspections holding them
+// @Tags conditions
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Excursion ID"
+// @Success 200 {object} entity.ConditionExcursion
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /conditions/excursions/{id} [get]
+func (h *ConditionHandlers) GetExcursion(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid excursion ID"))
+		return
+	}
+
+	excursion, err := h.conditionUseCase.GetExcursion(c.Request.Context(), uint(id))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, excursion)
+}
@@ -0,0 +1,198 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/auth"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/server/middleware"
+)
+
+// ConsignmentHandlers handles HTTP requests for vendor-owned consignment stock
+type ConsignmentHandlers struct {
+	consignmentUseCase *usecase.ConsignmentUseCase
+}
+
+// NewConsignmentHandlers creates a new consignment handlers instance
+func NewConsignmentHandlers(consignmentUseCase *usecase.ConsignmentUseCase) *ConsignmentHandlers {
+	return &ConsignmentHandlers{
+		consignmentUseCase: consignmentUseCase,
+	}
+}
+
+// RegisterRoutes registers consignment routes
+func (h *ConsignmentHandlers) RegisterRoutes(router *gin.RouterGroup) {
+	consignmentRouter := router.Group("/stocks/consignment")
+	{
+		consignmentRouter.POST("/receipts", middleware.PermissionMiddleware(entity.StockConsignmentManage), h.ReceiveStock)
+		consignmentRouter.POST("/returns", middleware.PermissionMiddleware(entity.StockConsignmentManage), h.ReturnStock)
+		consignmentRouter.GET("/balances", middleware.PermissionMiddleware(entity.StockConsignmentRead), h.ListBalances)
+		consignmentRouter.GET("/consumptions", middleware.PermissionMiddleware(entity.StockConsignmentRead), h.ListConsumptions)
+		consignmentRouter.POST("/bill", middleware.PermissionMiddleware(entity.StockConsignmentManage), h.BillConsumptions)
+	}
+}
+
+// ReceiveStock handles receiving consignment stock from a vendor
+// @Summary Receive consignment stock
+// @Description Post goods a vendor placed in a store on consignment, at the unit costs it charges once they are consumed. The stock grows as with any receipt, but the vendor owns it until it is issued.
+// @Tags stocks
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body entity.ConsignmentReceiptRequest true "Receipt"
+// @Success 201 {array} entity.StockEntry
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /stocks/consignment/receipts [post]
+func (h *ConsignmentHandlers) ReceiveStock(c *gin.Context) {
+	var req entity.ConsignmentReceiptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	entries, err := h.consignmentUseCase.ReceiveStock(c.Request.Context(), &req, auth.GetUserIDFromContext(c))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, entries)
+}
+
+// ReturnStock handles returning consignment stock to its vendor
+// @Summary Return consignment stock
+// @Description Post consigned goods a vendor takes back from a store. The vendor must still own the quantities returned, which are not owed to it.
+// @Tags stocks
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body entity.ConsignmentReceiptRequest true "Return"
+// @Success 201 {array} entity.StockEntry
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse "Vendor owns less stock than returned"
+// @Failure 500 {object} ErrorResponse
+// @Router /stocks/consignment/returns [post]
+func (h *ConsignmentHandlers) ReturnStock(c *gin.Context) {
+	var req entity.ConsignmentReceiptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	entries, err := h.consignmentUseCase.ReturnStock(c.Request.Context(), &req, auth.GetUserIDFromContext(c))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, entries)
+}
+
+// ListBalances handles reporting the consignment balances per vendor
+// @Summary Consignment balances
+// @Description Report per vendor the consignment stock it still owns, with its value at the vendor's unit costs, and the amount of consumed stock not billed yet
+// @Tags stocks
+// @Security BearerAuth
+// @Produce json
+// @Param vendor_id query int false "Vendor ID"
+// @Param store_id query string false "Store ID"
+// @Param sku_id query string false "SKU ID"
+// @Success 200 {array} entity.ConsignmentBalance
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /stocks/consignment/balances [get]
+func (h *ConsignmentHandlers) ListBalances(c *gin.Context) {
+	filter, ok := consignmentFilter(c)
+	if !ok {
+		return
+	}
+
+	balances, err := h.consignmentUseCase.ListBalances(c.Request.Context(), filter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, balances)
+}
+
+// ListConsumptions handles listing the consigned stock consumed
+// @Summary List consignment consumptions
+// @Description List the consigned stock that stock issues and counts converted to owned stock, latest first, with the purchase invoice billing each
+// @Tags stocks
+// @Security BearerAuth
+// @Produce json
+// @Param vendor_id query int false "Vendor ID"
+// @Param store_id query string false "Store ID"
+// @Param sku_id query string false "SKU ID"
+// @Param unbilled query bool false "Only consumptions not billed yet"
+// @Success 200 {array} entity.ConsignmentConsumption
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /stocks/consignment/consumptions [get]
+func (h *ConsignmentHandlers) ListConsumptions(c *gin.Context) {
+	filter, ok := consignmentFilter(c)
+	if !ok {
+		return
+	}
+	filter.Unbilled = c.Query("unbilled") == "true"
+
+	consumptions, err := h.consignmentUseCase.ListConsumptions(c.Request.Context(), filter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, consumptions)
+}
+
+// BillConsumptions handles billing the consigned stock consumed
+// @Summary Bill consignment consumptions
+// @Description Raise a pending purchase invoice to each vendor, or to one vendor, for the consumed consignment stock not billed yet. Consumptions are billed as deliveries ship and stock is issued already; this bills those that failed or were consumed otherwise, such as by counts.
+// @Tags stocks
+// @Security BearerAuth
+// @Produce json
+// @Param vendor_id query int false "Vendor ID"
+// @Success 200 {object} entity.ConsignmentBillingResult
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /stocks/consignment/bill [post]
+func (h *ConsignmentHandlers) BillConsumptions(c *gin.Context) {
+	filter, ok := consignmentFilter(c)
+	if !ok {
+		return
+	}
+	userID, _ := strconv.ParseInt(auth.GetUserIDFromContext(c), 10, 64)
+
+	result, err := h.consignmentUseCase.BillConsumptions(c.Request.Context(), filter.VendorID, userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// consignmentFilter reads the consignment filters from the query string
+func consignmentFilter(c *gin.Context) (*entity.ConsignmentFilter, bool) {
+	filter := &entity.ConsignmentFilter{
+		StoreID: c.Query("store_id"),
+		SKUID:   c.Query("sku_id"),
+	}
+	if v := c.Query("vendor_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "invalid vendor ID"))
+			return nil, false
+		}
+		vendorID := uint(id)
+		filter.VendorID = &vendorID
+	}
+	return filter, true
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/server/middleware"
+)
+
+// CostToServeHandlers handles cost-to-serve report HTTP requests
+type CostToServeHandlers struct {
+	costUseCase *usecase.CostToServeUseCase
+}
+
+// NewCostToServeHandlers creates a new cost-to-serve handlers instance
+func NewCostToServeHandlers(costUseCase *usecase.CostToServeUseCase) *CostToServeHandlers {
+	return &CostToServeHandlers{
+		costUseCase: costUseCase,
+	}
+}
+
+// RegisterRoutes registers cost-to-serve routes
+func (h *CostToServeHandlers) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("/reports/cost-to-serve", middleware.PermissionMiddleware(entity.ReportRead), h.GetCostToServe)
+}
+
+// GetCostToServe handles the cost-to-serve report
+// @Summary Get cost-to-serve report
+// @Description Charge the freight, picks and lines, returns and late payments of each customer against its gross margin, least profitable customer first
+// @Tags Reports
+// @Security BearerAuth
+// @Produce json
+// @Param start_date query string false "Period start (YYYY-MM-DD), defaults to 90 days before the end"
+// @Param end_date query string false "Period end (YYYY-MM-DD), defaults to now"
+// @Param client_id query int false "Limit the report to one customer"
+// @Success 200 {object} entity.CostToServeReport
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /reports/cost-to-serve [get]
+func (h *CostToServeHandlers) GetCostToServe(c *gin.Context) {
+	filter := &entity.CostToServeFilter{}
+	if startDate, err := time.Parse("2006-01-02", c.Query("start_date")); err == nil {
+		filter.StartDate = &startDate
+	}
+	if endDate, err := time.Parse("2006-01-02", c.Query("end_date")); err == nil {
+		endDate = endDate.Add(24*time.Hour - time.Nanosecond)
+		filter.EndDate = &endDate
+	}
+	if clientID, err := strconv.ParseUint(c.Query("client_id"), 10, 32); err == nil {
+		id := uint(clientID)
+		filter.ClientID = &id
+	}
+
+	report, err := h.costUseCase.Report(c.Request.Context(), filter)
+	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidCostToServePeriod) {
+			c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+			return
+		}
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
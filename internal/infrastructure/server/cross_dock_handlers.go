@@ -0,0 +1,66 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/server/middleware"
+)
+
+// CrossDockHandlers handles HTTP requests for receiving goods straight into
+// outbound deliveries
+type CrossDockHandlers struct {
+	crossDockUseCase *usecase.CrossDockUseCase
+}
+
+// NewCrossDockHandlers creates a new cross-dock handlers instance
+func NewCrossDockHandlers(crossDockUseCase *usecase.CrossDockUseCase) *CrossDockHandlers {
+	return &CrossDockHandlers{
+		crossDockUseCase: crossDockUseCase,
+	}
+}
+
+// RegisterRoutes registers cross-dock routes beside the purchase receipt
+// routes
+func (h *CrossDockHandlers) RegisterRoutes(router *gin.RouterGroup) {
+	router.POST("/purchase/receipts/cross-dock", middleware.PermissionMiddleware(entity.DeliveryOrderProcess), h.Receive)
+}
+
+// Receive handles posting a purchase receipt in cross-dock mode
+// @Summary Create a cross-dock purchase receipt
+// @Description Post a purchase receipt whose goods ship straight to the pending and preparing deliveries of the receiving store they cover in full, earliest delivery date first, without putaway. The receipt lines are linked to the deliveries in cross_docks and the deliveries are shipped, so the receipt and the shipments post their stock movements together. Capital lines, SKUs held for inspection on receipt and the quantities no delivery takes are put away as usual.
+// @Tags purchase-receipts
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param receipt body entity.PurchaseReceipt true "Purchase Receipt"
+// @Success 201 {object} entity.CrossDockResult
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse "Purchase order is not sent or confirmed"
+// @Failure 500 {object} ErrorResponse
+// @Router /purchase/receipts/cross-dock [post]
+func (h *CrossDockHandlers) Receive(c *gin.Context) {
+	userID := currentUserID(c)
+	if userID == nil {
+		c.Error(entity.NewError(entity.ErrCodeUnauthenticated, "unauthorized"))
+		return
+	}
+	var receipt entity.PurchaseReceipt
+	if err := c.ShouldBindJSON(&receipt); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+	receipt.ReceivedByID = *userID
+
+	result, err := h.crossDockUseCase.Receive(c.Request.Context(), &receipt, strconv.FormatUint(uint64(*userID), 10))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, result)
+}
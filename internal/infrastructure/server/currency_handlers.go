@@ -0,0 +1,149 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/server/middleware"
+)
+
+// CurrencyHandlers handles exchange rate HTTP requests
+type CurrencyHandlers struct {
+	currencyUseCase *usecase.CurrencyUseCase
+}
+
+// NewCurrencyHandlers creates a new currency handlers instance
+func NewCurrencyHandlers(currencyUseCase *usecase.CurrencyUseCase) *CurrencyHandlers {
+	return &CurrencyHandlers{
+		currencyUseCase: currencyUseCase,
+	}
+}
+
+// RegisterRoutes registers currency-related routes
+func (h *CurrencyHandlers) RegisterRoutes(router *gin.RouterGroup) {
+	currencyRouter := router.Group("/finance/currencies")
+	{
+		currencyRouter.GET("/rates", middleware.PermissionMiddleware(entity.FinanceCurrencyRead), h.ListRates)
+		currencyRouter.POST("/rates", middleware.PermissionMiddleware(entity.FinanceCurrencyManage), h.SetRate)
+		currencyRouter.GET("/convert", middleware.PermissionMiddleware(entity.FinanceCurrencyRead), h.Convert)
+	}
+}
+
+// SetRate handles recording a daily exchange rate
+// @Summary Set exchange rate
+// @Description Record the rate converting one unit of a currency into the base currency for a day, replacing any rate already recorded for that day
+// @Tags Finance
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param rate body entity.SetExchangeRateRequest true "Exchange rate"
+// @Success 200 {object} entity.ExchangeRate
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /finance/currencies/rates [post]
+func (h *CurrencyHandlers) SetRate(c *gin.Context) {
+	var req entity.SetExchangeRateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	rate, err := h.currencyUseCase.SetRate(c.Request.Context(), &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rate)
+}
+
+// ListRates handles listing exchange rates
+// @Summary List exchange rates
+// @Description List recorded exchange rates against the base currency, newest first
+// @Tags Finance
+// @Security BearerAuth
+// @Produce json
+// @Param currency query string false "Currency code"
+// @Param start_date query string false "Start date (YYYY-MM-DD)"
+// @Param end_date query string false "End date (YYYY-MM-DD)"
+// @Success 200 {array} entity.ExchangeRate
+// @Failure 500 {object} map[string]string
+// @Router /finance/currencies/rates [get]
+func (h *CurrencyHandlers) ListRates(c *gin.Context) {
+	filter := &entity.ExchangeRateFilter{
+		Currency: c.Query("currency"),
+	}
+
+	if startDateStr := c.Query("start_date"); startDateStr != "" {
+		if startDate, err := time.Parse("2006-01-02", startDateStr); err == nil {
+			filter.StartDate = &startDate
+		}
+	}
+	if endDateStr := c.Query("end_date"); endDateStr != "" {
+		if endDate, err := time.Parse("2006-01-02", endDateStr); err == nil {
+			filter.EndDate = &endDate
+		}
+	}
+
+	rates, err := h.currencyUseCase.ListRates(c.Request.Context(), filter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rates)
+}
+
+// Convert handles converting an amount into the base currency
+// @Summary Convert to base currency
+// @Description Convert an amount into the base currency using the latest rate on or before the date
+// @Tags Finance
+// @Security BearerAuth
+// @Produce json
+// @Param amount query number true "Amount"
+// @Param currency query string true "Currency code"
+// @Param date query string false "Rate date (YYYY-MM-DD), defaults to today"
+// @Success 200 {object} entity.CurrencyConversion
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /finance/currencies/convert [get]
+func (h *CurrencyHandlers) Convert(c *gin.Context) {
+	amount, err := strconv.ParseFloat(c.Query("amount"), 64)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid amount"))
+		return
+	}
+
+	currency := c.Query("currency")
+	if currency == "" {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Currency is required"))
+		return
+	}
+
+	date := time.Now()
+	if dateStr := c.Query("date"); dateStr != "" {
+		date, err = time.Parse("2006-01-02", dateStr)
+		if err != nil {
+			c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid date format"))
+			return
+		}
+	}
+
+	conversion, err := h.currencyUseCase.Convert(c.Request.Context(), amount, currency, date)
+	if err != nil {
+		if errors.Is(err, usecase.ErrExchangeRateNotFound) {
+			c.Error(entity.WrapError(entity.ErrCodeNotFound, err))
+			return
+		}
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, conversion)
+}
@@ -0,0 +1,196 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/server/middleware"
+)
+
+// customFieldQueryPrefix prefixes the query parameters lists are filtered by
+// custom field values with, as in ?cf.color=red
+const customFieldQueryPrefix = "cf."
+
+// CustomFieldHandlers handles custom field definition HTTP requests
+type CustomFieldHandlers struct {
+	customFieldUseCase *usecase.CustomFieldUseCase
+}
+
+// NewCustomFieldHandlers creates a new custom field handlers instance
+func NewCustomFieldHandlers(customFieldUseCase *usecase.CustomFieldUseCase) *CustomFieldHandlers {
+	return &CustomFieldHandlers{
+		customFieldUseCase: customFieldUseCase,
+	}
+}
+
+// RegisterRoutes registers custom field routes. Any authenticated user can
+// read the definitions, as they are needed to fill in the entities.
+func (h *CustomFieldHandlers) RegisterRoutes(router *gin.RouterGroup) {
+	fields := router.Group("/custom-fields")
+	{
+		fields.GET("", h.ListDefinitions)
+		fields.GET("/:id", h.GetDefinition)
+		fields.POST("", middleware.PermissionMiddleware(entity.SystemSettingsUpdate), h.CreateDefinition)
+		fields.PUT("/:id", middleware.PermissionMiddleware(entity.SystemSettingsUpdate), h.UpdateDefinition)
+		fields.DELETE("/:id", middleware.PermissionMiddleware(entity.SystemSettingsUpdate), h.DeleteDefinition)
+	}
+}
+
+// ListDefinitions handles listing custom field definitions
+// @Summary List custom fields
+// @Description List the custom fields defined on SKUs (skus), sales orders (sales_orders) and vendors (vendors), in their position order
+// @Tags custom-fields
+// @Security BearerAuth
+// @Produce json
+// @Param entity_type query string false "Entity type: skus, sales_orders or vendors"
+// @Success 200 {array} entity.CustomFieldDefinition
+// @Failure 500 {object} ErrorResponse
+// @Router /custom-fields [get]
+func (h *CustomFieldHandlers) ListDefinitions(c *gin.Context) {
+	definitions, err := h.customFieldUseCase.ListDefinitions(c.Request.Context(), c.Query("entity_type"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, definitions)
+}
+
+// GetDefinition handles getting a custom field definition
+// @Summary Get custom field
+// @Description Get a custom field definition
+// @Tags custom-fields
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Custom field ID"
+// @Success 200 {object} entity.CustomFieldDefinition
+// @Failure 404 {object} ErrorResponse
+// @Router /custom-fields/{id} [get]
+func (h *CustomFieldHandlers) GetDefinition(c *gin.Context) {
+	id, ok := parseCustomFieldID(c)
+	if !ok {
+		return
+	}
+
+	definition, err := h.customFieldUseCase.GetDefinition(c.Request.Context(), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, definition)
+}
+
+// CreateDefinition handles defining a custom field
+// @Summary Define custom field
+// @Description Define a custom field on an entity type: its key, label, type (TEXT, NUMBER, BOOLEAN, DATE or SELECT) and validation. Values are then given under custom_fields by key when the entities are saved.
+// @Tags custom-fields
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body entity.CustomFieldDefinitionRequest true "Custom field"
+// @Success 201 {object} entity.CustomFieldDefinition
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /custom-fields [post]
+func (h *CustomFieldHandlers) CreateDefinition(c *gin.Context) {
+	var req entity.CustomFieldDefinitionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	definition, err := h.customFieldUseCase.CreateDefinition(c.Request.Context(), &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, definition)
+}
+
+// UpdateDefinition handles changing a custom field
+// @Summary Update custom field
+// @Description Change the label, validation and position of a custom field. Its entity type, key and type cannot be changed.
+// @Tags custom-fields
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Custom field ID"
+// @Param request body entity.CustomFieldDefinitionRequest true "Custom field"
+// @Success 200 {object} entity.CustomFieldDefinition
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /custom-fields/{id} [put]
+func (h *CustomFieldHandlers) UpdateDefinition(c *gin.Context) {
+	id, ok := parseCustomFieldID(c)
+	if !ok {
+		return
+	}
+	var req entity.CustomFieldDefinitionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	definition, err := h.customFieldUseCase.UpdateDefinition(c.Request.Context(), id, &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, definition)
+}
+
+// DeleteDefinition handles removing a custom field
+// @Summary Delete custom field
+// @Description Remove a custom field along with the values the entities of its type hold for it
+// @Tags custom-fields
+// @Security BearerAuth
+// @Param id path int true "Custom field ID"
+// @Success 204 "No Content"
+// @Failure 404 {object} ErrorResponse
+// @Router /custom-fields/{id} [delete]
+func (h *CustomFieldHandlers) DeleteDefinition(c *gin.Context) {
+	id, ok := parseCustomFieldID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.customFieldUseCase.DeleteDefinition(c.Request.Context(), id); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func parseCustomFieldID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid custom field ID"))
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// customFieldQuery reads the custom field values a list is filtered by from
+// the cf.<key> query parameters
+func customFieldQuery(c *gin.Context) entity.JSONMap {
+	var values entity.JSONMap
+	for param, value := range c.Request.URL.Query() {
+		key, ok := strings.CutPrefix(param, customFieldQueryPrefix)
+		if !ok || key == "" || len(value) == 0 {
+			continue
+		}
+		if values == nil {
+			values = make(entity.JSONMap)
+		}
+		values[key] = value[0]
+	}
+	return values
+}
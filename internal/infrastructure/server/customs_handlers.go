@@ -0,0 +1,58 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/server/middleware"
+)
+
+// CustomsHandlers handles Intrastat and customs report HTTP requests
+type CustomsHandlers struct {
+	customsUseCase *usecase.CustomsUseCase
+}
+
+// NewCustomsHandlers creates a new customs handlers instance
+func NewCustomsHandlers(customsUseCase *usecase.CustomsUseCase) *CustomsHandlers {
+	return &CustomsHandlers{
+		customsUseCase: customsUseCase,
+	}
+}
+
+// RegisterRoutes registers customs routes
+func (h *CustomsHandlers) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("/reports/customs", middleware.PermissionMiddleware(entity.ReportRead), h.GetCustomsReport)
+}
+
+// GetCustomsReport handles the Intrastat and customs report
+// @Summary Get Intrastat and customs report
+// @Description Aggregate the month's purchase receipts from and deliveries to other countries by flow, regime, commodity code, partner country and country of origin, with quantities, net mass and base currency values. Movements with partners in the customs union are INTRASTAT, others CUSTOMS; lines missing customs data are listed as incomplete.
+// @Tags Reports
+// @Security BearerAuth
+// @Produce json
+// @Param period query string false "Month (YYYY-MM), defaults to the previous month"
+// @Param flow query string false "ARRIVAL or DISPATCH, defaults to both"
+// @Param regime query string false "INTRASTAT or CUSTOMS, defaults to both"
+// @Success 200 {object} entity.CustomsReport
+// @Failure 400 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /reports/customs [get]
+func (h *CustomsHandlers) GetCustomsReport(c *gin.Context) {
+	filter := &entity.CustomsReportFilter{
+		Period: c.Query("period"),
+		Flow:   entity.CustomsFlow(strings.ToUpper(c.Query("flow"))),
+		Regime: entity.CustomsRegime(strings.ToUpper(c.Query("regime"))),
+	}
+
+	report, err := h.customsUseCase.Report(c.Request.Context(), filter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,235 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/server/middleware"
+)
+
+// DemandForecastHandlers handles SKU demand forecast HTTP requests
+type DemandForecastHandlers struct {
+	demandUseCase *usecase.DemandForecastUseCase
+}
+
+// NewDemandForecastHandlers creates a new demand forecast handlers instance
+func NewDemandForecastHandlers(demandUseCase *usecase.DemandForecastUseCase) *DemandForecastHandlers {
+	return &DemandForecastHandlers{
+		demandUseCase: demandUseCase,
+	}
+}
+
+// RegisterRoutes registers demand forecast routes
+func (h *DemandForecastHandlers) RegisterRoutes(router *gin.RouterGroup) {
+	demandRouter := router.Group("/reports/forecast/demand")
+	{
+		demandRouter.GET("", middleware.PermissionMiddleware(entity.ReportRead), h.GetDemandForecast)
+		demandRouter.POST("/versions", middleware.PermissionMiddleware(entity.ReportCreate), h.StoreVersion)
+		demandRouter.GET("/versions", middleware.PermissionMiddleware(entity.ReportRead), h.ListVersions)
+		demandRouter.GET("/versions/:id", middleware.PermissionMiddleware(entity.ReportRead), h.GetVersion)
+		demandRouter.POST("/versions/:id/activate", middleware.PermissionMiddleware(entity.ReportCreate), h.ActivateVersion)
+		demandRouter.GET("/replenishment", middleware.PermissionMiddleware(entity.ReportRead), h.GetReplenishment)
+	}
+}
+
+// GetDemandForecast handles forecasting monthly SKU demand
+// @Summary Get demand forecast
+// @Description Forecast the monthly quantity sold of each SKU over closed months with a simple moving average, an exponentially weighted moving average or a seasonal naive model
+// @Tags Reports
+// @Security BearerAuth
+// @Produce json
+// @Param model query string false "Model (SMA/EWMA/SEASONAL_NAIVE), defaults to SMA"
+// @Param horizon query int false "Months to forecast, starting with the current month"
+// @Param window query int false "Months in the moving average (SMA)"
+// @Param alpha query number false "Smoothing factor between 0 and 1 (EWMA)"
+// @Param history_months query int false "Closed months of sales history used"
+// @Param sku_ids query string false "Comma-separated SKU IDs to forecast"
+// @Success 200 {object} entity.DemandForecastResult
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /reports/forecast/demand [get]
+func (h *DemandForecastHandlers) GetDemandForecast(c *gin.Context) {
+	result, err := h.demandUseCase.Forecast(c.Request.Context(), parseDemandForecastRequest(c))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// StoreVersion handles storing a demand forecast version
+// @Summary Store demand forecast version
+// @Description Forecast SKU demand and store it as a new version. An activated version feeds replenishment suggestions and MRP.
+// @Tags Reports
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body entity.DemandForecastVersionRequest true "Forecast parameters"
+// @Success 201 {object} entity.DemandForecastVersion
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /reports/forecast/demand/versions [post]
+func (h *DemandForecastHandlers) StoreVersion(c *gin.Context) {
+	var req entity.DemandForecastVersionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	version, err := h.demandUseCase.StoreVersion(c.Request.Context(), &req, currentUserID(c))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, version)
+}
+
+// ListVersions handles listing demand forecast versions
+// @Summary List demand forecast versions
+// @Description List stored demand forecast versions without their lines, newest first
+// @Tags Reports
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} entity.DemandForecastVersion
+// @Failure 500 {object} ErrorResponse
+// @Router /reports/forecast/demand/versions [get]
+func (h *DemandForecastHandlers) ListVersions(c *gin.Context) {
+	versions, err := h.demandUseCase.ListVersions(c.Request.Context())
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, versions)
+}
+
+// GetVersion handles getting a demand forecast version
+// @Summary Get demand forecast version
+// @Description Get a stored demand forecast version with its lines
+// @Tags Reports
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Version ID"
+// @Success 200 {object} entity.DemandForecastVersion
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /reports/forecast/demand/versions/{id} [get]
+func (h *DemandForecastHandlers) GetVersion(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid version ID"))
+		return
+	}
+
+	version, err := h.demandUseCase.GetVersion(c.Request.Context(), uint(id))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, version)
+}
+
+// ActivateVersion handles activating a demand forecast version
+// @Summary Activate demand forecast version
+// @Description Make a stored version the one replenishment suggestions and MRP use
+// @Tags Reports
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Version ID"
+// @Success 200 {object} entity.DemandForecastVersion
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /reports/forecast/demand/versions/{id}/activate [post]
+func (h *DemandForecastHandlers) ActivateVersion(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid version ID"))
+		return
+	}
+
+	version, err := h.demandUseCase.ActivateVersion(c.Request.Context(), uint(id))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, version)
+}
+
+// GetReplenishment handles suggesting replenishment orders
+// @Summary Get replenishment suggestions
+// @Description Suggest order quantities for the SKUs whose available stock does not cover the active forecast over the vendor lead time plus the review period, and the safety stock of their XYZ class
+// @Tags Reports
+// @Security BearerAuth
+// @Produce json
+// @Param store_id query string false "Store ID; all stores when empty"
+// @Param review_days query int false "Days of demand to cover after the delivery arrives; defaults to 7, 14 or 30 days by the SKU's ABC class"
+// @Success 200 {object} entity.ReplenishmentPlan
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /reports/forecast/demand/replenishment [get]
+func (h *DemandForecastHandlers) GetReplenishment(c *gin.Context) {
+	reviewDays, _ := strconv.Atoi(c.Query("review_days"))
+
+	plan, err := h.demandUseCase.Replenishment(c.Request.Context(), c.Query("store_id"), reviewDays)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, plan)
+}
+
+// handleError maps demand forecast errors to HTTP responses
+func (h *DemandForecastHandlers) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, usecase.ErrDemandForecastNotFound):
+		c.Error(entity.WrapError(entity.ErrCodeNotFound, err))
+	case errors.Is(err, usecase.ErrNoActiveDemandForecast):
+		c.Error(entity.WrapError(entity.ErrCodeConflict, err))
+	case errors.Is(err, usecase.ErrInvalidDemandModel),
+		errors.Is(err, usecase.ErrInvalidForecastAlpha):
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+	default:
+		c.Error(err)
+	}
+}
+
+// parseDemandForecastRequest reads demand forecast parameters from the query string
+func parseDemandForecastRequest(c *gin.Context) *entity.DemandForecastRequest {
+	req := &entity.DemandForecastRequest{
+		Model: entity.DemandForecastModel(strings.ToUpper(c.Query("model"))),
+	}
+
+	if horizon, err := strconv.Atoi(c.Query("horizon")); err == nil {
+		req.Horizon = horizon
+	}
+
+	if window, err := strconv.Atoi(c.Query("window")); err == nil {
+		req.Window = window
+	}
+
+	if alpha, err := strconv.ParseFloat(c.Query("alpha"), 64); err == nil {
+		req.Alpha = alpha
+	}
+
+	if historyMonths, err := strconv.Atoi(c.Query("history_months")); err == nil {
+		req.HistoryMonths = historyMonths
+	}
+
+	if skuIDs := c.Query("sku_ids"); skuIDs != "" {
+		req.SKUIDs = strings.Split(skuIDs, ",")
+	}
+
+	return req
+}
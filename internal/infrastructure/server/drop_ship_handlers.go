@@ -0,0 +1,114 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/server/middleware"
+)
+
+// DropShipHandlers handles HTTP requests for drop-shipping sales order lines
+type DropShipHandlers struct {
+	dropShipUseCase *usecase.DropShipUseCase
+}
+
+// NewDropShipHandlers creates a new drop-ship handlers instance
+func NewDropShipHandlers(dropShipUseCase *usecase.DropShipUseCase) *DropShipHandlers {
+	return &DropShipHandlers{
+		dropShipUseCase: dropShipUseCase,
+	}
+}
+
+// RegisterRoutes registers drop-ship routes
+func (h *DropShipHandlers) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("/orders/:id/drop-ship", middleware.PermissionMiddleware(entity.SalesOrderRead), h.ListPurchaseOrders)
+	router.POST("/orders/:id/drop-ship", middleware.PermissionMiddleware(entity.PurchaseOrderCreate), h.CreatePurchaseOrders)
+	router.POST("/drop-ship/:id/shipment", middleware.PermissionMiddleware(entity.DeliveryOrderProcess), h.ConfirmShipment)
+}
+
+// ListPurchaseOrders handles listing the drop-ship orders of a sales order
+// @Summary List drop-ship orders
+// @Description List the purchase orders generated for the drop-ship lines of a sales order
+// @Tags orders
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Sales Order ID"
+// @Success 200 {array} entity.PurchaseOrder
+// @Failure 500 {object} ErrorResponse
+// @Router /orders/{id}/drop-ship [get]
+func (h *DropShipHandlers) ListPurchaseOrders(c *gin.Context) {
+	orders, err := h.dropShipUseCase.ListPurchaseOrders(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, orders)
+}
+
+// CreatePurchaseOrders handles generating the drop-ship orders of a sales order
+// @Summary Generate drop-ship orders
+// @Description Generate a draft purchase order to each vendor for the drop-ship lines of a confirmed sales order that are not ordered yet, shipping to the customer's address. Confirming a sales order generates them already; this generates those that failed. Returns every drop-ship order of the sales order.
+// @Tags orders
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Sales Order ID"
+// @Success 200 {array} entity.PurchaseOrder
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse "Sales order is not confirmed"
+// @Failure 500 {object} ErrorResponse
+// @Router /orders/{id}/drop-ship [post]
+func (h *DropShipHandlers) CreatePurchaseOrders(c *gin.Context) {
+	userID := currentUserID(c)
+	if userID == nil {
+		c.Error(entity.NewError(entity.ErrCodeUnauthenticated, "unauthorized"))
+		return
+	}
+
+	orders, err := h.dropShipUseCase.CreatePurchaseOrders(c.Request.Context(), c.Param("id"), *userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, orders)
+}
+
+// ConfirmShipment handles a vendor's confirmation that it shipped a drop-ship order
+// @Summary Confirm drop-ship shipment
+// @Description Record that the vendor shipped a sent or confirmed drop-ship order to the customer. A delivered delivery order of its items is created for the sales order without moving stock, the purchase order is marked received, and the sales order is delivered once all its lines are drop-shipped and shipped.
+// @Tags orders
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Purchase Order ID"
+// @Param request body entity.DropShipShipmentRequest true "Shipment"
+// @Success 201 {object} entity.DeliveryOrder
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse "Not a drop-ship order, or not sent"
+// @Failure 500 {object} ErrorResponse
+// @Router /drop-ship/{id}/shipment [post]
+func (h *DropShipHandlers) ConfirmShipment(c *gin.Context) {
+	userID := currentUserID(c)
+	if userID == nil {
+		c.Error(entity.NewError(entity.ErrCodeUnauthenticated, "unauthorized"))
+		return
+	}
+	var req entity.DropShipShipmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	delivery, err := h.dropShipUseCase.ConfirmShipment(c.Request.Context(), c.Param("id"), &req, *userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, delivery)
+}
@@ -0,0 +1,217 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/server/middleware"
+)
+
+// DunningHandlers handles dunning HTTP requests
+type DunningHandlers struct {
+	dunningUseCase *usecase.DunningUseCase
+}
+
+// NewDunningHandlers creates a new dunning handlers instance
+func NewDunningHandlers(dunningUseCase *usecase.DunningUseCase) *DunningHandlers {
+	return &DunningHandlers{
+		dunningUseCase: dunningUseCase,
+	}
+}
+
+// RegisterRoutes registers dunning routes
+func (h *DunningHandlers) RegisterRoutes(router *gin.RouterGroup) {
+	dunningRouter := router.Group("/finance/dunning")
+	{
+		dunningRouter.POST("/levels", middleware.PermissionMiddleware(entity.FinanceDunningManage), h.CreateLevel)
+		dunningRouter.GET("/levels", middleware.PermissionMiddleware(entity.FinanceDunningRead), h.ListLevels)
+		dunningRouter.PUT("/levels/:id", middleware.PermissionMiddleware(entity.FinanceDunningManage), h.UpdateLevel)
+		dunningRouter.GET("/reminders", middleware.PermissionMiddleware(entity.FinanceDunningRead), h.ListReminders)
+		dunningRouter.POST("/run", middleware.PermissionMiddleware(entity.FinanceDunningManage), h.Run)
+	}
+}
+
+// CreateLevel handles creating a dunning level
+// @Summary Create dunning level
+// @Description Create a reminder level for sales invoices overdue by the given number of days
+// @Tags finance
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param level body entity.CreateDunningLevelRequest true "Dunning level details"
+// @Success 201 {object} entity.DunningLevel
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /finance/dunning/levels [post]
+func (h *DunningHandlers) CreateLevel(c *gin.Context) {
+	var req entity.CreateDunningLevelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	level, err := h.dunningUseCase.CreateLevel(c.Request.Context(), &req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, level)
+}
+
+// ListLevels handles listing dunning levels
+// @Summary List dunning levels
+// @Description List the dunning levels in escalation order
+// @Tags finance
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} entity.DunningLevel
+// @Failure 500 {object} ErrorResponse
+// @Router /finance/dunning/levels [get]
+func (h *DunningHandlers) ListLevels(c *gin.Context) {
+	levels, err := h.dunningUseCase.ListLevels(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, levels)
+}
+
+// UpdateLevel handles updating a dunning level
+// @Summary Update dunning level
+// @Description Update a dunning level's threshold, template or escalation, or deactivate it
+// @Tags finance
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Dunning level ID"
+// @Param level body entity.UpdateDunningLevelRequest true "Dunning level details"
+// @Success 200 {object} entity.DunningLevel
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /finance/dunning/levels/{id} [put]
+func (h *DunningHandlers) UpdateLevel(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid dunning level ID"))
+		return
+	}
+
+	var req entity.UpdateDunningLevelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	level, err := h.dunningUseCase.UpdateLevel(c.Request.Context(), uint(id), &req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, level)
+}
+
+// ListReminders handles listing the dunning reminder history
+// @Summary List dunning reminders
+// @Description List the reminders sent for overdue invoices, latest first
+// @Tags finance
+// @Security BearerAuth
+// @Produce json
+// @Param invoice_id query int false "Invoice ID"
+// @Param entity_id query int false "Client ID"
+// @Param level_id query int false "Dunning level ID"
+// @Param start_date query string false "Start date (YYYY-MM-DD)"
+// @Param end_date query string false "End date (YYYY-MM-DD)"
+// @Param page query int false "Page number"
+// @Param page_size query int false "Page size"
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} ErrorResponse
+// @Router /finance/dunning/reminders [get]
+func (h *DunningHandlers) ListReminders(c *gin.Context) {
+	filter := &entity.DunningReminderFilter{}
+
+	if invoiceID, err := strconv.ParseInt(c.Query("invoice_id"), 10, 64); err == nil {
+		filter.InvoiceID = invoiceID
+	}
+
+	if entityID, err := strconv.ParseInt(c.Query("entity_id"), 10, 64); err == nil {
+		filter.EntityID = entityID
+	}
+
+	if levelID, err := strconv.ParseUint(c.Query("level_id"), 10, 32); err == nil {
+		filter.LevelID = uint(levelID)
+	}
+
+	if startDateStr := c.Query("start_date"); startDateStr != "" {
+		if startDate, err := time.Parse("2006-01-02", startDateStr); err == nil {
+			filter.StartDate = &startDate
+		}
+	}
+
+	if endDateStr := c.Query("end_date"); endDateStr != "" {
+		if endDate, err := time.Parse("2006-01-02", endDateStr); err == nil {
+			endDate = endDate.AddDate(0, 0, 1).Add(-time.Nanosecond)
+			filter.EndDate = &endDate
+		}
+	}
+
+	if page, err := strconv.Atoi(c.Query("page")); err == nil {
+		filter.Page = page
+	}
+
+	if pageSize, err := strconv.Atoi(c.Query("page_size")); err == nil {
+		filter.PageSize = pageSize
+	}
+
+	reminders, total, err := h.dunningUseCase.ListReminders(c.Request.Context(), filter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"reminders": reminders,
+		"total":     total,
+		"page":      filter.Page,
+		"page_size": filter.PageSize,
+	})
+}
+
+// Run handles running the dunning process
+// @Summary Run dunning
+// @Description Flag overdue sales invoices and send the reminders for the dunning levels they have reached
+// @Tags finance
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} entity.DunningRunResult
+// @Failure 500 {object} ErrorResponse
+// @Router /finance/dunning/run [post]
+func (h *DunningHandlers) Run(c *gin.Context) {
+	result, err := h.dunningUseCase.Run(c.Request.Context(), time.Now(), currentUserID(c))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// handleError maps dunning errors to HTTP responses
+func (h *DunningHandlers) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, usecase.ErrDunningLevelNotFound):
+		c.Error(entity.WrapError(entity.ErrCodeNotFound, err))
+	case errors.Is(err, usecase.ErrDuplicateDunningLevel):
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+	default:
+		c.Error(err)
+	}
+}
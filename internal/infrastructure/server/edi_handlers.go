@@ -0,0 +1,386 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/server/middleware"
+)
+
+// maxInterchangeBytes caps the size of an inbound EDI interchange
+const maxInterchangeBytes = 4 << 20
+
+// EDIHandlers handles HTTP requests for EDI trading partners and the X12
+// documents exchanged with them
+type EDIHandlers struct {
+	ediUseCase *usecase.EDIUseCase
+}
+
+// NewEDIHandlers creates a new EDI handlers instance
+func NewEDIHandlers(ediUseCase *usecase.EDIUseCase) *EDIHandlers {
+	return &EDIHandlers{
+		ediUseCase: ediUseCase,
+	}
+}
+
+// RegisterRoutes registers EDI routes beside the purchase routes
+func (h *EDIHandlers) RegisterRoutes(router *gin.RouterGroup) {
+	router.POST("/purchase/orders/:id/edi", middleware.PermissionMiddleware(entity.PurchaseEDIExchange), h.GeneratePurchaseOrder)
+
+	edi := router.Group("/purchase/edi")
+	{
+		edi.POST("/partners", middleware.PermissionMiddleware(entity.PurchaseEDIManage), h.CreatePartner)
+		edi.GET("/partners", middleware.PermissionMiddleware(entity.PurchaseEDIRead), h.ListPartners)
+		edi.GET("/partners/:id", middleware.PermissionMiddleware(entity.PurchaseEDIRead), h.GetPartner)
+		edi.PUT("/partners/:id", middleware.PermissionMiddleware(entity.PurchaseEDIManage), h.UpdatePartner)
+		edi.POST("/inbound", middleware.PermissionMiddleware(entity.PurchaseEDIExchange), h.ReceiveInterchange)
+		edi.GET("/documents", middleware.PermissionMiddleware(entity.PurchaseEDIRead), h.ListDocuments)
+		edi.GET("/documents/:id", middleware.PermissionMiddleware(entity.PurchaseEDIRead), h.GetDocument)
+		edi.GET("/documents/:id/content", middleware.PermissionMiddleware(entity.PurchaseEDIRead), h.GetDocumentContent)
+		edi.POST("/documents/:id/delivered", middleware.PermissionMiddleware(entity.PurchaseEDIExchange), h.MarkDelivered)
+		edi.POST("/documents/:id/receive", middleware.PermissionMiddleware(entity.PurchaseReceiptCreate), h.ReceiveShipNotice)
+	}
+}
+
+// CreatePartner handles setting up a trading partner
+// @Summary Create trading partner
+// @Description Set up a vendor as an EDI trading partner, with the interchange (ISA) and application (GS) IDs both sides are known by
+// @Tags edi
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body entity.TradingPartnerRequest true "Trading partner"
+// @Success 201 {object} entity.TradingPartner
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /purchase/edi/partners [post]
+func (h *EDIHandlers) CreatePartner(c *gin.Context) {
+	var req entity.TradingPartnerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	partner, err := h.ediUseCase.CreatePartner(c.Request.Context(), &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, partner)
+}
+
+// ListPartners handles listing trading partners
+// @Summary List trading partners
+// @Description List the vendors set up as EDI trading partners
+// @Tags edi
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} entity.TradingPartner
+// @Failure 500 {object} ErrorResponse
+// @Router /purchase/edi/partners [get]
+func (h *EDIHandlers) ListPartners(c *gin.Context) {
+	partners, err := h.ediUseCase.ListPartners(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, partners)
+}
+
+// GetPartner handles getting a trading partner
+// @Summary Get trading partner
+// @Description Get an EDI trading partner by ID
+// @Tags edi
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Trading partner ID"
+// @Success 200 {object} entity.TradingPartner
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /purchase/edi/partners/{id} [get]
+func (h *EDIHandlers) GetPartner(c *gin.Context) {
+	id, ok := parseEDIID(c)
+	if !ok {
+		return
+	}
+
+	partner, err := h.ediUseCase.GetPartner(c.Request.Context(), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, partner)
+}
+
+// UpdatePartner handles updating a trading partner
+// @Summary Update trading partner
+// @Description Update the configuration of an EDI trading partner
+// @Tags edi
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Trading partner ID"
+// @Param request body entity.TradingPartnerRequest true "Trading partner"
+// @Success 200 {object} entity.TradingPartner
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /purchase/edi/partners/{id} [put]
+func (h *EDIHandlers) UpdatePartner(c *gin.Context) {
+	id, ok := parseEDIID(c)
+	if !ok {
+		return
+	}
+	var req entity.TradingPartnerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	partner, err := h.ediUseCase.UpdatePartner(c.Request.Context(), id, &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, partner)
+}
+
+// GeneratePurchaseOrder handles translating a purchase order to EDI
+// @Summary Generate purchase order EDI
+// @Description Translate an approved, sent or confirmed purchase order to an 850 interchange for the trading partner of its vendor, archived until it is marked delivered
+// @Tags edi
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Purchase order ID"
+// @Success 201 {object} entity.EDIDocument
+// @Failure 404 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse "Order status or vendor not set up as a trading partner"
+// @Failure 500 {object} ErrorResponse
+// @Router /purchase/orders/{id}/edi [post]
+func (h *EDIHandlers) GeneratePurchaseOrder(c *gin.Context) {
+	document, err := h.ediUseCase.GeneratePurchaseOrder(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, document)
+}
+
+// ReceiveInterchange handles an inbound EDI interchange
+// @Summary Receive EDI interchange
+// @Description Archive the transaction sets of an X12 interchange sent by a trading partner and map them: 856 ship notices confirm their purchase order and set its expected date, and 810 invoices raise pending purchase invoices. Transaction sets that cannot be mapped are archived as FAILED, and those already applied as DUPLICATE.
+// @Tags edi
+// @Security BearerAuth
+// @Accept plain
+// @Produce json
+// @Param interchange body string true "X12 interchange"
+// @Success 200 {object} entity.EDIInboundResult
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse "Sender is not an active trading partner"
+// @Failure 500 {object} ErrorResponse
+// @Router /purchase/edi/inbound [post]
+func (h *EDIHandlers) ReceiveInterchange(c *gin.Context) {
+	userID := currentUserID(c)
+	if userID == nil {
+		c.Error(entity.NewError(entity.ErrCodeUnauthenticated, "unauthorized"))
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxInterchangeBytes))
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid request body"))
+		return
+	}
+
+	result, err := h.ediUseCase.ReceiveInterchange(c.Request.Context(), body, int64(*userID))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// ListDocuments handles listing EDI documents
+// @Summary List EDI documents
+// @Description List the archived EDI documents, latest first
+// @Tags edi
+// @Security BearerAuth
+// @Produce json
+// @Param partner_id query int false "Trading partner ID"
+// @Param direction query string false "IN or OUT"
+// @Param type query string false "Transaction set, such as 850, 856 or 810"
+// @Param status query string false "Document status"
+// @Param purchase_order_id query string false "Purchase order ID"
+// @Param page query integer false "Page number"
+// @Param page_size query integer false "Page size"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /purchase/edi/documents [get]
+func (h *EDIHandlers) ListDocuments(c *gin.Context) {
+	var filter entity.EDIDocumentFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+
+	documents, total, err := h.ediUseCase.ListDocuments(c.Request.Context(), &filter, page, pageSize)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"documents": documents,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+// GetDocument handles getting an EDI document
+// @Summary Get EDI document
+// @Description Get an archived EDI document with its translation and what it was mapped to
+// @Tags edi
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "EDI document ID"
+// @Success 200 {object} entity.EDIDocument
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /purchase/edi/documents/{id} [get]
+func (h *EDIHandlers) GetDocument(c *gin.Context) {
+	id, ok := parseEDIID(c)
+	if !ok {
+		return
+	}
+
+	document, err := h.ediUseCase.GetDocument(c.Request.Context(), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, document)
+}
+
+// GetDocumentContent handles downloading the X12 of an EDI document
+// @Summary Download EDI document
+// @Description Download the X12 interchange of an archived EDI document, to deliver an outbound one to its partner
+// @Tags edi
+// @Security BearerAuth
+// @Produce plain
+// @Param id path int true "EDI document ID"
+// @Success 200 {string} string "X12 interchange"
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /purchase/edi/documents/{id}/content [get]
+func (h *EDIHandlers) GetDocumentContent(c *gin.Context) {
+	id, ok := parseEDIID(c)
+	if !ok {
+		return
+	}
+
+	document, err := h.ediUseCase.GetDocument(c.Request.Context(), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Header("Content-Disposition", "attachment; filename=\""+document.Type+"-"+document.ControlNumber+".edi\"")
+	c.Data(http.StatusOK, "application/edi-x12", []byte(document.Content))
+}
+
+// MarkDelivered handles recording the delivery of an outbound EDI document
+// @Summary Mark EDI document delivered
+// @Description Record that a generated outbound document reached its trading partner
+// @Tags edi
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "EDI document ID"
+// @Success 200 {object} entity.EDIDocument
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse "Document is not a generated outbound document"
+// @Failure 500 {object} ErrorResponse
+// @Router /purchase/edi/documents/{id}/delivered [post]
+func (h *EDIHandlers) MarkDelivered(c *gin.Context) {
+	id, ok := parseEDIID(c)
+	if !ok {
+		return
+	}
+
+	document, err := h.ediUseCase.MarkDelivered(c.Request.Context(), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, document)
+}
+
+// ReceiveShipNotice handles receiving the goods of a ship notice
+// @Summary Receive ship notice
+// @Description Post a purchase receipt of the goods an applied 856 ship notice shipped into a store, at the prices of its purchase order
+// @Tags edi
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "EDI document ID"
+// @Param request body entity.EDIReceiveRequest true "Receiving store"
+// @Success 201 {object} entity.PurchaseReceipt
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse "Not an applied ship notice, or already received"
+// @Failure 500 {object} ErrorResponse
+// @Router /purchase/edi/documents/{id}/receive [post]
+func (h *EDIHandlers) ReceiveShipNotice(c *gin.Context) {
+	id, ok := parseEDIID(c)
+	if !ok {
+		return
+	}
+	userID := currentUserID(c)
+	if userID == nil {
+		c.Error(entity.NewError(entity.ErrCodeUnauthenticated, "unauthorized"))
+		return
+	}
+	var req entity.EDIReceiveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	receipt, err := h.ediUseCase.ReceiveShipNotice(c.Request.Context(), id, &req, *userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, receipt)
+}
+
+func parseEDIID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid ID"))
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -0,0 +1,381 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/server/middleware"
+)
+
+// ElevatedAccessHandlers handles temporary elevated access HTTP requests
+type ElevatedAccessHandlers struct {
+	elevationUseCase *usecase.ElevatedAccessUseCase
+}
+
+// NewElevatedAccessHandlers creates a new elevated access handlers instance
+func NewElevatedAccessHandlers(elevationUseCase *usecase.ElevatedAccessUseCase) *ElevatedAccessHandlers {
+	return &ElevatedAccessHandlers{
+		elevationUseCase: elevationUseCase,
+	}
+}
+
+// RegisterRoutes registers elevated access routes
+func (h *ElevatedAccessHandlers) RegisterRoutes(router *gin.RouterGroup) {
+	// Grants widen what a person may do, so API keys cannot request or use
+	// them, nor admins impersonating the person
+	elevations := router.Group("/access/elevations", middleware.DenyAPIKeyMiddleware(), middleware.DenyImpersonationMiddleware())
+	{
+		elevations.POST("", h.RequestElevation)
+		elevations.GET("/mine", h.ListMyElevations)
+		elevations.GET("", middleware.PermissionMiddleware(entity.AccessElevationRead), h.ListElevations)
+		elevations.GET("/report", middleware.PermissionMiddleware(entity.AccessElevationRead), h.GetReviewReport)
+		elevations.GET("/:id", middleware.PermissionMiddleware(entity.AccessElevationRead), h.GetElevation)
+		elevations.GET("/:id/actions", middleware.PermissionMiddleware(entity.AccessElevationRead), h.ListActions)
+		elevations.POST("/:id/approve", middleware.PermissionMiddleware(entity.AccessElevationApprove), h.ApproveElevation)
+		elevations.POST("/:id/reject", middleware.PermissionMiddleware(entity.AccessElevationApprove), h.RejectElevation)
+		elevations.POST("/:id/revoke", h.RevokeElevation)
+	}
+}
+
+// RequestElevation handles requesting temporary elevated access
+// @Summary Request elevated access
+// @Description Request temporary permissions on top of the caller's role. The grant is pending until another user approves it, then expires after duration_hours.
+// @Tags access
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body entity.ElevationRequest true "Permissions, reason and duration"
+// @Success 201 {object} entity.ElevatedAccessGrant
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Router /access/elevations [post]
+func (h *ElevatedAccessHandlers) RequestElevation(c *gin.Context) {
+	userID := currentUserID(c)
+	if userID == nil {
+		c.Error(entity.NewError(entity.ErrCodeUnauthenticated, "User not found in context"))
+		return
+	}
+
+	var req entity.ElevationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	grant, err := h.elevationUseCase.RequestElevation(c.Request.Context(), *userID, &req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, grant)
+}
+
+// ListMyElevations handles listing the caller's own grants
+// @Summary List my elevated access
+// @Description List the caller's elevated access grants, latest first
+// @Tags access
+// @Security BearerAuth
+// @Produce json
+// @Param status query string false "Status (PENDING/APPROVED/REJECTED/REVOKED/EXPIRED)"
+// @Param page query int false "Page number"
+// @Param page_size query int false "Page size"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} ErrorResponse
+// @Router /access/elevations/mine [get]
+func (h *ElevatedAccessHandlers) ListMyElevations(c *gin.Context) {
+	userID := currentUserID(c)
+	if userID == nil {
+		c.Error(entity.NewError(entity.ErrCodeUnauthenticated, "User not found in context"))
+		return
+	}
+
+	filter := elevationFilter(c)
+	filter.UserID = *userID
+	h.listElevations(c, filter)
+}
+
+// ListElevations handles listing grants
+// @Summary List elevated access
+// @Description List elevated access grants, latest first, filtered by user, status and request date
+// @Tags access
+// @Security BearerAuth
+// @Produce json
+// @Param user_id query int false "User ID"
+// @Param status query string false "Status (PENDING/APPROVED/REJECTED/REVOKED/EXPIRED)"
+// @Param start_date query string false "Requested from (YYYY-MM-DD)"
+// @Param end_date query string false "Requested to (YYYY-MM-DD)"
+// @Param page query int false "Page number"
+// @Param page_size query int false "Page size"
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} ErrorResponse
+// @Router /access/elevations [get]
+func (h *ElevatedAccessHandlers) ListElevations(c *gin.Context) {
+	filter := elevationFilter(c)
+	if userID, err := strconv.ParseUint(c.Query("user_id"), 10, 32); err == nil {
+		filter.UserID = uint(userID)
+	}
+	h.listElevations(c, filter)
+}
+
+func (h *ElevatedAccessHandlers) listElevations(c *gin.Context, filter *entity.ElevationFilter) {
+	grants, total, err := h.elevationUseCase.ListElevations(c.Request.Context(), filter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"grants":    grants,
+		"total":     total,
+		"page":      filter.Page,
+		"page_size": filter.PageSize,
+	})
+}
+
+// GetElevation handles getting a grant
+// @Summary Get elevated access
+// @Description Get an elevated access grant
+// @Tags access
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Grant ID"
+// @Success 200 {object} entity.ElevatedAccessGrant
+// @Failure 404 {object} ErrorResponse
+// @Router /access/elevations/{id} [get]
+func (h *ElevatedAccessHandlers) GetElevation(c *gin.Context) {
+	id, ok := parseElevationID(c)
+	if !ok {
+		return
+	}
+
+	grant, err := h.elevationUseCase.GetElevation(c.Request.Context(), id)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, grant)
+}
+
+// ListActions handles listing the actions taken under a grant
+// @Summary List elevated actions
+// @Description List the requests made through an elevated access grant
+// @Tags access
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Grant ID"
+// @Success 200 {array} entity.ElevatedAction
+// @Failure 404 {object} ErrorResponse
+// @Router /access/elevations/{id}/actions [get]
+func (h *ElevatedAccessHandlers) ListActions(c *gin.Context) {
+	id, ok := parseElevationID(c)
+	if !ok {
+		return
+	}
+
+	actions, err := h.elevationUseCase.ListActions(c.Request.Context(), id)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, actions)
+}
+
+// ApproveElevation handles approving a grant
+// @Summary Approve elevated access
+// @Description Approve a pending grant, which is active from now for its duration. Requesters cannot approve their own grants.
+// @Tags access
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Grant ID"
+// @Param request body entity.ElevationReviewRequest false "Review note"
+// @Success 200 {object} entity.ElevatedAccessGrant
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /access/elevations/{id}/approve [post]
+func (h *ElevatedAccessHandlers) ApproveElevation(c *gin.Context) {
+	h.review(c, h.elevationUseCase.ApproveElevation)
+}
+
+// RejectElevation handles rejecting a grant
+// @Summary Reject elevated access
+// @Description Reject a pending grant
+// @Tags access
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Grant ID"
+// @Param request body entity.ElevationReviewRequest false "Review note"
+// @Success 200 {object} entity.ElevatedAccessGrant
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /access/elevations/{id}/reject [post]
+func (h *ElevatedAccessHandlers) RejectElevation(c *gin.Context) {
+	h.review(c, h.elevationUseCase.RejectElevation)
+}
+
+func (h *ElevatedAccessHandlers) review(c *gin.Context, decide func(ctx context.Context, id, reviewerID uint, note string) (*entity.ElevatedAccessGrant, error)) {
+	id, ok := parseElevationID(c)
+	if !ok {
+		return
+	}
+	reviewerID := currentUserID(c)
+	if reviewerID == nil {
+		c.Error(entity.NewError(entity.ErrCodeUnauthenticated, "User not found in context"))
+		return
+	}
+
+	var req entity.ElevationReviewRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+			return
+		}
+	}
+
+	grant, err := decide(c.Request.Context(), id, *reviewerID, req.Note)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, grant)
+}
+
+// RevokeElevation handles revoking an active grant
+// @Summary Revoke elevated access
+// @Description End an active grant early. Approvers can revoke any grant and users their own.
+// @Tags access
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Grant ID"
+// @Success 200 {object} entity.ElevatedAccessGrant
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /access/elevations/{id}/revoke [post]
+func (h *ElevatedAccessHandlers) RevokeElevation(c *gin.Context) {
+	id, ok := parseElevationID(c)
+	if !ok {
+		return
+	}
+	userID := currentUserID(c)
+	if userID == nil {
+		c.Error(entity.NewError(entity.ErrCodeUnauthenticated, "User not found in context"))
+		return
+	}
+
+	approver := middleware.HasPermission(c, entity.AccessElevationApprove)
+	grant, err := h.elevationUseCase.RevokeElevation(c.Request.Context(), id, *userID, approver)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, grant)
+}
+
+// GetReviewReport handles the elevated access review report
+// @Summary Get elevated access review report
+// @Description List every grant active in a period with the elevated actions taken under it. Defaults to the last 30 days.
+// @Tags access
+// @Security BearerAuth
+// @Produce json
+// @Param user_id query int false "User ID"
+// @Param start_date query string false "Start date (YYYY-MM-DD)"
+// @Param end_date query string false "End date (YYYY-MM-DD)"
+// @Success 200 {object} entity.ElevationReviewReport
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /access/elevations/report [get]
+func (h *ElevatedAccessHandlers) GetReviewReport(c *gin.Context) {
+	end := time.Now()
+	start := end.AddDate(0, 0, -30)
+
+	if startDateStr := c.Query("start_date"); startDateStr != "" {
+		startDate, err := time.Parse("2006-01-02", startDateStr)
+		if err != nil {
+			c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid start date format. Use YYYY-MM-DD"))
+			return
+		}
+		start = startDate
+	}
+	if endDateStr := c.Query("end_date"); endDateStr != "" {
+		endDate, err := time.Parse("2006-01-02", endDateStr)
+		if err != nil {
+			c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid end date format. Use YYYY-MM-DD"))
+			return
+		}
+		end = endDate.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+
+	var userID uint
+	if id, err := strconv.ParseUint(c.Query("user_id"), 10, 32); err == nil {
+		userID = uint(id)
+	}
+
+	report, err := h.elevationUseCase.ReviewReport(c.Request.Context(), userID, start, end)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// handleError maps elevated access errors to HTTP responses
+func (h *ElevatedAccessHandlers) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, usecase.ErrElevationNotFound):
+		c.Error(entity.WrapError(entity.ErrCodeNotFound, err))
+	case errors.Is(err, usecase.ErrElevationTooLong),
+		errors.Is(err, usecase.ErrElevationPermission),
+		errors.Is(err, usecase.ErrInvalidElevationPeriod):
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+	case errors.Is(err, usecase.ErrElevationSelfReview),
+		errors.Is(err, usecase.ErrElevationRevokeDenied):
+		c.Error(entity.WrapError(entity.ErrCodePermissionDenied, err))
+	case errors.Is(err, usecase.ErrElevationNotPending),
+		errors.Is(err, usecase.ErrElevationNotActive):
+		c.Error(entity.WrapError(entity.ErrCodeConflict, err))
+	default:
+		c.Error(err)
+	}
+}
+
+func elevationFilter(c *gin.Context) *entity.ElevationFilter {
+	filter := &entity.ElevationFilter{
+		Status: entity.ElevationStatus(c.Query("status")),
+	}
+	filter.StartDate, filter.EndDate = activityPeriod(c)
+
+	if page, err := strconv.Atoi(c.Query("page")); err == nil {
+		filter.Page = page
+	}
+
+	if pageSize, err := strconv.Atoi(c.Query("page_size")); err == nil {
+		filter.PageSize = pageSize
+	}
+
+	return filter
+}
+
+func parseElevationID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid grant ID"))
+		return 0, false
+	}
+	return uint(id), true
+}
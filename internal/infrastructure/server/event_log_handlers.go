@@ -0,0 +1,66 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/server/middleware"
+)
+
+// EventLogHandlers handles domain event log HTTP requests
+type EventLogHandlers struct {
+	eventLogUseCase *usecase.EventLogUseCase
+}
+
+// NewEventLogHandlers creates a new event log handlers instance
+func NewEventLogHandlers(eventLogUseCase *usecase.EventLogUseCase) *EventLogHandlers {
+	return &EventLogHandlers{
+		eventLogUseCase: eventLogUseCase,
+	}
+}
+
+// RegisterRoutes registers event log routes
+func (h *EventLogHandlers) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("/audit/events", middleware.PermissionMiddleware(entity.AuditLogRead), h.ListEvents)
+}
+
+// ListEvents handles listing the domain event log
+// @Summary List domain events
+// @Description List the domain events published by the use cases, latest first, with their payloads
+// @Tags audit
+// @Security BearerAuth
+// @Produce json
+// @Param name query string false "Event name, e.g. order.confirmed"
+// @Param start_date query string false "Occurred from (YYYY-MM-DD)"
+// @Param end_date query string false "Occurred to (YYYY-MM-DD)"
+// @Param page query int false "Page number"
+// @Param page_size query int false "Page size"
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} ErrorResponse
+// @Router /audit/events [get]
+func (h *EventLogHandlers) ListEvents(c *gin.Context) {
+	filter := &entity.EventLogFilter{Name: c.Query("name")}
+	filter.StartDate, filter.EndDate = activityPeriod(c)
+	if page, err := strconv.Atoi(c.Query("page")); err == nil {
+		filter.Page = page
+	}
+	if pageSize, err := strconv.Atoi(c.Query("page_size")); err == nil {
+		filter.PageSize = pageSize
+	}
+
+	events, total, err := h.eventLogUseCase.List(c.Request.Context(), filter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"events":    events,
+		"total":     total,
+		"page":      filter.Page,
+		"page_size": filter.PageSize,
+	})
+}
@@ -0,0 +1,141 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/realtime"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/server/middleware"
+)
+
+const (
+	// eventStreamKeepAlive is how often a comment is sent on an idle stream,
+	// so proxies do not close it
+	eventStreamKeepAlive = 30 * time.Second
+	// eventStreamRetry is the delay in milliseconds clients wait before
+	// reconnecting
+	eventStreamRetry = 3000
+)
+
+// EventStreamHandlers streams realtime events as Server-Sent Events, for
+// clients that cannot open a WebSocket
+type EventStreamHandlers struct {
+	stream *realtime.Stream
+}
+
+// NewEventStreamHandlers creates a new event stream handlers instance
+func NewEventStreamHandlers(stream *realtime.Stream) *EventStreamHandlers {
+	return &EventStreamHandlers{
+		stream: stream,
+	}
+}
+
+// RegisterRoutes registers event stream routes
+func (h *EventStreamHandlers) RegisterRoutes(router *gin.RouterGroup) {
+	events := router.Group("/events")
+	{
+		events.GET("/orders", middleware.PermissionMiddleware(entity.SalesOrderRead), h.StreamOrders)
+		events.GET("/stocks", middleware.PermissionMiddleware(entity.StockRead), h.StreamStocks)
+	}
+}
+
+// StreamOrders handles streaming sales order status changes
+// @Summary Stream order status changes
+// @Description Stream the order.status_changed events as Server-Sent Events. A client reconnecting with Last-Event-ID receives the events it missed; a reset event tells it some were lost.
+// @Tags events
+// @Security BearerAuth
+// @Produce text/event-stream
+// @Param Last-Event-ID header string false "ID of the last event received"
+// @Success 200 {string} string "Event stream"
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Router /events/orders [get]
+func (h *EventStreamHandlers) StreamOrders(c *gin.Context) {
+	h.serve(c, entity.TopicOrderStatusChanged)
+}
+
+// StreamStocks handles streaming low stock alerts
+// @Summary Stream low stock alerts
+// @Description Stream the stock.below_reorder events of the stores in the caller's access scope as Server-Sent Events. A client reconnecting with Last-Event-ID receives the events it missed; a reset event tells it some were lost.
+// @Tags events
+// @Security BearerAuth
+// @Produce text/event-stream
+// @Param Last-Event-ID header string false "ID of the last event received"
+// @Success 200 {string} string "Event stream"
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Router /events/stocks [get]
+func (h *EventStreamHandlers) StreamStocks(c *gin.Context) {
+	h.serve(c, entity.TopicStockBelowReorder)
+}
+
+// serve streams the events of a topic the caller may receive until the
+// client goes away or the server shuts down
+func (h *EventStreamHandlers) serve(c *gin.Context, topic entity.RealtimeTopic) {
+	lastID := c.GetHeader("Last-Event-ID")
+	if lastID == "" {
+		// For clients that cannot set headers
+		lastID = c.Query("last_event_id")
+	}
+	userID := currentUserID(c)
+	scope := entity.AccessScopeFromContext(c.Request.Context())
+
+	header := c.Writer.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	fmt.Fprintf(c.Writer, "retry: %d\n\n", eventStreamRetry)
+	c.Writer.Flush()
+
+	keepAlive := time.NewTicker(eventStreamKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		events, nextID, missed, wait := h.stream.Since(lastID)
+		if missed {
+			// Tell the client to reload what it shows, as events were lost
+			fmt.Fprintf(c.Writer, "id: %s\nevent: reset\ndata: {}\n\n", nextID)
+			events = nil
+		}
+		for _, event := range events {
+			if event.Topic != topic || !receives(c, event.RealtimeEvent, userID, scope) {
+				continue
+			}
+			data, err := json.Marshal(gin.H{"timestamp": event.Timestamp, "data": event.Data})
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(c.Writer, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Topic, data)
+		}
+		c.Writer.Flush()
+		lastID = nextID
+
+		select {
+		case <-wait:
+		case <-keepAlive.C:
+			fmt.Fprint(c.Writer, ": keepalive\n\n")
+			c.Writer.Flush()
+		case <-h.stream.Done():
+			return
+		case <-c.Request.Context().Done():
+			return
+		}
+	}
+}
+
+// receives reports whether the caller may receive an event, as WebSocket
+// clients of the gateway would
+func receives(c *gin.Context, event *entity.RealtimeEvent, userID *uint, scope entity.AccessScope) bool {
+	if event.Permission != "" && !middleware.HasPermission(c, event.Permission) {
+		return false
+	}
+	if len(event.UserIDs) > 0 && (userID == nil || !slices.Contains(event.UserIDs, *userID)) {
+		return false
+	}
+	return event.StoreID == "" || scope.AllowsStore(event.StoreID)
+}
@@ -0,0 +1,520 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/server/middleware"
+)
+
+// ExpenseHandlers handles expense claim HTTP requests
+type ExpenseHandlers struct {
+	expenseUseCase    *usecase.ExpenseUseCase
+	delegationUseCase *usecase.ApprovalDelegationUseCase
+}
+
+// NewExpenseHandlers creates a new expense handlers instance
+func NewExpenseHandlers(expenseUseCase *usecase.ExpenseUseCase, delegationUseCase *usecase.ApprovalDelegationUseCase) *ExpenseHandlers {
+	return &ExpenseHandlers{
+		expenseUseCase:    expenseUseCase,
+		delegationUseCase: delegationUseCase,
+	}
+}
+
+// RegisterRoutes registers expense claim routes. Employees holding
+// finance:expense:create see and change their own claims only; approval is
+// checked in the handlers so that delegates may stand in for approvers away.
+func (h *ExpenseHandlers) RegisterRoutes(router *gin.RouterGroup) {
+	categories := router.Group("/finance/expense-categories")
+	{
+		categories.POST("", middleware.PermissionMiddleware(entity.FinanceExpenseManage), h.CreateExpenseCategory)
+		categories.GET("", middleware.PermissionMiddleware(entity.FinanceExpenseCreate), h.ListExpenseCategories)
+		categories.PUT("/:id", middleware.PermissionMiddleware(entity.FinanceExpenseManage), h.UpdateExpenseCategory)
+	}
+
+	claims := router.Group("/finance/expense-claims")
+	{
+		claims.POST("", middleware.PermissionMiddleware(entity.FinanceExpenseCreate), h.CreateExpenseClaim)
+		claims.GET("", h.ListExpenseClaims)
+		claims.GET("/:id", h.GetExpenseClaim)
+		claims.PUT("/:id", middleware.PermissionMiddleware(entity.FinanceExpenseCreate), h.UpdateExpenseClaim)
+		claims.POST("/:id/submit", middleware.PermissionMiddleware(entity.FinanceExpenseCreate), h.SubmitExpenseClaim)
+		claims.POST("/:id/approve", middleware.ApprovalMiddleware(entity.FinanceExpenseApprove, h.delegationUseCase), h.ApproveExpenseClaim)
+		claims.POST("/:id/reject", h.RejectExpenseClaim)
+		claims.POST("/:id/reimburse", middleware.PermissionMiddleware(entity.FinanceExpenseReimburse), h.ReimburseExpenseClaim)
+	}
+
+	router.GET("/reports/financial/expenses", middleware.PermissionMiddleware(entity.FinanceExpenseRead), h.GetExpenseReport)
+}
+
+// CreateExpenseCategory handles creating an expense category
+// @Summary Create expense category
+// @Description Create a category expense claim lines are classified in, e.g. TRAVEL or MEALS
+// @Tags Finance
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body entity.ExpenseCategoryRequest true "Expense category"
+// @Success 201 {object} entity.ExpenseCategory
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /finance/expense-categories [post]
+func (h *ExpenseHandlers) CreateExpenseCategory(c *gin.Context) {
+	var req entity.ExpenseCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	category, err := h.expenseUseCase.CreateCategory(c.Request.Context(), &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, category)
+}
+
+// ListExpenseCategories handles listing expense categories
+// @Summary List expense categories
+// @Description List the active expense categories by code, or all of them with all=true
+// @Tags Finance
+// @Security BearerAuth
+// @Produce json
+// @Param all query bool false "Include inactive categories"
+// @Success 200 {array} entity.ExpenseCategory
+// @Failure 500 {object} ErrorResponse
+// @Router /finance/expense-categories [get]
+func (h *ExpenseHandlers) ListExpenseCategories(c *gin.Context) {
+	all, _ := strconv.ParseBool(c.Query("all"))
+
+	categories, err := h.expenseUseCase.ListCategories(c.Request.Context(), all)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, categories)
+}
+
+// UpdateExpenseCategory handles updating an expense category
+// @Summary Update expense category
+// @Description Change an expense category, or deactivate it with active=false. Lines already claimed keep their category.
+// @Tags Finance
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Expense category ID"
+// @Param request body entity.ExpenseCategoryRequest true "Expense category"
+// @Success 200 {object} entity.ExpenseCategory
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /finance/expense-categories/{id} [put]
+func (h *ExpenseHandlers) UpdateExpenseCategory(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid expense category ID"))
+		return
+	}
+
+	var req entity.ExpenseCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	category, err := h.expenseUseCase.UpdateCategory(c.Request.Context(), uint(id), &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, category)
+}
+
+// CreateExpenseClaim handles creating an expense claim
+// @Summary Create expense claim
+// @Description Create a draft claim for expenses the caller paid out of pocket. Each line is converted into the base currency at the rate of its expense date. Attach the receipts with owner_type expense_claim.
+// @Tags Finance
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body entity.ExpenseClaimRequest true "Expense claim"
+// @Success 201 {object} entity.ExpenseClaim
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /finance/expense-claims [post]
+func (h *ExpenseHandlers) CreateExpenseClaim(c *gin.Context) {
+	var req entity.ExpenseClaimRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	userID, ok := h.userID(c)
+	if !ok {
+		return
+	}
+
+	claim, err := h.expenseUseCase.CreateClaim(c.Request.Context(), &req, userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, claim)
+}
+
+// ListExpenseClaims handles listing expense claims
+// @Summary List expense claims
+// @Description List expense claims, latest first. Callers without finance:expense:read only see their own.
+// @Tags Finance
+// @Security BearerAuth
+// @Produce json
+// @Param employee_id query int false "Employee (user) ID"
+// @Param status query string false "Status (DRAFT/SUBMITTED/APPROVED/REJECTED/REIMBURSED)"
+// @Param start_date query string false "Claims with an expense on or after (YYYY-MM-DD)"
+// @Param end_date query string false "Claims with an expense on or before (YYYY-MM-DD)"
+// @Param page query int false "Page number"
+// @Param page_size query int false "Page size"
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /finance/expense-claims [get]
+func (h *ExpenseHandlers) ListExpenseClaims(c *gin.Context) {
+	filter := &entity.ExpenseClaimFilter{
+		Status: entity.ExpenseClaimStatus(c.Query("status")),
+	}
+
+	if middleware.HasPermission(c, entity.FinanceExpenseRead) {
+		if employeeID, err := strconv.ParseUint(c.Query("employee_id"), 10, 32); err == nil {
+			id := uint(employeeID)
+			filter.EmployeeID = &id
+		}
+	} else if middleware.HasPermission(c, entity.FinanceExpenseCreate) {
+		userID, ok := h.userID(c)
+		if !ok {
+			return
+		}
+		filter.EmployeeID = &userID
+	} else {
+		c.Error(entity.NewError(entity.ErrCodePermissionDenied, "Insufficient permissions"))
+		return
+	}
+
+	if startDate, err := time.Parse("2006-01-02", c.Query("start_date")); err == nil {
+		filter.StartDate = &startDate
+	}
+	if endDate, err := time.Parse("2006-01-02", c.Query("end_date")); err == nil {
+		filter.EndDate = &endDate
+	}
+	if page, err := strconv.Atoi(c.Query("page")); err == nil {
+		filter.Page = page
+	}
+	if pageSize, err := strconv.Atoi(c.Query("page_size")); err == nil {
+		filter.PageSize = pageSize
+	}
+
+	claims, total, err := h.expenseUseCase.ListClaims(c.Request.Context(), filter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"expense_claims": claims,
+		"total":          total,
+		"page":           filter.Page,
+		"page_size":      filter.PageSize,
+	})
+}
+
+// GetExpenseClaim handles getting an expense claim
+// @Summary Get expense claim
+// @Description Get an expense claim with its lines. Callers without finance:expense:read only find their own.
+// @Tags Finance
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Expense claim ID"
+// @Success 200 {object} entity.ExpenseClaim
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /finance/expense-claims/{id} [get]
+func (h *ExpenseHandlers) GetExpenseClaim(c *gin.Context) {
+	id, ok := parseExpenseClaimID(c)
+	if !ok {
+		return
+	}
+
+	readAll := middleware.HasPermission(c, entity.FinanceExpenseRead)
+	if !readAll && !middleware.HasPermission(c, entity.FinanceExpenseCreate) {
+		c.Error(entity.NewError(entity.ErrCodePermissionDenied, "Insufficient permissions"))
+		return
+	}
+
+	claim, err := h.expenseUseCase.GetClaim(c.Request.Context(), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if !readAll {
+		userID, ok := h.userID(c)
+		if !ok {
+			return
+		}
+		if claim.EmployeeID != userID {
+			c.Error(usecase.ErrExpenseClaimNotFound)
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, claim)
+}
+
+// UpdateExpenseClaim handles updating an expense claim
+// @Summary Update expense claim
+// @Description Replace the title, currency and lines of one of the caller's draft or rejected expense claims. A rejected claim goes back to draft.
+// @Tags Finance
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Expense claim ID"
+// @Param request body entity.ExpenseClaimRequest true "Expense claim"
+// @Success 200 {object} entity.ExpenseClaim
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /finance/expense-claims/{id} [put]
+func (h *ExpenseHandlers) UpdateExpenseClaim(c *gin.Context) {
+	id, ok := parseExpenseClaimID(c)
+	if !ok {
+		return
+	}
+
+	var req entity.ExpenseClaimRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	userID, ok := h.userID(c)
+	if !ok {
+		return
+	}
+
+	claim, err := h.expenseUseCase.UpdateClaim(c.Request.Context(), id, &req, userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, claim)
+}
+
+// SubmitExpenseClaim handles submitting an expense claim for approval
+// @Summary Submit expense claim
+// @Description Submit one of the caller's draft expense claims to the holders of finance:expense:approve, or their delegates
+// @Tags Finance
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Expense claim ID"
+// @Success 200 {object} entity.ExpenseClaim
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /finance/expense-claims/{id}/submit [post]
+func (h *ExpenseHandlers) SubmitExpenseClaim(c *gin.Context) {
+	id, ok := parseExpenseClaimID(c)
+	if !ok {
+		return
+	}
+
+	userID, ok := h.userID(c)
+	if !ok {
+		return
+	}
+
+	claim, err := h.expenseUseCase.SubmitClaim(c.Request.Context(), id, userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, claim)
+}
+
+// ApproveExpenseClaim handles approving an expense claim
+// @Summary Approve expense claim
+// @Description Approve a submitted expense claim, as an approver or the delegate of an approver away. Its total becomes owed to the employee by an approved purchase invoice issued today.
+// @Tags Finance
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Expense claim ID"
+// @Param review body map[string]interface{} false "Review notes"
+// @Success 200 {object} entity.ExpenseClaim
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /finance/expense-claims/{id}/approve [post]
+func (h *ExpenseHandlers) ApproveExpenseClaim(c *gin.Context) {
+	h.reviewExpenseClaim(c, h.expenseUseCase.ApproveClaim)
+}
+
+// RejectExpenseClaim handles rejecting an expense claim
+// @Summary Reject expense claim
+// @Description Reject a submitted expense claim, as an approver or the delegate of an approver away. The employee may correct and submit it again.
+// @Tags Finance
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Expense claim ID"
+// @Param review body map[string]interface{} false "Review notes"
+// @Success 200 {object} entity.ExpenseClaim
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /finance/expense-claims/{id}/reject [post]
+func (h *ExpenseHandlers) RejectExpenseClaim(c *gin.Context) {
+	h.reviewExpenseClaim(c, h.expenseUseCase.RejectClaim)
+}
+
+// reviewExpenseClaim approves or rejects an expense claim with review
+func (h *ExpenseHandlers) reviewExpenseClaim(c *gin.Context, review func(ctx context.Context, id uint, reviewerID uint, onBehalfOf *uint, notes string) (*entity.ExpenseClaim, error)) {
+	id, ok := parseExpenseClaimID(c)
+	if !ok {
+		return
+	}
+
+	var data struct {
+		Notes string `json:"notes"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&data); err != nil {
+			c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+			return
+		}
+	}
+
+	userID, onBehalfOf, ok := resolveApprover(c)
+	if !ok {
+		return
+	}
+
+	claim, err := review(c.Request.Context(), id, userID, onBehalfOf, data.Notes)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, claim)
+}
+
+// ReimburseExpenseClaim handles reimbursing an expense claim
+// @Summary Reimburse expense claim
+// @Description Pay an approved expense claim in full by a completed finance payment against its purchase invoice. Use payment_method CASH for reimbursements out of petty cash.
+// @Tags Finance
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Expense claim ID"
+// @Param request body entity.ReimburseExpenseClaimRequest true "Payment details"
+// @Success 200 {object} entity.ExpenseClaim
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /finance/expense-claims/{id}/reimburse [post]
+func (h *ExpenseHandlers) ReimburseExpenseClaim(c *gin.Context) {
+	id, ok := parseExpenseClaimID(c)
+	if !ok {
+		return
+	}
+
+	var req entity.ReimburseExpenseClaimRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	userID, ok := h.userID(c)
+	if !ok {
+		return
+	}
+
+	claim, err := h.expenseUseCase.ReimburseClaim(c.Request.Context(), id, &req, userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, claim)
+}
+
+// GetExpenseReport handles the expense report by category
+// @Summary Get expense report
+// @Description Total the approved and reimbursed expenses dated in a period by category, in the base currency. These are the expenses of the profit and loss report.
+// @Tags Finance
+// @Security BearerAuth
+// @Produce json
+// @Param start_date query string false "Start date (YYYY-MM-DD)"
+// @Param end_date query string false "End date (YYYY-MM-DD)"
+// @Success 200 {object} entity.ExpenseReport
+// @Failure 500 {object} ErrorResponse
+// @Router /reports/financial/expenses [get]
+func (h *ExpenseHandlers) GetExpenseReport(c *gin.Context) {
+	var startDate, endDate time.Time
+	if date, err := time.Parse("2006-01-02", c.Query("start_date")); err == nil {
+		startDate = date
+	}
+	if date, err := time.Parse("2006-01-02", c.Query("end_date")); err == nil {
+		endDate = date
+	}
+
+	report, err := h.expenseUseCase.GetExpenseReport(c.Request.Context(), startDate, endDate)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// userID returns the ID of the calling user, writing the response when the
+// user is not authenticated
+func (h *ExpenseHandlers) userID(c *gin.Context) (uint, bool) {
+	userID := currentUserID(c)
+	if userID == nil {
+		c.Error(entity.NewError(entity.ErrCodeUnauthenticated, "user not authenticated"))
+		return 0, false
+	}
+	return *userID, true
+}
+
+// parseExpenseClaimID reads the expense claim ID path parameter, responding
+// with a bad request when it is not a number
+func parseExpenseClaimID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid expense claim ID"))
+		return 0, false
+	}
+	return uint(id), true
+}
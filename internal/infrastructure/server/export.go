@@ -0,0 +1,46 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/export"
+)
+
+// parseExportFormat reads the file format of a list export from the format
+// query parameter, CSV when it is not given
+func parseExportFormat(c *gin.Context) (export.Format, bool) {
+	format := export.Format(c.DefaultQuery("format", string(export.FormatCSV)))
+	switch format {
+	case export.FormatCSV, export.FormatXLSX, export.FormatPDF, export.FormatJSON:
+		return format, true
+	}
+	c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "format must be csv, xlsx, pdf or json"))
+	return "", false
+}
+
+// writeExport sends the rows of a list export as a file download named after
+// the list and the current time
+func writeExport(c *gin.Context, format export.Format, name, title string, columns []export.Column, rows [][]interface{}) {
+	c.Header("Content-Type", format.ContentType())
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s-%s%s", name, time.Now().Format("20060102-150405"), format.Extension()))
+	c.Status(http.StatusOK)
+
+	w, err := export.NewWriter(format, c.Writer, &export.Document{Title: title, Columns: columns})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	for _, row := range rows {
+		if err := w.WriteRow(row); err != nil {
+			c.Error(err)
+			return
+		}
+	}
+	if err := w.Close(); err != nil {
+		c.Error(err)
+	}
+}
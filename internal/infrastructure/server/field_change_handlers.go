@@ -0,0 +1,58 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/server/middleware"
+)
+
+// FieldChangeHandlers handles the field change history HTTP requests of
+// critical entities
+type FieldChangeHandlers struct {
+	fieldChangeUseCase *usecase.FieldChangeUseCase
+}
+
+// NewFieldChangeHandlers creates a new field change handlers instance
+func NewFieldChangeHandlers(fieldChangeUseCase *usecase.FieldChangeUseCase) *FieldChangeHandlers {
+	return &FieldChangeHandlers{
+		fieldChangeUseCase: fieldChangeUseCase,
+	}
+}
+
+// RegisterRoutes registers the history route of every entity whose field
+// changes are recorded. Stocks answer their history of movements on
+// /stocks/:id/history, so their field changes are on /stocks/:id/changes.
+func (h *FieldChangeHandlers) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("/purchase/orders/:id/history", middleware.PermissionMiddleware(entity.PurchaseOrderRead), h.history(entity.ChangeEntityPurchaseOrder))
+	router.GET("/orders/:id/history", middleware.PermissionMiddleware(entity.SalesOrderRead), h.history(entity.ChangeEntitySalesOrder))
+	router.GET("/orders/invoices/:id/history", middleware.PermissionMiddleware(entity.InvoiceRead), h.history(entity.ChangeEntityInvoice))
+	router.GET("/finance/invoices/:id/history", middleware.PermissionMiddleware(entity.FinanceInvoiceRead), h.history(entity.ChangeEntityFinanceInvoice))
+	router.GET("/stocks/:id/changes", middleware.PermissionMiddleware(entity.StockRead), h.history(entity.ChangeEntityStock))
+}
+
+// history returns the handler answering the field changes of an entity type
+// @Summary Get field change history
+// @Description Get who changed which field of a purchase order, sales order, invoice, finance invoice or stock when, with the values before and after, latest first
+// @Tags history
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Entity ID"
+// @Param field query string false "Only the changes of this field (column name)"
+// @Success 200 {array} entity.FieldChange
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /orders/{id}/history [get]
+func (h *FieldChangeHandlers) history(entityType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		changes, err := h.fieldChangeUseCase.GetHistory(c.Request.Context(), entityType, c.Param("id"), c.Query("field"))
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		c.JSON(http.StatusOK, changes)
+	}
+}
@@ -0,0 +1,129 @@
+package server
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+)
+
+// fieldsParam names the fields a list returns of each row, as in
+// ?fields=id,order_number,status
+const fieldsParam = "fields"
+
+// jsonFieldIndexes caches the field indexes of struct types by JSON name
+var jsonFieldIndexes sync.Map // reflect.Type -> map[string][]int
+
+// fieldSet is a sparse fieldset requested for the rows of a list
+type fieldSet struct {
+	names   []string
+	indexes map[string][]int
+}
+
+// parseFieldSet reads the fields query parameter of a list of T, refusing
+// names T has no JSON field for. It returns nil when the parameter is not
+// given, for the rows to be answered whole.
+func parseFieldSet[T any](c *gin.Context) (*fieldSet, error) {
+	value, ok := c.GetQuery(fieldsParam)
+	if !ok {
+		return nil, nil
+	}
+
+	set := &fieldSet{indexes: jsonFields(reflect.TypeOf((*T)(nil)).Elem())}
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if _, ok := set.indexes[name]; !ok {
+			return nil, entity.NewError(entity.ErrCodeInvalidArgument, fmt.Sprintf("Unknown field %q in fields", name))
+		}
+		set.names = append(set.names, name)
+	}
+	if len(set.names) == 0 {
+		return nil, entity.NewError(entity.ErrCodeInvalidArgument, "fields must name at least one field")
+	}
+	return set, nil
+}
+
+// pick returns the rows, a slice of structs or struct pointers, with only
+// the fields of the set. A nil set returns the rows as they are.
+func (f *fieldSet) pick(rows interface{}) interface{} {
+	if f == nil {
+		return rows
+	}
+	v := reflect.ValueOf(rows)
+	if v.Kind() != reflect.Slice {
+		return rows
+	}
+
+	picked := make([]map[string]interface{}, v.Len())
+	for i := range picked {
+		row := reflect.Indirect(v.Index(i))
+		fields := make(map[string]interface{}, len(f.names))
+		for _, name := range f.names {
+			// Fields of embedded struct pointers that are nil are null
+			var value interface{}
+			if row.IsValid() {
+				if field, err := row.FieldByIndexErr(f.indexes[name]); err == nil {
+					value = field.Interface()
+				}
+			}
+			fields[name] = value
+		}
+		picked[i] = fields
+	}
+	return picked
+}
+
+// jsonFields returns the indexes of the fields of a struct type by the names
+// encoding/json gives them, including those promoted from embedded structs
+func jsonFields(t reflect.Type) map[string][]int {
+	if cached, ok := jsonFieldIndexes.Load(t); ok {
+		return cached.(map[string][]int)
+	}
+
+	indexes := make(map[string][]int)
+	var embedded []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" || (!field.IsExported() && !field.Anonymous) {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+		if field.Anonymous && name == "" && fieldType.Kind() == reflect.Struct {
+			embedded = append(embedded, field)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		indexes[name] = field.Index
+	}
+	// Fields of the struct itself hide the promoted ones
+	for _, field := range embedded {
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+		for name, index := range jsonFields(fieldType) {
+			if _, ok := indexes[name]; !ok {
+				indexes[name] = append(append([]int{}, field.Index...), index...)
+			}
+		}
+	}
+
+	jsonFieldIndexes.Store(t, indexes)
+	return indexes
+}
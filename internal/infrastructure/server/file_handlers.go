@@ -0,0 +1,85 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/filestore"
+)
+
+// FileHandlers serves the files of the local file store through signed URLs
+type FileHandlers struct {
+	store  filestore.Store
+	signer *filestore.Signer
+}
+
+// NewFileHandlers creates a new file handlers instance
+func NewFileHandlers(store filestore.Store, signer *filestore.Signer) *FileHandlers {
+	return &FileHandlers{
+		store:  store,
+		signer: signer,
+	}
+}
+
+// RegisterRoutes registers the download route. It needs no session, as the
+// URLs carry their own signature.
+func (h *FileHandlers) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("/files/*key", h.DownloadFile)
+}
+
+// DownloadFile handles downloading a stored file
+// @Summary Download file
+// @Description Download a stored file, such as a report export, through the signed URL handed out for it. The URL stops working once it expires.
+// @Tags files
+// @Produce octet-stream
+// @Param key path string true "File key"
+// @Param expires query int true "Expiry as a Unix time"
+// @Param signature query string true "URL signature"
+// @Success 200 {file} file
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 410 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /files/{key} [get]
+func (h *FileHandlers) DownloadFile(c *gin.Context) {
+	key, err := filestore.CleanKey(strings.TrimPrefix(c.Param("key"), "/"))
+	if err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeNotFound, err))
+		return
+	}
+	if err := h.signer.Verify(key, c.Query("expires"), c.Query("signature"), time.Now()); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	object, err := h.store.Open(c.Request.Context(), key)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	defer object.Body.Close()
+
+	c.DataFromReader(http.StatusOK, object.Size, object.ContentType, object.Body, map[string]string{
+		"Content-Disposition": fmt.Sprintf("attachment; filename=%q", path.Base(key)),
+		"Cache-Control":       "private, no-store",
+	})
+}
+
+func (h *FileHandlers) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, filestore.ErrInvalidSignature):
+		c.Error(entity.WrapError(entity.ErrCodePermissionDenied, err))
+	case errors.Is(err, filestore.ErrURLExpired):
+		c.Error(entity.WrapError(entity.ErrCodeGone, err))
+	case errors.Is(err, filestore.ErrNotFound), errors.Is(err, filestore.ErrInvalidKey):
+		c.Error(entity.WrapError(entity.ErrCodeNotFound, err))
+	default:
+		c.Error(err)
+	}
+}
@@ -67,7 +67,7 @@ func (h *FinanceHandlers) RegisterRoutes(router *gin.RouterGroup) {
 func (h *FinanceHandlers) CreateInvoice(c *gin.Context) {
 	var req entity.CreateFinanceInvoiceRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 
@@ -75,7 +75,7 @@ func (h *FinanceHandlers) CreateInvoice(c *gin.Context) {
 	userID, _ := strconv.ParseInt(userIDStr, 10, 64)
 	invoice, err := h.financeUseCase.CreateInvoice(c.Request.Context(), &req, userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -96,13 +96,13 @@ func (h *FinanceHandlers) CreateInvoice(c *gin.Context) {
 func (h *FinanceHandlers) GetInvoice(c *gin.Context) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invoice ID"})
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid invoice ID"))
 		return
 	}
 
 	invoice, err := h.financeUseCase.GetInvoiceByID(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeNotFound, err))
 		return
 	}
 
@@ -126,19 +126,19 @@ func (h *FinanceHandlers) GetInvoice(c *gin.Context) {
 func (h *FinanceHandlers) UpdateInvoice(c *gin.Context) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invoice ID"})
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid invoice ID"))
 		return
 	}
 
 	var req entity.UpdateFinanceInvoiceRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 
 	invoice, err := h.financeUseCase.UpdateInvoice(c.Request.Context(), id, &req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -162,7 +162,7 @@ func (h *FinanceHandlers) UpdateInvoice(c *gin.Context) {
 func (h *FinanceHandlers) UpdateInvoiceStatus(c *gin.Context) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invoice ID"})
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid invoice ID"))
 		return
 	}
 
@@ -170,13 +170,13 @@ func (h *FinanceHandlers) UpdateInvoiceStatus(c *gin.Context) {
 		Status string `json:"status" binding:"required"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 
 	status := entity.FinanceInvoiceStatus(req.Status)
 	if err := h.financeUseCase.UpdateInvoiceStatus(c.Request.Context(), id, status); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -198,12 +198,12 @@ func (h *FinanceHandlers) UpdateInvoiceStatus(c *gin.Context) {
 func (h *FinanceHandlers) CancelInvoice(c *gin.Context) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invoice ID"})
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid invoice ID"))
 		return
 	}
 
 	if err := h.financeUseCase.CancelInvoice(c.Request.Context(), id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -225,6 +225,10 @@ func (h *FinanceHandlers) CancelInvoice(c *gin.Context) {
 // @Param end_date query string false "End date (YYYY-MM-DD)"
 // @Param page query int false "Page number"
 // @Param page_size query int false "Page size"
+// @Param cursor query string false "Return the invoices after this next_cursor of a previous page, instead of paging by number"
+// @Param limit query int false "Invoices per page when paging by cursor (default 50, at most 500)"
+// @Param archived query bool false "List archived invoices instead of live ones"
+// @Param fields query string false "Only return these fields of each invoice, e.g. id,invoice_number,status"
 // @Success 200 {object} entity.FinanceInvoiceListResponse
 // @Failure 400 {object} entity.FinanceInvoiceListResponse
 // @Failure 500 {object} entity.FinanceInvoiceListResponse
@@ -249,6 +253,10 @@ func (h *FinanceHandlers) ListInvoices(c *gin.Context) {
 		filter.PageSize = pageSize
 	}
 
+	if archived, err := strconv.ParseBool(c.Query("archived")); err == nil {
+		filter.Archived = archived
+	}
+
 	if startDateStr := c.Query("start_date"); startDateStr != "" {
 		if startDate, err := time.Parse("2006-01-02", startDateStr); err == nil {
 			filter.StartDate = &startDate
@@ -261,14 +269,35 @@ func (h *FinanceHandlers) ListInvoices(c *gin.Context) {
 		}
 	}
 
+	fields, err := parseFieldSet[entity.FinanceInvoice](c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	page, err := parseCursorPage(c)
+	if err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+	if page != nil {
+		invoices, next, err := h.financeUseCase.ListInvoicesPage(c.Request.Context(), filter, *page)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		c.JSON(http.StatusOK, cursorPageResponse("invoices", fields.pick(invoices), page, next))
+		return
+	}
+
 	invoices, total, err := h.financeUseCase.ListInvoices(c.Request.Context(), filter)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"invoices":  invoices,
+		"invoices":  fields.pick(invoices),
 		"total":     total,
 		"page":      filter.Page,
 		"page_size": filter.PageSize,
@@ -290,7 +319,7 @@ func (h *FinanceHandlers) ListInvoices(c *gin.Context) {
 func (h *FinanceHandlers) CreatePayment(c *gin.Context) {
 	var req entity.CreateFinancePaymentRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 
@@ -298,7 +327,7 @@ func (h *FinanceHandlers) CreatePayment(c *gin.Context) {
 	userID, _ := strconv.ParseInt(userIDStr, 10, 64)
 	payment, err := h.financeUseCase.CreatePayment(c.Request.Context(), &req, userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -319,13 +348,13 @@ func (h *FinanceHandlers) CreatePayment(c *gin.Context) {
 func (h *FinanceHandlers) GetPayment(c *gin.Context) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payment ID"})
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid payment ID"))
 		return
 	}
 
 	payment, err := h.financeUseCase.GetPaymentByID(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeNotFound, err))
 		return
 	}
 
@@ -349,19 +378,19 @@ func (h *FinanceHandlers) GetPayment(c *gin.Context) {
 func (h *FinanceHandlers) UpdatePayment(c *gin.Context) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payment ID"})
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid payment ID"))
 		return
 	}
 
 	var req entity.UpdateFinancePaymentRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 
 	payment, err := h.financeUseCase.UpdatePayment(c.Request.Context(), id, &req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -383,12 +412,12 @@ func (h *FinanceHandlers) UpdatePayment(c *gin.Context) {
 func (h *FinanceHandlers) ConfirmPayment(c *gin.Context) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payment ID"})
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid payment ID"))
 		return
 	}
 
 	if err := h.financeUseCase.ConfirmPayment(c.Request.Context(), id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -410,12 +439,12 @@ func (h *FinanceHandlers) ConfirmPayment(c *gin.Context) {
 func (h *FinanceHandlers) CancelPayment(c *gin.Context) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payment ID"})
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid payment ID"))
 		return
 	}
 
 	if err := h.financeUseCase.CancelPayment(c.Request.Context(), id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -437,12 +466,12 @@ func (h *FinanceHandlers) CancelPayment(c *gin.Context) {
 func (h *FinanceHandlers) RefundPayment(c *gin.Context) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payment ID"})
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid payment ID"))
 		return
 	}
 
 	if err := h.financeUseCase.RefundPayment(c.Request.Context(), id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -509,7 +538,7 @@ func (h *FinanceHandlers) ListPayments(c *gin.Context) {
 
 	payments, total, err := h.financeUseCase.ListPayments(c.Request.Context(), filter)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -549,7 +578,7 @@ func (h *FinanceHandlers) GetAccountsReceivable(c *gin.Context) {
 
 	receivables, err := h.financeUseCase.GetAccountsReceivable(c.Request.Context(), startDate, endDate)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -584,7 +613,7 @@ func (h *FinanceHandlers) GetAccountsPayable(c *gin.Context) {
 
 	payables, err := h.financeUseCase.GetAccountsPayable(c.Request.Context(), startDate, endDate)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -608,25 +637,25 @@ func (h *FinanceHandlers) GetFinanceReport(c *gin.Context) {
 	endDateStr := c.Query("end_date")
 
 	if startDateStr == "" || endDateStr == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Start date and end date are required"})
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Start date and end date are required"))
 		return
 	}
 
 	startDate, err := time.Parse("2006-01-02", startDateStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start date format"})
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid start date format"))
 		return
 	}
 
 	endDate, err := time.Parse("2006-01-02", endDateStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid end date format"})
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid end date format"))
 		return
 	}
 
 	report, err := h.financeUseCase.GetFinanceReport(c.Request.Context(), startDate, endDate)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -0,0 +1,178 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/auth"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/server/middleware"
+)
+
+// FiscalHandlers handles fiscal calendar HTTP requests
+type FiscalHandlers struct {
+	fiscalUseCase *usecase.FiscalUseCase
+}
+
+// NewFiscalHandlers creates a new fiscal handlers instance
+func NewFiscalHandlers(fiscalUseCase *usecase.FiscalUseCase) *FiscalHandlers {
+	return &FiscalHandlers{
+		fiscalUseCase: fiscalUseCase,
+	}
+}
+
+// RegisterRoutes registers fiscal calendar routes
+func (h *FiscalHandlers) RegisterRoutes(router *gin.RouterGroup) {
+	years := router.Group("/finance/fiscal-years")
+	{
+		years.POST("", middleware.PermissionMiddleware(entity.FinancePeriodManage), h.CreateFiscalYear)
+		years.GET("", middleware.PermissionMiddleware(entity.FinancePeriodRead), h.ListFiscalYears)
+		years.GET("/:id", middleware.PermissionMiddleware(entity.FinancePeriodRead), h.GetFiscalYear)
+	}
+
+	periods := router.Group("/finance/fiscal-periods")
+	{
+		periods.POST("/:id/close", middleware.PermissionMiddleware(entity.FinancePeriodManage), h.CloseFiscalPeriod)
+		periods.POST("/:id/reopen", middleware.PermissionMiddleware(entity.FinancePeriodManage), h.ReopenFiscalPeriod)
+	}
+}
+
+// CreateFiscalYear handles setting up a fiscal year
+// @Summary Create fiscal year
+// @Description Set up a fiscal year of twelve months from its start date, divided into open monthly (default) or quarterly periods. Fiscal years do not overlap.
+// @Tags finance
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body entity.CreateFiscalYearRequest true "Fiscal year"
+// @Success 201 {object} entity.FiscalYear
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /finance/fiscal-years [post]
+func (h *FiscalHandlers) CreateFiscalYear(c *gin.Context) {
+	var req entity.CreateFiscalYearRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	userID, _ := strconv.ParseUint(auth.GetUserIDFromContext(c), 10, 32)
+
+	year, err := h.fiscalUseCase.CreateYear(c.Request.Context(), &req, uint(userID))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, year)
+}
+
+// ListFiscalYears handles listing the fiscal years
+// @Summary List fiscal years
+// @Description List the fiscal years with their periods, the latest first
+// @Tags finance
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} entity.FiscalYear
+// @Failure 500 {object} ErrorResponse
+// @Router /finance/fiscal-years [get]
+func (h *FiscalHandlers) ListFiscalYears(c *gin.Context) {
+	years, err := h.fiscalUseCase.ListYears(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, years)
+}
+
+// GetFiscalYear handles getting a fiscal year
+// @Summary Get fiscal year
+// @Description Get a fiscal year with its periods
+// @Tags finance
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Fiscal year ID"
+// @Success 200 {object} entity.FiscalYear
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /finance/fiscal-years/{id} [get]
+func (h *FiscalHandlers) GetFiscalYear(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid fiscal year ID"))
+		return
+	}
+
+	year, err := h.fiscalUseCase.GetYear(c.Request.Context(), uint(id))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, year)
+}
+
+// CloseFiscalPeriod handles closing a fiscal period
+// @Summary Close fiscal period
+// @Description Close a fiscal period once its books are final. Invoices, payments and stock adjustments dated in it are refused afterwards, unless the user holds finance:period:override.
+// @Tags finance
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Fiscal period ID"
+// @Success 200 {object} entity.FiscalPeriod
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /finance/fiscal-periods/{id}/close [post]
+func (h *FiscalHandlers) CloseFiscalPeriod(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid fiscal period ID"))
+		return
+	}
+
+	userID, _ := strconv.ParseUint(auth.GetUserIDFromContext(c), 10, 32)
+
+	period, err := h.fiscalUseCase.ClosePeriod(c.Request.Context(), uint(id), uint(userID))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, period)
+}
+
+// ReopenFiscalPeriod handles reopening a fiscal period
+// @Summary Reopen fiscal period
+// @Description Reopen a closed fiscal period for postings
+// @Tags finance
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Fiscal period ID"
+// @Success 200 {object} entity.FiscalPeriod
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /finance/fiscal-periods/{id}/reopen [post]
+func (h *FiscalHandlers) ReopenFiscalPeriod(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid fiscal period ID"))
+		return
+	}
+
+	period, err := h.fiscalUseCase.ReopenPeriod(c.Request.Context(), uint(id))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, period)
+}
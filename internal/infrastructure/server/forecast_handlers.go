@@ -0,0 +1,140 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/server/middleware"
+)
+
+// ForecastHandlers handles sales forecast HTTP requests
+type ForecastHandlers struct {
+	forecastUseCase *usecase.ForecastUseCase
+}
+
+// NewForecastHandlers creates a new forecast handlers instance
+func NewForecastHandlers(forecastUseCase *usecase.ForecastUseCase) *ForecastHandlers {
+	return &ForecastHandlers{
+		forecastUseCase: forecastUseCase,
+	}
+}
+
+// RegisterRoutes registers sales forecast routes
+func (h *ForecastHandlers) RegisterRoutes(router *gin.RouterGroup) {
+	forecastRouter := router.Group("/reports/forecasts/sales")
+	{
+		forecastRouter.GET("", middleware.PermissionMiddleware(entity.ReportRead), h.GetSalesForecast)
+		forecastRouter.POST("/snapshot", middleware.PermissionMiddleware(entity.ReportCreate), h.SnapshotSalesForecast)
+		forecastRouter.GET("/accuracy", middleware.PermissionMiddleware(entity.ReportRead), h.GetForecastAccuracy)
+	}
+}
+
+// GetSalesForecast handles forecasting monthly revenue
+// @Summary Get sales forecast
+// @Description Forecast monthly revenue per customer or SKU category with a seasonal moving average over closed months
+// @Tags Reports
+// @Security BearerAuth
+// @Produce json
+// @Param dimension query string false "Dimension (CUSTOMER/CATEGORY), defaults to CATEGORY"
+// @Param horizon query int false "Months to forecast, starting with the current month"
+// @Param window query int false "Months in the moving average"
+// @Param history_months query int false "Closed months of sales history used"
+// @Success 200 {object} entity.SalesForecastResult
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /reports/forecasts/sales [get]
+func (h *ForecastHandlers) GetSalesForecast(c *gin.Context) {
+	result, err := h.forecastUseCase.Forecast(c.Request.Context(), parseForecastRequest(c))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// SnapshotSalesForecast handles storing the current month's sales forecast
+// @Summary Snapshot sales forecast
+// @Description Forecast monthly revenue and store it as this month's forecast of record for accuracy tracking and planning
+// @Tags Reports
+// @Security BearerAuth
+// @Produce json
+// @Param dimension query string false "Dimension (CUSTOMER/CATEGORY), defaults to CATEGORY"
+// @Param horizon query int false "Months to forecast, starting with the current month"
+// @Param window query int false "Months in the moving average"
+// @Param history_months query int false "Closed months of sales history used"
+// @Success 201 {object} entity.SalesForecastResult
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /reports/forecasts/sales/snapshot [post]
+func (h *ForecastHandlers) SnapshotSalesForecast(c *gin.Context) {
+	result, err := h.forecastUseCase.Snapshot(c.Request.Context(), parseForecastRequest(c))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, result)
+}
+
+// GetForecastAccuracy handles comparing stored forecasts with actual sales
+// @Summary Get forecast accuracy
+// @Description Compare stored sales forecasts with actual revenue for closed months, with MAPE, WAPE and bias
+// @Tags Reports
+// @Security BearerAuth
+// @Produce json
+// @Param dimension query string false "Dimension (CUSTOMER/CATEGORY), defaults to CATEGORY"
+// @Param from query string false "First period (YYYY-MM)"
+// @Param to query string false "Last period (YYYY-MM), defaults to the previous month"
+// @Success 200 {object} entity.ForecastAccuracy
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /reports/forecasts/sales/accuracy [get]
+func (h *ForecastHandlers) GetForecastAccuracy(c *gin.Context) {
+	dimension := entity.ForecastDimension(c.DefaultQuery("dimension", string(entity.ForecastDimensionCategory)))
+
+	accuracy, err := h.forecastUseCase.Accuracy(c.Request.Context(), dimension, c.Query("from"), c.Query("to"))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, accuracy)
+}
+
+// handleError maps sales forecast errors to HTTP responses
+func (h *ForecastHandlers) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, usecase.ErrInvalidForecastDimension),
+		errors.Is(err, usecase.ErrInvalidPeriod),
+		errors.Is(err, usecase.ErrForecastPeriodNotClosed):
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+	default:
+		c.Error(err)
+	}
+}
+
+// parseForecastRequest reads sales forecast parameters from the query string
+func parseForecastRequest(c *gin.Context) *entity.SalesForecastRequest {
+	req := &entity.SalesForecastRequest{
+		Dimension: entity.ForecastDimension(c.Query("dimension")),
+	}
+
+	if horizon, err := strconv.Atoi(c.Query("horizon")); err == nil {
+		req.Horizon = horizon
+	}
+
+	if window, err := strconv.Atoi(c.Query("window")); err == nil {
+		req.Window = window
+	}
+
+	if historyMonths, err := strconv.Atoi(c.Query("history_months")); err == nil {
+		req.HistoryMonths = historyMonths
+	}
+
+	return req
+}
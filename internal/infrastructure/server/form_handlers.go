@@ -0,0 +1,415 @@
+package server
+
+import (
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/server/middleware"
+)
+
+// formDefinition names the request type behind an entity's form and the
+// endpoint it is submitted to
+type formDefinition struct {
+	title      string
+	help       string
+	method     string
+	path       string
+	permission entity.Permission
+	request    interface{}
+	omit       []string // server-assigned fields of entities bound directly
+	customType string   // entity type whose custom fields the custom_fields object holds
+}
+
+// formDefinitions are the forms served by /forms, by entity name
+var formDefinitions = map[string]formDefinition{
+	"role": {
+		title: "Role", method: http.MethodPost, path: "/api/v1/roles",
+		permission: entity.RoleCreate, request: CreateRoleRequest{},
+	},
+	"user": {
+		title: "User", method: http.MethodPut, path: "/api/v1/users/:id",
+		permission: entity.UserUpdate, request: UpdateUserRequest{},
+	},
+	"store": {
+		title: "Store", method: http.MethodPost, path: "/api/v1/stores",
+		permission: entity.StoreCreate, request: entity.Store{},
+	},
+	"stock-entry": {
+		title: "Stock entry", help: "Book goods into (IN) or out of (OUT) a store",
+		method: http.MethodPost, path: "/api/v1/stocks/stock-entries",
+		permission: entity.StockEntryCreate, request: entity.StockEntry{}, omit: []string{"created_by"},
+	},
+	"vendor": {
+		title: "Vendor", method: http.MethodPost, path: "/api/v1/vendors",
+		permission: entity.VendorCreate, request: entity.Vendor{}, customType: entity.CustomFieldEntityVendor,
+	},
+	"sku": {
+		title: "SKU", method: http.MethodPost, path: "/api/v1/skus",
+		permission: entity.ProductCreate, request: entity.SKU{}, customType: entity.CustomFieldEntitySKU,
+	},
+	"sku-category": {
+		title: "SKU category", method: http.MethodPost, path: "/api/v1/sku-categories",
+		permission: entity.ProductCreate, request: entity.SKUCategory{},
+	},
+	"client": {
+		title: "Client", method: http.MethodPost, path: "/api/v1/clients",
+		permission: entity.ClientCreate, request: entity.Client{},
+	},
+	"purchase-request": {
+		title: "Purchase request", help: "Saved as a draft and submitted for approval separately",
+		method: http.MethodPost, path: "/api/v1/purchases/requests",
+		permission: entity.PurchaseRequestCreate, request: entity.PurchaseRequest{},
+		omit: []string{"request_number", "requester_id", "request_date", "status", "approver_id", "approval_date", "approval_notes"},
+	},
+	"purchase-order": {
+		title: "Purchase order", help: "Saved as a draft and submitted for approval separately",
+		method: http.MethodPost, path: "/api/v1/purchases/orders",
+		permission: entity.PurchaseOrderCreate, request: entity.PurchaseOrder{},
+		omit: []string{"order_number", "status", "payment_status", "exchange_rate", "base_grand_total", "created_by_id", "approved_by_id", "approval_date"},
+	},
+	"sales-order": {
+		title: "Sales order", help: "Stock is checked in the store when the order is created",
+		method: http.MethodPost, path: "/api/v1/orders",
+		permission: entity.SalesOrderCreate, request: CreateSalesOrderRequest{}, customType: entity.CustomFieldEntitySalesOrder,
+	},
+	"delivery-order": {
+		title: "Delivery order", method: http.MethodPost, path: "/api/v1/orders/:id/deliveries",
+		permission: entity.DeliveryOrderCreate, request: CreateDeliveryOrderRequest{},
+	},
+	"sales-return": {
+		title: "Sales return", help: "The reason is a quality defect code",
+		method: http.MethodPost, path: "/api/v1/orders/:id/returns",
+		permission: entity.SalesOrderUpdate, request: entity.SalesReturnRequest{},
+	},
+	"finance-invoice": {
+		title: "Finance invoice", method: http.MethodPost, path: "/api/v1/finance/invoices",
+		permission: entity.FinanceInvoiceCreate, request: entity.CreateFinanceInvoiceRequest{},
+	},
+	"finance-payment": {
+		title: "Finance payment", method: http.MethodPost, path: "/api/v1/finance/payments",
+		permission: entity.FinancePaymentCreate, request: entity.CreateFinancePaymentRequest{},
+	},
+	"work-center": {
+		title: "Work center", method: http.MethodPost, path: "/api/v1/manufacturing/work-centers",
+		permission: entity.ManufacturingFacilityCreate, request: entity.WorkCenterRequest{},
+	},
+}
+
+// formEnums are the values of the named types whose options validation tags
+// do not list
+var formEnums = map[reflect.Type][]string{
+	reflect.TypeOf(entity.PaymentMethod("")): enumValues(entity.PaymentMethodCash, entity.PaymentMethodCreditCard,
+		entity.PaymentMethodBankTransfer, entity.PaymentMethodDigitalWallet),
+	reflect.TypeOf(entity.FinancePaymentMethod("")): enumValues(entity.FinancePaymentMethodCash, entity.FinancePaymentMethodBankTransfer,
+		entity.FinancePaymentMethodCreditCard, entity.FinancePaymentMethodCheck, entity.FinancePaymentMethodDigitalWallet, entity.FinancePaymentMethodOther),
+	reflect.TypeOf(entity.StoreType("")):         enumValues(entity.StoreTypeRaw, entity.StoreTypeFinished, entity.StoreTypeGeneral),
+	reflect.TypeOf(entity.StoreStatus("")):       enumValues(entity.StoreStatusActive, entity.StoreStatusInactive),
+	reflect.TypeOf(entity.SKUStatus("")):         enumValues(entity.SKUStatusActive, entity.SKUStatusInactive, entity.SKUStatusArchived),
+	reflect.TypeOf(entity.UserStatus("")):        enumValues(entity.StatusActive, entity.StatusInactive, entity.StatusLocked),
+	reflect.TypeOf(entity.ClientLoyaltyTier("")): enumValues(entity.ClientLoyaltyTierStandard, entity.ClientLoyaltyTierSilver, entity.ClientLoyaltyTierGold, entity.ClientLoyaltyTierPlatinum),
+}
+
+// FormHandlers serves the form schemas user interfaces generate their forms from
+type FormHandlers struct {
+	customFieldUseCase *usecase.CustomFieldUseCase
+}
+
+// NewFormHandlers creates a new form handlers instance
+func NewFormHandlers(customFieldUseCase *usecase.CustomFieldUseCase) *FormHandlers {
+	return &FormHandlers{
+		customFieldUseCase: customFieldUseCase,
+	}
+}
+
+// RegisterRoutes registers form schema routes. Any authenticated user can read
+// them; each schema tells whether the user may submit it.
+func (h *FormHandlers) RegisterRoutes(router *gin.RouterGroup) {
+	forms := router.Group("/forms")
+	{
+		forms.GET("", h.ListForms)
+		forms.GET("/:entity", h.GetForm)
+	}
+}
+
+// ListForms handles listing the form schemas
+// @Summary List form schemas
+// @Description List the schema of every entity form: fields, required flags, enum options and limits taken from the API's validation, and the permission needed to submit it
+// @Tags forms
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} entity.FormSchema
+// @Router /forms [get]
+func (h *FormHandlers) ListForms(c *gin.Context) {
+	names := make([]string, 0, len(formDefinitions))
+	for name := range formDefinitions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	schemas := make([]entity.FormSchema, 0, len(names))
+	for _, name := range names {
+		schema, err := h.buildFormSchema(c, name, formDefinitions[name])
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		schemas = append(schemas, schema)
+	}
+	c.JSON(http.StatusOK, schemas)
+}
+
+// GetForm handles getting the form schema of an entity
+// @Summary Get form schema
+// @Description Get the fields, required flags, enum options and limits of an entity form, taken from the API's validation, and the permission needed to submit it
+// @Tags forms
+// @Security BearerAuth
+// @Produce json
+// @Param entity path string true "Entity name, such as sales-order or sku"
+// @Success 200 {object} entity.FormSchema
+// @Failure 404 {object} ErrorResponse
+// @Router /forms/{entity} [get]
+func (h *FormHandlers) GetForm(c *gin.Context) {
+	name := c.Param("entity")
+	definition, ok := formDefinitions[name]
+	if !ok {
+		c.Error(entity.NewError(entity.ErrCodeNotFound, "form not found"))
+		return
+	}
+	schema, err := h.buildFormSchema(c, name, definition)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, schema)
+}
+
+// buildFormSchema generates the schema of a form for the current user. The
+// custom_fields object of entities with custom fields lists the fields
+// defined on them.
+func (h *FormHandlers) buildFormSchema(c *gin.Context, name string, definition formDefinition) (entity.FormSchema, error) {
+	omit := make(map[string]bool, len(definition.omit))
+	for _, field := range definition.omit {
+		omit[field] = true
+	}
+	fields := formFields(reflect.TypeOf(definition.request), omit, map[reflect.Type]bool{})
+	if definition.customType != "" {
+		customFields, err := h.customFieldUseCase.ListDefinitions(c.Request.Context(), definition.customType)
+		if err != nil {
+			return entity.FormSchema{}, err
+		}
+		for i := range fields {
+			if fields[i].Name == "custom_fields" {
+				fields[i].Fields = customFormFields(customFields)
+			}
+		}
+	}
+	return entity.FormSchema{
+		Entity:     name,
+		Title:      definition.title,
+		Help:       definition.help,
+		Method:     definition.method,
+		Path:       definition.path,
+		Permission: definition.permission,
+		Permitted:  definition.permission == "" || middleware.HasPermission(c, definition.permission),
+		Fields:     fields,
+	}, nil
+}
+
+// customFormFields describes the custom fields defined on an entity type
+func customFormFields(definitions []entity.CustomFieldDefinition) []entity.FormField {
+	fields := make([]entity.FormField, 0, len(definitions))
+	for _, definition := range definitions {
+		field := entity.FormField{
+			Name:     definition.Key,
+			Type:     "string",
+			Required: definition.Required,
+			Help:     definition.Label,
+		}
+		switch definition.Type {
+		case entity.CustomFieldNumber:
+			field.Type = "number"
+			field.Minimum, field.Maximum = definition.Min, definition.Max
+		case entity.CustomFieldBoolean:
+			field.Type = "boolean"
+		case entity.CustomFieldDate:
+			field.Format = "date"
+		case entity.CustomFieldSelect:
+			field.Enum = definition.Options
+		default:
+			if definition.MaxLength > 0 {
+				maxLength := definition.MaxLength
+				field.MaxLength = &maxLength
+			}
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// formFields describes the JSON fields of a struct type. Primary keys,
+// timestamps the database sets and related records are left out, as are the
+// omitted top-level fields. Types already being described further up are not
+// expanded again, so self-referencing types end.
+func formFields(t reflect.Type, omit map[string]bool, path map[reflect.Type]bool) []entity.FormField {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	fields := []entity.FormField{}
+	if path[t] {
+		return fields
+	}
+	path[t] = true
+	defer delete(path, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		if sf.Anonymous && sf.Tag.Get("json") == "" {
+			fields = append(fields, formFields(sf.Type, omit, path)...)
+			continue
+		}
+
+		name := strings.Split(sf.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		gormTag := sf.Tag.Get("gorm")
+		if omit[name] || strings.Contains(gormTag, "primaryKey") || strings.Contains(gormTag, "foreignKey") || strings.Contains(gormTag, "many2many") ||
+			strings.Contains(gormTag, "autoCreateTime") || strings.Contains(gormTag, "autoUpdateTime") ||
+			name == "id" || name == "created_at" || name == "updated_at" {
+			continue
+		}
+
+		field := entity.FormField{
+			Name:    name,
+			Example: sf.Tag.Get("example"),
+			Help:    sf.Tag.Get("help"),
+		}
+		describeFormType(&field, sf.Type, path)
+		applyFormRules(&field, sf.Tag.Get("binding"))
+		// Entities bound directly carry no binding tags; columns that cannot
+		// be null and have no default must be given
+		if sf.Tag.Get("binding") == "" && strings.Contains(gormTag, "not null") && !strings.Contains(gormTag, "default") {
+			field.Required = true
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// describeFormType sets the type, enum options and nested fields of a field from its Go type
+func describeFormType(field *entity.FormField, t reflect.Type, path map[reflect.Type]bool) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if values, ok := formEnums[t]; ok {
+		field.Enum = values
+	}
+
+	switch {
+	case t == timeType:
+		field.Type = "date-time"
+	case t.Kind() == reflect.Bool:
+		field.Type = "boolean"
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		field.Type = "integer"
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		field.Type = "number"
+	case t.Kind() == reflect.String:
+		field.Type = "string"
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		field.Type = "array"
+		item := entity.FormField{}
+		describeFormType(&item, t.Elem(), path)
+		field.ItemType = item.Type
+		field.Fields = item.Fields
+		if len(item.Enum) > 0 {
+			field.Enum = item.Enum
+		}
+	case t.Kind() == reflect.Struct:
+		field.Type = "object"
+		field.Fields = formFields(t, nil, path)
+	default:
+		field.Type = "object"
+	}
+}
+
+// applyFormRules sets the required flag, options and limits of a field from its
+// binding tag. Rules after dive apply to array items and are not described.
+func applyFormRules(field *entity.FormField, binding string) {
+	if binding == "" {
+		return
+	}
+	for _, rule := range strings.Split(binding, ",") {
+		key, value, _ := strings.Cut(rule, "=")
+		switch key {
+		case "dive":
+			return
+		case "required":
+			field.Required = true
+		case "oneof":
+			field.Enum = strings.Fields(value)
+		case "email":
+			field.Format = "email"
+		case "url", "uri":
+			field.Format = "uri"
+		case "uuid", "uuid4":
+			field.Format = "uuid"
+		case "len":
+			if n, err := strconv.Atoi(value); err == nil {
+				field.MinLength, field.MaxLength = &n, &n
+			}
+		case "min", "max":
+			if field.Type == "string" || field.Type == "array" {
+				if n, err := strconv.Atoi(value); err == nil {
+					if key == "min" {
+						field.MinLength = &n
+					} else {
+						field.MaxLength = &n
+					}
+				}
+				continue
+			}
+			if n, err := strconv.ParseFloat(value, 64); err == nil {
+				if key == "min" {
+					field.Minimum = &n
+				} else {
+					field.Maximum = &n
+				}
+			}
+		case "gt", "gte":
+			if n, err := strconv.ParseFloat(value, 64); err == nil {
+				field.Minimum = &n
+				field.ExclusiveMinimum = key == "gt"
+			}
+		case "lt", "lte":
+			if n, err := strconv.ParseFloat(value, 64); err == nil {
+				field.Maximum = &n
+				field.ExclusiveMaximum = key == "lt"
+			}
+		}
+	}
+}
+
+// enumValues lists the values of a named string type's constants
+func enumValues[T ~string](values ...T) []string {
+	out := make([]string, len(values))
+	for i, v := range values {
+		out[i] = string(v)
+	}
+	return out
+}
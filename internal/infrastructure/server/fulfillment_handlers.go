@@ -0,0 +1,206 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/server/middleware"
+)
+
+// FulfillmentHandlers handles open order book and fulfillment SLA HTTP requests
+type FulfillmentHandlers struct {
+	fulfillmentUseCase *usecase.FulfillmentUseCase
+}
+
+// NewFulfillmentHandlers creates a new fulfillment handlers instance
+func NewFulfillmentHandlers(fulfillmentUseCase *usecase.FulfillmentUseCase) *FulfillmentHandlers {
+	return &FulfillmentHandlers{
+		fulfillmentUseCase: fulfillmentUseCase,
+	}
+}
+
+// RegisterRoutes registers fulfillment SLA alert routes
+func (h *FulfillmentHandlers) RegisterRoutes(router *gin.RouterGroup) {
+	fulfillment := router.Group("/fulfillment/sla")
+	{
+		fulfillment.POST("/check", middleware.PermissionMiddleware(entity.SalesFulfillmentManage), h.CheckSLA)
+		fulfillment.GET("/alerts", middleware.PermissionMiddleware(entity.ReportRead), h.ListAlerts)
+		fulfillment.POST("/alerts/:id/acknowledge", middleware.PermissionMiddleware(entity.SalesFulfillmentManage), h.AcknowledgeAlert)
+	}
+}
+
+// RegisterReportRoutes registers the open order book and fulfillment SLA
+// report routes
+func (h *FulfillmentHandlers) RegisterReportRoutes(router *gin.RouterGroup) {
+	reports := router.Group("/reports/fulfillment")
+	{
+		reports.GET("/open-orders", middleware.PermissionMiddleware(entity.ReportRead), h.GetOpenOrders)
+		reports.GET("/sla", middleware.PermissionMiddleware(entity.ReportRead), h.GetSLAReport)
+	}
+}
+
+// GetOpenOrders handles the open order book
+// @Summary Get open order book
+// @Description List the confirmed sales orders with lines left to ship, earliest committed ship date first, with the shipped and remaining quantity of each line. Lines not fully shipped by the committed date are late. Amounts are in the base currency.
+// @Tags Reports
+// @Security BearerAuth
+// @Produce json
+// @Param client_id query int false "Client ID"
+// @Param late_only query bool false "Only orders with late lines"
+// @Success 200 {object} entity.OpenOrderBook
+// @Failure 500 {object} ErrorResponse
+// @Router /reports/fulfillment/open-orders [get]
+func (h *FulfillmentHandlers) GetOpenOrders(c *gin.Context) {
+	filter := &entity.OpenOrderFilter{}
+	if clientID, err := strconv.ParseUint(c.Query("client_id"), 10, 32); err == nil {
+		filter.ClientID = uint(clientID)
+	}
+	if lateOnly, err := strconv.ParseBool(c.Query("late_only")); err == nil {
+		filter.LateOnly = lateOnly
+	}
+
+	book, err := h.fulfillmentUseCase.OpenOrders(c.Request.Context(), filter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	for i := range book.Orders {
+		book.Orders[i].OrderURL = "/api/v1/orders/" + book.Orders[i].OrderID
+	}
+	c.JSON(http.StatusOK, book)
+}
+
+// GetSLAReport handles the fulfillment SLA report
+// @Summary Get fulfillment SLA report
+// @Description Measure each warehouse against the fulfillment SLA over a period: the average order-to-ship lead time of the deliveries shipped in it against the SLA ship days, and the fill rate, the percentage of units shipped by the committed date, of the orders committed in it against the target
+// @Tags Reports
+// @Security BearerAuth
+// @Produce json
+// @Param start_date query string false "Period start (YYYY-MM-DD), defaults to the lookback days before the end"
+// @Param end_date query string false "Period end (YYYY-MM-DD), defaults to now"
+// @Success 200 {object} entity.FulfillmentSLAReport
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /reports/fulfillment/sla [get]
+func (h *FulfillmentHandlers) GetSLAReport(c *gin.Context) {
+	filter := &entity.FulfillmentSLAFilter{}
+	if startDate, err := time.Parse("2006-01-02", c.Query("start_date")); err == nil {
+		filter.StartDate = &startDate
+	}
+	if endDate, err := time.Parse("2006-01-02", c.Query("end_date")); err == nil {
+		endDate = endDate.Add(24*time.Hour - time.Nanosecond)
+		filter.EndDate = &endDate
+	}
+
+	report, err := h.fulfillmentUseCase.SLAReport(c.Request.Context(), filter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// CheckSLA handles running the fulfillment SLA check
+// @Summary Check fulfillment SLA
+// @Description Measure the warehouses over the lookback days, raise an alert for each newly breaching the lead time or fill rate SLA and resolve the alerts of those meeting it again
+// @Tags Reports
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} entity.FulfillmentSLARunResult
+// @Failure 500 {object} ErrorResponse
+// @Router /fulfillment/sla/check [post]
+func (h *FulfillmentHandlers) CheckSLA(c *gin.Context) {
+	result, err := h.fulfillmentUseCase.Run(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// ListAlerts handles listing fulfillment SLA alerts
+// @Summary List fulfillment SLA alerts
+// @Description List the alerts raised when warehouses breached the fulfillment SLA, latest first
+// @Tags Reports
+// @Security BearerAuth
+// @Produce json
+// @Param store_id query string false "Store ID"
+// @Param metric query string false "Metric (LEAD_TIME/FILL_RATE)"
+// @Param resolved query bool false "Only resolved (true) or unresolved (false) alerts"
+// @Param acknowledged query bool false "Only acknowledged (true) or open (false) alerts"
+// @Param page query int false "Page number"
+// @Param page_size query int false "Page size"
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} ErrorResponse
+// @Router /fulfillment/sla/alerts [get]
+func (h *FulfillmentHandlers) ListAlerts(c *gin.Context) {
+	filter := &entity.FulfillmentSLAAlertFilter{
+		StoreID: c.Query("store_id"),
+		Metric:  entity.FulfillmentSLAMetric(c.Query("metric")),
+	}
+
+	if resolved, err := strconv.ParseBool(c.Query("resolved")); err == nil {
+		filter.Resolved = &resolved
+	}
+
+	if acknowledged, err := strconv.ParseBool(c.Query("acknowledged")); err == nil {
+		filter.Acknowledged = &acknowledged
+	}
+
+	if page, err := strconv.Atoi(c.Query("page")); err == nil {
+		filter.Page = page
+	}
+
+	if pageSize, err := strconv.Atoi(c.Query("page_size")); err == nil {
+		filter.PageSize = pageSize
+	}
+
+	alerts, total, err := h.fulfillmentUseCase.ListAlerts(c.Request.Context(), filter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"alerts":    alerts,
+		"total":     total,
+		"page":      filter.Page,
+		"page_size": filter.PageSize,
+	})
+}
+
+// AcknowledgeAlert handles acknowledging a fulfillment SLA alert
+// @Summary Acknowledge fulfillment SLA alert
+// @Description Mark a fulfillment SLA alert as handled. It stays unresolved until the warehouse meets the SLA again.
+// @Tags Reports
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Alert ID"
+// @Success 200 {object} entity.FulfillmentSLAAlert
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /fulfillment/sla/alerts/{id}/acknowledge [post]
+func (h *FulfillmentHandlers) AcknowledgeAlert(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid alert ID"))
+		return
+	}
+
+	alert, err := h.fulfillmentUseCase.AcknowledgeAlert(c.Request.Context(), uint(id), currentUserID(c))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, alert)
+}
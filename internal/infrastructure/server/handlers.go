@@ -7,6 +7,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
 	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/logging"
 )
 
 // Request/Response models
@@ -42,8 +43,13 @@ type UpdateUserRequest struct {
 	Status   entity.UserStatus `json:"status" binding:"required" example:"active"`
 }
 
+// ErrorResponse is the body of every failed request, written by
+// middleware.ErrorMiddleware
 type ErrorResponse struct {
-	Error string `json:"error" example:"Error message"`
+	Error   string              `json:"error" example:"Error message"`
+	Code    entity.ErrorCode    `json:"code" example:"INVALID_ARGUMENT"`
+	Fields  []entity.FieldError `json:"fields,omitempty"`
+	TraceID string              `json:"trace_id" example:"3f2c9a7e-8d41-4b6e-9a55-0c1d2e3f4a5b"`
 }
 
 type MessageResponse struct {
@@ -61,8 +67,10 @@ type TokenResponse struct {
 }
 
 type AuditLogResponse struct {
-	Total int64             `json:"total" example:"100"`
-	Logs  []entity.AuditLog `json:"logs"`
+	Total    int64             `json:"total" example:"100"`
+	Page     int               `json:"page" example:"1"`
+	PageSize int               `json:"page_size" example:"10"`
+	Logs     []entity.AuditLog `json:"logs"`
 }
 
 // Auth handlers
@@ -79,7 +87,7 @@ type AuditLogResponse struct {
 func (s *Server) handleRegister(c *gin.Context) {
 	var req RegisterRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 
@@ -91,7 +99,7 @@ func (s *Server) handleRegister(c *gin.Context) {
 	})
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -111,30 +119,33 @@ func (s *Server) handleRegister(c *gin.Context) {
 func (s *Server) handleLogin(c *gin.Context) {
 	var req LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 
 	user, err := s.userUC.ValidateCredentials(req.Email, req.Password)
+	if recordErr := s.activityUC.RecordLogin(c.Request.Context(), req.Email, c.ClientIP(), c.Request.UserAgent(), err); recordErr != nil {
+		logging.FromContext(c.Request.Context()).Error("error recording login", "email", req.Email, "error", recordErr)
+	}
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeUnauthenticated, err))
 		return
 	}
 
 	accessToken, err := s.jwtService.GenerateAccessToken(user)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate access token"})
+		c.Error(entity.NewError(entity.ErrCodeInternal, "Failed to generate access token"))
 		return
 	}
 
 	refreshToken, expiry, err := s.jwtService.GenerateRefreshToken(user)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate refresh token"})
+		c.Error(entity.NewError(entity.ErrCodeInternal, "Failed to generate refresh token"))
 		return
 	}
 
 	if err := s.userUC.UpdateRefreshToken(user.ID, refreshToken, expiry); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save refresh token"})
+		c.Error(entity.NewError(entity.ErrCodeInternal, "Failed to save refresh token"))
 		return
 	}
 
@@ -156,12 +167,12 @@ func (s *Server) handleLogin(c *gin.Context) {
 func (s *Server) handleLogout(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		c.Error(entity.NewError(entity.ErrCodeUnauthenticated, "User not found in context"))
 		return
 	}
 
 	if err := s.userUC.UpdateRefreshToken(userID.(uint), "", time.Time{}); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to invalidate refresh token"})
+		c.Error(entity.NewError(entity.ErrCodeInternal, "Failed to invalidate refresh token"))
 		return
 	}
 
@@ -181,19 +192,19 @@ func (s *Server) handleLogout(c *gin.Context) {
 func (s *Server) handleRefreshToken(c *gin.Context) {
 	var req RefreshTokenRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 
 	user, err := s.userUC.ValidateRefreshToken(req.RefreshToken)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+		c.Error(entity.NewError(entity.ErrCodeUnauthenticated, "Invalid refresh token"))
 		return
 	}
 
 	accessToken, err := s.jwtService.GenerateAccessToken(user)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate access token"})
+		c.Error(entity.NewError(entity.ErrCodeInternal, "Failed to generate access token"))
 		return
 	}
 
@@ -215,12 +226,12 @@ func (s *Server) handleRefreshToken(c *gin.Context) {
 func (s *Server) handleForgotPassword(c *gin.Context) {
 	var req ForgotPasswordRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 
 	if _, err := s.userUC.RequestPasswordReset(req.Email); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -239,12 +250,12 @@ func (s *Server) handleForgotPassword(c *gin.Context) {
 func (s *Server) handleResetPassword(c *gin.Context) {
 	var req ResetPasswordRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 
 	if err := s.userUC.ResetPassword(req.Token, req.NewPassword); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 
@@ -0,0 +1,259 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/auth"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/logging"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/server/middleware"
+)
+
+// ImpersonationResponse represents a started impersonation session with the
+// access token to act as its user
+type ImpersonationResponse struct {
+	Session     *entity.ImpersonationSession `json:"session"`
+	AccessToken string                       `json:"access_token" example:"eyJhbGciOiJ..."`
+	ExpiresAt   time.Time                    `json:"expires_at"`
+}
+
+// ImpersonationHandlers handles admin impersonation HTTP requests
+type ImpersonationHandlers struct {
+	impersonationUseCase *usecase.ImpersonationUseCase
+	jwtService           *auth.JWTService
+}
+
+// NewImpersonationHandlers creates a new impersonation handlers instance
+func NewImpersonationHandlers(impersonationUseCase *usecase.ImpersonationUseCase, jwtService *auth.JWTService) *ImpersonationHandlers {
+	return &ImpersonationHandlers{
+		impersonationUseCase: impersonationUseCase,
+		jwtService:           jwtService,
+	}
+}
+
+// RegisterRoutes registers impersonation routes
+func (h *ImpersonationHandlers) RegisterRoutes(router *gin.RouterGroup) {
+	// Sessions act for a person, so API keys cannot start or end them, and a
+	// session cannot start another
+	impersonations := router.Group("/access/impersonations", middleware.DenyAPIKeyMiddleware())
+	{
+		impersonations.POST("", middleware.DenyImpersonationMiddleware(), middleware.PermissionMiddleware(entity.UserImpersonate), h.StartImpersonation)
+		impersonations.GET("/mine", h.ListMySessions)
+		impersonations.GET("", middleware.PermissionMiddleware(entity.UserImpersonate), h.ListSessions)
+		impersonations.GET("/:id", middleware.PermissionMiddleware(entity.UserImpersonate), h.GetSession)
+		impersonations.POST("/:id/end", h.EndImpersonation)
+	}
+}
+
+// StartImpersonation handles starting to impersonate a user
+// @Summary Start impersonation
+// @Description Start acting as another user for troubleshooting, for up to the configured maximum minutes. The returned access token carries the user's permissions and expires with the session; every request made with it is audited under the user with the caller as impersonator. The user is notified. Users who can impersonate cannot be impersonated.
+// @Tags access
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body entity.ImpersonationRequest true "User, reason and duration"
+// @Success 201 {object} ImpersonationResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Router /access/impersonations [post]
+func (h *ImpersonationHandlers) StartImpersonation(c *gin.Context) {
+	userID := currentUserID(c)
+	if userID == nil {
+		c.Error(entity.NewError(entity.ErrCodeUnauthenticated, "User not found in context"))
+		return
+	}
+
+	var req entity.ImpersonationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	session, err := h.impersonationUseCase.StartImpersonation(c.Request.Context(), *userID, c.ClientIP(), &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	token, err := h.jwtService.GenerateImpersonationToken(session)
+	if err != nil {
+		// The session is of no use without its token
+		if _, endErr := h.impersonationUseCase.EndImpersonation(context.WithoutCancel(c.Request.Context()), session.ID, *userID, true); endErr != nil {
+			logging.FromContext(c.Request.Context()).Error("error ending impersonation session", "session_id", session.ID, "error", endErr)
+		}
+		c.Error(entity.NewError(entity.ErrCodeInternal, "failed to generate impersonation token"))
+		return
+	}
+
+	c.JSON(http.StatusCreated, ImpersonationResponse{
+		Session:     session,
+		AccessToken: token,
+		ExpiresAt:   session.ExpiresAt,
+	})
+}
+
+// ListMySessions handles listing the sessions in which the caller was impersonated
+// @Summary List my impersonations
+// @Description List the impersonation sessions in which admins acted as the caller, latest first
+// @Tags access
+// @Security BearerAuth
+// @Produce json
+// @Param active query bool false "Only open (true) or ended (false) sessions"
+// @Param page query int false "Page number"
+// @Param page_size query int false "Page size"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} ErrorResponse
+// @Router /access/impersonations/mine [get]
+func (h *ImpersonationHandlers) ListMySessions(c *gin.Context) {
+	userID := currentUserID(c)
+	if userID == nil {
+		c.Error(entity.NewError(entity.ErrCodeUnauthenticated, "User not found in context"))
+		return
+	}
+
+	filter := impersonationFilter(c)
+	filter.UserID = *userID
+	h.listSessions(c, filter)
+}
+
+// ListSessions handles listing impersonation sessions
+// @Summary List impersonations
+// @Description List impersonation sessions, latest first, filtered by impersonator, impersonated user, state and start date
+// @Tags access
+// @Security BearerAuth
+// @Produce json
+// @Param impersonator_id query int false "Impersonator user ID"
+// @Param user_id query int false "Impersonated user ID"
+// @Param active query bool false "Only open (true) or ended (false) sessions"
+// @Param start_date query string false "Started from (YYYY-MM-DD)"
+// @Param end_date query string false "Started to (YYYY-MM-DD)"
+// @Param page query int false "Page number"
+// @Param page_size query int false "Page size"
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} ErrorResponse
+// @Router /access/impersonations [get]
+func (h *ImpersonationHandlers) ListSessions(c *gin.Context) {
+	filter := impersonationFilter(c)
+	if impersonatorID, err := strconv.ParseUint(c.Query("impersonator_id"), 10, 32); err == nil {
+		filter.ImpersonatorID = uint(impersonatorID)
+	}
+	if userID, err := strconv.ParseUint(c.Query("user_id"), 10, 32); err == nil {
+		filter.UserID = uint(userID)
+	}
+	h.listSessions(c, filter)
+}
+
+func (h *ImpersonationHandlers) listSessions(c *gin.Context, filter *entity.ImpersonationFilter) {
+	sessions, total, err := h.impersonationUseCase.ListSessions(c.Request.Context(), filter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"sessions":  sessions,
+		"total":     total,
+		"page":      filter.Page,
+		"page_size": filter.PageSize,
+	})
+}
+
+// GetSession handles getting an impersonation session
+// @Summary Get impersonation
+// @Description Get an impersonation session. Its requests are in the audit log under impersonator_id.
+// @Tags access
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Session ID"
+// @Success 200 {object} entity.ImpersonationSession
+// @Failure 404 {object} ErrorResponse
+// @Router /access/impersonations/{id} [get]
+func (h *ImpersonationHandlers) GetSession(c *gin.Context) {
+	id, ok := parseImpersonationID(c)
+	if !ok {
+		return
+	}
+
+	session, err := h.impersonationUseCase.GetSession(c.Request.Context(), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, session)
+}
+
+// EndImpersonation handles ending an impersonation session
+// @Summary End impersonation
+// @Description End an open impersonation session early; its token stops working at once. The impersonator, with either of their tokens, the impersonated user and holders of user:impersonate can end it.
+// @Tags access
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Session ID"
+// @Success 200 {object} entity.ImpersonationSession
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Router /access/impersonations/{id}/end [post]
+func (h *ImpersonationHandlers) EndImpersonation(c *gin.Context) {
+	id, ok := parseImpersonationID(c)
+	if !ok {
+		return
+	}
+	userID := currentUserID(c)
+	if userID == nil {
+		c.Error(entity.NewError(entity.ErrCodeUnauthenticated, "User not found in context"))
+		return
+	}
+
+	// In a session, the admin behind it is the one ending it
+	actorID := *userID
+	if impersonatorID := auth.GetImpersonatorIDFromContext(c); impersonatorID != 0 {
+		actorID = impersonatorID
+	}
+
+	admin := middleware.HasPermission(c, entity.UserImpersonate)
+	session, err := h.impersonationUseCase.EndImpersonation(c.Request.Context(), id, actorID, admin)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, session)
+}
+
+func impersonationFilter(c *gin.Context) *entity.ImpersonationFilter {
+	filter := &entity.ImpersonationFilter{}
+	filter.StartDate, filter.EndDate = activityPeriod(c)
+
+	if active, err := strconv.ParseBool(c.Query("active")); err == nil {
+		filter.Active = &active
+	}
+
+	if page, err := strconv.Atoi(c.Query("page")); err == nil {
+		filter.Page = page
+	}
+
+	if pageSize, err := strconv.Atoi(c.Query("page_size")); err == nil {
+		filter.PageSize = pageSize
+	}
+
+	return filter
+}
+
+func parseImpersonationID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid session ID"))
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -0,0 +1,368 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/server/middleware"
+)
+
+// InboundHandlers handles HTTP requests for the expected receipts calendar
+// and dock scheduling
+type InboundHandlers struct {
+	inboundUseCase *usecase.InboundUseCase
+}
+
+// NewInboundHandlers creates a new inbound handlers instance
+func NewInboundHandlers(inboundUseCase *usecase.InboundUseCase) *InboundHandlers {
+	return &InboundHandlers{
+		inboundUseCase: inboundUseCase,
+	}
+}
+
+// RegisterRoutes registers inbound routes
+func (h *InboundHandlers) RegisterRoutes(router *gin.RouterGroup) {
+	inboundRouter := router.Group("/stocks/inbound")
+	{
+		inboundRouter.GET("/calendar", middleware.PermissionMiddleware(entity.StockInboundRead), h.Calendar)
+		inboundRouter.GET("/overdue", middleware.PermissionMiddleware(entity.StockInboundRead), h.Overdue)
+		inboundRouter.POST("/doors", middleware.PermissionMiddleware(entity.StockInboundManage), h.CreateDoor)
+		inboundRouter.GET("/doors", middleware.PermissionMiddleware(entity.StockInboundRead), h.ListDoors)
+		inboundRouter.PUT("/doors/:id", middleware.PermissionMiddleware(entity.StockInboundManage), h.UpdateDoor)
+		inboundRouter.POST("/appointments", middleware.PermissionMiddleware(entity.StockInboundSchedule), h.BookAppointment)
+		inboundRouter.GET("/appointments", middleware.PermissionMiddleware(entity.StockInboundRead), h.ListAppointments)
+		inboundRouter.GET("/appointments/:id", middleware.PermissionMiddleware(entity.StockInboundRead), h.GetAppointment)
+		inboundRouter.POST("/appointments/:id/check-in", middleware.PermissionMiddleware(entity.StockInboundSchedule), h.CheckIn)
+		inboundRouter.POST("/appointments/:id/cancel", middleware.PermissionMiddleware(entity.StockInboundSchedule), h.CancelAppointment)
+	}
+}
+
+// Calendar handles getting the expected receipts calendar
+// @Summary Expected receipts calendar
+// @Description Get the deliveries expected at the warehouses by day: open purchase orders on their expected date and ship notices not received yet on their delivery date, with the dock appointments booked for them and whether the order is overdue
+// @Tags stocks
+// @Security BearerAuth
+// @Produce json
+// @Param start_date query string false "First day (YYYY-MM-DD), defaults to today"
+// @Param end_date query string false "Last day (YYYY-MM-DD), defaults to two weeks from the first"
+// @Param store_id query string false "Store ID"
+// @Success 200 {object} entity.InboundCalendar
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /stocks/inbound/calendar [get]
+func (h *InboundHandlers) Calendar(c *gin.Context) {
+	filter := &entity.InboundCalendarFilter{StoreID: c.Query("store_id")}
+
+	if startDateStr := c.Query("start_date"); startDateStr != "" {
+		startDate, err := time.Parse("2006-01-02", startDateStr)
+		if err != nil {
+			c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid start_date"))
+			return
+		}
+		filter.StartDate = &startDate
+	}
+	if endDateStr := c.Query("end_date"); endDateStr != "" {
+		endDate, err := time.Parse("2006-01-02", endDateStr)
+		if err != nil {
+			c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid end_date"))
+			return
+		}
+		filter.EndDate = &endDate
+	}
+
+	calendar, err := h.inboundUseCase.Calendar(c.Request.Context(), filter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, calendar)
+}
+
+// Overdue handles listing the overdue inbound orders
+// @Summary List overdue inbound orders
+// @Description List the sent, confirmed and partially received purchase orders past their expected date, most overdue first, for follow-up with the vendors
+// @Tags stocks
+// @Security BearerAuth
+// @Produce json
+// @Param store_id query string false "Store ID"
+// @Success 200 {array} entity.InboundEntry
+// @Failure 500 {object} ErrorResponse
+// @Router /stocks/inbound/overdue [get]
+func (h *InboundHandlers) Overdue(c *gin.Context) {
+	entries, err := h.inboundUseCase.Overdue(c.Request.Context(), c.Query("store_id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
+
+// CreateDoor handles creating a dock door
+// @Summary Create dock door
+// @Description Create a dock door of a store where inbound deliveries are unloaded
+// @Tags stocks
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body entity.DockDoorRequest true "Dock door"
+// @Success 201 {object} entity.DockDoor
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /stocks/inbound/doors [post]
+func (h *InboundHandlers) CreateDoor(c *gin.Context) {
+	var req entity.DockDoorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	door, err := h.inboundUseCase.CreateDoor(c.Request.Context(), &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, door)
+}
+
+// ListDoors handles listing dock doors
+// @Summary List dock doors
+// @Description List the dock doors per store
+// @Tags stocks
+// @Security BearerAuth
+// @Produce json
+// @Param store_id query string false "Store ID"
+// @Success 200 {array} entity.DockDoor
+// @Failure 500 {object} ErrorResponse
+// @Router /stocks/inbound/doors [get]
+func (h *InboundHandlers) ListDoors(c *gin.Context) {
+	doors, err := h.inboundUseCase.ListDoors(c.Request.Context(), c.Query("store_id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, doors)
+}
+
+// UpdateDoor handles updating a dock door
+// @Summary Update dock door
+// @Description Rename, move or deactivate a dock door. Appointments already booked keep their slot.
+// @Tags stocks
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Dock door ID"
+// @Param request body entity.DockDoorRequest true "Dock door"
+// @Success 200 {object} entity.DockDoor
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /stocks/inbound/doors/{id} [put]
+func (h *InboundHandlers) UpdateDoor(c *gin.Context) {
+	id, ok := parseInboundID(c)
+	if !ok {
+		return
+	}
+
+	var req entity.DockDoorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	door, err := h.inboundUseCase.UpdateDoor(c.Request.Context(), id, &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, door)
+}
+
+// BookAppointment handles booking a dock door slot
+// @Summary Book dock appointment
+// @Description Book a slot at an active dock door for a delivery, optionally of a sent, confirmed or partially received purchase order or of a ship notice. The slot must start in working time on the warehouse calendar and not overlap another booking of the door.
+// @Tags stocks
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body entity.DockAppointmentRequest true "Dock appointment"
+// @Success 201 {object} entity.DockAppointment
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse "The door is already booked at that time"
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /stocks/inbound/appointments [post]
+func (h *InboundHandlers) BookAppointment(c *gin.Context) {
+	userID := currentUserID(c)
+	if userID == nil {
+		c.Error(entity.NewError(entity.ErrCodeUnauthenticated, "unauthorized"))
+		return
+	}
+	var req entity.DockAppointmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	appointment, err := h.inboundUseCase.BookAppointment(c.Request.Context(), &req, *userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, appointment)
+}
+
+// ListAppointments handles listing dock appointments
+// @Summary List dock appointments
+// @Description List dock appointments by start time
+// @Tags stocks
+// @Security BearerAuth
+// @Produce json
+// @Param store_id query string false "Store ID"
+// @Param door_id query int false "Dock door ID"
+// @Param purchase_order_id query string false "Purchase order ID"
+// @Param status query string false "Status (BOOKED, ARRIVED, CANCELLED)"
+// @Param from query string false "Slots ending after (RFC 3339)"
+// @Param to query string false "Slots starting before (RFC 3339)"
+// @Success 200 {array} entity.DockAppointment
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /stocks/inbound/appointments [get]
+func (h *InboundHandlers) ListAppointments(c *gin.Context) {
+	filter := &entity.DockAppointmentFilter{
+		StoreID:         c.Query("store_id"),
+		PurchaseOrderID: c.Query("purchase_order_id"),
+		Status:          entity.DockAppointmentStatus(c.Query("status")),
+	}
+
+	if doorID, err := strconv.ParseUint(c.Query("door_id"), 10, 32); err == nil {
+		filter.DoorID = uint(doorID)
+	}
+	if fromStr := c.Query("from"); fromStr != "" {
+		from, err := time.Parse(time.RFC3339, fromStr)
+		if err != nil {
+			c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid from"))
+			return
+		}
+		filter.From = &from
+	}
+	if toStr := c.Query("to"); toStr != "" {
+		to, err := time.Parse(time.RFC3339, toStr)
+		if err != nil {
+			c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid to"))
+			return
+		}
+		filter.To = &to
+	}
+
+	appointments, err := h.inboundUseCase.ListAppointments(c.Request.Context(), filter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, appointments)
+}
+
+// GetAppointment handles getting a dock appointment
+// @Summary Get dock appointment
+// @Description Get a dock appointment with its door
+// @Tags stocks
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Dock appointment ID"
+// @Success 200 {object} entity.DockAppointment
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /stocks/inbound/appointments/{id} [get]
+func (h *InboundHandlers) GetAppointment(c *gin.Context) {
+	id, ok := parseInboundID(c)
+	if !ok {
+		return
+	}
+
+	appointment, err := h.inboundUseCase.GetAppointment(c.Request.Context(), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, appointment)
+}
+
+// CheckIn handles checking in the delivery of a dock appointment
+// @Summary Check in dock appointment
+// @Description Record that the delivery of a booked appointment arrived at the door
+// @Tags stocks
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Dock appointment ID"
+// @Success 200 {object} entity.DockAppointment
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse "Appointment is not booked"
+// @Failure 500 {object} ErrorResponse
+// @Router /stocks/inbound/appointments/{id}/check-in [post]
+func (h *InboundHandlers) CheckIn(c *gin.Context) {
+	id, ok := parseInboundID(c)
+	if !ok {
+		return
+	}
+
+	appointment, err := h.inboundUseCase.CheckIn(c.Request.Context(), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, appointment)
+}
+
+// CancelAppointment handles cancelling a dock appointment
+// @Summary Cancel dock appointment
+// @Description Cancel a booked appointment, freeing its slot
+// @Tags stocks
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Dock appointment ID"
+// @Success 200 {object} entity.DockAppointment
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse "Appointment is not booked"
+// @Failure 500 {object} ErrorResponse
+// @Router /stocks/inbound/appointments/{id}/cancel [post]
+func (h *InboundHandlers) CancelAppointment(c *gin.Context) {
+	id, ok := parseInboundID(c)
+	if !ok {
+		return
+	}
+
+	appointment, err := h.inboundUseCase.CancelAppointment(c.Request.Context(), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, appointment)
+}
+
+func parseInboundID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid ID"))
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -0,0 +1,172 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/server/middleware"
+)
+
+// JobHandlers handles background job HTTP requests
+type JobHandlers struct {
+	jobUseCase *usecase.JobUseCase
+}
+
+// NewJobHandlers creates a new job handlers instance
+func NewJobHandlers(jobUseCase *usecase.JobUseCase) *JobHandlers {
+	return &JobHandlers{
+		jobUseCase: jobUseCase,
+	}
+}
+
+// RegisterRoutes registers job routes. Users see and cancel the jobs they
+// queued; system:job:read and system:job:manage extend that to all jobs.
+func (h *JobHandlers) RegisterRoutes(router *gin.RouterGroup) {
+	jobs := router.Group("/jobs")
+	{
+		jobs.GET("", h.ListJobs)
+		jobs.GET("/:id", h.GetJob)
+		jobs.POST("/:id/cancel", h.CancelJob)
+	}
+}
+
+// ListJobs handles listing background jobs
+// @Summary List jobs
+// @Description List background jobs, latest first and without their payloads and results. Callers without system:job:read only see the jobs they queued.
+// @Tags jobs
+// @Security BearerAuth
+// @Produce json
+// @Param type query string false "Job type, e.g. report.generate"
+// @Param status query string false "Status (QUEUED/RUNNING/SUCCEEDED/FAILED/CANCELED)"
+// @Param created_by query int false "ID of the user who queued the jobs; needs system:job:read"
+// @Param page query int false "Page number"
+// @Param page_size query int false "Page size"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /jobs [get]
+func (h *JobHandlers) ListJobs(c *gin.Context) {
+	userID := currentUserID(c)
+	if userID == nil {
+		c.Error(entity.NewError(entity.ErrCodeUnauthenticated, "User not found in context"))
+		return
+	}
+
+	filter := &entity.JobFilter{
+		Type:      c.Query("type"),
+		Status:    entity.JobStatus(c.Query("status")),
+		CreatedBy: userID,
+	}
+	if middleware.HasPermission(c, entity.SystemJobRead) {
+		filter.CreatedBy = nil
+		if createdBy, err := strconv.ParseUint(c.Query("created_by"), 10, 32); err == nil {
+			id := uint(createdBy)
+			filter.CreatedBy = &id
+		}
+	}
+	if page, err := strconv.Atoi(c.Query("page")); err == nil {
+		filter.Page = page
+	}
+	if pageSize, err := strconv.Atoi(c.Query("page_size")); err == nil {
+		filter.PageSize = pageSize
+	}
+
+	jobs, total, err := h.jobUseCase.List(c.Request.Context(), filter)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"jobs":      jobs,
+		"total":     total,
+		"page":      filter.Page,
+		"page_size": filter.PageSize,
+	})
+}
+
+// GetJob handles getting a background job
+// @Summary Get job
+// @Description Get a background job's status, progress, error and, once it succeeded, its result
+// @Tags jobs
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Job ID"
+// @Success 200 {object} entity.Job
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /jobs/{id} [get]
+func (h *JobHandlers) GetJob(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid job ID"))
+		return
+	}
+
+	job, err := h.jobUseCase.Get(c.Request.Context(), id, h.scope(c, entity.SystemJobRead))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// CancelJob handles canceling a background job
+// @Summary Cancel job
+// @Description Cancel a background job that has not started yet. Running jobs cannot be canceled.
+// @Tags jobs
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Job ID"
+// @Success 200 {object} entity.Job
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /jobs/{id}/cancel [post]
+func (h *JobHandlers) CancelJob(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid job ID"))
+		return
+	}
+
+	job, err := h.jobUseCase.Cancel(c.Request.Context(), id, h.scope(c, entity.SystemJobManage))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// scope returns the user whose jobs the caller may reach, or nil when the
+// caller holds the permission reaching all jobs
+func (h *JobHandlers) scope(c *gin.Context, all entity.Permission) *uint {
+	if middleware.HasPermission(c, all) {
+		return nil
+	}
+	if userID := currentUserID(c); userID != nil {
+		return userID
+	}
+	// Without a user no job is found
+	none := uint(0)
+	return &none
+}
+
+func (h *JobHandlers) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, usecase.ErrJobNotFound):
+		c.Error(entity.WrapError(entity.ErrCodeNotFound, err))
+	case errors.Is(err, usecase.ErrJobNotCancellable):
+		c.Error(entity.WrapError(entity.ErrCodeConflict, err))
+	default:
+		c.Error(err)
+	}
+}
@@ -0,0 +1,341 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/database"
+)
+
+// scheduleTasks registers the recurring tasks with the scheduler, which runs
+// them on the instance holding the scheduler lease. The interval of a task
+// comes from its settings, and ERP_SCHEDULER_CRON replaces it with a cron
+// expression.
+func (s *Server) scheduleTasks() {
+	if s.config.Archive.Enabled {
+		interval := time.Duration(s.config.Archive.IntervalHours) * time.Hour
+		if interval <= 0 {
+			interval = 24 * time.Hour
+		}
+		s.schedulerUC.Register("archive", "Archive closed documents", every(interval), true, func(ctx context.Context) error {
+			result, err := s.archiveUC.Run(ctx)
+			if err != nil {
+				return err
+			}
+			slog.Info("archived closed documents", "cutoff", result.Cutoff.Format("2006-01-02"), "moved_rows", result.MovedRows)
+			return nil
+		})
+	}
+
+	// Audit logs past their retention are exported to the file store before
+	// they are removed, keeping the hash that anchors the chain of the rest
+	if s.config.Audit.RetentionDays > 0 {
+		interval := time.Duration(s.config.Audit.IntervalHours) * time.Hour
+		if interval <= 0 {
+			interval = 24 * time.Hour
+		}
+		s.schedulerUC.Register("audit_retention", "Export and remove audit logs past their retention", every(interval), true, func(ctx context.Context) error {
+			result, err := s.auditService.RunRetention(ctx)
+			if err != nil {
+				return err
+			}
+			if result.Archive != nil {
+				slog.Info("archived audit logs", "cutoff", result.Cutoff.Format("2006-01-02"), "exported", result.Exported, "file_key", result.Archive.FileKey)
+			}
+			return nil
+		})
+	}
+
+	// The previous month's provisions and depreciation are posted once the
+	// month has closed; the daily runs skip months already posted
+	if s.config.WriteDown.Enabled {
+		s.schedulerUC.Register("write_downs", "Post the previous month's inventory provisions", every(24*time.Hour), true, func(ctx context.Context) error {
+			result, err := s.provisionUC.PostPreviousMonth(ctx)
+			if err != nil {
+				return err
+			}
+			if result != nil {
+				slog.Info("posted write-downs", "period", result.Period, "provisioned", result.Provisioned, "released", result.Released)
+			}
+			return nil
+		})
+	}
+
+	if s.config.RFM.Enabled {
+		interval := time.Duration(s.config.RFM.IntervalHours) * time.Hour
+		if interval <= 0 {
+			interval = 24 * time.Hour
+		}
+		s.schedulerUC.Register("rfm", "Score clients by recency, frequency and monetary value", every(interval), true, func(ctx context.Context) error {
+			result, err := s.rfmUC.Run(ctx)
+			if err != nil {
+				return err
+			}
+			slog.Info("scored clients by rfm", "clients", result.Scored)
+			return nil
+		})
+	}
+
+	if s.config.SKUClasses.Enabled {
+		interval := time.Duration(s.config.SKUClasses.IntervalHours) * time.Hour
+		if interval <= 0 {
+			interval = 24 * time.Hour
+		}
+		s.schedulerUC.Register("sku_classes", "Classify SKUs by consumption value and demand variability", every(interval), true, func(ctx context.Context) error {
+			result, err := s.skuClassUC.Run(ctx)
+			if err != nil {
+				return err
+			}
+			slog.Info("classified skus", "skus", result.Classified)
+			return nil
+		})
+	}
+
+	if s.config.Snapshots.Enabled {
+		s.schedulerUC.Register("stock_snapshots", "Snapshot the closing stock of the days closed since the latest snapshot", every(time.Hour), true, func(ctx context.Context) error {
+			results, err := s.stockSnapshotUC.SnapshotMissing(ctx)
+			for _, result := range results {
+				slog.Info("snapshotted closing stock", "date", result.SnapshotDate.Format("2006-01-02"), "lines", result.Lines)
+			}
+			return err
+		})
+	}
+
+	if s.config.Dashboard.Enabled {
+		interval := time.Duration(s.config.Dashboard.RefreshMinutes) * time.Minute
+		if interval <= 0 {
+			interval = 10 * time.Minute
+		}
+		s.schedulerUC.Register("dashboard", "Pre-aggregate the dashboard metrics", every(interval), true, s.reportUC.RefreshDashboardMetrics)
+	}
+
+	if s.config.Assets.AutoDepreciate {
+		s.schedulerUC.Register("depreciation", "Post the previous month's fixed asset depreciation", every(24*time.Hour), true, func(ctx context.Context) error {
+			result, err := s.assetUC.PostPreviousMonth(ctx)
+			if err != nil {
+				return err
+			}
+			if result != nil {
+				slog.Info("posted depreciation", "period", result.Period, "assets", result.Assets, "depreciation", result.Depreciation)
+			}
+			return nil
+		})
+	}
+
+	// A forecast snapshot is stored for each dimension once a month, so
+	// accuracy can be tracked against actuals
+	if s.config.Forecast.AutoSnapshot {
+		s.schedulerUC.Register("forecasts", "Store this month's sales forecast snapshots", every(24*time.Hour), true, func(ctx context.Context) error {
+			stored, err := s.forecastUC.SnapshotCurrentMonth(ctx)
+			if err != nil {
+				return err
+			}
+			if stored > 0 {
+				slog.Info("stored forecasts", "forecasts", stored, "period", time.Now().Format("2006-01"))
+			}
+			return nil
+		})
+	}
+
+	if s.config.Recurring.Enabled {
+		s.schedulerUC.Register("recurring_invoices", "Generate the recurring invoices that have fallen due", every(time.Hour), true, func(ctx context.Context) error {
+			result, err := s.recurringUC.RunDue(ctx, time.Now())
+			if err != nil {
+				return err
+			}
+			if result.Invoices > 0 || result.Ended > 0 {
+				slog.Info("generated recurring invoices", "invoices", result.Invoices, "templates_ended", result.Ended)
+			}
+			for _, msg := range result.Errors {
+				slog.Warn("recurring invoice not generated", "reason", msg)
+			}
+			return nil
+		})
+	}
+
+	if s.config.VendorRisk.Enabled {
+		interval := time.Duration(s.config.VendorRisk.IntervalHours) * time.Hour
+		if interval <= 0 {
+			interval = 24 * time.Hour
+		}
+		s.schedulerUC.Register("vendor_risk", "Rescore vendor supply risk", every(interval), true, func(ctx context.Context) error {
+			result, err := s.vendorRiskUC.Run(ctx)
+			if err != nil {
+				return err
+			}
+			slog.Info("scored vendor risk", "vendors", result.Vendors, "at_risk", result.AtRisk, "new_alerts", len(result.Alerts))
+			return nil
+		})
+	}
+
+	if s.config.Fulfill.AlertsEnabled {
+		interval := time.Duration(s.config.Fulfill.IntervalHours) * time.Hour
+		if interval <= 0 {
+			interval = 24 * time.Hour
+		}
+		s.schedulerUC.Register("fulfillment_sla", "Check the fulfillment SLA of the warehouses", every(interval), true, func(ctx context.Context) error {
+			result, err := s.fulfillmentUC.Run(ctx)
+			if err != nil {
+				return err
+			}
+			slog.Info("checked fulfillment SLA", "warehouses", result.Warehouses, "breaching", result.Breaching, "new_alerts", len(result.Alerts), "resolved", result.Resolved)
+			return nil
+		})
+	}
+
+	if s.config.Dunning.Enabled {
+		s.schedulerUC.Register("dunning", "Send the due dunning reminders", every(24*time.Hour), true, func(ctx context.Context) error {
+			result, err := s.dunningUC.Run(ctx, time.Now(), nil)
+			if err != nil {
+				return err
+			}
+			slog.Info("ran dunning", "overdue", result.Overdue, "flagged", result.Flagged, "reminders", len(result.Reminders))
+			return nil
+		})
+	}
+
+	s.schedulerUC.Register("idempotency_purge", "Remove the idempotency keys whose responses are no longer replayed", every(time.Hour), false, func(ctx context.Context) error {
+		purged, err := s.idempotencyUC.PurgeExpired(ctx)
+		if err != nil {
+			return err
+		}
+		if purged > 0 {
+			slog.Info("purged expired idempotency keys", "keys", purged)
+		}
+		return nil
+	})
+
+	s.schedulerUC.Register("report_schedules", "Queue the reports of the due report schedules", "*/15 * * * *", true, s.reportUC.RunScheduledReports)
+
+	if s.config.Channels.SyncEnabled && s.config.Channels.SyncUserID != 0 {
+		interval := time.Duration(s.config.Channels.IntervalMinutes) * time.Minute
+		if interval <= 0 {
+			interval = 15 * time.Minute
+		}
+		s.schedulerUC.Register("sales_channels", "Ingest the orders of the active sales channels and push their inventory levels", every(interval), true, func(ctx context.Context) error {
+			runs, err := s.salesChannelUC.SyncAll(ctx, s.config.Channels.SyncUserID)
+			if err != nil {
+				return err
+			}
+			incomplete := 0
+			for _, run := range runs {
+				if run.Status != entity.ChannelSyncSucceeded {
+					slog.Warn("sales channel sync incomplete", "channel_id", run.ChannelID, "kind", run.Kind, "status", run.Status, "failed", run.Failed)
+					incomplete++
+				}
+			}
+			if incomplete > 0 {
+				return fmt.Errorf("%d of %d channel syncs incomplete", incomplete, len(runs))
+			}
+			return nil
+		})
+	}
+
+	if s.config.BankFeeds.ImportEnabled {
+		interval := time.Duration(s.config.BankFeeds.IntervalMinutes) * time.Minute
+		if interval <= 0 {
+			interval = time.Hour
+		}
+		s.schedulerUC.Register("bank_feeds", "Import the transactions of the active bank accounts", every(interval), true, func(ctx context.Context) error {
+			runs, err := s.bankFeedUC.ImportAll(ctx)
+			if err != nil {
+				return err
+			}
+			failed := 0
+			for _, run := range runs {
+				if run.Status != entity.BankImportSucceeded {
+					slog.Warn("bank feed import failed", "account_id", run.AccountID, "error", run.Error)
+					failed++
+				}
+			}
+			if failed > 0 {
+				return fmt.Errorf("%d of %d bank feed imports failed", failed, len(runs))
+			}
+			return nil
+		})
+	}
+
+	if days := s.config.Scheduler.HistoryDays; days > 0 {
+		s.schedulerUC.Register("scheduler_history", "Remove the records of old scheduled runs", "@daily", false, func(ctx context.Context) error {
+			purged, err := s.schedulerUC.PurgeRuns(ctx, days)
+			if err != nil {
+				return err
+			}
+			if purged > 0 {
+				slog.Info("purged scheduled run history", "runs", purged)
+			}
+			return nil
+		})
+	}
+}
+
+// every returns the schedule of a task running at a fixed interval
+func every(interval time.Duration) string {
+	return "@every " + interval.String()
+}
+
+// startReplicaCheckJob measures the lag of the read replica once at startup
+// and then every configured interval, routing report reads to it only while
+// it answers within the allowed lag. Unlike the scheduled tasks it runs on every
+// instance, as each routes its own reads.
+func (s *Server) startReplicaCheckJob() {
+	if s.config.Database.ReplicaDSN == "" {
+		return
+	}
+
+	interval := time.Duration(s.config.Database.ReplicaCheckSeconds) * time.Second
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+
+	// Log when reads move between the replica and the primary, not every check
+	maxLag := time.Duration(s.config.Database.ReplicaMaxLag) * time.Second
+	current := true
+	s.runEvery(interval, true, func(ctx context.Context) {
+		lag, err := database.CheckReplica(ctx, s.db)
+		switch {
+		case err != nil:
+			if current {
+				slog.Warn("read replica unavailable, reading from primary", "error", err)
+			}
+			current = false
+		case lag > maxLag:
+			if current {
+				slog.Warn("read replica lagging, reading from primary", "lag", lag.String())
+			}
+			current = false
+		default:
+			if !current {
+				slog.Info("reading reports from read replica", "lag", lag.String())
+			}
+			current = true
+		}
+	})
+}
+
+// runEvery calls run in the background every interval, and right away when
+// immediately is set, until Shutdown. Shutdown waits for a run in progress and
+// cancels its context once the shutdown deadline passes.
+func (s *Server) runEvery(interval time.Duration, immediately bool, run func(ctx context.Context)) {
+	s.background.Add(1)
+	go func() {
+		defer s.background.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		if immediately {
+			run(s.ctx)
+		}
+		for {
+			select {
+			case <-ticker.C:
+				run(s.ctx)
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
@@ -1,12 +1,16 @@
 package server
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
 	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/auth"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
 )
 
 type ManufacturingHandler struct {
@@ -31,12 +35,12 @@ func NewManufacturingHandler(manufacturingUseCase *usecase.ManufacturingUseCase)
 func (h *ManufacturingHandler) CreateFacility(c *gin.Context) {
 	var facility entity.ManufacturingFacility
 	if err := c.ShouldBindJSON(&facility); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 
 	if err := h.manufacturingUseCase.CreateFacility(c.Request.Context(), &facility); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -54,13 +58,13 @@ func (h *ManufacturingHandler) CreateFacility(c *gin.Context) {
 func (h *ManufacturingHandler) GetFacility(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid ID format"})
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "invalid ID format"))
 		return
 	}
 
 	facility, err := h.manufacturingUseCase.GetFacility(c.Request.Context(), uint(id))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "facility not found"})
+		c.Error(entity.NewError(entity.ErrCodeNotFound, "facility not found"))
 		return
 	}
 
@@ -77,7 +81,7 @@ func (h *ManufacturingHandler) GetFacility(c *gin.Context) {
 func (h *ManufacturingHandler) ListFacilities(c *gin.Context) {
 	facilities, err := h.manufacturingUseCase.ListFacilities(c.Request.Context())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -96,12 +100,12 @@ func (h *ManufacturingHandler) ListFacilities(c *gin.Context) {
 func (h *ManufacturingHandler) CreateProductionOrder(c *gin.Context) {
 	var order entity.ProductionOrder
 	if err := c.ShouldBindJSON(&order); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 
 	if err := h.manufacturingUseCase.CreateProductionOrder(c.Request.Context(), &order); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		h.handleError(c, err)
 		return
 	}
 
@@ -119,12 +123,12 @@ func (h *ManufacturingHandler) CreateProductionOrder(c *gin.Context) {
 func (h *ManufacturingHandler) StartProduction(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid ID format"})
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "invalid ID format"))
 		return
 	}
 
 	if err := h.manufacturingUseCase.StartProduction(c.Request.Context(), uint(id)); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -132,7 +136,7 @@ func (h *ManufacturingHandler) StartProduction(c *gin.Context) {
 }
 
 // @Summary Update production progress
-// @Description Update production progress for an order
+// @Description Record the cumulative completed and defect quantities of an order. Newly completed units are received into the target store and, for backflush orders, BOM components are consumed from the source store.
 // @Tags Manufacturing
 // @Security BearerAuth
 // @Accept json
@@ -144,7 +148,7 @@ func (h *ManufacturingHandler) StartProduction(c *gin.Context) {
 func (h *ManufacturingHandler) UpdateProductionProgress(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid ID format"})
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "invalid ID format"))
 		return
 	}
 
@@ -154,26 +158,114 @@ func (h *ManufacturingHandler) UpdateProductionProgress(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&progress); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 
+	userID := auth.GetUserIDFromContext(c)
 	err = h.manufacturingUseCase.UpdateProductionProgress(
 		c.Request.Context(),
 		uint(id),
 		progress.CompletedQty,
 		progress.DefectQty,
+		userID,
 	)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		h.handleError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "progress updated"})
 }
 
+// @Summary Issue materials
+// @Description Issue components to an in-process production order from stock
+// @Tags Manufacturing
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Order ID"
+// @Param request body entity.MaterialIssueRequest true "Components to issue"
+// @Success 201 {array} entity.ProductionMaterialIssue
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /manufacturing/orders/{id}/issue [post]
+func (h *ManufacturingHandler) IssueMaterials(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "invalid ID format"))
+		return
+	}
+
+	var req entity.MaterialIssueRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	userID := auth.GetUserIDFromContext(c)
+	issues, err := h.manufacturingUseCase.IssueMaterials(c.Request.Context(), uint(id), &req, userID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, issues)
+}
+
+// @Summary List material issues
+// @Description List the components issued to a production order, backflushed or manual
+// @Tags Manufacturing
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Order ID"
+// @Success 200 {array} entity.ProductionMaterialIssue
+// @Failure 404 {object} ErrorResponse
+// @Router /manufacturing/orders/{id}/issues [get]
+func (h *ManufacturingHandler) ListMaterialIssues(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "invalid ID format"))
+		return
+	}
+
+	issues, err := h.manufacturingUseCase.ListMaterialIssues(c.Request.Context(), uint(id))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, issues)
+}
+
+// @Summary Get material variance
+// @Description Compare the components issued to a production order with the BOM standard for the units produced
+// @Tags Manufacturing
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Order ID"
+// @Success 200 {object} entity.ProductionVarianceReport
+// @Failure 404 {object} ErrorResponse
+// @Router /manufacturing/orders/{id}/variance [get]
+func (h *ManufacturingHandler) GetMaterialVariance(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "invalid ID format"))
+		return
+	}
+
+	report, err := h.manufacturingUseCase.GetMaterialVariance(c.Request.Context(), uint(id))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
 // @Summary Create BOM
-// @Description Create bill of materials for a product
+// @Description Create a version of a product's bill of materials, effective from effective_from (defaults to now). The version it follows ends where it takes effect. Components may be sub-assemblies with their own bill of materials.
 // @Tags Manufacturing
 // @Security BearerAuth
 // @Accept json
@@ -188,14 +280,416 @@ func (h *ManufacturingHandler) CreateBOM(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 
 	if err := h.manufacturingUseCase.CreateBOM(c.Request.Context(), &request.BOM, request.Items); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		h.handleError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusCreated, request.BOM)
 }
+
+// @Summary List BOMs
+// @Description List bill of materials versions, of one product if given, latest effective first
+// @Tags Manufacturing
+// @Security BearerAuth
+// @Produce json
+// @Param product_id query int false "Product ID"
+// @Success 200 {array} entity.BillOfMaterial
+// @Router /manufacturing/bom [get]
+func (h *ManufacturingHandler) ListBOMs(c *gin.Context) {
+	var productID *uint
+	if productIDStr := c.Query("product_id"); productIDStr != "" {
+		id, err := strconv.ParseUint(productIDStr, 10, 32)
+		if err != nil {
+			c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "invalid product ID"))
+			return
+		}
+		pid := uint(id)
+		productID = &pid
+	}
+
+	boms, err := h.manufacturingUseCase.ListBOMs(c.Request.Context(), productID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, boms)
+}
+
+// @Summary Get BOM
+// @Description Get a bill of materials version with its items
+// @Tags Manufacturing
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "BOM ID"
+// @Success 200 {object} entity.BillOfMaterial
+// @Failure 404 {object} ErrorResponse
+// @Router /manufacturing/bom/{id} [get]
+func (h *ManufacturingHandler) GetBOM(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "invalid ID format"))
+		return
+	}
+
+	bom, err := h.manufacturingUseCase.GetBOM(c.Request.Context(), uint(id))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, bom)
+}
+
+// @Summary Get product cost roll-up
+// @Description Compute the standard cost of a product from the bill of materials in effect on a date, rolling up sub-assemblies and adding labor and overhead
+// @Tags Manufacturing
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param date query string false "Costing date (YYYY-MM-DD), defaults to now"
+// @Param quantity query number false "Quantity to price"
+// @Success 200 {object} entity.BOMCostRollup
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /manufacturing/products/{id}/cost [get]
+func (h *ManufacturingHandler) GetProductCost(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "invalid ID format"))
+		return
+	}
+
+	var date time.Time
+	if dateStr := c.Query("date"); dateStr != "" {
+		if date, err = time.Parse("2006-01-02", dateStr); err != nil {
+			c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "invalid date format, use YYYY-MM-DD"))
+			return
+		}
+	}
+
+	var quantity float64
+	if quantityStr := c.Query("quantity"); quantityStr != "" {
+		if quantity, err = strconv.ParseFloat(quantityStr, 64); err != nil || quantity < 0 {
+			c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "invalid quantity"))
+			return
+		}
+	}
+
+	rollup, err := h.manufacturingUseCase.RollUpCost(c.Request.Context(), uint(id), date, quantity)
+	if err != nil {
+		if errors.Is(err, usecase.ErrBOMNotFound) {
+			c.Error(entity.WrapError(entity.ErrCodeNotFound, err))
+			return
+		}
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rollup)
+}
+
+// @Summary Set material cost
+// @Description Set the standard unit cost of a purchased component, used by the cost roll-up
+// @Tags Manufacturing
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Material ID"
+// @Param cost body map[string]number true "Unit cost"
+// @Success 200 {object} entity.MaterialCost
+// @Failure 400 {object} ErrorResponse
+// @Router /manufacturing/materials/{id}/cost [put]
+func (h *ManufacturingHandler) SetMaterialCost(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil || id == 0 {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "invalid ID format"))
+		return
+	}
+
+	var request struct {
+		UnitCost *float64 `json:"unit_cost" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	cost, err := h.manufacturingUseCase.SetMaterialCost(c.Request.Context(), uint(id), *request.UnitCost)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, cost)
+}
+
+// @Summary List material costs
+// @Description List the standard unit costs of purchased components
+// @Tags Manufacturing
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} entity.MaterialCost
+// @Router /manufacturing/materials/costs [get]
+func (h *ManufacturingHandler) ListMaterialCosts(c *gin.Context) {
+	costs, err := h.manufacturingUseCase.ListMaterialCosts(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, costs)
+}
+
+// @Summary Create work center
+// @Description Create a machine, line or cell in a facility that production orders are scheduled on. Its daily capacity is shifts_per_day times hours_per_shift, on weekdays unless works_weekends is set.
+// @Tags Manufacturing
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param workCenter body entity.WorkCenterRequest true "Work center details"
+// @Success 201 {object} entity.WorkCenter
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /manufacturing/work-centers [post]
+func (h *ManufacturingHandler) CreateWorkCenter(c *gin.Context) {
+	var req entity.WorkCenterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	workCenter, err := h.manufacturingUseCase.CreateWorkCenter(c.Request.Context(), &req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, workCenter)
+}
+
+// @Summary List work centers
+// @Description List work centers by code, optionally of one facility
+// @Tags Manufacturing
+// @Security BearerAuth
+// @Produce json
+// @Param facility_id query int false "Facility ID"
+// @Success 200 {array} entity.WorkCenter
+// @Router /manufacturing/work-centers [get]
+func (h *ManufacturingHandler) ListWorkCenters(c *gin.Context) {
+	var facilityID uint
+	if id, err := strconv.ParseUint(c.Query("facility_id"), 10, 32); err == nil {
+		facilityID = uint(id)
+	}
+
+	workCenters, err := h.manufacturingUseCase.ListWorkCenters(c.Request.Context(), facilityID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, workCenters)
+}
+
+// @Summary Get work center
+// @Description Get a work center by ID
+// @Tags Manufacturing
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Work center ID"
+// @Success 200 {object} entity.WorkCenter
+// @Failure 404 {object} ErrorResponse
+// @Router /manufacturing/work-centers/{id} [get]
+func (h *ManufacturingHandler) GetWorkCenter(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "invalid ID format"))
+		return
+	}
+
+	workCenter, err := h.manufacturingUseCase.GetWorkCenter(c.Request.Context(), uint(id))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, workCenter)
+}
+
+// @Summary Update work center
+// @Description Update a work center. The load of orders already scheduled on it is recomputed against its new capacity.
+// @Tags Manufacturing
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Work center ID"
+// @Param workCenter body entity.WorkCenterRequest true "Work center details"
+// @Success 200 {object} entity.WorkCenter
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /manufacturing/work-centers/{id} [put]
+func (h *ManufacturingHandler) UpdateWorkCenter(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "invalid ID format"))
+		return
+	}
+
+	var req entity.WorkCenterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	workCenter, err := h.manufacturingUseCase.UpdateWorkCenter(c.Request.Context(), uint(id), &req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, workCenter)
+}
+
+// @Summary Get production schedule
+// @Description Gantt-style feed of the open production orders scheduled on active work centers in a period, with each work center's daily load against its capacity. Days loaded beyond capacity, and the orders running on them, are flagged as overloaded; orders ending after their deadline are flagged as late.
+// @Tags Manufacturing
+// @Security BearerAuth
+// @Produce json
+// @Param facility_id query int false "Facility ID"
+// @Param work_center_id query int false "Work center ID"
+// @Param start_date query string false "Start date (YYYY-MM-DD), defaults to today"
+// @Param end_date query string false "End date (YYYY-MM-DD), defaults to four weeks from the start"
+// @Success 200 {object} entity.ProductionSchedule
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /manufacturing/schedule [get]
+func (h *ManufacturingHandler) GetSchedule(c *gin.Context) {
+	filter := &entity.ScheduleFilter{}
+	if id, err := strconv.ParseUint(c.Query("facility_id"), 10, 32); err == nil {
+		filter.FacilityID = uint(id)
+	}
+	if id, err := strconv.ParseUint(c.Query("work_center_id"), 10, 32); err == nil {
+		filter.WorkCenterID = uint(id)
+	}
+
+	var err error
+	if startDateStr := c.Query("start_date"); startDateStr != "" {
+		if filter.StartDate, err = time.Parse("2006-01-02", startDateStr); err != nil {
+			c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "invalid start_date format, use YYYY-MM-DD"))
+			return
+		}
+	}
+	if endDateStr := c.Query("end_date"); endDateStr != "" {
+		if filter.EndDate, err = time.Parse("2006-01-02", endDateStr); err != nil {
+			c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "invalid end_date format, use YYYY-MM-DD"))
+			return
+		}
+	}
+
+	schedule, err := h.manufacturingUseCase.GetSchedule(c.Request.Context(), filter)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, schedule)
+}
+
+// @Summary Schedule production order
+// @Description Schedule a production order on a work center, or reschedule it to another work center or start date. The order uses the work center's full daily capacity from its start until its planned hours, by default the BOM labor hours of the remaining quantity, are used up. Without a start date it is queued after the work center's last scheduled order. Overloaded days of the work center while the order runs are returned.
+// @Tags Manufacturing
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Order ID"
+// @Param schedule body entity.ScheduleRequest true "Work center, start date and planned hours"
+// @Success 200 {object} entity.ScheduleResult
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /manufacturing/orders/{id}/schedule [put]
+func (h *ManufacturingHandler) ScheduleOrder(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "invalid ID format"))
+		return
+	}
+
+	var req entity.ScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	result, err := h.manufacturingUseCase.ScheduleOrder(c.Request.Context(), uint(id), &req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// @Summary Unschedule production order
+// @Description Take a production order off its work center
+// @Tags Manufacturing
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Order ID"
+// @Success 200 {object} entity.ProductionOrder
+// @Failure 404 {object} ErrorResponse
+// @Router /manufacturing/orders/{id}/schedule [delete]
+func (h *ManufacturingHandler) UnscheduleOrder(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "invalid ID format"))
+		return
+	}
+
+	order, err := h.manufacturingUseCase.UnscheduleOrder(c.Request.Context(), uint(id))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, order)
+}
+
+// handleError maps manufacturing errors to HTTP responses
+func (h *ManufacturingHandler) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repository.ErrRecordNotFound):
+		c.Error(entity.WrapError(entity.ErrCodeNotFound, err))
+	case errors.Is(err, usecase.ErrInvalidIssueMode),
+		errors.Is(err, usecase.ErrInvalidBOM),
+		errors.Is(err, usecase.ErrInvalidBOMPeriod),
+		errors.Is(err, usecase.ErrInvalidMaterialCost),
+		errors.Is(err, usecase.ErrTargetStoreRequired),
+		errors.Is(err, usecase.ErrSourceStoreRequired),
+		errors.Is(err, usecase.ErrProgressDecrease),
+		errors.Is(err, usecase.ErrWorkCenterCapacity),
+		errors.Is(err, usecase.ErrWorkCenterFacility),
+		errors.Is(err, usecase.ErrWorkCenterMismatch),
+		errors.Is(err, usecase.ErrPlannedHoursRequired),
+		errors.Is(err, usecase.ErrScheduleTooLong),
+		errors.Is(err, usecase.ErrInvalidScheduleDate),
+		errors.Is(err, usecase.ErrInvalidSchedulePeriod):
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+	case errors.Is(err, usecase.ErrBOMNotFound),
+		errors.Is(err, usecase.ErrInsufficientMaterial),
+		errors.Is(err, usecase.ErrBOMVersionExists),
+		errors.Is(err, usecase.ErrBOMCycle),
+		errors.Is(err, usecase.ErrWorkCenterCodeExists),
+		errors.Is(err, usecase.ErrWorkCenterInactive),
+		errors.Is(err, usecase.ErrOrderNotSchedulable):
+		c.Error(entity.WrapError(entity.ErrCodeConflict, err))
+	default:
+		c.Error(err)
+	}
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/server/middleware"
+)
+
+// MarginHandlers handles gross margin report HTTP requests
+type MarginHandlers struct {
+	marginUseCase *usecase.MarginUseCase
+}
+
+// NewMarginHandlers creates a new margin handlers instance
+func NewMarginHandlers(marginUseCase *usecase.MarginUseCase) *MarginHandlers {
+	return &MarginHandlers{
+		marginUseCase: marginUseCase,
+	}
+}
+
+// RegisterRoutes registers gross margin report routes
+func (h *MarginHandlers) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("/reports/margin", middleware.PermissionMiddleware(entity.ReportRead), h.GetMarginReport)
+	router.GET("/reports/margin/orders", middleware.PermissionMiddleware(entity.ReportRead), h.GetMarginOrders)
+}
+
+// GetMarginReport handles the gross margin report
+// @Summary Get gross margin report
+// @Description Break down the revenue, cost of goods and gross margin of the sales orders placed in a period by product, category, customer, salesperson, warehouse or month, highest margin first, in the base currency. Each row links to the orders behind it.
+// @Tags Reports
+// @Security BearerAuth
+// @Produce json
+// @Param group_by query string false "Dimension: product (default), category, customer, salesperson, warehouse or month"
+// @Param start_date query string false "Period start (YYYY-MM-DD), defaults to a month before the end"
+// @Param end_date query string false "Period end (YYYY-MM-DD), defaults to now"
+// @Success 200 {object} entity.MarginReport
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /reports/margin [get]
+func (h *MarginHandlers) GetMarginReport(c *gin.Context) {
+	filter := marginFilter(c)
+
+	report, err := h.marginUseCase.Report(c.Request.Context(), filter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	for i := range report.Rows {
+		report.Rows[i].OrdersURL = marginOrdersURL(report, report.Rows[i].Key)
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// GetMarginOrders handles drilling down into a group of the gross margin report
+// @Summary Get gross margin report orders
+// @Description List the sales orders behind a row of the gross margin report, with the part of each falling in it, latest first
+// @Tags Reports
+// @Security BearerAuth
+// @Produce json
+// @Param group_by query string false "Dimension: product (default), category, customer, salesperson, warehouse or month"
+// @Param key query string false "Key of the row"
+// @Param start_date query string false "Period start (YYYY-MM-DD), defaults to a month before the end"
+// @Param end_date query string false "Period end (YYYY-MM-DD), defaults to now"
+// @Success 200 {array} entity.MarginOrder
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /reports/margin/orders [get]
+func (h *MarginHandlers) GetMarginOrders(c *gin.Context) {
+	filter := marginFilter(c)
+	filter.Key = c.Query("key")
+
+	orders, err := h.marginUseCase.Orders(c.Request.Context(), filter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	for i := range orders {
+		orders[i].OrderURL = "/api/v1/orders/" + orders[i].OrderID
+	}
+	c.JSON(http.StatusOK, orders)
+}
+
+// marginFilter reads the dimension and period of the gross margin report
+func marginFilter(c *gin.Context) *entity.MarginFilter {
+	filter := &entity.MarginFilter{GroupBy: entity.MarginDimension(c.Query("group_by"))}
+	if startDate, err := time.Parse("2006-01-02", c.Query("start_date")); err == nil {
+		filter.StartDate = &startDate
+	}
+	if endDate, err := time.Parse("2006-01-02", c.Query("end_date")); err == nil {
+		endDate = endDate.Add(24*time.Hour - time.Nanosecond)
+		filter.EndDate = &endDate
+	}
+	return filter
+}
+
+// marginOrdersURL links a row of the gross margin report to its orders, over
+// the period of the report
+func marginOrdersURL(report *entity.MarginReport, key string) string {
+	query := url.Values{}
+	query.Set("group_by", string(report.GroupBy))
+	query.Set("key", key)
+	query.Set("start_date", report.StartDate.Format("2006-01-02"))
+	query.Set("end_date", report.EndDate.Format("2006-01-02"))
+	return "/api/v1/reports/margin/orders?" + query.Encode()
+}
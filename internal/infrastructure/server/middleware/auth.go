@@ -1,32 +1,64 @@
 package middleware
 
 import (
-	"net/http"
+	"context"
 
 	"github.com/gin-gonic/gin"
 	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
 	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/auth"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/logging"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/service"
 )
 
-func AuthMiddleware(authService *auth.JWTService) gin.HandlerFunc {
+// APIKeyHeader carries the API key of machine-to-machine requests, in place
+// of the Authorization header
+const APIKeyHeader = "X-API-Key"
+
+// APIKeyAuthenticator authenticates API keys
+type APIKeyAuthenticator interface {
+	Authenticate(ctx context.Context, secret, ip string) (*entity.APIKey, error)
+}
+
+// ImpersonationChecker tells whether an impersonation session can still be
+// acted in
+type ImpersonationChecker interface {
+	SessionActive(ctx context.Context, id uint) (bool, error)
+}
+
+// AuthMiddleware authenticates requests with a JWT access token, or with an
+// API key in the X-API-Key header when apiKeys is set. Impersonation tokens
+// are refused once their session has ended, or when impersonations is nil.
+func AuthMiddleware(authService *auth.JWTService, apiKeys APIKeyAuthenticator, impersonations ImpersonationChecker) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if secret := c.GetHeader(APIKeyHeader); secret != "" && apiKeys != nil {
+			authenticateAPIKey(c, apiKeys, secret)
+			return
+		}
+
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header is required"})
+			c.Error(entity.NewError(entity.ErrCodeUnauthenticated, "Authorization header is required"))
 			c.Abort()
 			return
 		}
 
 		tokenString := auth.ExtractTokenFromHeader(authHeader)
 		if tokenString == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token format"})
+			c.Error(entity.NewError(entity.ErrCodeUnauthenticated, "Invalid token format"))
 			c.Abort()
 			return
 		}
 
 		claims, err := authService.ValidateAccessToken(tokenString)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			c.Error(entity.NewError(entity.ErrCodeUnauthenticated, "Invalid token"))
+			c.Abort()
+			return
+		}
+
+		// Portal tokens are scoped to the customer portal and cannot reach staff routes
+		if claims.TokenType == auth.TokenTypePortal {
+			c.Error(entity.NewError(entity.ErrCodePermissionDenied, "Portal token cannot access this resource"))
 			c.Abort()
 			return
 		}
@@ -36,6 +68,127 @@ func AuthMiddleware(authService *auth.JWTService) gin.HandlerFunc {
 		c.Set("username", claims.Username)
 		c.Set("role", claims.Role)
 		c.Set("permissions", claims.Permissions)
+		ctx := logging.With(c.Request.Context(), "user_id", claims.UserID)
+		if claims.ImpersonationID != 0 {
+			if impersonations == nil {
+				c.Error(entity.NewError(entity.ErrCodeUnauthenticated, "Impersonation is not available"))
+				c.Abort()
+				return
+			}
+			active, err := impersonations.SessionActive(c.Request.Context(), claims.ImpersonationID)
+			if err != nil {
+				c.Error(err)
+				c.Abort()
+				return
+			}
+			if !active {
+				c.Error(entity.NewError(entity.ErrCodeUnauthenticated, "Impersonation session has ended"))
+				c.Abort()
+				return
+			}
+			c.Set(auth.ImpersonatorIDKey, claims.ImpersonatorID)
+			c.Set(auth.ImpersonationIDKey, claims.ImpersonationID)
+			ctx = logging.With(ctx, "impersonator_id", claims.ImpersonatorID)
+		}
+		ctx = entity.WithActor(ctx, claims.UserID)
+		// Limit the use cases to the stores and departments of the user's role
+		if claims.RestrictsStores() || claims.RestrictsDepartments() {
+			ctx = entity.WithAccessScope(ctx, claims.AccessScope)
+		}
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+	}
+}
+
+// authenticateAPIKey lets a request act on behalf of the creator of its API
+// key, with the key's permissions and access scope
+func authenticateAPIKey(c *gin.Context, apiKeys APIKeyAuthenticator, secret string) {
+	key, err := apiKeys.Authenticate(c.Request.Context(), secret, c.ClientIP())
+	if err != nil {
+		c.Error(err)
+		c.Abort()
+		return
+	}
+
+	c.Set("user_id", key.CreatedBy)
+	c.Set("username", "apikey:"+key.Name)
+	c.Set("role", "api_key")
+	c.Set("permissions", []entity.Permission(key.Permissions))
+	c.Set(APIKeyIDKey, key.ID)
+	ctx := logging.With(c.Request.Context(), "user_id", key.CreatedBy, "api_key_id", key.ID)
+	ctx = entity.WithActor(ctx, key.CreatedBy)
+	if scope := key.AccessScope(); scope.RestrictsStores() || scope.RestrictsDepartments() {
+		ctx = entity.WithAccessScope(ctx, scope)
+	}
+	c.Request = c.Request.WithContext(ctx)
+
+	c.Next()
+}
+
+// APIKeyIDKey holds the ID of the API key a request was authenticated with
+const APIKeyIDKey = "api_key_id"
+
+// IsAPIKeyRequest reports whether a request was authenticated with an API key
+func IsAPIKeyRequest(c *gin.Context) bool {
+	_, ok := c.Get(APIKeyIDKey)
+	return ok
+}
+
+// DenyAPIKeyMiddleware keeps requests authenticated with an API key from
+// routes that only make sense for a person, such as logging out
+func DenyAPIKeyMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if IsAPIKeyRequest(c) {
+			c.Error(entity.NewError(entity.ErrCodePermissionDenied, "API keys cannot access this resource"))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// IsImpersonatedRequest reports whether a request was made by an admin
+// impersonating its user
+func IsImpersonatedRequest(c *gin.Context) bool {
+	_, ok := c.Get(auth.ImpersonationIDKey)
+	return ok
+}
+
+// DenyImpersonationMiddleware keeps impersonated requests from routes that
+// would let the admin act as the user beyond the session, such as creating
+// API keys or requesting elevated access
+func DenyImpersonationMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if IsImpersonatedRequest(c) {
+			c.Error(entity.NewError(entity.ErrCodePermissionDenied, "Impersonation sessions cannot access this resource"))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// PortalAuthMiddleware authenticates customer portal tokens and sets the client ID in context
+func PortalAuthMiddleware(authService *auth.JWTService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tokenString := auth.ExtractTokenFromHeader(c.GetHeader("Authorization"))
+		if tokenString == "" {
+			c.Error(entity.NewError(entity.ErrCodeUnauthenticated, "Authorization header is required"))
+			c.Abort()
+			return
+		}
+
+		claims, err := authService.ValidateAccessToken(tokenString)
+		if err != nil || claims.TokenType != auth.TokenTypePortal || claims.ClientID == 0 {
+			c.Error(entity.NewError(entity.ErrCodeUnauthenticated, "Invalid portal token"))
+			c.Abort()
+			return
+		}
+
+		c.Set("client_id", claims.ClientID)
+		c.Set("username", claims.Username)
+		c.Request = c.Request.WithContext(logging.With(c.Request.Context(), "client_id", claims.ClientID))
 
 		c.Next()
 	}
@@ -45,7 +198,7 @@ func RoleMiddleware(roles ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		role, exists := c.Get("role")
 		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Role not found in context"})
+			c.Error(entity.NewError(entity.ErrCodeUnauthenticated, "Role not found in context"))
 			c.Abort()
 			return
 		}
@@ -59,7 +212,7 @@ func RoleMiddleware(roles ...string) gin.HandlerFunc {
 		}
 
 		if !hasRole {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+			c.Error(entity.NewError(entity.ErrCodePermissionDenied, "Insufficient permissions"))
 			c.Abort()
 			return
 		}
@@ -72,7 +225,7 @@ func PermissionMiddleware(requiredPermission entity.Permission) gin.HandlerFunc
 	return func(c *gin.Context) {
 		permissions, exists := c.Get("permissions")
 		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Permissions not found in context"})
+			c.Error(entity.NewError(entity.ErrCodeUnauthenticated, "Permissions not found in context"))
 			c.Abort()
 			return
 		}
@@ -86,11 +239,54 @@ func PermissionMiddleware(requiredPermission entity.Permission) gin.HandlerFunc
 		}
 
 		if !hasPermission {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+			c.Error(entity.NewError(entity.ErrCodePermissionDenied, "Insufficient permissions"))
 			c.Abort()
 			return
 		}
 
+		markElevatedUse(c, requiredPermission)
 		c.Next()
 	}
 }
+
+// ApproverResolver finds the approver away a user approves for under an
+// approval delegation
+type ApproverResolver interface {
+	ResolveApprover(ctx context.Context, userID uint, permission entity.Permission) (*uint, error)
+}
+
+// ApprovalMiddleware lets through users holding an approval permission, as
+// PermissionMiddleware does, and the delegates of approvers away holding it,
+// whose request is recorded as acting on the approver's behalf
+func ApprovalMiddleware(requiredPermission entity.Permission, approvers ApproverResolver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if hasPermission(c, requiredPermission) {
+			markElevatedUse(c, requiredPermission)
+			c.Next()
+			return
+		}
+
+		userID, exists := c.Get("user_id")
+		if !exists {
+			c.Error(entity.NewError(entity.ErrCodeUnauthenticated, "User not found in context"))
+			c.Abort()
+			return
+		}
+		uid, _ := userID.(uint)
+
+		onBehalfOf, err := approvers.ResolveApprover(c.Request.Context(), uid, requiredPermission)
+		if err != nil {
+			c.Error(err)
+			c.Abort()
+			return
+		}
+		c.Set(service.OnBehalfOfKey, *onBehalfOf)
+		c.Next()
+	}
+}
+
+// HasPermission reports whether the authenticated user holds a permission,
+// including through elevated access
+func HasPermission(c *gin.Context, permission entity.Permission) bool {
+	return hasPermission(c, permission)
+}
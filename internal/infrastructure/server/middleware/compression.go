@@ -0,0 +1,171 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+var gzipWriters = sync.Pool{
+	New: func() interface{} {
+		return gzip.NewWriter(nil)
+	},
+}
+
+// compressWriter holds back the start of the response body until it is
+// large enough to be worth compressing, then gzips the rest as it is written
+type compressWriter struct {
+	gin.ResponseWriter
+	minSize int
+	buf     []byte
+	gz      *gzip.Writer
+	raw     bool // the body is sent as written
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	switch {
+	case w.gz != nil:
+		return w.gz.Write(b)
+	case w.raw:
+		return w.ResponseWriter.Write(b)
+	}
+	w.buf = append(w.buf, b...)
+	if len(w.buf) >= w.minSize {
+		if err := w.start(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *compressWriter) Written() bool {
+	return len(w.buf) > 0 || w.ResponseWriter.Written()
+}
+
+func (w *compressWriter) Flush() {
+	if w.gz == nil && !w.raw {
+		w.start(len(w.buf) >= w.minSize)
+	}
+	if w.gz != nil {
+		w.gz.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// start sends the held back body, gzipped when compress is set and the
+// response is of a type that compresses
+func (w *compressWriter) start(compress bool) error {
+	buf := w.buf
+	w.buf = nil
+	header := w.Header()
+	if !compress || header.Get("Content-Encoding") != "" || !compressible(header.Get("Content-Type")) ||
+		w.Status() < http.StatusOK || w.Status() == http.StatusNoContent || w.Status() == http.StatusNotModified {
+		w.raw = true
+		_, err := w.ResponseWriter.Write(buf)
+		return err
+	}
+
+	header.Set("Content-Encoding", "gzip")
+	header.Del("Content-Length")
+	w.gz = gzipWriters.Get().(*gzip.Writer)
+	w.gz.Reset(w.ResponseWriter)
+	_, err := w.gz.Write(buf)
+	return err
+}
+
+// finish sends what is still held back and ends the gzip stream. Write
+// errors mean the client went away, so they are dropped.
+func (w *compressWriter) finish() {
+	if w.gz == nil {
+		if !w.raw && len(w.buf) > 0 {
+			w.start(false)
+		}
+		return
+	}
+	w.gz.Close()
+	gzipWriters.Put(w.gz)
+	w.gz = nil
+}
+
+// CompressionMiddleware gzips the responses of clients accepting it whose body
+// is at least minSize bytes of JSON, text, CSV or XML. Smaller responses, and
+// files that are compressed already, are sent as they are. A minSize of 0
+// turns compression off.
+func CompressionMiddleware(minSize int) gin.HandlerFunc {
+	if minSize <= 0 {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+
+	return func(c *gin.Context) {
+		c.Writer.Header().Add("Vary", "Accept-Encoding")
+		if c.Request.Method == http.MethodHead || !acceptsGzip(c.GetHeader("Accept-Encoding")) {
+			c.Next()
+			return
+		}
+
+		writer := c.Writer
+		compress := &compressWriter{ResponseWriter: writer, minSize: minSize}
+		c.Writer = compress
+		c.Next()
+		WriteError(c)
+		c.Writer = writer
+
+		compress.finish()
+	}
+}
+
+// acceptsGzip reports whether an Accept-Encoding header allows gzip
+func acceptsGzip(header string) bool {
+	gzipQ, anyQ := -1.0, -1.0
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(part, ";")
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		switch strings.ToLower(strings.TrimSpace(coding)) {
+		case "gzip", "x-gzip":
+			gzipQ = q
+		case "*":
+			anyQ = q
+		}
+	}
+	if gzipQ >= 0 {
+		return gzipQ > 0
+	}
+	return anyQ > 0
+}
+
+// compressible reports whether a content type is text that gzip shrinks
+func compressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch {
+	case strings.HasPrefix(mediaType, "text/"),
+		strings.HasSuffix(mediaType, "+json"),
+		strings.HasSuffix(mediaType, "+xml"):
+		return true
+	}
+	switch mediaType {
+	case "application/json", "application/xml", "application/javascript", "application/x-ndjson":
+		return true
+	}
+	return false
+}
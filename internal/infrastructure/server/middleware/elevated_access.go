@@ -0,0 +1,135 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/logging"
+)
+
+const (
+	// elevatedPermissionsKey holds the permissions a request only has through
+	// elevated access, mapped to the grant giving each
+	elevatedPermissionsKey = "elevated_permissions"
+	// elevatedUseKey holds the elevated permission a request was authorized by
+	elevatedUseKey = "elevated_use"
+)
+
+// ElevationSource provides a user's active elevated access grants and records the
+// requests made through them
+type ElevationSource interface {
+	ActiveGrants(ctx context.Context, userID uint) ([]entity.ElevatedAccessGrant, error)
+	RecordActions(ctx context.Context, actions []entity.ElevatedAction) error
+}
+
+type elevatedUse struct {
+	grantID    uint
+	permission entity.Permission
+}
+
+// ElevatedAccessMiddleware adds the permissions of the user's active elevated
+// access grants to the request, and records every request authorized by one of
+// them once it completes. Must run after AuthMiddleware.
+func ElevatedAccessMiddleware(source ElevationSource) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := c.Get("user_id")
+		if !ok || IsAPIKeyRequest(c) {
+			c.Next()
+			return
+		}
+		uid, _ := userID.(uint)
+
+		// Later middleware may swap the request context, e.g. for the sandbox
+		ctx := c.Request.Context()
+		grants, err := source.ActiveGrants(ctx, uid)
+		if err != nil {
+			logging.FromContext(ctx).Error("error loading elevated access grants", "error", err)
+			c.Next()
+			return
+		}
+		if len(grants) == 0 {
+			c.Next()
+			return
+		}
+
+		var permissions []entity.Permission
+		if value, exists := c.Get("permissions"); exists {
+			permissions = value.([]entity.Permission)
+		}
+		held := make(map[entity.Permission]bool, len(permissions))
+		for _, p := range permissions {
+			held[p] = true
+		}
+
+		elevated := make(map[entity.Permission]uint)
+		merged := append([]entity.Permission{}, permissions...)
+		for _, grant := range grants {
+			for _, p := range grant.Permissions {
+				if held[p] {
+					continue
+				}
+				held[p] = true
+				elevated[p] = grant.ID
+				merged = append(merged, p)
+			}
+		}
+		c.Set("permissions", merged)
+		c.Set(elevatedPermissionsKey, elevated)
+
+		c.Next()
+		WriteError(c)
+
+		value, used := c.Get(elevatedUseKey)
+		if !used {
+			return
+		}
+		use := value.(elevatedUse)
+		action := entity.ElevatedAction{
+			GrantID:    use.grantID,
+			UserID:     uid,
+			Permission: use.permission,
+			Method:     c.Request.Method,
+			Path:       c.Request.URL.Path,
+			StatusCode: c.Writer.Status(),
+			IP:         c.ClientIP(),
+			CreatedAt:  time.Now(),
+		}
+		// The request context may be done once the handler returns
+		if err := source.RecordActions(context.WithoutCancel(ctx), []entity.ElevatedAction{action}); err != nil {
+			logging.FromContext(ctx).Error("error recording elevated action", "grant_id", use.grantID, "error", err)
+		}
+	}
+}
+
+// markElevatedUse notes that the request was authorized by an elevated permission
+func markElevatedUse(c *gin.Context, permission entity.Permission) {
+	value, exists := c.Get(elevatedPermissionsKey)
+	if !exists {
+		return
+	}
+	if grantID, ok := value.(map[entity.Permission]uint)[permission]; ok {
+		c.Set(elevatedUseKey, elevatedUse{grantID: grantID, permission: permission})
+	}
+}
+
+// RolePermissions returns the permissions the authenticated user holds
+// through their role, leaving out those of elevated access grants
+func RolePermissions(c *gin.Context) []entity.Permission {
+	value, exists := c.Get("permissions")
+	if !exists {
+		return nil
+	}
+	permissions := value.([]entity.Permission)
+	elevated, _ := c.Get(elevatedPermissionsKey)
+	grants, _ := elevated.(map[entity.Permission]uint)
+
+	held := make([]entity.Permission, 0, len(permissions))
+	for _, p := range permissions {
+		if _, ok := grants[p]; !ok {
+			held = append(held, p)
+		}
+	}
+	return held
+}
@@ -0,0 +1,172 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/logging"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/tracing"
+)
+
+// RequestIDHeader carries the trace ID of a request. A client may send its own
+// to follow the request through the logs; the response always carries it.
+// When tracing is enabled the trace ID is the OpenTelemetry one, for finding
+// the trace of a failed request.
+const RequestIDHeader = "X-Request-ID"
+
+const (
+	traceIDKey         = "trace_id"
+	maxRequestIDLength = 128
+)
+
+// errorStatus maps error codes to HTTP statuses. Codes missing here answer 500.
+var errorStatus = map[entity.ErrorCode]int{
+	entity.ErrCodeInvalidArgument:      http.StatusBadRequest,
+	entity.ErrCodeUnauthenticated:      http.StatusUnauthorized,
+	entity.ErrCodePermissionDenied:     http.StatusForbidden,
+	entity.ErrCodeNotFound:             http.StatusNotFound,
+	entity.ErrCodeConflict:             http.StatusConflict,
+	entity.ErrCodeFailedPrecondition:   http.StatusUnprocessableEntity,
+	entity.ErrCodeResourceExhausted:    http.StatusTooManyRequests,
+	entity.ErrCodeGone:                 http.StatusGone,
+	entity.ErrCodePayloadTooLarge:      http.StatusRequestEntityTooLarge,
+	entity.ErrCodeUnsupportedMediaType: http.StatusUnsupportedMediaType,
+	entity.ErrCodeTimeout:              http.StatusGatewayTimeout,
+	entity.ErrCodeUnavailable:          http.StatusServiceUnavailable,
+	entity.ErrCodeInternal:             http.StatusInternalServerError,
+}
+
+// errorBody is the response to a failed request
+type errorBody struct {
+	Error   string              `json:"error"`
+	Code    entity.ErrorCode    `json:"code"`
+	Fields  []entity.FieldError `json:"fields,omitempty"`
+	TraceID string              `json:"trace_id"`
+}
+
+// ErrorMiddleware gives every request a trace ID and answers the error a
+// handler or middleware added with c.Error. The status comes from the code of
+// the entity.Error in the error's chain; any other error is logged with the
+// trace ID and answered as a 500 without its message, which may hold SQL or
+// other internals.
+func ErrorMiddleware() gin.HandlerFunc {
+	// Name invalid fields by their JSON names
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+			if name == "-" || name == "" {
+				return field.Name
+			}
+			return name
+		})
+	}
+
+	return func(c *gin.Context) {
+		traceID := tracing.TraceIDFromContext(c.Request.Context())
+		if traceID == "" {
+			traceID = c.GetHeader(RequestIDHeader)
+		}
+		if traceID == "" || len(traceID) > maxRequestIDLength {
+			traceID = uuid.NewString()
+		}
+		c.Set(traceIDKey, traceID)
+		c.Header(RequestIDHeader, traceID)
+
+		c.Next()
+
+		WriteError(c)
+	}
+}
+
+// TraceID returns the trace ID ErrorMiddleware gave the request
+func TraceID(c *gin.Context) string {
+	return c.GetString(traceIDKey)
+}
+
+// WriteError answers the last error added to the request with c.Error, unless
+// a response was written already. Middleware reading the response after
+// c.Next calls it first, as ErrorMiddleware only runs once they return.
+func WriteError(c *gin.Context) {
+	if len(c.Errors) == 0 || c.Writer.Written() {
+		return
+	}
+	err := c.Errors.Last().Err
+	coded := codedError(err)
+	status, ok := errorStatus[coded.Code]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+	if status >= http.StatusInternalServerError {
+		logging.FromContext(c.Request.Context()).Error("request failed", "error", err)
+	}
+	c.AbortWithStatusJSON(status, errorBody{
+		Error:   coded.Message,
+		Code:    coded.Code,
+		Fields:  coded.Fields,
+		TraceID: TraceID(c),
+	})
+}
+
+// codedError returns the entity.Error to answer err with. Binding errors
+// become field errors.
+func codedError(err error) *entity.Error {
+	var invalid validator.ValidationErrors
+	if errors.As(err, &invalid) {
+		fields := make([]entity.FieldError, 0, len(invalid))
+		for _, fe := range invalid {
+			fields = append(fields, entity.FieldError{Field: fe.Field(), Message: validationMessage(fe)})
+		}
+		return &entity.Error{Code: entity.ErrCodeInvalidArgument, Message: "Invalid request", Fields: fields, Err: err}
+	}
+	var mistyped *json.UnmarshalTypeError
+	if errors.As(err, &mistyped) {
+		return &entity.Error{
+			Code:    entity.ErrCodeInvalidArgument,
+			Message: "Invalid request",
+			Fields:  []entity.FieldError{{Field: mistyped.Field, Message: "must be of type " + mistyped.Type.String()}},
+			Err:     err,
+		}
+	}
+
+	var coded *entity.Error
+	if errors.As(err, &coded) {
+		return coded
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return entity.NewError(entity.ErrCodeTimeout, "The request took too long")
+	}
+	return entity.NewError(entity.ErrCodeInternal, "Internal server error")
+}
+
+// validationMessage describes a failed binding rule
+func validationMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be an email address"
+	case "min", "gte":
+		return "must be at least " + fe.Param()
+	case "max", "lte":
+		return "must be at most " + fe.Param()
+	case "gt":
+		return "must be greater than " + fe.Param()
+	case "lt":
+		return "must be less than " + fe.Param()
+	case "oneof":
+		return "must be one of " + fe.Param()
+	}
+	if fe.Param() != "" {
+		return "must satisfy " + fe.Tag() + "=" + fe.Param()
+	}
+	return "must satisfy " + fe.Tag()
+}
@@ -0,0 +1,147 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxETagBody is the largest body tagged; larger responses are sent as they
+// are written rather than held in memory to be hashed
+const maxETagBody = 1 << 20
+
+// etagWriter holds back a JSON response body to hash it. Other responses,
+// downloads, flushed streams and bodies over maxETagBody are passed through as
+// they are written.
+type etagWriter struct {
+	gin.ResponseWriter
+	body    bytes.Buffer
+	decided bool
+	raw     bool // the body is sent as written
+}
+
+func (w *etagWriter) Write(b []byte) (int, error) {
+	if !w.decided {
+		w.decided = true
+		w.raw = !taggable(w.Status(), w.Header())
+	}
+	if !w.raw && w.body.Len()+len(b) > maxETagBody {
+		if err := w.passThrough(); err != nil {
+			return 0, err
+		}
+	}
+	if w.raw {
+		return w.ResponseWriter.Write(b)
+	}
+	return w.body.Write(b)
+}
+
+func (w *etagWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *etagWriter) Written() bool {
+	return w.body.Len() > 0 || w.ResponseWriter.Written()
+}
+
+// Flush sends what is held back, as a handler flushing streams its response
+func (w *etagWriter) Flush() {
+	w.decided = true
+	if !w.raw {
+		w.passThrough()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// passThrough sends the held back body and the rest as it is written
+func (w *etagWriter) passThrough() error {
+	w.raw = true
+	if w.body.Len() == 0 {
+		return nil
+	}
+	_, err := w.ResponseWriter.Write(w.body.Bytes())
+	w.body = bytes.Buffer{}
+	return err
+}
+
+// taggable reports whether a response is worth tagging: a successful JSON
+// response, not a download, of a known size no larger than maxETagBody
+func taggable(status int, header http.Header) bool {
+	if status != http.StatusOK || header.Get("ETag") != "" {
+		return false
+	}
+	if disposition, _, _ := mime.ParseMediaType(header.Get("Content-Disposition")); disposition == "attachment" {
+		return false
+	}
+	if length, err := strconv.ParseInt(header.Get("Content-Length"), 10, 64); err == nil && length > maxETagBody {
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(header.Get("Content-Type"))
+	return err == nil && (mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"))
+}
+
+// ETagMiddleware tags successful GET responses of JSON with a hash of their
+// body in the ETag header. A request whose If-None-Match holds the tag is
+// answered 304 without a body, so clients polling a list, report or dashboard
+// only download it again once it changed. The tag is weak, as the same body
+// is sent gzipped or not. Responses are marked private, as they depend on the
+// user. Exports, streams and bodies over maxETagBody are not held in memory,
+// so they are sent untagged.
+func ETagMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+			c.Next()
+			return
+		}
+
+		writer := c.Writer
+		buffer := &etagWriter{ResponseWriter: writer}
+		c.Writer = buffer
+		c.Next()
+		WriteError(c)
+		c.Writer = writer
+
+		header := writer.Header()
+		if buffer.raw || writer.Written() || !taggable(writer.Status(), header) {
+			writer.Write(buffer.body.Bytes())
+			return
+		}
+
+		sum := sha256.Sum256(buffer.body.Bytes())
+		etag := `W/"` + hex.EncodeToString(sum[:16]) + `"`
+		header.Set("ETag", etag)
+		if header.Get("Cache-Control") == "" {
+			header.Set("Cache-Control", "private, no-cache")
+		}
+		if etagMatches(c.GetHeader("If-None-Match"), etag) {
+			header.Del("Content-Type")
+			header.Del("Content-Length")
+			writer.WriteHeader(http.StatusNotModified)
+			writer.WriteHeaderNow()
+			return
+		}
+		writer.Write(buffer.body.Bytes())
+	}
+}
+
+// etagMatches reports whether an If-None-Match header holds etag, comparing
+// the tags weakly
+func etagMatches(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
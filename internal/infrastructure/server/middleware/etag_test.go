@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newETagRouter(handler gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/", ETagMiddleware(), handler)
+	return router
+}
+
+func TestETagJSON(t *testing.T) {
+	router := newETagRouter(func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"items": []int{1, 2, 3}})
+	})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	etag := rec.Header().Get("ETag")
+	if rec.Code != http.StatusOK || !strings.HasPrefix(etag, `W/"`) || rec.Body.String() != `{"items":[1,2,3]}` {
+		t.Fatalf("got %d %q %q, want a tagged body", rec.Code, etag, rec.Body.String())
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Errorf("got %d with %d bytes, want 304 without a body", rec.Code, rec.Body.Len())
+	}
+}
+
+func TestETagPassesThrough(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler gin.HandlerFunc
+	}{
+		{"download", func(c *gin.Context) {
+			c.Header("Content-Disposition", `attachment; filename="stock.json"`)
+			c.Header("Content-Type", "application/json")
+			c.Writer.Write([]byte(`{"a":1}`))
+		}},
+		{"csv", func(c *gin.Context) {
+			c.Header("Content-Type", "text/csv")
+			c.Writer.Write([]byte("a,b\n"))
+		}},
+		{"large json", func(c *gin.Context) {
+			c.Header("Content-Type", "application/json")
+			c.Writer.Write([]byte("[" + strings.Repeat(`"x",`, maxETagBody/4)))
+		}},
+		{"flushed", func(c *gin.Context) {
+			c.Header("Content-Type", "application/json")
+			c.Writer.Write([]byte(`{"a":1}`))
+			c.Writer.Flush()
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router := newETagRouter(func(c *gin.Context) {
+				tt.handler(c)
+				// The body reaches the client while the handler still writes
+				if rec.Body.Len() == 0 {
+					t.Error("body held back until the handler returned")
+				}
+				c.Writer.Write([]byte("]"))
+			})
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+			if etag := rec.Header().Get("ETag"); etag != "" {
+				t.Errorf("tagged %q", etag)
+			}
+			if !strings.HasSuffix(rec.Body.String(), "]") {
+				t.Errorf("body lost its end: %q", rec.Body.String()[max(0, rec.Body.Len()-20):])
+			}
+		})
+	}
+}
@@ -0,0 +1,18 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+)
+
+// FiscalPeriodOverrideMiddleware lets the postings of users holding the
+// override permission be dated in closed fiscal periods. Must run after
+// AuthMiddleware and ElevatedAccessMiddleware.
+func FiscalPeriodOverrideMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if hasPermission(c, entity.FinancePeriodOverride) {
+			c.Request = c.Request.WithContext(entity.WithClosedPeriodOverride(c.Request.Context()))
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,130 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/logging"
+)
+
+const (
+	// IdempotencyKeyHeader lets clients retry a mutating request safely
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader marks a response replayed for a retried request
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+)
+
+// IdempotencyStore reserves idempotency keys and keeps the responses of their
+// requests
+type IdempotencyStore interface {
+	Reserve(ctx context.Context, key *entity.IdempotencyKey) (*entity.IdempotencyKey, error)
+	Complete(ctx context.Context, key *entity.IdempotencyKey, statusCode int, contentType string, response []byte) error
+	Release(ctx context.Context, key *entity.IdempotencyKey) error
+}
+
+// responseRecorder copies the response body while it is written
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// IdempotencyMiddleware makes POST, PUT, PATCH and DELETE requests sent with an
+// Idempotency-Key header run once per user and key. A retry gets the original
+// response replayed; reusing the key for a different request, or while the
+// first one is still running, is refused. Server errors release the key so the
+// request can be retried. Must run after AuthMiddleware.
+func IdempotencyMiddleware(store IdempotencyStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value := c.GetHeader(IdempotencyKeyHeader)
+		if value == "" || c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead || c.Request.Method == http.MethodOptions {
+			c.Next()
+			return
+		}
+		if len(value) > maxIdempotencyKeyLength {
+			c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Idempotency-Key must be at most 255 characters"))
+			c.Abort()
+			return
+		}
+		userID, ok := c.Get("user_id")
+		if !ok {
+			c.Next()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Failed to read request body"))
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		hash := sha256.New()
+		hash.Write([]byte(c.Request.Method + " " + c.Request.URL.RequestURI() + "\n"))
+		hash.Write(body)
+
+		// Later middleware may swap the request context, e.g. for the sandbox
+		ctx := c.Request.Context()
+		key := &entity.IdempotencyKey{
+			UserID:      userID.(uint),
+			Key:         value,
+			Method:      c.Request.Method,
+			Path:        c.Request.URL.Path,
+			RequestHash: hex.EncodeToString(hash.Sum(nil)),
+		}
+		existing, err := store.Reserve(ctx, key)
+		if err != nil {
+			c.Error(err)
+			c.Abort()
+			return
+		}
+		if existing != nil {
+			switch {
+			case existing.RequestHash != key.RequestHash:
+				c.Error(entity.NewError(entity.ErrCodeFailedPrecondition, "Idempotency-Key was already used for a different request"))
+			case !existing.Completed():
+				c.Error(entity.NewError(entity.ErrCodeConflict, "A request with this Idempotency-Key is still in progress"))
+			default:
+				c.Header(IdempotentReplayedHeader, "true")
+				c.Data(existing.StatusCode, existing.ContentType, existing.Response)
+			}
+			c.Abort()
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+		WriteError(c)
+
+		// The request context may be done once the handler returns
+		ctx = context.WithoutCancel(ctx)
+		status := recorder.Status()
+		if status >= http.StatusInternalServerError {
+			err = store.Release(ctx, key)
+		} else {
+			err = store.Complete(ctx, key, status, recorder.Header().Get("Content-Type"), recorder.body.Bytes())
+		}
+		if err != nil {
+			logging.FromContext(ctx).Error("error storing idempotent response", "error", err)
+		}
+	}
+}
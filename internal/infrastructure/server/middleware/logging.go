@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/logging"
+)
+
+// LoggingMiddleware gives the request context a logger tagged with the
+// request ID and the route parameters naming the entities the request is
+// about, to which AuthMiddleware adds the user, and logs every answered
+// request with it. It runs inside ErrorMiddleware, whose request ID it uses.
+func LoggingMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		args := []any{"request_id", TraceID(c)}
+		for _, param := range c.Params {
+			args = append(args, param.Key, param.Value)
+		}
+		c.Request = c.Request.WithContext(logging.With(c.Request.Context(), args...))
+
+		c.Next()
+
+		WriteError(c)
+		if c.Request.URL.Path == "/health" {
+			return
+		}
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+		// c.Request holds the context the inner middleware added to
+		ctx := c.Request.Context()
+		logging.FromContext(ctx).Log(ctx, level, "request",
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"route", c.FullPath(),
+			"status", status,
+			"duration_ms", time.Since(start).Milliseconds(),
+			"bytes", c.Writer.Size(),
+			"client_ip", c.ClientIP(),
+		)
+	}
+}
@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"golang.org/x/time/rate"
+)
+
+// rateLimitIdle is how long a caller's bucket is kept after their last
+// request; a caller coming back later starts with a full bucket
+const rateLimitIdle = 10 * time.Minute
+
+// RateLimit is the sustained rate of requests a caller may make and the burst
+// they may make at once
+type RateLimit struct {
+	PerMinute int
+	Burst     int
+}
+
+type rateBucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// RateLimitMiddleware limits the requests of each user, and of each API key
+// apart from its creator, with a token bucket refilled at limit.PerMinute and
+// holding limit.Burst requests. Requests over the limit answer 429 with the
+// seconds to wait in Retry-After; every response carries the limit and the
+// requests left in X-RateLimit-Limit and X-RateLimit-Remaining. Each call
+// makes a separate set of buckets, so a route group gets a quota of its own by
+// adding its own middleware. Must run after AuthMiddleware; a limit without a
+// rate disables limiting.
+func RateLimitMiddleware(limit RateLimit) gin.HandlerFunc {
+	if limit.PerMinute <= 0 {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+	if limit.Burst <= 0 {
+		limit.Burst = 1
+	}
+	every := rate.Limit(float64(limit.PerMinute) / 60)
+
+	var mu sync.Mutex
+	buckets := make(map[string]*rateBucket)
+	lastSweep := time.Now()
+
+	return func(c *gin.Context) {
+		caller, ok := rateLimitCaller(c)
+		if !ok {
+			c.Next()
+			return
+		}
+
+		now := time.Now()
+		mu.Lock()
+		if now.Sub(lastSweep) > rateLimitIdle {
+			for key, bucket := range buckets {
+				if now.Sub(bucket.lastSeen) > rateLimitIdle {
+					delete(buckets, key)
+				}
+			}
+			lastSweep = now
+		}
+		bucket, found := buckets[caller]
+		if !found {
+			bucket = &rateBucket{limiter: rate.NewLimiter(every, limit.Burst)}
+			buckets[caller] = bucket
+		}
+		bucket.lastSeen = now
+		reservation := bucket.limiter.ReserveN(now, 1)
+		delay := reservation.DelayFrom(now)
+		if delay > 0 {
+			reservation.CancelAt(now)
+		}
+		remaining := int(math.Max(0, math.Floor(bucket.limiter.TokensAt(now))))
+		mu.Unlock()
+
+		c.Header("X-RateLimit-Limit", strconv.Itoa(limit.PerMinute))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		if delay > 0 {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+			c.Error(entity.NewError(entity.ErrCodeResourceExhausted, "Rate limit exceeded, try again later"))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// rateLimitCaller returns the key the requests of a caller are counted under
+func rateLimitCaller(c *gin.Context) (string, bool) {
+	if keyID, ok := c.Get(APIKeyIDKey); ok {
+		return fmt.Sprintf("apikey:%v", keyID), true
+	}
+	if userID, ok := c.Get("user_id"); ok {
+		return fmt.Sprintf("user:%v", userID), true
+	}
+	return "", false
+}
@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/database"
+)
+
+// ReplicaMiddleware lets the reads of GET requests run on the read replica,
+// when one is configured and current. Other requests stay on the primary, so
+// they read their own writes.
+func ReplicaMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodGet {
+			c.Request = c.Request.WithContext(database.WithReplica(c.Request.Context()))
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/database"
+)
+
+// SandboxHeader opts a single request into sandbox mode
+const SandboxHeader = "X-Sandbox"
+
+// SandboxMiddleware routes the request's database calls to the sandbox schema when the
+// request sends X-Sandbox: true, or always when the user holds the enforce permission
+// (e.g. training accounts). Must run after AuthMiddleware.
+func SandboxMiddleware(enabled bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requested, _ := strconv.ParseBool(c.GetHeader(SandboxHeader))
+		enforced := hasPermission(c, entity.SystemSandboxEnforce)
+
+		if !requested && !enforced {
+			c.Next()
+			return
+		}
+
+		if !enabled {
+			c.Error(entity.NewError(entity.ErrCodeConflict, "Sandbox mode is not enabled"))
+			c.Abort()
+			return
+		}
+		if !enforced && !hasPermission(c, entity.SystemSandboxUse) {
+			c.Error(entity.NewError(entity.ErrCodePermissionDenied, "Insufficient permissions for sandbox mode"))
+			c.Abort()
+			return
+		}
+
+		c.Request = c.Request.WithContext(database.WithSandbox(c.Request.Context()))
+		c.Header(SandboxHeader, "true")
+		c.Next()
+	}
+}
+
+func hasPermission(c *gin.Context, permission entity.Permission) bool {
+	permissions, exists := c.Get("permissions")
+	if !exists {
+		return false
+	}
+	for _, p := range permissions.([]entity.Permission) {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+)
+
+// SavedViewSource resolves the criteria of the saved views users apply to
+// lists
+type SavedViewSource interface {
+	ViewCriteria(ctx context.Context, userID uint, role string, id uint, route string) (map[string]string, error)
+}
+
+// SavedViewMiddleware filters the lists requested with a view_id by the
+// criteria of the saved view, merged into the query string so that list
+// handlers parse them like their own parameters. Parameters the request gives
+// itself take precedence. Must run after AuthMiddleware and before anything
+// reads the query.
+func SavedViewMiddleware(views SavedViewSource) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		query := c.Request.URL.Query()
+		value := query.Get(entity.SavedViewParam)
+		if value == "" || c.Request.Method != http.MethodGet {
+			c.Next()
+			return
+		}
+
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid view ID"))
+			c.Abort()
+			return
+		}
+		criteria, err := views.ViewCriteria(c.Request.Context(), c.GetUint("user_id"), c.GetString("role"), uint(id), c.FullPath())
+		if err != nil {
+			c.Error(err)
+			c.Abort()
+			return
+		}
+
+		for param, value := range criteria {
+			if !query.Has(param) {
+				query.Set(param, value)
+			}
+		}
+		c.Request.URL.RawQuery = query.Encode()
+
+		c.Next()
+	}
+}
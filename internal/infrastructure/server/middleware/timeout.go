@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+)
+
+// QueryTimeoutMiddleware bounds the request context so database calls made with it
+// are cancelled once the timeout elapses. Requests under any of the slow path
+// prefixes get slowTimeout instead, for long running report queries.
+func QueryTimeoutMiddleware(timeout, slowTimeout time.Duration, slowPrefixes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit := timeout
+		for _, prefix := range slowPrefixes {
+			if strings.HasPrefix(c.Request.URL.Path, prefix) {
+				limit = slowTimeout
+				break
+			}
+		}
+
+		if limit <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), limit)
+		defer cancel()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// ConcurrencyLimitMiddleware caps the number of requests handled at once.
+// Requests wait for a free slot until their context is done and are then rejected,
+// so heavy endpoints cannot hold every database connection.
+func ConcurrencyLimitMiddleware(limit int) gin.HandlerFunc {
+	if limit <= 0 {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+
+	slots := make(chan struct{}, limit)
+	return func(c *gin.Context) {
+		select {
+		case slots <- struct{}{}:
+			defer func() { <-slots }()
+			c.Next()
+		case <-c.Request.Context().Done():
+			c.Error(entity.NewError(entity.ErrCodeUnavailable, "Too many concurrent requests, try again later"))
+			c.Abort()
+		}
+	}
+}
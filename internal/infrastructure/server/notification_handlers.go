@@ -0,0 +1,335 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/server/middleware"
+)
+
+// NotificationHandlers handles notification inbox, preference and template HTTP requests
+type NotificationHandlers struct {
+	notificationUseCase *usecase.NotificationUseCase
+}
+
+// NewNotificationHandlers creates a new notification handlers instance
+func NewNotificationHandlers(notificationUseCase *usecase.NotificationUseCase) *NotificationHandlers {
+	return &NotificationHandlers{
+		notificationUseCase: notificationUseCase,
+	}
+}
+
+// RegisterRoutes registers notification routes. The inbox and preferences
+// belong to the caller and need no permission.
+func (h *NotificationHandlers) RegisterRoutes(router *gin.RouterGroup) {
+	notifications := router.Group("/notifications")
+	{
+		notifications.GET("", h.ListNotifications)
+		notifications.GET("/unread-count", h.CountUnread)
+		notifications.POST("/read-all", h.MarkAllRead)
+		notifications.POST("/:id/read", h.MarkRead)
+		notifications.POST("/:id/unread", h.MarkUnread)
+		notifications.GET("/preferences", h.GetPreferences)
+		notifications.PUT("/preferences", h.UpdatePreferences)
+		notifications.GET("/templates", middleware.PermissionMiddleware(entity.SystemSettingsRead), h.ListTemplates)
+		notifications.PUT("/templates/:type/:channel", middleware.PermissionMiddleware(entity.SystemSettingsUpdate), h.UpdateTemplate)
+		notifications.DELETE("/templates/:type/:channel", middleware.PermissionMiddleware(entity.SystemSettingsUpdate), h.ResetTemplate)
+	}
+}
+
+// ListNotifications handles listing the caller's inbox
+// @Summary List notifications
+// @Description List the caller's in-app notifications, latest first
+// @Tags notifications
+// @Security BearerAuth
+// @Produce json
+// @Param type query string false "Type (APPROVAL_PENDING/INVOICE_OVERDUE/STOCK_LOW)"
+// @Param unread query bool false "Only unread notifications"
+// @Param page query int false "Page number"
+// @Param page_size query int false "Page size"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /notifications [get]
+func (h *NotificationHandlers) ListNotifications(c *gin.Context) {
+	userID := currentUserID(c)
+	if userID == nil {
+		c.Error(entity.NewError(entity.ErrCodeUnauthenticated, "User not found in context"))
+		return
+	}
+
+	filter := &entity.NotificationFilter{
+		UserID: *userID,
+		Type:   entity.NotificationType(c.Query("type")),
+	}
+	filter.Unread, _ = strconv.ParseBool(c.Query("unread"))
+	if page, err := strconv.Atoi(c.Query("page")); err == nil {
+		filter.Page = page
+	}
+	if pageSize, err := strconv.Atoi(c.Query("page_size")); err == nil {
+		filter.PageSize = pageSize
+	}
+
+	notifications, total, err := h.notificationUseCase.ListNotifications(c.Request.Context(), filter)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"notifications": notifications,
+		"total":         total,
+		"page":          filter.Page,
+		"page_size":     filter.PageSize,
+	})
+}
+
+// CountUnread handles counting the caller's unread notifications
+// @Summary Count unread notifications
+// @Description Count the caller's unread in-app notifications
+// @Tags notifications
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /notifications/unread-count [get]
+func (h *NotificationHandlers) CountUnread(c *gin.Context) {
+	userID := currentUserID(c)
+	if userID == nil {
+		c.Error(entity.NewError(entity.ErrCodeUnauthenticated, "User not found in context"))
+		return
+	}
+
+	count, err := h.notificationUseCase.CountUnread(c.Request.Context(), *userID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"unread": count})
+}
+
+// MarkRead handles marking a notification read
+// @Summary Mark notification read
+// @Description Mark one of the caller's notifications read
+// @Tags notifications
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Notification ID"
+// @Success 200 {object} entity.Notification
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /notifications/{id}/read [post]
+func (h *NotificationHandlers) MarkRead(c *gin.Context) {
+	h.setRead(c, true)
+}
+
+// MarkUnread handles marking a notification unread
+// @Summary Mark notification unread
+// @Description Mark one of the caller's notifications unread
+// @Tags notifications
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Notification ID"
+// @Success 200 {object} entity.Notification
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /notifications/{id}/unread [post]
+func (h *NotificationHandlers) MarkUnread(c *gin.Context) {
+	h.setRead(c, false)
+}
+
+func (h *NotificationHandlers) setRead(c *gin.Context, read bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid notification ID"))
+		return
+	}
+	userID := currentUserID(c)
+	if userID == nil {
+		c.Error(entity.NewError(entity.ErrCodeUnauthenticated, "User not found in context"))
+		return
+	}
+
+	notification, err := h.notificationUseCase.SetRead(c.Request.Context(), *userID, uint(id), read)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, notification)
+}
+
+// MarkAllRead handles marking all of the caller's notifications read
+// @Summary Mark all notifications read
+// @Description Mark all of the caller's unread notifications read
+// @Tags notifications
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /notifications/read-all [post]
+func (h *NotificationHandlers) MarkAllRead(c *gin.Context) {
+	userID := currentUserID(c)
+	if userID == nil {
+		c.Error(entity.NewError(entity.ErrCodeUnauthenticated, "User not found in context"))
+		return
+	}
+
+	count, err := h.notificationUseCase.MarkAllRead(c.Request.Context(), *userID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"marked": count})
+}
+
+// GetPreferences handles getting the caller's notification settings
+// @Summary Get notification preferences
+// @Description Get the caller's phone number and whether each channel is on for each notification type, defaults included
+// @Tags notifications
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} entity.NotificationSettings
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /notifications/preferences [get]
+func (h *NotificationHandlers) GetPreferences(c *gin.Context) {
+	userID := currentUserID(c)
+	if userID == nil {
+		c.Error(entity.NewError(entity.ErrCodeUnauthenticated, "User not found in context"))
+		return
+	}
+
+	settings, err := h.notificationUseCase.GetSettings(c.Request.Context(), *userID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// UpdatePreferences handles changing the caller's notification settings
+// @Summary Update notification preferences
+// @Description Change the caller's phone number and turn channels on or off per notification type. Preferences not listed are left unchanged.
+// @Tags notifications
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body entity.UpdateNotificationSettingsRequest true "Phone number and preferences"
+// @Success 200 {object} entity.NotificationSettings
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Router /notifications/preferences [put]
+func (h *NotificationHandlers) UpdatePreferences(c *gin.Context) {
+	userID := currentUserID(c)
+	if userID == nil {
+		c.Error(entity.NewError(entity.ErrCodeUnauthenticated, "User not found in context"))
+		return
+	}
+
+	var req entity.UpdateNotificationSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	settings, err := h.notificationUseCase.UpdateSettings(c.Request.Context(), *userID, &req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// ListTemplates handles listing the notification templates
+// @Summary List notification templates
+// @Description List the template in use for every notification type and channel, built-in or custom
+// @Tags notifications
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} entity.NotificationTemplate
+// @Failure 500 {object} ErrorResponse
+// @Router /notifications/templates [get]
+func (h *NotificationHandlers) ListTemplates(c *gin.Context) {
+	templates, err := h.notificationUseCase.ListTemplates(c.Request.Context())
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, templates)
+}
+
+// UpdateTemplate handles overriding a notification template
+// @Summary Update notification template
+// @Description Override the built-in template of a notification type and channel. The subject and body may use the type's {{placeholders}}.
+// @Tags notifications
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param type path string true "Notification type"
+// @Param channel path string true "Channel (IN_APP/EMAIL/SMS)"
+// @Param request body entity.UpdateNotificationTemplateRequest true "Subject and body"
+// @Success 200 {object} entity.NotificationTemplate
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /notifications/templates/{type}/{channel} [put]
+func (h *NotificationHandlers) UpdateTemplate(c *gin.Context) {
+	var req entity.UpdateNotificationTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	template, err := h.notificationUseCase.UpdateTemplate(c.Request.Context(),
+		entity.NotificationType(c.Param("type")), entity.NotificationChannel(c.Param("channel")), &req, currentUserID(c))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+// ResetTemplate handles restoring a built-in notification template
+// @Summary Reset notification template
+// @Description Remove the custom template of a notification type and channel, restoring the built-in one
+// @Tags notifications
+// @Security BearerAuth
+// @Produce json
+// @Param type path string true "Notification type"
+// @Param channel path string true "Channel (IN_APP/EMAIL/SMS)"
+// @Success 200 {object} entity.NotificationTemplate
+// @Failure 404 {object} ErrorResponse
+// @Router /notifications/templates/{type}/{channel} [delete]
+func (h *NotificationHandlers) ResetTemplate(c *gin.Context) {
+	template, err := h.notificationUseCase.ResetTemplate(c.Request.Context(),
+		entity.NotificationType(c.Param("type")), entity.NotificationChannel(c.Param("channel")))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+// handleError maps notification errors to HTTP responses
+func (h *NotificationHandlers) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, usecase.ErrNotificationNotFound),
+		errors.Is(err, usecase.ErrUnknownNotificationTemplate):
+		c.Error(entity.WrapError(entity.ErrCodeNotFound, err))
+	default:
+		c.Error(err)
+	}
+}
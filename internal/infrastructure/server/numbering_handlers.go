@@ -0,0 +1,126 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/server/middleware"
+)
+
+// NumberingHandlers handles document numbering scheme HTTP requests
+type NumberingHandlers struct {
+	numberingUseCase *usecase.NumberingUseCase
+}
+
+// NewNumberingHandlers creates a new numbering handlers instance
+func NewNumberingHandlers(numberingUseCase *usecase.NumberingUseCase) *NumberingHandlers {
+	return &NumberingHandlers{
+		numberingUseCase: numberingUseCase,
+	}
+}
+
+// RegisterRoutes registers numbering scheme routes
+func (h *NumberingHandlers) RegisterRoutes(router *gin.RouterGroup) {
+	schemes := router.Group("/numbering-schemes")
+	{
+		schemes.GET("", middleware.PermissionMiddleware(entity.SystemSettingsRead), h.ListSchemes)
+		schemes.PUT("", middleware.PermissionMiddleware(entity.SystemSettingsUpdate), h.SaveScheme)
+		schemes.GET("/preview", middleware.PermissionMiddleware(entity.SystemSettingsRead), h.PreviewNumber)
+		schemes.DELETE("/:id", middleware.PermissionMiddleware(entity.SystemSettingsUpdate), h.DeleteScheme)
+	}
+}
+
+// ListSchemes handles listing numbering schemes
+// @Summary List numbering schemes
+// @Description List the configured document numbering schemes. Document types without one are numbered by their default.
+// @Tags numbering
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} entity.NumberingScheme
+// @Failure 500 {object} ErrorResponse
+// @Router /numbering-schemes [get]
+func (h *NumberingHandlers) ListSchemes(c *gin.Context) {
+	schemes, err := h.numberingUseCase.ListSchemes(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, schemes)
+}
+
+// SaveScheme handles configuring a numbering scheme
+// @Summary Configure numbering scheme
+// @Description Configure the numbering of a document type, or of its documents of one store, replacing the scheme configured before
+// @Tags numbering
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body entity.NumberingSchemeRequest true "Document type, store and settings"
+// @Success 200 {object} entity.NumberingScheme
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /numbering-schemes [put]
+func (h *NumberingHandlers) SaveScheme(c *gin.Context) {
+	var req entity.NumberingSchemeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	scheme, err := h.numberingUseCase.SaveScheme(c.Request.Context(), &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, scheme)
+}
+
+// PreviewNumber handles previewing the next document number
+// @Summary Preview next document number
+// @Description Get the scheme a new document of a type and store is numbered by, with the number it would be given now
+// @Tags numbering
+// @Security BearerAuth
+// @Produce json
+// @Param document_type query string true "Document type, e.g. sales_order"
+// @Param store_id query string false "Store ID"
+// @Success 200 {object} entity.NumberPreview
+// @Failure 400 {object} ErrorResponse
+// @Router /numbering-schemes/preview [get]
+func (h *NumberingHandlers) PreviewNumber(c *gin.Context) {
+	preview, err := h.numberingUseCase.PreviewNumber(c.Request.Context(), entity.DocumentType(c.Query("document_type")), c.Query("store_id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, preview)
+}
+
+// DeleteScheme handles removing a numbering scheme
+// @Summary Delete numbering scheme
+// @Description Remove a configured scheme; its documents are numbered by the scheme of the document type, or the default, again
+// @Tags numbering
+// @Security BearerAuth
+// @Param id path int true "Scheme ID"
+// @Success 204
+// @Failure 404 {object} ErrorResponse
+// @Router /numbering-schemes/{id} [delete]
+func (h *NumberingHandlers) DeleteScheme(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid scheme ID"))
+		return
+	}
+
+	if err := h.numberingUseCase.DeleteScheme(c.Request.Context(), uint(id)); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -34,6 +34,7 @@ type CreateSalesOrderRequest struct {
 	PaymentMethod   entity.PaymentMethod    `json:"payment_method"`
 	Notes           string                  `json:"notes"`
 	StoreID         string                  `json:"store_id" binding:"required"`
+	CustomFields    entity.JSONMap          `json:"custom_fields"` // values of the custom fields defined on sales orders, by key
 }
 
 // CreateSalesOrder creates a new sales order
@@ -52,14 +53,14 @@ type CreateSalesOrderRequest struct {
 func (h *OrderHandlers) CreateSalesOrder(c *gin.Context) {
 	var req CreateSalesOrderRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 
 	// Get user ID from context
 	userID := auth.GetUserIDFromContext(c)
 	if userID == "" {
-		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
+		c.Error(entity.NewError(entity.ErrCodeUnauthenticated, "unauthorized"))
 		return
 	}
 
@@ -74,11 +75,12 @@ func (h *OrderHandlers) CreateSalesOrder(c *gin.Context) {
 		Notes:           req.Notes,
 		Status:          entity.SalesOrderStatusDraft,
 		PaymentStatus:   entity.PaymentStatusPending,
+		CustomFields:    req.CustomFields,
 	}
 
 	// Create the order
 	if err := h.orderUseCase.CreateSalesOrder(c.Request.Context(), order, req.StoreID, userID); err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -100,13 +102,13 @@ func (h *OrderHandlers) CreateSalesOrder(c *gin.Context) {
 func (h *OrderHandlers) GetSalesOrder(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "id is required"})
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "id is required"))
 		return
 	}
 
 	order, err := h.orderUseCase.GetSalesOrder(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeNotFound, err))
 		return
 	}
 
@@ -122,11 +124,12 @@ type SalesOrderFilter struct {
 	StartDate     time.Time `form:"start_date" time_format:"2006-01-02"`
 	EndDate       time.Time `form:"end_date" time_format:"2006-01-02"`
 	SKUID         string    `form:"sku_id"`
+	Archived      bool      `form:"archived"`
 }
 
 // ListSalesOrders lists sales orders with optional filtering
 // @Summary List sales orders
-// @Description List sales orders with optional filtering
+// @Description List sales orders with optional filtering, latest first. Passing cursor or limit pages the list instead, returning {orders, limit, next_cursor}.
 // @Tags orders
 // @Security BearerAuth
 // @Produce json
@@ -137,22 +140,105 @@ type SalesOrderFilter struct {
 // @Param start_date query string false "Start Date (YYYY-MM-DD)"
 // @Param end_date query string false "End Date (YYYY-MM-DD)"
 // @Param item_id query string false "Item ID"
+// @Param archived query bool false "List archived orders instead of live ones"
+// @Param cf.key query string false "Value of the custom field with this key, e.g. cf.channel=web"
+// @Param cursor query string false "Return the orders after this next_cursor of a previous page"
+// @Param limit query int false "Orders per page when paging by cursor (default 50, at most 500)"
+// @Param fields query string false "Only return these fields of each order, e.g. id,order_number,status"
 // @Success 200 {array} entity.SalesOrder
 // @Failure 400 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /orders [get]
 func (h *OrderHandlers) ListSalesOrders(c *gin.Context) {
+	entityFilter, err := salesOrderFilter(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	fields, err := parseFieldSet[entity.SalesOrder](c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	page, err := parseCursorPage(c)
+	if err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+	if page != nil {
+		orders, next, err := h.orderUseCase.ListSalesOrdersPage(c.Request.Context(), entityFilter, *page)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		c.JSON(http.StatusOK, cursorPageResponse("orders", fields.pick(orders), page, next))
+		return
+	}
+
+	orders, err := h.orderUseCase.ListSalesOrders(c.Request.Context(), entityFilter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, fields.pick(orders))
+}
+
+// ExportSalesOrders exports sales orders
+// @Summary Export sales orders
+// @Description Download the sales orders matching the filters of the sales order list, latest first, with a column per custom field defined on sales orders
+// @Tags orders
+// @Security BearerAuth
+// @Produce text/csv
+// @Param format query string false "File format: csv (default), xlsx, pdf or json"
+// @Param order_number query string false "Order Number"
+// @Param client_id query integer false "Client ID"
+// @Param status query string false "Order Status"
+// @Param payment_status query string false "Payment Status"
+// @Param start_date query string false "Start Date (YYYY-MM-DD)"
+// @Param end_date query string false "End Date (YYYY-MM-DD)"
+// @Param sku_id query string false "SKU ID"
+// @Param archived query bool false "Export archived orders instead of live ones"
+// @Param cf.key query string false "Value of the custom field with this key, e.g. cf.channel=web"
+// @Success 200 {file} file
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /orders/export [get]
+func (h *OrderHandlers) ExportSalesOrders(c *gin.Context) {
+	format, ok := parseExportFormat(c)
+	if !ok {
+		return
+	}
+	filter, err := salesOrderFilter(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	columns, rows, err := h.orderUseCase.ExportSalesOrders(c.Request.Context(), filter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	writeExport(c, format, "sales-orders", "Sales orders", columns, rows)
+}
+
+// salesOrderFilter reads the sales order list filters from the query string
+func salesOrderFilter(c *gin.Context) (*entity.SalesOrderFilter, error) {
 	var filter SalesOrderFilter
 	if err := c.ShouldBindQuery(&filter); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
-		return
+		return nil, entity.WrapError(entity.ErrCodeInvalidArgument, err)
 	}
 
 	// Convert filter to entity filter
 	entityFilter := &entity.SalesOrderFilter{
-		OrderNumber: filter.OrderNumber,
-		ClientID:    filter.ClientID,
-		SKUID:       filter.SKUID,
+		OrderNumber:  filter.OrderNumber,
+		ClientID:     filter.ClientID,
+		SKUID:        filter.SKUID,
+		Archived:     filter.Archived,
+		CustomFields: customFieldQuery(c),
 	}
 
 	// Convert string status to entity status if provided
@@ -175,13 +261,36 @@ func (h *OrderHandlers) ListSalesOrders(c *gin.Context) {
 		entityFilter.EndDate = &filter.EndDate
 	}
 
-	orders, err := h.orderUseCase.ListSalesOrders(c.Request.Context(), entityFilter)
+	return entityFilter, nil
+}
+
+// UpdateSalesOrderCustomFields replaces the custom field values of a sales order
+// @Summary Update sales order custom fields
+// @Description Replace the values of the custom fields defined on sales orders that an order holds
+// @Tags orders
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Sales Order ID"
+// @Param custom_fields body object true "Custom field values by key"
+// @Success 200 {object} entity.SalesOrder
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /orders/{id}/custom-fields [put]
+func (h *OrderHandlers) UpdateSalesOrderCustomFields(c *gin.Context) {
+	var values entity.JSONMap
+	if err := c.ShouldBindJSON(&values); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	order, err := h.orderUseCase.UpdateSalesOrderCustomFields(c.Request.Context(), c.Param("id"), values)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, orders)
+	c.JSON(http.StatusOK, order)
 }
 
 // ConfirmSalesOrder confirms a sales order
@@ -199,19 +308,19 @@ func (h *OrderHandlers) ListSalesOrders(c *gin.Context) {
 func (h *OrderHandlers) ConfirmSalesOrder(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "id is required"})
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "id is required"))
 		return
 	}
 
 	// Get user ID from context
 	userID := auth.GetUserIDFromContext(c)
 	if userID == "" {
-		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
+		c.Error(entity.NewError(entity.ErrCodeUnauthenticated, "unauthorized"))
 		return
 	}
 
 	if err := h.orderUseCase.ConfirmSalesOrder(c.Request.Context(), id, userID); err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -233,12 +342,12 @@ func (h *OrderHandlers) ConfirmSalesOrder(c *gin.Context) {
 func (h *OrderHandlers) CancelSalesOrder(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "id is required"})
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "id is required"))
 		return
 	}
 
 	if err := h.orderUseCase.CancelSalesOrder(c.Request.Context(), id); err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -260,12 +369,12 @@ func (h *OrderHandlers) CancelSalesOrder(c *gin.Context) {
 func (h *OrderHandlers) CompleteSalesOrder(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "id is required"})
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "id is required"))
 		return
 	}
 
 	if err := h.orderUseCase.CompleteSalesOrder(c.Request.Context(), id); err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -279,6 +388,7 @@ type CreateDeliveryOrderRequest struct {
 	ShippingAddress string                     `json:"shipping_address"`
 	TrackingNumber  string                     `json:"tracking_number"`
 	ShippingMethod  string                     `json:"shipping_method"`
+	FreightCost     float64                    `json:"freight_cost" binding:"gte=0"` // in the base currency
 	StoreID         string                     `json:"store_id" binding:"required"`
 	Notes           string                     `json:"notes"`
 }
@@ -300,20 +410,20 @@ type CreateDeliveryOrderRequest struct {
 func (h *OrderHandlers) CreateDeliveryOrder(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "sales order id is required"})
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "sales order id is required"))
 		return
 	}
 
 	var req CreateDeliveryOrderRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 
 	// Get user ID from context
 	userID := auth.GetUserIDFromContext(c)
 	if userID == "" {
-		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
+		c.Error(entity.NewError(entity.ErrCodeUnauthenticated, "unauthorized"))
 		return
 	}
 
@@ -325,6 +435,7 @@ func (h *OrderHandlers) CreateDeliveryOrder(c *gin.Context) {
 		ShippingAddress: req.ShippingAddress,
 		TrackingNumber:  req.TrackingNumber,
 		ShippingMethod:  req.ShippingMethod,
+		FreightCost:     req.FreightCost,
 		StoreID:         req.StoreID,
 		Notes:           req.Notes,
 		Status:          entity.DeliveryOrderStatusPending,
@@ -332,7 +443,7 @@ func (h *OrderHandlers) CreateDeliveryOrder(c *gin.Context) {
 
 	// Create the delivery order
 	if err := h.orderUseCase.CreateDeliveryOrder(c.Request.Context(), delivery, userID); err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -354,13 +465,13 @@ func (h *OrderHandlers) CreateDeliveryOrder(c *gin.Context) {
 func (h *OrderHandlers) GetDeliveryOrder(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "id is required"})
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "id is required"))
 		return
 	}
 
 	delivery, err := h.orderUseCase.GetDeliveryOrder(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeNotFound, err))
 		return
 	}
 
@@ -396,7 +507,7 @@ type DeliveryOrderFilter struct {
 func (h *OrderHandlers) ListDeliveryOrders(c *gin.Context) {
 	var filter DeliveryOrderFilter
 	if err := c.ShouldBindQuery(&filter); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 
@@ -423,7 +534,7 @@ func (h *OrderHandlers) ListDeliveryOrders(c *gin.Context) {
 
 	deliveries, err := h.orderUseCase.ListDeliveryOrders(c.Request.Context(), entityFilter)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -445,12 +556,12 @@ func (h *OrderHandlers) ListDeliveryOrders(c *gin.Context) {
 func (h *OrderHandlers) PrepareDelivery(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "id is required"})
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "id is required"))
 		return
 	}
 
 	if err := h.orderUseCase.PrepareDelivery(c.Request.Context(), id); err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -472,19 +583,19 @@ func (h *OrderHandlers) PrepareDelivery(c *gin.Context) {
 func (h *OrderHandlers) ShipDelivery(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "id is required"})
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "id is required"))
 		return
 	}
 
 	// Get user ID from context
 	userID := auth.GetUserIDFromContext(c)
 	if userID == "" {
-		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
+		c.Error(entity.NewError(entity.ErrCodeUnauthenticated, "unauthorized"))
 		return
 	}
 
 	if err := h.orderUseCase.ShipDelivery(c.Request.Context(), id, userID); err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -506,12 +617,12 @@ func (h *OrderHandlers) ShipDelivery(c *gin.Context) {
 func (h *OrderHandlers) CompleteDelivery(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "id is required"})
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "id is required"))
 		return
 	}
 
 	if err := h.orderUseCase.CompleteDelivery(c.Request.Context(), id); err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -544,20 +655,20 @@ type CreateInvoiceRequest struct {
 func (h *OrderHandlers) CreateInvoice(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "sales order id is required"})
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "sales order id is required"))
 		return
 	}
 
 	var req CreateInvoiceRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 
 	// Get user ID from context
 	userID := auth.GetUserIDFromContext(c)
 	if userID == "" {
-		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
+		c.Error(entity.NewError(entity.ErrCodeUnauthenticated, "unauthorized"))
 		return
 	}
 
@@ -575,7 +686,7 @@ func (h *OrderHandlers) CreateInvoice(c *gin.Context) {
 
 	// Create the invoice
 	if err := h.orderUseCase.CreateInvoice(c.Request.Context(), invoice, userID); err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -597,13 +708,13 @@ func (h *OrderHandlers) CreateInvoice(c *gin.Context) {
 func (h *OrderHandlers) GetInvoice(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "id is required"})
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "id is required"))
 		return
 	}
 
 	invoice, err := h.orderUseCase.GetInvoice(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeNotFound, err))
 		return
 	}
 
@@ -617,6 +728,7 @@ type InvoiceFilter struct {
 	Status        string    `form:"status"`
 	StartDate     time.Time `form:"start_date" time_format:"2006-01-02"`
 	EndDate       time.Time `form:"end_date" time_format:"2006-01-02"`
+	Archived      bool      `form:"archived"`
 }
 
 // ListInvoices lists invoices with optional filtering
@@ -630,6 +742,8 @@ type InvoiceFilter struct {
 // @Param status query string false "Invoice Status"
 // @Param start_date query string false "Start Date (YYYY-MM-DD)"
 // @Param end_date query string false "End Date (YYYY-MM-DD)"
+// @Param archived query bool false "List archived invoices instead of live ones"
+// @Param fields query string false "Only return these fields of each invoice, e.g. id,invoice_number,status"
 // @Success 200 {array} entity.Invoice
 // @Failure 400 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
@@ -637,7 +751,12 @@ type InvoiceFilter struct {
 func (h *OrderHandlers) ListInvoices(c *gin.Context) {
 	var filter InvoiceFilter
 	if err := c.ShouldBindQuery(&filter); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+	fields, err := parseFieldSet[entity.Invoice](c)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
@@ -645,6 +764,7 @@ func (h *OrderHandlers) ListInvoices(c *gin.Context) {
 	entityFilter := &entity.InvoiceFilter{
 		InvoiceNumber: filter.InvoiceNumber,
 		SalesOrderID:  filter.SalesOrderID,
+		Archived:      filter.Archived,
 	}
 
 	// Convert string status to entity status if provided
@@ -663,11 +783,11 @@ func (h *OrderHandlers) ListInvoices(c *gin.Context) {
 
 	invoices, err := h.orderUseCase.ListInvoices(c.Request.Context(), entityFilter)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, invoices)
+	c.JSON(http.StatusOK, fields.pick(invoices))
 }
 
 // IssueInvoice changes an invoice from draft to issued status
@@ -685,12 +805,12 @@ func (h *OrderHandlers) ListInvoices(c *gin.Context) {
 func (h *OrderHandlers) IssueInvoice(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "id is required"})
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "id is required"))
 		return
 	}
 
 	if err := h.orderUseCase.IssueInvoice(c.Request.Context(), id); err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -712,12 +832,12 @@ func (h *OrderHandlers) IssueInvoice(c *gin.Context) {
 func (h *OrderHandlers) PayInvoice(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "id is required"})
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "id is required"))
 		return
 	}
 
 	if err := h.orderUseCase.PayInvoice(c.Request.Context(), id); err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -0,0 +1,47 @@
+package server
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+)
+
+// parseCursorPage reads the cursor and limit query parameters of listings that
+// can be paged by cursor as well as by page number. It returns nil when
+// neither is given, for the listing to be paged by page and page_size.
+func parseCursorPage(c *gin.Context) (*entity.CursorPage, error) {
+	token, byCursor := c.GetQuery("cursor")
+	limit, byLimit := c.GetQuery("limit")
+	if !byCursor && !byLimit {
+		return nil, nil
+	}
+
+	page := &entity.CursorPage{Limit: entity.DefaultCursorLimit}
+	if limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > entity.MaxCursorLimit {
+			return nil, fmt.Errorf("limit must be between 1 and %d", entity.MaxCursorLimit)
+		}
+		page.Limit = n
+	}
+	if token != "" {
+		after, err := entity.DecodeCursor(token)
+		if err != nil {
+			return nil, err
+		}
+		page.After = after
+	}
+	return page, nil
+}
+
+// cursorPageResponse renders a page of a listing paged by cursor, the rows
+// under key. next_cursor is empty on the last page.
+func cursorPageResponse(key string, rows interface{}, page *entity.CursorPage, next *entity.Cursor) gin.H {
+	return gin.H{
+		key:           rows,
+		"limit":       page.Limit,
+		"next_cursor": entity.NextCursorToken(next),
+	}
+}
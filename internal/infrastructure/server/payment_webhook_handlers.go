@@ -0,0 +1,131 @@
+package server
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/payment"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/server/middleware"
+)
+
+// maxWebhookBodyBytes caps the size of a payment webhook request body
+const maxWebhookBodyBytes = 1 << 20
+
+// PaymentWebhookHandlers handles payment gateway webhook HTTP requests
+type PaymentWebhookHandlers struct {
+	webhookUseCase *usecase.PaymentWebhookUseCase
+	provider       payment.Provider
+}
+
+// NewPaymentWebhookHandlers creates a new payment webhook handlers instance
+func NewPaymentWebhookHandlers(webhookUseCase *usecase.PaymentWebhookUseCase, provider payment.Provider) *PaymentWebhookHandlers {
+	return &PaymentWebhookHandlers{
+		webhookUseCase: webhookUseCase,
+		provider:       provider,
+	}
+}
+
+// RegisterWebhookRoutes registers the webhook route, which is authenticated by
+// the request signature instead of a bearer token
+func (h *PaymentWebhookHandlers) RegisterWebhookRoutes(router *gin.RouterGroup) {
+	router.POST("/integrations/payments/webhook", h.ReceiveWebhook)
+}
+
+// RegisterRoutes registers payment webhook routes
+func (h *PaymentWebhookHandlers) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("/integrations/payments/events", middleware.PermissionMiddleware(entity.FinancePaymentRead), h.ListEvents)
+}
+
+// ReceiveWebhook handles a payment gateway webhook
+// @Summary Receive payment webhook
+// @Description Verify a payment gateway event and confirm or refund the finance payment it references. Redelivered events are applied once.
+// @Tags finance
+// @Accept json
+// @Produce json
+// @Param Webhook-Signature header string true "t=<unix time>,v1=<hex HMAC-SHA256 of '<t>.<body>'>"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 422 {object} map[string]interface{}
+// @Failure 500 {object} ErrorResponse
+// @Router /integrations/payments/webhook [post]
+func (h *PaymentWebhookHandlers) ReceiveWebhook(c *gin.Context) {
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxWebhookBodyBytes))
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid request body"))
+		return
+	}
+
+	event, err := h.provider.ParseEvent(c.Request.Header, body)
+	if err != nil {
+		switch {
+		case errors.Is(err, payment.ErrInvalidSignature):
+			c.Error(entity.WrapError(entity.ErrCodeUnauthenticated, err))
+		default:
+			c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		}
+		return
+	}
+
+	record, duplicate, err := h.webhookUseCase.HandleEvent(c.Request.Context(), h.provider.Name(), event)
+	if err != nil {
+		if record == nil {
+			c.Error(err)
+			return
+		}
+		// Recorded as failed; the gateway's redelivery applies it again
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "event": record})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"event":     record,
+		"duplicate": duplicate,
+	})
+}
+
+// ListEvents handles listing received payment webhook events
+// @Summary List payment webhook events
+// @Description List the payment gateway events received, latest first
+// @Tags finance
+// @Security BearerAuth
+// @Produce json
+// @Param provider query string false "Provider"
+// @Param status query string false "Status (RECEIVED/PROCESSED/IGNORED/FAILED)"
+// @Param page query int false "Page number"
+// @Param page_size query int false "Page size"
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} ErrorResponse
+// @Router /integrations/payments/events [get]
+func (h *PaymentWebhookHandlers) ListEvents(c *gin.Context) {
+	filter := &entity.PaymentWebhookEventFilter{
+		Provider: c.Query("provider"),
+		Status:   entity.PaymentWebhookStatus(c.Query("status")),
+	}
+
+	if page, err := strconv.Atoi(c.Query("page")); err == nil {
+		filter.Page = page
+	}
+
+	if pageSize, err := strconv.Atoi(c.Query("page_size")); err == nil {
+		filter.PageSize = pageSize
+	}
+
+	events, total, err := h.webhookUseCase.ListEvents(c.Request.Context(), filter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"events":    events,
+		"total":     total,
+		"page":      filter.Page,
+		"page_size": filter.PageSize,
+	})
+}
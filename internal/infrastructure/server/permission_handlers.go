@@ -0,0 +1,168 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/auth"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/server/middleware"
+)
+
+// PermissionHandlers handles permission introspection HTTP requests
+type PermissionHandlers struct {
+	permissionUseCase *usecase.PermissionUseCase
+}
+
+// NewPermissionHandlers creates a new permission handlers instance
+func NewPermissionHandlers(permissionUseCase *usecase.PermissionUseCase) *PermissionHandlers {
+	return &PermissionHandlers{
+		permissionUseCase: permissionUseCase,
+	}
+}
+
+// RegisterRoutes registers permission introspection routes
+func (h *PermissionHandlers) RegisterRoutes(router *gin.RouterGroup) {
+	permissions := router.Group("/auth/permissions")
+	{
+		permissions.GET("/me", h.GetMyPermissions)
+		permissions.POST("/check", h.CheckPermissions)
+	}
+
+	router.GET("/roles/:id/effective-permissions", middleware.PermissionMiddleware(entity.RoleRead), h.GetRolePermissions)
+}
+
+// GetMyPermissions handles getting the caller's permissions
+// @Summary Get my permissions
+// @Description Get the permissions the caller's requests are authorized with, each with its source: the role or API key, an active elevated access grant or an approval delegation, and the access scope limiting them
+// @Tags auth
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} entity.EffectivePermissions
+// @Failure 401 {object} ErrorResponse
+// @Router /auth/permissions/me [get]
+func (h *PermissionHandlers) GetMyPermissions(c *gin.Context) {
+	effective, ok := h.callerPermissions(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, effective)
+}
+
+// CheckPermissions handles dry-run permission checks
+// @Summary Check permissions
+// @Description Tell whether a user, the caller when user_id is empty, can take each action, given as a permission or as a resource and action, optionally on a store or department of their access scope. Nothing is performed. Checking another user requires user:read.
+// @Tags auth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body entity.PermissionCheckRequest true "User and checks"
+// @Success 200 {object} entity.PermissionCheckResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /auth/permissions/check [post]
+func (h *PermissionHandlers) CheckPermissions(c *gin.Context) {
+	userID := currentUserID(c)
+	if userID == nil {
+		c.Error(entity.NewError(entity.ErrCodeUnauthenticated, "User not found in context"))
+		return
+	}
+
+	var req entity.PermissionCheckRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	var effective *entity.EffectivePermissions
+	if req.UserID == 0 || req.UserID == *userID {
+		var ok bool
+		if effective, ok = h.callerPermissions(c); !ok {
+			return
+		}
+	} else {
+		if !middleware.HasPermission(c, entity.UserRead) {
+			c.Error(entity.NewError(entity.ErrCodePermissionDenied, "Insufficient permissions"))
+			return
+		}
+		var err error
+		if effective, err = h.permissionUseCase.UserPermissions(c.Request.Context(), req.UserID); err != nil {
+			c.Error(err)
+			return
+		}
+	}
+
+	results, err := h.permissionUseCase.Check(effective, req.Checks)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, entity.PermissionCheckResponse{
+		UserID:  effective.UserID,
+		Results: results,
+	})
+}
+
+// GetRolePermissions handles getting the permissions of a role
+// @Summary Get role effective permissions
+// @Description Get the permissions the users of a role hold through it and the access scope limiting them, before any elevated access or delegation of their own
+// @Tags roles
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Role ID"
+// @Success 200 {object} entity.EffectivePermissions
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /roles/{id}/effective-permissions [get]
+func (h *PermissionHandlers) GetRolePermissions(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid role ID"))
+		return
+	}
+
+	effective, err := h.permissionUseCase.RolePermissions(c.Request.Context(), uint(id))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, effective)
+}
+
+// callerPermissions resolves the permissions of the request from its token or
+// API key, as the request itself is authorized, rather than from the user's
+// current role
+func (h *PermissionHandlers) callerPermissions(c *gin.Context) (*entity.EffectivePermissions, bool) {
+	userID := currentUserID(c)
+	if userID == nil {
+		c.Error(entity.NewError(entity.ErrCodeUnauthenticated, "User not found in context"))
+		return nil, false
+	}
+
+	source := entity.PermissionFromRole
+	if middleware.IsAPIKeyRequest(c) {
+		source = entity.PermissionFromAPIKey
+	}
+	ctx := c.Request.Context()
+	effective, err := h.permissionUseCase.Resolve(ctx, *userID, middleware.RolePermissions(c), source, entity.AccessScopeFromContext(ctx))
+	if err != nil {
+		c.Error(err)
+		return nil, false
+	}
+
+	effective.Role = c.GetString("role")
+	if keyID, ok := c.Get(middleware.APIKeyIDKey); ok {
+		id, _ := keyID.(uint)
+		effective.APIKeyID = &id
+	}
+	if impersonatorID := auth.GetImpersonatorIDFromContext(c); impersonatorID != 0 {
+		effective.ImpersonatorID = &impersonatorID
+	}
+	return effective, true
+}
@@ -0,0 +1,278 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/auth"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
+)
+
+// PortalHandlers serves the read-only customer self-service API
+type PortalHandlers struct {
+	portalUseCase *usecase.PortalUseCase
+	jwtService    *auth.JWTService
+}
+
+// NewPortalHandlers creates a new PortalHandlers
+func NewPortalHandlers(portalUseCase *usecase.PortalUseCase, jwtService *auth.JWTService) *PortalHandlers {
+	return &PortalHandlers{
+		portalUseCase: portalUseCase,
+		jwtService:    jwtService,
+	}
+}
+
+// PortalTokenResponse represents an issued customer portal token
+type PortalTokenResponse struct {
+	AccessToken string    `json:"access_token" example:"eyJhbGciOiJ..."`
+	ClientID    uint      `json:"client_id" example:"1"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// RegisterRoutes registers the portal routes on a group authenticated with portal tokens
+func (h *PortalHandlers) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("/me", h.GetProfile)
+	router.GET("/orders", h.ListOrders)
+	router.GET("/orders/:id", h.GetOrder)
+	router.GET("/deliveries", h.ListDeliveries)
+	router.GET("/deliveries/:id", h.GetDelivery)
+	router.GET("/invoices", h.ListInvoices)
+	router.GET("/invoices/:id", h.GetInvoice)
+	router.GET("/payment-status", h.GetPaymentStatus)
+}
+
+// IssuePortalToken handles issuing a portal token for a client
+// @Summary Issue customer portal token
+// @Description Issue a read-only token that lets a client view their own orders, deliveries and invoices
+// @Tags portal
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Client ID"
+// @Success 200 {object} PortalTokenResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /clients/{id}/portal-token [post]
+func (h *PortalHandlers) IssuePortalToken(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "invalid client ID"))
+		return
+	}
+
+	client, err := h.portalUseCase.GetClient(c.Request.Context(), uint(id))
+	if err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeNotFound, err))
+		return
+	}
+
+	token, expiry, err := h.jwtService.GeneratePortalToken(client)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInternal, "failed to generate portal token"))
+		return
+	}
+
+	c.JSON(http.StatusOK, PortalTokenResponse{
+		AccessToken: token,
+		ClientID:    client.ID,
+		ExpiresAt:   expiry,
+	})
+}
+
+// GetProfile handles retrieving the authenticated client's profile
+// @Summary Get portal profile
+// @Description Get the profile of the client the portal token was issued for
+// @Tags portal
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} entity.Client
+// @Failure 404 {object} ErrorResponse
+// @Router /portal/me [get]
+func (h *PortalHandlers) GetProfile(c *gin.Context) {
+	client, err := h.portalUseCase.GetClient(c.Request.Context(), auth.GetClientIDFromContext(c))
+	if err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeNotFound, err))
+		return
+	}
+
+	c.JSON(http.StatusOK, client)
+}
+
+// ListOrders handles listing the client's own sales orders
+// @Summary List my orders
+// @Description List sales orders belonging to the authenticated client
+// @Tags portal
+// @Security BearerAuth
+// @Produce json
+// @Param status query string false "Order Status"
+// @Param payment_status query string false "Payment Status"
+// @Success 200 {array} entity.SalesOrder
+// @Failure 500 {object} ErrorResponse
+// @Router /portal/orders [get]
+func (h *PortalHandlers) ListOrders(c *gin.Context) {
+	filter := &entity.SalesOrderFilter{}
+	if status := c.Query("status"); status != "" {
+		orderStatus := entity.SalesOrderStatus(status)
+		filter.Status = &orderStatus
+	}
+	if paymentStatus := c.Query("payment_status"); paymentStatus != "" {
+		ps := entity.PaymentStatus(paymentStatus)
+		filter.PaymentStatus = &ps
+	}
+
+	orders, err := h.portalUseCase.ListOrders(c.Request.Context(), auth.GetClientIDFromContext(c), filter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, orders)
+}
+
+// GetOrder handles retrieving one of the client's sales orders
+// @Summary Get my order
+// @Description Get a sales order belonging to the authenticated client
+// @Tags portal
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Sales Order ID"
+// @Success 200 {object} entity.SalesOrder
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /portal/orders/{id} [get]
+func (h *PortalHandlers) GetOrder(c *gin.Context) {
+	order, err := h.portalUseCase.GetOrder(c.Request.Context(), auth.GetClientIDFromContext(c), c.Param("id"))
+	if err != nil {
+		respondPortalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, order)
+}
+
+// ListDeliveries handles listing deliveries for the client's orders
+// @Summary List my deliveries
+// @Description List delivery orders for sales orders belonging to the authenticated client
+// @Tags portal
+// @Security BearerAuth
+// @Produce json
+// @Param status query string false "Delivery Status"
+// @Success 200 {array} entity.DeliveryOrder
+// @Failure 500 {object} ErrorResponse
+// @Router /portal/deliveries [get]
+func (h *PortalHandlers) ListDeliveries(c *gin.Context) {
+	filter := &entity.DeliveryOrderFilter{}
+	if status := c.Query("status"); status != "" {
+		deliveryStatus := entity.DeliveryOrderStatus(status)
+		filter.Status = &deliveryStatus
+	}
+
+	deliveries, err := h.portalUseCase.ListDeliveries(c.Request.Context(), auth.GetClientIDFromContext(c), filter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// GetDelivery handles retrieving a delivery for one of the client's orders
+// @Summary Get my delivery
+// @Description Get a delivery order belonging to the authenticated client
+// @Tags portal
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Delivery Order ID"
+// @Success 200 {object} entity.DeliveryOrder
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /portal/deliveries/{id} [get]
+func (h *PortalHandlers) GetDelivery(c *gin.Context) {
+	delivery, err := h.portalUseCase.GetDelivery(c.Request.Context(), auth.GetClientIDFromContext(c), c.Param("id"))
+	if err != nil {
+		respondPortalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, delivery)
+}
+
+// ListInvoices handles listing invoices for the client's orders
+// @Summary List my invoices
+// @Description List invoices for sales orders belonging to the authenticated client
+// @Tags portal
+// @Security BearerAuth
+// @Produce json
+// @Param status query string false "Invoice Status"
+// @Success 200 {array} entity.Invoice
+// @Failure 500 {object} ErrorResponse
+// @Router /portal/invoices [get]
+func (h *PortalHandlers) ListInvoices(c *gin.Context) {
+	filter := &entity.InvoiceFilter{}
+	if status := c.Query("status"); status != "" {
+		invoiceStatus := entity.InvoiceStatus(status)
+		filter.Status = &invoiceStatus
+	}
+
+	invoices, err := h.portalUseCase.ListInvoices(c.Request.Context(), auth.GetClientIDFromContext(c), filter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, invoices)
+}
+
+// GetInvoice handles retrieving an invoice for one of the client's orders
+// @Summary Get my invoice
+// @Description Get an invoice belonging to the authenticated client
+// @Tags portal
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Invoice ID"
+// @Success 200 {object} entity.Invoice
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /portal/invoices/{id} [get]
+func (h *PortalHandlers) GetInvoice(c *gin.Context) {
+	invoice, err := h.portalUseCase.GetInvoice(c.Request.Context(), auth.GetClientIDFromContext(c), c.Param("id"))
+	if err != nil {
+		respondPortalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, invoice)
+}
+
+// GetPaymentStatus handles retrieving the client's payment summary
+// @Summary Get my payment status
+// @Description Get invoiced, paid, outstanding and overdue totals for the authenticated client
+// @Tags portal
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} entity.PortalPaymentStatus
+// @Failure 500 {object} ErrorResponse
+// @Router /portal/payment-status [get]
+func (h *PortalHandlers) GetPaymentStatus(c *gin.Context) {
+	status, err := h.portalUseCase.GetPaymentStatus(c.Request.Context(), auth.GetClientIDFromContext(c))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// respondPortalError hides the existence of other clients' documents behind a 404
+func respondPortalError(c *gin.Context, err error) {
+	if errors.Is(err, usecase.ErrPortalAccessDenied) || errors.Is(err, repository.ErrRecordNotFound) {
+		c.Error(entity.NewError(entity.ErrCodeNotFound, "not found"))
+		return
+	}
+	c.Error(err)
+}
@@ -0,0 +1,219 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/server/middleware"
+)
+
+// PrepaymentHandlers handles customer deposit and vendor advance HTTP requests
+type PrepaymentHandlers struct {
+	prepaymentUseCase *usecase.PrepaymentUseCase
+}
+
+// NewPrepaymentHandlers creates a new prepayment handlers instance
+func NewPrepaymentHandlers(prepaymentUseCase *usecase.PrepaymentUseCase) *PrepaymentHandlers {
+	return &PrepaymentHandlers{
+		prepaymentUseCase: prepaymentUseCase,
+	}
+}
+
+// RegisterRoutes registers prepayment routes
+func (h *PrepaymentHandlers) RegisterRoutes(router *gin.RouterGroup) {
+	prepayments := router.Group("/finance/prepayments")
+	{
+		prepayments.POST("", middleware.PermissionMiddleware(entity.FinancePrepaymentCreate), h.CreatePrepayment)
+		prepayments.GET("", middleware.PermissionMiddleware(entity.FinancePrepaymentRead), h.ListPrepayments)
+		prepayments.GET("/balances", middleware.PermissionMiddleware(entity.FinancePrepaymentRead), h.GetPrepaymentBalances)
+		prepayments.GET("/:id", middleware.PermissionMiddleware(entity.FinancePrepaymentRead), h.GetPrepayment)
+		prepayments.POST("/:id/cancel", middleware.PermissionMiddleware(entity.FinancePrepaymentCancel), h.CancelPrepayment)
+	}
+}
+
+// CreatePrepayment handles recording a customer deposit or a vendor advance
+// @Summary Create prepayment
+// @Description Record a deposit a customer paid against a sales order, or an advance paid to a vendor against a purchase order, in the order currency. Its unapplied balance is applied to the invoices of the order as they are created.
+// @Tags Finance
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body entity.CreatePrepaymentRequest true "Prepayment"
+// @Success 201 {object} entity.Prepayment
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /finance/prepayments [post]
+func (h *PrepaymentHandlers) CreatePrepayment(c *gin.Context) {
+	userID := currentUserID(c)
+	if userID == nil {
+		c.Error(entity.NewError(entity.ErrCodeUnauthenticated, "user not authenticated"))
+		return
+	}
+
+	var req entity.CreatePrepaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	prepayment, err := h.prepaymentUseCase.Create(c.Request.Context(), &req, *userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, prepayment)
+}
+
+// ListPrepayments handles listing prepayments
+// @Summary List prepayments
+// @Description List customer deposits and vendor advances, latest first
+// @Tags Finance
+// @Security BearerAuth
+// @Produce json
+// @Param type query string false "Prepayment type (CUSTOMER_DEPOSIT/VENDOR_ADVANCE)"
+// @Param order_id query string false "Sales or purchase order ID"
+// @Param entity_type query string false "Entity type (CUSTOMER/SUPPLIER)"
+// @Param entity_id query int false "Client or vendor ID"
+// @Param status query string false "Prepayment status (OPEN/APPLIED/CANCELLED)"
+// @Param page query int false "Page number"
+// @Param page_size query int false "Page size"
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} ErrorResponse
+// @Router /finance/prepayments [get]
+func (h *PrepaymentHandlers) ListPrepayments(c *gin.Context) {
+	filter := &entity.PrepaymentFilter{
+		Type:       entity.PrepaymentType(c.Query("type")),
+		OrderID:    c.Query("order_id"),
+		EntityType: c.Query("entity_type"),
+		Status:     entity.PrepaymentStatus(c.Query("status")),
+	}
+
+	if entityID, err := strconv.ParseUint(c.Query("entity_id"), 10, 32); err == nil {
+		filter.EntityID = uint(entityID)
+	}
+
+	if page, err := strconv.Atoi(c.Query("page")); err == nil {
+		filter.Page = page
+	}
+
+	if pageSize, err := strconv.Atoi(c.Query("page_size")); err == nil {
+		filter.PageSize = pageSize
+	}
+
+	prepayments, total, err := h.prepaymentUseCase.List(c.Request.Context(), filter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"prepayments": prepayments,
+		"total":       total,
+		"page":        filter.Page,
+		"page_size":   filter.PageSize,
+	})
+}
+
+// GetPrepaymentBalances handles the unapplied prepayment balances
+// @Summary Get prepayment balances
+// @Description Total the unapplied deposits of each customer and advances to each vendor by currency, or those of one customer or vendor
+// @Tags Finance
+// @Security BearerAuth
+// @Produce json
+// @Param entity_type query string false "Entity type (CUSTOMER/SUPPLIER)"
+// @Param entity_id query int false "Client or vendor ID"
+// @Success 200 {array} entity.PrepaymentBalance
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /finance/prepayments/balances [get]
+func (h *PrepaymentHandlers) GetPrepaymentBalances(c *gin.Context) {
+	var entityID uint
+	if c.Query("entity_id") != "" {
+		id, err := strconv.ParseUint(c.Query("entity_id"), 10, 32)
+		if err != nil {
+			c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid entity ID"))
+			return
+		}
+		entityID = uint(id)
+	}
+
+	balances, err := h.prepaymentUseCase.Balances(c.Request.Context(), c.Query("entity_type"), entityID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, balances)
+}
+
+// GetPrepayment handles getting a prepayment
+// @Summary Get prepayment
+// @Description Get a prepayment with the invoices it was applied to
+// @Tags Finance
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Prepayment ID"
+// @Success 200 {object} entity.Prepayment
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /finance/prepayments/{id} [get]
+func (h *PrepaymentHandlers) GetPrepayment(c *gin.Context) {
+	id, ok := parsePrepaymentID(c)
+	if !ok {
+		return
+	}
+
+	prepayment, err := h.prepaymentUseCase.Get(c.Request.Context(), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, prepayment)
+}
+
+// CancelPrepayment handles cancelling a prepayment
+// @Summary Cancel prepayment
+// @Description Cancel a prepayment recorded in error or refunded. A prepayment applied to invoices cannot be cancelled until the invoices are.
+// @Tags Finance
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Prepayment ID"
+// @Success 200 {object} entity.Prepayment
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /finance/prepayments/{id}/cancel [post]
+func (h *PrepaymentHandlers) CancelPrepayment(c *gin.Context) {
+	id, ok := parsePrepaymentID(c)
+	if !ok {
+		return
+	}
+
+	prepayment, err := h.prepaymentUseCase.Cancel(c.Request.Context(), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, prepayment)
+}
+
+// parsePrepaymentID reads the prepayment ID path parameter, responding with a
+// bad request when it is not a number
+func parsePrepaymentID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid prepayment ID"))
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -0,0 +1,254 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/server/middleware"
+)
+
+// ProvisionHandlers handles inventory write-down HTTP requests
+type ProvisionHandlers struct {
+	provisionUseCase *usecase.ProvisionUseCase
+}
+
+// NewProvisionHandlers creates a new provision handlers instance
+func NewProvisionHandlers(provisionUseCase *usecase.ProvisionUseCase) *ProvisionHandlers {
+	return &ProvisionHandlers{
+		provisionUseCase: provisionUseCase,
+	}
+}
+
+// RegisterRoutes registers inventory write-down routes
+func (h *ProvisionHandlers) RegisterRoutes(router *gin.RouterGroup) {
+	provisionRouter := router.Group("/stocks/provisions")
+	{
+		provisionRouter.GET("/rules", middleware.PermissionMiddleware(entity.StockProvisionRead), h.ListRules)
+		provisionRouter.POST("/rules", middleware.PermissionMiddleware(entity.StockProvisionManage), h.CreateRule)
+		provisionRouter.PUT("/rules/:id", middleware.PermissionMiddleware(entity.StockProvisionManage), h.UpdateRule)
+		provisionRouter.DELETE("/rules/:id", middleware.PermissionMiddleware(entity.StockProvisionManage), h.DeleteRule)
+
+		provisionRouter.GET("", middleware.PermissionMiddleware(entity.StockProvisionRead), h.ListEntries)
+		provisionRouter.GET("/preview", middleware.PermissionMiddleware(entity.StockProvisionRead), h.Preview)
+		provisionRouter.POST("/post", middleware.PermissionMiddleware(entity.StockProvisionManage), h.Post)
+	}
+}
+
+// ListRules handles listing write-down rules
+// @Summary List write-down rules
+// @Description List the inventory aging write-down rules
+// @Tags stocks
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} entity.WriteDownRule
+// @Failure 500 {object} ErrorResponse
+// @Router /stocks/provisions/rules [get]
+func (h *ProvisionHandlers) ListRules(c *gin.Context) {
+	rules, err := h.provisionUseCase.ListRules(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rules)
+}
+
+// CreateRule handles creating a write-down rule
+// @Summary Create write-down rule
+// @Description Create a rule provisioning a percentage of the value of stock older than a number of days
+// @Tags stocks
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param rule body entity.WriteDownRuleRequest true "Rule details"
+// @Success 201 {object} entity.WriteDownRule
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /stocks/provisions/rules [post]
+func (h *ProvisionHandlers) CreateRule(c *gin.Context) {
+	var req entity.WriteDownRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	rule, err := h.provisionUseCase.CreateRule(c.Request.Context(), &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, rule)
+}
+
+// UpdateRule handles updating a write-down rule
+// @Summary Update write-down rule
+// @Description Update an inventory aging write-down rule
+// @Tags stocks
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Rule ID"
+// @Param rule body entity.WriteDownRuleRequest true "Rule details"
+// @Success 200 {object} entity.WriteDownRule
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /stocks/provisions/rules/{id} [put]
+func (h *ProvisionHandlers) UpdateRule(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid rule ID"))
+		return
+	}
+
+	var req entity.WriteDownRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	rule, err := h.provisionUseCase.UpdateRule(c.Request.Context(), uint(id), &req)
+	if err != nil {
+		if errors.Is(err, usecase.ErrWriteDownRuleNotFound) {
+			c.Error(entity.WrapError(entity.ErrCodeNotFound, err))
+			return
+		}
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+// DeleteRule handles deleting a write-down rule
+// @Summary Delete write-down rule
+// @Description Delete an inventory aging write-down rule. Provisions already posted are kept.
+// @Tags stocks
+// @Security BearerAuth
+// @Param id path int true "Rule ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /stocks/provisions/rules/{id} [delete]
+func (h *ProvisionHandlers) DeleteRule(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid rule ID"))
+		return
+	}
+
+	if err := h.provisionUseCase.DeleteRule(c.Request.Context(), uint(id)); err != nil {
+		if errors.Is(err, usecase.ErrWriteDownRuleNotFound) {
+			c.Error(entity.WrapError(entity.ErrCodeNotFound, err))
+			return
+		}
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListEntries handles listing the provision ledger
+// @Summary List provision ledger entries
+// @Description List inventory provision and release postings
+// @Tags stocks
+// @Security BearerAuth
+// @Produce json
+// @Param period query string false "Period (YYYY-MM)"
+// @Param stock_id query string false "Stock ID"
+// @Param type query string false "Entry type (PROVISION/RELEASE)"
+// @Param page query int false "Page number"
+// @Param page_size query int false "Page size"
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} ErrorResponse
+// @Router /stocks/provisions [get]
+func (h *ProvisionHandlers) ListEntries(c *gin.Context) {
+	filter := &entity.InventoryProvisionFilter{
+		Period:  c.Query("period"),
+		StockID: c.Query("stock_id"),
+		Type:    entity.ProvisionEntryType(c.Query("type")),
+	}
+
+	if page, err := strconv.Atoi(c.Query("page")); err == nil {
+		filter.Page = page
+	}
+
+	if pageSize, err := strconv.Atoi(c.Query("page_size")); err == nil {
+		filter.PageSize = pageSize
+	}
+
+	entries, total, err := h.provisionUseCase.ListEntries(c.Request.Context(), filter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"entries":   entries,
+		"total":     total,
+		"page":      filter.Page,
+		"page_size": filter.PageSize,
+	})
+}
+
+// Preview handles previewing the provisions required by the write-down rules
+// @Summary Preview inventory provisions
+// @Description Age stock on hand first-in first-out, apply the write-down rules and compare with the provision held, without posting
+// @Tags stocks
+// @Security BearerAuth
+// @Produce json
+// @Param as_of_date query string false "As of date (YYYY-MM-DD)"
+// @Success 200 {array} entity.StockProvision
+// @Failure 500 {object} ErrorResponse
+// @Router /stocks/provisions/preview [get]
+func (h *ProvisionHandlers) Preview(c *gin.Context) {
+	var asOfDate time.Time
+	if asOfDateStr := c.Query("as_of_date"); asOfDateStr != "" {
+		if date, err := time.Parse("2006-01-02", asOfDateStr); err == nil {
+			asOfDate = date
+		}
+	}
+
+	provisions, err := h.provisionUseCase.Preview(c.Request.Context(), asOfDate)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, provisions)
+}
+
+// Post handles posting the provisions of a period to the ledger
+// @Summary Post inventory provisions
+// @Description Post provision changes for a month to the provision ledger, releasing provisions of aged stock that has sold
+// @Tags stocks
+// @Security BearerAuth
+// @Produce json
+// @Param period query string false "Period (YYYY-MM), defaults to the current month"
+// @Success 200 {object} entity.ProvisionRunResult
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /stocks/provisions/post [post]
+func (h *ProvisionHandlers) Post(c *gin.Context) {
+	period := c.DefaultQuery("period", time.Now().Format("2006-01"))
+
+	result, err := h.provisionUseCase.Post(c.Request.Context(), period, currentUserID(c))
+	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidPeriod) || errors.Is(err, usecase.ErrPeriodInFuture) {
+			c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+			return
+		}
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/server/middleware"
+)
+
+// PurchaseConsolidationHandlers handles HTTP requests for ordering the lines
+// of several purchase requests, one purchase order per vendor
+type PurchaseConsolidationHandlers struct {
+	consolidationUseCase *usecase.PurchaseConsolidationUseCase
+}
+
+// NewPurchaseConsolidationHandlers creates a new purchase consolidation handlers instance
+func NewPurchaseConsolidationHandlers(consolidationUseCase *usecase.PurchaseConsolidationUseCase) *PurchaseConsolidationHandlers {
+	return &PurchaseConsolidationHandlers{
+		consolidationUseCase: consolidationUseCase,
+	}
+}
+
+// RegisterRoutes registers consolidation routes beside the purchase order
+// and request routes
+func (h *PurchaseConsolidationHandlers) RegisterRoutes(router *gin.RouterGroup) {
+	router.POST("/purchase/orders/consolidation/plan", middleware.PermissionMiddleware(entity.PurchaseOrderCreate), h.Plan)
+	router.POST("/purchase/orders/consolidate", middleware.PermissionMiddleware(entity.PurchaseOrderCreate), h.Consolidate)
+	router.GET("/purchase/requests/:id/orders", middleware.PermissionMiddleware(entity.PurchaseRequestRead), h.ListRequestOrders)
+}
+
+// Plan handles grouping the lines of purchase requests by vendor
+// @Summary Plan a purchase request consolidation
+// @Description Group the lines of approved purchase requests not ordered yet by the preferred vendor of their SKU, the lines of SKUs without one coming last under vendor 0. The buyer adjusts the groups and posts them to /purchase/orders/consolidate.
+// @Tags purchase-orders
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param plan body entity.ConsolidationPlanRequest true "Purchase requests"
+// @Success 200 {object} entity.ConsolidationPlan
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse "Purchase request not approved or already ordered"
+// @Failure 500 {object} ErrorResponse
+// @Router /purchase/orders/consolidation/plan [post]
+func (h *PurchaseConsolidationHandlers) Plan(c *gin.Context) {
+	var req entity.ConsolidationPlanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	plan, err := h.consolidationUseCase.Plan(c.Request.Context(), req.RequestIDs)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, plan)
+}
+
+// Consolidate handles generating the purchase orders of grouped request lines
+// @Summary Consolidate purchase requests into purchase orders
+// @Description Generate a draft purchase order per vendor group in one transaction. The groups must order every line of the purchase requests they draw from in full; a line may be split between vendors by quantity. The lines of a SKU in a group make one order line priced for the total quantity, listing the requests it orders for in sources. The requests are marked ordered and linked to their orders.
+// @Tags purchase-orders
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param consolidation body entity.ConsolidationRequest true "Request lines grouped by vendor"
+// @Success 201 {array} entity.PurchaseOrder
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse "Purchase request not approved or already ordered"
+// @Failure 500 {object} ErrorResponse
+// @Router /purchase/orders/consolidate [post]
+func (h *PurchaseConsolidationHandlers) Consolidate(c *gin.Context) {
+	userID := currentUserID(c)
+	if userID == nil {
+		c.Error(entity.NewError(entity.ErrCodeUnauthenticated, "unauthorized"))
+		return
+	}
+	var req entity.ConsolidationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	orders, err := h.consolidationUseCase.Consolidate(c.Request.Context(), &req, *userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, orders)
+}
+
+// ListRequestOrders handles listing the purchase orders of a purchase request
+// @Summary List the purchase orders of a purchase request
+// @Description List the purchase orders generated from a purchase request, several when its lines were split between vendors
+// @Tags purchase-requests
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Purchase Request ID"
+// @Success 200 {array} entity.PurchaseOrder
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /purchase/requests/{id}/orders [get]
+func (h *PurchaseConsolidationHandlers) ListRequestOrders(c *gin.Context) {
+	orders, err := h.consolidationUseCase.ListRequestOrders(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, orders)
+}
@@ -8,67 +8,73 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
 	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/server/middleware"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/service"
 )
 
 type PurchaseHandler struct {
-	purchaseUseCase *usecase.PurchaseUseCase
+	purchaseUseCase   *usecase.PurchaseUseCase
+	delegationUseCase *usecase.ApprovalDelegationUseCase
 }
 
-func NewPurchaseHandler(purchaseUseCase *usecase.PurchaseUseCase) *PurchaseHandler {
+func NewPurchaseHandler(purchaseUseCase *usecase.PurchaseUseCase, delegationUseCase *usecase.ApprovalDelegationUseCase) *PurchaseHandler {
 	return &PurchaseHandler{
-		purchaseUseCase: purchaseUseCase,
+		purchaseUseCase:   purchaseUseCase,
+		delegationUseCase: delegationUseCase,
 	}
 }
 
-// RegisterRoutes registers purchase routes
-func (h *PurchaseHandler) RegisterRoutes(router *gin.Engine) {
-	purchase := router.Group("/api/purchase")
+// RegisterRoutes registers purchase routes under /purchase of an
+// authenticated router. Approvals are open to the delegates of approvers away
+// as well.
+func (h *PurchaseHandler) RegisterRoutes(router *gin.RouterGroup) {
+	purchase := router.Group("/purchase")
 	{
 		// Purchase Request routes
 		requests := purchase.Group("/requests")
 		{
-			requests.POST("", h.CreatePurchaseRequest)
-			requests.GET("", h.ListPurchaseRequests)
-			requests.GET("/:id", h.GetPurchaseRequest)
-			requests.PUT("/:id", h.UpdatePurchaseRequest)
-			requests.DELETE("/:id", h.DeletePurchaseRequest)
-			requests.POST("/:id/submit", h.SubmitPurchaseRequest)
-			requests.POST("/:id/approve", h.ApprovePurchaseRequest)
-			requests.POST("/:id/reject", h.RejectPurchaseRequest)
-			requests.POST("/:id/order", h.CreateOrderFromRequest)
+			requests.POST("", middleware.PermissionMiddleware(entity.PurchaseRequestCreate), h.CreatePurchaseRequest)
+			requests.GET("", middleware.PermissionMiddleware(entity.PurchaseRequestRead), h.ListPurchaseRequests)
+			requests.GET("/:id", middleware.PermissionMiddleware(entity.PurchaseRequestRead), h.GetPurchaseRequest)
+			requests.PUT("/:id", middleware.PermissionMiddleware(entity.PurchaseRequestUpdate), h.UpdatePurchaseRequest)
+			requests.DELETE("/:id", middleware.PermissionMiddleware(entity.PurchaseRequestDelete), h.DeletePurchaseRequest)
+			requests.POST("/:id/submit", middleware.PermissionMiddleware(entity.PurchaseRequestUpdate), h.SubmitPurchaseRequest)
+			requests.POST("/:id/approve", middleware.ApprovalMiddleware(entity.PurchaseRequestApprove, h.delegationUseCase), h.ApprovePurchaseRequest)
+			requests.POST("/:id/reject", middleware.ApprovalMiddleware(entity.PurchaseRequestApprove, h.delegationUseCase), h.RejectPurchaseRequest)
+			requests.POST("/:id/order", middleware.PermissionMiddleware(entity.PurchaseOrderCreate), h.CreateOrderFromRequest)
 		}
 
 		// Purchase Order routes
 		orders := purchase.Group("/orders")
 		{
-			orders.POST("", h.CreatePurchaseOrder)
-			orders.GET("", h.ListPurchaseOrders)
-			orders.GET("/:id", h.GetPurchaseOrder)
-			orders.PUT("/:id", h.UpdatePurchaseOrder)
-			orders.DELETE("/:id", h.DeletePurchaseOrder)
-			orders.POST("/:id/submit", h.SubmitPurchaseOrder)
-			orders.POST("/:id/approve", h.ApprovePurchaseOrder)
-			orders.POST("/:id/send", h.SendPurchaseOrder)
-			orders.POST("/:id/confirm", h.ConfirmPurchaseOrder)
-			orders.POST("/:id/cancel", h.CancelPurchaseOrder)
-			orders.POST("/:id/close", h.ClosePurchaseOrder)
-			orders.GET("/:id/receipts", h.ListPurchaseReceiptsByOrder)
-			orders.GET("/:id/payments", h.ListPurchasePaymentsByOrder)
-			orders.GET("/:id/payment-summary", h.GetPurchaseOrderPaymentSummary)
+			orders.POST("", middleware.PermissionMiddleware(entity.PurchaseOrderCreate), h.CreatePurchaseOrder)
+			orders.GET("", middleware.PermissionMiddleware(entity.PurchaseOrderRead), h.ListPurchaseOrders)
+			orders.GET("/:id", middleware.PermissionMiddleware(entity.PurchaseOrderRead), h.GetPurchaseOrder)
+			orders.PUT("/:id", middleware.PermissionMiddleware(entity.PurchaseOrderUpdate), h.UpdatePurchaseOrder)
+			orders.DELETE("/:id", middleware.PermissionMiddleware(entity.PurchaseOrderDelete), h.DeletePurchaseOrder)
+			orders.POST("/:id/submit", middleware.PermissionMiddleware(entity.PurchaseOrderUpdate), h.SubmitPurchaseOrder)
+			orders.POST("/:id/approve", middleware.ApprovalMiddleware(entity.PurchaseOrderApprove, h.delegationUseCase), h.ApprovePurchaseOrder)
+			orders.POST("/:id/send", middleware.PermissionMiddleware(entity.PurchaseOrderUpdate), h.SendPurchaseOrder)
+			orders.POST("/:id/confirm", middleware.PermissionMiddleware(entity.PurchaseOrderUpdate), h.ConfirmPurchaseOrder)
+			orders.POST("/:id/cancel", middleware.PermissionMiddleware(entity.PurchaseOrderUpdate), h.CancelPurchaseOrder)
+			orders.POST("/:id/close", middleware.PermissionMiddleware(entity.PurchaseOrderUpdate), h.ClosePurchaseOrder)
+			orders.GET("/:id/receipts", middleware.PermissionMiddleware(entity.PurchaseReceiptRead), h.ListPurchaseReceiptsByOrder)
+			orders.GET("/:id/payments", middleware.PermissionMiddleware(entity.PurchasePaymentRead), h.ListPurchasePaymentsByOrder)
+			orders.GET("/:id/payment-summary", middleware.PermissionMiddleware(entity.PurchasePaymentRead), h.GetPurchaseOrderPaymentSummary)
 		}
 
 		// Purchase Receipt routes
 		receipts := purchase.Group("/receipts")
 		{
-			receipts.POST("", h.CreatePurchaseReceipt)
-			receipts.GET("/:id", h.GetPurchaseReceipt)
+			receipts.POST("", middleware.PermissionMiddleware(entity.PurchaseReceiptCreate), h.CreatePurchaseReceipt)
+			receipts.GET("/:id", middleware.PermissionMiddleware(entity.PurchaseReceiptRead), h.GetPurchaseReceipt)
 		}
 
 		// Purchase Payment routes
 		payments := purchase.Group("/payments")
 		{
-			payments.POST("", h.CreatePurchasePayment)
-			payments.GET("/:id", h.GetPurchasePayment)
+			payments.POST("", middleware.PermissionMiddleware(entity.PurchasePaymentCreate), h.CreatePurchasePayment)
+			payments.GET("/:id", middleware.PermissionMiddleware(entity.PurchasePaymentRead), h.GetPurchasePayment)
 		}
 	}
 }
@@ -88,20 +94,20 @@ func (h *PurchaseHandler) RegisterRoutes(router *gin.Engine) {
 func (h *PurchaseHandler) CreatePurchaseRequest(c *gin.Context) {
 	var request entity.PurchaseRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 
 	// Get user ID from context (set by auth middleware)
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "user not authenticated"})
+		c.Error(entity.NewError(entity.ErrCodeUnauthenticated, "user not authenticated"))
 		return
 	}
 	request.RequesterID = userID.(uint)
 
 	if err := h.purchaseUseCase.CreatePurchaseRequest(c.Request.Context(), &request); err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -123,7 +129,7 @@ func (h *PurchaseHandler) GetPurchaseRequest(c *gin.Context) {
 
 	request, err := h.purchaseUseCase.GetPurchaseRequest(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeNotFound, err))
 		return
 	}
 
@@ -146,14 +152,14 @@ func (h *PurchaseHandler) UpdatePurchaseRequest(c *gin.Context) {
 
 	var request entity.PurchaseRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 
 	request.ID = id
 
 	if err := h.purchaseUseCase.UpdatePurchaseRequest(c.Request.Context(), &request); err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -174,7 +180,7 @@ func (h *PurchaseHandler) DeletePurchaseRequest(c *gin.Context) {
 	id := c.Param("id")
 
 	if err := h.purchaseUseCase.DeletePurchaseRequest(c.Request.Context(), id); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 
@@ -230,7 +236,7 @@ func (h *PurchaseHandler) ListPurchaseRequests(c *gin.Context) {
 
 	requests, total, err := h.purchaseUseCase.ListPurchaseRequests(c.Request.Context(), filter, page, pageSize)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -256,7 +262,7 @@ func (h *PurchaseHandler) SubmitPurchaseRequest(c *gin.Context) {
 	id := c.Param("id")
 
 	if err := h.purchaseUseCase.SubmitPurchaseRequest(c.Request.Context(), id); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 
@@ -264,7 +270,7 @@ func (h *PurchaseHandler) SubmitPurchaseRequest(c *gin.Context) {
 }
 
 // @Summary Approve a purchase request
-// @Description Approve a purchase request
+// @Description Approve a purchase request, as an approver or the delegate of an approver away
 // @Tags purchase-requests
 // @Security BearerAuth
 // @Accept json
@@ -273,6 +279,7 @@ func (h *PurchaseHandler) SubmitPurchaseRequest(c *gin.Context) {
 // @Param approval body map[string]interface{} true "Approval details"
 // @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Router /purchase/requests/{id}/approve [post]
 func (h *PurchaseHandler) ApprovePurchaseRequest(c *gin.Context) {
 	id := c.Param("id")
@@ -282,19 +289,17 @@ func (h *PurchaseHandler) ApprovePurchaseRequest(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&data); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 
-	// Get user ID from context (set by auth middleware)
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "user not authenticated"})
+	userID, onBehalfOf, ok := h.approver(c)
+	if !ok {
 		return
 	}
 
-	if err := h.purchaseUseCase.ApprovePurchaseRequest(c.Request.Context(), id, userID.(uint), data.Notes); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	if err := h.purchaseUseCase.ApprovePurchaseRequest(c.Request.Context(), id, userID, onBehalfOf, data.Notes); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 
@@ -302,7 +307,7 @@ func (h *PurchaseHandler) ApprovePurchaseRequest(c *gin.Context) {
 }
 
 // @Summary Reject a purchase request
-// @Description Reject a purchase request
+// @Description Reject a purchase request, as an approver or the delegate of an approver away
 // @Tags purchase-requests
 // @Security BearerAuth
 // @Accept json
@@ -311,6 +316,7 @@ func (h *PurchaseHandler) ApprovePurchaseRequest(c *gin.Context) {
 // @Param rejection body map[string]interface{} true "Rejection details"
 // @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Router /purchase/requests/{id}/reject [post]
 func (h *PurchaseHandler) RejectPurchaseRequest(c *gin.Context) {
 	id := c.Param("id")
@@ -320,19 +326,17 @@ func (h *PurchaseHandler) RejectPurchaseRequest(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&data); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 
-	// Get user ID from context (set by auth middleware)
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "user not authenticated"})
+	userID, onBehalfOf, ok := h.approver(c)
+	if !ok {
 		return
 	}
 
-	if err := h.purchaseUseCase.RejectPurchaseRequest(c.Request.Context(), id, userID.(uint), data.Notes); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	if err := h.purchaseUseCase.RejectPurchaseRequest(c.Request.Context(), id, userID, onBehalfOf, data.Notes); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 
@@ -358,20 +362,20 @@ func (h *PurchaseHandler) CreateOrderFromRequest(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&data); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 
 	// Get user ID from context (set by auth middleware)
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "user not authenticated"})
+		c.Error(entity.NewError(entity.ErrCodeUnauthenticated, "user not authenticated"))
 		return
 	}
 
 	order, err := h.purchaseUseCase.CreatePurchaseOrderFromRequest(c.Request.Context(), id, data.SupplierID, userID.(uint))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 
@@ -393,20 +397,20 @@ func (h *PurchaseHandler) CreateOrderFromRequest(c *gin.Context) {
 func (h *PurchaseHandler) CreatePurchaseOrder(c *gin.Context) {
 	var order entity.PurchaseOrder
 	if err := c.ShouldBindJSON(&order); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 
 	// Get user ID from context (set by auth middleware)
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "user not authenticated"})
+		c.Error(entity.NewError(entity.ErrCodeUnauthenticated, "user not authenticated"))
 		return
 	}
 	order.CreatedByID = userID.(uint)
 
 	if err := h.purchaseUseCase.CreatePurchaseOrder(c.Request.Context(), &order); err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -428,7 +432,7 @@ func (h *PurchaseHandler) GetPurchaseOrder(c *gin.Context) {
 
 	order, err := h.purchaseUseCase.GetPurchaseOrder(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeNotFound, err))
 		return
 	}
 
@@ -451,14 +455,14 @@ func (h *PurchaseHandler) UpdatePurchaseOrder(c *gin.Context) {
 
 	var order entity.PurchaseOrder
 	if err := c.ShouldBindJSON(&order); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 
 	order.ID = id
 
 	if err := h.purchaseUseCase.UpdatePurchaseOrder(c.Request.Context(), &order); err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -479,7 +483,7 @@ func (h *PurchaseHandler) DeletePurchaseOrder(c *gin.Context) {
 	id := c.Param("id")
 
 	if err := h.purchaseUseCase.DeletePurchaseOrder(c.Request.Context(), id); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 
@@ -501,11 +505,18 @@ func (h *PurchaseHandler) DeletePurchaseOrder(c *gin.Context) {
 // @Param item_id query string false "Item ID"
 // @Param page query integer false "Page number"
 // @Param page_size query integer false "Page size"
+// @Param archived query boolean false "List archived orders instead of live ones"
+// @Param fields query string false "Only return these fields of each order, e.g. id,order_number,status"
 // @Success 200 {object} map[string]interface{}
 // @Router /purchase/orders [get]
 func (h *PurchaseHandler) ListPurchaseOrders(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	fields, err := parseFieldSet[entity.PurchaseOrder](c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
 
 	filter := &entity.PurchaseOrderFilter{
 		OrderNumber: c.Query("order_number"),
@@ -527,6 +538,10 @@ func (h *PurchaseHandler) ListPurchaseOrders(c *gin.Context) {
 		filter.PaymentStatus = &pmtStatus
 	}
 
+	if archived, err := strconv.ParseBool(c.Query("archived")); err == nil {
+		filter.Archived = archived
+	}
+
 	if startDateStr := c.Query("start_date"); startDateStr != "" {
 		if startDate, err := time.Parse("2006-01-02", startDateStr); err == nil {
 			filter.StartDate = &startDate
@@ -541,12 +556,12 @@ func (h *PurchaseHandler) ListPurchaseOrders(c *gin.Context) {
 
 	orders, total, err := h.purchaseUseCase.ListPurchaseOrders(c.Request.Context(), filter, page, pageSize)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"orders":    orders,
+		"orders":    fields.pick(orders),
 		"total":     total,
 		"page":      page,
 		"page_size": pageSize,
@@ -567,7 +582,7 @@ func (h *PurchaseHandler) SubmitPurchaseOrder(c *gin.Context) {
 	id := c.Param("id")
 
 	if err := h.purchaseUseCase.SubmitPurchaseOrder(c.Request.Context(), id); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 
@@ -575,7 +590,7 @@ func (h *PurchaseHandler) SubmitPurchaseOrder(c *gin.Context) {
 }
 
 // @Summary Approve a purchase order
-// @Description Approve a purchase order
+// @Description Approve a purchase order, as an approver or the delegate of an approver away
 // @Tags purchase-orders
 // @Security BearerAuth
 // @Accept json
@@ -583,19 +598,18 @@ func (h *PurchaseHandler) SubmitPurchaseOrder(c *gin.Context) {
 // @Param id path string true "Purchase Order ID"
 // @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Router /purchase/orders/{id}/approve [post]
 func (h *PurchaseHandler) ApprovePurchaseOrder(c *gin.Context) {
 	id := c.Param("id")
 
-	// Get user ID from context (set by auth middleware)
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "user not authenticated"})
+	userID, onBehalfOf, ok := h.approver(c)
+	if !ok {
 		return
 	}
 
-	if err := h.purchaseUseCase.ApprovePurchaseOrder(c.Request.Context(), id, userID.(uint)); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	if err := h.purchaseUseCase.ApprovePurchaseOrder(c.Request.Context(), id, userID, onBehalfOf); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 
@@ -616,7 +630,7 @@ func (h *PurchaseHandler) SendPurchaseOrder(c *gin.Context) {
 	id := c.Param("id")
 
 	if err := h.purchaseUseCase.SendPurchaseOrder(c.Request.Context(), id); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 
@@ -637,7 +651,7 @@ func (h *PurchaseHandler) ConfirmPurchaseOrder(c *gin.Context) {
 	id := c.Param("id")
 
 	if err := h.purchaseUseCase.ConfirmPurchaseOrder(c.Request.Context(), id); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 
@@ -658,7 +672,7 @@ func (h *PurchaseHandler) CancelPurchaseOrder(c *gin.Context) {
 	id := c.Param("id")
 
 	if err := h.purchaseUseCase.CancelPurchaseOrder(c.Request.Context(), id); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 
@@ -679,7 +693,7 @@ func (h *PurchaseHandler) ClosePurchaseOrder(c *gin.Context) {
 	id := c.Param("id")
 
 	if err := h.purchaseUseCase.ClosePurchaseOrder(c.Request.Context(), id); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 
@@ -701,20 +715,20 @@ func (h *PurchaseHandler) ClosePurchaseOrder(c *gin.Context) {
 func (h *PurchaseHandler) CreatePurchaseReceipt(c *gin.Context) {
 	var receipt entity.PurchaseReceipt
 	if err := c.ShouldBindJSON(&receipt); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 
 	// Get user ID from context (set by auth middleware)
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "user not authenticated"})
+		c.Error(entity.NewError(entity.ErrCodeUnauthenticated, "user not authenticated"))
 		return
 	}
 	receipt.ReceivedByID = userID.(uint)
 
 	if err := h.purchaseUseCase.CreatePurchaseReceipt(c.Request.Context(), &receipt, userID.(string)); err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -736,7 +750,7 @@ func (h *PurchaseHandler) GetPurchaseReceipt(c *gin.Context) {
 
 	receipt, err := h.purchaseUseCase.GetPurchaseReceipt(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeNotFound, err))
 		return
 	}
 
@@ -758,7 +772,7 @@ func (h *PurchaseHandler) ListPurchaseReceiptsByOrder(c *gin.Context) {
 
 	receipts, err := h.purchaseUseCase.ListPurchaseReceiptsByOrder(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -780,20 +794,20 @@ func (h *PurchaseHandler) ListPurchaseReceiptsByOrder(c *gin.Context) {
 func (h *PurchaseHandler) CreatePurchasePayment(c *gin.Context) {
 	var payment entity.PurchasePayment
 	if err := c.ShouldBindJSON(&payment); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 
 	// Get user ID from context (set by auth middleware)
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "user not authenticated"})
+		c.Error(entity.NewError(entity.ErrCodeUnauthenticated, "user not authenticated"))
 		return
 	}
 	payment.CreatedByID = userID.(uint)
 
 	if err := h.purchaseUseCase.CreatePurchasePayment(c.Request.Context(), &payment); err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -815,7 +829,7 @@ func (h *PurchaseHandler) GetPurchasePayment(c *gin.Context) {
 
 	payment, err := h.purchaseUseCase.GetPurchasePayment(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeNotFound, err))
 		return
 	}
 
@@ -837,7 +851,7 @@ func (h *PurchaseHandler) ListPurchasePaymentsByOrder(c *gin.Context) {
 
 	payments, err := h.purchaseUseCase.ListPurchasePaymentsByOrder(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -859,9 +873,34 @@ func (h *PurchaseHandler) GetPurchaseOrderPaymentSummary(c *gin.Context) {
 
 	summary, err := h.purchaseUseCase.GetPurchaseOrderPaymentSummary(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
 	c.JSON(http.StatusOK, summary)
 }
+
+// approver returns the user approving and, when they only hold the approval
+// permission by delegation, the approver away they stand in for, who is
+// recorded in the audit log too
+func (h *PurchaseHandler) approver(c *gin.Context) (uint, *uint, bool) {
+	return resolveApprover(c)
+}
+
+// resolveApprover returns the user approving a document behind
+// ApprovalMiddleware, and the approver away they stand in for when it let
+// them through by delegation. It writes the response and returns false
+// without an authenticated user.
+func resolveApprover(c *gin.Context) (uint, *uint, bool) {
+	userID := currentUserID(c)
+	if userID == nil {
+		c.Error(entity.NewError(entity.ErrCodeUnauthenticated, "user not authenticated"))
+		return 0, nil, false
+	}
+
+	if value, ok := c.Get(service.OnBehalfOfKey); ok {
+		onBehalfOf := value.(uint)
+		return *userID, &onBehalfOf, true
+	}
+	return *userID, nil, true
+}
@@ -0,0 +1,265 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/auth"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/server/middleware"
+)
+
+// PutawayHandlers handles HTTP requests for putaway rules and tasks
+type PutawayHandlers struct {
+	putawayUseCase *usecase.PutawayUseCase
+}
+
+// NewPutawayHandlers creates a new putaway handlers instance
+func NewPutawayHandlers(putawayUseCase *usecase.PutawayUseCase) *PutawayHandlers {
+	return &PutawayHandlers{
+		putawayUseCase: putawayUseCase,
+	}
+}
+
+// RegisterRoutes registers putaway routes
+func (h *PutawayHandlers) RegisterRoutes(router *gin.RouterGroup) {
+	putawayRouter := router.Group("/stocks/putaway")
+	{
+		putawayRouter.POST("/rules", middleware.PermissionMiddleware(entity.StockPutawayManage), h.CreateRule)
+		putawayRouter.GET("/rules", middleware.PermissionMiddleware(entity.StockPutawayRead), h.ListRules)
+		putawayRouter.PUT("/rules/:id", middleware.PermissionMiddleware(entity.StockPutawayManage), h.UpdateRule)
+		putawayRouter.DELETE("/rules/:id", middleware.PermissionMiddleware(entity.StockPutawayManage), h.DeleteRule)
+		putawayRouter.GET("/tasks", middleware.PermissionMiddleware(entity.StockPutawayRead), h.ListTasks)
+		putawayRouter.GET("/tasks/:id", middleware.PermissionMiddleware(entity.StockPutawayRead), h.GetTask)
+		putawayRouter.POST("/tasks/:id/confirm", middleware.PermissionMiddleware(entity.StockPutawayConfirm), h.ConfirmTask)
+		putawayRouter.POST("/receipts/:id/tasks", middleware.PermissionMiddleware(entity.StockPutawayManage), h.GenerateTasks)
+	}
+}
+
+// CreateRule handles creating a putaway rule
+// @Summary Create putaway rule
+// @Description Create a rule suggesting where received goods of a store are put away: a fixed slot for a SKU, the bin the store already keeps the SKU in, or a zone for SKUs of a velocity class
+// @Tags stocks
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body entity.PutawayRuleRequest true "Putaway rule"
+// @Success 201 {object} entity.PutawayRule
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /stocks/putaway/rules [post]
+func (h *PutawayHandlers) CreateRule(c *gin.Context) {
+	var req entity.PutawayRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	rule, err := h.putawayUseCase.CreateRule(c.Request.Context(), &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, rule)
+}
+
+// ListRules handles listing putaway rules
+// @Summary List putaway rules
+// @Description List the putaway rules per store in the order they are tried
+// @Tags stocks
+// @Security BearerAuth
+// @Produce json
+// @Param store_id query string false "Store ID"
+// @Success 200 {array} entity.PutawayRule
+// @Failure 500 {object} ErrorResponse
+// @Router /stocks/putaway/rules [get]
+func (h *PutawayHandlers) ListRules(c *gin.Context) {
+	rules, err := h.putawayUseCase.ListRules(c.Request.Context(), c.Query("store_id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rules)
+}
+
+// UpdateRule handles updating a putaway rule
+// @Summary Update putaway rule
+// @Description Update a putaway rule. Tasks already generated keep their suggestion.
+// @Tags stocks
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Putaway rule ID"
+// @Param request body entity.PutawayRuleRequest true "Putaway rule"
+// @Success 200 {object} entity.PutawayRule
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /stocks/putaway/rules/{id} [put]
+func (h *PutawayHandlers) UpdateRule(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid putaway rule ID"))
+		return
+	}
+	var req entity.PutawayRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	rule, err := h.putawayUseCase.UpdateRule(c.Request.Context(), uint(id), &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+// DeleteRule handles deleting a putaway rule
+// @Summary Delete putaway rule
+// @Description Delete a putaway rule. Tasks already generated keep their suggestion.
+// @Tags stocks
+// @Security BearerAuth
+// @Param id path int true "Putaway rule ID"
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /stocks/putaway/rules/{id} [delete]
+func (h *PutawayHandlers) DeleteRule(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid putaway rule ID"))
+		return
+	}
+
+	if err := h.putawayUseCase.DeleteRule(c.Request.Context(), uint(id)); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListTasks handles listing putaway tasks
+// @Summary List putaway tasks
+// @Description List putaway tasks oldest first, such as the pending tasks of a store for a handheld scanner
+// @Tags stocks
+// @Security BearerAuth
+// @Produce json
+// @Param store_id query string false "Store ID"
+// @Param receipt_id query string false "Purchase receipt ID"
+// @Param sku_id query string false "SKU ID"
+// @Param status query string false "PENDING or CONFIRMED"
+// @Success 200 {array} entity.PutawayTask
+// @Failure 500 {object} ErrorResponse
+// @Router /stocks/putaway/tasks [get]
+func (h *PutawayHandlers) ListTasks(c *gin.Context) {
+	tasks, err := h.putawayUseCase.ListTasks(c.Request.Context(), &entity.PutawayTaskFilter{
+		StoreID:   c.Query("store_id"),
+		ReceiptID: c.Query("receipt_id"),
+		SKUID:     c.Query("sku_id"),
+		Status:    entity.PutawayTaskStatus(c.Query("status")),
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tasks)
+}
+
+// GetTask handles getting a putaway task
+// @Summary Get putaway task
+// @Description Get a putaway task by ID
+// @Tags stocks
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Putaway task ID"
+// @Success 200 {object} entity.PutawayTask
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /stocks/putaway/tasks/{id} [get]
+func (h *PutawayHandlers) GetTask(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid putaway task ID"))
+		return
+	}
+
+	task, err := h.putawayUseCase.GetTask(c.Request.Context(), uint(id))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, task)
+}
+
+// ConfirmTask handles confirming a putaway task
+// @Summary Confirm putaway task
+// @Description Confirm the goods of a putaway task were put away, at the bin scanned or, with an empty body, at the suggested bin. The stock of the SKU in the store moves to that location.
+// @Tags stocks
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Putaway task ID"
+// @Param request body entity.PutawayConfirmRequest false "Location scanned"
+// @Success 200 {object} entity.PutawayTask
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse "Task already confirmed"
+// @Failure 500 {object} ErrorResponse
+// @Router /stocks/putaway/tasks/{id}/confirm [post]
+func (h *PutawayHandlers) ConfirmTask(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid putaway task ID"))
+		return
+	}
+	var req entity.PutawayConfirmRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+			return
+		}
+	}
+
+	task, err := h.putawayUseCase.ConfirmTask(c.Request.Context(), uint(id), &req, auth.GetUserIDFromContext(c))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, task)
+}
+
+// GenerateTasks handles generating the putaway tasks of a purchase receipt
+// @Summary Generate putaway tasks
+// @Description Generate the putaway tasks of a purchase receipt whose tasks were not generated when it was posted
+// @Tags stocks
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Purchase receipt ID"
+// @Success 201 {array} entity.PutawayTask
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse "Tasks already generated"
+// @Failure 500 {object} ErrorResponse
+// @Router /stocks/putaway/receipts/{id}/tasks [post]
+func (h *PutawayHandlers) GenerateTasks(c *gin.Context) {
+	tasks, err := h.putawayUseCase.GenerateForReceipt(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, tasks)
+}
@@ -0,0 +1,334 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/auth"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/server/middleware"
+)
+
+// QualityHandlers handles quality control HTTP requests
+type QualityHandlers struct {
+	qualityUseCase *usecase.QualityUseCase
+}
+
+// NewQualityHandlers creates a new quality handlers instance
+func NewQualityHandlers(qualityUseCase *usecase.QualityUseCase) *QualityHandlers {
+	return &QualityHandlers{
+		qualityUseCase: qualityUseCase,
+	}
+}
+
+// RegisterRoutes registers quality control routes
+func (h *QualityHandlers) RegisterRoutes(router *gin.RouterGroup) {
+	quality := router.Group("/quality")
+	{
+		quality.POST("/plans", middleware.PermissionMiddleware(entity.QualityPlanManage), h.CreatePlan)
+		quality.GET("/plans", middleware.PermissionMiddleware(entity.QualityInspectionRead), h.ListPlans)
+		quality.GET("/plans/:id", middleware.PermissionMiddleware(entity.QualityInspectionRead), h.GetPlan)
+		quality.PUT("/plans/:id", middleware.PermissionMiddleware(entity.QualityPlanManage), h.UpdatePlan)
+		quality.POST("/defect-codes", middleware.PermissionMiddleware(entity.QualityPlanManage), h.CreateDefectCode)
+		quality.GET("/defect-codes", middleware.PermissionMiddleware(entity.QualityInspectionRead), h.ListDefectCodes)
+		quality.GET("/inspections", middleware.PermissionMiddleware(entity.QualityInspectionRead), h.ListInspections)
+		quality.GET("/inspections/:id", middleware.PermissionMiddleware(entity.QualityInspectionRead), h.GetInspection)
+		quality.POST("/inspections/:id/result", middleware.PermissionMiddleware(entity.QualityInspectionRecord), h.RecordResult)
+	}
+}
+
+// CreatePlan handles creating an inspection plan
+// @Summary Create inspection plan
+// @Description Hold purchase receipts and/or production output of a SKU for a quality inspection before it can be picked
+// @Tags quality
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body entity.InspectionPlanRequest true "Inspection plan"
+// @Success 201 {object} entity.InspectionPlan
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /quality/plans [post]
+func (h *QualityHandlers) CreatePlan(c *gin.Context) {
+	var req entity.InspectionPlanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	plan, err := h.qualityUseCase.CreatePlan(c.Request.Context(), &req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, plan)
+}
+
+// ListPlans handles listing inspection plans
+// @Summary List inspection plans
+// @Description List inspection plans by SKU
+// @Tags quality
+// @Security BearerAuth
+// @Produce json
+// @Param sku_id query string false "SKU ID"
+// @Success 200 {array} entity.InspectionPlan
+// @Failure 500 {object} ErrorResponse
+// @Router /quality/plans [get]
+func (h *QualityHandlers) ListPlans(c *gin.Context) {
+	plans, err := h.qualityUseCase.ListPlans(c.Request.Context(), c.Query("sku_id"))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, plans)
+}
+
+// GetPlan handles getting an inspection plan
+// @Summary Get inspection plan
+// @Description Get an inspection plan by ID
+// @Tags quality
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Inspection plan ID"
+// @Success 200 {object} entity.InspectionPlan
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /quality/plans/{id} [get]
+func (h *QualityHandlers) GetPlan(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid inspection plan ID"))
+		return
+	}
+
+	plan, err := h.qualityUseCase.GetPlan(c.Request.Context(), uint(id))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, plan)
+}
+
+// UpdatePlan handles updating an inspection plan
+// @Summary Update inspection plan
+// @Description Update an inspection plan. Goods already on hold stay on hold.
+// @Tags quality
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Inspection plan ID"
+// @Param request body entity.InspectionPlanRequest true "Inspection plan"
+// @Success 200 {object} entity.InspectionPlan
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /quality/plans/{id} [put]
+func (h *QualityHandlers) UpdatePlan(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid inspection plan ID"))
+		return
+	}
+
+	var req entity.InspectionPlanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	plan, err := h.qualityUseCase.UpdatePlan(c.Request.Context(), uint(id), &req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, plan)
+}
+
+// CreateDefectCode handles creating a defect code
+// @Summary Create defect code
+// @Description Create a code to classify rejected units with
+// @Tags quality
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body entity.DefectCodeRequest true "Defect code"
+// @Success 201 {object} entity.DefectCode
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /quality/defect-codes [post]
+func (h *QualityHandlers) CreateDefectCode(c *gin.Context) {
+	var req entity.DefectCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	code, err := h.qualityUseCase.CreateDefectCode(c.Request.Context(), &req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, code)
+}
+
+// ListDefectCodes handles listing defect codes
+// @Summary List defect codes
+// @Description List the defect codes
+// @Tags quality
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} entity.DefectCode
+// @Failure 500 {object} ErrorResponse
+// @Router /quality/defect-codes [get]
+func (h *QualityHandlers) ListDefectCodes(c *gin.Context) {
+	codes, err := h.qualityUseCase.ListDefectCodes(c.Request.Context())
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, codes)
+}
+
+// ListInspections handles listing quality inspections
+// @Summary List quality inspections
+// @Description List quality inspections, oldest first. Filter by PENDING status for the goods currently in quarantine.
+// @Tags quality
+// @Security BearerAuth
+// @Produce json
+// @Param status query string false "Status (PENDING, PASSED, FAILED, PARTIAL)"
+// @Param source query string false "Source (RECEIPT, PRODUCTION)"
+// @Param sku_id query string false "SKU ID"
+// @Param store_id query string false "Store ID"
+// @Param reference query string false "Receipt number or production order reference"
+// @Param page query int false "Page number"
+// @Param page_size query int false "Page size"
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} ErrorResponse
+// @Router /quality/inspections [get]
+func (h *QualityHandlers) ListInspections(c *gin.Context) {
+	filter := &entity.QualityInspectionFilter{
+		Status:    entity.InspectionStatus(c.Query("status")),
+		Source:    entity.InspectionSource(c.Query("source")),
+		SKUID:     c.Query("sku_id"),
+		StoreID:   c.Query("store_id"),
+		Reference: c.Query("reference"),
+	}
+
+	if page, err := strconv.Atoi(c.Query("page")); err == nil {
+		filter.Page = page
+	}
+
+	if pageSize, err := strconv.Atoi(c.Query("page_size")); err == nil {
+		filter.PageSize = pageSize
+	}
+
+	inspections, total, err := h.qualityUseCase.ListInspections(c.Request.Context(), filter)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"inspections": inspections,
+		"total":       total,
+		"page":        filter.Page,
+		"page_size":   filter.PageSize,
+	})
+}
+
+// GetInspection handles getting a quality inspection
+// @Summary Get quality inspection
+// @Description Get a quality inspection by ID
+// @Tags quality
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Inspection ID"
+// @Success 200 {object} entity.QualityInspection
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /quality/inspections/{id} [get]
+func (h *QualityHandlers) GetInspection(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid inspection ID"))
+		return
+	}
+
+	inspection, err := h.qualityUseCase.GetInspection(c.Request.Context(), uint(id))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, inspection)
+}
+
+// RecordResult handles recording an inspection result
+// @Summary Record inspection result
+// @Description Record the passed and rejected quantities of a pending inspection, with defect codes for the rejected units. The goods leave quarantine: passed units can be picked and rejected units are written off stock.
+// @Tags quality
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Inspection ID"
+// @Param request body entity.InspectionResultRequest true "Inspection result"
+// @Success 200 {object} entity.QualityInspection
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /quality/inspections/{id}/result [post]
+func (h *QualityHandlers) RecordResult(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid inspection ID"))
+		return
+	}
+
+	var req entity.InspectionResultRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	inspection, err := h.qualityUseCase.RecordResult(c.Request.Context(), uint(id), &req, auth.GetUserIDFromContext(c))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, inspection)
+}
+
+func (h *QualityHandlers) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, usecase.ErrInspectionPlanNotFound),
+		errors.Is(err, usecase.ErrInspectionNotFound):
+		c.Error(entity.WrapError(entity.ErrCodeNotFound, err))
+	case errors.Is(err, usecase.ErrInspectionPlanExists),
+		errors.Is(err, usecase.ErrDefectCodeExists),
+		errors.Is(err, usecase.ErrInspectionClosed),
+		errors.Is(err, usecase.ErrInspectionStockShort):
+		c.Error(entity.WrapError(entity.ErrCodeConflict, err))
+	case errors.Is(err, usecase.ErrInspectionQuantity),
+		errors.Is(err, usecase.ErrDefectsRequired),
+		errors.Is(err, usecase.ErrUnknownDefectCode):
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+	default:
+		c.Error(err)
+	}
+}
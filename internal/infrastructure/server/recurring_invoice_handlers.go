@@ -0,0 +1,364 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/auth"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/server/middleware"
+)
+
+// RecurringInvoiceHandlers handles recurring invoice HTTP requests
+type RecurringInvoiceHandlers struct {
+	recurringUseCase *usecase.RecurringInvoiceUseCase
+}
+
+// NewRecurringInvoiceHandlers creates a new recurring invoice handlers instance
+func NewRecurringInvoiceHandlers(recurringUseCase *usecase.RecurringInvoiceUseCase) *RecurringInvoiceHandlers {
+	return &RecurringInvoiceHandlers{
+		recurringUseCase: recurringUseCase,
+	}
+}
+
+// RegisterRoutes registers recurring invoice routes
+func (h *RecurringInvoiceHandlers) RegisterRoutes(router *gin.RouterGroup) {
+	recurringRouter := router.Group("/finance/recurring-invoices")
+	{
+		recurringRouter.POST("", middleware.PermissionMiddleware(entity.FinanceInvoiceCreate), h.CreateRecurringInvoice)
+		recurringRouter.GET("", middleware.PermissionMiddleware(entity.FinanceInvoiceRead), h.ListRecurringInvoices)
+		recurringRouter.GET("/:id", middleware.PermissionMiddleware(entity.FinanceInvoiceRead), h.GetRecurringInvoice)
+		recurringRouter.PUT("/:id", middleware.PermissionMiddleware(entity.FinanceInvoiceUpdate), h.UpdateRecurringInvoice)
+		recurringRouter.GET("/:id/preview", middleware.PermissionMiddleware(entity.FinanceInvoiceRead), h.PreviewRecurringInvoice)
+		recurringRouter.GET("/:id/runs", middleware.PermissionMiddleware(entity.FinanceInvoiceRead), h.ListRecurringInvoiceRuns)
+		recurringRouter.POST("/:id/pause", middleware.PermissionMiddleware(entity.FinanceInvoiceUpdate), h.PauseRecurringInvoice)
+		recurringRouter.POST("/:id/resume", middleware.PermissionMiddleware(entity.FinanceInvoiceUpdate), h.ResumeRecurringInvoice)
+		recurringRouter.POST("/:id/skip", middleware.PermissionMiddleware(entity.FinanceInvoiceUpdate), h.SkipRecurringInvoice)
+		recurringRouter.POST("/run", middleware.PermissionMiddleware(entity.FinanceInvoiceCreate), h.RunDueRecurringInvoices)
+	}
+}
+
+// CreateRecurringInvoice handles creating a recurring invoice template
+// @Summary Create recurring invoice
+// @Description Create a recurring invoice template the scheduler turns into invoices from its start date
+// @Tags Finance
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body entity.CreateRecurringInvoiceRequest true "Recurring invoice details"
+// @Success 201 {object} entity.RecurringInvoice
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /finance/recurring-invoices [post]
+func (h *RecurringInvoiceHandlers) CreateRecurringInvoice(c *gin.Context) {
+	var req entity.CreateRecurringInvoiceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	userID, _ := strconv.ParseInt(auth.GetUserIDFromContext(c), 10, 64)
+	template, err := h.recurringUseCase.Create(c.Request.Context(), &req, userID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, template)
+}
+
+// ListRecurringInvoices handles listing recurring invoice templates
+// @Summary List recurring invoices
+// @Description List recurring invoice templates with filters
+// @Tags Finance
+// @Security BearerAuth
+// @Produce json
+// @Param status query string false "Status (ACTIVE/PAUSED/ENDED)"
+// @Param type query string false "Invoice type (SALES/PURCHASE)"
+// @Param entity_id query int false "Customer or supplier ID"
+// @Param entity_type query string false "Entity type (CUSTOMER/SUPPLIER)"
+// @Param page query int false "Page number"
+// @Param page_size query int false "Page size"
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} ErrorResponse
+// @Router /finance/recurring-invoices [get]
+func (h *RecurringInvoiceHandlers) ListRecurringInvoices(c *gin.Context) {
+	filter := &entity.RecurringInvoiceFilter{
+		Status:     entity.RecurringInvoiceStatus(c.Query("status")),
+		Type:       entity.FinanceInvoiceType(c.Query("type")),
+		EntityType: c.Query("entity_type"),
+	}
+
+	if entityID, err := strconv.ParseInt(c.Query("entity_id"), 10, 64); err == nil {
+		filter.EntityID = entityID
+	}
+
+	if page, err := strconv.Atoi(c.Query("page")); err == nil {
+		filter.Page = page
+	}
+
+	if pageSize, err := strconv.Atoi(c.Query("page_size")); err == nil {
+		filter.PageSize = pageSize
+	}
+
+	templates, total, err := h.recurringUseCase.List(c.Request.Context(), filter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"recurring_invoices": templates,
+		"total":              total,
+		"page":               filter.Page,
+		"page_size":          filter.PageSize,
+	})
+}
+
+// GetRecurringInvoice handles getting a recurring invoice template
+// @Summary Get recurring invoice
+// @Description Get a recurring invoice template with its next run date
+// @Tags Finance
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Recurring invoice ID"
+// @Success 200 {object} entity.RecurringInvoice
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /finance/recurring-invoices/{id} [get]
+func (h *RecurringInvoiceHandlers) GetRecurringInvoice(c *gin.Context) {
+	id, ok := parseRecurringInvoiceID(c)
+	if !ok {
+		return
+	}
+
+	template, err := h.recurringUseCase.Get(c.Request.Context(), id)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+// UpdateRecurringInvoice handles updating a recurring invoice template
+// @Summary Update recurring invoice
+// @Description Update the lines, terms, escalation or end date of a recurring invoice for occurrences not yet invoiced
+// @Tags Finance
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Recurring invoice ID"
+// @Param request body entity.UpdateRecurringInvoiceRequest true "Recurring invoice changes"
+// @Success 200 {object} entity.RecurringInvoice
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /finance/recurring-invoices/{id} [put]
+func (h *RecurringInvoiceHandlers) UpdateRecurringInvoice(c *gin.Context) {
+	id, ok := parseRecurringInvoiceID(c)
+	if !ok {
+		return
+	}
+
+	var req entity.UpdateRecurringInvoiceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	template, err := h.recurringUseCase.Update(c.Request.Context(), id, &req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+// PreviewRecurringInvoice handles previewing the upcoming occurrences of a recurring invoice
+// @Summary Preview recurring invoice
+// @Description List the upcoming occurrences of a recurring invoice with their escalated amounts
+// @Tags Finance
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Recurring invoice ID"
+// @Param count query int false "Occurrences to preview (default 12, max 60)"
+// @Success 200 {array} entity.RecurringInvoicePreviewLine
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /finance/recurring-invoices/{id}/preview [get]
+func (h *RecurringInvoiceHandlers) PreviewRecurringInvoice(c *gin.Context) {
+	id, ok := parseRecurringInvoiceID(c)
+	if !ok {
+		return
+	}
+
+	count, _ := strconv.Atoi(c.Query("count"))
+	preview, err := h.recurringUseCase.Preview(c.Request.Context(), id, count)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, preview)
+}
+
+// ListRecurringInvoiceRuns handles listing the occurrences of a recurring invoice
+// @Summary List recurring invoice runs
+// @Description List the occurrences of a recurring invoice invoiced or skipped so far
+// @Tags Finance
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Recurring invoice ID"
+// @Success 200 {array} entity.RecurringInvoiceRun
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /finance/recurring-invoices/{id}/runs [get]
+func (h *RecurringInvoiceHandlers) ListRecurringInvoiceRuns(c *gin.Context) {
+	id, ok := parseRecurringInvoiceID(c)
+	if !ok {
+		return
+	}
+
+	runs, err := h.recurringUseCase.ListRuns(c.Request.Context(), id)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, runs)
+}
+
+// PauseRecurringInvoice handles pausing a recurring invoice
+// @Summary Pause recurring invoice
+// @Description Stop a recurring invoice from generating invoices until it is resumed
+// @Tags Finance
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Recurring invoice ID"
+// @Success 200 {object} entity.RecurringInvoice
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /finance/recurring-invoices/{id}/pause [post]
+func (h *RecurringInvoiceHandlers) PauseRecurringInvoice(c *gin.Context) {
+	id, ok := parseRecurringInvoiceID(c)
+	if !ok {
+		return
+	}
+
+	template, err := h.recurringUseCase.Pause(c.Request.Context(), id)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+// ResumeRecurringInvoice handles resuming a paused recurring invoice
+// @Summary Resume recurring invoice
+// @Description Restart a paused recurring invoice from its next occurrence on or after today
+// @Tags Finance
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Recurring invoice ID"
+// @Success 200 {object} entity.RecurringInvoice
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /finance/recurring-invoices/{id}/resume [post]
+func (h *RecurringInvoiceHandlers) ResumeRecurringInvoice(c *gin.Context) {
+	id, ok := parseRecurringInvoiceID(c)
+	if !ok {
+		return
+	}
+
+	template, err := h.recurringUseCase.Resume(c.Request.Context(), id)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+// SkipRecurringInvoice handles skipping the next occurrence of a recurring invoice
+// @Summary Skip next occurrence
+// @Description Pass over the next occurrence of a recurring invoice without invoicing it
+// @Tags Finance
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Recurring invoice ID"
+// @Success 200 {object} entity.RecurringInvoiceRun
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /finance/recurring-invoices/{id}/skip [post]
+func (h *RecurringInvoiceHandlers) SkipRecurringInvoice(c *gin.Context) {
+	id, ok := parseRecurringInvoiceID(c)
+	if !ok {
+		return
+	}
+
+	run, err := h.recurringUseCase.Skip(c.Request.Context(), id, currentUserID(c))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, run)
+}
+
+// RunDueRecurringInvoices handles invoicing the recurring invoices that are due
+// @Summary Run due recurring invoices
+// @Description Invoice every occurrence of the active recurring invoices due by now, as the scheduler does
+// @Tags Finance
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} entity.RecurringInvoiceBatchResult
+// @Failure 500 {object} ErrorResponse
+// @Router /finance/recurring-invoices/run [post]
+func (h *RecurringInvoiceHandlers) RunDueRecurringInvoices(c *gin.Context) {
+	result, err := h.recurringUseCase.RunDue(c.Request.Context(), time.Now())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// handleError maps recurring invoice errors to HTTP responses
+func (h *RecurringInvoiceHandlers) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, usecase.ErrRecurringInvoiceNotFound):
+		c.Error(entity.WrapError(entity.ErrCodeNotFound, err))
+	case errors.Is(err, usecase.ErrRecurringInvoiceEnded),
+		errors.Is(err, usecase.ErrRecurringInvoiceNotActive),
+		errors.Is(err, usecase.ErrRecurringInvoiceNotPaused),
+		errors.Is(err, usecase.ErrInvalidRecurringEndDate):
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+	default:
+		c.Error(err)
+	}
+}
+
+// parseRecurringInvoiceID reads the recurring invoice ID path parameter, responding
+// with a bad request when it is not a number
+func parseRecurringInvoiceID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid recurring invoice ID"))
+		return 0, false
+	}
+	return uint(id), true
+}
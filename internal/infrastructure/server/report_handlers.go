@@ -41,13 +41,14 @@ func (h *ReportHandlers) RegisterRoutes(router *gin.RouterGroup) {
 		reportRouter.GET("/schedules/:id", middleware.PermissionMiddleware(entity.ReportScheduleRead), h.GetReportSchedule)
 		reportRouter.PUT("/schedules/:id", middleware.PermissionMiddleware(entity.ReportScheduleUpdate), h.UpdateReportSchedule)
 		reportRouter.DELETE("/schedules/:id", middleware.PermissionMiddleware(entity.ReportScheduleDelete), h.DeleteReportSchedule)
+		reportRouter.GET("/schedules/:id/runs", middleware.PermissionMiddleware(entity.ReportScheduleRead), h.ListReportRuns)
+		reportRouter.POST("/schedules/:id/runs/:run_id/rerun", middleware.PermissionMiddleware(entity.ReportScheduleUpdate), h.RerunReportRun)
 
 		// Inventory reports
 		reportRouter.GET("/inventory/value", middleware.PermissionMiddleware(entity.ReportRead), h.GetInventoryValueReport)
 		reportRouter.GET("/inventory/age", middleware.PermissionMiddleware(entity.ReportRead), h.GetInventoryAgeReport)
 
 		// Sales reports
-		reportRouter.GET("/sales/products", middleware.PermissionMiddleware(entity.ReportRead), h.GetProductSalesReport)
 		reportRouter.GET("/sales/customers", middleware.PermissionMiddleware(entity.ReportRead), h.GetCustomerSalesReport)
 
 		// Purchase reports
@@ -63,33 +64,33 @@ func (h *ReportHandlers) RegisterRoutes(router *gin.RouterGroup) {
 
 // CreateReport handles the creation of a new report
 // @Summary Create a new report
-// @Description Create a new report
+// @Description Create a pending report and queue the job generating it. Poll the job under /jobs/{id} or the report for its status.
 // @Tags Reports
 // @Security BearerAuth
 // @Accept json
 // @Produce json
 // @Param report body entity.CreateReportRequest true "Report details"
-// @Success 201 {object} map[string]interface{}
+// @Success 202 {object} map[string]interface{}
 // @Failure 400 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /reports [post]
 func (h *ReportHandlers) CreateReport(c *gin.Context) {
 	var req entity.CreateReportRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 
 	userIDStr := auth.GetUserIDFromContext(c)
 	userID, _ := strconv.ParseUint(userIDStr, 10, 32)
 
-	report, err := h.reportUseCase.CreateReport(c.Request.Context(), &req, uint(userID))
+	report, job, err := h.reportUseCase.CreateReport(c.Request.Context(), &req, uint(userID))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"report": report})
+	c.JSON(http.StatusAccepted, gin.H{"report": report, "job": job})
 }
 
 // GetReport handles the retrieval of a report by ID
@@ -108,7 +109,7 @@ func (h *ReportHandlers) GetReport(c *gin.Context) {
 
 	report, err := h.reportUseCase.GetReportByID(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeNotFound, err))
 		return
 	}
 
@@ -161,7 +162,7 @@ func (h *ReportHandlers) ListReports(c *gin.Context) {
 
 	reports, total, err := h.reportUseCase.ListReports(c.Request.Context(), filter)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -186,7 +187,7 @@ func (h *ReportHandlers) DeleteReport(c *gin.Context) {
 	id := c.Param("id")
 
 	if err := h.reportUseCase.DeleteReport(c.Request.Context(), id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -195,13 +196,13 @@ func (h *ReportHandlers) DeleteReport(c *gin.Context) {
 
 // ExportReport handles the export of a report to a specific format
 // @Summary Export a report
-// @Description Export a report to a specific format (CSV, Excel, PDF)
+// @Description Queue the job exporting a report to a specific format (CSV, Excel, PDF). The finished job's result holds the file URL.
 // @Tags Reports
 // @Security BearerAuth
 // @Produce json
 // @Param id path string true "Report ID"
 // @Param format query string true "Export format (CSV, EXCEL, PDF)"
-// @Success 200 {object} map[string]string
+// @Success 202 {object} map[string]interface{}
 // @Failure 400 {object} map[string]string
 // @Failure 404 {object} map[string]string
 // @Failure 500 {object} map[string]string
@@ -211,25 +212,28 @@ func (h *ReportHandlers) ExportReport(c *gin.Context) {
 	formatStr := c.Query("format")
 
 	if formatStr == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Format is required"})
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Format is required"))
 		return
 	}
 
 	format := entity.ReportFormat(formatStr)
 	if format != entity.ReportFormatCSV && format != entity.ReportFormatExcel && format != entity.ReportFormatPDF && format != entity.ReportFormatJSON {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid format. Supported formats: CSV, EXCEL, PDF, JSON"})
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid format. Supported formats: CSV, EXCEL, PDF, JSON"))
 		return
 	}
 
-	fileURL, err := h.reportUseCase.ExportReport(c.Request.Context(), id, format)
+	userIDStr := auth.GetUserIDFromContext(c)
+	userID, _ := strconv.ParseUint(userIDStr, 10, 32)
+
+	job, err := h.reportUseCase.ExportReport(c.Request.Context(), id, format, uint(userID))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message":  "Report exported successfully",
-		"file_url": fileURL,
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Report export queued",
+		"job":     job,
 	})
 }
 
@@ -248,7 +252,7 @@ func (h *ReportHandlers) ExportReport(c *gin.Context) {
 func (h *ReportHandlers) CreateReportSchedule(c *gin.Context) {
 	var req entity.CreateReportScheduleRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 
@@ -257,7 +261,7 @@ func (h *ReportHandlers) CreateReportSchedule(c *gin.Context) {
 
 	schedule, err := h.reportUseCase.CreateReportSchedule(c.Request.Context(), &req, uint(userID))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -280,7 +284,7 @@ func (h *ReportHandlers) GetReportSchedule(c *gin.Context) {
 
 	schedule, err := h.reportUseCase.GetReportScheduleByID(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeNotFound, err))
 		return
 	}
 
@@ -306,13 +310,13 @@ func (h *ReportHandlers) UpdateReportSchedule(c *gin.Context) {
 
 	var req entity.UpdateReportScheduleRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 
 	schedule, err := h.reportUseCase.UpdateReportSchedule(c.Request.Context(), id, &req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -359,7 +363,7 @@ func (h *ReportHandlers) ListReportSchedules(c *gin.Context) {
 
 	schedules, total, err := h.reportUseCase.ListReportSchedules(c.Request.Context(), filter)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -384,16 +388,88 @@ func (h *ReportHandlers) DeleteReportSchedule(c *gin.Context) {
 	id := c.Param("id")
 
 	if err := h.reportUseCase.DeleteReportSchedule(c.Request.Context(), id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Report schedule deleted successfully"})
 }
 
+// ListReportRuns handles listing the runs of a report schedule
+// @Summary List report schedule runs
+// @Description List the runs of a report schedule, latest first, with their period, status, row count, error and the download URL of the export emailed to the recipients
+// @Tags Reports
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Report Schedule ID"
+// @Param status query string false "Status (QUEUED/RUNNING/SUCCEEDED/FAILED)"
+// @Param page query int false "Page number"
+// @Param page_size query int false "Page size"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /reports/schedules/{id}/runs [get]
+func (h *ReportHandlers) ListReportRuns(c *gin.Context) {
+	filter := &entity.ReportRunFilter{
+		Status: entity.ReportRunStatus(c.Query("status")),
+	}
+	if page, err := strconv.Atoi(c.Query("page")); err == nil {
+		filter.Page = page
+	}
+	if pageSize, err := strconv.Atoi(c.Query("page_size")); err == nil {
+		filter.PageSize = pageSize
+	}
+
+	runs, total, err := h.reportUseCase.ListReportRuns(c.Request.Context(), c.Param("id"), filter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"runs":      runs,
+		"total":     total,
+		"page":      filter.Page,
+		"page_size": filter.PageSize,
+	})
+}
+
+// RerunReportRun handles generating the report of a schedule's run again
+// @Summary Re-run a report schedule run
+// @Description Generate the report of a finished run again for the same period and email it to the schedule's recipients, recording a new run. The schedule's next run is unchanged.
+// @Tags Reports
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Report Schedule ID"
+// @Param run_id path int true "Report Run ID"
+// @Success 202 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /reports/schedules/{id}/runs/{run_id}/rerun [post]
+func (h *ReportHandlers) RerunReportRun(c *gin.Context) {
+	runID, err := strconv.ParseUint(c.Param("run_id"), 10, 64)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid report run ID"))
+		return
+	}
+
+	userIDStr := auth.GetUserIDFromContext(c)
+	userID, _ := strconv.ParseUint(userIDStr, 10, 32)
+
+	run, job, err := h.reportUseCase.RerunReportRun(c.Request.Context(), c.Param("id"), runID, uint(userID))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"run": run, "job": job})
+}
+
 // GetInventoryValueReport handles the retrieval of an inventory value report
 // @Summary Get inventory value report
-// @Description Get inventory value report
+// @Description Get the inventory value per SKU at SKU price, today from the stock on hand and on a past day from the latest stock snapshot taken on or before it
 // @Tags Reports
 // @Security BearerAuth
 // @Produce json
@@ -414,7 +490,7 @@ func (h *ReportHandlers) GetInventoryValueReport(c *gin.Context) {
 
 	report, err := h.reportUseCase.GetInventoryValueReport(c.Request.Context(), warehouseID, asOfDate)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -444,42 +520,7 @@ func (h *ReportHandlers) GetInventoryAgeReport(c *gin.Context) {
 
 	report, err := h.reportUseCase.GetInventoryAgeReport(c.Request.Context(), warehouseID, asOfDate)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{"report": report})
-}
-
-// GetProductSalesReport handles the retrieval of a product sales report
-// @Summary Get product sales report
-// @Description Get product sales report
-// @Tags Reports
-// @Security BearerAuth
-// @Produce json
-// @Param start_date query string false "Start date (YYYY-MM-DD)"
-// @Param end_date query string false "End date (YYYY-MM-DD)"
-// @Success 200 {object} map[string]interface{}
-// @Failure 500 {object} map[string]string
-// @Router /reports/sales/products [get]
-func (h *ReportHandlers) GetProductSalesReport(c *gin.Context) {
-	var startDate, endDate time.Time
-
-	if startDateStr := c.Query("start_date"); startDateStr != "" {
-		if date, err := time.Parse("2006-01-02", startDateStr); err == nil {
-			startDate = date
-		}
-	}
-
-	if endDateStr := c.Query("end_date"); endDateStr != "" {
-		if date, err := time.Parse("2006-01-02", endDateStr); err == nil {
-			endDate = date
-		}
-	}
-
-	report, err := h.reportUseCase.GetProductSalesReport(c.Request.Context(), startDate, endDate)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -514,7 +555,7 @@ func (h *ReportHandlers) GetCustomerSalesReport(c *gin.Context) {
 
 	report, err := h.reportUseCase.GetCustomerSalesReport(c.Request.Context(), startDate, endDate)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -549,7 +590,7 @@ func (h *ReportHandlers) GetSupplierPurchaseReport(c *gin.Context) {
 
 	report, err := h.reportUseCase.GetSupplierPurchaseReport(c.Request.Context(), startDate, endDate)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -564,10 +605,25 @@ func (h *ReportHandlers) GetSupplierPurchaseReport(c *gin.Context) {
 // @Produce json
 // @Param start_date query string false "Start date (YYYY-MM-DD)"
 // @Param end_date query string false "End date (YYYY-MM-DD)"
+// @Param fiscal_year_id query int false "Report on a fiscal year instead of dates"
+// @Param fiscal_period_id query int false "Report on a fiscal period instead of dates"
 // @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /reports/financial/profit-loss [get]
 func (h *ReportHandlers) GetProfitAndLossReport(c *gin.Context) {
+	yearID, _ := strconv.ParseUint(c.Query("fiscal_year_id"), 10, 64)
+	periodID, _ := strconv.ParseUint(c.Query("fiscal_period_id"), 10, 64)
+	if yearID != 0 || periodID != 0 {
+		report, err := h.reportUseCase.GetFiscalProfitAndLossReport(c.Request.Context(), uint(yearID), uint(periodID))
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"report": report})
+		return
+	}
+
 	var startDate, endDate time.Time
 
 	if startDateStr := c.Query("start_date"); startDateStr != "" {
@@ -584,7 +640,7 @@ func (h *ReportHandlers) GetProfitAndLossReport(c *gin.Context) {
 
 	report, err := h.reportUseCase.GetProfitAndLossReport(c.Request.Context(), startDate, endDate)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -598,15 +654,25 @@ func (h *ReportHandlers) GetProfitAndLossReport(c *gin.Context) {
 // @Security BearerAuth
 // @Produce json
 // @Param period query string false "Period (day, week, month, quarter, year)"
+// @Param rfm_segment query string false "Only count sales to clients in this RFM segment"
+// @Param min_recency_score query int false "Only count sales to clients with at least this RFM recency score"
+// @Param min_frequency_score query int false "Only count sales to clients with at least this RFM frequency score"
+// @Param min_monetary_score query int false "Only count sales to clients with at least this RFM monetary score"
 // @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /reports/dashboard/metrics [get]
 func (h *ReportHandlers) GetDashboardMetrics(c *gin.Context) {
 	period := c.DefaultQuery("period", "month")
+	rfm, err := parseRFMFilter(c)
+	if err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
 
-	metrics, err := h.reportUseCase.GetDashboardMetrics(c.Request.Context(), period)
+	metrics, err := h.reportUseCase.GetDashboardMetrics(c.Request.Context(), period, rfm)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -0,0 +1,166 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/auth"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/server/middleware"
+)
+
+// ReturnsHandlers handles sales return and returns analytics HTTP requests
+type ReturnsHandlers struct {
+	returnsUseCase *usecase.ReturnsUseCase
+}
+
+// NewReturnsHandlers creates a new returns handlers instance
+func NewReturnsHandlers(returnsUseCase *usecase.ReturnsUseCase) *ReturnsHandlers {
+	return &ReturnsHandlers{
+		returnsUseCase: returnsUseCase,
+	}
+}
+
+// RegisterRoutes registers the returns and quality report routes
+func (h *ReturnsHandlers) RegisterRoutes(router *gin.RouterGroup) {
+	returnsRouter := router.Group("/reports/returns")
+	{
+		returnsRouter.GET("/quality", middleware.PermissionMiddleware(entity.ReportRead), h.GetReturnsQuality)
+		returnsRouter.GET("/review", middleware.PermissionMiddleware(entity.ReportRead), h.GetReturnsReview)
+	}
+}
+
+// RecordReturn handles recording a sales return
+// @Summary Record sales return
+// @Description Record goods a client sent back against a shipped, delivered or completed order. The reason is a quality defect code. Restocked goods are booked into the store and held for inspection when the SKU's plan inspects receipts.
+// @Tags orders
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Sales order ID"
+// @Param request body entity.SalesReturnRequest true "Return details"
+// @Success 201 {object} entity.SalesReturn
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /orders/{id}/returns [post]
+func (h *ReturnsHandlers) RecordReturn(c *gin.Context) {
+	var req entity.SalesReturnRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	ret, err := h.returnsUseCase.RecordReturn(c.Request.Context(), c.Param("id"), &req, auth.GetUserIDFromContext(c))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, ret)
+}
+
+// ListOrderReturns handles listing the returns of a sales order
+// @Summary List sales order returns
+// @Description List the goods returned against a sales order
+// @Tags orders
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Sales order ID"
+// @Success 200 {array} entity.SalesReturn
+// @Failure 500 {object} ErrorResponse
+// @Router /orders/{id}/returns [get]
+func (h *ReturnsHandlers) ListOrderReturns(c *gin.Context) {
+	returns, err := h.returnsUseCase.ListOrderReturns(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, returns)
+}
+
+// GetReturnsQuality handles the returns and quality report
+// @Summary Get returns and quality report
+// @Description Correlate sales returns with quality inspection rejections by SKU, lot, vendor or reason code. SKU rows whose return rate exceeds the review threshold are flagged.
+// @Tags Reports
+// @Security BearerAuth
+// @Produce json
+// @Param group_by query string false "Grouping (SKU/LOT/VENDOR/REASON), defaults to SKU"
+// @Param start_date query string false "Period start (YYYY-MM-DD), defaults to 90 days before the end"
+// @Param end_date query string false "Period end (YYYY-MM-DD), defaults to now"
+// @Success 200 {object} entity.ReturnsQualityReport
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /reports/returns/quality [get]
+func (h *ReturnsHandlers) GetReturnsQuality(c *gin.Context) {
+	filter := parseReturnsQualityFilter(c)
+	filter.GroupBy = entity.ReturnsGroupBy(strings.ToUpper(c.Query("group_by")))
+
+	report, err := h.returnsUseCase.Report(c.Request.Context(), filter)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// GetReturnsReview handles listing the SKUs flagged for engineering review
+// @Summary Get returns engineering review list
+// @Description List the SKUs whose return rate in the period exceeds the review threshold, highest first
+// @Tags Reports
+// @Security BearerAuth
+// @Produce json
+// @Param start_date query string false "Period start (YYYY-MM-DD), defaults to 90 days before the end"
+// @Param end_date query string false "Period end (YYYY-MM-DD), defaults to now"
+// @Success 200 {object} entity.ReturnsQualityReport
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /reports/returns/review [get]
+func (h *ReturnsHandlers) GetReturnsReview(c *gin.Context) {
+	report, err := h.returnsUseCase.Review(c.Request.Context(), parseReturnsQualityFilter(c))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// handleError maps returns errors to HTTP responses
+func (h *ReturnsHandlers) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, usecase.ErrSalesOrderNotFound):
+		c.Error(entity.WrapError(entity.ErrCodeNotFound, err))
+	case errors.Is(err, usecase.ErrReturnOrderStatus),
+		errors.Is(err, usecase.ErrReturnQuantity):
+		c.Error(entity.WrapError(entity.ErrCodeConflict, err))
+	case errors.Is(err, usecase.ErrReturnSKUNotOnOrder),
+		errors.Is(err, usecase.ErrReturnStoreRequired),
+		errors.Is(err, usecase.ErrUnknownDefectCode),
+		errors.Is(err, usecase.ErrInvalidReturnsGroup),
+		errors.Is(err, usecase.ErrInvalidReturnsPeriod):
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+	default:
+		c.Error(err)
+	}
+}
+
+// parseReturnsQualityFilter reads the report period from the query string
+func parseReturnsQualityFilter(c *gin.Context) *entity.ReturnsQualityFilter {
+	filter := &entity.ReturnsQualityFilter{}
+	if startDate, err := time.Parse("2006-01-02", c.Query("start_date")); err == nil {
+		filter.StartDate = &startDate
+	}
+	if endDate, err := time.Parse("2006-01-02", c.Query("end_date")); err == nil {
+		endDate = endDate.Add(24*time.Hour - time.Nanosecond)
+		filter.EndDate = &endDate
+	}
+	return filter
+}
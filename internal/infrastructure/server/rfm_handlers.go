@@ -0,0 +1,107 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/server/middleware"
+)
+
+// RFMHandlers handles client RFM scoring HTTP requests
+type RFMHandlers struct {
+	rfmUseCase *usecase.RFMUseCase
+}
+
+// NewRFMHandlers creates a new RFM handlers instance
+func NewRFMHandlers(rfmUseCase *usecase.RFMUseCase) *RFMHandlers {
+	return &RFMHandlers{
+		rfmUseCase: rfmUseCase,
+	}
+}
+
+// RegisterRoutes registers RFM scoring routes
+func (h *RFMHandlers) RegisterRoutes(router *gin.RouterGroup) {
+	rfmRouter := router.Group("/clients/rfm")
+	{
+		rfmRouter.GET("", middleware.PermissionMiddleware(entity.ClientRFMRead), h.ListScores)
+		rfmRouter.POST("/run", middleware.PermissionMiddleware(entity.ClientRFMRun), h.RunScoring)
+	}
+}
+
+// ListScores handles listing client RFM scores
+// @Summary List client RFM scores
+// @Description List the recency, frequency and monetary scores of clients, best first
+// @Tags clients
+// @Security BearerAuth
+// @Produce json
+// @Param rfm_segment query string false "RFM segment"
+// @Param min_recency_score query int false "Minimum recency score (1-5)"
+// @Param min_frequency_score query int false "Minimum frequency score (1-5)"
+// @Param min_monetary_score query int false "Minimum monetary score (1-5)"
+// @Success 200 {array} entity.ClientRFMScore
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /clients/rfm [get]
+func (h *RFMHandlers) ListScores(c *gin.Context) {
+	filter, err := parseRFMFilter(c)
+	if err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	scores, err := h.rfmUseCase.ListScores(c.Request.Context(), filter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, scores)
+}
+
+// RunScoring handles scoring clients on demand
+// @Summary Run RFM scoring
+// @Description Score every client with orders in the lookback window by recency, frequency and monetary value, and replace the stored scores
+// @Tags clients
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} entity.RFMRunResult
+// @Failure 500 {object} ErrorResponse
+// @Router /clients/rfm/run [post]
+func (h *RFMHandlers) RunScoring(c *gin.Context) {
+	result, err := h.rfmUseCase.Run(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// parseRFMFilter reads the RFM filter query parameters shared by the client list,
+// the RFM score list and the sales dashboard
+func parseRFMFilter(c *gin.Context) (entity.RFMFilter, error) {
+	filter := entity.RFMFilter{
+		Segment: entity.RFMSegment(c.Query("rfm_segment")),
+	}
+
+	for param, dst := range map[string]*int{
+		"min_recency_score":   &filter.MinRecencyScore,
+		"min_frequency_score": &filter.MinFrequencyScore,
+		"min_monetary_score":  &filter.MinMonetaryScore,
+	} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		score, err := strconv.Atoi(value)
+		if err != nil || score < 1 || score > 5 {
+			return filter, fmt.Errorf("%s must be between 1 and 5", param)
+		}
+		*dst = score
+	}
+	return filter, nil
+}
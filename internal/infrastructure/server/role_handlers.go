@@ -10,13 +10,17 @@ import (
 )
 
 type CreateRoleRequest struct {
-	Name        string              `json:"name" binding:"required" example:"manager"`
-	Permissions []entity.Permission `json:"permissions" binding:"required" example:"[\"user:read\",\"user:create\"]"`
+	Name          string              `json:"name" binding:"required" example:"manager"`
+	Permissions   []entity.Permission `json:"permissions" binding:"required" example:"[\"user:read\",\"user:create\"]"`
+	StoreIDs      []string            `json:"store_ids"`      // stores (warehouses) the role's users are limited to, all when empty
+	DepartmentIDs []uint              `json:"department_ids"` // departments the role's users are limited to, all when empty
 }
 
 type UpdateRoleRequest struct {
-	Name        string              `json:"name" binding:"required" example:"manager"`
-	Permissions []entity.Permission `json:"permissions" binding:"required" example:"[\"user:read\",\"user:create\"]"`
+	Name          string              `json:"name" binding:"required" example:"manager"`
+	Permissions   []entity.Permission `json:"permissions" binding:"required" example:"[\"user:read\",\"user:create\"]"`
+	StoreIDs      []string            `json:"store_ids"`      // stores (warehouses) the role's users are limited to, all when empty
+	DepartmentIDs []uint              `json:"department_ids"` // departments the role's users are limited to, all when empty
 }
 
 // @Summary Create new role
@@ -33,22 +37,23 @@ type UpdateRoleRequest struct {
 func (s *Server) handleCreateRole(c *gin.Context) {
 	var req CreateRoleRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 
 	if !s.roleUC.ValidatePermissions(req.Permissions) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid permissions"})
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid permissions"))
 		return
 	}
 
 	role, err := s.roleUC.CreateRole(&usecase.CreateRoleInput{
 		Name:        req.Name,
 		Permissions: req.Permissions,
+		Scope:       entity.AccessScope{StoreIDs: req.StoreIDs, DepartmentIDs: req.DepartmentIDs},
 	})
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -66,7 +71,7 @@ func (s *Server) handleCreateRole(c *gin.Context) {
 func (s *Server) handleListRoles(c *gin.Context) {
 	roles, err := s.roleUC.ListRoles()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -86,13 +91,13 @@ func (s *Server) handleListRoles(c *gin.Context) {
 func (s *Server) handleGetRole(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role ID"})
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid role ID"))
 		return
 	}
 
 	role, err := s.roleUC.GetRoleByID(uint(id))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
+		c.Error(entity.NewError(entity.ErrCodeNotFound, "Role not found"))
 		return
 	}
 
@@ -114,18 +119,18 @@ func (s *Server) handleGetRole(c *gin.Context) {
 func (s *Server) handleUpdateRole(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role ID"})
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid role ID"))
 		return
 	}
 
 	var req UpdateRoleRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 
 	if !s.roleUC.ValidatePermissions(req.Permissions) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid permissions"})
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid permissions"))
 		return
 	}
 
@@ -133,10 +138,11 @@ func (s *Server) handleUpdateRole(c *gin.Context) {
 		ID:          uint(id),
 		Name:        req.Name,
 		Permissions: req.Permissions,
+		Scope:       entity.AccessScope{StoreIDs: req.StoreIDs, DepartmentIDs: req.DepartmentIDs},
 	})
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -156,12 +162,12 @@ func (s *Server) handleUpdateRole(c *gin.Context) {
 func (s *Server) handleDeleteRole(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role ID"})
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid role ID"))
 		return
 	}
 
 	if err := s.roleUC.DeleteRole(uint(id)); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -0,0 +1,377 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/auth"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/server/middleware"
+)
+
+// SalesChannelHandlers handles HTTP requests for e-commerce sales channels,
+// their SKU mappings and syncs
+type SalesChannelHandlers struct {
+	salesChannelUseCase *usecase.SalesChannelUseCase
+}
+
+// NewSalesChannelHandlers creates a new sales channel handlers instance
+func NewSalesChannelHandlers(salesChannelUseCase *usecase.SalesChannelUseCase) *SalesChannelHandlers {
+	return &SalesChannelHandlers{
+		salesChannelUseCase: salesChannelUseCase,
+	}
+}
+
+// RegisterRoutes registers sales channel routes
+func (h *SalesChannelHandlers) RegisterRoutes(router *gin.RouterGroup) {
+	channels := router.Group("/sales-channels")
+	{
+		channels.POST("", middleware.PermissionMiddleware(entity.SalesChannelManage), h.CreateChannel)
+		channels.GET("", middleware.PermissionMiddleware(entity.SalesChannelRead), h.ListChannels)
+		channels.GET("/:id", middleware.PermissionMiddleware(entity.SalesChannelRead), h.GetChannel)
+		channels.PUT("/:id", middleware.PermissionMiddleware(entity.SalesChannelManage), h.UpdateChannel)
+		channels.PUT("/:id/warehouses", middleware.PermissionMiddleware(entity.SalesChannelManage), h.SetWarehouses)
+		channels.GET("/:id/mappings", middleware.PermissionMiddleware(entity.SalesChannelRead), h.ListMappings)
+		channels.PUT("/:id/mappings", middleware.PermissionMiddleware(entity.SalesChannelManage), h.SaveMapping)
+		channels.DELETE("/:id/mappings/:mappingId", middleware.PermissionMiddleware(entity.SalesChannelManage), h.DeleteMapping)
+		channels.POST("/:id/sync/orders", middleware.PermissionMiddleware(entity.SalesChannelSync), h.SyncOrders)
+		channels.POST("/:id/sync/inventory", middleware.PermissionMiddleware(entity.SalesChannelSync), h.PushInventory)
+		channels.GET("/:id/sync-runs", middleware.PermissionMiddleware(entity.SalesChannelRead), h.ListSyncRuns)
+	}
+}
+
+// CreateChannel handles creating a sales channel
+// @Summary Create sales channel
+// @Description Connect a web shop on an e-commerce platform (SHOPIFY with an access token, or WOOCOMMERCE with an API key and secret). Credentials are never returned.
+// @Tags sales-channels
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body entity.SalesChannelRequest true "Sales channel"
+// @Success 201 {object} entity.SalesChannel
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /sales-channels [post]
+func (h *SalesChannelHandlers) CreateChannel(c *gin.Context) {
+	var req entity.SalesChannelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	channel, err := h.salesChannelUseCase.CreateChannel(c.Request.Context(), &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, channel)
+}
+
+// ListChannels handles listing sales channels
+// @Summary List sales channels
+// @Description List the sales channels with the stores linked to them
+// @Tags sales-channels
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} entity.SalesChannel
+// @Failure 500 {object} ErrorResponse
+// @Router /sales-channels [get]
+func (h *SalesChannelHandlers) ListChannels(c *gin.Context) {
+	channels, err := h.salesChannelUseCase.ListChannels(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, channels)
+}
+
+// GetChannel handles getting a sales channel
+// @Summary Get sales channel
+// @Description Get a sales channel with the stores linked to it
+// @Tags sales-channels
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Sales channel ID"
+// @Success 200 {object} entity.SalesChannel
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /sales-channels/{id} [get]
+func (h *SalesChannelHandlers) GetChannel(c *gin.Context) {
+	id, ok := parseSalesChannelID(c)
+	if !ok {
+		return
+	}
+
+	channel, err := h.salesChannelUseCase.GetChannel(c.Request.Context(), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, channel)
+}
+
+// UpdateChannel handles updating a sales channel
+// @Summary Update sales channel
+// @Description Update a sales channel. Credentials left empty are kept.
+// @Tags sales-channels
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Sales channel ID"
+// @Param request body entity.SalesChannelRequest true "Sales channel"
+// @Success 200 {object} entity.SalesChannel
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /sales-channels/{id} [put]
+func (h *SalesChannelHandlers) UpdateChannel(c *gin.Context) {
+	id, ok := parseSalesChannelID(c)
+	if !ok {
+		return
+	}
+	var req entity.SalesChannelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	channel, err := h.salesChannelUseCase.UpdateChannel(c.Request.Context(), id, &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, channel)
+}
+
+// SetWarehouses handles linking stores to a sales channel
+// @Summary Link stores to sales channel
+// @Description Replace the stores linked to a sales channel. Orders of a linked location are fulfilled from its store, and the stock available in the stores linked to a location is pushed as its inventory levels.
+// @Tags sales-channels
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Sales channel ID"
+// @Param request body entity.SalesChannelWarehousesRequest true "Linked stores"
+// @Success 200 {object} entity.SalesChannel
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /sales-channels/{id}/warehouses [put]
+func (h *SalesChannelHandlers) SetWarehouses(c *gin.Context) {
+	id, ok := parseSalesChannelID(c)
+	if !ok {
+		return
+	}
+	var req entity.SalesChannelWarehousesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	channel, err := h.salesChannelUseCase.SetWarehouses(c.Request.Context(), id, &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, channel)
+}
+
+// ListMappings handles listing the SKU mappings of a sales channel
+// @Summary List channel SKU mappings
+// @Description List the listings of a sales channel mapped to SKUs
+// @Tags sales-channels
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Sales channel ID"
+// @Success 200 {array} entity.ChannelSKUMapping
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /sales-channels/{id}/mappings [get]
+func (h *SalesChannelHandlers) ListMappings(c *gin.Context) {
+	id, ok := parseSalesChannelID(c)
+	if !ok {
+		return
+	}
+
+	mappings, err := h.salesChannelUseCase.ListMappings(c.Request.Context(), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, mappings)
+}
+
+// SaveMapping handles mapping a SKU to a listing of a sales channel
+// @Summary Map SKU to channel listing
+// @Description Map a SKU to a listing of a sales channel by its external SKU, replacing the listing's mapping. The external ID identifies the listing inventory levels are pushed to: the inventory item ID on Shopify, the product ID or "<product>/<variation>" on WooCommerce.
+// @Tags sales-channels
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Sales channel ID"
+// @Param request body entity.ChannelSKUMappingRequest true "SKU mapping"
+// @Success 200 {object} entity.ChannelSKUMapping
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /sales-channels/{id}/mappings [put]
+func (h *SalesChannelHandlers) SaveMapping(c *gin.Context) {
+	id, ok := parseSalesChannelID(c)
+	if !ok {
+		return
+	}
+	var req entity.ChannelSKUMappingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	mapping, err := h.salesChannelUseCase.SaveMapping(c.Request.Context(), id, &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapping)
+}
+
+// DeleteMapping handles deleting a SKU mapping of a sales channel
+// @Summary Delete channel SKU mapping
+// @Description Delete a SKU mapping of a sales channel
+// @Tags sales-channels
+// @Security BearerAuth
+// @Param id path int true "Sales channel ID"
+// @Param mappingId path int true "Mapping ID"
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /sales-channels/{id}/mappings/{mappingId} [delete]
+func (h *SalesChannelHandlers) DeleteMapping(c *gin.Context) {
+	id, ok := parseSalesChannelID(c)
+	if !ok {
+		return
+	}
+	mappingID, err := strconv.ParseUint(c.Param("mappingId"), 10, 32)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid mapping ID"))
+		return
+	}
+
+	if err := h.salesChannelUseCase.DeleteMapping(c.Request.Context(), id, uint(mappingID)); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// SyncOrders handles ingesting the orders of a sales channel
+// @Summary Sync channel orders
+// @Description Ingest the orders created on a sales channel since the last sync as draft sales orders. Orders already ingested are left as they are; an order that fails, such as one with an unmapped listing, is fetched again on the next sync.
+// @Tags sales-channels
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Sales channel ID"
+// @Success 200 {object} entity.ChannelSyncRun
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /sales-channels/{id}/sync/orders [post]
+func (h *SalesChannelHandlers) SyncOrders(c *gin.Context) {
+	id, ok := parseSalesChannelID(c)
+	if !ok {
+		return
+	}
+	userID := currentUserID(c)
+	if userID == nil {
+		c.Error(entity.NewError(entity.ErrCodeUnauthenticated, "Sales orders are created by a user"))
+		return
+	}
+
+	run, err := h.salesChannelUseCase.SyncOrders(c.Request.Context(), id, *userID, auth.GetUserIDFromContext(c))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, run)
+}
+
+// PushInventory handles pushing the inventory levels of a sales channel
+// @Summary Push channel inventory
+// @Description Push the stock available in the stores linked to a sales channel as the inventory levels of its mapped listings
+// @Tags sales-channels
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Sales channel ID"
+// @Success 200 {object} entity.ChannelSyncRun
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /sales-channels/{id}/sync/inventory [post]
+func (h *SalesChannelHandlers) PushInventory(c *gin.Context) {
+	id, ok := parseSalesChannelID(c)
+	if !ok {
+		return
+	}
+
+	run, err := h.salesChannelUseCase.PushInventory(c.Request.Context(), id, auth.GetUserIDFromContext(c))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, run)
+}
+
+// ListSyncRuns handles listing the sync runs of a sales channel
+// @Summary List channel sync runs
+// @Description List the latest order and inventory syncs of a sales channel with the orders and levels that failed
+// @Tags sales-channels
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Sales channel ID"
+// @Success 200 {array} entity.ChannelSyncRun
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /sales-channels/{id}/sync-runs [get]
+func (h *SalesChannelHandlers) ListSyncRuns(c *gin.Context) {
+	id, ok := parseSalesChannelID(c)
+	if !ok {
+		return
+	}
+
+	runs, err := h.salesChannelUseCase.ListSyncRuns(c.Request.Context(), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, runs)
+}
+
+func parseSalesChannelID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid sales channel ID"))
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -0,0 +1,201 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+)
+
+// SavedViewHandlers handles saved view HTTP requests
+type SavedViewHandlers struct {
+	viewUseCase *usecase.SavedViewUseCase
+}
+
+// NewSavedViewHandlers creates a new saved view handlers instance
+func NewSavedViewHandlers(viewUseCase *usecase.SavedViewUseCase) *SavedViewHandlers {
+	return &SavedViewHandlers{
+		viewUseCase: viewUseCase,
+	}
+}
+
+// RegisterRoutes registers saved view routes. Every user saves views of their
+// own; applying one to a list still takes the list's permission.
+func (h *SavedViewHandlers) RegisterRoutes(router *gin.RouterGroup) {
+	views := router.Group("/saved-views")
+	{
+		views.POST("", h.CreateView)
+		views.GET("", h.ListViews)
+		views.GET("/:id", h.GetView)
+		views.PUT("/:id", h.UpdateView)
+		views.DELETE("/:id", h.DeleteView)
+	}
+}
+
+// CreateView handles saving a view
+// @Summary Save view
+// @Description Save a named combination of the query parameters of a module's list, optionally shared with roles by name. Lists requested with view_id=<id> are then filtered by them.
+// @Tags saved-views
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body entity.SavedViewRequest true "View"
+// @Success 201 {object} entity.SavedView
+// @Failure 400 {object} ErrorResponse
+// @Router /saved-views [post]
+func (h *SavedViewHandlers) CreateView(c *gin.Context) {
+	userID := currentUserID(c)
+	if userID == nil {
+		c.Error(entity.NewError(entity.ErrCodeUnauthenticated, "User not found in context"))
+		return
+	}
+
+	var req entity.SavedViewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	view, err := h.viewUseCase.CreateView(c.Request.Context(), *userID, &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, view)
+}
+
+// ListViews handles listing saved views
+// @Summary List saved views
+// @Description List the views the caller saved or that are shared with their role, by module and name
+// @Tags saved-views
+// @Security BearerAuth
+// @Produce json
+// @Param module query string false "Module, e.g. purchase_orders"
+// @Success 200 {array} entity.SavedView
+// @Failure 500 {object} ErrorResponse
+// @Router /saved-views [get]
+func (h *SavedViewHandlers) ListViews(c *gin.Context) {
+	userID := currentUserID(c)
+	if userID == nil {
+		c.Error(entity.NewError(entity.ErrCodeUnauthenticated, "User not found in context"))
+		return
+	}
+
+	views, err := h.viewUseCase.ListViews(c.Request.Context(), *userID, c.GetString("role"), c.Query("module"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, views)
+}
+
+// GetView handles getting a saved view
+// @Summary Get saved view
+// @Description Get a view the caller saved or that is shared with their role
+// @Tags saved-views
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "View ID"
+// @Success 200 {object} entity.SavedView
+// @Failure 404 {object} ErrorResponse
+// @Router /saved-views/{id} [get]
+func (h *SavedViewHandlers) GetView(c *gin.Context) {
+	userID := currentUserID(c)
+	if userID == nil {
+		c.Error(entity.NewError(entity.ErrCodeUnauthenticated, "User not found in context"))
+		return
+	}
+	id, ok := parseSavedViewID(c)
+	if !ok {
+		return
+	}
+
+	view, err := h.viewUseCase.GetView(c.Request.Context(), *userID, c.GetString("role"), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, view)
+}
+
+// UpdateView handles changing a saved view
+// @Summary Update saved view
+// @Description Change the name, criteria and sharing of a view the caller saved
+// @Tags saved-views
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "View ID"
+// @Param request body entity.SavedViewRequest true "View"
+// @Success 200 {object} entity.SavedView
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /saved-views/{id} [put]
+func (h *SavedViewHandlers) UpdateView(c *gin.Context) {
+	userID := currentUserID(c)
+	if userID == nil {
+		c.Error(entity.NewError(entity.ErrCodeUnauthenticated, "User not found in context"))
+		return
+	}
+	id, ok := parseSavedViewID(c)
+	if !ok {
+		return
+	}
+	var req entity.SavedViewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	view, err := h.viewUseCase.UpdateView(c.Request.Context(), *userID, c.GetString("role"), id, &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, view)
+}
+
+// DeleteView handles removing a saved view
+// @Summary Delete saved view
+// @Description Remove a view the caller saved
+// @Tags saved-views
+// @Security BearerAuth
+// @Param id path int true "View ID"
+// @Success 204 "No Content"
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /saved-views/{id} [delete]
+func (h *SavedViewHandlers) DeleteView(c *gin.Context) {
+	userID := currentUserID(c)
+	if userID == nil {
+		c.Error(entity.NewError(entity.ErrCodeUnauthenticated, "User not found in context"))
+		return
+	}
+	id, ok := parseSavedViewID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.viewUseCase.DeleteView(c.Request.Context(), *userID, c.GetString("role"), id); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func parseSavedViewID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid view ID"))
+		return 0, false
+	}
+	return uint(id), true
+}
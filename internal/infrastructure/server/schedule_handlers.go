@@ -0,0 +1,119 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/server/middleware"
+)
+
+// ScheduleHandlers handles scheduled task HTTP requests
+type ScheduleHandlers struct {
+	schedulerUseCase *usecase.SchedulerUseCase
+}
+
+// NewScheduleHandlers creates a new schedule handlers instance
+func NewScheduleHandlers(schedulerUseCase *usecase.SchedulerUseCase) *ScheduleHandlers {
+	return &ScheduleHandlers{
+		schedulerUseCase: schedulerUseCase,
+	}
+}
+
+// RegisterRoutes registers scheduled task routes
+func (h *ScheduleHandlers) RegisterRoutes(router *gin.RouterGroup) {
+	schedules := router.Group("/system/schedules")
+	{
+		schedules.GET("", middleware.PermissionMiddleware(entity.SystemJobRead), h.ListSchedules)
+		schedules.GET("/runs", middleware.PermissionMiddleware(entity.SystemJobRead), h.ListScheduledRuns)
+		schedules.POST("/:name/run", middleware.PermissionMiddleware(entity.SystemJobManage), h.RunScheduledTask)
+	}
+}
+
+// ListSchedules handles listing the scheduled tasks
+// @Summary List scheduled tasks
+// @Description List the recurring background tasks with their schedule and last run. The next run is only known when the instance answering runs the schedules, as leader says.
+// @Tags system
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} entity.SchedulerStatus
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /system/schedules [get]
+func (h *ScheduleHandlers) ListSchedules(c *gin.Context) {
+	status, err := h.schedulerUseCase.Status(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// ListScheduledRuns handles listing the runs of the scheduled tasks
+// @Summary List scheduled task runs
+// @Description List the recorded runs of the scheduled tasks, latest first, with the instance that ran them and the error of the failed ones
+// @Tags system
+// @Security BearerAuth
+// @Produce json
+// @Param task query string false "Task name, e.g. report_schedules"
+// @Param status query string false "Status (RUNNING/SUCCEEDED/FAILED)"
+// @Param page query int false "Page number"
+// @Param page_size query int false "Page size"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /system/schedules/runs [get]
+func (h *ScheduleHandlers) ListScheduledRuns(c *gin.Context) {
+	filter := &entity.ScheduledRunFilter{
+		Task:   c.Query("task"),
+		Status: entity.ScheduledRunStatus(c.Query("status")),
+	}
+	if page, err := strconv.Atoi(c.Query("page")); err == nil {
+		filter.Page = page
+	}
+	if pageSize, err := strconv.Atoi(c.Query("page_size")); err == nil {
+		filter.PageSize = pageSize
+	}
+
+	runs, total, err := h.schedulerUseCase.ListRuns(c.Request.Context(), filter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"runs":      runs,
+		"total":     total,
+		"page":      filter.Page,
+		"page_size": filter.PageSize,
+	})
+}
+
+// RunScheduledTask handles running a scheduled task by hand
+// @Summary Run scheduled task
+// @Description Run a scheduled task now, in the background on the instance answering. The run is recorded like the scheduled ones; poll the runs for its outcome.
+// @Tags system
+// @Security BearerAuth
+// @Produce json
+// @Param name path string true "Task name, e.g. report_schedules"
+// @Success 202 {object} entity.ScheduledRun
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /system/schedules/{name}/run [post]
+func (h *ScheduleHandlers) RunScheduledTask(c *gin.Context) {
+	run, err := h.schedulerUseCase.Trigger(c.Request.Context(), c.Param("name"), currentUserID(c))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, run)
+}
@@ -0,0 +1,599 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+)
+
+const (
+	scimContentType    = "application/scim+json"
+	scimUserSchema     = "urn:ietf:params:scim:schemas:core:2.0:User"
+	scimListSchema     = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	scimPatchSchema    = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	scimErrorSchema    = "urn:ietf:params:scim:api:messages:2.0:Error"
+	scimConfigSchema   = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	scimMaxResults     = 200
+	scimDefaultResults = 100
+)
+
+// scimFilterPattern matches the "<attribute> eq "<value>"" filters identity
+// providers send to look users up
+var scimFilterPattern = regexp.MustCompile(`(?i)^\s*([a-z.]+)\s+eq\s+"((?:[^"\\]|\\.)*)"\s*$`)
+
+// SCIMUser is a user in the SCIM 2.0 core User schema. Roles carry the
+// directory groups the user's role is mapped from.
+type SCIMUser struct {
+	Schemas    []string         `json:"schemas"`
+	ID         string           `json:"id,omitempty"`
+	ExternalID string           `json:"externalId,omitempty"`
+	UserName   string           `json:"userName"`
+	Active     *bool            `json:"active,omitempty"`
+	Emails     []SCIMMultiValue `json:"emails,omitempty"`
+	Roles      []SCIMMultiValue `json:"roles,omitempty"`
+	Groups     []SCIMMultiValue `json:"groups,omitempty"`
+	Meta       *SCIMMeta        `json:"meta,omitempty"`
+}
+
+// SCIMMultiValue is an entry of a multi-valued SCIM attribute
+type SCIMMultiValue struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+// SCIMMeta is the metadata of a SCIM resource
+type SCIMMeta struct {
+	ResourceType string    `json:"resourceType"`
+	Created      time.Time `json:"created"`
+	LastModified time.Time `json:"lastModified"`
+	Location     string    `json:"location"`
+}
+
+// SCIMListResponse is a page of SCIM resources
+type SCIMListResponse struct {
+	Schemas      []string   `json:"schemas"`
+	TotalResults int64      `json:"totalResults"`
+	StartIndex   int        `json:"startIndex"`
+	ItemsPerPage int        `json:"itemsPerPage"`
+	Resources    []SCIMUser `json:"Resources"`
+}
+
+// SCIMPatchRequest is a SCIM PATCH request
+type SCIMPatchRequest struct {
+	Schemas    []string             `json:"schemas"`
+	Operations []SCIMPatchOperation `json:"Operations" binding:"required,min=1"`
+}
+
+// SCIMPatchOperation is one operation of a SCIM PATCH request
+type SCIMPatchOperation struct {
+	Op    string          `json:"op" binding:"required"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+// SCIMError is a SCIM error response
+type SCIMError struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	SCIMType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail"`
+}
+
+// SCIMHandlers serves the SCIM 2.0 Users endpoints identity providers use to
+// provision and deprovision users from the corporate directory
+type SCIMHandlers struct {
+	provisioningUseCase *usecase.UserProvisioningUseCase
+	token               string
+}
+
+// NewSCIMHandlers creates a new SCIM handlers instance. Requests must carry
+// token as their bearer token.
+func NewSCIMHandlers(provisioningUseCase *usecase.UserProvisioningUseCase, token string) *SCIMHandlers {
+	return &SCIMHandlers{
+		provisioningUseCase: provisioningUseCase,
+		token:               token,
+	}
+}
+
+// RegisterRoutes registers the SCIM routes, which are authenticated by the
+// provisioning token instead of a user's token
+func (h *SCIMHandlers) RegisterRoutes(router *gin.RouterGroup) {
+	scim := router.Group("/scim/v2")
+	scim.Use(h.authenticate)
+	{
+		scim.GET("/ServiceProviderConfig", h.GetServiceProviderConfig)
+		scim.GET("/Users", h.ListUsers)
+		scim.POST("/Users", h.CreateUser)
+		scim.GET("/Users/:id", h.GetUser)
+		scim.PUT("/Users/:id", h.ReplaceUser)
+		scim.PATCH("/Users/:id", h.PatchUser)
+		scim.DELETE("/Users/:id", h.DeleteUser)
+	}
+}
+
+func (h *SCIMHandlers) authenticate(c *gin.Context) {
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) != 1 {
+		scimError(c, http.StatusUnauthorized, "", "Invalid provisioning token")
+		c.Abort()
+		return
+	}
+	c.Next()
+}
+
+// GetServiceProviderConfig handles describing the supported SCIM features
+// @Summary Get SCIM service provider config
+// @Description Describe the SCIM features supported: filtering on userName, externalId and emails with eq, and PATCH
+// @Tags scim
+// @Produce json
+// @Param Authorization header string true "Bearer <provisioning.scim_token>"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} SCIMError
+// @Router /scim/v2/ServiceProviderConfig [get]
+func (h *SCIMHandlers) GetServiceProviderConfig(c *gin.Context) {
+	c.Header("Content-Type", scimContentType)
+	c.JSON(http.StatusOK, gin.H{
+		"schemas":        []string{scimConfigSchema},
+		"patch":          gin.H{"supported": true},
+		"bulk":           gin.H{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         gin.H{"supported": true, "maxResults": scimMaxResults},
+		"changePassword": gin.H{"supported": false},
+		"sort":           gin.H{"supported": false},
+		"etag":           gin.H{"supported": false},
+		"authenticationSchemes": []gin.H{{
+			"type":        "oauthbearertoken",
+			"name":        "Bearer token",
+			"description": "The provisioning.scim_token configured on the server",
+		}},
+	})
+}
+
+// ListUsers handles listing users
+// @Summary List SCIM users
+// @Description List users in ID order, optionally filtered with 'userName eq "..."', 'externalId eq "..."' or 'emails eq "..."'
+// @Tags scim
+// @Produce json
+// @Param Authorization header string true "Bearer <provisioning.scim_token>"
+// @Param filter query string false "SCIM filter"
+// @Param startIndex query int false "1-based index of the first result"
+// @Param count query int false "Results per page (max 200)"
+// @Success 200 {object} SCIMListResponse
+// @Failure 400 {object} SCIMError
+// @Failure 401 {object} SCIMError
+// @Router /scim/v2/Users [get]
+func (h *SCIMHandlers) ListUsers(c *gin.Context) {
+	filter := &entity.ProvisionedUserFilter{Limit: scimDefaultResults}
+	if expr := c.Query("filter"); expr != "" {
+		match := scimFilterPattern.FindStringSubmatch(expr)
+		if match == nil {
+			scimError(c, http.StatusBadRequest, "invalidFilter", "Only 'eq' filters on userName, externalId and emails are supported")
+			return
+		}
+		value := strings.ReplaceAll(strings.ReplaceAll(match[2], `\"`, `"`), `\\`, `\`)
+		switch strings.ToLower(match[1]) {
+		case "username":
+			filter.Username = value
+		case "externalid":
+			filter.ExternalID = value
+		case "emails", "emails.value":
+			filter.Email = value
+		default:
+			scimError(c, http.StatusBadRequest, "invalidFilter", "Only 'eq' filters on userName, externalId and emails are supported")
+			return
+		}
+	}
+
+	startIndex := 1
+	if value, err := strconv.Atoi(c.Query("startIndex")); err == nil && value > 1 {
+		startIndex = value
+	}
+	filter.Offset = startIndex - 1
+	if count, err := strconv.Atoi(c.Query("count")); err == nil && count >= 0 {
+		filter.Limit = min(count, scimMaxResults)
+	}
+
+	resources := []SCIMUser{}
+	var total int64
+	if filter.Limit > 0 {
+		users, count, err := h.provisioningUseCase.ListUsers(c.Request.Context(), filter)
+		if err != nil {
+			h.handleError(c, err)
+			return
+		}
+		for i := range users {
+			resources = append(resources, toSCIMUser(&users[i]))
+		}
+		total = count
+	} else {
+		// A count of 0 asks for the number of matches only
+		filter.Limit = 1
+		_, count, err := h.provisioningUseCase.ListUsers(c.Request.Context(), filter)
+		if err != nil {
+			h.handleError(c, err)
+			return
+		}
+		total = count
+	}
+
+	c.Header("Content-Type", scimContentType)
+	c.JSON(http.StatusOK, SCIMListResponse{
+		Schemas:      []string{scimListSchema},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	})
+}
+
+// CreateUser handles provisioning a user
+// @Summary Create SCIM user
+// @Description Provision a user. Their role comes from the first of their roles or groups with a role mapping, then the first named after a role, then provisioning.default_role. Provisioned users set a password through the password reset flow.
+// @Tags scim
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer <provisioning.scim_token>"
+// @Param request body SCIMUser true "User"
+// @Success 201 {object} SCIMUser
+// @Failure 400 {object} SCIMError
+// @Failure 401 {object} SCIMError
+// @Failure 409 {object} SCIMError
+// @Router /scim/v2/Users [post]
+func (h *SCIMHandlers) CreateUser(c *gin.Context) {
+	var req SCIMUser
+	if err := c.ShouldBindJSON(&req); err != nil {
+		scimError(c, http.StatusBadRequest, "invalidSyntax", err.Error())
+		return
+	}
+
+	user, err := h.provisioningUseCase.CreateUser(c.Request.Context(), req.provisionInput())
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.Header("Content-Type", scimContentType)
+	c.Header("Location", scimLocation(user.ID))
+	c.JSON(http.StatusCreated, toSCIMUser(user))
+}
+
+// GetUser handles getting a user
+// @Summary Get SCIM user
+// @Description Get a user
+// @Tags scim
+// @Produce json
+// @Param Authorization header string true "Bearer <provisioning.scim_token>"
+// @Param id path int true "User ID"
+// @Success 200 {object} SCIMUser
+// @Failure 401 {object} SCIMError
+// @Failure 404 {object} SCIMError
+// @Router /scim/v2/Users/{id} [get]
+func (h *SCIMHandlers) GetUser(c *gin.Context) {
+	user, ok := h.findUser(c)
+	if !ok {
+		return
+	}
+
+	c.Header("Content-Type", scimContentType)
+	c.JSON(http.StatusOK, toSCIMUser(user))
+}
+
+// ReplaceUser handles replacing a user
+// @Summary Replace SCIM user
+// @Description Replace a user's userName, externalId, email and active flag. Their role is reassigned when roles or groups are sent, and kept otherwise.
+// @Tags scim
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer <provisioning.scim_token>"
+// @Param id path int true "User ID"
+// @Param request body SCIMUser true "User"
+// @Success 200 {object} SCIMUser
+// @Failure 400 {object} SCIMError
+// @Failure 401 {object} SCIMError
+// @Failure 404 {object} SCIMError
+// @Failure 409 {object} SCIMError
+// @Router /scim/v2/Users/{id} [put]
+func (h *SCIMHandlers) ReplaceUser(c *gin.Context) {
+	id, ok := scimUserID(c)
+	if !ok {
+		return
+	}
+
+	var req SCIMUser
+	if err := c.ShouldBindJSON(&req); err != nil {
+		scimError(c, http.StatusBadRequest, "invalidSyntax", err.Error())
+		return
+	}
+
+	user, err := h.provisioningUseCase.ReplaceUser(c.Request.Context(), id, req.provisionInput())
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.Header("Content-Type", scimContentType)
+	c.JSON(http.StatusOK, toSCIMUser(user))
+}
+
+// PatchUser handles updating part of a user
+// @Summary Patch SCIM user
+// @Description Add, replace or remove a user's active, userName, externalId, emails and roles. Other attributes are ignored. Setting active to false deprovisions the user.
+// @Tags scim
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer <provisioning.scim_token>"
+// @Param id path int true "User ID"
+// @Param request body SCIMPatchRequest true "Patch operations"
+// @Success 200 {object} SCIMUser
+// @Failure 400 {object} SCIMError
+// @Failure 401 {object} SCIMError
+// @Failure 404 {object} SCIMError
+// @Failure 409 {object} SCIMError
+// @Router /scim/v2/Users/{id} [patch]
+func (h *SCIMHandlers) PatchUser(c *gin.Context) {
+	var req SCIMPatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		scimError(c, http.StatusBadRequest, "invalidSyntax", err.Error())
+		return
+	}
+
+	user, ok := h.findUser(c)
+	if !ok {
+		return
+	}
+
+	// Roles are left out so the role is only reassigned when the patch sets them
+	patched := toSCIMUser(user)
+	patched.Roles = nil
+	for _, op := range req.Operations {
+		if err := patched.apply(op); err != nil {
+			scimError(c, http.StatusBadRequest, "invalidValue", err.Error())
+			return
+		}
+	}
+
+	user, err := h.provisioningUseCase.ReplaceUser(c.Request.Context(), user.ID, patched.provisionInput())
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.Header("Content-Type", scimContentType)
+	c.JSON(http.StatusOK, toSCIMUser(user))
+}
+
+// DeleteUser handles deprovisioning a user
+// @Summary Delete SCIM user
+// @Description Deprovision a user. The account is deactivated and its sessions ended, but kept for the audit trail.
+// @Tags scim
+// @Param Authorization header string true "Bearer <provisioning.scim_token>"
+// @Param id path int true "User ID"
+// @Success 204 "No Content"
+// @Failure 401 {object} SCIMError
+// @Failure 404 {object} SCIMError
+// @Router /scim/v2/Users/{id} [delete]
+func (h *SCIMHandlers) DeleteUser(c *gin.Context) {
+	id, ok := scimUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.provisioningUseCase.DeactivateUser(c.Request.Context(), id); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *SCIMHandlers) findUser(c *gin.Context) (*entity.User, bool) {
+	id, ok := scimUserID(c)
+	if !ok {
+		return nil, false
+	}
+
+	user, err := h.provisioningUseCase.GetUser(c.Request.Context(), id)
+	if err != nil {
+		h.handleError(c, err)
+		return nil, false
+	}
+	return user, true
+}
+
+func (h *SCIMHandlers) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, usecase.ErrProvisionedUserNotFound):
+		scimError(c, http.StatusNotFound, "", err.Error())
+	case errors.Is(err, usecase.ErrProvisionedUserExists),
+		errors.Is(err, usecase.ErrProvisionIdentityTaken):
+		scimError(c, http.StatusConflict, "uniqueness", err.Error())
+	case errors.Is(err, usecase.ErrProvisionInvalidEmail),
+		errors.Is(err, usecase.ErrProvisionNoRole):
+		scimError(c, http.StatusBadRequest, "invalidValue", err.Error())
+	default:
+		scimError(c, http.StatusInternalServerError, "", err.Error())
+	}
+}
+
+// apply applies a PATCH operation to the user
+func (u *SCIMUser) apply(op SCIMPatchOperation) error {
+	operation := strings.ToLower(op.Op)
+	if operation != "add" && operation != "replace" && operation != "remove" {
+		return errors.New("unsupported patch operation " + op.Op)
+	}
+
+	path := strings.ToLower(op.Path)
+	if path == "" {
+		if operation == "remove" {
+			return errors.New("remove requires a path")
+		}
+		var values map[string]json.RawMessage
+		if err := json.Unmarshal(op.Value, &values); err != nil {
+			return errors.New("patch value must be an object when no path is given")
+		}
+		for attribute, value := range values {
+			if err := u.apply(SCIMPatchOperation{Op: op.Op, Path: attribute, Value: value}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	switch {
+	case path == "active":
+		if operation == "remove" {
+			return errors.New("active cannot be removed")
+		}
+		// Some identity providers send booleans as strings
+		var active bool
+		if err := json.Unmarshal(op.Value, &active); err != nil {
+			var text string
+			if err := json.Unmarshal(op.Value, &text); err != nil {
+				return errors.New("active must be a boolean")
+			}
+			if active, err = strconv.ParseBool(text); err != nil {
+				return errors.New("active must be a boolean")
+			}
+		}
+		u.Active = &active
+	case path == "username":
+		if operation == "remove" {
+			return errors.New("userName cannot be removed")
+		}
+		if err := json.Unmarshal(op.Value, &u.UserName); err != nil {
+			return errors.New("userName must be a string")
+		}
+	case path == "externalid":
+		if operation == "remove" {
+			u.ExternalID = ""
+			return nil
+		}
+		if err := json.Unmarshal(op.Value, &u.ExternalID); err != nil {
+			return errors.New("externalId must be a string")
+		}
+	case strings.HasPrefix(path, "emails"):
+		if operation == "remove" {
+			u.Emails = nil
+			return nil
+		}
+		emails, err := multiValues(op.Value)
+		if err != nil {
+			return errors.New("emails must be a list of values")
+		}
+		u.Emails = emails
+	case strings.HasPrefix(path, "roles"):
+		if operation == "remove" {
+			u.Roles = nil
+			return nil
+		}
+		roles, err := multiValues(op.Value)
+		if err != nil {
+			return errors.New("roles must be a list of values")
+		}
+		if operation == "add" {
+			roles = append(u.Roles, roles...)
+		}
+		u.Roles = roles
+	}
+	return nil
+}
+
+// provisionInput converts the user to the directory attributes provisioning uses
+func (u *SCIMUser) provisionInput() *entity.ProvisionUserInput {
+	input := &entity.ProvisionUserInput{
+		ExternalID: u.ExternalID,
+		Username:   u.UserName,
+		Active:     u.Active == nil || *u.Active,
+	}
+	for _, email := range u.Emails {
+		if input.Email == "" || email.Primary {
+			input.Email = email.Value
+		}
+	}
+	if input.Email == "" && strings.Contains(u.UserName, "@") {
+		input.Email = u.UserName
+	}
+	for _, values := range [][]SCIMMultiValue{u.Roles, u.Groups} {
+		for _, value := range values {
+			if value.Value != "" {
+				input.Groups = append(input.Groups, value.Value)
+			} else if value.Display != "" {
+				input.Groups = append(input.Groups, value.Display)
+			}
+		}
+	}
+	return input
+}
+
+func toSCIMUser(user *entity.User) SCIMUser {
+	active := user.IsActive()
+	scimUser := SCIMUser{
+		Schemas:    []string{scimUserSchema},
+		ID:         strconv.FormatUint(uint64(user.ID), 10),
+		ExternalID: user.ExternalID,
+		UserName:   user.Username,
+		Active:     &active,
+		Emails:     []SCIMMultiValue{{Value: user.Email, Type: "work", Primary: true}},
+		Meta: &SCIMMeta{
+			ResourceType: "User",
+			Created:      user.CreatedAt,
+			LastModified: user.UpdatedAt,
+			Location:     scimLocation(user.ID),
+		},
+	}
+	if user.Role != nil {
+		scimUser.Roles = []SCIMMultiValue{{Value: user.Role.Name, Primary: true}}
+	}
+	return scimUser
+}
+
+// multiValues reads a multi-valued attribute, given either as a list of entries
+// or as a single value
+func multiValues(raw json.RawMessage) ([]SCIMMultiValue, error) {
+	var values []SCIMMultiValue
+	if err := json.Unmarshal(raw, &values); err == nil {
+		return values, nil
+	}
+	var value SCIMMultiValue
+	if err := json.Unmarshal(raw, &value); err == nil {
+		return []SCIMMultiValue{value}, nil
+	}
+	var text string
+	if err := json.Unmarshal(raw, &text); err != nil {
+		return nil, err
+	}
+	return []SCIMMultiValue{{Value: text, Primary: true}}, nil
+}
+
+func scimUserID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		scimError(c, http.StatusNotFound, "", "user not found")
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func scimLocation(id uint) string {
+	return "/api/v1/scim/v2/Users/" + strconv.FormatUint(uint64(id), 10)
+}
+
+func scimError(c *gin.Context, status int, scimType, detail string) {
+	c.Header("Content-Type", scimContentType)
+	c.JSON(status, SCIMError{
+		Schemas:  []string{scimErrorSchema},
+		Status:   strconv.Itoa(status),
+		SCIMType: scimType,
+		Detail:   detail,
+	})
+}
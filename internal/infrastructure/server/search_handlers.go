@@ -0,0 +1,95 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/server/middleware"
+)
+
+// searchPermissions are the permissions to read each kind of record the
+// search covers; records the caller may not read are left out of the results
+var searchPermissions = map[entity.SearchEntityType]entity.Permission{
+	entity.SearchSKU:           entity.ProductRead,
+	entity.SearchVendorItem:    entity.VendorRead,
+	entity.SearchClient:        entity.ClientRead,
+	entity.SearchVendor:        entity.VendorRead,
+	entity.SearchSalesOrder:    entity.SalesOrderRead,
+	entity.SearchPurchaseOrder: entity.PurchaseOrderRead,
+}
+
+// SearchHandlers handles HTTP requests for searching across records
+type SearchHandlers struct {
+	searchUseCase *usecase.SearchUseCase
+}
+
+// NewSearchHandlers creates a new search handlers instance
+func NewSearchHandlers(searchUseCase *usecase.SearchUseCase) *SearchHandlers {
+	return &SearchHandlers{
+		searchUseCase: searchUseCase,
+	}
+}
+
+// RegisterRoutes registers search routes
+func (h *SearchHandlers) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("/search", h.Search)
+}
+
+// Search handles searching across records
+// @Summary Search
+// @Description Search SKUs, vendor items, clients, vendors, sales orders and purchase orders by code, name, email or number, best match first. Words match as prefixes, misspelt words still match, and an exact code or number ranks first. Only records the caller may read are searched; facets count the matches of each type before the type filter.
+// @Tags search
+// @Security BearerAuth
+// @Produce json
+// @Param q query string true "Search text, at least 2 characters"
+// @Param type query string false "Comma separated types to return (sku/vendor_item/client/vendor/sales_order/purchase_order)"
+// @Param page query int false "Page number"
+// @Param page_size query int false "Page size (default 20, at most 100)"
+// @Success 200 {object} entity.SearchResult
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /search [get]
+func (h *SearchHandlers) Search(c *gin.Context) {
+	query := entity.SearchQuery{Text: c.Query("q")}
+	if types := c.Query("type"); types != "" {
+		for _, t := range strings.Split(types, ",") {
+			query.Types = append(query.Types, entity.SearchEntityType(strings.TrimSpace(t)))
+		}
+	}
+	if page, err := strconv.Atoi(c.Query("page")); err == nil {
+		query.Page = page
+	}
+	if pageSize, err := strconv.Atoi(c.Query("page_size")); err == nil {
+		query.PageSize = pageSize
+	}
+
+	var readable []entity.SearchEntityType
+	for _, t := range entity.SearchEntityTypes {
+		if middleware.HasPermission(c, searchPermissions[t]) {
+			readable = append(readable, t)
+		}
+	}
+	if len(readable) == 0 {
+		c.Error(entity.NewError(entity.ErrCodePermissionDenied, "Insufficient permissions"))
+		return
+	}
+
+	result, err := h.searchUseCase.Search(c.Request.Context(), query, readable)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrSearchTextTooShort), errors.Is(err, usecase.ErrSearchTypeInvalid):
+			c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		default:
+			c.Error(err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
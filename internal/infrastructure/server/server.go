@@ -1,17 +1,33 @@
 package server
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
 	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/accounting"
 	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/auth"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/broker"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/cache"
 	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/config"
 	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/database"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/eventbus"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/extension"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/filestore"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/notification"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/payment"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/realtime"
 	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
 	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/server/middleware"
 	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/service"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/tracing"
+	"gorm.io/gorm"
 )
 
 type Server struct {
@@ -25,12 +41,89 @@ type Server struct {
 	manufacturingUC *usecase.ManufacturingUseCase
 	skuUC           *usecase.SKUUseCase
 	purchaseUC      *usecase.PurchaseUseCase
+	dropShipUC      *usecase.DropShipUseCase
+	crossDockUC     *usecase.CrossDockUseCase
+	consolidationUC *usecase.PurchaseConsolidationUseCase
 	orderUC         *usecase.OrderUseCase
 	clientUC        usecase.ClientUseCase // Changed from *usecase.ClientUseCase
 	financeUC       *usecase.FinanceUseCase
+	currencyUC      *usecase.CurrencyUseCase
 	reportUC        *usecase.ReportUseCase
+	portalUC        *usecase.PortalUseCase
+	systemUC        *usecase.SystemUseCase
+	archiveUC       *usecase.ArchiveUseCase
+	provisionUC     *usecase.ProvisionUseCase
+	consignmentUC   *usecase.ConsignmentUseCase
+	putawayUC       *usecase.PutawayUseCase
+	inboundUC       *usecase.InboundUseCase
+	salesChannelUC  *usecase.SalesChannelUseCase
+	ediUC           *usecase.EDIUseCase
+	accountingUC    *usecase.AccountingExportUseCase
+	bankFeedUC      *usecase.BankFeedUseCase
+	allocationUC    *usecase.AllocationUseCase
+	rfmUC           *usecase.RFMUseCase
+	skuClassUC      *usecase.SKUClassUseCase
+	stockSnapshotUC *usecase.StockSnapshotUseCase
+	assetUC         *usecase.AssetUseCase
+	forecastUC      *usecase.ForecastUseCase
+	demandUC        *usecase.DemandForecastUseCase
+	returnsUC       *usecase.ReturnsUseCase
+	costServeUC     *usecase.CostToServeUseCase
+	marginUC        *usecase.MarginUseCase
+	fulfillmentUC   *usecase.FulfillmentUseCase
+	customsUC       *usecase.CustomsUseCase
+	recurringUC     *usecase.RecurringInvoiceUseCase
+	vendorRiskUC    *usecase.VendorRiskUseCase
+	priceListUC     *usecase.VendorPriceListUseCase
+	dunningUC       *usecase.DunningUseCase
+	activityUC      *usecase.UserActivityUseCase
+	elevationUC     *usecase.ElevatedAccessUseCase
+	impersonationUC *usecase.ImpersonationUseCase
+	permissionUC    *usecase.PermissionUseCase
+	apiKeyUC        *usecase.APIKeyUseCase
+	fieldChangeUC   *usecase.FieldChangeUseCase
+	delegationUC    *usecase.ApprovalDelegationUseCase
+	numberingUC     *usecase.NumberingUseCase
+	customFieldUC   *usecase.CustomFieldUseCase
+	savedViewUC     *usecase.SavedViewUseCase
+	provisioningUC  *usecase.UserProvisioningUseCase
+	brandingUC      *usecase.BrandingUseCase
+	qualityUC       *usecase.QualityUseCase
+	conditionUC     *usecase.ConditionUseCase
+	calendarUC      *usecase.CalendarUseCase
+	fiscalUC        *usecase.FiscalUseCase
+	expenseUC       *usecase.ExpenseUseCase
+	withholdingUC   *usecase.WithholdingUseCase
+	prepaymentUC    *usecase.PrepaymentUseCase
+	notificationUC  *usecase.NotificationUseCase
+	eventLogUC      *usecase.EventLogUseCase
+	paymentHookUC   *usecase.PaymentWebhookUseCase
+	ingestUC        *usecase.EventIngestUseCase
+	idempotencyUC   *usecase.IdempotencyUseCase
+	jobUC           *usecase.JobUseCase
+	schedulerUC     *usecase.SchedulerUseCase
+	attachmentUC    *usecase.AttachmentUseCase
+	skuImageUC      *usecase.SKUImageUseCase
+	clientPrivacyUC *usecase.ClientPrivacyUseCase
+	searchUC        *usecase.SearchUseCase
+	fileStore       filestore.Store
+	eventStream     *realtime.Stream
+	paymentProvider payment.Provider
+	hooks           *extension.Hooks
 	jwtService      *auth.JWTService
 	auditService    *service.AuditService
+
+	httpServer *http.Server
+	db         *gorm.DB
+	broker     broker.Broker
+	cache      *cache.Cache
+
+	// Background jobs stop scheduling runs once stop is closed; ctx is
+	// canceled when the runs in progress outlast the shutdown deadline
+	stop       chan struct{}
+	ctx        context.Context
+	cancel     context.CancelFunc
+	background sync.WaitGroup
 }
 
 // Router returns the gin engine
@@ -39,6 +132,11 @@ func (s *Server) Router() *gin.Engine {
 }
 
 func NewServer(cfg *config.Config) (*Server, error) {
+	// Record OpenTelemetry spans, if enabled
+	if err := tracing.InitFromConfig(cfg.Tracing, "erp-warehouse-api"); err != nil {
+		return nil, err
+	}
+
 	// Initialize database
 	db, err := database.NewDatabase(cfg)
 	if err != nil {
@@ -52,36 +150,318 @@ func NewServer(cfg *config.Config) (*Server, error) {
 	storeRepo := repository.NewStoreRepository(db)
 	stocksRepo := repository.NewStocksRepository(db)
 	vendorRepo := repository.NewVendorRepository(db)
-	manufacturingRepo := repository.NewManufacturingRepository(db)
+	priceListRepo := repository.NewVendorPriceListRepository(db)
+	manufacturingRepo := repository.NewManufacturingRepository(db, stocksRepo)
 	skuRepo := repository.NewSKURepository(db)
 	purchaseRepo := repository.NewPurchaseRepository(db)
-	orderRepo := repository.NewOrderRepository(db, stocksRepo)
+	prepaymentRepo := repository.NewPrepaymentRepository(db)
+	orderRepo := repository.NewOrderRepository(db, stocksRepo, prepaymentRepo)
 	clientRepo := repository.NewClientRepository(db)
-	financeRepo := repository.NewFinanceRepository(db)
+	financeRepo := repository.NewFinanceRepository(db, prepaymentRepo)
 	reportRepo := repository.NewReportRepository(db)
+	systemRepo := repository.NewSystemRepository(db)
+	var sandboxRepo *repository.SandboxRepository
+	if cfg.Sandbox.Enabled {
+		sandboxRepo = repository.NewSandboxRepository(db, cfg.Sandbox.Schema, cfg.Sandbox.SeedTables)
+	}
+	currencyRepo := repository.NewCurrencyRepository(db)
+	archiveRepo := repository.NewArchiveRepository(db)
+	provisionRepo := repository.NewProvisionRepository(db)
+	allocationRepo := repository.NewAllocationRepository(db)
+	rfmRepo := repository.NewRFMRepository(db)
+	skuClassRepo := repository.NewSKUClassRepository(db)
+	stockSnapshotRepo := repository.NewStockSnapshotRepository(db)
+	assetRepo := repository.NewAssetRepository(db)
+	forecastRepo := repository.NewForecastRepository(db)
+	demandRepo := repository.NewDemandForecastRepository(db)
+	returnsRepo := repository.NewReturnsRepository(db)
+	costServeRepo := repository.NewCostToServeRepository(db)
+	marginRepo := repository.NewMarginRepository(db)
+	fulfillmentRepo := repository.NewFulfillmentRepository(db)
+	customsRepo := repository.NewCustomsRepository(db)
+	recurringRepo := repository.NewRecurringInvoiceRepository(db)
+	vendorRiskRepo := repository.NewVendorRiskRepository(db)
+	dunningRepo := repository.NewDunningRepository(db)
+	activityRepo := repository.NewUserActivityRepository(db)
+	elevationRepo := repository.NewElevatedAccessRepository(db)
+	impersonationRepo := repository.NewImpersonationRepository(db)
+	apiKeyRepo := repository.NewAPIKeyRepository(db)
+	fieldChangeRepo := repository.NewFieldChangeRepository(db)
+	delegationRepo := repository.NewApprovalDelegationRepository(db)
+	numberingRepo := repository.NewNumberingRepository(db)
+	customFieldRepo := repository.NewCustomFieldRepository(db)
+	savedViewRepo := repository.NewSavedViewRepository(db)
+	provisioningRepo := repository.NewUserProvisioningRepository(db)
+	brandingRepo := repository.NewBrandingRepository(db)
+	qualityRepo := repository.NewQualityRepository(db, stocksRepo)
+	conditionRepo := repository.NewConditionRepository(db)
+	calendarRepo := repository.NewCalendarRepository(db)
+	fiscalRepo := repository.NewFiscalRepository(db)
+	paymentHookRepo := repository.NewPaymentWebhookRepository(db)
+	notificationRepo := repository.NewNotificationRepository(db)
+	eventLogRepo := repository.NewEventLogRepository(db)
+	idempotencyRepo := repository.NewIdempotencyRepository(db)
+	jobRepo := repository.NewJobRepository(db)
+	scheduledRunRepo := repository.NewScheduledRunRepository(db)
+	attachmentRepo := repository.NewAttachmentRepository(db)
+	skuImageRepo := repository.NewSKUImageRepository(db)
+	privacyRepo := repository.NewPrivacyRepository(db)
+	consignmentRepo := repository.NewConsignmentRepository(db, stocksRepo)
+	putawayRepo := repository.NewPutawayRepository(db)
+	inboundRepo := repository.NewInboundRepository(db)
+	salesChannelRepo := repository.NewSalesChannelRepository(db)
+	ediRepo := repository.NewEDIRepository(db, prepaymentRepo)
+	accountingRepo := repository.NewAccountingExportRepository(db)
+	bankFeedRepo := repository.NewBankFeedRepository(db)
+	searchRepo := repository.NewSearchRepository(db, cfg.Search.Similarity)
+
+	// Initialize compiled-in extensions
+	hooks, err := extension.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	// Initialize the payment gateway adapter
+	paymentProvider, err := payment.NewProvider(cfg.Payments.Provider, cfg.Payments.WebhookSecret,
+		time.Duration(cfg.Payments.SignatureToleranceSeconds)*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	// Connect to the message broker, if one is configured
+	messageBroker, err := broker.New(broker.Config{
+		Driver:   cfg.Broker.Driver,
+		URL:      cfg.Broker.URL,
+		Username: cfg.Broker.Username,
+		Password: cfg.Broker.Password,
+		Group:    cfg.Broker.Group,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Connect to the master data cache, if one is configured
+	masterCache, err := cache.New(cache.Config{
+		Driver: cfg.Cache.Driver,
+		URL:    cfg.Cache.URL,
+		Prefix: cfg.Cache.Prefix,
+		TTL:    time.Duration(cfg.Cache.TTLSeconds) * time.Second,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep generated and uploaded files, signing the download URLs of local
+	// files with the JWT secret unless a secret of their own is configured
+	fileSecret := cfg.Files.URLSecret
+	if fileSecret == "" {
+		fileSecret = cfg.JWT.AccessSecret
+	}
+	fileStore, err := filestore.New(filestore.Config{
+		Driver:    cfg.Files.Driver,
+		Dir:       cfg.Files.Dir,
+		PublicURL: cfg.Files.PublicURL,
+		Secret:    fileSecret,
+		S3: filestore.S3Config{
+			Endpoint:  cfg.Files.S3Endpoint,
+			Region:    cfg.Files.S3Region,
+			Bucket:    cfg.Files.S3Bucket,
+			AccessKey: cfg.Files.S3AccessKey,
+			SecretKey: cfg.Files.S3SecretKey,
+			PathStyle: cfg.Files.S3PathStyle,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Hand WebSocket events to the API gateway, through the broker when there is one
+	publisher := realtime.NewPublisher(cfg.Realtime.GatewayURL, cfg.Realtime.Secret)
+	if messageBroker != nil {
+		publisher = realtime.NewBrokerPublisher(messageBroker, cfg.Broker.TopicPrefix+realtime.BrokerTopic)
+	}
+	// Keep the recent events for the Server-Sent Events clients
+	eventStream := realtime.NewStream(cfg.Realtime.StreamBuffer)
+
+	// Initialize the notification channel adapters
+	senders := notification.NewSenders(notification.Config{
+		SMTPHost:      cfg.Notify.SMTPHost,
+		SMTPPort:      cfg.Notify.SMTPPort,
+		SMTPUsername:  cfg.Notify.SMTPUsername,
+		SMTPPassword:  cfg.Notify.SMTPPassword,
+		EmailFrom:     cfg.Notify.EmailFrom,
+		EmailAPIURL:   cfg.Notify.EmailAPIURL,
+		EmailAPIToken: cfg.Notify.EmailAPIToken,
+		SMSWebhookURL: cfg.Notify.SMSWebhookURL,
+		SMSToken:      cfg.Notify.SMSToken,
+	})
+
+	// Initialize the domain event bus and its consumers. The event log runs
+	// first so that events are recorded before anything reacts to them.
+	bus := eventbus.New()
+	notificationUC := usecase.NewNotificationUseCase(notificationRepo, senders, publisher)
+	eventLogUC := usecase.NewEventLogUseCase(eventLogRepo)
+	eventLogUC.Subscribe(bus)
+	usecase.SubscribeExtensions(bus, hooks)
+	usecase.SubscribeStockLevels(bus, stocksRepo)
+	usecase.SubscribeRealtime(bus, publisher, eventStream)
+	delegationUC := usecase.NewApprovalDelegationUseCase(delegationRepo, userRepo)
+	usecase.SubscribeNotifications(bus, notificationUC, delegationUC)
+	usecase.SubscribeBroker(bus, messageBroker, cfg.Broker.TopicPrefix)
 
 	// Initialize use cases
+	currencyUC := usecase.NewCurrencyUseCase(currencyRepo, cfg.Finance.BaseCurrency, masterCache)
 	userUC := usecase.NewUserUseCase(userRepo)
 	roleUC := usecase.NewRoleUseCase(roleRepo)
 	storeUC := usecase.NewStoreUseCase(storeRepo)
-	stocksUC := usecase.NewStocksUseCase(stocksRepo, storeRepo)
-	vendorUC := usecase.NewVendorUseCase(vendorRepo)
-	manufacturingUC := usecase.NewManufacturingUseCase(manufacturingRepo, stocksRepo)
-	skuUC := usecase.NewSKUUseCase(skuRepo)
-	purchaseUC := usecase.NewPurchaseUseCase(purchaseRepo, stocksRepo, vendorRepo, skuRepo)
-	orderUC := usecase.NewOrderUseCase(orderRepo, stocksRepo)
+	fiscalUC := usecase.NewFiscalUseCase(fiscalRepo)
+	stocksUC := usecase.NewStocksUseCase(stocksRepo, storeRepo, skuRepo, fiscalUC, bus)
+	customFieldUC := usecase.NewCustomFieldUseCase(customFieldRepo)
+	vendorUC := usecase.NewVendorUseCase(vendorRepo, customFieldUC)
+	vendorRiskUC := usecase.NewVendorRiskUseCase(vendorRiskRepo, vendorUC, usecase.VendorRiskSettings{
+		LookbackMonths:    cfg.VendorRisk.LookbackMonths,
+		Threshold:         cfg.VendorRisk.Threshold,
+		ExposureLimit:     cfg.VendorRisk.ExposureLimit,
+		SingleSourceLimit: cfg.VendorRisk.SingleSourceLimit,
+	}, bus)
+	calendarUC := usecase.NewCalendarUseCase(calendarRepo)
+	qualityUC := usecase.NewQualityUseCase(qualityRepo)
+	conditionUC := usecase.NewConditionUseCase(conditionRepo, storeRepo, qualityUC, bus)
+	demandUC := usecase.NewDemandForecastUseCase(forecastRepo, demandRepo, calendarUC)
+	manufacturingUC := usecase.NewManufacturingUseCase(manufacturingRepo, stocksRepo, qualityUC, demandUC)
+	jobUC := usecase.NewJobUseCase(jobRepo, time.Duration(cfg.Jobs.TimeoutMinutes)*time.Minute, cfg.Jobs.MaxAttempts)
+	schedules, err := usecase.ParseSchedules(cfg.Scheduler.Cron)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scheduler config: %w", err)
+	}
+	schedulerUC, err := usecase.NewSchedulerUseCase(scheduledRunRepo, cfg.Scheduler.Enabled, schedules)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scheduler config: %w", err)
+	}
+	skuImageUC := usecase.NewSKUImageUseCase(skuImageRepo, skuRepo, fileStore, filestore.Limits{
+		MaxSize: int64(cfg.Files.MaxUploadMB) << 20,
+	}, cfg.Files.ThumbnailPx, time.Duration(cfg.Files.URLMinutes)*time.Minute, masterCache)
+	skuUC := usecase.NewSKUUseCase(skuRepo, searchRepo, jobUC, customFieldUC, skuImageUC, masterCache)
+	searchUC := usecase.NewSearchUseCase(searchRepo)
+	clientPrivacyUC := usecase.NewClientPrivacyUseCase(privacyRepo)
+	assetUC := usecase.NewAssetUseCase(assetRepo)
+	priceListUC := usecase.NewVendorPriceListUseCase(priceListRepo, vendorRepo, skuRepo)
+	purchaseUC := usecase.NewPurchaseUseCase(purchaseRepo, stocksRepo, vendorRepo, skuRepo, currencyUC, assetUC, qualityUC, calendarUC, priceListUC, hooks, bus, cfg.Purchasing.PriceVariancePercent)
+	orderUC := usecase.NewOrderUseCase(orderRepo, stocksRepo, skuRepo, currencyUC, calendarUC, fiscalUC, cfg.Calendar.PromiseDays, hooks, bus, customFieldUC)
+	dropShipUC := usecase.NewDropShipUseCase(orderRepo, purchaseRepo, orderUC, purchaseUC)
+	usecase.SubscribeDropShip(bus, dropShipUC)
+	salesChannelUC := usecase.NewSalesChannelUseCase(salesChannelRepo, storeRepo, skuRepo, clientRepo, orderUC)
+	crossDockUC := usecase.NewCrossDockUseCase(purchaseRepo, orderRepo, purchaseUC, orderUC, qualityUC)
+	consolidationUC := usecase.NewPurchaseConsolidationUseCase(purchaseRepo, vendorRepo, skuRepo, purchaseUC)
+	putawayUC := usecase.NewPutawayUseCase(putawayRepo, purchaseRepo, stocksRepo, storeRepo, skuRepo, cfg.Putaway.VelocityDays)
+	usecase.SubscribePutaway(bus, putawayUC)
+	inboundUC := usecase.NewInboundUseCase(inboundRepo, purchaseRepo, ediRepo, storeRepo, calendarUC)
+	returnsUC := usecase.NewReturnsUseCase(returnsRepo, orderRepo, stocksRepo, forecastRepo, qualityUC, cfg.Quality.ReturnRateThreshold)
 	clientUC := usecase.NewClientUseCase(clientRepo)
-	financeUC := usecase.NewFinanceUseCase(financeRepo)
-	reportUC := usecase.NewReportUseCase(reportRepo, stocksRepo, orderRepo, purchaseRepo, skuRepo)
+	withholdingRepo := repository.NewWithholdingRepository(db)
+	financeUC := usecase.NewFinanceUseCase(financeRepo, withholdingRepo, currencyUC, fiscalUC, bus)
+	consignmentUC := usecase.NewConsignmentUseCase(consignmentRepo, vendorRepo, storeRepo, financeUC, bus)
+	usecase.SubscribeConsignment(bus, consignmentUC)
+	ediUC := usecase.NewEDIUseCase(ediRepo, purchaseRepo, vendorRepo, skuRepo, purchaseUC, financeUC)
+	usecase.SubscribeEDI(bus, ediUC)
+	accountingUC := usecase.NewAccountingExportUseCase(accountingRepo, accounting.Accounts{
+		Receivable:              cfg.Accounting.Receivable,
+		Payable:                 cfg.Accounting.Payable,
+		Sales:                   cfg.Accounting.Sales,
+		Purchases:               cfg.Accounting.Purchases,
+		SalesTax:                cfg.Accounting.SalesTax,
+		PurchaseTax:             cfg.Accounting.PurchaseTax,
+		Discounts:               cfg.Accounting.Discounts,
+		Bank:                    cfg.Accounting.Bank,
+		WithholdingTax:          cfg.Accounting.WithholdingTax,
+		DepreciationExpense:     cfg.Accounting.DepreciationExpense,
+		AccumulatedDepreciation: cfg.Accounting.AccumulatedDepreciation,
+		ProvisionExpense:        cfg.Accounting.ProvisionExpense,
+		InventoryProvision:      cfg.Accounting.InventoryProvision,
+		XeroSalesTax:            cfg.Accounting.XeroSalesTax,
+		XeroPurchaseTax:         cfg.Accounting.XeroPurchaseTax,
+		XeroJournalTax:          cfg.Accounting.XeroJournalTax,
+	})
+	bankFeedUC := usecase.NewBankFeedUseCase(bankFeedRepo, financeUC, cfg.BankFeeds.LookbackDays)
+	recurringUC := usecase.NewRecurringInvoiceUseCase(recurringRepo, financeUC)
+	brandingUC := usecase.NewBrandingUseCase(brandingRepo)
+	dunningUC := usecase.NewDunningUseCase(dunningRepo, brandingUC, bus)
+	activityUC := usecase.NewUserActivityUseCase(activityRepo, userRepo, cfg.Security.InactiveAccountDays)
+	elevationUC := usecase.NewElevatedAccessUseCase(elevationRepo, bus, cfg.Security.MaxElevationHours, masterCache)
+	impersonationUC := usecase.NewImpersonationUseCase(impersonationRepo, userRepo, bus, cfg.Security.MaxImpersonationMinutes, masterCache)
+	permissionUC := usecase.NewPermissionUseCase(userRepo, roleRepo, elevationUC, delegationUC)
+	apiKeyUC := usecase.NewAPIKeyUseCase(apiKeyRepo)
+	fieldChangeUC := usecase.NewFieldChangeUseCase(fieldChangeRepo, stocksRepo)
+	numberingUC := usecase.NewNumberingUseCase(numberingRepo, repository.NewSequenceGenerator(db), storeRepo)
+	savedViewUC := usecase.NewSavedViewUseCase(savedViewRepo, roleRepo)
+	provisioningUC := usecase.NewUserProvisioningUseCase(provisioningRepo, roleRepo, cfg.Provision.DefaultRole, jobUC)
+	paymentHookUC := usecase.NewPaymentWebhookUseCase(paymentHookRepo, financeUC)
+	idempotencyUC := usecase.NewIdempotencyUseCase(idempotencyRepo, time.Duration(cfg.Server.IdempotencyKeyHours)*time.Hour)
+	documentEmailUC := usecase.NewDocumentEmailUseCase(purchaseRepo, orderRepo, reportRepo, skuRepo, userRepo, brandingUC, notificationUC, jobUC, fileStore, time.Duration(cfg.Files.URLMinutes)*time.Minute)
+	usecase.SubscribeDocumentEmails(bus, documentEmailUC, notificationUC)
+	marginUC := usecase.NewMarginUseCase(marginRepo, costServeRepo)
+	fulfillmentUC := usecase.NewFulfillmentUseCase(fulfillmentRepo, marginRepo, costServeRepo, usecase.FulfillmentSettings{
+		ShipDays:       cfg.Fulfill.SLAShipDays,
+		FillRateTarget: cfg.Fulfill.FillRateTarget,
+		LookbackDays:   cfg.Fulfill.LookbackDays,
+	}, bus)
+	reportUC := usecase.NewReportUseCase(reportRepo, stocksRepo, orderRepo, purchaseRepo, skuRepo, marginUC, calendarUC, fiscalUC, brandingUC, jobUC, documentEmailUC, fileStore, time.Duration(cfg.Files.URLMinutes)*time.Minute, time.Duration(cfg.Dashboard.MaxAgeMinutes)*time.Minute)
+	usecase.SubscribeDashboardMetrics(bus, reportUC)
+	expenseRepo := repository.NewExpenseRepository(db)
+	withholdingUC := usecase.NewWithholdingUseCase(withholdingRepo, vendorRepo, currencyUC)
+	prepaymentUC := usecase.NewPrepaymentUseCase(prepaymentRepo, orderRepo, purchaseRepo, currencyUC, fiscalUC)
+	expenseUC := usecase.NewExpenseUseCase(expenseRepo, financeUC, currencyUC, bus)
+	attachmentUC := usecase.NewAttachmentUseCase(attachmentRepo, purchaseRepo, expenseRepo, fileStore, filestore.Limits{
+		MaxSize:      int64(cfg.Files.MaxUploadMB) << 20,
+		ContentTypes: cfg.Files.AllowedTypes,
+	}, time.Duration(cfg.Files.URLMinutes)*time.Minute)
+	portalUC := usecase.NewPortalUseCase(orderRepo, clientRepo)
+	systemUC := usecase.NewSystemUseCase(systemRepo, sandboxRepo, cfg.Sandbox.Schema, masterCache)
+	archiveUC := usecase.NewArchiveUseCase(archiveRepo, cfg.Archive.RetentionDays, cfg.Archive.BatchSize)
+	provisionUC := usecase.NewProvisionUseCase(provisionRepo)
+	allocationUC := usecase.NewAllocationUseCase(allocationRepo)
+	rfmUC := usecase.NewRFMUseCase(rfmRepo, cfg.RFM.LookbackMonths)
+	skuClassUC := usecase.NewSKUClassUseCase(skuClassRepo, cfg.SKUClasses.LookbackMonths, map[entity.ABCClass]int{
+		entity.ABCClassA: cfg.SKUClasses.CountDaysA,
+		entity.ABCClassB: cfg.SKUClasses.CountDaysB,
+		entity.ABCClassC: cfg.SKUClasses.CountDaysC,
+	}, masterCache)
+	stockSnapshotUC := usecase.NewStockSnapshotUseCase(stockSnapshotRepo)
+	forecastUC := usecase.NewForecastUseCase(forecastRepo)
+	costServeUC := usecase.NewCostToServeUseCase(costServeRepo, forecastRepo, entity.CostToServeRates{
+		PickCost:    cfg.CostServe.PickCost,
+		LineCost:    cfg.CostServe.LineCost,
+		ReturnCost:  cfg.CostServe.ReturnCost,
+		CapitalRate: cfg.CostServe.CapitalRate,
+	})
+	customsUC := usecase.NewCustomsUseCase(customsRepo, skuRepo, currencyUC, cfg.Customs.Country, cfg.Customs.UnionCountries)
+
+	// Ingest the events external systems publish to the broker
+	ingestUC := usecase.NewEventIngestUseCase(messageBroker, cfg.Broker.TopicPrefix)
+	if cfg.Broker.IngestUserID != 0 {
+		ingestUC.RegisterSalesOrders(orderUC, cfg.Broker.IngestUserID)
+	}
+
+	// Create the archive tables before the sandbox copies the live schema
+	if err := archiveUC.EnsureTables(context.Background()); err != nil {
+		return nil, err
+	}
+
+	// Create the sandbox schema and any tables added since it was last provisioned
+	if cfg.Sandbox.Enabled {
+		if err := systemUC.ProvisionSandbox(context.Background()); err != nil {
+			return nil, err
+		}
+	}
 
 	// Initialize services
 	jwtService := auth.NewJWTService(cfg.JWT.AccessSecret, cfg.JWT.RefreshSecret)
-	auditService := service.NewAuditService(auditRepo)
+	auditService := service.NewAuditService(auditRepo, fileStore, cfg.Audit.RetentionDays, time.Duration(cfg.Files.URLMinutes)*time.Minute)
 
 	// Initialize server
 	server := &Server{
 		config:          cfg,
-		router:          gin.Default(),
+		router:          gin.New(),
 		userUC:          userUC,
 		roleUC:          roleUC,
 		storeUC:         storeUC,
@@ -90,13 +470,89 @@ func NewServer(cfg *config.Config) (*Server, error) {
 		manufacturingUC: manufacturingUC,
 		skuUC:           skuUC,
 		purchaseUC:      purchaseUC,
+		dropShipUC:      dropShipUC,
+		crossDockUC:     crossDockUC,
+		consolidationUC: consolidationUC,
 		orderUC:         orderUC,
 		clientUC:        clientUC, // Using interface instead of pointer
 		financeUC:       financeUC,
+		currencyUC:      currencyUC,
 		reportUC:        reportUC,
+		portalUC:        portalUC,
+		systemUC:        systemUC,
+		archiveUC:       archiveUC,
+		provisionUC:     provisionUC,
+		consignmentUC:   consignmentUC,
+		putawayUC:       putawayUC,
+		inboundUC:       inboundUC,
+		salesChannelUC:  salesChannelUC,
+		ediUC:           ediUC,
+		accountingUC:    accountingUC,
+		bankFeedUC:      bankFeedUC,
+		allocationUC:    allocationUC,
+		rfmUC:           rfmUC,
+		skuClassUC:      skuClassUC,
+		stockSnapshotUC: stockSnapshotUC,
+		assetUC:         assetUC,
+		forecastUC:      forecastUC,
+		demandUC:        demandUC,
+		returnsUC:       returnsUC,
+		costServeUC:     costServeUC,
+		marginUC:        marginUC,
+		fulfillmentUC:   fulfillmentUC,
+		customsUC:       customsUC,
+		recurringUC:     recurringUC,
+		vendorRiskUC:    vendorRiskUC,
+		priceListUC:     priceListUC,
+		dunningUC:       dunningUC,
+		activityUC:      activityUC,
+		elevationUC:     elevationUC,
+		impersonationUC: impersonationUC,
+		permissionUC:    permissionUC,
+		apiKeyUC:        apiKeyUC,
+		fieldChangeUC:   fieldChangeUC,
+		delegationUC:    delegationUC,
+		numberingUC:     numberingUC,
+		customFieldUC:   customFieldUC,
+		savedViewUC:     savedViewUC,
+		provisioningUC:  provisioningUC,
+		brandingUC:      brandingUC,
+		qualityUC:       qualityUC,
+		conditionUC:     conditionUC,
+		calendarUC:      calendarUC,
+		fiscalUC:        fiscalUC,
+		expenseUC:       expenseUC,
+		withholdingUC:   withholdingUC,
+		prepaymentUC:    prepaymentUC,
+		notificationUC:  notificationUC,
+		eventLogUC:      eventLogUC,
+		paymentHookUC:   paymentHookUC,
+		ingestUC:        ingestUC,
+		idempotencyUC:   idempotencyUC,
+		jobUC:           jobUC,
+		schedulerUC:     schedulerUC,
+		attachmentUC:    attachmentUC,
+		skuImageUC:      skuImageUC,
+		clientPrivacyUC: clientPrivacyUC,
+		searchUC:        searchUC,
+		fileStore:       fileStore,
+		eventStream:     eventStream,
+		paymentProvider: paymentProvider,
+		hooks:           hooks,
 		jwtService:      jwtService,
 		auditService:    auditService,
+		db:              db,
+		broker:          messageBroker,
+		cache:           masterCache,
+		stop:            make(chan struct{}),
+	}
+	server.ctx, server.cancel = context.WithCancel(context.Background())
+	server.httpServer = &http.Server{
+		Addr:    fmt.Sprintf(":%s", cfg.Server.Port),
+		Handler: server.router,
 	}
+	// End the event streams on shutdown, which would otherwise wait for them
+	server.httpServer.RegisterOnShutdown(eventStream.Close)
 
 	// Setup routes
 	server.setupRoutes()
@@ -105,9 +561,21 @@ func NewServer(cfg *config.Config) (*Server, error) {
 }
 
 func (s *Server) setupRoutes() {
+	// Trace every request, around everything else it does
+	s.router.Use(tracing.Middleware())
+
 	// Apply audit logging middleware globally
 	s.router.Use(service.CreateAuditLogMiddleware(s.auditService))
 
+	// Answer the errors handlers add with c.Error, inside the audit log so
+	// that it records their status
+	s.router.Use(middleware.ErrorMiddleware())
+
+	// Log every request, with a logger handlers reach through its context,
+	// including those whose handler panicked
+	s.router.Use(middleware.LoggingMiddleware())
+	s.router.Use(gin.Recovery())
+
 	// Health check
 	s.router.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{
@@ -126,11 +594,60 @@ func (s *Server) setupRoutes() {
 			auth.POST("/forgot-password", s.handleForgotPassword)
 			auth.POST("/reset-password", s.handleResetPassword)
 		}
+
+		// Payment gateway webhook, authenticated by its signature
+		if s.config.Payments.WebhookSecret != "" {
+			NewPaymentWebhookHandlers(s.paymentHookUC, s.paymentProvider).RegisterWebhookRoutes(public)
+		}
+
+		// Downloads of locally kept files, authenticated by the URL signature
+		if local, ok := s.fileStore.(*filestore.Local); ok {
+			NewFileHandlers(local, local.Signer()).RegisterRoutes(public)
+		}
+
+		// SCIM provisioning, authenticated by the provisioning token
+		if s.config.Provision.SCIMToken != "" {
+			NewSCIMHandlers(s.provisioningUC, s.config.Provision.SCIMToken).RegisterRoutes(public)
+		}
+	}
+
+	// Limit the requests of each user and API key, with quotas of their own
+	// for the heavier route groups
+	limits := s.config.RateLimits
+	rateLimit := middleware.RateLimitMiddleware(middleware.RateLimit{PerMinute: limits.PerMinute, Burst: limits.Burst})
+
+	// Gzip large responses and let pollers revalidate unchanged ones by ETag
+	compress := middleware.CompressionMiddleware(s.config.Server.CompressMinBytes)
+	etag := middleware.ETagMiddleware()
+
+	// Event streams, which stay open, so they are neither buffered nor
+	// bounded by the query timeout
+	events := s.router.Group("/api/v1")
+	events.Use(middleware.AuthMiddleware(s.jwtService, s.apiKeyUC, s.impersonationUC))
+	events.Use(rateLimit)
+	events.Use(middleware.ElevatedAccessMiddleware(s.elevationUC))
+	NewEventStreamHandlers(s.eventStream).RegisterRoutes(events)
+
+	// Middleware of the protected routes, shared by the /api purchase routes
+	protectedChain := []gin.HandlerFunc{
+		middleware.AuthMiddleware(s.jwtService, s.apiKeyUC, s.impersonationUC),
+		rateLimit,
+		compress, etag,
+		middleware.QueryTimeoutMiddleware(
+			time.Duration(s.config.Database.QueryTimeout)*time.Second,
+			time.Duration(s.config.Database.ReportQueryTimeout)*time.Second,
+			"/api/v1/reports",
+		),
+		middleware.ElevatedAccessMiddleware(s.elevationUC),
+		middleware.FiscalPeriodOverrideMiddleware(),
+		middleware.IdempotencyMiddleware(s.idempotencyUC),
+		middleware.SandboxMiddleware(s.config.Sandbox.Enabled),
+		middleware.SavedViewMiddleware(s.savedViewUC),
 	}
 
 	// Protected routes
 	protected := s.router.Group("/api/v1")
-	protected.Use(middleware.AuthMiddleware(s.jwtService))
+	protected.Use(protectedChain...)
 	{
 		// User routes
 		users := protected.Group("/users")
@@ -139,7 +656,7 @@ func (s *Server) setupRoutes() {
 			users.GET("/:id", middleware.PermissionMiddleware(entity.UserRead), s.handleGetUser)
 			users.PUT("/:id", middleware.PermissionMiddleware(entity.UserUpdate), s.handleUpdateUser)
 			users.DELETE("/:id", middleware.PermissionMiddleware(entity.UserDelete), s.handleDeleteUser)
-			users.POST("/logout", s.handleLogout)
+			users.POST("/logout", middleware.DenyAPIKeyMiddleware(), middleware.DenyImpersonationMiddleware(), s.handleLogout)
 		}
 
 		// Role routes
@@ -156,8 +673,40 @@ func (s *Server) setupRoutes() {
 		audit := protected.Group("/audit")
 		{
 			audit.GET("/logs", middleware.PermissionMiddleware(entity.AuditLogRead), s.handleListAuditLogs)
+			audit.GET("/logs/export", middleware.PermissionMiddleware(entity.AuditLogExport), s.handleExportAuditLogs)
 			audit.GET("/logs/user/:id", middleware.PermissionMiddleware(entity.AuditLogRead), s.handleUserAuditLogs)
+			audit.GET("/logs/verify", middleware.PermissionMiddleware(entity.AuditLogVerify), s.handleVerifyAuditLogs)
+			audit.POST("/logs/retention/run", middleware.PermissionMiddleware(entity.AuditLogRetain), s.handleRunAuditRetention)
+			audit.GET("/logs/archives", middleware.PermissionMiddleware(entity.AuditLogExport), s.handleListAuditArchives)
 		}
+		NewEventLogHandlers(s.eventLogUC).RegisterRoutes(protected)
+		NewUserActivityHandlers(s.activityUC).RegisterRoutes(protected)
+		NewElevatedAccessHandlers(s.elevationUC).RegisterRoutes(protected)
+		NewImpersonationHandlers(s.impersonationUC, s.jwtService).RegisterRoutes(protected)
+		NewPermissionHandlers(s.permissionUC).RegisterRoutes(protected)
+		NewAPIKeyHandlers(s.apiKeyUC).RegisterRoutes(protected)
+		NewFieldChangeHandlers(s.fieldChangeUC).RegisterRoutes(protected)
+		NewApprovalDelegationHandlers(s.delegationUC).RegisterRoutes(protected)
+		NewNumberingHandlers(s.numberingUC).RegisterRoutes(protected)
+		NewCustomFieldHandlers(s.customFieldUC).RegisterRoutes(protected)
+		NewSavedViewHandlers(s.savedViewUC).RegisterRoutes(protected)
+		NewUserProvisioningHandlers(s.provisioningUC).RegisterRoutes(protected)
+		NewBrandingHandlers(s.brandingUC).RegisterRoutes(protected)
+		NewCalendarHandlers(s.calendarUC).RegisterRoutes(protected)
+		NewFiscalHandlers(s.fiscalUC).RegisterRoutes(protected)
+		NewExpenseHandlers(s.expenseUC, s.delegationUC).RegisterRoutes(protected)
+		NewWithholdingHandlers(s.withholdingUC).RegisterRoutes(protected)
+		NewPrepaymentHandlers(s.prepaymentUC).RegisterRoutes(protected)
+		NewNotificationHandlers(s.notificationUC).RegisterRoutes(protected)
+		NewJobHandlers(s.jobUC).RegisterRoutes(protected)
+		NewScheduleHandlers(s.schedulerUC).RegisterRoutes(protected)
+		NewAttachmentHandlers(s.attachmentUC).RegisterRoutes(protected)
+		NewSKUImageHandlers(s.skuImageUC).RegisterRoutes(protected)
+		NewDropShipHandlers(s.dropShipUC).RegisterRoutes(protected)
+		NewSearchHandlers(s.searchUC).RegisterRoutes(protected.Group("", middleware.RateLimitMiddleware(middleware.RateLimit{
+			PerMinute: limits.SearchPerMinute,
+			Burst:     limits.SearchBurst,
+		})))
 
 		// Initialize handlers
 		storeHandler := NewStoreHandler(s.storeUC, s.stocksUC)
@@ -165,7 +714,7 @@ func (s *Server) setupRoutes() {
 		vendorHandler := NewVendorHandler(s.vendorUC)
 		manufacturingHandler := NewManufacturingHandler(s.manufacturingUC)
 		skuHandler := NewSKUHandler(s.skuUC)
-		purchaseHandler := NewPurchaseHandler(s.purchaseUC)
+		purchaseHandler := NewPurchaseHandler(s.purchaseUC, s.delegationUC)
 
 		// Store routes
 		stores := protected.Group("/stores")
@@ -182,17 +731,30 @@ func (s *Server) setupRoutes() {
 		{
 			stocks.GET("", middleware.PermissionMiddleware(entity.StockRead), stocksHandler.ListStocks)
 			stocks.GET("/check-stock", middleware.PermissionMiddleware(entity.StockRead), stocksHandler.CheckStock)
+			stocks.GET("/atp", middleware.PermissionMiddleware(entity.StockRead), stocksHandler.AvailableToPromise)
+			stocks.GET("/stock-entries", middleware.PermissionMiddleware(entity.StockEntryRead), stocksHandler.ListStockEntries)
 			stocks.POST("/stock-entries", middleware.PermissionMiddleware(entity.StockEntryCreate), stocksHandler.ProcessStockEntry)
 			stocks.POST("/batch-stock-entries", middleware.PermissionMiddleware(entity.StockEntryCreate), stocksHandler.BatchStockEntry)
 			stocks.PUT("/:id/location", middleware.PermissionMiddleware(entity.StockUpdate), stocksHandler.UpdateStockLocation)
 			stocks.GET("/:id/history", middleware.PermissionMiddleware(entity.StockEntryRead), stocksHandler.GetStockHistory)
+			stocks.GET("/:id/:warehouse/ledger", middleware.PermissionMiddleware(entity.StockEntryRead), stocksHandler.GetStockLedger)
+			stocks.GET("/:id/:warehouse/ledger/export", middleware.PermissionMiddleware(entity.StockEntryRead), stocksHandler.ExportStockLedger)
 		}
 
+		provisionHandler := NewProvisionHandlers(s.provisionUC)
+		provisionHandler.RegisterRoutes(protected)
+		NewConsignmentHandlers(s.consignmentUC).RegisterRoutes(protected)
+		NewPutawayHandlers(s.putawayUC).RegisterRoutes(protected)
+		NewInboundHandlers(s.inboundUC).RegisterRoutes(protected)
+		NewSKUClassHandlers(s.skuClassUC).RegisterRoutes(protected)
+		NewStockSnapshotHandlers(s.stockSnapshotUC).RegisterRoutes(protected)
+
 		// Vendor routes
 		vendors := protected.Group("/vendors")
 		{
 			vendors.POST("", middleware.PermissionMiddleware(entity.VendorCreate), vendorHandler.CreateVendor)
 			vendors.GET("", middleware.PermissionMiddleware(entity.VendorRead), vendorHandler.ListVendors)
+			vendors.GET("/export", middleware.PermissionMiddleware(entity.VendorRead), vendorHandler.ExportVendors)
 			vendors.GET("/:id", middleware.PermissionMiddleware(entity.VendorRead), vendorHandler.GetVendor)
 			vendors.PUT("/:id", middleware.PermissionMiddleware(entity.VendorUpdate), vendorHandler.UpdateVendor)
 			vendors.DELETE("/:id", middleware.PermissionMiddleware(entity.VendorDelete), vendorHandler.DeleteVendor)
@@ -210,8 +772,16 @@ func (s *Server) setupRoutes() {
 			// Rating management
 			vendors.POST("/:id/ratings", middleware.PermissionMiddleware(entity.RatingCreate), vendorHandler.AddRating)
 			vendors.GET("/:id/ratings", middleware.PermissionMiddleware(entity.RatingRead), vendorHandler.GetRatings)
+
+			// Scorecards
+			vendors.GET("/:id/scorecard", middleware.PermissionMiddleware(entity.VendorRead), vendorHandler.GetScorecard)
+			vendors.GET("/scorecards/ranking", middleware.PermissionMiddleware(entity.VendorRead), vendorHandler.RankVendors)
 		}
 
+		vendorRiskHandler := NewVendorRiskHandlers(s.vendorRiskUC)
+		vendorRiskHandler.RegisterRoutes(protected)
+		NewVendorPriceListHandlers(s.priceListUC).RegisterRoutes(protected)
+
 		// Manufacturing routes
 		manufacturing := protected.Group("/manufacturing")
 		{
@@ -220,25 +790,50 @@ func (s *Server) setupRoutes() {
 			manufacturing.GET("/facilities", middleware.PermissionMiddleware(entity.ManufacturingFacilityRead), manufacturingHandler.ListFacilities)
 			manufacturing.GET("/facilities/:id", middleware.PermissionMiddleware(entity.ManufacturingFacilityRead), manufacturingHandler.GetFacility)
 
+			// Work center and scheduling routes
+			manufacturing.POST("/work-centers", middleware.PermissionMiddleware(entity.ManufacturingFacilityCreate), manufacturingHandler.CreateWorkCenter)
+			manufacturing.GET("/work-centers", middleware.PermissionMiddleware(entity.ManufacturingFacilityRead), manufacturingHandler.ListWorkCenters)
+			manufacturing.GET("/work-centers/:id", middleware.PermissionMiddleware(entity.ManufacturingFacilityRead), manufacturingHandler.GetWorkCenter)
+			manufacturing.PUT("/work-centers/:id", middleware.PermissionMiddleware(entity.ManufacturingFacilityUpdate), manufacturingHandler.UpdateWorkCenter)
+			manufacturing.GET("/schedule", middleware.PermissionMiddleware(entity.ProductionOrderRead), manufacturingHandler.GetSchedule)
+			manufacturing.PUT("/orders/:id/schedule", middleware.PermissionMiddleware(entity.ProductionOrderUpdate), manufacturingHandler.ScheduleOrder)
+			manufacturing.DELETE("/orders/:id/schedule", middleware.PermissionMiddleware(entity.ProductionOrderUpdate), manufacturingHandler.UnscheduleOrder)
+
 			// Production routes
 			manufacturing.POST("/orders", middleware.PermissionMiddleware(entity.ProductionOrderCreate), manufacturingHandler.CreateProductionOrder)
 			manufacturing.POST("/orders/:id/start", middleware.PermissionMiddleware(entity.ProductionOrderUpdate), manufacturingHandler.StartProduction)
 			manufacturing.PUT("/orders/:id/progress", middleware.PermissionMiddleware(entity.ProductionOrderUpdate), manufacturingHandler.UpdateProductionProgress)
+			manufacturing.POST("/orders/:id/issue", middleware.PermissionMiddleware(entity.ProductionOrderUpdate), manufacturingHandler.IssueMaterials)
+			manufacturing.GET("/orders/:id/issues", middleware.PermissionMiddleware(entity.ProductionOrderRead), manufacturingHandler.ListMaterialIssues)
+			manufacturing.GET("/orders/:id/variance", middleware.PermissionMiddleware(entity.ProductionOrderRead), manufacturingHandler.GetMaterialVariance)
 
 			// BOM routes
 			manufacturing.POST("/bom", middleware.PermissionMiddleware(entity.BOMCreate), manufacturingHandler.CreateBOM)
+			manufacturing.GET("/bom", middleware.PermissionMiddleware(entity.BOMRead), manufacturingHandler.ListBOMs)
+			manufacturing.GET("/bom/:id", middleware.PermissionMiddleware(entity.BOMRead), manufacturingHandler.GetBOM)
+			manufacturing.GET("/products/:id/cost", middleware.PermissionMiddleware(entity.BOMRead), manufacturingHandler.GetProductCost)
+			manufacturing.GET("/materials/costs", middleware.PermissionMiddleware(entity.BOMRead), manufacturingHandler.ListMaterialCosts)
+			manufacturing.PUT("/materials/:id/cost", middleware.PermissionMiddleware(entity.BOMUpdate), manufacturingHandler.SetMaterialCost)
 		}
 
+		NewQualityHandlers(s.qualityUC).RegisterRoutes(protected)
+		NewConditionHandlers(s.conditionUC).RegisterRoutes(protected)
+
 		// SKU routes
 		skus := protected.Group("/skus")
 		{
 			skus.POST("", middleware.PermissionMiddleware(entity.ProductCreate), skuHandler.CreateSKU)
 			skus.GET("", middleware.PermissionMiddleware(entity.ProductRead), skuHandler.ListSKUs)
 			skus.GET("/search", middleware.PermissionMiddleware(entity.ProductRead), skuHandler.SearchSKUs)
+			skus.GET("/export", middleware.PermissionMiddleware(entity.ProductRead), skuHandler.ExportSKUs)
 			skus.GET("/:id", middleware.PermissionMiddleware(entity.ProductRead), skuHandler.GetSKU)
 			skus.GET("/code/:code", middleware.PermissionMiddleware(entity.ProductRead), skuHandler.GetSKUByCode)
 			skus.PUT("/:id", middleware.PermissionMiddleware(entity.ProductUpdate), skuHandler.UpdateSKU)
 			skus.DELETE("/:id", middleware.PermissionMiddleware(entity.ProductDelete), skuHandler.DeleteSKU)
+			skus.GET("/:id/variants", middleware.PermissionMiddleware(entity.ProductRead), skuHandler.GetVariants)
+			skus.POST("/:id/variants", middleware.PermissionMiddleware(entity.ProductCreate), skuHandler.GenerateVariants)
+			skus.GET("/:id/components", middleware.PermissionMiddleware(entity.ProductRead), skuHandler.GetKitComponents)
+			skus.PUT("/:id/components", middleware.PermissionMiddleware(entity.ProductUpdate), skuHandler.SetKitComponents)
 			skus.POST("/bulk", middleware.PermissionMiddleware(entity.ProductCreate), skuHandler.BulkCreateSKUs)
 			skus.PUT("/bulk", middleware.PermissionMiddleware(entity.ProductUpdate), skuHandler.BulkUpdateSKUs)
 		}
@@ -255,19 +850,33 @@ func (s *Server) setupRoutes() {
 			skuCategories.GET("/:id/skus", middleware.PermissionMiddleware(entity.ProductRead), skuHandler.GetSKUsByCategory)
 		}
 
-		// Purchase routes
-		purchaseHandler.RegisterRoutes(s.router)
+		// Purchase routes, served under /api with the middleware of the
+		// protected routes
+		purchaseRouter := s.router.Group("/api", protectedChain...)
+		purchaseHandler.RegisterRoutes(purchaseRouter)
+		NewCrossDockHandlers(s.crossDockUC).RegisterRoutes(purchaseRouter)
+		NewPurchaseConsolidationHandlers(s.consolidationUC).RegisterRoutes(purchaseRouter)
+		NewEDIHandlers(s.ediUC).RegisterRoutes(purchaseRouter)
+
+		NewSalesChannelHandlers(s.salesChannelUC).RegisterRoutes(protected)
 
 		// Order routes
 		orderHandler := NewOrderHandlers(s.orderUC)
+		returnsHandler := NewReturnsHandlers(s.returnsUC)
 		orders := protected.Group("/orders")
 		{
 			orders.POST("", middleware.PermissionMiddleware(entity.SalesOrderCreate), orderHandler.CreateSalesOrder)
 			orders.GET("", middleware.PermissionMiddleware(entity.SalesOrderRead), orderHandler.ListSalesOrders)
+			orders.GET("/export", middleware.PermissionMiddleware(entity.SalesOrderRead), orderHandler.ExportSalesOrders)
 			orders.GET("/:id", middleware.PermissionMiddleware(entity.SalesOrderRead), orderHandler.GetSalesOrder)
 			orders.POST("/:id/confirm", middleware.PermissionMiddleware(entity.SalesOrderConfirm), orderHandler.ConfirmSalesOrder)
 			orders.POST("/:id/cancel", middleware.PermissionMiddleware(entity.SalesOrderCancel), orderHandler.CancelSalesOrder)
 			orders.POST("/:id/complete", middleware.PermissionMiddleware(entity.SalesOrderUpdate), orderHandler.CompleteSalesOrder)
+			orders.PUT("/:id/custom-fields", middleware.PermissionMiddleware(entity.SalesOrderUpdate), orderHandler.UpdateSalesOrderCustomFields)
+
+			// Return routes
+			orders.POST("/:id/returns", middleware.PermissionMiddleware(entity.SalesOrderUpdate), returnsHandler.RecordReturn)
+			orders.GET("/:id/returns", middleware.PermissionMiddleware(entity.SalesOrderRead), returnsHandler.ListOrderReturns)
 
 			// Delivery routes
 			orders.POST("/:id/deliveries", middleware.PermissionMiddleware(entity.DeliveryOrderCreate), orderHandler.CreateDeliveryOrder)
@@ -285,20 +894,164 @@ func (s *Server) setupRoutes() {
 			orders.POST("/invoices/:id/pay", middleware.PermissionMiddleware(entity.InvoicePay), orderHandler.PayInvoice)
 		}
 
+		allocationHandler := NewAllocationHandlers(s.allocationUC)
+		allocationHandler.RegisterRoutes(protected)
+
+		fulfillmentHandler := NewFulfillmentHandlers(s.fulfillmentUC)
+		fulfillmentHandler.RegisterRoutes(protected)
+
 		// Client routes
 		clientHandler := NewClientHandler(s.clientUC)
 		clientHandler.RegisterRoutes(protected)
+		NewClientPrivacyHandlers(s.clientPrivacyUC).RegisterRoutes(protected)
+
+		rfmHandler := NewRFMHandlers(s.rfmUC)
+		rfmHandler.RegisterRoutes(protected)
 
 		// Finance routes
 		financeHandler := NewFinanceHandlers(s.financeUC)
 		financeHandler.RegisterRoutes(protected)
 
+		recurringHandler := NewRecurringInvoiceHandlers(s.recurringUC)
+		recurringHandler.RegisterRoutes(protected)
+
+		dunningHandler := NewDunningHandlers(s.dunningUC)
+		dunningHandler.RegisterRoutes(protected)
+
+		paymentHookHandler := NewPaymentWebhookHandlers(s.paymentHookUC, s.paymentProvider)
+		paymentHookHandler.RegisterRoutes(protected)
+
+		assetHandler := NewAssetHandlers(s.assetUC)
+		assetHandler.RegisterRoutes(protected)
+
+		currencyHandler := NewCurrencyHandlers(s.currencyUC)
+		currencyHandler.RegisterRoutes(protected)
+
+		accountingHandler := NewAccountingExportHandlers(s.accountingUC)
+		accountingHandler.RegisterRoutes(protected)
+
+		bankFeedHandler := NewBankFeedHandlers(s.bankFeedUC)
+		bankFeedHandler.RegisterRoutes(protected)
+
 		// Report routes
+		// Reports have a request quota of their own and share a bounded number
+		// of concurrent slots so long queries cannot exhaust connections needed
+		// by transactional traffic, and read from the replica when one is configured
+		reportRouter := protected.Group("",
+			middleware.RateLimitMiddleware(middleware.RateLimit{PerMinute: limits.ReportsPerMinute, Burst: limits.ReportsBurst}),
+			middleware.ConcurrencyLimitMiddleware(s.config.Database.ReportMaxConcurrency),
+			middleware.ReplicaMiddleware(),
+		)
 		reportHandler := NewReportHandlers(s.reportUC)
-		reportHandler.RegisterRoutes(protected)
+		reportHandler.RegisterRoutes(reportRouter)
+
+		forecastHandler := NewForecastHandlers(s.forecastUC)
+		forecastHandler.RegisterRoutes(reportRouter)
+
+		demandForecastHandler := NewDemandForecastHandlers(s.demandUC)
+		demandForecastHandler.RegisterRoutes(reportRouter)
+
+		returnsHandler.RegisterRoutes(reportRouter)
+
+		costToServeHandler := NewCostToServeHandlers(s.costServeUC)
+		costToServeHandler.RegisterRoutes(reportRouter)
+
+		marginHandler := NewMarginHandlers(s.marginUC)
+		marginHandler.RegisterRoutes(reportRouter)
+
+		fulfillmentHandler.RegisterReportRoutes(reportRouter)
+
+		customsHandler := NewCustomsHandlers(s.customsUC)
+		customsHandler.RegisterRoutes(reportRouter)
+
+		// System diagnostics routes
+		systemHandler := NewSystemHandlers(s.systemUC)
+		systemHandler.RegisterRoutes(protected)
+
+		archiveHandler := NewArchiveHandlers(s.archiveUC)
+		archiveHandler.RegisterRoutes(protected)
+
+		// Form schema routes for generated user interfaces
+		formHandler := NewFormHandlers(s.customFieldUC)
+		formHandler.RegisterRoutes(protected)
+
+		// Extension routes
+		s.hooks.RegisterRoutes(protected)
 	}
+
+	// Customer portal routes, authenticated with client-scoped portal tokens
+	portalHandler := NewPortalHandlers(s.portalUC, s.jwtService)
+	protected.POST("/clients/:id/portal-token", middleware.PermissionMiddleware(entity.ClientPortalTokenIssue), portalHandler.IssuePortalToken)
+
+	portal := s.router.Group("/api/v1/portal")
+	portal.Use(middleware.PortalAuthMiddleware(s.jwtService))
+	portal.Use(middleware.QueryTimeoutMiddleware(
+		time.Duration(s.config.Database.QueryTimeout)*time.Second,
+		time.Duration(s.config.Database.QueryTimeout)*time.Second,
+	))
+	portalHandler.RegisterRoutes(portal)
 }
 
+// Run starts the background jobs and serves requests until Shutdown
 func (s *Server) Run() error {
-	return s.router.Run(fmt.Sprintf(":%s", s.config.Server.Port))
+	s.scheduleTasks()
+	s.schedulerUC.Start(s.ctx)
+	s.startReplicaCheckJob()
+	s.jobUC.Start(s.config.Jobs.Workers)
+	if err := s.ingestUC.Start(); err != nil {
+		return err
+	}
+	if err := s.httpServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Shutdown stops the server gracefully. It stops accepting connections and
+// waits for the requests in flight, then stops the scheduler, background jobs
+// and job workers, waiting for the runs in progress, and finally closes the broker
+// and database connections and exports the last spans. Runs still in progress
+// when ctx is done are canceled.
+func (s *Server) Shutdown(ctx context.Context) error {
+	var errs []error
+	if err := s.httpServer.Shutdown(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("error draining requests: %w", err))
+	}
+
+	close(s.stop)
+	if err := s.schedulerUC.Stop(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("error waiting for running scheduled tasks: %w", err))
+	}
+	if err := s.jobUC.Stop(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("error waiting for running jobs: %w", err))
+	}
+	done := make(chan struct{})
+	go func() {
+		s.background.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		errs = append(errs, fmt.Errorf("error waiting for background jobs: %w", ctx.Err()))
+	}
+	s.cancel()
+
+	if s.broker != nil {
+		if err := s.broker.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("error closing broker connection: %w", err))
+		}
+	}
+	if err := s.cache.Close(); err != nil {
+		errs = append(errs, fmt.Errorf("error closing cache connections: %w", err))
+	}
+	if sqlDB, err := s.db.DB(); err == nil {
+		if err := sqlDB.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("error closing database: %w", err))
+		}
+	}
+	if err := tracing.Shutdown(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("error exporting the last spans: %w", err))
+	}
+	return errors.Join(errs...)
 }
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/server/middleware"
+)
+
+// SKUClassHandlers handles HTTP requests for the ABC/XYZ classification of
+// SKUs and the cycle counts it schedules
+type SKUClassHandlers struct {
+	classUseCase *usecase.SKUClassUseCase
+}
+
+// NewSKUClassHandlers creates a new SKU class handlers instance
+func NewSKUClassHandlers(classUseCase *usecase.SKUClassUseCase) *SKUClassHandlers {
+	return &SKUClassHandlers{
+		classUseCase: classUseCase,
+	}
+}
+
+// RegisterRoutes registers SKU classification and cycle count routes
+func (h *SKUClassHandlers) RegisterRoutes(router *gin.RouterGroup) {
+	router.POST("/skus/classes/run", middleware.PermissionMiddleware(entity.StockClassRun), h.RunClassification)
+	router.GET("/stocks/cycle-counts", middleware.PermissionMiddleware(entity.StockRead), h.ListCycleCounts)
+}
+
+// RunClassification handles classifying SKUs on demand
+// @Summary Run ABC/XYZ classification
+// @Description Classify every SKU not archived by the value of the stock it issued over the lookback window at its price (ABC) and by the variability of its monthly issues (XYZ), and store the classes on the SKUs
+// @Tags skus
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} entity.SKUClassRunResult
+// @Failure 500 {object} ErrorResponse
+// @Router /skus/classes/run [post]
+func (h *SKUClassHandlers) RunClassification(c *gin.Context) {
+	result, err := h.classUseCase.Run(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// ListCycleCounts handles listing the stocks due for a cycle count
+// @Summary List cycle counts due
+// @Description List the stocks on hand due for a count, most overdue first. A stock is counted every interval of its SKU's ABC class after its last adjustment, or after it was first stocked.
+// @Tags stocks
+// @Security BearerAuth
+// @Produce json
+// @Param store_id query string false "Store ID"
+// @Param abc_class query string false "ABC class (A, B or C)"
+// @Param date query string false "Stocks due on or before (YYYY-MM-DD), defaults to today"
+// @Success 200 {array} entity.CycleCountLine
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /stocks/cycle-counts [get]
+func (h *SKUClassHandlers) ListCycleCounts(c *gin.Context) {
+	filter := &entity.CycleCountFilter{
+		StoreID:  c.Query("store_id"),
+		ABCClass: entity.ABCClass(c.Query("abc_class")),
+	}
+	if dateStr := c.Query("date"); dateStr != "" {
+		date, err := time.Parse("2006-01-02", dateStr)
+		if err != nil {
+			c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid date"))
+			return
+		}
+		filter.Date = &date
+	}
+
+	lines, err := h.classUseCase.CycleCounts(c.Request.Context(), filter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, lines)
+}
@@ -3,6 +3,7 @@ package server
 import (
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
@@ -34,21 +35,12 @@ func NewSKUHandler(skuUseCase *usecase.SKUUseCase) *SKUHandler {
 func (h *SKUHandler) CreateSKU(c *gin.Context) {
 	var sku entity.SKU
 	if err := c.ShouldBindJSON(&sku); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 
 	if err := h.skuUseCase.CreateSKU(c.Request.Context(), &sku); err != nil {
-		statusCode := http.StatusInternalServerError
-		switch err {
-		case usecase.ErrInvalidSKUCode:
-			statusCode = http.StatusBadRequest
-		case usecase.ErrDuplicateSKUCode:
-			statusCode = http.StatusConflict
-		case usecase.ErrInvalidPriceRange:
-			statusCode = http.StatusBadRequest
-		}
-		c.JSON(statusCode, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -73,24 +65,13 @@ func (h *SKUHandler) UpdateSKU(c *gin.Context) {
 	id := c.Param("id")
 	var sku entity.SKU
 	if err := c.ShouldBindJSON(&sku); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 	sku.ID = id
 
 	if err := h.skuUseCase.UpdateSKU(c.Request.Context(), &sku); err != nil {
-		statusCode := http.StatusInternalServerError
-		switch err {
-		case usecase.ErrInvalidSKUCode:
-			statusCode = http.StatusBadRequest
-		case usecase.ErrDuplicateSKUCode:
-			statusCode = http.StatusConflict
-		case usecase.ErrSKUNotFound:
-			statusCode = http.StatusNotFound
-		case usecase.ErrInvalidPriceRange:
-			statusCode = http.StatusBadRequest
-		}
-		c.JSON(statusCode, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -112,11 +93,7 @@ func (h *SKUHandler) GetSKU(c *gin.Context) {
 
 	sku, err := h.skuUseCase.GetSKU(c.Request.Context(), id)
 	if err != nil {
-		statusCode := http.StatusInternalServerError
-		if err == usecase.ErrSKUNotFound {
-			statusCode = http.StatusNotFound
-		}
-		c.JSON(statusCode, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -138,11 +115,7 @@ func (h *SKUHandler) GetSKUByCode(c *gin.Context) {
 
 	sku, err := h.skuUseCase.GetSKUBySKUCode(c.Request.Context(), code)
 	if err != nil {
-		statusCode := http.StatusInternalServerError
-		if err == usecase.ErrSKUNotFound {
-			statusCode = http.StatusNotFound
-		}
-		c.JSON(statusCode, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -163,11 +136,7 @@ func (h *SKUHandler) DeleteSKU(c *gin.Context) {
 	id := c.Param("id")
 
 	if err := h.skuUseCase.DeleteSKU(c.Request.Context(), id); err != nil {
-		statusCode := http.StatusInternalServerError
-		if err == usecase.ErrSKUNotFound {
-			statusCode = http.StatusNotFound
-		}
-		c.JSON(statusCode, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -189,18 +158,107 @@ func (h *SKUHandler) DeleteSKU(c *gin.Context) {
 // @Param manufacturer_id query int false "Manufacturer ID"
 // @Param min_price query number false "Minimum price"
 // @Param max_price query number false "Maximum price"
+// @Param cf.key query string false "Value of the custom field with this key, e.g. cf.color=red"
+// @Param parent_id query string false "Variants of this parent item"
+// @Param no_variants query bool false "Only parent items and SKUs without variants"
+// @Param variant.dimension query string false "Variants with this value of a dimension, e.g. variant.size=M"
+// @Param abc_class query string false "ABC class (A, B or C)"
+// @Param xyz_class query string false "XYZ class (X, Y or Z)"
+// @Param fields query string false "Only return these fields of each SKU, e.g. id,sku_code,name"
 // @Success 200 {object} PaginatedResponse
-// @Failure 400 {object} ErrorResponse "Invalid price range"
+// @Failure 400 {object} ErrorResponse "Invalid price range, class or field"
 // @Failure 500 {object} ErrorResponse "Server error"
 // @Router /skus [get]
 func (h *SKUHandler) ListSKUs(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	fields, err := parseFieldSet[entity.SKU](c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	skus, total, err := h.skuUseCase.ListSKUs(c.Request.Context(), skuFilter(c), page, pageSize)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, PaginatedResponse{
+		Data:      fields.pick(skus),
+		Total:     total,
+		Page:      page,
+		PageSize:  pageSize,
+		TotalPage: (total + int64(pageSize) - 1) / int64(pageSize),
+	})
+}
 
+// @Summary Export SKUs
+// @Description Download the SKUs matching the filters of the SKU list, with a column per custom field defined on SKUs. At most 10000 SKUs are exported.
+// @Tags skus
+// @Security BearerAuth
+// @Produce text/csv
+// @Param format query string false "File format: csv (default), xlsx, pdf or json"
+// @Param sku_code query string false "SKU code"
+// @Param name query string false "SKU name"
+// @Param category query string false "Category"
+// @Param status query string false "Status"
+// @Param vendor_id query int false "Vendor ID"
+// @Param manufacturer_id query int false "Manufacturer ID"
+// @Param min_price query number false "Minimum price"
+// @Param max_price query number false "Maximum price"
+// @Param cf.key query string false "Value of the custom field with this key, e.g. cf.color=red"
+// @Param parent_id query string false "Variants of this parent item"
+// @Param no_variants query bool false "Only parent items and SKUs without variants"
+// @Param variant.dimension query string false "Variants with this value of a dimension, e.g. variant.size=M"
+// @Param abc_class query string false "ABC class (A, B or C)"
+// @Param xyz_class query string false "XYZ class (X, Y or Z)"
+// @Success 200 {file} file
+// @Failure 400 {object} ErrorResponse "Invalid filter or format"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /skus/export [get]
+func (h *SKUHandler) ExportSKUs(c *gin.Context) {
+	format, ok := parseExportFormat(c)
+	if !ok {
+		return
+	}
+
+	columns, rows, err := h.skuUseCase.ExportSKUs(c.Request.Context(), skuFilter(c))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	writeExport(c, format, "skus", "SKUs", columns, rows)
+}
+
+// variantQueryPrefix prefixes the query parameters SKU lists are filtered by
+// variant options with, as in ?variant.size=M
+const variantQueryPrefix = "variant."
+
+// skuFilter reads the SKU list filters from the query string
+func skuFilter(c *gin.Context) *entity.SKUFilter {
 	filter := &entity.SKUFilter{
-		SKUCode:  c.Query("sku_code"),
-		Name:     c.Query("name"),
-		Category: c.Query("category"),
+		SKUCode:      c.Query("sku_code"),
+		Name:         c.Query("name"),
+		Category:     c.Query("category"),
+		CustomFields: customFieldQuery(c),
+		ParentID:     c.Query("parent_id"),
+		ABCClass:     entity.ABCClass(c.Query("abc_class")),
+		XYZClass:     entity.XYZClass(c.Query("xyz_class")),
+	}
+
+	if noVariants, err := strconv.ParseBool(c.Query("no_variants")); err == nil {
+		filter.NoVariants = noVariants
+	}
+
+	for param, value := range c.Request.URL.Query() {
+		if dimension, ok := strings.CutPrefix(param, variantQueryPrefix); ok && dimension != "" {
+			if filter.VariantOptions == nil {
+				filter.VariantOptions = make(entity.JSONMap)
+			}
+			filter.VariantOptions[dimension] = value[0]
+		}
 	}
 
 	if status := c.Query("status"); status != "" {
@@ -234,27 +292,109 @@ func (h *SKUHandler) ListSKUs(c *gin.Context) {
 		}
 	}
 
-	skus, total, err := h.skuUseCase.ListSKUs(c.Request.Context(), filter, page, pageSize)
+	return filter
+}
+
+// @Summary Get SKU variants
+// @Description Get a parent item with its variant dimensions and variants, each holding its value of every dimension in variant_options
+// @Tags skus
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Parent item ID"
+// @Success 200 {object} entity.SKUVariantMatrix
+// @Failure 404 {object} ErrorResponse "SKU not found"
+// @Failure 422 {object} ErrorResponse "SKU is a variant"
+// @Router /skus/{id}/variants [get]
+func (h *SKUHandler) GetVariants(c *gin.Context) {
+	matrix, err := h.skuUseCase.GetVariantMatrix(c.Request.Context(), c.Param("id"))
 	if err != nil {
-		statusCode := http.StatusInternalServerError
-		if err == usecase.ErrInvalidPriceRange {
-			statusCode = http.StatusBadRequest
-		}
-		c.JSON(statusCode, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, PaginatedResponse{
-		Data:      skus,
-		Total:     total,
-		Page:      page,
-		PageSize:  pageSize,
-		TotalPage: (total + int64(pageSize) - 1) / int64(pageSize),
-	})
+	c.JSON(http.StatusOK, matrix)
+}
+
+// @Summary Generate SKU variants
+// @Description Generate a variant of the parent item for every combination of the values of the dimensions it has none for yet, e.g. sizes S, M and L by colors Red and Blue. Variants copy the parent's details, with the code TSHIRT-M-RED and the name "T-shirt (M, Red)" for parent TSHIRT. A parent with variants can gain values, but keeps its dimensions.
+// @Tags skus
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Parent item ID"
+// @Param request body entity.SKUVariantRequest true "Dimensions and price"
+// @Success 201 {object} entity.SKUVariantMatrix
+// @Failure 400 {object} ErrorResponse "Invalid dimensions"
+// @Failure 404 {object} ErrorResponse "SKU not found"
+// @Failure 409 {object} ErrorResponse "Duplicate SKU code"
+// @Failure 422 {object} ErrorResponse "SKU is a variant, or dimensions differ from its variants'"
+// @Router /skus/{id}/variants [post]
+func (h *SKUHandler) GenerateVariants(c *gin.Context) {
+	var req entity.SKUVariantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	matrix, err := h.skuUseCase.GenerateVariants(c.Request.Context(), c.Param("id"), &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, matrix)
+}
+
+// @Summary Get kit components
+// @Description Get the components of a kit with the quantity of each in one kit
+// @Tags skus
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Kit ID"
+// @Success 200 {array} entity.KitComponent
+// @Failure 404 {object} ErrorResponse "SKU not found"
+// @Router /skus/{id}/components [get]
+func (h *SKUHandler) GetKitComponents(c *gin.Context) {
+	components, err := h.skuUseCase.GetKitComponents(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, components)
+}
+
+// @Summary Set kit components
+// @Description Replace the components of a SKU, making it a kit sold as a bundle of them; no components make it a plain SKU again. Sales orders reserve and deliveries ship the components of kits, and the available-to-promise of a kit follows from its components' stock. Kits cannot hold kits.
+// @Tags skus
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Kit ID"
+// @Param request body entity.KitComponentsRequest true "Components"
+// @Success 200 {array} entity.KitComponent
+// @Failure 400 {object} ErrorResponse "Invalid components"
+// @Failure 404 {object} ErrorResponse "SKU not found"
+// @Failure 422 {object} ErrorResponse "Kit nested in a kit"
+// @Router /skus/{id}/components [put]
+func (h *SKUHandler) SetKitComponents(c *gin.Context) {
+	var req entity.KitComponentsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	components, err := h.skuUseCase.SetKitComponents(c.Request.Context(), c.Param("id"), &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, components)
 }
 
 // @Summary Search SKUs
-// @Description Search for SKUs by a search term
+// @Description Search SKUs by code, name or description, best match first. Words match as prefixes and misspelt words still match; without a term all SKUs are listed.
 // @Tags skus
 // @Security BearerAuth
 // @Produce json
@@ -271,7 +411,7 @@ func (h *SKUHandler) SearchSKUs(c *gin.Context) {
 
 	skus, total, err := h.skuUseCase.SearchSKUs(c.Request.Context(), searchTerm, page, pageSize)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -298,16 +438,16 @@ func (h *SKUHandler) SearchSKUs(c *gin.Context) {
 func (h *SKUHandler) CreateSKUCategory(c *gin.Context) {
 	var category entity.SKUCategory
 	if err := c.ShouldBindJSON(&category); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 
 	if err := h.skuUseCase.CreateSKUCategory(c.Request.Context(), &category); err != nil {
-		statusCode := http.StatusInternalServerError
+		// The parent category of a new category must exist
 		if err == usecase.ErrCategoryNotFound {
-			statusCode = http.StatusBadRequest
+			err = entity.WrapError(entity.ErrCodeInvalidArgument, err)
 		}
-		c.JSON(statusCode, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -331,22 +471,13 @@ func (h *SKUHandler) UpdateSKUCategory(c *gin.Context) {
 	id := c.Param("id")
 	var category entity.SKUCategory
 	if err := c.ShouldBindJSON(&category); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 	category.ID = id
 
 	if err := h.skuUseCase.UpdateSKUCategory(c.Request.Context(), &category); err != nil {
-		statusCode := http.StatusInternalServerError
-		switch err {
-		case usecase.ErrCategoryNotFound:
-			statusCode = http.StatusNotFound
-		default:
-			if err.Error() == "category cannot be its own parent" {
-				statusCode = http.StatusBadRequest
-			}
-		}
-		c.JSON(statusCode, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -368,11 +499,7 @@ func (h *SKUHandler) GetSKUCategory(c *gin.Context) {
 
 	category, err := h.skuUseCase.GetSKUCategory(c.Request.Context(), id)
 	if err != nil {
-		statusCode := http.StatusInternalServerError
-		if err == usecase.ErrCategoryNotFound {
-			statusCode = http.StatusNotFound
-		}
-		c.JSON(statusCode, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -394,16 +521,7 @@ func (h *SKUHandler) DeleteSKUCategory(c *gin.Context) {
 	id := c.Param("id")
 
 	if err := h.skuUseCase.DeleteSKUCategory(c.Request.Context(), id); err != nil {
-		statusCode := http.StatusInternalServerError
-		switch err {
-		case usecase.ErrCategoryNotFound:
-			statusCode = http.StatusNotFound
-		default:
-			if err.Error() == "cannot delete category with children" || err.Error() == "cannot delete category used by SKUs" {
-				statusCode = http.StatusBadRequest
-			}
-		}
-		c.JSON(statusCode, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -421,7 +539,7 @@ func (h *SKUHandler) DeleteSKUCategory(c *gin.Context) {
 func (h *SKUHandler) ListSKUCategories(c *gin.Context) {
 	categories, err := h.skuUseCase.ListSKUCategories(c.Request.Context())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -439,7 +557,7 @@ func (h *SKUHandler) ListSKUCategories(c *gin.Context) {
 func (h *SKUHandler) GetSKUCategoriesTree(c *gin.Context) {
 	categories, err := h.skuUseCase.GetSKUCategoriesTree(c.Request.Context())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -465,11 +583,7 @@ func (h *SKUHandler) GetSKUsByCategory(c *gin.Context) {
 
 	skus, total, err := h.skuUseCase.GetSKUsByCategory(c.Request.Context(), categoryID, page, pageSize)
 	if err != nil {
-		statusCode := http.StatusInternalServerError
-		if err == usecase.ErrCategoryNotFound {
-			statusCode = http.StatusNotFound
-		}
-		c.JSON(statusCode, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -483,53 +597,55 @@ func (h *SKUHandler) GetSKUsByCategory(c *gin.Context) {
 }
 
 // @Summary Bulk create SKUs
-// @Description Create multiple SKUs in a single request
+// @Description Validate multiple SKUs and queue the job creating them in a single transaction. Follow the job under /jobs/{id}.
 // @Tags skus
 // @Security BearerAuth
 // @Accept json
 // @Produce json
 // @Param skus body []entity.SKU true "Array of SKU details"
-// @Success 201 {object} map[string]string
+// @Success 202 {object} map[string]interface{}
 // @Failure 400 {object} ErrorResponse "Invalid input"
 // @Router /skus/bulk [post]
 func (h *SKUHandler) BulkCreateSKUs(c *gin.Context) {
 	var skus []*entity.SKU
 	if err := c.ShouldBindJSON(&skus); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 
-	if err := h.skuUseCase.BulkCreateSKUs(c.Request.Context(), skus); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	job, err := h.skuUseCase.QueueBulkCreateSKUs(c.Request.Context(), skus, currentUserID(c))
+	if err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"message": "SKUs created successfully"})
+	c.JSON(http.StatusAccepted, gin.H{"message": "SKU creation queued", "job": job})
 }
 
 // @Summary Bulk update SKUs
-// @Description Update multiple SKUs in a single request
+// @Description Validate multiple SKUs and queue the job updating them in a single transaction. Follow the job under /jobs/{id}.
 // @Tags skus
 // @Security BearerAuth
 // @Accept json
 // @Produce json
 // @Param skus body []entity.SKU true "Array of SKU details"
-// @Success 200 {object} map[string]string
+// @Success 202 {object} map[string]interface{}
 // @Failure 400 {object} ErrorResponse "Invalid input"
 // @Router /skus/bulk [put]
 func (h *SKUHandler) BulkUpdateSKUs(c *gin.Context) {
 	var skus []*entity.SKU
 	if err := c.ShouldBindJSON(&skus); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 
-	if err := h.skuUseCase.BulkUpdateSKUs(c.Request.Context(), skus); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	job, err := h.skuUseCase.QueueBulkUpdateSKUs(c.Request.Context(), skus, currentUserID(c))
+	if err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "SKUs updated successfully"})
+	c.JSON(http.StatusAccepted, gin.H{"message": "SKU update queued", "job": job})
 }
 
 // PaginatedResponse represents a paginated response
@@ -0,0 +1,177 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/filestore"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/server/middleware"
+)
+
+// SKUImageHandlers handles HTTP requests for the images of SKUs
+type SKUImageHandlers struct {
+	imageUseCase *usecase.SKUImageUseCase
+}
+
+// NewSKUImageHandlers creates a new SKU image handlers instance
+func NewSKUImageHandlers(imageUseCase *usecase.SKUImageUseCase) *SKUImageHandlers {
+	return &SKUImageHandlers{
+		imageUseCase: imageUseCase,
+	}
+}
+
+// RegisterRoutes registers SKU image routes
+func (h *SKUImageHandlers) RegisterRoutes(router *gin.RouterGroup) {
+	images := router.Group("/skus/:id/images")
+	{
+		images.GET("", middleware.PermissionMiddleware(entity.ProductRead), h.ListImages)
+		images.POST("", middleware.PermissionMiddleware(entity.ProductUpdate), h.UploadImage)
+		images.PUT("/order", middleware.PermissionMiddleware(entity.ProductUpdate), h.ReorderImages)
+		images.DELETE("/:image_id", middleware.PermissionMiddleware(entity.ProductUpdate), h.DeleteImage)
+	}
+}
+
+// ListImages handles listing the images of a SKU
+// @Summary List SKU images
+// @Description List the images of a SKU in the order they are shown in, each with URLs of the image and its thumbnail that expire
+// @Tags skus
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "SKU ID"
+// @Success 200 {array} entity.SKUImage
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /skus/{id}/images [get]
+func (h *SKUImageHandlers) ListImages(c *gin.Context) {
+	images, err := h.imageUseCase.ListImages(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, images)
+}
+
+// UploadImage handles adding an image to a SKU
+// @Summary Upload SKU image
+// @Description Add a JPEG, PNG or GIF image after the other images of a SKU, generating its thumbnail. The size of the image is checked against the configured upload limit.
+// @Tags skus
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path string true "SKU ID"
+// @Param file formData file true "Image"
+// @Success 201 {object} entity.SKUImage
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 413 {object} ErrorResponse
+// @Failure 415 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse "SKU has the most images allowed"
+// @Failure 500 {object} ErrorResponse
+// @Router /skus/{id}/images [post]
+func (h *SKUImageHandlers) UploadImage(c *gin.Context) {
+	if maxSize := h.imageUseCase.Limits().MaxSize; maxSize > 0 {
+		// Leave room for the multipart envelope around the file
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize+64<<10)
+	}
+	header, err := c.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			h.handleError(c, filestore.ErrFileTooLarge)
+			return
+		}
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "File is required"))
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid file"))
+		return
+	}
+	defer file.Close()
+
+	image, err := h.imageUseCase.Upload(c.Request.Context(), c.Param("id"),
+		header.Filename, header.Header.Get("Content-Type"), file, currentUserID(c))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, image)
+}
+
+// ReorderImages handles changing the order of the images of a SKU
+// @Summary Reorder SKU images
+// @Description Show the images of a SKU in the order of image_ids, which lists every one of them
+// @Tags skus
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "SKU ID"
+// @Param request body entity.SKUImageOrderRequest true "Image IDs in order"
+// @Success 200 {array} entity.SKUImage
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /skus/{id}/images/order [put]
+func (h *SKUImageHandlers) ReorderImages(c *gin.Context) {
+	var req entity.SKUImageOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	images, err := h.imageUseCase.ReorderImages(c.Request.Context(), c.Param("id"), &req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, images)
+}
+
+// DeleteImage handles removing an image of a SKU
+// @Summary Delete SKU image
+// @Description Remove an image of a SKU with its thumbnail
+// @Tags skus
+// @Security BearerAuth
+// @Param id path string true "SKU ID"
+// @Param image_id path int true "Image ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /skus/{id}/images/{image_id} [delete]
+func (h *SKUImageHandlers) DeleteImage(c *gin.Context) {
+	imageID, err := strconv.ParseUint(c.Param("image_id"), 10, 64)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid image ID"))
+		return
+	}
+
+	if err := h.imageUseCase.DeleteImage(c.Request.Context(), c.Param("id"), imageID); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *SKUImageHandlers) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, filestore.ErrFileEmpty), errors.Is(err, filestore.ErrImageInvalid):
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+	case errors.Is(err, filestore.ErrFileTooLarge):
+		c.Error(entity.WrapError(entity.ErrCodePayloadTooLarge, err))
+	case errors.Is(err, filestore.ErrContentTypeInvalid):
+		c.Error(entity.WrapError(entity.ErrCodeUnsupportedMediaType, err))
+	default:
+		c.Error(err)
+	}
+}
@@ -0,0 +1,100 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/server/middleware"
+)
+
+// StockSnapshotHandlers handles HTTP requests for the daily closing stock
+// snapshots and the stock on past days read from them
+type StockSnapshotHandlers struct {
+	snapshotUseCase *usecase.StockSnapshotUseCase
+}
+
+// NewStockSnapshotHandlers creates a new stock snapshot handlers instance
+func NewStockSnapshotHandlers(snapshotUseCase *usecase.StockSnapshotUseCase) *StockSnapshotHandlers {
+	return &StockSnapshotHandlers{
+		snapshotUseCase: snapshotUseCase,
+	}
+}
+
+// RegisterRoutes registers stock snapshot routes
+func (h *StockSnapshotHandlers) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("/stocks/snapshots", middleware.PermissionMiddleware(entity.StockRead), h.GetSnapshot)
+	router.POST("/stocks/snapshots", middleware.PermissionMiddleware(entity.StockSnapshotRun), h.TakeSnapshot)
+}
+
+// GetSnapshot handles reading the stock at the close of a past day
+// @Summary Get stock on a past day
+// @Description Get the quantity and value of each SKU in each store at the close of a past day, from the latest snapshot taken on or before it
+// @Tags stocks
+// @Security BearerAuth
+// @Produce json
+// @Param date query string false "Day (YYYY-MM-DD), defaults to yesterday"
+// @Param store_id query string false "Store ID"
+// @Param sku_id query string false "SKU ID"
+// @Success 200 {object} entity.StockSnapshotReport
+// @Failure 400 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /stocks/snapshots [get]
+func (h *StockSnapshotHandlers) GetSnapshot(c *gin.Context) {
+	filter := &entity.StockSnapshotFilter{
+		StoreID: c.Query("store_id"),
+		SKUID:   c.Query("sku_id"),
+	}
+	if dateStr := c.Query("date"); dateStr != "" {
+		date, err := time.Parse("2006-01-02", dateStr)
+		if err != nil {
+			c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid date"))
+			return
+		}
+		filter.Date = date
+	}
+
+	report, err := h.snapshotUseCase.Report(c.Request.Context(), filter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// TakeSnapshot handles taking the snapshot of a past day on demand
+// @Summary Take stock snapshot
+// @Description Snapshot the closing stock of a past day per SKU and store, replacing the snapshot already taken of it. The stock is reconstructed from the stock history recorded since the day ended.
+// @Tags stocks
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body entity.StockSnapshotRequest true "Day to snapshot"
+// @Success 200 {object} entity.StockSnapshotResult
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /stocks/snapshots [post]
+func (h *StockSnapshotHandlers) TakeSnapshot(c *gin.Context) {
+	var req entity.StockSnapshotRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+	day, err := time.Parse("2006-01-02", req.Date)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid date"))
+		return
+	}
+
+	result, err := h.snapshotUseCase.TakeSnapshot(c.Request.Context(), day)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -76,7 +76,7 @@ func (h *StocksHandler) ListStocks(c *gin.Context) {
 
 	stocks, err := h.stocksUC.ListStocks(c.Request.Context(), filter)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -99,19 +99,124 @@ func (h *StocksHandler) CheckStock(c *gin.Context) {
 	storeID := c.Query("store_id")
 
 	if skuID == "" || storeID == "" {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "sku_id and store_id are required"})
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "sku_id and store_id are required"))
 		return
 	}
 
 	stock, err := h.stocksUC.CheckStock(c.Request.Context(), skuID, storeID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
 	c.JSON(http.StatusOK, stock)
 }
 
+// @Summary Available to promise
+// @Description Get the quantity of a SKU a store can still promise: its stock outside quarantine less what allocation runs reserved. A kit's is the number of whole kits the available stock of its components makes up, with the availability of each component.
+// @Tags stocks
+// @Security BearerAuth
+// @Produce json
+// @Param sku_id query string true "SKU ID"
+// @Param store_id query string true "Store ID"
+// @Success 200 {object} entity.AvailableToPromise
+// @Failure 400 {object} ErrorResponse "Missing required parameters"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /stocks/atp [get]
+func (h *StocksHandler) AvailableToPromise(c *gin.Context) {
+	skuID := c.Query("sku_id")
+	storeID := c.Query("store_id")
+
+	if skuID == "" || storeID == "" {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "sku_id and store_id are required"))
+		return
+	}
+
+	atp, err := h.stocksUC.AvailableToPromise(c.Request.Context(), skuID, storeID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, atp)
+}
+
+// @Summary List stock entries
+// @Description List stock entries, latest first, by page number or, for deep listings, by cursor. Passing cursor or limit returns {entries, limit, next_cursor} instead of {entries, total, page, page_size}.
+// @Tags stocks
+// @Security BearerAuth
+// @Produce json
+// @Param sku_id query string false "SKU ID"
+// @Param store_id query string false "Store ID"
+// @Param type query string false "Entry type (IN/OUT)"
+// @Param reference query string false "Reference"
+// @Param start_date query string false "Start date (YYYY-MM-DD)"
+// @Param end_date query string false "End date (YYYY-MM-DD)"
+// @Param page query int false "Page number"
+// @Param page_size query int false "Page size"
+// @Param cursor query string false "Return the entries after this next_cursor of a previous page"
+// @Param limit query int false "Entries per page when paging by cursor (default 50, at most 500)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse "Invalid cursor or limit"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /stocks/stock-entries [get]
+func (h *StocksHandler) ListStockEntries(c *gin.Context) {
+	filter := &entity.StockEntryFilter{
+		SKUID:     c.Query("sku_id"),
+		StoreID:   c.Query("store_id"),
+		Type:      c.Query("type"),
+		Reference: c.Query("reference"),
+	}
+
+	if startDateStr := c.Query("start_date"); startDateStr != "" {
+		if startDate, err := time.Parse("2006-01-02", startDateStr); err == nil {
+			filter.StartDate = &startDate
+		}
+	}
+
+	if endDateStr := c.Query("end_date"); endDateStr != "" {
+		if endDate, err := time.Parse("2006-01-02", endDateStr); err == nil {
+			endDate = endDate.AddDate(0, 0, 1).Add(-time.Nanosecond)
+			filter.EndDate = &endDate
+		}
+	}
+
+	page, err := parseCursorPage(c)
+	if err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+	if page != nil {
+		entries, next, err := h.stocksUC.ListStockEntriesPage(c.Request.Context(), filter, *page)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		c.JSON(http.StatusOK, cursorPageResponse("entries", entries, page, next))
+		return
+	}
+
+	if p, err := strconv.Atoi(c.Query("page")); err == nil {
+		filter.Page = p
+	}
+	if pageSize, err := strconv.Atoi(c.Query("page_size")); err == nil {
+		filter.PageSize = pageSize
+	}
+
+	entries, total, err := h.stocksUC.ListStockEntries(c.Request.Context(), filter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"entries":   entries,
+		"total":     total,
+		"page":      filter.Page,
+		"page_size": filter.PageSize,
+	})
+}
+
 // @Summary Process stock entry
 // @Description Process a stock entry (add, remove, transfer, adjust)
 // @Tags stocks
@@ -127,23 +232,23 @@ func (h *StocksHandler) CheckStock(c *gin.Context) {
 func (h *StocksHandler) ProcessStockEntry(c *gin.Context) {
 	var entry entity.StockEntry
 	if err := c.ShouldBindJSON(&entry); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 
 	userID := c.GetString("user_id") // Assuming user_id is set in auth middleware
 	if userID == "" {
-		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "user not authenticated"})
+		c.Error(entity.NewError(entity.ErrCodeUnauthenticated, "user not authenticated"))
 		return
 	}
 
 	if err := h.stocksUC.ValidateStockEntry(&entry); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 
 	if err := h.stocksUC.ProcessStockEntry(c.Request.Context(), &entry, userID); err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -165,26 +270,26 @@ func (h *StocksHandler) ProcessStockEntry(c *gin.Context) {
 func (h *StocksHandler) BatchStockEntry(c *gin.Context) {
 	var entries []entity.StockEntry
 	if err := c.ShouldBindJSON(&entries); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 
 	userID := c.GetString("user_id")
 	if userID == "" {
-		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "user not authenticated"})
+		c.Error(entity.NewError(entity.ErrCodeUnauthenticated, "user not authenticated"))
 		return
 	}
 
 	// Validate all entries first
 	for _, entry := range entries {
 		if err := h.stocksUC.ValidateStockEntry(&entry); err != nil {
-			c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 			return
 		}
 	}
 
 	if err := h.stocksUC.BatchStockEntry(c.Request.Context(), entries, userID); err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -212,12 +317,12 @@ func (h *StocksHandler) UpdateStockLocation(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 
 	if err := h.stocksUC.UpdateStockLocation(c.Request.Context(), id, req.BinLocation, req.ShelfNumber, req.ZoneCode); err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -238,9 +343,98 @@ func (h *StocksHandler) GetStockHistory(c *gin.Context) {
 
 	history, err := h.stocksUC.GetStockHistory(c.Request.Context(), stockID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
 	c.JSON(http.StatusOK, history)
 }
+
+// @Summary Get stock ledger
+// @Description List every movement of a SKU in a store with the balance after it, its cost and the purchase receipt, delivery, sales or production order it came from. Receipts are costed at the receipt line price, other movements at the SKU price. Archived movements are included.
+// @Tags stocks
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "SKU ID"
+// @Param warehouse path string true "Store ID"
+// @Param start_date query string false "Movements on or after this date (YYYY-MM-DD)"
+// @Param end_date query string false "Movements on or before this date (YYYY-MM-DD)"
+// @Success 200 {object} entity.StockLedger
+// @Failure 400 {object} ErrorResponse "Invalid date range"
+// @Failure 404 {object} ErrorResponse "SKU or store not found"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /stocks/{id}/{warehouse}/ledger [get]
+func (h *StocksHandler) GetStockLedger(c *gin.Context) {
+	filter, err := stockLedgerFilter(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	ledger, err := h.stocksUC.GetStockLedger(c.Request.Context(), filter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ledger)
+}
+
+// @Summary Export stock ledger
+// @Description Download the stock ledger of a SKU in a store, its movements between an opening and a closing balance row
+// @Tags stocks
+// @Security BearerAuth
+// @Produce text/csv
+// @Param id path string true "SKU ID"
+// @Param warehouse path string true "Store ID"
+// @Param format query string false "File format: csv (default), xlsx, pdf or json"
+// @Param start_date query string false "Movements on or after this date (YYYY-MM-DD)"
+// @Param end_date query string false "Movements on or before this date (YYYY-MM-DD)"
+// @Success 200 {file} file
+// @Failure 400 {object} ErrorResponse "Invalid date range or format"
+// @Failure 404 {object} ErrorResponse "SKU or store not found"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /stocks/{id}/{warehouse}/ledger/export [get]
+func (h *StocksHandler) ExportStockLedger(c *gin.Context) {
+	format, ok := parseExportFormat(c)
+	if !ok {
+		return
+	}
+	filter, err := stockLedgerFilter(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	columns, rows, err := h.stocksUC.ExportStockLedger(c.Request.Context(), filter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	writeExport(c, format, "stock-ledger", "Stock ledger", columns, rows)
+}
+
+// stockLedgerFilter reads the SKU and store of a stock ledger from the path
+// and its date range from the query
+func stockLedgerFilter(c *gin.Context) (*entity.StockLedgerFilter, error) {
+	filter := &entity.StockLedgerFilter{
+		SKUID:   c.Param("id"),
+		StoreID: c.Param("warehouse"),
+	}
+	if startDateStr := c.Query("start_date"); startDateStr != "" {
+		startDate, err := time.Parse("2006-01-02", startDateStr)
+		if err != nil {
+			return nil, entity.NewError(entity.ErrCodeInvalidArgument, "Invalid start_date")
+		}
+		filter.From = &startDate
+	}
+	if endDateStr := c.Query("end_date"); endDateStr != "" {
+		endDate, err := time.Parse("2006-01-02", endDateStr)
+		if err != nil {
+			return nil, entity.NewError(entity.ErrCodeInvalidArgument, "Invalid end_date")
+		}
+		filter.To = &endDate
+	}
+	return filter, nil
+}
@@ -35,12 +35,12 @@ func NewStoreHandler(storeUC *usecase.StoreUseCase, stocksUC *usecase.StocksUseC
 func (h *StoreHandler) CreateStore(c *gin.Context) {
 	var store entity.Store
 	if err := c.ShouldBindJSON(&store); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 
 	if err := h.storeUC.CreateStore(c.Request.Context(), &store); err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -61,7 +61,7 @@ func (h *StoreHandler) GetStore(c *gin.Context) {
 
 	store, err := h.storeUC.GetStore(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeNotFound, err))
 		return
 	}
 
@@ -84,13 +84,13 @@ func (h *StoreHandler) UpdateStore(c *gin.Context) {
 	id := c.Param("id")
 	var store entity.Store
 	if err := c.ShouldBindJSON(&store); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 	store.ID = id
 
 	if err := h.storeUC.UpdateStore(c.Request.Context(), &store); err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -110,7 +110,7 @@ func (h *StoreHandler) DeleteStore(c *gin.Context) {
 	id := c.Param("id")
 
 	if err := h.storeUC.DeleteStore(c.Request.Context(), id); err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -156,7 +156,7 @@ func (h *StoreHandler) ListStores(c *gin.Context) {
 
 	stores, err := h.storeUC.ListStores(c.Request.Context(), &filter)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -199,7 +199,7 @@ func (h *StoreHandler) GetStoreStocks(c *gin.Context) {
 
 	stocks, err := h.storeUC.GetStoreStocks(c.Request.Context(), storeID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -220,7 +220,7 @@ func (h *StoreHandler) GetStoreStockValue(c *gin.Context) {
 
 	value, err := h.storeUC.GetStoreStockValue(c.Request.Context(), storeID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -246,12 +246,12 @@ func (h *StoreHandler) UpdateStoreStatus(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 
 	if err := h.storeUC.UpdateStatus(c.Request.Context(), id, req.Status); err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -277,12 +277,12 @@ func (h *StoreHandler) AssignManager(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 
 	if err := h.storeUC.AssignManager(c.Request.Context(), storeID, req.ManagerID); err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
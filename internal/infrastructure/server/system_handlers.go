@@ -0,0 +1,138 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/repository"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/server/middleware"
+)
+
+// SystemHandlers handles administrative diagnostics HTTP requests
+type SystemHandlers struct {
+	systemUseCase *usecase.SystemUseCase
+}
+
+// NewSystemHandlers creates a new system handlers instance
+func NewSystemHandlers(systemUseCase *usecase.SystemUseCase) *SystemHandlers {
+	return &SystemHandlers{
+		systemUseCase: systemUseCase,
+	}
+}
+
+// RegisterRoutes registers system-related routes
+func (h *SystemHandlers) RegisterRoutes(router *gin.RouterGroup) {
+	systemRouter := router.Group("/system")
+	{
+		systemRouter.GET("/database/slow-queries", middleware.PermissionMiddleware(entity.SystemDatabaseRead), h.GetSlowQueries)
+		systemRouter.GET("/database/pool", middleware.PermissionMiddleware(entity.SystemDatabaseRead), h.GetPoolStats)
+		systemRouter.GET("/cache", middleware.PermissionMiddleware(entity.SystemDatabaseRead), h.GetCacheStats)
+		systemRouter.POST("/sandbox/reset", middleware.PermissionMiddleware(entity.SystemSandboxReset), h.ResetSandbox)
+	}
+}
+
+// GetSlowQueries handles retrieving slow queries captured by pg_stat_statements
+// @Summary Get slow queries
+// @Description Get the slowest statements by mean execution time from pg_stat_statements
+// @Tags system
+// @Security BearerAuth
+// @Produce json
+// @Param min_mean_ms query number false "Minimum mean execution time in milliseconds"
+// @Param limit query int false "Maximum number of statements (default 20, max 200)"
+// @Success 200 {array} entity.SlowQuery
+// @Failure 400 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /system/database/slow-queries [get]
+func (h *SystemHandlers) GetSlowQueries(c *gin.Context) {
+	filter := &entity.SlowQueryFilter{}
+
+	if minMean := c.Query("min_mean_ms"); minMean != "" {
+		value, err := strconv.ParseFloat(minMean, 64)
+		if err != nil {
+			c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "invalid min_mean_ms"))
+			return
+		}
+		filter.MinMeanTimeMs = value
+	}
+
+	if limit := c.Query("limit"); limit != "" {
+		value, err := strconv.Atoi(limit)
+		if err != nil {
+			c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "invalid limit"))
+			return
+		}
+		filter.Limit = value
+	}
+
+	queries, err := h.systemUseCase.GetSlowQueries(c.Request.Context(), filter)
+	if err != nil {
+		if errors.Is(err, repository.ErrQueryStatsUnavailable) {
+			c.Error(entity.WrapError(entity.ErrCodeUnavailable, err))
+			return
+		}
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, queries)
+}
+
+// GetPoolStats handles retrieving database connection pool statistics
+// @Summary Get database pool statistics
+// @Description Get open, in-use and idle connection counts, wait statistics and pool saturation
+// @Tags system
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} entity.DBPoolStats
+// @Failure 500 {object} ErrorResponse
+// @Router /system/database/pool [get]
+func (h *SystemHandlers) GetPoolStats(c *gin.Context) {
+	stats, err := h.systemUseCase.GetPoolStats(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// GetCacheStats handles retrieving master data cache statistics
+// @Summary Get cache statistics
+// @Description Get the cache driver and the hits, misses, errors and invalidations of each kind of cached master data since the server started
+// @Tags system
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} entity.CacheStats
+// @Router /system/cache [get]
+func (h *SystemHandlers) GetCacheStats(c *gin.Context) {
+	c.JSON(http.StatusOK, h.systemUseCase.GetCacheStats(c.Request.Context()))
+}
+
+// ResetSandbox handles resetting the sandbox schema
+// @Summary Reset sandbox
+// @Description Discard all sandbox documents, recreate the sandbox tables and reload master data from the live schema
+// @Tags system
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} entity.SandboxResetResult
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /system/sandbox/reset [post]
+func (h *SystemHandlers) ResetSandbox(c *gin.Context) {
+	result, err := h.systemUseCase.ResetSandbox(c.Request.Context())
+	if err != nil {
+		if errors.Is(err, usecase.ErrSandboxDisabled) {
+			c.Error(entity.WrapError(entity.ErrCodeConflict, err))
+			return
+		}
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
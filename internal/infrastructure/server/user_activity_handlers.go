@@ -0,0 +1,257 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/server/middleware"
+)
+
+// UserActivityHandlers handles user activity and login history HTTP requests
+type UserActivityHandlers struct {
+	activityUseCase *usecase.UserActivityUseCase
+}
+
+// NewUserActivityHandlers creates a new user activity handlers instance
+func NewUserActivityHandlers(activityUseCase *usecase.UserActivityUseCase) *UserActivityHandlers {
+	return &UserActivityHandlers{
+		activityUseCase: activityUseCase,
+	}
+}
+
+// RegisterRoutes registers user activity routes
+func (h *UserActivityHandlers) RegisterRoutes(router *gin.RouterGroup) {
+	activity := router.Group("/audit/activity")
+	{
+		activity.GET("/users", middleware.PermissionMiddleware(entity.AuditLogRead), h.ListUserActivity)
+		activity.GET("/users/:id", middleware.PermissionMiddleware(entity.AuditLogRead), h.GetUserActivity)
+		activity.GET("/logins", middleware.PermissionMiddleware(entity.AuditLogRead), h.ListLoginAttempts)
+		activity.GET("/logins/failed", middleware.PermissionMiddleware(entity.AuditLogRead), h.GetFailedLogins)
+		activity.GET("/inactive", middleware.PermissionMiddleware(entity.AuditLogRead), h.ListInactiveAccounts)
+	}
+}
+
+// ListUserActivity handles summarizing the activity of all users
+// @Summary List user activity
+// @Description Summarize each user's sign-ins, failed sign-ins, audited actions per module and last seen time in a period, most recently seen first
+// @Tags audit
+// @Security BearerAuth
+// @Produce json
+// @Param start_date query string false "Start date (YYYY-MM-DD)"
+// @Param end_date query string false "End date (YYYY-MM-DD)"
+// @Success 200 {array} entity.UserActivitySummary
+// @Failure 500 {object} ErrorResponse
+// @Router /audit/activity/users [get]
+func (h *UserActivityHandlers) ListUserActivity(c *gin.Context) {
+	filter := &entity.UserActivityFilter{}
+	filter.StartDate, filter.EndDate = activityPeriod(c)
+
+	summaries, err := h.activityUseCase.ActivitySummary(c.Request.Context(), filter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, summaries)
+}
+
+// GetUserActivity handles summarizing the activity of one user
+// @Summary Get user activity
+// @Description Summarize a user's activity in a period, with their latest sign-in attempts
+// @Tags audit
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "User ID"
+// @Param start_date query string false "Start date (YYYY-MM-DD)"
+// @Param end_date query string false "End date (YYYY-MM-DD)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /audit/activity/users/{id} [get]
+func (h *UserActivityHandlers) GetUserActivity(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil || id == 0 {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid user ID"))
+		return
+	}
+
+	filter := &entity.UserActivityFilter{UserID: uint(id)}
+	filter.StartDate, filter.EndDate = activityPeriod(c)
+
+	summaries, err := h.activityUseCase.ActivitySummary(c.Request.Context(), filter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if len(summaries) == 0 {
+		c.Error(entity.NewError(entity.ErrCodeNotFound, "User not found"))
+		return
+	}
+
+	logins, _, err := h.activityUseCase.ListLoginAttempts(c.Request.Context(), &entity.LoginAttemptFilter{
+		UserID:    uint(id),
+		StartDate: filter.StartDate,
+		EndDate:   filter.EndDate,
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"activity":      summaries[0],
+		"recent_logins": logins,
+	})
+}
+
+// ListLoginAttempts handles listing the login history
+// @Summary List login attempts
+// @Description List sign-in attempts, latest first, filtered by user, email, IP, outcome and date
+// @Tags audit
+// @Security BearerAuth
+// @Produce json
+// @Param user_id query int false "User ID"
+// @Param email query string false "Part of the email signed in with"
+// @Param ip query string false "Client IP address"
+// @Param success query bool false "Outcome"
+// @Param start_date query string false "Start date (YYYY-MM-DD)"
+// @Param end_date query string false "End date (YYYY-MM-DD)"
+// @Param page query int false "Page number"
+// @Param page_size query int false "Page size"
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} ErrorResponse
+// @Router /audit/activity/logins [get]
+func (h *UserActivityHandlers) ListLoginAttempts(c *gin.Context) {
+	filter := loginAttemptFilter(c)
+
+	if success, err := strconv.ParseBool(c.Query("success")); err == nil {
+		filter.Success = &success
+	}
+
+	attempts, total, err := h.activityUseCase.ListLoginAttempts(c.Request.Context(), filter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"attempts":  attempts,
+		"total":     total,
+		"page":      filter.Page,
+		"page_size": filter.PageSize,
+	})
+}
+
+// GetFailedLogins handles the failed login report
+// @Summary Get failed logins
+// @Description Report failed sign-in attempts for security review, with the emails and IP addresses they came from most often
+// @Tags audit
+// @Security BearerAuth
+// @Produce json
+// @Param user_id query int false "User ID"
+// @Param email query string false "Part of the email signed in with"
+// @Param ip query string false "Client IP address"
+// @Param start_date query string false "Start date (YYYY-MM-DD)"
+// @Param end_date query string false "End date (YYYY-MM-DD)"
+// @Param page query int false "Page number"
+// @Param page_size query int false "Page size"
+// @Success 200 {object} entity.FailedLoginReport
+// @Failure 500 {object} ErrorResponse
+// @Router /audit/activity/logins/failed [get]
+func (h *UserActivityHandlers) GetFailedLogins(c *gin.Context) {
+	report, err := h.activityUseCase.FailedLogins(c.Request.Context(), loginAttemptFilter(c))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// ListInactiveAccounts handles listing accounts eligible for deactivation
+// @Summary List inactive accounts
+// @Description List active users who have not signed in for the given number of days, longest inactive first. They can be deactivated by setting their status to inactive.
+// @Tags audit
+// @Security BearerAuth
+// @Produce json
+// @Param days query int false "Days without sign-in (defaults to security.inactive_account_days)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /audit/activity/inactive [get]
+func (h *UserActivityHandlers) ListInactiveAccounts(c *gin.Context) {
+	days := 0
+	if daysStr := c.Query("days"); daysStr != "" {
+		var err error
+		if days, err = strconv.Atoi(daysStr); err != nil {
+			c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid days"))
+			return
+		}
+	}
+
+	accounts, err := h.activityUseCase.InactiveAccounts(c.Request.Context(), days)
+	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidInactiveDays) {
+			c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+			return
+		}
+		c.Error(err)
+		return
+	}
+
+	if days == 0 {
+		days = h.activityUseCase.InactiveDays()
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"inactive_days": days,
+		"accounts":      accounts,
+	})
+}
+
+func loginAttemptFilter(c *gin.Context) *entity.LoginAttemptFilter {
+	filter := &entity.LoginAttemptFilter{
+		Email: c.Query("email"),
+		IP:    c.Query("ip"),
+	}
+
+	if userID, err := strconv.ParseUint(c.Query("user_id"), 10, 32); err == nil {
+		filter.UserID = uint(userID)
+	}
+
+	filter.StartDate, filter.EndDate = activityPeriod(c)
+
+	if page, err := strconv.Atoi(c.Query("page")); err == nil {
+		filter.Page = page
+	}
+
+	if pageSize, err := strconv.Atoi(c.Query("page_size")); err == nil {
+		filter.PageSize = pageSize
+	}
+
+	return filter
+}
+
+// activityPeriod parses the start_date and end_date query parameters, the end
+// date running to the end of its day
+func activityPeriod(c *gin.Context) (start, end *time.Time) {
+	if startDateStr := c.Query("start_date"); startDateStr != "" {
+		if startDate, err := time.Parse("2006-01-02", startDateStr); err == nil {
+			start = &startDate
+		}
+	}
+
+	if endDateStr := c.Query("end_date"); endDateStr != "" {
+		if endDate, err := time.Parse("2006-01-02", endDateStr); err == nil {
+			endDate = endDate.AddDate(0, 0, 1).Add(-time.Nanosecond)
+			end = &endDate
+		}
+	}
+
+	return start, end
+}
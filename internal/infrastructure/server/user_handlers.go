@@ -6,6 +6,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
 )
 
 // @Summary List users
@@ -19,7 +20,7 @@ import (
 func (s *Server) handleListUsers(c *gin.Context) {
 	users, err := s.userUC.ListUsers()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -44,13 +45,13 @@ func (s *Server) handleListUsers(c *gin.Context) {
 func (s *Server) handleGetUser(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid user ID"})
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "invalid user ID"))
 		return
 	}
 
 	user, err := s.userUC.GetUserByID(uint(id))
 	if err != nil {
-		c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeNotFound, err))
 		return
 	}
 
@@ -75,13 +76,13 @@ func (s *Server) handleGetUser(c *gin.Context) {
 func (s *Server) handleUpdateUser(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid user ID"})
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "invalid user ID"))
 		return
 	}
 
 	var req UpdateUserRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 
@@ -95,7 +96,7 @@ func (s *Server) handleUpdateUser(c *gin.Context) {
 
 	user, err := s.userUC.UpdateUser(input)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -119,12 +120,12 @@ func (s *Server) handleUpdateUser(c *gin.Context) {
 func (s *Server) handleDeleteUser(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid user ID"})
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "invalid user ID"))
 		return
 	}
 
 	if err := s.userUC.Delete(uint(id)); err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -0,0 +1,190 @@
+package server
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/server/middleware"
+)
+
+// maxUserImportBytes caps the size of a user import file
+const maxUserImportBytes = 10 << 20
+
+// UserProvisioningHandlers handles bulk user import and role mapping HTTP requests
+type UserProvisioningHandlers struct {
+	provisioningUseCase *usecase.UserProvisioningUseCase
+}
+
+// NewUserProvisioningHandlers creates a new user provisioning handlers instance
+func NewUserProvisioningHandlers(provisioningUseCase *usecase.UserProvisioningUseCase) *UserProvisioningHandlers {
+	return &UserProvisioningHandlers{
+		provisioningUseCase: provisioningUseCase,
+	}
+}
+
+// RegisterRoutes registers user provisioning routes
+func (h *UserProvisioningHandlers) RegisterRoutes(router *gin.RouterGroup) {
+	provisioning := router.Group("/provisioning")
+	{
+		provisioning.POST("/users/import", middleware.PermissionMiddleware(entity.UserProvision), h.ImportUsers)
+		provisioning.GET("/role-mappings", middleware.PermissionMiddleware(entity.UserProvision), h.ListRoleMappings)
+		provisioning.POST("/role-mappings", middleware.PermissionMiddleware(entity.UserProvision), h.CreateRoleMapping)
+		provisioning.DELETE("/role-mappings/:id", middleware.PermissionMiddleware(entity.UserProvision), h.DeleteRoleMapping)
+	}
+}
+
+// ImportUsers handles a bulk CSV user import
+// @Summary Import users
+// @Description Create, update and deactivate users from a CSV export of the corporate directory, uploaded as the "file" form field or as a text/csv body. Columns: email (required), username, external_id, groups (separated by ";" or "|"), role, active. Users are matched by external_id, then email, and get the role of their first mapped group, then of their first group named after a role, then the configured default role. Each row is applied on its own. A dry run previews the changes and responds with the report; otherwise the file is checked and a job importing it is queued, whose result is the report.
+// @Tags users
+// @Security BearerAuth
+// @Accept multipart/form-data,text/csv
+// @Produce json
+// @Param file formData file false "CSV file"
+// @Param dry_run query bool false "Report the changes without making them"
+// @Success 200 {object} entity.UserImportReport
+// @Success 202 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
+// @Failure 413 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /provisioning/users/import [post]
+func (h *UserProvisioningHandlers) ImportUsers(c *gin.Context) {
+	dryRun, _ := strconv.ParseBool(c.Query("dry_run"))
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxUserImportBytes)
+	var body io.Reader = c.Request.Body
+	if c.ContentType() == "multipart/form-data" {
+		header, err := c.FormFile("file")
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				h.handleError(c, err)
+				return
+			}
+			c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Import file is required"))
+			return
+		}
+		file, err := header.Open()
+		if err != nil {
+			c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid import file"))
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+
+	if !dryRun {
+		job, err := h.provisioningUseCase.QueueImportCSV(c.Request.Context(), body, currentUserID(c))
+		if err != nil {
+			h.handleError(c, err)
+			return
+		}
+		c.JSON(http.StatusAccepted, gin.H{"job": job})
+		return
+	}
+
+	report, err := h.provisioningUseCase.ImportCSV(c.Request.Context(), body, true)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// ListRoleMappings handles listing the directory group to role mappings
+// @Summary List role mappings
+// @Description List the corporate directory groups mapped to roles for provisioning
+// @Tags users
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} entity.RoleMapping
+// @Failure 500 {object} ErrorResponse
+// @Router /provisioning/role-mappings [get]
+func (h *UserProvisioningHandlers) ListRoleMappings(c *gin.Context) {
+	mappings, err := h.provisioningUseCase.ListRoleMappings(c.Request.Context())
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, mappings)
+}
+
+// CreateRoleMapping handles mapping a directory group to a role
+// @Summary Create role mapping
+// @Description Give provisioned users in a corporate directory group a role. Group names match case-insensitively.
+// @Tags users
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body entity.RoleMappingRequest true "Directory group and role"
+// @Success 201 {object} entity.RoleMapping
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /provisioning/role-mappings [post]
+func (h *UserProvisioningHandlers) CreateRoleMapping(c *gin.Context) {
+	var req entity.RoleMappingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	mapping, err := h.provisioningUseCase.CreateRoleMapping(c.Request.Context(), &req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, mapping)
+}
+
+// DeleteRoleMapping handles removing a role mapping
+// @Summary Delete role mapping
+// @Description Remove a role mapping. Users keep their role until they are next provisioned.
+// @Tags users
+// @Security BearerAuth
+// @Param id path int true "Role mapping ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /provisioning/role-mappings/{id} [delete]
+func (h *UserProvisioningHandlers) DeleteRoleMapping(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid role mapping ID"))
+		return
+	}
+
+	if err := h.provisioningUseCase.DeleteRoleMapping(c.Request.Context(), uint(id)); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *UserProvisioningHandlers) handleError(c *gin.Context, err error) {
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxBytesErr):
+		c.Error(entity.NewError(entity.ErrCodePayloadTooLarge, "Import file is too large"))
+	case errors.Is(err, usecase.ErrRoleMappingNotFound):
+		c.Error(entity.WrapError(entity.ErrCodeNotFound, err))
+	case errors.Is(err, usecase.ErrRoleMappingExists):
+		c.Error(entity.WrapError(entity.ErrCodeConflict, err))
+	case errors.Is(err, usecase.ErrInvalidUserImport),
+		errors.Is(err, usecase.ErrUserImportTooLarge),
+		errors.Is(err, usecase.ErrRoleMappingRole):
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+	default:
+		c.Error(err)
+	}
+}
@@ -3,6 +3,7 @@ package server
 import (
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
@@ -33,12 +34,12 @@ func NewVendorHandler(vendorUC *usecase.VendorUseCase) *VendorHandler {
 func (h *VendorHandler) CreateVendor(c *gin.Context) {
 	var vendor entity.Vendor
 	if err := c.ShouldBindJSON(&vendor); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 
 	if err := h.vendorUC.CreateVendor(c.Request.Context(), &vendor); err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -58,13 +59,13 @@ func (h *VendorHandler) CreateVendor(c *gin.Context) {
 func (h *VendorHandler) GetVendor(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid vendor ID"})
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "invalid vendor ID"))
 		return
 	}
 
 	vendor, err := h.vendorUC.GetVendor(c.Request.Context(), uint(id))
 	if err != nil {
-		c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeNotFound, err))
 		return
 	}
 
@@ -86,19 +87,19 @@ func (h *VendorHandler) GetVendor(c *gin.Context) {
 func (h *VendorHandler) UpdateVendor(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid vendor ID"})
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "invalid vendor ID"))
 		return
 	}
 
 	var vendor entity.Vendor
 	if err := c.ShouldBindJSON(&vendor); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 	vendor.ID = uint(id)
 
 	if err := h.vendorUC.UpdateVendor(c.Request.Context(), &vendor); err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -118,12 +119,12 @@ func (h *VendorHandler) UpdateVendor(c *gin.Context) {
 func (h *VendorHandler) DeleteVendor(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid vendor ID"})
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "invalid vendor ID"))
 		return
 	}
 
 	if err := h.vendorUC.DeleteVendor(c.Request.Context(), uint(id)); err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -141,15 +142,60 @@ func (h *VendorHandler) DeleteVendor(c *gin.Context) {
 // @Param country query string false "Vendor country"
 // @Param min_rating query number false "Minimum rating"
 // @Param product_ids[] query array false "Product IDs"
+// @Param cf.key query string false "Value of the custom field with this key, e.g. cf.tier=gold"
 // @Success 200 {array} entity.Vendor
 // @Failure 500 {object} ErrorResponse "Server error"
 // @Router /vendors [get]
 func (h *VendorHandler) ListVendors(c *gin.Context) {
+	vendors, err := h.vendorUC.ListVendors(c.Request.Context(), vendorFilter(c))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, vendors)
+}
+
+// @Summary Export vendors
+// @Description Download the vendors matching the filters of the vendor list, with a column per custom field defined on vendors
+// @Tags vendors
+// @Security BearerAuth
+// @Produce text/csv
+// @Param format query string false "File format: csv (default), xlsx, pdf or json"
+// @Param code query string false "Vendor code"
+// @Param name query string false "Vendor name"
+// @Param type query string false "Vendor type"
+// @Param country query string false "Vendor country"
+// @Param min_rating query number false "Minimum rating"
+// @Param product_ids[] query array false "Product IDs"
+// @Param cf.key query string false "Value of the custom field with this key, e.g. cf.tier=gold"
+// @Success 200 {file} file
+// @Failure 400 {object} ErrorResponse "Invalid filter or format"
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /vendors/export [get]
+func (h *VendorHandler) ExportVendors(c *gin.Context) {
+	format, ok := parseExportFormat(c)
+	if !ok {
+		return
+	}
+
+	columns, rows, err := h.vendorUC.ExportVendors(c.Request.Context(), vendorFilter(c))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	writeExport(c, format, "vendors", "Vendors", columns, rows)
+}
+
+// vendorFilter reads the vendor list filters from the query string
+func vendorFilter(c *gin.Context) entity.VendorFilter {
 	filter := entity.VendorFilter{
-		Code:    c.Query("code"),
-		Name:    c.Query("name"),
-		Type:    c.Query("type"),
-		Country: c.Query("country"),
+		Code:         c.Query("code"),
+		Name:         c.Query("name"),
+		Type:         c.Query("type"),
+		Country:      c.Query("country"),
+		CustomFields: customFieldQuery(c),
 	}
 
 	if minRating := c.Query("min_rating"); minRating != "" {
@@ -169,13 +215,7 @@ func (h *VendorHandler) ListVendors(c *gin.Context) {
 		filter.ProductIDs = productIDs
 	}
 
-	vendors, err := h.vendorUC.ListVendors(c.Request.Context(), filter)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
-		return
-	}
-
-	c.JSON(http.StatusOK, vendors)
+	return filter
 }
 
 // @Summary Create a new product
@@ -192,12 +232,12 @@ func (h *VendorHandler) ListVendors(c *gin.Context) {
 func (h *VendorHandler) CreateProduct(c *gin.Context) {
 	var product entity.Product
 	if err := c.ShouldBindJSON(&product); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 
 	if err := h.vendorUC.CreateProduct(c.Request.Context(), &product); err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -218,18 +258,18 @@ func (h *VendorHandler) CreateProduct(c *gin.Context) {
 func (h *VendorHandler) AddProductToVendor(c *gin.Context) {
 	vendorID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid vendor ID"})
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "invalid vendor ID"))
 		return
 	}
 
 	productID, err := strconv.ParseUint(c.Param("productId"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid product ID"})
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "invalid product ID"))
 		return
 	}
 
 	if err := h.vendorUC.AddProductToVendor(c.Request.Context(), uint(vendorID), uint(productID)); err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -250,18 +290,18 @@ func (h *VendorHandler) AddProductToVendor(c *gin.Context) {
 func (h *VendorHandler) RemoveProductFromVendor(c *gin.Context) {
 	vendorID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid vendor ID"})
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "invalid vendor ID"))
 		return
 	}
 
 	productID, err := strconv.ParseUint(c.Param("productId"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid product ID"})
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "invalid product ID"))
 		return
 	}
 
 	if err := h.vendorUC.RemoveProductFromVendor(c.Request.Context(), uint(vendorID), uint(productID)); err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -283,19 +323,19 @@ func (h *VendorHandler) RemoveProductFromVendor(c *gin.Context) {
 func (h *VendorHandler) CreateContract(c *gin.Context) {
 	vendorID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid vendor ID"})
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "invalid vendor ID"))
 		return
 	}
 
 	var contract entity.Contract
 	if err := c.ShouldBindJSON(&contract); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 	contract.VendorID = uint(vendorID)
 
 	if err := h.vendorUC.CreateContract(c.Request.Context(), &contract); err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -317,19 +357,19 @@ func (h *VendorHandler) CreateContract(c *gin.Context) {
 func (h *VendorHandler) UpdateContract(c *gin.Context) {
 	contractID, err := strconv.ParseUint(c.Param("contractId"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid contract ID"})
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "invalid contract ID"))
 		return
 	}
 
 	var contract entity.Contract
 	if err := c.ShouldBindJSON(&contract); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 	contract.ID = uint(contractID)
 
 	if err := h.vendorUC.UpdateContract(c.Request.Context(), &contract); err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -349,13 +389,13 @@ func (h *VendorHandler) UpdateContract(c *gin.Context) {
 func (h *VendorHandler) GetContract(c *gin.Context) {
 	contractID, err := strconv.ParseUint(c.Param("contractId"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid contract ID"})
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "invalid contract ID"))
 		return
 	}
 
 	contract, err := h.vendorUC.GetContract(c.Request.Context(), uint(contractID))
 	if err != nil {
-		c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeNotFound, err))
 		return
 	}
 
@@ -377,24 +417,20 @@ func (h *VendorHandler) GetContract(c *gin.Context) {
 func (h *VendorHandler) AddRating(c *gin.Context) {
 	vendorID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid vendor ID"})
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "invalid vendor ID"))
 		return
 	}
 
 	var rating entity.VendorRating
 	if err := c.ShouldBindJSON(&rating); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
 		return
 	}
 	rating.VendorID = uint(vendorID)
 	rating.RatedByID = c.GetUint("user_id") // Assuming user_id is set in auth middleware
 
 	if err := h.vendorUC.AddVendorRating(c.Request.Context(), &rating); err != nil {
-		statusCode := http.StatusInternalServerError
-		if err == entity.ErrInvalidRating {
-			statusCode = http.StatusBadRequest
-		}
-		c.JSON(statusCode, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -414,15 +450,85 @@ func (h *VendorHandler) AddRating(c *gin.Context) {
 func (h *VendorHandler) GetRatings(c *gin.Context) {
 	vendorID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid vendor ID"})
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "invalid vendor ID"))
 		return
 	}
 
 	ratings, err := h.vendorUC.GetVendorRatings(c.Request.Context(), uint(vendorID))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
 	c.JSON(http.StatusOK, ratings)
 }
+
+// @Summary Get vendor scorecard
+// @Description Get on-time delivery rate, rejection rate, price variance and average lead time computed from the vendor's purchase orders and receipts
+// @Tags vendors
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Vendor ID"
+// @Param start_date query string false "Order date from (YYYY-MM-DD)"
+// @Param end_date query string false "Order date to (YYYY-MM-DD)"
+// @Success 200 {object} entity.VendorScorecard
+// @Failure 400 {object} ErrorResponse "Invalid vendor ID"
+// @Failure 404 {object} ErrorResponse "Vendor not found"
+// @Router /vendors/{id}/scorecard [get]
+func (h *VendorHandler) GetScorecard(c *gin.Context) {
+	vendorID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "invalid vendor ID"))
+		return
+	}
+
+	scorecard, err := h.vendorUC.GetVendorScorecard(c.Request.Context(), uint(vendorID), parseScorecardFilter(c))
+	if err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeNotFound, err))
+		return
+	}
+
+	c.JSON(http.StatusOK, scorecard)
+}
+
+// @Summary Rank vendors by scorecard
+// @Description Compare vendors with purchase activity in the period, ranked by weighted scorecard score
+// @Tags vendors
+// @Security BearerAuth
+// @Produce json
+// @Param start_date query string false "Order date from (YYYY-MM-DD)"
+// @Param end_date query string false "Order date to (YYYY-MM-DD)"
+// @Param limit query int false "Maximum number of vendors"
+// @Success 200 {array} entity.VendorScorecard
+// @Failure 500 {object} ErrorResponse "Server error"
+// @Router /vendors/scorecards/ranking [get]
+func (h *VendorHandler) RankVendors(c *gin.Context) {
+	filter := parseScorecardFilter(c)
+	if limit, err := strconv.Atoi(c.Query("limit")); err == nil && limit > 0 {
+		filter.Limit = limit
+	}
+
+	ranking, err := h.vendorUC.RankVendors(c.Request.Context(), filter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ranking)
+}
+
+func parseScorecardFilter(c *gin.Context) entity.VendorScorecardFilter {
+	var filter entity.VendorScorecardFilter
+	if startDateStr := c.Query("start_date"); startDateStr != "" {
+		if startDate, err := time.Parse("2006-01-02", startDateStr); err == nil {
+			filter.StartDate = &startDate
+		}
+	}
+	if endDateStr := c.Query("end_date"); endDateStr != "" {
+		if endDate, err := time.Parse("2006-01-02", endDateStr); err == nil {
+			endDate = endDate.Add(24*time.Hour - time.Nanosecond)
+			filter.EndDate = &endDate
+		}
+	}
+	return filter
+}
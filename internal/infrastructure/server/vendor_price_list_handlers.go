@@ -0,0 +1,259 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/server/middleware"
+)
+
+// VendorPriceListHandlers handles vendor price list HTTP requests
+type VendorPriceListHandlers struct {
+	priceListUseCase *usecase.VendorPriceListUseCase
+}
+
+// NewVendorPriceListHandlers creates a new vendor price list handlers instance
+func NewVendorPriceListHandlers(priceListUseCase *usecase.VendorPriceListUseCase) *VendorPriceListHandlers {
+	return &VendorPriceListHandlers{
+		priceListUseCase: priceListUseCase,
+	}
+}
+
+// RegisterRoutes registers vendor price list routes
+func (h *VendorPriceListHandlers) RegisterRoutes(router *gin.RouterGroup) {
+	vendors := router.Group("/vendors")
+	{
+		vendors.POST("/:id/price-lists", middleware.PermissionMiddleware(entity.VendorUpdate), h.CreatePriceList)
+		vendors.GET("/:id/price-lists", middleware.PermissionMiddleware(entity.VendorRead), h.ListPriceLists)
+		vendors.GET("/:id/price", middleware.PermissionMiddleware(entity.VendorRead), h.QuotePrice)
+		vendors.GET("/price-lists/:priceListId", middleware.PermissionMiddleware(entity.VendorRead), h.GetPriceList)
+		vendors.PUT("/price-lists/:priceListId", middleware.PermissionMiddleware(entity.VendorUpdate), h.UpdatePriceList)
+		vendors.DELETE("/price-lists/:priceListId", middleware.PermissionMiddleware(entity.VendorUpdate), h.DeletePriceList)
+	}
+}
+
+// CreatePriceList handles creating a vendor price list
+// @Summary Create vendor price list
+// @Description Create a price list of a vendor with quantity breaks, valid from a date and, optionally, to a date
+// @Tags vendors
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Vendor ID"
+// @Param priceList body entity.VendorPriceListRequest true "Price list details"
+// @Success 201 {object} entity.VendorPriceList
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /vendors/{id}/price-lists [post]
+func (h *VendorPriceListHandlers) CreatePriceList(c *gin.Context) {
+	vendorID, ok := parsePriceListID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req entity.VendorPriceListRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	priceList, err := h.priceListUseCase.CreatePriceList(c.Request.Context(), vendorID, &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, priceList)
+}
+
+// ListPriceLists handles listing the price lists of a vendor
+// @Summary List vendor price lists
+// @Description List the price lists of a vendor without their prices, latest first
+// @Tags vendors
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Vendor ID"
+// @Param valid_on query string false "Only the lists valid on this day (YYYY-MM-DD)"
+// @Success 200 {array} entity.VendorPriceList
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /vendors/{id}/price-lists [get]
+func (h *VendorPriceListHandlers) ListPriceLists(c *gin.Context) {
+	vendorID, ok := parsePriceListID(c, "id")
+	if !ok {
+		return
+	}
+
+	var validOn *time.Time
+	if value := c.Query("valid_on"); value != "" {
+		day, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid valid_on"))
+			return
+		}
+		validOn = &day
+	}
+
+	priceLists, err := h.priceListUseCase.ListPriceLists(c.Request.Context(), vendorID, validOn)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, priceLists)
+}
+
+// QuotePrice handles quoting a vendor's price for a SKU
+// @Summary Quote vendor price
+// @Description Get the unit price a vendor's price lists give a SKU for a quantity on a day: the highest quantity break reached, on the list valid from the latest date
+// @Tags vendors
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Vendor ID"
+// @Param sku_id query string true "SKU ID"
+// @Param quantity query number false "Quantity ordered, defaults to 1"
+// @Param date query string false "Order day (YYYY-MM-DD), defaults to today"
+// @Success 200 {object} entity.VendorPrice
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /vendors/{id}/price [get]
+func (h *VendorPriceListHandlers) QuotePrice(c *gin.Context) {
+	vendorID, ok := parsePriceListID(c, "id")
+	if !ok {
+		return
+	}
+	skuID := c.Query("sku_id")
+	if skuID == "" {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "sku_id is required"))
+		return
+	}
+
+	quantity := 1.0
+	if value := c.Query("quantity"); value != "" {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil || parsed <= 0 {
+			c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid quantity"))
+			return
+		}
+		quantity = parsed
+	}
+	day := time.Now()
+	if value := c.Query("date"); value != "" {
+		parsed, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid date"))
+			return
+		}
+		day = parsed
+	}
+
+	price, err := h.priceListUseCase.QuotePrice(c.Request.Context(), vendorID, skuID, quantity, day)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, price)
+}
+
+// GetPriceList handles getting a vendor price list
+// @Summary Get vendor price list
+// @Description Get a vendor price list with its prices
+// @Tags vendors
+// @Security BearerAuth
+// @Produce json
+// @Param priceListId path int true "Price list ID"
+// @Success 200 {object} entity.VendorPriceList
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /vendors/price-lists/{priceListId} [get]
+func (h *VendorPriceListHandlers) GetPriceList(c *gin.Context) {
+	id, ok := parsePriceListID(c, "priceListId")
+	if !ok {
+		return
+	}
+
+	priceList, err := h.priceListUseCase.GetPriceList(c.Request.Context(), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, priceList)
+}
+
+// UpdatePriceList handles updating a vendor price list
+// @Summary Update vendor price list
+// @Description Replace the name, currency, validity and prices of a vendor price list
+// @Tags vendors
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param priceListId path int true "Price list ID"
+// @Param priceList body entity.VendorPriceListRequest true "Price list details"
+// @Success 200 {object} entity.VendorPriceList
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /vendors/price-lists/{priceListId} [put]
+func (h *VendorPriceListHandlers) UpdatePriceList(c *gin.Context) {
+	id, ok := parsePriceListID(c, "priceListId")
+	if !ok {
+		return
+	}
+
+	var req entity.VendorPriceListRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	priceList, err := h.priceListUseCase.UpdatePriceList(c.Request.Context(), id, &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, priceList)
+}
+
+// DeletePriceList handles deleting a vendor price list
+// @Summary Delete vendor price list
+// @Description Delete a vendor price list; purchase orders priced from it keep their prices
+// @Tags vendors
+// @Security BearerAuth
+// @Param priceListId path int true "Price list ID"
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /vendors/price-lists/{priceListId} [delete]
+func (h *VendorPriceListHandlers) DeletePriceList(c *gin.Context) {
+	id, ok := parsePriceListID(c, "priceListId")
+	if !ok {
+		return
+	}
+
+	if err := h.priceListUseCase.DeletePriceList(c.Request.Context(), id); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func parsePriceListID(c *gin.Context, param string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(param), 10, 32)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid ID"))
+		return 0, false
+	}
+	return uint(id), true
+}
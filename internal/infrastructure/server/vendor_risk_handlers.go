@@ -0,0 +1,227 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/server/middleware"
+)
+
+// VendorRiskHandlers handles vendor risk scoring HTTP requests
+type VendorRiskHandlers struct {
+	riskUseCase *usecase.VendorRiskUseCase
+}
+
+// NewVendorRiskHandlers creates a new vendor risk handlers instance
+func NewVendorRiskHandlers(riskUseCase *usecase.VendorRiskUseCase) *VendorRiskHandlers {
+	return &VendorRiskHandlers{
+		riskUseCase: riskUseCase,
+	}
+}
+
+// RegisterRoutes registers vendor risk routes
+func (h *VendorRiskHandlers) RegisterRoutes(router *gin.RouterGroup) {
+	riskRouter := router.Group("/vendors/risk")
+	{
+		riskRouter.GET("", middleware.PermissionMiddleware(entity.VendorRiskRead), h.ListScores)
+		riskRouter.GET("/at-risk", middleware.PermissionMiddleware(entity.VendorRiskRead), h.ListAtRisk)
+		riskRouter.GET("/single-source", middleware.PermissionMiddleware(entity.VendorRiskRead), h.SingleSourceReport)
+		riskRouter.POST("/run", middleware.PermissionMiddleware(entity.VendorRiskRun), h.RunScoring)
+		riskRouter.GET("/alerts", middleware.PermissionMiddleware(entity.VendorRiskRead), h.ListAlerts)
+		riskRouter.POST("/alerts/:id/acknowledge", middleware.PermissionMiddleware(entity.VendorRiskRun), h.AcknowledgeAlert)
+	}
+}
+
+// ListScores handles listing vendor risk scores
+// @Summary List vendor risk scores
+// @Description List the supply risk scores of vendors, riskiest first
+// @Tags vendors
+// @Security BearerAuth
+// @Produce json
+// @Param level query string false "Risk level (LOW/MEDIUM/HIGH)"
+// @Param min_score query number false "Minimum risk score (0-100)"
+// @Success 200 {array} entity.VendorRiskScore
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /vendors/risk [get]
+func (h *VendorRiskHandlers) ListScores(c *gin.Context) {
+	filter := entity.VendorRiskFilter{
+		Level: entity.VendorRiskLevel(c.Query("level")),
+	}
+	if minScore := c.Query("min_score"); minScore != "" {
+		score, err := strconv.ParseFloat(minScore, 64)
+		if err != nil {
+			c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid min_score"))
+			return
+		}
+		filter.MinScore = score
+	}
+
+	scores, err := h.riskUseCase.ListScores(c.Request.Context(), filter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, scores)
+}
+
+// ListAtRisk handles the at-risk suppliers report
+// @Summary List at-risk suppliers
+// @Description List the vendors whose risk score is at or above the alert threshold
+// @Tags vendors
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} ErrorResponse
+// @Router /vendors/risk/at-risk [get]
+func (h *VendorRiskHandlers) ListAtRisk(c *gin.Context) {
+	scores, err := h.riskUseCase.ListAtRisk(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"threshold": h.riskUseCase.Threshold(),
+		"vendors":   scores,
+	})
+}
+
+// SingleSourceReport handles the single-source dependency report
+// @Summary Single-source dependency report
+// @Description List the SKUs with only one approved vendor, or one vendor ordered from over the lookback window, with the open sales demand against them
+// @Tags vendors
+// @Security BearerAuth
+// @Produce json
+// @Param vendor_id query int false "Vendor ID"
+// @Param with_demand query bool false "Only SKUs with open demand"
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} ErrorResponse
+// @Router /vendors/risk/single-source [get]
+func (h *VendorRiskHandlers) SingleSourceReport(c *gin.Context) {
+	filter := entity.SingleSourceFilter{}
+
+	if vendorID, err := strconv.ParseUint(c.Query("vendor_id"), 10, 32); err == nil {
+		filter.VendorID = uint(vendorID)
+	}
+
+	if withDemand, err := strconv.ParseBool(c.Query("with_demand")); err == nil {
+		filter.WithDemand = withDemand
+	}
+
+	skus, err := h.riskUseCase.SingleSourceReport(c.Request.Context(), filter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"lookback_months": h.riskUseCase.LookbackMonths(),
+		"skus":            skus,
+	})
+}
+
+// RunScoring handles rescoring vendor risk
+// @Summary Run vendor risk scoring
+// @Description Rescore the supply risk of all vendors and raise alerts for vendors that reached the threshold
+// @Tags vendors
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} entity.VendorRiskRunResult
+// @Failure 500 {object} ErrorResponse
+// @Router /vendors/risk/run [post]
+func (h *VendorRiskHandlers) RunScoring(c *gin.Context) {
+	result, err := h.riskUseCase.Run(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// ListAlerts handles listing vendor risk alerts
+// @Summary List vendor risk alerts
+// @Description List the alerts raised when vendors reached the risk threshold
+// @Tags vendors
+// @Security BearerAuth
+// @Produce json
+// @Param vendor_id query int false "Vendor ID"
+// @Param acknowledged query bool false "Only acknowledged (true) or open (false) alerts"
+// @Param page query int false "Page number"
+// @Param page_size query int false "Page size"
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} ErrorResponse
+// @Router /vendors/risk/alerts [get]
+func (h *VendorRiskHandlers) ListAlerts(c *gin.Context) {
+	filter := &entity.VendorRiskAlertFilter{}
+
+	if vendorID, err := strconv.ParseUint(c.Query("vendor_id"), 10, 32); err == nil {
+		filter.VendorID = uint(vendorID)
+	}
+
+	if acknowledged, err := strconv.ParseBool(c.Query("acknowledged")); err == nil {
+		filter.Acknowledged = &acknowledged
+	}
+
+	if page, err := strconv.Atoi(c.Query("page")); err == nil {
+		filter.Page = page
+	}
+
+	if pageSize, err := strconv.Atoi(c.Query("page_size")); err == nil {
+		filter.PageSize = pageSize
+	}
+
+	alerts, total, err := h.riskUseCase.ListAlerts(c.Request.Context(), filter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"alerts":    alerts,
+		"total":     total,
+		"page":      filter.Page,
+		"page_size": filter.PageSize,
+	})
+}
+
+// AcknowledgeAlert handles acknowledging a vendor risk alert
+// @Summary Acknowledge vendor risk alert
+// @Description Mark a vendor risk alert as handled
+// @Tags vendors
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Alert ID"
+// @Success 200 {object} entity.VendorRiskAlert
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /vendors/risk/alerts/{id}/acknowledge [post]
+func (h *VendorRiskHandlers) AcknowledgeAlert(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid alert ID"))
+		return
+	}
+
+	alert, err := h.riskUseCase.AcknowledgeAlert(c.Request.Context(), uint(id), currentUserID(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrVendorRiskAlertNotFound):
+			c.Error(entity.WrapError(entity.ErrCodeNotFound, err))
+		case errors.Is(err, usecase.ErrVendorRiskAlertAcknowledged):
+			c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		default:
+			c.Error(err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, alert)
+}
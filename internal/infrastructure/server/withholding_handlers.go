@@ -0,0 +1,198 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lugondev/erp-warehouse-simple/internal/application/usecase"
+	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/server/middleware"
+)
+
+// WithholdingHandlers handles withholding tax HTTP requests
+type WithholdingHandlers struct {
+	withholdingUseCase *usecase.WithholdingUseCase
+}
+
+// NewWithholdingHandlers creates a new withholding handlers instance
+func NewWithholdingHandlers(withholdingUseCase *usecase.WithholdingUseCase) *WithholdingHandlers {
+	return &WithholdingHandlers{
+		withholdingUseCase: withholdingUseCase,
+	}
+}
+
+// RegisterRoutes registers withholding tax routes
+func (h *WithholdingHandlers) RegisterRoutes(router *gin.RouterGroup) {
+	withholding := router.Group("/finance/withholding")
+	{
+		withholding.POST("/rates", middleware.PermissionMiddleware(entity.FinanceWithholdingManage), h.CreateWithholdingRate)
+		withholding.GET("/rates", middleware.PermissionMiddleware(entity.FinanceWithholdingRead), h.ListWithholdingRates)
+		withholding.GET("/rates/:id", middleware.PermissionMiddleware(entity.FinanceWithholdingRead), h.GetWithholdingRate)
+		withholding.PUT("/rates/:id", middleware.PermissionMiddleware(entity.FinanceWithholdingManage), h.UpdateWithholdingRate)
+		withholding.GET("/certificates", middleware.PermissionMiddleware(entity.FinanceWithholdingRead), h.GetWithholdingCertificates)
+	}
+}
+
+// CreateWithholdingRate handles setting a withholding tax rate
+// @Summary Create withholding tax rate
+// @Description Set the percentage withheld from the payments of purchase invoices issued afterwards, for a vendor or for the vendors of a country. A vendor's own rate takes precedence over its country's.
+// @Tags Finance
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body entity.WithholdingTaxRateRequest true "Withholding tax rate"
+// @Success 201 {object} entity.WithholdingTaxRate
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /finance/withholding/rates [post]
+func (h *WithholdingHandlers) CreateWithholdingRate(c *gin.Context) {
+	var req entity.WithholdingTaxRateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	rate, err := h.withholdingUseCase.CreateRate(c.Request.Context(), &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, rate)
+}
+
+// ListWithholdingRates handles listing withholding tax rates
+// @Summary List withholding tax rates
+// @Description List the active withholding tax rates, the countries' first, or all of them with all=true
+// @Tags Finance
+// @Security BearerAuth
+// @Produce json
+// @Param all query bool false "Include inactive rates"
+// @Success 200 {array} entity.WithholdingTaxRate
+// @Failure 500 {object} ErrorResponse
+// @Router /finance/withholding/rates [get]
+func (h *WithholdingHandlers) ListWithholdingRates(c *gin.Context) {
+	all, _ := strconv.ParseBool(c.Query("all"))
+
+	rates, err := h.withholdingUseCase.ListRates(c.Request.Context(), all)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rates)
+}
+
+// GetWithholdingRate handles getting a withholding tax rate
+// @Summary Get withholding tax rate
+// @Description Get a withholding tax rate with its vendor
+// @Tags Finance
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Withholding tax rate ID"
+// @Success 200 {object} entity.WithholdingTaxRate
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /finance/withholding/rates/{id} [get]
+func (h *WithholdingHandlers) GetWithholdingRate(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid withholding tax rate ID"))
+		return
+	}
+
+	rate, err := h.withholdingUseCase.GetRate(c.Request.Context(), uint(id))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rate)
+}
+
+// UpdateWithholdingRate handles updating a withholding tax rate
+// @Summary Update withholding tax rate
+// @Description Change a withholding tax rate, or deactivate it with active=false. Invoices already issued keep the rate they were issued with.
+// @Tags Finance
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Withholding tax rate ID"
+// @Param request body entity.WithholdingTaxRateRequest true "Withholding tax rate"
+// @Success 200 {object} entity.WithholdingTaxRate
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /finance/withholding/rates/{id} [put]
+func (h *WithholdingHandlers) UpdateWithholdingRate(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid withholding tax rate ID"))
+		return
+	}
+
+	var req entity.WithholdingTaxRateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(entity.WrapError(entity.ErrCodeInvalidArgument, err))
+		return
+	}
+
+	rate, err := h.withholdingUseCase.UpdateRate(c.Request.Context(), uint(id), &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rate)
+}
+
+// GetWithholdingCertificates handles the withholding certificates of a period
+// @Summary Get withholding certificates
+// @Description State the tax withheld from the completed payments to each vendor, or to one vendor, between two dates, with the payments, in the base currency
+// @Tags Finance
+// @Security BearerAuth
+// @Produce json
+// @Param vendor_id query int false "Vendor ID"
+// @Param start_date query string true "Start date (YYYY-MM-DD)"
+// @Param end_date query string true "End date (YYYY-MM-DD), included"
+// @Success 200 {array} entity.WithholdingCertificate
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /finance/withholding/certificates [get]
+func (h *WithholdingHandlers) GetWithholdingCertificates(c *gin.Context) {
+	startDate, err := time.Parse("2006-01-02", c.Query("start_date"))
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid start date format. Use YYYY-MM-DD"))
+		return
+	}
+	endDate, err := time.Parse("2006-01-02", c.Query("end_date"))
+	if err != nil {
+		c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid end date format. Use YYYY-MM-DD"))
+		return
+	}
+
+	var vendorID *uint
+	if c.Query("vendor_id") != "" {
+		id, err := strconv.ParseUint(c.Query("vendor_id"), 10, 32)
+		if err != nil {
+			c.Error(entity.NewError(entity.ErrCodeInvalidArgument, "Invalid vendor ID"))
+			return
+		}
+		vendor := uint(id)
+		vendorID = &vendor
+	}
+
+	certificates, err := h.withholdingUseCase.GetCertificates(c.Request.Context(), vendorID, startDate, endDate)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, certificates)
+}
@@ -1,20 +1,47 @@
 package service
 
 import (
+	"compress/gzip"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"io"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/lugondev/erp-warehouse-simple/internal/domain/entity"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/auth"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/filestore"
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/logging"
 )
 
+// maxAuditLogExportRows caps the rows written by a single audit log export
+const maxAuditLogExportRows = 50000
+
+// auditChainBatchSize is the number of logs read at a time when archiving or
+// verifying the hash chain
+const auditChainBatchSize = 1000
+
+// maxAuditChainBreaks caps the breaks listed by a chain verification; all of
+// them are counted
+const maxAuditChainBreaks = 100
+
 type AuditService struct {
-	repo entity.AuditLogRepository
+	repo          entity.AuditLogRepository
+	files         filestore.Store
+	retentionDays int           // days audit logs are kept in the database, 0 to keep them
+	fileURLTTL    time.Duration // how long archive download URLs stay valid
 }
 
-func NewAuditService(repo entity.AuditLogRepository) *AuditService {
-	return &AuditService{repo: repo}
+func NewAuditService(repo entity.AuditLogRepository, files filestore.Store, retentionDays int, fileURLTTL time.Duration) *AuditService {
+	return &AuditService{
+		repo:          repo,
+		files:         files,
+		retentionDays: retentionDays,
+		fileURLTTL:    fileURLTTL,
+	}
 }
 
 // LogUserAction creates an audit log entry for a user action
@@ -33,6 +60,10 @@ func (s *AuditService) LogUserAction(ctx context.Context, userID uint, action en
 		UserAgent: c.Request.UserAgent(),
 		CreatedAt: time.Now(),
 	}
+	// Requests made in an impersonation session carry both identities
+	if impersonatorID := auth.GetImpersonatorIDFromContext(c); impersonatorID != 0 {
+		log.ImpersonatorID = &impersonatorID
+	}
 
 	return s.repo.Create(log)
 }
@@ -66,22 +97,252 @@ func (s *AuditService) GetAuditLogsCount(filter map[string]interface{}) (int64,
 	return s.repo.Count(filter)
 }
 
+// SearchAuditLogs retrieves the audit logs matching a filter with pagination
+func (s *AuditService) SearchAuditLogs(filter *entity.AuditLogFilter) ([]entity.AuditLog, int64, error) {
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+	if filter.PageSize <= 0 {
+		filter.PageSize = 10
+	}
+	return s.repo.Search(filter)
+}
+
+// SearchAuditLogsPage retrieves the page of audit logs matching a filter that
+// follows a cursor, with the cursor of the next page when there is one
+func (s *AuditService) SearchAuditLogsPage(filter *entity.AuditLogFilter, page entity.CursorPage) ([]entity.AuditLog, *entity.Cursor, error) {
+	return s.repo.SearchPage(filter, page)
+}
+
+// ExportAuditLogs retrieves the audit logs matching a filter for export, latest
+// first and at most maxAuditLogExportRows of them. The total counts every match.
+func (s *AuditService) ExportAuditLogs(filter *entity.AuditLogFilter) ([]entity.AuditLog, int64, error) {
+	filter.Page = 1
+	filter.PageSize = maxAuditLogExportRows
+	return s.repo.Search(filter)
+}
+
+// RunRetention exports the audit logs, live and archived, written before the
+// retention days to the file store as gzipped JSON lines, oldest first, and
+// removes them from the database. The archive keeps the hash of the last log
+// exported, so the chain of the logs kept can still be verified.
+func (s *AuditService) RunRetention(ctx context.Context) (*entity.AuditRetentionResult, error) {
+	if s.retentionDays <= 0 {
+		return nil, entity.NewError(entity.ErrCodeFailedPrecondition, "Audit log retention is not configured")
+	}
+
+	now := time.Now()
+	cutoff := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()).AddDate(0, 0, -s.retentionDays)
+	result := &entity.AuditRetentionResult{Cutoff: cutoff}
+
+	count, err := s.repo.CountBefore(ctx, cutoff)
+	if err != nil {
+		return nil, fmt.Errorf("error counting expired audit logs: %w", err)
+	}
+	if count == 0 {
+		return result, nil
+	}
+
+	archive := &entity.AuditLogArchive{Cutoff: cutoff}
+	checksum := sha256.New()
+
+	// Logs are written while the store reads them, so they are never held in
+	// memory as a whole
+	pr, pw := io.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		gz := gzip.NewWriter(io.MultiWriter(pw, checksum))
+		enc := json.NewEncoder(gz)
+		var afterID uint
+		for {
+			logs, err := s.repo.ChainRows(ctx, time.Time{}, cutoff.Add(-time.Nanosecond), afterID, auditChainBatchSize)
+			if err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+			for i := range logs {
+				if err := enc.Encode(&logs[i]); err != nil {
+					pw.CloseWithError(err)
+					return
+				}
+				if archive.Rows == 0 {
+					archive.FirstID = logs[i].ID
+					archive.FirstCreatedAt = logs[i].CreatedAt
+				}
+				archive.LastID = logs[i].ID
+				archive.LastCreatedAt = logs[i].CreatedAt
+				archive.LastHash = logs[i].Hash
+				archive.Rows++
+			}
+			if len(logs) < auditChainBatchSize {
+				break
+			}
+			afterID = logs[len(logs)-1].ID
+		}
+		pw.CloseWithError(gz.Close())
+	}()
+
+	key := fmt.Sprintf("audit/audit-logs-%s-%s.jsonl.gz", cutoff.Format("20060102"), now.Format("20060102-150405"))
+	err = s.files.Put(ctx, key, "application/gzip", pr)
+	pr.CloseWithError(err)
+	<-done
+	if err != nil {
+		return nil, fmt.Errorf("error storing audit log archive: %w", err)
+	}
+	if archive.Rows == 0 {
+		s.files.Delete(context.WithoutCancel(ctx), key)
+		return result, nil
+	}
+
+	archive.FileKey = key
+	archive.Checksum = hex.EncodeToString(checksum.Sum(nil))
+	if err := s.repo.CreateArchive(ctx, archive); err != nil {
+		s.files.Delete(context.WithoutCancel(ctx), key)
+		return nil, fmt.Errorf("error recording audit log archive: %w", err)
+	}
+
+	// Only the logs exported are removed, should any have been written
+	// before the cutoff since
+	deleted, err := s.repo.DeleteBefore(ctx, cutoff, archive.LastID)
+	if err != nil {
+		return nil, fmt.Errorf("error removing exported audit logs: %w", err)
+	}
+	result.Exported = deleted
+	result.Archive = archive
+	s.signArchiveURL(ctx, archive)
+	return result, nil
+}
+
+// VerifyChain checks the hash chain of the audit logs, live and archived,
+// written in a period, all of them by default. Each chained log must match
+// its hash and follow the hash of the log before it, which may have been
+// exported; logs written before chaining are counted but cannot be verified.
+func (s *AuditService) VerifyChain(ctx context.Context, startDate, endDate *time.Time) (*entity.AuditChainVerification, error) {
+	if startDate != nil && endDate != nil && endDate.Before(*startDate) {
+		return nil, entity.NewError(entity.ErrCodeInvalidArgument, "End date must not be before start date")
+	}
+
+	verification := &entity.AuditChainVerification{
+		StartDate:  startDate,
+		EndDate:    endDate,
+		Breaks:     []entity.AuditChainBreak{},
+		VerifiedAt: time.Now(),
+	}
+	start := time.Time{}
+	if startDate != nil {
+		start = *startDate
+	}
+	end := verification.VerifiedAt
+	if endDate != nil {
+		end = *endDate
+	}
+
+	addBreak := func(logID uint, reason string) {
+		verification.BreakCount++
+		if len(verification.Breaks) < maxAuditChainBreaks {
+			verification.Breaks = append(verification.Breaks, entity.AuditChainBreak{LogID: logID, Reason: reason})
+		}
+	}
+
+	var prevHash string
+	chained := false
+	var afterID uint
+	for {
+		logs, err := s.repo.ChainRows(ctx, start, end, afterID, auditChainBatchSize)
+		if err != nil {
+			return nil, fmt.Errorf("error reading audit logs: %w", err)
+		}
+		for i := range logs {
+			log := &logs[i]
+			if verification.Checked == 0 {
+				verification.FirstID = log.ID
+				hash, found, err := s.repo.PreviousHash(ctx, log.ID)
+				if err != nil {
+					return nil, fmt.Errorf("error reading previous audit log: %w", err)
+				}
+				prevHash = hash
+				chained = found && hash != ""
+			}
+			verification.Checked++
+			verification.LastID = log.ID
+
+			if log.Hash == "" {
+				if chained {
+					addBreak(log.ID, "log has no hash but follows chained logs")
+				} else {
+					verification.Unhashed++
+				}
+				prevHash = ""
+				continue
+			}
+			if log.PrevHash != prevHash {
+				addBreak(log.ID, "previous hash does not match the log before it, which was removed or changed")
+			}
+			if log.ChainHash() != log.Hash {
+				addBreak(log.ID, "log content does not match its hash")
+			}
+			prevHash = log.Hash
+			chained = true
+		}
+		if len(logs) < auditChainBatchSize {
+			break
+		}
+		afterID = logs[len(logs)-1].ID
+	}
+
+	verification.Valid = verification.BreakCount == 0
+	return verification, nil
+}
+
+// ListArchives lists the archives of exported audit logs, latest first, with
+// a download URL for each
+func (s *AuditService) ListArchives(ctx context.Context, page, pageSize int) ([]entity.AuditLogArchive, int64, error) {
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 10
+	}
+	archives, total, err := s.repo.ListArchives(ctx, page, pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i := range archives {
+		s.signArchiveURL(ctx, &archives[i])
+	}
+	return archives, total, nil
+}
+
+// signArchiveURL sets the download URL of an audit log archive
+func (s *AuditService) signArchiveURL(ctx context.Context, archive *entity.AuditLogArchive) {
+	fileURL, err := s.files.URL(ctx, archive.FileKey, s.fileURLTTL)
+	if err != nil {
+		logging.FromContext(ctx).Error("error signing audit log archive URL", "archive_id", archive.ID, "error", err)
+		return
+	}
+	archive.DownloadURL = fileURL
+}
+
+// OnBehalfOfKey holds the user a request acted for, such as the approver away
+// a delegate approved for, recorded in the audit log with the user
+const OnBehalfOfKey = "on_behalf_of"
+
 // CreateAuditLogMiddleware creates a middleware that logs user actions
 func CreateAuditLogMiddleware(auditService *AuditService) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		userID, exists := c.Get("user_id")
-		if !exists {
-			c.Next()
-			return
-		}
-
 		// Get the start time
 		start := time.Now()
 
 		// Process request
 		c.Next()
 
-		// After request
+		// After request; the user is only known once the route's
+		// authentication has run
+		userID, exists := c.Get("user_id")
+		if !exists {
+			return
+		}
 		latency := time.Since(start)
 
 		// Determine action type based on request method
@@ -104,6 +365,9 @@ func CreateAuditLogMiddleware(auditService *AuditService) gin.HandlerFunc {
 			c.Writer.Status(),
 			latency,
 		)
+		if onBehalfOf, ok := c.Get(OnBehalfOfKey); ok {
+			detail += fmt.Sprintf(", On behalf of: %v", onBehalfOf)
+		}
 
 		_ = auditService.LogUserAction(c,
 			userID.(uint),
@@ -0,0 +1,87 @@
+package tracing
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+const gormSpanKey = "tracing:span"
+
+// gormPlugin records a client span of every query GORM runs with the context
+// of a traced request, e.g. through db.WithContext(ctx)
+type gormPlugin struct{}
+
+// GORMPlugin returns the plugin tracing GORM queries, for db.Use
+func GORMPlugin() gorm.Plugin {
+	return gormPlugin{}
+}
+
+func (gormPlugin) Name() string {
+	return "tracing"
+}
+
+func (p gormPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	hooks := []struct {
+		operation string
+		before    func(name string, fn func(*gorm.DB)) error
+		after     func(name string, fn func(*gorm.DB)) error
+	}{
+		{"create", cb.Create().Before("gorm:create").Register, cb.Create().After("gorm:create").Register},
+		{"query", cb.Query().Before("gorm:query").Register, cb.Query().After("gorm:query").Register},
+		{"update", cb.Update().Before("gorm:update").Register, cb.Update().After("gorm:update").Register},
+		{"delete", cb.Delete().Before("gorm:delete").Register, cb.Delete().After("gorm:delete").Register},
+		{"row", cb.Row().Before("gorm:row").Register, cb.Row().After("gorm:row").Register},
+		{"raw", cb.Raw().Before("gorm:raw").Register, cb.Raw().After("gorm:raw").Register},
+	}
+	for _, hook := range hooks {
+		if err := hook.before("tracing:before_"+hook.operation, p.before(hook.operation)); err != nil {
+			return err
+		}
+		if err := hook.after("tracing:after_"+hook.operation, p.after); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (gormPlugin) before(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		ctx := db.Statement.Context
+		// Only queries of a traced request or job are recorded, leaving out
+		// the tickers' background queries
+		if ctx == nil || !SpanContextFromContext(ctx).TraceID.IsValid() {
+			return
+		}
+		name := "db " + operation
+		if db.Statement.Table != "" {
+			name += " " + db.Statement.Table
+		}
+		_, span := Start(ctx, name, SpanKindClient)
+		if span == nil {
+			return
+		}
+		span.SetAttribute("db.system", db.Dialector.Name())
+		span.SetAttribute("db.operation.name", operation)
+		if db.Statement.Table != "" {
+			span.SetAttribute("db.collection.name", db.Statement.Table)
+		}
+		db.InstanceSet(gormSpanKey, span)
+	}
+}
+
+func (gormPlugin) after(db *gorm.DB) {
+	value, ok := db.InstanceGet(gormSpanKey)
+	if !ok {
+		return
+	}
+	span := value.(*Span)
+	// The statement is rendered with placeholders, without the values bound to them
+	span.SetAttribute("db.query.text", db.Statement.SQL.String())
+	span.SetAttribute("db.response.returned_rows", db.Statement.RowsAffected)
+	if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+		span.SetError(db.Error)
+	}
+	span.End()
+}
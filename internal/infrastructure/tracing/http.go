@@ -0,0 +1,87 @@
+package tracing
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Middleware records a server span of every request, continuing the trace of
+// the caller's traceparent header. Handlers reach the span through the
+// request context, so the queries and calls they make become its children.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !Enabled() {
+			c.Next()
+			return
+		}
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched route"
+		}
+		ctx := Extract(c.Request.Context(), c.Request.Header)
+		ctx, span := Start(ctx, c.Request.Method+" "+route, SpanKindServer)
+		defer span.End()
+		c.Request = c.Request.WithContext(ctx)
+
+		span.SetAttribute("http.request.method", c.Request.Method)
+		span.SetAttribute("http.route", route)
+		span.SetAttribute("url.path", c.Request.URL.Path)
+		span.SetAttribute("client.address", c.ClientIP())
+
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttribute("http.response.status_code", status)
+		if userID, ok := c.Get("user_id"); ok {
+			span.SetAttribute("enduser.id", fmt.Sprint(userID))
+		}
+		if status >= http.StatusInternalServerError {
+			span.SetError(fmt.Errorf("%d %s", status, http.StatusText(status)))
+		}
+	}
+}
+
+// transport records a client span of every outbound request and passes its
+// trace context on in the traceparent header
+type transport struct {
+	base http.RoundTripper
+}
+
+// Transport wraps base, or http.DefaultTransport when nil, to trace the
+// requests sent through it. Requests must carry the context of their caller's
+// span, e.g. from http.NewRequestWithContext.
+func Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{base: base}
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, span := Start(req.Context(), "HTTP "+req.Method, SpanKindClient)
+	if span == nil {
+		return t.base.RoundTrip(req)
+	}
+	defer span.End()
+
+	// A RoundTripper must not change the caller's request
+	req = req.Clone(ctx)
+	Inject(ctx, req.Header)
+	span.SetAttribute("http.request.method", req.Method)
+	span.SetAttribute("url.full", req.URL.Redacted())
+	span.SetAttribute("server.address", req.URL.Host)
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		span.SetError(err)
+		return nil, err
+	}
+	span.SetAttribute("http.response.status_code", resp.StatusCode)
+	if resp.StatusCode >= http.StatusInternalServerError {
+		span.SetError(fmt.Errorf("%d %s", resp.StatusCode, http.StatusText(resp.StatusCode)))
+	}
+	return resp, nil
+}
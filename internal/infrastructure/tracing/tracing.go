@@ -0,0 +1,275 @@
+// Package tracing records OpenTelemetry spans of requests, database queries
+// and outbound calls, propagates them with W3C trace context headers and
+// exports them to a collector over OTLP/HTTP.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/lugondev/erp-warehouse-simple/internal/infrastructure/config"
+)
+
+// SpanKind tells how a span relates to the other spans of its trace, with
+// the values of the OTLP protocol
+type SpanKind int
+
+const (
+	SpanKindInternal SpanKind = 1
+	SpanKindServer   SpanKind = 2
+	SpanKindClient   SpanKind = 3
+)
+
+// statusError is the OTLP status code of a failed span
+const statusError = 2
+
+// Config configures tracing
+type Config struct {
+	Enabled        bool
+	Endpoint       string        // base URL of the OTLP/HTTP collector, e.g. http://localhost:4318
+	ServiceName    string        // reported as the service.name resource attribute
+	SampleRatio    float64       // share of new traces recorded, from 0 to 1; traces continued from a caller follow its decision
+	ExportInterval time.Duration // how often finished spans are sent
+}
+
+// TraceID identifies a trace across services
+type TraceID [16]byte
+
+// SpanID identifies a span within its trace
+type SpanID [8]byte
+
+func (t TraceID) String() string { return hex.EncodeToString(t[:]) }
+func (s SpanID) String() string  { return hex.EncodeToString(s[:]) }
+
+func (t TraceID) IsValid() bool { return t != TraceID{} }
+func (s SpanID) IsValid() bool  { return s != SpanID{} }
+
+// SpanContext is the part of a span propagated to its children, within the
+// process and to other services
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+}
+
+// Span is an operation of a trace. Unsampled spans are only propagated, and
+// every method of a nil Span does nothing.
+type Span struct {
+	mu         sync.Mutex
+	context    SpanContext
+	parent     SpanID
+	name       string
+	kind       SpanKind
+	start      time.Time
+	end        time.Time
+	attributes map[string]interface{}
+	status     int
+	message    string
+	ended      bool
+}
+
+// SetAttribute records a string, bool, integer or float attribute of the span
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil || !s.context.Sampled {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attributes[key] = value
+}
+
+// SetError marks the span as failed with err
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil || !s.context.Sampled {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status = statusError
+	s.message = err.Error()
+}
+
+// End finishes the span and queues it for export
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	s.mu.Unlock()
+
+	if s.context.Sampled {
+		if t := current(); t != nil {
+			t.exporter.enqueue(s)
+		}
+	}
+}
+
+// Context returns what is propagated of the span
+func (s *Span) Context() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.context
+}
+
+// tracer starts spans for a service
+type tracer struct {
+	sampleBound uint64 // new traces whose ID falls below it are sampled
+	exporter    *exporter
+}
+
+var (
+	globalMu sync.RWMutex
+	global   *tracer
+)
+
+func current() *tracer {
+	globalMu.RLock()
+	defer globalMu.RUnlock()
+	return global
+}
+
+// Init starts tracing with cfg. Until it is called, or when tracing is
+// disabled, Start returns nil spans and nothing is recorded.
+func Init(cfg Config) error {
+	if !cfg.Enabled {
+		return nil
+	}
+	if cfg.Endpoint == "" {
+		return fmt.Errorf("tracing is enabled without a collector endpoint")
+	}
+	if cfg.SampleRatio < 0 || cfg.SampleRatio > 1 {
+		return fmt.Errorf("tracing sample ratio must be between 0 and 1")
+	}
+	if cfg.ExportInterval <= 0 {
+		cfg.ExportInterval = 5 * time.Second
+	}
+
+	t := &tracer{
+		sampleBound: uint64(cfg.SampleRatio * (1 << 63)),
+		exporter:    newExporter(cfg.Endpoint, cfg.ServiceName, cfg.ExportInterval),
+	}
+	if cfg.SampleRatio >= 1 {
+		t.sampleBound = 1 << 63
+	}
+
+	globalMu.Lock()
+	previous := global
+	global = t
+	globalMu.Unlock()
+	if previous != nil {
+		previous.exporter.shutdown(context.Background())
+	}
+	return nil
+}
+
+// Enabled tells whether spans are recorded
+func Enabled() bool {
+	return current() != nil
+}
+
+// Shutdown exports the spans still queued and stops tracing
+func Shutdown(ctx context.Context) error {
+	globalMu.Lock()
+	t := global
+	global = nil
+	globalMu.Unlock()
+	if t == nil {
+		return nil
+	}
+	return t.exporter.shutdown(ctx)
+}
+
+type spanKey struct{}
+
+// Start starts a span named name as a child of the span in ctx, or of the
+// remote span Extract put there, and returns a context holding it. The span
+// is nil when tracing is not enabled.
+func Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	t := current()
+	if t == nil {
+		return ctx, nil
+	}
+
+	parent := SpanContextFromContext(ctx)
+	span := &Span{
+		name:       name,
+		kind:       kind,
+		start:      time.Now(),
+		attributes: make(map[string]interface{}),
+	}
+	span.context.SpanID = newSpanID()
+	if parent.TraceID.IsValid() {
+		span.context.TraceID = parent.TraceID
+		span.context.Sampled = parent.Sampled
+		span.parent = parent.SpanID
+	} else {
+		span.context.TraceID = newTraceID()
+		// Sample by the random low half of the trace ID, so that every
+		// service would decide alike for the same trace
+		span.context.Sampled = binary.BigEndian.Uint64(span.context.TraceID[8:])>>1 < t.sampleBound
+	}
+	return context.WithValue(ctx, spanKey{}, span.context), span
+}
+
+// SpanContextFromContext returns the context of the span in ctx, which is
+// zero when there is none
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	sc, _ := ctx.Value(spanKey{}).(SpanContext)
+	return sc
+}
+
+// TraceIDFromContext returns the hex trace ID of the span in ctx, or an empty
+// string when there is none
+func TraceIDFromContext(ctx context.Context) string {
+	sc := SpanContextFromContext(ctx)
+	if !sc.TraceID.IsValid() {
+		return ""
+	}
+	return sc.TraceID.String()
+}
+
+// ContextWithRemoteSpan returns a context whose spans continue the trace of a
+// span of another service
+func ContextWithRemoteSpan(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, spanKey{}, sc)
+}
+
+func newTraceID() TraceID {
+	var id TraceID
+	for !id.IsValid() {
+		_, _ = rand.Read(id[:])
+	}
+	return id
+}
+
+func newSpanID() SpanID {
+	var id SpanID
+	for !id.IsValid() {
+		_, _ = rand.Read(id[:])
+	}
+	return id
+}
+
+// InitFromConfig starts tracing for a service with the tracing section of the
+// configuration
+func InitFromConfig(cfg config.TracingConfig, service string) error {
+	return Init(Config{
+		Enabled:        cfg.Enabled,
+		Endpoint:       cfg.Endpoint,
+		ServiceName:    service,
+		SampleRatio:    cfg.SampleRatio,
+		ExportInterval: time.Duration(cfg.ExportIntervalSeconds) * time.Second,
+	})
+}
@@ -0,0 +1,328 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// collector is an OTLP/HTTP collector stub recording the export requests it
+// receives as decoded JSON
+type collector struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests []map[string]interface{}
+	status   int
+}
+
+func newCollector(t *testing.T) *collector {
+	t.Helper()
+	c := &collector{status: http.StatusOK}
+	c.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v1/traces" {
+			t.Errorf("collector got %s %s, want POST /v1/traces", r.Method, r.URL.Path)
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Content-Type = %q, want application/json", ct)
+		}
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decoding export request: %v", err)
+		}
+
+		c.mu.Lock()
+		c.requests = append(c.requests, body)
+		status := c.status
+		c.mu.Unlock()
+		w.WriteHeader(status)
+		if status >= http.StatusBadRequest {
+			io.WriteString(w, "collector unavailable\n")
+		}
+	}))
+	t.Cleanup(c.Close)
+	return c
+}
+
+// spans returns the spans of every export request received, by name
+func (c *collector) spans(t *testing.T) map[string]map[string]interface{} {
+	t.Helper()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	spans := make(map[string]map[string]interface{})
+	for _, req := range c.requests {
+		for _, rs := range req["resourceSpans"].([]interface{}) {
+			for _, ss := range rs.(map[string]interface{})["scopeSpans"].([]interface{}) {
+				for _, s := range ss.(map[string]interface{})["spans"].([]interface{}) {
+					span := s.(map[string]interface{})
+					spans[span["name"].(string)] = span
+				}
+			}
+		}
+	}
+	return spans
+}
+
+func (c *collector) requestCount() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.requests)
+}
+
+// startTracing traces to the collector, exporting only on shutdown
+func startTracing(t *testing.T, c *collector, sampleRatio float64) {
+	t.Helper()
+	if err := Init(Config{
+		Enabled:        true,
+		Endpoint:       c.URL + "/",
+		ServiceName:    "erp-test",
+		SampleRatio:    sampleRatio,
+		ExportInterval: time.Hour,
+	}); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	t.Cleanup(func() { Shutdown(context.Background()) })
+}
+
+// attribute returns the OTLP value of a span or resource attribute
+func attribute(attributes interface{}, key string) map[string]interface{} {
+	list, _ := attributes.([]interface{})
+	for _, a := range list {
+		attr := a.(map[string]interface{})
+		if attr["key"] == key {
+			return attr["value"].(map[string]interface{})
+		}
+	}
+	return nil
+}
+
+// Spans of a request are exported as OTLP JSON, continuing the caller's trace
+func TestExportWireFormat(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c := newCollector(t)
+	startTracing(t, c, 1)
+
+	router := gin.New()
+	router.Use(Middleware())
+	router.GET("/items/:id", func(ctx *gin.Context) {
+		_, span := Start(ctx.Request.Context(), "load item", SpanKindInternal)
+		span.SetAttribute("item.count", 3)
+		span.SetAttribute("item.cached", true)
+		span.SetAttribute("item.weight", 1.5)
+		span.SetError(errors.New("item is locked"))
+		span.End()
+		ctx.Status(http.StatusServiceUnavailable)
+	})
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(http.MethodGet, "/items/42", nil)
+	req.Header.Set(TraceparentHeader, "00-"+traceID+"-00f067aa0ba902b7-01")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	if err := Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	if n := c.requestCount(); n != 1 {
+		t.Fatalf("collector got %d requests, want 1", n)
+	}
+
+	c.mu.Lock()
+	resource := c.requests[0]["resourceSpans"].([]interface{})[0].(map[string]interface{})["resource"].(map[string]interface{})
+	c.mu.Unlock()
+	if v := attribute(resource["attributes"], "service.name"); v == nil || v["stringValue"] != "erp-test" {
+		t.Errorf("service.name = %v, want erp-test", v)
+	}
+
+	spans := c.spans(t)
+	server, child := spans["GET /items/:id"], spans["load item"]
+	if server == nil || child == nil {
+		t.Fatalf("spans = %v, want the request and its child", spans)
+	}
+	if server["traceId"] != traceID || child["traceId"] != traceID {
+		t.Errorf("trace IDs = %v, %v, want %s", server["traceId"], child["traceId"], traceID)
+	}
+	if server["parentSpanId"] != "00f067aa0ba902b7" {
+		t.Errorf("request parentSpanId = %v, want the caller's span", server["parentSpanId"])
+	}
+	if child["parentSpanId"] != server["spanId"] {
+		t.Errorf("child parentSpanId = %v, want %v", child["parentSpanId"], server["spanId"])
+	}
+	if id, _ := server["spanId"].(string); len(id) != 16 || strings.ToLower(id) != id {
+		t.Errorf("spanId = %q, want 16 lowercase hex digits", id)
+	}
+	if server["kind"] != float64(SpanKindServer) || child["kind"] != float64(SpanKindInternal) {
+		t.Errorf("kinds = %v, %v, want %d, %d", server["kind"], child["kind"], SpanKindServer, SpanKindInternal)
+	}
+	for _, field := range []string{"startTimeUnixNano", "endTimeUnixNano"} {
+		if _, ok := server[field].(string); !ok {
+			t.Errorf("%s = %v, want a decimal string", field, server[field])
+		}
+	}
+
+	// Integers are strings in OTLP JSON, the other scalars JSON values
+	if v := attribute(child["attributes"], "item.count"); v == nil || v["intValue"] != "3" {
+		t.Errorf("item.count = %v, want intValue \"3\"", v)
+	}
+	if v := attribute(child["attributes"], "item.cached"); v == nil || v["boolValue"] != true {
+		t.Errorf("item.cached = %v, want boolValue true", v)
+	}
+	if v := attribute(child["attributes"], "item.weight"); v == nil || v["doubleValue"] != 1.5 {
+		t.Errorf("item.weight = %v, want doubleValue 1.5", v)
+	}
+	if v := attribute(server["attributes"], "http.response.status_code"); v == nil || v["intValue"] != "503" {
+		t.Errorf("http.response.status_code = %v, want intValue \"503\"", v)
+	}
+	if status := child["status"].(map[string]interface{}); status["code"] != float64(statusError) || status["message"] != "item is locked" {
+		t.Errorf("child status = %v, want error item is locked", status)
+	}
+	if status := server["status"].(map[string]interface{}); status["code"] != float64(statusError) {
+		t.Errorf("request status = %v, want error", status)
+	}
+}
+
+// Spans are sent in batches of at most maxBatchSpans
+func TestExportBatches(t *testing.T) {
+	c := newCollector(t)
+	startTracing(t, c, 1)
+
+	for i := 0; i < maxBatchSpans+10; i++ {
+		_, span := Start(context.Background(), "job", SpanKindInternal)
+		span.End()
+	}
+	if err := Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	if n := c.requestCount(); n != 2 {
+		t.Errorf("collector got %d requests, want 2", n)
+	}
+}
+
+// A collector error is reported with its answer, and later exports go on
+func TestExportCollectorError(t *testing.T) {
+	c := newCollector(t)
+	c.status = http.StatusServiceUnavailable
+	e := &exporter{url: c.URL + "/v1/traces", service: "erp-test", client: c.Client()}
+
+	span := &Span{context: SpanContext{TraceID: newTraceID(), SpanID: newSpanID(), Sampled: true}, name: "job", attributes: map[string]interface{}{}}
+	err := e.send([]*Span{span})
+	if err == nil || !strings.Contains(err.Error(), "503") || !strings.Contains(err.Error(), "collector unavailable") {
+		t.Errorf("send() error = %v, want the collector's answer", err)
+	}
+
+	c.mu.Lock()
+	c.status = http.StatusOK
+	c.mu.Unlock()
+	if err := e.send([]*Span{span}); err != nil {
+		t.Errorf("send() after recovery error = %v", err)
+	}
+	if n := c.requestCount(); n != 2 {
+		t.Errorf("collector got %d requests, want 2", n)
+	}
+}
+
+// Unsampled new traces are not exported, while traces continued from a
+// caller follow its sampling decision
+func TestSampling(t *testing.T) {
+	c := newCollector(t)
+	startTracing(t, c, 0)
+
+	_, dropped := Start(context.Background(), "unsampled", SpanKindInternal)
+	dropped.End()
+	sampled, _ := parseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	_, kept := Start(ContextWithRemoteSpan(context.Background(), sampled), "sampled by caller", SpanKindServer)
+	kept.End()
+
+	if err := Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	spans := c.spans(t)
+	if _, ok := spans["unsampled"]; ok {
+		t.Error("unsampled span was exported")
+	}
+	if _, ok := spans["sampled by caller"]; !ok {
+		t.Error("span of a sampled caller was not exported")
+	}
+}
+
+// Outbound requests carry the traceparent of their client span
+func TestTransportInjectsTraceparent(t *testing.T) {
+	c := newCollector(t)
+	startTracing(t, c, 1)
+
+	var got string
+	downstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get(TraceparentHeader)
+	}))
+	defer downstream.Close()
+
+	ctx, parent := Start(context.Background(), "caller", SpanKindInternal)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, downstream.URL, nil)
+	resp, err := (&http.Client{Transport: Transport(nil)}).Do(req)
+	if err != nil {
+		t.Fatalf("request error = %v", err)
+	}
+	resp.Body.Close()
+	parent.End()
+
+	sc, ok := parseTraceparent(got)
+	if !ok {
+		t.Fatalf("traceparent = %q, want a valid header", got)
+	}
+	if sc.TraceID != parent.Context().TraceID || sc.SpanID == parent.Context().SpanID || !sc.Sampled {
+		t.Errorf("traceparent = %q, want a sampled child of the caller's trace", got)
+	}
+	if req.Header.Get(TraceparentHeader) != "" {
+		t.Error("the caller's request was changed")
+	}
+}
+
+func TestParseTraceparent(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		ok      bool
+		sampled bool
+	}{
+		{"sampled", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true, true},
+		{"not sampled", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", true, false},
+		{"later version with more fields", "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", true, true},
+		{"version 00 with more fields", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", false, false},
+		{"invalid version", "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false, false},
+		{"zero trace ID", "00-00000000000000000000000000000000-00f067aa0ba902b7-01", false, false},
+		{"zero span ID", "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", false, false},
+		{"short trace ID", "00-4bf92f3577b34da6a3ce929d0e0e47-00f067aa0ba902b7-01", false, false},
+		{"not hex", "00-4bf92f3577b34da6a3ce929d0e0e473z-00f067aa0ba902b7-01", false, false},
+		{"empty", "", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc, ok := parseTraceparent(tt.value)
+			if ok != tt.ok || sc.Sampled != tt.sampled {
+				t.Errorf("parseTraceparent(%q) = %+v, %v, want sampled %v, %v", tt.value, sc, ok, tt.sampled, tt.ok)
+			}
+		})
+	}
+}
+
+func TestInjectExtract(t *testing.T) {
+	sc := SpanContext{TraceID: newTraceID(), SpanID: newSpanID(), Sampled: true}
+	header := http.Header{}
+	Inject(ContextWithRemoteSpan(context.Background(), sc), header)
+	if got := SpanContextFromContext(Extract(context.Background(), header)); got != sc {
+		t.Errorf("Extract(Inject()) = %+v, want %+v", got, sc)
+	}
+
+	empty := http.Header{}
+	Inject(context.Background(), empty)
+	if len(empty) != 0 {
+		t.Errorf("Inject() without a span set %v", empty)
+	}
+}